@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,15 +14,45 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/redis/go-redis/v9"
 	"github.com/sungwon/smtp-proxy/server/internal/api"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
 	"github.com/sungwon/smtp-proxy/server/internal/bootstrap"
 	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
+	"github.com/sungwon/smtp-proxy/server/internal/dnscache"
+	"github.com/sungwon/smtp-proxy/server/internal/fips"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preview"
+	"github.com/sungwon/smtp-proxy/server/internal/profiling"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/ratelimit"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
+	"github.com/sungwon/smtp-proxy/server/internal/version"
 )
 
 func main() {
+	validate := flag.Bool("validate-config", false, "check the configuration and dependencies, print a report and exit")
+	migrateOnly := flag.Bool("migrate", false, "apply database migrations and exit")
+	pprofAddr := flag.String("pprof-addr", "", "serve /debug/pprof/ on this address, overriding profiling.pprof_addr")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println("api-server " + version.Get().String())
+		return
+	}
+	if err := fips.Check(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	// Load configuration
 	cfg, err := config.Load("config")
 	if err != nil {
@@ -27,24 +60,95 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *validate {
+		os.Exit(validateConfig(cfg))
+	}
+
 	// Initialize logger
-	log := logger.New(cfg.Logging.Level)
-	log.Info().Msg("starting API server")
+	logCfg := logger.LoggingConfig{
+		Level:         cfg.Logging.Level,
+		Output:        cfg.Logging.Output,
+		Outputs:       cfg.Logging.Outputs,
+		FilePath:      cfg.Logging.FilePath,
+		MaxSizeMB:     cfg.Logging.MaxSizeMB,
+		MaxFiles:      cfg.Logging.MaxFiles,
+		CWGroup:       cfg.Logging.CWGroup,
+		CWStream:      cfg.Logging.CWStream,
+		CWRegion:      cfg.Logging.CWRegion,
+		SyslogNetwork: cfg.Logging.Syslog.Network,
+		SyslogAddress: cfg.Logging.Syslog.Address,
+		SyslogTag:     cfg.Logging.Syslog.Tag,
+		Modules:       cfg.Logging.Modules,
+		SampleBurst:   cfg.Logging.Sampling.Burst,
+		SamplePeriod:  cfg.Logging.Sampling.Period,
+		SampleEvery:   cfg.Logging.Sampling.Every,
+	}
+	log := logger.NewFromConfig(logCfg)
+	redact.Configure(redact.Config{
+		Recipients: cfg.Logging.RedactRecipients,
+		Subjects:   cfg.Logging.RedactSubjects,
+	})
+	if err := crash.Configure(crash.Config{
+		SentryDSN:   cfg.CrashReporting.SentryDSN,
+		Environment: cfg.CrashReporting.Environment,
+		Release:     cfg.CrashReporting.Release,
+		Service:     "api-server",
+		Timeout:     cfg.CrashReporting.Timeout,
+	}); err != nil {
+		log.Fatal().Err(err).Msg("invalid crash reporting configuration")
+	}
+	log.Info().Stringer("version", version.Get()).Msg("starting API server")
 
 	// Connect to database
 	ctx := context.Background()
-	db, err := storage.NewDB(
-		ctx,
-		cfg.Database.URL,
-		cfg.Database.PoolMin,
-		cfg.Database.PoolMax,
-		cfg.Database.ConnectTimeout,
-	)
+	if *migrateOnly {
+		if err := migrate.Run(ctx, cfg.Database.URL, log); err != nil {
+			log.Fatal().Err(err).Msg("database migration failed")
+		}
+		return
+	}
+
+	profiler, err := profiling.Start(ctx, profiling.ConfigFrom(cfg.Profiling, *pprofAddr), "api-server", log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start profiling")
+	}
+
+	db, err := storage.NewDB(ctx, cfg.Database.URL, storage.PoolConfig{
+		MinConns:               cfg.Database.PoolMin,
+		MaxConns:               cfg.Database.PoolMax,
+		ConnectTimeout:         cfg.Database.ConnectTimeout,
+		MaxConnLifetime:        cfg.Database.MaxConnLifetime,
+		MaxConnIdleTime:        cfg.Database.MaxConnIdleTime,
+		HealthCheckPeriod:      cfg.Database.HealthCheckPeriod,
+		QueryExecMode:          cfg.Database.QueryExecMode,
+		StatementCacheCapacity: cfg.Database.StatementCacheCapacity,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 	defer db.Close()
 
+	// Replicas starting together take turns on an advisory lock; all but
+	// the first find the schema already current.
+	if cfg.Database.MigrateOnStart {
+		if err := migrate.UpPool(ctx, db.Pool, log); err != nil {
+			log.Fatal().Err(err).Msg("database migration failed")
+		}
+	}
+
+	// Refuse to run against a schema this release was not built for, so a
+	// partial rollout cannot write rows an old or new schema does not
+	// expect.
+	schemaReadOnly, err := migrate.Verify(ctx, db.Pool, cfg.Database.SchemaCheck, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("database schema check failed")
+	}
+
+	// Sample pool statistics for metrics.
+	poolMonitor := storage.NewPoolMonitor(db.Stats, storage.PoolMonitorConfig{Interval: cfg.Database.StatsInterval}, log)
+	poolMonitor.Start(ctx)
+	defer poolMonitor.Stop()
+
 	log.Info().Msg("database connection established")
 
 	// Create sqlc queries instance
@@ -57,9 +161,10 @@ func main() {
 		RefreshTokenExpiry: cfg.Auth.RefreshTokenExpiry,
 		Issuer:             cfg.Auth.Issuer,
 		Audience:           cfg.Auth.Audience,
+		ScopedTokenMaxTTL:  cfg.Auth.ScopedTokenMaxTTL,
 	})
 
-	if cfg.Auth.SigningKey == "" || cfg.Auth.SigningKey == "change-me-in-production-use-a-strong-secret" {
+	if auth.WeakSigningKey(cfg.Auth.SigningKey) {
 		log.Warn().Msg("JWT signing key is not set or using default value; set SMTP_PROXY_AUTH_SIGNING_KEY in production")
 	}
 
@@ -87,14 +192,6 @@ func main() {
 	})
 	auditLogger := auth.NewAuditLogger(auditStore, log)
 
-	// Initialize rate limiter.
-	rateLimiter := auth.NewRateLimiter(nil, auth.RateLimitConfig{
-		DefaultMonthlyLimit:  cfg.RateLimit.DefaultMonthlyLimit,
-		LoginAttemptsLimit:   cfg.RateLimit.LoginAttemptsLimit,
-		LoginLockoutDuration: cfg.RateLimit.LoginLockoutDuration,
-	})
-	log.Info().Msg("rate limiter initialized")
-
 	// Auto-seed system admin on startup (idempotent)
 	adminEmail := os.Getenv("SMTP_PROXY_ADMIN_EMAIL")
 	if adminEmail == "" {
@@ -104,19 +201,197 @@ func main() {
 	if adminPassword == "" {
 		adminPassword = "admin"
 	}
-	if err := bootstrap.SeedSystemAdmin(ctx, queries, log, adminEmail, adminPassword); err != nil {
+	if schemaReadOnly {
+		log.Warn().Msg("read-only: system admin not seeded")
+	} else if err := bootstrap.SeedSystemAdmin(ctx, queries, log, adminEmail, adminPassword); err != nil {
 		log.Error().Err(err).Msg("failed to seed system admin")
 	}
 
+	// Message store for previews of stored messages. The API server can
+	// run without access to message bodies, so a failure is not fatal.
+	store, err := msgstore.New(msgstore.Config{
+		Type:       cfg.Storage.Type,
+		Path:       cfg.Storage.Path,
+		S3Bucket:   cfg.Storage.S3Bucket,
+		S3Prefix:   cfg.Storage.S3Prefix,
+		S3Endpoint: cfg.Storage.S3Endpoint,
+		S3Region:   cfg.Storage.S3Region,
+	}, log)
+	if err != nil {
+		log.Warn().Err(err).Msg("message store unavailable, previews of stored messages disabled")
+		store = nil
+	}
+
+	// Optional rendering test service for message previews.
+	var renderTester preview.RenderTester
+	if cfg.Preview.RenderTestURL != "" {
+		renderTester = preview.NewHTTPRenderTester(
+			cfg.Preview.RenderTestURL,
+			cfg.Preview.RenderTestAPIKey,
+			cfg.Preview.Clients,
+			cfg.Preview.Timeout,
+		)
+		log.Info().Str("url", cfg.Preview.RenderTestURL).Msg("rendering test service configured")
+	}
+
+	// Address validation and sender identity verification resolve through
+	// the caching resolver.
+	validator := validation.New(nil)
+	var senderResolver senderpolicy.Resolver
+	if cfg.DNS.Enabled {
+		dnsResolver := dnscache.New(dnscache.Config{
+			Servers:     cfg.DNS.Servers,
+			Timeout:     cfg.DNS.Timeout,
+			MinTTL:      cfg.DNS.MinTTL,
+			MaxTTL:      cfg.DNS.MaxTTL,
+			NegativeTTL: cfg.DNS.NegativeTTL,
+			MaxEntries:  cfg.DNS.MaxEntries,
+		})
+		validator = validation.New(dnsResolver)
+		senderResolver = dnsResolver
+		log.Info().Strs("servers", dnsResolver.Servers()).Msg("caching DNS resolver enabled")
+	}
+
+	// The DLQ browser reads the queue worker's Redis dead letter streams.
+	// Without Redis the DLQ endpoints are not registered.
+	var dlq queue.DeadLetterQueue
+	var redisOK bool
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Queue.RedisAddr,
+		Password: cfg.Queue.RedisPassword,
+		DB:       cfg.Queue.RedisDB,
+	})
+	defer redisClient.Close()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		log.Warn().Err(err).Msg("Redis unavailable, dead letter queue endpoints disabled")
+	} else {
+		redisOK = true
+		dlq = queue.NewRedisDLQ(redisClient, queue.NewRedisEnqueuer(redisClient))
+	}
+
+	// Failed login counters, delays and lockouts are shared through Redis
+	// so every replica agrees; without it they are disabled.
+	loginRedis := redisClient
+	if !redisOK {
+		log.Warn().Msg("Redis unavailable, login lockout and delays disabled")
+		loginRedis = nil
+	}
+	rateLimiter := auth.NewRateLimiter(loginRedis, auth.RateLimitConfig{
+		DefaultMonthlyLimit:  cfg.RateLimit.DefaultMonthlyLimit,
+		LoginAttemptsLimit:   cfg.RateLimit.LoginAttemptsLimit,
+		LoginLockoutDuration: cfg.RateLimit.LoginLockoutDuration,
+		LoginDelayAfter:      cfg.RateLimit.LoginDelayAfter,
+		LoginDelayBase:       cfg.RateLimit.LoginDelayBase,
+		LoginDelayMax:        cfg.RateLimit.LoginDelayMax,
+	})
+	log.Info().Msg("rate limiter initialized")
+
+	// Unlock links, sign-in notices, invitations and signup verification
+	// links go through the proxy itself.
+	loginSecurity := api.LoginSecurity{
+		DetectAnomalies: cfg.Auth.LoginAnomalyDetection,
+		CountryHeader:   cfg.Auth.CountryHeader,
+	}
+	invitations := api.Invitations{TTL: cfg.Auth.InvitationTTL}
+	signup := api.Signup{
+		TTL:                 cfg.Signup.VerificationTTL,
+		MonthlyLimit:        cfg.Signup.MonthlyLimit,
+		RecipientValidation: cfg.Signup.RecipientValidation,
+	}
+	if cfg.SystemMail.Enabled {
+		mailer := sysmail.New(queries, db, sysmail.Config{
+			From:    cfg.SystemMail.From,
+			BaseURL: cfg.SystemMail.BaseURL,
+		})
+		loginSecurity.Mailer = mailer
+		invitations.Mailer = mailer
+		if cfg.Signup.Enabled {
+			signup.Mailer = mailer
+		}
+	}
+	if cfg.Signup.Enabled && signup.Mailer == nil {
+		log.Warn().Msg("signup requires system_mail for verification links, self-service signup disabled")
+	}
+
+	// Request rate limits are shared through Redis; without it each API
+	// server enforces them on its own.
+	requestRateLimit, err := apiRateLimit(cfg.API.RateLimit)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid api.rate_limit configuration")
+	}
+	if cfg.API.RateLimit.Enabled {
+		if redisOK {
+			requestRateLimit.Bucket = ratelimit.NewRedisBucket(redisClient, "ratelimit:api:")
+		} else {
+			log.Warn().Msg("Redis unavailable, API rate limits enforced per process")
+			requestRateLimit.Bucket = ratelimit.NewMemoryBucket()
+		}
+	}
+
+	// Per route group IP allowlists and client certificate requirements.
+	accessRules, err := apiAccessRules(cfg.API)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid api.access configuration")
+	}
+
+	// Password rules for passwords set through the API.
+	passwordPolicy := &auth.PasswordPolicy{
+		MinLength:     cfg.PasswordPolicy.MinLength,
+		RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+		RequireLower:  cfg.PasswordPolicy.RequireLower,
+		RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+	}
+	if cfg.PasswordPolicy.BreachCheck {
+		passwordPolicy.Breach = auth.NewHIBPChecker(cfg.PasswordPolicy.BreachAPIURL, cfg.PasswordPolicy.BreachTimeout)
+	}
+
+	// Pacing of bulk resends of failed messages.
+	resend := api.Resend{
+		Rate:        cfg.Resend.Rate,
+		MaxMessages: cfg.Resend.MaxMessages,
+	}
+
+	// Signed download URLs of CSV delivery reports.
+	deliveryReports := api.DeliveryReports{
+		BaseURL: cfg.SystemMail.BaseURL,
+		URLTTL:  cfg.DeliveryReports.URLTTL,
+	}
+
 	// Build router with full config
 	router := api.NewRouterWithConfig(api.RouterConfig{
-		Queries:     queries,
-		DB:          db,
-		Log:         log,
-		DLQ:         nil,
-		JWTService:  jwtService,
-		AuditLogger: auditLogger,
-		RateLimiter: rateLimiter,
+		Queries:          queries,
+		DB:               db,
+		Log:              logger.Module(log, logCfg, "api"),
+		DLQ:              dlq,
+		JWTService:       jwtService,
+		AuditLogger:      auditLogger,
+		RateLimiter:      rateLimiter,
+		LoginSecurity:    loginSecurity,
+		Invitations:      invitations,
+		Signup:           signup,
+		Resend:           resend,
+		DeliveryReports:  deliveryReports,
+		MessageStore:     store,
+		RenderTester:     renderTester,
+		Validator:        validator,
+		SenderResolver:   senderResolver,
+		AccessRules:      accessRules,
+		RequestRateLimit: requestRateLimit,
+		PasswordPolicy:   passwordPolicy,
+		CORS: api.CORSConfig{
+			AllowedOrigins:   cfg.API.CORS.AllowedOrigins,
+			AllowedHeaders:   cfg.API.CORS.AllowedHeaders,
+			AllowCredentials: cfg.API.CORS.AllowCredentials,
+			MaxAge:           cfg.API.CORS.MaxAge,
+		},
+		CSRF: api.CSRFConfig{
+			Enabled:    cfg.API.CSRF.Enabled,
+			CookieName: cfg.API.CSRF.CookieName,
+			Secure:     cfg.API.CSRF.CookieSecure,
+		},
+		ReadOnly:  schemaReadOnly,
+		LegacyAPI: cfg.API.Legacy.Enabled,
 	})
 
 	// Configure HTTP server
@@ -128,10 +403,24 @@ func main() {
 		WriteTimeout: cfg.API.WriteTimeout,
 	}
 
+	if cfg.API.TLS.Enabled {
+		tlsConfig, err := apiTLSConfig(cfg.API.TLS)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to configure API TLS")
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
 	// Start server in goroutine
 	go func() {
-		log.Info().Str("addr", addr).Msg("API server listening")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info().Str("addr", addr).Bool("tls", srv.TLSConfig != nil).Msg("API server listening")
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("server error")
 		}
 	}()
@@ -150,5 +439,73 @@ func main() {
 		log.Error().Err(err).Msg("server forced to shutdown")
 	}
 
+	profiler.Shutdown(shutdownCtx)
+	crash.Flush(5 * time.Second)
+
 	log.Info().Msg("server stopped")
 }
+
+// apiAccessRules parses the api.access rules. Rules requiring a client
+// certificate need TLS with a client CA.
+func apiAccessRules(cfg config.APIConfig) ([]api.AccessRule, error) {
+	rules := make([]api.AccessRule, 0, len(cfg.Access))
+	for _, a := range cfg.Access {
+		rule, err := api.NewAccessRule(a.PathPrefix, a.AllowCIDRs, a.RequireClientCert)
+		if err != nil {
+			return nil, err
+		}
+		if rule.RequireClientCert && (!cfg.TLS.Enabled || cfg.TLS.ClientCAFile == "") {
+			return nil, fmt.Errorf("access rule %s requires client certificates but api.tls.client_ca_file is not set", a.PathPrefix)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// apiRateLimit converts api.rate_limit to the router's rate limit
+// configuration, without a bucket.
+func apiRateLimit(cfg config.APIRateLimitConfig) (api.RateLimitConfig, error) {
+	proxies, err := api.ParseCIDRs(cfg.TrustedProxies)
+	if err != nil {
+		return api.RateLimitConfig{}, fmt.Errorf("trusted_proxies: %w", err)
+	}
+	class := func(c config.APIRateLimitClass) api.RateLimitClass {
+		return api.RateLimitClass{
+			PerIP:  ratelimit.PerMinute(c.PerIP, c.Burst),
+			PerKey: ratelimit.PerMinute(c.PerKey, c.Burst),
+		}
+	}
+	return api.RateLimitConfig{
+		Auth:           class(cfg.Auth),
+		Webhooks:       class(cfg.Webhooks),
+		Default:        class(cfg.Default),
+		TrustedProxies: proxies,
+	}, nil
+}
+
+// apiTLSConfig loads the API server certificate and, when configured, the
+// CAs that client certificates are verified against. Client certificates
+// are optional at the handshake; access rules decide which routes need one.
+func apiTLSConfig(cfg config.APITLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
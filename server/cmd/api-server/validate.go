@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preflight"
+)
+
+// validateConfig checks the configuration and the services the API server
+// depends on, prints a report and returns the process exit code.
+func validateConfig(cfg *config.Config) int {
+	_, accessErr := apiAccessRules(cfg.API)
+	_, rateLimitErr := apiRateLimit(cfg.API.RateLimit)
+	checks := []preflight.Check{
+		preflight.Static("api.access", accessErr),
+		preflight.Static("api.rate_limit", rateLimitErr),
+		preflight.Postgres(cfg.Database.URL),
+		preflight.Schema(cfg.Database.URL, cfg.Database.SchemaCheck, cfg.Database.MigrateOnStart),
+		preflight.Redis(&redis.Options{
+			Addr:     cfg.Queue.RedisAddr,
+			Password: cfg.Queue.RedisPassword,
+			DB:       cfg.Queue.RedisDB,
+		}),
+		preflight.MessageStore(msgstore.Config{
+			Type:       cfg.Storage.Type,
+			Path:       cfg.Storage.Path,
+			S3Bucket:   cfg.Storage.S3Bucket,
+			S3Prefix:   cfg.Storage.S3Prefix,
+			S3Endpoint: cfg.Storage.S3Endpoint,
+			S3Region:   cfg.Storage.S3Region,
+		}),
+	}
+	if cfg.API.TLS.Enabled {
+		checks = append(checks, preflight.KeyPair("api.tls", cfg.API.TLS.CertFile, cfg.API.TLS.KeyFile))
+		if cfg.API.TLS.ClientCAFile != "" {
+			checks = append(checks, preflight.CAFile("api.tls client CA", cfg.API.TLS.ClientCAFile))
+		}
+	}
+
+	report := preflight.Run(context.Background(), preflight.DefaultTimeout, checks)
+	report.Write(os.Stdout)
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,510 @@
+// Package main provides a load generator for the smtp-proxy SMTP server. It
+// drives concurrent authenticated SMTP sessions with a mix of message sizes
+// and attachments and reports accepted messages per second, enqueue latency
+// (MAIL FROM to the final DATA reply) and, when an API token is given,
+// end-to-end delivery latency read back from the messages API.
+//
+// Usage:
+//
+//	loadgen --user dev --password dev --from dev@example.com --to sink@example.com
+//	loadgen --sessions 50 --messages 200 --sizes 2KB,64KB,1MB --attach-ratio 0.3
+//	loadgen --api http://localhost:8080 --api-token $TOKEN --max-p99 250ms --min-rate 100
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	gosmtp "github.com/emersion/go-smtp"
+)
+
+// queuedPrefix precedes the message ID in the server's final DATA reply.
+const queuedPrefix = "queued as "
+
+type config struct {
+	host        string
+	port        int
+	tlsMode     string
+	insecure    bool
+	user        string
+	password    string
+	from        string
+	to          stringSlice
+	sessions    int
+	messages    int
+	duration    time.Duration
+	sizes       []int
+	attachRatio float64
+	apiURL      string
+	apiToken    string
+	wait        time.Duration
+	minRate     float64
+	maxP99      time.Duration
+}
+
+// stringSlice implements flag.Value for repeatable --to flags.
+type stringSlice []string
+
+func (s *stringSlice) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// result is the outcome of one submitted message.
+type result struct {
+	queuedID string
+	size     int
+	latency  time.Duration
+	err      error
+}
+
+func main() {
+	cfg := parseFlags()
+	runID := newRunID()
+	tag := "loadgen-" + runID
+
+	addr := net.JoinHostPort(cfg.host, strconv.Itoa(cfg.port))
+	fmt.Printf("SMTP Load Generator\n")
+	fmt.Printf("  Server:   %s (tls=%s)\n", addr, cfg.tlsMode)
+	fmt.Printf("  Sessions: %d x %d messages\n", cfg.sessions, cfg.messages)
+	if cfg.duration > 0 {
+		fmt.Printf("  Duration: %s max\n", cfg.duration)
+	}
+	fmt.Printf("  Sizes:    %s, %.0f%% with attachment\n", formatSizes(cfg.sizes), cfg.attachRatio*100)
+	fmt.Printf("  Tag:      %s\n", tag)
+	fmt.Println()
+
+	var deadline time.Time
+	if cfg.duration > 0 {
+		deadline = time.Now().Add(cfg.duration)
+	}
+
+	var (
+		mu      sync.Mutex
+		results []result
+		seq     atomic.Int64
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < cfg.sessions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rs := runSession(cfg, addr, tag, &seq, deadline)
+			mu.Lock()
+			results = append(results, rs...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var (
+		latencies []time.Duration
+		accepted  []string
+		bytesSent int64
+		errCounts = make(map[string]int)
+	)
+	for _, r := range results {
+		if r.err != nil {
+			errCounts[r.err.Error()]++
+			continue
+		}
+		latencies = append(latencies, r.latency)
+		bytesSent += int64(r.size)
+		if r.queuedID != "" {
+			accepted = append(accepted, r.queuedID)
+		}
+	}
+
+	rate := float64(len(latencies)) / elapsed.Seconds()
+	fmt.Printf("Results\n")
+	fmt.Printf("  Accepted:   %d of %d in %s\n", len(latencies), len(results), elapsed.Round(time.Millisecond))
+	fmt.Printf("  Throughput: %.1f msg/s, %.2f MB/s\n", rate, float64(bytesSent)/elapsed.Seconds()/(1<<20))
+	enqueueP99 := printLatencies("Enqueue latency", latencies)
+	for msg, n := range errCounts {
+		fmt.Printf("  Error (%dx): %s\n", n, msg)
+	}
+
+	if cfg.apiURL != "" && cfg.apiToken != "" && len(accepted) > 0 {
+		delivery, err := waitForDelivery(cfg, tag, len(accepted))
+		if err != nil {
+			fmt.Printf("  Delivery:   %v\n", err)
+		} else {
+			printLatencies(fmt.Sprintf("Delivery latency (n=%d)", len(delivery)), delivery)
+		}
+	}
+
+	failed := len(errCounts) > 0
+	if cfg.minRate > 0 && rate < cfg.minRate {
+		fmt.Printf("\nFAIL: throughput %.1f msg/s below --min-rate %.1f\n", rate, cfg.minRate)
+		failed = true
+	}
+	if cfg.maxP99 > 0 && enqueueP99 > cfg.maxP99 {
+		fmt.Printf("\nFAIL: enqueue p99 %s above --max-p99 %s\n", enqueueP99, cfg.maxP99)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func parseFlags() config {
+	var (
+		cfg   config
+		sizes string
+	)
+
+	flag.StringVar(&cfg.host, "host", "localhost", "SMTP server host")
+	flag.IntVar(&cfg.port, "port", 587, "SMTP server port")
+	flag.StringVar(&cfg.tlsMode, "tls", "starttls", "TLS mode: starttls, implicit, none")
+	flag.BoolVar(&cfg.insecure, "insecure", false, "Skip TLS certificate verification")
+	flag.StringVar(&cfg.user, "user", "", "SMTP AUTH username")
+	flag.StringVar(&cfg.password, "password", "", "SMTP AUTH password")
+	flag.StringVar(&cfg.from, "from", "", "Sender email address")
+	flag.Var(&cfg.to, "to", "Recipient email address (can be specified multiple times)")
+	flag.IntVar(&cfg.sessions, "sessions", 10, "Concurrent SMTP sessions")
+	flag.IntVar(&cfg.messages, "messages", 100, "Messages sent per session")
+	flag.DurationVar(&cfg.duration, "duration", 0, "Stop sending after this long (0 = send all messages)")
+	flag.StringVar(&sizes, "sizes", "2KB,32KB,256KB", "Comma-separated message body sizes, picked round-robin")
+	flag.Float64Var(&cfg.attachRatio, "attach-ratio", 0.2, "Fraction of messages sent with a binary attachment")
+	flag.StringVar(&cfg.apiURL, "api", "", "API base URL for end-to-end delivery latency, e.g. http://localhost:8080")
+	flag.StringVar(&cfg.apiToken, "api-token", "", "Bearer token (JWT or API key) for --api")
+	flag.DurationVar(&cfg.wait, "wait", 2*time.Minute, "How long to wait for deliveries when --api is set")
+	flag.Float64Var(&cfg.minRate, "min-rate", 0, "Exit non-zero when accepted msg/s falls below this")
+	flag.DurationVar(&cfg.maxP99, "max-p99", 0, "Exit non-zero when enqueue p99 latency exceeds this")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: loadgen [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Drives concurrent SMTP traffic against the smtp-proxy SMTP server.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if cfg.from == "" || len(cfg.to) == 0 {
+		fmt.Fprintln(os.Stderr, "error: --from and at least one --to are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if cfg.sessions < 1 || cfg.messages < 1 {
+		fmt.Fprintln(os.Stderr, "error: --sessions and --messages must be positive")
+		os.Exit(2)
+	}
+	for _, s := range strings.Split(sizes, ",") {
+		n, err := parseSize(strings.TrimSpace(s))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: --sizes: %v\n", err)
+			os.Exit(2)
+		}
+		cfg.sizes = append(cfg.sizes, n)
+	}
+	return cfg
+}
+
+// runSession sends cfg.messages messages over one SMTP connection,
+// reconnecting after connection-level errors.
+func runSession(cfg config, addr, tag string, seq *atomic.Int64, deadline time.Time) []result {
+	results := make([]result, 0, cfg.messages)
+	var c *gosmtp.Client
+	defer func() {
+		if c != nil {
+			c.Quit()
+		}
+	}()
+
+	for i := 0; i < cfg.messages; i++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		if c == nil {
+			var err error
+			if c, err = connect(cfg, addr); err != nil {
+				results = append(results, result{err: err})
+				continue
+			}
+		}
+
+		n := seq.Add(1)
+		size := cfg.sizes[int(n)%len(cfg.sizes)]
+		attach := float64(n%100) < cfg.attachRatio*100
+		msg := buildMessage(cfg, tag, n, size, attach)
+
+		r := send(c, cfg, msg)
+		r.size = len(msg)
+		results = append(results, r)
+
+		var smtpErr *gosmtp.SMTPError
+		if r.err != nil && !errors.As(r.err, &smtpErr) {
+			// The connection is unusable; start a new session.
+			c.Close()
+			c = nil
+		} else if r.err != nil {
+			c.Reset()
+		}
+	}
+	return results
+}
+
+func connect(cfg config, addr string) (*gosmtp.Client, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.host,
+		InsecureSkipVerify: cfg.insecure, //nolint:gosec // Intentional for dev self-signed certs.
+	}
+
+	var (
+		c   *gosmtp.Client
+		err error
+	)
+	switch cfg.tlsMode {
+	case "none":
+		c, err = gosmtp.Dial(addr)
+	case "implicit":
+		c, err = gosmtp.DialTLS(addr, tlsConfig)
+	case "starttls":
+		c, err = gosmtp.DialStartTLS(addr, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unknown TLS mode: %s (use starttls, implicit, or none)", cfg.tlsMode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	if cfg.user != "" {
+		if err := c.Auth(sasl.NewPlainClient("", cfg.user, cfg.password)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// send submits one message and measures MAIL FROM through the final DATA
+// reply.
+func send(c *gosmtp.Client, cfg config, msg []byte) result {
+	start := time.Now()
+	if err := c.Mail(cfg.from, nil); err != nil {
+		return result{err: err}
+	}
+	for _, rcpt := range cfg.to {
+		if err := c.Rcpt(rcpt, nil); err != nil {
+			return result{err: err}
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return result{err: err}
+	}
+	if _, err := w.Write(msg); err != nil {
+		return result{err: err}
+	}
+	resp, err := w.CloseWithResponse()
+	if err != nil {
+		return result{err: err}
+	}
+
+	r := result{latency: time.Since(start)}
+	if i := strings.Index(resp.StatusText, queuedPrefix); i >= 0 {
+		r.queuedID = strings.TrimSpace(resp.StatusText[i+len(queuedPrefix):])
+	}
+	return r
+}
+
+// buildMessage returns a message with a text body of about size bytes,
+// tagged so its deliveries can be found through the messages API.
+func buildMessage(cfg config, tag string, n int64, size int, attach bool) []byte {
+	var buf bytes.Buffer
+	buf.Grow(size + size/3 + 1024)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", cfg.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(cfg.to, ", "))
+	fmt.Fprintf(&buf, "Subject: loadgen message %d\r\n", n)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "X-SMTPProxy-Tag: %s\r\n", tag)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	text := filler(size)
+	if !attach {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buf.Write(text)
+		return buf.Bytes()
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mw.Boundary())
+
+	part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	part.Write(text[:len(text)/2])
+
+	blob := make([]byte, size/2)
+	rand.Read(blob)
+	part, _ = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/octet-stream"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=\"blob-%d.bin\"", n)},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	enc := base64.StdEncoding.EncodeToString(blob)
+	for len(enc) > 76 {
+		part.Write([]byte(enc[:76] + "\r\n"))
+		enc = enc[76:]
+	}
+	part.Write([]byte(enc + "\r\n"))
+	mw.Close()
+
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// filler returns size bytes of printable text in 76-column lines.
+func filler(size int) []byte {
+	const line = "The quick brown fox jumps over the lazy dog. Pack my box with five dozen.\r\n"
+	out := make([]byte, 0, size+len(line))
+	for len(out) < size {
+		out = append(out, line...)
+	}
+	return out[:size]
+}
+
+// apiMessage is the subset of the messages API response loadgen reads.
+type apiMessage struct {
+	Status      string     `json:"status"`
+	EnqueuedAt  time.Time  `json:"enqueued_at"`
+	ProcessedAt *time.Time `json:"processed_at"`
+}
+
+// waitForDelivery polls the messages API until no tagged message is queued
+// or processing, then returns processed_at - enqueued_at for the most recent
+// delivered messages (the API returns at most 500).
+func waitForDelivery(cfg config, tag string, accepted int) ([]time.Duration, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	deadline := time.Now().Add(cfg.wait)
+	for {
+		pending := 0
+		for _, status := range []string{"queued", "processing"} {
+			msgs, err := listMessages(client, cfg, tag, status)
+			if err != nil {
+				return nil, err
+			}
+			pending += len(msgs)
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%d of %d messages still pending after %s", pending, accepted, cfg.wait)
+		}
+		time.Sleep(time.Second)
+	}
+
+	msgs, err := listMessages(client, cfg, tag, "delivered")
+	if err != nil {
+		return nil, err
+	}
+	latencies := make([]time.Duration, 0, len(msgs))
+	for _, m := range msgs {
+		if m.ProcessedAt != nil {
+			latencies = append(latencies, m.ProcessedAt.Sub(m.EnqueuedAt))
+		}
+	}
+	return latencies, nil
+}
+
+func listMessages(client *http.Client, cfg config, tag, status string) ([]apiMessage, error) {
+	q := url.Values{"tag": {tag}, "status": {status}, "limit": {"500"}}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(cfg.apiURL, "/")+"/api/v1/messages?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.apiToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list messages: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list messages: HTTP %d", resp.StatusCode)
+	}
+
+	var msgs []apiMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msgs); err != nil {
+		return nil, fmt.Errorf("decode messages: %w", err)
+	}
+	return msgs, nil
+}
+
+// printLatencies prints percentiles of ds and returns the p99.
+func printLatencies(label string, ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	slices.Sort(ds)
+	pct := func(p float64) time.Duration {
+		return ds[min(len(ds)-1, int(float64(len(ds))*p))].Round(100 * time.Microsecond)
+	}
+	fmt.Printf("  %s: p50 %s, p95 %s, p99 %s, max %s\n", label, pct(0.50), pct(0.95), pct(0.99), ds[len(ds)-1].Round(100*time.Microsecond))
+	return pct(0.99)
+}
+
+// parseSize parses sizes such as "512", "2KB" or "1MB".
+func parseSize(s string) (int, error) {
+	mult := 1
+	upper := strings.ToUpper(s)
+	switch {
+	case strings.HasSuffix(upper, "MB"):
+		mult, upper = 1<<20, strings.TrimSuffix(upper, "MB")
+	case strings.HasSuffix(upper, "KB"):
+		mult, upper = 1<<10, strings.TrimSuffix(upper, "KB")
+	case strings.HasSuffix(upper, "B"):
+		upper = strings.TrimSuffix(upper, "B")
+	}
+	n, err := strconv.Atoi(upper)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+func formatSizes(sizes []int) string {
+	parts := make([]string, len(sizes))
+	for i, n := range sizes {
+		switch {
+		case n >= 1<<20 && n%(1<<20) == 0:
+			parts[i] = fmt.Sprintf("%dMB", n>>20)
+		case n >= 1<<10 && n%(1<<10) == 0:
+			parts[i] = fmt.Sprintf("%dKB", n>>10)
+		default:
+			parts[i] = fmt.Sprintf("%dB", n)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
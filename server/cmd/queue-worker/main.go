@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,37 +12,188 @@ import (
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/sungwon/smtp-proxy/server/internal/analytics"
 	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
+	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/deliveryreport"
+	"github.com/sungwon/smtp-proxy/server/internal/dnsbl"
+	"github.com/sungwon/smtp-proxy/server/internal/dnscache"
+	"github.com/sungwon/smtp-proxy/server/internal/fips"
+	"github.com/sungwon/smtp-proxy/server/internal/logarchive"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
 	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/plugins"
+	"github.com/sungwon/smtp-proxy/server/internal/pop3"
+	"github.com/sungwon/smtp-proxy/server/internal/profiling"
 	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/ratelimit"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/scripting"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/version"
 	"github.com/sungwon/smtp-proxy/server/internal/worker"
 )
 
 func main() {
+	validate := flag.Bool("validate-config", false, "check the configuration and dependencies, print a report and exit")
+	migrateOnly := flag.Bool("migrate", false, "apply database migrations and exit")
+	pprofAddr := flag.String("pprof-addr", "", "serve /debug/pprof/ on this address, overriding profiling.pprof_addr")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println("queue-worker " + version.Get().String())
+		return
+	}
+	if err := fips.Check(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	cfg, err := config.Load("config")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
 		os.Exit(1)
 	}
 
-	log := logger.New(cfg.Logging.Level)
-	log.Info().Msg("starting queue worker")
+	if *validate {
+		os.Exit(validateConfig(cfg))
+	}
+
+	logCfg := logger.LoggingConfig{
+		Level:         cfg.Logging.Level,
+		Output:        cfg.Logging.Output,
+		Outputs:       cfg.Logging.Outputs,
+		FilePath:      cfg.Logging.FilePath,
+		MaxSizeMB:     cfg.Logging.MaxSizeMB,
+		MaxFiles:      cfg.Logging.MaxFiles,
+		CWGroup:       cfg.Logging.CWGroup,
+		CWStream:      cfg.Logging.CWStream,
+		CWRegion:      cfg.Logging.CWRegion,
+		SyslogNetwork: cfg.Logging.Syslog.Network,
+		SyslogAddress: cfg.Logging.Syslog.Address,
+		SyslogTag:     cfg.Logging.Syslog.Tag,
+		Modules:       cfg.Logging.Modules,
+		SampleBurst:   cfg.Logging.Sampling.Burst,
+		SamplePeriod:  cfg.Logging.Sampling.Period,
+		SampleEvery:   cfg.Logging.Sampling.Every,
+	}
+	log := logger.NewFromConfig(logCfg)
+	redact.Configure(redact.Config{
+		Recipients: cfg.Logging.RedactRecipients,
+		Subjects:   cfg.Logging.RedactSubjects,
+	})
+	if err := crash.Configure(crash.Config{
+		SentryDSN:   cfg.CrashReporting.SentryDSN,
+		Environment: cfg.CrashReporting.Environment,
+		Release:     cfg.CrashReporting.Release,
+		Service:     "queue-worker",
+		Timeout:     cfg.CrashReporting.Timeout,
+	}); err != nil {
+		log.Fatal().Err(err).Msg("invalid crash reporting configuration")
+	}
+	log.Info().Stringer("version", version.Get()).Msg("starting queue worker")
 
 	// Initialize database connection pool.
 	ctx := context.Background()
-	db, err := storage.NewDB(ctx, cfg.Database.URL, cfg.Database.PoolMin, cfg.Database.PoolMax, cfg.Database.ConnectTimeout)
+	if *migrateOnly {
+		if err := migrate.Run(ctx, cfg.Database.URL, log); err != nil {
+			log.Fatal().Err(err).Msg("database migration failed")
+		}
+		return
+	}
+
+	profiler, err := profiling.Start(ctx, profiling.ConfigFrom(cfg.Profiling, *pprofAddr), "queue-worker", log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start profiling")
+	}
+
+	db, err := storage.NewDB(ctx, cfg.Database.URL, storage.PoolConfig{
+		MinConns:               cfg.Database.PoolMin,
+		MaxConns:               cfg.Database.PoolMax,
+		ConnectTimeout:         cfg.Database.ConnectTimeout,
+		MaxConnLifetime:        cfg.Database.MaxConnLifetime,
+		MaxConnIdleTime:        cfg.Database.MaxConnIdleTime,
+		HealthCheckPeriod:      cfg.Database.HealthCheckPeriod,
+		QueryExecMode:          cfg.Database.QueryExecMode,
+		StatementCacheCapacity: cfg.Database.StatementCacheCapacity,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 	defer db.Close()
 
+	// Replicas starting together take turns on an advisory lock; all but
+	// the first find the schema already current.
+	if cfg.Database.MigrateOnStart {
+		if err := migrate.UpPool(ctx, db.Pool, log); err != nil {
+			log.Fatal().Err(err).Msg("database migration failed")
+		}
+	}
+
+	// Refuse to run against a schema this release was not built for, so a
+	// partial rollout cannot write rows an old or new schema does not
+	// expect.
+	schemaReadOnly, err := migrate.Verify(ctx, db.Pool, cfg.Database.SchemaCheck, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("database schema check failed")
+	}
+
+	// Every loop of the worker writes to the database, so a read-only
+	// worker idles until it is replaced by the upgraded release.
+	if schemaReadOnly {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		profiler.Shutdown(context.Background())
+		log.Info().Msg("queue worker stopped")
+		return
+	}
+
+	// Sample pool statistics for metrics.
+	poolMonitor := storage.NewPoolMonitor(db.Stats, storage.PoolMonitorConfig{Interval: cfg.Database.StatsInterval}, log)
+	poolMonitor.Start(ctx)
+	defer poolMonitor.Stop()
+
 	queries := storage.New(db.Pool)
 
+	// Plugins register custom provider types and delivery hooks before
+	// any provider is created.
+	if err := plugins.Load(cfg.Plugins.Paths, log); err != nil {
+		log.Fatal().Err(err).Msg("failed to load plugins")
+	}
+
 	// Initialize provider resolver with HTTP client and stdout fallback.
+	// Provider API hosts are resolved through the caching DNS resolver.
 	httpClient := provider.NewHTTPClient(30 * time.Second)
+	var blocklistResolver dnsbl.Resolver = net.DefaultResolver
+	if cfg.DNS.Enabled {
+		dnsResolver := dnscache.New(dnscache.Config{
+			Servers:     cfg.DNS.Servers,
+			Timeout:     cfg.DNS.Timeout,
+			MinTTL:      cfg.DNS.MinTTL,
+			MaxTTL:      cfg.DNS.MaxTTL,
+			NegativeTTL: cfg.DNS.NegativeTTL,
+			MaxEntries:  cfg.DNS.MaxEntries,
+		})
+		httpClient = provider.NewHTTPClientWithDialer(30*time.Second, dnsResolver.DialContext)
+		blocklistResolver = dnsResolver
+		log.Info().Strs("servers", dnsResolver.Servers()).Msg("caching DNS resolver enabled")
+	}
+	if cfg.Egress.ProxyURL != "" {
+		proxyURL, err := provider.ParseProxyURL(cfg.Egress.ProxyURL)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid egress proxy configuration")
+		}
+		if httpClient, err = httpClient.WithProxy(cfg.Egress.ProxyURL); err != nil {
+			log.Fatal().Err(err).Msg("invalid egress proxy configuration")
+		}
+		log.Info().Str("proxy", proxyURL.Redacted()).Msg("provider traffic routed through egress proxy")
+	}
 	resolver := provider.NewResolver(queries, httpClient, log)
 
 	// Connect to Redis.
@@ -68,7 +221,19 @@ func main() {
 	}
 
 	// Create message handler with delivery logic.
-	handler := worker.NewHandler(resolver, queries, store, log)
+	queueLog := logger.Module(log, logCfg, "queue")
+	handler := worker.NewHandler(resolver, queries, store, queueLog)
+	// Recipient domain limits are shared by every worker through Redis.
+	handler.SetDomainThrottle(ratelimit.NewRedisBucket(redisClient, "ratelimit:domain:"))
+	if cfg.Scripting.Enabled {
+		handler.SetScripts(scripting.NewEngine(queries, scripting.Config{
+			Timeout:         cfg.Scripting.Timeout,
+			CallStackSize:   cfg.Scripting.CallStackSize,
+			RegistryMaxSize: cfg.Scripting.RegistryMaxSize,
+			MaxStringSize:   cfg.Scripting.MaxStringSize,
+			MaxMemory:       cfg.Scripting.MaxMemory,
+		}, logger.Module(log, logCfg, "scripting")))
+	}
 
 	// Build worker pool configuration.
 	workerCount := cfg.Queue.Workers
@@ -88,10 +253,24 @@ func main() {
 		MaxRetries:      5,
 	}
 
+	// Operational alerts go to the deployment's channels and to the
+	// affected group's own channels.
+	deploymentAlerts, err := alertChannels(cfg.Alerts)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid alert channel configuration")
+	}
+	alerts := notify.NewRouter(deploymentAlerts, queries, cfg.Alerts.Timeout)
+
 	// Create queue components using Redis implementations.
 	enqueuer := queue.NewRedisEnqueuer(redisClient)
 	retryStrategy := queue.NewRetryStrategy(queueCfg.MaxRetries)
-	dlq := queue.NewRedisDLQ(redisClient, enqueuer)
+	var dlq queue.DeadLetterQueue = queue.NewRedisDLQ(redisClient, enqueuer)
+	if cfg.Alerts.DLQ.Enabled {
+		dlq = worker.NewDLQAlerter(dlq, alerts, worker.DLQAlertConfig{
+			Threshold: cfg.Alerts.DLQ.Threshold,
+			Window:    cfg.Alerts.DLQ.Window,
+		}, log)
+	}
 	dequeuer := queue.NewRedisDequeuer(
 		redisClient,
 		enqueuer,
@@ -99,7 +278,7 @@ func main() {
 		handler,
 		retryStrategy,
 		queueCfg,
-		log,
+		queueLog,
 		cfg.Queue.StreamName,
 		cfg.Queue.GroupName,
 	)
@@ -113,6 +292,178 @@ func main() {
 		Str("group", cfg.Queue.GroupName).
 		Msg("queue worker pool started")
 
+	// Start the stuck-message sweeper (re-enqueues via the same Redis stream).
+	var sweeper *worker.Sweeper
+	if cfg.Sweeper.Enabled {
+		sweeper = worker.NewSweeper(queries, delivery.NewAsyncService(enqueuer, log), worker.SweeperConfig{
+			Interval:          cfg.Sweeper.Interval,
+			QueuedTimeout:     cfg.Sweeper.QueuedTimeout,
+			ProcessingTimeout: cfg.Sweeper.ProcessingTimeout,
+			MaxRequeues:       cfg.Sweeper.MaxRequeues,
+			BatchSize:         cfg.Sweeper.BatchSize,
+		}, log)
+		sweeper.Start(ctx)
+	}
+
+	// Start the delivery log archiver (moves old logs into the message store).
+	var archiver *logarchive.Archiver
+	if cfg.LogArchive.Enabled {
+		archiver = logarchive.NewArchiver(queries, db, store, logarchive.Config{
+			After:      cfg.LogArchive.After,
+			Interval:   cfg.LogArchive.Interval,
+			BatchSize:  cfg.LogArchive.BatchSize,
+			MaxBatches: cfg.LogArchive.MaxBatches,
+		}, log)
+		archiver.Start(ctx)
+	}
+
+	// Start the delivery report generator (writes requested CSV reports to the message store).
+	var reportGenerator *deliveryreport.Generator
+	if cfg.DeliveryReports.Enabled {
+		reportGenerator = deliveryreport.NewGenerator(queries, store, deliveryreport.Config{
+			Interval:  cfg.DeliveryReports.Interval,
+			Retention: cfg.DeliveryReports.Retention,
+			MaxRows:   cfg.DeliveryReports.MaxRows,
+		}, log)
+		reportGenerator.Start(ctx)
+	}
+
+	// Start the analytics exporter (streams delivery events to ClickHouse or Kafka).
+	var exporter *analytics.Exporter
+	if cfg.Analytics.Enabled {
+		sinkCfg := analytics.SinkConfig{
+			Type:     cfg.Analytics.Sink,
+			URL:      cfg.Analytics.ClickHouse.URL,
+			Database: cfg.Analytics.ClickHouse.Database,
+			Table:    cfg.Analytics.ClickHouse.Table,
+			Username: cfg.Analytics.ClickHouse.Username,
+			Password: cfg.Analytics.ClickHouse.Password,
+			Timeout:  cfg.Analytics.Timeout,
+		}
+		if cfg.Analytics.Sink == analytics.SinkKafka {
+			sinkCfg.URL = cfg.Analytics.Kafka.RestURL
+			sinkCfg.Topic = cfg.Analytics.Kafka.Topic
+		}
+		sink, err := analytics.NewSink(sinkCfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid analytics export configuration")
+		}
+		exporter = analytics.NewExporter(queries, sink, analytics.Config{
+			Interval:   cfg.Analytics.Interval,
+			BatchSize:  cfg.Analytics.BatchSize,
+			MaxBatches: cfg.Analytics.MaxBatches,
+			Lag:        cfg.Analytics.Lag,
+			Backfill:   cfg.Analytics.Backfill,
+		}, log)
+		exporter.Start(ctx)
+	}
+
+	// Start the provider health prober.
+	var prober *worker.ProviderProber
+	if cfg.Prober.Enabled {
+		prober = worker.NewProviderProber(queries, httpClient, worker.ProberConfig{
+			Interval:         cfg.Prober.Interval,
+			Timeout:          cfg.Prober.Timeout,
+			FailureThreshold: cfg.Prober.FailureThreshold,
+			HistoryRetention: cfg.Prober.HistoryRetention,
+		}, log)
+		prober.SetNotifier(alerts)
+		prober.Start(ctx)
+	}
+
+	// Start the ESP quota and reputation poller.
+	var accountPoller *worker.AccountPoller
+	if cfg.AccountPoller.Enabled {
+		accountPoller = worker.NewAccountPoller(queries, httpClient, worker.AccountPollerConfig{
+			Interval: cfg.AccountPoller.Interval,
+			Timeout:  cfg.AccountPoller.Timeout,
+		}, log)
+		if rc := cfg.AccountPoller.Reputation; rc.Enabled {
+			accountPoller.SetReputationPolicy(worker.ReputationPolicy{
+				Action:           rc.Action,
+				MaxBounceRate:    rc.MaxBounceRate,
+				MaxComplaintRate: rc.MaxComplaintRate,
+				MinReputation:    rc.MinReputation,
+				BlocklistZones:   rc.BlocklistZones,
+				Resolver:         blocklistResolver,
+			})
+		}
+		accountPoller.SetNotifier(alerts)
+		accountPoller.Start(ctx)
+	}
+
+	// Start the monthly limit warning notifier.
+	var quotaNotifier *worker.QuotaNotifier
+	if cfg.QuotaWarnings.Enabled {
+		quotaNotifier = worker.NewQuotaNotifier(queries, db, worker.QuotaNotifierConfig{
+			Interval:   cfg.QuotaWarnings.Interval,
+			Thresholds: cfg.QuotaWarnings.Thresholds,
+			From:       cfg.QuotaWarnings.From,
+		}, log)
+		quotaNotifier.SetNotifier(alerts)
+		quotaNotifier.Start(ctx)
+	}
+
+	// Warn groups about SMTP passwords nearing expiry.
+	var credentialExpiry *worker.CredentialExpiryNotifier
+	if cfg.CredentialExpiry.Enabled {
+		credentialExpiry = worker.NewCredentialExpiryNotifier(queries, alerts, worker.CredentialExpiryConfig{
+			Interval:   cfg.CredentialExpiry.Interval,
+			MaxAge:     cfg.CredentialExpiry.MaxAge,
+			WarnBefore: cfg.CredentialExpiry.WarnBefore,
+		}, log)
+		credentialExpiry.Start(ctx)
+	}
+
+	// Start the delivery latency SLO monitor.
+	var sloMonitor *worker.SLOMonitor
+	if cfg.SLO.Enabled {
+		notifiers := notify.Multi{alerts}
+		if cfg.SLO.WebhookURL != "" {
+			notifiers = append(notifiers, notify.NewWebhookNotifier(cfg.SLO.WebhookURL, 10*time.Second))
+		}
+		if cfg.SLO.Email.SMTPAddr != "" && len(cfg.SLO.Email.To) > 0 {
+			notifiers = append(notifiers, notify.NewEmailNotifier(notify.EmailConfig{
+				Addr:     cfg.SLO.Email.SMTPAddr,
+				Username: cfg.SLO.Email.Username,
+				Password: cfg.SLO.Email.Password,
+				From:     cfg.SLO.Email.From,
+				To:       cfg.SLO.Email.To,
+			}))
+		}
+		sloMonitor = worker.NewSLOMonitor(queries, notifiers, worker.SLOConfig{
+			Interval:      cfg.SLO.Interval,
+			Window:        cfg.SLO.Window,
+			P50:           cfg.SLO.P50Threshold,
+			P95:           cfg.SLO.P95Threshold,
+			P99:           cfg.SLO.P99Threshold,
+			MinSamples:    cfg.SLO.MinSamples,
+			AlertCooldown: cfg.SLO.AlertCooldown,
+		}, log)
+		sloMonitor.Start(ctx)
+	}
+
+	// Start the read-only POP3 server for messages captured by the file provider.
+	var pop3Server *pop3.Server
+	if cfg.Capture.POP3.Enabled {
+		pop3Server = pop3.NewServer(pop3.Config{
+			Dir:      cfg.Capture.POP3.Dir,
+			Username: cfg.Capture.POP3.Username,
+			Password: cfg.Capture.POP3.Password,
+		}, log)
+		addr := fmt.Sprintf("%s:%d", cfg.Capture.POP3.Host, cfg.Capture.POP3.Port)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal().Err(err).Str("addr", addr).Msg("failed to listen for POP3")
+		}
+		go func() {
+			log.Info().Str("addr", addr).Str("dir", cfg.Capture.POP3.Dir).Msg("capture POP3 server listening")
+			if err := pop3Server.Serve(ln); err != nil {
+				log.Error().Err(err).Msg("capture POP3 server error")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal for graceful shutdown.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -123,9 +474,65 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if sweeper != nil {
+		sweeper.Stop()
+	}
+
+	if archiver != nil {
+		archiver.Stop()
+	}
+
+	if reportGenerator != nil {
+		reportGenerator.Stop()
+	}
+
+	if exporter != nil {
+		exporter.Stop()
+	}
+
+	if sloMonitor != nil {
+		sloMonitor.Stop()
+	}
+
+	if prober != nil {
+		prober.Stop()
+	}
+
+	if accountPoller != nil {
+		accountPoller.Stop()
+	}
+
+	if quotaNotifier != nil {
+		quotaNotifier.Stop()
+	}
+
+	if credentialExpiry != nil {
+		credentialExpiry.Stop()
+	}
+
+	if pop3Server != nil {
+		_ = pop3Server.Close()
+	}
+
 	if err := dequeuer.Stop(shutdownCtx); err != nil {
 		log.Error().Err(err).Msg("dequeuer shutdown error")
 	}
 
+	profiler.Shutdown(shutdownCtx)
+	crash.Flush(5 * time.Second)
+
 	log.Info().Msg("queue worker stopped")
 }
+
+// alertChannels builds the deployment-wide alert channels from config.
+func alertChannels(cfg config.AlertsConfig) (notify.Multi, error) {
+	channels := make([]notify.ChannelConfig, len(cfg.Channels))
+	for i, ch := range cfg.Channels {
+		channels[i] = notify.ChannelConfig{Type: ch.Type, Target: ch.Target, Kinds: ch.Kinds}
+	}
+	n, err := notify.NewChannels(channels, cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("alerts: %w", err)
+	}
+	return n, nil
+}
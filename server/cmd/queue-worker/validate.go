@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preflight"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// validateConfig checks the configuration and the services the queue
+// worker depends on, including the health of every enabled ESP provider,
+// prints a report and returns the process exit code.
+func validateConfig(cfg *config.Config) int {
+	checks := []preflight.Check{
+		preflight.Postgres(cfg.Database.URL),
+		preflight.Schema(cfg.Database.URL, cfg.Database.SchemaCheck, cfg.Database.MigrateOnStart),
+		preflight.Redis(&redis.Options{
+			Addr:     cfg.Queue.RedisAddr,
+			Password: cfg.Queue.RedisPassword,
+			DB:       cfg.Queue.RedisDB,
+		}),
+		preflight.MessageStore(msgstore.Config{
+			Type:       cfg.Storage.Type,
+			Path:       cfg.Storage.Path,
+			S3Bucket:   cfg.Storage.S3Bucket,
+			S3Prefix:   cfg.Storage.S3Prefix,
+			S3Endpoint: cfg.Storage.S3Endpoint,
+			S3Region:   cfg.Storage.S3Region,
+		}),
+	}
+
+	// Provider checks go through the egress proxy, as deliveries do.
+	httpClient := provider.NewHTTPClient(30 * time.Second)
+	if cfg.Egress.ProxyURL != "" {
+		proxied, err := httpClient.WithProxy(cfg.Egress.ProxyURL)
+		if err != nil {
+			err = fmt.Errorf("egress.proxy_url: %w", err)
+		}
+		checks = append(checks, preflight.Static("egress", err))
+		if err == nil {
+			httpClient = proxied
+		}
+	}
+	checks = append(checks, preflight.Providers(cfg.Database.URL, httpClient))
+
+	_, err := alertChannels(cfg.Alerts)
+	checks = append(checks, preflight.Static("alerts", err))
+
+	report := preflight.Run(context.Background(), preflight.DefaultTimeout, checks)
+	report.Write(os.Stdout)
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
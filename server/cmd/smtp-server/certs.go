@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/sungwon/smtp-proxy/server/internal/certmon"
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+)
+
+// certTargets lists the certificates the expiry monitor watches: the SMTP
+// server's own (from tls.cert_file, or the self-signed one in tlsConfig),
+// api.tls.cert_file, cert_monitor.files and cert_monitor.relays.
+func certTargets(cfg *config.Config, tlsConfig *tls.Config) []certmon.Target {
+	var targets []certmon.Target
+	switch {
+	case tlsConfig == nil:
+	case cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "":
+		targets = append(targets, certmon.FileTarget("smtp", cfg.TLS.CertFile))
+	case len(tlsConfig.Certificates) > 0:
+		if leaf, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0]); err == nil {
+			targets = append(targets, certmon.CertificateTarget("smtp", "self-signed", leaf))
+		}
+	}
+	if cfg.API.TLS.Enabled && cfg.API.TLS.CertFile != "" {
+		targets = append(targets, certmon.FileTarget("api", cfg.API.TLS.CertFile))
+	}
+	for _, path := range cfg.CertMonitor.Files {
+		targets = append(targets, certmon.FileTarget("file", path))
+	}
+	for _, addr := range cfg.CertMonitor.Relays {
+		targets = append(targets, certmon.RelayTarget(addr, cfg.CertMonitor.Timeout))
+	}
+	return targets
+}
+
+// alertChannels builds the deployment-wide alert channels from config.
+// Certificate alerts concern no group, so group channels are not used.
+func alertChannels(cfg config.AlertsConfig) (notify.Multi, error) {
+	channels := make([]notify.ChannelConfig, len(cfg.Channels))
+	for i, ch := range cfg.Channels {
+		channels[i] = notify.ChannelConfig{Type: ch.Type, Target: ch.Target, Kinds: ch.Kinds}
+	}
+	n, err := notify.NewChannels(channels, cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("alerts: %w", err)
+	}
+	return n, nil
+}
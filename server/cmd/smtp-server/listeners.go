@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+)
+
+// Listener TLS policies.
+const (
+	tlsStartTLS = "starttls"
+	tlsImplicit = "implicit"
+	tlsNone     = "none"
+)
+
+// listenerConfigs returns the SMTP listeners to serve. Without
+// smtp.listeners, it is the submission listener on smtp.host:smtp.port with
+// the global tls.mode, plus the inbound listener when enabled.
+func listenerConfigs(cfg *config.Config) ([]config.ListenerConfig, error) {
+	defaultTLS := tlsStartTLS
+	if cfg.TLS.Mode == tlsNone {
+		defaultTLS = tlsNone
+	}
+
+	listeners := cfg.SMTP.Listeners
+	if len(listeners) == 0 {
+		listeners = []config.ListenerConfig{{
+			Name:    "submission",
+			Address: fmt.Sprintf("%s:%d", cfg.SMTP.Host, cfg.SMTP.Port),
+		}}
+		if cfg.SMTP.Inbound.Enabled {
+			listeners = append(listeners, config.ListenerConfig{
+				Name:    "inbound",
+				Address: fmt.Sprintf("%s:%d", cfg.SMTP.Inbound.Host, cfg.SMTP.Inbound.Port),
+				Inbound: true,
+			})
+		}
+	}
+
+	seen := make(map[string]bool, len(listeners))
+	out := make([]config.ListenerConfig, 0, len(listeners))
+	for i, lc := range listeners {
+		if lc.Address == "" {
+			return nil, fmt.Errorf("smtp.listeners[%d]: address is required", i)
+		}
+		if lc.Name == "" {
+			lc.Name = lc.Address
+		}
+		if seen[lc.Name] {
+			return nil, fmt.Errorf("smtp.listeners[%d]: duplicate name %q", i, lc.Name)
+		}
+		seen[lc.Name] = true
+
+		if lc.TLS == "" {
+			lc.TLS = defaultTLS
+		}
+		switch lc.TLS {
+		case tlsStartTLS, tlsImplicit:
+		case tlsNone:
+			if lc.RequireTLS {
+				return nil, fmt.Errorf("smtp.listeners[%d] (%s): require_tls needs tls starttls", i, lc.Name)
+			}
+		default:
+			return nil, fmt.Errorf("smtp.listeners[%d] (%s): unknown tls policy %q", i, lc.Name, lc.TLS)
+		}
+		out = append(out, lc)
+	}
+	return out, nil
+}
+
+// loadTLSConfig loads the configured certificate, falling back to a
+// self-signed one.
+func loadTLSConfig(cfg *config.Config, log zerolog.Logger) *tls.Config {
+	var cert tls.Certificate
+	var err error
+	if cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
+		cert, err = tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to load TLS certificate")
+		}
+		log.Info().Msg("TLS: loaded certificate from files")
+	} else {
+		cert, err = tlsutil.GenerateSelfSigned()
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to generate self-signed TLS certificate")
+		}
+		log.Info().Msg("TLS: using auto-generated self-signed certificate")
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// Client certificates are optional: clients without one authenticate
+	// with AUTH PLAIN. They are not verified during the handshake, so
+	// certificates registered by fingerprint need not chain to a client
+	// CA; the SMTP backend verifies chains itself before matching by SAN.
+	if cfg.TLS.ClientAuth {
+		tlsConfig.ClientAuth = tls.RequestClientCert
+		log.Info().Msg("TLS: client certificate authentication enabled")
+	}
+	return tlsConfig
+}
+
+// loadClientCAs loads the CAs that client certificates are verified
+// against, or returns nil when none are configured.
+func loadClientCAs(cfg *config.Config, log zerolog.Logger) *x509.CertPool {
+	if !cfg.TLS.ClientAuth || cfg.TLS.ClientCAFile == "" {
+		return nil
+	}
+	pem, err := os.ReadFile(cfg.TLS.ClientCAFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to read client CA file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Fatal().Str("file", cfg.TLS.ClientCAFile).Msg("no certificates in client CA file")
+	}
+	return pool
+}
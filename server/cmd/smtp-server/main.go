@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,17 +15,48 @@ import (
 	gosmtp "github.com/emersion/go-smtp"
 	"github.com/redis/go-redis/v9"
 
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/certmon"
 	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
+	"github.com/sungwon/smtp-proxy/server/internal/dedup"
 	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/dnsbl"
+	"github.com/sungwon/smtp-proxy/server/internal/dnscache"
+	"github.com/sungwon/smtp-proxy/server/internal/fips"
+	"github.com/sungwon/smtp-proxy/server/internal/greylist"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
 	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/plugins"
+	"github.com/sungwon/smtp-proxy/server/internal/profiling"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/scripting"
 	smtpserver "github.com/sungwon/smtp-proxy/server/internal/smtp"
+	"github.com/sungwon/smtp-proxy/server/internal/socketactivation"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
-	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+	"github.com/sungwon/smtp-proxy/server/internal/version"
+	"github.com/sungwon/smtp-proxy/server/internal/worker"
 )
 
 func main() {
+	validate := flag.Bool("validate-config", false, "check the configuration and dependencies, print a report and exit")
+	migrateOnly := flag.Bool("migrate", false, "apply database migrations and exit")
+	pprofAddr := flag.String("pprof-addr", "", "serve /debug/pprof/ on this address, overriding profiling.pprof_addr")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println("smtp-server " + version.Get().String())
+		return
+	}
+	if err := fips.Check(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
 	// Load configuration from the "config" directory.
 	cfg, err := config.Load("config")
 	if err != nil {
@@ -31,34 +64,119 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *validate {
+		os.Exit(validateConfig(cfg))
+	}
+
 	// Initialize structured JSON logger.
-	log := logger.New(cfg.Logging.Level)
-	log.Info().Msg("starting SMTP server")
+	logCfg := logger.LoggingConfig{
+		Level:         cfg.Logging.Level,
+		Output:        cfg.Logging.Output,
+		Outputs:       cfg.Logging.Outputs,
+		FilePath:      cfg.Logging.FilePath,
+		MaxSizeMB:     cfg.Logging.MaxSizeMB,
+		MaxFiles:      cfg.Logging.MaxFiles,
+		CWGroup:       cfg.Logging.CWGroup,
+		CWStream:      cfg.Logging.CWStream,
+		CWRegion:      cfg.Logging.CWRegion,
+		SyslogNetwork: cfg.Logging.Syslog.Network,
+		SyslogAddress: cfg.Logging.Syslog.Address,
+		SyslogTag:     cfg.Logging.Syslog.Tag,
+		Modules:       cfg.Logging.Modules,
+		SampleBurst:   cfg.Logging.Sampling.Burst,
+		SamplePeriod:  cfg.Logging.Sampling.Period,
+		SampleEvery:   cfg.Logging.Sampling.Every,
+	}
+	log := logger.NewFromConfig(logCfg)
+	redact.Configure(redact.Config{
+		Recipients: cfg.Logging.RedactRecipients,
+		Subjects:   cfg.Logging.RedactSubjects,
+	})
+	if err := crash.Configure(crash.Config{
+		SentryDSN:   cfg.CrashReporting.SentryDSN,
+		Environment: cfg.CrashReporting.Environment,
+		Release:     cfg.CrashReporting.Release,
+		Service:     "smtp-server",
+		Timeout:     cfg.CrashReporting.Timeout,
+	}); err != nil {
+		log.Fatal().Err(err).Msg("invalid crash reporting configuration")
+	}
+	log.Info().Stringer("version", version.Get()).Msg("starting SMTP server")
 
 	// Initialize database connection pool.
 	ctx := context.Background()
-	db, err := storage.NewDB(ctx, cfg.Database.URL, cfg.Database.PoolMin, cfg.Database.PoolMax, cfg.Database.ConnectTimeout)
+	if *migrateOnly {
+		if err := migrate.Run(ctx, cfg.Database.URL, log); err != nil {
+			log.Fatal().Err(err).Msg("database migration failed")
+		}
+		return
+	}
+
+	profiler, err := profiling.Start(ctx, profiling.ConfigFrom(cfg.Profiling, *pprofAddr), "smtp-server", log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start profiling")
+	}
+
+	db, err := storage.NewDB(ctx, cfg.Database.URL, storage.PoolConfig{
+		MinConns:               cfg.Database.PoolMin,
+		MaxConns:               cfg.Database.PoolMax,
+		ConnectTimeout:         cfg.Database.ConnectTimeout,
+		MaxConnLifetime:        cfg.Database.MaxConnLifetime,
+		MaxConnIdleTime:        cfg.Database.MaxConnIdleTime,
+		HealthCheckPeriod:      cfg.Database.HealthCheckPeriod,
+		QueryExecMode:          cfg.Database.QueryExecMode,
+		StatementCacheCapacity: cfg.Database.StatementCacheCapacity,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to connect to database")
 	}
 	defer db.Close()
 
+	// Replicas starting together take turns on an advisory lock; all but
+	// the first find the schema already current.
+	if cfg.Database.MigrateOnStart {
+		if err := migrate.UpPool(ctx, db.Pool, log); err != nil {
+			log.Fatal().Err(err).Msg("database migration failed")
+		}
+	}
+
+	// Refuse to run against a schema this release was not built for, so a
+	// partial rollout cannot write rows an old or new schema does not
+	// expect.
+	schemaReadOnly, err := migrate.Verify(ctx, db.Pool, cfg.Database.SchemaCheck, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("database schema check failed")
+	}
+
+	// Sample pool statistics for metrics and SMTP load shedding.
+	poolMonitor := storage.NewPoolMonitor(db.Stats, storage.PoolMonitorConfig{
+		Interval:       cfg.Database.StatsInterval,
+		MaxAcquireWait: cfg.SMTP.LoadShedding.MaxAcquireWait,
+	}, log)
+	poolMonitor.Start(ctx)
+	defer poolMonitor.Stop()
+
 	queries := storage.New(db.Pool)
 
-	// Create async delivery service (Redis is required).
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.Queue.RedisAddr,
-		Password: cfg.Queue.RedisPassword,
-		DB:       cfg.Queue.RedisDB,
-	})
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		log.Fatal().Err(err).Msg("failed to connect to Redis")
+	if err := checkDeliveryMode(cfg.Delivery.Mode); err != nil {
+		log.Fatal().Err(err).Msg("invalid delivery configuration")
 	}
-	defer redisClient.Close()
+	queueLog := logger.Module(log, logCfg, "queue")
 
-	enqueuer := queue.NewRedisEnqueuer(redisClient)
-	deliverySvc := delivery.NewAsyncService(enqueuer, log)
-	log.Info().Msg("delivery mode: async (Redis Streams)")
+	// Redis carries the delivery queue in async mode and the greylisting
+	// and dedup state; sync mode without either runs without it.
+	var redisClient *redis.Client
+	if needsRedis(cfg) {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.Queue.RedisAddr,
+			Password: cfg.Queue.RedisPassword,
+			DB:       cfg.Queue.RedisDB,
+		})
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			log.Fatal().Err(err).Msg("failed to connect to Redis")
+		}
+		defer redisClient.Close()
+	}
 
 	// Initialize message body storage.
 	store, err := msgstore.New(msgstore.Config{
@@ -74,70 +192,434 @@ func main() {
 	}
 	log.Info().Str("type", cfg.Storage.Type).Msg("message store initialized")
 
-	// Create SMTP backend with delivery service.
-	backend := smtpserver.NewBackend(queries, deliverySvc, store, log, cfg.SMTP.MaxConnections)
-
-	// Configure SMTP server.
-	s := gosmtp.NewServer(backend)
-	s.Addr = fmt.Sprintf("%s:%d", cfg.SMTP.Host, cfg.SMTP.Port)
-	s.Domain = "smtp-proxy"
-	s.ReadTimeout = cfg.SMTP.ReadTimeout
-	s.WriteTimeout = cfg.SMTP.WriteTimeout
-	s.MaxMessageBytes = cfg.SMTP.MaxMessageSize
-	// Configure TLS based on mode.
-	if cfg.TLS.Mode == "none" {
-		s.AllowInsecureAuth = true
-		log.Warn().Msg("TLS disabled (mode=none); ensure TLS is terminated upstream (NLB/proxy)")
+	// Create SMTP backend; messages are persisted with an outbox entry in one transaction.
+	backend := smtpserver.NewBackend(queries, db, store, logger.Module(log, logCfg, "smtp"), cfg.SMTP.MaxConnections)
+	if cfg.SMTP.LoadShedding.Enabled {
+		backend.SetLoadShedder(poolMonitor)
+	}
+
+	// Backpressure defers and then rejects mail while the queue workers fall
+	// behind or persisting messages slows down. Sync mode has no queue.
+	var backpressure *smtpserver.Backpressure
+	if cfg.SMTP.Backpressure.Enabled {
+		bpCfg := smtpserver.BackpressureConfig{
+			Interval:           cfg.SMTP.Backpressure.Interval,
+			DeferBacklog:       cfg.SMTP.Backpressure.DeferBacklog,
+			RejectBacklog:      cfg.SMTP.Backpressure.RejectBacklog,
+			DeferWriteLatency:  cfg.SMTP.Backpressure.DeferWriteLatency,
+			RejectWriteLatency: cfg.SMTP.Backpressure.RejectWriteLatency,
+			RetryAfter:         cfg.SMTP.Backpressure.RetryAfter,
+		}
+		if cfg.Delivery.Mode == "sync" {
+			backpressure = smtpserver.NewBackpressure(nil, bpCfg, log)
+		} else {
+			backlog := queue.NewRedisBacklog(redisClient, cfg.Queue.StreamName, cfg.Queue.GroupName)
+			backpressure = smtpserver.NewBackpressure(backlog, bpCfg, log)
+		}
+		backpressure.Start(ctx)
+		defer backpressure.Stop()
+		backend.SetBackpressure(backpressure)
+	}
+
+	// In-flight message bodies beyond the memory budget are spilled to disk.
+	var memoryBudget *smtpserver.MemoryBudget
+	if cfg.SMTP.MemoryBudget.MaxBytes > 0 {
+		memoryBudget = smtpserver.NewMemoryBudget(cfg.SMTP.MemoryBudget.MaxBytes, cfg.SMTP.MemoryBudget.SpillDir)
+		backend.SetMemoryBudget(memoryBudget)
+	}
+
+	// Drain mode rejects new connections with 421 while existing sessions
+	// finish, for restarts that do not bounce mail.
+	drainer := smtpserver.NewDrainer(cfg.SMTP.Drain.RetryAfter, log)
+	backend.SetDrainer(drainer)
+	if schemaReadOnly {
+		drainer.Hold()
+	}
+	activeSessions := backend.ActiveSessions
+
+	// Identical submissions within the dedup window are dropped or tagged.
+	if cfg.SMTP.Dedup.Enabled {
+		d, err := dedup.New(redisClient, dedup.Config{
+			Enabled: true,
+			Window:  cfg.SMTP.Dedup.Window,
+			Action:  cfg.SMTP.Dedup.Action,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid dedup configuration")
+		}
+		backend.SetDeduper(d)
+		log.Info().Dur("window", cfg.SMTP.Dedup.Window).Str("action", d.Action()).Msg("duplicate detection enabled")
+	}
+
+	// Mail that looped back through a downstream relay is rejected.
+	var loopHostnames []string
+	if cfg.SMTP.LoopDetection.Enabled {
+		loopHostnames = append(loopHostnames, cfg.SMTP.LoopDetection.Hostnames...)
+		if h, err := os.Hostname(); err == nil {
+			loopHostnames = append(loopHostnames, h)
+		}
+		backend.SetLoopDetection(cfg.SMTP.LoopDetection.MaxReceived, loopHostnames)
+	}
+
+	// Client certificates matched by SAN must chain to a client CA.
+	if pool := loadClientCAs(cfg, log); pool != nil {
+		backend.SetClientCAs(pool)
+	}
+
+	// Scoped tokens with the send scope are accepted as AUTH passwords,
+	// unless anyone could sign one.
+	if auth.WeakSigningKey(cfg.Auth.SigningKey) {
+		log.Warn().Msg("JWT signing key is not set or using default value; scoped tokens are not accepted for SMTP AUTH")
 	} else {
-		s.AllowInsecureAuth = false
-		var cert tls.Certificate
-		if cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
-			cert, err = tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		backend.SetTokenValidator(auth.NewJWTService(auth.JWTConfig{
+			SigningKey: cfg.Auth.SigningKey,
+			Issuer:     cfg.Auth.Issuer,
+			Audience:   cfg.Auth.Audience,
+		}))
+	}
+
+	if cfg.CredentialExpiry.Enabled {
+		backend.SetPasswordMaxAge(cfg.CredentialExpiry.MaxAge)
+	}
+	backend.SetSessionActivity(cfg.SMTP.SessionActivity.Enabled)
+
+	// Resolve recipient MX records through the caching resolver.
+	var dnsResolver *dnscache.Resolver
+	if cfg.DNS.Enabled {
+		dnsResolver = dnscache.New(dnscache.Config{
+			Servers:     cfg.DNS.Servers,
+			Timeout:     cfg.DNS.Timeout,
+			MinTTL:      cfg.DNS.MinTTL,
+			MaxTTL:      cfg.DNS.MaxTTL,
+			NegativeTTL: cfg.DNS.NegativeTTL,
+			MaxEntries:  cfg.DNS.MaxEntries,
+		})
+		backend.SetResolver(dnsResolver)
+		log.Info().Strs("servers", dnsResolver.Servers()).Msg("caching DNS resolver enabled")
+	}
+
+	// Sync delivery mode and pass-through mode deliver with the queue
+	// worker's handler: the same providers, routing, message store and
+	// delivery logs.
+	var handler *worker.Handler
+	if cfg.Delivery.Mode == "sync" || cfg.SMTP.PassThrough.Enabled {
+		if err := plugins.Load(cfg.Plugins.Paths, log); err != nil {
+			log.Fatal().Err(err).Msg("failed to load plugins")
+		}
+		httpClient := provider.NewHTTPClient(30 * time.Second)
+		if dnsResolver != nil {
+			httpClient = provider.NewHTTPClientWithDialer(30*time.Second, dnsResolver.DialContext)
+		}
+		if cfg.Egress.ProxyURL != "" {
+			if httpClient, err = httpClient.WithProxy(cfg.Egress.ProxyURL); err != nil {
+				log.Fatal().Err(err).Msg("invalid egress proxy configuration")
+			}
+		}
+		handler = worker.NewHandler(provider.NewResolver(queries, httpClient, log), queries, store, queueLog)
+		if cfg.Scripting.Enabled {
+			handler.SetScripts(scripting.NewEngine(queries, scripting.Config{
+				Timeout:         cfg.Scripting.Timeout,
+				CallStackSize:   cfg.Scripting.CallStackSize,
+				RegistryMaxSize: cfg.Scripting.RegistryMaxSize,
+				MaxStringSize:   cfg.Scripting.MaxStringSize,
+				MaxMemory:       cfg.Scripting.MaxMemory,
+			}, logger.Module(log, logCfg, "scripting")))
+		}
+	}
+
+	// The outbox relay hands persisted messages to the delivery service:
+	// the Redis stream in async mode, the providers themselves in sync mode.
+	relayCfg := delivery.OutboxRelayConfig{
+		PollInterval: cfg.Queue.OutboxPollInterval,
+		BatchSize:    cfg.Queue.OutboxBatchSize,
+	}
+	var deliverySvc delivery.Service
+	if cfg.Delivery.Mode == "sync" {
+		deliverySvc = delivery.NewSyncService(handler, queries, cfg.Delivery.Timeout, queueLog)
+		// Claim one batch per round of workers so every claimed entry is
+		// delivered within its lease.
+		workers := max(cfg.Delivery.Workers, 1)
+		relayCfg.BatchSize = workers
+		relayCfg.Concurrency = workers
+		relayCfg.ClaimLease = cfg.Delivery.Timeout + delivery.DefaultOutboxRelayConfig().ClaimLease
+		log.Info().Int("workers", workers).Dur("timeout", cfg.Delivery.Timeout).Msg("delivery mode: sync")
+	} else {
+		deliverySvc = delivery.NewAsyncService(queue.NewRedisEnqueuer(redisClient), queueLog)
+		log.Info().Msg("delivery mode: async (Redis Streams)")
+	}
+	relay := delivery.NewOutboxRelay(queries, deliverySvc, relayCfg, queueLog)
+	if !schemaReadOnly {
+		relay.Start(ctx)
+	}
+
+	// Without a queue worker, the SMTP server sweeps its own stuck messages.
+	var sweeper *worker.Sweeper
+	if cfg.Delivery.Mode == "sync" && cfg.Sweeper.Enabled && !schemaReadOnly {
+		sweeper = worker.NewSweeper(queries, deliverySvc, worker.SweeperConfig{
+			Interval:          cfg.Sweeper.Interval,
+			QueuedTimeout:     cfg.Sweeper.QueuedTimeout,
+			ProcessingTimeout: cfg.Sweeper.ProcessingTimeout,
+			MaxRequeues:       cfg.Sweeper.MaxRequeues,
+			BatchSize:         cfg.Sweeper.BatchSize,
+		}, log)
+		sweeper.Start(ctx)
+	}
+
+	// Pass-through mode delivers submissions before DATA is answered.
+	// Inbound mail is still queued.
+	if cfg.SMTP.PassThrough.Enabled {
+		backend.SetPassThrough(delivery.NewSyncService(handler, queries, cfg.SMTP.PassThrough.Timeout, queueLog))
+		log.Info().Dur("timeout", cfg.SMTP.PassThrough.Timeout).Msg("delivery mode: pass-through for submissions")
+	}
+
+	listeners, err := listenerConfigs(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid SMTP listener configuration")
+	}
+
+	// Load the TLS certificate when any listener uses TLS.
+	var tlsConfig *tls.Config
+	for _, lc := range listeners {
+		if lc.TLS != tlsNone {
+			tlsConfig = loadTLSConfig(cfg, log)
+			break
+		}
+	}
+	if tlsConfig == nil {
+		log.Warn().Msg("TLS disabled (mode=none); ensure TLS is terminated upstream (NLB/proxy)")
+	}
+
+	// The inbound backend accepts unauthenticated mail for inbound route
+	// domains and queues it for delivery to HTTP endpoints.
+	var inboundBackend *smtpserver.Backend
+	for _, lc := range listeners {
+		if !lc.Inbound {
+			continue
+		}
+		var gl *greylist.Greylister
+		if cfg.SMTP.Greylist.Enabled {
+			gl, err = greylist.New(redisClient, greylist.Config{
+				Enabled:        true,
+				Delay:          cfg.SMTP.Greylist.Delay,
+				RetryWindow:    cfg.SMTP.Greylist.RetryWindow,
+				KnownSenderTTL: cfg.SMTP.Greylist.KnownSenderTTL,
+				Allowlist:      cfg.SMTP.Greylist.Allowlist,
+			})
 			if err != nil {
-				log.Fatal().Err(err).Msg("failed to load TLS certificate")
+				log.Fatal().Err(err).Msg("invalid greylist configuration")
+			}
+			log.Info().Dur("delay", cfg.SMTP.Greylist.Delay).Msg("greylisting enabled on inbound listener")
+		}
+
+		inboundBackend = smtpserver.NewInboundBackend(queries, db, store, logger.Module(log, logCfg, "smtp"), cfg.SMTP.MaxConnections, gl)
+		if cfg.SMTP.LoadShedding.Enabled {
+			inboundBackend.SetLoadShedder(poolMonitor)
+		}
+		if backpressure != nil {
+			inboundBackend.SetBackpressure(backpressure)
+		}
+		if memoryBudget != nil {
+			inboundBackend.SetMemoryBudget(memoryBudget)
+		}
+		inboundBackend.SetDrainer(drainer)
+		if cfg.SMTP.LoopDetection.Enabled {
+			inboundBackend.SetLoopDetection(cfg.SMTP.LoopDetection.MaxReceived, loopHostnames)
+		}
+		if cfg.SMTP.DNSBL.Enabled {
+			var blocklistResolver dnsbl.Resolver = net.DefaultResolver
+			if dnsResolver != nil {
+				blocklistResolver = dnsResolver
 			}
-			log.Info().Msg("TLS: loaded certificate from files")
+			lists := make([]dnsbl.List, 0, len(cfg.SMTP.DNSBL.Lists))
+			for _, l := range cfg.SMTP.DNSBL.Lists {
+				lists = append(lists, dnsbl.List{Zone: l.Zone, Weight: l.Weight})
+			}
+			inboundBackend.SetDNSBL(dnsbl.NewChecker(blocklistResolver, dnsbl.Config{
+				Enabled:       true,
+				Lists:         lists,
+				RejectScore:   cfg.SMTP.DNSBL.RejectScore,
+				GreylistScore: cfg.SMTP.DNSBL.GreylistScore,
+				CacheTTL:      cfg.SMTP.DNSBL.CacheTTL,
+				Timeout:       cfg.SMTP.DNSBL.Timeout,
+			}), gl != nil && cfg.SMTP.Greylist.DNSBLOnly)
+			log.Info().Int("lists", len(lists)).Msg("dns blocklist checks enabled on inbound listener")
+		}
+		activeSessions = func() int64 {
+			return backend.ActiveSessions() + inboundBackend.ActiveSessions()
+		}
+		break
+	}
+
+	// Sockets passed by systemd socket activation are used in place of
+	// binding the configured address.
+	inherited, err := socketactivation.Listeners()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to inherit systemd sockets")
+	}
+
+	// Serve each listener with its own go-smtp server and TLS policy; all
+	// share the backend's connection limit.
+	var servers []*gosmtp.Server
+	for _, lc := range listeners {
+		be := backend
+		if lc.Inbound {
+			be = inboundBackend
+		}
+
+		srv := gosmtp.NewServer(be.Listener(lc.Name, lc.RequireTLS))
+		srv.Addr = lc.Address
+		srv.Domain = "smtp-proxy"
+		srv.ReadTimeout = cfg.SMTP.ReadTimeout
+		srv.WriteTimeout = cfg.SMTP.WriteTimeout
+		srv.MaxMessageBytes = cfg.SMTP.MaxMessageSize
+		srv.EnableSMTPUTF8 = true
+		if lc.TLS == tlsNone {
+			srv.AllowInsecureAuth = true
 		} else {
-			cert, err = tlsutil.GenerateSelfSigned()
+			srv.TLSConfig = tlsConfig
+		}
+
+		ln, ok := socketactivation.Take(&inherited, lc.Name, lc.Address)
+		if ok {
+			log.Info().Str("listener", lc.Name).Str("addr", ln.Addr().String()).Msg("using socket from systemd")
+		} else {
+			ln, err = net.Listen("tcp", lc.Address)
 			if err != nil {
-				log.Fatal().Err(err).Msg("failed to generate self-signed TLS certificate")
+				log.Fatal().Err(err).Str("addr", lc.Address).Msg("failed to listen")
 			}
-			log.Info().Msg("TLS: using auto-generated self-signed certificate")
 		}
-		s.TLSConfig = &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
+		if lc.TLS == tlsImplicit {
+			ln = tls.NewListener(ln, tlsConfig)
 		}
+
+		go func() {
+			log.Info().
+				Str("listener", lc.Name).
+				Str("addr", ln.Addr().String()).
+				Str("tls", lc.TLS).
+				Bool("inbound", lc.Inbound).
+				Msg("SMTP server listening")
+			if err := srv.Serve(ln); err != nil {
+				log.Error().Err(err).Str("listener", lc.Name).Msg("SMTP server error")
+			}
+		}()
+		servers = append(servers, srv)
+	}
+	for _, l := range inherited {
+		log.Warn().Str("name", l.Name).Str("addr", l.Addr().String()).Msg("ignoring systemd socket not matching any listener")
+		l.Close()
 	}
-	s.EnableSMTPUTF8 = true
 
-	// Start listening on the configured address.
-	ln, err := net.Listen("tcp", s.Addr)
-	if err != nil {
-		log.Fatal().Err(err).Str("addr", s.Addr).Msg("failed to listen")
+	// Watch the expiry of the certificates this server presents and
+	// depends on.
+	var certMonitor *certmon.Monitor
+	if cfg.CertMonitor.Enabled {
+		alerts, err := alertChannels(cfg.Alerts)
+		if err != nil {
+			log.Fatal().Err(err).Msg("invalid alert channel configuration")
+		}
+		certMonitor = certmon.NewMonitor(certTargets(cfg, tlsConfig), alerts, certmon.Config{
+			Interval:     cfg.CertMonitor.Interval,
+			WarnDays:     cfg.CertMonitor.WarnDays,
+			CriticalDays: cfg.CertMonitor.CriticalDays,
+		}, logger.Module(log, logCfg, "certmon"))
+		certMonitor.Start(ctx)
 	}
 
-	// Serve connections in a goroutine.
-	go func() {
-		log.Info().Str("addr", s.Addr).Msg("SMTP server listening")
-		if err := s.Serve(ln); err != nil {
-			log.Error().Err(err).Msg("SMTP server error")
+	// Start the admin listener serving health checks and drain control.
+	var adminServer *http.Server
+	if cfg.SMTP.Admin.Enabled {
+		if cfg.SMTP.Admin.Token == "" {
+			log.Warn().Msg("smtp.admin.token is empty; /admin endpoints are disabled")
 		}
-	}()
+		adminCfg := smtpserver.AdminConfig{
+			Token:    cfg.SMTP.Admin.Token,
+			Sessions: activeSessions,
+			Ping:     db.Pool.Ping,
+		}
+		if certMonitor != nil {
+			adminCfg.Certificates = certMonitor.Status
+		}
+		adminServer = &http.Server{
+			Addr:              fmt.Sprintf("%s:%d", cfg.SMTP.Admin.Host, cfg.SMTP.Admin.Port),
+			Handler:           smtpserver.NewAdminHandler(drainer, adminCfg),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Info().Str("addr", adminServer.Addr).Msg("SMTP admin server listening")
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error().Err(err).Msg("SMTP admin server error")
+			}
+		}()
+	}
 
-	// Wait for interrupt signal for graceful shutdown.
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// SIGUSR1 enters drain mode and SIGUSR2 leaves it; SIGINT and SIGTERM
+	// drain and shut down.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+	for sig := range sigs {
+		if sig == syscall.SIGUSR1 {
+			drainer.Drain()
+			continue
+		}
+		if sig == syscall.SIGUSR2 {
+			drainer.Resume()
+			continue
+		}
+		break
+	}
 
 	log.Info().Msg("shutting down SMTP server")
 
+	// Stop accepting sessions and give active ones time to finish before
+	// closing connections.
+	drainer.Drain()
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.SMTP.Drain.Timeout)
+	if n := smtpserver.WaitIdle(drainCtx, activeSessions); n > 0 {
+		log.Warn().Int64("active_sessions", n).Msg("drain timeout reached, closing remaining sessions")
+	}
+	drainCancel()
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := s.Shutdown(shutdownCtx); err != nil {
-		log.Error().Err(err).Msg("SMTP server shutdown error")
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Str("addr", srv.Addr).Msg("SMTP server shutdown error")
+		}
+	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("SMTP admin server shutdown error")
+		}
 	}
 
+	relay.Stop()
+	if certMonitor != nil {
+		certMonitor.Stop()
+	}
+	if sweeper != nil {
+		sweeper.Stop()
+	}
+
+	profiler.Shutdown(shutdownCtx)
+	crash.Flush(5 * time.Second)
+
 	log.Info().Msg("SMTP server stopped")
 }
+
+// checkDeliveryMode reports an unknown delivery.mode.
+func checkDeliveryMode(mode string) error {
+	switch mode {
+	case "async", "sync":
+		return nil
+	}
+	return fmt.Errorf("unknown delivery mode %q (use async or sync)", mode)
+}
+
+// needsRedis reports whether the SMTP server uses Redis: for the delivery
+// queue in async mode, and for greylisting and duplicate detection.
+func needsRedis(cfg *config.Config) bool {
+	return cfg.Delivery.Mode != "sync" || cfg.SMTP.Greylist.Enabled || cfg.SMTP.Dedup.Enabled
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preflight"
+)
+
+// validateConfig checks the configuration and the services the SMTP server
+// depends on, prints a report and returns the process exit code.
+func validateConfig(cfg *config.Config) int {
+	listeners, err := listenerConfigs(cfg)
+	checks := []preflight.Check{
+		preflight.Static("listeners", err),
+		preflight.Static("delivery mode", checkDeliveryMode(cfg.Delivery.Mode)),
+		preflight.Postgres(cfg.Database.URL),
+		preflight.Schema(cfg.Database.URL, cfg.Database.SchemaCheck, cfg.Database.MigrateOnStart),
+		preflight.MessageStore(msgstore.Config{
+			Type:       cfg.Storage.Type,
+			Path:       cfg.Storage.Path,
+			S3Bucket:   cfg.Storage.S3Bucket,
+			S3Prefix:   cfg.Storage.S3Prefix,
+			S3Endpoint: cfg.Storage.S3Endpoint,
+			S3Region:   cfg.Storage.S3Region,
+		}),
+	}
+
+	if needsRedis(cfg) {
+		checks = append(checks, preflight.Redis(&redis.Options{
+			Addr:     cfg.Queue.RedisAddr,
+			Password: cfg.Queue.RedisPassword,
+			DB:       cfg.Queue.RedisDB,
+		}))
+	}
+
+	// Without certificate files TLS listeners use a self-signed
+	// certificate, so there is nothing to check.
+	for _, lc := range listeners {
+		if lc.TLS != tlsNone && cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "" {
+			checks = append(checks, preflight.KeyPair("tls", cfg.TLS.CertFile, cfg.TLS.KeyFile))
+			break
+		}
+	}
+	if cfg.TLS.ClientAuth && cfg.TLS.ClientCAFile != "" {
+		checks = append(checks, preflight.CAFile("tls client CA", cfg.TLS.ClientCAFile))
+	}
+	if cfg.CertMonitor.Enabled {
+		_, err := alertChannels(cfg.Alerts)
+		checks = append(checks, preflight.Static("alerts", err))
+	}
+
+	report := preflight.Run(context.Background(), preflight.DefaultTimeout, checks)
+	report.Write(os.Stdout)
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
@@ -1,11 +1,24 @@
 // Package main provides a standalone CLI tool for sending test emails
 // through the smtp-proxy SMTP server. It supports STARTTLS, implicit TLS,
-// plaintext connections, SMTP AUTH PLAIN, and batch sending with rate limiting.
+// plaintext connections, SMTP AUTH (PLAIN, LOGIN or CRAM-MD5), and batch
+// sending with rate limiting.
+// Messages can carry an HTML body with inline images, attachments and
+// custom headers. With several workers or a duration it doubles as a small
+// load generator, reporting latency percentiles and optionally writing
+// per-message results as CSV or JSON. With --api it follows every sent
+// message through the messages API until it is delivered or failed and
+// prints its delivery timeline, making it an end-to-end smoke test. With
+// --probe it instead runs protocol failure scenarios (bad credentials,
+// malformed commands, oversized messages) and checks the server's reply
+// codes, for protocol regression testing.
 //
 // Usage:
 //
 //	test-client --from sender@example.com --to recipient@example.com --subject "Test" --body "Hello"
 //	test-client --tls starttls --insecure --count 10 --rate 5
+//	test-client --concurrency 8 --duration 1m --rate 0 --output results.csv
+//	test-client --api http://localhost:8080 --api-token $TOKEN
+//	test-client --user admin --password secret --probe all
 package main
 
 import (
@@ -14,17 +27,24 @@ import (
 	"encoding/base64"
 	"flag"
 	"fmt"
+	"mime"
 	"mime/multipart"
-	"net"
 	"net/http"
-	"net/smtp"
 	"net/textproto"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
 )
 
+// queuedPrefix precedes the message ID in the server's final DATA reply.
+const queuedPrefix = "queued as "
+
 type config struct {
 	host     string
 	port     int
@@ -32,14 +52,36 @@ type config struct {
 	insecure bool
 	user     string
 	password string
+	auth     string
 	from     string
 	to       stringSlice
 	subject  string
 	body     string
 	html     string
+	inline   stringSlice
 	attach   stringSlice
+	headers  stringSlice
 	count    int
 	rate     float64
+
+	concurrency int
+	duration    time.Duration
+	output      string
+	format      string
+
+	probes stringSlice
+	expect string
+
+	apiURL       string
+	apiToken     string
+	wait         time.Duration
+	pollInterval time.Duration
+
+	// extraHeaders are the parsed --header values.
+	extraHeaders [][2]string
+	// tag is added as X-SMTPProxy-Tag with --api, so messages can be found
+	// through the messages API when the DATA reply has no message ID.
+	tag string
 }
 
 // stringSlice implements flag.Value for repeatable --to flags.
@@ -68,6 +110,55 @@ func main() {
 		os.Exit(2)
 	}
 
+	if len(cfg.inline) > 0 && cfg.html == "" {
+		fmt.Fprintln(os.Stderr, "error: --inline requires --html")
+		os.Exit(2)
+	}
+	for _, raw := range cfg.headers {
+		h, err := parseHeader(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(2)
+		}
+		cfg.extraHeaders = append(cfg.extraHeaders, h)
+	}
+	if _, err := saslClient(cfg.auth, cfg.user, cfg.password); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	selected, err := selectProbes(cfg.probes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.expect != "" {
+		if len(selected) != 1 {
+			fmt.Fprintln(os.Stderr, "error: --expect requires exactly one --probe")
+			os.Exit(2)
+		}
+		if err := validExpect(cfg.expect); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if cfg.apiURL != "" {
+		if cfg.apiToken == "" {
+			fmt.Fprintln(os.Stderr, "error: --api requires --api-token")
+			os.Exit(2)
+		}
+		cfg.tag = fmt.Sprintf("test-client-%x", time.Now().UnixNano())
+	}
+	if err := formatFlag(cfg.format); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	html, err := loadHTML(cfg.html)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	cfg.html = html
+
 	addr := fmt.Sprintf("%s:%d", cfg.host, cfg.port)
 
 	fmt.Printf("SMTP Test Client\n")
@@ -75,13 +166,48 @@ func main() {
 	fmt.Printf("  TLS:      %s\n", cfg.tlsMode)
 	fmt.Printf("  From:     %s\n", cfg.from)
 	fmt.Printf("  To:       %s\n", strings.Join(cfg.to, ", "))
-	fmt.Printf("  Count:    %d\n", cfg.count)
-	if cfg.count > 1 {
-		fmt.Printf("  Rate:     %.1f emails/sec\n", cfg.rate)
+	if cfg.user != "" {
+		fmt.Printf("  Auth:     %s as %s\n", strings.ToUpper(cfg.auth), cfg.user)
+	}
+	if len(selected) > 0 {
+		fmt.Println()
+		fmt.Printf("Probes\n")
+		if failed := runProbes(cfg, addr, selected); failed > 0 {
+			fmt.Printf("\nFAIL: %d of %d probes got an unexpected reply\n", failed, len(selected))
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.duration > 0 {
+		fmt.Printf("  Duration: %s\n", cfg.duration)
+	} else {
+		fmt.Printf("  Count:    %d\n", cfg.count)
+	}
+	if cfg.count > 1 || cfg.duration > 0 {
+		if cfg.rate > 0 {
+			fmt.Printf("  Rate:     %.1f emails/sec\n", cfg.rate)
+		} else {
+			fmt.Printf("  Rate:     unlimited\n")
+		}
+	}
+	if cfg.concurrency > 1 {
+		fmt.Printf("  Workers:  %d\n", cfg.concurrency)
 	}
 	if cfg.html != "" {
 		fmt.Printf("  HTML:     yes\n")
 	}
+	for _, h := range cfg.extraHeaders {
+		fmt.Printf("  Header:   %s: %s\n", h[0], h[1])
+	}
+	if cfg.apiURL != "" {
+		fmt.Printf("  API:      %s (wait %s)\n", cfg.apiURL, cfg.wait)
+	}
+	if len(cfg.inline) > 0 {
+		fmt.Printf("  Inline:   %d image(s)\n", len(cfg.inline))
+		for _, f := range cfg.inline {
+			fmt.Printf("            - %s (cid:%s)\n", f, filepath.Base(f))
+		}
+	}
 	if len(cfg.attach) > 0 {
 		fmt.Printf("  Attach:   %d file(s)\n", len(cfg.attach))
 		for _, f := range cfg.attach {
@@ -90,50 +216,138 @@ func main() {
 	}
 	fmt.Println()
 
-	var (
-		successCount int
-		failCount    int
-		totalSend    time.Duration
-	)
+	results, elapsed := run(cfg, addr)
 
-	interval := time.Duration(0)
-	if cfg.count > 1 && cfg.rate > 0 {
-		interval = time.Duration(float64(time.Second) / cfg.rate)
+	sum := summarize(results, elapsed)
+	fmt.Println()
+	fmt.Printf("Results: %d sent, %d failed in %s (%.1f emails/sec)\n",
+		sum.Sent, sum.Failed, elapsed.Round(time.Millisecond), sum.Throughput)
+	if sum.Sent > 0 {
+		fmt.Printf("Latency: p50 %s, p95 %s, p99 %s, max %s\n",
+			msDuration(sum.P50Ms), msDuration(sum.P95Ms), msDuration(sum.P99Ms), msDuration(sum.MaxMs))
 	}
 
-	for i := 0; i < cfg.count; i++ {
-		if i > 0 && interval > 0 {
-			time.Sleep(interval)
-		}
+	undelivered := 0
+	if cfg.apiURL != "" && sum.Sent > 0 {
+		fmt.Println()
+		fmt.Printf("Delivery timeline\n")
+		undelivered = track(cfg, results)
+	}
 
-		seq := i + 1
-		subject := cfg.subject
-		body := cfg.body
-		if cfg.count > 1 {
-			subject = fmt.Sprintf("%s [%d/%d]", cfg.subject, seq, cfg.count)
-			body = fmt.Sprintf("%s\n\n-- Email %d of %d --", cfg.body, seq, cfg.count)
+	if cfg.output != "" {
+		if err := writeResults(cfg.output, cfg.format, sum, results); err != nil {
+			fmt.Fprintf(os.Stderr, "error: write results: %v\n", err)
+			os.Exit(1)
 		}
+		fmt.Printf("Wrote results to %s\n", cfg.output)
+	}
 
-		sendStart := time.Now()
-		err := sendEmail(cfg, addr, subject, body)
-		sendDuration := time.Since(sendStart)
-		totalSend += sendDuration
+	if sum.Failed > 0 || undelivered > 0 {
+		os.Exit(1)
+	}
+}
 
-		if err != nil {
-			failCount++
-			fmt.Printf("  [%d/%d] FAIL (%s): %v\n", seq, cfg.count, sendDuration, err)
-		} else {
-			successCount++
-			fmt.Printf("  [%d/%d] OK   (%s)\n", seq, cfg.count, sendDuration)
+// run sends the messages with cfg.concurrency workers, at most cfg.rate
+// per second overall, and returns one result per message in sequence
+// order. With a duration it sends until the duration has passed instead of
+// cfg.count messages.
+func run(cfg config, addr string) ([]result, time.Duration) {
+	var tick <-chan time.Time
+	if cfg.rate > 0 && (cfg.count > 1 || cfg.duration > 0) {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	start := time.Now()
+	var deadline time.Time
+	if cfg.duration > 0 {
+		deadline = start.Add(cfg.duration)
+	}
+
+	seqs := make(chan int)
+	go func() {
+		defer close(seqs)
+		for seq := 1; cfg.duration > 0 || seq <= cfg.count; seq++ {
+			if seq > 1 && tick != nil {
+				<-tick
+			}
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return
+			}
+			seqs <- seq
 		}
+	}()
+
+	var (
+		mu      sync.Mutex
+		results []result
+		wg      sync.WaitGroup
+	)
+	for range max(cfg.concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seq := range seqs {
+				r := sendOne(cfg, addr, seq)
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}()
 	}
+	wg.Wait()
 
-	fmt.Println()
-	fmt.Printf("Results: %d sent, %d failed, total time %s\n", successCount, failCount, totalSend)
+	slices.SortFunc(results, func(a, b result) int { return a.Seq - b.Seq })
+	return results, time.Since(start)
+}
 
-	if failCount > 0 {
-		os.Exit(1)
+// sendOne sends message seq and prints its outcome.
+func sendOne(cfg config, addr string, seq int) result {
+	label := fmt.Sprintf("%d", seq)
+	subject := cfg.subject
+	body := cfg.body
+	switch {
+	case cfg.duration > 0:
+		subject = fmt.Sprintf("%s [%d]", cfg.subject, seq)
+		body = fmt.Sprintf("%s\n\n-- Email %d --", cfg.body, seq)
+	case cfg.count > 1:
+		label = fmt.Sprintf("%d/%d", seq, cfg.count)
+		subject = fmt.Sprintf("%s [%s]", cfg.subject, label)
+		body = fmt.Sprintf("%s\n\n-- Email %d of %d --", cfg.body, seq, cfg.count)
+	default:
+		label = "1/1"
+	}
+
+	m := message{
+		from:    cfg.from,
+		to:      cfg.to,
+		subject: subject,
+		text:    body,
+		html:    cfg.html,
+		headers: cfg.extraHeaders,
+		inline:  cfg.inline,
+		attach:  cfg.attach,
+	}
+	if cfg.tag != "" {
+		m.headers = append(slices.Clip(m.headers), [2]string{"X-SMTPProxy-Tag", cfg.tag})
+	}
+
+	r := result{Seq: seq, Label: label, Subject: subject, StartedAt: time.Now()}
+	id, err := sendEmail(cfg, addr, m)
+	r.Latency = time.Since(r.StartedAt)
+	r.MessageID = id
+
+	switch {
+	case err != nil:
+		r.Error = err.Error()
+		fmt.Printf("  [%s] FAIL (%s): %v\n", label, r.Latency, err)
+	case id != "":
+		fmt.Printf("  [%s] OK   (%s) queued as %s\n", label, r.Latency, id)
+	default:
+		fmt.Printf("  [%s] OK   (%s)\n", label, r.Latency)
 	}
+	return r
 }
 
 func parseFlags() config {
@@ -145,14 +359,27 @@ func parseFlags() config {
 	flag.BoolVar(&cfg.insecure, "insecure", false, "Skip TLS certificate verification")
 	flag.StringVar(&cfg.user, "user", "", "SMTP AUTH username")
 	flag.StringVar(&cfg.password, "password", "", "SMTP AUTH password")
+	flag.StringVar(&cfg.auth, "auth", "plain", "SMTP AUTH mechanism: plain, login, cram-md5")
 	flag.StringVar(&cfg.from, "from", "", "Sender email address")
 	flag.Var(&cfg.to, "to", "Recipient email address (can be specified multiple times)")
 	flag.StringVar(&cfg.subject, "subject", "Test Email", "Email subject")
 	flag.StringVar(&cfg.body, "body", "This is a test email sent by smtp-proxy test-client.", "Email body")
 	flag.IntVar(&cfg.count, "count", 1, "Number of emails to send (for batch testing)")
-	flag.Float64Var(&cfg.rate, "rate", 1, "Emails per second for batch sending")
-	flag.StringVar(&cfg.html, "html", "", "HTML body content (sends multipart/alternative)")
+	flag.Float64Var(&cfg.rate, "rate", 1, "Emails per second for batch sending, across all workers (0 = unlimited)")
+	flag.IntVar(&cfg.concurrency, "concurrency", 1, "Number of messages sent in parallel, each over its own connection")
+	flag.DurationVar(&cfg.duration, "duration", 0, "Send until this much time has passed instead of --count messages")
+	flag.StringVar(&cfg.output, "output", "", "Write per-message results and latency percentiles to this file")
+	flag.StringVar(&cfg.format, "format", "", "Format of --output: csv or json (default: from the file extension, else csv)")
+	flag.StringVar(&cfg.html, "html", "", "HTML body, or path to a file containing it (sends multipart/alternative)")
+	flag.Var(&cfg.inline, "inline", "Image file embedded in the HTML body, referenced as cid:<file name> (can be specified multiple times)")
 	flag.Var(&cfg.attach, "attach", "File path to attach (can be specified multiple times)")
+	flag.Var(&cfg.headers, "header", "Extra header \"Name: value\" (can be specified multiple times)")
+	flag.Var(&cfg.probes, "probe", "Run a protocol failure scenario instead of sending: "+strings.Join(probeNames(), ", ")+" (can be specified multiple times)")
+	flag.StringVar(&cfg.expect, "expect", "", "Reply code expected from a single --probe, e.g. 550 or 5xx (default: the server's code for the scenario)")
+	flag.StringVar(&cfg.apiURL, "api", "", "API base URL; when set, poll each sent message until it is delivered or failed, e.g. http://localhost:8080")
+	flag.StringVar(&cfg.apiToken, "api-token", "", "Bearer token (JWT or API key) for --api")
+	flag.DurationVar(&cfg.wait, "wait", 2*time.Minute, "How long to wait for messages to be delivered or fail when --api is set")
+	flag.DurationVar(&cfg.pollInterval, "poll-interval", time.Second, "How often to poll the messages API when --api is set")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: test-client [options]\n\n")
@@ -164,259 +391,307 @@ func parseFlags() config {
 		fmt.Fprintf(os.Stderr, "  test-client --tls none --from test@example.com --to recipient@example.com\n")
 		fmt.Fprintf(os.Stderr, "  test-client --insecure --user admin --password secret --from test@example.com --to recipient@example.com\n")
 		fmt.Fprintf(os.Stderr, "  test-client --count 100 --rate 10 --from test@example.com --to recipient@example.com\n")
+		fmt.Fprintf(os.Stderr, "  test-client --concurrency 8 --duration 1m --rate 0 --output results.json --from test@example.com --to recipient@example.com\n")
 		fmt.Fprintf(os.Stderr, "  test-client --from test@example.com --to recipient@example.com --html '<h1>Hello</h1>'\n")
 		fmt.Fprintf(os.Stderr, "  test-client --from test@example.com --to recipient@example.com --attach /path/to/file.pdf\n")
+		fmt.Fprintf(os.Stderr, "  test-client --from test@example.com --to recipient@example.com --html page.html --inline logo.png --header \"X-Tag: foo\"\n")
+		fmt.Fprintf(os.Stderr, "  test-client --from test@example.com --to recipient@example.com --api http://localhost:8080 --api-token $TOKEN\n")
+		fmt.Fprintf(os.Stderr, "  test-client --user admin --password secret --from test@example.com --to recipient@example.com --probe all\n")
+		fmt.Fprintf(os.Stderr, "  test-client --user admin --password secret --from test@example.com --to recipient@example.com --auth cram-md5 --probe auth --expect 454\n")
 	}
 
 	flag.Parse()
 	return cfg
 }
 
-func sendEmail(cfg config, addr, subject, body string) error {
-	tlsConfig := &tls.Config{
-		ServerName:         cfg.host,
-		InsecureSkipVerify: cfg.insecure, //nolint:gosec // Intentional for dev self-signed certs.
+// sendEmail sends one message and returns the message ID from the server's
+// final DATA reply, or "" when the reply carries none.
+func sendEmail(cfg config, addr string, m message) (string, error) {
+	msg, err := buildMessage(m)
+	if err != nil {
+		return "", fmt.Errorf("build message: %w", err)
 	}
 
-	msg, err := buildMessage(cfg.from, cfg.to, subject, body, cfg.html, cfg.attach)
+	c, err := connect(cfg, addr)
 	if err != nil {
-		return fmt.Errorf("build message: %w", err)
+		return "", err
 	}
+	defer c.Close()
 
-	switch cfg.tlsMode {
-	case "none":
-		return sendPlain(addr, cfg, msg)
-	case "implicit":
-		return sendImplicitTLS(addr, cfg, tlsConfig, msg)
-	case "starttls":
-		return sendSTARTTLS(addr, cfg, tlsConfig, msg)
-	default:
-		return fmt.Errorf("unknown TLS mode: %s (use starttls, implicit, or none)", cfg.tlsMode)
+	if err := c.Mail(cfg.from, nil); err != nil {
+		return "", fmt.Errorf("mail from: %w", err)
+	}
+	for _, rcpt := range cfg.to {
+		if err := c.Rcpt(rcpt, nil); err != nil {
+			return "", fmt.Errorf("rcpt to %s: %w", rcpt, err)
+		}
 	}
-}
 
-func sendPlain(addr string, cfg config, msg []byte) error {
-	c, err := smtp.Dial(addr)
+	w, err := c.Data()
 	if err != nil {
-		return fmt.Errorf("dial: %w", err)
+		return "", fmt.Errorf("data: %w", err)
 	}
-	defer c.Close()
-
-	return smtpSend(c, cfg, msg)
-}
-
-func sendSTARTTLS(addr string, cfg config, tlsConfig *tls.Config, msg []byte) error {
-	c, err := smtp.Dial(addr)
+	if _, err := w.Write(msg); err != nil {
+		return "", fmt.Errorf("write: %w", err)
+	}
+	resp, err := w.CloseWithResponse()
 	if err != nil {
-		return fmt.Errorf("dial: %w", err)
+		return "", fmt.Errorf("close data: %w", err)
 	}
-	defer c.Close()
 
-	if err := c.StartTLS(tlsConfig); err != nil {
-		return fmt.Errorf("starttls: %w", err)
+	var queuedID string
+	if i := strings.Index(resp.StatusText, queuedPrefix); i >= 0 {
+		queuedID = strings.TrimSpace(resp.StatusText[i+len(queuedPrefix):])
 	}
-
-	return smtpSend(c, cfg, msg)
+	return queuedID, c.Quit()
 }
 
-func sendImplicitTLS(addr string, cfg config, tlsConfig *tls.Config, msg []byte) error {
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
-	if err != nil {
-		return fmt.Errorf("tls dial: %w", err)
+func connect(cfg config, addr string) (*gosmtp.Client, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.host,
+		InsecureSkipVerify: cfg.insecure, //nolint:gosec // Intentional for dev self-signed certs.
 	}
 
-	host, _, err := net.SplitHostPort(addr)
-	if err != nil {
-		conn.Close()
-		return fmt.Errorf("split host port: %w", err)
+	var (
+		c   *gosmtp.Client
+		err error
+	)
+	switch cfg.tlsMode {
+	case "none":
+		c, err = gosmtp.Dial(addr)
+	case "implicit":
+		c, err = gosmtp.DialTLS(addr, tlsConfig)
+	case "starttls":
+		c, err = gosmtp.DialStartTLS(addr, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unknown TLS mode: %s (use starttls, implicit, or none)", cfg.tlsMode)
 	}
-
-	c, err := smtp.NewClient(conn, host)
 	if err != nil {
-		conn.Close()
-		return fmt.Errorf("new client: %w", err)
+		return nil, fmt.Errorf("dial: %w", err)
 	}
-	defer c.Close()
-
-	return smtpSend(c, cfg, msg)
-}
 
-func smtpSend(c *smtp.Client, cfg config, msg []byte) error {
 	// Authenticate if credentials are provided.
 	if cfg.user != "" && cfg.password != "" {
-		auth := smtp.PlainAuth("", cfg.user, cfg.password, cfg.host)
-		if err := c.Auth(auth); err != nil {
-			return fmt.Errorf("auth: %w", err)
+		a, err := saslClient(cfg.auth, cfg.user, cfg.password)
+		if err != nil {
+			c.Close()
+			return nil, err
 		}
-	}
-
-	if err := c.Mail(cfg.from); err != nil {
-		return fmt.Errorf("mail from: %w", err)
-	}
-
-	for _, rcpt := range cfg.to {
-		if err := c.Rcpt(rcpt); err != nil {
-			return fmt.Errorf("rcpt to %s: %w", rcpt, err)
+		if err := c.Auth(a); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("auth: %w", err)
 		}
 	}
-
-	w, err := c.Data()
-	if err != nil {
-		return fmt.Errorf("data: %w", err)
-	}
-
-	if _, err := w.Write(msg); err != nil {
-		return fmt.Errorf("write: %w", err)
-	}
-
-	if err := w.Close(); err != nil {
-		return fmt.Errorf("close data: %w", err)
-	}
-
-	return c.Quit()
+	return c, nil
 }
 
-func buildMessage(from string, to []string, subject, body, html string, attachments []string) ([]byte, error) {
-	var buf bytes.Buffer
-
-	fmt.Fprintf(&buf, "From: %s\r\n", from)
-	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
-	fmt.Fprintf(&buf, "Subject: %s\r\n", subject)
-	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
-	buf.WriteString("MIME-Version: 1.0\r\n")
+// message is the content of a test email.
+type message struct {
+	from    string
+	to      []string
+	subject string
+	text    string
+	html    string
+	headers [][2]string
+	inline  []string
+	attach  []string
+}
 
-	hasHTML := html != ""
-	hasAttach := len(attachments) > 0
+// mimePart is one entity of a MIME message: its header fields and the
+// encoded body.
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
 
-	// Plain text only (backward compatible).
-	if !hasHTML && !hasAttach {
-		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(body)
-		return buf.Bytes(), nil
+// buildMessage renders m as an RFC 5322 message. The body is the plain text
+// alone, or nested as needed:
+//
+//	multipart/mixed             (with --attach)
+//	  multipart/related         (with --inline)
+//	    multipart/alternative   (with --html)
+//	      text/plain
+//	      text/html
+//	    inline images
+//	  attachments
+func buildMessage(m message) ([]byte, error) {
+	content := textPart(m.text)
+	if m.html != "" {
+		var err error
+		content, err = multipartOf("alternative", content, htmlPart(m.html))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if hasAttach {
-		// multipart/mixed with attachments.
-		mixed := multipart.NewWriter(&buf)
-		fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n", mixed.Boundary())
-		buf.WriteString("\r\n")
-
-		if hasHTML {
-			if err := writeAlternativePart(mixed, body, html); err != nil {
-				return nil, err
-			}
-		} else {
-			if err := writeTextPart(mixed, body); err != nil {
+	if len(m.inline) > 0 {
+		parts := []mimePart{content}
+		for _, path := range m.inline {
+			p, err := filePart(path, "inline")
+			if err != nil {
 				return nil, err
 			}
+			parts = append(parts, p)
 		}
+		var err error
+		content, err = multipartOf("related", parts...)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		for _, path := range attachments {
-			if err := writeAttachmentPart(mixed, path); err != nil {
+	if len(m.attach) > 0 {
+		parts := []mimePart{content}
+		for _, path := range m.attach {
+			p, err := filePart(path, "attachment")
+			if err != nil {
 				return nil, err
 			}
+			parts = append(parts, p)
 		}
-
-		if err := mixed.Close(); err != nil {
-			return nil, fmt.Errorf("close mixed writer: %w", err)
-		}
-	} else {
-		// HTML without attachments: multipart/alternative.
-		alt := multipart.NewWriter(&buf)
-		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n", alt.Boundary())
-		buf.WriteString("\r\n")
-
-		if err := writeTextPart(alt, body); err != nil {
-			return nil, err
-		}
-		if err := writeHTMLPart(alt, html); err != nil {
+		var err error
+		content, err = multipartOf("mixed", parts...)
+		if err != nil {
 			return nil, err
 		}
-
-		if err := alt.Close(); err != nil {
-			return nil, fmt.Errorf("close alternative writer: %w", err)
-		}
 	}
 
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", m.subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	for _, h := range m.headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	writeHeader(&buf, content.header)
+	buf.WriteString("\r\n")
+	buf.Write(content.body)
 	return buf.Bytes(), nil
 }
 
-func writeTextPart(w *multipart.Writer, text string) error {
-	h := textproto.MIMEHeader{}
-	h.Set("Content-Type", "text/plain; charset=utf-8")
-	part, err := w.CreatePart(h)
-	if err != nil {
-		return fmt.Errorf("create text part: %w", err)
+// reservedHeaders are set by test-client itself and cannot be given with
+// --header.
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Subject":                   true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+}
+
+// parseHeader parses a --header value of the form "Name: value".
+func parseHeader(s string) ([2]string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return [2]string{}, fmt.Errorf("invalid header %q: want \"Name: value\"", s)
 	}
-	_, err = part.Write([]byte(text))
-	return err
+	if strings.ContainsAny(s, "\r\n") {
+		return [2]string{}, fmt.Errorf("invalid header %q: contains a line break", s)
+	}
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	if reservedHeaders[name] {
+		return [2]string{}, fmt.Errorf("header %s is set by test-client", name)
+	}
+	return [2]string{name, value}, nil
 }
 
-func writeHTMLPart(w *multipart.Writer, html string) error {
-	h := textproto.MIMEHeader{}
-	h.Set("Content-Type", "text/html; charset=utf-8")
-	part, err := w.CreatePart(h)
+// loadHTML returns the --html value, read from the file it names when
+// there is one.
+func loadHTML(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	info, err := os.Stat(value)
+	if err != nil || info.IsDir() {
+		return value, nil
+	}
+	data, err := os.ReadFile(value)
 	if err != nil {
-		return fmt.Errorf("create html part: %w", err)
+		return "", fmt.Errorf("read html %s: %w", value, err)
 	}
-	_, err = part.Write([]byte(html))
-	return err
+	return string(data), nil
+}
+
+func textPart(text string) mimePart {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	return mimePart{header: h, body: []byte(text)}
 }
 
-func writeAlternativePart(mixed *multipart.Writer, text, html string) error {
-	var altBuf bytes.Buffer
-	alt := multipart.NewWriter(&altBuf)
+func htmlPart(html string) mimePart {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	return mimePart{header: h, body: []byte(html)}
+}
 
-	if err := writeTextPart(alt, text); err != nil {
-		return err
-	}
-	if err := writeHTMLPart(alt, html); err != nil {
-		return err
+// multipartOf wraps parts in a multipart entity of the given subtype.
+func multipartOf(subtype string, parts ...mimePart) (mimePart, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		pw, err := w.CreatePart(p.header)
+		if err != nil {
+			return mimePart{}, fmt.Errorf("create %s part: %w", subtype, err)
+		}
+		if _, err := pw.Write(p.body); err != nil {
+			return mimePart{}, err
+		}
 	}
-	if err := alt.Close(); err != nil {
-		return fmt.Errorf("close alternative writer: %w", err)
+	if err := w.Close(); err != nil {
+		return mimePart{}, fmt.Errorf("close %s writer: %w", subtype, err)
 	}
 
 	h := textproto.MIMEHeader{}
-	h.Set("Content-Type", fmt.Sprintf("multipart/alternative; boundary=%s", alt.Boundary()))
-	part, err := mixed.CreatePart(h)
-	if err != nil {
-		return fmt.Errorf("create alternative part: %w", err)
-	}
-	_, err = part.Write(altBuf.Bytes())
-	return err
+	h.Set("Content-Type", fmt.Sprintf("multipart/%s; boundary=%s", subtype, w.Boundary()))
+	return mimePart{header: h, body: buf.Bytes()}, nil
 }
 
-func writeAttachmentPart(w *multipart.Writer, filePath string) error {
+// filePart reads a file into a base64-encoded part. Inline parts get a
+// Content-ID of the file name, so HTML refers to them as cid:<file name>.
+func filePart(filePath, disposition string) (mimePart, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("read attachment %s: %w", filePath, err)
+		return mimePart{}, fmt.Errorf("read %s %s: %w", disposition, filePath, err)
 	}
 
 	filename := filepath.Base(filePath)
-	contentType := http.DetectContentType(data)
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
 
 	h := textproto.MIMEHeader{}
 	h.Set("Content-Type", fmt.Sprintf("%s; name=%q", contentType, filename))
-	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	h.Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, filename))
 	h.Set("Content-Transfer-Encoding", "base64")
-
-	part, err := w.CreatePart(h)
-	if err != nil {
-		return fmt.Errorf("create attachment part: %w", err)
+	if disposition == "inline" {
+		h.Set("Content-ID", "<"+filename+">")
 	}
 
+	var body bytes.Buffer
 	encoded := base64.StdEncoding.EncodeToString(data)
 	for i := 0; i < len(encoded); i += 76 {
-		end := i + 76
-		if end > len(encoded) {
-			end = len(encoded)
-		}
-		if _, err := part.Write([]byte(encoded[i:end] + "\r\n")); err != nil {
-			return err
-		}
+		end := min(i+76, len(encoded))
+		body.WriteString(encoded[i:end] + "\r\n")
 	}
+	return mimePart{header: h, body: body.Bytes()}, nil
+}
 
-	return nil
+// writeHeader writes header fields in a stable order.
+func writeHeader(buf *bytes.Buffer, h textproto.MIMEHeader) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
 }
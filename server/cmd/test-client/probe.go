@@ -0,0 +1,358 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-sasl"
+)
+
+// probeTimeout bounds each probe's connection.
+const probeTimeout = 30 * time.Second
+
+// probe is a protocol scenario run over a raw SMTP connection, with the
+// reply code the server is expected to give.
+type probe struct {
+	name   string
+	expect string
+	// authenticate makes the probe log in first when --user is given.
+	authenticate bool
+	// run sends the scenario and returns a description of what was sent
+	// and the reply that is checked.
+	run func(p *probeConn, cfg config) (sent string, code int, msg string, err error)
+}
+
+// probes are the --probe scenarios. The expected codes are those of the
+// smtp-proxy server.
+var probes = []probe{
+	{name: "auth", expect: "235", run: probeAuth(false)},
+	{name: "bad-auth", expect: "535", run: probeAuth(true)},
+	{name: "no-auth", expect: "530", run: func(p *probeConn, cfg config) (string, int, string, error) {
+		return p.send("MAIL FROM:<" + cfg.from + ">")
+	}},
+	{name: "bad-command", expect: "500", authenticate: true, run: func(p *probeConn, _ config) (string, int, string, error) {
+		return p.send("XYZZ")
+	}},
+	{name: "bad-sequence", expect: "502", authenticate: true, run: func(p *probeConn, cfg config) (string, int, string, error) {
+		return p.send("RCPT TO:<" + cfg.to[0] + ">")
+	}},
+	{name: "bad-address", expect: "501", authenticate: true, run: func(p *probeConn, cfg config) (string, int, string, error) {
+		return p.send("MAIL FROM:<" + cfg.from)
+	}},
+	{name: "long-line", expect: "500", authenticate: true, run: func(p *probeConn, _ config) (string, int, string, error) {
+		_, code, msg, err := p.send("NOOP " + strings.Repeat("x", 4096))
+		return "NOOP <4096 bytes>", code, msg, err
+	}},
+	{name: "oversize", expect: "552", authenticate: true, run: probeOversize},
+}
+
+// probeNames lists the --probe values.
+func probeNames() []string {
+	names := make([]string, 0, len(probes)+1)
+	for _, pr := range probes {
+		names = append(names, pr.name)
+	}
+	return append(names, "all")
+}
+
+// selectProbes resolves --probe values, expanding "all".
+func selectProbes(names []string) ([]probe, error) {
+	var selected []probe
+	for _, name := range names {
+		if name == "all" {
+			return probes, nil
+		}
+		i := -1
+		for j, pr := range probes {
+			if pr.name == name {
+				i = j
+			}
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("unknown --probe %q (use %s)", name, strings.Join(probeNames(), ", "))
+		}
+		selected = append(selected, probes[i])
+	}
+	return selected, nil
+}
+
+// validExpect reports whether s is a --expect value: comma-separated
+// three-character codes where x matches any digit, e.g. "550,5x4".
+func validExpect(s string) error {
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if len(c) != 3 || strings.Trim(strings.ToLower(c), "0123456789x") != "" {
+			return fmt.Errorf("invalid --expect %q: want codes such as 550 or 5xx", s)
+		}
+	}
+	return nil
+}
+
+// matchCode reports whether code matches an --expect value.
+func matchCode(expect string, code int) bool {
+	got := strconv.Itoa(code)
+	for _, c := range strings.Split(expect, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		ok := len(got) == len(c)
+		for i := 0; ok && i < len(c); i++ {
+			ok = c[i] == 'x' || c[i] == got[i]
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// runProbes runs each probe on a fresh connection, prints the replies and
+// returns the number of probes whose reply code did not match.
+func runProbes(cfg config, addr string, selected []probe) int {
+	failed := 0
+	for _, pr := range selected {
+		expect := pr.expect
+		if cfg.expect != "" {
+			expect = cfg.expect
+		}
+
+		sent, code, msg, err := runProbe(cfg, addr, pr)
+		reply := fmt.Sprintf("%d %s", code, firstLine(msg))
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("  [%s] FAIL %s: %v\n", pr.name, sent, err)
+		case !matchCode(expect, code):
+			failed++
+			fmt.Printf("  [%s] FAIL %s -> %s (want %s)\n", pr.name, sent, reply, expect)
+		default:
+			fmt.Printf("  [%s] OK   %s -> %s\n", pr.name, sent, reply)
+		}
+	}
+	return failed
+}
+
+func runProbe(cfg config, addr string, pr probe) (string, int, string, error) {
+	p, err := dialProbe(cfg, addr)
+	if err != nil {
+		return "connect", 0, "", err
+	}
+	defer p.close()
+
+	if pr.authenticate && cfg.user != "" {
+		sent, code, msg, err := p.auth(cfg, cfg.password)
+		if err != nil {
+			return sent, code, msg, err
+		}
+		if code != 235 {
+			return sent, code, msg, fmt.Errorf("login failed: %d %s", code, firstLine(msg))
+		}
+	}
+	return pr.run(p, cfg)
+}
+
+// probeAuth logs in with --auth and --password, or a wrong password.
+func probeAuth(wrongPassword bool) func(p *probeConn, cfg config) (string, int, string, error) {
+	return func(p *probeConn, cfg config) (string, int, string, error) {
+		if cfg.user == "" {
+			return "AUTH", 0, "", errors.New("requires --user")
+		}
+		password := cfg.password
+		if wrongPassword {
+			password += "-wrong"
+		}
+		return p.auth(cfg, password)
+	}
+}
+
+// probeOversize sends a message one line larger than the SIZE limit the
+// server advertises, without declaring its size in MAIL FROM, so the limit
+// is enforced while the data is received.
+func probeOversize(p *probeConn, cfg config) (string, int, string, error) {
+	limit, err := strconv.Atoi(p.ext["SIZE"])
+	if err != nil || limit <= 0 {
+		return "DATA", 0, "", errors.New("server does not advertise a SIZE limit")
+	}
+
+	for _, line := range []string{"MAIL FROM:<" + cfg.from + ">", "RCPT TO:<" + cfg.to[0] + ">"} {
+		sent, code, msg, err := p.send(line)
+		if err != nil || code != 250 {
+			return sent, code, msg, err
+		}
+	}
+	sent, code, msg, err := p.send("DATA")
+	if err != nil || code != 354 {
+		return sent, code, msg, err
+	}
+
+	w := p.text.DotWriter()
+	n, _ := fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n", cfg.from, cfg.to[0], cfg.subject)
+	line := strings.Repeat("x", 998) + "\r\n"
+	for n <= limit {
+		m, err := w.Write([]byte(line))
+		n += m
+		if err != nil {
+			// The server may reject the data before it is complete; its
+			// reply is still read below.
+			break
+		}
+	}
+	w.Close()
+
+	sent = fmt.Sprintf("DATA <%d bytes, limit %d>", n, limit)
+	code, msg, err = p.text.ReadResponse(0)
+	return sent, code, msg, err
+}
+
+// probeConn is a raw SMTP connection that reports every reply instead of
+// treating unexpected codes as errors.
+type probeConn struct {
+	conn net.Conn
+	text *textproto.Conn
+	// ext holds the EHLO extensions, keyed by upper-case keyword.
+	ext map[string]string
+}
+
+// dialProbe connects with --tls, reads the greeting and sends EHLO.
+func dialProbe(cfg config, addr string) (*probeConn, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.host,
+		InsecureSkipVerify: cfg.insecure, //nolint:gosec // Intentional for dev self-signed certs.
+	}
+	dialer := &net.Dialer{Timeout: probeTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch cfg.tlsMode {
+	case "none", "starttls":
+		conn, err = dialer.Dial("tcp", addr)
+	case "implicit":
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unknown TLS mode: %s (use starttls, implicit, or none)", cfg.tlsMode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	p := &probeConn{}
+	p.setConn(conn)
+
+	if code, msg, err := p.text.ReadResponse(0); err != nil || code != 220 {
+		p.conn.Close()
+		return nil, fmt.Errorf("greeting: %d %s %v", code, firstLine(msg), err)
+	}
+	if err := p.ehlo(); err != nil {
+		p.conn.Close()
+		return nil, err
+	}
+
+	if cfg.tlsMode == "starttls" {
+		if _, code, msg, err := p.send("STARTTLS"); err != nil || code != 220 {
+			p.conn.Close()
+			return nil, fmt.Errorf("starttls: %d %s %v", code, firstLine(msg), err)
+		}
+		tlsConn := tls.Client(p.conn, tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			p.conn.Close()
+			return nil, fmt.Errorf("starttls: %w", err)
+		}
+		p.setConn(tlsConn)
+		if err := p.ehlo(); err != nil {
+			p.conn.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *probeConn) setConn(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+	p.conn = conn
+	p.text = textproto.NewConn(conn)
+}
+
+func (p *probeConn) ehlo() error {
+	_, code, msg, err := p.send("EHLO localhost")
+	if err != nil || code != 250 {
+		return fmt.Errorf("ehlo: %d %s %v", code, firstLine(msg), err)
+	}
+	p.ext = map[string]string{}
+	for _, line := range strings.Split(msg, "\n")[1:] {
+		keyword, params, _ := strings.Cut(line, " ")
+		p.ext[strings.ToUpper(keyword)] = params
+	}
+	return nil
+}
+
+// send writes one command line and reads the reply.
+func (p *probeConn) send(line string) (string, int, string, error) {
+	if err := p.text.PrintfLine("%s", line); err != nil {
+		return line, 0, "", err
+	}
+	code, msg, err := p.text.ReadResponse(0)
+	return line, code, msg, err
+}
+
+// auth runs an AUTH exchange with the --auth mechanism and returns the
+// final reply.
+func (p *probeConn) auth(cfg config, password string) (string, int, string, error) {
+	client, err := saslClient(cfg.auth, cfg.user, password)
+	if err != nil {
+		return "AUTH", 0, "", err
+	}
+	return saslExchange(p, client)
+}
+
+func saslExchange(p *probeConn, client sasl.Client) (string, int, string, error) {
+	mech, ir, err := client.Start()
+	if err != nil {
+		return "AUTH", 0, "", err
+	}
+	sent := "AUTH " + mech
+	line := sent
+	if ir != nil {
+		line += " " + encodeResponse(ir)
+	}
+
+	_, code, msg, err := p.send(line)
+	for err == nil && code == 334 {
+		challenge, derr := base64.StdEncoding.DecodeString(msg)
+		if derr != nil {
+			return sent, code, msg, fmt.Errorf("decode challenge: %w", derr)
+		}
+		resp, nerr := client.Next(challenge)
+		if nerr != nil {
+			// Abort the exchange.
+			_, code, msg, err = p.send("*")
+			return sent, code, msg, err
+		}
+		_, code, msg, err = p.send(encodeResponse(resp))
+	}
+	return sent, code, msg, err
+}
+
+// encodeResponse encodes a SASL response, with "=" for an empty one.
+func encodeResponse(b []byte) string {
+	if len(b) == 0 {
+		return "="
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func (p *probeConn) close() {
+	p.send("QUIT")
+	p.conn.Close()
+}
+
+// firstLine returns the first line of a multi-line reply.
+func firstLine(msg string) string {
+	line, _, _ := strings.Cut(msg, "\n")
+	return line
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// result is the outcome of sending one message.
+type result struct {
+	Seq       int
+	Label     string
+	Subject   string
+	StartedAt time.Time
+	Latency   time.Duration
+	Error     string
+	// MessageID is the ID the server queued the message under, from the
+	// DATA reply or, with --api, the messages API.
+	MessageID string
+	// DeliveryStatus is the last status seen through the messages API.
+	DeliveryStatus string
+}
+
+// summary aggregates the results of a run. Latency percentiles cover
+// successful sends only.
+type summary struct {
+	Sent       int     `json:"sent"`
+	Failed     int     `json:"failed"`
+	ElapsedMs  float64 `json:"elapsed_ms"`
+	Throughput float64 `json:"throughput_per_sec"`
+	P50Ms      float64 `json:"p50_ms"`
+	P95Ms      float64 `json:"p95_ms"`
+	P99Ms      float64 `json:"p99_ms"`
+	MaxMs      float64 `json:"max_ms"`
+}
+
+func summarize(results []result, elapsed time.Duration) summary {
+	var latencies []time.Duration
+	s := summary{ElapsedMs: ms(elapsed)}
+	for _, r := range results {
+		if r.Error != "" {
+			s.Failed++
+			continue
+		}
+		s.Sent++
+		latencies = append(latencies, r.Latency)
+	}
+	if elapsed > 0 {
+		s.Throughput = float64(s.Sent) / elapsed.Seconds()
+	}
+	if len(latencies) == 0 {
+		return s
+	}
+
+	slices.Sort(latencies)
+	pct := func(p float64) float64 {
+		return ms(latencies[min(len(latencies)-1, int(float64(len(latencies))*p))])
+	}
+	s.P50Ms = pct(0.50)
+	s.P95Ms = pct(0.95)
+	s.P99Ms = pct(0.99)
+	s.MaxMs = ms(latencies[len(latencies)-1])
+	return s
+}
+
+// writeResults writes the summary and per-message results to path as CSV
+// or JSON. An empty format is taken from the file extension.
+func writeResults(path, format string, sum summary, results []result) error {
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = "json"
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		err = writeJSON(f, sum, results)
+	} else {
+		err = writeCSV(f, results)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeCSV writes one row per message. Percentiles are left to the
+// spreadsheet; they are printed on stdout.
+func writeCSV(f *os.File, results []result) error {
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"seq", "started_at", "latency_ms", "status", "error", "message_id", "delivery_status"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		status := "ok"
+		if r.Error != "" {
+			status = "failed"
+		}
+		if err := w.Write([]string{
+			strconv.Itoa(r.Seq),
+			r.StartedAt.UTC().Format(time.RFC3339Nano),
+			strconv.FormatFloat(ms(r.Latency), 'f', 3, 64),
+			status,
+			r.Error,
+			r.MessageID,
+			r.DeliveryStatus,
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeJSON writes the summary and the per-message results as one object.
+func writeJSON(f *os.File, sum summary, results []result) error {
+	type jsonResult struct {
+		Seq       int       `json:"seq"`
+		StartedAt time.Time `json:"started_at"`
+		LatencyMs float64   `json:"latency_ms"`
+		Error     string    `json:"error,omitempty"`
+		MessageID string    `json:"message_id,omitempty"`
+		Delivery  string    `json:"delivery_status,omitempty"`
+	}
+	out := struct {
+		Summary summary      `json:"summary"`
+		Results []jsonResult `json:"results"`
+	}{Summary: sum, Results: make([]jsonResult, len(results))}
+	for i, r := range results {
+		out.Results[i] = jsonResult{
+			Seq:       r.Seq,
+			StartedAt: r.StartedAt.UTC(),
+			LatencyMs: ms(r.Latency),
+			Error:     r.Error,
+			MessageID: r.MessageID,
+			Delivery:  r.DeliveryStatus,
+		}
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// ms returns d in fractional milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// msDuration converts fractional milliseconds back to a rounded duration
+// for printing.
+func msDuration(v float64) time.Duration {
+	return time.Duration(v * float64(time.Millisecond)).Round(100 * time.Microsecond)
+}
+
+// formatFlag reports whether format is a supported --format value.
+func formatFlag(format string) error {
+	switch format {
+	case "", "csv", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown --format %q (use csv or json)", format)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// finalStatuses are the message statuses that no longer change.
+var finalStatuses = map[string]bool{
+	"delivered":      true,
+	"failed":         true,
+	"enqueue_failed": true,
+	"storage_error":  true,
+}
+
+// apiMessage is the subset of the messages API response test-client reads.
+type apiMessage struct {
+	ID          string       `json:"id"`
+	Sender      string       `json:"sender"`
+	Subject     string       `json:"subject"`
+	Status      string       `json:"status"`
+	EnqueuedAt  time.Time    `json:"enqueued_at"`
+	ProcessedAt *time.Time   `json:"processed_at"`
+	Deliveries  []apiAttempt `json:"deliveries"`
+}
+
+// apiAttempt is one delivery attempt of an apiMessage.
+type apiAttempt struct {
+	AttemptNumber     int       `json:"attempt_number"`
+	Provider          string    `json:"provider"`
+	ProviderMessageID string    `json:"provider_message_id"`
+	Status            string    `json:"status"`
+	ResponseCode      *int      `json:"response_code"`
+	Error             string    `json:"error"`
+	DurationMs        *int      `json:"duration_ms"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// apiError is a non-2xx response from the API.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("HTTP %d", e.status)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.status, e.message)
+}
+
+// apiClient calls the smtp-proxy API with a bearer token.
+type apiClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newAPIClient(cfg config) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(cfg.apiURL, "/"),
+		token:   cfg.apiToken,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *apiClient) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = json.Unmarshal(data, &body)
+		return &apiError{status: resp.StatusCode, message: body.Error}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// message fetches a message with its delivery attempts.
+func (c *apiClient) message(ctx context.Context, id string) (*apiMessage, error) {
+	var m apiMessage
+	if err := c.get(ctx, "/api/v1/messages/"+url.PathEscape(id), &m); err != nil {
+		return nil, fmt.Errorf("get message %s: %w", id, err)
+	}
+	return &m, nil
+}
+
+// findMessage looks up a message by its run tag and subject, for servers
+// whose DATA reply carries no message ID.
+func (c *apiClient) findMessage(ctx context.Context, tag, sender, subject string) (string, error) {
+	q := url.Values{"tag": {tag}, "limit": {"500"}}
+	var msgs []apiMessage
+	if err := c.get(ctx, "/api/v1/messages?"+q.Encode(), &msgs); err != nil {
+		return "", fmt.Errorf("list messages: %w", err)
+	}
+	for _, m := range msgs {
+		if m.Subject == subject && strings.EqualFold(m.Sender, sender) {
+			return m.ID, nil
+		}
+	}
+	return "", fmt.Errorf("message %q not found through the API", subject)
+}
+
+// waitForFinal polls a message until it reaches a final status or ctx is
+// done, returning the last state seen. Server errors and network failures
+// are retried; other API errors end the wait.
+func (c *apiClient) waitForFinal(ctx context.Context, id string, interval time.Duration) (*apiMessage, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *apiMessage
+	for {
+		m, err := c.message(ctx, id)
+		var apiErr *apiError
+		switch {
+		case err == nil:
+			last = m
+			if finalStatuses[m.Status] {
+				return m, nil
+			}
+		case errors.As(err, &apiErr) && apiErr.status < http.StatusInternalServerError:
+			return last, err
+		}
+
+		select {
+		case <-ctx.Done():
+			if last != nil {
+				return last, fmt.Errorf("still %s when the wait ended", last.Status)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// track follows every sent message through the API until it is delivered
+// or failed, or cfg.wait has passed, and prints the delivery timelines in
+// sequence order. It records the final status in results and returns the
+// number of messages that were not delivered.
+func track(cfg config, results []result) int {
+	client := newAPIClient(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.wait)
+	defer cancel()
+
+	type outcome struct {
+		msg *apiMessage
+		err error
+	}
+	outcomes := make([]outcome, len(results))
+	sem := make(chan struct{}, max(cfg.concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range results {
+		r := &results[i]
+		if r.Error != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if r.MessageID == "" {
+				id, err := client.findMessage(ctx, cfg.tag, cfg.from, r.Subject)
+				if err != nil {
+					outcomes[i] = outcome{err: err}
+					return
+				}
+				r.MessageID = id
+			}
+			m, err := client.waitForFinal(ctx, r.MessageID, cfg.pollInterval)
+			outcomes[i] = outcome{msg: m, err: err}
+		}()
+	}
+	wg.Wait()
+
+	counts := map[string]int{}
+	undelivered := 0
+	for i, r := range results {
+		if r.Error != "" {
+			continue
+		}
+		o := outcomes[i]
+		if o.msg != nil {
+			results[i].DeliveryStatus = o.msg.Status
+			printTimeline(r.Label, o.msg)
+		}
+		if o.err != nil {
+			fmt.Printf("  [%s] %v\n", r.Label, o.err)
+		}
+
+		status := results[i].DeliveryStatus
+		if status == "" {
+			status = "unknown"
+		}
+		counts[status]++
+		if status != "delivered" {
+			undelivered++
+		}
+	}
+
+	parts := make([]string, 0, len(counts))
+	for _, status := range []string{"delivered", "failed", "enqueue_failed", "storage_error", "queued", "processing", "unknown"} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	fmt.Printf("Delivery: %s\n", strings.Join(parts, ", "))
+	return undelivered
+}
+
+// printTimeline prints a message's status and delivery attempts, each
+// offset from the time it was queued.
+func printTimeline(label string, m *apiMessage) {
+	fmt.Printf("  [%s] %s: %s\n", label, m.ID, m.Status)
+	offset := func(t time.Time) string {
+		return "+" + t.Sub(m.EnqueuedAt).Round(time.Millisecond).String()
+	}
+
+	fmt.Printf("      %-10s queued at %s\n", offset(m.EnqueuedAt), m.EnqueuedAt.Local().Format(time.RFC3339))
+	for _, a := range m.Deliveries {
+		line := fmt.Sprintf("attempt %d", a.AttemptNumber)
+		if a.Provider != "" {
+			line += " via " + a.Provider
+		}
+		line += ": " + a.Status
+		var details []string
+		if a.ResponseCode != nil {
+			details = append(details, fmt.Sprintf("code %d", *a.ResponseCode))
+		}
+		if a.DurationMs != nil {
+			details = append(details, fmt.Sprintf("%dms", *a.DurationMs))
+		}
+		if a.ProviderMessageID != "" {
+			details = append(details, "provider id "+a.ProviderMessageID)
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		if a.Error != "" {
+			line += ": " + a.Error
+		}
+		fmt.Printf("      %-10s %s\n", offset(a.CreatedAt), line)
+	}
+	if m.ProcessedAt != nil {
+		fmt.Printf("      %-10s %s\n", offset(*m.ProcessedAt), m.Status)
+	}
+}
@@ -0,0 +1,91 @@
+// Package analytics streams delivery events to an external analytics store
+// (ClickHouse, or a Kafka topic through a REST proxy) so high-cardinality
+// reporting does not run against PostgreSQL.
+//
+// The exporter tails delivery_logs in (updated_at, id) order. A new
+// delivery attempt and a later webhook status change (bounce, complaint)
+// both move a row's updated_at forward, so each is exported as an Event;
+// events with the same ID are versions of one attempt, newest UpdatedAt
+// last.
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Sink types accepted by NewSink.
+const (
+	SinkClickHouse = "clickhouse"
+	SinkKafka      = "kafka"
+)
+
+// Sink writes batches of events to an analytics store. Write must be
+// all-or-nothing from the exporter's point of view: a failed batch is sent
+// again, so sinks should tolerate duplicates.
+type Sink interface {
+	// Name identifies the sink and its destination. The exporter keeps one
+	// cursor per name, so pointing a sink at a new table or topic starts
+	// a new export.
+	Name() string
+	Write(ctx context.Context, events []Event) error
+}
+
+// Event is a delivery attempt as exported. Message content, recipients and
+// provider responses are not included.
+type Event struct {
+	ID                uuid.UUID  `json:"id"`
+	MessageID         uuid.UUID  `json:"message_id"`
+	GroupID           *uuid.UUID `json:"group_id"`
+	UserID            *uuid.UUID `json:"user_id"`
+	ProviderID        *uuid.UUID `json:"provider_id"`
+	Provider          string     `json:"provider"`
+	ProviderMessageID string     `json:"provider_message_id"`
+	Status            string     `json:"status"`
+	AttemptNumber     int32      `json:"attempt_number"`
+	RetryCount        int32      `json:"retry_count"`
+	ResponseCode      *int32     `json:"response_code"`
+	DurationMs        *int32     `json:"duration_ms"`
+	EgressIP          string     `json:"egress_ip"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// NewEvent converts a delivery log row to an Event.
+func NewEvent(l storage.DeliveryLog) Event {
+	e := Event{
+		ID:                l.ID,
+		MessageID:         l.MessageID,
+		Provider:          l.Provider.String,
+		ProviderMessageID: l.ProviderMessageID.String,
+		Status:            l.Status,
+		AttemptNumber:     l.AttemptNumber,
+		RetryCount:        l.RetryCount,
+		EgressIP:          l.EgressIp.String,
+		CreatedAt:         l.CreatedAt.Time.UTC(),
+		UpdatedAt:         l.UpdatedAt.Time.UTC(),
+	}
+	if l.GroupID.Valid {
+		id := uuid.UUID(l.GroupID.Bytes)
+		e.GroupID = &id
+	}
+	if l.UserID.Valid {
+		id := uuid.UUID(l.UserID.Bytes)
+		e.UserID = &id
+	}
+	if l.ProviderID.Valid {
+		id := uuid.UUID(l.ProviderID.Bytes)
+		e.ProviderID = &id
+	}
+	if l.ResponseCode.Valid {
+		e.ResponseCode = &l.ResponseCode.Int32
+	}
+	if l.DurationMs.Valid {
+		e.DurationMs = &l.DurationMs.Int32
+	}
+	return e
+}
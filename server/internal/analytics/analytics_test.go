@@ -0,0 +1,281 @@
+package analytics
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakeQuerier keeps delivery logs and cursors in memory. Methods the
+// exporter does not use panic through the nil embedded interface.
+type fakeQuerier struct {
+	storage.Querier
+	logs    []storage.DeliveryLog
+	cursors map[string]storage.AnalyticsExportCursor
+}
+
+func (f *fakeQuerier) GetAnalyticsExportCursor(_ context.Context, sink string) (storage.AnalyticsExportCursor, error) {
+	c, ok := f.cursors[sink]
+	if !ok {
+		return c, pgx.ErrNoRows
+	}
+	return c, nil
+}
+
+func (f *fakeQuerier) UpsertAnalyticsExportCursor(_ context.Context, arg storage.UpsertAnalyticsExportCursorParams) error {
+	if f.cursors == nil {
+		f.cursors = map[string]storage.AnalyticsExportCursor{}
+	}
+	f.cursors[arg.Sink] = storage.AnalyticsExportCursor{Sink: arg.Sink, LastUpdatedAt: arg.LastUpdatedAt, LastID: arg.LastID}
+	return nil
+}
+
+func (f *fakeQuerier) ListDeliveryLogsForExport(_ context.Context, arg storage.ListDeliveryLogsForExportParams) ([]storage.DeliveryLog, error) {
+	sorted := append([]storage.DeliveryLog(nil), f.logs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].UpdatedAt.Time.Equal(sorted[j].UpdatedAt.Time) {
+			return sorted[i].UpdatedAt.Time.Before(sorted[j].UpdatedAt.Time)
+		}
+		return sorted[i].ID.String() < sorted[j].ID.String()
+	})
+	var out []storage.DeliveryLog
+	for _, l := range sorted {
+		after := l.UpdatedAt.Time.After(arg.AfterUpdatedAt.Time) ||
+			(l.UpdatedAt.Time.Equal(arg.AfterUpdatedAt.Time) && l.ID.String() > arg.AfterID.String())
+		if after && l.UpdatedAt.Time.Before(arg.Before.Time) && len(out) < int(arg.MaxResults) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+type fakeSink struct {
+	events []Event
+	fail   bool
+}
+
+func (s *fakeSink) Name() string { return "fake" }
+
+func (s *fakeSink) Write(_ context.Context, events []Event) error {
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func deliveryLog(age time.Duration) storage.DeliveryLog {
+	ts := pgtype.Timestamptz{Time: now.Add(-age), Valid: true}
+	return storage.DeliveryLog{
+		ID:            uuid.New(),
+		MessageID:     uuid.New(),
+		GroupID:       pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Status:        "sent",
+		Provider:      sql.NullString{String: "ses", Valid: true},
+		AttemptNumber: 1,
+		ResponseCode:  pgtype.Int4{Int32: 200, Valid: true},
+		CreatedAt:     ts,
+		UpdatedAt:     ts,
+	}
+}
+
+func newTestExporter(q *fakeQuerier, sink Sink, cfg Config) *Exporter {
+	e := NewExporter(q, sink, cfg, zerolog.Nop())
+	e.now = func() time.Time { return now }
+	return e
+}
+
+func TestNewEvent(t *testing.T) {
+	l := deliveryLog(time.Hour)
+	l.LastError = pgtype.Text{String: "rcpt@example.com rejected", Valid: true}
+
+	e := NewEvent(l)
+	if e.ID != l.ID || e.Provider != "ses" || e.ResponseCode == nil || *e.ResponseCode != 200 || e.DurationMs != nil {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.GroupID == nil || *e.GroupID != uuid.UUID(l.GroupID.Bytes) || e.UserID != nil {
+		t.Errorf("unexpected ids: group %v user %v", e.GroupID, e.UserID)
+	}
+	data, _ := json.Marshal(e)
+	if strings.Contains(string(data), "example.com") {
+		t.Errorf("event leaks the provider error: %s", data)
+	}
+}
+
+func TestExportOnce_Backfill(t *testing.T) {
+	q := &fakeQuerier{}
+	for i := 0; i < 5; i++ {
+		q.logs = append(q.logs, deliveryLog(time.Duration(100-i)*24*time.Hour))
+	}
+	recent := deliveryLog(10 * time.Second)
+	q.logs = append(q.logs, recent)
+	sink := &fakeSink{}
+	e := newTestExporter(q, sink, Config{BatchSize: 2, MaxBatches: 2, Backfill: true})
+
+	n, err := e.ExportOnce(context.Background())
+	if err != nil || n != 4 {
+		t.Fatalf("first run exported %d (err %v), want 4", n, err)
+	}
+	n, err = e.ExportOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("second run exported %d (err %v), want 1", n, err)
+	}
+	if len(sink.events) != 5 {
+		t.Fatalf("sink got %d events, want 5", len(sink.events))
+	}
+	for _, ev := range sink.events {
+		if ev.ID == recent.ID {
+			t.Error("a log updated within the lag was exported")
+		}
+	}
+
+	// A webhook status update exports the attempt again.
+	q.logs[0].Status = "bounced"
+	q.logs[0].UpdatedAt = pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true}
+	if n, err := e.ExportOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("update run exported %d (err %v), want 1", n, err)
+	}
+	if last := sink.events[len(sink.events)-1]; last.ID != q.logs[0].ID || last.Status != "bounced" {
+		t.Errorf("last event = %+v, want the bounced attempt", last)
+	}
+}
+
+func TestExportOnce_WithoutBackfillStartsNow(t *testing.T) {
+	q := &fakeQuerier{logs: []storage.DeliveryLog{deliveryLog(24 * time.Hour)}}
+	sink := &fakeSink{}
+	e := newTestExporter(q, sink, Config{})
+
+	if n, err := e.ExportOnce(context.Background()); err != nil || n != 0 {
+		t.Fatalf("exported %d (err %v), want 0", n, err)
+	}
+	if _, ok := q.cursors["fake"]; !ok {
+		t.Fatal("cursor was not saved")
+	}
+
+	q.logs = append(q.logs, deliveryLog(0))
+	e.now = func() time.Time { return now.Add(time.Minute) }
+	if n, err := e.ExportOnce(context.Background()); err != nil || n != 1 {
+		t.Errorf("exported %d (err %v), want only the new log", n, err)
+	}
+}
+
+func TestExportOnce_SinkFailureKeepsCursor(t *testing.T) {
+	q := &fakeQuerier{logs: []storage.DeliveryLog{deliveryLog(time.Hour)}}
+	sink := &fakeSink{fail: true}
+	e := newTestExporter(q, sink, Config{Backfill: true})
+
+	if _, err := e.ExportOnce(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, ok := q.cursors["fake"]; ok {
+		t.Error("cursor moved past a batch the sink rejected")
+	}
+
+	sink.fail = false
+	if n, err := e.ExportOnce(context.Background()); err != nil || n != 1 {
+		t.Errorf("retry exported %d (err %v), want 1", n, err)
+	}
+}
+
+func TestClickHouseSink(t *testing.T) {
+	var query, user string
+	var rows []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		user = r.Header.Get("X-ClickHouse-User")
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var e Event
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				t.Errorf("invalid row %q: %v", sc.Text(), err)
+			}
+			rows = append(rows, e)
+		}
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(SinkConfig{Type: SinkClickHouse, URL: srv.URL, Database: "mail", Table: "delivery_events", Username: "writer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink.Name() != "clickhouse:mail.delivery_events" {
+		t.Errorf("Name() = %q", sink.Name())
+	}
+	events := []Event{NewEvent(deliveryLog(time.Hour)), NewEvent(deliveryLog(time.Minute))}
+	if err := sink.Write(context.Background(), events); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if query != "INSERT INTO mail.delivery_events FORMAT JSONEachRow" || user != "writer" {
+		t.Errorf("query %q as %q", query, user)
+	}
+	if len(rows) != 2 || rows[1].ID != events[1].ID {
+		t.Errorf("rows = %+v", rows)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var path string
+	var body struct {
+		Records []kafkaRecord `json:"records"`
+	}
+	partialFailure := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		if partialFailure {
+			w.Write([]byte(`{"offsets":[{"partition":0,"offset":1},{"error_code":50003,"error":"leader not available"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+	}))
+	defer srv.Close()
+
+	sink, err := NewSink(SinkConfig{Type: SinkKafka, URL: srv.URL + "/", Topic: "delivery-events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := NewEvent(deliveryLog(time.Hour))
+	if err := sink.Write(context.Background(), []Event{ev}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if path != "/topics/delivery-events" || len(body.Records) != 1 || body.Records[0].Key != ev.MessageID.String() {
+		t.Errorf("posted %+v to %s", body, path)
+	}
+
+	partialFailure = true
+	if err := sink.Write(context.Background(), []Event{ev, ev}); err == nil || !strings.Contains(err.Error(), "leader not available") {
+		t.Errorf("Write() error = %v, want the record error", err)
+	}
+}
+
+func TestNewSink_Invalid(t *testing.T) {
+	for _, cfg := range []SinkConfig{
+		{Type: SinkClickHouse, URL: "clickhouse:8123", Table: "t"},
+		{Type: SinkClickHouse, URL: "http://clickhouse:8123"},
+		{Type: SinkKafka, URL: "http://rest-proxy:8082"},
+		{Type: "bigquery", URL: "http://example.com"},
+	} {
+		if _, err := NewSink(cfg); err == nil {
+			t.Errorf("NewSink(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
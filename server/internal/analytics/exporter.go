@@ -0,0 +1,213 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Config controls how fast the exporter reads delivery_logs.
+type Config struct {
+	// Interval is the delay between export runs.
+	Interval time.Duration
+	// BatchSize is the number of events read and written per batch.
+	BatchSize int
+	// MaxBatches bounds the batches handled per run, so a backfill is
+	// worked off over several runs.
+	MaxBatches int
+	// Lag holds back rows updated within this long, so rows committed out
+	// of updated_at order by concurrent transactions are not skipped.
+	Lag time.Duration
+	// Backfill starts a sink without a cursor at the oldest delivery log
+	// instead of at the time the export is enabled.
+	Backfill bool
+}
+
+// DefaultConfig returns sensible defaults for the exporter.
+func DefaultConfig() Config {
+	return Config{
+		Interval:   10 * time.Second,
+		BatchSize:  1000,
+		MaxBatches: 10,
+		Lag:        30 * time.Second,
+	}
+}
+
+// Exporter periodically writes delivery logs updated since its cursor to
+// a Sink. The cursor is saved after each batch the sink accepts, so a
+// failed batch is sent again by the next run.
+type Exporter struct {
+	queries storage.Querier
+	sink    Sink
+	config  Config
+	log     zerolog.Logger
+	now     func() time.Time
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+// NewExporter creates an Exporter writing to sink. Zero-valued config
+// fields fall back to DefaultConfig.
+func NewExporter(queries storage.Querier, sink Sink, cfg Config, log zerolog.Logger) *Exporter {
+	defaults := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.MaxBatches <= 0 {
+		cfg.MaxBatches = defaults.MaxBatches
+	}
+	if cfg.Lag <= 0 {
+		cfg.Lag = defaults.Lag
+	}
+	return &Exporter{
+		queries: queries,
+		sink:    sink,
+		config:  cfg,
+		log:     log.With().Str("sink", sink.Name()).Logger(),
+		now:     time.Now,
+	}
+}
+
+// Start launches the export loop in a background goroutine.
+func (e *Exporter) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+
+	e.wg.Add(1)
+	go e.run(ctx)
+
+	e.log.Info().
+		Dur("interval", e.config.Interval).
+		Bool("backfill", e.config.Backfill).
+		Msg("analytics exporter started")
+}
+
+// Stop signals the export loop to exit and waits for the current run.
+func (e *Exporter) Stop() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.wg.Wait()
+	e.log.Info().Msg("analytics exporter stopped")
+}
+
+func (e *Exporter) run(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := e.ExportOnce(ctx); err != nil && ctx.Err() == nil {
+				metrics.AnalyticsExportFailuresTotal.WithLabelValues(e.sink.Name()).Inc()
+				e.log.Error().Err(err).Msg("analytics export failed")
+			}
+		}
+	}
+}
+
+// ExportOnce writes up to MaxBatches batches of delivery logs to the sink
+// and returns the number of events written.
+func (e *Exporter) ExportOnce(ctx context.Context) (int, error) {
+	before := e.now().Add(-e.config.Lag)
+	cursor, err := e.cursor(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	for i := 0; i < e.config.MaxBatches; i++ {
+		logs, err := e.queries.ListDeliveryLogsForExport(ctx, storage.ListDeliveryLogsForExportParams{
+			AfterUpdatedAt: cursor.LastUpdatedAt,
+			AfterID:        cursor.LastID,
+			Before:         pgtype.Timestamptz{Time: before, Valid: true},
+			MaxResults:     int32(e.config.BatchSize),
+		})
+		if err != nil {
+			return exported, fmt.Errorf("list delivery logs: %w", err)
+		}
+		if len(logs) == 0 {
+			break
+		}
+
+		events := make([]Event, len(logs))
+		for j, l := range logs {
+			events[j] = NewEvent(l)
+		}
+		if err := e.sink.Write(ctx, events); err != nil {
+			return exported, err
+		}
+
+		last := logs[len(logs)-1]
+		cursor.LastUpdatedAt, cursor.LastID = last.UpdatedAt, last.ID
+		if err := e.saveCursor(ctx, cursor); err != nil {
+			return exported, err
+		}
+		exported += len(logs)
+		metrics.AnalyticsEventsExportedTotal.WithLabelValues(e.sink.Name()).Add(float64(len(logs)))
+
+		if len(logs) < e.config.BatchSize {
+			break
+		}
+	}
+
+	if exported > 0 {
+		e.log.Debug().
+			Int("exported", exported).
+			Time("cursor", cursor.LastUpdatedAt.Time).
+			Msg("analytics events exported")
+	}
+	return exported, nil
+}
+
+// cursor loads the sink's cursor. A sink without one starts at the oldest
+// delivery log when backfilling, and otherwise at start, which is saved
+// right away so later runs do not move it.
+func (e *Exporter) cursor(ctx context.Context, start time.Time) (storage.AnalyticsExportCursor, error) {
+	cursor, err := e.queries.GetAnalyticsExportCursor(ctx, e.sink.Name())
+	if err == nil {
+		return cursor, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return cursor, fmt.Errorf("load cursor: %w", err)
+	}
+
+	cursor = storage.AnalyticsExportCursor{
+		Sink:          e.sink.Name(),
+		LastUpdatedAt: pgtype.Timestamptz{Time: time.Unix(0, 0).UTC(), Valid: true},
+		LastID:        uuid.Nil,
+	}
+	if e.config.Backfill {
+		e.log.Info().Msg("analytics export backfilling existing delivery logs")
+		return cursor, nil
+	}
+	cursor.LastUpdatedAt.Time = start
+	return cursor, e.saveCursor(ctx, cursor)
+}
+
+func (e *Exporter) saveCursor(ctx context.Context, cursor storage.AnalyticsExportCursor) error {
+	if err := e.queries.UpsertAnalyticsExportCursor(ctx, storage.UpsertAnalyticsExportCursorParams{
+		Sink:          cursor.Sink,
+		LastUpdatedAt: cursor.LastUpdatedAt,
+		LastID:        cursor.LastID,
+	}); err != nil {
+		return fmt.Errorf("save cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SinkConfig describes the analytics store events are written to.
+type SinkConfig struct {
+	// Type is SinkClickHouse or SinkKafka.
+	Type string
+	// URL is the ClickHouse HTTP interface or the Kafka REST proxy.
+	URL string
+	// Database and Table name the ClickHouse table.
+	Database string
+	Table    string
+	// Username and Password authenticate to ClickHouse.
+	Username string
+	Password string
+	// Topic is the Kafka topic.
+	Topic string
+	// Timeout bounds each write.
+	Timeout time.Duration
+}
+
+// NewSink creates the sink described by cfg.
+func NewSink(cfg SinkConfig) (Sink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%s sink: url must be an http(s) URL", cfg.Type)
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	switch cfg.Type {
+	case SinkClickHouse:
+		if cfg.Table == "" {
+			return nil, fmt.Errorf("clickhouse sink: table is required")
+		}
+		return &ClickHouseSink{config: cfg, client: client}, nil
+	case SinkKafka:
+		if cfg.Topic == "" {
+			return nil, fmt.Errorf("kafka sink: topic is required")
+		}
+		return &KafkaSink{config: cfg, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown analytics sink %q", cfg.Type)
+	}
+}
+
+// ClickHouseSink inserts events through the ClickHouse HTTP interface in
+// JSONEachRow format.
+type ClickHouseSink struct {
+	config SinkConfig
+	client *http.Client
+}
+
+// Name implements Sink.
+func (s *ClickHouseSink) Name() string {
+	return SinkClickHouse + ":" + s.table()
+}
+
+func (s *ClickHouseSink) table() string {
+	if s.config.Database == "" {
+		return s.config.Table
+	}
+	return s.config.Database + "." + s.config.Table
+}
+
+// Write implements Sink.
+func (s *ClickHouseSink) Write(ctx context.Context, events []Event) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("clickhouse: encode event %s: %w", e.ID, err)
+		}
+	}
+
+	q := url.Values{}
+	q.Set("query", "INSERT INTO "+s.table()+" FORMAT JSONEachRow")
+	q.Set("date_time_input_format", "best_effort")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.config.URL, "/")+"/?"+q.Encode(), &body)
+	if err != nil {
+		return fmt.Errorf("clickhouse: build request: %w", err)
+	}
+	if s.config.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.config.Username)
+		req.Header.Set("X-ClickHouse-Key", s.config.Password)
+	}
+	_, err = send(s.client, req, "clickhouse")
+	return err
+}
+
+// KafkaSink produces events to a Kafka topic through a Confluent-compatible
+// REST proxy. Events are keyed by message ID, so the versions of an attempt
+// land on one partition in order.
+type KafkaSink struct {
+	config SinkConfig
+	client *http.Client
+}
+
+// Name implements Sink.
+func (s *KafkaSink) Name() string {
+	return SinkKafka + ":" + s.config.Topic
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// Write implements Sink.
+func (s *KafkaSink) Write(ctx context.Context, events []Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, e := range events {
+		records[i] = kafkaRecord{Key: e.MessageID.String(), Value: e}
+	}
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return fmt.Errorf("kafka: marshal records: %w", err)
+	}
+
+	endpoint := strings.TrimRight(s.config.URL, "/") + "/topics/" + url.PathEscape(s.config.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("kafka: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := send(s.client, req, "kafka")
+	if err != nil {
+		return err
+	}
+
+	// The proxy answers 200 even when single records fail; each record's
+	// outcome is reported in offsets.
+	var produced struct {
+		Offsets []struct {
+			Error string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(resp, &produced); err != nil {
+		return fmt.Errorf("kafka: decode response: %w", err)
+	}
+	for _, o := range produced.Offsets {
+		if o.Error != "" {
+			return fmt.Errorf("kafka: produce: %s", o.Error)
+		}
+	}
+	return nil
+}
+
+// maxResponseBody bounds the sink responses that are read.
+const maxResponseBody = 1 << 20
+
+// send performs req and returns the response body, treating any non-2xx
+// response as a failure. name prefixes returned errors.
+func send(client *http.Client, req *http.Request, name string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: send request: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, fmt.Errorf("%s: read response: %w", name, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, fmt.Errorf("%s: unexpected status %d: %s", name, resp.StatusCode, msg)
+	}
+	return body, nil
+}
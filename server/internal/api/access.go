@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+)
+
+// AccessRule restricts the routes under PathPrefix to clients from
+// AllowCIDRs and, when RequireClientCert is set, to clients that presented
+// a verified TLS certificate.
+type AccessRule struct {
+	PathPrefix        string
+	AllowCIDRs        []netip.Prefix
+	RequireClientCert bool
+}
+
+// NewAccessRule parses an access rule. cidrs may contain single addresses,
+// which are treated as /32 or /128 networks.
+func NewAccessRule(pathPrefix string, cidrs []string, requireClientCert bool) (AccessRule, error) {
+	if !strings.HasPrefix(pathPrefix, "/") {
+		return AccessRule{}, fmt.Errorf("access rule path_prefix %q must start with /", pathPrefix)
+	}
+	prefixes, err := ParseCIDRs(cidrs)
+	if err != nil {
+		return AccessRule{}, fmt.Errorf("access rule %s: %w", pathPrefix, err)
+	}
+	return AccessRule{PathPrefix: pathPrefix, AllowCIDRs: prefixes, RequireClientCert: requireClientCert}, nil
+}
+
+// ParseCIDRs parses networks in CIDR notation. Single addresses are
+// treated as /32 or /128 networks.
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", c)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", c)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// allows reports whether addr is in the rule's allowlist.
+func (a AccessRule) allows(addr netip.Addr) bool {
+	return len(a.AllowCIDRs) == 0 || inPrefixes(addr, a.AllowCIDRs)
+}
+
+// AccessControlMiddleware enforces rules on each request using the rule
+// with the longest PathPrefix matching the request path. Requests from a
+// client outside the allowlist, or without a verified client certificate
+// where one is required, are rejected with 403.
+//
+// The client address is the TCP peer: the allowlist is meant for API
+// servers exposed without a proxy in front, so forwarding headers are not
+// trusted.
+func AccessControlMiddleware(rules []AccessRule, log zerolog.Logger) func(http.Handler) http.Handler {
+	sorted := append([]AccessRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].PathPrefix) > len(sorted[j].PathPrefix)
+	})
+
+	return func(next http.Handler) http.Handler {
+		if len(sorted) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var rule *AccessRule
+			for i := range sorted {
+				if strings.HasPrefix(r.URL.Path, sorted[i].PathPrefix) {
+					rule = &sorted[i]
+					break
+				}
+			}
+			if rule == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			addr, ok := peerAddr(r)
+			if !ok || !rule.allows(addr) {
+				log.Warn().
+					Str("path", r.URL.Path).
+					Str("remote_addr", r.RemoteAddr).
+					Str("rule", rule.PathPrefix).
+					Msg("request rejected by IP allowlist")
+				respondErrorCode(w, http.StatusForbidden, apierror.CodeIPNotAllowed, "access denied")
+				return
+			}
+			if rule.RequireClientCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+				log.Warn().
+					Str("path", r.URL.Path).
+					Str("remote_addr", r.RemoteAddr).
+					Str("rule", rule.PathPrefix).
+					Msg("request rejected: client certificate required")
+				respondErrorCode(w, http.StatusForbidden, apierror.CodeClientCertRequired, "client certificate required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// peerAddr returns the address of the request's TCP peer.
+func peerAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewAccessRule(t *testing.T) {
+	rule, err := NewAccessRule("/api/v1/webhooks/", []string{"10.0.0.0/8", "192.0.2.7", "2001:db8::/32"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rule.AllowCIDRs) != 3 || rule.AllowCIDRs[1].String() != "192.0.2.7/32" {
+		t.Errorf("unexpected CIDRs %v", rule.AllowCIDRs)
+	}
+
+	for _, tt := range []struct {
+		prefix string
+		cidrs  []string
+	}{
+		{"api/v1", nil},
+		{"/api", []string{"10.0.0.0/33"}},
+		{"/api", []string{"not-an-ip"}},
+	} {
+		if _, err := NewAccessRule(tt.prefix, tt.cidrs, false); err == nil {
+			t.Errorf("expected error for %q %v", tt.prefix, tt.cidrs)
+		}
+	}
+}
+
+func TestAccessControlMiddleware(t *testing.T) {
+	webhooks, _ := NewAccessRule("/api/v1/webhooks/", []string{"10.0.0.0/8"}, false)
+	sendgrid, _ := NewAccessRule("/api/v1/webhooks/sendgrid", []string{"192.0.2.0/24"}, false)
+	admin, _ := NewAccessRule("/api/v1/groups", nil, true)
+
+	handler := AccessControlMiddleware([]AccessRule{webhooks, sendgrid, admin}, zerolog.Nop())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{}}}
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		tls        *tls.ConnectionState
+		wantCode   int
+	}{
+		{"no rule", "/healthz", "203.0.113.1:1234", nil, http.StatusOK},
+		{"allowed", "/api/v1/webhooks/ses", "10.1.2.3:1234", nil, http.StatusOK},
+		{"denied", "/api/v1/webhooks/ses", "203.0.113.1:1234", nil, http.StatusForbidden},
+		{"longest prefix wins", "/api/v1/webhooks/sendgrid", "10.1.2.3:1234", nil, http.StatusForbidden},
+		{"longest prefix allowed", "/api/v1/webhooks/sendgrid", "192.0.2.9:1234", nil, http.StatusOK},
+		{"ipv4-mapped", "/api/v1/webhooks/ses", "[::ffff:10.1.2.3]:1234", nil, http.StatusOK},
+		{"cert required", "/api/v1/groups", "10.1.2.3:1234", nil, http.StatusForbidden},
+		{"unverified cert", "/api/v1/groups", "10.1.2.3:1234", &tls.ConnectionState{}, http.StatusForbidden},
+		{"verified cert", "/api/v1/groups/x", "10.1.2.3:1234", verified, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.TLS = tt.tls
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+		})
+	}
+}
@@ -1,17 +1,20 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
+// Legacy account endpoints (see registerLegacyRoutes). An account is an
+// SMTP user; its name is the username SMTP clients authenticate with.
+
 // accountRequest is the JSON body for creating or updating an account.
 type accountRequest struct {
 	Name           string   `json:"name"`
@@ -31,51 +34,33 @@ type accountResponse struct {
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
-// toAccountResponse converts a storage.Account to an accountResponse.
+// toAccountResponse converts an SMTP user to an accountResponse.
 // If includeAPIKey is true, the api_key field is populated.
-func toAccountResponse(a storage.Account, includeAPIKey bool) accountResponse {
-	domains := decodeDomains(a.AllowedDomains)
-
+func toAccountResponse(u storage.User, includeAPIKey bool) accountResponse {
 	resp := accountResponse{
-		ID:             a.ID,
-		Name:           a.Name,
-		Email:          a.Email,
-		AllowedDomains: domains,
-		CreatedAt:      timestampToTime(a.CreatedAt),
-		UpdatedAt:      timestampToTime(a.UpdatedAt),
+		ID:             u.ID,
+		Name:           u.Username.String,
+		Email:          u.Email,
+		AllowedDomains: decodeDomains(u.AllowedDomains),
+		CreatedAt:      timestampToTime(u.CreatedAt),
+		UpdatedAt:      timestampToTime(u.UpdatedAt),
 	}
 	if includeAPIKey {
-		resp.APIKey = a.ApiKey
+		resp.APIKey = u.ApiKey.String
 	}
 	return resp
 }
 
-// timestampToTime converts a pgtype.Timestamptz to time.Time.
-// Returns zero time if the timestamp is not valid.
-func timestampToTime(ts pgtype.Timestamptz) time.Time {
-	if ts.Valid {
-		return ts.Time
-	}
-	return time.Time{}
-}
-
-// decodeDomains unmarshals a JSON byte slice into a string slice.
-// Returns an empty slice on failure or nil input.
-func decodeDomains(data []byte) []string {
-	if len(data) == 0 {
-		return []string{}
-	}
-	var domains []string
-	if err := json.Unmarshal(data, &domains); err != nil {
-		return []string{}
-	}
-	return domains
-}
-
 // CreateAccountHandler handles POST /api/v1/accounts.
-// This endpoint does NOT require authentication.
-func CreateAccountHandler(queries storage.Querier) http.HandlerFunc {
+// Creates an SMTP user in the caller's group. Requires owner or admin role.
+func CreateAccountHandler(queries storage.Querier, passwords *auth.PasswordPolicy, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if !isGroupAdmin(r) || groupID == uuid.Nil {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
 		var req accountRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			respondError(w, http.StatusBadRequest, "invalid request body")
@@ -97,75 +82,83 @@ func CreateAccountHandler(queries storage.Querier) http.HandlerFunc {
 			respondValidationErrors(w, errs)
 			return
 		}
+		if !checkPassword(w, r, passwords, req.Password) {
+			return
+		}
 
-		// Hash password
 		hash, err := auth.HashPassword(req.Password)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
-
-		// Generate API key
 		apiKey, err := auth.GenerateAPIKey()
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
 		}
-
-		// Marshal allowed domains
-		domains := req.AllowedDomains
-		if domains == nil {
-			domains = []string{}
-		}
-		domainsJSON, err := json.Marshal(domains)
+		domainsJSON, err := json.Marshal(accountDomains(req.AllowedDomains))
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
-		account, err := queries.CreateAccount(r.Context(), storage.CreateAccountParams{
-			Name:           req.Name,
+		user, err := queries.CreateUser(r.Context(), storage.CreateUserParams{
 			Email:          req.Email,
 			PasswordHash:   hash,
+			AccountType:    "smtp",
+			Username:       sql.NullString{String: req.Name, Valid: true},
+			ApiKey:         sql.NullString{String: apiKey, Valid: true},
 			AllowedDomains: domainsJSON,
-			ApiKey:         apiKey,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusConflict, "account name or email already in use")
 			return
 		}
+		if _, err := queries.CreateGroupMember(r.Context(), storage.CreateGroupMemberParams{
+			GroupID: groupID,
+			UserID:  user.ID,
+			Role:    "member",
+		}); err != nil {
+			_ = queries.DeleteUser(r.Context(), user.ID)
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateUser, "user", user.ID.String(), map[string]interface{}{
+				"email":        req.Email,
+				"account_type": "smtp",
+				"legacy_api":   true,
+			})
+		}
 
-		respondJSON(w, http.StatusCreated, toAccountResponse(account, true))
+		respondJSON(w, http.StatusCreated, toAccountResponse(user, true))
 	}
 }
 
 // GetAccountHandler handles GET /api/v1/accounts/{id}.
 func GetAccountHandler(queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, "invalid account ID format")
-			return
-		}
-
-		account, err := queries.GetAccountByID(r.Context(), id)
-		if err != nil {
-			respondError(w, http.StatusNotFound, "account not found")
+		user, ok := legacyAccount(w, r, queries)
+		if !ok {
 			return
 		}
-
-		respondJSON(w, http.StatusOK, toAccountResponse(account, false))
+		respondJSON(w, http.StatusOK, toAccountResponse(user, false))
 	}
 }
 
 // UpdateAccountHandler handles PUT /api/v1/accounts/{id}.
-func UpdateAccountHandler(queries storage.Querier) http.HandlerFunc {
+// Replaces the email and allowed domains of the account; an empty email
+// keeps the current one. The name cannot be changed, as SMTP clients
+// authenticate with it. Requires owner or admin role.
+func UpdateAccountHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, "invalid account ID format")
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		user, ok := legacyAccount(w, r, queries)
+		if !ok {
 			return
 		}
 
@@ -174,48 +167,97 @@ func UpdateAccountHandler(queries storage.Querier) http.HandlerFunc {
 			respondError(w, http.StatusBadRequest, "invalid request body")
 			return
 		}
-
-		// Marshal allowed domains
-		domains := req.AllowedDomains
-		if domains == nil {
-			domains = []string{}
+		if req.Name != "" && req.Name != user.Username.String {
+			respondValidationErrors(w, []string{"name cannot be changed; create a new account instead"})
+			return
 		}
-		domainsJSON, err := json.Marshal(domains)
+		email := req.Email
+		if email == "" {
+			email = user.Email
+		}
+		domainsJSON, err := json.Marshal(accountDomains(req.AllowedDomains))
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
-		account, err := queries.UpdateAccount(r.Context(), storage.UpdateAccountParams{
-			ID:             id,
-			Name:           req.Name,
-			Email:          req.Email,
+		updated, err := queries.UpdateUser(r.Context(), storage.UpdateUserParams{
+			ID:             user.ID,
+			Email:          email,
+			Status:         user.Status,
 			AllowedDomains: domainsJSON,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusConflict, "email already in use")
 			return
 		}
 
-		respondJSON(w, http.StatusOK, toAccountResponse(account, false))
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, "admin.update_user", "user", user.ID.String(), map[string]interface{}{
+				"legacy_api": true,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toAccountResponse(updated, false))
 	}
 }
 
 // DeleteAccountHandler handles DELETE /api/v1/accounts/{id}.
-func DeleteAccountHandler(queries storage.Querier) http.HandlerFunc {
+// Deletes the SMTP user and its group memberships. Requires owner or
+// admin role.
+func DeleteAccountHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, "invalid account ID format")
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		user, ok := legacyAccount(w, r, queries)
+		if !ok {
 			return
 		}
 
-		if err := queries.DeleteAccount(r.Context(), id); err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+		_ = queries.DeleteGroupMembersByUserID(r.Context(), user.ID)
+		if err := queries.DeleteUser(r.Context(), user.ID); err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, "admin.delete_user", "user", user.ID.String(), map[string]interface{}{
+				"legacy_api": true,
+			})
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// legacyAccount loads the account named by the {id} URL parameter. Users
+// that are not SMTP users, and accounts outside the caller's groups, are
+// reported as not found.
+func legacyAccount(w http.ResponseWriter, r *http.Request, queries storage.Querier) (storage.User, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid account ID format")
+		return storage.User{}, false
+	}
+	user, err := queries.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondStorageError(w, err, http.StatusNotFound, "account not found")
+		return storage.User{}, false
+	}
+	if user.AccountType != "smtp" || !canAccessUser(r.Context(), queries, user.ID) {
+		respondError(w, http.StatusNotFound, "account not found")
+		return storage.User{}, false
+	}
+	return user, true
+}
+
+// accountDomains returns domains, or an empty list when nil, as the
+// legacy API always stored a list.
+func accountDomains(domains []string) []string {
+	if domains == nil {
+		return []string{}
+	}
+	return domains
+}
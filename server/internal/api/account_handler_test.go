@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -14,11 +15,50 @@ import (
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
+var accountCallerID = uuid.MustParse("00000000-0000-0000-0000-0000000000a2")
+
+// testSMTPAccount returns an SMTP user as served by the legacy account API.
+func testSMTPAccount() storage.User {
+	u := testUser()
+	u.AccountType = "smtp"
+	u.Username = sql.NullString{String: "test-account", Valid: true}
+	u.AllowedDomains = []byte(`["example.com"]`)
+	return u
+}
+
+func accountHTTPRequest(method, target, id, body, role string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rctx := chi.NewRouteContext()
+	if id != "" {
+		rctx.URLParams.Add("id", id)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, accountCallerID, testGroup().ID, role, "organization")
+	return req.WithContext(ctx)
+}
+
+// accountMock serves account from GetUserByID as a member of the test group.
+func accountMock(account storage.User) *mockQuerier {
+	return &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			if id != account.ID {
+				return storage.User{}, sql.ErrNoRows
+			}
+			return account, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+	}
+}
+
 func TestCreateAccountHandler_Valid(t *testing.T) {
+	var member storage.CreateGroupMemberParams
 	mock := &mockQuerier{
-		createAccountFn: func(ctx context.Context, arg storage.CreateAccountParams) (storage.Account, error) {
-			if arg.Name != "testuser" {
-				t.Errorf("expected name testuser, got %s", arg.Name)
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			if arg.AccountType != "smtp" || arg.Username.String != "testuser" {
+				t.Errorf("expected smtp user testuser, got %s %q", arg.AccountType, arg.Username.String)
 			}
 			if arg.Email != "test@example.com" {
 				t.Errorf("expected email test@example.com, got %s", arg.Email)
@@ -26,33 +66,38 @@ func TestCreateAccountHandler_Valid(t *testing.T) {
 			if arg.PasswordHash == "" {
 				t.Error("expected non-empty password hash")
 			}
-			if arg.ApiKey == "" {
+			if !arg.ApiKey.Valid || arg.ApiKey.String == "" {
 				t.Error("expected non-empty API key")
 			}
-			return testAccount(), nil
+			return testSMTPAccount(), nil
+		},
+		createGroupMemberFn: func(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error) {
+			member = arg
+			return storage.GroupMember{}, nil
 		},
 	}
 
 	body := `{"name":"testuser","email":"test@example.com","password":"secret123","allowed_domains":["example.com"]}`
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
-
-	handler := CreateAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
+	CreateAccountHandler(mock, nil, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodPost, "/api/v1/accounts", "", body, "admin"))
 
 	if rec.Code != http.StatusCreated {
-		t.Fatalf("expected status 201, got %d", rec.Code)
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if member.GroupID != testGroup().ID || member.UserID != testSMTPAccount().ID || member.Role != "member" {
+		t.Errorf("group membership = %+v, want a member of the caller's group", member)
 	}
 
 	var resp accountResponse
 	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
-
 	if resp.Name != "test-account" {
 		t.Errorf("expected name test-account, got %s", resp.Name)
 	}
+	if len(resp.AllowedDomains) != 1 || resp.AllowedDomains[0] != "example.com" {
+		t.Errorf("expected allowed_domains [example.com], got %v", resp.AllowedDomains)
+	}
 	// API key should be included in creation response
 	if resp.APIKey == "" {
 		t.Error("expected api_key in creation response")
@@ -74,12 +119,8 @@ func TestCreateAccountHandler_MissingFields(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts", strings.NewReader(tc.body))
-			req.Header.Set("Content-Type", "application/json")
 			rec := httptest.NewRecorder()
-
-			handler := CreateAccountHandler(mock)
-			handler.ServeHTTP(rec, req)
+			CreateAccountHandler(mock, nil, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodPost, "/api/v1/accounts", "", tc.body, "owner"))
 
 			if rec.Code != http.StatusBadRequest {
 				t.Fatalf("expected status 400, got %d", rec.Code)
@@ -96,56 +137,54 @@ func TestCreateAccountHandler_MissingFields(t *testing.T) {
 	}
 }
 
-func TestCreateAccountHandler_DuplicateName(t *testing.T) {
+func TestCreateAccountHandler_MemberForbidden(t *testing.T) {
 	mock := &mockQuerier{
-		createAccountFn: func(ctx context.Context, arg storage.CreateAccountParams) (storage.Account, error) {
-			return storage.Account{}, errors.New("duplicate key value")
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			t.Error("CreateUser should not be called")
+			return storage.User{}, nil
 		},
 	}
 
-	body := `{"name":"existing","email":"a@b.com","password":"pass1234"}`
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	body := `{"name":"testuser","email":"test@example.com","password":"secret123"}`
 	rec := httptest.NewRecorder()
+	CreateAccountHandler(mock, nil, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodPost, "/api/v1/accounts", "", body, "member"))
 
-	handler := CreateAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
-
-	if rec.Code != http.StatusInternalServerError {
-		t.Fatalf("expected status 500, got %d", rec.Code)
-	}
-
-	var resp map[string]string
-	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
-	// Must NOT expose internal error details
-	if resp["error"] != "internal server error" {
-		t.Errorf("expected generic error, got %s", resp["error"])
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
 	}
 }
 
-func TestGetAccountHandler_Found(t *testing.T) {
-	acct := testAccount()
+func TestCreateAccountHandler_MembershipFailureRollsBack(t *testing.T) {
+	var deleted uuid.UUID
 	mock := &mockQuerier{
-		getAccountByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Account, error) {
-			if id != acct.ID {
-				t.Errorf("expected ID %s, got %s", acct.ID, id)
-			}
-			return acct, nil
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			return testSMTPAccount(), nil
+		},
+		createGroupMemberFn: func(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error) {
+			return storage.GroupMember{}, errors.New("connection reset")
+		},
+		deleteUserFn: func(ctx context.Context, id uuid.UUID) error {
+			deleted = id
+			return nil
 		},
 	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/"+acct.ID.String(), nil)
+	body := `{"name":"testuser","email":"test@example.com","password":"secret123"}`
 	rec := httptest.NewRecorder()
+	CreateAccountHandler(mock, nil, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodPost, "/api/v1/accounts", "", body, "admin"))
 
-	// Set up chi URL parameter
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", acct.ID.String())
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rec.Code)
+	}
+	if deleted != testSMTPAccount().ID {
+		t.Errorf("deleted user %v, want the new account", deleted)
+	}
+}
 
-	handler := GetAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
+func TestGetAccountHandler_Valid(t *testing.T) {
+	account := testSMTPAccount()
+	rec := httptest.NewRecorder()
+	GetAccountHandler(accountMock(account)).ServeHTTP(rec, accountHTTPRequest(http.MethodGet, "/api/v1/accounts/"+account.ID.String(), account.ID.String(), "", "member"))
 
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", rec.Code)
@@ -155,33 +194,39 @@ func TestGetAccountHandler_Found(t *testing.T) {
 	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
-
-	// Must NOT expose api_key in get response
+	if resp.ID != account.ID || resp.Name != "test-account" {
+		t.Errorf("unexpected account %+v", resp)
+	}
+	// API key should NOT be included in get response
 	if resp.APIKey != "" {
 		t.Error("api_key should not be included in get response")
 	}
-	if resp.Name != acct.Name {
-		t.Errorf("expected name %s, got %s", acct.Name, resp.Name)
+}
+
+func TestGetAccountHandler_NotSMTPUser(t *testing.T) {
+	user := testUser()
+	rec := httptest.NewRecorder()
+	GetAccountHandler(accountMock(user)).ServeHTTP(rec, accountHTTPRequest(http.MethodGet, "/api/v1/accounts/"+user.ID.String(), user.ID.String(), "", "owner"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
 	}
 }
 
-func TestGetAccountHandler_NotFound(t *testing.T) {
-	mock := &mockQuerier{
-		getAccountByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Account, error) {
-			return storage.Account{}, errNotFound
-		},
+func TestGetAccountHandler_OtherGroup(t *testing.T) {
+	account := testSMTPAccount()
+	other := testGroup()
+	other.ID = uuid.New()
+	mock := accountMock(account)
+	mock.listGroupsByUserIDFn = func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+		return []storage.Group{other}, nil
+	}
+	mock.getGroupByIDFn = func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+		return other, nil
 	}
 
-	id := uuid.New()
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/"+id.String(), nil)
 	rec := httptest.NewRecorder()
-
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", id.String())
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
-	handler := GetAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
+	GetAccountHandler(mock).ServeHTTP(rec, accountHTTPRequest(http.MethodGet, "/api/v1/accounts/"+account.ID.String(), account.ID.String(), "", "owner"))
 
 	if rec.Code != http.StatusNotFound {
 		t.Fatalf("expected status 404, got %d", rec.Code)
@@ -189,104 +234,69 @@ func TestGetAccountHandler_NotFound(t *testing.T) {
 }
 
 func TestGetAccountHandler_InvalidUUID(t *testing.T) {
-	mock := &mockQuerier{}
-
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/not-a-uuid", nil)
 	rec := httptest.NewRecorder()
-
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "not-a-uuid")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
-	handler := GetAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
+	GetAccountHandler(&mockQuerier{}).ServeHTTP(rec, accountHTTPRequest(http.MethodGet, "/api/v1/accounts/not-a-uuid", "not-a-uuid", "", "owner"))
 
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("expected status 400, got %d", rec.Code)
 	}
 }
 
-func TestUpdateAccountHandler(t *testing.T) {
-	acct := testAccount()
-	mock := &mockQuerier{
-		updateAccountFn: func(ctx context.Context, arg storage.UpdateAccountParams) (storage.Account, error) {
-			if arg.ID != acct.ID {
-				t.Errorf("expected ID %s, got %s", acct.ID, arg.ID)
-			}
-			updated := acct
-			updated.Name = arg.Name
-			updated.Email = arg.Email
-			return updated, nil
-		},
+func TestUpdateAccountHandler_Valid(t *testing.T) {
+	account := testSMTPAccount()
+	var got storage.UpdateUserParams
+	mock := accountMock(account)
+	mock.updateUserFn = func(ctx context.Context, arg storage.UpdateUserParams) (storage.User, error) {
+		got = arg
+		updated := account
+		updated.AllowedDomains = arg.AllowedDomains
+		return updated, nil
 	}
 
-	body := `{"name":"updated-name","email":"updated@example.com","allowed_domains":["new.com"]}`
-	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/"+acct.ID.String(), strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	body := `{"allowed_domains":["example.org"]}`
 	rec := httptest.NewRecorder()
-
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", acct.ID.String())
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
-	handler := UpdateAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
+	UpdateAccountHandler(mock, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodPut, "/api/v1/accounts/"+account.ID.String(), account.ID.String(), body, "admin"))
 
 	if rec.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", rec.Code)
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
 	}
-
-	var resp accountResponse
-	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
-	if resp.Name != "updated-name" {
-		t.Errorf("expected name updated-name, got %s", resp.Name)
+	if got.Email != account.Email || got.Status != account.Status || string(got.AllowedDomains) != `["example.org"]` {
+		t.Errorf("UpdateUser(%+v), want the current email and status with the new domains", got)
 	}
 }
 
-func TestDeleteAccountHandler(t *testing.T) {
-	id := uuid.New()
-	deleteCalled := false
-	mock := &mockQuerier{
-		deleteAccountFn: func(ctx context.Context, delID uuid.UUID) error {
-			deleteCalled = true
-			if delID != id {
-				t.Errorf("expected ID %s, got %s", id, delID)
-			}
-			return nil
-		},
+func TestUpdateAccountHandler_NameChange(t *testing.T) {
+	account := testSMTPAccount()
+	mock := accountMock(account)
+	mock.updateUserFn = func(ctx context.Context, arg storage.UpdateUserParams) (storage.User, error) {
+		t.Error("UpdateUser should not be called")
+		return account, nil
 	}
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/accounts/"+id.String(), nil)
 	rec := httptest.NewRecorder()
+	UpdateAccountHandler(mock, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodPut, "/api/v1/accounts/"+account.ID.String(), account.ID.String(), `{"name":"renamed"}`, "admin"))
 
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", id.String())
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-
-	handler := DeleteAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
-
-	if rec.Code != http.StatusNoContent {
-		t.Fatalf("expected status 204, got %d", rec.Code)
-	}
-	if !deleteCalled {
-		t.Error("expected delete to be called")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
 	}
 }
 
-func TestCreateAccountHandler_InvalidJSON(t *testing.T) {
-	mock := &mockQuerier{}
+func TestDeleteAccountHandler_Valid(t *testing.T) {
+	account := testSMTPAccount()
+	var deleted uuid.UUID
+	mock := accountMock(account)
+	mock.deleteUserFn = func(ctx context.Context, id uuid.UUID) error {
+		deleted = id
+		return nil
+	}
 
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts", strings.NewReader("not json"))
-	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
+	DeleteAccountHandler(mock, nil).ServeHTTP(rec, accountHTTPRequest(http.MethodDelete, "/api/v1/accounts/"+account.ID.String(), account.ID.String(), "", "owner"))
 
-	handler := CreateAccountHandler(mock)
-	handler.ServeHTTP(rec, req)
-
-	if rec.Code != http.StatusBadRequest {
-		t.Fatalf("expected status 400, got %d", rec.Code)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if deleted != account.ID {
+		t.Errorf("deleted %v, want %v", deleted, account.ID)
 	}
 }
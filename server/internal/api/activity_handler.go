@@ -8,7 +8,6 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
-	"github.com/sungwon/smtp-proxy/server/internal/auth"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -76,9 +75,7 @@ func ListActivityLogsHandler(queries storage.Querier) http.HandlerFunc {
 		}
 
 		// Verify access
-		callerGroupID := auth.GroupIDFromContext(r.Context())
-		callerGroupType := auth.GroupTypeFromContext(r.Context())
-		if callerGroupType != "system" && callerGroupID != groupID {
+		if !canAccessGroup(r.Context(), queries, groupID) {
 			respondError(w, http.StatusForbidden, "access denied")
 			return
 		}
@@ -109,7 +106,7 @@ func ListActivityLogsHandler(queries storage.Querier) http.HandlerFunc {
 			Offset:  offset,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// alertChannelRequest is the JSON body for creating an alert channel.
+// Kinds defaults to all alert kinds and Enabled to true.
+type alertChannelRequest struct {
+	Type    string   `json:"type"`
+	Target  string   `json:"target"`
+	Kinds   []string `json:"kinds"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// alertChannelResponse is the JSON representation of an alert channel.
+// The target is intentionally excluded: webhook URLs and routing keys
+// grant access to the destination.
+type alertChannelResponse struct {
+	ID        uuid.UUID `json:"id"`
+	GroupID   uuid.UUID `json:"group_id"`
+	Type      string    `json:"type"`
+	Kinds     []string  `json:"kinds"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toAlertChannelResponse(ch storage.AlertChannel) alertChannelResponse {
+	kinds := []string{}
+	if len(ch.Kinds) > 0 {
+		_ = json.Unmarshal(ch.Kinds, &kinds)
+	}
+	return alertChannelResponse{
+		ID:        ch.ID,
+		GroupID:   ch.GroupID,
+		Type:      ch.ChannelType,
+		Kinds:     kinds,
+		Enabled:   ch.Enabled,
+		CreatedAt: ch.CreatedAt.Time,
+	}
+}
+
+// CreateAlertChannelHandler handles POST /api/v1/alert-channels.
+// Adds a Slack, PagerDuty or webhook destination for the operational
+// alerts raised about the authenticated user's group. Requires group
+// admin+ role.
+func CreateAlertChannelHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req alertChannelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+		req.Target = strings.TrimSpace(req.Target)
+
+		var errs []string
+		if _, err := notify.NewChannel(req.Type, req.Target, time.Second); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if err := notify.ValidateKinds(req.Kinds); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+		if req.Kinds == nil {
+			req.Kinds = []string{}
+		}
+		enabled := true
+		if req.Enabled != nil {
+			enabled = *req.Enabled
+		}
+
+		kindsJSON, err := json.Marshal(req.Kinds)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		ch, err := queries.CreateAlertChannel(r.Context(), storage.CreateAlertChannelParams{
+			GroupID:     groupID,
+			ChannelType: req.Type,
+			Target:      req.Target,
+			Kinds:       kindsJSON,
+			Enabled:     enabled,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateAlertChannel, "alert_channel", ch.ID.String(), map[string]interface{}{
+				"type":  ch.ChannelType,
+				"kinds": req.Kinds,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, toAlertChannelResponse(ch))
+	}
+}
+
+// ListAlertChannelsHandler handles GET /api/v1/alert-channels.
+func ListAlertChannelsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		channels, err := queries.ListAlertChannelsByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]alertChannelResponse, 0, len(channels))
+		for _, ch := range channels {
+			resp = append(resp, toAlertChannelResponse(ch))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DeleteAlertChannelHandler handles DELETE /api/v1/alert-channels/{id}.
+// Requires group admin+ role.
+func DeleteAlertChannelHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid alert channel ID format")
+			return
+		}
+
+		n, err := queries.DeleteAlertChannel(r.Context(), storage.DeleteAlertChannelParams{ID: id, GroupID: groupID})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "alert channel not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteAlertChannel, "alert_channel", id.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestCreateAlertChannelHandler(t *testing.T) {
+	var stored storage.CreateAlertChannelParams
+	mock := &mockQuerier{
+		createAlertChannelFn: func(_ context.Context, arg storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+			stored = arg
+			return storage.AlertChannel{ID: uuid.New(), GroupID: arg.GroupID, ChannelType: arg.ChannelType, Target: arg.Target, Kinds: arg.Kinds, Enabled: arg.Enabled}, nil
+		},
+	}
+
+	body := `{"type":"Slack","target":"https://hooks.slack.com/services/T/B/secret","kinds":["dlq_growth"]}`
+	rec := httptest.NewRecorder()
+	CreateAlertChannelHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodPost, "/api/v1/alert-channels", "", body))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if stored.GroupID != testGroup().ID || stored.ChannelType != "slack" || string(stored.Kinds) != `["dlq_growth"]` || !stored.Enabled {
+		t.Errorf("stored %+v", stored)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("response exposes the channel target: %s", rec.Body.String())
+	}
+
+	var resp alertChannelResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Type != "slack" || len(resp.Kinds) != 1 || resp.Kinds[0] != "dlq_growth" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestCreateAlertChannelHandler_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown type": `{"type":"sms","target":"+15550100"}`,
+		"bad url":      `{"type":"webhook","target":"not a url"}`,
+		"missing key":  `{"type":"pagerduty","target":""}`,
+		"unknown kind": `{"type":"pagerduty","target":"key","kinds":["disk_full"]}`,
+		"bad json":     `{`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock := &mockQuerier{
+				createAlertChannelFn: func(context.Context, storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+					t.Error("invalid channel was stored")
+					return storage.AlertChannel{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			CreateAlertChannelHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodPost, "/api/v1/alert-channels", "", body))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestListAlertChannelsHandler(t *testing.T) {
+	mock := &mockQuerier{
+		listAlertChannelsByGroupIDFn: func(_ context.Context, groupID uuid.UUID) ([]storage.AlertChannel, error) {
+			if groupID != testGroup().ID {
+				t.Errorf("listed group %s", groupID)
+			}
+			return []storage.AlertChannel{{ChannelType: "pagerduty", Target: "routing-key", Kinds: []byte(`[]`)}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListAlertChannelsHandler(mock).ServeHTTP(rec, scriptRequestWithID(http.MethodGet, "/api/v1/alert-channels", "", ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "routing-key") {
+		t.Errorf("response exposes the routing key: %s", rec.Body.String())
+	}
+	var resp []alertChannelResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Type != "pagerduty" || resp[0].Kinds == nil {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestDeleteAlertChannelHandler(t *testing.T) {
+	id := uuid.NewString()
+	mock := &mockQuerier{
+		deleteAlertChannelFn: func(context.Context, storage.DeleteAlertChannelParams) (int64, error) {
+			return 0, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	DeleteAlertChannelHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodDelete, "/api/v1/alert-channels/"+id, id, ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a channel of another group, got %d", rec.Code)
+	}
+
+	mock.deleteAlertChannelFn = nil
+	rec = httptest.NewRecorder()
+	DeleteAlertChannelHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodDelete, "/api/v1/alert-channels/"+id, id, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+}
+
+func TestAlertChannelHandlers_RequireGroupAdmin(t *testing.T) {
+	id := uuid.NewString()
+	body := `{"type":"webhook","target":"https://ops.example.com/alerts"}`
+	mock := &mockQuerier{
+		createAlertChannelFn: func(context.Context, storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+			t.Error("channel created")
+			return storage.AlertChannel{}, nil
+		},
+		deleteAlertChannelFn: func(context.Context, storage.DeleteAlertChannelParams) (int64, error) {
+			t.Error("channel deleted")
+			return 1, nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		req     *http.Request
+	}{
+		{"create", CreateAlertChannelHandler(mock, nil), scriptRequestWithID(http.MethodPost, "/api/v1/alert-channels", "", body)},
+		{"delete", DeleteAlertChannelHandler(mock, nil), scriptRequestWithID(http.MethodDelete, "/api/v1/alert-channels/"+id, id, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req.WithContext(setJWTContext(tt.req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status 403 for a member, got %d", rec.Code)
+			}
+		})
+	}
+}
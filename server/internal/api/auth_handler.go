@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -38,12 +41,44 @@ type switchGroupRequest struct {
 	GroupID string `json:"group_id"`
 }
 
+// groupMembership returns the user's membership of the group. A user who is
+// not a direct member inherits their membership of the nearest ancestor
+// group, so a company's owners and admins can work in any of its sub-groups.
+func groupMembership(ctx context.Context, queries storage.Querier, userID, groupID uuid.UUID) (storage.GroupMember, error) {
+	member, err := queries.GetGroupMemberByUserAndGroup(ctx, storage.GetGroupMemberByUserAndGroupParams{
+		UserID:  userID,
+		GroupID: groupID,
+	})
+	if err == nil {
+		return member, nil
+	}
+
+	ancestors, aerr := queries.ListGroupAncestors(ctx, groupID)
+	if aerr != nil {
+		return storage.GroupMember{}, err
+	}
+	for _, a := range ancestors {
+		if a.ID == groupID {
+			continue
+		}
+		if m, merr := queries.GetGroupMemberByUserAndGroup(ctx, storage.GetGroupMemberByUserAndGroupParams{
+			UserID:  userID,
+			GroupID: a.ID,
+		}); merr == nil {
+			return m, nil
+		}
+	}
+	return storage.GroupMember{}, err
+}
+
 // LoginHandler handles POST /api/v1/auth/login.
 // Authenticates a user by email and password, resolves group membership,
 // creates a session, and returns JWT tokens.
 // If group_id is provided, the user must be a member of that group.
 // If group_id is omitted, the first group membership is used.
-func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLogger *auth.AuditLogger, rateLimiter *auth.RateLimiter) http.HandlerFunc {
+// Repeated failures delay further attempts and then lock the account; see
+// LoginSecurity for unlock links and anomaly notices.
+func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLogger *auth.AuditLogger, rateLimiter *auth.RateLimiter, security LoginSecurity) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req loginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -65,14 +100,20 @@ func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLog
 				respondError(w, http.StatusTooManyRequests, "too many login attempts, try again later")
 				return
 			}
+			if wait, err := rateLimiter.LoginDelay(r.Context(), req.Email); err == nil && wait > 0 {
+				if auditLogger != nil {
+					auditLogger.LogAuthFailure(r.Context(), r, auth.AuditActionLoginFailed, "delayed")
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				respondError(w, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+				return
+			}
 		}
 
 		// Look up user by email
 		user, err := queries.GetUserByEmail(r.Context(), req.Email)
 		if err != nil {
-			if rateLimiter != nil {
-				_ = rateLimiter.RecordFailedLogin(r.Context(), req.Email)
-			}
+			recordLoginFailure(r, queries, jwtService, rateLimiter, security, auditLogger, req.Email, nil)
 			if auditLogger != nil {
 				auditLogger.LogAuthFailure(r.Context(), r, auth.AuditActionLoginFailed, "user not found")
 			}
@@ -92,9 +133,7 @@ func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLog
 		// Verify password
 		if err := auth.VerifyPassword(user.PasswordHash, req.Password); err != nil {
 			_ = queries.IncrementFailedAttempts(r.Context(), user.ID)
-			if rateLimiter != nil {
-				_ = rateLimiter.RecordFailedLogin(r.Context(), req.Email)
-			}
+			recordLoginFailure(r, queries, jwtService, rateLimiter, security, auditLogger, req.Email, &user)
 			if auditLogger != nil {
 				auditLogger.LogAuthFailure(r.Context(), r, auth.AuditActionLoginFailed, "invalid password")
 			}
@@ -115,11 +154,8 @@ func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLog
 				return
 			}
 
-			// Verify user is a member of this group
-			member, err := queries.GetGroupMemberByUserAndGroup(r.Context(), storage.GetGroupMemberByUserAndGroupParams{
-				UserID:  user.ID,
-				GroupID: gid,
-			})
+			// Verify user is a member of this group or one of its ancestors
+			member, err := groupMembership(r.Context(), queries, user.ID, gid)
 			if err != nil {
 				respondError(w, http.StatusForbidden, "user is not a member of the specified group")
 				return
@@ -128,7 +164,7 @@ func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLog
 			// Get group details for group_type
 			group, err := queries.GetGroupByID(r.Context(), gid)
 			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 				return
 			}
 
@@ -153,15 +189,21 @@ func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLog
 				GroupID: groupID,
 			})
 			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 				return
 			}
 			role = member.Role
 		}
 
+		groupPath, err := auth.GroupPath(r.Context(), queries, groupID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
 		// Create session
 		sessionID := uuid.New()
-		accessToken, err := jwtService.GenerateAccessToken(user.ID, groupID, user.Email, role, groupType)
+		accessToken, err := jwtService.GenerateAccessToken(user.ID, groupID, user.Email, role, groupType, groupPath)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
@@ -177,19 +219,24 @@ func LoginHandler(queries storage.Querier, jwtService *auth.JWTService, auditLog
 		refreshHash := hashToken(refreshToken)
 
 		expiresAt := time.Now().Add(7 * 24 * time.Hour)
+		userAgent, ip := sessionClient(r)
 		_, err = queries.CreateSession(r.Context(), storage.CreateSessionParams{
+			ID:               sessionID,
 			UserID:           user.ID,
 			GroupID:          groupID,
 			RefreshTokenHash: refreshHash,
 			ExpiresAt:        pgtype.Timestamptz{Time: expiresAt, Valid: true},
+			UserAgent:        userAgent,
+			IpAddress:        ip,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
 		// Update last login
 		_ = queries.UpdateUserLastLogin(r.Context(), user.ID)
+		checkLoginAnomaly(r, queries, security, auditLogger, user, groupID, ip)
 
 		// Clear failed login attempts
 		if rateLimiter != nil {
@@ -278,22 +325,32 @@ func RefreshHandler(queries storage.Querier, jwtService *auth.JWTService, auditL
 			return
 		}
 
-		member, err := queries.GetGroupMemberByUserAndGroup(r.Context(), storage.GetGroupMemberByUserAndGroupParams{
-			UserID:  user.ID,
-			GroupID: session.GroupID,
-		})
+		member, err := groupMembership(r.Context(), queries, user.ID, session.GroupID)
 		if err != nil {
 			respondError(w, http.StatusUnauthorized, "user is no longer a member of this group")
 			return
 		}
 
+		groupPath, err := auth.GroupPath(r.Context(), queries, session.GroupID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
 		// Generate new access token
-		accessToken, err := jwtService.GenerateAccessToken(user.ID, session.GroupID, user.Email, member.Role, group.GroupType)
+		accessToken, err := jwtService.GenerateAccessToken(user.ID, session.GroupID, user.Email, member.Role, group.GroupType, groupPath)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
+		userAgent, ip := sessionClient(r)
+		_ = queries.TouchSession(r.Context(), storage.TouchSessionParams{
+			ID:        sessionID,
+			UserAgent: userAgent,
+			IpAddress: ip,
+		})
+
 		if auditLogger != nil {
 			auditLogger.LogAuthAttempt(r.Context(), r, session.GroupID, user.ID, auth.AuditActionTokenRefresh)
 		}
@@ -393,11 +450,8 @@ func SwitchGroupHandler(queries storage.Querier, jwtService *auth.JWTService, au
 			return
 		}
 
-		// Verify user is a member of the target group
-		member, err := queries.GetGroupMemberByUserAndGroup(r.Context(), storage.GetGroupMemberByUserAndGroupParams{
-			UserID:  userID,
-			GroupID: targetGroupID,
-		})
+		// Verify user is a member of the target group or one of its ancestors
+		member, err := groupMembership(r.Context(), queries, userID, targetGroupID)
 		if err != nil {
 			respondError(w, http.StatusForbidden, "user is not a member of the specified group")
 			return
@@ -406,13 +460,19 @@ func SwitchGroupHandler(queries storage.Querier, jwtService *auth.JWTService, au
 		// Get group details
 		group, err := queries.GetGroupByID(r.Context(), targetGroupID)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "group not found")
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+
+		groupPath, err := auth.GroupPath(r.Context(), queries, targetGroupID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
 		// Create new session for the target group
 		sessionID := uuid.New()
-		accessToken, err := jwtService.GenerateAccessToken(user.ID, targetGroupID, user.Email, member.Role, group.GroupType)
+		accessToken, err := jwtService.GenerateAccessToken(user.ID, targetGroupID, user.Email, member.Role, group.GroupType, groupPath)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "internal server error")
 			return
@@ -426,14 +486,18 @@ func SwitchGroupHandler(queries storage.Querier, jwtService *auth.JWTService, au
 
 		refreshHash := hashToken(refreshToken)
 		expiresAt := time.Now().Add(7 * 24 * time.Hour)
+		userAgent, ip := sessionClient(r)
 		_, err = queries.CreateSession(r.Context(), storage.CreateSessionParams{
+			ID:               sessionID,
 			UserID:           user.ID,
 			GroupID:          targetGroupID,
 			RefreshTokenHash: refreshHash,
 			ExpiresAt:        pgtype.Timestamptz{Time: expiresAt, Valid: true},
+			UserAgent:        userAgent,
+			IpAddress:        ip,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
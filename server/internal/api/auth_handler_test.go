@@ -85,7 +85,13 @@ func TestLoginHandler_Success(t *testing.T) {
 			if arg.GroupID != groupID {
 				t.Errorf("expected session GroupID %s, got %s", groupID, arg.GroupID)
 			}
-			return storage.Session{ID: uuid.New()}, nil
+			if arg.UserAgent.String != "test-client/1.0" {
+				t.Errorf("expected session UserAgent test-client/1.0, got %q", arg.UserAgent.String)
+			}
+			if arg.IpAddress == nil || arg.IpAddress.String() != "192.0.2.1" {
+				t.Errorf("expected session IpAddress 192.0.2.1, got %v", arg.IpAddress)
+			}
+			return storage.Session{ID: arg.ID}, nil
 		},
 	}
 
@@ -98,9 +104,10 @@ func TestLoginHandler_Success(t *testing.T) {
 	body := `{"email":"test@example.com","password":"password123"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-client/1.0")
 	rec := httptest.NewRecorder()
 
-	handler := LoginHandler(mock, jwtSvc, nil, nil)
+	handler := LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
@@ -178,7 +185,7 @@ func TestLoginHandler_WithGroupID(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
-	handler := LoginHandler(mock, jwtSvc, nil, nil)
+	handler := LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
@@ -204,7 +211,7 @@ func TestLoginHandler_InvalidCredentials(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
-	handler := LoginHandler(mock, jwtSvc, nil, nil)
+	handler := LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusUnauthorized {
@@ -239,7 +246,7 @@ func TestLoginHandler_InactiveUser(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
-	handler := LoginHandler(mock, jwtSvc, nil, nil)
+	handler := LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusUnauthorized {
@@ -277,7 +284,7 @@ func TestLoginHandler_NoGroupMembership(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
-	handler := LoginHandler(mock, jwtSvc, nil, nil)
+	handler := LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusForbidden {
@@ -298,7 +305,7 @@ func TestLoginHandler_MissingFields(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
-	handler := LoginHandler(mock, jwtSvc, nil, nil)
+	handler := LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -586,6 +593,86 @@ func TestSwitchGroupHandler_NotMember(t *testing.T) {
 	}
 }
 
+func TestSwitchGroupHandler_InheritedMembership(t *testing.T) {
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	companyID := uuid.MustParse("00000000-0000-0000-0000-000000000010")
+	teamID := uuid.MustParse("00000000-0000-0000-0000-000000000020")
+
+	user := storage.User{
+		ID:          userID,
+		Email:       "test@example.com",
+		Status:      "active",
+		AccountType: "user",
+	}
+	company := storage.Group{ID: companyID, Name: "company", GroupType: "company", Status: "active"}
+	team := storage.Group{
+		ID:        teamID,
+		Name:      "team",
+		GroupType: "company",
+		Status:    "active",
+		ParentID:  pgtype.UUID{Bytes: companyID, Valid: true},
+	}
+
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return user, nil
+		},
+		getGroupMemberByUserAndGroupFn: func(ctx context.Context, arg storage.GetGroupMemberByUserAndGroupParams) (storage.GroupMember, error) {
+			// Only a member of the company, not of the team.
+			if arg.GroupID != companyID {
+				return storage.GroupMember{}, errNotFound
+			}
+			return storage.GroupMember{ID: uuid.New(), GroupID: companyID, UserID: userID, Role: "owner"}, nil
+		},
+		listGroupAncestorsFn: func(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{team, company}, nil
+		},
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return team, nil
+		},
+		createSessionFn: func(ctx context.Context, arg storage.CreateSessionParams) (storage.Session, error) {
+			return storage.Session{ID: uuid.New()}, nil
+		},
+	}
+
+	jwtSvc := auth.NewJWTService(auth.JWTConfig{
+		SigningKey:         "test-secret-key-that-is-long-enough-32",
+		AccessTokenExpiry:  15 * time.Minute,
+		RefreshTokenExpiry: 7 * 24 * time.Hour,
+	})
+
+	body := `{"group_id":"` + teamID.String() + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/switch-group", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	ctx := setJWTContext(req.Context(), userID, companyID, "owner", "company")
+	req = req.WithContext(ctx)
+
+	rec := httptest.NewRecorder()
+
+	handler := SwitchGroupHandler(mock, jwtSvc, nil)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp tokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	claims, err := jwtSvc.ValidateAccessToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if claims.Role != "owner" {
+		t.Errorf("expected inherited role owner, got %s", claims.Role)
+	}
+	if len(claims.GroupPath) != 2 || claims.GroupPath[0] != companyID.String() || claims.GroupPath[1] != teamID.String() {
+		t.Errorf("expected group path [company team], got %v", claims.GroupPath)
+	}
+}
+
 func TestSwitchGroupHandler_MissingGroupID(t *testing.T) {
 	mock := &mockQuerier{}
 	jwtSvc := auth.NewJWTService(auth.JWTConfig{
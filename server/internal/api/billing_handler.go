@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/billing"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// GetBillingUsageHandler handles GET /api/v1/billing/usage.
+// Exports per-group message count and bytes for one month, with a breakdown
+// by delivering provider, for billing systems.
+// Supports query params: month (YYYY-MM, default the current month),
+// group_id and format (json or csv, default json). Non-system callers see
+// their own group, or one of its sub-groups via group_id; system admins see
+// every group unless group_id is given.
+func GetBillingUsageHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		callerGroupID := auth.GroupIDFromContext(r.Context())
+		callerGroupType := auth.GroupTypeFromContext(r.Context())
+
+		groupID := uuid.Nil
+		if callerGroupType != "system" {
+			groupID = callerGroupID
+		}
+		if g := r.URL.Query().Get("group_id"); g != "" {
+			id, err := uuid.Parse(g)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid group_id format")
+				return
+			}
+			if !canAccessGroup(r.Context(), queries, id) {
+				respondError(w, http.StatusForbidden, "access denied")
+				return
+			}
+			groupID = id
+		}
+
+		month := time.Now().UTC()
+		if v := r.URL.Query().Get("month"); v != "" {
+			t, err := time.Parse(billing.MonthLayout, v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid month, expected YYYY-MM")
+				return
+			}
+			month = t
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			respondError(w, http.StatusBadRequest, "format must be one of: json, csv")
+			return
+		}
+
+		report, err := billing.Load(r.Context(), queries, month, groupID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="usage-`+report.Month+`.csv"`)
+			w.WriteHeader(http.StatusOK)
+			_ = report.WriteCSV(w)
+			return
+		}
+		respondJSON(w, http.StatusOK, report)
+	}
+}
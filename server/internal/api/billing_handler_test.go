@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/billing"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func billingMock(groupID, otherGroup uuid.UUID, gotFrom *time.Time) *mockQuerier {
+	return &mockQuerier{
+		monthlyMessageUsageFn: func(ctx context.Context, arg storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error) {
+			*gotFrom = arg.EnqueuedAt.Time
+			return []storage.MonthlyMessageUsageRow{
+				{GroupID: pgtype.UUID{Bytes: groupID, Valid: true}, GroupName: "acme", Messages: 2, TotalBytes: 2048},
+				{GroupID: pgtype.UUID{Bytes: otherGroup, Valid: true}, GroupName: "other", Messages: 7, TotalBytes: 7000},
+			}, nil
+		},
+		monthlyProviderUsageFn: func(ctx context.Context, arg storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error) {
+			return []storage.MonthlyProviderUsageRow{
+				{GroupID: pgtype.UUID{Bytes: groupID, Valid: true}, Provider: sql.NullString{String: "ses", Valid: true}, Messages: 2, TotalBytes: 2048},
+			}, nil
+		},
+	}
+}
+
+func TestGetBillingUsageHandler_JSON(t *testing.T) {
+	groupID := testGroup().ID
+	var gotFrom time.Time
+	mock := billingMock(groupID, uuid.New(), &gotFrom)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/billing/usage?month=2026-09", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, groupID, "member", "organization"))
+	rec := httptest.NewRecorder()
+
+	GetBillingUsageHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if want := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC); !gotFrom.Equal(want) {
+		t.Errorf("expected query from %v, got %v", want, gotFrom)
+	}
+
+	var resp billing.Report
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// Non-system callers only see their own group.
+	if resp.Month != "2026-09" || len(resp.Groups) != 1 {
+		t.Fatalf("unexpected report: %+v", resp)
+	}
+	g := resp.Groups[0]
+	if g.GroupID != groupID || g.Bytes != 2048 || len(g.Providers) != 1 || g.Providers[0].Provider != "ses" {
+		t.Errorf("unexpected group usage: %+v", g)
+	}
+}
+
+func TestGetBillingUsageHandler_CSV(t *testing.T) {
+	groupID := testGroup().ID
+	var gotFrom time.Time
+	mock := billingMock(groupID, uuid.New(), &gotFrom)
+
+	systemGroupID := uuid.MustParse("00000000-0000-0000-0000-000000000099")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/billing/usage?month=2026-09&format=csv", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, systemGroupID, "admin", "system"))
+	rec := httptest.NewRecorder()
+
+	GetBillingUsageHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected text/csv content type, got %q", ct)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	// Header, acme total and provider rows, other total row.
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 CSV lines, got %d:\n%s", len(lines), rec.Body.String())
+	}
+	if want := "2026-09," + groupID.String() + ",acme,ses,2,2048"; lines[2] != want {
+		t.Errorf("expected provider row %q, got %q", want, lines[2])
+	}
+}
+
+func TestGetBillingUsageHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "invalid month", query: "?month=2026-13", want: http.StatusBadRequest},
+		{name: "invalid format", query: "?format=xml", want: http.StatusBadRequest},
+		{name: "other group", query: "?group_id=" + uuid.New().String(), want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/billing/usage"+tt.query, nil)
+			req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+			rec := httptest.NewRecorder()
+
+			GetBillingUsageHandler(&mockQuerier{}).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+// Size limits of branding fields.
+const (
+	maxBrandingLogoURL  = 2048
+	maxBrandingFooter   = 2000
+	maxBrandingTemplate = 16 << 10
+)
+
+// brandingRequest is the JSON body for PUT /api/v1/groups/{id}/branding.
+type brandingRequest struct {
+	FromAddress string                      `json:"from_address"`
+	LogoURL     string                      `json:"logo_url"`
+	Footer      string                      `json:"footer"`
+	Templates   map[string]sysmail.Template `json:"templates"`
+}
+
+// brandingResponse is the JSON representation of a group's branding.
+// Templates only lists overrides; AvailableTemplates names every template
+// that can be overridden.
+type brandingResponse struct {
+	GroupID            uuid.UUID                   `json:"group_id"`
+	FromAddress        string                      `json:"from_address"`
+	LogoURL            string                      `json:"logo_url"`
+	Footer             string                      `json:"footer"`
+	Templates          map[string]sysmail.Template `json:"templates"`
+	AvailableTemplates []string                    `json:"available_templates"`
+	UpdatedAt          *time.Time                  `json:"updated_at,omitempty"`
+}
+
+func toBrandingResponse(groupID uuid.UUID, b storage.GroupBranding) brandingResponse {
+	branding := sysmail.BrandingFromStorage(b)
+	resp := brandingResponse{
+		GroupID:            groupID,
+		FromAddress:        branding.From,
+		LogoURL:            branding.LogoURL,
+		Footer:             branding.Footer,
+		Templates:          branding.Templates,
+		AvailableTemplates: sysmail.TemplateNames(),
+	}
+	if resp.Templates == nil {
+		resp.Templates = map[string]sysmail.Template{}
+	}
+	if b.UpdatedAt.Valid {
+		t := b.UpdatedAt.Time
+		resp.UpdatedAt = &t
+	}
+	return resp
+}
+
+// validate normalizes the request and returns its validation errors.
+func (req *brandingRequest) validate() []string {
+	var errs []string
+	if req.FromAddress != "" {
+		if req.FromAddress = normalizeEmail(req.FromAddress); req.FromAddress == "" {
+			errs = append(errs, "from_address must be a valid address")
+		}
+	}
+	if req.LogoURL != "" {
+		u, err := url.Parse(req.LogoURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" || len(req.LogoURL) > maxBrandingLogoURL {
+			errs = append(errs, "logo_url must be an https URL of at most 2048 characters")
+		}
+	}
+	if len(req.Footer) > maxBrandingFooter {
+		errs = append(errs, "footer must be at most 2000 characters")
+	}
+	for name, t := range req.Templates {
+		if len(t.Subject)+len(t.Body) > maxBrandingTemplate {
+			errs = append(errs, "template "+name+" must be at most 16 KiB")
+		}
+	}
+	if len(errs) == 0 {
+		if err := sysmail.ValidateTemplates(req.Templates); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
+// GetBrandingHandler handles GET /api/v1/groups/{id}/branding.
+// Returns the branding of the group's system emails. Groups without
+// branding report empty fields. Requires group admin+ role.
+func GetBrandingHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+
+		b, err := queries.GetGroupBranding(r.Context(), groupID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		respondJSON(w, http.StatusOK, toBrandingResponse(groupID, b))
+	}
+}
+
+// PutBrandingHandler handles PUT /api/v1/groups/{id}/branding.
+// Sets the sender address, logo, footer and template overrides of system
+// emails sent on the group's behalf, such as invitations and quota
+// warnings. from_address must be covered by a verified sender identity of
+// the group. Templates are checked by rendering them with sample data.
+// Requires group admin+ role.
+func PutBrandingHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+
+		var req brandingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if errs := req.validate(); len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+		if req.FromAddress != "" {
+			verified, err := queries.ListVerifiedSenderIdentities(r.Context(), groupID)
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if !senderpolicy.Allowed(verified, req.FromAddress) {
+				respondValidationErrors(w, []string{"from_address must be covered by a verified sender identity"})
+				return
+			}
+		}
+
+		templates := req.Templates
+		if templates == nil {
+			templates = map[string]sysmail.Template{}
+		}
+		templatesJSON, _ := json.Marshal(templates)
+		b, err := queries.UpsertGroupBranding(r.Context(), storage.UpsertGroupBrandingParams{
+			GroupID:     groupID,
+			FromAddress: req.FromAddress,
+			LogoUrl:     req.LogoURL,
+			Footer:      req.Footer,
+			Templates:   templatesJSON,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			overridden := make([]string, 0, len(templates))
+			for name := range templates {
+				overridden = append(overridden, name)
+			}
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateBranding, "group", groupID.String(), map[string]interface{}{
+				"from_address": b.FromAddress,
+				"logo_url":     b.LogoUrl,
+				"templates":    overridden,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toBrandingResponse(groupID, b))
+	}
+}
+
+// DeleteBrandingHandler handles DELETE /api/v1/groups/{id}/branding.
+// Reverts the group's system emails to the defaults. Requires group admin+
+// role.
+func DeleteBrandingHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+
+		n, err := queries.DeleteGroupBranding(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "branding not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteBranding, "group", groupID.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func brandingHTTPRequest(method, body, role string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/groups/"+testGroup().ID.String()+"/branding", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", testGroup().ID.String())
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "company")
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestGetBrandingHandler(t *testing.T) {
+	t.Run("no branding", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		GetBrandingHandler(&mockQuerier{}).ServeHTTP(rec, brandingHTTPRequest(http.MethodGet, "", "admin"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+		}
+		var resp brandingResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.FromAddress != "" || len(resp.Templates) != 0 || resp.UpdatedAt != nil {
+			t.Errorf("expected empty branding, got %+v", resp)
+		}
+		if len(resp.AvailableTemplates) == 0 {
+			t.Error("expected available templates to be listed")
+		}
+	})
+
+	t.Run("member caller", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		GetBrandingHandler(&mockQuerier{}).ServeHTTP(rec, brandingHTTPRequest(http.MethodGet, "", "member"))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rec.Code)
+		}
+	})
+}
+
+func TestPutBrandingHandler(t *testing.T) {
+	var saved storage.UpsertGroupBrandingParams
+	mock := &mockQuerier{
+		listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+			return []string{"example.com"}, nil
+		},
+		upsertGroupBrandingFn: func(ctx context.Context, arg storage.UpsertGroupBrandingParams) (storage.GroupBranding, error) {
+			saved = arg
+			return storage.GroupBranding{
+				GroupID:     arg.GroupID,
+				FromAddress: arg.FromAddress,
+				LogoUrl:     arg.LogoUrl,
+				Footer:      arg.Footer,
+				Templates:   arg.Templates,
+				UpdatedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			}, nil
+		},
+	}
+
+	body := `{"from_address":"Notices@Example.com","logo_url":"https://example.com/logo.png","footer":"Example Inc.",` +
+		`"templates":{"invitation":{"subject":"Join {{.Group}}"}}}`
+	rec := httptest.NewRecorder()
+	PutBrandingHandler(mock, nil).ServeHTTP(rec, brandingHTTPRequest(http.MethodPut, body, "admin"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if saved.GroupID != testGroup().ID || saved.FromAddress != "notices@example.com" {
+		t.Errorf("unexpected upsert: %+v", saved)
+	}
+	var resp brandingResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Templates["invitation"].Subject != "Join {{.Group}}" || resp.UpdatedAt == nil {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestPutBrandingHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		role string
+		code int
+	}{
+		{"member caller", `{}`, "member", http.StatusForbidden},
+		{"invalid body", `{`, "admin", http.StatusBadRequest},
+		{"invalid from", `{"from_address":"not an address"}`, "admin", http.StatusBadRequest},
+		{"unverified from", `{"from_address":"ceo@other.com"}`, "admin", http.StatusBadRequest},
+		{"http logo", `{"logo_url":"http://example.com/logo.png"}`, "admin", http.StatusBadRequest},
+		{"long footer", `{"footer":"` + strings.Repeat("x", maxBrandingFooter+1) + `"}`, "admin", http.StatusBadRequest},
+		{"unknown template", `{"templates":{"welcome":{"body":"hi"}}}`, "admin", http.StatusBadRequest},
+		{"broken template", `{"templates":{"unlock":{"body":"{{.Link"}}}`, "admin", http.StatusBadRequest},
+		{"unknown field", `{"templates":{"unlock":{"body":"{{.Password}}"}}}`, "admin", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+					return []string{"example.com"}, nil
+				},
+				upsertGroupBrandingFn: func(ctx context.Context, arg storage.UpsertGroupBrandingParams) (storage.GroupBranding, error) {
+					t.Error("branding must not be saved")
+					return storage.GroupBranding{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			PutBrandingHandler(mock, nil).ServeHTTP(rec, brandingHTTPRequest(http.MethodPut, tt.body, tt.role))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteBrandingHandler(t *testing.T) {
+	for _, tc := range []struct {
+		rows int64
+		code int
+	}{
+		{1, http.StatusNoContent},
+		{0, http.StatusNotFound},
+	} {
+		mock := &mockQuerier{
+			deleteGroupBrandingFn: func(ctx context.Context, groupID uuid.UUID) (int64, error) {
+				return tc.rows, nil
+			},
+		}
+		rec := httptest.NewRecorder()
+		DeleteBrandingHandler(mock, nil).ServeHTTP(rec, brandingHTTPRequest(http.MethodDelete, "", "admin"))
+		if rec.Code != tc.code {
+			t.Errorf("rows %d: expected status %d, got %d", tc.rows, tc.code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+)
+
+// createClientCertRequest is the JSON body for
+// POST /api/v1/users/{id}/certificates. Exactly one of Certificate (PEM),
+// Fingerprint (SHA-256 hex) and SAN must be set; a PEM certificate is stored
+// by fingerprint.
+type createClientCertRequest struct {
+	Certificate string `json:"certificate"`
+	Fingerprint string `json:"fingerprint"`
+	SAN         string `json:"san"`
+	Description string `json:"description"`
+}
+
+type clientCertResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	SAN         string     `json:"san,omitempty"`
+	Description string     `json:"description"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func toClientCertResponse(c storage.SmtpClientCert) clientCertResponse {
+	resp := clientCertResponse{
+		ID:          c.ID,
+		UserID:      c.UserID,
+		Fingerprint: c.Fingerprint.String,
+		SAN:         c.San.String,
+		Description: c.Description,
+		CreatedAt:   c.CreatedAt.Time,
+	}
+	if c.LastUsedAt.Valid {
+		resp.LastUsedAt = &c.LastUsedAt.Time
+	}
+	return resp
+}
+
+// CreateClientCertHandler handles POST /api/v1/users/{id}/certificates.
+// Maps a client certificate to an SMTP user so the user can authenticate
+// with mTLS instead of a password. Requires group admin+ role for the
+// user's group. Returns 409 if the fingerprint or SAN is already mapped.
+func CreateClientCertHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req createClientCertRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		set := 0
+		for _, v := range []string{req.Certificate, req.Fingerprint, req.SAN} {
+			if strings.TrimSpace(v) != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			respondError(w, http.StatusBadRequest, "exactly one of certificate, fingerprint or san is required")
+			return
+		}
+
+		params := storage.CreateSmtpClientCertParams{
+			UserID:      id,
+			Description: req.Description,
+		}
+		switch {
+		case req.Certificate != "":
+			cert, err := tlsutil.ParseCertificatePEM([]byte(req.Certificate))
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid certificate")
+				return
+			}
+			params.Fingerprint = pgtype.Text{String: tlsutil.Fingerprint(cert), Valid: true}
+		case req.Fingerprint != "":
+			fp := tlsutil.NormalizeFingerprint(req.Fingerprint)
+			if fp == "" {
+				respondError(w, http.StatusBadRequest, "fingerprint must be a SHA-256 hex digest")
+				return
+			}
+			params.Fingerprint = pgtype.Text{String: fp, Valid: true}
+		default:
+			params.San = pgtype.Text{String: strings.TrimSpace(req.SAN), Valid: true}
+		}
+
+		user, err := queries.GetUserByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+		if user.AccountType != "smtp" {
+			respondError(w, http.StatusBadRequest, "client certificates apply to smtp accounts only")
+			return
+		}
+
+		cert, err := queries.CreateSmtpClientCert(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusConflict, "certificate is already mapped to a user")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, "admin.create_client_cert", "user", id.String(), map[string]interface{}{
+				"cert_id":     cert.ID.String(),
+				"fingerprint": params.Fingerprint.String,
+				"san":         params.San.String,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, toClientCertResponse(cert))
+	}
+}
+
+// ListClientCertsHandler handles GET /api/v1/users/{id}/certificates.
+// Requires group admin+ role for the user's group.
+func ListClientCertsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		certs, err := queries.ListSmtpClientCertsByUserID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]clientCertResponse, 0, len(certs))
+		for _, c := range certs {
+			resp = append(resp, toClientCertResponse(c))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DeleteClientCertHandler handles DELETE /api/v1/users/{id}/certificates/{certId}.
+// Requires group admin+ role for the user's group.
+func DeleteClientCertHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		certID, err := uuid.Parse(chi.URLParam(r, "certId"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid certificate ID format")
+			return
+		}
+
+		n, err := queries.DeleteSmtpClientCert(r.Context(), storage.DeleteSmtpClientCertParams{
+			ID:     certID,
+			UserID: id,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "certificate not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, "admin.delete_client_cert", "user", id.String(), map[string]interface{}{
+				"cert_id": certID.String(),
+			})
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestCreateClientCertHandler(t *testing.T) {
+	usr := testUser()
+	usr.AccountType = "smtp"
+	var stored storage.CreateSmtpClientCertParams
+	mock := &mockQuerier{
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+		createSmtpClientCertFn: func(ctx context.Context, arg storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error) {
+			if arg.San.String == "taken.example.com" {
+				return storage.SmtpClientCert{}, errors.New("duplicate key")
+			}
+			stored = arg
+			return storage.SmtpClientCert{ID: uuid.New(), UserID: arg.UserID, Fingerprint: arg.Fingerprint, San: arg.San}, nil
+		},
+	}
+
+	fp := strings.Repeat("AB:", 31) + "AB"
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"fingerprint", `{"fingerprint":"` + fp + `"}`, http.StatusCreated},
+		{"san", `{"san":"mailer.example.com"}`, http.StatusCreated},
+		{"none", `{"description":"x"}`, http.StatusBadRequest},
+		{"both", `{"fingerprint":"` + fp + `","san":"mailer.example.com"}`, http.StatusBadRequest},
+		{"bad fingerprint", `{"fingerprint":"abc"}`, http.StatusBadRequest},
+		{"bad pem", `{"certificate":"not a cert"}`, http.StatusBadRequest},
+		{"duplicate", `{"san":"taken.example.com"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := shadowRequest(http.MethodPost, "/api/v1/users/"+usr.ID.String()+"/certificates", tt.body, "admin")
+			rec := httptest.NewRecorder()
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", usr.ID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			CreateClientCertHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.name == "fingerprint" && stored.Fingerprint.String != strings.Repeat("ab", 32) {
+				t.Errorf("expected normalized fingerprint, got %q", stored.Fingerprint.String)
+			}
+		})
+	}
+}
+
+func TestCreateClientCertHandler_NotSMTPAccount(t *testing.T) {
+	usr := testUser()
+	mock := &mockQuerier{
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+	}
+
+	req := shadowRequest(http.MethodPost, "/api/v1/users/"+usr.ID.String()+"/certificates", `{"san":"mailer.example.com"}`, "admin")
+	rec := httptest.NewRecorder()
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", usr.ID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	CreateClientCertHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestDeleteClientCertHandler_NotFound(t *testing.T) {
+	mock := &mockQuerier{
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		deleteSmtpClientCertFn: func(ctx context.Context, arg storage.DeleteSmtpClientCertParams) (int64, error) {
+			return 0, nil
+		},
+	}
+
+	userID, certID := uuid.New(), uuid.New()
+	req := shadowRequest(http.MethodDelete, "/api/v1/users/"+userID.String()+"/certificates/"+certID.String(), "", "admin")
+	rec := httptest.NewRecorder()
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", userID.String())
+	rctx.URLParams.Add("certId", certID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	DeleteClientCertHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestClientCertHandlers_AccessDenied(t *testing.T) {
+	usr := testUser()
+	usr.AccountType = "smtp"
+	otherGroup := storage.Group{ID: uuid.New(), Name: "other"}
+	stored := false
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{otherGroup}, nil
+		},
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return otherGroup, nil
+		},
+		createSmtpClientCertFn: func(ctx context.Context, arg storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error) {
+			stored = true
+			return storage.SmtpClientCert{ID: uuid.New(), UserID: arg.UserID}, nil
+		},
+		deleteSmtpClientCertFn: func(ctx context.Context, arg storage.DeleteSmtpClientCertParams) (int64, error) {
+			stored = true
+			return 1, nil
+		},
+	}
+	certID := uuid.New()
+	tests := []struct {
+		name    string
+		method  string
+		role    string
+		handler http.Handler
+	}{
+		{"create as member", http.MethodPost, "member", CreateClientCertHandler(mock, nil)},
+		{"create in other group", http.MethodPost, "admin", CreateClientCertHandler(mock, nil)},
+		{"list as member", http.MethodGet, "member", ListClientCertsHandler(mock)},
+		{"list in other group", http.MethodGet, "admin", ListClientCertsHandler(mock)},
+		{"delete as member", http.MethodDelete, "member", DeleteClientCertHandler(mock, nil)},
+		{"delete in other group", http.MethodDelete, "admin", DeleteClientCertHandler(mock, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := shadowRequest(tt.method, "/api/v1/users/"+usr.ID.String()+"/certificates", `{"san":"mailer.example.com"}`, tt.role)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", usr.ID.String())
+			rctx.URLParams.Add("certId", certID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if stored {
+				t.Error("certificate mapping changed")
+			}
+		})
+	}
+}
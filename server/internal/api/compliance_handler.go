@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/compliance"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// eraseGroupDataRequest is the body of a compliance delete. Confirm must
+// repeat the group's name.
+type eraseGroupDataRequest struct {
+	Confirm string `json:"confirm"`
+}
+
+// ExportGroupDataHandler handles GET /api/v1/groups/{id}/export.
+// Returns a zip archive with the group, its users, message metadata,
+// delivery logs and activity logs. Requires group admin+ role.
+func ExportGroupDataHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+
+		callerGroupType := auth.GroupTypeFromContext(r.Context())
+		callerRole := auth.RoleFromContext(r.Context())
+		if !canAccessGroup(r.Context(), queries, id) || (callerGroupType != "system" && callerRole != "admin" && callerRole != "owner") {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		if _, err := queries.GetGroupByID(r.Context(), id); err != nil {
+			respondError(w, http.StatusNotFound, "group not found")
+			return
+		}
+
+		// Build the archive in memory so a failure can still be reported
+		// as an error response.
+		var buf bytes.Buffer
+		manifest, err := compliance.Export(r.Context(), queries, id, &buf)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionExportGroup, "group", id.String(), map[string]interface{}{
+				"messages":      manifest.Messages,
+				"delivery_logs": manifest.DeliveryLogs,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="group-`+id.String()+`-export.zip"`)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(http.StatusOK)
+		_, _ = buf.WriteTo(w)
+	}
+}
+
+// EraseGroupDataHandler handles POST /api/v1/groups/{id}/erase.
+// Executes a compliance delete: message bodies are purged from the message
+// store, and sender, recipient and content fields are cleared from messages
+// and delivery logs. The group, its users and the activity log are kept.
+// Messages still queued will fail delivery. Requires the group owner role
+// or a system admin, and a body of {"confirm": "<group name>"}.
+func EraseGroupDataHandler(queries storage.Querier, store msgstore.MessageStore, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+
+		callerGroupType := auth.GroupTypeFromContext(r.Context())
+		callerRole := auth.RoleFromContext(r.Context())
+		if !canAccessGroup(r.Context(), queries, id) || (callerGroupType != "system" && callerRole != "owner") {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		group, err := queries.GetGroupByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+		if group.GroupType == "system" {
+			respondError(w, http.StatusForbidden, "cannot erase system group")
+			return
+		}
+
+		var req eraseGroupDataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Confirm != group.Name {
+			respondError(w, http.StatusBadRequest, "confirm must match the group name")
+			return
+		}
+
+		erasure, err := compliance.Erase(r.Context(), queries, store, id)
+		if err != nil {
+			if errors.Is(err, compliance.ErrNoMessageStore) {
+				respondError(w, http.StatusServiceUnavailable, "message store not configured")
+				return
+			}
+			respondError(w, http.StatusInternalServerError, "erasure incomplete, retry the request")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionEraseGroup, "group", id.String(), map[string]interface{}{
+				"bodies_deleted":         erasure.BodiesDeleted,
+				"messages_erased":        erasure.MessagesErased,
+				"delivery_logs_scrubbed": erasure.DeliveryLogsScrubbed,
+				"captures_deleted":       erasure.CapturesDeleted,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, erasure)
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/compliance"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func complianceRequest(method, path, body string, groupID uuid.UUID, role, groupType string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", testGroup().ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, groupID, role, groupType)
+	return req.WithContext(ctx)
+}
+
+func TestExportGroupDataHandler_Success(t *testing.T) {
+	grp := testGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		listGroupMembersByGroupIDFn: func(ctx context.Context, groupID uuid.UUID) ([]storage.GroupMember, error) {
+			return []storage.GroupMember{testGroupMember()}, nil
+		},
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return testUser(), nil
+		},
+	}
+
+	req := complianceRequest(http.MethodGet, "/api/v1/groups/"+grp.ID.String()+"/export", "", grp.ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	ExportGroupDataHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Content-Type = %q, want application/zip", ct)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("response is not a zip archive: %v", err)
+	}
+	if len(zr.File) != 6 {
+		t.Errorf("archive has %d files, want 6", len(zr.File))
+	}
+}
+
+func TestExportGroupDataHandler_MemberForbidden(t *testing.T) {
+	grp := testGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+	}
+
+	req := complianceRequest(http.MethodGet, "/api/v1/groups/"+grp.ID.String()+"/export", "", grp.ID, "member", "organization")
+	rec := httptest.NewRecorder()
+	ExportGroupDataHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestEraseGroupDataHandler_Success(t *testing.T) {
+	grp := testGroup()
+	var erased, scrubbed bool
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		eraseGroupMessagesFn: func(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+			erased = uuid.UUID(groupID.Bytes) == grp.ID
+			return 3, nil
+		},
+		scrubGroupDeliveryLogsFn: func(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+			scrubbed = true
+			return 5, nil
+		},
+	}
+
+	req := complianceRequest(http.MethodPost, "/api/v1/groups/"+grp.ID.String()+"/erase", `{"confirm":"test-group"}`, grp.ID, "owner", "organization")
+	rec := httptest.NewRecorder()
+	EraseGroupDataHandler(mock, nil, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if !erased || !scrubbed {
+		t.Error("expected messages erased and delivery logs scrubbed")
+	}
+	var resp compliance.Erasure
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.MessagesErased != 3 || resp.DeliveryLogsScrubbed != 5 {
+		t.Errorf("unexpected erasure: %+v", resp)
+	}
+}
+
+func TestEraseGroupDataHandler_ConfirmMismatch(t *testing.T) {
+	grp := testGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		eraseGroupMessagesFn: func(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+			t.Error("messages must not be erased without confirmation")
+			return 0, nil
+		},
+	}
+
+	req := complianceRequest(http.MethodPost, "/api/v1/groups/"+grp.ID.String()+"/erase", `{"confirm":"other"}`, grp.ID, "owner", "organization")
+	rec := httptest.NewRecorder()
+	EraseGroupDataHandler(mock, nil, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestEraseGroupDataHandler_AdminForbidden(t *testing.T) {
+	grp := testGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+	}
+
+	req := complianceRequest(http.MethodPost, "/api/v1/groups/"+grp.ID.String()+"/erase", `{"confirm":"test-group"}`, grp.ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	EraseGroupDataHandler(mock, nil, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestEraseGroupDataHandler_NoMessageStore(t *testing.T) {
+	grp := testGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		listGroupMessageStorageRefsFn: func(ctx context.Context, groupID pgtype.UUID) ([]pgtype.Text, error) {
+			return []pgtype.Text{{String: uuid.NewString(), Valid: true}}, nil
+		},
+	}
+
+	systemGroupID := uuid.MustParse("00000000-0000-0000-0000-000000000099")
+	req := complianceRequest(http.MethodPost, "/api/v1/groups/"+grp.ID.String()+"/erase", `{"confirm":"test-group"}`, systemGroupID, "admin", "system")
+	rec := httptest.NewRecorder()
+	EraseGroupDataHandler(mock, nil, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+}
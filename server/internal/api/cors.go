@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultCORSHeaders are the request headers allowed in cross-origin
+// requests when CORSConfig.AllowedHeaders is empty.
+var defaultCORSHeaders = []string{"Authorization", "Content-Type", "X-Correlation-ID", "X-CSRF-Token"}
+
+// CORSConfig configures cross-origin access to the API.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API, e.g.
+	// "https://admin.example.com". "*" allows any origin and
+	// "https://*.example.com" any subdomain. Empty disables CORS.
+	AllowedOrigins []string
+	// AllowedHeaders overrides the request headers allowed in
+	// cross-origin requests.
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies with cross-origin
+	// requests.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses.
+	MaxAge time.Duration
+}
+
+// allowed reports whether origin matches an allowed origin.
+func (c CORSConfig) allowed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+		if scheme, host, ok := strings.Cut(o, "://*."); ok {
+			rest, ok := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if ok && strings.HasSuffix(rest, "."+strings.ToLower(host)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers to
+// responses for allowed origins. Requests from other origins are served
+// without CORS headers, so browsers withhold the response; preflights from
+// them are rejected with 403. With no allowed origins it does nothing.
+func CORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	allowHeaders := strings.Join(headers, ", ")
+
+	return func(next http.Handler) http.Handler {
+		if len(cfg.AllowedOrigins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !cfg.allowed(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+				if cfg.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			w.Header().Set("Access-Control-Expose-Headers", "X-Correlation-ID")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	handler := CORSMiddleware(CORSConfig{
+		AllowedOrigins:   []string{"https://admin.example.com", "https://*.corp.example"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		preflight   bool
+		wantCode    int
+		wantAllowed bool
+	}{
+		{"no origin", http.MethodGet, "", false, http.StatusOK, false},
+		{"allowed", http.MethodGet, "https://admin.example.com", false, http.StatusOK, true},
+		{"wildcard subdomain", http.MethodGet, "https://ui.corp.example", false, http.StatusOK, true},
+		{"wildcard scheme mismatch", http.MethodGet, "http://ui.corp.example", false, http.StatusOK, false},
+		{"other origin", http.MethodPost, "https://evil.example", false, http.StatusOK, false},
+		{"preflight allowed", http.MethodOptions, "https://admin.example.com", true, http.StatusNoContent, true},
+		{"preflight denied", http.MethodOptions, "https://evil.example", true, http.StatusForbidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/users", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.wantAllowed && got != tt.origin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.origin, got)
+			}
+			if !tt.wantAllowed && got != "" {
+				t.Errorf("expected no Access-Control-Allow-Origin, got %q", got)
+			}
+			if tt.wantAllowed && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("expected Access-Control-Allow-Credentials: true")
+			}
+			if tt.preflight && tt.wantAllowed && rec.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("expected Access-Control-Max-Age 600, got %q", rec.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware_Disabled(t *testing.T) {
+	handler := CORSMiddleware(CORSConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("expected no CORS headers when no origins are configured")
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	handler := CSRFMiddleware(CSRFConfig{Enabled: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A safe request without a token receives one.
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != "csrf_token" || len(cookies[0].Value) != 64 {
+		t.Fatalf("expected csrf_token cookie, got status %d cookies %v", rec.Code, cookies)
+	}
+	token := cookies[0].Value
+
+	tests := []struct {
+		name     string
+		cookie   bool
+		header   string
+		auth     string
+		wantCode int
+	}{
+		{"no cookies", false, "", "", http.StatusOK},
+		{"bearer token", true, "", "Bearer abc", http.StatusOK},
+		{"missing header", true, "", "", http.StatusForbidden},
+		{"wrong header", true, "nope", "", http.StatusForbidden},
+		{"matching header", true, token, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users", nil)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/logarchive"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// deliveryLogResponse is a delivery attempt together with the message it
+// belongs to. Archived is set for attempts read from the log archive.
+type deliveryLogResponse struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Archived  bool      `json:"archived,omitempty"`
+	deliveryAttemptResponse
+}
+
+// ListDeliveryLogsHandler handles GET /api/v1/delivery-logs.
+// Lists the delivery attempts of the caller's group, newest first, with the
+// connection that carried each one. Supports query params: group_id (the
+// caller's group or a sub-group), egress_ip, provider, since and until
+// (RFC 3339, until exclusive) and limit (default 100, max 1000).
+//
+// Archived logs are older than every log still in the database, so when
+// the database returns fewer than limit logs the rest are filled from the
+// log archive in store. Without a store only the database is searched.
+func ListDeliveryLogsHandler(queries storage.Querier, store msgstore.MessageStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := requestGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query()
+		params := storage.ListGroupDeliveryLogsParams{
+			GroupID: pgtype.UUID{Bytes: groupID, Valid: true},
+		}
+		if ip := q.Get("egress_ip"); ip != "" {
+			params.EgressIp = pgtype.Text{String: ip, Valid: true}
+		}
+		if p := q.Get("provider"); p != "" {
+			params.Provider = pgtype.Text{String: p, Valid: true}
+		}
+		if !parseDeliveryLogRange(w, q, &params.Since, &params.Until) {
+			return
+		}
+		params.MaxResults = deliveryLogLimit(q)
+
+		logs, err := queries.ListGroupDeliveryLogs(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]deliveryLogResponse, 0, len(logs))
+		for _, l := range logs {
+			resp = append(resp, deliveryLogResponse{
+				MessageID:               l.MessageID,
+				deliveryAttemptResponse: toDeliveryAttemptResponse(l),
+			})
+		}
+
+		if store != nil && len(logs) < int(params.MaxResults) {
+			archived, err := logarchive.Search(r.Context(), queries, store, logarchive.Filter{
+				GroupID:  groupID,
+				EgressIP: params.EgressIp.String,
+				Provider: params.Provider.String,
+				Since:    params.Since.Time,
+				Until:    params.Until.Time,
+			}, int(params.MaxResults)-len(logs))
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "failed to read archived delivery logs")
+				return
+			}
+			for _, l := range archived {
+				resp = append(resp, deliveryLogResponse{
+					MessageID:               l.MessageID,
+					Archived:                true,
+					deliveryAttemptResponse: toDeliveryAttemptResponse(l),
+				})
+			}
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// recipientDeliveryResponse is a delivery attempt to a recipient together
+// with the message it delivered.
+type recipientDeliveryResponse struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Sender    string    `json:"sender"`
+	Subject   string    `json:"subject,omitempty"`
+	deliveryAttemptResponse
+}
+
+// ListRecipientDeliveriesHandler handles GET
+// /api/v1/recipients/{address}/deliveries.
+// Lists the delivery attempts of every message of the caller's group that
+// was sent to address, newest first, so a single recipient's mail and its
+// bounces can be traced. Addresses match case-insensitively. Supports the
+// group_id, since, until and limit query params of
+// ListDeliveryLogsHandler. The log archive does not record recipients, so
+// only attempts still in the database are returned.
+func ListRecipientDeliveriesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := strings.TrimSpace(chi.URLParam(r, "address"))
+		if !strings.Contains(address, "@") {
+			respondError(w, http.StatusBadRequest, "invalid recipient address")
+			return
+		}
+		groupID, ok := requestGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query()
+		params := storage.ListRecipientDeliveryLogsParams{
+			GroupID:   pgtype.UUID{Bytes: groupID, Valid: true},
+			Recipient: address,
+		}
+		if !parseDeliveryLogRange(w, q, &params.Since, &params.Until) {
+			return
+		}
+		params.MaxResults = deliveryLogLimit(q)
+
+		rows, err := queries.ListRecipientDeliveryLogs(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]recipientDeliveryResponse, 0, len(rows))
+		for _, row := range rows {
+			resp = append(resp, recipientDeliveryResponse{
+				MessageID:               row.DeliveryLog.MessageID,
+				Sender:                  row.Sender,
+				Subject:                 row.Subject.String,
+				deliveryAttemptResponse: toDeliveryAttemptResponse(row.DeliveryLog),
+			})
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// parseDeliveryLogRange reads the since and until query params as RFC 3339
+// timestamps. It responds with 400 and returns false when either is
+// malformed.
+func parseDeliveryLogRange(w http.ResponseWriter, q url.Values, since, until *pgtype.Timestamptz) bool {
+	for _, f := range []struct {
+		name string
+		dst  *pgtype.Timestamptz
+	}{{"since", since}, {"until", until}} {
+		v := q.Get(f.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid "+f.name+", expected RFC 3339 timestamp")
+			return false
+		}
+		*f.dst = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	return true
+}
+
+// deliveryLogLimit returns the limit query param, defaulting to 100 and
+// capped at 1000.
+func deliveryLogLimit(q url.Values) int32 {
+	if l := q.Get("limit"); l != "" {
+		if v, err := strconv.Atoi(l); err == nil && v > 0 {
+			return int32(min(v, 1000))
+		}
+	}
+	return 100
+}
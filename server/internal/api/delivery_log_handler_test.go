@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/logarchive"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func deliveryLogsRequest(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	return req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+}
+
+func TestListDeliveryLogsHandler_Filters(t *testing.T) {
+	msgID := uuid.New()
+	var got storage.ListGroupDeliveryLogsParams
+	mock := &mockQuerier{
+		listGroupDeliveryLogsFn: func(ctx context.Context, arg storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+			got = arg
+			return []storage.DeliveryLog{{
+				MessageID:        msgID,
+				AttemptNumber:    1,
+				Status:           "delivered",
+				EgressIp:         pgtype.Text{String: "203.0.113.7", Valid: true},
+				RemoteAddr:       pgtype.Text{String: "198.51.100.1:443", Valid: true},
+				ProviderEndpoint: pgtype.Text{String: "https://api.sendgrid.com/v3/mail/send", Valid: true},
+				TlsVersion:       pgtype.Text{String: "TLS 1.3", Valid: true},
+				TlsCipher:        pgtype.Text{String: "TLS_AES_128_GCM_SHA256", Valid: true},
+				FirstByteMs:      pgtype.Int4{Int32: 120, Valid: true},
+			}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListDeliveryLogsHandler(mock, nil).ServeHTTP(rec, deliveryLogsRequest(
+		"/api/v1/delivery-logs?egress_ip=203.0.113.7&provider=sendgrid&since=2026-01-01T00:00:00Z&until=2026-02-01T00:00:00Z&limit=5000"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID.Bytes != testGroup().ID || got.EgressIp.String != "203.0.113.7" || got.Provider.String != "sendgrid" {
+		t.Errorf("unexpected list params: %+v", got)
+	}
+	if !got.Since.Time.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !got.Until.Time.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time range: %v - %v", got.Since.Time, got.Until.Time)
+	}
+	if got.MaxResults != 1000 {
+		t.Errorf("expected limit capped at 1000, got %d", got.MaxResults)
+	}
+
+	var resp []deliveryLogResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].MessageID != msgID {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	c := resp[0].Connection
+	if c == nil || c.EgressIP != "203.0.113.7" || c.TLSVersion != "TLS 1.3" || c.Endpoint != "https://api.sendgrid.com/v3/mail/send" {
+		t.Fatalf("unexpected connection: %+v", c)
+	}
+	if c.FirstByteMs == nil || *c.FirstByteMs != 120 || c.ConnectMs != nil {
+		t.Errorf("unexpected timings: %+v", c)
+	}
+}
+
+func TestListDeliveryLogsHandler_NoConnection(t *testing.T) {
+	mock := &mockQuerier{
+		listGroupDeliveryLogsFn: func(ctx context.Context, arg storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+			return []storage.DeliveryLog{{MessageID: uuid.New(), Status: "delivered"}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListDeliveryLogsHandler(mock, nil).ServeHTTP(rec, deliveryLogsRequest("/api/v1/delivery-logs"))
+
+	var resp []deliveryLogResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Connection != nil {
+		t.Errorf("expected no connection, got %+v", resp)
+	}
+}
+
+func TestListDeliveryLogsHandler_InvalidSince(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ListDeliveryLogsHandler(&mockQuerier{}, nil).ServeHTTP(rec, deliveryLogsRequest("/api/v1/delivery-logs?since=yesterday"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestListDeliveryLogsHandler_FillsFromArchive(t *testing.T) {
+	group := pgtype.UUID{Bytes: testGroup().ID, Valid: true}
+	live := storage.DeliveryLog{MessageID: uuid.New(), GroupID: group, Status: "delivered"}
+	old := storage.DeliveryLog{
+		MessageID: uuid.New(),
+		GroupID:   group,
+		Status:    "bounced",
+		CreatedAt: pgtype.Timestamptz{Time: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+	data, err := logarchive.Encode([]storage.DeliveryLog{old})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &mockPreviewStore{data: map[string][]byte{"delivery-logs-20250110-a.ndjson.gz": data}}
+
+	var gotArchive storage.ListGroupDeliveryLogArchivesParams
+	mock := &mockQuerier{
+		listGroupDeliveryLogsFn: func(ctx context.Context, arg storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+			return []storage.DeliveryLog{live}, nil
+		},
+		listGroupDeliveryLogArchivesFn: func(ctx context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+			gotArchive = arg
+			return []storage.DeliveryLogArchive{{GroupID: group, ObjectKey: "delivery-logs-20250110-a.ndjson.gz"}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListDeliveryLogsHandler(mock, store).ServeHTTP(rec, deliveryLogsRequest("/api/v1/delivery-logs?since=2025-01-01T00:00:00Z"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if gotArchive.GroupID != group || !gotArchive.Since.Valid {
+		t.Errorf("unexpected archive params: %+v", gotArchive)
+	}
+	var resp []deliveryLogResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 2 || resp[0].MessageID != live.MessageID || resp[0].Archived {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp[1].MessageID != old.MessageID || !resp[1].Archived || resp[1].Status != "bounced" {
+		t.Errorf("unexpected archived entry: %+v", resp[1])
+	}
+}
+
+func TestListDeliveryLogsHandler_SkipsArchiveWhenFull(t *testing.T) {
+	mock := &mockQuerier{
+		listGroupDeliveryLogsFn: func(ctx context.Context, arg storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+			return []storage.DeliveryLog{{MessageID: uuid.New()}}, nil
+		},
+		listGroupDeliveryLogArchivesFn: func(ctx context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+			t.Error("archive should not be searched when the database filled the page")
+			return nil, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListDeliveryLogsHandler(mock, &mockPreviewStore{}).ServeHTTP(rec, deliveryLogsRequest("/api/v1/delivery-logs?limit=1"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func recipientDeliveriesRequest(address, query string) *http.Request {
+	req := deliveryLogsRequest("/api/v1/recipients/" + address + "/deliveries" + query)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("address", address)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestListRecipientDeliveriesHandler(t *testing.T) {
+	msgID := uuid.New()
+	var got storage.ListRecipientDeliveryLogsParams
+	mock := &mockQuerier{
+		listRecipientDeliveryLogsFn: func(ctx context.Context, arg storage.ListRecipientDeliveryLogsParams) ([]storage.ListRecipientDeliveryLogsRow, error) {
+			got = arg
+			return []storage.ListRecipientDeliveryLogsRow{{
+				DeliveryLog: storage.DeliveryLog{
+					MessageID:     msgID,
+					AttemptNumber: 1,
+					Status:        "bounced",
+					ResponseCode:  pgtype.Int4{Int32: 550, Valid: true},
+				},
+				Sender:  "billing@example.com",
+				Subject: sql.NullString{String: "Your invoice", Valid: true},
+			}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListRecipientDeliveriesHandler(mock).ServeHTTP(rec, recipientDeliveriesRequest("alice@example.com", "?since=2026-01-01T00:00:00Z&limit=10"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID.Bytes != testGroup().ID || got.Recipient != "alice@example.com" || got.MaxResults != 10 || got.Until.Valid ||
+		!got.Since.Time.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected list params: %+v", got)
+	}
+
+	var resp []recipientDeliveryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].MessageID != msgID || resp[0].Subject != "Your invoice" || resp[0].Status != "bounced" ||
+		resp[0].ResponseCode == nil || *resp[0].ResponseCode != 550 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestListRecipientDeliveriesHandler_InvalidAddress(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ListRecipientDeliveriesHandler(&mockQuerier{}).ServeHTTP(rec, recipientDeliveriesRequest("alice", ""))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/deliveryreport"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/msgtag"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// DefaultDeliveryReportURLTTL is how long a download URL stays valid when
+// DeliveryReports.URLTTL is not set.
+const DefaultDeliveryReportURLTTL = time.Hour
+
+// maxDeliveryReportWindow bounds the time range of one report.
+const maxDeliveryReportWindow = 31 * 24 * time.Hour
+
+// deliveryReportDownloadPath is the endpoint signed download URLs point to.
+const deliveryReportDownloadPath = "/api/v1/delivery-reports/download"
+
+// DeliveryReports configures the download URLs of delivery reports.
+type DeliveryReports struct {
+	// BaseURL is the externally reachable API URL download URLs start
+	// with. Relative URLs are returned when it is empty.
+	BaseURL string
+	// URLTTL is how long a download URL stays valid. Defaults to
+	// DefaultDeliveryReportURLTTL.
+	URLTTL time.Duration
+}
+
+func (c DeliveryReports) urlTTL() time.Duration {
+	if c.URLTTL <= 0 {
+		return DefaultDeliveryReportURLTTL
+	}
+	return c.URLTTL
+}
+
+// deliveryReportRequest is the JSON body for creating a delivery report.
+type deliveryReportRequest struct {
+	Tag   string    `json:"tag"`
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+// deliveryReportResponse is the JSON representation of a delivery report.
+// DownloadURL is a signed URL, valid until URLExpiresAt, set once the
+// report is ready.
+type deliveryReportResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	GroupID      uuid.UUID  `json:"group_id"`
+	Tag          string     `json:"tag"`
+	Since        time.Time  `json:"since"`
+	Until        time.Time  `json:"until"`
+	Status       string     `json:"status"`
+	Rows         int32      `json:"rows"`
+	Truncated    bool       `json:"truncated"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	DownloadURL  string     `json:"download_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+func toDeliveryReportResponse(r storage.DeliveryReport) deliveryReportResponse {
+	resp := deliveryReportResponse{
+		ID:        r.ID,
+		GroupID:   r.GroupID,
+		Tag:       r.Tag,
+		Since:     timestampToTime(r.Since),
+		Until:     timestampToTime(r.Until),
+		Status:    r.Status,
+		Rows:      r.RowCount,
+		Truncated: r.Truncated,
+		Error:     r.Error.String,
+		CreatedAt: timestampToTime(r.CreatedAt),
+	}
+	if r.CompletedAt.Valid {
+		resp.CompletedAt = &r.CompletedAt.Time
+	}
+	return resp
+}
+
+// withDownloadURL signs a download URL for a ready report.
+func withDownloadURL(resp deliveryReportResponse, jwtService *auth.JWTService, reports DeliveryReports) (deliveryReportResponse, error) {
+	if resp.Status != deliveryreport.StatusReady {
+		return resp, nil
+	}
+	ttl := reports.urlTTL()
+	token, err := jwtService.GenerateActionToken(auth.PurposeDeliveryReport, resp.ID.String(), uuid.NewString(), ttl)
+	if err != nil {
+		return resp, err
+	}
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	resp.DownloadURL = strings.TrimRight(reports.BaseURL, "/") + deliveryReportDownloadPath + "?" + url.Values{"token": {token}}.Encode()
+	resp.URLExpiresAt = &expires
+	return resp, nil
+}
+
+// groupMemberParam parses the {id} URL parameter and checks that the
+// caller can access the group.
+func groupMemberParam(w http.ResponseWriter, r *http.Request, queries storage.Querier) (uuid.UUID, bool) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid group ID format")
+		return uuid.Nil, false
+	}
+	if !canAccessGroup(r.Context(), queries, groupID) {
+		respondError(w, http.StatusForbidden, "access denied")
+		return uuid.Nil, false
+	}
+	return groupID, true
+}
+
+// CreateDeliveryReportHandler handles POST /api/v1/groups/{id}/delivery-reports.
+// Requests a CSV report of per-recipient outcomes for the group's messages
+// tagged tag and enqueued within [since, until), at most 31 days. The
+// report is generated asynchronously by the queue worker; poll it with
+// GetDeliveryReportHandler. Requires group membership.
+func CreateDeliveryReportHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupMemberParam(w, r, queries)
+		if !ok {
+			return
+		}
+		var req deliveryReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.Tag = strings.TrimSpace(req.Tag)
+		var errs []string
+		if req.Tag == "" {
+			errs = append(errs, "tag is required")
+		} else if len(req.Tag) > msgtag.MaxTagLength {
+			errs = append(errs, "tag must be at most 64 characters")
+		}
+		if req.Since.IsZero() {
+			errs = append(errs, "since is required")
+		}
+		if req.Until.IsZero() {
+			errs = append(errs, "until is required")
+		}
+		if !req.Since.IsZero() && !req.Until.IsZero() {
+			if !req.Since.Before(req.Until) {
+				errs = append(errs, "since must be before until")
+			} else if req.Until.Sub(req.Since) > maxDeliveryReportWindow {
+				errs = append(errs, "time range must not exceed 31 days")
+			}
+		}
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		userID := auth.UserFromContext(r.Context())
+		report, err := queries.CreateDeliveryReport(r.Context(), storage.CreateDeliveryReportParams{
+			GroupID: groupID,
+			UserID:  pgtype.UUID{Bytes: userID, Valid: userID != uuid.Nil},
+			Tag:     req.Tag,
+			Since:   pgtype.Timestamptz{Time: req.Since, Valid: true},
+			Until:   pgtype.Timestamptz{Time: req.Until, Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "failed to create delivery report")
+			return
+		}
+		respondJSON(w, http.StatusAccepted, toDeliveryReportResponse(report))
+	}
+}
+
+// ListDeliveryReportsHandler handles GET /api/v1/groups/{id}/delivery-reports.
+// Lists the group's 50 most recent delivery reports, newest first, with
+// download URLs for ready reports. Requires group membership.
+func ListDeliveryReportsHandler(queries storage.Querier, jwtService *auth.JWTService, reports DeliveryReports) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupMemberParam(w, r, queries)
+		if !ok {
+			return
+		}
+		list, err := queries.ListGroupDeliveryReports(r.Context(), storage.ListGroupDeliveryReportsParams{
+			GroupID: groupID,
+			Limit:   50,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "failed to list delivery reports")
+			return
+		}
+		resp := make([]deliveryReportResponse, 0, len(list))
+		for _, report := range list {
+			item, err := withDownloadURL(toDeliveryReportResponse(report), jwtService, reports)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "failed to sign download URL")
+				return
+			}
+			resp = append(resp, item)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// GetDeliveryReportHandler handles GET /api/v1/groups/{id}/delivery-reports/{reportId}.
+// Returns a delivery report's status and, once it is ready, a fresh signed
+// download URL. Requires group membership.
+func GetDeliveryReportHandler(queries storage.Querier, jwtService *auth.JWTService, reports DeliveryReports) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupMemberParam(w, r, queries)
+		if !ok {
+			return
+		}
+		reportID, err := uuid.Parse(chi.URLParam(r, "reportId"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid report ID format")
+			return
+		}
+		report, err := queries.GetDeliveryReport(r.Context(), reportID)
+		if err != nil || report.GroupID != groupID {
+			respondStorageError(w, err, http.StatusNotFound, "delivery report not found")
+			return
+		}
+		resp, err := withDownloadURL(toDeliveryReportResponse(report), jwtService, reports)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to sign download URL")
+			return
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DownloadDeliveryReportHandler handles GET /api/v1/delivery-reports/download.
+// Serves a ready report's CSV to the holder of a signed download URL. No
+// auth required: the token in the URL grants access to one report until
+// it expires.
+func DownloadDeliveryReportHandler(queries storage.Querier, store msgstore.MessageStore, jwtService *auth.JWTService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			respondError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+		claims, err := jwtService.ValidateActionToken(token, auth.PurposeDeliveryReport)
+		if err != nil {
+			respondError(w, http.StatusForbidden, "invalid or expired download link")
+			return
+		}
+		reportID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			respondError(w, http.StatusForbidden, "invalid or expired download link")
+			return
+		}
+		report, err := queries.GetDeliveryReport(r.Context(), reportID)
+		if err != nil || report.Status != deliveryreport.StatusReady || !report.ObjectKey.Valid {
+			respondStorageError(w, err, http.StatusNotFound, "delivery report not found")
+			return
+		}
+		if store == nil {
+			respondError(w, http.StatusServiceUnavailable, "report storage unavailable")
+			return
+		}
+		data, err := store.Get(r.Context(), report.ObjectKey.String)
+		if errors.Is(err, msgstore.ErrNotFound) {
+			respondError(w, http.StatusNotFound, "delivery report not found")
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to read delivery report")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="delivery-report-`+report.ID.String()+`.csv"`)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	}
+}
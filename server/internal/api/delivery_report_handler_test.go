@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/deliveryreport"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func deliveryReportHTTPRequest(method, path, body string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", testGroup().ID.String())
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "company")
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func readyDeliveryReport() storage.DeliveryReport {
+	return storage.DeliveryReport{
+		ID:          uuid.New(),
+		GroupID:     testGroup().ID,
+		Tag:         "spring-sale",
+		Status:      deliveryreport.StatusReady,
+		ObjectKey:   pgtype.Text{String: "delivery-report-1.csv", Valid: true},
+		RowCount:    2,
+		CompletedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+func TestCreateDeliveryReportHandler(t *testing.T) {
+	var created storage.CreateDeliveryReportParams
+	mock := &mockQuerier{
+		createDeliveryReportFn: func(ctx context.Context, arg storage.CreateDeliveryReportParams) (storage.DeliveryReport, error) {
+			created = arg
+			return storage.DeliveryReport{ID: uuid.New(), GroupID: arg.GroupID, Tag: arg.Tag, Since: arg.Since, Until: arg.Until, Status: deliveryreport.StatusPending}, nil
+		},
+	}
+	body := `{"tag":" spring-sale ","since":"2026-10-01T00:00:00Z","until":"2026-10-08T00:00:00Z"}`
+	rec := httptest.NewRecorder()
+	CreateDeliveryReportHandler(mock).ServeHTTP(rec, deliveryReportHTTPRequest(http.MethodPost, "/", body, nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if created.GroupID != testGroup().ID || created.Tag != "spring-sale" || created.UserID.Bytes != testUser().ID {
+		t.Errorf("unexpected create params: %+v", created)
+	}
+	var resp deliveryReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Status != deliveryreport.StatusPending || resp.DownloadURL != "" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCreateDeliveryReportHandler_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid body", `{`},
+		{"missing tag", `{"since":"2026-10-01T00:00:00Z","until":"2026-10-02T00:00:00Z"}`},
+		{"long tag", `{"tag":"` + strings.Repeat("t", 65) + `","since":"2026-10-01T00:00:00Z","until":"2026-10-02T00:00:00Z"}`},
+		{"missing window", `{"tag":"spring-sale"}`},
+		{"reversed window", `{"tag":"spring-sale","since":"2026-10-02T00:00:00Z","until":"2026-10-01T00:00:00Z"}`},
+		{"window too long", `{"tag":"spring-sale","since":"2026-08-01T00:00:00Z","until":"2026-10-01T00:00:00Z"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				createDeliveryReportFn: func(ctx context.Context, arg storage.CreateDeliveryReportParams) (storage.DeliveryReport, error) {
+					t.Error("report must not be created")
+					return storage.DeliveryReport{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			CreateDeliveryReportHandler(mock).ServeHTTP(rec, deliveryReportHTTPRequest(http.MethodPost, "/", tt.body, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetDeliveryReportHandler_SignedDownload(t *testing.T) {
+	jwtSvc := newInvitationJWTService()
+	report := readyDeliveryReport()
+	mock := &mockQuerier{
+		getDeliveryReportFn: func(ctx context.Context, id uuid.UUID) (storage.DeliveryReport, error) {
+			if id != report.ID {
+				t.Errorf("unexpected report ID %s", id)
+			}
+			return report, nil
+		},
+	}
+	reports := DeliveryReports{BaseURL: "https://mail.example.com/", URLTTL: 10 * time.Minute}
+
+	rec := httptest.NewRecorder()
+	GetDeliveryReportHandler(mock, jwtSvc, reports).ServeHTTP(rec,
+		deliveryReportHTTPRequest(http.MethodGet, "/", "", map[string]string{"reportId": report.ID.String()}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp deliveryReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(resp.DownloadURL, "https://mail.example.com"+deliveryReportDownloadPath+"?token=") || resp.URLExpiresAt == nil {
+		t.Fatalf("unexpected download URL: %+v", resp)
+	}
+	if until := time.Until(*resp.URLExpiresAt); until < 9*time.Minute || until > 11*time.Minute {
+		t.Errorf("URL expires in %v, want about 10m", until)
+	}
+
+	link, _ := url.Parse(resp.DownloadURL)
+	store := &mockPreviewStore{data: map[string][]byte{report.ObjectKey.String: []byte("message_id\n")}}
+	rec = httptest.NewRecorder()
+	DownloadDeliveryReportHandler(mock, store, jwtSvc).ServeHTTP(rec,
+		httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download: expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" || rec.Body.String() != "message_id\n" {
+		t.Errorf("unexpected download: %q %q", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestGetDeliveryReportHandler_OtherGroup(t *testing.T) {
+	report := readyDeliveryReport()
+	report.GroupID = uuid.New()
+	mock := &mockQuerier{
+		getDeliveryReportFn: func(ctx context.Context, id uuid.UUID) (storage.DeliveryReport, error) {
+			return report, nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	GetDeliveryReportHandler(mock, newInvitationJWTService(), DeliveryReports{}).ServeHTTP(rec,
+		deliveryReportHTTPRequest(http.MethodGet, "/", "", map[string]string{"reportId": report.ID.String()}))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestDownloadDeliveryReportHandler_Errors(t *testing.T) {
+	jwtSvc := newInvitationJWTService()
+	report := readyDeliveryReport()
+	pending := readyDeliveryReport()
+	pending.Status = deliveryreport.StatusPending
+	mock := &mockQuerier{
+		getDeliveryReportFn: func(ctx context.Context, id uuid.UUID) (storage.DeliveryReport, error) {
+			if id == pending.ID {
+				return pending, nil
+			}
+			return report, nil
+		},
+	}
+	sign := func(purpose string, id uuid.UUID) string {
+		token, err := jwtSvc.GenerateActionToken(purpose, id.String(), uuid.NewString(), time.Hour)
+		if err != nil {
+			t.Fatalf("GenerateActionToken: %v", err)
+		}
+		return token
+	}
+	store := &mockPreviewStore{data: map[string][]byte{}}
+
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"missing token", "", http.StatusBadRequest},
+		{"invalid token", "not-a-token", http.StatusForbidden},
+		{"wrong purpose", sign(auth.PurposeInvite, report.ID), http.StatusForbidden},
+		{"pending report", sign(auth.PurposeDeliveryReport, pending.ID), http.StatusNotFound},
+		{"purged file", sign(auth.PurposeDeliveryReport, report.ID), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			DownloadDeliveryReportHandler(mock, store, jwtSvc).ServeHTTP(rec,
+				httptest.NewRequest(http.MethodGet, deliveryReportDownloadPath+"?token="+tt.token, nil))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
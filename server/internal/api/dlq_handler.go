@@ -2,18 +2,29 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
 	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
+// maxDLQReprocess caps the number of entries in one reprocess request.
+const maxDLQReprocess = 100
+
 // dlqReprocessRequest is the JSON body for POST /api/v1/dlq/reprocess.
 type dlqReprocessRequest struct {
-	MessageIDs     []string `json:"message_ids"`
-	ResetRetryCount bool    `json:"reset_retry_count"`
+	MessageIDs      []string `json:"message_ids"`
+	ResetRetryCount bool     `json:"reset_retry_count"`
+	// ProviderID, when set, delivers the reprocessed messages through this
+	// provider instead of the group's routing.
+	ProviderID string `json:"provider_id"`
 }
 
 // dlqReprocessResponse is the JSON response for a DLQ reprocess operation.
@@ -22,17 +33,127 @@ type dlqReprocessResponse struct {
 	Total       int `json:"total"`
 }
 
+// dlqEntryResponse summarizes a dead-lettered message. From, To and Subject
+// come from the queue payload for legacy full-payload messages and from the
+// messages table for ID-only ones.
+type dlqEntryResponse struct {
+	ID            string    `json:"id"`
+	MessageID     string    `json:"message_id"`
+	Status        string    `json:"status,omitempty"`
+	From          string    `json:"from"`
+	To            []string  `json:"to"`
+	Subject       string    `json:"subject"`
+	FailureReason string    `json:"failure_reason"`
+	FinalError    string    `json:"final_error"`
+	RetryCount    int       `json:"retry_count"`
+	ProviderID    string    `json:"provider_id,omitempty"`
+	MovedAt       time.Time `json:"moved_at"`
+}
+
+// dlqEntryDetailResponse is the JSON response for GET /api/v1/dlq/{id}.
+type dlqEntryDetailResponse struct {
+	dlqEntryResponse
+	RetryHistory []string                  `json:"retry_history"`
+	Attempts     []deliveryAttemptResponse `json:"attempts"`
+}
+
+// ListDLQHandler handles GET /api/v1/dlq.
+// Lists the dead letter queue of the caller's group, oldest first, with a
+// summary of each original message. Supports query params: group_id (the
+// caller's group or a sub-group) and limit (default 50, max 500).
+func ListDLQHandler(dlq queue.DeadLetterQueue, queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := dlqGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		limit := 50
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil && v > 0 {
+				limit = min(v, 500)
+			}
+		}
+
+		entries, err := dlq.List(r.Context(), groupID.String(), limit)
+		if err != nil {
+			log := logger.FromContext(r.Context())
+			log.Error().Err(err).Stringer("group_id", groupID).Msg("dlq list failed")
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]dlqEntryResponse, 0, len(entries))
+		for _, e := range entries {
+			if !dlqEntryInGroup(e, groupID) {
+				continue
+			}
+			resp = append(resp, toDLQEntryResponse(r, queries, e))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// GetDLQEntryHandler handles GET /api/v1/dlq/{id}.
+// Returns a dead letter queue entry with the original message summary, the
+// failure reason and the message's delivery attempts.
+func GetDLQEntryHandler(dlq queue.DeadLetterQueue, queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := dlqGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		entry, err := dlq.Get(r.Context(), groupID.String(), chi.URLParam(r, "id"))
+		if errors.Is(err, queue.ErrDLQEntryNotFound) || (err == nil && !dlqEntryInGroup(*entry, groupID)) {
+			respondError(w, http.StatusNotFound, "dlq entry not found")
+			return
+		}
+		if err != nil {
+			log := logger.FromContext(r.Context())
+			log.Error().Err(err).Stringer("group_id", groupID).Msg("dlq get failed")
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := dlqEntryDetailResponse{
+			dlqEntryResponse: toDLQEntryResponse(r, queries, *entry),
+			RetryHistory:     entry.Message.RetryHistory,
+			Attempts:         []deliveryAttemptResponse{},
+		}
+		if resp.RetryHistory == nil {
+			resp.RetryHistory = []string{}
+		}
+		if messageID, err := uuid.Parse(entry.Message.OriginalMessage.ID); err == nil {
+			logs, err := queries.ListDeliveryLogsByMessageID(r.Context(), messageID)
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			for _, l := range logs {
+				resp.Attempts = append(resp.Attempts, toDeliveryAttemptResponse(l))
+			}
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
 // DLQReprocessHandler handles POST /api/v1/dlq/reprocess.
-// It re-enqueues messages from the dead letter queue back to the primary queue.
-func DLQReprocessHandler(dlq queue.DeadLetterQueue) http.HandlerFunc {
+// It re-enqueues messages from the dead letter queue back to the primary
+// queue, optionally routing them through a different provider. Requires
+// group admin+ role.
+func DLQReprocessHandler(dlq queue.DeadLetterQueue, queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.FromContext(r.Context())
 
-		accountID := auth.AccountFromContext(r.Context())
-		if accountID == uuid.Nil {
+		if auth.GroupIDFromContext(r.Context()) == uuid.Nil {
 			respondError(w, http.StatusUnauthorized, "unauthorized")
 			return
 		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
 
 		var req dlqReprocessRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -44,11 +165,28 @@ func DLQReprocessHandler(dlq queue.DeadLetterQueue) http.HandlerFunc {
 			respondError(w, http.StatusBadRequest, "message_ids is required and must not be empty")
 			return
 		}
+		if len(req.MessageIDs) > maxDLQReprocess {
+			respondError(w, http.StatusBadRequest, "message_ids must not contain more than 100 entries")
+			return
+		}
 
-		// Use the account ID as tenant ID for DLQ lookup.
-		tenantID := accountID.String()
+		groupID, ok := dlqGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		var opts queue.ReprocessOptions
+		if req.ProviderID != "" {
+			if !validReprocessProvider(w, r, queries, groupID, req.ProviderID) {
+				return
+			}
+			opts.ProviderID = req.ProviderID
+		}
 
-		reprocessed, err := dlq.Reprocess(r.Context(), tenantID, req.MessageIDs)
+		// Reprocess skips entries of other groups; a shared DLQ (SQS) holds
+		// every group's messages.
+		tenantID := groupID.String()
+		reprocessed, err := dlq.Reprocess(r.Context(), tenantID, req.MessageIDs, opts)
 		if err != nil {
 			log.Error().Err(err).
 				Str("tenant_id", tenantID).
@@ -61,6 +199,7 @@ func DLQReprocessHandler(dlq queue.DeadLetterQueue) http.HandlerFunc {
 
 		log.Info().
 			Str("tenant_id", tenantID).
+			Str("provider_id", opts.ProviderID).
 			Int("reprocessed", reprocessed).
 			Int("total", len(req.MessageIDs)).
 			Msg("dlq reprocess completed")
@@ -71,3 +210,78 @@ func DLQReprocessHandler(dlq queue.DeadLetterQueue) http.HandlerFunc {
 		})
 	}
 }
+
+// dlqGroupID returns the group whose DLQ the request addresses, writing an
+// error response when the caller is unauthenticated or may not access it.
+func dlqGroupID(w http.ResponseWriter, r *http.Request, queries storage.Querier) (uuid.UUID, bool) {
+	if auth.GroupIDFromContext(r.Context()) == uuid.Nil {
+		respondError(w, http.StatusUnauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	return requestGroupID(w, r, queries)
+}
+
+// dlqEntryInGroup reports whether a DLQ entry belongs to groupID. Queue
+// messages carry their group ID as the tenant ID.
+func dlqEntryInGroup(e queue.DLQEntry, groupID uuid.UUID) bool {
+	return e.Message.OriginalMessage != nil && e.Message.OriginalMessage.TenantID == groupID.String()
+}
+
+// validReprocessProvider checks that providerID names an enabled provider
+// that serves groupID, i.e. one owned by the group or an ancestor, writing
+// an error response otherwise.
+func validReprocessProvider(w http.ResponseWriter, r *http.Request, queries storage.Querier, groupID uuid.UUID, providerID string) bool {
+	id, err := uuid.Parse(providerID)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid provider_id format")
+		return false
+	}
+	esp, err := queries.GetProviderByID(r.Context(), id)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "provider not found")
+		return false
+	}
+	served, err := auth.InGroupTree(r.Context(), queries, groupID, esp.GroupID)
+	if err != nil || !served {
+		respondError(w, http.StatusBadRequest, "provider not found")
+		return false
+	}
+	if !esp.Enabled {
+		respondError(w, http.StatusBadRequest, "provider is disabled")
+		return false
+	}
+	return true
+}
+
+// toDLQEntryResponse summarizes a DLQ entry, filling in the message summary
+// from the database for ID-only queue messages.
+func toDLQEntryResponse(r *http.Request, queries storage.Querier, e queue.DLQEntry) dlqEntryResponse {
+	msg := e.Message.OriginalMessage
+	resp := dlqEntryResponse{
+		ID:            e.ID,
+		MessageID:     msg.ID,
+		From:          msg.From,
+		To:            msg.To,
+		Subject:       msg.Subject,
+		FailureReason: e.Message.FailureReason,
+		FinalError:    e.Message.FinalError,
+		RetryCount:    msg.RetryCount,
+		ProviderID:    msg.ProviderID,
+		MovedAt:       e.Message.MovedAt,
+	}
+
+	if messageID, err := uuid.Parse(msg.ID); err == nil {
+		if m, err := queries.GetMessageByID(r.Context(), messageID); err == nil {
+			resp.Status = string(m.Status)
+			if resp.From == "" {
+				resp.From = m.Sender
+				_ = json.Unmarshal(m.Recipients, &resp.To)
+				resp.Subject = m.Subject.String
+			}
+		}
+	}
+	if resp.To == nil {
+		resp.To = []string{}
+	}
+	return resp
+}
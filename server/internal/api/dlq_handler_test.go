@@ -1,23 +1,32 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
 func TestDLQReprocessHandler_Unauthorized(t *testing.T) {
-	// No auth context set -- AccountFromContext returns uuid.Nil
+	// No auth context set -- GroupIDFromContext returns uuid.Nil
 	body := `{"message_ids":["id1","id2"]}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
-	handler := DLQReprocessHandler(nil)
+	handler := DLQReprocessHandler(nil, &mockQuerier{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusUnauthorized {
@@ -34,16 +43,14 @@ func TestDLQReprocessHandler_Unauthorized(t *testing.T) {
 }
 
 func TestDLQReprocessHandler_InvalidJSON(t *testing.T) {
-	accountID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
-
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader("not json"))
 	req.Header.Set("Content-Type", "application/json")
 
-	ctx := setAuthContext(req.Context(), accountID)
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization")
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
-	handler := DLQReprocessHandler(nil)
+	handler := DLQReprocessHandler(nil, &mockQuerier{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -60,17 +67,15 @@ func TestDLQReprocessHandler_InvalidJSON(t *testing.T) {
 }
 
 func TestDLQReprocessHandler_EmptyMessageIDs(t *testing.T) {
-	accountID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
-
 	body := `{"message_ids":[]}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 
-	ctx := setAuthContext(req.Context(), accountID)
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization")
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
-	handler := DLQReprocessHandler(nil)
+	handler := DLQReprocessHandler(nil, &mockQuerier{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -87,21 +92,246 @@ func TestDLQReprocessHandler_EmptyMessageIDs(t *testing.T) {
 }
 
 func TestDLQReprocessHandler_MissingMessageIDsField(t *testing.T) {
-	accountID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
-
 	// Valid JSON but no message_ids field -- Go zero-value for []string is nil, len 0
 	body := `{"reset_retry_count":true}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 
-	ctx := setAuthContext(req.Context(), accountID)
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization")
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
-	handler := DLQReprocessHandler(nil)
+	handler := DLQReprocessHandler(nil, &mockQuerier{})
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
 	}
 }
+
+// mockDLQ implements queue.DeadLetterQueue over an in-memory list.
+type mockDLQ struct {
+	entries          []queue.DLQEntry
+	reprocessedIDs   []string
+	reprocessOptions queue.ReprocessOptions
+}
+
+func (m *mockDLQ) MoveToDLQ(_ context.Context, _ *queue.Message, _ string) error { return nil }
+
+func (m *mockDLQ) List(_ context.Context, _ string, limit int) ([]queue.DLQEntry, error) {
+	return m.entries[:min(limit, len(m.entries))], nil
+}
+
+func (m *mockDLQ) Get(_ context.Context, _, entryID string) (*queue.DLQEntry, error) {
+	for i := range m.entries {
+		if m.entries[i].ID == entryID {
+			return &m.entries[i], nil
+		}
+	}
+	return nil, queue.ErrDLQEntryNotFound
+}
+
+func (m *mockDLQ) Reprocess(_ context.Context, tenantID string, entryIDs []string, opts queue.ReprocessOptions) (int, error) {
+	m.reprocessOptions = opts
+	for _, e := range m.entries {
+		if slices.Contains(entryIDs, e.ID) && e.Message.OriginalMessage.TenantID == tenantID {
+			m.reprocessedIDs = append(m.reprocessedIDs, e.ID)
+		}
+	}
+	return len(m.reprocessedIDs), nil
+}
+
+// newTestDLQ returns a DLQ with an ID-only entry of the test group, a
+// legacy full-payload entry of the test group and an entry of another group.
+func newTestDLQ(messageID uuid.UUID) *mockDLQ {
+	tenant := testGroup().ID.String()
+	movedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &mockDLQ{entries: []queue.DLQEntry{
+		{ID: "1-0", Message: queue.DLQMessage{
+			OriginalMessage: &queue.Message{ID: messageID.String(), TenantID: tenant, RetryCount: 5},
+			FailureReason:   "provider send: 503 service unavailable",
+			FinalError:      "provider send: 503 service unavailable",
+			MovedAt:         movedAt,
+		}},
+		{ID: "2-0", Message: queue.DLQMessage{
+			OriginalMessage: &queue.Message{ID: "legacy-1", TenantID: tenant, From: "old@example.com", To: []string{"to@example.com"}, Subject: "Legacy"},
+			FailureReason:   "provider send: 400",
+			MovedAt:         movedAt,
+		}},
+		{ID: "3-0", Message: queue.DLQMessage{
+			OriginalMessage: &queue.Message{ID: uuid.NewString(), TenantID: uuid.NewString()},
+			FailureReason:   "other group",
+			MovedAt:         movedAt,
+		}},
+	}}
+}
+
+func TestListDLQHandler_DecodesEntries(t *testing.T) {
+	messageID := uuid.New()
+	mock := &mockQuerier{
+		getMessageByIDFn: func(_ context.Context, id uuid.UUID) (storage.Message, error) {
+			if id != messageID {
+				t.Errorf("unexpected message lookup %s", id)
+			}
+			return storage.Message{
+				ID:         messageID,
+				Sender:     "sender@example.com",
+				Recipients: []byte(`["a@example.com","b@example.com"]`),
+				Subject:    sql.NullString{String: "Invoice", Valid: true},
+				Status:     storage.MessageStatusFailed,
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/dlq", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+	rec := httptest.NewRecorder()
+	ListDLQHandler(newTestDLQ(messageID), mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp []dlqEntryResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 2 {
+		t.Fatalf("expected the other group's entry to be filtered out, got %d entries", len(resp))
+	}
+
+	idOnly := resp[0]
+	if idOnly.ID != "1-0" || idOnly.From != "sender@example.com" || idOnly.Subject != "Invoice" || len(idOnly.To) != 2 {
+		t.Errorf("expected summary from the messages table, got %+v", idOnly)
+	}
+	if idOnly.Status != "failed" || idOnly.RetryCount != 5 || idOnly.FailureReason != "provider send: 503 service unavailable" {
+		t.Errorf("unexpected failure details: %+v", idOnly)
+	}
+
+	legacy := resp[1]
+	if legacy.From != "old@example.com" || legacy.Subject != "Legacy" {
+		t.Errorf("expected summary from the queue payload, got %+v", legacy)
+	}
+}
+
+func TestGetDLQEntryHandler_AttemptHistory(t *testing.T) {
+	messageID := uuid.New()
+	mock := &mockQuerier{
+		listDeliveryLogsByMessageIDFn: func(_ context.Context, id uuid.UUID) ([]storage.DeliveryLog, error) {
+			return []storage.DeliveryLog{
+				{MessageID: id, AttemptNumber: 1, Status: "failed", Provider: sql.NullString{String: "sendgrid", Valid: true}, LastError: pgtype.Text{String: "503", Valid: true}},
+				{MessageID: id, AttemptNumber: 2, Status: "failed", Provider: sql.NullString{String: "sendgrid", Valid: true}, ResponseCode: pgtype.Int4{Int32: 503, Valid: true}},
+			}, nil
+		},
+	}
+	dlq := newTestDLQ(messageID)
+
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dlq/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization")
+		req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		GetDLQEntryHandler(dlq, mock).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("1-0")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp dlqEntryDetailResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.MessageID != messageID.String() || len(resp.Attempts) != 2 {
+		t.Fatalf("unexpected entry: %+v", resp)
+	}
+	if resp.Attempts[0].Error != "503" || resp.Attempts[1].ResponseCode == nil || *resp.Attempts[1].ResponseCode != 503 {
+		t.Errorf("unexpected attempts: %+v", resp.Attempts)
+	}
+
+	if rec := get("3-0"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another group's entry, got %d", rec.Code)
+	}
+	if rec := get("9-0"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown entry, got %d", rec.Code)
+	}
+}
+
+func TestDLQReprocessHandler_ProviderOverride(t *testing.T) {
+	providerID := uuid.New()
+	mock := &mockQuerier{
+		getProviderByIDFn: func(_ context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return storage.EspProvider{ID: id, GroupID: testGroup().ID, Enabled: true}, nil
+		},
+	}
+	dlq := newTestDLQ(uuid.New())
+
+	body := `{"message_ids":["1-0","3-0","9-0"],"provider_id":"` + providerID.String() + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader(body))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+	rec := httptest.NewRecorder()
+	DLQReprocessHandler(dlq, mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(dlq.reprocessedIDs) != 1 || dlq.reprocessedIDs[0] != "1-0" {
+		t.Errorf("expected only the group's entry to be reprocessed, got %v", dlq.reprocessedIDs)
+	}
+	if dlq.reprocessOptions.ProviderID != providerID.String() {
+		t.Errorf("expected provider override %s, got %q", providerID, dlq.reprocessOptions.ProviderID)
+	}
+}
+
+func TestDLQReprocessHandler_RequiresAdmin(t *testing.T) {
+	dlq := newTestDLQ(uuid.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader(`{"message_ids":["1-0"]}`))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+	rec := httptest.NewRecorder()
+	DLQReprocessHandler(dlq, &mockQuerier{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+	if dlq.reprocessedIDs != nil {
+		t.Error("expected nothing to be reprocessed")
+	}
+}
+
+func TestDLQReprocessHandler_InvalidProvider(t *testing.T) {
+	otherGroup := uuid.New()
+	tests := []struct {
+		name     string
+		provider storage.EspProvider
+		want     string
+	}{
+		{"other group", storage.EspProvider{GroupID: otherGroup, Enabled: true}, "provider not found"},
+		{"disabled", storage.EspProvider{GroupID: testGroup().ID}, "provider is disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getProviderByIDFn: func(_ context.Context, _ uuid.UUID) (storage.EspProvider, error) {
+					return tt.provider, nil
+				},
+			}
+			dlq := newTestDLQ(uuid.New())
+
+			body := `{"message_ids":["1-0"],"provider_id":"` + uuid.NewString() + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/dlq/reprocess", strings.NewReader(body))
+			req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+			rec := httptest.NewRecorder()
+			DLQReprocessHandler(dlq, mock).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("expected 400 %q, got %d %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if dlq.reprocessedIDs != nil {
+				t.Error("expected nothing to be reprocessed")
+			}
+		})
+	}
+}
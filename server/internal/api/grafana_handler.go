@@ -0,0 +1,262 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// The Grafana handlers implement the HTTP API of the Grafana JSON
+// datasource plugin (and the older SimpleJSON plugin), so dashboards can
+// chart delivery stats without database access.
+
+// grafanaMinInterval and grafanaMaxPoints bound the buckets of a query: a
+// panel's interval is widened until the range fits in grafanaMaxPoints.
+const (
+	grafanaMinInterval = time.Minute
+	grafanaMaxPoints   = 2000
+)
+
+// grafanaMetric is a time series served to Grafana.
+type grafanaMetric struct {
+	Label string
+	// Value returns the series value of a bucket and whether the bucket
+	// has one. Counts are zero-filled; latencies only exist for buckets
+	// with deliveries.
+	Value func(row storage.DeliveryTimeSeriesRow) (float64, bool)
+}
+
+// grafanaMetrics are keyed by target name, in the order listed.
+var (
+	grafanaMetricNames = []string{"sends", "deliveries", "bounces", "complaints", "failures", "latency_p50", "latency_p95", "duration_avg"}
+	grafanaMetrics     = map[string]grafanaMetric{
+		"sends": {"Sends (delivery attempts)", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return float64(r.Attempts), true
+		}},
+		"deliveries": {"Deliveries", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return float64(r.Delivered), true
+		}},
+		"bounces": {"Bounces", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return float64(r.Bounced), true
+		}},
+		"complaints": {"Complaints", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return float64(r.Complained), true
+		}},
+		"failures": {"Failures", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return float64(r.Failed), true
+		}},
+		"latency_p50": {"Delivery latency p50 (seconds)", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return r.P50Seconds, r.Delivered > 0
+		}},
+		"latency_p95": {"Delivery latency p95 (seconds)", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return r.P95Seconds, r.Delivered > 0
+		}},
+		"duration_avg": {"Provider call duration avg (ms)", func(r storage.DeliveryTimeSeriesRow) (float64, bool) {
+			return r.AvgDurationMs, r.Attempts > 0
+		}},
+	}
+)
+
+// GrafanaHealthHandler handles GET /api/v1/stats/grafana, which Grafana
+// calls to test the datasource.
+func GrafanaHealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// grafanaPayloadOption describes a per-target option shown in the query
+// editor.
+type grafanaPayloadOption struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Type        string `json:"type"`
+	Placeholder string `json:"placeholder,omitempty"`
+}
+
+// grafanaMetricResponse is one entry of the POST /metrics response.
+type grafanaMetricResponse struct {
+	Label    string                 `json:"label"`
+	Value    string                 `json:"value"`
+	Payloads []grafanaPayloadOption `json:"payloads"`
+}
+
+// GrafanaMetricsHandler handles POST /api/v1/stats/grafana/metrics.
+// Lists the available series and their options: group_id (the caller's
+// group or a sub-group) and provider (a provider type such as sendgrid).
+func GrafanaMetricsHandler() http.HandlerFunc {
+	payloads := []grafanaPayloadOption{
+		{Name: "group_id", Label: "Group ID", Type: "input", Placeholder: "caller's group"},
+		{Name: "provider", Label: "Provider", Type: "input", Placeholder: "all providers"},
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := make([]grafanaMetricResponse, len(grafanaMetricNames))
+		for i, name := range grafanaMetricNames {
+			resp[i] = grafanaMetricResponse{Label: grafanaMetrics[name].Label, Value: name, Payloads: payloads}
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// GrafanaSearchHandler handles POST /api/v1/stats/grafana/search for the
+// SimpleJSON plugin, which lists series by name only.
+func GrafanaSearchHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, grafanaMetricNames)
+	}
+}
+
+// grafanaQueryRequest is the body Grafana posts to /query.
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64 `json:"intervalMs"`
+	MaxDataPoints int   `json:"maxDataPoints"`
+	Targets       []struct {
+		Target  string `json:"target"`
+		RefID   string `json:"refId"`
+		Hide    bool   `json:"hide"`
+		Payload struct {
+			GroupID  string `json:"group_id"`
+			Provider string `json:"provider"`
+		} `json:"payload"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is a time series in the /query response. Each datapoint
+// is [value, unix milliseconds].
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaQueryHandler handles POST /api/v1/stats/grafana/query.
+// Returns each target's series over the requested range, bucketed by the
+// panel interval (at least one minute, widened to at most 2000 points).
+// Targets name a series from /metrics; the payload's group_id selects a
+// sub-group and provider filters by provider type.
+func GrafanaQueryHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req grafanaQueryRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		from, to := req.Range.From, req.Range.To
+		if from.IsZero() || to.IsZero() || !from.Before(to) {
+			respondError(w, http.StatusBadRequest, "range.from must be before range.to")
+			return
+		}
+		if to.Sub(from) > maxStatsRangeDays*24*time.Hour {
+			respondError(w, http.StatusBadRequest, "range must not exceed 366 days")
+			return
+		}
+		bucket := grafanaBucket(from, to, time.Duration(req.IntervalMs)*time.Millisecond, req.MaxDataPoints)
+
+		type seriesKey struct {
+			groupID  uuid.UUID
+			provider string
+		}
+		loaded := make(map[seriesKey][]storage.DeliveryTimeSeriesRow)
+		resp := []grafanaSeries{}
+		for _, t := range req.Targets {
+			if t.Hide || t.Target == "" {
+				continue
+			}
+			metric, ok := grafanaMetrics[t.Target]
+			if !ok {
+				respondError(w, http.StatusBadRequest, "unknown target: "+t.Target)
+				return
+			}
+			key := seriesKey{groupID: auth.GroupIDFromContext(r.Context()), provider: t.Payload.Provider}
+			if t.Payload.GroupID != "" {
+				id, err := uuid.Parse(t.Payload.GroupID)
+				if err != nil {
+					respondError(w, http.StatusBadRequest, "invalid group_id format")
+					return
+				}
+				if !canAccessGroup(r.Context(), queries, id) {
+					respondError(w, http.StatusForbidden, "access denied")
+					return
+				}
+				key.groupID = id
+			}
+
+			rows, ok := loaded[key]
+			if !ok {
+				var err error
+				rows, err = queries.DeliveryTimeSeries(r.Context(), storage.DeliveryTimeSeriesParams{
+					BucketSeconds: bucket.Seconds(),
+					GroupID:       pgtype.UUID{Bytes: key.groupID, Valid: true},
+					Since:         pgtype.Timestamptz{Time: from, Valid: true},
+					Until:         pgtype.Timestamptz{Time: to, Valid: true},
+					Provider:      pgtype.Text{String: key.provider, Valid: key.provider != ""},
+				})
+				if err != nil {
+					respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+					return
+				}
+				loaded[key] = rows
+			}
+
+			name := t.Target
+			if key.provider != "" {
+				name += " (" + key.provider + ")"
+			}
+			resp = append(resp, grafanaSeries{
+				Target:     name,
+				RefID:      t.RefID,
+				Datapoints: grafanaDatapoints(rows, metric, from, to, bucket),
+			})
+		}
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// grafanaBucket returns the bucket width for a query: the panel interval,
+// at least grafanaMinInterval, widened so the range has at most maxPoints
+// (capped at grafanaMaxPoints) buckets. Widths are rounded up to whole
+// minutes.
+func grafanaBucket(from, to time.Time, interval time.Duration, maxPoints int) time.Duration {
+	if maxPoints <= 0 || maxPoints > grafanaMaxPoints {
+		maxPoints = grafanaMaxPoints
+	}
+	if floor := to.Sub(from) / time.Duration(maxPoints); interval < floor {
+		interval = floor
+	}
+	if interval < grafanaMinInterval {
+		interval = grafanaMinInterval
+	}
+	return (interval + time.Minute - 1).Truncate(time.Minute)
+}
+
+// grafanaDatapoints turns rows into a metric's datapoints. Rows only exist
+// for buckets with attempts, so count metrics get zeros for the buckets
+// in between.
+func grafanaDatapoints(rows []storage.DeliveryTimeSeriesRow, metric grafanaMetric, from, to time.Time, bucket time.Duration) [][2]float64 {
+	byBucket := make(map[int64]storage.DeliveryTimeSeriesRow, len(rows))
+	for _, row := range rows {
+		byBucket[row.Bucket.Time.Unix()] = row
+	}
+	// Buckets start at multiples of the width since the Unix epoch, as in
+	// the query.
+	width := int64(bucket.Seconds())
+	start := time.Unix(from.Unix()/width*width, 0)
+	points := [][2]float64{}
+	for t := start; t.Before(to); t = t.Add(bucket) {
+		row := byBucket[t.Unix()]
+		if v, ok := metric.Value(row); ok {
+			points = append(points, [2]float64{v, float64(t.UnixMilli())})
+		}
+	}
+	return points
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func grafanaRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/stats/grafana/query", strings.NewReader(body))
+	return req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+}
+
+func TestGrafanaQueryHandler(t *testing.T) {
+	from := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	var calls []storage.DeliveryTimeSeriesParams
+	mock := &mockQuerier{
+		deliveryTimeSeriesFn: func(ctx context.Context, arg storage.DeliveryTimeSeriesParams) ([]storage.DeliveryTimeSeriesRow, error) {
+			calls = append(calls, arg)
+			return []storage.DeliveryTimeSeriesRow{{
+				Bucket:     pgtype.Timestamptz{Time: from.Add(10 * time.Minute), Valid: true},
+				Attempts:   5,
+				Delivered:  4,
+				Bounced:    1,
+				P95Seconds: 2.5,
+			}}, nil
+		},
+	}
+
+	body := `{"range":{"from":"2026-03-01T10:00:00Z","to":"2026-03-01T10:30:00Z"},"intervalMs":1000,"maxDataPoints":500,
+		"targets":[{"refId":"A","target":"deliveries","payload":{"provider":"ses"}},
+		{"refId":"B","target":"latency_p95","payload":{"provider":"ses"}},
+		{"refId":"C","target":"bounces","hide":true}]}`
+	rec := httptest.NewRecorder()
+	GrafanaQueryHandler(mock).ServeHTTP(rec, grafanaRequest(body))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(calls) != 1 {
+		t.Fatalf("expected targets with the same group and provider to share one query, got %d", len(calls))
+	}
+	if c := calls[0]; c.BucketSeconds != 60 || c.Provider.String != "ses" || uuid.UUID(c.GroupID.Bytes) != testGroup().ID {
+		t.Errorf("unexpected query params: %+v", c)
+	}
+
+	var resp []grafanaSeries
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 2 || resp[0].Target != "deliveries (ses)" || resp[0].RefID != "A" {
+		t.Fatalf("unexpected series: %+v", resp)
+	}
+	if len(resp[0].Datapoints) != 30 {
+		t.Errorf("deliveries has %d points, want 30 zero-filled minutes", len(resp[0].Datapoints))
+	}
+	if p := resp[0].Datapoints[10]; p[0] != 4 || p[1] != float64(from.Add(10*time.Minute).UnixMilli()) {
+		t.Errorf("datapoint = %v, want 4 at 10:10", p)
+	}
+	if len(resp[1].Datapoints) != 1 || resp[1].Datapoints[0][0] != 2.5 {
+		t.Errorf("latency datapoints = %v, want only the bucket with deliveries", resp[1].Datapoints)
+	}
+}
+
+func TestGrafanaQueryHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"invalid body", `{`, http.StatusBadRequest},
+		{"missing range", `{"targets":[{"target":"sends"}]}`, http.StatusBadRequest},
+		{"range too long", `{"range":{"from":"2024-01-01T00:00:00Z","to":"2026-01-01T00:00:00Z"},"targets":[{"target":"sends"}]}`, http.StatusBadRequest},
+		{"unknown target", `{"range":{"from":"2026-01-01T00:00:00Z","to":"2026-01-02T00:00:00Z"},"targets":[{"target":"opens"}]}`, http.StatusBadRequest},
+		{"foreign group", `{"range":{"from":"2026-01-01T00:00:00Z","to":"2026-01-02T00:00:00Z"},"targets":[{"target":"sends","payload":{"group_id":"` + uuid.NewString() + `"}}]}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+					return storage.Group{ID: id, GroupType: "company"}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			GrafanaQueryHandler(mock).ServeHTTP(rec, grafanaRequest(tt.body))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGrafanaBucket(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := grafanaBucket(from, from.Add(time.Hour), 15*time.Second, 1000); got != time.Minute {
+		t.Errorf("short interval = %v, want the 1m floor", got)
+	}
+	if got := grafanaBucket(from, from.AddDate(0, 0, 30), time.Minute, 720); got != time.Hour {
+		t.Errorf("30 days in 720 points = %v, want 1h", got)
+	}
+	if got := grafanaBucket(from, from.AddDate(0, 0, 365), time.Minute, 0); got > 5*time.Hour || got < 4*time.Hour {
+		t.Errorf("a year without maxDataPoints = %v, want about 4h24m", got)
+	}
+}
+
+func TestGrafanaMetricsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GrafanaMetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/stats/grafana/metrics", nil))
+
+	var resp []grafanaMetricResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != len(grafanaMetricNames) || resp[0].Value != "sends" || len(resp[0].Payloads) != 2 {
+		t.Errorf("unexpected metrics: %+v", resp)
+	}
+}
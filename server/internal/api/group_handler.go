@@ -1,20 +1,30 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/i18n"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
-// createGroupRequest is the JSON body for POST /api/v1/groups.
+// maxGroupDepth is the maximum number of levels in a group hierarchy,
+// counting the top-level group.
+const maxGroupDepth = 8
+
+// createGroupRequest is the JSON body for POST /api/v1/groups and
+// POST /api/v1/groups/{id}/subgroups.
 type createGroupRequest struct {
 	Name         string `json:"name"`
 	MonthlyLimit int32  `json:"monthly_limit,omitempty"`
+	ParentID     string `json:"parent_id,omitempty"`
 }
 
 // addMemberRequest is the JSON body for POST /api/v1/groups/{id}/members.
@@ -28,16 +38,55 @@ type updateMemberRoleRequest struct {
 	Role string `json:"role"`
 }
 
+// updateGroupSettingsRequest is the JSON body for PATCH /api/v1/groups/{id}/settings.
+// Omitted fields keep their current value.
+type updateGroupSettingsRequest struct {
+	SanitizeHTML        *bool   `json:"sanitize_html"`
+	InlineCSS           *bool   `json:"inline_css"`
+	RecipientValidation *string `json:"recipient_validation"`
+	// Sandbox may only be changed by system admins.
+	Sandbox *bool `json:"sandbox"`
+	// Locale is the language of system emails sent on the group's behalf.
+	Locale *string `json:"locale"`
+}
+
+// recipientValidationPolicies are the accepted recipient_validation values.
+var recipientValidationPolicies = map[string]bool{"off": true, "tag": true, "reject": true}
+
 // groupResponse is the JSON response for a group.
 type groupResponse struct {
-	ID           uuid.UUID `json:"id"`
-	Name         string    `json:"name"`
-	GroupType    string    `json:"group_type"`
-	Status       string    `json:"status"`
-	MonthlyLimit int32     `json:"monthly_limit"`
-	MonthlySent  int32     `json:"monthly_sent"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	GroupType    string     `json:"group_type"`
+	Status       string     `json:"status"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	MonthlyLimit int32      `json:"monthly_limit"`
+	MonthlySent  int32      `json:"monthly_sent"`
+	SanitizeHTML bool       `json:"sanitize_html"`
+	InlineCSS    bool       `json:"inline_css"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// RecipientValidation is the policy for risky recipients at RCPT TO:
+	// off, tag or reject.
+	RecipientValidation string `json:"recipient_validation"`
+	// Sandbox restricts SMTP recipients to the group's verified members.
+	Sandbox bool `json:"sandbox"`
+	// Locale is the language of system emails sent on the group's behalf.
+	Locale string `json:"locale"`
+}
+
+// groupUsageResponse is the JSON response for GET /api/v1/groups/{id}/usage.
+// A monthly_limit of 0 means unlimited; remaining is then omitted.
+// limit_inherited is set when the limit comes from an ancestor group.
+type groupUsageResponse struct {
+	GroupID        uuid.UUID `json:"group_id"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	MonthlyLimit   int32     `json:"monthly_limit"`
+	LimitInherited bool      `json:"limit_inherited,omitempty"`
+	Sent           int64     `json:"sent"`
+	Remaining      *int64    `json:"remaining,omitempty"`
+	PercentUsed    float64   `json:"percent_used"`
 }
 
 // groupMemberResponse is the JSON response for a group member.
@@ -51,16 +100,26 @@ type groupMemberResponse struct {
 
 // toGroupResponse converts a storage.Group to a groupResponse.
 func toGroupResponse(g storage.Group) groupResponse {
-	return groupResponse{
-		ID:           g.ID,
-		Name:         g.Name,
-		GroupType:    g.GroupType,
-		Status:       g.Status,
-		MonthlyLimit: g.MonthlyLimit,
-		MonthlySent:  g.MonthlySent,
-		CreatedAt:    timestampToTime(g.CreatedAt),
-		UpdatedAt:    timestampToTime(g.UpdatedAt),
+	resp := groupResponse{
+		ID:                  g.ID,
+		Name:                g.Name,
+		GroupType:           g.GroupType,
+		Status:              g.Status,
+		MonthlyLimit:        g.MonthlyLimit,
+		MonthlySent:         g.MonthlySent,
+		SanitizeHTML:        g.SanitizeHtml,
+		InlineCSS:           g.InlineCss,
+		CreatedAt:           timestampToTime(g.CreatedAt),
+		UpdatedAt:           timestampToTime(g.UpdatedAt),
+		RecipientValidation: g.RecipientValidation,
+		Sandbox:             g.Sandbox,
+		Locale:              g.Locale,
+	}
+	if g.ParentID.Valid {
+		parentID := uuid.UUID(g.ParentID.Bytes)
+		resp.ParentID = &parentID
 	}
+	return resp
 }
 
 // toGroupMemberResponse converts a storage.GroupMember to a groupMemberResponse.
@@ -76,6 +135,7 @@ func toGroupMemberResponse(gm storage.GroupMember) groupMemberResponse {
 
 // CreateGroupHandler handles POST /api/v1/groups.
 // Creates a new group with group_type='company' and status='active'.
+// If parent_id is given the group is created as a sub-group of that group.
 // Requires system admin access (group_type == "system").
 func CreateGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -85,51 +145,131 @@ func CreateGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger)
 			return
 		}
 
-		if req.Name == "" {
-			respondError(w, http.StatusBadRequest, "name is required")
-			return
+		var parentID uuid.UUID
+		if req.ParentID != "" {
+			id, err := uuid.Parse(req.ParentID)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid parent_id format")
+				return
+			}
+			parentID = id
 		}
 
-		group, err := queries.CreateGroup(r.Context(), storage.CreateGroupParams{
-			Name:      req.Name,
-			GroupType: "company",
-		})
+		createGroup(w, r, queries, auditLogger, req, parentID)
+	}
+}
+
+// CreateSubGroupHandler handles POST /api/v1/groups/{id}/subgroups.
+// Creates a sub-group (e.g. a team within a company) under the group.
+// Sub-groups inherit the monthly limit and providers of their nearest
+// ancestor until they set their own. Requires system admin access or the
+// admin/owner role in the group or one of its ancestors.
+func CreateSubGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
 		if err != nil {
-			respondError(w, http.StatusConflict, "group name already exists")
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
 			return
 		}
 
-		// If monthly_limit was specified, update it
-		if req.MonthlyLimit > 0 {
-			group, err = queries.UpdateGroup(r.Context(), storage.UpdateGroupParams{
-				ID:           group.ID,
-				Name:         group.Name,
-				Status:       group.Status,
-				MonthlyLimit: req.MonthlyLimit,
-			})
-			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
-				return
-			}
+		callerGroupType := auth.GroupTypeFromContext(r.Context())
+		callerRole := auth.RoleFromContext(r.Context())
+		if !canAccessGroup(r.Context(), queries, id) || (callerGroupType != "system" && callerRole != "admin" && callerRole != "owner") {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
 		}
 
-		if auditLogger != nil {
-			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateGroup, "group", group.ID.String(), map[string]interface{}{
-				"name": req.Name,
-			})
+		var req createGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
 		}
 
-		respondJSON(w, http.StatusCreated, toGroupResponse(group))
+		createGroup(w, r, queries, auditLogger, req, id)
 	}
 }
 
+// createGroup creates the group described by req, as a sub-group of
+// parentID unless it is uuid.Nil, and writes the response.
+func createGroup(w http.ResponseWriter, r *http.Request, queries storage.Querier, auditLogger *auth.AuditLogger, req createGroupRequest, parentID uuid.UUID) {
+	if req.Name == "" {
+		respondError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	var parent pgtype.UUID
+	if parentID != uuid.Nil {
+		p, err := queries.GetGroupByID(r.Context(), parentID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "parent group not found")
+			return
+		}
+		if p.GroupType == "system" || p.Status != "active" {
+			respondError(w, http.StatusBadRequest, "parent group cannot have sub-groups")
+			return
+		}
+		if p.Sandbox {
+			// A sub-group would escape the sandbox's recipient restriction.
+			respondError(w, http.StatusBadRequest, "sandboxed groups cannot have sub-groups")
+			return
+		}
+		path, err := auth.GroupPath(r.Context(), queries, parentID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if len(path) >= maxGroupDepth {
+			respondError(w, http.StatusBadRequest, "maximum group nesting depth exceeded")
+			return
+		}
+		parent = pgtype.UUID{Bytes: parentID, Valid: true}
+	}
+
+	group, err := queries.CreateGroup(r.Context(), storage.CreateGroupParams{
+		Name:      req.Name,
+		GroupType: "company",
+		ParentID:  parent,
+	})
+	if err != nil {
+		respondStorageError(w, err, http.StatusConflict, "group name already exists")
+		return
+	}
+
+	// If monthly_limit was specified, update it
+	if req.MonthlyLimit > 0 {
+		group, err = queries.UpdateGroup(r.Context(), storage.UpdateGroupParams{
+			ID:           group.ID,
+			Name:         group.Name,
+			Status:       group.Status,
+			MonthlyLimit: req.MonthlyLimit,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	if auditLogger != nil {
+		details := map[string]interface{}{
+			"name": req.Name,
+		}
+		if parent.Valid {
+			details["parent_id"] = parentID.String()
+		}
+		auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateGroup, "group", group.ID.String(), details)
+	}
+
+	respondJSON(w, http.StatusCreated, toGroupResponse(group))
+}
+
 // ListGroupsHandler handles GET /api/v1/groups.
 // Lists all groups. Requires system admin access.
 func ListGroupsHandler(queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		groups, err := queries.ListGroups(r.Context())
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -154,16 +294,14 @@ func GetGroupHandler(queries storage.Querier) http.HandlerFunc {
 		}
 
 		// Verify the requesting user has access to this group
-		callerGroupID := auth.GroupIDFromContext(r.Context())
-		callerGroupType := auth.GroupTypeFromContext(r.Context())
-		if callerGroupType != "system" && callerGroupID != id {
+		if !canAccessGroup(r.Context(), queries, id) {
 			respondError(w, http.StatusForbidden, "access denied")
 			return
 		}
 
 		group, err := queries.GetGroupByID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "group not found")
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
 			return
 		}
 
@@ -171,10 +309,229 @@ func GetGroupHandler(queries storage.Querier) http.HandlerFunc {
 	}
 }
 
+// ListSubGroupsHandler handles GET /api/v1/groups/{id}/subgroups.
+// Lists the direct sub-groups of a group.
+func ListSubGroupsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+
+		if !canAccessGroup(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		groups, err := queries.ListSubGroups(r.Context(), pgtype.UUID{Bytes: id, Valid: true})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]groupResponse, len(groups))
+		for i, g := range groups {
+			resp[i] = toGroupResponse(g)
+		}
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// effectiveMonthlyLimit returns the group's monthly limit or, when it has
+// none of its own, the limit of its nearest ancestor that has one. The
+// boolean reports whether the limit was inherited.
+func effectiveMonthlyLimit(ctx context.Context, queries storage.Querier, g storage.Group) (int32, bool, error) {
+	if g.MonthlyLimit > 0 || !g.ParentID.Valid {
+		return g.MonthlyLimit, false, nil
+	}
+	ancestors, err := queries.ListGroupAncestors(ctx, g.ID)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, a := range ancestors {
+		if a.ID != g.ID && a.MonthlyLimit > 0 {
+			return a.MonthlyLimit, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// GetGroupUsageHandler handles GET /api/v1/groups/{id}/usage.
+// Returns the messages accepted for the group in the current calendar month
+// (UTC) against its effective monthly limit.
+func GetGroupUsageHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+
+		if !canAccessGroup(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		group, err := queries.GetGroupByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+
+		limit, inherited, err := effectiveMonthlyLimit(r.Context(), queries, group)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		now := time.Now().UTC()
+		periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		sent, err := queries.CountGroupMessagesSince(r.Context(), storage.CountGroupMessagesSinceParams{
+			GroupID:    pgtype.UUID{Bytes: id, Valid: true},
+			EnqueuedAt: pgtype.Timestamptz{Time: periodStart, Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := groupUsageResponse{
+			GroupID:        id,
+			PeriodStart:    periodStart,
+			PeriodEnd:      periodStart.AddDate(0, 1, 0),
+			MonthlyLimit:   limit,
+			LimitInherited: inherited,
+			Sent:           sent,
+		}
+		if limit > 0 {
+			remaining := max(int64(limit)-sent, 0)
+			resp.Remaining = &remaining
+			resp.PercentUsed = float64(sent) * 100 / float64(limit)
+		}
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// UpdateGroupSettingsHandler handles PATCH /api/v1/groups/{id}/settings.
+// Updates the group's HTML processing settings, which the worker applies to
+// HTML bodies before handing them to the ESP, and its recipient validation
+// policy, which the SMTP server applies at RCPT TO, and the locale of its
+// system emails. Requires system admin
+// access or the admin/owner role in the group; only system admins may
+// change sandbox.
+func UpdateGroupSettingsHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+
+		callerGroupType := auth.GroupTypeFromContext(r.Context())
+		callerRole := auth.RoleFromContext(r.Context())
+		if !canAccessGroup(r.Context(), queries, id) || (callerGroupType != "system" && callerRole != "admin" && callerRole != "owner") {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req updateGroupSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.RecipientValidation != nil && !recipientValidationPolicies[*req.RecipientValidation] {
+			respondError(w, http.StatusBadRequest, "recipient_validation must be off, tag or reject")
+			return
+		}
+		if req.Locale != nil && !i18n.Supported(*req.Locale) {
+			respondError(w, http.StatusBadRequest, "locale must be one of: "+strings.Join(i18n.Locales(), ", "))
+			return
+		}
+		if req.Sandbox != nil && callerGroupType != "system" {
+			respondError(w, http.StatusForbidden, "only system admins can change sandbox")
+			return
+		}
+
+		group, err := queries.GetGroupByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+
+		params := storage.UpdateGroupHTMLProcessingParams{
+			ID:           id,
+			SanitizeHtml: group.SanitizeHtml,
+			InlineCss:    group.InlineCss,
+		}
+		if req.SanitizeHTML != nil {
+			params.SanitizeHtml = *req.SanitizeHTML
+		}
+		if req.InlineCSS != nil {
+			params.InlineCss = *req.InlineCSS
+		}
+
+		updated, err := queries.UpdateGroupHTMLProcessing(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if req.RecipientValidation != nil {
+			updated, err = queries.UpdateGroupRecipientValidation(r.Context(), storage.UpdateGroupRecipientValidationParams{
+				ID:                  id,
+				RecipientValidation: *req.RecipientValidation,
+			})
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+		if req.Sandbox != nil {
+			updated, err = queries.UpdateGroupSandbox(r.Context(), storage.UpdateGroupSandboxParams{
+				ID:      id,
+				Sandbox: *req.Sandbox,
+			})
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+
+		if req.Locale != nil {
+			updated, err = queries.UpdateGroupLocale(r.Context(), storage.UpdateGroupLocaleParams{
+				ID:     id,
+				Locale: *req.Locale,
+			})
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, "admin.update_group_settings", "group", id.String(), map[string]interface{}{
+				"sanitize_html":        updated.SanitizeHtml,
+				"inline_css":           updated.InlineCss,
+				"recipient_validation": updated.RecipientValidation,
+				"sandbox":              updated.Sandbox,
+				"locale":               updated.Locale,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toGroupResponse(updated))
+	}
+}
+
 // DeleteGroupHandler handles DELETE /api/v1/groups/{id}.
 // Soft-deletes a group by setting status='deleted'.
 // Auto-suspends SMTP accounts in the group.
-// Returns 403 if attempting to delete a system group.
+// Returns 403 if attempting to delete a system group and 409 if the group
+// still has sub-groups that are not deleted.
 // Requires system admin access.
 func DeleteGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -188,7 +545,7 @@ func DeleteGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger)
 		// Get group to check type
 		group, err := queries.GetGroupByID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "group not found")
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
 			return
 		}
 
@@ -198,13 +555,25 @@ func DeleteGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger)
 			return
 		}
 
+		subGroups, err := queries.ListSubGroups(r.Context(), pgtype.UUID{Bytes: id, Valid: true})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		for _, sg := range subGroups {
+			if sg.Status != "deleted" {
+				respondError(w, http.StatusConflict, "group has sub-groups; delete them first")
+				return
+			}
+		}
+
 		// Soft-delete: set status to 'deleted'
 		_, err = queries.UpdateGroupStatus(r.Context(), storage.UpdateGroupStatusParams{
 			ID:     id,
 			Status: "deleted",
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -242,16 +611,14 @@ func ListGroupMembersHandler(queries storage.Querier) http.HandlerFunc {
 		}
 
 		// Verify access
-		callerGroupID := auth.GroupIDFromContext(r.Context())
-		callerGroupType := auth.GroupTypeFromContext(r.Context())
-		if callerGroupType != "system" && callerGroupID != id {
+		if !canAccessGroup(r.Context(), queries, id) {
 			respondError(w, http.StatusForbidden, "access denied")
 			return
 		}
 
 		members, err := queries.ListGroupMembersByGroupID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -298,7 +665,7 @@ func AddGroupMemberHandler(queries storage.Querier, auditLogger *auth.AuditLogge
 		// Check if user is an SMTP account already in another group
 		user, err := queries.GetUserByID(r.Context(), userID)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "user not found")
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
 			return
 		}
 
@@ -316,7 +683,7 @@ func AddGroupMemberHandler(queries storage.Querier, auditLogger *auth.AuditLogge
 			Role:    req.Role,
 		})
 		if err != nil {
-			respondError(w, http.StatusConflict, "user is already a member of this group")
+			respondStorageError(w, err, http.StatusConflict, "user is already a member of this group")
 			return
 		}
 
@@ -367,7 +734,7 @@ func UpdateGroupMemberRoleHandler(queries storage.Querier, auditLogger *auth.Aud
 			GroupID: groupID,
 		})
 		if err != nil {
-			respondError(w, http.StatusNotFound, "member not found")
+			respondStorageError(w, err, http.StatusNotFound, "member not found")
 			return
 		}
 
@@ -375,7 +742,7 @@ func UpdateGroupMemberRoleHandler(queries storage.Querier, auditLogger *auth.Aud
 		if member.Role == "owner" && req.Role != "owner" {
 			count, err := queries.CountGroupOwners(r.Context(), groupID)
 			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 				return
 			}
 			if count <= 1 {
@@ -390,7 +757,7 @@ func UpdateGroupMemberRoleHandler(queries storage.Querier, auditLogger *auth.Aud
 			Role: req.Role,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -429,7 +796,7 @@ func RemoveGroupMemberHandler(queries storage.Querier, auditLogger *auth.AuditLo
 			GroupID: groupID,
 		})
 		if err != nil {
-			respondError(w, http.StatusNotFound, "member not found")
+			respondStorageError(w, err, http.StatusNotFound, "member not found")
 			return
 		}
 
@@ -437,7 +804,7 @@ func RemoveGroupMemberHandler(queries storage.Querier, auditLogger *auth.AuditLo
 		if member.Role == "owner" {
 			count, err := queries.CountGroupOwners(r.Context(), groupID)
 			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 				return
 			}
 			if count <= 1 {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -260,6 +261,301 @@ func TestDeleteGroupHandler_SystemGroupForbidden(t *testing.T) {
 	}
 }
 
+func TestGetGroupUsageHandler(t *testing.T) {
+	grp := testGroup()
+	grp.MonthlyLimit = 1000
+	var gotParams storage.CountGroupMessagesSinceParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		countGroupMessagesSinceFn: func(ctx context.Context, arg storage.CountGroupMessagesSinceParams) (int64, error) {
+			gotParams = arg
+			return 850, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/"+grp.ID.String()+"/usage", nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", grp.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, grp.ID, "member", "company")
+	req = req.WithContext(ctx)
+
+	handler := GetGroupUsageHandler(mock)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if start := gotParams.EnqueuedAt.Time; start.Day() != 1 || start.Hour() != 0 {
+		t.Errorf("expected usage counted from the start of the month, got %v", start)
+	}
+
+	var resp groupUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Sent != 850 || resp.MonthlyLimit != 1000 || resp.PercentUsed != 85 {
+		t.Errorf("unexpected usage: %+v", resp)
+	}
+	if resp.Remaining == nil || *resp.Remaining != 150 {
+		t.Errorf("expected 150 remaining, got %v", resp.Remaining)
+	}
+}
+
+func TestGetGroupUsageHandler_Unlimited(t *testing.T) {
+	grp := testGroup()
+	grp.MonthlyLimit = 0
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		countGroupMessagesSinceFn: func(ctx context.Context, arg storage.CountGroupMessagesSinceParams) (int64, error) {
+			return 42, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/"+grp.ID.String()+"/usage", nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", grp.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	systemGroupID := uuid.MustParse("00000000-0000-0000-0000-000000000099")
+	ctx = setJWTContext(ctx, testUser().ID, systemGroupID, "admin", "system")
+	req = req.WithContext(ctx)
+
+	handler := GetGroupUsageHandler(mock)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if _, ok := resp["remaining"]; ok {
+		t.Errorf("expected no remaining for unlimited group, got %v", resp["remaining"])
+	}
+	if resp["sent"] != float64(42) {
+		t.Errorf("expected sent 42, got %v", resp["sent"])
+	}
+}
+
+func TestUpdateGroupSettingsHandler_Valid(t *testing.T) {
+	grp := testGroup()
+	grp.InlineCss = true
+	var got storage.UpdateGroupHTMLProcessingParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		updateGroupHTMLProcessingFn: func(ctx context.Context, arg storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+			got = arg
+			grp.SanitizeHtml = arg.SanitizeHtml
+			grp.InlineCss = arg.InlineCss
+			return grp, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/groups/"+grp.ID.String()+"/settings", strings.NewReader(`{"sanitize_html":true}`))
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", grp.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, grp.ID, "admin", "company")
+	req = req.WithContext(ctx)
+
+	handler := UpdateGroupSettingsHandler(mock, nil)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	// Omitted inline_css keeps its current value.
+	if !got.SanitizeHtml || !got.InlineCss {
+		t.Errorf("unexpected update params: %+v", got)
+	}
+
+	var resp groupResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.SanitizeHTML || !resp.InlineCSS {
+		t.Errorf("expected both settings enabled, got %+v", resp)
+	}
+}
+
+func TestUpdateGroupSettingsHandler_RecipientValidation(t *testing.T) {
+	grp := testGroup()
+	var got storage.UpdateGroupRecipientValidationParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		updateGroupRecipientValidationFn: func(ctx context.Context, arg storage.UpdateGroupRecipientValidationParams) (storage.Group, error) {
+			got = arg
+			grp.RecipientValidation = arg.RecipientValidation
+			return grp, nil
+		},
+	}
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"recipient_validation":"maybe"}`, http.StatusBadRequest},
+		{`{"recipient_validation":"reject"}`, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/groups/"+grp.ID.String()+"/settings", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", grp.ID.String())
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = setJWTContext(ctx, testUser().ID, grp.ID, "owner", "company")
+		req = req.WithContext(ctx)
+
+		UpdateGroupSettingsHandler(mock, nil).ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s: expected status %d, got %d", tt.body, tt.code, rec.Code)
+		}
+	}
+	if got.ID != grp.ID || got.RecipientValidation != "reject" {
+		t.Errorf("unexpected update params: %+v", got)
+	}
+}
+
+func TestUpdateGroupSettingsHandler_Sandbox(t *testing.T) {
+	grp := testGroup()
+	grp.Sandbox = true
+	var got *storage.UpdateGroupSandboxParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		updateGroupHTMLProcessingFn: func(ctx context.Context, arg storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+			return grp, nil
+		},
+		updateGroupSandboxFn: func(ctx context.Context, arg storage.UpdateGroupSandboxParams) (storage.Group, error) {
+			got = &arg
+			grp.Sandbox = arg.Sandbox
+			return grp, nil
+		},
+	}
+
+	for _, tt := range []struct {
+		groupType string
+		role      string
+		code      int
+	}{
+		{"company", "owner", http.StatusForbidden},
+		{"system", "admin", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/groups/"+grp.ID.String()+"/settings", strings.NewReader(`{"sandbox":false}`))
+		rec := httptest.NewRecorder()
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", grp.ID.String())
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = setJWTContext(ctx, testUser().ID, grp.ID, tt.role, tt.groupType)
+		req = req.WithContext(ctx)
+
+		UpdateGroupSettingsHandler(mock, nil).ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.groupType, tt.role, tt.code, rec.Code)
+		}
+		if tt.code == http.StatusForbidden && got != nil {
+			t.Fatal("sandbox changed by a group owner")
+		}
+	}
+	if got == nil || got.ID != grp.ID || got.Sandbox {
+		t.Errorf("unexpected update params: %+v", got)
+	}
+}
+
+func TestUpdateGroupSettingsHandler_Locale(t *testing.T) {
+	grp := testGroup()
+	var got *storage.UpdateGroupLocaleParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		updateGroupHTMLProcessingFn: func(ctx context.Context, arg storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+			return grp, nil
+		},
+		updateGroupLocaleFn: func(ctx context.Context, arg storage.UpdateGroupLocaleParams) (storage.Group, error) {
+			got = &arg
+			grp.Locale = arg.Locale
+			return grp, nil
+		},
+	}
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"locale":"fr"}`, http.StatusBadRequest},
+		{`{"locale":"ja"}`, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/groups/"+grp.ID.String()+"/settings", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", grp.ID.String())
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		ctx = setJWTContext(ctx, testUser().ID, grp.ID, "admin", "company")
+		req = req.WithContext(ctx)
+
+		UpdateGroupSettingsHandler(mock, nil).ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Fatalf("%s: expected status %d, got %d", tt.body, tt.code, rec.Code)
+		}
+	}
+	if got == nil || got.ID != grp.ID || got.Locale != "ja" {
+		t.Errorf("unexpected update params: %+v", got)
+	}
+}
+
+func TestUpdateGroupSettingsHandler_Forbidden(t *testing.T) {
+	grp := testGroup()
+	tests := []struct {
+		name    string
+		groupID uuid.UUID
+		role    string
+	}{
+		{name: "other group", groupID: uuid.MustParse("00000000-0000-0000-0000-000000000099"), role: "admin"},
+		{name: "member role", groupID: grp.ID, role: "member"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/groups/"+grp.ID.String()+"/settings", strings.NewReader(`{"inline_css":true}`))
+			rec := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", grp.ID.String())
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = setJWTContext(ctx, testUser().ID, tt.groupID, tt.role, "company")
+			req = req.WithContext(ctx)
+
+			handler := UpdateGroupSettingsHandler(&mockQuerier{}, nil)
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d", rec.Code)
+			}
+		})
+	}
+}
+
 func TestListGroupMembersHandler(t *testing.T) {
 	grp := testGroup()
 	member := testGroupMember()
@@ -492,3 +788,276 @@ func TestRemoveGroupMemberHandler_LastOwner(t *testing.T) {
 		t.Fatalf("expected status 409, got %d", rec.Code)
 	}
 }
+
+// testSubGroup returns a sub-group of testGroup without a limit of its own.
+func testSubGroup() storage.Group {
+	grp := testGroup()
+	grp.ID = uuid.MustParse("00000000-0000-0000-0000-000000000020")
+	grp.Name = "test-team"
+	grp.MonthlyLimit = 0
+	grp.ParentID = pgtype.UUID{Bytes: testGroup().ID, Valid: true}
+	return grp
+}
+
+func TestGetGroupHandler_ParentGroupAccess(t *testing.T) {
+	parent, sub := testGroup(), testSubGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return sub, nil
+		},
+		listGroupAncestorsFn: func(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{sub, parent}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/"+sub.ID.String(), nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", sub.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, parent.ID, "admin", "company")
+	req = req.WithContext(ctx)
+
+	handler := GetGroupHandler(mock)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp groupResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ParentID == nil || *resp.ParentID != parent.ID {
+		t.Errorf("expected parent_id %s, got %v", parent.ID, resp.ParentID)
+	}
+}
+
+func TestGetGroupHandler_SubGroupCannotAccessParent(t *testing.T) {
+	parent, sub := testGroup(), testSubGroup()
+	mock := &mockQuerier{
+		listGroupAncestorsFn: func(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{parent}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/"+parent.ID.String(), nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", parent.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, sub.ID, "owner", "company")
+	req = req.WithContext(ctx)
+
+	handler := GetGroupHandler(mock)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCreateSubGroupHandler_Valid(t *testing.T) {
+	parent := testGroup()
+	var gotParams storage.CreateGroupParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return parent, nil
+		},
+		listGroupAncestorsFn: func(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{parent}, nil
+		},
+		createGroupFn: func(ctx context.Context, arg storage.CreateGroupParams) (storage.Group, error) {
+			gotParams = arg
+			sub := testSubGroup()
+			sub.Name = arg.Name
+			return sub, nil
+		},
+	}
+
+	body := `{"name":"marketing"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/"+parent.ID.String()+"/subgroups", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", parent.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, parent.ID, "owner", "company")
+	req = req.WithContext(ctx)
+
+	handler := CreateSubGroupHandler(mock, nil)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if !gotParams.ParentID.Valid || uuid.UUID(gotParams.ParentID.Bytes) != parent.ID {
+		t.Errorf("expected parent_id %s, got %v", parent.ID, gotParams.ParentID)
+	}
+	if gotParams.Name != "marketing" || gotParams.GroupType != "company" {
+		t.Errorf("unexpected create params: %+v", gotParams)
+	}
+}
+
+func TestCreateSubGroupHandler_MemberForbidden(t *testing.T) {
+	parent := testGroup()
+	mock := &mockQuerier{}
+
+	body := `{"name":"marketing"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/"+parent.ID.String()+"/subgroups", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", parent.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, parent.ID, "member", "company")
+	req = req.WithContext(ctx)
+
+	handler := CreateSubGroupHandler(mock, nil)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCreateSubGroupHandler_MaxDepth(t *testing.T) {
+	parent := testGroup()
+	ancestors := make([]storage.Group, maxGroupDepth)
+	for i := range ancestors {
+		ancestors[i] = storage.Group{ID: uuid.New()}
+	}
+	ancestors[0] = parent
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return parent, nil
+		},
+		listGroupAncestorsFn: func(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+			return ancestors, nil
+		},
+	}
+
+	body := `{"name":"too-deep"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/"+parent.ID.String()+"/subgroups", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", parent.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	systemGroupID := uuid.MustParse("00000000-0000-0000-0000-000000000099")
+	ctx = setJWTContext(ctx, testUser().ID, systemGroupID, "admin", "system")
+	req = req.WithContext(ctx)
+
+	handler := CreateSubGroupHandler(mock, nil)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListSubGroupsHandler(t *testing.T) {
+	parent := testGroup()
+	mock := &mockQuerier{
+		listSubGroupsFn: func(ctx context.Context, parentID pgtype.UUID) ([]storage.Group, error) {
+			if uuid.UUID(parentID.Bytes) != parent.ID {
+				t.Errorf("expected parent %s, got %v", parent.ID, parentID)
+			}
+			return []storage.Group{testSubGroup()}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/"+parent.ID.String()+"/subgroups", nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", parent.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, parent.ID, "member", "company")
+	req = req.WithContext(ctx)
+
+	handler := ListSubGroupsHandler(mock)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp []groupResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Name != "test-team" {
+		t.Errorf("unexpected sub-groups: %+v", resp)
+	}
+}
+
+func TestGetGroupUsageHandler_InheritedLimit(t *testing.T) {
+	parent, sub := testGroup(), testSubGroup()
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return sub, nil
+		},
+		listGroupAncestorsFn: func(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{sub, parent}, nil
+		},
+		countGroupMessagesSinceFn: func(ctx context.Context, arg storage.CountGroupMessagesSinceParams) (int64, error) {
+			return 2500, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/groups/"+sub.ID.String()+"/usage", nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", sub.ID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, sub.ID, "member", "company")
+	req = req.WithContext(ctx)
+
+	handler := GetGroupUsageHandler(mock)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp groupUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.MonthlyLimit != parent.MonthlyLimit || !resp.LimitInherited || resp.PercentUsed != 25 {
+		t.Errorf("unexpected usage: %+v", resp)
+	}
+}
+
+func TestDeleteGroupHandler_ActiveSubGroups(t *testing.T) {
+	grp := testGroup()
+	grp.GroupType = "company"
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		listSubGroupsFn: func(ctx context.Context, parentID pgtype.UUID) ([]storage.Group, error) {
+			return []storage.Group{testSubGroup()}, nil
+		},
+		updateGroupStatusFn: func(ctx context.Context, arg storage.UpdateGroupStatusParams) (storage.Group, error) {
+			t.Error("group should not be deleted")
+			return grp, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/groups/"+grp.ID.String(), nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", grp.ID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler := DeleteGroupHandler(mock, nil)
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", rec.Code)
+	}
+}
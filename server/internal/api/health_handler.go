@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/version"
 )
 
 // HealthzHandler handles GET /healthz.
@@ -27,3 +28,12 @@ func ReadyzHandler(db *storage.DB) http.HandlerFunc {
 		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 	}
 }
+
+// VersionHandler handles GET /api/v1/version.
+// Returns the build version, commit, date, Go toolchain, platform and
+// whether FIPS mode is active.
+func VersionHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respondJSON(w, http.StatusOK, version.Get())
+	}
+}
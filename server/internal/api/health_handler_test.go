@@ -75,3 +75,23 @@ func TestReadyzHandler_UnhealthyDB(t *testing.T) {
 	// This behavior is tested in integration tests.
 	t.Skip("requires real database connection; covered by integration tests")
 }
+
+func TestVersionHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+	rec := httptest.NewRecorder()
+
+	VersionHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, key := range []string{"version", "commit", "date", "go_version", "platform", "fips"} {
+		if _, ok := resp[key]; !ok {
+			t.Errorf("response missing %q: %v", key, resp)
+		}
+	}
+}
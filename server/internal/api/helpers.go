@@ -1,10 +1,18 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
 // timestampToTime converts a pgtype.Timestamptz to time.Time.
@@ -28,3 +36,40 @@ func decodeDomains(data []byte) []string {
 	}
 	return domains
 }
+
+// normalizeEmail returns the lowercased address, or "" if email is not a
+// bare address.
+func normalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ""
+	}
+	return strings.ToLower(email)
+}
+
+// canAccessGroup reports whether the caller may act on the target group:
+// system admins may access every group, other callers their own group and
+// its sub-groups.
+func canAccessGroup(ctx context.Context, queries storage.Querier, target uuid.UUID) bool {
+	if auth.GroupTypeFromContext(ctx) == "system" {
+		return true
+	}
+	ok, err := auth.InGroupTree(ctx, queries, target, auth.GroupIDFromContext(ctx))
+	return err == nil && ok
+}
+
+// groupAdminParam parses the {id} URL parameter and checks that the
+// caller administers the group.
+func groupAdminParam(w http.ResponseWriter, r *http.Request, queries storage.Querier) (uuid.UUID, bool) {
+	groupID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid group ID format")
+		return uuid.Nil, false
+	}
+	if !isGroupAdmin(r) || !canAccessGroup(r.Context(), queries, groupID) {
+		respondError(w, http.StatusForbidden, "access denied")
+		return uuid.Nil, false
+	}
+	return groupID, true
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/inbound"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// inboundRouteRequest is the JSON body for creating or updating an inbound
+// route. Domain is ignored on update. A nil Secret keeps the current secret
+// on update; an empty string removes it.
+type inboundRouteRequest struct {
+	Domain  string  `json:"domain"`
+	URL     string  `json:"url"`
+	Format  string  `json:"format"`
+	Secret  *string `json:"secret"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// inboundRouteResponse is the JSON response for an inbound route. The
+// signing secret is never returned.
+type inboundRouteResponse struct {
+	ID        uuid.UUID `json:"id"`
+	GroupID   uuid.UUID `json:"group_id"`
+	Domain    string    `json:"domain"`
+	URL       string    `json:"url"`
+	Format    string    `json:"format"`
+	HasSecret bool      `json:"has_secret"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+}
+
+// toInboundRouteResponse converts a storage.InboundRoute to an inboundRouteResponse.
+func toInboundRouteResponse(rt storage.InboundRoute) inboundRouteResponse {
+	return inboundRouteResponse{
+		ID:        rt.ID,
+		GroupID:   rt.GroupID,
+		Domain:    rt.Domain,
+		URL:       rt.Url,
+		Format:    rt.Format,
+		HasSecret: rt.Secret.Valid && rt.Secret.String != "",
+		Enabled:   rt.Enabled,
+		CreatedAt: timestampToTime(rt.CreatedAt).Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: timestampToTime(rt.UpdatedAt).Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// validateInboundRoute normalizes the format and checks the URL and format,
+// returning an error message for the client or "" when the request is valid.
+// arf routes process feedback reports and take no URL.
+func validateInboundRoute(req *inboundRouteRequest) string {
+	if req.Format == "" {
+		req.Format = inbound.FormatJSON
+	}
+	if req.Format == inbound.FormatARF {
+		if req.URL != "" {
+			return "url must be empty for arf routes"
+		}
+		return ""
+	}
+	if req.Format != inbound.FormatJSON && req.Format != inbound.FormatRaw {
+		return "format must be json, raw or arf"
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url must be an absolute http or https URL"
+	}
+	if provider.ValidatePublicURL(req.URL) != nil {
+		return "url must be a public address"
+	}
+	return ""
+}
+
+// CreateInboundRouteHandler handles POST /api/v1/inbound-routes.
+// Creates an inbound route for the caller's group. Mail received for the
+// domain on the inbound listener is posted to url, or processed as
+// complaint feedback reports when format is arf. The domain must be a
+// verified sender identity of the group, so only its owner can route its
+// mail. Requires group admin+ role.
+func CreateInboundRouteHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req inboundRouteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		req.Domain = strings.ToLower(strings.TrimSpace(req.Domain))
+		if req.Domain == "" || strings.ContainsAny(req.Domain, "@ /") {
+			respondError(w, http.StatusBadRequest, "a valid domain is required")
+			return
+		}
+		if msg := validateInboundRoute(&req); msg != "" {
+			respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+		verified, err := queries.ListVerifiedSenderIdentities(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if !slices.Contains(verified, req.Domain) {
+			respondValidationErrors(w, []string{"domain must be a verified sender identity"})
+			return
+		}
+
+		params := storage.CreateInboundRouteParams{
+			GroupID: groupID,
+			Domain:  req.Domain,
+			Url:     req.URL,
+			Format:  req.Format,
+			Enabled: req.Enabled == nil || *req.Enabled,
+		}
+		if req.Secret != nil && *req.Secret != "" {
+			params.Secret = pgtype.Text{String: *req.Secret, Valid: true}
+		}
+
+		route, err := queries.CreateInboundRoute(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusConflict, "domain already has an inbound route")
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, toInboundRouteResponse(route))
+	}
+}
+
+// ListInboundRoutesHandler handles GET /api/v1/inbound-routes.
+// Routes are returned ordered by domain.
+func ListInboundRoutesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		routes, err := queries.ListInboundRoutesByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		result := make([]inboundRouteResponse, len(routes))
+		for i, rt := range routes {
+			result[i] = toInboundRouteResponse(rt)
+		}
+
+		respondJSON(w, http.StatusOK, result)
+	}
+}
+
+// GetInboundRouteHandler handles GET /api/v1/inbound-routes/{id}.
+func GetInboundRouteHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, ok := loadInboundRoute(w, r, queries)
+		if !ok {
+			return
+		}
+
+		respondJSON(w, http.StatusOK, toInboundRouteResponse(route))
+	}
+}
+
+// UpdateInboundRouteHandler handles PUT /api/v1/inbound-routes/{id}.
+// The domain of a route cannot be changed. Requires group admin+ role.
+func UpdateInboundRouteHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, ok := loadInboundRoute(w, r, queries)
+		if !ok {
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req inboundRouteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if msg := validateInboundRoute(&req); msg != "" {
+			respondError(w, http.StatusBadRequest, msg)
+			return
+		}
+
+		params := storage.UpdateInboundRouteParams{
+			ID:      route.ID,
+			Url:     req.URL,
+			Format:  req.Format,
+			Secret:  route.Secret,
+			Enabled: route.Enabled,
+		}
+		if req.Secret != nil {
+			params.Secret = pgtype.Text{String: *req.Secret, Valid: *req.Secret != ""}
+		}
+		if req.Enabled != nil {
+			params.Enabled = *req.Enabled
+		}
+
+		updated, err := queries.UpdateInboundRoute(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, toInboundRouteResponse(updated))
+	}
+}
+
+// DeleteInboundRouteHandler handles DELETE /api/v1/inbound-routes/{id}.
+// Messages already queued for the route fail without being retried.
+// Requires group admin+ role.
+func DeleteInboundRouteHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, ok := loadInboundRoute(w, r, queries)
+		if !ok {
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		if err := queries.DeleteInboundRoute(r.Context(), route.ID); err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// loadInboundRoute resolves the {id} inbound route and checks that it
+// belongs to a group the caller can access. It writes the error response
+// and returns false on failure.
+func loadInboundRoute(w http.ResponseWriter, r *http.Request, queries storage.Querier) (storage.InboundRoute, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid inbound route ID format")
+		return storage.InboundRoute{}, false
+	}
+
+	route, err := queries.GetInboundRouteByID(r.Context(), id)
+	if err != nil || !canAccessGroup(r.Context(), queries, route.GroupID) {
+		respondError(w, http.StatusNotFound, "inbound route not found")
+		return storage.InboundRoute{}, false
+	}
+	return route, true
+}
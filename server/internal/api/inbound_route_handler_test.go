@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func testInboundRoute() storage.InboundRoute {
+	return storage.InboundRoute{
+		ID:      uuid.MustParse("00000000-0000-0000-0000-000000000031"),
+		GroupID: testGroup().ID,
+		Domain:  "inbound.example.com",
+		Url:     "https://app.example.com/inbound",
+		Format:  "json",
+		Secret:  pgtype.Text{String: "s3cret", Valid: true},
+		Enabled: true,
+	}
+}
+
+func TestCreateInboundRouteHandler_Success(t *testing.T) {
+	var got storage.CreateInboundRouteParams
+	mock := &mockQuerier{
+		listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+			return []string{"inbound.example.com"}, nil
+		},
+		createInboundRouteFn: func(ctx context.Context, arg storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+			got = arg
+			return storage.InboundRoute{ID: uuid.New(), GroupID: arg.GroupID, Domain: arg.Domain, Url: arg.Url, Format: arg.Format, Secret: arg.Secret, Enabled: arg.Enabled}, nil
+		},
+	}
+
+	body := `{"domain":"Inbound.Example.com","url":"https://app.example.com/inbound","secret":"s3cret"}`
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/inbound-routes", body, "", testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	CreateInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.Domain != "inbound.example.com" || got.Format != "json" || !got.Enabled || got.GroupID != testGroup().ID {
+		t.Errorf("unexpected create params: %+v", got)
+	}
+	if strings.Contains(rec.Body.String(), "s3cret") {
+		t.Error("response must not contain the secret")
+	}
+	var resp inboundRouteResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.HasSecret {
+		t.Error("expected has_secret=true")
+	}
+}
+
+func TestCreateInboundRouteHandler_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing domain", `{"url":"https://app.example.com/inbound"}`},
+		{"email as domain", `{"domain":"a@example.com","url":"https://app.example.com/inbound"}`},
+		{"bad scheme", `{"domain":"example.com","url":"ftp://app.example.com/inbound"}`},
+		{"relative url", `{"domain":"example.com","url":"/inbound"}`},
+		{"bad format", `{"domain":"example.com","url":"https://app.example.com/inbound","format":"xml"}`},
+		{"arf with url", `{"domain":"example.com","url":"https://app.example.com/inbound","format":"arf"}`},
+		{"loopback url", `{"domain":"example.com","url":"http://127.0.0.1:8080/inbound"}`},
+		{"localhost url", `{"domain":"example.com","url":"http://localhost/inbound"}`},
+		{"private url", `{"domain":"example.com","url":"https://10.0.0.5/inbound"}`},
+		{"metadata url", `{"domain":"example.com","url":"http://169.254.169.254/latest/meta-data"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := smtpDebugRequest(http.MethodPost, "/api/v1/inbound-routes", tt.body, "", testGroup().ID, "admin", "organization")
+			rec := httptest.NewRecorder()
+			CreateInboundRouteHandler(&mockQuerier{}).ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCreateInboundRouteHandler_ARF(t *testing.T) {
+	var got storage.CreateInboundRouteParams
+	mock := &mockQuerier{
+		listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+			return []string{"example.com", "fbl.example.com"}, nil
+		},
+		createInboundRouteFn: func(ctx context.Context, arg storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+			got = arg
+			return storage.InboundRoute{ID: uuid.New(), GroupID: arg.GroupID, Domain: arg.Domain, Format: arg.Format, Enabled: arg.Enabled}, nil
+		},
+	}
+
+	body := `{"domain":"fbl.example.com","format":"arf"}`
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/inbound-routes", body, "", testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	CreateInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.Format != "arf" || got.Url != "" {
+		t.Errorf("unexpected create params: %+v", got)
+	}
+}
+
+func TestCreateInboundRouteHandler_RequiresAdmin(t *testing.T) {
+	body := `{"domain":"example.com","url":"https://app.example.com/inbound"}`
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/inbound-routes", body, "", testGroup().ID, "member", "organization")
+	rec := httptest.NewRecorder()
+	CreateInboundRouteHandler(&mockQuerier{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCreateInboundRouteHandler_UnverifiedDomain(t *testing.T) {
+	mock := &mockQuerier{
+		listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+			return []string{"alice@example.com", "other.example.com"}, nil
+		},
+		createInboundRouteFn: func(ctx context.Context, arg storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+			t.Error("route for an unverified domain must not be created")
+			return storage.InboundRoute{}, nil
+		},
+	}
+	body := `{"domain":"example.com","url":"https://app.example.com/inbound"}`
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/inbound-routes", body, "", testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	CreateInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestCreateInboundRouteHandler_DuplicateDomain(t *testing.T) {
+	mock := &mockQuerier{
+		listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+			return []string{"example.com"}, nil
+		},
+		createInboundRouteFn: func(ctx context.Context, arg storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+			return storage.InboundRoute{}, errors.New("duplicate key value violates unique constraint")
+		},
+	}
+	body := `{"domain":"example.com","url":"https://app.example.com/inbound"}`
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/inbound-routes", body, "", testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	CreateInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", rec.Code)
+	}
+}
+
+func TestListInboundRoutesHandler(t *testing.T) {
+	mock := &mockQuerier{
+		listInboundRoutesByGroupIDFn: func(ctx context.Context, groupID uuid.UUID) ([]storage.InboundRoute, error) {
+			if groupID != testGroup().ID {
+				t.Errorf("expected group %s, got %s", testGroup().ID, groupID)
+			}
+			return []storage.InboundRoute{testInboundRoute()}, nil
+		},
+	}
+	req := smtpDebugRequest(http.MethodGet, "/api/v1/inbound-routes", "", "", testGroup().ID, "member", "organization")
+	rec := httptest.NewRecorder()
+	ListInboundRoutesHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp []inboundRouteResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Domain != "inbound.example.com" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetInboundRouteHandler_OtherGroup(t *testing.T) {
+	route := testInboundRoute()
+	route.GroupID = uuid.New()
+	mock := &mockQuerier{
+		getInboundRouteByIDFn: func(ctx context.Context, id uuid.UUID) (storage.InboundRoute, error) {
+			return route, nil
+		},
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return storage.Group{ID: id}, nil
+		},
+	}
+	req := smtpDebugRequest(http.MethodGet, "/api/v1/inbound-routes/"+route.ID.String(), "", route.ID.String(), testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	GetInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestUpdateInboundRouteHandler_KeepsSecret(t *testing.T) {
+	route := testInboundRoute()
+	var got storage.UpdateInboundRouteParams
+	mock := &mockQuerier{
+		getInboundRouteByIDFn: func(ctx context.Context, id uuid.UUID) (storage.InboundRoute, error) {
+			return route, nil
+		},
+		updateInboundRouteFn: func(ctx context.Context, arg storage.UpdateInboundRouteParams) (storage.InboundRoute, error) {
+			got = arg
+			return route, nil
+		},
+	}
+
+	body := `{"url":"https://app.example.com/v2/inbound","format":"raw","enabled":false}`
+	req := smtpDebugRequest(http.MethodPut, "/api/v1/inbound-routes/"+route.ID.String(), body, route.ID.String(), testGroup().ID, "owner", "organization")
+	rec := httptest.NewRecorder()
+	UpdateInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.Url != "https://app.example.com/v2/inbound" || got.Format != "raw" || got.Enabled {
+		t.Errorf("unexpected update params: %+v", got)
+	}
+	if got.Secret.String != "s3cret" {
+		t.Error("expected the secret to be kept when omitted")
+	}
+}
+
+func TestUpdateInboundRouteHandler_PrivateURL(t *testing.T) {
+	route := testInboundRoute()
+	mock := &mockQuerier{
+		getInboundRouteByIDFn: func(ctx context.Context, id uuid.UUID) (storage.InboundRoute, error) {
+			return route, nil
+		},
+		updateInboundRouteFn: func(ctx context.Context, arg storage.UpdateInboundRouteParams) (storage.InboundRoute, error) {
+			t.Error("route must not be updated to a private URL")
+			return route, nil
+		},
+	}
+
+	body := `{"url":"http://[::1]:9090/metrics"}`
+	req := smtpDebugRequest(http.MethodPut, "/api/v1/inbound-routes/"+route.ID.String(), body, route.ID.String(), testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	UpdateInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestDeleteInboundRouteHandler(t *testing.T) {
+	route := testInboundRoute()
+	mock := &mockQuerier{
+		getInboundRouteByIDFn: func(ctx context.Context, id uuid.UUID) (storage.InboundRoute, error) {
+			return route, nil
+		},
+	}
+	req := smtpDebugRequest(http.MethodDelete, "/api/v1/inbound-routes/"+route.ID.String(), "", route.ID.String(), testGroup().ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	DeleteInboundRouteHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", rec.Code)
+	}
+}
@@ -156,7 +156,7 @@ func TestAuthLifecycle_LoginSwitchRefreshLogout(t *testing.T) {
 	loginReq.Header.Set("Content-Type", "application/json")
 	loginRec := httptest.NewRecorder()
 
-	LoginHandler(mock, jwtSvc, nil, nil).ServeHTTP(loginRec, loginReq)
+	LoginHandler(mock, jwtSvc, nil, nil, LoginSecurity{}).ServeHTTP(loginRec, loginReq)
 
 	if loginRec.Code != http.StatusOK {
 		t.Fatalf("Step 1 (Login): expected 200, got %d; body: %s", loginRec.Code, loginRec.Body.String())
@@ -478,7 +478,7 @@ func TestUnifiedAuth_JWTAndAPIKey(t *testing.T) {
 	t.Run("ValidJWT_Succeeds", func(t *testing.T) {
 		t.Parallel()
 
-		token, err := jwtSvc.GenerateAccessToken(userID, groupID, "test@example.com", "admin", "system")
+		token, err := jwtSvc.GenerateAccessToken(userID, groupID, "test@example.com", "admin", "system", nil)
 		if err != nil {
 			t.Fatalf("failed to generate token: %v", err)
 		}
@@ -628,7 +628,7 @@ func TestSMTPAccountCreation_WithGroupMembership(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	CreateUserHandler(mock, nil).ServeHTTP(rec, req)
+	CreateUserHandler(mock, nil, nil).ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusCreated {
 		t.Fatalf("Step 1 (Create): expected 201, got %d; body: %s", rec.Code, rec.Body.String())
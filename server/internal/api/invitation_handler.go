@@ -0,0 +1,467 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+// DefaultInvitationTTL is how long an invitation stays valid when
+// Invitations.TTL is not set.
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+// invitationAcceptPath is the endpoint invitation links point to.
+const invitationAcceptPath = "/api/v1/invitations/accept"
+
+// errInvitationUsed is returned inside the accept transaction when the
+// invitation was accepted, revoked or expired concurrently.
+var errInvitationUsed = errors.New("invitation already used")
+
+// Invitations configures group invitations.
+type Invitations struct {
+	// Mailer emails invitation links. When nil, or when sending fails,
+	// the invite token is returned to the inviting admin to pass on.
+	Mailer *sysmail.Mailer
+	// TTL is how long an invitation stays valid. Defaults to
+	// DefaultInvitationTTL.
+	TTL time.Duration
+}
+
+func (c Invitations) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultInvitationTTL
+	}
+	return c.TTL
+}
+
+// createInvitationRequest is the JSON body for POST /api/v1/groups/{id}/invitations.
+type createInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// acceptInvitationRequest is the JSON body for POST /api/v1/invitations/accept.
+// Password is the new account's password, or the current password when the
+// email already has an account.
+type acceptInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// invitationResponse is the JSON response for an invitation. Status is
+// pending or expired.
+type invitationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	GroupID   uuid.UUID  `json:"group_id"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	InvitedBy *uuid.UUID `json:"invited_by,omitempty"`
+	Status    string     `json:"status"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// createInvitationResponse is the JSON response for a new invitation.
+// InviteToken is only returned when the invitation email was not sent.
+type createInvitationResponse struct {
+	invitationResponse
+	EmailSent   bool   `json:"email_sent"`
+	InviteToken string `json:"invite_token,omitempty"`
+}
+
+// invitationDetailsResponse is the JSON response for GET
+// /api/v1/invitations/accept. ExistingAccount tells the invitee whether to
+// confirm with their current password or choose a new one.
+type invitationDetailsResponse struct {
+	Email           string    `json:"email"`
+	Role            string    `json:"role"`
+	GroupID         uuid.UUID `json:"group_id"`
+	GroupName       string    `json:"group_name"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	ExistingAccount bool      `json:"existing_account"`
+}
+
+// toInvitationResponse converts a storage.Invitation to an invitationResponse.
+func toInvitationResponse(inv storage.Invitation) invitationResponse {
+	resp := invitationResponse{
+		ID:        inv.ID,
+		GroupID:   inv.GroupID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		Status:    "pending",
+		ExpiresAt: timestampToTime(inv.ExpiresAt),
+		CreatedAt: timestampToTime(inv.CreatedAt),
+	}
+	if inv.InvitedBy.Valid {
+		invitedBy := uuid.UUID(inv.InvitedBy.Bytes)
+		resp.InvitedBy = &invitedBy
+	}
+	if !resp.ExpiresAt.After(time.Now()) {
+		resp.Status = "expired"
+	}
+	return resp
+}
+
+// CreateInvitationHandler handles POST /api/v1/groups/{id}/invitations.
+// Invites an email address to the group with a role (default member) and
+// emails the invitee a signed link to accept it. Inviting an address with
+// a pending invitation replaces that invitation. Requires group admin+
+// role; only owners can invite owners.
+func CreateInvitationHandler(queries storage.Querier, jwtService *auth.JWTService, invitations Invitations, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+
+		var req createInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		var errs []string
+		email := normalizeEmail(req.Email)
+		if email == "" {
+			errs = append(errs, "email must be a valid address")
+		}
+		if req.Role == "" {
+			req.Role = "member"
+		}
+		if _, ok := validRoles[req.Role]; !ok {
+			errs = append(errs, "role must be one of: owner, admin, member")
+		}
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		ctx := r.Context()
+		if req.Role == "owner" && auth.RoleFromContext(ctx) != "owner" && auth.GroupTypeFromContext(ctx) != "system" {
+			respondError(w, http.StatusForbidden, "only owners can invite owners")
+			return
+		}
+
+		group, err := queries.GetGroupByID(ctx, groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+
+		if user, err := queries.GetUserByEmail(ctx, email); err == nil {
+			if user.AccountType == "smtp" {
+				respondError(w, http.StatusConflict, "email belongs to an smtp account")
+				return
+			}
+			if _, err := queries.GetGroupMemberByUserAndGroup(ctx, storage.GetGroupMemberByUserAndGroupParams{
+				UserID:  user.ID,
+				GroupID: groupID,
+			}); err == nil {
+				respondError(w, http.StatusConflict, "user is already a member of this group")
+				return
+			}
+		}
+
+		inviterID := auth.UserFromContext(ctx)
+		ttl := invitations.ttl()
+		inv, err := queries.CreateInvitation(ctx, storage.CreateInvitationParams{
+			GroupID:   groupID,
+			Email:     email,
+			Role:      req.Role,
+			InvitedBy: pgtype.UUID{Bytes: inviterID, Valid: inviterID != uuid.Nil},
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		token, err := jwtService.GenerateActionToken(auth.PurposeInvite, inv.Email, inv.ID.String(), ttl)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := createInvitationResponse{invitationResponse: toInvitationResponse(inv)}
+		if invitations.Mailer != nil {
+			resp.EmailSent = sendInvitationEmail(r, invitations.Mailer, inv, group, token) == nil
+		}
+		if !resp.EmailSent {
+			resp.InviteToken = token
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(ctx, r, auth.AuditActionInviteMember, "invitation", inv.ID.String(), map[string]interface{}{
+				"group_id":   groupID.String(),
+				"email":      inv.Email,
+				"role":       inv.Role,
+				"email_sent": resp.EmailSent,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, resp)
+	}
+}
+
+// sendInvitationEmail emails the invitation link to the invitee, branded
+// for the group.
+func sendInvitationEmail(r *http.Request, mailer *sysmail.Mailer, inv storage.Invitation, group storage.Group, token string) error {
+	inviter := auth.UserEmailFromContext(r.Context())
+	if inviter == "" {
+		inviter = "An administrator"
+	}
+	return mailer.SendTemplate(r.Context(), group.ID, []string{inv.Email}, sysmail.TemplateInvitation, sysmail.Data{
+		Email:     inv.Email,
+		Group:     group.Name,
+		Link:      mailer.Link(invitationAcceptPath, url.Values{"token": {token}}),
+		ExpiresAt: timestampToTime(inv.ExpiresAt),
+		Inviter:   inviter,
+		Role:      inv.Role,
+	})
+}
+
+// ListInvitationsHandler handles GET /api/v1/groups/{id}/invitations.
+// Lists the group's pending and expired invitations, newest first.
+// Requires group admin+ role.
+func ListInvitationsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+
+		invitations, err := queries.ListPendingInvitationsByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]invitationResponse, len(invitations))
+		for i, inv := range invitations {
+			resp[i] = toInvitationResponse(inv)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// RevokeInvitationHandler handles DELETE /api/v1/groups/{id}/invitations/{invitationId}.
+// Deletes a pending invitation so its link no longer works. Requires group
+// admin+ role.
+func RevokeInvitationHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "invitationId"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid invitation ID format")
+			return
+		}
+
+		n, err := queries.DeleteInvitation(r.Context(), storage.DeleteInvitationParams{ID: id, GroupID: groupID})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "invitation not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionRevokeInvite, "invitation", id.String(), map[string]interface{}{
+				"group_id": groupID.String(),
+			})
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// loadInvitation validates an invite token and returns its invitation if
+// it is still pending.
+func loadInvitation(w http.ResponseWriter, r *http.Request, queries storage.Querier, jwtService *auth.JWTService, token string) (storage.Invitation, bool) {
+	claims, err := jwtService.ValidateActionToken(token, auth.PurposeInvite)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+		return storage.Invitation{}, false
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+		return storage.Invitation{}, false
+	}
+
+	inv, err := queries.GetInvitationByID(r.Context(), id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+		return storage.Invitation{}, false
+	}
+	if err != nil || inv.AcceptedAt.Valid || inv.Email != claims.Subject || !timestampToTime(inv.ExpiresAt).After(time.Now()) {
+		respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+		return storage.Invitation{}, false
+	}
+	return inv, true
+}
+
+// GetInvitationHandler handles GET /api/v1/invitations/accept.
+// Returns the invitation of the token query parameter so the invitee can
+// review it before accepting. No auth required; the token is the credential.
+func GetInvitationHandler(queries storage.Querier, jwtService *auth.JWTService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			respondError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+		inv, ok := loadInvitation(w, r, queries, jwtService, token)
+		if !ok {
+			return
+		}
+
+		group, err := queries.GetGroupByID(r.Context(), inv.GroupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+		_, err = queries.GetUserByEmail(r.Context(), inv.Email)
+
+		respondJSON(w, http.StatusOK, invitationDetailsResponse{
+			Email:           inv.Email,
+			Role:            inv.Role,
+			GroupID:         group.ID,
+			GroupName:       group.Name,
+			ExpiresAt:       timestampToTime(inv.ExpiresAt),
+			ExistingAccount: err == nil,
+		})
+	}
+}
+
+// AcceptInvitationHandler handles POST /api/v1/invitations/accept.
+// Accepts an invitation with the token of the emailed link. An invitee
+// without an account chooses a password, which must satisfy the password
+// policy, and a user account is created; an existing user confirms with
+// their current password. The user then joins the group with the invited
+// role. Each invitation can be accepted once. No auth required.
+func AcceptInvitationHandler(queries storage.Querier, tx storage.TxRunner, jwtService *auth.JWTService, passwords *auth.PasswordPolicy, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req acceptInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		var errs []string
+		if req.Token == "" {
+			errs = append(errs, "token is required")
+		}
+		if req.Password == "" {
+			errs = append(errs, "password is required")
+		}
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		ctx := r.Context()
+		inv, ok := loadInvitation(w, r, queries, jwtService, req.Token)
+		if !ok {
+			return
+		}
+
+		user, err := queries.GetUserByEmail(ctx, inv.Email)
+		existing := err == nil
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		var passwordHash string
+		if existing {
+			if user.AccountType == "smtp" || user.Status != "active" {
+				respondError(w, http.StatusForbidden, "account cannot join groups")
+				return
+			}
+			if err := auth.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+				respondError(w, http.StatusUnauthorized, "invalid password")
+				return
+			}
+			if _, err := queries.GetGroupMemberByUserAndGroup(ctx, storage.GetGroupMemberByUserAndGroupParams{
+				UserID:  user.ID,
+				GroupID: inv.GroupID,
+			}); err == nil {
+				respondError(w, http.StatusConflict, "user is already a member of this group")
+				return
+			}
+		} else {
+			if !checkPassword(w, r, passwords, req.Password) {
+				return
+			}
+			passwordHash, err = auth.HashPassword(req.Password)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+
+		var member storage.GroupMember
+		err = tx.ExecTx(ctx, func(q storage.Querier) error {
+			if !existing {
+				created, err := q.CreateUser(ctx, storage.CreateUserParams{
+					Email:        inv.Email,
+					PasswordHash: passwordHash,
+					AccountType:  "user",
+				})
+				if err != nil {
+					return err
+				}
+				user = created
+			}
+			n, err := q.AcceptInvitation(ctx, inv.ID)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errInvitationUsed
+			}
+			// The invite link reached this address, which verifies it.
+			if err := q.MarkEmailVerified(ctx, user.ID); err != nil {
+				return err
+			}
+			member, err = q.CreateGroupMember(ctx, storage.CreateGroupMemberParams{
+				GroupID: inv.GroupID,
+				UserID:  user.ID,
+				Role:    inv.Role,
+			})
+			return err
+		})
+		if errors.Is(err, errInvitationUsed) {
+			respondError(w, http.StatusBadRequest, "invalid or expired invitation")
+			return
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusConflict, "could not accept invitation")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAuthEvent(ctx, r, inv.GroupID, user.ID, auth.AuditActionAcceptInvite, "", map[string]interface{}{
+				"invitation_id": inv.ID.String(),
+				"role":          inv.Role,
+				"new_account":   !existing,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, toGroupMemberResponse(member))
+	}
+}
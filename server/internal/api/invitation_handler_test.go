@@ -0,0 +1,366 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+func newInvitationJWTService() *auth.JWTService {
+	return auth.NewJWTService(auth.JWTConfig{
+		SigningKey:        "test-secret-key-that-is-long-enough-32",
+		AccessTokenExpiry: 15 * time.Minute,
+	})
+}
+
+func testInvitation() storage.Invitation {
+	return storage.Invitation{
+		ID:        uuid.MustParse("00000000-0000-0000-0000-000000000070"),
+		GroupID:   testGroup().ID,
+		Email:     "invitee@example.com",
+		Role:      "admin",
+		InvitedBy: pgtype.UUID{Bytes: testUser().ID, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+func invitationHTTPRequest(method, body, role string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/groups/"+testGroup().ID.String()+"/invitations", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", testGroup().ID.String())
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "company")
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func inviteToken(t *testing.T, jwtSvc *auth.JWTService, inv storage.Invitation) string {
+	t.Helper()
+	token, err := jwtSvc.GenerateActionToken(auth.PurposeInvite, inv.Email, inv.ID.String(), time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateActionToken: %v", err)
+	}
+	return token
+}
+
+func TestCreateInvitationHandler(t *testing.T) {
+	var created storage.CreateInvitationParams
+	var body string
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return testGroup(), nil
+		},
+		getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+			return storage.User{}, pgx.ErrNoRows
+		},
+		createInvitationFn: func(ctx context.Context, arg storage.CreateInvitationParams) (storage.Invitation, error) {
+			created = arg
+			inv := testInvitation()
+			inv.Email, inv.Role, inv.ExpiresAt = arg.Email, arg.Role, arg.ExpiresAt
+			return inv, nil
+		},
+		getGroupByNameFn: func(ctx context.Context, name string) (storage.Group, error) {
+			return storage.Group{ID: uuid.New(), Name: name}, nil
+		},
+		enqueueMessageFn: func(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			body = arg.Body.String
+			return storage.Message{ID: uuid.New()}, nil
+		},
+	}
+	jwtSvc := newInvitationJWTService()
+	invitations := Invitations{
+		Mailer: sysmail.New(mock, directTx{mock}, sysmail.Config{BaseURL: "https://mail.example.com"}),
+		TTL:    48 * time.Hour,
+	}
+
+	rec := httptest.NewRecorder()
+	CreateInvitationHandler(mock, jwtSvc, invitations, nil).ServeHTTP(rec,
+		invitationHTTPRequest(http.MethodPost, `{"email":" New.User@Example.com ","role":"admin"}`, "admin"))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp createInvitationResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.EmailSent || resp.InviteToken != "" || resp.Status != "pending" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if created.Email != "new.user@example.com" || created.Role != "admin" || created.InvitedBy.Bytes != testUser().ID {
+		t.Errorf("unexpected invitation: %+v", created)
+	}
+	if d := time.Until(created.ExpiresAt.Time); d < 47*time.Hour || d > 48*time.Hour {
+		t.Errorf("invitation expires in %v, want 48h", d)
+	}
+
+	// The emailed link carries a token for this invitation.
+	i := strings.Index(body, "https://mail.example.com/api/v1/invitations/accept?token=")
+	if i < 0 {
+		t.Fatalf("email has no invitation link:\n%s", body)
+	}
+	link, _ := url.Parse(strings.Fields(body[i:])[0])
+	claims, err := jwtSvc.ValidateActionToken(link.Query().Get("token"), auth.PurposeInvite)
+	if err != nil || claims.ID != testInvitation().ID.String() || claims.Subject != "new.user@example.com" {
+		t.Errorf("invalid invitation token: %+v, %v", claims, err)
+	}
+}
+
+func TestCreateInvitationHandler_WithoutMailer(t *testing.T) {
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return testGroup(), nil
+		},
+		getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+			return storage.User{}, pgx.ErrNoRows
+		},
+		createInvitationFn: func(ctx context.Context, arg storage.CreateInvitationParams) (storage.Invitation, error) {
+			return testInvitation(), nil
+		},
+	}
+	jwtSvc := newInvitationJWTService()
+
+	rec := httptest.NewRecorder()
+	CreateInvitationHandler(mock, jwtSvc, Invitations{}, nil).ServeHTTP(rec,
+		invitationHTTPRequest(http.MethodPost, `{"email":"invitee@example.com"}`, "owner"))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp createInvitationResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.EmailSent {
+		t.Error("email_sent set without a mailer")
+	}
+	if _, err := jwtSvc.ValidateActionToken(resp.InviteToken, auth.PurposeInvite); err != nil {
+		t.Errorf("invite_token is not a valid invitation token: %v", err)
+	}
+}
+
+func TestCreateInvitationHandler_Errors(t *testing.T) {
+	member := testUser()
+	tests := []struct {
+		name     string
+		body     string
+		role     string
+		existing bool
+		code     int
+	}{
+		{"member caller", `{"email":"a@example.com"}`, "member", false, http.StatusForbidden},
+		{"invalid email", `{"email":"Someone <a@example.com>"}`, "admin", false, http.StatusBadRequest},
+		{"invalid role", `{"email":"a@example.com","role":"root"}`, "admin", false, http.StatusBadRequest},
+		{"owner by admin", `{"email":"a@example.com","role":"owner"}`, "admin", false, http.StatusForbidden},
+		{"already a member", `{"email":"a@example.com"}`, "admin", true, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+					return testGroup(), nil
+				},
+				getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+					if !tt.existing {
+						return storage.User{}, pgx.ErrNoRows
+					}
+					return member, nil
+				},
+				getGroupMemberByUserAndGroupFn: func(ctx context.Context, arg storage.GetGroupMemberByUserAndGroupParams) (storage.GroupMember, error) {
+					return storage.GroupMember{UserID: arg.UserID, GroupID: arg.GroupID, Role: "member"}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			CreateInvitationHandler(mock, newInvitationJWTService(), Invitations{}, nil).ServeHTTP(rec,
+				invitationHTTPRequest(http.MethodPost, tt.body, tt.role))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAcceptInvitationHandler_NewUser(t *testing.T) {
+	inv := testInvitation()
+	newUserID := uuid.New()
+	var createdUser storage.CreateUserParams
+	var member storage.CreateGroupMemberParams
+	mock := &mockQuerier{
+		getInvitationByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Invitation, error) {
+			return inv, nil
+		},
+		getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+			return storage.User{}, pgx.ErrNoRows
+		},
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			createdUser = arg
+			return storage.User{ID: newUserID, Email: arg.Email, AccountType: arg.AccountType}, nil
+		},
+		createGroupMemberFn: func(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error) {
+			member = arg
+			return storage.GroupMember{ID: uuid.New(), GroupID: arg.GroupID, UserID: arg.UserID, Role: arg.Role}, nil
+		},
+	}
+	jwtSvc := newInvitationJWTService()
+	handler := AcceptInvitationHandler(mock, directTx{mock}, jwtSvc, &auth.PasswordPolicy{MinLength: 10}, nil)
+	token := inviteToken(t, jwtSvc, inv)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/invitations/accept",
+		strings.NewReader(`{"token":"`+token+`","password":"short"}`)))
+	if rec.Code != http.StatusBadRequest || createdUser.Email != "" {
+		t.Fatalf("weak password: expected status 400 and no account, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/invitations/accept",
+		strings.NewReader(`{"token":"`+token+`","password":"a long enough password"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if createdUser.Email != inv.Email || createdUser.AccountType != "user" || auth.VerifyPassword(createdUser.PasswordHash, "a long enough password") != nil {
+		t.Errorf("unexpected account: %+v", createdUser)
+	}
+	if member.UserID != newUserID || member.GroupID != inv.GroupID || member.Role != "admin" {
+		t.Errorf("unexpected membership: %+v", member)
+	}
+}
+
+func TestAcceptInvitationHandler_ExistingUser(t *testing.T) {
+	inv := testInvitation()
+	hash, _ := auth.HashPassword("current password")
+	user := testUser()
+	user.Email = inv.Email
+	user.PasswordHash = hash
+	joined := false
+	mock := &mockQuerier{
+		getInvitationByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Invitation, error) {
+			return inv, nil
+		},
+		getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+			return user, nil
+		},
+		getGroupMemberByUserAndGroupFn: func(ctx context.Context, arg storage.GetGroupMemberByUserAndGroupParams) (storage.GroupMember, error) {
+			return storage.GroupMember{}, pgx.ErrNoRows
+		},
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			t.Error("account created for an existing user")
+			return storage.User{}, nil
+		},
+		createGroupMemberFn: func(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error) {
+			joined = arg.UserID == user.ID
+			return storage.GroupMember{ID: uuid.New(), GroupID: arg.GroupID, UserID: arg.UserID, Role: arg.Role}, nil
+		},
+	}
+	jwtSvc := newInvitationJWTService()
+	handler := AcceptInvitationHandler(mock, directTx{mock}, jwtSvc, nil, nil)
+	token := inviteToken(t, jwtSvc, inv)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/invitations/accept",
+		strings.NewReader(`{"token":"`+token+`","password":"wrong password"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: expected status 401, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/invitations/accept",
+		strings.NewReader(`{"token":"`+token+`","password":"current password"}`)))
+	if rec.Code != http.StatusCreated || !joined {
+		t.Errorf("expected status 201 and membership, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAcceptInvitationHandler_InvalidInvitation(t *testing.T) {
+	jwtSvc := newInvitationJWTService()
+	accepted := testInvitation()
+	accepted.AcceptedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	expired := testInvitation()
+	expired.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	reinvited := testInvitation()
+	reinvited.Email = "someone.else@example.com"
+
+	tests := []struct {
+		name      string
+		stored    storage.Invitation
+		storeErr  error
+		acceptErr bool
+	}{
+		{"revoked", storage.Invitation{}, pgx.ErrNoRows, false},
+		{"already accepted", accepted, nil, false},
+		{"expired", expired, nil, false},
+		{"different email", reinvited, nil, false},
+		{"accepted concurrently", testInvitation(), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getInvitationByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Invitation, error) {
+					return tt.stored, tt.storeErr
+				},
+				getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+					return storage.User{}, pgx.ErrNoRows
+				},
+				acceptInvitationFn: func(ctx context.Context, id uuid.UUID) (int64, error) {
+					if tt.acceptErr {
+						return 0, nil
+					}
+					return 1, nil
+				},
+			}
+			token := inviteToken(t, jwtSvc, testInvitation())
+			rec := httptest.NewRecorder()
+			AcceptInvitationHandler(mock, directTx{mock}, jwtSvc, nil, nil).ServeHTTP(rec,
+				httptest.NewRequest(http.MethodPost, "/api/v1/invitations/accept",
+					strings.NewReader(`{"token":"`+token+`","password":"a long enough password"}`)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRevokeInvitationHandler(t *testing.T) {
+	inv := testInvitation()
+	var deleted storage.DeleteInvitationParams
+	mock := &mockQuerier{
+		deleteInvitationFn: func(ctx context.Context, arg storage.DeleteInvitationParams) (int64, error) {
+			deleted = arg
+			if arg.ID != inv.ID {
+				return 0, nil
+			}
+			return 1, nil
+		},
+	}
+
+	for _, tc := range []struct {
+		id   uuid.UUID
+		code int
+	}{
+		{inv.ID, http.StatusNoContent},
+		{uuid.New(), http.StatusNotFound},
+	} {
+		req := invitationHTTPRequest(http.MethodDelete, "", "admin")
+		chi.RouteContext(req.Context()).URLParams.Add("invitationId", tc.id.String())
+		rec := httptest.NewRecorder()
+		RevokeInvitationHandler(mock, nil).ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Errorf("revoke %s: expected status %d, got %d", tc.id, tc.code, rec.Code)
+		}
+		if deleted.GroupID != testGroup().ID {
+			t.Errorf("revoke scoped to group %s, want %s", deleted.GroupID, testGroup().ID)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+)
+
+// The legacy API serves the account and tenant endpoints of the API server
+// from before the Group/User redesign, so integrations built against them
+// keep working while they move to /api/v1/users and /api/v1/groups. It is
+// a thin layer over the new model, as migration 010 converted the data:
+//
+//	accounts  ->  users with account_type "smtp" (name = username)
+//	tenants   ->  groups of type "company"
+//
+// Unlike the old server, every endpoint requires authentication.
+
+// registerLegacyRoutes mounts the legacy endpoints on r, which must
+// authenticate requests. Responses carry a Deprecation header and a Link
+// to the replacing endpoint, and requests are counted in
+// api_legacy_requests_total.
+func registerLegacyRoutes(r chi.Router, cfg RouterConfig) {
+	r.Route("/api/v1/accounts", func(r chi.Router) {
+		r.Use(legacyMiddleware("accounts", "/api/v1/users"))
+		r.Post("/", CreateAccountHandler(cfg.Queries, cfg.PasswordPolicy, cfg.AuditLogger))
+		r.Get("/{id}", GetAccountHandler(cfg.Queries))
+		r.Put("/{id}", UpdateAccountHandler(cfg.Queries, cfg.AuditLogger))
+		r.Delete("/{id}", DeleteAccountHandler(cfg.Queries, cfg.AuditLogger))
+	})
+	r.Route("/api/v1/tenants", func(r chi.Router) {
+		r.Use(legacyMiddleware("tenants", "/api/v1/groups"))
+		r.With(auth.RequireSystemAdmin()).Post("/", CreateTenantHandler(cfg.Queries, cfg.PasswordPolicy, cfg.AuditLogger))
+		r.Get("/{id}", GetTenantHandler(cfg.Queries))
+		r.With(auth.RequireSystemAdmin()).Delete("/{id}", DeleteGroupHandler(cfg.Queries, cfg.AuditLogger))
+	})
+}
+
+// legacyMiddleware marks responses of a legacy resource as deprecated in
+// favor of successor and counts its requests.
+func legacyMiddleware(resource, successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			metrics.APILegacyRequestsTotal.WithLabelValues(resource).Inc()
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRegisterLegacyRoutes(t *testing.T) {
+	account := testSMTPAccount()
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := setJWTContext(req.Context(), accountCallerID, testGroup().ID, "owner", "organization")
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+	registerLegacyRoutes(r, RouterConfig{Queries: accountMock(account)})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/accounts/"+account.ID.String(), nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Errorf("Deprecation = %q, want true", rec.Header().Get("Deprecation"))
+	}
+	if want := `</api/v1/users>; rel="successor-version"`; rec.Header().Get("Link") != want {
+		t.Errorf("Link = %q, want %q", rec.Header().Get("Link"), want)
+	}
+
+	// Creating tenants requires a system admin.
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/tenants", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST /api/v1/tenants: status %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,422 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Lockdown report limits. The report covers the last hours (default 24,
+// at most 30 days) and lists at most maxLockdownReportMessages messages.
+const (
+	defaultLockdownHours      = 24
+	maxLockdownHours          = 720
+	maxLockdownReportMessages = 10000
+)
+
+// lockdownRequest is the optional JSON body of a lockdown.
+type lockdownRequest struct {
+	// Hours is how far back the report of sent messages reaches.
+	Hours int `json:"hours"`
+	// Reason is recorded in the audit log.
+	Reason string `json:"reason"`
+}
+
+// lockdownUserResult is what a lockdown revoked for one user.
+type lockdownUserResult struct {
+	UserID          uuid.UUID `json:"user_id"`
+	Email           string    `json:"email"`
+	SessionsRevoked int       `json:"sessions_revoked"`
+	APIKeyRevoked   bool      `json:"api_key_revoked"`
+}
+
+// lockdownReportMessage is a message submitted by the locked down
+// principal. Bodies are not returned.
+type lockdownReportMessage struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	GroupID     *uuid.UUID `json:"group_id,omitempty"`
+	Sender      string     `json:"sender"`
+	Recipients  []string   `json:"recipients"`
+	Subject     string     `json:"subject,omitempty"`
+	Status      string     `json:"status"`
+	SizeBytes   int64      `json:"size_bytes"`
+	EnqueuedAt  time.Time  `json:"enqueued_at"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	RequestID   string     `json:"request_id,omitempty"`
+}
+
+// lockdownReport lists the messages submitted since Since, newest first.
+// Truncated is set when more than maxLockdownReportMessages matched.
+type lockdownReport struct {
+	Since     time.Time               `json:"since"`
+	Until     time.Time               `json:"until"`
+	ByStatus  map[string]int          `json:"by_status"`
+	Truncated bool                    `json:"truncated"`
+	Messages  []lockdownReportMessage `json:"messages"`
+}
+
+// lockdownResponse is the JSON response of a lockdown.
+type lockdownResponse struct {
+	TargetType     string               `json:"target_type"`
+	TargetID       uuid.UUID            `json:"target_id"`
+	Users          []lockdownUserResult `json:"users"`
+	MessagesPaused int64                `json:"messages_paused"`
+	Report         lockdownReport       `json:"report"`
+}
+
+// releaseResponse is the JSON response of a lockdown release.
+type releaseResponse struct {
+	TargetType       string    `json:"target_type"`
+	TargetID         uuid.UUID `json:"target_id"`
+	MessagesReleased int64     `json:"messages_released"`
+}
+
+// LockdownUserHandler handles POST /api/v1/users/{id}/lockdown.
+// Responds to compromised credentials in one call: suspends the user,
+// revokes their API key and sessions, pauses their queued messages and
+// reports the messages they submitted in the last hours. Each step is
+// audit logged. Requires group admin+ role for the user's group; callers
+// cannot lock down themselves. Repeating the call is safe.
+func LockdownUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		req, ok := decodeLockdownRequest(w, r)
+		if !ok {
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		if id == auth.UserFromContext(r.Context()) {
+			respondError(w, http.StatusBadRequest, "cannot lock down your own account")
+			return
+		}
+
+		if !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		user, err := queries.GetUserByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionLockdownUser, "user", id.String(), map[string]interface{}{
+				"reason": req.Reason,
+				"hours":  req.Hours,
+			})
+		}
+
+		result, err := lockdownUser(r.Context(), r, queries, auditLogger, user)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "lockdown incomplete, retry the request")
+			return
+		}
+		resp := lockdownResponse{TargetType: "user", TargetID: id, Users: []lockdownUserResult{result}}
+		filter := pgtype.UUID{Bytes: id, Valid: true}
+		if !lockdownMessages(w, r, queries, auditLogger, &resp, req.Hours, filter, pgtype.UUID{}) {
+			return
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// LockdownGroupHandler handles POST /api/v1/groups/{id}/lockdown.
+// Locks down every member of the group as LockdownUserHandler does, except
+// the caller, and pauses and reports all of the group's messages, whether
+// submitted over SMTP or the API. Sub-groups are not included. Requires
+// group admin+ role with access to the group.
+func LockdownGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+		req, ok := decodeLockdownRequest(w, r)
+		if !ok {
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessGroup(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		group, err := queries.GetGroupByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "group not found")
+			return
+		}
+		if group.GroupType == "system" {
+			respondError(w, http.StatusForbidden, "cannot lock down system group")
+			return
+		}
+		members, err := queries.ListGroupMembersByGroupID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionLockdownGroup, "group", id.String(), map[string]interface{}{
+				"reason":  req.Reason,
+				"hours":   req.Hours,
+				"members": len(members),
+			})
+		}
+
+		resp := lockdownResponse{TargetType: "group", TargetID: id, Users: []lockdownUserResult{}}
+		callerID := auth.UserFromContext(r.Context())
+		for _, m := range members {
+			if m.UserID == callerID {
+				continue
+			}
+			user, err := queries.GetUserByID(r.Context(), m.UserID)
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "lockdown incomplete, retry the request")
+				return
+			}
+			result, err := lockdownUser(r.Context(), r, queries, auditLogger, user)
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "lockdown incomplete, retry the request")
+				return
+			}
+			resp.Users = append(resp.Users, result)
+		}
+		filter := pgtype.UUID{Bytes: id, Valid: true}
+		if !lockdownMessages(w, r, queries, auditLogger, &resp, req.Hours, pgtype.UUID{}, filter) {
+			return
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// ReleaseUserHandler handles POST /api/v1/users/{id}/lockdown/release.
+// Returns the messages paused by a lockdown to the queue; the
+// stuck-message sweeper publishes them again. Reactivating the user is a
+// separate status change. Requires group admin+ role for the user's group.
+func ReleaseUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		releaseMessages(w, r, queries, auditLogger, "user", id, storage.ResumePausedMessagesParams{
+			UserID: pgtype.UUID{Bytes: id, Valid: true},
+		})
+	}
+}
+
+// ReleaseGroupHandler handles POST /api/v1/groups/{id}/lockdown/release.
+// Returns the group's paused messages to the queue. Requires group admin+
+// role with access to the group.
+func ReleaseGroupHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid group ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessGroup(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		releaseMessages(w, r, queries, auditLogger, "group", id, storage.ResumePausedMessagesParams{
+			GroupID: pgtype.UUID{Bytes: id, Valid: true},
+		})
+	}
+}
+
+// decodeLockdownRequest reads the optional lockdown body and applies the
+// default report window.
+func decodeLockdownRequest(w http.ResponseWriter, r *http.Request) (lockdownRequest, bool) {
+	var req lockdownRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return req, false
+	}
+	if req.Hours == 0 {
+		req.Hours = defaultLockdownHours
+	}
+	if req.Hours < 0 || req.Hours > maxLockdownHours {
+		respondError(w, http.StatusBadRequest, "hours must be between 1 and 720")
+		return req, false
+	}
+	return req, true
+}
+
+// canAccessUser reports whether the caller may manage the user: system
+// users may manage anyone, others only users of a group in their tree.
+func canAccessUser(ctx context.Context, queries storage.Querier, userID uuid.UUID) bool {
+	if auth.GroupTypeFromContext(ctx) == "system" {
+		return true
+	}
+	groups, err := queries.ListGroupsByUserID(ctx, userID)
+	if err != nil || len(groups) == 0 {
+		return false
+	}
+	return canAccessGroup(ctx, queries, groups[0].ID)
+}
+
+// lockdownUser suspends the user, so neither SMTP AUTH nor API keys are
+// accepted, then revokes their API key and refresh sessions. Access tokens
+// already issued stay valid until they expire.
+func lockdownUser(ctx context.Context, r *http.Request, queries storage.Querier, auditLogger *auth.AuditLogger, user storage.User) (lockdownUserResult, error) {
+	result := lockdownUserResult{UserID: user.ID, Email: user.Email}
+
+	if user.Status != "suspended" {
+		if _, err := queries.UpdateUserStatus(ctx, storage.UpdateUserStatusParams{
+			ID:     user.ID,
+			Status: "suspended",
+		}); err != nil {
+			return result, err
+		}
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(ctx, r, "admin.update_user_status", "user", user.ID.String(), map[string]interface{}{
+				"status": "suspended",
+			})
+		}
+	}
+
+	revoked, err := queries.RevokeUserAPIKey(ctx, user.ID)
+	if err != nil {
+		return result, err
+	}
+	result.APIKeyRevoked = revoked > 0
+	if result.APIKeyRevoked && auditLogger != nil {
+		auditLogger.LogAdminAction(ctx, r, auth.AuditActionRevokeAPIKey, "user", user.ID.String(), nil)
+	}
+
+	sessions, err := queries.ListSessionsByUserID(ctx, user.ID)
+	if err != nil {
+		return result, err
+	}
+	if len(sessions) > 0 {
+		if err := queries.DeleteSessionsByUserID(ctx, user.ID); err != nil {
+			return result, err
+		}
+		result.SessionsRevoked = len(sessions)
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(ctx, r, auth.AuditActionRevokeSessions, "user", user.ID.String(), map[string]interface{}{
+				"sessions": len(sessions),
+			})
+		}
+	}
+	return result, nil
+}
+
+// lockdownMessages pauses the queued messages of the user or group and
+// adds the report of their messages from the last hours to resp. It
+// writes the error response and returns false on failure.
+func lockdownMessages(w http.ResponseWriter, r *http.Request, queries storage.Querier, auditLogger *auth.AuditLogger, resp *lockdownResponse, hours int, userID, groupID pgtype.UUID) bool {
+	paused, err := queries.PauseQueuedMessages(r.Context(), storage.PauseQueuedMessagesParams{
+		UserID:  userID,
+		GroupID: groupID,
+	})
+	if err != nil {
+		respondStorageError(w, err, http.StatusInternalServerError, "lockdown incomplete, retry the request")
+		return false
+	}
+	resp.MessagesPaused = paused
+	if paused > 0 && auditLogger != nil {
+		auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionPauseMessages, resp.TargetType, resp.TargetID.String(), map[string]interface{}{
+			"messages": paused,
+		})
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-time.Duration(hours) * time.Hour)
+	rows, err := queries.ListMessagesSentSince(r.Context(), storage.ListMessagesSentSinceParams{
+		UserID:     userID,
+		GroupID:    groupID,
+		Since:      pgtype.Timestamptz{Time: since, Valid: true},
+		MaxResults: maxLockdownReportMessages + 1,
+	})
+	if err != nil {
+		respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+		return false
+	}
+
+	report := lockdownReport{
+		Since:    since,
+		Until:    until,
+		ByStatus: map[string]int{},
+		Messages: make([]lockdownReportMessage, 0, len(rows)),
+	}
+	if len(rows) > maxLockdownReportMessages {
+		rows = rows[:maxLockdownReportMessages]
+		report.Truncated = true
+	}
+	for _, m := range rows {
+		report.ByStatus[string(m.Status)]++
+		report.Messages = append(report.Messages, toLockdownReportMessage(m))
+	}
+	resp.Report = report
+	return true
+}
+
+func toLockdownReportMessage(m storage.ListMessagesSentSinceRow) lockdownReportMessage {
+	msg := lockdownReportMessage{
+		ID:         m.ID,
+		Sender:     m.Sender,
+		Subject:    m.Subject.String,
+		Status:     string(m.Status),
+		SizeBytes:  m.SizeBytes,
+		EnqueuedAt: timestampToTime(m.EnqueuedAt),
+		RequestID:  m.RequestID.String,
+	}
+	if m.UserID.Valid {
+		id := uuid.UUID(m.UserID.Bytes)
+		msg.UserID = &id
+	}
+	if m.GroupID.Valid {
+		id := uuid.UUID(m.GroupID.Bytes)
+		msg.GroupID = &id
+	}
+	_ = json.Unmarshal(m.Recipients, &msg.Recipients)
+	if msg.Recipients == nil {
+		msg.Recipients = []string{}
+	}
+	if m.ProcessedAt.Valid {
+		t := m.ProcessedAt.Time
+		msg.ProcessedAt = &t
+	}
+	return msg
+}
+
+// releaseMessages requeues the paused messages selected by params.
+func releaseMessages(w http.ResponseWriter, r *http.Request, queries storage.Querier, auditLogger *auth.AuditLogger, targetType string, id uuid.UUID, params storage.ResumePausedMessagesParams) {
+	released, err := queries.ResumePausedMessages(r.Context(), params)
+	if err != nil {
+		respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if auditLogger != nil {
+		auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionReleaseMessages, targetType, id.String(), map[string]interface{}{
+			"messages": released,
+		})
+	}
+	respondJSON(w, http.StatusOK, releaseResponse{TargetType: targetType, TargetID: id, MessagesReleased: released})
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+var lockdownCallerID = uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+
+func lockdownHTTPRequest(id uuid.UUID, body, role string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+id.String()+"/lockdown", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, lockdownCallerID, testGroup().ID, role, "organization")
+	return req.WithContext(ctx)
+}
+
+func TestLockdownUserHandler(t *testing.T) {
+	user := testUser()
+	var statuses []string
+	var paused storage.PauseQueuedMessagesParams
+	var reported storage.ListMessagesSentSinceParams
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return user, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		updateUserStatusFn: func(ctx context.Context, arg storage.UpdateUserStatusParams) (storage.User, error) {
+			statuses = append(statuses, arg.Status)
+			return user, nil
+		},
+		revokeUserAPIKeyFn: func(ctx context.Context, id uuid.UUID) (int64, error) {
+			return 1, nil
+		},
+		listSessionsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Session, error) {
+			return []storage.Session{{ID: uuid.New()}, {ID: uuid.New()}}, nil
+		},
+		pauseQueuedMessagesFn: func(ctx context.Context, arg storage.PauseQueuedMessagesParams) (int64, error) {
+			paused = arg
+			return 3, nil
+		},
+		listMessagesSentSinceFn: func(ctx context.Context, arg storage.ListMessagesSentSinceParams) ([]storage.ListMessagesSentSinceRow, error) {
+			reported = arg
+			now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return []storage.ListMessagesSentSinceRow{
+				{ID: uuid.New(), Sender: "a@example.com", Recipients: []byte(`["x@example.net"]`), Status: storage.MessageStatusDelivered, EnqueuedAt: now},
+				{ID: uuid.New(), Sender: "a@example.com", Recipients: []byte(`["y@example.net"]`), Status: storage.MessageStatusPaused, EnqueuedAt: now},
+			}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	LockdownUserHandler(mock, nil).ServeHTTP(rec, lockdownHTTPRequest(user.ID, `{"hours":6,"reason":"leaked password"}`, "admin"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(statuses) != 1 || statuses[0] != "suspended" {
+		t.Errorf("status updates = %v, want [suspended]", statuses)
+	}
+	if uuid.UUID(paused.UserID.Bytes) != user.ID || paused.GroupID.Valid {
+		t.Errorf("paused with %+v, want only the user", paused)
+	}
+	if since := time.Since(reported.Since.Time); since < 6*time.Hour-time.Minute || since > 6*time.Hour+time.Minute {
+		t.Errorf("report since %v ago, want 6h", since)
+	}
+
+	var resp lockdownResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Users) != 1 || resp.Users[0].SessionsRevoked != 2 || !resp.Users[0].APIKeyRevoked {
+		t.Errorf("users = %+v", resp.Users)
+	}
+	if resp.MessagesPaused != 3 || len(resp.Report.Messages) != 2 || resp.Report.ByStatus["paused"] != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.Report.Messages[0].Recipients[0] != "x@example.net" {
+		t.Errorf("report message = %+v", resp.Report.Messages[0])
+	}
+}
+
+func TestLockdownUserHandler_Errors(t *testing.T) {
+	otherGroup := storage.Group{ID: uuid.New(), GroupType: "company"}
+	tests := []struct {
+		name string
+		id   uuid.UUID
+		body string
+		role string
+		code int
+	}{
+		{"member role", testUser().ID, "", "member", http.StatusForbidden},
+		{"own account", lockdownCallerID, "", "owner", http.StatusBadRequest},
+		{"hours too large", testUser().ID, `{"hours":1000}`, "owner", http.StatusBadRequest},
+		{"user outside the group tree", uuid.New(), "", "owner", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+					if userID == testUser().ID {
+						return []storage.Group{testGroup()}, nil
+					}
+					return []storage.Group{otherGroup}, nil
+				},
+				getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+					return otherGroup, nil
+				},
+				updateUserStatusFn: func(ctx context.Context, arg storage.UpdateUserStatusParams) (storage.User, error) {
+					t.Error("user was suspended")
+					return storage.User{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			LockdownUserHandler(mock, nil).ServeHTTP(rec, lockdownHTTPRequest(tt.id, tt.body, tt.role))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLockdownGroupHandler_SkipsCaller(t *testing.T) {
+	grp := testGroup()
+	member := uuid.New()
+	var suspended []uuid.UUID
+	var paused storage.PauseQueuedMessagesParams
+	mock := &mockQuerier{
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return grp, nil
+		},
+		listGroupMembersByGroupIDFn: func(ctx context.Context, groupID uuid.UUID) ([]storage.GroupMember, error) {
+			return []storage.GroupMember{{GroupID: grp.ID, UserID: lockdownCallerID}, {GroupID: grp.ID, UserID: member}}, nil
+		},
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return storage.User{ID: id, Status: "active"}, nil
+		},
+		updateUserStatusFn: func(ctx context.Context, arg storage.UpdateUserStatusParams) (storage.User, error) {
+			suspended = append(suspended, arg.ID)
+			return storage.User{}, nil
+		},
+		pauseQueuedMessagesFn: func(ctx context.Context, arg storage.PauseQueuedMessagesParams) (int64, error) {
+			paused = arg
+			return 0, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	LockdownGroupHandler(mock, nil).ServeHTTP(rec, lockdownHTTPRequest(grp.ID, "", "owner"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(suspended) != 1 || suspended[0] != member {
+		t.Errorf("suspended %v, want only the other member", suspended)
+	}
+	if uuid.UUID(paused.GroupID.Bytes) != grp.ID || paused.UserID.Valid {
+		t.Errorf("paused with %+v, want only the group", paused)
+	}
+}
+
+func TestReleaseGroupHandler(t *testing.T) {
+	grp := testGroup()
+	var released storage.ResumePausedMessagesParams
+	mock := &mockQuerier{
+		resumePausedMessagesFn: func(ctx context.Context, arg storage.ResumePausedMessagesParams) (int64, error) {
+			released = arg
+			return 4, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ReleaseGroupHandler(mock, nil).ServeHTTP(rec, lockdownHTTPRequest(grp.ID, "", "admin"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp releaseResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.MessagesReleased != 4 || uuid.UUID(released.GroupID.Bytes) != grp.ID {
+		t.Errorf("released %+v with %+v", resp, released)
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+// LoginSecurity configures the login protections beyond the failed attempt
+// counters of auth.RateLimiter.
+type LoginSecurity struct {
+	// Mailer sends self-service unlock links to locked accounts and notices
+	// of logins from unfamiliar locations. When nil, no emails are sent.
+	Mailer *sysmail.Mailer
+	// DetectAnomalies flags logins from networks or countries the user has
+	// not logged in from before.
+	DetectAnomalies bool
+	// CountryHeader names a request header with the client's ISO country
+	// code, set by a trusted proxy or CDN (e.g. CF-IPCountry). When empty,
+	// logins are compared by network only.
+	CountryHeader string
+}
+
+// unlockRequest is the JSON body for POST /api/v1/auth/unlock.
+type unlockRequest struct {
+	Token string `json:"token"`
+}
+
+// recordLoginFailure counts a failed login for email. When the failure
+// locks the account of user (nil for an unknown email), an unlock link is
+// emailed to them, once per lockout.
+func recordLoginFailure(r *http.Request, queries storage.Querier, jwtService *auth.JWTService, rateLimiter *auth.RateLimiter, security LoginSecurity, auditLogger *auth.AuditLogger, email string, user *storage.User) {
+	if rateLimiter == nil {
+		return
+	}
+	ctx := r.Context()
+	_ = rateLimiter.RecordFailedLogin(ctx, email)
+	if user == nil || rateLimiter.CheckLoginRateLimit(ctx, email) == nil {
+		return
+	}
+
+	nonce, issued, err := rateLimiter.IssueUnlockNonce(ctx, email)
+	if err != nil || !issued {
+		return
+	}
+	groupID := userGroupID(r, queries, user.ID)
+	if auditLogger != nil {
+		auditLogger.LogAuthEvent(ctx, r, groupID, user.ID, auth.AuditActionLockout, "too many failed login attempts", nil)
+	}
+	if security.Mailer == nil {
+		return
+	}
+
+	lockout := rateLimiter.LoginLockoutDuration()
+	token, err := jwtService.GenerateActionToken(auth.PurposeUnlock, email, nonce, lockout)
+	if err != nil {
+		return
+	}
+	_ = security.Mailer.SendTemplate(ctx, groupID, []string{user.Email}, sysmail.TemplateUnlock, sysmail.Data{
+		Email:   user.Email,
+		Link:    security.Mailer.Link("/api/v1/auth/unlock", url.Values{"token": {token}}),
+		Lockout: lockout,
+	})
+}
+
+// checkLoginAnomaly records the network of a successful login and, when it
+// is unfamiliar, logs an auth.login_anomaly activity entry and emails the
+// user a notice.
+func checkLoginAnomaly(r *http.Request, queries storage.Querier, security LoginSecurity, auditLogger *auth.AuditLogger, user storage.User, groupID uuid.UUID, ip *netip.Addr) {
+	if !security.DetectAnomalies || ip == nil {
+		return
+	}
+	ctx := r.Context()
+	network := auth.LoginNetwork(*ip)
+	country := ""
+	if security.CountryHeader != "" {
+		country = auth.NormalizeCountry(r.Header.Get(security.CountryHeader))
+	}
+
+	known, err := queries.ListLoginNetworks(ctx, user.ID)
+	if err != nil {
+		return
+	}
+	reason := auth.DetectLoginAnomaly(known, network, country)
+	_ = queries.RecordLoginNetwork(ctx, storage.RecordLoginNetworkParams{
+		UserID:  user.ID,
+		Network: network,
+		Country: pgtype.Text{String: country, Valid: country != ""},
+	})
+	if reason == "" {
+		return
+	}
+
+	if auditLogger != nil {
+		auditLogger.LogAuthEvent(ctx, r, groupID, user.ID, auth.AuditActionLoginAnomaly, reason, map[string]interface{}{
+			"network": network,
+			"country": country,
+		})
+	}
+	if security.Mailer == nil {
+		return
+	}
+
+	location := network
+	if country != "" {
+		location = fmt.Sprintf("%s (%s)", network, country)
+	}
+	_ = security.Mailer.SendTemplate(ctx, groupID, []string{user.Email}, sysmail.TemplateSignInNotice, sysmail.Data{
+		Email:    user.Email,
+		Link:     security.Mailer.Link("/api/v1/auth/sessions", nil),
+		Location: location,
+		Time:     time.Now(),
+	})
+}
+
+// userGroupID returns the ID of the user's first group for activity log
+// entries, or uuid.Nil.
+func userGroupID(r *http.Request, queries storage.Querier, userID uuid.UUID) uuid.UUID {
+	groups, err := queries.ListGroupsByUserID(r.Context(), userID)
+	if err != nil || len(groups) == 0 {
+		return uuid.Nil
+	}
+	return groups[0].ID
+}
+
+// unlockDetailsResponse is the JSON response for GET /api/v1/auth/unlock.
+type unlockDetailsResponse struct {
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetUnlockHandler handles GET /api/v1/auth/unlock.
+// Shows the locked account of an emailed unlock link without lifting the
+// lockout, so link scanners and prefetchers cannot use up the link. The
+// client unlocks with POST /api/v1/auth/unlock.
+func GetUnlockHandler(jwtService *auth.JWTService, rateLimiter *auth.RateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			respondError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+
+		claims, err := jwtService.ValidateActionToken(token, auth.PurposeUnlock)
+		if err != nil || rateLimiter == nil {
+			respondError(w, http.StatusBadRequest, "invalid or expired unlock link")
+			return
+		}
+		pending, err := rateLimiter.UnlockPending(r.Context(), claims.Subject, claims.ID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if !pending {
+			respondError(w, http.StatusBadRequest, "invalid or expired unlock link")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, unlockDetailsResponse{
+			Email:     claims.Subject,
+			ExpiresAt: claims.ExpiresAt.Time,
+		})
+	}
+}
+
+// UnlockHandler handles POST /api/v1/auth/unlock.
+// Lifts a login lockout with the token of the emailed unlock link, given in
+// the JSON body. Each link works once and only during the lockout it was
+// sent for.
+func UnlockHandler(queries storage.Querier, jwtService *auth.JWTService, rateLimiter *auth.RateLimiter, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req unlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Token == "" {
+			respondError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+
+		claims, err := jwtService.ValidateActionToken(req.Token, auth.PurposeUnlock)
+		if err != nil || rateLimiter == nil {
+			respondError(w, http.StatusBadRequest, "invalid or expired unlock link")
+			return
+		}
+		unlocked, err := rateLimiter.Unlock(r.Context(), claims.Subject, claims.ID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if !unlocked {
+			respondError(w, http.StatusBadRequest, "invalid or expired unlock link")
+			return
+		}
+
+		if auditLogger != nil {
+			if user, err := queries.GetUserByEmail(r.Context(), claims.Subject); err == nil {
+				auditLogger.LogAuthEvent(r.Context(), r, userGroupID(r, queries, user.ID), user.ID, auth.AuditActionUnlock, "", nil)
+			}
+		}
+
+		respondJSON(w, http.StatusOK, map[string]string{"status": "unlocked"})
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+// directTx runs transactions directly against the mock querier.
+type directTx struct{ queries storage.Querier }
+
+func (d directTx) ExecTx(_ context.Context, fn func(storage.Querier) error) error {
+	return fn(d.queries)
+}
+
+func TestLoginHandler_AnomalyNotice(t *testing.T) {
+	hash, _ := auth.HashPassword("password123")
+	user := testUser()
+	user.PasswordHash = hash
+	known := []storage.LoginNetwork{{UserID: user.ID, Network: "198.51.100.0/24", Country: pgtype.Text{String: "KR", Valid: true}}}
+
+	tests := []struct {
+		country string
+		notice  bool
+	}{
+		{"KR", false},
+		{"us", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			var recorded storage.RecordLoginNetworkParams
+			var sent []string
+			mock := &mockQuerier{
+				getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+					return user, nil
+				},
+				listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+					return []storage.Group{testGroup()}, nil
+				},
+				getGroupByNameFn: func(ctx context.Context, name string) (storage.Group, error) {
+					return storage.Group{ID: uuid.New(), Name: name}, nil
+				},
+				listLoginNetworksFn: func(ctx context.Context, userID uuid.UUID) ([]storage.LoginNetwork, error) {
+					return known, nil
+				},
+				recordLoginNetworkFn: func(ctx context.Context, arg storage.RecordLoginNetworkParams) error {
+					recorded = arg
+					return nil
+				},
+				enqueueMessageFn: func(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+					var to []string
+					_ = json.Unmarshal(arg.Recipients, &to)
+					sent = append(sent, to...)
+					if !strings.Contains(arg.Body.String, "192.0.2.0/24 (US)") {
+						t.Errorf("notice does not name the location:\n%s", arg.Body.String)
+					}
+					return storage.Message{ID: uuid.New()}, nil
+				},
+			}
+			security := LoginSecurity{
+				Mailer:          sysmail.New(mock, directTx{mock}, sysmail.Config{BaseURL: "https://mail.example.com"}),
+				DetectAnomalies: true,
+				CountryHeader:   "CF-IPCountry",
+			}
+			jwtSvc := auth.NewJWTService(auth.JWTConfig{
+				SigningKey:         "test-secret-key-that-is-long-enough-32",
+				AccessTokenExpiry:  15 * time.Minute,
+				RefreshTokenExpiry: 7 * 24 * time.Hour,
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"test@example.com","password":"password123"}`))
+			req.Header.Set("CF-IPCountry", tt.country)
+			rec := httptest.NewRecorder()
+			LoginHandler(mock, jwtSvc, nil, nil, security).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if recorded.Network != "192.0.2.0/24" || recorded.Country.String != strings.ToUpper(tt.country) {
+				t.Errorf("recorded login network %+v", recorded)
+			}
+			if got := len(sent) > 0; got != tt.notice {
+				t.Errorf("notice sent = %v, want %v", got, tt.notice)
+			}
+			if tt.notice && sent[0] != user.Email {
+				t.Errorf("notice sent to %v", sent)
+			}
+		})
+	}
+}
+
+func TestUnlockHandler_InvalidToken(t *testing.T) {
+	jwtSvc := auth.NewJWTService(auth.JWTConfig{SigningKey: "test-secret-key-that-is-long-enough-32"})
+	wrongPurpose, _ := jwtSvc.GenerateActionToken("other", "test@example.com", "nonce", time.Hour)
+	valid, _ := jwtSvc.GenerateActionToken(auth.PurposeUnlock, "test@example.com", "nonce", time.Hour)
+	rateLimiter := auth.NewRateLimiter(nil, auth.RateLimitConfig{})
+
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"missing token", httptest.NewRequest(http.MethodPost, "/api/v1/auth/unlock", strings.NewReader(`{}`))},
+		{"garbage", httptest.NewRequest(http.MethodPost, "/api/v1/auth/unlock", strings.NewReader(`{"token":"abc"}`))},
+		{"other purpose", httptest.NewRequest(http.MethodPost, "/api/v1/auth/unlock", strings.NewReader(`{"token":"`+wrongPurpose+`"}`))},
+		{"query token", httptest.NewRequest(http.MethodPost, "/api/v1/auth/unlock?token="+valid, strings.NewReader(`{}`))},
+		{"no lockout", httptest.NewRequest(http.MethodPost, "/api/v1/auth/unlock", strings.NewReader(`{"token":"`+valid+`"}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			UnlockHandler(&mockQuerier{}, jwtSvc, rateLimiter, nil).ServeHTTP(rec, tt.req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetUnlockHandler_InvalidToken(t *testing.T) {
+	jwtSvc := auth.NewJWTService(auth.JWTConfig{SigningKey: "test-secret-key-that-is-long-enough-32"})
+	wrongPurpose, _ := jwtSvc.GenerateActionToken("other", "test@example.com", "nonce", time.Hour)
+	valid, _ := jwtSvc.GenerateActionToken(auth.PurposeUnlock, "test@example.com", "nonce", time.Hour)
+	rateLimiter := auth.NewRateLimiter(nil, auth.RateLimitConfig{})
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"missing token", "/api/v1/auth/unlock"},
+		{"garbage", "/api/v1/auth/unlock?token=abc"},
+		{"other purpose", "/api/v1/auth/unlock?token=" + wrongPurpose},
+		{"no lockout", "/api/v1/auth/unlock?token=" + valid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			GetUnlockHandler(jwtSvc, rateLimiter).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/msgdefaults"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// messageDefaultsResponse is the JSON representation of a group's message
+// defaults.
+type messageDefaultsResponse struct {
+	Enabled bool `json:"enabled"`
+	msgdefaults.Defaults
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// GetMessageDefaultsHandler handles GET /api/v1/message-defaults. Groups
+// that never set defaults report enabled false.
+func GetMessageDefaultsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		md, err := queries.GetMessageDefaults(r.Context(), groupID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusOK, toMessageDefaultsResponse(storage.MessageDefault{}, false))
+			return
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		respondJSON(w, http.StatusOK, toMessageDefaultsResponse(md, true))
+	}
+}
+
+// UpdateMessageDefaultsHandler handles PUT /api/v1/message-defaults.
+// Replaces the headers the worker adds to the group's messages that do not
+// set them, the footer it appends to their bodies, and the tags and
+// templates exempt from both. Requires owner or admin role.
+func UpdateMessageDefaultsHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req msgdefaults.Defaults
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := req.Normalize(); err != nil {
+			respondValidationErrors(w, []string{err.Error()})
+			return
+		}
+		headers, err := json.Marshal(req.Headers)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		excludeTags, err := json.Marshal(req.ExcludeTags)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		excludeTemplates, err := json.Marshal(req.ExcludeTemplates)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		md, err := queries.UpsertMessageDefaults(r.Context(), storage.UpsertMessageDefaultsParams{
+			GroupID:          groupID,
+			Headers:          headers,
+			FooterText:       req.FooterText,
+			FooterHtml:       req.FooterHTML,
+			ExcludeTags:      excludeTags,
+			ExcludeTemplates: excludeTemplates,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateMessageDefaults, "group", groupID.String(), map[string]interface{}{
+				"headers":           req.Headers,
+				"footer_text":       req.FooterText != "",
+				"footer_html":       req.FooterHTML != "",
+				"exclude_tags":      req.ExcludeTags,
+				"exclude_templates": req.ExcludeTemplates,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toMessageDefaultsResponse(md, true))
+	}
+}
+
+// DeleteMessageDefaultsHandler handles DELETE /api/v1/message-defaults.
+// Messages are sent without default headers or footer again. Requires
+// owner or admin role.
+func DeleteMessageDefaultsHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		n, err := queries.DeleteMessageDefaults(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "message defaults not set")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteMessageDefaults, "group", groupID.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func toMessageDefaultsResponse(md storage.MessageDefault, enabled bool) messageDefaultsResponse {
+	resp := messageDefaultsResponse{
+		Enabled: enabled,
+		Defaults: msgdefaults.Defaults{
+			Headers:          map[string]string{},
+			FooterText:       md.FooterText,
+			FooterHTML:       md.FooterHtml,
+			ExcludeTags:      []string{},
+			ExcludeTemplates: []string{},
+		},
+	}
+	_ = json.Unmarshal(md.Headers, &resp.Headers)
+	_ = json.Unmarshal(md.ExcludeTags, &resp.ExcludeTags)
+	_ = json.Unmarshal(md.ExcludeTemplates, &resp.ExcludeTemplates)
+	if md.UpdatedAt.Valid {
+		t := md.UpdatedAt.Time
+		resp.UpdatedAt = &t
+	}
+	return resp
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestGetMessageDefaultsHandler_NotSet(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetMessageDefaultsHandler(&mockQuerier{}).ServeHTTP(rec, shadowRequest(http.MethodGet, "/api/v1/message-defaults", "", "member"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp messageDefaultsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Enabled || resp.Headers == nil || resp.ExcludeTags == nil {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestUpdateMessageDefaultsHandler(t *testing.T) {
+	var got storage.UpsertMessageDefaultsParams
+	mock := &mockQuerier{
+		upsertMessageDefaultsFn: func(ctx context.Context, arg storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error) {
+			got = arg
+			return storage.MessageDefault{GroupID: arg.GroupID, Headers: arg.Headers, FooterText: arg.FooterText, ExcludeTags: arg.ExcludeTags, ExcludeTemplates: arg.ExcludeTemplates}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"headers":{"organization":"Example Inc."},"footer_text":"Example Inc., 1 Main St","exclude_templates":["password-reset"]}`
+	UpdateMessageDefaultsHandler(mock, nil).ServeHTTP(rec, shadowRequest(http.MethodPut, "/api/v1/message-defaults", body, "owner"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID != testGroup().ID || string(got.Headers) != `{"Organization":"Example Inc."}` || string(got.ExcludeTags) != `[]` {
+		t.Errorf("unexpected upsert params: %+v", got)
+	}
+	var resp messageDefaultsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Enabled || resp.Headers["Organization"] != "Example Inc." || resp.FooterText != "Example Inc., 1 Main St" || len(resp.ExcludeTemplates) != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestUpdateMessageDefaultsHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		role     string
+		wantCode int
+	}{
+		{name: "member", body: `{"footer_text":"x"}`, role: "member", wantCode: http.StatusForbidden},
+		{name: "reserved header", body: `{"headers":{"From":"x@example.com"}}`, role: "admin", wantCode: http.StatusBadRequest},
+		{name: "header injection", body: `{"headers":{"X-A":"a\r\nBcc: x@example.com"}}`, role: "admin", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				upsertMessageDefaultsFn: func(ctx context.Context, arg storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error) {
+					t.Error("message defaults changed")
+					return storage.MessageDefault{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			UpdateMessageDefaultsHandler(mock, nil).ServeHTTP(rec, shadowRequest(http.MethodPut, "/api/v1/message-defaults", tt.body, tt.role))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteMessageDefaultsHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		deleted  int64
+		wantCode int
+	}{
+		{"deleted", 1, http.StatusNoContent},
+		{"not set", 0, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				deleteMessageDefaultsFn: func(ctx context.Context, groupID uuid.UUID) (int64, error) {
+					return tt.deleted, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			DeleteMessageDefaultsHandler(mock, nil).ServeHTTP(rec, shadowRequest(http.MethodDelete, "/api/v1/message-defaults", "", "admin"))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
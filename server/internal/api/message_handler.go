@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/msgtag"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// messageStatuses are the values accepted by the status filter.
+var messageStatuses = map[storage.MessageStatus]bool{
+	storage.MessageStatusQueued:        true,
+	storage.MessageStatusProcessing:    true,
+	storage.MessageStatusDelivered:     true,
+	storage.MessageStatusFailed:        true,
+	storage.MessageStatusEnqueueFailed: true,
+	storage.MessageStatusStorageError:  true,
+	storage.MessageStatusPaused:        true,
+}
+
+// messageResponse is the JSON representation of a message. Bodies are not
+// returned.
+type messageResponse struct {
+	ID          uuid.UUID         `json:"id"`
+	Sender      string            `json:"sender"`
+	Recipients  []string          `json:"recipients"`
+	Subject     string            `json:"subject,omitempty"`
+	Status      string            `json:"status"`
+	Tags        []string          `json:"tags"`
+	Metadata    map[string]string `json:"metadata"`
+	SizeBytes   int64             `json:"size_bytes"`
+	EnqueuedAt  time.Time         `json:"enqueued_at"`
+	ProcessedAt *time.Time        `json:"processed_at,omitempty"`
+	// TLSVersion and TLSCipher record how the message was submitted; both
+	// are omitted for plaintext submissions.
+	TLSVersion string `json:"tls_version,omitempty"`
+	TLSCipher  string `json:"tls_cipher,omitempty"`
+	// RequestID is the correlation ID of the SMTP session that submitted
+	// the message; search the logs for it to trace delivery.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// deliveryAttemptResponse is one delivery attempt of a message.
+type deliveryAttemptResponse struct {
+	AttemptNumber     int32     `json:"attempt_number"`
+	Provider          string    `json:"provider,omitempty"`
+	ProviderID        string    `json:"provider_id,omitempty"`
+	ProviderMessageID string    `json:"provider_message_id,omitempty"`
+	Status            string    `json:"status"`
+	ResponseCode      *int32    `json:"response_code,omitempty"`
+	Error             string    `json:"error,omitempty"`
+	DurationMs        *int32    `json:"duration_ms,omitempty"`
+	RequestID         string    `json:"request_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// Connection is omitted when no provider request was recorded, as for
+	// inbound routes and providers that are not reached over HTTP.
+	Connection *deliveryConnectionResponse `json:"connection,omitempty"`
+}
+
+// deliveryConnectionResponse is the connection that carried a delivery
+// attempt to the provider. The timings of the connection set-up are
+// omitted for connections reused from the idle pool.
+type deliveryConnectionResponse struct {
+	EgressIP       string `json:"egress_ip,omitempty"`
+	RemoteAddr     string `json:"remote_addr,omitempty"`
+	Endpoint       string `json:"endpoint,omitempty"`
+	TLSVersion     string `json:"tls_version,omitempty"`
+	TLSCipher      string `json:"tls_cipher,omitempty"`
+	ConnectMs      *int32 `json:"connect_ms,omitempty"`
+	TLSHandshakeMs *int32 `json:"tls_handshake_ms,omitempty"`
+	FirstByteMs    *int32 `json:"first_byte_ms,omitempty"`
+}
+
+// messageDetailResponse is the JSON response for GET /api/v1/messages/{id}:
+// the message and its delivery attempts, oldest first.
+type messageDetailResponse struct {
+	messageResponse
+	Deliveries []deliveryAttemptResponse `json:"deliveries"`
+}
+
+func toMessageResponse(m storage.Message) messageResponse {
+	resp := messageResponse{
+		ID:         m.ID,
+		Sender:     m.Sender,
+		Subject:    m.Subject.String,
+		Status:     string(m.Status),
+		SizeBytes:  m.SizeBytes,
+		EnqueuedAt: timestampToTime(m.EnqueuedAt),
+		TLSVersion: m.TlsVersion.String,
+		TLSCipher:  m.TlsCipher.String,
+		RequestID:  m.RequestID.String,
+	}
+	_ = json.Unmarshal(m.Recipients, &resp.Recipients)
+	resp.Tags, resp.Metadata = msgtag.Decode(m.Tags, m.Metadata)
+	if resp.Recipients == nil {
+		resp.Recipients = []string{}
+	}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+	if m.ProcessedAt.Valid {
+		t := m.ProcessedAt.Time
+		resp.ProcessedAt = &t
+	}
+	return resp
+}
+
+// requestGroupID returns the group a group-scoped report is for: the
+// caller's group, or the accessible group named by the group_id query
+// param. It writes the error response and returns false on failure.
+func requestGroupID(w http.ResponseWriter, r *http.Request, queries storage.Querier) (uuid.UUID, bool) {
+	g := r.URL.Query().Get("group_id")
+	if g == "" {
+		return auth.GroupIDFromContext(r.Context()), true
+	}
+	id, err := uuid.Parse(g)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid group_id format")
+		return uuid.Nil, false
+	}
+	if !canAccessGroup(r.Context(), queries, id) {
+		respondError(w, http.StatusForbidden, "access denied")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// ListMessagesHandler handles GET /api/v1/messages.
+// Lists the most recent messages of the caller's group, newest first.
+// Supports query params: group_id (the caller's group or a sub-group),
+// tag, status and limit (default 50, max 500).
+func ListMessagesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := requestGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		params := storage.ListGroupMessagesParams{
+			GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
+			MaxResults: 50,
+		}
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			params.Tag = pgtype.Text{String: tag, Valid: true}
+		}
+		if s := r.URL.Query().Get("status"); s != "" {
+			status := storage.MessageStatus(s)
+			if !messageStatuses[status] {
+				respondError(w, http.StatusBadRequest, "invalid status")
+				return
+			}
+			params.Status = storage.NullMessageStatus{MessageStatus: status, Valid: true}
+		}
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil && v > 0 {
+				params.MaxResults = int32(min(v, 500))
+			}
+		}
+
+		messages, err := queries.ListGroupMessages(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]messageResponse, len(messages))
+		for i, m := range messages {
+			resp[i] = toMessageResponse(m)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// GetMessageHandler handles GET /api/v1/messages/{id}.
+// Returns a message of the caller's group or a sub-group together with its
+// delivery timeline, so clients can poll a submitted message until it is
+// delivered or failed.
+func GetMessageHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid message id format")
+			return
+		}
+
+		msg, err := queries.GetMessageByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "message not found")
+			return
+		}
+		// Messages of other groups are reported as missing rather than
+		// forbidden so IDs cannot be probed.
+		if !msg.GroupID.Valid || !canAccessGroup(r.Context(), queries, uuid.UUID(msg.GroupID.Bytes)) {
+			respondError(w, http.StatusNotFound, "message not found")
+			return
+		}
+
+		logs, err := queries.ListDeliveryLogsByMessageID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := messageDetailResponse{
+			messageResponse: toMessageResponse(msg),
+			Deliveries:      make([]deliveryAttemptResponse, len(logs)),
+		}
+		for i, l := range logs {
+			resp.Deliveries[i] = toDeliveryAttemptResponse(l)
+		}
+		slices.SortStableFunc(resp.Deliveries, func(a, b deliveryAttemptResponse) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// toDeliveryAttemptResponse converts a delivery log row to an attempt
+// summary.
+func toDeliveryAttemptResponse(l storage.DeliveryLog) deliveryAttemptResponse {
+	a := deliveryAttemptResponse{
+		AttemptNumber:     l.AttemptNumber,
+		Provider:          l.Provider.String,
+		ProviderMessageID: l.ProviderMessageID.String,
+		Status:            l.Status,
+		Error:             l.LastError.String,
+		RequestID:         l.RequestID.String,
+		CreatedAt:         timestampToTime(l.CreatedAt),
+		UpdatedAt:         timestampToTime(l.UpdatedAt),
+	}
+	if l.ProviderID.Valid {
+		a.ProviderID = uuid.UUID(l.ProviderID.Bytes).String()
+	}
+	if l.ResponseCode.Valid {
+		a.ResponseCode = &l.ResponseCode.Int32
+	}
+	if l.DurationMs.Valid {
+		a.DurationMs = &l.DurationMs.Int32
+	}
+	if l.ProviderEndpoint.Valid || l.EgressIp.Valid {
+		c := &deliveryConnectionResponse{
+			EgressIP:   l.EgressIp.String,
+			RemoteAddr: l.RemoteAddr.String,
+			Endpoint:   l.ProviderEndpoint.String,
+			TLSVersion: l.TlsVersion.String,
+			TLSCipher:  l.TlsCipher.String,
+		}
+		if l.ConnectMs.Valid {
+			c.ConnectMs = &l.ConnectMs.Int32
+		}
+		if l.TlsHandshakeMs.Valid {
+			c.TLSHandshakeMs = &l.TlsHandshakeMs.Int32
+		}
+		if l.FirstByteMs.Valid {
+			c.FirstByteMs = &l.FirstByteMs.Int32
+		}
+		a.Connection = c
+	}
+	return a
+}
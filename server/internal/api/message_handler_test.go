@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestListMessagesHandler_Filters(t *testing.T) {
+	var got storage.ListGroupMessagesParams
+	mock := &mockQuerier{
+		listGroupMessagesFn: func(ctx context.Context, arg storage.ListGroupMessagesParams) ([]storage.Message, error) {
+			got = arg
+			return []storage.Message{{
+				ID:         uuid.New(),
+				Sender:     "sender@example.com",
+				Recipients: []byte(`["a@example.com"]`),
+				Status:     storage.MessageStatusDelivered,
+				Tags:       []byte(`["billing"]`),
+				Metadata:   []byte(`{"customer_id":"42"}`),
+			}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?tag=billing&status=delivered&limit=1000", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+	rec := httptest.NewRecorder()
+	ListMessagesHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID.Bytes != testGroup().ID || got.Tag.String != "billing" || got.Status.MessageStatus != storage.MessageStatusDelivered {
+		t.Errorf("unexpected list params: %+v", got)
+	}
+	if got.MaxResults != 500 {
+		t.Errorf("expected limit capped at 500, got %d", got.MaxResults)
+	}
+
+	var resp []messageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Tags[0] != "billing" || resp[0].Metadata["customer_id"] != "42" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestListMessagesHandler_InvalidStatus(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages?status=lost", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+	rec := httptest.NewRecorder()
+	ListMessagesHandler(&mockQuerier{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func getMessageRequest(id string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/messages/"+id, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization")
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestGetMessageHandler_Timeline(t *testing.T) {
+	msgID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+	mock := &mockQuerier{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Message, error) {
+			return storage.Message{
+				ID:      id,
+				Sender:  "sender@example.com",
+				Status:  storage.MessageStatusDelivered,
+				GroupID: pgtype.UUID{Bytes: testGroup().ID, Valid: true},
+			}, nil
+		},
+		listDeliveryLogsByMessageIDFn: func(ctx context.Context, id uuid.UUID) ([]storage.DeliveryLog, error) {
+			return []storage.DeliveryLog{
+				{MessageID: id, AttemptNumber: 2, Status: "delivered", CreatedAt: pgtype.Timestamptz{Time: now, Valid: true}},
+				{MessageID: id, AttemptNumber: 1, Status: "failed", CreatedAt: pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true}},
+			}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	GetMessageHandler(mock).ServeHTTP(rec, getMessageRequest(msgID.String()))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp messageDetailResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.ID != msgID || resp.Status != "delivered" {
+		t.Errorf("unexpected message: %+v", resp.messageResponse)
+	}
+	if len(resp.Deliveries) != 2 || resp.Deliveries[0].AttemptNumber != 1 || resp.Deliveries[1].AttemptNumber != 2 {
+		t.Errorf("expected deliveries oldest first, got %+v", resp.Deliveries)
+	}
+}
+
+func TestGetMessageHandler_OtherGroup(t *testing.T) {
+	mock := &mockQuerier{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Message, error) {
+			return storage.Message{ID: id, GroupID: pgtype.UUID{Bytes: uuid.New(), Valid: true}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	GetMessageHandler(mock).ServeHTTP(rec, getMessageRequest(uuid.NewString()))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestGetMessageHandler_InvalidID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetMessageHandler(&mockQuerier{}).ServeHTTP(rec, getMessageRequest("not-a-uuid"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
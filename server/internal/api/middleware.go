@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
+	"github.com/sungwon/smtp-proxy/server/internal/i18n"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
 )
 
@@ -45,33 +48,70 @@ func (sw *statusWriter) WriteHeader(code int) {
 	sw.ResponseWriter.WriteHeader(code)
 }
 
+// requestIDHeader is accepted as an alternative to X-Correlation-ID for
+// clients and proxies that already issue request IDs.
+const requestIDHeader = "X-Request-ID"
+
+// maxCorrelationIDLen bounds client-supplied IDs, which are logged and
+// stored with each message.
+const maxCorrelationIDLen = 128
+
 // CorrelationIDMiddleware generates or extracts a correlation ID from the
-// X-Correlation-ID header and stores it in the request context.
+// X-Correlation-ID (or X-Request-ID) header, stores it in the request
+// context, and returns it in both response headers. Messages submitted by
+// the request carry the ID through the queue to the worker.
 func CorrelationIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		correlationID := r.Header.Get("X-Correlation-ID")
+		correlationID := r.Header.Get(apierror.RequestIDHeader)
 		if correlationID == "" {
+			correlationID = r.Header.Get(requestIDHeader)
+		}
+		if correlationID == "" || len(correlationID) > maxCorrelationIDLen {
 			correlationID = logger.NewCorrelationID()
 		}
 
-		w.Header().Set("X-Correlation-ID", correlationID)
+		w.Header().Set(apierror.RequestIDHeader, correlationID)
+		w.Header().Set(requestIDHeader, correlationID)
 
 		ctx := logger.WithCorrelationID(r.Context(), correlationID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// RecoverMiddleware recovers from panics, logs the error, and returns a 500 response.
+// LocaleMiddleware negotiates the request's locale from the Accept-Language
+// header. Error messages are localized through the Content-Language
+// response header, and system emails sent by the request fall back to the
+// locale stored in the request context. Requests naming no supported
+// language get neither.
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		if locale == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(apierror.LanguageHeader, locale)
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+	})
+}
+
+// RecoverMiddleware recovers from panics, reports them with crash.Report,
+// and returns a 500 response. http.ErrAbortHandler is re-panicked so
+// net/http still aborts the response.
 func RecoverMiddleware(log zerolog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					log.Error().
-						Interface("panic", err).
-						Str("method", r.Method).
-						Str("path", r.URL.Path).
-						Msg("panic recovered")
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+					crash.Report(log, "api", err, map[string]string{
+						"method":         r.Method,
+						"path":           r.URL.Path,
+						"correlation_id": logger.CorrelationIDFromContext(r.Context()),
+					})
 					respondError(w, http.StatusInternalServerError, "internal server error")
 				}
 			}()
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/rs/zerolog"
+	"github.com/sungwon/smtp-proxy/server/internal/i18n"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
 )
 
@@ -59,6 +61,49 @@ func TestCorrelationIDMiddleware_UsesExistingID(t *testing.T) {
 	}
 }
 
+func TestCorrelationIDMiddleware_AcceptsRequestIDHeader(t *testing.T) {
+	var capturedID string
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedID = logger.CorrelationIDFromContext(r.Context())
+	})
+
+	handler := CorrelationIDMiddleware(inner)
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "req-456")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if capturedID != "req-456" {
+		t.Errorf("expected correlation ID req-456, got %s", capturedID)
+	}
+	for _, h := range []string{"X-Correlation-ID", "X-Request-ID"} {
+		if got := rec.Header().Get(h); got != "req-456" {
+			t.Errorf("%s = %q, want req-456", h, got)
+		}
+	}
+}
+
+func TestCorrelationIDMiddleware_RejectsOversizedID(t *testing.T) {
+	var capturedID string
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedID = logger.CorrelationIDFromContext(r.Context())
+	})
+
+	handler := CorrelationIDMiddleware(inner)
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Correlation-ID", strings.Repeat("a", 200))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if capturedID == "" || len(capturedID) > 128 {
+		t.Errorf("expected a generated correlation ID, got %q", capturedID)
+	}
+}
+
 func TestLoggingMiddleware_SetsStatus(t *testing.T) {
 	log := zerolog.Nop()
 
@@ -96,6 +141,48 @@ func TestLoggingMiddleware_DefaultStatus(t *testing.T) {
 	}
 }
 
+func TestLocaleMiddleware_LocalizesErrors(t *testing.T) {
+	var locale string
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale = i18n.FromContext(r.Context())
+		respondError(w, http.StatusBadRequest, "invalid request body")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Accept-Language", "fr-CA, ko-KR;q=0.9, en;q=0.8")
+	rec := httptest.NewRecorder()
+	LocaleMiddleware(inner).ServeHTTP(rec, req)
+
+	if locale != "ko" || rec.Header().Get("Content-Language") != "ko" {
+		t.Fatalf("expected locale ko, got context %q and header %q", locale, rec.Header().Get("Content-Language"))
+	}
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["message"] != "요청 본문이 올바르지 않습니다" || resp["error"] != "invalid request body" {
+		t.Errorf("unexpected localization: %v", resp)
+	}
+}
+
+func TestLocaleMiddleware_Unsupported(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rec := httptest.NewRecorder()
+	LocaleMiddleware(inner).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Language"); got != "" {
+		t.Errorf("expected no Content-Language, got %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), `"message":"invalid request body"`) {
+		t.Errorf("expected English message, got %s", rec.Body.String())
+	}
+}
+
 func TestRecoverMiddleware_RecoversPanic(t *testing.T) {
 	log := zerolog.Nop()
 
@@ -142,6 +229,20 @@ func TestRecoverMiddleware_NoRecoverOnNormal(t *testing.T) {
 	}
 }
 
+func TestRecoverMiddleware_RepanicsAbortHandler(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	handler := RecoverMiddleware(zerolog.Nop())(inner)
+
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler to propagate", err)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+}
+
 func TestStatusWriter_WriteHeaderOnce(t *testing.T) {
 	rec := httptest.NewRecorder()
 	sw := &statusWriter{ResponseWriter: rec, status: http.StatusOK}
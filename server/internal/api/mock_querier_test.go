@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
@@ -27,7 +28,13 @@ type mockQuerier struct {
 	getUserByAPIKeyFn  func(ctx context.Context, apiKey sql.NullString) (storage.User, error)
 	listUsersFn        func(ctx context.Context) ([]storage.User, error)
 	updateUserFn       func(ctx context.Context, arg storage.UpdateUserParams) (storage.User, error)
+	updateUserPasswordFn func(ctx context.Context, arg storage.UpdateUserPasswordParams) error
 	updateUserStatusFn func(ctx context.Context, arg storage.UpdateUserStatusParams) (storage.User, error)
+	updateUserTLSPolicyFn func(ctx context.Context, arg storage.UpdateUserTLSPolicyParams) (storage.User, error)
+	updateUserAutoBCCFn   func(ctx context.Context, arg storage.UpdateUserAutoBCCParams) (storage.User, error)
+	createSmtpClientCertFn        func(ctx context.Context, arg storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error)
+	listSmtpClientCertsByUserIDFn func(ctx context.Context, userID uuid.UUID) ([]storage.SmtpClientCert, error)
+	deleteSmtpClientCertFn        func(ctx context.Context, arg storage.DeleteSmtpClientCertParams) (int64, error)
 	deleteUserFn       func(ctx context.Context, id uuid.UUID) error
 
 	// Group methods
@@ -39,6 +46,12 @@ type mockQuerier struct {
 	updateGroupStatusFn func(ctx context.Context, arg storage.UpdateGroupStatusParams) (storage.Group, error)
 	deleteGroupFn       func(ctx context.Context, id uuid.UUID) error
 
+	updateGroupHTMLProcessingFn func(ctx context.Context, arg storage.UpdateGroupHTMLProcessingParams) (storage.Group, error)
+	updateGroupRecipientValidationFn func(ctx context.Context, arg storage.UpdateGroupRecipientValidationParams) (storage.Group, error)
+	updateGroupLocaleFn func(ctx context.Context, arg storage.UpdateGroupLocaleParams) (storage.Group, error)
+	listGroupAncestorsFn        func(ctx context.Context, id uuid.UUID) ([]storage.Group, error)
+	listSubGroupsFn             func(ctx context.Context, parentID pgtype.UUID) ([]storage.Group, error)
+
 	// GroupMember methods
 	createGroupMemberFn            func(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error)
 	getGroupMemberByIDFn           func(ctx context.Context, id uuid.UUID) (storage.GroupMember, error)
@@ -56,6 +69,9 @@ type mockQuerier struct {
 	updateProviderFn      func(ctx context.Context, arg storage.UpdateProviderParams) (storage.EspProvider, error)
 	deleteProviderFn      func(ctx context.Context, id uuid.UUID) error
 
+	// ProviderHealthCheck methods
+	listProviderHealthChecksFn func(ctx context.Context, arg storage.ListProviderHealthChecksParams) ([]storage.ProviderHealthCheck, error)
+
 	// Routing Rule methods
 	createRoutingRuleFn      func(ctx context.Context, arg storage.CreateRoutingRuleParams) (storage.RoutingRule, error)
 	getRoutingRuleByIDFn     func(ctx context.Context, id uuid.UUID) (storage.RoutingRule, error)
@@ -63,19 +79,145 @@ type mockQuerier struct {
 	updateRoutingRuleFn      func(ctx context.Context, arg storage.UpdateRoutingRuleParams) (storage.RoutingRule, error)
 	deleteRoutingRuleFn      func(ctx context.Context, id uuid.UUID) error
 
+	// Message script methods
+	createMessageScriptFn         func(ctx context.Context, arg storage.CreateMessageScriptParams) (storage.MessageScript, error)
+	getMessageScriptFn            func(ctx context.Context, arg storage.GetMessageScriptParams) (storage.MessageScript, error)
+	listMessageScriptsByGroupIDFn func(ctx context.Context, groupID uuid.UUID) ([]storage.MessageScript, error)
+	updateMessageScriptFn         func(ctx context.Context, arg storage.UpdateMessageScriptParams) (storage.MessageScript, error)
+	deleteMessageScriptFn         func(ctx context.Context, arg storage.DeleteMessageScriptParams) (int64, error)
+
+	// Alert channel methods
+	createAlertChannelFn         func(ctx context.Context, arg storage.CreateAlertChannelParams) (storage.AlertChannel, error)
+	listAlertChannelsByGroupIDFn func(ctx context.Context, groupID uuid.UUID) ([]storage.AlertChannel, error)
+	deleteAlertChannelFn         func(ctx context.Context, arg storage.DeleteAlertChannelParams) (int64, error)
+
+	// Sender identity methods
+	createSenderIdentityFn         func(ctx context.Context, arg storage.CreateSenderIdentityParams) (storage.SenderIdentity, error)
+	getSenderIdentityFn            func(ctx context.Context, arg storage.GetSenderIdentityParams) (storage.SenderIdentity, error)
+	listSenderIdentitiesFn         func(ctx context.Context, groupID uuid.UUID) ([]storage.SenderIdentity, error)
+	listVerifiedSenderIdentitiesFn func(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	markSenderIdentityVerifiedFn   func(ctx context.Context, arg storage.MarkSenderIdentityVerifiedParams) (storage.SenderIdentity, error)
+	deleteSenderIdentityFn         func(ctx context.Context, arg storage.DeleteSenderIdentityParams) (int64, error)
+	getSenderPolicyFn              func(ctx context.Context, groupID uuid.UUID) (storage.SenderPolicy, error)
+	upsertSenderPolicyFn           func(ctx context.Context, arg storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error)
+	listSendingDomainsFn           func(ctx context.Context, groupID uuid.UUID) ([]storage.SendingDomain, error)
+	upsertSendingDomainFn          func(ctx context.Context, arg storage.UpsertSendingDomainParams) (storage.SendingDomain, error)
+	deleteSendingDomainFn          func(ctx context.Context, arg storage.DeleteSendingDomainParams) (int64, error)
+	getSigningKeyFn                func(ctx context.Context, groupID uuid.UUID) (storage.SigningKey, error)
+	upsertSigningKeyFn             func(ctx context.Context, arg storage.UpsertSigningKeyParams) (storage.SigningKey, error)
+	deleteSigningKeyFn             func(ctx context.Context, groupID uuid.UUID) (int64, error)
+	listRecipientCertsFn           func(ctx context.Context, groupID uuid.UUID) ([]storage.RecipientCertificate, error)
+	upsertRecipientCertFn          func(ctx context.Context, arg storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error)
+	deleteRecipientCertFn          func(ctx context.Context, arg storage.DeleteRecipientCertificateParams) (int64, error)
+	listProviderCapturesFn         func(ctx context.Context, providerID uuid.UUID) ([]storage.ProviderCapture, error)
+	deleteProviderCapturesFn       func(ctx context.Context, providerID uuid.UUID) (int64, error)
+	deliveryTimeSeriesFn           func(ctx context.Context, arg storage.DeliveryTimeSeriesParams) ([]storage.DeliveryTimeSeriesRow, error)
+	listGroupDeliveryLogArchivesFn func(ctx context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error)
+	revokeUserAPIKeyFn             func(ctx context.Context, id uuid.UUID) (int64, error)
+	pauseQueuedMessagesFn          func(ctx context.Context, arg storage.PauseQueuedMessagesParams) (int64, error)
+	resumePausedMessagesFn         func(ctx context.Context, arg storage.ResumePausedMessagesParams) (int64, error)
+	listMessagesSentSinceFn        func(ctx context.Context, arg storage.ListMessagesSentSinceParams) ([]storage.ListMessagesSentSinceRow, error)
+
 	// ActivityLog methods
 	createActivityLogFn          func(ctx context.Context, arg storage.CreateActivityLogParams) (storage.ActivityLog, error)
 	listActivityLogsByGroupIDFn  func(ctx context.Context, arg storage.ListActivityLogsByGroupIDParams) ([]storage.ActivityLog, error)
 
+	// Message methods
+	countGroupMessagesSinceFn func(ctx context.Context, arg storage.CountGroupMessagesSinceParams) (int64, error)
+	countGroupMessagesByTagFn func(ctx context.Context, arg storage.CountGroupMessagesByTagParams) ([]storage.CountGroupMessagesByTagRow, error)
+	listGroupMessagesFn       func(ctx context.Context, arg storage.ListGroupMessagesParams) ([]storage.Message, error)
+	getMessageByIDFn          func(ctx context.Context, id uuid.UUID) (storage.Message, error)
+	listDeliveryLogsByMessageIDFn func(ctx context.Context, messageID uuid.UUID) ([]storage.DeliveryLog, error)
+	listGroupDeliveryLogsFn   func(ctx context.Context, arg storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error)
+	listRecipientDeliveryLogsFn func(ctx context.Context, arg storage.ListRecipientDeliveryLogsParams) ([]storage.ListRecipientDeliveryLogsRow, error)
+
+	// Suppression methods
+	listSuppressionsFn  func(ctx context.Context, arg storage.ListSuppressionsParams) ([]storage.Suppression, error)
+	deleteSuppressionFn func(ctx context.Context, arg storage.DeleteSuppressionParams) (int64, error)
+	monthlyMessageUsageFn     func(ctx context.Context, arg storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error)
+	monthlyProviderUsageFn    func(ctx context.Context, arg storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error)
+
 	// DeliveryLog methods
 	getDeliveryLogByProviderMessageIDFn func(ctx context.Context, providerMessageID sql.NullString) (storage.DeliveryLog, error)
 	updateDeliveryLogStatusFn           func(ctx context.Context, arg storage.UpdateDeliveryLogStatusParams) error
 
+	// Aggregate query methods
+	dailyDeliveryVolumeFn func(ctx context.Context, arg storage.DailyDeliveryVolumeParams) ([]storage.DailyDeliveryVolumeRow, error)
+
 	// Session methods
 	createSessionFn      func(ctx context.Context, arg storage.CreateSessionParams) (storage.Session, error)
 	getSessionByIDFn     func(ctx context.Context, id uuid.UUID) (storage.Session, error)
 	deleteSessionFn      func(ctx context.Context, id uuid.UUID) error
 	listSessionsByUserIDFn func(ctx context.Context, userID uuid.UUID) ([]storage.Session, error)
+	deleteUserSessionFn    func(ctx context.Context, arg storage.DeleteUserSessionParams) (int64, error)
+	listLoginNetworksFn    func(ctx context.Context, userID uuid.UUID) ([]storage.LoginNetwork, error)
+	recordLoginNetworkFn   func(ctx context.Context, arg storage.RecordLoginNetworkParams) error
+	enqueueMessageFn       func(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error)
+
+	// Invitation methods
+	createInvitationFn       func(ctx context.Context, arg storage.CreateInvitationParams) (storage.Invitation, error)
+	getInvitationByIDFn      func(ctx context.Context, id uuid.UUID) (storage.Invitation, error)
+	listPendingInvitationsFn func(ctx context.Context, groupID uuid.UUID) ([]storage.Invitation, error)
+	acceptInvitationFn       func(ctx context.Context, id uuid.UUID) (int64, error)
+	deleteInvitationFn       func(ctx context.Context, arg storage.DeleteInvitationParams) (int64, error)
+
+	// Signup methods
+	createSignupFn       func(ctx context.Context, arg storage.CreateSignupParams) (storage.Signup, error)
+	getSignupByIDFn      func(ctx context.Context, id uuid.UUID) (storage.Signup, error)
+	deleteSignupFn       func(ctx context.Context, id uuid.UUID) (int64, error)
+	updateGroupSandboxFn func(ctx context.Context, arg storage.UpdateGroupSandboxParams) (storage.Group, error)
+	markEmailVerifiedFn  func(ctx context.Context, id uuid.UUID) error
+
+	// Branding methods
+	getGroupBrandingFn    func(ctx context.Context, groupID uuid.UUID) (storage.GroupBranding, error)
+	upsertGroupBrandingFn func(ctx context.Context, arg storage.UpsertGroupBrandingParams) (storage.GroupBranding, error)
+	deleteGroupBrandingFn func(ctx context.Context, groupID uuid.UUID) (int64, error)
+
+	// Resend methods
+	countResendableMessagesFn  func(ctx context.Context, arg storage.CountResendableMessagesParams) (int64, error)
+	listResendableMessagesFn   func(ctx context.Context, arg storage.ListResendableMessagesParams) ([]storage.ListResendableMessagesRow, error)
+	resendMessageFn            func(ctx context.Context, id uuid.UUID) (int64, error)
+	createDelayedOutboxEntryFn func(ctx context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error)
+
+	// Delivery report methods
+	createDeliveryReportFn     func(ctx context.Context, arg storage.CreateDeliveryReportParams) (storage.DeliveryReport, error)
+	getDeliveryReportFn        func(ctx context.Context, id uuid.UUID) (storage.DeliveryReport, error)
+	listGroupDeliveryReportsFn func(ctx context.Context, arg storage.ListGroupDeliveryReportsParams) ([]storage.DeliveryReport, error)
+
+	// Compliance methods
+	eraseGroupMessagesFn          func(ctx context.Context, groupID pgtype.UUID) (int64, error)
+	listGroupMessageStorageRefsFn func(ctx context.Context, groupID pgtype.UUID) ([]pgtype.Text, error)
+	scrubGroupDeliveryLogsFn      func(ctx context.Context, groupID pgtype.UUID) (int64, error)
+
+	// SMTP debug methods
+	createSMTPDebugTargetFn       func(ctx context.Context, arg storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error)
+	getSMTPDebugTargetFn          func(ctx context.Context, id uuid.UUID) (storage.SmtpDebugTarget, error)
+	listSMTPTranscriptsByTargetFn func(ctx context.Context, targetID uuid.UUID) ([]storage.SmtpTranscript, error)
+
+	// Inbound route methods
+	createInboundRouteFn         func(ctx context.Context, arg storage.CreateInboundRouteParams) (storage.InboundRoute, error)
+	getInboundRouteByIDFn        func(ctx context.Context, id uuid.UUID) (storage.InboundRoute, error)
+	listInboundRoutesByGroupIDFn func(ctx context.Context, groupID uuid.UUID) ([]storage.InboundRoute, error)
+	updateInboundRouteFn         func(ctx context.Context, arg storage.UpdateInboundRouteParams) (storage.InboundRoute, error)
+
+	// Shadow policy methods
+	listShadowPoliciesFn        func(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	deleteShadowPoliciesFn      func(ctx context.Context, groupID uuid.UUID) error
+	addShadowPolicyFn           func(ctx context.Context, arg storage.AddShadowPolicyParams) error
+	summarizeShadowRejectionsFn func(ctx context.Context, arg storage.SummarizeShadowRejectionsParams) ([]storage.SummarizeShadowRejectionsRow, error)
+
+	// Send window methods
+	getSendWindowFn    func(ctx context.Context, groupID uuid.UUID) (storage.SendWindow, error)
+	upsertSendWindowFn func(ctx context.Context, arg storage.UpsertSendWindowParams) (storage.SendWindow, error)
+	deleteSendWindowFn func(ctx context.Context, groupID uuid.UUID) (int64, error)
+
+	// Message defaults methods
+	getMessageDefaultsFn    func(ctx context.Context, groupID uuid.UUID) (storage.MessageDefault, error)
+	upsertMessageDefaultsFn func(ctx context.Context, arg storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error)
+	deleteMessageDefaultsFn func(ctx context.Context, groupID uuid.UUID) (int64, error)
+
+	// Provider daily send methods
+	listProviderDailySendsFn func(ctx context.Context, arg storage.ListProviderDailySendsByGroupIDParams) ([]storage.ProviderDailySend, error)
 }
 
 // --- User methods ---
@@ -129,7 +271,10 @@ func (m *mockQuerier) UpdateUser(ctx context.Context, arg storage.UpdateUserPara
 	return storage.User{}, nil
 }
 
-func (m *mockQuerier) UpdateUserPassword(_ context.Context, _ storage.UpdateUserPasswordParams) error {
+func (m *mockQuerier) UpdateUserPassword(ctx context.Context, arg storage.UpdateUserPasswordParams) error {
+	if m.updateUserPasswordFn != nil {
+		return m.updateUserPasswordFn(ctx, arg)
+	}
 	return nil
 }
 
@@ -140,6 +285,53 @@ func (m *mockQuerier) UpdateUserStatus(ctx context.Context, arg storage.UpdateUs
 	return storage.User{}, nil
 }
 
+func (m *mockQuerier) UpdateUserAutoBCC(ctx context.Context, arg storage.UpdateUserAutoBCCParams) (storage.User, error) {
+	if m.updateUserAutoBCCFn != nil {
+		return m.updateUserAutoBCCFn(ctx, arg)
+	}
+	return storage.User{}, nil
+}
+
+func (m *mockQuerier) UpdateUserTLSPolicy(ctx context.Context, arg storage.UpdateUserTLSPolicyParams) (storage.User, error) {
+	if m.updateUserTLSPolicyFn != nil {
+		return m.updateUserTLSPolicyFn(ctx, arg)
+	}
+	return storage.User{}, nil
+}
+
+func (m *mockQuerier) CreateSmtpClientCert(ctx context.Context, arg storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error) {
+	if m.createSmtpClientCertFn != nil {
+		return m.createSmtpClientCertFn(ctx, arg)
+	}
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) ListSmtpClientCertsByUserID(ctx context.Context, userID uuid.UUID) ([]storage.SmtpClientCert, error) {
+	if m.listSmtpClientCertsByUserIDFn != nil {
+		return m.listSmtpClientCertsByUserIDFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertByFingerprint(_ context.Context, _ pgtype.Text) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertBySAN(_ context.Context, _ []string) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) TouchSmtpClientCert(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSmtpClientCert(ctx context.Context, arg storage.DeleteSmtpClientCertParams) (int64, error) {
+	if m.deleteSmtpClientCertFn != nil {
+		return m.deleteSmtpClientCertFn(ctx, arg)
+	}
+	return 1, nil
+}
+
 func (m *mockQuerier) UpdateUserLastLogin(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
@@ -218,6 +410,38 @@ func (m *mockQuerier) ResetMonthlySent(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
 
+func (m *mockQuerier) UpdateGroupHTMLProcessing(ctx context.Context, arg storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+	if m.updateGroupHTMLProcessingFn != nil {
+		return m.updateGroupHTMLProcessingFn(ctx, arg)
+	}
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) ListGroupAncestors(ctx context.Context, id uuid.UUID) ([]storage.Group, error) {
+	if m.listGroupAncestorsFn != nil {
+		return m.listGroupAncestorsFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSubGroups(ctx context.Context, parentID pgtype.UUID) ([]storage.Group, error) {
+	if m.listSubGroupsFn != nil {
+		return m.listSubGroupsFn(ctx, parentID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMonthlyUsage(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListGroupMonthlyUsageRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateGroupRecipientValidation(ctx context.Context, arg storage.UpdateGroupRecipientValidationParams) (storage.Group, error) {
+	if m.updateGroupRecipientValidationFn != nil {
+		return m.updateGroupRecipientValidationFn(ctx, arg)
+	}
+	return storage.Group{}, nil
+}
+
 // --- GroupMember methods ---
 
 func (m *mockQuerier) CreateGroupMember(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error) {
@@ -273,6 +497,10 @@ func (m *mockQuerier) DeleteGroupMembersByUserID(_ context.Context, _ uuid.UUID)
 	return nil
 }
 
+func (m *mockQuerier) DeleteGroupProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
 func (m *mockQuerier) CountGroupOwners(ctx context.Context, groupID uuid.UUID) (int64, error) {
 	if m.countGroupOwnersFn != nil {
 		return m.countGroupOwnersFn(ctx, groupID)
@@ -280,6 +508,10 @@ func (m *mockQuerier) CountGroupOwners(ctx context.Context, groupID uuid.UUID) (
 	return 0, nil
 }
 
+func (m *mockQuerier) ListGroupOwnerEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
 // --- Provider methods ---
 
 func (m *mockQuerier) CreateProvider(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
@@ -317,6 +549,30 @@ func (m *mockQuerier) DeleteProvider(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (m *mockQuerier) AutoDisableProvider(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) AutoEnableProvider(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListProvidersForHealthCheck(_ context.Context) ([]storage.EspProvider, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateProviderHealth(_ context.Context, _ storage.UpdateProviderHealthParams) error {
+	return nil
+}
+
+func (m *mockQuerier) UpdateProviderReputationPause(_ context.Context, _ storage.UpdateProviderReputationPauseParams) error {
+	return nil
+}
+
+func (m *mockQuerier) ListEnabledProviders(_ context.Context) ([]storage.EspProvider, error) {
+	return nil, nil
+}
+
 // --- Routing Rule methods ---
 
 func (m *mockQuerier) CreateRoutingRule(ctx context.Context, arg storage.CreateRoutingRuleParams) (storage.RoutingRule, error) {
@@ -354,9 +610,220 @@ func (m *mockQuerier) DeleteRoutingRule(ctx context.Context, id uuid.UUID) error
 	return nil
 }
 
+// --- Message script methods ---
+
+func (m *mockQuerier) CreateMessageScript(ctx context.Context, arg storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+	if m.createMessageScriptFn != nil {
+		return m.createMessageScriptFn(ctx, arg)
+	}
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) GetMessageScript(ctx context.Context, arg storage.GetMessageScriptParams) (storage.MessageScript, error) {
+	if m.getMessageScriptFn != nil {
+		return m.getMessageScriptFn(ctx, arg)
+	}
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) ListMessageScriptsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.MessageScript, error) {
+	if m.listMessageScriptsByGroupIDFn != nil {
+		return m.listMessageScriptsByGroupIDFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListEnabledMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateMessageScript(ctx context.Context, arg storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+	if m.updateMessageScriptFn != nil {
+		return m.updateMessageScriptFn(ctx, arg)
+	}
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) DeleteMessageScript(ctx context.Context, arg storage.DeleteMessageScriptParams) (int64, error) {
+	if m.deleteMessageScriptFn != nil {
+		return m.deleteMessageScriptFn(ctx, arg)
+	}
+	return 1, nil
+}
+
+// --- Alert channel methods ---
+
+func (m *mockQuerier) CreateAlertChannel(ctx context.Context, arg storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+	if m.createAlertChannelFn != nil {
+		return m.createAlertChannelFn(ctx, arg)
+	}
+	return storage.AlertChannel{}, nil
+}
+
+func (m *mockQuerier) ListAlertChannelsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.AlertChannel, error) {
+	if m.listAlertChannelsByGroupIDFn != nil {
+		return m.listAlertChannelsByGroupIDFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListEnabledAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) DeleteAlertChannel(ctx context.Context, arg storage.DeleteAlertChannelParams) (int64, error) {
+	if m.deleteAlertChannelFn != nil {
+		return m.deleteAlertChannelFn(ctx, arg)
+	}
+	return 1, nil
+}
+
+// --- Sender identity methods ---
+
+func (m *mockQuerier) CreateSenderIdentity(ctx context.Context, arg storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+	if m.createSenderIdentityFn != nil {
+		return m.createSenderIdentityFn(ctx, arg)
+	}
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) GetSenderIdentity(ctx context.Context, arg storage.GetSenderIdentityParams) (storage.SenderIdentity, error) {
+	if m.getSenderIdentityFn != nil {
+		return m.getSenderIdentityFn(ctx, arg)
+	}
+	return storage.SenderIdentity{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) ListSenderIdentitiesByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.SenderIdentity, error) {
+	if m.listSenderIdentitiesFn != nil {
+		return m.listSenderIdentitiesFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListVerifiedSenderIdentities(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	if m.listVerifiedSenderIdentitiesFn != nil {
+		return m.listVerifiedSenderIdentitiesFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkSenderIdentityVerified(ctx context.Context, arg storage.MarkSenderIdentityVerifiedParams) (storage.SenderIdentity, error) {
+	if m.markSenderIdentityVerifiedFn != nil {
+		return m.markSenderIdentityVerifiedFn(ctx, arg)
+	}
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) DeleteSenderIdentity(ctx context.Context, arg storage.DeleteSenderIdentityParams) (int64, error) {
+	if m.deleteSenderIdentityFn != nil {
+		return m.deleteSenderIdentityFn(ctx, arg)
+	}
+	return 1, nil
+}
+
+func (m *mockQuerier) GetSenderPolicy(ctx context.Context, groupID uuid.UUID) (storage.SenderPolicy, error) {
+	if m.getSenderPolicyFn != nil {
+		return m.getSenderPolicyFn(ctx, groupID)
+	}
+	return storage.SenderPolicy{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertSenderPolicy(ctx context.Context, arg storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error) {
+	if m.upsertSenderPolicyFn != nil {
+		return m.upsertSenderPolicyFn(ctx, arg)
+	}
+	return storage.SenderPolicy{GroupID: arg.GroupID, Mode: arg.Mode, RewriteAddress: arg.RewriteAddress}, nil
+}
+
+func (m *mockQuerier) GetSendingDomain(_ context.Context, _ storage.GetSendingDomainParams) (storage.SendingDomain, error) {
+	return storage.SendingDomain{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) ListSendingDomainsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.SendingDomain, error) {
+	if m.listSendingDomainsFn != nil {
+		return m.listSendingDomainsFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertSendingDomain(ctx context.Context, arg storage.UpsertSendingDomainParams) (storage.SendingDomain, error) {
+	if m.upsertSendingDomainFn != nil {
+		return m.upsertSendingDomainFn(ctx, arg)
+	}
+	return storage.SendingDomain{GroupID: arg.GroupID, Domain: arg.Domain, ReplyTo: arg.ReplyTo, ReturnPath: arg.ReturnPath}, nil
+}
+
+func (m *mockQuerier) DeleteSendingDomain(ctx context.Context, arg storage.DeleteSendingDomainParams) (int64, error) {
+	if m.deleteSendingDomainFn != nil {
+		return m.deleteSendingDomainFn(ctx, arg)
+	}
+	return 1, nil
+}
+
+func (m *mockQuerier) GetSigningKey(ctx context.Context, groupID uuid.UUID) (storage.SigningKey, error) {
+	if m.getSigningKeyFn != nil {
+		return m.getSigningKeyFn(ctx, groupID)
+	}
+	return storage.SigningKey{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertSigningKey(ctx context.Context, arg storage.UpsertSigningKeyParams) (storage.SigningKey, error) {
+	if m.upsertSigningKeyFn != nil {
+		return m.upsertSigningKeyFn(ctx, arg)
+	}
+	return storage.SigningKey{
+		GroupID:     arg.GroupID,
+		Kind:        arg.Kind,
+		Certificate: arg.Certificate,
+		PrivateKey:  arg.PrivateKey,
+		Fingerprint: arg.Fingerprint,
+		ExpiresAt:   arg.ExpiresAt,
+		Encrypt:     arg.Encrypt,
+		OnFailure:   arg.OnFailure,
+	}, nil
+}
+
+func (m *mockQuerier) DeleteSigningKey(ctx context.Context, groupID uuid.UUID) (int64, error) {
+	if m.deleteSigningKeyFn != nil {
+		return m.deleteSigningKeyFn(ctx, groupID)
+	}
+	return 1, nil
+}
+
+func (m *mockQuerier) ListRecipientCertificatesByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.RecipientCertificate, error) {
+	if m.listRecipientCertsFn != nil {
+		return m.listRecipientCertsFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertRecipientCertificate(ctx context.Context, arg storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error) {
+	if m.upsertRecipientCertFn != nil {
+		return m.upsertRecipientCertFn(ctx, arg)
+	}
+	return storage.RecipientCertificate{
+		GroupID:     arg.GroupID,
+		Email:       arg.Email,
+		Certificate: arg.Certificate,
+		Fingerprint: arg.Fingerprint,
+		ExpiresAt:   arg.ExpiresAt,
+	}, nil
+}
+
+func (m *mockQuerier) DeleteRecipientCertificate(ctx context.Context, arg storage.DeleteRecipientCertificateParams) (int64, error) {
+	if m.deleteRecipientCertFn != nil {
+		return m.deleteRecipientCertFn(ctx, arg)
+	}
+	return 1, nil
+}
+
 // --- Message methods ---
 
-func (m *mockQuerier) EnqueueMessage(_ context.Context, _ storage.EnqueueMessageParams) (storage.Message, error) {
+func (m *mockQuerier) EnqueueMessage(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+	if m.enqueueMessageFn != nil {
+		return m.enqueueMessageFn(ctx, arg)
+	}
 	return storage.Message{}, nil
 }
 
@@ -364,7 +831,10 @@ func (m *mockQuerier) EnqueueMessageMetadata(_ context.Context, _ storage.Enqueu
 	return storage.Message{}, nil
 }
 
-func (m *mockQuerier) GetMessageByID(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+func (m *mockQuerier) GetMessageByID(ctx context.Context, id uuid.UUID) (storage.Message, error) {
+	if m.getMessageByIDFn != nil {
+		return m.getMessageByIDFn(ctx, id)
+	}
 	return storage.Message{}, nil
 }
 
@@ -380,6 +850,93 @@ func (m *mockQuerier) UpdateMessageStatus(_ context.Context, _ storage.UpdateMes
 	return nil
 }
 
+func (m *mockQuerier) ListStuckMessages(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RequeueMessage(_ context.Context, _ storage.RequeueMessageParams) (int64, error) {
+	return 1, nil
+}
+
+func (m *mockQuerier) ExpireStuckMessage(_ context.Context, _ storage.ExpireStuckMessageParams) (int64, error) {
+	return 1, nil
+}
+
+func (m *mockQuerier) MonthlyMessageUsage(ctx context.Context, arg storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error) {
+	if m.monthlyMessageUsageFn != nil {
+		return m.monthlyMessageUsageFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) MonthlyProviderUsage(ctx context.Context, arg storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error) {
+	if m.monthlyProviderUsageFn != nil {
+		return m.monthlyProviderUsageFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) CountGroupMessagesSince(ctx context.Context, arg storage.CountGroupMessagesSinceParams) (int64, error) {
+	if m.countGroupMessagesSinceFn != nil {
+		return m.countGroupMessagesSinceFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) CountGroupMessagesByTag(ctx context.Context, arg storage.CountGroupMessagesByTagParams) ([]storage.CountGroupMessagesByTagRow, error) {
+	if m.countGroupMessagesByTagFn != nil {
+		return m.countGroupMessagesByTagFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryLogs(ctx context.Context, arg storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+	if m.listGroupDeliveryLogsFn != nil {
+		return m.listGroupDeliveryLogsFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListRecipientDeliveryLogs(ctx context.Context, arg storage.ListRecipientDeliveryLogsParams) ([]storage.ListRecipientDeliveryLogsRow, error) {
+	if m.listRecipientDeliveryLogsFn != nil {
+		return m.listRecipientDeliveryLogsFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) AddSuppression(ctx context.Context, arg storage.AddSuppressionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSuppression(ctx context.Context, arg storage.DeleteSuppressionParams) (int64, error) {
+	if m.deleteSuppressionFn != nil {
+		return m.deleteSuppressionFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) FindMessageByHeaderMessageID(ctx context.Context, arg storage.FindMessageByHeaderMessageIDParams) (storage.Message, error) {
+	return storage.Message{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) IsRecipientSuppressed(ctx context.Context, arg storage.IsRecipientSuppressedParams) (bool, error) {
+	return false, nil
+}
+
+func (m *mockQuerier) ListSuppressions(ctx context.Context, arg storage.ListSuppressionsParams) ([]storage.Suppression, error) {
+	if m.listSuppressionsFn != nil {
+		return m.listSuppressionsFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMessages(ctx context.Context, arg storage.ListGroupMessagesParams) ([]storage.Message, error) {
+	if m.listGroupMessagesFn != nil {
+		return m.listGroupMessagesFn(ctx, arg)
+	}
+	return nil, nil
+}
+
 // --- DeliveryLog methods ---
 
 func (m *mockQuerier) CreateDeliveryLog(_ context.Context, _ storage.CreateDeliveryLogParams) (storage.DeliveryLog, error) {
@@ -401,7 +958,10 @@ func (m *mockQuerier) IncrementRetryCount(_ context.Context, _ storage.Increment
 	return nil
 }
 
-func (m *mockQuerier) ListDeliveryLogsByMessageID(_ context.Context, _ uuid.UUID) ([]storage.DeliveryLog, error) {
+func (m *mockQuerier) ListDeliveryLogsByMessageID(ctx context.Context, messageID uuid.UUID) ([]storage.DeliveryLog, error) {
+	if m.listDeliveryLogsByMessageIDFn != nil {
+		return m.listDeliveryLogsByMessageIDFn(ctx, messageID)
+	}
 	return nil, nil
 }
 
@@ -434,6 +994,17 @@ func (m *mockQuerier) CountDeliveryLogsByStatus(_ context.Context, _ storage.Cou
 	return nil, nil
 }
 
+func (m *mockQuerier) DailyDeliveryVolume(ctx context.Context, arg storage.DailyDeliveryVolumeParams) ([]storage.DailyDeliveryVolumeRow, error) {
+	if m.dailyDeliveryVolumeFn != nil {
+		return m.dailyDeliveryVolumeFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) DeliveryLatencyPercentiles(_ context.Context, _ pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+	return nil, nil
+}
+
 // --- ActivityLog methods ---
 
 func (m *mockQuerier) CreateActivityLog(ctx context.Context, arg storage.CreateActivityLogParams) (storage.ActivityLog, error) {
@@ -500,42 +1071,304 @@ func (m *mockQuerier) ListSessionsByUserID(ctx context.Context, userID uuid.UUID
 	return nil, nil
 }
 
-// --- Context helpers ---
+// --- Outbox methods ---
 
-// setAuthContext injects the account ID into context the same way the BearerAuth
-// middleware does, so that auth.AccountFromContext can retrieve it.
-func setAuthContext(ctx context.Context, accountID uuid.UUID) context.Context {
-	lookup := func(_ context.Context, _ string) (uuid.UUID, error) {
-		return accountID, nil
-	}
+func (m *mockQuerier) ClaimOutboxEntries(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+	return nil, nil
+}
 
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
-	req.Header.Set("Authorization", "Bearer fakekey")
-	req = req.WithContext(ctx)
+func (m *mockQuerier) CountOutboxEntries(_ context.Context) (int64, error) {
+	return 0, nil
+}
 
-	var resultCtx context.Context
-	captured := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		resultCtx = r.Context()
-	})
-	auth.BearerAuth(lookup)(captured).ServeHTTP(httptest.NewRecorder(), req)
+func (m *mockQuerier) CreateOutboxEntry(_ context.Context, _ storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+	return storage.OutboxEntry{}, nil
+}
 
-	return resultCtx
+func (m *mockQuerier) DeleteOutboxEntry(_ context.Context, _ uuid.UUID) error {
+	return nil
 }
 
-// setJWTContext injects JWT-style user/group context values the same way the
-// JWTAuth middleware does, so that auth.GroupIDFromContext, auth.UserFromContext,
-// auth.RoleFromContext, and auth.GroupTypeFromContext can retrieve them.
-func setJWTContext(ctx context.Context, userID, groupID uuid.UUID, role, groupType string) context.Context {
-	// We pass a fake request through the real JWTAuth middleware.
-	// To avoid needing a real JWT, we create a JWTService with a known secret,
-	// generate a real token, and let the middleware parse it.
-	jwtSvc := auth.NewJWTService(auth.JWTConfig{
-		SigningKey:         "test-secret-key-that-is-long-enough-32",
-		AccessTokenExpiry:  15 * time.Minute,
-		RefreshTokenExpiry: 7 * 24 * time.Hour,
-	})
+func (m *mockQuerier) RecordOutboxEntryFailure(_ context.Context, _ storage.RecordOutboxEntryFailureParams) error {
+	return nil
+}
+
+// --- ProviderHealthCheck methods ---
+
+func (m *mockQuerier) CreateProviderHealthCheck(_ context.Context, _ storage.CreateProviderHealthCheckParams) (storage.ProviderHealthCheck, error) {
+	return storage.ProviderHealthCheck{}, nil
+}
+
+func (m *mockQuerier) DeleteProviderHealthChecksBefore(_ context.Context, _ pgtype.Timestamptz) error {
+	return nil
+}
+
+func (m *mockQuerier) ListProviderHealthChecks(ctx context.Context, arg storage.ListProviderHealthChecksParams) ([]storage.ProviderHealthCheck, error) {
+	if m.listProviderHealthChecksFn != nil {
+		return m.listProviderHealthChecksFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+// --- ProviderAccountStats methods ---
+
+func (m *mockQuerier) ListProviderAccountStatsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.ProviderAccountStat, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RecordProviderAccountStatsError(_ context.Context, _ storage.RecordProviderAccountStatsErrorParams) error {
+	return nil
+}
+
+func (m *mockQuerier) UpsertProviderAccountStats(_ context.Context, _ storage.UpsertProviderAccountStatsParams) (storage.ProviderAccountStat, error) {
+	return storage.ProviderAccountStat{}, nil
+}
+
+// --- QuotaNotification methods ---
+
+func (m *mockQuerier) RecordQuotaNotification(_ context.Context, _ storage.RecordQuotaNotificationParams) (int64, error) {
+	return 0, nil
+}
+
+// --- Send window methods ---
+
+func (m *mockQuerier) GetSendWindow(ctx context.Context, groupID uuid.UUID) (storage.SendWindow, error) {
+	if m.getSendWindowFn != nil {
+		return m.getSendWindowFn(ctx, groupID)
+	}
+	return storage.SendWindow{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertSendWindow(ctx context.Context, arg storage.UpsertSendWindowParams) (storage.SendWindow, error) {
+	if m.upsertSendWindowFn != nil {
+		return m.upsertSendWindowFn(ctx, arg)
+	}
+	return storage.SendWindow{GroupID: arg.GroupID, Timezone: arg.Timezone, Windows: arg.Windows}, nil
+}
+
+func (m *mockQuerier) DeleteSendWindow(ctx context.Context, groupID uuid.UUID) (int64, error) {
+	if m.deleteSendWindowFn != nil {
+		return m.deleteSendWindowFn(ctx, groupID)
+	}
+	return 0, nil
+}
+
+// --- Message defaults methods ---
+
+func (m *mockQuerier) GetMessageDefaults(ctx context.Context, groupID uuid.UUID) (storage.MessageDefault, error) {
+	if m.getMessageDefaultsFn != nil {
+		return m.getMessageDefaultsFn(ctx, groupID)
+	}
+	return storage.MessageDefault{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertMessageDefaults(ctx context.Context, arg storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error) {
+	if m.upsertMessageDefaultsFn != nil {
+		return m.upsertMessageDefaultsFn(ctx, arg)
+	}
+	return storage.MessageDefault{
+		GroupID:          arg.GroupID,
+		Headers:          arg.Headers,
+		FooterText:       arg.FooterText,
+		FooterHtml:       arg.FooterHtml,
+		ExcludeTags:      arg.ExcludeTags,
+		ExcludeTemplates: arg.ExcludeTemplates,
+	}, nil
+}
+
+func (m *mockQuerier) DeleteMessageDefaults(ctx context.Context, groupID uuid.UUID) (int64, error) {
+	if m.deleteMessageDefaultsFn != nil {
+		return m.deleteMessageDefaultsFn(ctx, groupID)
+	}
+	return 0, nil
+}
+
+// --- Shadow policy methods ---
+
+func (m *mockQuerier) ListShadowPolicies(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	if m.listShadowPoliciesFn != nil {
+		return m.listShadowPoliciesFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) DeleteShadowPolicies(ctx context.Context, groupID uuid.UUID) error {
+	if m.deleteShadowPoliciesFn != nil {
+		return m.deleteShadowPoliciesFn(ctx, groupID)
+	}
+	return nil
+}
+
+func (m *mockQuerier) AddShadowPolicy(ctx context.Context, arg storage.AddShadowPolicyParams) error {
+	if m.addShadowPolicyFn != nil {
+		return m.addShadowPolicyFn(ctx, arg)
+	}
+	return nil
+}
+
+func (m *mockQuerier) RecordShadowRejection(_ context.Context, _ storage.RecordShadowRejectionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) SummarizeShadowRejections(ctx context.Context, arg storage.SummarizeShadowRejectionsParams) ([]storage.SummarizeShadowRejectionsRow, error) {
+	if m.summarizeShadowRejectionsFn != nil {
+		return m.summarizeShadowRejectionsFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+// --- Compliance methods ---
+
+func (m *mockQuerier) EraseGroupMessages(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+	if m.eraseGroupMessagesFn != nil {
+		return m.eraseGroupMessagesFn(ctx, groupID)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) ExportGroupActivityLogs(_ context.Context, _ uuid.UUID) ([]storage.ActivityLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ExportGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ExportGroupMessages(_ context.Context, _ pgtype.UUID) ([]storage.ExportGroupMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMessageStorageRefs(ctx context.Context, groupID pgtype.UUID) ([]pgtype.Text, error) {
+	if m.listGroupMessageStorageRefsFn != nil {
+		return m.listGroupMessageStorageRefsFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ScrubGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+	if m.scrubGroupDeliveryLogsFn != nil {
+		return m.scrubGroupDeliveryLogsFn(ctx, groupID)
+	}
+	return 0, nil
+}
 
-	token, err := jwtSvc.GenerateAccessToken(userID, groupID, "test@example.com", role, groupType)
+// --- SMTP debug methods ---
+
+func (m *mockQuerier) CreateSMTPDebugTarget(ctx context.Context, arg storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error) {
+	if m.createSMTPDebugTargetFn != nil {
+		return m.createSMTPDebugTargetFn(ctx, arg)
+	}
+	return storage.SmtpDebugTarget{}, nil
+}
+
+func (m *mockQuerier) CreateSMTPTranscript(_ context.Context, _ storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+	return storage.SmtpTranscript{}, nil
+}
+
+func (m *mockQuerier) DeleteSMTPDebugTarget(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) GetSMTPDebugTarget(ctx context.Context, id uuid.UUID) (storage.SmtpDebugTarget, error) {
+	if m.getSMTPDebugTargetFn != nil {
+		return m.getSMTPDebugTargetFn(ctx, id)
+	}
+	return storage.SmtpDebugTarget{}, errNotFound
+}
+
+func (m *mockQuerier) ListActiveSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSMTPDebugTargetsByGroupID(_ context.Context, _ pgtype.UUID) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSMTPTranscriptsByTarget(ctx context.Context, targetID uuid.UUID) ([]storage.SmtpTranscript, error) {
+	if m.listSMTPTranscriptsByTargetFn != nil {
+		return m.listSMTPTranscriptsByTargetFn(ctx, targetID)
+	}
+	return nil, nil
+}
+
+// --- Inbound routes methods ---
+
+func (m *mockQuerier) CreateInboundRoute(ctx context.Context, arg storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+	if m.createInboundRouteFn != nil {
+		return m.createInboundRouteFn(ctx, arg)
+	}
+	return storage.InboundRoute{}, nil
+}
+
+func (m *mockQuerier) DeleteInboundRoute(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) GetInboundRouteByDomain(_ context.Context, _ string) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+
+func (m *mockQuerier) GetInboundRouteByID(ctx context.Context, id uuid.UUID) (storage.InboundRoute, error) {
+	if m.getInboundRouteByIDFn != nil {
+		return m.getInboundRouteByIDFn(ctx, id)
+	}
+	return storage.InboundRoute{}, errNotFound
+}
+
+func (m *mockQuerier) ListInboundRoutesByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.InboundRoute, error) {
+	if m.listInboundRoutesByGroupIDFn != nil {
+		return m.listInboundRoutesByGroupIDFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateInboundRoute(ctx context.Context, arg storage.UpdateInboundRouteParams) (storage.InboundRoute, error) {
+	if m.updateInboundRouteFn != nil {
+		return m.updateInboundRouteFn(ctx, arg)
+	}
+	return storage.InboundRoute{}, nil
+}
+
+// --- Context helpers ---
+
+// setAuthContext injects the account ID into context the same way the BearerAuth
+// middleware does, so that auth.AccountFromContext can retrieve it.
+func setAuthContext(ctx context.Context, accountID uuid.UUID) context.Context {
+	lookup := func(_ context.Context, _ string) (uuid.UUID, error) {
+		return accountID, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer fakekey")
+	req = req.WithContext(ctx)
+
+	var resultCtx context.Context
+	captured := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resultCtx = r.Context()
+	})
+	auth.BearerAuth(lookup)(captured).ServeHTTP(httptest.NewRecorder(), req)
+
+	return resultCtx
+}
+
+// setJWTContext injects JWT-style user/group context values the same way the
+// JWTAuth middleware does, so that auth.GroupIDFromContext, auth.UserFromContext,
+// auth.RoleFromContext, and auth.GroupTypeFromContext can retrieve them.
+func setJWTContext(ctx context.Context, userID, groupID uuid.UUID, role, groupType string) context.Context {
+	// We pass a fake request through the real JWTAuth middleware.
+	// To avoid needing a real JWT, we create a JWTService with a known secret,
+	// generate a real token, and let the middleware parse it.
+	jwtSvc := auth.NewJWTService(auth.JWTConfig{
+		SigningKey:         "test-secret-key-that-is-long-enough-32",
+		AccessTokenExpiry:  15 * time.Minute,
+		RefreshTokenExpiry: 7 * 24 * time.Hour,
+	})
+
+	token, err := jwtSvc.GenerateAccessToken(userID, groupID, "test@example.com", role, groupType, nil)
 	if err != nil {
 		panic("setJWTContext: failed to generate token: " + err.Error())
 	}
@@ -629,3 +1462,324 @@ func testRoutingRule() storage.RoutingRule {
 
 // Compile-time verification that mockQuerier implements storage.Querier.
 var _ storage.Querier = (*mockQuerier)(nil)
+
+func (m *mockQuerier) CreateProviderCapture(_ context.Context, _ storage.CreateProviderCaptureParams) (storage.ProviderCapture, error) {
+	return storage.ProviderCapture{}, nil
+}
+
+func (m *mockQuerier) ListProviderCaptures(ctx context.Context, providerID uuid.UUID) ([]storage.ProviderCapture, error) {
+	if m.listProviderCapturesFn != nil {
+		return m.listProviderCapturesFn(ctx, providerID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) PruneProviderCaptures(_ context.Context, _ storage.PruneProviderCapturesParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteProviderCaptures(ctx context.Context, providerID uuid.UUID) (int64, error) {
+	if m.deleteProviderCapturesFn != nil {
+		return m.deleteProviderCapturesFn(ctx, providerID)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDeliveryLogArchive(_ context.Context, _ storage.CreateDeliveryLogArchiveParams) (storage.DeliveryLogArchive, error) {
+	return storage.DeliveryLogArchive{}, nil
+}
+
+func (m *mockQuerier) DeleteArchivedDeliveryLogs(_ context.Context, _ []uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryLogArchive(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsToArchive(_ context.Context, _ storage.ListDeliveryLogsToArchiveParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryLogArchives(ctx context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+	if m.listGroupDeliveryLogArchivesFn != nil {
+		return m.listGroupDeliveryLogArchivesFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) GetAnalyticsExportCursor(_ context.Context, _ string) (storage.AnalyticsExportCursor, error) {
+	return storage.AnalyticsExportCursor{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsForExport(_ context.Context, _ storage.ListDeliveryLogsForExportParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertAnalyticsExportCursor(_ context.Context, _ storage.UpsertAnalyticsExportCursorParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeliveryTimeSeries(ctx context.Context, arg storage.DeliveryTimeSeriesParams) ([]storage.DeliveryTimeSeriesRow, error) {
+	if m.deliveryTimeSeriesFn != nil {
+		return m.deliveryTimeSeriesFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ListMessagesSentSince(ctx context.Context, arg storage.ListMessagesSentSinceParams) ([]storage.ListMessagesSentSinceRow, error) {
+	if m.listMessagesSentSinceFn != nil {
+		return m.listMessagesSentSinceFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) PauseQueuedMessages(ctx context.Context, arg storage.PauseQueuedMessagesParams) (int64, error) {
+	if m.pauseQueuedMessagesFn != nil {
+		return m.pauseQueuedMessagesFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) ResumePausedMessages(ctx context.Context, arg storage.ResumePausedMessagesParams) (int64, error) {
+	if m.resumePausedMessagesFn != nil {
+		return m.resumePausedMessagesFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) RevokeUserAPIKey(ctx context.Context, id uuid.UUID) (int64, error) {
+	if m.revokeUserAPIKeyFn != nil {
+		return m.revokeUserAPIKeyFn(ctx, id)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteUserSession(ctx context.Context, arg storage.DeleteUserSessionParams) (int64, error) {
+	if m.deleteUserSessionFn != nil {
+		return m.deleteUserSessionFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) TouchSession(_ context.Context, _ storage.TouchSessionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) ListExpiringSMTPPasswords(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListExpiringSMTPPasswordsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkPasswordExpiryWarned(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListLoginNetworks(ctx context.Context, userID uuid.UUID) ([]storage.LoginNetwork, error) {
+	if m.listLoginNetworksFn != nil {
+		return m.listLoginNetworksFn(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) RecordLoginNetwork(ctx context.Context, arg storage.RecordLoginNetworkParams) error {
+	if m.recordLoginNetworkFn != nil {
+		return m.recordLoginNetworkFn(ctx, arg)
+	}
+	return nil
+}
+
+func (m *mockQuerier) AcceptInvitation(ctx context.Context, id uuid.UUID) (int64, error) {
+	if m.acceptInvitationFn != nil {
+		return m.acceptInvitationFn(ctx, id)
+	}
+	return 1, nil
+}
+
+func (m *mockQuerier) CreateInvitation(ctx context.Context, arg storage.CreateInvitationParams) (storage.Invitation, error) {
+	if m.createInvitationFn != nil {
+		return m.createInvitationFn(ctx, arg)
+	}
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) DeleteInvitation(ctx context.Context, arg storage.DeleteInvitationParams) (int64, error) {
+	if m.deleteInvitationFn != nil {
+		return m.deleteInvitationFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) GetInvitationByID(ctx context.Context, id uuid.UUID) (storage.Invitation, error) {
+	if m.getInvitationByIDFn != nil {
+		return m.getInvitationByIDFn(ctx, id)
+	}
+	return storage.Invitation{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) ListPendingInvitationsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.Invitation, error) {
+	if m.listPendingInvitationsFn != nil {
+		return m.listPendingInvitationsFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) CreateSignup(ctx context.Context, arg storage.CreateSignupParams) (storage.Signup, error) {
+	if m.createSignupFn != nil {
+		return m.createSignupFn(ctx, arg)
+	}
+	return storage.Signup{ID: uuid.New(), Email: arg.Email, GroupName: arg.GroupName, ExpiresAt: arg.ExpiresAt}, nil
+}
+
+func (m *mockQuerier) DeleteExpiredSignups(_ context.Context) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSignup(ctx context.Context, id uuid.UUID) (int64, error) {
+	if m.deleteSignupFn != nil {
+		return m.deleteSignupFn(ctx, id)
+	}
+	return 1, nil
+}
+
+func (m *mockQuerier) GetSignupByID(ctx context.Context, id uuid.UUID) (storage.Signup, error) {
+	if m.getSignupByIDFn != nil {
+		return m.getSignupByIDFn(ctx, id)
+	}
+	return storage.Signup{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) ListVerifiedGroupMemberEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateGroupSandbox(ctx context.Context, arg storage.UpdateGroupSandboxParams) (storage.Group, error) {
+	if m.updateGroupSandboxFn != nil {
+		return m.updateGroupSandboxFn(ctx, arg)
+	}
+	return storage.Group{ID: arg.ID, Sandbox: arg.Sandbox}, nil
+}
+
+func (m *mockQuerier) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	if m.markEmailVerifiedFn != nil {
+		return m.markEmailVerifiedFn(ctx, id)
+	}
+	return nil
+}
+
+func (m *mockQuerier) DeleteGroupBranding(ctx context.Context, groupID uuid.UUID) (int64, error) {
+	if m.deleteGroupBrandingFn != nil {
+		return m.deleteGroupBrandingFn(ctx, groupID)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) GetGroupBranding(ctx context.Context, groupID uuid.UUID) (storage.GroupBranding, error) {
+	if m.getGroupBrandingFn != nil {
+		return m.getGroupBrandingFn(ctx, groupID)
+	}
+	return storage.GroupBranding{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertGroupBranding(ctx context.Context, arg storage.UpsertGroupBrandingParams) (storage.GroupBranding, error) {
+	if m.upsertGroupBrandingFn != nil {
+		return m.upsertGroupBrandingFn(ctx, arg)
+	}
+	return storage.GroupBranding{}, nil
+}
+
+func (m *mockQuerier) UpdateGroupLocale(ctx context.Context, arg storage.UpdateGroupLocaleParams) (storage.Group, error) {
+	if m.updateGroupLocaleFn != nil {
+		return m.updateGroupLocaleFn(ctx, arg)
+	}
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) CountResendableMessages(ctx context.Context, arg storage.CountResendableMessagesParams) (int64, error) {
+	if m.countResendableMessagesFn != nil {
+		return m.countResendableMessagesFn(ctx, arg)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDelayedOutboxEntry(ctx context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+	if m.createDelayedOutboxEntryFn != nil {
+		return m.createDelayedOutboxEntryFn(ctx, arg)
+	}
+	return storage.OutboxEntry{}, nil
+}
+
+func (m *mockQuerier) ListResendableMessages(ctx context.Context, arg storage.ListResendableMessagesParams) ([]storage.ListResendableMessagesRow, error) {
+	if m.listResendableMessagesFn != nil {
+		return m.listResendableMessagesFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ResendMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	if m.resendMessageFn != nil {
+		return m.resendMessageFn(ctx, id)
+	}
+	return 0, nil
+}
+
+func (m *mockQuerier) ClaimDeliveryReport(_ context.Context, _ int32) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) CompleteDeliveryReport(_ context.Context, _ storage.CompleteDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) CreateDeliveryReport(ctx context.Context, arg storage.CreateDeliveryReportParams) (storage.DeliveryReport, error) {
+	if m.createDeliveryReportFn != nil {
+		return m.createDeliveryReportFn(ctx, arg)
+	}
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryReport(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) FailDeliveryReport(_ context.Context, _ storage.FailDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) GetDeliveryReport(ctx context.Context, id uuid.UUID) (storage.DeliveryReport, error) {
+	if m.getDeliveryReportFn != nil {
+		return m.getDeliveryReportFn(ctx, id)
+	}
+	return storage.DeliveryReport{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) ListDeliveryLogsByMessageIDs(_ context.Context, _ []uuid.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListDeliveryReportMessages(_ context.Context, _ storage.ListDeliveryReportMessagesParams) ([]storage.ListDeliveryReportMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListExpiredDeliveryReports(_ context.Context, _ storage.ListExpiredDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryReports(ctx context.Context, arg storage.ListGroupDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	if m.listGroupDeliveryReportsFn != nil {
+		return m.listGroupDeliveryReportsFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+// --- Provider daily send methods ---
+
+func (m *mockQuerier) ListProviderDailySendsByGroupID(ctx context.Context, arg storage.ListProviderDailySendsByGroupIDParams) ([]storage.ProviderDailySend, error) {
+	if m.listProviderDailySendsFn != nil {
+		return m.listProviderDailySendsFn(ctx, arg)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) ReserveProviderDailySend(_ context.Context, arg storage.ReserveProviderDailySendParams) (int32, error) {
+	return 1, nil
+}
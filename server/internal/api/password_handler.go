@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// setPasswordRequest is the JSON body for PUT /api/v1/users/{id}/password.
+type setPasswordRequest struct {
+	Password string `json:"password"`
+	// CurrentPassword is required when users change their own password.
+	CurrentPassword string `json:"current_password,omitempty"`
+}
+
+// checkPassword applies the password policy and writes a validation error
+// response listing the violated rules. It returns false when the password
+// was rejected.
+func checkPassword(w http.ResponseWriter, r *http.Request, passwords *auth.PasswordPolicy, password string) bool {
+	err := passwords.Check(r.Context(), password)
+	if err == nil {
+		return true
+	}
+	var perr *auth.PasswordPolicyError
+	if errors.As(err, &perr) {
+		errs := make([]string, len(perr.Violations))
+		for i, v := range perr.Violations {
+			errs[i] = "password " + v
+		}
+		respondValidationErrors(w, errs)
+		return false
+	}
+	respondError(w, http.StatusInternalServerError, "internal server error")
+	return false
+}
+
+// SetPasswordHandler handles PUT /api/v1/users/{id}/password.
+// Sets a new password that satisfies the password policy. Users changing
+// their own password must send their current one; other users' passwords
+// require group admin+ role for the user's group. Setting a password
+// restarts the SMTP credential expiry clock.
+func SetPasswordHandler(queries storage.Querier, passwords *auth.PasswordPolicy, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		self := id == auth.UserFromContext(r.Context())
+		if !self && (!isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id)) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req setPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Password == "" {
+			respondValidationErrors(w, []string{"password is required"})
+			return
+		}
+
+		user, err := queries.GetUserByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+		if self && auth.VerifyPassword(user.PasswordHash, req.CurrentPassword) != nil {
+			respondError(w, http.StatusForbidden, "current password is incorrect")
+			return
+		}
+		if !checkPassword(w, r, passwords, req.Password) {
+			return
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if err := queries.UpdateUserPassword(r.Context(), storage.UpdateUserPasswordParams{
+			ID:           id,
+			PasswordHash: hash,
+		}); err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionSetPassword, "user", id.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
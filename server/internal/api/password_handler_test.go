@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func setPasswordHTTPRequest(userID uuid.UUID, body string, callerID uuid.UUID, role string) *http.Request {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+userID.String()+"/password", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", userID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, callerID, testGroup().ID, role, "organization")
+	return req.WithContext(ctx)
+}
+
+func TestSetPasswordHandler(t *testing.T) {
+	hash, _ := auth.HashPassword("password123")
+	user := testUser()
+	user.PasswordHash = hash
+	smtpUser := testUser()
+	smtpUser.ID = uuid.New()
+	smtpUser.AccountType = "smtp"
+	policy := &auth.PasswordPolicy{MinLength: 12, RequireDigit: true}
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		body   string
+		role   string
+		code   int
+	}{
+		{"self", user.ID, `{"password":"a-long-passphrase-1","current_password":"password123"}`, "member", http.StatusNoContent},
+		{"self with wrong current password", user.ID, `{"password":"a-long-passphrase-1","current_password":"wrong"}`, "member", http.StatusForbidden},
+		{"policy violation", user.ID, `{"password":"short","current_password":"password123"}`, "member", http.StatusBadRequest},
+		{"other user as admin", smtpUser.ID, `{"password":"a-long-passphrase-1"}`, "admin", http.StatusNoContent},
+		{"other user as member", smtpUser.ID, `{"password":"a-long-passphrase-1"}`, "member", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated uuid.UUID
+			mock := &mockQuerier{
+				getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+					if id == smtpUser.ID {
+						return smtpUser, nil
+					}
+					return user, nil
+				},
+				listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+					return []storage.Group{testGroup()}, nil
+				},
+				getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+					return testGroup(), nil
+				},
+				updateUserPasswordFn: func(ctx context.Context, arg storage.UpdateUserPasswordParams) error {
+					if auth.VerifyPassword(arg.PasswordHash, "a-long-passphrase-1") != nil {
+						t.Error("stored hash does not match the new password")
+					}
+					updated = arg.ID
+					return nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			SetPasswordHandler(mock, policy, nil).ServeHTTP(rec, setPasswordHTTPRequest(tt.userID, tt.body, user.ID, tt.role))
+
+			if rec.Code != tt.code {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+			if tt.code == http.StatusNoContent && updated != tt.userID {
+				t.Errorf("updated password of %s, want %s", updated, tt.userID)
+			}
+			if tt.code != http.StatusNoContent && updated != uuid.Nil {
+				t.Error("password updated although the request was rejected")
+			}
+		})
+	}
+}
+
+func TestCreateUserHandler_PasswordPolicy(t *testing.T) {
+	mock := &mockQuerier{
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			t.Error("user created with a password that breaks the policy")
+			return testUser(), nil
+		},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(`{"email":"new@example.com","password":"pass"}`))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+
+	rec := httptest.NewRecorder()
+	CreateUserHandler(mock, &auth.PasswordPolicy{MinLength: 8}, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "at least 8 characters") {
+		t.Errorf("expected a policy violation, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+}
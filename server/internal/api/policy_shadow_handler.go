@@ -0,0 +1,217 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/shadow"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Shadow report limits. The report covers the last days (default 7, at
+// most 90), counted in whole UTC days including today.
+const (
+	defaultShadowReportDays = 7
+	maxShadowReportDays     = 90
+)
+
+// shadowPoliciesRequest is the JSON body for setting the policies a group
+// runs in shadow mode. It replaces the current list.
+type shadowPoliciesRequest struct {
+	Policies []string `json:"policies"`
+}
+
+// shadowPoliciesResponse lists the policies a group runs in shadow mode.
+type shadowPoliciesResponse struct {
+	Policies []string `json:"policies"`
+}
+
+// shadowReasonResponse counts the would-be rejections of one policy for
+// one reason.
+type shadowReasonResponse struct {
+	Reason     string     `json:"reason"`
+	Count      int64      `json:"count"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// shadowPolicyReport summarizes the would-be rejections of one policy,
+// reasons with the most rejections first.
+type shadowPolicyReport struct {
+	Policy   string                 `json:"policy"`
+	Shadowed bool                   `json:"shadowed"`
+	Total    int64                  `json:"total"`
+	Reasons  []shadowReasonResponse `json:"reasons"`
+}
+
+// shadowReportResponse is the JSON response of the shadow report.
+type shadowReportResponse struct {
+	Since    time.Time            `json:"since"`
+	Days     int                  `json:"days"`
+	Policies []shadowPolicyReport `json:"policies"`
+}
+
+// GetShadowPoliciesHandler handles GET /api/v1/policy-shadow.
+func GetShadowPoliciesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		policies, err := queries.ListShadowPolicies(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if policies == nil {
+			policies = []string{}
+		}
+		respondJSON(w, http.StatusOK, shadowPoliciesResponse{Policies: policies})
+	}
+}
+
+// UpdateShadowPoliciesHandler handles PUT /api/v1/policy-shadow.
+// Replaces the policies the group runs in shadow mode: they log what they
+// would reject instead of rejecting it. An empty list enforces all of
+// them again. Requires owner or admin role.
+func UpdateShadowPoliciesHandler(tx storage.TxRunner, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req shadowPoliciesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		policies := []string{}
+		for _, p := range req.Policies {
+			p = strings.ToLower(strings.TrimSpace(p))
+			if !shadow.Valid(p) {
+				respondValidationErrors(w, []string{"policies must be one of " + strings.Join(shadow.Policies, ", ")})
+				return
+			}
+			policies = append(policies, p)
+		}
+		slices.Sort(policies)
+		policies = slices.Compact(policies)
+
+		err := tx.ExecTx(r.Context(), func(q storage.Querier) error {
+			if err := q.DeleteShadowPolicies(r.Context(), groupID); err != nil {
+				return err
+			}
+			for _, p := range policies {
+				if err := q.AddShadowPolicy(r.Context(), storage.AddShadowPolicyParams{GroupID: groupID, Policy: p}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateShadowPolicies, "group", groupID.String(), map[string]interface{}{
+				"policies": policies,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, shadowPoliciesResponse{Policies: policies})
+	}
+}
+
+// ShadowReportHandler handles GET /api/v1/policy-shadow/report?days=7.
+// Summarizes the rejections each policy would have made in shadow mode
+// over the last days, per reason. Policies that have since been enforced
+// again keep their counts, with shadowed false.
+func ShadowReportHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		days := defaultShadowReportDays
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxShadowReportDays {
+				respondValidationErrors(w, []string{"days must be between 1 and " + strconv.Itoa(maxShadowReportDays)})
+				return
+			}
+			days = n
+		}
+		since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+		shadowed, err := queries.ListShadowPolicies(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		rows, err := queries.SummarizeShadowRejections(r.Context(), storage.SummarizeShadowRejectionsParams{
+			GroupID: groupID,
+			Day:     pgtype.Date{Time: since, Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, shadowReportResponse{
+			Since:    since,
+			Days:     days,
+			Policies: buildShadowReport(shadowed, rows),
+		})
+	}
+}
+
+// buildShadowReport groups the summary rows by policy. Shadowed policies
+// without rejections are listed with a zero total.
+func buildShadowReport(shadowed []string, rows []storage.SummarizeShadowRejectionsRow) []shadowPolicyReport {
+	byPolicy := map[string]*shadowPolicyReport{}
+	report := []shadowPolicyReport{}
+	for _, p := range shadow.Policies {
+		byPolicy[p] = &shadowPolicyReport{Policy: p, Reasons: []shadowReasonResponse{}}
+	}
+	for _, p := range shadowed {
+		if pr, ok := byPolicy[p]; ok {
+			pr.Shadowed = true
+		}
+	}
+	for _, row := range rows {
+		pr, ok := byPolicy[row.Policy]
+		if !ok {
+			continue
+		}
+		reason := shadowReasonResponse{Reason: row.Reason, Count: row.Count}
+		if row.LastSeenAt.Valid {
+			t := row.LastSeenAt.Time
+			reason.LastSeenAt = &t
+		}
+		pr.Total += row.Count
+		pr.Reasons = append(pr.Reasons, reason)
+	}
+	for _, p := range shadow.Policies {
+		if pr := byPolicy[p]; pr.Shadowed || pr.Total > 0 {
+			report = append(report, *pr)
+		}
+	}
+	return report
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func shadowRequest(method, target, body, role string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	return req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "organization"))
+}
+
+func TestUpdateShadowPoliciesHandler(t *testing.T) {
+	var deleted bool
+	var added []string
+	mock := &mockQuerier{
+		deleteShadowPoliciesFn: func(ctx context.Context, groupID uuid.UUID) error {
+			deleted = groupID == testGroup().ID
+			return nil
+		},
+		addShadowPolicyFn: func(ctx context.Context, arg storage.AddShadowPolicyParams) error {
+			added = append(added, arg.Policy)
+			return nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"policies":["sender_policy"," Allowed_Domains ","sender_policy"]}`
+	UpdateShadowPoliciesHandler(directTx{mock}, nil).ServeHTTP(rec, shadowRequest(http.MethodPut, "/api/v1/policy-shadow", body, "admin"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if !deleted || strings.Join(added, ",") != "allowed_domains,sender_policy" {
+		t.Errorf("deleted = %v, added = %v", deleted, added)
+	}
+	var resp shadowPoliciesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if strings.Join(resp.Policies, ",") != "allowed_domains,sender_policy" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestUpdateShadowPoliciesHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		role     string
+		wantCode int
+	}{
+		{name: "member", body: `{"policies":["scripts"]}`, role: "member", wantCode: http.StatusForbidden},
+		{name: "unknown policy", body: `{"policies":["sandbox"]}`, role: "owner", wantCode: http.StatusBadRequest},
+		{name: "invalid body", body: `{`, role: "owner", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				deleteShadowPoliciesFn: func(ctx context.Context, groupID uuid.UUID) error {
+					t.Error("shadow policies changed")
+					return nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			UpdateShadowPoliciesHandler(directTx{mock}, nil).ServeHTTP(rec, shadowRequest(http.MethodPut, "/api/v1/policy-shadow", tt.body, tt.role))
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestShadowReportHandler(t *testing.T) {
+	seen := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	var gotDay time.Time
+	mock := &mockQuerier{
+		listShadowPoliciesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+			return []string{"recipient_validation", "sender_policy"}, nil
+		},
+		summarizeShadowRejectionsFn: func(ctx context.Context, arg storage.SummarizeShadowRejectionsParams) ([]storage.SummarizeShadowRejectionsRow, error) {
+			gotDay = arg.Day.Time
+			return []storage.SummarizeShadowRejectionsRow{
+				{Policy: "scripts", Reason: "script footer: spam", Count: 2, LastSeenAt: pgtype.Timestamptz{Time: seen, Valid: true}},
+				{Policy: "sender_policy", Reason: "From not verified", Count: 5, LastSeenAt: pgtype.Timestamptz{Time: seen, Valid: true}},
+				{Policy: "sender_policy", Reason: "From is not a single address", Count: 1, LastSeenAt: pgtype.Timestamptz{Time: seen, Valid: true}},
+			}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ShadowReportHandler(mock).ServeHTTP(rec, shadowRequest(http.MethodGet, "/api/v1/policy-shadow/report?days=3", "", "member"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if want := today.AddDate(0, 0, -2); !gotDay.Equal(want) {
+		t.Errorf("report since %v, want %v", gotDay, want)
+	}
+	var resp shadowReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Days != 3 || len(resp.Policies) != 3 {
+		t.Fatalf("unexpected report: %+v", resp)
+	}
+	// Policies are listed in the order of shadow.Policies.
+	rv, sp, sc := resp.Policies[0], resp.Policies[1], resp.Policies[2]
+	if rv.Policy != "recipient_validation" || !rv.Shadowed || rv.Total != 0 || len(rv.Reasons) != 0 {
+		t.Errorf("unexpected recipient_validation report: %+v", rv)
+	}
+	if sp.Policy != "sender_policy" || !sp.Shadowed || sp.Total != 6 || len(sp.Reasons) != 2 || sp.Reasons[0].Count != 5 {
+		t.Errorf("unexpected sender_policy report: %+v", sp)
+	}
+	if sc.Policy != "scripts" || sc.Shadowed || sc.Total != 2 || sc.Reasons[0].LastSeenAt == nil || !sc.Reasons[0].LastSeenAt.Equal(seen) {
+		t.Errorf("unexpected scripts report: %+v", sc)
+	}
+}
+
+func TestShadowReportHandler_InvalidDays(t *testing.T) {
+	for _, days := range []string{"0", "91", "week"} {
+		rec := httptest.NewRecorder()
+		ShadowReportHandler(&mockQuerier{}).ServeHTTP(rec, shadowRequest(http.MethodGet, "/api/v1/policy-shadow/report?days="+days, "", "member"))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("days=%s: expected status 400, got %d", days, rec.Code)
+		}
+	}
+}
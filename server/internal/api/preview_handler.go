@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/htmlutil"
+	"github.com/sungwon/smtp-proxy/server/internal/mimeparse"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preview"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// maxPreviewBodySize caps the request body of a preview request.
+const maxPreviewBodySize = 10 << 20
+
+// previewRequest is the JSON body for POST /api/v1/preview. Exactly one of
+// message_id, raw or html must be set.
+type previewRequest struct {
+	// MessageID previews a message already accepted by the proxy.
+	MessageID *uuid.UUID `json:"message_id"`
+	// Raw is a complete RFC 5322 message.
+	Raw string `json:"raw"`
+	// HTML is an HTML body, optionally with Subject.
+	HTML    string `json:"html"`
+	Subject string `json:"subject"`
+	// RenderTest forwards the sanitized HTML to the configured rendering
+	// test service. Clients overrides its default client list.
+	RenderTest bool     `json:"render_test"`
+	Clients    []string `json:"clients"`
+}
+
+// previewResponse is the JSON response for POST /api/v1/preview.
+type previewResponse struct {
+	Subject    string                `json:"subject"`
+	HTML       string                `json:"html"`
+	Text       string                `json:"text,omitempty"`
+	RenderTest *preview.RenderResult `json:"render_test,omitempty"`
+}
+
+// PreviewHandler handles POST /api/v1/preview.
+// Renders a stored or submitted message's HTML body with scripts and other
+// active content removed, and optionally submits it to an external
+// rendering test service that returns per-client preview URLs.
+// store and tester may be nil; previews of stored bodies and rendering
+// tests are then unavailable.
+func PreviewHandler(queries storage.Querier, store msgstore.MessageStore, tester preview.RenderTester) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		callerGroupID := auth.GroupIDFromContext(r.Context())
+		if callerGroupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		var req previewRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPreviewBodySize)).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		sources := 0
+		if req.MessageID != nil {
+			sources++
+		}
+		if req.Raw != "" {
+			sources++
+		}
+		if req.HTML != "" {
+			sources++
+		}
+		if sources != 1 {
+			respondError(w, http.StatusBadRequest, "exactly one of message_id, raw or html is required")
+			return
+		}
+
+		resp := previewResponse{Subject: req.Subject, HTML: req.HTML}
+
+		var raw []byte
+		switch {
+		case req.Raw != "":
+			raw = []byte(req.Raw)
+		case req.MessageID != nil:
+			msg, err := queries.GetMessageByID(r.Context(), *req.MessageID)
+			if err != nil {
+				respondStorageError(w, err, http.StatusNotFound, "message not found")
+				return
+			}
+			if !canAccessGroup(r.Context(), queries, uuid.UUID(msg.GroupID.Bytes)) {
+				respondError(w, http.StatusForbidden, "access denied")
+				return
+			}
+
+			switch {
+			case msg.Body.Valid:
+				raw = []byte(msg.Body.String)
+			case msg.StorageRef.Valid && store != nil:
+				raw, err = store.Get(r.Context(), msg.StorageRef.String)
+				if errors.Is(err, msgstore.ErrNotFound) {
+					respondError(w, http.StatusNotFound, "message body not found")
+					return
+				}
+				if err != nil {
+					respondError(w, http.StatusInternalServerError, "internal server error")
+					return
+				}
+			default:
+				respondError(w, http.StatusUnprocessableEntity, "message body is not available")
+				return
+			}
+			if msg.Subject.Valid {
+				resp.Subject = msg.Subject.String
+			}
+		}
+
+		if raw != nil {
+			parsed, err := mimeparse.Parse(raw)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "message could not be parsed")
+				return
+			}
+			if parsed.Subject != "" {
+				resp.Subject = parsed.Subject
+			}
+			resp.HTML = parsed.HTMLBody
+			resp.Text = parsed.TextBody
+		}
+
+		// Text-only messages are previewed as preformatted text.
+		if resp.HTML == "" && resp.Text != "" {
+			resp.HTML = "<pre>" + html.EscapeString(resp.Text) + "</pre>"
+		}
+		resp.HTML = htmlutil.Sanitize(resp.HTML)
+
+		if req.RenderTest {
+			if tester == nil {
+				respondError(w, http.StatusServiceUnavailable, "rendering test service not configured")
+				return
+			}
+			result, err := tester.Submit(r.Context(), preview.RenderRequest{
+				Subject: resp.Subject,
+				HTML:    resp.HTML,
+				Clients: req.Clients,
+			})
+			if err != nil {
+				respondError(w, http.StatusBadGateway, "rendering test failed")
+				return
+			}
+			resp.RenderTest = result
+		}
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preview"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockRenderTester records the last rendering test request.
+type mockRenderTester struct {
+	got    preview.RenderRequest
+	result *preview.RenderResult
+	err    error
+}
+
+func (m *mockRenderTester) Submit(_ context.Context, req preview.RenderRequest) (*preview.RenderResult, error) {
+	m.got = req
+	return m.result, m.err
+}
+
+// mockPreviewStore serves message bodies from a map.
+type mockPreviewStore struct {
+	data map[string][]byte
+}
+
+func (m *mockPreviewStore) Put(_ context.Context, _ string, _ []byte) error { return nil }
+func (m *mockPreviewStore) Delete(_ context.Context, _ string) error        { return nil }
+func (m *mockPreviewStore) Get(_ context.Context, id string) ([]byte, error) {
+	if d, ok := m.data[id]; ok {
+		return d, nil
+	}
+	return nil, msgstore.ErrNotFound
+}
+
+func doPreview(t *testing.T, h http.HandlerFunc, groupID uuid.UUID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/preview", strings.NewReader(body))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, groupID, "member", "organization"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPreviewHandler_SanitizesHTML(t *testing.T) {
+	body := `{"subject":"Launch","html":"<p onclick=\"x()\">Hi</p><script>alert(1)</script>"}`
+	rec := doPreview(t, PreviewHandler(&mockQuerier{}, nil, nil), testGroup().ID, body)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp previewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.HTML != "<p>Hi</p>" {
+		t.Errorf("expected sanitized html <p>Hi</p>, got %q", resp.HTML)
+	}
+	if resp.Subject != "Launch" {
+		t.Errorf("expected subject Launch, got %q", resp.Subject)
+	}
+}
+
+func TestPreviewHandler_StoredMessage(t *testing.T) {
+	groupID := testGroup().ID
+	msgID := uuid.New()
+	raw := "Subject: Stored\r\nContent-Type: text/html\r\n\r\n<h1>Stored</h1><iframe src=\"x\"></iframe>"
+
+	mock := &mockQuerier{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Message, error) {
+			return storage.Message{
+				ID:         id,
+				GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
+				StorageRef: pgtype.Text{String: id.String(), Valid: true},
+			}, nil
+		},
+	}
+	store := &mockPreviewStore{data: map[string][]byte{msgID.String(): []byte(raw)}}
+	tester := &mockRenderTester{result: &preview.RenderResult{
+		ID:       "rt-1",
+		Previews: []preview.RenderPreview{{Client: "gmail", URL: "https://render.example/rt-1/gmail.png"}},
+	}}
+
+	body := `{"message_id":"` + msgID.String() + `","render_test":true,"clients":["gmail"]}`
+	rec := doPreview(t, PreviewHandler(mock, store, tester), groupID, body)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp previewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Subject != "Stored" || resp.HTML != "<h1>Stored</h1>" {
+		t.Errorf("unexpected preview: %+v", resp)
+	}
+	if tester.got.HTML != "<h1>Stored</h1>" || len(tester.got.Clients) != 1 {
+		t.Errorf("unexpected rendering test request: %+v", tester.got)
+	}
+	if resp.RenderTest == nil || len(resp.RenderTest.Previews) != 1 {
+		t.Errorf("expected render_test previews, got %+v", resp.RenderTest)
+	}
+}
+
+func TestPreviewHandler_OtherGroupMessageForbidden(t *testing.T) {
+	mock := &mockQuerier{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Message, error) {
+			return storage.Message{
+				ID:      id,
+				GroupID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+				Body:    pgtype.Text{String: "Subject: x\r\n\r\nhi", Valid: true},
+			}, nil
+		},
+	}
+
+	rec := doPreview(t, PreviewHandler(mock, nil, nil), testGroup().ID, `{"message_id":"`+uuid.New().String()+`"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestPreviewHandler_StoredBodyUnavailable(t *testing.T) {
+	groupID := testGroup().ID
+	mock := &mockQuerier{
+		getMessageByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Message, error) {
+			return storage.Message{
+				ID:         id,
+				GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
+				StorageRef: pgtype.Text{String: id.String(), Valid: true},
+			}, nil
+		},
+	}
+
+	rec := doPreview(t, PreviewHandler(mock, nil, nil), groupID, `{"message_id":"`+uuid.New().String()+`"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", rec.Code)
+	}
+}
+
+func TestPreviewHandler_TextOnlyRaw(t *testing.T) {
+	raw := "Subject: Plain\r\n\r\n<b>not markup</b>"
+	body, _ := json.Marshal(map[string]string{"raw": raw})
+	rec := doPreview(t, PreviewHandler(&mockQuerier{}, nil, nil), testGroup().ID, string(body))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp previewResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.HTML != "<pre>&lt;b&gt;not markup&lt;/b&gt;</pre>" {
+		t.Errorf("unexpected html for text-only message: %q", resp.HTML)
+	}
+}
+
+func TestPreviewHandler_Errors(t *testing.T) {
+	failing := &mockRenderTester{err: errors.New("boom")}
+	tests := []struct {
+		name   string
+		tester preview.RenderTester
+		body   string
+		want   int
+	}{
+		{name: "no source", body: `{}`, want: http.StatusBadRequest},
+		{name: "two sources", body: `{"html":"<p>x</p>","raw":"Subject: x\r\n\r\nx"}`, want: http.StatusBadRequest},
+		{name: "render test not configured", body: `{"html":"<p>x</p>","render_test":true}`, want: http.StatusServiceUnavailable},
+		{name: "render test failed", tester: failing, body: `{"html":"<p>x</p>","render_test":true}`, want: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doPreview(t, PreviewHandler(&mockQuerier{}, nil, tt.tester), testGroup().ID, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+type providerCaptureResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	MessageID *uuid.UUID      `json:"message_id,omitempty"`
+	Exchanges json.RawMessage `json:"exchanges"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func toProviderCaptureResponse(c storage.ProviderCapture) providerCaptureResponse {
+	resp := providerCaptureResponse{
+		ID:        c.ID,
+		Exchanges: json.RawMessage(c.Exchanges),
+		Error:     c.Error.String,
+		CreatedAt: c.CreatedAt.Time,
+	}
+	if c.MessageID.Valid {
+		id := uuid.UUID(c.MessageID.Bytes)
+		resp.MessageID = &id
+	}
+	return resp
+}
+
+// ListProviderCapturesHandler handles GET /api/v1/providers/{id}/captures.
+// Returns the ESP API requests and responses captured for the provider's
+// recent deliveries, newest first.
+func ListProviderCapturesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		esp, ok := loadCaptureProvider(w, r, queries)
+		if !ok {
+			return
+		}
+
+		captures, err := queries.ListProviderCaptures(r.Context(), esp.ID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]providerCaptureResponse, len(captures))
+		for i, c := range captures {
+			resp[i] = toProviderCaptureResponse(c)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DeleteProviderCapturesHandler handles DELETE /api/v1/providers/{id}/captures.
+// Deletes the provider's captures. Capture itself stays on until
+// smtp_config.debug_capture is cleared.
+func DeleteProviderCapturesHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		esp, ok := loadCaptureProvider(w, r, queries)
+		if !ok {
+			return
+		}
+
+		n, err := queries.DeleteProviderCaptures(r.Context(), esp.ID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteProviderCaptures, "provider", esp.ID.String(), map[string]interface{}{
+				"deleted": n,
+			})
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// loadCaptureProvider resolves the {id} provider and checks that the
+// caller is an admin of a group that may access it. Captures can hold
+// message content, so members cannot read them. It writes the error
+// response and returns false on failure.
+func loadCaptureProvider(w http.ResponseWriter, r *http.Request, queries storage.Querier) (storage.EspProvider, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid provider ID format")
+		return storage.EspProvider{}, false
+	}
+
+	esp, err := queries.GetProviderByID(r.Context(), id)
+	if err != nil {
+		respondStorageError(w, err, http.StatusNotFound, "provider not found")
+		return storage.EspProvider{}, false
+	}
+
+	if !isGroupAdmin(r) || !canAccessGroup(r.Context(), queries, esp.GroupID) {
+		respondError(w, http.StatusForbidden, "access denied")
+		return storage.EspProvider{}, false
+	}
+	return esp, true
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func providerCaptureRequest(method string, id uuid.UUID, role string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/providers/"+id.String()+"/captures", nil)
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "system")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id.String())
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestListProviderCapturesHandler(t *testing.T) {
+	prov := testProvider()
+	msgID := uuid.New()
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+		listProviderCapturesFn: func(ctx context.Context, providerID uuid.UUID) ([]storage.ProviderCapture, error) {
+			if providerID != prov.ID {
+				t.Errorf("listed captures of %s, want %s", providerID, prov.ID)
+			}
+			return []storage.ProviderCapture{{
+				ID:         uuid.New(),
+				ProviderID: prov.ID,
+				MessageID:  pgtype.UUID{Bytes: msgID, Valid: true},
+				Exchanges:  []byte(`[{"method":"POST","status_code":400}]`),
+				Error:      pgtype.Text{String: "sendgrid: 400", Valid: true},
+			}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListProviderCapturesHandler(mock).ServeHTTP(rec, providerCaptureRequest(http.MethodGet, prov.ID, "admin"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp []providerCaptureResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 || resp[0].MessageID == nil || *resp[0].MessageID != msgID || resp[0].Error != "sendgrid: 400" {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+}
+
+func TestProviderCapturesHandler_MemberForbidden(t *testing.T) {
+	prov := testProvider()
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+		deleteProviderCapturesFn: func(ctx context.Context, providerID uuid.UUID) (int64, error) {
+			t.Error("captures deleted by a member")
+			return 0, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "member", "organization")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", prov.ID.String())
+	req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+
+	for _, h := range []http.HandlerFunc{ListProviderCapturesHandler(mock), DeleteProviderCapturesHandler(mock, nil)} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", rec.Code)
+		}
+	}
+}
+
+func TestDeleteProviderCapturesHandler(t *testing.T) {
+	prov := testProvider()
+	var deleted uuid.UUID
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+		deleteProviderCapturesFn: func(ctx context.Context, providerID uuid.UUID) (int64, error) {
+			deleted = providerID
+			return 3, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	DeleteProviderCapturesHandler(mock, nil).ServeHTTP(rec, providerCaptureRequest(http.MethodDelete, prov.ID, "admin"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+	if deleted != prov.ID {
+		t.Errorf("deleted captures of %s, want %s", deleted, prov.ID)
+	}
+}
@@ -1,13 +1,21 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/cost"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -17,19 +25,55 @@ type providerRequest struct {
 	ProviderType string          `json:"provider_type"`
 	APIKey       *string         `json:"api_key"`
 	SMTPConfig   json.RawMessage `json:"smtp_config"`
+	CostModel    json.RawMessage `json:"cost_model"`
 	Enabled      bool            `json:"enabled"`
+	// SkipVerification saves the provider without checking its
+	// credentials, e.g. when only the workers can reach the ESP.
+	SkipVerification bool `json:"skip_verification"`
 }
 
 // providerResponse is the JSON response for a provider.
 type providerResponse struct {
-	ID           uuid.UUID       `json:"id"`
-	GroupID      uuid.UUID       `json:"group_id"`
-	Name         string          `json:"name"`
-	ProviderType string          `json:"provider_type"`
-	SMTPConfig   json.RawMessage `json:"smtp_config"`
-	Enabled      bool            `json:"enabled"`
-	CreatedAt    string          `json:"created_at"`
-	UpdatedAt    string          `json:"updated_at"`
+	ID           uuid.UUID         `json:"id"`
+	GroupID      uuid.UUID         `json:"group_id"`
+	Name         string            `json:"name"`
+	ProviderType string            `json:"provider_type"`
+	SMTPConfig   json.RawMessage   `json:"smtp_config"`
+	CostModel    *cost.Model       `json:"cost_model"`
+	Enabled      bool              `json:"enabled"`
+	Health       providerHealth    `json:"health"`
+	DailyCap     *providerDailyCap `json:"daily_cap,omitempty"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at"`
+}
+
+// providerHealth is the health state maintained by the queue worker's
+// provider health prober.
+type providerHealth struct {
+	Status              string  `json:"status"`
+	ConsecutiveFailures int32   `json:"consecutive_failures"`
+	LastCheckedAt       *string `json:"last_checked_at,omitempty"`
+	LastError           *string `json:"last_error,omitempty"`
+	AutoDisabled        bool    `json:"auto_disabled"`
+	AutoDisabledAt      *string `json:"auto_disabled_at,omitempty"`
+}
+
+// providerDailyCap is the state of a provider's daily cap. Once Sent
+// reaches Limit the provider is skipped by routing until ResetsAt.
+type providerDailyCap struct {
+	Limit    int    `json:"limit"`
+	Timezone string `json:"timezone"`
+	Sent     int    `json:"sent"`
+	Reached  bool   `json:"reached"`
+	ResetsAt string `json:"resets_at"`
+}
+
+// providerHealthCheckResponse is a single entry of a provider's health history.
+type providerHealthCheckResponse struct {
+	Healthy   bool    `json:"healthy"`
+	LatencyMs int32   `json:"latency_ms"`
+	Error     *string `json:"error,omitempty"`
+	CheckedAt string  `json:"checked_at"`
 }
 
 // toProviderResponse converts a storage.EspProvider to a providerResponse.
@@ -39,6 +83,9 @@ func toProviderResponse(p storage.EspProvider) providerResponse {
 	if len(smtpConfig) == 0 {
 		smtpConfig = json.RawMessage(`{}`)
 	}
+	// A stored model that no longer validates is omitted rather than
+	// failing the whole response.
+	costModel, _ := cost.ParseModel(p.CostModel)
 
 	return providerResponse{
 		ID:           p.ID,
@@ -46,30 +93,214 @@ func toProviderResponse(p storage.EspProvider) providerResponse {
 		Name:         p.Name,
 		ProviderType: string(p.ProviderType),
 		SMTPConfig:   smtpConfig,
+		CostModel:    costModel,
 		Enabled:      p.Enabled,
+		Health:       toProviderHealth(p),
 		CreatedAt:    timestampToTime(p.CreatedAt).Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:    timestampToTime(p.UpdatedAt).Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
 
+// withDailyCaps adds the daily cap state to the responses of providers
+// that have a cap. Without daily send counts, the caps show nothing sent.
+func withDailyCaps(r *http.Request, queries storage.Querier, groupID uuid.UUID, providers []storage.EspProvider, result []providerResponse) {
+	now := time.Now()
+	var sends []storage.ProviderDailySend
+	for i := range providers {
+		c := provider.StorageDailyCap(&providers[i])
+		if !c.Enabled() {
+			continue
+		}
+		if sends == nil {
+			sends, _ = queries.ListProviderDailySendsByGroupID(r.Context(), storage.ListProviderDailySendsByGroupIDParams{
+				GroupID: groupID,
+				Day:     provider.DailySendsSince(now),
+			})
+		}
+		sent := provider.DailySent(providers[i].ID, c, sends, now)
+		result[i].DailyCap = &providerDailyCap{
+			Limit:    c.Limit,
+			Timezone: c.Location.String(),
+			Sent:     sent,
+			Reached:  sent >= c.Limit,
+			ResetsAt: c.Reset(now).Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+}
+
+// toProviderHealth extracts the health fields of a storage.EspProvider.
+func toProviderHealth(p storage.EspProvider) providerHealth {
+	h := providerHealth{
+		Status:              p.HealthStatus,
+		ConsecutiveFailures: p.ConsecutiveFailures,
+		AutoDisabled:        p.AutoDisabledAt.Valid,
+	}
+	if h.Status == "" {
+		h.Status = "unknown"
+	}
+	if p.LastHealthCheckAt.Valid {
+		s := p.LastHealthCheckAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		h.LastCheckedAt = &s
+	}
+	if p.LastHealthError.Valid {
+		h.LastError = &p.LastHealthError.String
+	}
+	if p.AutoDisabledAt.Valid {
+		s := p.AutoDisabledAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		h.AutoDisabledAt = &s
+	}
+	return h
+}
+
 // validProviderTypes contains the set of allowed provider type values.
 var validProviderTypes = map[string]storage.ProviderType{
-	"sendgrid": storage.ProviderTypeSendgrid,
-	"mailgun":  storage.ProviderTypeMailgun,
-	"ses":      storage.ProviderTypeSes,
-	"smtp":     storage.ProviderTypeSmtp,
-	"msgraph":  storage.ProviderTypeMsgraph,
+	"sendgrid":  storage.ProviderTypeSendgrid,
+	"mailgun":   storage.ProviderTypeMailgun,
+	"ses":       storage.ProviderTypeSes,
+	"smtp":      storage.ProviderTypeSmtp,
+	"msgraph":   storage.ProviderTypeMsgraph,
+	"plugin":    storage.ProviderTypePlugin,
+	"gmail":     storage.ProviderTypeGmail,
+	"resend":    storage.ProviderTypeResend,
+	"brevo":     storage.ProviderTypeBrevo,
+	"zeptomail": storage.ProviderTypeZeptomail,
+	"ews":       storage.ProviderTypeEws,
+}
+
+// validateSMTPConfig checks the smtp_config fields the API understands.
+// Other fields are stored as given. Plugin providers must name their
+// plugin's provider type; the API server does not load plugins, so the
+// name is checked by the worker when it creates the provider.
+func validateSMTPConfig(pt storage.ProviderType, raw json.RawMessage) error {
+	var cfg struct {
+		ProxyURL         string                 `json:"proxy_url"`
+		Region           string                 `json:"region"`
+		Endpoint         string                 `json:"endpoint"`
+		Plugin           string                 `json:"plugin"`
+		Options          map[string]string      `json:"options"`
+		Auth             string                 `json:"auth"`
+		RelayIPs         []string               `json:"relay_ips"`
+		DebugCapture     int                    `json:"debug_capture"`
+		DomainLimits     []provider.DomainLimit `json:"domain_limits"`
+		DailyCap         int                    `json:"daily_cap"`
+		DailyCapTimezone string                 `json:"daily_cap_timezone"`
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return err
+	}
+	if pt == storage.ProviderTypePlugin && cfg.Plugin == "" {
+		return errors.New("plugin is required for plugin providers")
+	}
+	if pt == storage.ProviderTypeEws {
+		if err := provider.ValidateEWSAuth(cfg.Auth); err != nil {
+			return err
+		}
+	}
+	if cfg.ProxyURL != "" {
+		if _, err := provider.ParseProxyURL(cfg.ProxyURL); err != nil {
+			return err
+		}
+	}
+	if err := provider.ValidateRegion(string(pt), cfg.Region); err != nil {
+		return err
+	}
+	if err := provider.ValidateEndpoint(string(pt), cfg.Endpoint); err != nil {
+		return err
+	}
+	if cfg.DebugCapture < 0 || cfg.DebugCapture > provider.MaxCaptureLimit {
+		return fmt.Errorf("debug_capture must be between 0 and %d", provider.MaxCaptureLimit)
+	}
+	for _, ip := range cfg.RelayIPs {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("relay_ips: %q is not an IP address", ip)
+		}
+	}
+	if err := provider.ValidateDailyCap(cfg.DailyCap, cfg.DailyCapTimezone); err != nil {
+		return err
+	}
+	return provider.ValidateDomainLimits(cfg.DomainLimits)
+}
+
+// providerVerifyTimeout bounds the credential check of provider create and
+// update requests.
+const providerVerifyTimeout = 15 * time.Second
+
+// unverifiableProviderTypes are the provider types whose credentials are
+// not verified: smtp providers have no ESP API to check, and plugin types
+// are registered only in the worker.
+var unverifiableProviderTypes = map[storage.ProviderType]bool{
+	storage.ProviderTypeSmtp:   true,
+	storage.ProviderTypePlugin: true,
+}
+
+// verifyProviderCredentials checks the credentials of esp with its ESP by
+// running the provider's health check, which authenticates against the ESP
+// API without sending mail. It returns the status code to reject the
+// request with and the reason, or 0 and nil when the credentials work.
+// The reason never includes the ESP's response: the endpoint and proxy are
+// chosen by the caller, so the response could come from any server the
+// API server can reach. Endpoints and proxies on loopback, private and
+// link-local addresses are refused.
+func verifyProviderCredentials(ctx context.Context, client provider.HTTPClient, esp storage.EspProvider) (int, error) {
+	if unverifiableProviderTypes[esp.ProviderType] {
+		return 0, nil
+	}
+	var targets struct {
+		Endpoint string `json:"endpoint"`
+		ProxyURL string `json:"proxy_url"`
+	}
+	_ = json.Unmarshal(esp.SmtpConfig, &targets)
+	for _, target := range []string{targets.Endpoint, targets.ProxyURL} {
+		if target == "" {
+			continue
+		}
+		if err := provider.ValidatePublicURL(target); err != nil {
+			return http.StatusBadRequest, errors.New("endpoint and proxy_url must be public addresses")
+		}
+	}
+	p, err := provider.NewProviderFromStorage(&esp, client)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, providerVerifyTimeout)
+	defer cancel()
+	err = p.HealthCheck(ctx)
+	if err == nil {
+		return 0, nil
+	}
+	var pe *provider.ProviderError
+	if errors.As(err, &pe) && pe.Permanent {
+		return http.StatusUnprocessableEntity, errors.New("provider rejected the credentials")
+	}
+	return http.StatusBadGateway, errors.New("could not verify provider credentials")
+}
+
+// normalizeCostModel validates a cost_model request field and returns the
+// JSON to store, or nil when the field is absent or null.
+func normalizeCostModel(raw json.RawMessage) ([]byte, error) {
+	m, err := cost.ParseModel(raw)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return json.Marshal(m)
 }
 
 // CreateProviderHandler handles POST /api/v1/providers.
-// Creates a new ESP provider for the authenticated user's group.
-func CreateProviderHandler(queries storage.Querier) http.HandlerFunc {
+// Creates a new ESP provider for the authenticated user's group. Requires
+// group admin+ role. When an api_key is given, it is verified with the ESP
+// first, unless skip_verification is set.
+func CreateProviderHandler(queries storage.Querier, client provider.HTTPClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		groupID := auth.GroupIDFromContext(r.Context())
 		if groupID == uuid.Nil {
 			respondError(w, http.StatusUnauthorized, "unauthorized")
 			return
 		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
 
 		var req providerRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -95,6 +326,29 @@ func CreateProviderHandler(queries storage.Querier) http.HandlerFunc {
 		if len(req.SMTPConfig) > 0 {
 			smtpConfig = req.SMTPConfig
 		}
+		if err := validateSMTPConfig(pt, smtpConfig); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid smtp_config: "+err.Error())
+			return
+		}
+
+		costModel, err := normalizeCostModel(req.CostModel)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid cost_model: "+err.Error())
+			return
+		}
+
+		if apiKey.String != "" && !req.SkipVerification {
+			status, err := verifyProviderCredentials(r.Context(), client, storage.EspProvider{
+				Name:         req.Name,
+				ProviderType: pt,
+				ApiKey:       apiKey,
+				SmtpConfig:   smtpConfig,
+			})
+			if err != nil {
+				respondError(w, status, err.Error())
+				return
+			}
+		}
 
 		provider, err := queries.CreateProvider(r.Context(), storage.CreateProviderParams{
 			GroupID:      groupID,
@@ -102,10 +356,11 @@ func CreateProviderHandler(queries storage.Querier) http.HandlerFunc {
 			ProviderType: pt,
 			ApiKey:       apiKey,
 			SmtpConfig:   smtpConfig,
+			CostModel:    costModel,
 			Enabled:      req.Enabled,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -125,7 +380,7 @@ func ListProvidersHandler(queries storage.Querier) http.HandlerFunc {
 
 		providers, err := queries.ListProvidersByGroupID(r.Context(), groupID)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -133,6 +388,7 @@ func ListProvidersHandler(queries storage.Querier) http.HandlerFunc {
 		for i, p := range providers {
 			result[i] = toProviderResponse(p)
 		}
+		withDailyCaps(r, queries, groupID, providers, result)
 
 		respondJSON(w, http.StatusOK, result)
 	}
@@ -150,17 +406,26 @@ func GetProviderHandler(queries storage.Querier) http.HandlerFunc {
 
 		provider, err := queries.GetProviderByID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "provider not found")
+			respondStorageError(w, err, http.StatusNotFound, "provider not found")
 			return
 		}
 
-		respondJSON(w, http.StatusOK, toProviderResponse(provider))
+		result := []providerResponse{toProviderResponse(provider)}
+		withDailyCaps(r, queries, provider.GroupID, []storage.EspProvider{provider}, result)
+		respondJSON(w, http.StatusOK, result[0])
 	}
 }
 
-// UpdateProviderHandler handles PUT /api/v1/providers/{id}.
-func UpdateProviderHandler(queries storage.Querier) http.HandlerFunc {
+// UpdateProviderHandler handles PUT /api/v1/providers/{id}. Requires group
+// admin+ role. A changed api_key or provider_type is verified with the ESP
+// first, unless skip_verification is set.
+func UpdateProviderHandler(queries storage.Querier, client provider.HTTPClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
 		idStr := chi.URLParam(r, "id")
 		id, err := uuid.Parse(idStr)
 		if err != nil {
@@ -192,6 +457,37 @@ func UpdateProviderHandler(queries storage.Querier) http.HandlerFunc {
 		if len(req.SMTPConfig) > 0 {
 			smtpConfig = req.SMTPConfig
 		}
+		if err := validateSMTPConfig(pt, smtpConfig); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid smtp_config: "+err.Error())
+			return
+		}
+
+		costModel, err := normalizeCostModel(req.CostModel)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid cost_model: "+err.Error())
+			return
+		}
+
+		if apiKey.String != "" && !req.SkipVerification {
+			existing, err := queries.GetProviderByID(r.Context(), id)
+			if err != nil {
+				respondStorageError(w, err, http.StatusNotFound, "provider not found")
+				return
+			}
+			if existing.ApiKey != apiKey || existing.ProviderType != pt {
+				status, err := verifyProviderCredentials(r.Context(), client, storage.EspProvider{
+					ID:           id,
+					Name:         req.Name,
+					ProviderType: pt,
+					ApiKey:       apiKey,
+					SmtpConfig:   smtpConfig,
+				})
+				if err != nil {
+					respondError(w, status, err.Error())
+					return
+				}
+			}
+		}
 
 		provider, err := queries.UpdateProvider(r.Context(), storage.UpdateProviderParams{
 			ID:           id,
@@ -199,10 +495,11 @@ func UpdateProviderHandler(queries storage.Querier) http.HandlerFunc {
 			ProviderType: pt,
 			ApiKey:       apiKey,
 			SmtpConfig:   smtpConfig,
+			CostModel:    costModel,
 			Enabled:      req.Enabled,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -211,8 +508,14 @@ func UpdateProviderHandler(queries storage.Querier) http.HandlerFunc {
 }
 
 // DeleteProviderHandler handles DELETE /api/v1/providers/{id}.
+// Requires group admin+ role.
 func DeleteProviderHandler(queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
 		idStr := chi.URLParam(r, "id")
 		id, err := uuid.Parse(idStr)
 		if err != nil {
@@ -228,3 +531,66 @@ func DeleteProviderHandler(queries storage.Querier) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// GetProviderHealthHandler handles GET /api/v1/providers/{id}/health.
+// Returns the provider's current health and its most recent health checks.
+// Supports query param: limit (default 20, max 100).
+func GetProviderHealthHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid provider ID format")
+			return
+		}
+
+		provider, err := queries.GetProviderByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "provider not found")
+			return
+		}
+
+		// Verify access
+		if !canAccessGroup(r.Context(), queries, provider.GroupID) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		limit := int32(20)
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil && v > 0 {
+				limit = int32(v)
+			}
+		}
+		if limit > 100 {
+			limit = 100
+		}
+
+		checks, err := queries.ListProviderHealthChecks(r.Context(), storage.ListProviderHealthChecksParams{
+			ProviderID: id,
+			Limit:      limit,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		history := make([]providerHealthCheckResponse, len(checks))
+		for i, c := range checks {
+			history[i] = providerHealthCheckResponse{
+				Healthy:   c.Healthy,
+				LatencyMs: c.LatencyMs,
+				CheckedAt: timestampToTime(c.CheckedAt).Format("2006-01-02T15:04:05Z07:00"),
+			}
+			if c.Error.Valid {
+				history[i].Error = &c.Error.String
+			}
+		}
+
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"provider_id": provider.ID,
+			"health":      toProviderHealth(provider),
+			"history":     history,
+		})
+	}
+}
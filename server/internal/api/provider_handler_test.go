@@ -2,14 +2,18 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -37,12 +41,16 @@ func TestCreateProviderHandler_Valid(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateProviderHandler(mock)
+	client := &fakeProviderClient{status: http.StatusOK}
+	handler := CreateProviderHandler(mock, client)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusCreated {
 		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
 	}
+	if len(client.requests) != 1 || client.requests[0].Headers["Authorization"] != "Bearer sg-key" {
+		t.Errorf("expected the api key to be verified, got requests %+v", client.requests)
+	}
 
 	var resp providerResponse
 	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
@@ -68,7 +76,7 @@ func TestCreateProviderHandler_InvalidType(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateProviderHandler(mock)
+	handler := CreateProviderHandler(mock, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -113,6 +121,44 @@ func TestListProvidersHandler_FilteredByGroup(t *testing.T) {
 	}
 }
 
+func TestListProvidersHandler_DailyCap(t *testing.T) {
+	groupID := testGroup().ID
+	capped := testProvider()
+	capped.SmtpConfig = []byte(`{"daily_cap":500,"daily_cap_timezone":"Asia/Seoul"}`)
+	uncapped := testProvider()
+	uncapped.ID = uuid.New()
+
+	mock := &mockQuerier{
+		listProvidersByGroupFn: func(ctx context.Context, gID uuid.UUID) ([]storage.EspProvider, error) {
+			return []storage.EspProvider{capped, uncapped}, nil
+		},
+		listProviderDailySendsFn: func(ctx context.Context, arg storage.ListProviderDailySendsByGroupIDParams) ([]storage.ProviderDailySend, error) {
+			day := provider.StorageDailyCap(&capped).Day(time.Now())
+			return []storage.ProviderDailySend{{ProviderID: capped.ID, Day: day, Sent: 500}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/providers", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, groupID, "admin", "organization"))
+	rec := httptest.NewRecorder()
+	ListProvidersHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp []providerResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	dc := resp[0].DailyCap
+	if dc == nil || dc.Limit != 500 || dc.Sent != 500 || !dc.Reached || dc.Timezone != "Asia/Seoul" || !strings.HasSuffix(dc.ResetsAt, "+09:00") {
+		t.Errorf("daily_cap = %+v, want a reached cap of 500 resetting at midnight in Seoul", dc)
+	}
+	if resp[1].DailyCap != nil {
+		t.Errorf("expected no daily_cap for an uncapped provider, got %+v", resp[1].DailyCap)
+	}
+}
+
 func TestGetProviderHandler_Found(t *testing.T) {
 	prov := testProvider()
 	mock := &mockQuerier{
@@ -170,7 +216,7 @@ func TestUpdateProviderHandler(t *testing.T) {
 	}
 
 	body := `{"name":"updated-provider","provider_type":"mailgun","enabled":false}`
-	req := httptest.NewRequest(http.MethodPut, "/api/v1/providers/"+prov.ID.String(), strings.NewReader(body))
+	req := shadowRequest(http.MethodPut, "/api/v1/providers/"+prov.ID.String(), body, "admin")
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
@@ -178,7 +224,7 @@ func TestUpdateProviderHandler(t *testing.T) {
 	rctx.URLParams.Add("id", prov.ID.String())
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
-	handler := UpdateProviderHandler(mock)
+	handler := UpdateProviderHandler(mock, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusOK {
@@ -196,7 +242,7 @@ func TestDeleteProviderHandler(t *testing.T) {
 		},
 	}
 
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/providers/"+id.String(), nil)
+	req := shadowRequest(http.MethodDelete, "/api/v1/providers/"+id.String(), "", "admin")
 	rec := httptest.NewRecorder()
 
 	rctx := chi.NewRouteContext()
@@ -213,3 +259,434 @@ func TestDeleteProviderHandler(t *testing.T) {
 		t.Error("expected delete to be called")
 	}
 }
+
+func TestGetProviderHandler_IncludesHealth(t *testing.T) {
+	prov := testProvider()
+	prov.Enabled = false
+	prov.HealthStatus = "unhealthy"
+	prov.ConsecutiveFailures = 5
+	prov.LastHealthError = pgtype.Text{String: "sendgrid: 401", Valid: true}
+	prov.AutoDisabledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/providers/"+prov.ID.String(), nil)
+	rec := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", prov.ID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	GetProviderHandler(mock).ServeHTTP(rec, req)
+
+	var resp providerResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Health.Status != "unhealthy" || resp.Health.ConsecutiveFailures != 5 {
+		t.Errorf("unexpected health: %+v", resp.Health)
+	}
+	if !resp.Health.AutoDisabled || resp.Health.AutoDisabledAt == nil {
+		t.Error("expected provider to be reported as auto-disabled")
+	}
+	if resp.Health.LastError == nil || *resp.Health.LastError != "sendgrid: 401" {
+		t.Errorf("expected last_error, got %v", resp.Health.LastError)
+	}
+}
+
+func TestGetProviderHealthHandler_ReturnsHistory(t *testing.T) {
+	prov := testProvider()
+	prov.HealthStatus = "healthy"
+
+	var gotLimit int32
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+		listProviderHealthChecksFn: func(ctx context.Context, arg storage.ListProviderHealthChecksParams) ([]storage.ProviderHealthCheck, error) {
+			gotLimit = arg.Limit
+			return []storage.ProviderHealthCheck{
+				{ProviderID: prov.ID, Healthy: true, LatencyMs: 42, CheckedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+				{ProviderID: prov.ID, Healthy: false, LatencyMs: 10000, Error: pgtype.Text{String: "timeout", Valid: true}, CheckedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/providers/"+prov.ID.String()+"/health?limit=500", nil)
+	ctx := setJWTContext(req.Context(), testUser().ID, prov.GroupID, "member", "organization")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", prov.ID.String())
+	req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+
+	GetProviderHealthHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if gotLimit != 100 {
+		t.Errorf("expected limit capped at 100, got %d", gotLimit)
+	}
+
+	var resp struct {
+		Health  providerHealth                `json:"health"`
+		History []providerHealthCheckResponse `json:"history"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Health.Status != "healthy" {
+		t.Errorf("expected status healthy, got %s", resp.Health.Status)
+	}
+	if len(resp.History) != 2 || resp.History[1].Error == nil {
+		t.Errorf("unexpected history: %+v", resp.History)
+	}
+}
+
+func TestGetProviderHealthHandler_OtherGroupForbidden(t *testing.T) {
+	prov := testProvider()
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/providers/"+prov.ID.String()+"/health", nil)
+	ctx := setJWTContext(req.Context(), testUser().ID, uuid.New(), "admin", "organization")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", prov.ID.String())
+	req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+
+	GetProviderHealthHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCreateProviderHandler_CostModel(t *testing.T) {
+	groupID := testGroup().ID
+
+	var stored []byte
+	mock := &mockQuerier{
+		createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+			stored = arg.CostModel
+			prov := testProvider()
+			prov.CostModel = arg.CostModel
+			return prov, nil
+		},
+	}
+
+	body := `{"name":"ses","provider_type":"ses","enabled":true,"cost_model":{"tiers":[{"up_to":1000,"price_per_1k":1},{"price_per_1k":0.5}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/providers", strings.NewReader(body))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, groupID, "admin", "organization"))
+	rec := httptest.NewRecorder()
+
+	CreateProviderHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(string(stored), `"currency":"USD"`) {
+		t.Errorf("expected stored cost model with default currency, got %s", stored)
+	}
+
+	var resp providerResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.CostModel == nil || len(resp.CostModel.Tiers) != 2 {
+		t.Errorf("unexpected cost_model in response: %+v", resp.CostModel)
+	}
+}
+
+func TestCreateProviderHandler_InvalidCostModel(t *testing.T) {
+	mock := &mockQuerier{
+		createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+			t.Error("CreateProvider should not be called for an invalid cost model")
+			return storage.EspProvider{}, nil
+		},
+	}
+
+	body := `{"name":"ses","provider_type":"ses","cost_model":{"tiers":[]}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/providers", strings.NewReader(body))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+	rec := httptest.NewRecorder()
+
+	CreateProviderHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestCreateProviderHandler_Plugin(t *testing.T) {
+	var created storage.CreateProviderParams
+	mock := &mockQuerier{
+		createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+			created = arg
+			return testProvider(), nil
+		},
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"named plugin", `{"name":"hooks","provider_type":"plugin","smtp_config":{"plugin":"webhook","options":{"url":"https://hooks.example.com"}}}`, http.StatusCreated},
+		{"missing plugin", `{"name":"hooks","provider_type":"plugin","smtp_config":{"options":{"url":"https://hooks.example.com"}}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/providers", strings.NewReader(tt.body))
+			req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+			rec := httptest.NewRecorder()
+
+			CreateProviderHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+	if created.ProviderType != storage.ProviderTypePlugin {
+		t.Errorf("expected provider type plugin, got %s", created.ProviderType)
+	}
+}
+
+func TestCreateProviderHandler_InvalidProxyURL(t *testing.T) {
+	mock := &mockQuerier{
+		createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+			t.Error("CreateProvider should not be called for an invalid proxy url")
+			return storage.EspProvider{}, nil
+		},
+	}
+
+	body := `{"name":"sg","provider_type":"sendgrid","smtp_config":{"proxy_url":"ftp://proxy.internal"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/providers", strings.NewReader(body))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+	rec := httptest.NewRecorder()
+
+	CreateProviderHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestCreateProviderHandler_SMTPConfigValidation(t *testing.T) {
+	mock := &mockQuerier{
+		createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+			return testProvider(), nil
+		},
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"mailgun eu", `{"name":"mg","provider_type":"mailgun","smtp_config":{"domain":"mg.example.com","region":"eu"}}`, http.StatusCreated},
+		{"unknown mailgun region", `{"name":"mg","provider_type":"mailgun","smtp_config":{"domain":"mg.example.com","region":"asia"}}`, http.StatusBadRequest},
+		{"bad ses region", `{"name":"ses","provider_type":"ses","smtp_config":{"region":"Virginia"}}`, http.StatusBadRequest},
+		{"self-hosted endpoint", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"endpoint":"https://mail-api.internal/sendgrid"}}`, http.StatusCreated},
+		{"relative endpoint", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"endpoint":"mail-api.internal"}}`, http.StatusBadRequest},
+		{"debug capture", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"debug_capture":20}}`, http.StatusCreated},
+		{"debug capture too large", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"debug_capture":1000}}`, http.StatusBadRequest},
+		{"domain limits", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"domain_limits":[{"domains":["gmail.com"],"per_minute":10}]}}`, http.StatusCreated},
+		{"daily cap", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"daily_cap":1000,"daily_cap_timezone":"Europe/Berlin"}}`, http.StatusCreated},
+		{"daily cap unknown timezone", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"daily_cap":1000,"daily_cap_timezone":"Mars/Base"}}`, http.StatusBadRequest},
+		{"domain limit without a limit", `{"name":"sg","provider_type":"sendgrid","smtp_config":{"domain_limits":[{"domains":["gmail.com"]}]}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/providers", strings.NewReader(tt.body))
+			req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+			rec := httptest.NewRecorder()
+
+			CreateProviderHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d; body: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// fakeProviderClient answers every ESP API call with status and body.
+type fakeProviderClient struct {
+	status   int
+	body     string
+	requests []*provider.HTTPRequest
+}
+
+func (f *fakeProviderClient) Do(req *provider.HTTPRequest) (*provider.HTTPResponse, error) {
+	f.requests = append(f.requests, req)
+	return &provider.HTTPResponse{StatusCode: f.status, Body: []byte(f.body)}, nil
+}
+
+func TestCreateProviderHandler_VerifiesCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		status     int
+		want       int
+		wantError  string
+		wantChecks int
+	}{
+		{"valid key", `{"name":"mg","provider_type":"mailgun","api_key":"key-1","smtp_config":{"domain":"mg.example.com"}}`, http.StatusOK, http.StatusCreated, "", 1},
+		{"rejected key", `{"name":"mg","provider_type":"mailgun","api_key":"key-1","smtp_config":{"domain":"mg.example.com"}}`, http.StatusUnauthorized, http.StatusUnprocessableEntity, "provider rejected the credentials", 1},
+		{"esp unavailable", `{"name":"mg","provider_type":"mailgun","api_key":"key-1","smtp_config":{"domain":"mg.example.com"}}`, http.StatusServiceUnavailable, http.StatusBadGateway, "could not verify provider credentials", 1},
+		{"incomplete config", `{"name":"mg","provider_type":"mailgun","api_key":"key-1"}`, http.StatusOK, http.StatusBadRequest, "domain", 0},
+		{"skip verification", `{"name":"mg","provider_type":"mailgun","api_key":"key-1","smtp_config":{"domain":"mg.example.com"},"skip_verification":true}`, http.StatusUnauthorized, http.StatusCreated, "", 0},
+		{"plugin", `{"name":"hooks","provider_type":"plugin","api_key":"key-1","smtp_config":{"plugin":"webhook"}}`, http.StatusUnauthorized, http.StatusCreated, "", 0},
+		{"loopback endpoint", `{"name":"sg","provider_type":"sendgrid","api_key":"key-1","smtp_config":{"endpoint":"http://127.0.0.1:8080"}}`, http.StatusOK, http.StatusBadRequest, "public addresses", 0},
+		{"metadata endpoint", `{"name":"sg","provider_type":"sendgrid","api_key":"key-1","smtp_config":{"endpoint":"http://169.254.169.254/latest"}}`, http.StatusOK, http.StatusBadRequest, "public addresses", 0},
+		{"private proxy", `{"name":"mg","provider_type":"mailgun","api_key":"key-1","smtp_config":{"domain":"mg.example.com","proxy_url":"http://10.0.0.1:3128"}}`, http.StatusOK, http.StatusBadRequest, "public addresses", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			mock := &mockQuerier{
+				createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+					created = true
+					return testProvider(), nil
+				},
+			}
+			client := &fakeProviderClient{status: tt.status}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/providers", strings.NewReader(tt.body))
+			req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+			rec := httptest.NewRecorder()
+
+			CreateProviderHandler(mock, client).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("expected error %q, got %s", tt.wantError, rec.Body.String())
+			}
+			if created != (tt.want == http.StatusCreated) {
+				t.Errorf("provider created = %v", created)
+			}
+			if len(client.requests) != tt.wantChecks {
+				t.Errorf("expected %d ESP requests, got %d", tt.wantChecks, len(client.requests))
+			}
+		})
+	}
+}
+
+func TestUpdateProviderHandler_VerifiesChangedKey(t *testing.T) {
+	prov := testProvider()
+	prov.ProviderType = storage.ProviderTypeSendgrid
+	prov.ApiKey = sql.NullString{String: "old-key", Valid: true}
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   int
+		checks int
+	}{
+		{"unchanged key", "old-key", http.StatusOK, 0},
+		{"changed key", "new-key", http.StatusUnprocessableEntity, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+					return prov, nil
+				},
+				updateProviderFn: func(ctx context.Context, arg storage.UpdateProviderParams) (storage.EspProvider, error) {
+					return prov, nil
+				},
+			}
+			client := &fakeProviderClient{status: http.StatusUnauthorized, body: `{"errors":[{"message":"authorization required"}]}`}
+			body := `{"name":"sg","provider_type":"sendgrid","api_key":"` + tt.apiKey + `","enabled":true}`
+			req := shadowRequest(http.MethodPut, "/api/v1/providers/"+prov.ID.String(), body, "admin")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", prov.ID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			UpdateProviderHandler(mock, client).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if len(client.requests) != tt.checks {
+				t.Errorf("expected %d ESP requests, got %d", tt.checks, len(client.requests))
+			}
+		})
+	}
+}
+
+func TestCreateProviderHandler_HidesESPResponse(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusServiceUnavailable} {
+		client := &fakeProviderClient{status: status, body: `{"errors":[{"message":"internal-secret-token"}]}`}
+		body := `{"name":"sg","provider_type":"sendgrid","api_key":"key-1","smtp_config":{"endpoint":"https://esp.example.com"}}`
+		rec := httptest.NewRecorder()
+		CreateProviderHandler(&mockQuerier{}, client).ServeHTTP(rec, shadowRequest(http.MethodPost, "/api/v1/providers", body, "admin"))
+
+		if rec.Code != http.StatusUnprocessableEntity && rec.Code != http.StatusBadGateway {
+			t.Fatalf("expected status 422 or 502, got %d; body: %s", rec.Code, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "internal-secret-token") || strings.Contains(rec.Body.String(), "esp.example.com") {
+			t.Errorf("error includes the ESP response: %s", rec.Body.String())
+		}
+	}
+}
+
+func TestProviderHandlers_RequireGroupAdmin(t *testing.T) {
+	id := uuid.New()
+	called := false
+	mock := &mockQuerier{
+		createProviderFn: func(ctx context.Context, arg storage.CreateProviderParams) (storage.EspProvider, error) {
+			called = true
+			return testProvider(), nil
+		},
+		updateProviderFn: func(ctx context.Context, arg storage.UpdateProviderParams) (storage.EspProvider, error) {
+			called = true
+			return testProvider(), nil
+		},
+		deleteProviderFn: func(ctx context.Context, delID uuid.UUID) error {
+			called = true
+			return nil
+		},
+	}
+	client := &fakeProviderClient{status: http.StatusOK}
+	body := `{"name":"sg","provider_type":"sendgrid","api_key":"key-1","enabled":true}`
+
+	tests := []struct {
+		name    string
+		method  string
+		handler http.Handler
+	}{
+		{"create", http.MethodPost, CreateProviderHandler(mock, client)},
+		{"update", http.MethodPut, UpdateProviderHandler(mock, client)},
+		{"delete", http.MethodDelete, DeleteProviderHandler(mock)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := shadowRequest(tt.method, "/api/v1/providers/"+id.String(), body, "member")
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if called || len(client.requests) != 0 {
+				t.Errorf("member reached the provider: stored = %v, ESP requests = %d", called, len(client.requests))
+			}
+		})
+	}
+}
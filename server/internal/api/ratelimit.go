@@ -0,0 +1,147 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/ratelimit"
+)
+
+// Endpoint classes with separate rate limits.
+const (
+	rateClassAuth     = "auth"
+	rateClassWebhooks = "webhooks"
+	rateClassDefault  = "default"
+)
+
+// RateLimitClass holds the limits of one endpoint class. A zero limit is
+// not enforced.
+type RateLimitClass struct {
+	// PerIP limits requests from one client address.
+	PerIP ratelimit.Limit
+	// PerKey limits requests carrying one Authorization credential (JWT
+	// or API key), wherever they come from.
+	PerKey ratelimit.Limit
+}
+
+// RateLimitConfig configures request rate limiting on the API.
+type RateLimitConfig struct {
+	// Bucket stores the token buckets. Nil disables rate limiting.
+	Bucket ratelimit.Bucket
+	// Auth applies to /api/v1/auth/ (login, refresh, logout).
+	Auth RateLimitClass
+	// Webhooks applies to /api/v1/webhooks/.
+	Webhooks RateLimitClass
+	// Default applies to every other /api/ route.
+	Default RateLimitClass
+	// TrustedProxies lists proxies whose X-Forwarded-For is used to find
+	// the client address. Without it the TCP peer is the client.
+	TrustedProxies []netip.Prefix
+}
+
+// class returns the endpoint class and limits of path, or false for routes
+// that are not rate limited.
+func (c RateLimitConfig) class(path string) (string, RateLimitClass, bool) {
+	switch {
+	case strings.HasPrefix(path, "/api/v1/auth/"):
+		return rateClassAuth, c.Auth, true
+	case strings.HasPrefix(path, "/api/v1/webhooks/"):
+		return rateClassWebhooks, c.Webhooks, true
+	case strings.HasPrefix(path, "/api/"):
+		return rateClassDefault, c.Default, true
+	}
+	return "", RateLimitClass{}, false
+}
+
+// RateLimitMiddleware limits /api/ requests per client address and per
+// credential using token buckets, with limits per endpoint class. Limited
+// requests get 429 with Retry-After. Bucket errors fail open so a Redis
+// outage does not take the API down.
+func RateLimitMiddleware(cfg RateLimitConfig, log zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if cfg.Bucket == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, class, ok := cfg.class(r.URL.Path)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			type check struct {
+				scope string
+				key   string
+				limit ratelimit.Limit
+			}
+			var checks []check
+			if class.PerIP.Enabled() {
+				if addr, ok := clientAddr(r, cfg.TrustedProxies); ok {
+					checks = append(checks, check{"ip", name + ":ip:" + addr.String(), class.PerIP})
+				}
+			}
+			if cred := r.Header.Get("Authorization"); cred != "" && class.PerKey.Enabled() {
+				sum := sha256.Sum256([]byte(cred))
+				checks = append(checks, check{"key", name + ":key:" + hex.EncodeToString(sum[:16]), class.PerKey})
+			}
+
+			for _, c := range checks {
+				allowed, wait, err := cfg.Bucket.Take(r.Context(), c.key, c.limit)
+				if err != nil {
+					log.Warn().Err(err).Str("class", name).Msg("rate limit check failed, allowing request")
+					continue
+				}
+				if !allowed {
+					metrics.APIRateLimitedTotal.WithLabelValues(name, c.scope).Inc()
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds())))))
+					respondErrorCode(w, http.StatusTooManyRequests, apierror.CodeRateLimited, "rate limit exceeded")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr returns the client's address: the TCP peer or, when the peer
+// is a trusted proxy, the last X-Forwarded-For entry that is not.
+func clientAddr(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	addr, ok := peerAddr(r)
+	if !ok || !inPrefixes(addr, trusted) {
+		return addr, ok
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Some proxies append host:port.
+			host, _, splitErr := net.SplitHostPort(strings.TrimSpace(hops[i]))
+			if hop, err = netip.ParseAddr(host); splitErr != nil || err != nil {
+				break
+			}
+		}
+		addr = hop.Unmap()
+		if !inPrefixes(addr, trusted) {
+			break
+		}
+	}
+	return addr, true
+}
+
+func inPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sungwon/smtp-proxy/server/internal/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := RateLimitConfig{
+		Bucket:  ratelimit.NewMemoryBucket(),
+		Auth:    RateLimitClass{PerIP: ratelimit.Limit{Rate: 0.001, Burst: 1}},
+		Default: RateLimitClass{PerKey: ratelimit.Limit{Rate: 0.001, Burst: 2}},
+	}
+	handler := RateLimitMiddleware(cfg, zerolog.Nop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(path, remoteAddr, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = remoteAddr
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Auth endpoints: one request per IP.
+	if rec := do("/api/v1/auth/login", "192.0.2.1:1000", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected first login to pass, got %d", rec.Code)
+	}
+	rec := do("/api/v1/auth/login", "192.0.2.1:1001", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+	if rec := do("/api/v1/auth/login", "192.0.2.2:1000", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected other IP to pass, got %d", rec.Code)
+	}
+
+	// Default endpoints: two requests per key, from any address.
+	for i, addr := range []string{"192.0.2.1:1", "198.51.100.1:1"} {
+		if rec := do("/api/v1/messages", addr, "Bearer key-a"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	if rec := do("/api/v1/messages", "203.0.113.1:1", "Bearer key-a"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected key limit to apply across addresses, got %d", rec.Code)
+	}
+	if rec := do("/api/v1/messages", "203.0.113.1:1", "Bearer key-b"); rec.Code != http.StatusOK {
+		t.Errorf("expected other key to pass, got %d", rec.Code)
+	}
+
+	// Health checks are never limited.
+	for i := 0; i < 3; i++ {
+		if rec := do("/healthz", "192.0.2.1:1", ""); rec.Code != http.StatusOK {
+			t.Fatalf("expected /healthz to pass, got %d", rec.Code)
+		}
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"peer", "192.0.2.1:1234", "", "192.0.2.1"},
+		{"untrusted peer ignores xff", "192.0.2.1:1234", "198.51.100.1", "192.0.2.1"},
+		{"trusted proxy", "10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"proxy chain", "10.0.0.1:1234", "203.0.113.9, 198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"no xff", "10.0.0.1:1234", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			got, ok := clientAddr(req, trusted)
+			if !ok || got.String() != tt.want {
+				t.Errorf("expected %s, got %s (ok=%v)", tt.want, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+)
+
+// ReadOnlyMiddleware answers requests other than GET, HEAD and OPTIONS
+// with 503 when enabled. The API server runs read-only when the database
+// schema was migrated by a newer release, so it keeps serving reads during
+// a rollout without writing rows the new schema does not expect.
+func ReadOnlyMiddleware(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", "30")
+			respondErrorCode(w, http.StatusServiceUnavailable, apierror.CodeUnavailable, "read-only until this server is upgraded to the database schema version")
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		enabled  bool
+		method   string
+		wantCode int
+	}{
+		{false, http.MethodPost, http.StatusOK},
+		{true, http.MethodGet, http.StatusOK},
+		{true, http.MethodOptions, http.StatusOK},
+		{true, http.MethodPost, http.StatusServiceUnavailable},
+		{true, http.MethodDelete, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		ReadOnlyMiddleware(tt.enabled)(ok).ServeHTTP(rec, httptest.NewRequest(tt.method, "/api/v1/users", nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("enabled=%v %s: status %d, want %d", tt.enabled, tt.method, rec.Code, tt.wantCode)
+		}
+		if tt.wantCode == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: no Retry-After header", tt.method)
+		}
+	}
+}
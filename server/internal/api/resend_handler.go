@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Defaults for Resend fields that are not set.
+const (
+	DefaultResendRate        = 10
+	DefaultResendMaxMessages = 1000
+)
+
+// maxResendWindow bounds the time range of one resend.
+const maxResendWindow = 31 * 24 * time.Hour
+
+// Resend configures bulk resends of failed messages. Resent messages are
+// released to the queue at Rate messages per second so that a recovering
+// provider is not hit by the whole backlog at once.
+type Resend struct {
+	// Rate is how many resent messages are released per second. Defaults
+	// to DefaultResendRate.
+	Rate int
+	// MaxMessages caps the messages resent by one request; the rest are
+	// reported as remaining. Defaults to DefaultResendMaxMessages.
+	MaxMessages int
+}
+
+func (c Resend) rate() int {
+	if c.Rate <= 0 {
+		return DefaultResendRate
+	}
+	return c.Rate
+}
+
+func (c Resend) maxMessages() int {
+	if c.MaxMessages <= 0 {
+		return DefaultResendMaxMessages
+	}
+	return c.MaxMessages
+}
+
+// resendRequest is the JSON body of a bulk resend.
+type resendRequest struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// DryRun only counts the messages that would be resent.
+	DryRun bool `json:"dry_run"`
+}
+
+// resendResponse is the JSON response of a bulk resend. Matched counts the
+// failed messages in the window, Resent those re-enqueued by this request
+// and Remaining those left for a later request.
+type resendResponse struct {
+	GroupID     uuid.UUID  `json:"group_id"`
+	Since       time.Time  `json:"since"`
+	Until       time.Time  `json:"until"`
+	DryRun      bool       `json:"dry_run"`
+	Matched     int64      `json:"matched"`
+	Resent      int64      `json:"resent"`
+	Remaining   int64      `json:"remaining"`
+	Rate        int        `json:"rate"`
+	CompletesAt *time.Time `json:"completes_at,omitempty"`
+}
+
+// ResendMessagesHandler handles POST /api/v1/groups/{id}/messages/resend.
+// Re-enqueues the group's failed messages enqueued within [since, until),
+// e.g. after a provider outage. Messages that bounced or drew a complaint
+// are never resent, nor are messages whose body has been purged. With
+// dry_run the matching messages are only counted. Resent messages go
+// through the outbox, staggered to the configured rate, and the resend is
+// audit logged. Requires group admin+ role. Repeating the call is safe:
+// resent messages are no longer failed.
+func ResendMessagesHandler(queries storage.Querier, tx storage.TxRunner, resend Resend, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := groupAdminParam(w, r, queries)
+		if !ok {
+			return
+		}
+		var req resendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		var errs []string
+		if req.Since.IsZero() {
+			errs = append(errs, "since is required")
+		}
+		if req.Until.IsZero() {
+			errs = append(errs, "until is required")
+		}
+		if len(errs) == 0 {
+			if !req.Since.Before(req.Until) {
+				errs = append(errs, "since must be before until")
+			} else if req.Until.Sub(req.Since) > maxResendWindow {
+				errs = append(errs, "time range must not exceed 31 days")
+			}
+		}
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		ctx := r.Context()
+		window := storage.CountResendableMessagesParams{
+			GroupID: pgtype.UUID{Bytes: groupID, Valid: true},
+			Since:   pgtype.Timestamptz{Time: req.Since, Valid: true},
+			Until:   pgtype.Timestamptz{Time: req.Until, Valid: true},
+		}
+		matched, err := queries.CountResendableMessages(ctx, window)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "failed to count messages")
+			return
+		}
+		resp := resendResponse{
+			GroupID: groupID,
+			Since:   req.Since,
+			Until:   req.Until,
+			DryRun:  req.DryRun,
+			Matched: matched,
+			Rate:    resend.rate(),
+		}
+		if req.DryRun || matched == 0 {
+			resp.Remaining = matched
+			respondJSON(w, http.StatusOK, resp)
+			return
+		}
+
+		// Entries are released one per 1/rate seconds from now on; the
+		// outbox relay skips them until their claimed_until has passed.
+		start := time.Now()
+		step := time.Second / time.Duration(resp.Rate)
+		err = tx.ExecTx(ctx, func(q storage.Querier) error {
+			rows, err := q.ListResendableMessages(ctx, storage.ListResendableMessagesParams{
+				GroupID:    window.GroupID,
+				Since:      window.Since,
+				Until:      window.Until,
+				MaxResults: int32(resend.maxMessages()),
+			})
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				n, err := q.ResendMessage(ctx, row.ID)
+				if err != nil {
+					return err
+				}
+				if n == 0 {
+					continue
+				}
+				if _, err := q.CreateDelayedOutboxEntry(ctx, storage.CreateDelayedOutboxEntryParams{
+					MessageID:    row.ID,
+					GroupID:      uuid.UUID(row.GroupID.Bytes),
+					UserID:       uuid.UUID(row.UserID.Bytes),
+					RequestID:    row.RequestID,
+					ClaimedUntil: pgtype.Timestamptz{Time: start.Add(time.Duration(resp.Resent) * step), Valid: true},
+				}); err != nil {
+					return err
+				}
+				resp.Resent++
+			}
+			return nil
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "failed to resend messages")
+			return
+		}
+		resp.Remaining = max(matched-resp.Resent, 0)
+		if resp.Resent > 0 {
+			completes := start.Add(time.Duration(resp.Resent-1) * step).UTC()
+			resp.CompletesAt = &completes
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(ctx, r, auth.AuditActionResendMessages, "group", groupID.String(), map[string]interface{}{
+				"since":     req.Since,
+				"until":     req.Until,
+				"matched":   matched,
+				"resent":    resp.Resent,
+				"remaining": resp.Remaining,
+			})
+		}
+		respondJSON(w, http.StatusAccepted, resp)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func resendHTTPRequest(body, role string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/groups/"+testGroup().ID.String()+"/messages/resend", strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", testGroup().ID.String())
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "company")
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+const resendWindow = `"since":"2026-10-01T00:00:00Z","until":"2026-10-02T00:00:00Z"`
+
+func TestResendMessagesHandler_DryRun(t *testing.T) {
+	mock := &mockQuerier{
+		countResendableMessagesFn: func(ctx context.Context, arg storage.CountResendableMessagesParams) (int64, error) {
+			if arg.GroupID.Bytes != testGroup().ID || !arg.Until.Time.Equal(time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("unexpected count params: %+v", arg)
+			}
+			return 42, nil
+		},
+		resendMessageFn: func(ctx context.Context, id uuid.UUID) (int64, error) {
+			t.Error("dry run must not resend")
+			return 0, nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	ResendMessagesHandler(mock, directTx{mock}, Resend{}, nil).ServeHTTP(rec,
+		resendHTTPRequest(`{`+resendWindow+`,"dry_run":true}`, "admin"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp resendResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.DryRun || resp.Matched != 42 || resp.Resent != 0 || resp.Remaining != 42 || resp.Rate != DefaultResendRate {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestResendMessagesHandler(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	var entries []storage.CreateDelayedOutboxEntryParams
+	mock := &mockQuerier{
+		countResendableMessagesFn: func(ctx context.Context, arg storage.CountResendableMessagesParams) (int64, error) {
+			return 5, nil
+		},
+		listResendableMessagesFn: func(ctx context.Context, arg storage.ListResendableMessagesParams) ([]storage.ListResendableMessagesRow, error) {
+			if arg.MaxResults != 3 {
+				t.Errorf("expected limit 3, got %d", arg.MaxResults)
+			}
+			var rows []storage.ListResendableMessagesRow
+			for _, id := range ids {
+				rows = append(rows, storage.ListResendableMessagesRow{
+					ID:      id,
+					GroupID: pgtype.UUID{Bytes: testGroup().ID, Valid: true},
+					UserID:  pgtype.UUID{Bytes: testUser().ID, Valid: true},
+				})
+			}
+			return rows, nil
+		},
+		resendMessageFn: func(ctx context.Context, id uuid.UUID) (int64, error) {
+			// The second message was resent concurrently.
+			if id == ids[1] {
+				return 0, nil
+			}
+			return 1, nil
+		},
+		createDelayedOutboxEntryFn: func(ctx context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+			entries = append(entries, arg)
+			return storage.OutboxEntry{}, nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	ResendMessagesHandler(mock, directTx{mock}, Resend{Rate: 2, MaxMessages: 3}, nil).ServeHTTP(rec,
+		resendHTTPRequest(`{`+resendWindow+`}`, "admin"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp resendResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Matched != 5 || resp.Resent != 2 || resp.Remaining != 3 || resp.CompletesAt == nil {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(entries) != 2 || entries[0].MessageID != ids[0] || entries[1].MessageID != ids[2] {
+		t.Fatalf("unexpected outbox entries: %+v", entries)
+	}
+	if gap := entries[1].ClaimedUntil.Time.Sub(entries[0].ClaimedUntil.Time); gap != 500*time.Millisecond {
+		t.Errorf("expected entries 500ms apart at rate 2, got %v", gap)
+	}
+	if entries[0].UserID != testUser().ID || entries[0].GroupID != testGroup().ID {
+		t.Errorf("unexpected outbox entry: %+v", entries[0])
+	}
+}
+
+func TestResendMessagesHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		role string
+		code int
+	}{
+		{"member caller", `{` + resendWindow + `}`, "member", http.StatusForbidden},
+		{"invalid body", `{`, "admin", http.StatusBadRequest},
+		{"missing window", `{}`, "admin", http.StatusBadRequest},
+		{"reversed window", `{"since":"2026-10-02T00:00:00Z","until":"2026-10-01T00:00:00Z"}`, "admin", http.StatusBadRequest},
+		{"window too long", `{"since":"2026-08-01T00:00:00Z","until":"2026-10-01T00:00:00Z"}`, "admin", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				countResendableMessagesFn: func(ctx context.Context, arg storage.CountResendableMessagesParams) (int64, error) {
+					t.Error("messages must not be counted")
+					return 0, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			ResendMessagesHandler(mock, directTx{mock}, Resend{}, nil).ServeHTTP(rec, resendHTTPRequest(tt.body, tt.role))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
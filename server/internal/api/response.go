@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
 )
 
 // respondJSON writes a JSON response with the given status code and data.
@@ -15,15 +17,41 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-// respondError writes a JSON error response with the given status code and message.
+// respondError writes an error envelope with the given status code and
+// message. The error code is derived from the status.
 func respondError(w http.ResponseWriter, status int, message string) {
-	respondJSON(w, status, map[string]string{"error": message})
+	apierror.Write(w, status, "", message, nil)
+}
+
+// respondErrorCode writes an error envelope with a specific error code.
+func respondErrorCode(w http.ResponseWriter, status int, code, message string) {
+	apierror.Write(w, status, code, message, nil)
+}
+
+// respondStorageError writes the error envelope for a failed storage call:
+// recognized errors (no rows, constraint violations, database unavailable,
+// provider errors) get their own status and code, anything else gets
+// status and message.
+func respondStorageError(w http.ResponseWriter, err error, status int, message string) {
+	s, code, ok := apierror.Classify(err)
+	if !ok {
+		respondError(w, status, message)
+		return
+	}
+	switch {
+	case code == apierror.CodeDatabaseUnavailable:
+		message = "database unavailable"
+	case code == apierror.CodeTimeout:
+		message = "request timed out"
+	case code == apierror.CodeProviderError:
+		message = "provider error: " + err.Error()
+	case s != status:
+		message = http.StatusText(s)
+	}
+	apierror.Write(w, s, code, message, nil)
 }
 
 // respondValidationErrors writes a 400 response with a list of validation error details.
 func respondValidationErrors(w http.ResponseWriter, errors []string) {
-	respondJSON(w, http.StatusBadRequest, map[string]interface{}{
-		"error":   "validation_failed",
-		"details": errors,
-	})
+	apierror.Write(w, http.StatusBadRequest, apierror.CodeValidationFailed, "request validation failed", errors)
 }
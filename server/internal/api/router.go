@@ -1,11 +1,18 @@
 package api
 
 import (
+	"time"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/preview"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
 )
 
 // RouterConfig holds dependencies for the router.
@@ -17,6 +24,50 @@ type RouterConfig struct {
 	JWTService  *auth.JWTService
 	AuditLogger *auth.AuditLogger
 	RateLimiter *auth.RateLimiter
+	// LoginSecurity configures unlock links and login anomaly detection.
+	LoginSecurity LoginSecurity
+	// Invitations configures how group invitations are delivered.
+	Invitations Invitations
+	// Signup configures self-service signup, which is enabled when its
+	// Mailer is set.
+	Signup Signup
+	// Resend paces bulk resends of failed messages.
+	Resend Resend
+	// DeliveryReports configures download URLs of delivery reports, which
+	// are served from MessageStore.
+	DeliveryReports DeliveryReports
+	// MessageStore, when set, lets previews load bodies of stored messages
+	// and delivery log lookups read the log archive.
+	MessageStore msgstore.MessageStore
+	// RenderTester, when set, enables rendering tests from previews.
+	RenderTester preview.RenderTester
+	// Validator checks addresses for /api/v1/validate. When nil, lookups
+	// use the system DNS resolver.
+	Validator *validation.Validator
+	// SenderResolver looks up sender identity verification records. When
+	// nil, the system DNS resolver is used.
+	SenderResolver senderpolicy.Resolver
+	// AccessRules restrict route groups by client address and
+	// certificate. See AccessControlMiddleware.
+	AccessRules []AccessRule
+	// RequestRateLimit limits API requests per client address and
+	// credential. See RateLimitMiddleware.
+	RequestRateLimit RateLimitConfig
+	// CORS configures cross-origin access for browser clients.
+	CORS CORSConfig
+	// CSRF protects cookie-authenticated browser sessions.
+	CSRF CSRFConfig
+	// PasswordPolicy is enforced when passwords are set. When nil, any
+	// password is accepted.
+	PasswordPolicy *auth.PasswordPolicy
+	// ProviderClient makes the ESP API calls of provider setup endpoints.
+	// When nil, a default client that refuses non-public addresses is used.
+	ProviderClient provider.HTTPClient
+	// ReadOnly rejects requests that may write. See ReadOnlyMiddleware.
+	ReadOnly bool
+	// LegacyAPI serves the account and tenant endpoints of the old API
+	// server. See registerLegacyRoutes.
+	LegacyAPI bool
 }
 
 // NewRouterWithConfig creates a chi.Mux with all routes using the full RouterConfig.
@@ -26,10 +77,25 @@ type RouterConfig struct {
 func NewRouterWithConfig(cfg RouterConfig) *chi.Mux {
 	r := chi.NewRouter()
 
+	validator := cfg.Validator
+	if validator == nil {
+		validator = validation.New(nil)
+	}
+	providerClient := cfg.ProviderClient
+	if providerClient == nil {
+		providerClient = provider.NewHTTPClientWithDialer(30*time.Second, provider.PublicDialer(10*time.Second))
+	}
+
 	// Global middleware
 	r.Use(CorrelationIDMiddleware)
+	r.Use(LocaleMiddleware)
 	r.Use(LoggingMiddleware(cfg.Log))
 	r.Use(RecoverMiddleware(cfg.Log))
+	r.Use(AccessControlMiddleware(cfg.AccessRules, cfg.Log))
+	r.Use(RateLimitMiddleware(cfg.RequestRateLimit, cfg.Log))
+	r.Use(CORSMiddleware(cfg.CORS))
+	r.Use(CSRFMiddleware(cfg.CSRF))
+	r.Use(ReadOnlyMiddleware(cfg.ReadOnly))
 
 	// Health endpoints (no auth required)
 	r.Get("/healthz", HealthzHandler())
@@ -39,21 +105,57 @@ func NewRouterWithConfig(cfg RouterConfig) *chi.Mux {
 	r.Post("/api/v1/webhooks/sendgrid", SendGridWebhookHandler(cfg.Queries))
 	r.Post("/api/v1/webhooks/ses", SESWebhookHandler(cfg.Queries))
 	r.Post("/api/v1/webhooks/mailgun", MailgunWebhookHandler(cfg.Queries))
+	r.Post("/api/v1/webhooks/resend", ResendWebhookHandler(cfg.Queries))
+	r.Post("/api/v1/webhooks/brevo", BrevoWebhookHandler(cfg.Queries))
+	r.Post("/api/v1/webhooks/zeptomail", ZeptoMailWebhookHandler(cfg.Queries))
 
-	// Auth endpoints (no auth required for login/refresh/logout)
-	r.Post("/api/v1/auth/login", LoginHandler(cfg.Queries, cfg.JWTService, cfg.AuditLogger, cfg.RateLimiter))
+	// Auth endpoints (no auth required for login/refresh/logout/unlock)
+	r.Post("/api/v1/auth/login", LoginHandler(cfg.Queries, cfg.JWTService, cfg.AuditLogger, cfg.RateLimiter, cfg.LoginSecurity))
+	r.Get("/api/v1/auth/unlock", GetUnlockHandler(cfg.JWTService, cfg.RateLimiter))
+	r.Post("/api/v1/auth/unlock", UnlockHandler(cfg.Queries, cfg.JWTService, cfg.RateLimiter, cfg.AuditLogger))
 	r.Post("/api/v1/auth/refresh", RefreshHandler(cfg.Queries, cfg.JWTService, cfg.AuditLogger))
 	r.Post("/api/v1/auth/logout", LogoutHandler(cfg.Queries, cfg.JWTService, cfg.AuditLogger))
 
+	// Invitation acceptance (no auth required - the invite token is the credential)
+	r.Get("/api/v1/invitations/accept", GetInvitationHandler(cfg.Queries, cfg.JWTService))
+	r.Post("/api/v1/invitations/accept", AcceptInvitationHandler(cfg.Queries, cfg.DB, cfg.JWTService, cfg.PasswordPolicy, cfg.AuditLogger))
+
+	// Self-service signup (no auth required - the verification token is the credential)
+	if cfg.Signup.Mailer != nil {
+		r.Post("/api/v1/auth/signup", SignupHandler(cfg.Queries, cfg.JWTService, cfg.PasswordPolicy, cfg.Signup))
+		r.Get("/api/v1/auth/verify-email", GetSignupHandler(cfg.Queries, cfg.JWTService))
+		r.Post("/api/v1/auth/verify-email", VerifyEmailHandler(cfg.Queries, cfg.DB, cfg.JWTService, cfg.Signup, cfg.AuditLogger))
+	}
+
+	// Delivery report downloads (no auth required - the signed URL is the credential)
+	r.Get("/api/v1/delivery-reports/download", DownloadDeliveryReportHandler(cfg.Queries, cfg.MessageStore, cfg.JWTService))
+
 	// Switch group requires JWT auth only (human users only)
 	r.Group(func(r chi.Router) {
 		r.Use(auth.JWTAuth(cfg.JWTService))
+		r.Use(auth.RestrictScopedTokens())
 		r.Post("/api/v1/auth/switch-group", SwitchGroupHandler(cfg.Queries, cfg.JWTService, cfg.AuditLogger))
+		r.Get("/api/v1/auth/sessions", ListSessionsHandler(cfg.Queries))
+		r.Delete("/api/v1/auth/sessions/{sessionId}", RevokeSessionHandler(cfg.Queries, cfg.AuditLogger))
 	})
 
-	// Unified authenticated routes: accepts both JWT tokens and API keys
+	// Unified authenticated routes: accepts both JWT tokens and API keys.
+	// Scoped tokens are limited to the requests their scope allows.
 	r.Group(func(r chi.Router) {
 		r.Use(auth.UnifiedAuth(cfg.JWTService, cfg.Queries))
+		r.Use(auth.RestrictScopedTokens())
+
+		// Scoped tokens for automation, minted from the caller's credential
+		r.Post("/api/v1/auth/tokens", MintScopedTokenHandler(cfg.Queries, cfg.JWTService, cfg.AuditLogger))
+
+		// Build version
+		r.Get("/api/v1/version", VersionHandler())
+
+		// Legacy account and tenant endpoints, for integrations still
+		// migrating to users and groups
+		if cfg.LegacyAPI {
+			registerLegacyRoutes(r, cfg)
+		}
 
 		// Group management (system admin only for create/list)
 		r.Route("/api/v1/groups", func(r chi.Router) {
@@ -66,6 +168,12 @@ func NewRouterWithConfig(cfg RouterConfig) *chi.Mux {
 			// Group detail routes
 			r.Route("/{id}", func(r chi.Router) {
 				r.Get("/", GetGroupHandler(cfg.Queries))
+				r.Patch("/settings", UpdateGroupSettingsHandler(cfg.Queries, cfg.AuditLogger))
+				r.Get("/usage", GetGroupUsageHandler(cfg.Queries))
+
+				// Sub-groups
+				r.Get("/subgroups", ListSubGroupsHandler(cfg.Queries))
+				r.Post("/subgroups", CreateSubGroupHandler(cfg.Queries, cfg.AuditLogger))
 
 				// System admin only: delete group
 				r.Group(func(r chi.Router) {
@@ -79,27 +187,67 @@ func NewRouterWithConfig(cfg RouterConfig) *chi.Mux {
 				r.Patch("/members/{uid}", UpdateGroupMemberRoleHandler(cfg.Queries, cfg.AuditLogger))
 				r.Delete("/members/{uid}", RemoveGroupMemberHandler(cfg.Queries, cfg.AuditLogger))
 
+				// Invitations
+				r.Get("/invitations", ListInvitationsHandler(cfg.Queries))
+				r.Post("/invitations", CreateInvitationHandler(cfg.Queries, cfg.JWTService, cfg.Invitations, cfg.AuditLogger))
+				r.Delete("/invitations/{invitationId}", RevokeInvitationHandler(cfg.Queries, cfg.AuditLogger))
+
+				// System email branding
+				r.Get("/branding", GetBrandingHandler(cfg.Queries))
+				r.Put("/branding", PutBrandingHandler(cfg.Queries, cfg.AuditLogger))
+				r.Delete("/branding", DeleteBrandingHandler(cfg.Queries, cfg.AuditLogger))
+
 				// Activity logs
 				r.Get("/activity", ListActivityLogsHandler(cfg.Queries))
+
+				// Data export and compliance delete
+				r.Get("/export", ExportGroupDataHandler(cfg.Queries, cfg.AuditLogger))
+				r.Post("/erase", EraseGroupDataHandler(cfg.Queries, cfg.MessageStore, cfg.AuditLogger))
+
+				// Compromised-credential lockdown
+				r.Post("/lockdown", LockdownGroupHandler(cfg.Queries, cfg.AuditLogger))
+				r.Post("/lockdown/release", ReleaseGroupHandler(cfg.Queries, cfg.AuditLogger))
+
+				// Bulk resend of failed messages
+				r.Post("/messages/resend", ResendMessagesHandler(cfg.Queries, cfg.DB, cfg.Resend, cfg.AuditLogger))
+
+				// Delivery reports of tagged batches
+				r.Get("/delivery-reports", ListDeliveryReportsHandler(cfg.Queries, cfg.JWTService, cfg.DeliveryReports))
+				r.Post("/delivery-reports", CreateDeliveryReportHandler(cfg.Queries))
+				r.Get("/delivery-reports/{reportId}", GetDeliveryReportHandler(cfg.Queries, cfg.JWTService, cfg.DeliveryReports))
 			})
 		})
 
 		// User management
 		r.Route("/api/v1/users", func(r chi.Router) {
 			r.Get("/", ListUsersHandler(cfg.Queries))
-			r.Post("/", CreateUserHandler(cfg.Queries, cfg.AuditLogger))
+			r.Post("/", CreateUserHandler(cfg.Queries, cfg.PasswordPolicy, cfg.AuditLogger))
 			r.Get("/{id}", GetUserHandler(cfg.Queries))
 			r.Patch("/{id}/status", UpdateUserStatusHandler(cfg.Queries, cfg.AuditLogger))
+			r.Put("/{id}/password", SetPasswordHandler(cfg.Queries, cfg.PasswordPolicy, cfg.AuditLogger))
+			r.Put("/{id}/tls-policy", UpdateUserTLSPolicyHandler(cfg.Queries, cfg.AuditLogger))
+			r.Put("/{id}/auto-bcc", UpdateUserAutoBCCHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/{id}/certificates", ListClientCertsHandler(cfg.Queries))
+			r.Post("/{id}/certificates", CreateClientCertHandler(cfg.Queries, cfg.AuditLogger))
+			r.Delete("/{id}/certificates/{certId}", DeleteClientCertHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/{id}/sessions", ListSessionsHandler(cfg.Queries))
+			r.Delete("/{id}/sessions/{sessionId}", RevokeSessionHandler(cfg.Queries, cfg.AuditLogger))
+			r.Post("/{id}/lockdown", LockdownUserHandler(cfg.Queries, cfg.AuditLogger))
+			r.Post("/{id}/lockdown/release", ReleaseUserHandler(cfg.Queries, cfg.AuditLogger))
 			r.Delete("/{id}", DeleteUserHandler(cfg.Queries, cfg.AuditLogger))
 		})
 
 		// Providers
 		r.Route("/api/v1/providers", func(r chi.Router) {
-			r.Post("/", CreateProviderHandler(cfg.Queries))
+			r.Post("/", CreateProviderHandler(cfg.Queries, providerClient))
 			r.Get("/", ListProvidersHandler(cfg.Queries))
 			r.Get("/{id}", GetProviderHandler(cfg.Queries))
-			r.Put("/{id}", UpdateProviderHandler(cfg.Queries))
+			r.Get("/{id}/health", GetProviderHealthHandler(cfg.Queries))
+			r.Put("/{id}", UpdateProviderHandler(cfg.Queries, providerClient))
 			r.Delete("/{id}", DeleteProviderHandler(cfg.Queries))
+			r.Post("/{id}/ses-setup", SESSetupHandler(cfg.Queries, providerClient, cfg.AuditLogger))
+			r.Get("/{id}/captures", ListProviderCapturesHandler(cfg.Queries))
+			r.Delete("/{id}/captures", DeleteProviderCapturesHandler(cfg.Queries, cfg.AuditLogger))
 		})
 
 		// Routing Rules
@@ -111,9 +259,117 @@ func NewRouterWithConfig(cfg RouterConfig) *chi.Mux {
 			r.Delete("/{id}", DeleteRoutingRuleHandler(cfg.Queries))
 		})
 
-		// DLQ Reprocess
+		// Message scripts
+		r.Route("/api/v1/scripts", func(r chi.Router) {
+			r.Post("/", CreateScriptHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/", ListScriptsHandler(cfg.Queries))
+			r.Get("/{id}", GetScriptHandler(cfg.Queries))
+			r.Put("/{id}", UpdateScriptHandler(cfg.Queries, cfg.AuditLogger))
+			r.Delete("/{id}", DeleteScriptHandler(cfg.Queries, cfg.AuditLogger))
+		})
+
+		// Operational alert channels
+		r.Route("/api/v1/alert-channels", func(r chi.Router) {
+			r.Post("/", CreateAlertChannelHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/", ListAlertChannelsHandler(cfg.Queries))
+			r.Delete("/{id}", DeleteAlertChannelHandler(cfg.Queries, cfg.AuditLogger))
+		})
+
+		// Sender identities and From spoofing policy
+		r.Route("/api/v1/sender-identities", func(r chi.Router) {
+			r.Post("/", CreateSenderIdentityHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/", ListSenderIdentitiesHandler(cfg.Queries))
+			r.Post("/{id}/verify", VerifySenderIdentityHandler(cfg.Queries, cfg.SenderResolver, cfg.AuditLogger))
+			r.Delete("/{id}", DeleteSenderIdentityHandler(cfg.Queries, cfg.AuditLogger))
+		})
+		r.Get("/api/v1/sender-policy", GetSenderPolicyHandler(cfg.Queries))
+		r.Put("/api/v1/sender-policy", UpdateSenderPolicyHandler(cfg.Queries, cfg.AuditLogger))
+
+		// Sending windows (quiet hours)
+		r.Get("/api/v1/send-window", GetSendWindowHandler(cfg.Queries))
+		r.Put("/api/v1/send-window", UpdateSendWindowHandler(cfg.Queries, cfg.AuditLogger))
+		r.Delete("/api/v1/send-window", DeleteSendWindowHandler(cfg.Queries, cfg.AuditLogger))
+
+		// Default headers and footer of the group's messages
+		r.Get("/api/v1/message-defaults", GetMessageDefaultsHandler(cfg.Queries))
+		r.Put("/api/v1/message-defaults", UpdateMessageDefaultsHandler(cfg.Queries, cfg.AuditLogger))
+		r.Delete("/api/v1/message-defaults", DeleteMessageDefaultsHandler(cfg.Queries, cfg.AuditLogger))
+
+		// Recipients suppressed after complaints
+		r.Get("/api/v1/suppressions", ListSuppressionsHandler(cfg.Queries))
+		r.Delete("/api/v1/suppressions/{address}", DeleteSuppressionHandler(cfg.Queries, cfg.AuditLogger))
+
+		// Reject policies in shadow mode and their would-be rejections
+		r.Get("/api/v1/policy-shadow", GetShadowPoliciesHandler(cfg.Queries))
+		r.Put("/api/v1/policy-shadow", UpdateShadowPoliciesHandler(cfg.DB, cfg.AuditLogger))
+		r.Get("/api/v1/policy-shadow/report", ShadowReportHandler(cfg.Queries))
+
+		// Per sending domain Reply-To and bounce address defaults
+		r.Route("/api/v1/sending-domains", func(r chi.Router) {
+			r.Get("/", ListSendingDomainsHandler(cfg.Queries))
+			r.Put("/{domain}", PutSendingDomainHandler(cfg.Queries, cfg.AuditLogger))
+			r.Delete("/{domain}", DeleteSendingDomainHandler(cfg.Queries, cfg.AuditLogger))
+		})
+
+		// S/MIME and PGP signing of outbound messages
+		r.Get("/api/v1/signing-key", GetSigningKeyHandler(cfg.Queries))
+		r.Put("/api/v1/signing-key", PutSigningKeyHandler(cfg.Queries, cfg.AuditLogger))
+		r.Delete("/api/v1/signing-key", DeleteSigningKeyHandler(cfg.Queries, cfg.AuditLogger))
+		r.Route("/api/v1/recipient-certificates", func(r chi.Router) {
+			r.Get("/", ListRecipientCertificatesHandler(cfg.Queries))
+			r.Put("/{email}", PutRecipientCertificateHandler(cfg.Queries, cfg.AuditLogger))
+			r.Delete("/{email}", DeleteRecipientCertificateHandler(cfg.Queries, cfg.AuditLogger))
+		})
+
+		// Inbound routes (inbound parse)
+		r.Route("/api/v1/inbound-routes", func(r chi.Router) {
+			r.Post("/", CreateInboundRouteHandler(cfg.Queries))
+			r.Get("/", ListInboundRoutesHandler(cfg.Queries))
+			r.Get("/{id}", GetInboundRouteHandler(cfg.Queries))
+			r.Put("/{id}", UpdateInboundRouteHandler(cfg.Queries))
+			r.Delete("/{id}", DeleteInboundRouteHandler(cfg.Queries))
+		})
+
+		// SMTP debug transcripts
+		r.Route("/api/v1/smtp-debug", func(r chi.Router) {
+			r.Post("/", CreateSMTPDebugTargetHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/", ListSMTPDebugTargetsHandler(cfg.Queries))
+			r.Delete("/{id}", DeleteSMTPDebugTargetHandler(cfg.Queries, cfg.AuditLogger))
+			r.Get("/{id}/transcripts", ListSMTPTranscriptsHandler(cfg.Queries))
+		})
+
+		// Message preview
+		r.Post("/api/v1/preview", PreviewHandler(cfg.Queries, cfg.MessageStore, cfg.RenderTester))
+
+		// Messages
+		r.Get("/api/v1/messages", ListMessagesHandler(cfg.Queries))
+		r.Get("/api/v1/messages/{id}", GetMessageHandler(cfg.Queries))
+
+		// Delivery logs
+		r.Get("/api/v1/delivery-logs", ListDeliveryLogsHandler(cfg.Queries, cfg.MessageStore))
+		r.Get("/api/v1/recipients/{address}/deliveries", ListRecipientDeliveriesHandler(cfg.Queries))
+
+		// Address validation
+		r.Post("/api/v1/validate", ValidateHandler(validator))
+
+		// Stats
+		r.Get("/api/v1/stats/costs", GetCostStatsHandler(cfg.Queries))
+		r.Get("/api/v1/stats/tags", GetTagStatsHandler(cfg.Queries))
+		r.Route("/api/v1/stats/grafana", func(r chi.Router) {
+			r.Get("/", GrafanaHealthHandler())
+			r.Post("/metrics", GrafanaMetricsHandler())
+			r.Post("/search", GrafanaSearchHandler())
+			r.Post("/query", GrafanaQueryHandler(cfg.Queries))
+		})
+
+		// Billing
+		r.Get("/api/v1/billing/usage", GetBillingUsageHandler(cfg.Queries))
+
+		// Dead letter queue
 		if cfg.DLQ != nil {
-			r.Post("/api/v1/dlq/reprocess", DLQReprocessHandler(cfg.DLQ))
+			r.Get("/api/v1/dlq", ListDLQHandler(cfg.DLQ, cfg.Queries))
+			r.Get("/api/v1/dlq/{id}", GetDLQEntryHandler(cfg.DLQ, cfg.Queries))
+			r.Post("/api/v1/dlq/reprocess", DLQReprocessHandler(cfg.DLQ, cfg.Queries))
 		}
 	})
 
@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -75,6 +76,10 @@ func CreateRoutingRuleHandler(queries storage.Querier) http.HandlerFunc {
 		if len(req.Conditions) > 0 {
 			conditions = req.Conditions
 		}
+		if _, err := provider.ParseRuleOptions(conditions); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid conditions: "+err.Error())
+			return
+		}
 
 		rule, err := queries.CreateRoutingRule(r.Context(), storage.CreateRoutingRuleParams{
 			GroupID:    groupID,
@@ -84,7 +89,7 @@ func CreateRoutingRuleHandler(queries storage.Querier) http.HandlerFunc {
 			Enabled:    req.Enabled,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -104,7 +109,7 @@ func ListRoutingRulesHandler(queries storage.Querier) http.HandlerFunc {
 
 		rules, err := queries.ListRoutingRulesByGroupID(r.Context(), groupID)
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -129,7 +134,7 @@ func GetRoutingRuleHandler(queries storage.Querier) http.HandlerFunc {
 
 		rule, err := queries.GetRoutingRuleByID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "routing rule not found")
+			respondStorageError(w, err, http.StatusNotFound, "routing rule not found")
 			return
 		}
 
@@ -163,6 +168,10 @@ func UpdateRoutingRuleHandler(queries storage.Querier) http.HandlerFunc {
 		if len(req.Conditions) > 0 {
 			conditions = req.Conditions
 		}
+		if _, err := provider.ParseRuleOptions(conditions); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid conditions: "+err.Error())
+			return
+		}
 
 		rule, err := queries.UpdateRoutingRule(r.Context(), storage.UpdateRoutingRuleParams{
 			ID:         id,
@@ -172,7 +181,7 @@ func UpdateRoutingRuleHandler(queries storage.Querier) http.HandlerFunc {
 			Enabled:    req.Enabled,
 		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -77,6 +77,19 @@ func TestCreateRoutingRuleHandler_InvalidProviderID(t *testing.T) {
 	}
 }
 
+func TestCreateRoutingRuleHandler_InvalidProviderOptions(t *testing.T) {
+	body := `{"priority":10,"conditions":{"provider_options":{"ip_poool":"x"}},"provider_id":"` + uuid.New().String() + `","enabled":true}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/routing-rules", strings.NewReader(body))
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+
+	rec := httptest.NewRecorder()
+	CreateRoutingRuleHandler(&mockQuerier{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+}
+
 func TestListRoutingRulesHandler_OrderedByPriority(t *testing.T) {
 	groupID := testGroup().ID
 
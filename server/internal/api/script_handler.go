@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/scripting"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// maxScriptSize is the largest script source the API accepts, in bytes.
+const maxScriptSize = 64 * 1024
+
+// scriptRequest is the JSON body for creating or updating a message script.
+// Enabled defaults to true.
+type scriptRequest struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Enabled *bool  `json:"enabled"`
+}
+
+type scriptResponse struct {
+	ID        uuid.UUID `json:"id"`
+	GroupID   uuid.UUID `json:"group_id"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func toScriptResponse(s storage.MessageScript) scriptResponse {
+	return scriptResponse{
+		ID:        s.ID,
+		GroupID:   s.GroupID,
+		Name:      s.Name,
+		Source:    s.Source,
+		Enabled:   s.Enabled,
+		CreatedAt: s.CreatedAt.Time,
+		UpdatedAt: s.UpdatedAt.Time,
+	}
+}
+
+// decodeScriptRequest reads and validates a script request, writing a 400
+// response and returning false when it is invalid. The source must compile
+// and define the process function.
+func decodeScriptRequest(w http.ResponseWriter, r *http.Request) (scriptRequest, bool) {
+	var req scriptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	var errs []string
+	if req.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if len(req.Source) > maxScriptSize {
+		errs = append(errs, "source exceeds 64 KiB")
+	} else if err := scripting.Validate(req.Source); err != nil {
+		errs = append(errs, "source: "+err.Error())
+	}
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs)
+		return req, false
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+	return req, true
+}
+
+// CreateScriptHandler handles POST /api/v1/scripts.
+// Creates a message script for the authenticated user's group. Requires
+// group admin+ role. Returns 409 if the group already has a script with the
+// same name.
+func CreateScriptHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		req, ok := decodeScriptRequest(w, r)
+		if !ok {
+			return
+		}
+
+		script, err := queries.CreateMessageScript(r.Context(), storage.CreateMessageScriptParams{
+			GroupID: groupID,
+			Name:    req.Name,
+			Source:  req.Source,
+			Enabled: *req.Enabled,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusConflict, "a script with this name already exists")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateScript, "message_script", script.ID.String(), map[string]interface{}{
+				"name":    script.Name,
+				"enabled": script.Enabled,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, toScriptResponse(script))
+	}
+}
+
+// ListScriptsHandler handles GET /api/v1/scripts.
+// Scripts are returned in the order they run, by name.
+func ListScriptsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		scripts, err := queries.ListMessageScriptsByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]scriptResponse, 0, len(scripts))
+		for _, s := range scripts {
+			resp = append(resp, toScriptResponse(s))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// GetScriptHandler handles GET /api/v1/scripts/{id}.
+func GetScriptHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid script ID format")
+			return
+		}
+
+		script, err := queries.GetMessageScript(r.Context(), storage.GetMessageScriptParams{ID: id, GroupID: groupID})
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "script not found")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, toScriptResponse(script))
+	}
+}
+
+// UpdateScriptHandler handles PUT /api/v1/scripts/{id}.
+// The new source takes effect on the next message the worker handles.
+// Requires group admin+ role.
+func UpdateScriptHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid script ID format")
+			return
+		}
+
+		req, ok := decodeScriptRequest(w, r)
+		if !ok {
+			return
+		}
+
+		script, err := queries.UpdateMessageScript(r.Context(), storage.UpdateMessageScriptParams{
+			ID:      id,
+			GroupID: groupID,
+			Name:    req.Name,
+			Source:  req.Source,
+			Enabled: *req.Enabled,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "script not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateScript, "message_script", script.ID.String(), map[string]interface{}{
+				"name":    script.Name,
+				"enabled": script.Enabled,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toScriptResponse(script))
+	}
+}
+
+// DeleteScriptHandler handles DELETE /api/v1/scripts/{id}.
+// Requires group admin+ role.
+func DeleteScriptHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid script ID format")
+			return
+		}
+
+		n, err := queries.DeleteMessageScript(r.Context(), storage.DeleteMessageScriptParams{ID: id, GroupID: groupID})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "script not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteScript, "message_script", id.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
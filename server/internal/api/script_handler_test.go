@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+const testScriptSource = `function process(msg) msg.headers["X-Team"] = "ops" end`
+
+func scriptRequestWithID(method, target, id, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rctx := chi.NewRouteContext()
+	if id != "" {
+		rctx.URLParams.Add("id", id)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, testGroup().ID, "admin", "organization")
+	return req.WithContext(ctx)
+}
+
+func TestCreateScriptHandler(t *testing.T) {
+	groupID := testGroup().ID
+	var stored storage.CreateMessageScriptParams
+	mock := &mockQuerier{
+		createMessageScriptFn: func(_ context.Context, arg storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+			stored = arg
+			return storage.MessageScript{ID: uuid.New(), GroupID: arg.GroupID, Name: arg.Name, Source: arg.Source, Enabled: arg.Enabled}, nil
+		},
+	}
+
+	body, _ := json.Marshal(map[string]string{"name": " tag-team ", "source": testScriptSource})
+	rec := httptest.NewRecorder()
+	CreateScriptHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodPost, "/api/v1/scripts", "", string(body)))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if stored.GroupID != groupID || stored.Name != "tag-team" || stored.Source != testScriptSource {
+		t.Errorf("stored %+v", stored)
+	}
+	if !stored.Enabled {
+		t.Error("expected scripts to be enabled by default")
+	}
+
+	var resp scriptResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Name != "tag-team" || resp.GroupID != groupID {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestCreateScriptHandler_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing name":    `{"source":"function process(msg) end"}`,
+		"syntax error":    `{"name":"a","source":"function process(msg"}`,
+		"missing process": `{"name":"a","source":"x = 1"}`,
+		"too large":       `{"name":"a","source":"--` + strings.Repeat("x", maxScriptSize) + `\nfunction process(msg) end"}`,
+		"bad json":        `{`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock := &mockQuerier{
+				createMessageScriptFn: func(context.Context, storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+					t.Error("invalid script was stored")
+					return storage.MessageScript{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			CreateScriptHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodPost, "/api/v1/scripts", "", body))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateScriptHandler(t *testing.T) {
+	id := uuid.New()
+	var stored storage.UpdateMessageScriptParams
+	mock := &mockQuerier{
+		updateMessageScriptFn: func(_ context.Context, arg storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+			stored = arg
+			return storage.MessageScript{ID: arg.ID, GroupID: arg.GroupID, Name: arg.Name, Source: arg.Source, Enabled: arg.Enabled}, nil
+		},
+	}
+
+	body, _ := json.Marshal(map[string]any{"name": "tag-team", "source": testScriptSource, "enabled": false})
+	rec := httptest.NewRecorder()
+	UpdateScriptHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodPut, "/api/v1/scripts/"+id.String(), id.String(), string(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if stored.ID != id || stored.GroupID != testGroup().ID || stored.Enabled {
+		t.Errorf("stored %+v", stored)
+	}
+}
+
+func TestUpdateScriptHandler_NotFound(t *testing.T) {
+	mock := &mockQuerier{
+		updateMessageScriptFn: func(context.Context, storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+			return storage.MessageScript{}, pgx.ErrNoRows
+		},
+	}
+
+	id := uuid.NewString()
+	body, _ := json.Marshal(map[string]string{"name": "a", "source": testScriptSource})
+	rec := httptest.NewRecorder()
+	UpdateScriptHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodPut, "/api/v1/scripts/"+id, id, string(body)))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestGetScriptHandler_ScopedToGroup(t *testing.T) {
+	id := uuid.New()
+	mock := &mockQuerier{
+		getMessageScriptFn: func(_ context.Context, arg storage.GetMessageScriptParams) (storage.MessageScript, error) {
+			if arg.ID != id || arg.GroupID != testGroup().ID {
+				t.Errorf("lookup %+v", arg)
+			}
+			return storage.MessageScript{ID: id, GroupID: arg.GroupID, Name: "a", Source: testScriptSource}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	GetScriptHandler(mock).ServeHTTP(rec, scriptRequestWithID(http.MethodGet, "/api/v1/scripts/"+id.String(), id.String(), ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestListScriptsHandler(t *testing.T) {
+	mock := &mockQuerier{
+		listMessageScriptsByGroupIDFn: func(_ context.Context, groupID uuid.UUID) ([]storage.MessageScript, error) {
+			return []storage.MessageScript{{Name: "a"}, {Name: "b"}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListScriptsHandler(mock).ServeHTTP(rec, scriptRequestWithID(http.MethodGet, "/api/v1/scripts", "", ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var resp []scriptResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp) != 2 || resp[0].Name != "a" {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestDeleteScriptHandler(t *testing.T) {
+	id := uuid.NewString()
+	mock := &mockQuerier{
+		deleteMessageScriptFn: func(context.Context, storage.DeleteMessageScriptParams) (int64, error) {
+			return 0, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	DeleteScriptHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodDelete, "/api/v1/scripts/"+id, id, ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for a script of another group, got %d", rec.Code)
+	}
+
+	mock.deleteMessageScriptFn = nil
+	rec = httptest.NewRecorder()
+	DeleteScriptHandler(mock, nil).ServeHTTP(rec, scriptRequestWithID(http.MethodDelete, "/api/v1/scripts/"+id, id, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rec.Code)
+	}
+}
+
+func TestScriptHandlers_RequireGroupAdmin(t *testing.T) {
+	id := uuid.NewString()
+	body, _ := json.Marshal(map[string]string{"name": "reroute", "source": testScriptSource})
+	mock := &mockQuerier{
+		createMessageScriptFn: func(context.Context, storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+			t.Error("script created")
+			return storage.MessageScript{}, nil
+		},
+		updateMessageScriptFn: func(context.Context, storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+			t.Error("script updated")
+			return storage.MessageScript{}, nil
+		},
+		deleteMessageScriptFn: func(context.Context, storage.DeleteMessageScriptParams) (int64, error) {
+			t.Error("script deleted")
+			return 1, nil
+		},
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		req     *http.Request
+	}{
+		{"create", CreateScriptHandler(mock, nil), scriptRequestWithID(http.MethodPost, "/api/v1/scripts", "", string(body))},
+		{"update", UpdateScriptHandler(mock, nil), scriptRequestWithID(http.MethodPut, "/api/v1/scripts/"+id, id, string(body))},
+		{"delete", DeleteScriptHandler(mock, nil), scriptRequestWithID(http.MethodDelete, "/api/v1/scripts/"+id, id, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req.WithContext(setJWTContext(tt.req.Context(), testUser().ID, testGroup().ID, "member", "organization"))
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status 403 for a member, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/sendwindow"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// sendWindowRequest is the JSON body for setting a group's sending
+// windows.
+type sendWindowRequest struct {
+	Timezone string              `json:"timezone"`
+	Windows  []sendwindow.Window `json:"windows"`
+}
+
+// sendWindowResponse is the JSON representation of a group's sending
+// windows. NextOpen is set while messages are being held.
+type sendWindowResponse struct {
+	Enabled   bool                `json:"enabled"`
+	Timezone  string              `json:"timezone"`
+	Windows   []sendwindow.Window `json:"windows"`
+	NextOpen  *time.Time          `json:"next_open,omitempty"`
+	UpdatedAt *time.Time          `json:"updated_at,omitempty"`
+}
+
+// GetSendWindowHandler handles GET /api/v1/send-window. Groups that never
+// set sending windows report enabled false and may send at any time.
+func GetSendWindowHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		sw, err := queries.GetSendWindow(r.Context(), groupID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusOK, sendWindowResponse{Timezone: "UTC", Windows: []sendwindow.Window{}})
+			return
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		respondJSON(w, http.StatusOK, toSendWindowResponse(sw, time.Now()))
+	}
+}
+
+// UpdateSendWindowHandler handles PUT /api/v1/send-window.
+// Replaces the group's sending windows: messages submitted over SMTP
+// outside all of them are held until the next one opens, unless they
+// carry the urgent header. Requires owner or admin role.
+func UpdateSendWindowHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req sendWindowRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.Timezone = strings.TrimSpace(req.Timezone)
+		if req.Timezone == "" {
+			req.Timezone = "UTC"
+		}
+		if _, err := sendwindow.Parse(req.Timezone, req.Windows); err != nil {
+			respondValidationErrors(w, []string{err.Error()})
+			return
+		}
+		windowsJSON, err := json.Marshal(req.Windows)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		sw, err := queries.UpsertSendWindow(r.Context(), storage.UpsertSendWindowParams{
+			GroupID:  groupID,
+			Timezone: req.Timezone,
+			Windows:  windowsJSON,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateSendWindow, "group", groupID.String(), map[string]interface{}{
+				"timezone": req.Timezone,
+				"windows":  req.Windows,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toSendWindowResponse(sw, time.Now()))
+	}
+}
+
+// DeleteSendWindowHandler handles DELETE /api/v1/send-window. The group
+// may send at any time again; messages already held stay held until their
+// window opens. Requires owner or admin role.
+func DeleteSendWindowHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		n, err := queries.DeleteSendWindow(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "send window not set")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteSendWindow, "group", groupID.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func toSendWindowResponse(sw storage.SendWindow, now time.Time) sendWindowResponse {
+	resp := sendWindowResponse{Enabled: true, Timezone: sw.Timezone, Windows: []sendwindow.Window{}}
+	_ = json.Unmarshal(sw.Windows, &resp.Windows)
+	if sched, err := sendwindow.Parse(sw.Timezone, resp.Windows); err == nil {
+		if next := sched.Next(now); next.After(now) {
+			resp.NextOpen = &next
+		}
+	}
+	if sw.UpdatedAt.Valid {
+		t := sw.UpdatedAt.Time
+		resp.UpdatedAt = &t
+	}
+	return resp
+}
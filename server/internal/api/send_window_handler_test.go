@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestGetSendWindowHandler_NotSet(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetSendWindowHandler(&mockQuerier{}).ServeHTTP(rec, shadowRequest(http.MethodGet, "/api/v1/send-window", "", "member"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp sendWindowResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Enabled || resp.Timezone != "UTC" || len(resp.Windows) != 0 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestUpdateSendWindowHandler(t *testing.T) {
+	var got storage.UpsertSendWindowParams
+	mock := &mockQuerier{
+		upsertSendWindowFn: func(ctx context.Context, arg storage.UpsertSendWindowParams) (storage.SendWindow, error) {
+			got = arg
+			return storage.SendWindow{GroupID: arg.GroupID, Timezone: arg.Timezone, Windows: arg.Windows}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"timezone":"Asia/Seoul","windows":[{"days":["mon","fri"],"start":"09:00","end":"18:00"}]}`
+	UpdateSendWindowHandler(mock, nil).ServeHTTP(rec, shadowRequest(http.MethodPut, "/api/v1/send-window", body, "owner"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID != testGroup().ID || got.Timezone != "Asia/Seoul" || !strings.Contains(string(got.Windows), `"start":"09:00"`) {
+		t.Errorf("unexpected upsert params: %+v", got)
+	}
+	var resp sendWindowResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Enabled || len(resp.Windows) != 1 || resp.Windows[0].End != "18:00" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestUpdateSendWindowHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		role     string
+		wantCode int
+	}{
+		{name: "member", body: `{"windows":[{"start":"09:00","end":"18:00"}]}`, role: "member", wantCode: http.StatusForbidden},
+		{name: "no windows", body: `{"timezone":"UTC","windows":[]}`, role: "admin", wantCode: http.StatusBadRequest},
+		{name: "unknown timezone", body: `{"timezone":"Nowhere/City","windows":[{"start":"09:00","end":"18:00"}]}`, role: "admin", wantCode: http.StatusBadRequest},
+		{name: "overnight window", body: `{"windows":[{"start":"22:00","end":"07:00"}]}`, role: "admin", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				upsertSendWindowFn: func(ctx context.Context, arg storage.UpsertSendWindowParams) (storage.SendWindow, error) {
+					t.Error("send window changed")
+					return storage.SendWindow{}, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			UpdateSendWindowHandler(mock, nil).ServeHTTP(rec, shadowRequest(http.MethodPut, "/api/v1/send-window", tt.body, tt.role))
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteSendWindowHandler(t *testing.T) {
+	for _, tt := range []struct {
+		rows     int64
+		wantCode int
+	}{{1, http.StatusNoContent}, {0, http.StatusNotFound}} {
+		mock := &mockQuerier{
+			deleteSendWindowFn: func(ctx context.Context, groupID uuid.UUID) (int64, error) {
+				return tt.rows, nil
+			},
+		}
+		rec := httptest.NewRecorder()
+		DeleteSendWindowHandler(mock, nil).ServeHTTP(rec, shadowRequest(http.MethodDelete, "/api/v1/send-window", "", "admin"))
+		if rec.Code != tt.wantCode {
+			t.Errorf("deleted %d rows: expected status %d, got %d", tt.rows, tt.wantCode, rec.Code)
+		}
+	}
+}
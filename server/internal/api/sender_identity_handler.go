@@ -0,0 +1,324 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// senderIdentityRequest is the JSON body for adding a sender identity.
+type senderIdentityRequest struct {
+	Identity string `json:"identity"`
+}
+
+// verificationRecordResponse is the TXT record that verifies an identity.
+type verificationRecordResponse struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// senderIdentityResponse is the JSON representation of a sender identity.
+type senderIdentityResponse struct {
+	ID                 uuid.UUID                  `json:"id"`
+	Identity           string                     `json:"identity"`
+	Verified           bool                       `json:"verified"`
+	VerifiedAt         *time.Time                 `json:"verified_at,omitempty"`
+	VerificationRecord verificationRecordResponse `json:"verification_record"`
+	CreatedAt          time.Time                  `json:"created_at"`
+}
+
+func toSenderIdentityResponse(si storage.SenderIdentity) senderIdentityResponse {
+	name, value := senderpolicy.VerificationRecord(si.Identity, si.VerificationToken)
+	resp := senderIdentityResponse{
+		ID:                 si.ID,
+		Identity:           si.Identity,
+		Verified:           si.VerifiedAt.Valid,
+		VerificationRecord: verificationRecordResponse{Type: "TXT", Name: name, Value: value},
+		CreatedAt:          si.CreatedAt.Time,
+	}
+	if si.VerifiedAt.Valid {
+		t := si.VerifiedAt.Time
+		resp.VerifiedAt = &t
+	}
+	return resp
+}
+
+// senderPolicyRequest is the JSON body for PUT /api/v1/sender-policy.
+type senderPolicyRequest struct {
+	Mode           string `json:"mode"`
+	RewriteAddress string `json:"rewrite_address"`
+}
+
+// senderPolicyResponse is the JSON representation of a group's sender
+// policy.
+type senderPolicyResponse struct {
+	Mode           string     `json:"mode"`
+	RewriteAddress string     `json:"rewrite_address,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// CreateSenderIdentityHandler handles POST /api/v1/sender-identities.
+// Adds an address or domain the group may use in the From header. The
+// identity is unverified until its verification record is published and
+// checked with the verify endpoint. Requires group admin+ role.
+func CreateSenderIdentityHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req senderIdentityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		identity, err := senderpolicy.NormalizeIdentity(req.Identity)
+		if err != nil {
+			respondValidationErrors(w, []string{err.Error()})
+			return
+		}
+
+		si, err := queries.CreateSenderIdentity(r.Context(), storage.CreateSenderIdentityParams{
+			GroupID:           groupID,
+			Identity:          identity,
+			VerificationToken: senderpolicy.NewToken(),
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateSenderIdentity, "sender_identity", si.ID.String(), map[string]interface{}{
+				"identity": si.Identity,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, toSenderIdentityResponse(si))
+	}
+}
+
+// ListSenderIdentitiesHandler handles GET /api/v1/sender-identities.
+func ListSenderIdentitiesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		identities, err := queries.ListSenderIdentitiesByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]senderIdentityResponse, 0, len(identities))
+		for _, si := range identities {
+			resp = append(resp, toSenderIdentityResponse(si))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// VerifySenderIdentityHandler handles POST /api/v1/sender-identities/{id}/verify.
+// Looks up the identity's verification record and marks it verified when
+// the record carries its token. A nil resolver uses the system resolver.
+// Requires group admin+ role.
+func VerifySenderIdentityHandler(queries storage.Querier, resolver senderpolicy.Resolver, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid sender identity ID format")
+			return
+		}
+
+		si, err := queries.GetSenderIdentity(r.Context(), storage.GetSenderIdentityParams{ID: id, GroupID: groupID})
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "sender identity not found")
+			return
+		}
+		if !si.VerifiedAt.Valid {
+			if err := senderpolicy.Verify(r.Context(), resolver, si.Identity, si.VerificationToken); err != nil {
+				respondError(w, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+			si, err = queries.MarkSenderIdentityVerified(r.Context(), storage.MarkSenderIdentityVerifiedParams{ID: id, GroupID: groupID})
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if auditLogger != nil {
+				auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionVerifySenderIdentity, "sender_identity", id.String(), map[string]interface{}{
+					"identity": si.Identity,
+				})
+			}
+		}
+
+		respondJSON(w, http.StatusOK, toSenderIdentityResponse(si))
+	}
+}
+
+// DeleteSenderIdentityHandler handles DELETE /api/v1/sender-identities/{id}.
+// Requires group admin+ role.
+func DeleteSenderIdentityHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid sender identity ID format")
+			return
+		}
+
+		n, err := queries.DeleteSenderIdentity(r.Context(), storage.DeleteSenderIdentityParams{ID: id, GroupID: groupID})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "sender identity not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteSenderIdentity, "sender_identity", id.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetSenderPolicyHandler handles GET /api/v1/sender-policy. Groups that
+// never set a policy report mode off.
+func GetSenderPolicyHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		policy, err := queries.GetSenderPolicy(r.Context(), groupID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondJSON(w, http.StatusOK, senderPolicyResponse{Mode: senderpolicy.ModeOff})
+			return
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		respondJSON(w, http.StatusOK, toSenderPolicyResponse(policy))
+	}
+}
+
+// UpdateSenderPolicyHandler handles PUT /api/v1/sender-policy.
+// Sets what the worker does with messages whose From is not a verified
+// sender identity: off, reject or rewrite. Rewrite requires a
+// rewrite_address that is itself covered by a verified identity. Requires
+// group admin+ role.
+func UpdateSenderPolicyHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req senderPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		req.Mode = strings.ToLower(strings.TrimSpace(req.Mode))
+		req.RewriteAddress = strings.ToLower(strings.TrimSpace(req.RewriteAddress))
+		if !senderpolicy.ValidMode(req.Mode) {
+			respondValidationErrors(w, []string{"mode must be off, reject or rewrite"})
+			return
+		}
+		if req.Mode == senderpolicy.ModeRewrite {
+			if req.RewriteAddress == "" {
+				respondValidationErrors(w, []string{"rewrite_address is required for mode rewrite"})
+				return
+			}
+			verified, err := queries.ListVerifiedSenderIdentities(r.Context(), groupID)
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if !senderpolicy.Allowed(verified, req.RewriteAddress) {
+				respondValidationErrors(w, []string{"rewrite_address must be covered by a verified sender identity"})
+				return
+			}
+		}
+
+		policy, err := queries.UpsertSenderPolicy(r.Context(), storage.UpsertSenderPolicyParams{
+			GroupID:        groupID,
+			Mode:           req.Mode,
+			RewriteAddress: pgtype.Text{String: req.RewriteAddress, Valid: req.RewriteAddress != ""},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateSenderPolicy, "sender_policy", groupID.String(), map[string]interface{}{
+				"mode":            policy.Mode,
+				"rewrite_address": policy.RewriteAddress.String,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toSenderPolicyResponse(policy))
+	}
+}
+
+func toSenderPolicyResponse(p storage.SenderPolicy) senderPolicyResponse {
+	resp := senderPolicyResponse{Mode: p.Mode, RewriteAddress: p.RewriteAddress.String}
+	if p.UpdatedAt.Valid {
+		t := p.UpdatedAt.Time
+		resp.UpdatedAt = &t
+	}
+	return resp
+}
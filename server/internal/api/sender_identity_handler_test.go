@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func senderRequest(method, target, body, id string) *http.Request {
+	return senderRoleRequest(method, target, body, id, "admin")
+}
+
+func senderRoleRequest(method, target, body, id, role string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "organization")
+	if id != "" {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+	}
+	return req.WithContext(ctx)
+}
+
+type txtResolver map[string][]string
+
+func (r txtResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if txt, ok := r[name]; ok {
+		return txt, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestCreateSenderIdentityHandler(t *testing.T) {
+	var got storage.CreateSenderIdentityParams
+	mock := &mockQuerier{
+		createSenderIdentityFn: func(ctx context.Context, arg storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+			got = arg
+			return storage.SenderIdentity{ID: uuid.New(), GroupID: arg.GroupID, Identity: arg.Identity, VerificationToken: arg.VerificationToken}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	CreateSenderIdentityHandler(mock, nil).ServeHTTP(rec, senderRequest(http.MethodPost, "/api/v1/sender-identities", `{"identity":" Example.COM "}`, ""))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID != testGroup().ID || got.Identity != "example.com" || got.VerificationToken == "" {
+		t.Errorf("unexpected create params: %+v", got)
+	}
+	var resp senderIdentityResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Verified || resp.VerificationRecord.Name != "_smtp-proxy.example.com" ||
+		resp.VerificationRecord.Value != "smtp-proxy-verification="+got.VerificationToken {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCreateSenderIdentityHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantCode int
+	}{
+		{name: "invalid identity", body: `{"identity":"Alice <alice@example.com>"}`, wantCode: http.StatusBadRequest},
+		{name: "duplicate", body: `{"identity":"example.com"}`, err: &pgconn.PgError{Code: "23505"}, wantCode: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				createSenderIdentityFn: func(ctx context.Context, arg storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+					return storage.SenderIdentity{}, tt.err
+				},
+			}
+			rec := httptest.NewRecorder()
+			CreateSenderIdentityHandler(mock, nil).ServeHTTP(rec, senderRequest(http.MethodPost, "/api/v1/sender-identities", tt.body, ""))
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestVerifySenderIdentityHandler(t *testing.T) {
+	si := storage.SenderIdentity{ID: uuid.New(), GroupID: testGroup().ID, Identity: "billing@example.com", VerificationToken: "tok"}
+	var marked bool
+	mock := &mockQuerier{
+		getSenderIdentityFn: func(ctx context.Context, arg storage.GetSenderIdentityParams) (storage.SenderIdentity, error) {
+			return si, nil
+		},
+		markSenderIdentityVerifiedFn: func(ctx context.Context, arg storage.MarkSenderIdentityVerifiedParams) (storage.SenderIdentity, error) {
+			marked = true
+			v := si
+			v.VerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return v, nil
+		},
+	}
+
+	// Without the record the identity stays unverified.
+	rec := httptest.NewRecorder()
+	VerifySenderIdentityHandler(mock, txtResolver{}, nil).ServeHTTP(rec, senderRequest(http.MethodPost, "/verify", "", si.ID.String()))
+	if rec.Code != http.StatusUnprocessableEntity || marked {
+		t.Fatalf("expected status 422 and no update, got %d (marked %v)", rec.Code, marked)
+	}
+
+	resolver := txtResolver{"_smtp-proxy.example.com": {"smtp-proxy-verification=tok"}}
+	rec = httptest.NewRecorder()
+	VerifySenderIdentityHandler(mock, resolver, nil).ServeHTTP(rec, senderRequest(http.MethodPost, "/verify", "", si.ID.String()))
+	if rec.Code != http.StatusOK || !marked {
+		t.Fatalf("expected status 200 and an update, got %d (marked %v)", rec.Code, marked)
+	}
+	var resp senderIdentityResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Verified || resp.VerifiedAt == nil {
+		t.Errorf("expected a verified identity, got %+v", resp)
+	}
+}
+
+func TestVerifySenderIdentityHandler_NotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	VerifySenderIdentityHandler(&mockQuerier{}, txtResolver{}, nil).ServeHTTP(rec, senderRequest(http.MethodPost, "/verify", "", uuid.New().String()))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestDeleteSenderIdentityHandler_NotFound(t *testing.T) {
+	mock := &mockQuerier{
+		deleteSenderIdentityFn: func(ctx context.Context, arg storage.DeleteSenderIdentityParams) (int64, error) {
+			return 0, nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	DeleteSenderIdentityHandler(mock, nil).ServeHTTP(rec, senderRequest(http.MethodDelete, "/", "", uuid.New().String()))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestGetSenderPolicyHandler_Default(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetSenderPolicyHandler(&mockQuerier{}).ServeHTTP(rec, senderRequest(http.MethodGet, "/api/v1/sender-policy", "", ""))
+
+	var resp senderPolicyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rec.Code != http.StatusOK || resp.Mode != "off" {
+		t.Errorf("expected mode off, got %d %+v", rec.Code, resp)
+	}
+}
+
+func TestUpdateSenderPolicyHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "reject", body: `{"mode":"Reject"}`, wantCode: http.StatusOK},
+		{name: "rewrite", body: `{"mode":"rewrite","rewrite_address":"noreply@example.com"}`, wantCode: http.StatusOK},
+		{name: "unknown mode", body: `{"mode":"quarantine"}`, wantCode: http.StatusBadRequest},
+		{name: "rewrite without address", body: `{"mode":"rewrite"}`, wantCode: http.StatusBadRequest},
+		{name: "rewrite to unverified address", body: `{"mode":"rewrite","rewrite_address":"noreply@other.example"}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upserted *storage.UpsertSenderPolicyParams
+			mock := &mockQuerier{
+				listVerifiedSenderIdentitiesFn: func(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+					return []string{"example.com"}, nil
+				},
+				upsertSenderPolicyFn: func(ctx context.Context, arg storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error) {
+					upserted = &arg
+					return storage.SenderPolicy{GroupID: arg.GroupID, Mode: arg.Mode, RewriteAddress: arg.RewriteAddress}, nil
+				},
+			}
+
+			rec := httptest.NewRecorder()
+			UpdateSenderPolicyHandler(mock, nil).ServeHTTP(rec, senderRequest(http.MethodPut, "/api/v1/sender-policy", tt.body, ""))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if upserted != nil {
+					t.Error("expected no policy update")
+				}
+				return
+			}
+			if upserted == nil || upserted.GroupID != testGroup().ID || upserted.Mode != strings.ToLower(upserted.Mode) {
+				t.Errorf("unexpected upsert: %+v", upserted)
+			}
+		})
+	}
+}
+
+func TestSenderIdentityHandlers_RequireGroupAdmin(t *testing.T) {
+	changed := false
+	mock := &mockQuerier{
+		createSenderIdentityFn: func(ctx context.Context, arg storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+			changed = true
+			return storage.SenderIdentity{}, nil
+		},
+		upsertSenderPolicyFn: func(ctx context.Context, arg storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error) {
+			changed = true
+			return storage.SenderPolicy{}, nil
+		},
+	}
+	id := uuid.New().String()
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		req     *http.Request
+	}{
+		{"create", CreateSenderIdentityHandler(mock, nil), senderRoleRequest(http.MethodPost, "/api/v1/sender-identities", `{"identity":"example.com"}`, "", "member")},
+		{"verify", VerifySenderIdentityHandler(mock, txtResolver{}, nil), senderRoleRequest(http.MethodPost, "/api/v1/sender-identities/"+id+"/verify", "", id, "member")},
+		{"delete", DeleteSenderIdentityHandler(mock, nil), senderRoleRequest(http.MethodDelete, "/api/v1/sender-identities/"+id, "", id, "member")},
+		{"policy", UpdateSenderPolicyHandler(mock, nil), senderRoleRequest(http.MethodPut, "/api/v1/sender-policy", `{"mode":"off"}`, "", "member")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, tt.req)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if changed {
+				t.Error("member changed the sender configuration")
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// sendingDomainRequest is the JSON body for PUT /api/v1/sending-domains/{domain}.
+type sendingDomainRequest struct {
+	ReplyTo    string `json:"reply_to"`
+	ReturnPath string `json:"return_path"`
+}
+
+// sendingDomainResponse is the JSON representation of a sending domain.
+type sendingDomainResponse struct {
+	Domain     string    `json:"domain"`
+	ReplyTo    string    `json:"reply_to,omitempty"`
+	ReturnPath string    `json:"return_path,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func toSendingDomainResponse(d storage.SendingDomain) sendingDomainResponse {
+	return sendingDomainResponse{
+		Domain:     d.Domain,
+		ReplyTo:    d.ReplyTo.String,
+		ReturnPath: d.ReturnPath.String,
+		UpdatedAt:  d.UpdatedAt.Time,
+	}
+}
+
+// sendingDomainParam returns the normalized {domain} URL parameter, or ""
+// if it is not a domain.
+func sendingDomainParam(r *http.Request) string {
+	domain, err := senderpolicy.NormalizeIdentity(chi.URLParam(r, "domain"))
+	if err != nil || strings.Contains(domain, "@") {
+		return ""
+	}
+	return domain
+}
+
+// validate trims the request and returns its validation errors.
+func (req *sendingDomainRequest) validate() []string {
+	req.ReplyTo = strings.TrimSpace(req.ReplyTo)
+	req.ReturnPath = strings.TrimSpace(req.ReturnPath)
+
+	var errs []string
+	if req.ReplyTo == "" && req.ReturnPath == "" {
+		errs = append(errs, "reply_to or return_path is required")
+	}
+	if req.ReplyTo != "" {
+		if _, err := mail.ParseAddressList(req.ReplyTo); err != nil {
+			errs = append(errs, "reply_to must be a list of addresses")
+		}
+	}
+	if req.ReturnPath != "" {
+		if addr, err := mail.ParseAddress(req.ReturnPath); err != nil || addr.Name != "" || addr.Address != req.ReturnPath {
+			errs = append(errs, "return_path must be a bare address")
+		}
+	}
+	return errs
+}
+
+// ListSendingDomainsHandler handles GET /api/v1/sending-domains.
+func ListSendingDomainsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		domains, err := queries.ListSendingDomainsByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]sendingDomainResponse, 0, len(domains))
+		for _, d := range domains {
+			resp = append(resp, toSendingDomainResponse(d))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// PutSendingDomainHandler handles PUT /api/v1/sending-domains/{domain}.
+// Sets the Reply-To and bounce address the worker applies to messages
+// sent from the domain that do not set their own. Requires group admin+
+// role.
+func PutSendingDomainHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		domain := sendingDomainParam(r)
+		if domain == "" {
+			respondError(w, http.StatusBadRequest, "invalid domain")
+			return
+		}
+
+		var req sendingDomainRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if errs := req.validate(); len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		d, err := queries.UpsertSendingDomain(r.Context(), storage.UpsertSendingDomainParams{
+			GroupID:    groupID,
+			Domain:     domain,
+			ReplyTo:    pgtype.Text{String: req.ReplyTo, Valid: req.ReplyTo != ""},
+			ReturnPath: pgtype.Text{String: req.ReturnPath, Valid: req.ReturnPath != ""},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateSendingDomain, "sending_domain", domain, map[string]interface{}{
+				"reply_to":    req.ReplyTo,
+				"return_path": req.ReturnPath,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toSendingDomainResponse(d))
+	}
+}
+
+// DeleteSendingDomainHandler handles DELETE /api/v1/sending-domains/{domain}.
+// Requires group admin+ role.
+func DeleteSendingDomainHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		domain := sendingDomainParam(r)
+		if domain == "" {
+			respondError(w, http.StatusBadRequest, "invalid domain")
+			return
+		}
+
+		n, err := queries.DeleteSendingDomain(r.Context(), storage.DeleteSendingDomainParams{GroupID: groupID, Domain: domain})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "sending domain not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteSendingDomain, "sending_domain", domain, nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func sendingDomainHTTPRequest(method, domain, body string) *http.Request {
+	return sendingDomainRoleRequest(method, domain, body, "admin")
+}
+
+func sendingDomainRoleRequest(method, domain, body, role string) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/sending-domains/"+domain, strings.NewReader(body))
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "organization")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("domain", domain)
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestPutSendingDomainHandler(t *testing.T) {
+	var got storage.UpsertSendingDomainParams
+	mock := &mockQuerier{
+		upsertSendingDomainFn: func(ctx context.Context, arg storage.UpsertSendingDomainParams) (storage.SendingDomain, error) {
+			got = arg
+			return storage.SendingDomain{GroupID: arg.GroupID, Domain: arg.Domain, ReplyTo: arg.ReplyTo, ReturnPath: arg.ReturnPath}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	PutSendingDomainHandler(mock, nil).ServeHTTP(rec, sendingDomainHTTPRequest(http.MethodPut, "Example.COM",
+		`{"reply_to":" Support <support@example.com> ","return_path":"bounces@example.com"}`))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID != testGroup().ID || got.Domain != "example.com" ||
+		got.ReplyTo.String != "Support <support@example.com>" || got.ReturnPath.String != "bounces@example.com" {
+		t.Errorf("unexpected upsert params: %+v", got)
+	}
+	var resp sendingDomainResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Domain != "example.com" || resp.ReturnPath != "bounces@example.com" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestPutSendingDomainHandler_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		domain   string
+		body     string
+		wantCode int
+	}{
+		{name: "address as domain", domain: "alice@example.com", body: `{"reply_to":"a@example.com"}`, wantCode: http.StatusBadRequest},
+		{name: "nothing set", domain: "example.com", body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "bad reply_to", domain: "example.com", body: `{"reply_to":"not an address"}`, wantCode: http.StatusBadRequest},
+		{name: "return_path with name", domain: "example.com", body: `{"return_path":"Bounces <b@example.com>"}`, wantCode: http.StatusBadRequest},
+		{name: "reply_to only", domain: "example.com", body: `{"reply_to":"a@example.com, b@example.com"}`, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			PutSendingDomainHandler(&mockQuerier{}, nil).ServeHTTP(rec, sendingDomainHTTPRequest(http.MethodPut, tt.domain, tt.body))
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeleteSendingDomainHandler_NotFound(t *testing.T) {
+	mock := &mockQuerier{
+		deleteSendingDomainFn: func(ctx context.Context, arg storage.DeleteSendingDomainParams) (int64, error) {
+			return 0, nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	DeleteSendingDomainHandler(mock, nil).ServeHTTP(rec, sendingDomainHTTPRequest(http.MethodDelete, "example.com", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestSendingDomainHandlers_RequireGroupAdmin(t *testing.T) {
+	mock := &mockQuerier{
+		upsertSendingDomainFn: func(ctx context.Context, arg storage.UpsertSendingDomainParams) (storage.SendingDomain, error) {
+			t.Error("sending domain updated")
+			return storage.SendingDomain{}, nil
+		},
+		deleteSendingDomainFn: func(ctx context.Context, arg storage.DeleteSendingDomainParams) (int64, error) {
+			t.Error("sending domain deleted")
+			return 1, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	PutSendingDomainHandler(mock, nil).ServeHTTP(rec, sendingDomainRoleRequest(http.MethodPut, "example.com", `{"reply_to":"support@example.com"}`, "member"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("put: expected status 403, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	DeleteSendingDomainHandler(mock, nil).ServeHTTP(rec, sendingDomainRoleRequest(http.MethodDelete, "example.com", "", "member"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("delete: expected status 403, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Defaults of POST /api/v1/providers/{id}/ses-setup.
+const (
+	defaultSESConfigurationSet = "smtp-proxy"
+	defaultSESTopicName        = "smtp-proxy-ses-events"
+)
+
+// sesSetupRequest is the JSON body for POST /api/v1/providers/{id}/ses-setup.
+// Every field is optional.
+type sesSetupRequest struct {
+	ConfigurationSet string `json:"configuration_set"`
+	TopicName        string `json:"topic_name"`
+	// WebhookURL defaults to the SES webhook on the host the request was
+	// made to.
+	WebhookURL string `json:"webhook_url"`
+}
+
+// SESSetupHandler handles POST /api/v1/providers/{id}/ses-setup. Creates,
+// or validates and repairs, the SES configuration set, SNS topic and
+// subscription that deliver the provider's delivery, bounce and complaint
+// events to the SES webhook. The configuration set is saved in the
+// provider's smtp_config so that messages are sent with it.
+func SESSetupHandler(queries storage.Querier, client provider.HTTPClient, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid provider ID format")
+			return
+		}
+
+		var req sesSetupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		esp, err := queries.GetProviderByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "provider not found")
+			return
+		}
+		if !canAccessGroup(r.Context(), queries, esp.GroupID) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		if esp.ProviderType != storage.ProviderTypeSes {
+			respondError(w, http.StatusBadRequest, "provider is not an ses provider")
+			return
+		}
+
+		p, err := provider.NewProviderFromStorage(&esp, client)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid provider config: "+err.Error())
+			return
+		}
+		ses := p.(*provider.SES)
+
+		var smtpConfig map[string]any
+		if len(esp.SmtpConfig) > 0 {
+			if err := json.Unmarshal(esp.SmtpConfig, &smtpConfig); err != nil {
+				respondError(w, http.StatusBadRequest, "invalid smtp_config: "+err.Error())
+				return
+			}
+		}
+		if smtpConfig == nil {
+			smtpConfig = make(map[string]any)
+		}
+		if req.ConfigurationSet == "" {
+			req.ConfigurationSet, _ = smtpConfig["configuration_set"].(string)
+		}
+		if req.ConfigurationSet == "" {
+			req.ConfigurationSet = defaultSESConfigurationSet
+		}
+		if req.TopicName == "" {
+			req.TopicName = defaultSESTopicName
+		}
+		if req.WebhookURL == "" {
+			scheme := "https"
+			if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") == "http" {
+				scheme = "http"
+			}
+			req.WebhookURL = scheme + "://" + r.Host + "/api/v1/webhooks/ses"
+		}
+		if u, err := url.Parse(req.WebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			respondError(w, http.StatusBadRequest, "webhook_url must be an absolute http(s) URL")
+			return
+		}
+
+		result, err := ses.SetupEvents(r.Context(), provider.SESSetupOptions{
+			ConfigurationSet: req.ConfigurationSet,
+			TopicName:        req.TopicName,
+			WebhookURL:       req.WebhookURL,
+		})
+		if err != nil {
+			respondError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		if smtpConfig["configuration_set"] != req.ConfigurationSet {
+			smtpConfig["configuration_set"] = req.ConfigurationSet
+			updated, err := json.Marshal(smtpConfig)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if _, err := queries.UpdateProvider(r.Context(), storage.UpdateProviderParams{
+				ID:           esp.ID,
+				Name:         esp.Name,
+				ProviderType: esp.ProviderType,
+				ApiKey:       esp.ApiKey,
+				SmtpConfig:   updated,
+				Enabled:      esp.Enabled,
+				CostModel:    esp.CostModel,
+			}); err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionSetupSESEvents, "provider", esp.ID.String(), map[string]interface{}{
+				"configuration_set": result.ConfigurationSet,
+				"topic_arn":         result.TopicARN,
+				"webhook_url":       req.WebhookURL,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakeSESClient answers SES and SNS calls as for an account where nothing
+// is set up yet.
+type fakeSESClient struct {
+	subscribed string
+}
+
+func (f *fakeSESClient) Do(req *provider.HTTPRequest) (*provider.HTTPResponse, error) {
+	if strings.HasSuffix(req.URL, "/") {
+		form, _ := url.ParseQuery(string(req.Body))
+		switch form.Get("Action") {
+		case "CreateTopic":
+			return &provider.HTTPResponse{StatusCode: 200, Body: []byte(`<CreateTopicResponse><CreateTopicResult><TopicArn>arn:aws:sns:us-east-1:1:t</TopicArn></CreateTopicResult></CreateTopicResponse>`)}, nil
+		case "ListSubscriptionsByTopic":
+			return &provider.HTTPResponse{StatusCode: 200, Body: []byte(`<ListSubscriptionsByTopicResponse/>`)}, nil
+		case "Subscribe":
+			f.subscribed = form.Get("Endpoint")
+			return &provider.HTTPResponse{StatusCode: 200, Body: []byte(`<SubscribeResponse><SubscribeResult><SubscriptionArn>pending confirmation</SubscriptionArn></SubscribeResult></SubscribeResponse>`)}, nil
+		}
+	}
+	if req.Method == "GET" && !strings.HasSuffix(req.URL, "/event-destinations") {
+		return &provider.HTTPResponse{StatusCode: 404}, nil
+	}
+	return &provider.HTTPResponse{StatusCode: 200, Body: []byte(`{}`)}, nil
+}
+
+func sesSetupRequestFor(id uuid.UUID, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/providers/"+id.String()+"/ses-setup", strings.NewReader(body))
+	req.Host = "relay.example.com"
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "system")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id.String())
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestSESSetupHandler(t *testing.T) {
+	prov := testProvider()
+	prov.ProviderType = storage.ProviderTypeSes
+	prov.ApiKey.String = "AKID:secret"
+	prov.SmtpConfig = []byte(`{"region":"us-east-1","endpoint":"https://aws.test"}`)
+
+	var updated storage.UpdateProviderParams
+	mock := &mockQuerier{
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+		updateProviderFn: func(ctx context.Context, arg storage.UpdateProviderParams) (storage.EspProvider, error) {
+			updated = arg
+			return prov, nil
+		},
+	}
+	client := &fakeSESClient{}
+
+	rec := httptest.NewRecorder()
+	SESSetupHandler(mock, client, nil).ServeHTTP(rec, sesSetupRequestFor(prov.ID, ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var res provider.SESSetupResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ConfigurationSet != "smtp-proxy" || !res.ConfigurationSetCreated || res.TopicARN != "arn:aws:sns:us-east-1:1:t" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if client.subscribed != "https://relay.example.com/api/v1/webhooks/ses" {
+		t.Errorf("subscribed endpoint = %q", client.subscribed)
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(updated.SmtpConfig, &cfg); err != nil {
+		t.Fatalf("provider not updated: %v", err)
+	}
+	if cfg["configuration_set"] != "smtp-proxy" || cfg["region"] != "us-east-1" {
+		t.Errorf("unexpected smtp_config: %s", updated.SmtpConfig)
+	}
+}
+
+func TestSESSetupHandler_Validation(t *testing.T) {
+	ses := testProvider()
+	ses.ProviderType = storage.ProviderTypeSes
+	ses.SmtpConfig = []byte(`{"region":"us-east-1"}`)
+
+	tests := []struct {
+		name string
+		prov storage.EspProvider
+		body string
+	}{
+		{name: "not ses", prov: testProvider()},
+		{name: "bad webhook url", prov: ses, body: `{"webhook_url":"relay.example.com/hook"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+					return tt.prov, nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			SESSetupHandler(mock, &fakeSESClient{}, nil).ServeHTTP(rec, sesSetupRequestFor(tt.prov.ID, tt.body))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// maxUserAgentLength bounds the User-Agent stored with a session.
+const maxUserAgentLength = 512
+
+// sessionResponse is the JSON representation of a refresh session. The
+// refresh token hash is never returned.
+type sessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	GroupID   uuid.UUID `json:"group_id"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastUsedAt is the last login or token refresh of the session.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+func toSessionResponse(s storage.Session) sessionResponse {
+	resp := sessionResponse{
+		ID:        s.ID,
+		GroupID:   s.GroupID,
+		UserAgent: s.UserAgent.String,
+		CreatedAt: timestampToTime(s.CreatedAt),
+		ExpiresAt: timestampToTime(s.ExpiresAt),
+	}
+	if s.IpAddress != nil {
+		resp.IPAddress = s.IpAddress.String()
+	}
+	if s.LastUsedAt.Valid {
+		t := s.LastUsedAt.Time
+		resp.LastUsedAt = &t
+	}
+	return resp
+}
+
+// sessionClient returns the User-Agent and client IP recorded with a
+// session created or refreshed by r.
+func sessionClient(r *http.Request) (pgtype.Text, *netip.Addr) {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+	return pgtype.Text{String: ua, Valid: ua != ""}, auth.IPToInet(auth.ClientIP(r))
+}
+
+// sessionUserID returns the user whose sessions a request manages: the
+// {id} user for /api/v1/users/{id}/sessions, otherwise the caller. Callers
+// may manage their own sessions; other users' sessions require group
+// admin+ role for the user's group. It writes the error response and
+// returns false when the request is not allowed.
+func sessionUserID(w http.ResponseWriter, r *http.Request, queries storage.Querier) (uuid.UUID, bool) {
+	callerID := auth.UserFromContext(r.Context())
+	idStr := chi.URLParam(r, "id")
+	if idStr == "" {
+		if callerID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return uuid.Nil, false
+		}
+		return callerID, true
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user ID format")
+		return uuid.Nil, false
+	}
+	if id != callerID && (!isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id)) {
+		respondError(w, http.StatusForbidden, "access denied")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// ListSessionsHandler handles GET /api/v1/auth/sessions and
+// GET /api/v1/users/{id}/sessions.
+// Lists the user's unexpired refresh sessions, newest first.
+func ListSessionsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r, queries)
+		if !ok {
+			return
+		}
+
+		sessions, err := queries.ListSessionsByUserID(r.Context(), userID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		now := time.Now()
+		resp := make([]sessionResponse, 0, len(sessions))
+		for _, s := range sessions {
+			if s.ExpiresAt.Valid && s.ExpiresAt.Time.Before(now) {
+				continue
+			}
+			resp = append(resp, toSessionResponse(s))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// RevokeSessionHandler handles DELETE /api/v1/auth/sessions/{sessionId} and
+// DELETE /api/v1/users/{id}/sessions/{sessionId}.
+// Revokes one refresh session; access tokens issued from it stay valid
+// until they expire.
+func RevokeSessionHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r, queries)
+		if !ok {
+			return
+		}
+		sessionID, err := uuid.Parse(chi.URLParam(r, "sessionId"))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid session ID format")
+			return
+		}
+
+		n, err := queries.DeleteUserSession(r.Context(), storage.DeleteUserSessionParams{
+			ID:     sessionID,
+			UserID: userID,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "session not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionRevokeSession, "session", sessionID.String(), map[string]interface{}{
+				"user_id": userID.String(),
+			})
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
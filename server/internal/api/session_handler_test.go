@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func sessionHTTPRequest(method string, params map[string]string, callerID uuid.UUID, role string) *http.Request {
+	req := httptest.NewRequest(method, "/", nil)
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, callerID, testGroup().ID, role, "organization")
+	return req.WithContext(ctx)
+}
+
+func TestListSessionsHandler_Own(t *testing.T) {
+	callerID := testUser().ID
+	ip := netip.MustParseAddr("203.0.113.7")
+	active := storage.Session{
+		ID:         uuid.New(),
+		UserID:     callerID,
+		UserAgent:  pgtype.Text{String: "curl/8.0", Valid: true},
+		IpAddress:  &ip,
+		CreatedAt:  pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		LastUsedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	expired := storage.Session{
+		ID:        uuid.New(),
+		UserID:    callerID,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+	}
+	mock := &mockQuerier{
+		listSessionsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Session, error) {
+			if userID != callerID {
+				t.Errorf("listed sessions of %s, want caller %s", userID, callerID)
+			}
+			return []storage.Session{active, expired}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListSessionsHandler(mock).ServeHTTP(rec, sessionHTTPRequest(http.MethodGet, nil, callerID, "member"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp []sessionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].ID != active.ID {
+		t.Fatalf("sessions = %+v, want only the active session", resp)
+	}
+	if resp[0].UserAgent != "curl/8.0" || resp[0].IPAddress != "203.0.113.7" || resp[0].LastUsedAt == nil {
+		t.Errorf("unexpected session: %+v", resp[0])
+	}
+}
+
+func TestListSessionsHandler_OtherUser(t *testing.T) {
+	otherGroup := storage.Group{ID: uuid.New(), GroupType: "company"}
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		role   string
+		code   int
+	}{
+		{"admin in the user's group", testUser().ID, "admin", http.StatusOK},
+		{"member role", testUser().ID, "member", http.StatusForbidden},
+		{"user outside the group tree", uuid.New(), "owner", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+					if userID == testUser().ID {
+						return []storage.Group{testGroup()}, nil
+					}
+					return []storage.Group{otherGroup}, nil
+				},
+				getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+					return otherGroup, nil
+				},
+			}
+			req := sessionHTTPRequest(http.MethodGet, map[string]string{"id": tt.userID.String()}, uuid.New(), tt.role)
+			rec := httptest.NewRecorder()
+			ListSessionsHandler(mock).ServeHTTP(rec, req)
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRevokeSessionHandler(t *testing.T) {
+	callerID := testUser().ID
+	sessionID := uuid.New()
+	var deleted storage.DeleteUserSessionParams
+	mock := &mockQuerier{
+		deleteUserSessionFn: func(ctx context.Context, arg storage.DeleteUserSessionParams) (int64, error) {
+			deleted = arg
+			if arg.ID == sessionID {
+				return 1, nil
+			}
+			return 0, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req := sessionHTTPRequest(http.MethodDelete, map[string]string{"sessionId": sessionID.String()}, callerID, "member")
+	RevokeSessionHandler(mock, nil).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if deleted.ID != sessionID || deleted.UserID != callerID {
+		t.Errorf("deleted %+v, want session %s of %s", deleted, sessionID, callerID)
+	}
+
+	rec = httptest.NewRecorder()
+	req = sessionHTTPRequest(http.MethodDelete, map[string]string{"sessionId": uuid.New().String()}, callerID, "member")
+	RevokeSessionHandler(mock, nil).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for another user's session, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = sessionHTTPRequest(http.MethodDelete, map[string]string{"sessionId": "not-a-uuid"}, callerID, "member")
+	RevokeSessionHandler(mock, nil).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid session ID, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/msgsign"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+)
+
+// signingKeyRequest is the JSON body for PUT /api/v1/signing-key.
+type signingKeyRequest struct {
+	Kind string `json:"kind"`
+	// Certificate is the PEM certificate chain of an S/MIME key.
+	Certificate string `json:"certificate"`
+	// PrivateKey is a PEM key for S/MIME or an armored secret key for PGP.
+	PrivateKey string `json:"private_key"`
+	Encrypt    bool   `json:"encrypt"`
+	OnFailure  string `json:"on_failure"`
+}
+
+// signingKeyResponse is the JSON representation of a signing key. The
+// private key is never returned.
+type signingKeyResponse struct {
+	Kind        string     `json:"kind"`
+	Certificate string     `json:"certificate,omitempty"`
+	Fingerprint string     `json:"fingerprint"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Encrypt     bool       `json:"encrypt"`
+	OnFailure   string     `json:"on_failure"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func toSigningKeyResponse(k storage.SigningKey) signingKeyResponse {
+	resp := signingKeyResponse{
+		Kind:        k.Kind,
+		Certificate: k.Certificate.String,
+		Fingerprint: k.Fingerprint,
+		Encrypt:     k.Encrypt,
+		OnFailure:   k.OnFailure,
+		UpdatedAt:   k.UpdatedAt.Time,
+	}
+	if k.ExpiresAt.Valid {
+		resp.ExpiresAt = &k.ExpiresAt.Time
+	}
+	return resp
+}
+
+// recipientCertificateResponse is the JSON representation of a recipient
+// encryption certificate.
+type recipientCertificateResponse struct {
+	Email       string     `json:"email"`
+	Fingerprint string     `json:"fingerprint"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func toRecipientCertificateResponse(c storage.RecipientCertificate) recipientCertificateResponse {
+	resp := recipientCertificateResponse{
+		Email:       c.Email,
+		Fingerprint: c.Fingerprint,
+		CreatedAt:   c.CreatedAt.Time,
+	}
+	if c.ExpiresAt.Valid {
+		resp.ExpiresAt = &c.ExpiresAt.Time
+	}
+	return resp
+}
+
+// validate trims the request and returns its validation errors.
+func (req *signingKeyRequest) validate() []string {
+	req.Kind = strings.TrimSpace(req.Kind)
+	req.OnFailure = strings.TrimSpace(req.OnFailure)
+	if req.OnFailure == "" {
+		req.OnFailure = msgsign.OnFailureSendUnsigned
+	}
+
+	var errs []string
+	switch req.Kind {
+	case msgsign.KindSMIME:
+		if req.Certificate == "" {
+			errs = append(errs, "certificate is required for smime keys")
+		}
+	case msgsign.KindPGP:
+		if req.Certificate != "" {
+			errs = append(errs, "certificate applies to smime keys only")
+		}
+		if req.Encrypt {
+			errs = append(errs, "encrypt applies to smime keys only")
+		}
+	default:
+		errs = append(errs, "kind must be smime or pgp")
+	}
+	if req.PrivateKey == "" {
+		errs = append(errs, "private_key is required")
+	}
+	if req.OnFailure != msgsign.OnFailureSendUnsigned && req.OnFailure != msgsign.OnFailureFail {
+		errs = append(errs, "on_failure must be send_unsigned or fail")
+	}
+	return errs
+}
+
+// GetSigningKeyHandler handles GET /api/v1/signing-key.
+func GetSigningKeyHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		key, err := queries.GetSigningKey(r.Context(), groupID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondError(w, http.StatusNotFound, "signing key not found")
+			return
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		respondJSON(w, http.StatusOK, toSigningKeyResponse(key))
+	}
+}
+
+// PutSigningKeyHandler handles PUT /api/v1/signing-key. Sets the S/MIME
+// certificate and key or the PGP key the worker signs the group's messages
+// with. The key is checked by loading it before it is stored. Requires
+// group admin+ role.
+func PutSigningKeyHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req signingKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if errs := req.validate(); len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		params := storage.UpsertSigningKeyParams{
+			GroupID:    groupID,
+			Kind:       req.Kind,
+			PrivateKey: req.PrivateKey,
+			Encrypt:    req.Encrypt,
+			OnFailure:  req.OnFailure,
+		}
+		switch req.Kind {
+		case msgsign.KindSMIME:
+			signer, err := msgsign.NewSMIMESigner(req.Certificate, req.PrivateKey)
+			if err != nil {
+				respondValidationErrors(w, []string{"invalid smime key: " + err.Error()})
+				return
+			}
+			cert := signer.Certificate()
+			params.Certificate = pgtype.Text{String: req.Certificate, Valid: true}
+			params.Fingerprint = tlsutil.Fingerprint(cert)
+			params.ExpiresAt = pgtype.Timestamptz{Time: cert.NotAfter, Valid: true}
+		case msgsign.KindPGP:
+			signer, err := msgsign.NewPGPSigner(req.PrivateKey)
+			if err != nil {
+				respondValidationErrors(w, []string{"invalid pgp key: " + err.Error()})
+				return
+			}
+			params.Fingerprint = signer.Fingerprint()
+		}
+
+		key, err := queries.UpsertSigningKey(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateSigningKey, "signing_key", groupID.String(), map[string]interface{}{
+				"kind":        req.Kind,
+				"fingerprint": params.Fingerprint,
+				"encrypt":     req.Encrypt,
+				"on_failure":  req.OnFailure,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toSigningKeyResponse(key))
+	}
+}
+
+// DeleteSigningKeyHandler handles DELETE /api/v1/signing-key. The group's
+// messages are sent unsigned afterwards. Requires group admin+ role.
+func DeleteSigningKeyHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		n, err := queries.DeleteSigningKey(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "signing key not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteSigningKey, "signing_key", groupID.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// recipientEmailParam returns the normalized {email} URL parameter, or ""
+// if it is not a bare address.
+func recipientEmailParam(r *http.Request) string {
+	email := strings.TrimSpace(chi.URLParam(r, "email"))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ""
+	}
+	return strings.ToLower(email)
+}
+
+// ListRecipientCertificatesHandler handles GET /api/v1/recipient-certificates.
+func ListRecipientCertificatesHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		certs, err := queries.ListRecipientCertificatesByGroupID(r.Context(), groupID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]recipientCertificateResponse, 0, len(certs))
+		for _, c := range certs {
+			resp = append(resp, toRecipientCertificateResponse(c))
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// PutRecipientCertificateHandler handles
+// PUT /api/v1/recipient-certificates/{email}. Sets the certificate messages
+// to the address are encrypted to when the signing key has encrypt set.
+// The body is {"certificate": "<PEM>"}. Requires group admin+ role.
+func PutRecipientCertificateHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		email := recipientEmailParam(r)
+		if email == "" {
+			respondError(w, http.StatusBadRequest, "invalid email address")
+			return
+		}
+
+		var req struct {
+			Certificate string `json:"certificate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		cert, err := tlsutil.ParseCertificatePEM([]byte(req.Certificate))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid certificate")
+			return
+		}
+		// Certificates are checked here so that a bad upload fails now
+		// rather than every message to the address.
+		if _, err := msgsign.EncryptSMIME(nil, []*x509.Certificate{cert}); err != nil {
+			respondError(w, http.StatusBadRequest, "certificate cannot be used for encryption")
+			return
+		}
+
+		c, err := queries.UpsertRecipientCertificate(r.Context(), storage.UpsertRecipientCertificateParams{
+			GroupID:     groupID,
+			Email:       email,
+			Certificate: req.Certificate,
+			Fingerprint: tlsutil.Fingerprint(cert),
+			ExpiresAt:   pgtype.Timestamptz{Time: cert.NotAfter, Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionUpdateRecipientCert, "recipient_certificate", email, map[string]interface{}{
+				"fingerprint": c.Fingerprint,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toRecipientCertificateResponse(c))
+	}
+}
+
+// DeleteRecipientCertificateHandler handles
+// DELETE /api/v1/recipient-certificates/{email}. Requires group admin+ role.
+func DeleteRecipientCertificateHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		email := recipientEmailParam(r)
+		if email == "" {
+			respondError(w, http.StatusBadRequest, "invalid email address")
+			return
+		}
+
+		n, err := queries.DeleteRecipientCertificate(r.Context(), storage.DeleteRecipientCertificateParams{GroupID: groupID, Email: email})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "recipient certificate not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteRecipientCert, "recipient_certificate", email, nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
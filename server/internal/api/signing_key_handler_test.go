@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// testSMIMEKey returns a self-signed PEM certificate and its PEM key.
+func testSMIMEKey(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app@example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return string(certPEM), string(keyPEM)
+}
+
+func jsonString(t *testing.T, s string) string {
+	t.Helper()
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func signingKeyHTTPRequest(method, path, email, body string) *http.Request {
+	return signingKeyRoleRequest(method, path, email, body, "admin")
+}
+
+func signingKeyRoleRequest(method, path, email, body, role string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	ctx := setJWTContext(req.Context(), testUser().ID, testGroup().ID, role, "organization")
+	rctx := chi.NewRouteContext()
+	if email != "" {
+		rctx.URLParams.Add("email", email)
+	}
+	return req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+}
+
+func TestPutSigningKeyHandler(t *testing.T) {
+	certPEM, keyPEM := testSMIMEKey(t)
+	var got storage.UpsertSigningKeyParams
+	mock := &mockQuerier{
+		upsertSigningKeyFn: func(ctx context.Context, arg storage.UpsertSigningKeyParams) (storage.SigningKey, error) {
+			got = arg
+			return storage.SigningKey{GroupID: arg.GroupID, Kind: arg.Kind, Certificate: arg.Certificate,
+				PrivateKey: arg.PrivateKey, Fingerprint: arg.Fingerprint, ExpiresAt: arg.ExpiresAt, OnFailure: arg.OnFailure}, nil
+		},
+	}
+
+	body := `{"kind":"smime","certificate":` + jsonString(t, certPEM) + `,"private_key":` + jsonString(t, keyPEM) + `}`
+	rec := httptest.NewRecorder()
+	PutSigningKeyHandler(mock, nil).ServeHTTP(rec, signingKeyHTTPRequest(http.MethodPut, "/api/v1/signing-key", "", body))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID != testGroup().ID || len(got.Fingerprint) != 64 || !got.ExpiresAt.Valid || got.OnFailure != "send_unsigned" {
+		t.Errorf("unexpected upsert params: %+v", got)
+	}
+	if strings.Contains(rec.Body.String(), "PRIVATE KEY") || strings.Contains(rec.Body.String(), "private_key") {
+		t.Errorf("response exposes the private key: %s", rec.Body.String())
+	}
+}
+
+func TestPutSigningKeyHandler_Validation(t *testing.T) {
+	certPEM, keyPEM := testSMIMEKey(t)
+	_, otherKey := testSMIMEKey(t)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "unknown kind", body: `{"kind":"gpg","private_key":"k"}`},
+		{name: "smime without certificate", body: `{"kind":"smime","private_key":` + jsonString(t, keyPEM) + `}`},
+		{name: "pgp encrypt", body: `{"kind":"pgp","private_key":"k","encrypt":true}`},
+		{name: "bad on_failure", body: `{"kind":"pgp","private_key":"k","on_failure":"drop"}`},
+		{name: "key mismatch", body: `{"kind":"smime","certificate":` + jsonString(t, certPEM) + `,"private_key":` + jsonString(t, otherKey) + `}`},
+		{name: "bad pgp key", body: `{"kind":"pgp","private_key":"not a key"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			PutSigningKeyHandler(&mockQuerier{}, nil).ServeHTTP(rec, signingKeyHTTPRequest(http.MethodPut, "/api/v1/signing-key", "", tt.body))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetSigningKeyHandler_NotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GetSigningKeyHandler(&mockQuerier{}).ServeHTTP(rec, signingKeyHTTPRequest(http.MethodGet, "/api/v1/signing-key", "", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestPutRecipientCertificateHandler(t *testing.T) {
+	certPEM, _ := testSMIMEKey(t)
+	var got storage.UpsertRecipientCertificateParams
+	mock := &mockQuerier{
+		upsertRecipientCertFn: func(ctx context.Context, arg storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error) {
+			got = arg
+			return storage.RecipientCertificate{GroupID: arg.GroupID, Email: arg.Email, Fingerprint: arg.Fingerprint}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	PutRecipientCertificateHandler(mock, nil).ServeHTTP(rec, signingKeyHTTPRequest(http.MethodPut,
+		"/api/v1/recipient-certificates/User@Example.com", "User@Example.com", `{"certificate":`+jsonString(t, certPEM)+`}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.Email != "user@example.com" || got.Certificate != certPEM || len(got.Fingerprint) != 64 {
+		t.Errorf("unexpected upsert params: %+v", got)
+	}
+
+	for _, tc := range []struct{ email, body string }{
+		{"not-an-address", `{"certificate":` + jsonString(t, certPEM) + `}`},
+		{"user@example.com", `{"certificate":"not a cert"}`},
+	} {
+		rec := httptest.NewRecorder()
+		PutRecipientCertificateHandler(mock, nil).ServeHTTP(rec, signingKeyHTTPRequest(http.MethodPut,
+			"/api/v1/recipient-certificates/"+tc.email, tc.email, tc.body))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tc.email, rec.Code)
+		}
+	}
+}
+
+func TestSigningKeyHandlers_RequireGroupAdmin(t *testing.T) {
+	changed := false
+	mock := &mockQuerier{
+		upsertSigningKeyFn: func(ctx context.Context, arg storage.UpsertSigningKeyParams) (storage.SigningKey, error) {
+			changed = true
+			return storage.SigningKey{}, nil
+		},
+		upsertRecipientCertFn: func(ctx context.Context, arg storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error) {
+			changed = true
+			return storage.RecipientCertificate{}, nil
+		},
+	}
+	certPEM, keyPEM := testSMIMEKey(t)
+	keyBody := `{"kind":"smime","certificate":` + jsonString(t, certPEM) + `,"private_key":` + jsonString(t, keyPEM) + `}`
+	certBody := `{"certificate":` + jsonString(t, certPEM) + `}`
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		req     *http.Request
+	}{
+		{"put signing key", PutSigningKeyHandler(mock, nil), signingKeyRoleRequest(http.MethodPut, "/api/v1/signing-key", "", keyBody, "member")},
+		{"delete signing key", DeleteSigningKeyHandler(mock, nil), signingKeyRoleRequest(http.MethodDelete, "/api/v1/signing-key", "", "", "member")},
+		{"put recipient certificate", PutRecipientCertificateHandler(mock, nil), signingKeyRoleRequest(http.MethodPut, "/api/v1/recipient-certificates/user@example.com", "user@example.com", certBody, "member")},
+		{"delete recipient certificate", DeleteRecipientCertificateHandler(mock, nil), signingKeyRoleRequest(http.MethodDelete, "/api/v1/recipient-certificates/user@example.com", "user@example.com", "", "member")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, tt.req)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if changed {
+				t.Error("member changed the signing configuration")
+			}
+		})
+	}
+}
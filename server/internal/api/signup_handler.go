@@ -0,0 +1,353 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+// Defaults for Signup fields that are not set.
+const (
+	DefaultSignupTTL          = 24 * time.Hour
+	DefaultSignupMonthlyLimit = 200
+)
+
+// signupVerifyPath is the endpoint verification links point to.
+const signupVerifyPath = "/api/v1/auth/verify-email"
+
+// errSignupUsed is returned inside the verify transaction when the signup
+// was completed concurrently.
+var errSignupUsed = errors.New("signup already used")
+
+// Signup configures self-service signup. Each verified signup gets its own
+// sandboxed company group, owned by the new user, which can only send to
+// the group's verified members until a system admin lifts the sandbox.
+type Signup struct {
+	// Mailer emails verification links. Signup is disabled without one.
+	Mailer *sysmail.Mailer
+	// TTL is how long a verification link stays valid. Defaults to
+	// DefaultSignupTTL.
+	TTL time.Duration
+	// MonthlyLimit is the new group's monthly message limit. Defaults to
+	// DefaultSignupMonthlyLimit.
+	MonthlyLimit int32
+	// RecipientValidation is the new group's recipient validation policy.
+	// Defaults to reject.
+	RecipientValidation string
+}
+
+func (c Signup) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultSignupTTL
+	}
+	return c.TTL
+}
+
+func (c Signup) monthlyLimit() int32 {
+	if c.MonthlyLimit <= 0 {
+		return DefaultSignupMonthlyLimit
+	}
+	return c.MonthlyLimit
+}
+
+func (c Signup) recipientValidation() string {
+	if !recipientValidationPolicies[c.RecipientValidation] {
+		return "reject"
+	}
+	return c.RecipientValidation
+}
+
+// signupRequest is the JSON body for POST /api/v1/auth/signup.
+// GroupName defaults to the email address.
+type signupRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	GroupName string `json:"group_name"`
+}
+
+// verifyEmailRequest is the JSON body for POST /api/v1/auth/verify-email.
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// signupDetailsResponse is the JSON response for GET /api/v1/auth/verify-email.
+type signupDetailsResponse struct {
+	Email     string    `json:"email"`
+	GroupName string    `json:"group_name"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// verifyEmailResponse is the JSON response for a completed signup.
+type verifyEmailResponse struct {
+	User  userResponse  `json:"user"`
+	Group groupResponse `json:"group"`
+}
+
+// SignupHandler handles POST /api/v1/auth/signup.
+// Registers a pending account and emails a signed verification link to
+// the address; nothing is created until the link is used. The password
+// must satisfy the password policy. The response is 202 whether or not
+// the email already has an account, so the endpoint cannot be used to
+// probe for accounts; an existing account is sent a notice instead.
+// No auth required.
+func SignupHandler(queries storage.Querier, jwtService *auth.JWTService, passwords *auth.PasswordPolicy, signup Signup) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req signupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		var errs []string
+		email := normalizeEmail(req.Email)
+		if email == "" {
+			errs = append(errs, "email must be a valid address")
+		}
+		if req.Password == "" {
+			errs = append(errs, "password is required")
+		}
+		if len(req.GroupName) > 255 {
+			errs = append(errs, "group_name must be at most 255 characters")
+		}
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+		if req.GroupName == "" {
+			req.GroupName = email
+		}
+		if !checkPassword(w, r, passwords, req.Password) {
+			return
+		}
+
+		ctx := r.Context()
+		if _, err := queries.GetUserByEmail(ctx, email); err == nil {
+			sendSignupNoticeEmail(r, signup.Mailer, email)
+			respondJSON(w, http.StatusAccepted, map[string]string{"status": "verification email sent"})
+			return
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if _, err := queries.GetGroupByName(ctx, req.GroupName); err == nil {
+			respondError(w, http.StatusConflict, "group name already exists")
+			return
+		}
+
+		passwordHash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if err := queries.DeleteExpiredSignups(ctx); err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		ttl := signup.ttl()
+		pending, err := queries.CreateSignup(ctx, storage.CreateSignupParams{
+			Email:        email,
+			PasswordHash: passwordHash,
+			GroupName:    req.GroupName,
+			ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		token, err := jwtService.GenerateActionToken(auth.PurposeVerifyEmail, pending.Email, pending.ID.String(), ttl)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if err := sendVerificationEmail(r, signup.Mailer, pending, token); err != nil {
+			respondError(w, http.StatusServiceUnavailable, "verification email could not be sent")
+			return
+		}
+
+		respondJSON(w, http.StatusAccepted, map[string]string{"status": "verification email sent"})
+	}
+}
+
+// sendVerificationEmail emails the verification link for a pending signup.
+func sendVerificationEmail(r *http.Request, mailer *sysmail.Mailer, pending storage.Signup, token string) error {
+	return mailer.SendTemplate(r.Context(), uuid.Nil, []string{pending.Email}, sysmail.TemplateVerifyEmail, sysmail.Data{
+		Email:     pending.Email,
+		Group:     pending.GroupName,
+		Link:      mailer.Link(signupVerifyPath, url.Values{"token": {token}}),
+		ExpiresAt: timestampToTime(pending.ExpiresAt),
+	})
+}
+
+// sendSignupNoticeEmail tells the owner of an existing account that
+// somebody tried to sign up with their address. Failures are ignored, as
+// the response must not differ from a new signup.
+func sendSignupNoticeEmail(r *http.Request, mailer *sysmail.Mailer, email string) {
+	_ = mailer.SendTemplate(r.Context(), uuid.Nil, []string{email}, sysmail.TemplateSignupNotice, sysmail.Data{Email: email})
+}
+
+// loadSignup validates a verification token and returns its pending
+// signup if it has not expired.
+func loadSignup(w http.ResponseWriter, r *http.Request, queries storage.Querier, jwtService *auth.JWTService, token string) (storage.Signup, bool) {
+	claims, err := jwtService.ValidateActionToken(token, auth.PurposeVerifyEmail)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return storage.Signup{}, false
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return storage.Signup{}, false
+	}
+
+	pending, err := queries.GetSignupByID(r.Context(), id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+		return storage.Signup{}, false
+	}
+	if err != nil || pending.Email != claims.Subject || !timestampToTime(pending.ExpiresAt).After(time.Now()) {
+		respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+		return storage.Signup{}, false
+	}
+	return pending, true
+}
+
+// GetSignupHandler handles GET /api/v1/auth/verify-email.
+// Returns the pending signup of the token query parameter so the user can
+// review it before confirming. No auth required; the token is the
+// credential.
+func GetSignupHandler(queries storage.Querier, jwtService *auth.JWTService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			respondError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+		pending, ok := loadSignup(w, r, queries, jwtService, token)
+		if !ok {
+			return
+		}
+
+		respondJSON(w, http.StatusOK, signupDetailsResponse{
+			Email:     pending.Email,
+			GroupName: pending.GroupName,
+			ExpiresAt: timestampToTime(pending.ExpiresAt),
+		})
+	}
+}
+
+// VerifyEmailHandler handles POST /api/v1/auth/verify-email.
+// Completes a signup with the token of the emailed link: creates the
+// sandboxed company group with the configured limits, the verified user
+// account and its owner membership in one transaction. Each link can be
+// used once. No auth required.
+func VerifyEmailHandler(queries storage.Querier, tx storage.TxRunner, jwtService *auth.JWTService, signup Signup, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req verifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if req.Token == "" {
+			respondValidationErrors(w, []string{"token is required"})
+			return
+		}
+
+		ctx := r.Context()
+		pending, ok := loadSignup(w, r, queries, jwtService, req.Token)
+		if !ok {
+			return
+		}
+
+		var (
+			user  storage.User
+			group storage.Group
+		)
+		err := tx.ExecTx(ctx, func(q storage.Querier) error {
+			n, err := q.DeleteSignup(ctx, pending.ID)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errSignupUsed
+			}
+
+			group, err = q.CreateGroup(ctx, storage.CreateGroupParams{
+				Name:      pending.GroupName,
+				GroupType: "company",
+			})
+			if err != nil {
+				return err
+			}
+			if group, err = q.UpdateGroup(ctx, storage.UpdateGroupParams{
+				ID:           group.ID,
+				Name:         group.Name,
+				Status:       group.Status,
+				MonthlyLimit: signup.monthlyLimit(),
+			}); err != nil {
+				return err
+			}
+			if group, err = q.UpdateGroupRecipientValidation(ctx, storage.UpdateGroupRecipientValidationParams{
+				ID:                  group.ID,
+				RecipientValidation: signup.recipientValidation(),
+			}); err != nil {
+				return err
+			}
+			if group, err = q.UpdateGroupSandbox(ctx, storage.UpdateGroupSandboxParams{
+				ID:      group.ID,
+				Sandbox: true,
+			}); err != nil {
+				return err
+			}
+
+			user, err = q.CreateUser(ctx, storage.CreateUserParams{
+				Email:        pending.Email,
+				PasswordHash: pending.PasswordHash,
+				AccountType:  "user",
+			})
+			if err != nil {
+				return err
+			}
+			if err := q.MarkEmailVerified(ctx, user.ID); err != nil {
+				return err
+			}
+			user.EmailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			_, err = q.CreateGroupMember(ctx, storage.CreateGroupMemberParams{
+				GroupID: group.ID,
+				UserID:  user.ID,
+				Role:    "owner",
+			})
+			return err
+		})
+		if errors.Is(err, errSignupUsed) {
+			respondError(w, http.StatusBadRequest, "invalid or expired verification link")
+			return
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusConflict, "email or group name already registered")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAuthEvent(ctx, r, group.ID, user.ID, auth.AuditActionSignup, "", map[string]interface{}{
+				"group_name":    group.Name,
+				"monthly_limit": group.MonthlyLimit,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, verifyEmailResponse{
+			User:  toUserResponse(user),
+			Group: toGroupResponse(group),
+		})
+	}
+}
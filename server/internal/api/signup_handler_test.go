@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+func testSignup() storage.Signup {
+	hash, _ := auth.HashPassword("a long enough password")
+	return storage.Signup{
+		ID:           uuid.MustParse("00000000-0000-0000-0000-000000000080"),
+		Email:        "new.user@example.com",
+		PasswordHash: hash,
+		GroupName:    "Acme",
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+}
+
+// signupQuerier returns a mock in which only the system group and the
+// given existing user exist, recording the bodies of sent emails.
+func signupQuerier(existing string, bodies *[]string) *mockQuerier {
+	return &mockQuerier{
+		getUserByEmailFn: func(ctx context.Context, email string) (storage.User, error) {
+			if email == existing {
+				return storage.User{ID: uuid.New(), Email: email}, nil
+			}
+			return storage.User{}, pgx.ErrNoRows
+		},
+		getGroupByNameFn: func(ctx context.Context, name string) (storage.Group, error) {
+			if name == "system" {
+				return storage.Group{ID: uuid.New(), Name: name, GroupType: "system"}, nil
+			}
+			return storage.Group{}, pgx.ErrNoRows
+		},
+		enqueueMessageFn: func(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			*bodies = append(*bodies, arg.Body.String)
+			return storage.Message{ID: uuid.New()}, nil
+		},
+	}
+}
+
+func TestSignupHandler(t *testing.T) {
+	var bodies []string
+	var created storage.CreateSignupParams
+	mock := signupQuerier("", &bodies)
+	mock.createSignupFn = func(ctx context.Context, arg storage.CreateSignupParams) (storage.Signup, error) {
+		created = arg
+		s := testSignup()
+		s.Email, s.GroupName, s.ExpiresAt = arg.Email, arg.GroupName, arg.ExpiresAt
+		return s, nil
+	}
+	jwtSvc := newInvitationJWTService()
+	signup := Signup{Mailer: sysmail.New(mock, directTx{mock}, sysmail.Config{BaseURL: "https://mail.example.com"})}
+
+	rec := httptest.NewRecorder()
+	SignupHandler(mock, jwtSvc, nil, signup).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup",
+		strings.NewReader(`{"email":"New.User@Example.com","password":"a long enough password"}`)))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if created.Email != "new.user@example.com" || created.GroupName != "new.user@example.com" {
+		t.Errorf("unexpected signup: %+v", created)
+	}
+	if auth.VerifyPassword(created.PasswordHash, "a long enough password") != nil {
+		t.Error("password hash does not match")
+	}
+	if d := time.Until(created.ExpiresAt.Time); d < 23*time.Hour || d > DefaultSignupTTL {
+		t.Errorf("signup expires in %v, want %v", d, DefaultSignupTTL)
+	}
+
+	// The emailed link carries a token for this signup.
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(bodies))
+	}
+	i := strings.Index(bodies[0], "https://mail.example.com/api/v1/auth/verify-email?token=")
+	if i < 0 {
+		t.Fatalf("email has no verification link:\n%s", bodies[0])
+	}
+	link, _ := url.Parse(strings.Fields(bodies[0][i:])[0])
+	claims, err := jwtSvc.ValidateActionToken(link.Query().Get("token"), auth.PurposeVerifyEmail)
+	if err != nil || claims.ID != testSignup().ID.String() || claims.Subject != "new.user@example.com" {
+		t.Errorf("invalid verification token: %+v, %v", claims, err)
+	}
+}
+
+func TestSignupHandler_ExistingAccount(t *testing.T) {
+	var bodies []string
+	mock := signupQuerier("taken@example.com", &bodies)
+	mock.createSignupFn = func(ctx context.Context, arg storage.CreateSignupParams) (storage.Signup, error) {
+		t.Error("signup created for an existing account")
+		return storage.Signup{}, nil
+	}
+	signup := Signup{Mailer: sysmail.New(mock, directTx{mock}, sysmail.Config{BaseURL: "https://mail.example.com"})}
+
+	rec := httptest.NewRecorder()
+	SignupHandler(mock, newInvitationJWTService(), nil, signup).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup",
+		strings.NewReader(`{"email":"taken@example.com","password":"a long enough password"}`)))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(bodies) != 1 || strings.Contains(bodies[0], "verify-email") {
+		t.Errorf("expected a notice without a verification link, got %q", bodies)
+	}
+}
+
+func TestSignupHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"invalid email", `{"email":"not an address","password":"a long enough password"}`, http.StatusBadRequest},
+		{"missing password", `{"email":"a@example.com"}`, http.StatusBadRequest},
+		{"weak password", `{"email":"a@example.com","password":"short"}`, http.StatusBadRequest},
+		{"group name taken", `{"email":"a@example.com","password":"a long enough password","group_name":"system"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			mock := signupQuerier("", &bodies)
+			signup := Signup{Mailer: sysmail.New(mock, directTx{mock}, sysmail.Config{})}
+			rec := httptest.NewRecorder()
+			SignupHandler(mock, newInvitationJWTService(), &auth.PasswordPolicy{MinLength: 10}, signup).ServeHTTP(rec,
+				httptest.NewRequest(http.MethodPost, "/api/v1/auth/signup", strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	pending := testSignup()
+	groupID, userID := uuid.New(), uuid.New()
+	var (
+		limits   storage.UpdateGroupParams
+		policy   storage.UpdateGroupRecipientValidationParams
+		sandbox  storage.UpdateGroupSandboxParams
+		user     storage.CreateUserParams
+		member   storage.CreateGroupMemberParams
+		verified uuid.UUID
+	)
+	mock := &mockQuerier{
+		getSignupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Signup, error) {
+			return pending, nil
+		},
+		createGroupFn: func(ctx context.Context, arg storage.CreateGroupParams) (storage.Group, error) {
+			return storage.Group{ID: groupID, Name: arg.Name, GroupType: arg.GroupType, Status: "active"}, nil
+		},
+		updateGroupFn: func(ctx context.Context, arg storage.UpdateGroupParams) (storage.Group, error) {
+			limits = arg
+			return storage.Group{ID: arg.ID, Name: arg.Name, MonthlyLimit: arg.MonthlyLimit}, nil
+		},
+		updateGroupRecipientValidationFn: func(ctx context.Context, arg storage.UpdateGroupRecipientValidationParams) (storage.Group, error) {
+			policy = arg
+			return storage.Group{ID: arg.ID}, nil
+		},
+		updateGroupSandboxFn: func(ctx context.Context, arg storage.UpdateGroupSandboxParams) (storage.Group, error) {
+			sandbox = arg
+			return storage.Group{ID: arg.ID, Name: pending.GroupName, Sandbox: arg.Sandbox}, nil
+		},
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			user = arg
+			return storage.User{ID: userID, Email: arg.Email, AccountType: arg.AccountType}, nil
+		},
+		markEmailVerifiedFn: func(ctx context.Context, id uuid.UUID) error {
+			verified = id
+			return nil
+		},
+		createGroupMemberFn: func(ctx context.Context, arg storage.CreateGroupMemberParams) (storage.GroupMember, error) {
+			member = arg
+			return storage.GroupMember{ID: uuid.New(), GroupID: arg.GroupID, UserID: arg.UserID, Role: arg.Role}, nil
+		},
+	}
+	jwtSvc := newInvitationJWTService()
+	token, err := jwtSvc.GenerateActionToken(auth.PurposeVerifyEmail, pending.Email, pending.ID.String(), time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateActionToken: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	VerifyEmailHandler(mock, directTx{mock}, jwtSvc, Signup{MonthlyLimit: 50}, nil).ServeHTTP(rec,
+		httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify-email", strings.NewReader(`{"token":"`+token+`"}`)))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp verifyEmailResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Group.Sandbox || resp.User.EmailVerifiedAt == nil {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if limits.MonthlyLimit != 50 || policy.RecipientValidation != "reject" || !sandbox.Sandbox {
+		t.Errorf("unexpected group settings: %+v, %+v, %+v", limits, policy, sandbox)
+	}
+	if user.Email != pending.Email || user.PasswordHash != pending.PasswordHash || user.AccountType != "user" || verified != userID {
+		t.Errorf("unexpected account: %+v, verified %v", user, verified)
+	}
+	if member.GroupID != groupID || member.UserID != userID || member.Role != "owner" {
+		t.Errorf("unexpected membership: %+v", member)
+	}
+}
+
+func TestVerifyEmailHandler_InvalidLink(t *testing.T) {
+	jwtSvc := newInvitationJWTService()
+	expired := testSignup()
+	expired.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	resubmitted := testSignup()
+	resubmitted.Email = "someone.else@example.com"
+
+	tests := []struct {
+		name     string
+		stored   storage.Signup
+		storeErr error
+		used     bool
+	}{
+		{"unknown", storage.Signup{}, pgx.ErrNoRows, false},
+		{"expired", expired, nil, false},
+		{"different email", resubmitted, nil, false},
+		{"used concurrently", testSignup(), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getSignupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Signup, error) {
+					return tt.stored, tt.storeErr
+				},
+				deleteSignupFn: func(ctx context.Context, id uuid.UUID) (int64, error) {
+					if tt.used {
+						return 0, nil
+					}
+					return 1, nil
+				},
+				createGroupFn: func(ctx context.Context, arg storage.CreateGroupParams) (storage.Group, error) {
+					t.Error("group created for an invalid link")
+					return storage.Group{}, nil
+				},
+			}
+			token, _ := jwtSvc.GenerateActionToken(auth.PurposeVerifyEmail, testSignup().Email, testSignup().ID.String(), time.Hour)
+			rec := httptest.NewRecorder()
+			VerifyEmailHandler(mock, directTx{mock}, jwtSvc, Signup{}, nil).ServeHTTP(rec,
+				httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify-email", strings.NewReader(`{"token":"`+token+`"}`)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
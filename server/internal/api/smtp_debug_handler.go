@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+const (
+	defaultSMTPDebugMinutes = 30
+	maxSMTPDebugMinutes     = 24 * 60
+)
+
+// createSMTPDebugTargetRequest enables SMTP debug mode for an SMTP user or
+// a client IP address. Exactly one of UserID and IP must be set.
+type createSMTPDebugTargetRequest struct {
+	UserID          *uuid.UUID `json:"user_id"`
+	IP              string     `json:"ip"`
+	DurationMinutes int        `json:"duration_minutes"`
+}
+
+type smtpDebugTargetResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	GroupID   *uuid.UUID `json:"group_id,omitempty"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type smtpTranscriptResponse struct {
+	ID         uuid.UUID       `json:"id"`
+	UserID     *uuid.UUID      `json:"user_id,omitempty"`
+	RemoteAddr string          `json:"remote_addr"`
+	Lines      json.RawMessage `json:"lines"`
+	StartedAt  time.Time       `json:"started_at"`
+	EndedAt    time.Time       `json:"ended_at"`
+}
+
+func toSMTPDebugTargetResponse(t storage.SmtpDebugTarget) smtpDebugTargetResponse {
+	resp := smtpDebugTargetResponse{
+		ID:        t.ID,
+		ExpiresAt: t.ExpiresAt.Time,
+		Active:    t.ExpiresAt.Time.After(time.Now()),
+		CreatedAt: t.CreatedAt.Time,
+	}
+	if t.GroupID.Valid {
+		id := uuid.UUID(t.GroupID.Bytes)
+		resp.GroupID = &id
+	}
+	if t.UserID.Valid {
+		id := uuid.UUID(t.UserID.Bytes)
+		resp.UserID = &id
+	}
+	if t.IpAddress != nil {
+		resp.IP = t.IpAddress.String()
+	}
+	return resp
+}
+
+func toSMTPTranscriptResponse(t storage.SmtpTranscript) smtpTranscriptResponse {
+	resp := smtpTranscriptResponse{
+		ID:         t.ID,
+		RemoteAddr: t.RemoteAddr,
+		Lines:      json.RawMessage(t.Lines),
+		StartedAt:  t.StartedAt.Time,
+		EndedAt:    t.EndedAt.Time,
+	}
+	if t.UserID.Valid {
+		id := uuid.UUID(t.UserID.Bytes)
+		resp.UserID = &id
+	}
+	return resp
+}
+
+// isGroupAdmin reports whether the caller is a system user or an owner or
+// admin of their group.
+func isGroupAdmin(r *http.Request) bool {
+	role := auth.RoleFromContext(r.Context())
+	return auth.GroupTypeFromContext(r.Context()) == "system" || role == "owner" || role == "admin"
+}
+
+// CreateSMTPDebugTargetHandler handles POST /api/v1/smtp-debug.
+// Enables transcript recording for an SMTP user or client IP for
+// duration_minutes (default 30, at most 1440). User targets require group
+// admin+ role for the user's group; IP targets require a system user.
+func CreateSMTPDebugTargetHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createSMTPDebugTargetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		if (req.UserID == nil) == (req.IP == "") {
+			respondError(w, http.StatusBadRequest, "exactly one of user_id or ip is required")
+			return
+		}
+		if req.DurationMinutes == 0 {
+			req.DurationMinutes = defaultSMTPDebugMinutes
+		}
+		if req.DurationMinutes < 0 || req.DurationMinutes > maxSMTPDebugMinutes {
+			respondError(w, http.StatusBadRequest, "duration_minutes must be between 1 and 1440")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		params := storage.CreateSMTPDebugTargetParams{
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute), Valid: true},
+		}
+		if callerID := auth.UserFromContext(r.Context()); callerID != uuid.Nil {
+			params.CreatedBy = pgtype.UUID{Bytes: callerID, Valid: true}
+		}
+
+		if req.UserID != nil {
+			user, err := queries.GetUserByID(r.Context(), *req.UserID)
+			if err != nil {
+				respondStorageError(w, err, http.StatusNotFound, "user not found")
+				return
+			}
+			if user.AccountType != "smtp" {
+				respondError(w, http.StatusBadRequest, "user is not an SMTP account")
+				return
+			}
+			groups, err := queries.ListGroupsByUserID(r.Context(), user.ID)
+			if err != nil || len(groups) == 0 {
+				respondError(w, http.StatusNotFound, "user not found")
+				return
+			}
+			if !canAccessGroup(r.Context(), queries, groups[0].ID) {
+				respondError(w, http.StatusForbidden, "access denied")
+				return
+			}
+			params.UserID = pgtype.UUID{Bytes: user.ID, Valid: true}
+			params.GroupID = pgtype.UUID{Bytes: groups[0].ID, Valid: true}
+		} else {
+			if auth.GroupTypeFromContext(r.Context()) != "system" {
+				respondError(w, http.StatusForbidden, "IP debug targets require a system admin")
+				return
+			}
+			ip, err := netip.ParseAddr(req.IP)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid ip address")
+				return
+			}
+			ip = ip.Unmap()
+			params.IpAddress = &ip
+		}
+
+		target, err := queries.CreateSMTPDebugTarget(r.Context(), params)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionEnableSMTPDebug, "smtp_debug_target", target.ID.String(), map[string]interface{}{
+				"user_id":          req.UserID,
+				"ip":               req.IP,
+				"duration_minutes": req.DurationMinutes,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, toSMTPDebugTargetResponse(target))
+	}
+}
+
+// ListSMTPDebugTargetsHandler handles GET /api/v1/smtp-debug.
+// System users see every target; group admins see the targets of their
+// group.
+func ListSMTPDebugTargetsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var targets []storage.SmtpDebugTarget
+		var err error
+		if auth.GroupTypeFromContext(r.Context()) == "system" {
+			targets, err = queries.ListSMTPDebugTargets(r.Context())
+		} else {
+			groupID := auth.GroupIDFromContext(r.Context())
+			targets, err = queries.ListSMTPDebugTargetsByGroupID(r.Context(), pgtype.UUID{Bytes: groupID, Valid: true})
+		}
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]smtpDebugTargetResponse, len(targets))
+		for i, t := range targets {
+			resp[i] = toSMTPDebugTargetResponse(t)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DeleteSMTPDebugTargetHandler handles DELETE /api/v1/smtp-debug/{id}.
+// Ends debug mode for the target and deletes its transcripts.
+func DeleteSMTPDebugTargetHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target, ok := loadSMTPDebugTarget(w, r, queries)
+		if !ok {
+			return
+		}
+
+		if err := queries.DeleteSMTPDebugTarget(r.Context(), target.ID); err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDisableSMTPDebug, "smtp_debug_target", target.ID.String(), nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListSMTPTranscriptsHandler handles GET /api/v1/smtp-debug/{id}/transcripts.
+// Returns the transcripts recorded for the target, newest first.
+func ListSMTPTranscriptsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target, ok := loadSMTPDebugTarget(w, r, queries)
+		if !ok {
+			return
+		}
+
+		transcripts, err := queries.ListSMTPTranscriptsByTarget(r.Context(), target.ID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]smtpTranscriptResponse, len(transcripts))
+		for i, t := range transcripts {
+			resp[i] = toSMTPTranscriptResponse(t)
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// loadSMTPDebugTarget resolves the {id} debug target and checks that the
+// caller may manage it. IP targets belong to no group and are limited to
+// system users. It writes the error response and returns false on failure.
+func loadSMTPDebugTarget(w http.ResponseWriter, r *http.Request, queries storage.Querier) (storage.SmtpDebugTarget, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid debug target ID format")
+		return storage.SmtpDebugTarget{}, false
+	}
+
+	target, err := queries.GetSMTPDebugTarget(r.Context(), id)
+	if err != nil {
+		respondStorageError(w, err, http.StatusNotFound, "debug target not found")
+		return storage.SmtpDebugTarget{}, false
+	}
+
+	if auth.GroupTypeFromContext(r.Context()) != "system" {
+		if !isGroupAdmin(r) || !target.GroupID.Valid || !canAccessGroup(r.Context(), queries, uuid.UUID(target.GroupID.Bytes)) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return storage.SmtpDebugTarget{}, false
+		}
+	}
+	return target, true
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+var systemGroupID = uuid.MustParse("00000000-0000-0000-0000-000000000099")
+
+func smtpDebugRequest(method, path, body, targetID string, groupID uuid.UUID, role, groupType string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	if targetID != "" {
+		rctx.URLParams.Add("id", targetID)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = setJWTContext(ctx, testUser().ID, groupID, role, groupType)
+	return req.WithContext(ctx)
+}
+
+func smtpUser() storage.User {
+	u := testUser()
+	u.ID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	u.AccountType = "smtp"
+	return u
+}
+
+func TestCreateSMTPDebugTargetHandler_User(t *testing.T) {
+	grp := testGroup()
+	user := smtpUser()
+	var got storage.CreateSMTPDebugTargetParams
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return user, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{grp}, nil
+		},
+		createSMTPDebugTargetFn: func(ctx context.Context, arg storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error) {
+			got = arg
+			return storage.SmtpDebugTarget{ID: uuid.New(), GroupID: arg.GroupID, UserID: arg.UserID, ExpiresAt: arg.ExpiresAt}, nil
+		},
+	}
+
+	body := `{"user_id":"` + user.ID.String() + `","duration_minutes":10}`
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/smtp-debug", body, "", grp.ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	CreateSMTPDebugTargetHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if uuid.UUID(got.UserID.Bytes) != user.ID || uuid.UUID(got.GroupID.Bytes) != grp.ID {
+		t.Errorf("unexpected target params: %+v", got)
+	}
+	if d := time.Until(got.ExpiresAt.Time); d < 9*time.Minute || d > 10*time.Minute {
+		t.Errorf("expected expiry in ~10 minutes, got %v", d)
+	}
+	var resp smtpDebugTargetResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Active {
+		t.Error("expected target to be active")
+	}
+}
+
+func TestCreateSMTPDebugTargetHandler_IPRequiresSystem(t *testing.T) {
+	mock := &mockQuerier{}
+
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/smtp-debug", `{"ip":"203.0.113.7"}`, "", testGroup().ID, "owner", "organization")
+	rec := httptest.NewRecorder()
+	CreateSMTPDebugTargetHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestCreateSMTPDebugTargetHandler_IPSystem(t *testing.T) {
+	var got storage.CreateSMTPDebugTargetParams
+	mock := &mockQuerier{
+		createSMTPDebugTargetFn: func(ctx context.Context, arg storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error) {
+			got = arg
+			return storage.SmtpDebugTarget{ID: uuid.New(), IpAddress: arg.IpAddress, ExpiresAt: arg.ExpiresAt}, nil
+		},
+	}
+
+	req := smtpDebugRequest(http.MethodPost, "/api/v1/smtp-debug", `{"ip":"203.0.113.7"}`, "", systemGroupID, "admin", "system")
+	rec := httptest.NewRecorder()
+	CreateSMTPDebugTargetHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.IpAddress == nil || got.IpAddress.String() != "203.0.113.7" {
+		t.Errorf("unexpected ip: %v", got.IpAddress)
+	}
+	if d := time.Until(got.ExpiresAt.Time); d < 29*time.Minute || d > 30*time.Minute {
+		t.Errorf("expected default expiry of 30 minutes, got %v", d)
+	}
+}
+
+func TestCreateSMTPDebugTargetHandler_Validation(t *testing.T) {
+	tests := map[string]string{
+		"neither":       `{}`,
+		"both":          `{"user_id":"00000000-0000-0000-0000-000000000002","ip":"203.0.113.7"}`,
+		"too long":      `{"ip":"203.0.113.7","duration_minutes":1441}`,
+		"invalid ip":    `{"ip":"not-an-ip"}`,
+		"invalid body":  `{`,
+		"negative time": `{"ip":"203.0.113.7","duration_minutes":-5}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := smtpDebugRequest(http.MethodPost, "/api/v1/smtp-debug", body, "", systemGroupID, "admin", "system")
+			rec := httptest.NewRecorder()
+			CreateSMTPDebugTargetHandler(&mockQuerier{}, nil).ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestListSMTPTranscriptsHandler_Success(t *testing.T) {
+	grp := testGroup()
+	target := storage.SmtpDebugTarget{
+		ID:        uuid.New(),
+		GroupID:   pgtype.UUID{Bytes: grp.ID, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	mock := &mockQuerier{
+		getSMTPDebugTargetFn: func(ctx context.Context, id uuid.UUID) (storage.SmtpDebugTarget, error) {
+			return target, nil
+		},
+		listSMTPTranscriptsByTargetFn: func(ctx context.Context, targetID uuid.UUID) ([]storage.SmtpTranscript, error) {
+			return []storage.SmtpTranscript{{
+				ID:         uuid.New(),
+				TargetID:   targetID,
+				RemoteAddr: "198.51.100.4:40000",
+				Lines:      []byte(`[{"dir":"C","text":"MAIL FROM:<a@b.io>"}]`),
+			}}, nil
+		},
+	}
+
+	req := smtpDebugRequest(http.MethodGet, "/api/v1/smtp-debug/"+target.ID.String()+"/transcripts", "", target.ID.String(), grp.ID, "admin", "organization")
+	rec := httptest.NewRecorder()
+	ListSMTPTranscriptsHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp []smtpTranscriptResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || !strings.Contains(string(resp[0].Lines), "MAIL FROM") {
+		t.Errorf("unexpected transcripts: %+v", resp)
+	}
+}
+
+func TestListSMTPTranscriptsHandler_IPTargetForbiddenForGroupAdmin(t *testing.T) {
+	target := storage.SmtpDebugTarget{ID: uuid.New()}
+	mock := &mockQuerier{
+		getSMTPDebugTargetFn: func(ctx context.Context, id uuid.UUID) (storage.SmtpDebugTarget, error) {
+			return target, nil
+		},
+	}
+
+	req := smtpDebugRequest(http.MethodGet, "/api/v1/smtp-debug/"+target.ID.String()+"/transcripts", "", target.ID.String(), testGroup().ID, "owner", "organization")
+	rec := httptest.NewRecorder()
+	ListSMTPTranscriptsHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestDeleteSMTPDebugTargetHandler_NotFound(t *testing.T) {
+	id := uuid.New().String()
+	req := smtpDebugRequest(http.MethodDelete, "/api/v1/smtp-debug/"+id, "", id, systemGroupID, "admin", "system")
+	rec := httptest.NewRecorder()
+	DeleteSMTPDebugTargetHandler(&mockQuerier{}, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/cost"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// maxStatsRangeDays caps the date range of a single stats report.
+const maxStatsRangeDays = 366
+
+// costRowResponse is the estimated spend of one group on one provider for
+// a single day.
+type costRowResponse struct {
+	Date         string    `json:"date"`
+	GroupID      uuid.UUID `json:"group_id"`
+	ProviderID   uuid.UUID `json:"provider_id"`
+	ProviderName string    `json:"provider_name,omitempty"`
+	Messages     int64     `json:"messages"`
+	Cost         float64   `json:"cost"`
+	Currency     string    `json:"currency,omitempty"`
+}
+
+// costTotalResponse sums the rows of a cost report that share a currency.
+// Messages sent through providers without a cost model are totalled under
+// an empty currency.
+type costTotalResponse struct {
+	Currency string  `json:"currency"`
+	Messages int64   `json:"messages"`
+	Cost     float64 `json:"cost"`
+}
+
+// costReportResponse is the JSON response for GET /api/v1/stats/costs.
+type costReportResponse struct {
+	From   string              `json:"from"`
+	To     string              `json:"to"`
+	Rows   []costRowResponse   `json:"rows"`
+	Totals []costTotalResponse `json:"totals"`
+}
+
+// GetCostStatsHandler handles GET /api/v1/stats/costs.
+// Estimates delivery spend per group, provider and day from delivered
+// messages and each provider's cost model.
+// Supports query params: from and to (YYYY-MM-DD, inclusive; default the
+// current month to date) and group_id. Non-system callers see their own
+// group, or one of its sub-groups via group_id; system admins see every
+// group unless group_id is given.
+func GetCostStatsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		callerGroupID := auth.GroupIDFromContext(r.Context())
+		callerGroupType := auth.GroupTypeFromContext(r.Context())
+
+		groupID := uuid.Nil
+		if callerGroupType != "system" {
+			groupID = callerGroupID
+		}
+		if g := r.URL.Query().Get("group_id"); g != "" {
+			id, err := uuid.Parse(g)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid group_id format")
+				return
+			}
+			if !canAccessGroup(r.Context(), queries, id) {
+				respondError(w, http.StatusForbidden, "access denied")
+				return
+			}
+			groupID = id
+		}
+
+		from, to, ok := statsDateRange(w, r)
+		if !ok {
+			return
+		}
+
+		// Tiered pricing depends on month-to-date volume, so volume is
+		// loaded from the start of from's month and trimmed afterwards.
+		monthStart := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+		volumes, err := queries.DailyDeliveryVolume(r.Context(), storage.DailyDeliveryVolumeParams{
+			CreatedAt:   pgtype.Timestamptz{Time: monthStart, Valid: true},
+			CreatedAt_2: pgtype.Timestamptz{Time: to.AddDate(0, 0, 1), Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		// Providers that were deleted since delivering keep their volume
+		// but are reported without a cost model.
+		models := make(map[uuid.UUID]*cost.Model)
+		names := make(map[uuid.UUID]string)
+		input := make([]cost.Volume, 0, len(volumes))
+		for _, v := range volumes {
+			providerID := uuid.UUID(v.ProviderID.Bytes)
+			if _, seen := names[providerID]; !seen {
+				names[providerID] = ""
+				if p, err := queries.GetProviderByID(r.Context(), providerID); err == nil {
+					names[providerID] = p.Name
+					models[providerID], _ = cost.ParseModel(p.CostModel)
+				}
+			}
+			input = append(input, cost.Volume{
+				Day:        v.Day.Time,
+				GroupID:    uuid.UUID(v.GroupID.Bytes),
+				ProviderID: providerID,
+				Count:      v.Count,
+			})
+		}
+
+		resp := costReportResponse{
+			From:   from.Format(time.DateOnly),
+			To:     to.Format(time.DateOnly),
+			Rows:   []costRowResponse{},
+			Totals: []costTotalResponse{},
+		}
+		totals := make(map[string]*costTotalResponse)
+		for _, s := range cost.Estimate(input, models) {
+			if s.Day.Before(from) || s.Day.After(to) {
+				continue
+			}
+			if groupID != uuid.Nil && s.GroupID != groupID {
+				continue
+			}
+			resp.Rows = append(resp.Rows, costRowResponse{
+				Date:         s.Day.Format(time.DateOnly),
+				GroupID:      s.GroupID,
+				ProviderID:   s.ProviderID,
+				ProviderName: names[s.ProviderID],
+				Messages:     s.Messages,
+				Cost:         s.Cost,
+				Currency:     s.Currency,
+			})
+			t, ok := totals[s.Currency]
+			if !ok {
+				t = &costTotalResponse{Currency: s.Currency}
+				totals[s.Currency] = t
+			}
+			t.Messages += s.Messages
+			t.Cost += s.Cost
+		}
+		for _, t := range totals {
+			resp.Totals = append(resp.Totals, *t)
+		}
+		sort.Slice(resp.Totals, func(i, j int) bool {
+			return resp.Totals[i].Currency < resp.Totals[j].Currency
+		})
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// statsDateRange parses the from and to query params of a stats report
+// (YYYY-MM-DD, inclusive; default the current month to date). It writes the
+// error response and returns false on failure.
+func statsDateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	now := time.Now().UTC()
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid to date, expected YYYY-MM-DD")
+			return from, to, false
+		}
+		to = t
+	}
+	from = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid from date, expected YYYY-MM-DD")
+			return from, to, false
+		}
+		from = t
+	}
+	if to.Before(from) {
+		respondError(w, http.StatusBadRequest, "from must not be after to")
+		return from, to, false
+	}
+	if to.Sub(from) > maxStatsRangeDays*24*time.Hour {
+		respondError(w, http.StatusBadRequest, "date range must not exceed 366 days")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// tagStatsRowResponse counts the messages of one tag by status.
+type tagStatsRowResponse struct {
+	Tag      string           `json:"tag"`
+	Messages int64            `json:"messages"`
+	ByStatus map[string]int64 `json:"by_status"`
+}
+
+// tagStatsResponse is the JSON response for GET /api/v1/stats/tags.
+type tagStatsResponse struct {
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	GroupID uuid.UUID             `json:"group_id"`
+	Tags    []tagStatsRowResponse `json:"tags"`
+}
+
+// GetTagStatsHandler handles GET /api/v1/stats/tags.
+// Counts a group's messages per tag and status, by submission date.
+// Supports query params: from and to (YYYY-MM-DD, inclusive; default the
+// current month to date) and group_id (the caller's group or a sub-group).
+func GetTagStatsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, ok := requestGroupID(w, r, queries)
+		if !ok {
+			return
+		}
+		from, to, ok := statsDateRange(w, r)
+		if !ok {
+			return
+		}
+
+		rows, err := queries.CountGroupMessagesByTag(r.Context(), storage.CountGroupMessagesByTagParams{
+			GroupID:      pgtype.UUID{Bytes: groupID, Valid: true},
+			EnqueuedAt:   pgtype.Timestamptz{Time: from, Valid: true},
+			EnqueuedAt_2: pgtype.Timestamptz{Time: to.AddDate(0, 0, 1), Valid: true},
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := tagStatsResponse{
+			From:    from.Format(time.DateOnly),
+			To:      to.Format(time.DateOnly),
+			GroupID: groupID,
+			Tags:    []tagStatsRowResponse{},
+		}
+		// Rows are ordered by tag, so each tag's statuses are adjacent.
+		for _, row := range rows {
+			n := len(resp.Tags)
+			if n == 0 || resp.Tags[n-1].Tag != row.Tag {
+				resp.Tags = append(resp.Tags, tagStatsRowResponse{Tag: row.Tag, ByStatus: map[string]int64{}})
+				n++
+			}
+			resp.Tags[n-1].Messages += row.Messages
+			resp.Tags[n-1].ByStatus[string(row.Status)] = row.Messages
+		}
+
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func volumeRow(providerID, groupID uuid.UUID, day string, count int64) storage.DailyDeliveryVolumeRow {
+	t, _ := time.Parse(time.DateOnly, day)
+	return storage.DailyDeliveryVolumeRow{
+		ProviderID: pgtype.UUID{Bytes: providerID, Valid: true},
+		GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
+		Day:        pgtype.Date{Time: t, Valid: true},
+		Count:      count,
+	}
+}
+
+func TestGetCostStatsHandler_EstimatesSpend(t *testing.T) {
+	prov := testProvider()
+	prov.CostModel = []byte(`{"currency":"USD","tiers":[{"up_to":1000,"price_per_1k":1},{"price_per_1k":0.5}]}`)
+	groupID := prov.GroupID
+	otherGroup := uuid.New()
+
+	var gotParams storage.DailyDeliveryVolumeParams
+	mock := &mockQuerier{
+		dailyDeliveryVolumeFn: func(ctx context.Context, arg storage.DailyDeliveryVolumeParams) ([]storage.DailyDeliveryVolumeRow, error) {
+			gotParams = arg
+			return []storage.DailyDeliveryVolumeRow{
+				volumeRow(prov.ID, groupID, "2026-03-01", 1000),
+				volumeRow(prov.ID, otherGroup, "2026-03-01", 500),
+				volumeRow(prov.ID, groupID, "2026-03-10", 1000),
+			}, nil
+		},
+		getProviderByIDFn: func(ctx context.Context, id uuid.UUID) (storage.EspProvider, error) {
+			return prov, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/costs?from=2026-03-05&to=2026-03-31", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, groupID, "member", "organization"))
+	rec := httptest.NewRecorder()
+
+	GetCostStatsHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !gotParams.CreatedAt.Time.Equal(want) {
+		t.Errorf("expected query from start of month, got %v", gotParams.CreatedAt.Time)
+	}
+	if want := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !gotParams.CreatedAt_2.Time.Equal(want) {
+		t.Errorf("expected exclusive end 2026-04-01, got %v", gotParams.CreatedAt_2.Time)
+	}
+
+	var resp costReportResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// Only the caller's group on 2026-03-10 is in range. 1,500 messages were
+	// already sent this month, so all 1,000 are priced at the second tier.
+	if len(resp.Rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %+v", len(resp.Rows), resp.Rows)
+	}
+	row := resp.Rows[0]
+	if row.Date != "2026-03-10" || row.ProviderName != prov.Name || row.Messages != 1000 {
+		t.Errorf("unexpected row: %+v", row)
+	}
+	if math.Abs(row.Cost-0.5) > 1e-9 {
+		t.Errorf("expected cost 0.5, got %v", row.Cost)
+	}
+	if len(resp.Totals) != 1 || resp.Totals[0].Currency != "USD" || resp.Totals[0].Messages != 1000 {
+		t.Errorf("unexpected totals: %+v", resp.Totals)
+	}
+}
+
+func TestGetCostStatsHandler_OtherGroupForbidden(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/costs?group_id="+uuid.New().String(), nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "organization"))
+	rec := httptest.NewRecorder()
+
+	GetCostStatsHandler(&mockQuerier{}).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestGetCostStatsHandler_InvalidRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "bad from", query: "from=March"},
+		{name: "bad to", query: "to=2026-13-01"},
+		{name: "from after to", query: "from=2026-03-10&to=2026-03-01"},
+		{name: "too long", query: "from=2024-01-01&to=2026-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/costs?"+tt.query, nil)
+			req = req.WithContext(setJWTContext(req.Context(), testUser().ID, testGroup().ID, "admin", "system"))
+			rec := httptest.NewRecorder()
+
+			GetCostStatsHandler(&mockQuerier{}).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestGetTagStatsHandler(t *testing.T) {
+	groupID := testGroup().ID
+	var gotParams storage.CountGroupMessagesByTagParams
+	mock := &mockQuerier{
+		countGroupMessagesByTagFn: func(ctx context.Context, arg storage.CountGroupMessagesByTagParams) ([]storage.CountGroupMessagesByTagRow, error) {
+			gotParams = arg
+			return []storage.CountGroupMessagesByTagRow{
+				{Tag: "billing", Status: storage.MessageStatusDelivered, Messages: 8},
+				{Tag: "billing", Status: storage.MessageStatusFailed, Messages: 2},
+				{Tag: "welcome", Status: storage.MessageStatusDelivered, Messages: 5},
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stats/tags?from=2026-03-01&to=2026-03-31", nil)
+	req = req.WithContext(setJWTContext(req.Context(), testUser().ID, groupID, "member", "organization"))
+	rec := httptest.NewRecorder()
+	GetTagStatsHandler(mock).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if gotParams.GroupID.Bytes != groupID {
+		t.Errorf("expected the caller's group, got %v", gotParams.GroupID)
+	}
+	if want := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !gotParams.EnqueuedAt_2.Time.Equal(want) {
+		t.Errorf("expected exclusive end %v, got %v", want, gotParams.EnqueuedAt_2.Time)
+	}
+
+	var resp tagStatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Tags) != 2 {
+		t.Fatalf("expected 2 tags, got %+v", resp.Tags)
+	}
+	if b := resp.Tags[0]; b.Tag != "billing" || b.Messages != 10 || b.ByStatus["failed"] != 2 {
+		t.Errorf("unexpected billing row: %+v", b)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// suppressionResponse is a recipient on a group's suppression list.
+// MessageID is the message that caused the suppression, while it is kept.
+type suppressionResponse struct {
+	Address   string `json:"address"`
+	Reason    string `json:"reason"`
+	MessageID string `json:"message_id,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ListSuppressionsHandler handles GET /api/v1/suppressions.
+// Lists the recipients the caller's group no longer sends to, newest
+// first. Supports query param limit (default 100, max 1000).
+func ListSuppressionsHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		limit := int32(100)
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if v, err := strconv.Atoi(l); err == nil && v > 0 {
+				limit = int32(min(v, 1000))
+			}
+		}
+
+		list, err := queries.ListSuppressions(r.Context(), storage.ListSuppressionsParams{GroupID: groupID, Limit: limit})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		resp := make([]suppressionResponse, len(list))
+		for i, s := range list {
+			resp[i] = suppressionResponse{
+				Address:   s.Address,
+				Reason:    s.Reason,
+				CreatedAt: timestampToTime(s.CreatedAt).Format("2006-01-02T15:04:05Z07:00"),
+			}
+			if s.MessageID.Valid {
+				resp[i].MessageID = uuid.UUID(s.MessageID.Bytes).String()
+			}
+		}
+		respondJSON(w, http.StatusOK, resp)
+	}
+}
+
+// DeleteSuppressionHandler handles DELETE /api/v1/suppressions/{address}.
+// The group may send to the address again. Requires owner or admin role.
+func DeleteSuppressionHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := auth.GroupIDFromContext(r.Context())
+		if groupID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if !isGroupAdmin(r) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		address := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "address")))
+		n, err := queries.DeleteSuppression(r.Context(), storage.DeleteSuppressionParams{GroupID: groupID, Address: address})
+		if err != nil {
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		if n == 0 {
+			respondError(w, http.StatusNotFound, "address is not suppressed")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteSuppression, "suppression", address, nil)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestListSuppressionsHandler(t *testing.T) {
+	msgID := uuid.New()
+	var got storage.ListSuppressionsParams
+	mock := &mockQuerier{
+		listSuppressionsFn: func(ctx context.Context, arg storage.ListSuppressionsParams) ([]storage.Suppression, error) {
+			got = arg
+			return []storage.Suppression{{
+				GroupID:   arg.GroupID,
+				Address:   "alice@example.com",
+				Reason:    "complaint",
+				MessageID: pgtype.UUID{Bytes: msgID, Valid: true},
+				CreatedAt: pgtype.Timestamptz{Time: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC), Valid: true},
+			}}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	ListSuppressionsHandler(mock).ServeHTTP(rec, shadowRequest(http.MethodGet, "/api/v1/suppressions?limit=5000", "", "member"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got.GroupID != testGroup().ID || got.Limit != 1000 {
+		t.Errorf("unexpected list params: %+v", got)
+	}
+	var resp []suppressionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp) != 1 || resp[0].Address != "alice@example.com" || resp[0].MessageID != msgID.String() || resp[0].CreatedAt != "2026-10-01T09:00:00Z" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestDeleteSuppressionHandler(t *testing.T) {
+	for _, tt := range []struct {
+		role     string
+		rows     int64
+		wantCode int
+	}{
+		{"admin", 1, http.StatusNoContent},
+		{"admin", 0, http.StatusNotFound},
+		{"member", 1, http.StatusForbidden},
+	} {
+		var got storage.DeleteSuppressionParams
+		mock := &mockQuerier{
+			deleteSuppressionFn: func(ctx context.Context, arg storage.DeleteSuppressionParams) (int64, error) {
+				got = arg
+				return tt.rows, nil
+			},
+		}
+		req := shadowRequest(http.MethodDelete, "/api/v1/suppressions/Alice@Example.com", "", tt.role)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("address", "Alice@Example.com")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rec := httptest.NewRecorder()
+		DeleteSuppressionHandler(mock, nil).ServeHTTP(rec, req)
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s, %d rows: expected status %d, got %d", tt.role, tt.rows, tt.wantCode, rec.Code)
+		}
+		if tt.wantCode != http.StatusForbidden && (got.GroupID != testGroup().ID || got.Address != "alice@example.com") {
+			t.Errorf("unexpected delete params: %+v", got)
+		}
+	}
+}
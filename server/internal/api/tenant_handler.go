@@ -11,6 +11,14 @@ import (
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
+// Legacy tenant endpoints (see registerLegacyRoutes). A tenant is a
+// company group. DELETE /api/v1/tenants/{id} is served by
+// DeleteGroupHandler.
+
+// legacyTenantMonthlyLimit is the monthly limit of tenants created without
+// one, as in the legacy API. Groups default to unlimited.
+const legacyTenantMonthlyLimit = 10000
+
 // createTenantRequest is the JSON body for POST /api/v1/tenants.
 type createTenantRequest struct {
 	Name         string `json:"name"`
@@ -31,22 +39,23 @@ type tenantResponse struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-// toTenantResponse converts a storage.Tenant to a tenantResponse.
-func toTenantResponse(t storage.Tenant) tenantResponse {
+// toTenantResponse converts a group to a tenantResponse.
+func toTenantResponse(g storage.Group) tenantResponse {
 	return tenantResponse{
-		ID:           t.ID,
-		Name:         t.Name,
-		Status:       t.Status,
-		MonthlyLimit: t.MonthlyLimit,
-		MonthlySent:  t.MonthlySent,
-		CreatedAt:    timestampToTime(t.CreatedAt),
-		UpdatedAt:    timestampToTime(t.UpdatedAt),
+		ID:           g.ID,
+		Name:         g.Name,
+		Status:       g.Status,
+		MonthlyLimit: g.MonthlyLimit,
+		MonthlySent:  g.MonthlySent,
+		CreatedAt:    timestampToTime(g.CreatedAt),
+		UpdatedAt:    timestampToTime(g.UpdatedAt),
 	}
 }
 
 // CreateTenantHandler handles POST /api/v1/tenants.
-// Creates a new tenant and its initial owner user. No auth required for initial setup.
-func CreateTenantHandler(queries storage.Querier, jwtService *auth.JWTService, auditLogger *auth.AuditLogger) http.HandlerFunc {
+// Creates a company group and its initial owner user. Requires system
+// admin access.
+func CreateTenantHandler(queries storage.Querier, passwords *auth.PasswordPolicy, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req createTenantRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -69,109 +78,95 @@ func CreateTenantHandler(queries storage.Querier, jwtService *auth.JWTService, a
 			respondValidationErrors(w, errs)
 			return
 		}
+		if !checkPassword(w, r, passwords, req.OwnerPassword) {
+			return
+		}
 
-		// Default monthly limit
 		monthlyLimit := req.MonthlyLimit
 		if monthlyLimit <= 0 {
-			monthlyLimit = 10000
+			monthlyLimit = legacyTenantMonthlyLimit
 		}
 
-		// Create tenant
-		tenant, err := queries.CreateTenant(r.Context(), storage.CreateTenantParams{
-			Name:         req.Name,
-			MonthlyLimit: monthlyLimit,
-		})
+		hash, err := auth.HashPassword(req.OwnerPassword)
 		if err != nil {
-			respondError(w, http.StatusConflict, "tenant name already exists")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
-		// Hash password for the owner user
-		hash, err := auth.HashPassword(req.OwnerPassword)
+		group, err := queries.CreateGroup(r.Context(), storage.CreateGroupParams{
+			Name:      req.Name,
+			GroupType: "company",
+		})
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusConflict, "tenant name already exists")
+			return
+		}
+		group, err = queries.UpdateGroup(r.Context(), storage.UpdateGroupParams{
+			ID:           group.ID,
+			Name:         group.Name,
+			Status:       group.Status,
+			MonthlyLimit: monthlyLimit,
+		})
+		if err != nil {
+			_ = queries.DeleteGroup(r.Context(), group.ID)
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
-		// Create owner user
-		_, err = queries.CreateUser(r.Context(), storage.CreateUserParams{
-			TenantID:     tenant.ID,
+		owner, err := queries.CreateUser(r.Context(), storage.CreateUserParams{
 			Email:        req.OwnerEmail,
 			PasswordHash: hash,
-			Role:         "owner",
+			AccountType:  "user",
 		})
 		if err != nil {
-			// Rollback: delete the tenant if user creation fails
-			_ = queries.DeleteTenant(r.Context(), tenant.ID)
-			respondError(w, http.StatusConflict, "email already in use")
+			// Rollback: delete the group if user creation fails
+			_ = queries.DeleteGroup(r.Context(), group.ID)
+			respondStorageError(w, err, http.StatusConflict, "email already in use")
+			return
+		}
+		if _, err := queries.CreateGroupMember(r.Context(), storage.CreateGroupMemberParams{
+			GroupID: group.ID,
+			UserID:  owner.ID,
+			Role:    "owner",
+		}); err != nil {
+			_ = queries.DeleteUser(r.Context(), owner.ID)
+			_ = queries.DeleteGroup(r.Context(), group.ID)
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
 		if auditLogger != nil {
-			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateTenant, "tenant", tenant.ID.String(), map[string]interface{}{
-				"tenant_name": req.Name,
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionCreateGroup, "group", group.ID.String(), map[string]interface{}{
+				"name":       req.Name,
+				"legacy_api": true,
 			})
 		}
 
-		respondJSON(w, http.StatusCreated, toTenantResponse(tenant))
+		respondJSON(w, http.StatusCreated, toTenantResponse(group))
 	}
 }
 
 // GetTenantHandler handles GET /api/v1/tenants/{id}.
-// Returns tenant details. Requires owner or admin role.
+// Returns the group as a tenant. Requires access to the group.
 func GetTenantHandler(queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		id, err := uuid.Parse(idStr)
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
 		if err != nil {
 			respondError(w, http.StatusBadRequest, "invalid tenant ID format")
 			return
 		}
 
-		// Verify the requesting user belongs to this tenant
-		tenantID := auth.TenantFromContext(r.Context())
-		if tenantID != id {
+		if !canAccessGroup(r.Context(), queries, id) {
 			respondError(w, http.StatusForbidden, "access denied")
 			return
 		}
 
-		tenant, err := queries.GetTenantByID(r.Context(), id)
+		group, err := queries.GetGroupByID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "tenant not found")
+			respondStorageError(w, err, http.StatusNotFound, "tenant not found")
 			return
 		}
 
-		respondJSON(w, http.StatusOK, toTenantResponse(tenant))
-	}
-}
-
-// DeleteTenantHandler handles DELETE /api/v1/tenants/{id}.
-// Deletes a tenant and all associated data. Requires owner role.
-func DeleteTenantHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := chi.URLParam(r, "id")
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			respondError(w, http.StatusBadRequest, "invalid tenant ID format")
-			return
-		}
-
-		// Verify the requesting user belongs to this tenant
-		tenantID := auth.TenantFromContext(r.Context())
-		if tenantID != id {
-			respondError(w, http.StatusForbidden, "access denied")
-			return
-		}
-
-		if err := queries.DeleteTenant(r.Context(), id); err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
-			return
-		}
-
-		if auditLogger != nil {
-			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionDeleteTenant, "tenant", id.String(), nil)
-		}
-
-		w.WriteHeader(http.StatusNoContent)
+		respondJSON(w, http.StatusOK, toTenantResponse(group))
 	}
 }
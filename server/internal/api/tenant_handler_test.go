@@ -1,6 +1,11 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,31 +16,75 @@ import (
 
 func TestToTenantResponse(t *testing.T) {
 	now := time.Now()
-	tenant := storage.Tenant{
+	group := storage.Group{
 		ID:           uuid.New(),
 		Name:         "test-tenant",
 		Status:       "active",
 		MonthlyLimit: 10000,
 		MonthlySent:  500,
+		GroupType:    "company",
 		CreatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
 		UpdatedAt:    pgtype.Timestamptz{Time: now, Valid: true},
 	}
 
-	resp := toTenantResponse(tenant)
+	resp := toTenantResponse(group)
 
-	if resp.ID != tenant.ID {
-		t.Errorf("ID = %v, want %v", resp.ID, tenant.ID)
+	if resp.ID != group.ID {
+		t.Errorf("ID = %v, want %v", resp.ID, group.ID)
 	}
-	if resp.Name != tenant.Name {
-		t.Errorf("Name = %q, want %q", resp.Name, tenant.Name)
+	if resp.Name != group.Name {
+		t.Errorf("Name = %q, want %q", resp.Name, group.Name)
 	}
-	if resp.Status != tenant.Status {
-		t.Errorf("Status = %q, want %q", resp.Status, tenant.Status)
+	if resp.Status != group.Status {
+		t.Errorf("Status = %q, want %q", resp.Status, group.Status)
 	}
-	if resp.MonthlyLimit != tenant.MonthlyLimit {
-		t.Errorf("MonthlyLimit = %d, want %d", resp.MonthlyLimit, tenant.MonthlyLimit)
+	if resp.MonthlyLimit != group.MonthlyLimit {
+		t.Errorf("MonthlyLimit = %d, want %d", resp.MonthlyLimit, group.MonthlyLimit)
 	}
-	if resp.MonthlySent != tenant.MonthlySent {
-		t.Errorf("MonthlySent = %d, want %d", resp.MonthlySent, tenant.MonthlySent)
+	if resp.MonthlySent != group.MonthlySent {
+		t.Errorf("MonthlySent = %d, want %d", resp.MonthlySent, group.MonthlySent)
+	}
+}
+
+func TestCreateTenantHandler_OwnerFailureRollsBack(t *testing.T) {
+	group := testGroup()
+	group.GroupType = "company"
+	var created storage.CreateGroupParams
+	var limit int32
+	var deleted uuid.UUID
+	mock := &mockQuerier{
+		createGroupFn: func(ctx context.Context, arg storage.CreateGroupParams) (storage.Group, error) {
+			created = arg
+			return group, nil
+		},
+		updateGroupFn: func(ctx context.Context, arg storage.UpdateGroupParams) (storage.Group, error) {
+			limit = arg.MonthlyLimit
+			return group, nil
+		},
+		createUserFn: func(ctx context.Context, arg storage.CreateUserParams) (storage.User, error) {
+			return storage.User{}, errors.New("duplicate key value")
+		},
+		deleteGroupFn: func(ctx context.Context, id uuid.UUID) error {
+			deleted = id
+			return nil
+		},
+	}
+
+	body := `{"name":"acme","owner_email":"owner@acme.test","owner_password":"secret123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/tenants", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	CreateTenantHandler(mock, nil, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError && rec.Code != http.StatusConflict {
+		t.Fatalf("expected an error status, got %d", rec.Code)
+	}
+	if created.Name != "acme" || created.GroupType != "company" {
+		t.Errorf("CreateGroup(%+v), want company group acme", created)
+	}
+	if limit != legacyTenantMonthlyLimit {
+		t.Errorf("monthly limit = %d, want %d", limit, legacyTenantMonthlyLimit)
+	}
+	if deleted != group.ID {
+		t.Errorf("deleted group %v, want %v", deleted, group.ID)
 	}
 }
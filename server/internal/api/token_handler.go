@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// defaultScopedTokenTTL is the lifetime of a scoped token when the request
+// does not set ttl_seconds.
+const defaultScopedTokenTTL = time.Hour
+
+// scopedTokenRequest is the JSON body for minting a scoped token.
+type scopedTokenRequest struct {
+	Scope []string `json:"scope"`
+	// TTLSeconds is the token lifetime; 0 means one hour.
+	TTLSeconds int `json:"ttl_seconds"`
+	// UserID is the user the token acts as; empty means the caller.
+	UserID string `json:"user_id"`
+}
+
+// scopedTokenResponse is the JSON response for a minted scoped token.
+type scopedTokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	Scope     []string  `json:"scope"`
+	UserID    uuid.UUID `json:"user_id"`
+	GroupID   uuid.UUID `json:"group_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MintScopedTokenHandler handles POST /api/v1/auth/tokens.
+// Mints a short-lived token limited to the requested scopes from the
+// caller's credential. Tokens for other users require group admin+ role
+// for the user's group, and the send scope requires an SMTP account.
+// Scoped tokens cannot be revoked; they stay valid until they expire.
+func MintScopedTokenHandler(queries storage.Querier, jwtService *auth.JWTService, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		callerID := auth.UserFromContext(r.Context())
+		if callerID == uuid.Nil {
+			respondError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		var req scopedTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		scope, err := parseTokenScope(req.Scope)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ttl := time.Duration(req.TTLSeconds) * time.Second
+		if req.TTLSeconds == 0 {
+			ttl = defaultScopedTokenTTL
+		}
+
+		userID := callerID
+		if req.UserID != "" {
+			userID, err = uuid.Parse(req.UserID)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid user_id format")
+				return
+			}
+		}
+		if userID != callerID && (!isGroupAdmin(r) || !canAccessUser(r.Context(), queries, userID)) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		user, err := queries.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+		if user.Status != "active" {
+			respondError(w, http.StatusBadRequest, "user is not active")
+			return
+		}
+		if slices.Contains(scope, auth.ScopeSend) && user.AccountType != "smtp" {
+			respondError(w, http.StatusBadRequest, "send scope requires an SMTP account")
+			return
+		}
+
+		// The caller's token acts in their active group; another user's
+		// token acts in the group they authenticate to, as with API keys.
+		groupID := auth.GroupIDFromContext(r.Context())
+		groupPath := auth.GroupPathFromContext(r.Context())
+		groupType := auth.GroupTypeFromContext(r.Context())
+		role := auth.RoleFromContext(r.Context())
+		if userID != callerID {
+			groups, err := queries.ListGroupsByUserID(r.Context(), userID)
+			if err != nil || len(groups) == 0 {
+				respondError(w, http.StatusBadRequest, "user has no group membership")
+				return
+			}
+			member, err := queries.GetGroupMemberByUserAndGroup(r.Context(), storage.GetGroupMemberByUserAndGroupParams{
+				UserID:  userID,
+				GroupID: groups[0].ID,
+			})
+			if err != nil {
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			groupPath, err = auth.GroupPath(r.Context(), queries, groups[0].ID)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			groupID, groupType, role = groups[0].ID, groups[0].GroupType, member.Role
+		}
+
+		token, expiresAt, err := jwtService.GenerateScopedToken(user.ID, groupID, user.Email, role, groupType, groupPath, scope, ttl)
+		if errors.Is(err, auth.ErrScopedTokenTTL) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("ttl_seconds must be between 1 and %d", int(jwtService.ScopedTokenMaxTTL().Seconds())))
+			return
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, auth.AuditActionMintToken, "user", user.ID.String(), map[string]interface{}{
+				"scope":      scope,
+				"group_id":   groupID.String(),
+				"expires_at": expiresAt,
+			})
+		}
+
+		respondJSON(w, http.StatusCreated, scopedTokenResponse{
+			Token:     token,
+			TokenType: "Bearer",
+			Scope:     scope,
+			UserID:    user.ID,
+			GroupID:   groupID,
+			ExpiresAt: expiresAt,
+		})
+	}
+}
+
+// parseTokenScope validates and deduplicates the requested token scopes.
+func parseTokenScope(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, errors.New("scope is required")
+	}
+	var scope []string
+	for _, s := range requested {
+		if !auth.ValidScope(s) {
+			return nil, fmt.Errorf("invalid scope %q: must be %q or %q", s, auth.ScopeSend, auth.ScopeRead)
+		}
+		if !slices.Contains(scope, s) {
+			scope = append(scope, s)
+		}
+	}
+	return scope, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func newScopedTokenJWTService() *auth.JWTService {
+	return auth.NewJWTService(auth.JWTConfig{
+		SigningKey:        "test-secret-key-that-is-long-enough-32",
+		AccessTokenExpiry: 15 * time.Minute,
+		ScopedTokenMaxTTL: 24 * time.Hour,
+	})
+}
+
+func scopedTokenHTTPRequest(body string, callerID uuid.UUID, role string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/tokens", strings.NewReader(body))
+	ctx := setJWTContext(req.Context(), callerID, testGroup().ID, role, "organization")
+	return req.WithContext(ctx)
+}
+
+func TestMintScopedTokenHandler_SMTPAccount(t *testing.T) {
+	smtpUser := testUser()
+	smtpUser.ID = uuid.New()
+	smtpUser.AccountType = "smtp"
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return smtpUser, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return testGroup(), nil
+		},
+		getGroupMemberByUserAndGroupFn: func(ctx context.Context, arg storage.GetGroupMemberByUserAndGroupParams) (storage.GroupMember, error) {
+			return storage.GroupMember{UserID: arg.UserID, GroupID: arg.GroupID, Role: "member"}, nil
+		},
+	}
+	jwtSvc := newScopedTokenJWTService()
+
+	body := `{"scope":["send","send"],"ttl_seconds":1800,"user_id":"` + smtpUser.ID.String() + `"}`
+	rec := httptest.NewRecorder()
+	MintScopedTokenHandler(mock, jwtSvc, nil).ServeHTTP(rec, scopedTokenHTTPRequest(body, testUser().ID, "admin"))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp scopedTokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Scope) != 1 || resp.UserID != smtpUser.ID || resp.GroupID != testGroup().ID {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if d := time.Until(resp.ExpiresAt); d < 29*time.Minute || d > 30*time.Minute {
+		t.Errorf("expires in %v, want 30m", d)
+	}
+
+	claims, err := jwtSvc.ValidateAccessToken(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if claims.Subject != smtpUser.ID.String() || claims.Role != "member" || !claims.HasScope(auth.ScopeSend) || claims.HasScope(auth.ScopeRead) {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestMintScopedTokenHandler_Errors(t *testing.T) {
+	otherUser := uuid.New()
+	tests := []struct {
+		name string
+		body string
+		role string
+		code int
+	}{
+		{"missing scope", `{}`, "member", http.StatusBadRequest},
+		{"unknown scope", `{"scope":["admin"]}`, "member", http.StatusBadRequest},
+		{"send for a login user", `{"scope":["send"]}`, "member", http.StatusBadRequest},
+		{"ttl too long", `{"scope":["read"],"ttl_seconds":90000}`, "member", http.StatusBadRequest},
+		{"other user as member", `{"scope":["read"],"user_id":"` + otherUser.String() + `"}`, "member", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+					return testUser(), nil
+				},
+			}
+			rec := httptest.NewRecorder()
+			MintScopedTokenHandler(mock, newScopedTokenJWTService(), nil).ServeHTTP(rec, scopedTokenHTTPRequest(tt.body, testUser().ID, tt.role))
+			if rec.Code != tt.code {
+				t.Errorf("expected status %d, got %d; body: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
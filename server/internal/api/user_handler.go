@@ -3,13 +3,17 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
 )
 
 // createUserRequest is the JSON body for POST /api/v1/users.
@@ -25,16 +29,20 @@ type createUserRequest struct {
 
 // userResponse is the JSON response for a user, excluding sensitive fields.
 type userResponse struct {
-	ID             uuid.UUID  `json:"id"`
-	Email          string     `json:"email"`
-	Username       *string    `json:"username,omitempty"`
-	AccountType    string     `json:"account_type"`
-	Status         string     `json:"status"`
-	AllowedDomains []string   `json:"allowed_domains,omitempty"`
-	ApiKey         *string    `json:"api_key,omitempty"`
-	LastLogin      *time.Time `json:"last_login,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID                uuid.UUID       `json:"id"`
+	Email             string          `json:"email"`
+	Username          *string         `json:"username,omitempty"`
+	AccountType       string          `json:"account_type"`
+	Status            string          `json:"status"`
+	AllowedDomains    []string        `json:"allowed_domains,omitempty"`
+	TLSPolicy         *tlsutil.Policy `json:"tls_policy,omitempty"`
+	AutoBCC           []string        `json:"auto_bcc,omitempty"`
+	ApiKey            *string         `json:"api_key,omitempty"`
+	LastLogin         *time.Time      `json:"last_login,omitempty"`
+	PasswordChangedAt *time.Time      `json:"password_changed_at,omitempty"`
+	EmailVerifiedAt   *time.Time      `json:"email_verified_at,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
 // toUserResponse converts a storage.User to a userResponse.
@@ -54,9 +62,23 @@ func toUserResponse(u storage.User) userResponse {
 		t := u.LastLogin.Time
 		resp.LastLogin = &t
 	}
+	if u.PasswordChangedAt.Valid {
+		t := u.PasswordChangedAt.Time
+		resp.PasswordChangedAt = &t
+	}
+	if u.EmailVerifiedAt.Valid {
+		t := u.EmailVerifiedAt.Time
+		resp.EmailVerifiedAt = &t
+	}
 	if len(u.AllowedDomains) > 0 {
 		resp.AllowedDomains = decodeDomains(u.AllowedDomains)
 	}
+	if p, err := tlsutil.ParsePolicy(u.TlsPolicy); err == nil && p.Active() {
+		resp.TLSPolicy = &p
+	}
+	if len(u.AutoBcc) > 0 {
+		_ = json.Unmarshal(u.AutoBcc, &resp.AutoBCC)
+	}
 	return resp
 }
 
@@ -81,8 +103,9 @@ var validRoles = map[string]struct{}{
 // For account_type="smtp", auto-generates an API key and password is optional.
 // If group_id is provided, creates a group membership. For system admins,
 // any group can be specified. For non-system users, the group must match their own.
+// Passwords must satisfy the password policy.
 // Requires owner or admin role.
-func CreateUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+func CreateUserHandler(queries storage.Querier, passwords *auth.PasswordPolicy, auditLogger *auth.AuditLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req createUserRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -128,12 +151,16 @@ func CreateUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) h
 			return
 		}
 
+		if req.Password != "" && !checkPassword(w, r, passwords, req.Password) {
+			return
+		}
+
 		// Hash password
 		var passwordHash string
 		if req.Password != "" {
 			hash, err := auth.HashPassword(req.Password)
 			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 				return
 			}
 			passwordHash = hash
@@ -170,7 +197,7 @@ func CreateUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) h
 			var err error
 			domainsJSON, err = json.Marshal(req.AllowedDomains)
 			if err != nil {
-				respondError(w, http.StatusInternalServerError, "internal server error")
+				respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 				return
 			}
 		}
@@ -184,7 +211,7 @@ func CreateUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) h
 			AllowedDomains: domainsJSON,
 		})
 		if err != nil {
-			respondError(w, http.StatusConflict, "email already in use")
+			respondStorageError(w, err, http.StatusConflict, "email already in use")
 			return
 		}
 
@@ -197,9 +224,7 @@ func CreateUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) h
 			}
 
 			// Verify the caller has access to this group
-			callerGroupID := auth.GroupIDFromContext(r.Context())
-			callerGroupType := auth.GroupTypeFromContext(r.Context())
-			if callerGroupType != "system" && callerGroupID != groupID {
+			if !canAccessGroup(r.Context(), queries, groupID) {
 				respondError(w, http.StatusForbidden, "access denied to the specified group")
 				return
 			}
@@ -210,7 +235,7 @@ func CreateUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) h
 				Role:    req.Role,
 			})
 			if err != nil {
-				respondError(w, http.StatusConflict, "failed to add user to group")
+				respondStorageError(w, err, http.StatusConflict, "failed to add user to group")
 				return
 			}
 		}
@@ -238,7 +263,7 @@ func ListUsersHandler(queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		users, err := queries.ListUsers(r.Context())
 		if err != nil {
-			respondError(w, http.StatusInternalServerError, "internal server error")
+			respondStorageError(w, err, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -264,7 +289,7 @@ func GetUserHandler(queries storage.Querier) http.HandlerFunc {
 
 		user, err := queries.GetUserByID(r.Context(), id)
 		if err != nil {
-			respondError(w, http.StatusNotFound, "user not found")
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
 			return
 		}
 
@@ -308,7 +333,7 @@ func UpdateUserStatusHandler(queries storage.Querier, auditLogger *auth.AuditLog
 			Status: req.Status,
 		})
 		if err != nil {
-			respondError(w, http.StatusNotFound, "user not found")
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
 			return
 		}
 
@@ -322,6 +347,167 @@ func UpdateUserStatusHandler(queries storage.Querier, auditLogger *auth.AuditLog
 	}
 }
 
+// UpdateUserTLSPolicyHandler handles PUT /api/v1/users/{id}/tls-policy.
+// Replaces the SMTP user's TLS policy; AUTH and MAIL FROM are then only
+// accepted over connections that meet it. An empty policy removes the
+// requirement. Requires group admin+ role for the user's group.
+func UpdateUserTLSPolicyHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req tlsutil.Policy
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		existing, err := queries.GetUserByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+		if existing.AccountType != "smtp" {
+			respondError(w, http.StatusBadRequest, "tls policy applies to smtp accounts only")
+			return
+		}
+
+		policyJSON, err := json.Marshal(req)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		user, err := queries.UpdateUserTLSPolicy(r.Context(), storage.UpdateUserTLSPolicyParams{
+			ID:        id,
+			TlsPolicy: policyJSON,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+
+		if auditLogger != nil {
+			auditLogger.LogAdminAction(r.Context(), r, "admin.update_user_tls_policy", "user", id.String(), map[string]interface{}{
+				"require_tls":   req.RequireTLS,
+				"min_version":   req.MinVersion,
+				"cipher_suites": req.CipherSuites,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toUserResponse(user))
+	}
+}
+
+// maxAutoBCC limits the auto-BCC addresses of a user.
+const maxAutoBCC = 10
+
+// autoBCCRequest is the JSON body for PUT /api/v1/users/{id}/auto-bcc.
+type autoBCCRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+// UpdateUserAutoBCCHandler handles PUT /api/v1/users/{id}/auto-bcc.
+// Replaces the SMTP user's auto-BCC addresses; the worker copies each of
+// the user's messages to them without showing them to the recipients. An
+// empty list removes the copies. Requires group admin+ role for the user's
+// group.
+func UpdateUserAutoBCCHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid user ID format")
+			return
+		}
+		if !isGroupAdmin(r) || !canAccessUser(r.Context(), queries, id) {
+			respondError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var req autoBCCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		addrs, errs := normalizeAutoBCC(req.Addresses)
+		if len(errs) > 0 {
+			respondValidationErrors(w, errs)
+			return
+		}
+
+		existing, err := queries.GetUserByID(r.Context(), id)
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+		if existing.AccountType != "smtp" {
+			respondError(w, http.StatusBadRequest, "auto-bcc applies to smtp accounts only")
+			return
+		}
+
+		addrsJSON, err := json.Marshal(addrs)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		user, err := queries.UpdateUserAutoBCC(r.Context(), storage.UpdateUserAutoBCCParams{
+			ID:      id,
+			AutoBcc: addrsJSON,
+		})
+		if err != nil {
+			respondStorageError(w, err, http.StatusNotFound, "user not found")
+			return
+		}
+
+		if auditLogger != nil {
+			var previous []string
+			_ = json.Unmarshal(existing.AutoBcc, &previous)
+			auditLogger.LogAdminAction(r.Context(), r, "admin.update_user_auto_bcc", "user", id.String(), map[string]interface{}{
+				"previous": previous,
+				"auto_bcc": addrs,
+			})
+		}
+
+		respondJSON(w, http.StatusOK, toUserResponse(user))
+	}
+}
+
+// normalizeAutoBCC trims and de-duplicates auto-BCC addresses and returns
+// the validation errors of the list.
+func normalizeAutoBCC(in []string) ([]string, []string) {
+	addrs := []string{}
+	var errs []string
+	seen := make(map[string]bool)
+	for _, a := range in {
+		a = strings.TrimSpace(a)
+		parsed, err := mail.ParseAddress(a)
+		if err != nil || parsed.Name != "" || parsed.Address != a {
+			errs = append(errs, fmt.Sprintf("%q is not a bare email address", a))
+			continue
+		}
+		if key := strings.ToLower(a); !seen[key] {
+			seen[key] = true
+			addrs = append(addrs, a)
+		}
+	}
+	if len(addrs) > maxAutoBCC {
+		errs = append(errs, fmt.Sprintf("at most %d auto-bcc addresses are allowed", maxAutoBCC))
+	}
+	return addrs, errs
+}
+
 // DeleteUserHandler handles DELETE /api/v1/users/{id}.
 // Deletes a user and all their group memberships.
 func DeleteUserHandler(queries storage.Querier, auditLogger *auth.AuditLogger) http.HandlerFunc {
@@ -144,7 +144,7 @@ func TestCreateUserHandler_HumanUser(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateUserHandler(mock, nil)
+	handler := CreateUserHandler(mock, nil, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusCreated {
@@ -178,7 +178,7 @@ func TestCreateUserHandler_SMTPAccount(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateUserHandler(mock, nil)
+	handler := CreateUserHandler(mock, nil, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusCreated {
@@ -206,7 +206,7 @@ func TestCreateUserHandler_MissingEmail(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateUserHandler(mock, nil)
+	handler := CreateUserHandler(mock, nil, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -226,7 +226,7 @@ func TestCreateUserHandler_UserMissingPassword(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateUserHandler(mock, nil)
+	handler := CreateUserHandler(mock, nil, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusBadRequest {
@@ -247,7 +247,7 @@ func TestCreateUserHandler_OwnerCannotBeCreatedByAdmin(t *testing.T) {
 	req = req.WithContext(ctx)
 
 	rec := httptest.NewRecorder()
-	handler := CreateUserHandler(mock, nil)
+	handler := CreateUserHandler(mock, nil, nil)
 	handler.ServeHTTP(rec, req)
 
 	if rec.Code != http.StatusForbidden {
@@ -357,6 +357,218 @@ func TestUpdateUserStatusHandler(t *testing.T) {
 	}
 }
 
+func TestUpdateUserTLSPolicyHandler(t *testing.T) {
+	usr := testUser()
+	usr.AccountType = "smtp"
+	var stored []byte
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		updateUserTLSPolicyFn: func(ctx context.Context, arg storage.UpdateUserTLSPolicyParams) (storage.User, error) {
+			stored = arg.TlsPolicy
+			usr.TlsPolicy = arg.TlsPolicy
+			return usr, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"valid", `{"require_tls":true,"min_version":"1.3","cipher_suites":["TLS_AES_128_GCM_SHA256"]}`, http.StatusOK},
+		{"bad version", `{"require_tls":true,"min_version":"1.0"}`, http.StatusBadRequest},
+		{"bad cipher", `{"cipher_suites":["RC4"]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := shadowRequest(http.MethodPut, "/api/v1/users/"+usr.ID.String()+"/tls-policy", tt.body, "admin")
+			rec := httptest.NewRecorder()
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", usr.ID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			UpdateUserTLSPolicyHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	var policy map[string]any
+	if err := json.Unmarshal(stored, &policy); err != nil || policy["min_version"] != "1.3" {
+		t.Errorf("unexpected stored policy %s", stored)
+	}
+}
+
+func TestUpdateUserTLSPolicyHandler_AccessDenied(t *testing.T) {
+	usr := testUser()
+	usr.AccountType = "smtp"
+	otherGroup := storage.Group{ID: uuid.New(), Name: "other"}
+	updated := false
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{otherGroup}, nil
+		},
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return otherGroup, nil
+		},
+		updateUserTLSPolicyFn: func(ctx context.Context, arg storage.UpdateUserTLSPolicyParams) (storage.User, error) {
+			updated = true
+			return usr, nil
+		},
+	}
+
+	for _, role := range []string{"member", "admin"} {
+		t.Run(role, func(t *testing.T) {
+			req := shadowRequest(http.MethodPut, "/api/v1/users/"+usr.ID.String()+"/tls-policy", `{}`, role)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", usr.ID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			UpdateUserTLSPolicyHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if updated {
+				t.Error("tls policy updated")
+			}
+		})
+	}
+}
+
+func TestUpdateUserAutoBCCHandler(t *testing.T) {
+	usr := testUser()
+	usr.AccountType = "smtp"
+	var stored []byte
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+		updateUserAutoBCCFn: func(ctx context.Context, arg storage.UpdateUserAutoBCCParams) (storage.User, error) {
+			stored = arg.AutoBcc
+			u := usr
+			u.AutoBcc = arg.AutoBcc
+			return u, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"valid", `{"addresses":[" archive@example.com ","Archive@example.com","legal@example.com"]}`, http.StatusOK},
+		{"display name", `{"addresses":["Archive <archive@example.com>"]}`, http.StatusBadRequest},
+		{"not an address", `{"addresses":["archive"]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := shadowRequest(http.MethodPut, "/api/v1/users/"+usr.ID.String()+"/auto-bcc", tt.body, "admin")
+			rec := httptest.NewRecorder()
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", usr.ID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			UpdateUserAutoBCCHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d; body: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp userResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.AutoBCC) != 2 {
+				t.Errorf("expected 2 auto-bcc addresses in the response, got %v", resp.AutoBCC)
+			}
+		})
+	}
+
+	if string(stored) != `["archive@example.com","legal@example.com"]` {
+		t.Errorf("unexpected stored addresses %s", stored)
+	}
+}
+
+func TestUpdateUserAutoBCCHandler_HumanAccount(t *testing.T) {
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return testUser(), nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{testGroup()}, nil
+		},
+	}
+	req := shadowRequest(http.MethodPut, "/", `{"addresses":["archive@example.com"]}`, "admin")
+	rec := httptest.NewRecorder()
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", uuid.New().String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	UpdateUserAutoBCCHandler(mock, nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestUpdateUserAutoBCCHandler_AccessDenied(t *testing.T) {
+	usr := testUser()
+	usr.AccountType = "smtp"
+	otherGroup := storage.Group{ID: uuid.New(), Name: "other"}
+	updated := false
+	mock := &mockQuerier{
+		getUserByIDFn: func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+			return usr, nil
+		},
+		listGroupsByUserIDFn: func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{otherGroup}, nil
+		},
+		getGroupByIDFn: func(ctx context.Context, id uuid.UUID) (storage.Group, error) {
+			return otherGroup, nil
+		},
+		updateUserAutoBCCFn: func(ctx context.Context, arg storage.UpdateUserAutoBCCParams) (storage.User, error) {
+			updated = true
+			return usr, nil
+		},
+	}
+
+	for _, role := range []string{"member", "admin"} {
+		t.Run(role, func(t *testing.T) {
+			req := shadowRequest(http.MethodPut, "/api/v1/users/"+usr.ID.String()+"/auto-bcc", `{"addresses":["spy@example.com"]}`, role)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", usr.ID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			UpdateUserAutoBCCHandler(mock, nil).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status 403, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if updated {
+				t.Error("auto-bcc updated")
+			}
+		})
+	}
+}
+
 func TestUpdateUserStatusHandler_InvalidStatus(t *testing.T) {
 	mock := &mockQuerier{}
 
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
+)
+
+// maxValidateAddresses caps the addresses checked by one request.
+const maxValidateAddresses = 100
+
+// validateRequest is the JSON body for POST /api/v1/validate.
+type validateRequest struct {
+	Emails []string `json:"emails"`
+}
+
+// validateResponse is the JSON response for POST /api/v1/validate.
+type validateResponse struct {
+	Results []validation.Result `json:"results"`
+}
+
+// ValidateHandler handles POST /api/v1/validate.
+// Checks each address's syntax, whether its domain accepts mail (MX or
+// implicit MX), and whether it is a disposable or role address. Results are
+// returned in request order.
+func ValidateHandler(validator *validation.Validator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req validateRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if len(req.Emails) == 0 {
+			respondError(w, http.StatusBadRequest, "emails is required and must not be empty")
+			return
+		}
+		if len(req.Emails) > maxValidateAddresses {
+			respondError(w, http.StatusBadRequest, "at most 100 emails can be validated per request")
+			return
+		}
+
+		respondJSON(w, http.StatusOK, validateResponse{
+			Results: validator.ValidateAll(r.Context(), req.Emails),
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
+)
+
+// staticResolver resolves example.com only.
+type staticResolver struct{}
+
+func (staticResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if name == "example.com" {
+		return []*net.MX{{Host: "mx.example.com.", Pref: 10}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (staticResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestValidateHandler(t *testing.T) {
+	body := `{"emails":["jane@example.com","postmaster@example.com","jane@nowhere.example","bad"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/validate", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	ValidateHandler(validation.New(staticResolver{})).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var resp validateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(resp.Results))
+	}
+	if r := resp.Results[0]; !r.Valid || r.Risky {
+		t.Errorf("jane@example.com: %+v", r)
+	}
+	if r := resp.Results[1]; !r.Valid || !r.Role || !r.Risky {
+		t.Errorf("postmaster@example.com: %+v", r)
+	}
+	if r := resp.Results[2]; r.Valid || r.MX != validation.MXNone {
+		t.Errorf("jane@nowhere.example: %+v", r)
+	}
+	if r := resp.Results[3]; r.Syntax {
+		t.Errorf("bad: %+v", r)
+	}
+}
+
+func TestValidateHandler_Limits(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", `{"emails":[]}`},
+		{"too many", `{"emails":[` + strings.Repeat(`"a@example.com",`, 100) + `"a@example.com"]}`},
+		{"malformed", `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/validate", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			ValidateHandler(validation.New(staticResolver{})).ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -53,7 +62,7 @@ func SendGridWebhookHandler(queries storage.Querier) http.HandlerFunc {
 				Provider:          sql.NullString{String: "sendgrid", Valid: true},
 				ProviderMessageID: sql.NullString{String: event.SGMessageID, Valid: true},
 				RetryCount:        0,
-				LastError:         pgtype.Text{String: event.Reason, Valid: event.Reason != ""},
+				LastError:         pgtype.Text{String: redact.Text(event.Reason), Valid: event.Reason != ""},
 				Metadata:          marshalMetadata(map[string]string{"event": event.Event, "email": event.Email}),
 			}); err != nil {
 				log.Error().Err(err).Str("message_id", msgID.String()).Msg("sendgrid webhook: update delivery log failed")
@@ -65,17 +74,49 @@ func SendGridWebhookHandler(queries storage.Querier) http.HandlerFunc {
 }
 
 // SESWebhookHandler handles POST /api/v1/webhooks/ses.
-// AWS SES sends SNS notification messages containing SES-specific event data.
+// AWS SES sends SNS notification messages containing SES-specific event data,
+// either as is (raw message delivery) or wrapped in an SNS envelope. SNS
+// subscription confirmations are confirmed.
 func SESWebhookHandler(queries storage.Querier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := logger.FromContext(r.Context())
 
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		var envelope snsEnvelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			log.Warn().Err(err).Msg("ses webhook: invalid payload")
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		switch envelope.Type {
+		case "SubscriptionConfirmation":
+			if err := confirmSNSSubscription(r.Context(), envelope.SubscribeURL); err != nil {
+				log.Warn().Err(err).Str("topic_arn", envelope.TopicArn).Msg("ses webhook: subscription not confirmed")
+				respondError(w, http.StatusBadRequest, "subscription not confirmed")
+				return
+			}
+			log.Info().Str("topic_arn", envelope.TopicArn).Msg("ses webhook: subscription confirmed")
+			respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+			return
+		case "Notification":
+			body = []byte(envelope.Message)
+		}
+
 		var notification sesNotification
-		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+		if err := json.Unmarshal(body, &notification); err != nil {
 			log.Warn().Err(err).Msg("ses webhook: invalid payload")
 			respondError(w, http.StatusBadRequest, "invalid request body")
 			return
 		}
+		// Identity notifications name their type notificationType, and
+		// configuration set events eventType.
+		if notification.NotificationType == "" {
+			notification.NotificationType = notification.EventType
+		}
 
 		status := normalizeSESStatus(notification.NotificationType)
 		if status == "" {
@@ -114,7 +155,7 @@ func SESWebhookHandler(queries storage.Querier) http.HandlerFunc {
 			Provider:          sql.NullString{String: "ses", Valid: true},
 			ProviderMessageID: sql.NullString{String: providerMsgID, Valid: providerMsgID != ""},
 			RetryCount:        0,
-			LastError:         pgtype.Text{String: lastError, Valid: lastError != ""},
+			LastError:         pgtype.Text{String: redact.Text(lastError), Valid: lastError != ""},
 			Metadata:          marshalMetadata(map[string]string{"notification_type": notification.NotificationType}),
 		}); err != nil {
 			log.Error().Err(err).Str("message_id", msgID.String()).Msg("ses webhook: update delivery log failed")
@@ -163,7 +204,7 @@ func MailgunWebhookHandler(queries storage.Querier) http.HandlerFunc {
 			Provider:          sql.NullString{String: "mailgun", Valid: true},
 			ProviderMessageID: sql.NullString{String: providerMsgID, Valid: providerMsgID != ""},
 			RetryCount:        0,
-			LastError:         pgtype.Text{String: reason, Valid: reason != ""},
+			LastError:         pgtype.Text{String: redact.Text(reason), Valid: reason != ""},
 			Metadata:          marshalMetadata(map[string]string{"event": event.Event, "recipient": event.Recipient}),
 		}); err != nil {
 			log.Error().Err(err).Str("message_id", msgID.String()).Msg("mailgun webhook: update delivery log failed")
@@ -173,6 +214,168 @@ func MailgunWebhookHandler(queries storage.Querier) http.HandlerFunc {
 	}
 }
 
+// ResendWebhookHandler handles POST /api/v1/webhooks/resend.
+// Resend sends one event per request, with the email ID in its data.
+func ResendWebhookHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		var event resendEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			log.Warn().Err(err).Msg("resend webhook: invalid payload")
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		status := normalizeResendStatus(event.Type)
+		if status == "" {
+			respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+			return
+		}
+
+		providerMsgID := event.Data.EmailID
+		msgID, err := lookupMessageIDByProvider(r, queries, providerMsgID)
+		if err != nil {
+			log.Warn().Str("provider_message_id", providerMsgID).Msg("resend webhook: delivery log not found")
+			respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+			return
+		}
+
+		reason := event.Data.Bounce.Message
+		if reason == "" {
+			reason = event.Data.Failed.Reason
+		}
+		if err := queries.UpdateDeliveryLogStatus(r.Context(), storage.UpdateDeliveryLogStatusParams{
+			MessageID:         msgID,
+			Status:            status,
+			Provider:          sql.NullString{String: "resend", Valid: true},
+			ProviderMessageID: sql.NullString{String: providerMsgID, Valid: providerMsgID != ""},
+			RetryCount:        0,
+			LastError:         pgtype.Text{String: redact.Text(reason), Valid: reason != ""},
+			Metadata:          marshalMetadata(map[string]string{"event": event.Type, "recipient": strings.Join(event.Data.To, ",")}),
+		}); err != nil {
+			log.Error().Err(err).Str("message_id", msgID.String()).Msg("resend webhook: update delivery log failed")
+		}
+
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// BrevoWebhookHandler handles POST /api/v1/webhooks/brevo.
+// Brevo sends one transactional event per request, or an array of events
+// when batched.
+func BrevoWebhookHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		var events []brevoEvent
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(body, &events)
+		} else {
+			var event brevoEvent
+			err = json.Unmarshal(body, &event)
+			events = []brevoEvent{event}
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("brevo webhook: invalid payload")
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		for _, event := range events {
+			status := normalizeBrevoStatus(event.Event)
+			if status == "" {
+				continue
+			}
+
+			msgID, err := lookupMessageIDByProvider(r, queries, event.MessageID)
+			if err != nil {
+				log.Warn().Str("provider_message_id", event.MessageID).Msg("brevo webhook: delivery log not found")
+				continue
+			}
+
+			if err := queries.UpdateDeliveryLogStatus(r.Context(), storage.UpdateDeliveryLogStatusParams{
+				MessageID:         msgID,
+				Status:            status,
+				Provider:          sql.NullString{String: "brevo", Valid: true},
+				ProviderMessageID: sql.NullString{String: event.MessageID, Valid: true},
+				RetryCount:        0,
+				LastError:         pgtype.Text{String: redact.Text(event.Reason), Valid: event.Reason != ""},
+				Metadata:          marshalMetadata(map[string]string{"event": event.Event, "email": event.Email}),
+			}); err != nil {
+				log.Error().Err(err).Str("message_id", msgID.String()).Msg("brevo webhook: update delivery log failed")
+			}
+		}
+
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// ZeptoMailWebhookHandler handles POST /api/v1/webhooks/zeptomail.
+// ZeptoMail sends the events of a send request together, identified by
+// the request ID its API returned.
+func ZeptoMailWebhookHandler(queries storage.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.FromContext(r.Context())
+
+		var payload zeptomailWebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			log.Warn().Err(err).Msg("zeptomail webhook: invalid payload")
+			respondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		var eventName string
+		if len(payload.EventName) > 0 {
+			eventName = payload.EventName[0]
+		}
+		status := normalizeZeptoMailStatus(eventName)
+		if status == "" {
+			respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+			return
+		}
+
+		for _, message := range payload.EventMessage {
+			msgID, err := lookupMessageIDByProvider(r, queries, message.RequestID)
+			if err != nil {
+				log.Warn().Str("provider_message_id", message.RequestID).Msg("zeptomail webhook: delivery log not found")
+				continue
+			}
+
+			var reason, recipient string
+			for _, data := range message.EventData {
+				for _, d := range data.Details {
+					if reason == "" {
+						reason, recipient = d.Reason, d.BouncedRecipient
+						if d.DiagnosticMessage != "" {
+							reason += ": " + d.DiagnosticMessage
+						}
+					}
+				}
+			}
+
+			if err := queries.UpdateDeliveryLogStatus(r.Context(), storage.UpdateDeliveryLogStatusParams{
+				MessageID:         msgID,
+				Status:            status,
+				Provider:          sql.NullString{String: "zeptomail", Valid: true},
+				ProviderMessageID: sql.NullString{String: message.RequestID, Valid: true},
+				RetryCount:        0,
+				LastError:         pgtype.Text{String: redact.Text(reason), Valid: reason != ""},
+				Metadata:          marshalMetadata(map[string]string{"event": eventName, "recipient": recipient}),
+			}); err != nil {
+				log.Error().Err(err).Str("message_id", msgID.String()).Msg("zeptomail webhook: update delivery log failed")
+			}
+		}
+
+		respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
 // --- SendGrid event types ---
 
 type sendGridEvent struct {
@@ -199,8 +402,50 @@ func normalizeSendGridStatus(event string) string {
 
 // --- SES event types ---
 
+// maxWebhookBody bounds the webhook request bodies that are read whole.
+const maxWebhookBody = 1 << 20
+
+// snsEnvelope is an SNS message posted to an HTTP(S) subscription without
+// raw message delivery.
+type snsEnvelope struct {
+	Type         string `json:"Type"`
+	TopicArn     string `json:"TopicArn"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// snsHostPattern matches the hosts of SNS subscription confirmation URLs.
+var snsHostPattern = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// snsClient confirms SNS subscriptions.
+var snsClient = &http.Client{Timeout: 10 * time.Second}
+
+// confirmSNSSubscription visits the SubscribeURL of a subscription
+// confirmation. Only HTTPS URLs of SNS itself are visited, so the
+// unauthenticated webhook cannot be used to make arbitrary requests.
+func confirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !snsHostPattern.MatchString(u.Hostname()) || u.Port() != "" {
+		return fmt.Errorf("subscribe URL %q is not an SNS URL", subscribeURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := snsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("confirm subscription: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 type sesNotification struct {
 	NotificationType string        `json:"notificationType"`
+	EventType        string        `json:"eventType"`
 	Mail             sesMail       `json:"mail"`
 	Bounce           *sesBounce    `json:"bounce,omitempty"`
 	Complaint        *sesComplaint `json:"complaint,omitempty"`
@@ -277,6 +522,98 @@ func normalizeMailgunStatus(event string) string {
 	}
 }
 
+// --- Resend event types ---
+
+type resendEvent struct {
+	Type string          `json:"type"`
+	Data resendEventData `json:"data"`
+}
+
+type resendEventData struct {
+	EmailID string   `json:"email_id"`
+	To      []string `json:"to"`
+	Bounce  struct {
+		Message string `json:"message"`
+	} `json:"bounce"`
+	Failed struct {
+		Reason string `json:"reason"`
+	} `json:"failed"`
+}
+
+func normalizeResendStatus(eventType string) string {
+	switch eventType {
+	case "email.delivered":
+		return "sent"
+	case "email.bounced":
+		return "bounced"
+	case "email.complained":
+		return "complained"
+	case "email.failed":
+		return "failed"
+	default:
+		return ""
+	}
+}
+
+// --- Brevo event types ---
+
+type brevoEvent struct {
+	Event     string `json:"event"`
+	Email     string `json:"email"`
+	MessageID string `json:"message-id"`
+	Reason    string `json:"reason"`
+}
+
+// normalizeBrevoStatus maps Brevo transactional events. Soft bounces and
+// deferrals are retried by Brevo and are not final.
+func normalizeBrevoStatus(event string) string {
+	switch event {
+	case "delivered":
+		return "sent"
+	case "hard_bounce", "blocked":
+		return "bounced"
+	case "invalid_email", "error":
+		return "failed"
+	case "spam":
+		return "complained"
+	default:
+		return ""
+	}
+}
+
+// --- ZeptoMail event types ---
+
+type zeptomailWebhookPayload struct {
+	EventName    []string                `json:"event_name"`
+	EventMessage []zeptomailEventMessage `json:"event_message"`
+}
+
+type zeptomailEventMessage struct {
+	RequestID string               `json:"request_id"`
+	EventData []zeptomailEventData `json:"event_data"`
+}
+
+type zeptomailEventData struct {
+	Details []zeptomailEventDetail `json:"details"`
+}
+
+type zeptomailEventDetail struct {
+	Reason            string `json:"reason"`
+	DiagnosticMessage string `json:"diagnostic_message"`
+	BouncedRecipient  string `json:"bounced_recipient"`
+}
+
+// normalizeZeptoMailStatus maps ZeptoMail webhook events. Soft bounces are
+// retried by ZeptoMail; opens and clicks do not change the status.
+func normalizeZeptoMailStatus(event string) string {
+	switch event {
+	case "hardbounce":
+		return "bounced"
+	default:
+		return ""
+	}
+}
+
 // --- Helpers ---
 
 // lookupMessageIDByProvider finds the internal message ID from a provider message ID.
@@ -291,11 +628,12 @@ func lookupMessageIDByProvider(r *http.Request, queries storage.Querier, provide
 	return log.MessageID, nil
 }
 
-// marshalMetadata marshals a string map to JSON bytes for storage.
+// marshalMetadata marshals a string map to JSON bytes for storage, masking
+// email addresses according to the redact configuration.
 func marshalMetadata(m map[string]string) []byte {
 	data, err := json.Marshal(m)
 	if err != nil {
 		return []byte("{}")
 	}
-	return data
+	return redact.JSON(data)
 }
@@ -513,3 +513,174 @@ func TestNormalizeMailgunStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestSESWebhookHandler_SNSEnvelope(t *testing.T) {
+	msgID := uuid.New()
+	var capturedStatus string
+	mock := &mockQuerier{
+		getDeliveryLogByProviderMessageIDFn: func(ctx context.Context, providerMsgID sql.NullString) (storage.DeliveryLog, error) {
+			return storage.DeliveryLog{MessageID: msgID}, nil
+		},
+		updateDeliveryLogStatusFn: func(ctx context.Context, arg storage.UpdateDeliveryLogStatusParams) error {
+			capturedStatus = arg.Status
+			return nil
+		},
+	}
+
+	// A configuration set event, wrapped in an SNS envelope.
+	event := `{"eventType":"Delivery","mail":{"messageId":"abc123"},"delivery":{"timestamp":"2024-01-01T00:00:00Z"}}`
+	envelope, _ := json.Marshal(map[string]string{"Type": "Notification", "TopicArn": "arn:aws:sns:us-east-1:1:t", "Message": event})
+	rec := httptest.NewRecorder()
+	SESWebhookHandler(mock).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/ses", strings.NewReader(string(envelope))))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if capturedStatus != "sent" {
+		t.Errorf("expected status 'sent', got %q", capturedStatus)
+	}
+}
+
+func TestSESWebhookHandler_SubscriptionConfirmationRejectsForeignURL(t *testing.T) {
+	for _, subscribeURL := range []string{
+		"https://evil.example.com/?Action=ConfirmSubscription",
+		"http://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription",
+		"https://sns.us-east-1.amazonaws.com.evil.com/",
+		"https://sns.us-east-1.amazonaws.com:8443/",
+	} {
+		body, _ := json.Marshal(map[string]string{"Type": "SubscriptionConfirmation", "SubscribeURL": subscribeURL})
+		rec := httptest.NewRecorder()
+		SESWebhookHandler(&mockQuerier{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/ses", strings.NewReader(string(body))))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", subscribeURL, rec.Code)
+		}
+	}
+}
+
+// --- Resend, Brevo and ZeptoMail Webhook Tests ---
+
+// webhookUpdates returns a mock resolving every provider message ID in ids
+// to a message, and the delivery log updates it receives.
+func webhookUpdates(ids map[string]uuid.UUID) (*mockQuerier, *[]storage.UpdateDeliveryLogStatusParams) {
+	var updates []storage.UpdateDeliveryLogStatusParams
+	return &mockQuerier{
+		getDeliveryLogByProviderMessageIDFn: func(ctx context.Context, providerMsgID sql.NullString) (storage.DeliveryLog, error) {
+			id, ok := ids[providerMsgID.String]
+			if !ok {
+				return storage.DeliveryLog{}, errors.New("not found")
+			}
+			return storage.DeliveryLog{MessageID: id}, nil
+		},
+		updateDeliveryLogStatusFn: func(ctx context.Context, arg storage.UpdateDeliveryLogStatusParams) error {
+			updates = append(updates, arg)
+			return nil
+		},
+	}, &updates
+}
+
+func TestResendWebhookHandler_Bounced(t *testing.T) {
+	msgID := uuid.New()
+	mock, updates := webhookUpdates(map[string]uuid.UUID{"4ef9a417": msgID})
+
+	body := `{"type":"email.bounced","created_at":"2026-10-17T12:00:00Z","data":{"email_id":"4ef9a417","to":["a@example.com"],"bounce":{"message":"Mailbox does not exist","type":"Permanent"}}}`
+	rec := httptest.NewRecorder()
+	ResendWebhookHandler(mock).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/resend", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(*updates) != 1 {
+		t.Fatalf("expected one update, got %d", len(*updates))
+	}
+	upd := (*updates)[0]
+	if upd.MessageID != msgID || upd.Status != "bounced" || upd.Provider.String != "resend" || upd.LastError.String != "Mailbox does not exist" {
+		t.Errorf("unexpected update: %+v", upd)
+	}
+}
+
+func TestResendWebhookHandler_UnknownEvent(t *testing.T) {
+	mock, updates := webhookUpdates(map[string]uuid.UUID{"4ef9a417": uuid.New()})
+
+	body := `{"type":"email.opened","data":{"email_id":"4ef9a417"}}`
+	rec := httptest.NewRecorder()
+	ResendWebhookHandler(mock).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/resend", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK || len(*updates) != 0 {
+		t.Errorf("status = %d, updates = %+v", rec.Code, *updates)
+	}
+}
+
+func TestBrevoWebhookHandler(t *testing.T) {
+	msgID := uuid.New()
+
+	for name, body := range map[string]string{
+		"single":  `{"event":"spam","email":"a@example.com","message-id":"<1@smtp-relay.mailin.fr>"}`,
+		"batched": `[{"event":"opened","message-id":"<1@smtp-relay.mailin.fr>"},{"event":"spam","email":"a@example.com","message-id":"<1@smtp-relay.mailin.fr>"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mock, updates := webhookUpdates(map[string]uuid.UUID{"<1@smtp-relay.mailin.fr>": msgID})
+			rec := httptest.NewRecorder()
+			BrevoWebhookHandler(mock).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/brevo", strings.NewReader(body)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+			}
+			if len(*updates) != 1 || (*updates)[0].Status != "complained" || (*updates)[0].MessageID != msgID || (*updates)[0].Provider.String != "brevo" {
+				t.Errorf("unexpected updates: %+v", *updates)
+			}
+		})
+	}
+}
+
+func TestBrevoWebhookHandler_InvalidJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	BrevoWebhookHandler(&mockQuerier{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/brevo", strings.NewReader("{bad")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestZeptoMailWebhookHandler_HardBounce(t *testing.T) {
+	msgID := uuid.New()
+	mock, updates := webhookUpdates(map[string]uuid.UUID{"2d6f.4c1d": msgID})
+
+	body := `{"event_name":["hardbounce"],"event_message":[{"request_id":"2d6f.4c1d","email_info":{"client_reference":"msg-1"},` +
+		`"event_data":[{"object":"hardbounce","details":[{"reason":"Invalid recipient","diagnostic_message":"550 5.1.1 user unknown","bounced_recipient":"a@example.com"}]}]}],"mailagent_key":"1a2b"}`
+	rec := httptest.NewRecorder()
+	ZeptoMailWebhookHandler(mock).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/zeptomail", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if len(*updates) != 1 {
+		t.Fatalf("expected one update, got %d", len(*updates))
+	}
+	upd := (*updates)[0]
+	if upd.MessageID != msgID || upd.Status != "bounced" || upd.Provider.String != "zeptomail" || upd.LastError.String != "Invalid recipient: 550 5.1.1 user unknown" {
+		t.Errorf("unexpected update: %+v", upd)
+	}
+}
+
+func TestNormalizeBrevoStatus(t *testing.T) {
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{"delivered", "sent"},
+		{"hard_bounce", "bounced"},
+		{"blocked", "bounced"},
+		{"soft_bounce", ""},
+		{"deferred", ""},
+		{"invalid_email", "failed"},
+		{"spam", "complained"},
+		{"opened", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.event, func(t *testing.T) {
+			if got := normalizeBrevoStatus(tc.event); got != tc.want {
+				t.Errorf("normalizeBrevoStatus(%q) = %q, want %q", tc.event, got, tc.want)
+			}
+		})
+	}
+}
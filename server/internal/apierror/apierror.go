@@ -0,0 +1,171 @@
+// Package apierror defines the JSON error envelope returned by the HTTP
+// APIs and the stable error codes clients can branch on.
+package apierror
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sungwon/smtp-proxy/server/internal/i18n"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// Error codes. Codes are stable: clients may branch on them, so existing
+// codes must not be renamed.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeValidationFailed    = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeIPNotAllowed        = "ip_not_allowed"
+	CodeClientCertRequired  = "client_certificate_required"
+	CodeCSRFTokenInvalid    = "csrf_token_invalid"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeConflict            = "conflict"
+	CodeAlreadyExists       = "already_exists"
+	CodeReferenceConflict   = "reference_conflict"
+	CodePayloadTooLarge     = "payload_too_large"
+	CodeUnprocessable       = "unprocessable"
+	CodeRateLimited         = "rate_limited"
+	CodeInternal            = "internal_error"
+	CodeProviderError       = "provider_error"
+	CodeUnavailable         = "unavailable"
+	CodeDatabaseUnavailable = "database_unavailable"
+	CodeTimeout             = "timeout"
+)
+
+// RequestIDHeader is the response header carrying the request's ID, which
+// Write copies into the envelope.
+const RequestIDHeader = "X-Correlation-ID"
+
+// LanguageHeader is the response header carrying the locale negotiated for
+// the request, in which Write localizes messages.
+const LanguageHeader = "Content-Language"
+
+// Response is the error envelope:
+//
+//	{"code": "not_found", "message": "user not found", "request_id": "...", "error": "user not found"}
+type Response struct {
+	// Code is a stable, machine-readable error code.
+	Code string `json:"code"`
+	// Message is a human-readable description.
+	Message string `json:"message"`
+	// Details holds code-specific data, such as validation errors.
+	Details any `json:"details,omitempty"`
+	// RequestID identifies the request in the server logs.
+	RequestID string `json:"request_id,omitempty"`
+	// Error is the pre-envelope error string, kept for clients that read
+	// it. It is never localized. Deprecated: use Code and Message.
+	Error string `json:"error"`
+}
+
+// Write writes an error envelope. An empty code is derived from status.
+// Message and string details are translated into the locale of the
+// LanguageHeader response header, if set.
+func Write(w http.ResponseWriter, status int, code, message string, details any) {
+	if code == "" {
+		code = CodeForStatus(status)
+	}
+	resp := Response{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+		Error:     message,
+	}
+	if locale := w.Header().Get(LanguageHeader); locale != "" {
+		resp.Message = i18n.Translate(locale, message)
+		if errs, ok := details.([]string); ok {
+			localized := make([]string, len(errs))
+			for i, e := range errs {
+				localized[i] = i18n.Translate(locale, e)
+			}
+			resp.Details = localized
+		}
+	}
+	if code == CodeValidationFailed {
+		resp.Error = CodeValidationFailed
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// CodeForStatus returns the default error code of an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeProviderError
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// PostgreSQL error codes mapped by Classify.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// Classify maps storage and provider errors to an HTTP status and code. It
+// returns false for errors it does not recognize.
+func Classify(err error) (status int, code string, ok bool) {
+	var pgErr *pgconn.PgError
+	var connErr *pgconn.ConnectError
+	var provErr *provider.ProviderError
+	switch {
+	case err == nil:
+		return 0, "", false
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, CodeNotFound, true
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return http.StatusConflict, CodeAlreadyExists, true
+		case pgForeignKeyViolation:
+			return http.StatusConflict, CodeReferenceConflict, true
+		case pgCheckViolation:
+			return http.StatusBadRequest, CodeValidationFailed, true
+		}
+		return 0, "", false
+	case errors.As(err, &connErr):
+		return http.StatusServiceUnavailable, CodeDatabaseUnavailable, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout, true
+	case errors.As(err, &provErr):
+		return http.StatusBadGateway, CodeProviderError, true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,108 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set(RequestIDHeader, "req-123")
+
+	Write(rec, http.StatusNotFound, "", "user not found", nil)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := map[string]any{
+		"code":       "not_found",
+		"message":    "user not found",
+		"request_id": "req-123",
+		"error":      "user not found",
+	}
+	for k, v := range want {
+		if resp[k] != v {
+			t.Errorf("%s = %v, want %v", k, resp[k], v)
+		}
+	}
+	if _, ok := resp["details"]; ok {
+		t.Error("expected details to be omitted")
+	}
+}
+
+func TestWrite_ValidationDetails(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Write(rec, http.StatusBadRequest, CodeValidationFailed, "request validation failed", []string{"email is required"})
+
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Code != CodeValidationFailed || resp.Error != "validation_failed" {
+		t.Errorf("unexpected envelope %+v", resp)
+	}
+	if details, ok := resp.Details.([]any); !ok || len(details) != 1 {
+		t.Errorf("expected one detail, got %v", resp.Details)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantOK     bool
+	}{
+		{"no rows", fmt.Errorf("get user: %w", pgx.ErrNoRows), http.StatusNotFound, CodeNotFound, true},
+		{"unique", &pgconn.PgError{Code: "23505"}, http.StatusConflict, CodeAlreadyExists, true},
+		{"foreign key", &pgconn.PgError{Code: "23503"}, http.StatusConflict, CodeReferenceConflict, true},
+		{"other pg error", &pgconn.PgError{Code: "42P01"}, 0, "", false},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, true},
+		{"provider", &provider.ProviderError{Provider: "sendgrid", StatusCode: 500}, http.StatusBadGateway, CodeProviderError, true},
+		{"unknown", errors.New("boom"), 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code, ok := Classify(tt.err)
+			if status != tt.wantStatus || code != tt.wantCode || ok != tt.wantOK {
+				t.Errorf("Classify() = %d %q %v, want %d %q %v", status, code, ok, tt.wantStatus, tt.wantCode, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWrite_Localized(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set(LanguageHeader, "ko")
+	Write(rec, http.StatusBadRequest, CodeValidationFailed, "request validation failed", []string{"email is required", "custom detail"})
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Message != "요청 검증에 실패했습니다" {
+		t.Errorf("Message = %q", resp.Message)
+	}
+	details, _ := resp.Details.([]any)
+	if len(details) != 2 || details[0] != "이메일이 필요합니다" || details[1] != "custom detail" {
+		t.Errorf("Details = %v", resp.Details)
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Login anomaly reasons.
+const (
+	// AnomalyNewCountry is a login from a country the user has not logged
+	// in from before.
+	AnomalyNewCountry = "new_country"
+	// AnomalyNewNetwork is a login from an unfamiliar network when the
+	// client's country is unknown.
+	AnomalyNewNetwork = "new_network"
+)
+
+// LoginNetwork returns the network of a login address in CIDR notation:
+// the /24 of an IPv4 address or the /48 of an IPv6 address, so addresses
+// reassigned within a provider's block are not treated as new.
+func LoginNetwork(ip netip.Addr) string {
+	ip = ip.Unmap()
+	bits := 48
+	if ip.Is4() {
+		bits = 24
+	}
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return ip.String()
+	}
+	return prefix.String()
+}
+
+// NormalizeCountry returns the upper-case ISO 3166 alpha-2 code in a
+// geolocation header value, or "" when the value is missing or is a
+// placeholder such as Cloudflare's XX (unknown) and T1 (Tor).
+func NormalizeCountry(value string) string {
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if len(code) != 2 || code == "XX" || code == "T1" || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return ""
+	}
+	return code
+}
+
+// DetectLoginAnomaly compares a login from network and country with the
+// networks the user logged in from before and returns the anomaly reason,
+// or "" for a familiar login. A user's first login is never anomalous. When
+// the country is known, only a new country is anomalous, so users moving
+// between networks of their usual countries are not flagged; otherwise any
+// new network is.
+func DetectLoginAnomaly(known []storage.LoginNetwork, network, country string) string {
+	if len(known) == 0 {
+		return ""
+	}
+	for _, k := range known {
+		if k.Network == network {
+			return ""
+		}
+	}
+	if country == "" {
+		return AnomalyNewNetwork
+	}
+	for _, k := range known {
+		if k.Country.String == country {
+			return ""
+		}
+	}
+	return AnomalyNewCountry
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestLoginNetwork(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.17":         "192.0.2.0/24",
+		"::ffff:192.0.2.200": "192.0.2.0/24",
+		"2001:db8:1:2::5":    "2001:db8:1::/48",
+	}
+	for ip, want := range tests {
+		if got := LoginNetwork(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("LoginNetwork(%s) = %s, want %s", ip, got, want)
+		}
+	}
+}
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := map[string]string{"kr": "KR", " JP ": "JP", "XX": "", "T1": "", "": "", "USA": "", "1A": ""}
+	for in, want := range tests {
+		if got := NormalizeCountry(in); got != want {
+			t.Errorf("NormalizeCountry(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDetectLoginAnomaly(t *testing.T) {
+	known := []storage.LoginNetwork{
+		{Network: "192.0.2.0/24", Country: pgtype.Text{String: "KR", Valid: true}},
+		{Network: "198.51.100.0/24"},
+	}
+	tests := []struct {
+		name    string
+		known   []storage.LoginNetwork
+		network string
+		country string
+		want    string
+	}{
+		{"first login", nil, "203.0.113.0/24", "US", ""},
+		{"known network", known, "198.51.100.0/24", "US", ""},
+		{"new network, known country", known, "203.0.113.0/24", "KR", ""},
+		{"new network, new country", known, "203.0.113.0/24", "US", AnomalyNewCountry},
+		{"new network, unknown country", known, "203.0.113.0/24", "", AnomalyNewNetwork},
+	}
+	for _, tt := range tests {
+		if got := DetectLoginAnomaly(tt.known, tt.network, tt.country); got != tt.want {
+			t.Errorf("%s: DetectLoginAnomaly() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -9,18 +9,90 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
 )
 
 // AuditAction defines known audit log actions.
 const (
-	AuditActionLogin        = "auth.login"
-	AuditActionLoginFailed  = "auth.login_failed"
-	AuditActionLogout       = "auth.logout"
-	AuditActionTokenRefresh = "auth.token_refresh"
-	AuditActionCreateUser   = "admin.create_user"
-	AuditActionUpdateRole   = "admin.update_role"
-	AuditActionCreateGroup  = "admin.create_group"
-	AuditActionDeleteGroup  = "admin.delete_group"
+	AuditActionLogin         = "auth.login"
+	AuditActionLoginFailed   = "auth.login_failed"
+	AuditActionLogout        = "auth.logout"
+	AuditActionTokenRefresh  = "auth.token_refresh"
+	AuditActionRevokeSession = "auth.revoke_session"
+	AuditActionMintToken     = "auth.mint_token"
+	AuditActionLockout       = "auth.lockout"
+	AuditActionUnlock        = "auth.unlock"
+	AuditActionLoginAnomaly  = "auth.login_anomaly"
+	AuditActionAcceptInvite  = "auth.accept_invitation"
+	AuditActionSignup        = "auth.signup"
+	AuditActionCreateUser    = "admin.create_user"
+	AuditActionUpdateRole    = "admin.update_role"
+	AuditActionSetPassword   = "admin.set_password"
+	AuditActionCreateGroup   = "admin.create_group"
+	AuditActionDeleteGroup   = "admin.delete_group"
+	AuditActionExportGroup   = "admin.export_group_data"
+	AuditActionEraseGroup    = "admin.erase_group_data"
+	AuditActionInviteMember  = "admin.invite_member"
+	AuditActionRevokeInvite  = "admin.revoke_invitation"
+
+	AuditActionUpdateBranding = "admin.update_branding"
+	AuditActionDeleteBranding = "admin.delete_branding"
+
+	AuditActionEnableSMTPDebug  = "admin.enable_smtp_debug"
+	AuditActionDisableSMTPDebug = "admin.disable_smtp_debug"
+
+	AuditActionCreateScript = "admin.create_script"
+	AuditActionUpdateScript = "admin.update_script"
+	AuditActionDeleteScript = "admin.delete_script"
+
+	AuditActionCreateAlertChannel = "admin.create_alert_channel"
+	AuditActionDeleteAlertChannel = "admin.delete_alert_channel"
+
+	AuditActionCreateSenderIdentity = "admin.create_sender_identity"
+	AuditActionVerifySenderIdentity = "admin.verify_sender_identity"
+	AuditActionDeleteSenderIdentity = "admin.delete_sender_identity"
+	AuditActionUpdateSenderPolicy   = "admin.update_sender_policy"
+
+	AuditActionUpdateShadowPolicies = "admin.update_shadow_policies"
+
+	AuditActionUpdateSendWindow = "admin.update_send_window"
+	AuditActionDeleteSendWindow = "admin.delete_send_window"
+
+	AuditActionUpdateMessageDefaults = "admin.update_message_defaults"
+	AuditActionDeleteMessageDefaults = "admin.delete_message_defaults"
+
+	AuditActionDeleteSuppression = "admin.delete_suppression"
+
+	AuditActionUpdateSendingDomain = "admin.update_sending_domain"
+	AuditActionDeleteSendingDomain = "admin.delete_sending_domain"
+
+	AuditActionUpdateSigningKey    = "admin.update_signing_key"
+	AuditActionDeleteSigningKey    = "admin.delete_signing_key"
+	AuditActionUpdateRecipientCert = "admin.update_recipient_certificate"
+	AuditActionDeleteRecipientCert = "admin.delete_recipient_certificate"
+
+	AuditActionSetupSESEvents = "admin.setup_ses_events"
+
+	AuditActionDeleteProviderCaptures = "admin.delete_provider_captures"
+
+	AuditActionLockdownUser    = "admin.lockdown_user"
+	AuditActionLockdownGroup   = "admin.lockdown_group"
+	AuditActionRevokeSessions  = "admin.revoke_sessions"
+	AuditActionRevokeAPIKey    = "admin.revoke_api_key"
+	AuditActionPauseMessages   = "admin.pause_messages"
+	AuditActionReleaseMessages = "admin.release_messages"
+
+	AuditActionResendMessages = "admin.resend_messages"
+
+	// System actions are recorded by background jobs without a human actor.
+	AuditActionMessageRequeued = "system.message_requeued"
+	AuditActionMessageExpired  = "system.message_expired"
+	AuditActionSenderRejected  = "system.sender_rejected"
+	AuditActionSenderRewritten = "system.sender_rewritten"
+
+	// SMTP actions are recorded by the SMTP server for the SMTP user.
+	AuditActionSMTPSession = "smtp.session"
 )
 
 // AuditEntry represents a single activity log entry to be persisted.
@@ -84,6 +156,24 @@ func (al *AuditLogger) LogAuthFailure(ctx context.Context, r *http.Request, acti
 	al.log(ctx, entry)
 }
 
+// LogAuthEvent logs a security event about a user's account that happens
+// outside an authenticated request, such as a lockout or a login from an
+// unfamiliar location.
+func (al *AuditLogger) LogAuthEvent(ctx context.Context, r *http.Request, groupID, userID uuid.UUID, action, comment string, changes map[string]interface{}) {
+	entry := AuditEntry{
+		GroupID:      groupID,
+		UserID:       userID,
+		Action:       action,
+		ResourceType: "user",
+		ResourceID:   userID.String(),
+		Changes:      changes,
+		Comment:      comment,
+		IPAddress:    extractIP(r),
+	}
+
+	al.log(ctx, entry)
+}
+
 // LogAdminAction logs an administrative action (user creation, role change, etc.).
 func (al *AuditLogger) LogAdminAction(ctx context.Context, r *http.Request, action, resourceType, resourceID string, changes map[string]interface{}) {
 	groupID := GroupIDFromContext(ctx)
@@ -102,8 +192,12 @@ func (al *AuditLogger) LogAdminAction(ctx context.Context, r *http.Request, acti
 	al.log(ctx, entry)
 }
 
-// log persists the audit entry and logs it via zerolog.
+// log persists the audit entry and logs it via zerolog. Email addresses in
+// the comment and changes are masked according to the redact configuration.
 func (al *AuditLogger) log(ctx context.Context, entry AuditEntry) {
+	entry.Comment = redact.Text(entry.Comment)
+	entry.Changes = redact.Fields(entry.Changes)
+
 	// Log to structured logger
 	event := al.logger.Info().
 		Str("action", entry.Action).
@@ -135,6 +229,12 @@ func (al *AuditLogger) log(ctx context.Context, entry AuditEntry) {
 	}
 }
 
+// ClientIP returns the client IP address of the request as recorded in the
+// activity log.
+func ClientIP(r *http.Request) string {
+	return extractIP(r)
+}
+
 // extractIP extracts the client IP address from the request,
 // checking X-Forwarded-For and X-Real-IP headers first.
 func extractIP(r *http.Request) string {
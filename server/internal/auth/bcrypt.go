@@ -2,14 +2,24 @@ package auth
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sungwon/smtp-proxy/server/internal/fips"
 )
 
 const bcryptCost = 12
 
-// HashPassword hashes a plaintext password using bcrypt with cost factor 12.
+// fipsEnabled selects the password hash; tests replace it.
+var fipsEnabled = fips.Enabled
+
+// HashPassword hashes a plaintext password using bcrypt with cost factor 12,
+// or PBKDF2-HMAC-SHA256 in FIPS mode, where bcrypt is not approved.
 func HashPassword(password string) (string, error) {
+	if fipsEnabled() {
+		return hashPBKDF2(password)
+	}
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
 	if err != nil {
 		return "", fmt.Errorf("hash password: %w", err)
@@ -17,8 +27,12 @@ func HashPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-// VerifyPassword checks a plaintext password against a bcrypt hash.
+// VerifyPassword checks a plaintext password against a bcrypt or PBKDF2
+// hash, so passwords set before FIPS mode was enabled keep working.
 // Returns nil on success, or an error if the password does not match.
 func VerifyPassword(hash, password string) error {
+	if strings.HasPrefix(hash, pbkdf2Prefix) {
+		return verifyPBKDF2(hash, password)
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
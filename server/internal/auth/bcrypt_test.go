@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -50,3 +51,49 @@ func TestHashPassword_DifferentHashesForSameInput(t *testing.T) {
 		t.Error("HashPassword() produced identical hashes for same input (bcrypt should use random salt)")
 	}
 }
+
+func TestHashPassword_FIPSUsesPBKDF2(t *testing.T) {
+	defer func(f func() bool) { fipsEnabled = f }(fipsEnabled)
+	fipsEnabled = func() bool { return true }
+
+	hash, err := HashPassword("correctpassword")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$pbkdf2-sha256$i=600000$") || len(hash) > 255 {
+		t.Fatalf("HashPassword() in FIPS mode = %q, want a PBKDF2 hash fitting password_hash", hash)
+	}
+	if err := VerifyPassword(hash, "correctpassword"); err != nil {
+		t.Errorf("VerifyPassword() with correct password returned error: %v", err)
+	}
+	if err := VerifyPassword(hash, "wrongpassword"); err == nil {
+		t.Error("VerifyPassword() with wrong password returned nil error")
+	}
+}
+
+func TestVerifyPassword_BcryptInFIPSMode(t *testing.T) {
+	hash, err := HashPassword("legacypassword")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	defer func(f func() bool) { fipsEnabled = f }(fipsEnabled)
+	fipsEnabled = func() bool { return true }
+
+	if err := VerifyPassword(hash, "legacypassword"); err != nil {
+		t.Errorf("VerifyPassword() of a bcrypt hash in FIPS mode returned error: %v", err)
+	}
+}
+
+func TestVerifyPassword_MalformedPBKDF2(t *testing.T) {
+	for _, hash := range []string{
+		"$pbkdf2-sha256$",
+		"$pbkdf2-sha256$i=0$c2FsdA$a2V5",
+		"$pbkdf2-sha256$i=1000$!!!$a2V5",
+		"$pbkdf2-sha256$i=1000$c2FsdA$",
+	} {
+		if err := VerifyPassword(hash, "password"); err == nil {
+			t.Errorf("VerifyPassword(%q) returned nil error", hash)
+		}
+	}
+}
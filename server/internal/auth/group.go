@@ -7,6 +7,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
 // GroupContext returns an HTTP middleware that sets the PostgreSQL session variable
@@ -18,12 +20,12 @@ func GroupContext(pool *pgxpool.Pool) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			groupID := GroupIDFromContext(r.Context())
 			if groupID == uuid.Nil {
-				http.Error(w, `{"error":"group context required"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "group context required", nil)
 				return
 			}
 
 			if err := setGroupID(r.Context(), pool, groupID); err != nil {
-				http.Error(w, `{"error":"failed to set group context"}`, http.StatusInternalServerError)
+				apierror.Write(w, http.StatusInternalServerError, "", "failed to set group context", nil)
 				return
 			}
 
@@ -37,3 +39,39 @@ func setGroupID(ctx context.Context, pool *pgxpool.Pool, groupID uuid.UUID) erro
 	_, err := pool.Exec(ctx, fmt.Sprintf("SET LOCAL app.current_group_id = '%s'", groupID.String()))
 	return err
 }
+
+// GroupPath returns the IDs of the group's ancestors followed by the group
+// itself, from the top of the hierarchy down (e.g. company, team). The path
+// of a top-level group is just its own ID.
+func GroupPath(ctx context.Context, queries storage.Querier, groupID uuid.UUID) ([]uuid.UUID, error) {
+	ancestors, err := queries.ListGroupAncestors(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("list group ancestors: %w", err)
+	}
+	path := make([]uuid.UUID, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		path = append(path, ancestors[i].ID)
+	}
+	if len(path) == 0 || path[len(path)-1] != groupID {
+		path = append(path, groupID)
+	}
+	return path, nil
+}
+
+// InGroupTree reports whether groupID is ancestorID or one of its sub-groups
+// at any depth.
+func InGroupTree(ctx context.Context, queries storage.Querier, groupID, ancestorID uuid.UUID) (bool, error) {
+	if groupID == ancestorID {
+		return true, nil
+	}
+	path, err := GroupPath(ctx, queries, groupID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range path {
+		if id == ancestorID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -16,6 +16,9 @@ type JWTConfig struct {
 	RefreshTokenExpiry time.Duration `mapstructure:"refresh_token_expiry"`
 	Issuer             string        `mapstructure:"issuer"`
 	Audience           string        `mapstructure:"audience"`
+	// ScopedTokenMaxTTL caps the lifetime of scoped tokens minted by
+	// GenerateScopedToken.
+	ScopedTokenMaxTTL time.Duration `mapstructure:"scoped_token_max_ttl"`
 }
 
 // AccessTokenClaims represents claims in an access token.
@@ -24,9 +27,45 @@ type AccessTokenClaims struct {
 	GroupType string `json:"group_type"`
 	Email     string `json:"email"`
 	Role      string `json:"role"`
+	// GroupPath lists the IDs from the top-level group down to GroupID.
+	// Tokens issued before sub-groups existed omit it.
+	GroupPath []string `json:"group_path,omitempty"`
+	// Scope restricts a scoped token to the listed scopes (ScopeSend,
+	// ScopeRead). Tokens without it are unrestricted.
+	Scope []string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Token scopes.
+const (
+	// ScopeSend allows SMTP submission with the token as the AUTH password.
+	ScopeSend = "send"
+	// ScopeRead allows read-only (GET and HEAD) API requests.
+	ScopeRead = "read"
+)
+
+// ValidScope reports whether scope is a known token scope.
+func ValidScope(scope string) bool {
+	return scope == ScopeSend || scope == ScopeRead
+}
+
+// HasScope reports whether the token is unrestricted or grants scope.
+func (c *AccessTokenClaims) HasScope(scope string) bool {
+	return hasScope(c.Scope, scope)
+}
+
+func hasScope(scopes []string, scope string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // RefreshTokenClaims represents claims in a refresh token.
 type RefreshTokenClaims struct {
 	GroupID   string `json:"group_id"`
@@ -34,6 +73,35 @@ type RefreshTokenClaims struct {
 	jwt.RegisteredClaims
 }
 
+// ActionTokenClaims represents claims in a single-purpose token emailed to
+// a user, such as an account unlock link.
+type ActionTokenClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// Action token purposes.
+const (
+	// PurposeUnlock unlocks an account locked after failed logins.
+	PurposeUnlock = "unlock"
+	// PurposeInvite accepts a group invitation.
+	PurposeInvite = "invite"
+	// PurposeVerifyEmail confirms a self-service signup.
+	PurposeVerifyEmail = "verify_email"
+	// PurposeDeliveryReport downloads a generated delivery report.
+	PurposeDeliveryReport = "delivery_report"
+)
+
+// PlaceholderSigningKey is the signing key shipped in config.yaml, which
+// must be replaced in production.
+const PlaceholderSigningKey = "change-me-in-production-use-a-strong-secret"
+
+// WeakSigningKey reports whether key is empty or the shipped placeholder.
+// Anyone can sign tokens that validate against such a key.
+func WeakSigningKey(key string) bool {
+	return key == "" || key == PlaceholderSigningKey
+}
+
 // JWTService handles JWT token generation and validation.
 type JWTService struct {
 	config JWTConfig
@@ -44,22 +112,36 @@ func NewJWTService(config JWTConfig) *JWTService {
 	return &JWTService{config: config}
 }
 
+// WeakSigningKey reports whether the service signs with an empty or
+// placeholder key.
+func (s *JWTService) WeakSigningKey() bool {
+	return WeakSigningKey(s.config.SigningKey)
+}
+
 // Predefined errors for JWT operations.
 var (
 	ErrTokenExpired   = errors.New("token has expired")
 	ErrTokenInvalid   = errors.New("token is invalid")
 	ErrTokenMalformed = errors.New("token is malformed")
 	ErrSigningMethod  = errors.New("unexpected signing method")
+	ErrScopedTokenTTL = errors.New("scoped token lifetime out of range")
 )
 
 // GenerateAccessToken creates a signed JWT access token for the given user.
-func (s *JWTService) GenerateAccessToken(userID, groupID uuid.UUID, email, role, groupType string) (string, error) {
+// groupPath is the group's path as returned by GroupPath; it may be nil for
+// a top-level group.
+func (s *JWTService) GenerateAccessToken(userID, groupID uuid.UUID, email, role, groupType string, groupPath []uuid.UUID) (string, error) {
 	now := time.Now()
+	var path []string
+	for _, id := range groupPath {
+		path = append(path, id.String())
+	}
 	claims := AccessTokenClaims{
 		GroupID:   groupID.String(),
 		GroupType: groupType,
 		Email:     email,
 		Role:      role,
+		GroupPath: path,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   userID.String(),
 			Issuer:    s.config.Issuer,
@@ -77,6 +159,48 @@ func (s *JWTService) GenerateAccessToken(userID, groupID uuid.UUID, email, role,
 	return signed, nil
 }
 
+// GenerateScopedToken creates a signed access token limited to scopes and
+// valid for ttl, which must be positive and at most ScopedTokenMaxTTL. It
+// returns the token and its expiry.
+func (s *JWTService) GenerateScopedToken(userID, groupID uuid.UUID, email, role, groupType string, groupPath []uuid.UUID, scopes []string, ttl time.Duration) (string, time.Time, error) {
+	if ttl <= 0 || ttl > s.config.ScopedTokenMaxTTL {
+		return "", time.Time{}, ErrScopedTokenTTL
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	var path []string
+	for _, id := range groupPath {
+		path = append(path, id.String())
+	}
+	claims := AccessTokenClaims{
+		GroupID:   groupID.String(),
+		GroupType: groupType,
+		Email:     email,
+		Role:      role,
+		GroupPath: path,
+		Scope:     scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			Issuer:    s.config.Issuer,
+			Audience:  jwt.ClaimStrings{s.config.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(s.config.SigningKey))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign scoped token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// ScopedTokenMaxTTL returns the longest lifetime a scoped token may have.
+func (s *JWTService) ScopedTokenMaxTTL() time.Duration {
+	return s.config.ScopedTokenMaxTTL
+}
+
 // GenerateRefreshToken creates a signed JWT refresh token for the given session.
 func (s *JWTService) GenerateRefreshToken(userID, groupID, sessionID uuid.UUID) (string, error) {
 	now := time.Now()
@@ -100,6 +224,31 @@ func (s *JWTService) GenerateRefreshToken(userID, groupID, sessionID uuid.UUID)
 	return signed, nil
 }
 
+// GenerateActionToken creates a signed token for purpose about subject
+// (e.g. an email address), valid for ttl. id is recorded as the token ID so
+// the caller can make the token single-use.
+func (s *JWTService) GenerateActionToken(purpose, subject, id string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := ActionTokenClaims{
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   subject,
+			Issuer:    s.config.Issuer,
+			Audience:  jwt.ClaimStrings{s.config.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(s.config.SigningKey))
+	if err != nil {
+		return "", fmt.Errorf("sign action token: %w", err)
+	}
+	return signed, nil
+}
+
 // ValidateAccessToken parses and validates a JWT access token string.
 // Returns the claims if valid, or an error if the token is expired, invalid, or malformed.
 func (s *JWTService) ValidateAccessToken(tokenString string) (*AccessTokenClaims, error) {
@@ -142,6 +291,27 @@ func (s *JWTService) ValidateRefreshToken(tokenString string) (*RefreshTokenClai
 	return claims, nil
 }
 
+// ValidateActionToken parses and validates an action token string and
+// checks that it was issued for purpose.
+func (s *JWTService) ValidateActionToken(tokenString, purpose string) (*ActionTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ActionTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrSigningMethod
+		}
+		return []byte(s.config.SigningKey), nil
+	})
+	if err != nil {
+		return nil, classifyJWTError(err)
+	}
+
+	claims, ok := token.Claims.(*ActionTokenClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose {
+		return nil, ErrTokenInvalid
+	}
+
+	return claims, nil
+}
+
 // classifyJWTError maps jwt library errors to domain-specific errors.
 func classifyJWTError(err error) error {
 	if errors.Is(err, jwt.ErrTokenExpired) {
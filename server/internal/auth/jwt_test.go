@@ -15,6 +15,7 @@ func newTestJWTService() *JWTService {
 		RefreshTokenExpiry: 7 * 24 * time.Hour,
 		Issuer:             "smtp-proxy-test",
 		Audience:           "smtp-proxy-api",
+		ScopedTokenMaxTTL:  24 * time.Hour,
 	})
 }
 
@@ -23,7 +24,7 @@ func TestGenerateAccessToken(t *testing.T) {
 	userID := uuid.New()
 	groupID := uuid.New()
 
-	token, err := svc.GenerateAccessToken(userID, groupID, "user@example.com", "admin", "organization")
+	token, err := svc.GenerateAccessToken(userID, groupID, "user@example.com", "admin", "organization", nil)
 	if err != nil {
 		t.Fatalf("GenerateAccessToken() error = %v", err)
 	}
@@ -54,7 +55,7 @@ func TestValidateAccessToken_Valid(t *testing.T) {
 	email := "user@example.com"
 	role := "admin"
 
-	token, err := svc.GenerateAccessToken(userID, groupID, email, role, "organization")
+	token, err := svc.GenerateAccessToken(userID, groupID, email, role, "organization", nil)
 	if err != nil {
 		t.Fatalf("GenerateAccessToken() error = %v", err)
 	}
@@ -93,7 +94,7 @@ func TestValidateAccessToken_Expired(t *testing.T) {
 		Audience:           "smtp-proxy-api",
 	})
 
-	token, err := svc.GenerateAccessToken(uuid.New(), uuid.New(), "user@example.com", "member", "organization")
+	token, err := svc.GenerateAccessToken(uuid.New(), uuid.New(), "user@example.com", "member", "organization", nil)
 	if err != nil {
 		t.Fatalf("GenerateAccessToken() error = %v", err)
 	}
@@ -107,7 +108,7 @@ func TestValidateAccessToken_Expired(t *testing.T) {
 func TestValidateAccessToken_InvalidSignature(t *testing.T) {
 	svc := newTestJWTService()
 
-	token, err := svc.GenerateAccessToken(uuid.New(), uuid.New(), "user@example.com", "member", "organization")
+	token, err := svc.GenerateAccessToken(uuid.New(), uuid.New(), "user@example.com", "member", "organization", nil)
 	if err != nil {
 		t.Fatalf("GenerateAccessToken() error = %v", err)
 	}
@@ -213,7 +214,7 @@ func TestGenerateAccessToken_ClaimsExtraction(t *testing.T) {
 	userID := uuid.New()
 	groupID := uuid.New()
 
-	token, _ := svc.GenerateAccessToken(userID, groupID, "test@test.com", "owner", "organization")
+	token, _ := svc.GenerateAccessToken(userID, groupID, "test@test.com", "owner", "organization", nil)
 	claims, err := svc.ValidateAccessToken(token)
 	if err != nil {
 		t.Fatalf("ValidateAccessToken() error = %v", err)
@@ -237,3 +238,103 @@ func TestGenerateAccessToken_ClaimsExtraction(t *testing.T) {
 		t.Error("IssuedAt should not be in the future")
 	}
 }
+
+func TestGenerateAccessToken_GroupPath(t *testing.T) {
+	svc := newTestJWTService()
+	company, team := uuid.New(), uuid.New()
+
+	token, err := svc.GenerateAccessToken(uuid.New(), team, "test@test.com", "admin", "company", []uuid.UUID{company, team})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	claims, err := svc.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	path, err := groupPathFromClaims(claims, team)
+	if err != nil {
+		t.Fatalf("groupPathFromClaims() error = %v", err)
+	}
+	if len(path) != 2 || path[0] != company || path[1] != team {
+		t.Errorf("group path = %v, want [%s %s]", path, company, team)
+	}
+
+	// The path must end with the token's group.
+	if _, err := groupPathFromClaims(claims, company); err == nil {
+		t.Error("groupPathFromClaims() expected error for path not ending with group")
+	}
+}
+
+func TestGroupPathFromClaims_Missing(t *testing.T) {
+	groupID := uuid.New()
+	path, err := groupPathFromClaims(&AccessTokenClaims{GroupID: groupID.String()}, groupID)
+	if err != nil {
+		t.Fatalf("groupPathFromClaims() error = %v", err)
+	}
+	if len(path) != 1 || path[0] != groupID {
+		t.Errorf("group path = %v, want [%s]", path, groupID)
+	}
+}
+
+func TestGenerateScopedToken(t *testing.T) {
+	svc := newTestJWTService()
+	userID := uuid.New()
+	groupID := uuid.New()
+
+	token, expiresAt, err := svc.GenerateScopedToken(userID, groupID, "ci@example.com", "member", "company", nil, []string{ScopeSend}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+	if d := time.Until(expiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expiresAt in %v, want 1h", d)
+	}
+
+	claims, err := svc.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if !claims.HasScope(ScopeSend) || claims.HasScope(ScopeRead) {
+		t.Errorf("Scope = %v, want [send]", claims.Scope)
+	}
+	if claims.Subject != userID.String() || claims.GroupID != groupID.String() {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	for _, ttl := range []time.Duration{0, 25 * time.Hour} {
+		if _, _, err := svc.GenerateScopedToken(userID, groupID, "", "member", "company", nil, []string{ScopeRead}, ttl); err != ErrScopedTokenTTL {
+			t.Errorf("ttl %v: error = %v, want ErrScopedTokenTTL", ttl, err)
+		}
+	}
+}
+
+func TestAccessTokenClaims_HasScope_Unrestricted(t *testing.T) {
+	claims := &AccessTokenClaims{}
+	if !claims.HasScope(ScopeSend) || !claims.HasScope(ScopeRead) {
+		t.Error("token without scope should be unrestricted")
+	}
+}
+
+func TestActionToken(t *testing.T) {
+	svc := newTestJWTService()
+
+	token, err := svc.GenerateActionToken(PurposeUnlock, "user@example.com", "nonce-1", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateActionToken() error = %v", err)
+	}
+	claims, err := svc.ValidateActionToken(token, PurposeUnlock)
+	if err != nil {
+		t.Fatalf("ValidateActionToken() error = %v", err)
+	}
+	if claims.Subject != "user@example.com" || claims.ID != "nonce-1" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, err := svc.ValidateActionToken(token, "invite"); err != ErrTokenInvalid {
+		t.Errorf("other purpose: error = %v, want ErrTokenInvalid", err)
+	}
+	access, _ := svc.GenerateAccessToken(uuid.New(), uuid.New(), "user@example.com", "admin", "company", nil)
+	if _, err := svc.ValidateActionToken(access, PurposeUnlock); err != ErrTokenInvalid {
+		t.Errorf("access token: error = %v, want ErrTokenInvalid", err)
+	}
+}
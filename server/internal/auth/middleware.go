@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -15,7 +16,9 @@ type contextKey string
 const (
 	accountIDKey  contextKey = "account_id"
 	groupIDKey    contextKey = "group_id"
+	groupPathKey  contextKey = "group_path"
 	groupTypeKey  contextKey = "group_type"
+	tokenScopeKey contextKey = "token_scope"
 	userIDKey     contextKey = "user_id"
 	userEmailKey  contextKey = "user_email"
 	userRoleKey   contextKey = "user_role"
@@ -53,6 +56,36 @@ func GroupTypeFromContext(ctx context.Context) string {
 	return ""
 }
 
+// GroupPathFromContext retrieves the group path (top-level group first,
+// current group last) from the request context. Returns nil if no path is
+// set.
+func GroupPathFromContext(ctx context.Context) []uuid.UUID {
+	if path, ok := ctx.Value(groupPathKey).([]uuid.UUID); ok {
+		return path
+	}
+	return nil
+}
+
+// groupPathFromClaims parses the group_path claim. Tokens without the claim
+// get a path of just their group. The path must end with the token's group.
+func groupPathFromClaims(claims *AccessTokenClaims, groupID uuid.UUID) ([]uuid.UUID, error) {
+	if len(claims.GroupPath) == 0 {
+		return []uuid.UUID{groupID}, nil
+	}
+	path := make([]uuid.UUID, 0, len(claims.GroupPath))
+	for _, s := range claims.GroupPath {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, id)
+	}
+	if path[len(path)-1] != groupID {
+		return nil, ErrTokenInvalid
+	}
+	return path, nil
+}
+
 // UserFromContext retrieves the user ID from the request context.
 // Returns uuid.Nil if no user is set.
 func UserFromContext(ctx context.Context) uuid.UUID {
@@ -80,6 +113,15 @@ func RoleFromContext(ctx context.Context) string {
 	return ""
 }
 
+// TokenScopeFromContext retrieves the scopes of a scoped token from the
+// request context. Returns nil for unrestricted credentials.
+func TokenScopeFromContext(ctx context.Context) []string {
+	if scope, ok := ctx.Value(tokenScopeKey).([]string); ok {
+		return scope
+	}
+	return nil
+}
+
 // AccountLookupFunc is a function that looks up an account by API key.
 // It returns the account ID if found, or an error if not.
 type AccountLookupFunc func(ctx context.Context, apiKey string) (uuid.UUID, error)
@@ -92,25 +134,25 @@ func BearerAuth(lookup AccountLookupFunc) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, `{"error":"authorization header required"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "authorization header required", nil)
 				return
 			}
 
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-				http.Error(w, `{"error":"invalid authorization format, expected Bearer <token>"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid authorization format, expected Bearer <token>", nil)
 				return
 			}
 
 			apiKey := parts[1]
 			if apiKey == "" {
-				http.Error(w, `{"error":"empty API key"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "empty API key", nil)
 				return
 			}
 
 			accountID, err := lookup(r.Context(), apiKey)
 			if err != nil {
-				http.Error(w, `{"error":"invalid API key"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid API key", nil)
 				return
 			}
 
@@ -131,19 +173,19 @@ func UnifiedAuth(jwtService *JWTService, queries storage.Querier) func(http.Hand
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, `{"error":"authorization header required"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "authorization header required", nil)
 				return
 			}
 
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-				http.Error(w, `{"error":"invalid authorization format, expected Bearer <token>"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid authorization format, expected Bearer <token>", nil)
 				return
 			}
 
 			token := parts[1]
 			if token == "" {
-				http.Error(w, `{"error":"empty token"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "empty token", nil)
 				return
 			}
 
@@ -153,20 +195,27 @@ func UnifiedAuth(jwtService *JWTService, queries storage.Querier) func(http.Hand
 				if err == nil {
 					userID, err := uuid.Parse(claims.Subject)
 					if err != nil {
-						http.Error(w, `{"error":"invalid token claims"}`, http.StatusUnauthorized)
+						apierror.Write(w, http.StatusUnauthorized, "", "invalid token claims", nil)
 						return
 					}
 					groupID, err := uuid.Parse(claims.GroupID)
 					if err != nil {
-						http.Error(w, `{"error":"invalid token claims"}`, http.StatusUnauthorized)
+						apierror.Write(w, http.StatusUnauthorized, "", "invalid token claims", nil)
+						return
+					}
+					groupPath, err := groupPathFromClaims(claims, groupID)
+					if err != nil {
+						apierror.Write(w, http.StatusUnauthorized, "", "invalid token claims", nil)
 						return
 					}
 					ctx := r.Context()
 					ctx = context.WithValue(ctx, userIDKey, userID)
 					ctx = context.WithValue(ctx, groupIDKey, groupID)
+					ctx = context.WithValue(ctx, groupPathKey, groupPath)
 					ctx = context.WithValue(ctx, groupTypeKey, claims.GroupType)
 					ctx = context.WithValue(ctx, userEmailKey, claims.Email)
 					ctx = context.WithValue(ctx, userRoleKey, claims.Role)
+					ctx = context.WithValue(ctx, tokenScopeKey, claims.Scope)
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
@@ -176,19 +225,19 @@ func UnifiedAuth(jwtService *JWTService, queries storage.Querier) func(http.Hand
 			// Try API key lookup
 			user, err := queries.GetUserByAPIKey(r.Context(), sql.NullString{String: token, Valid: true})
 			if err != nil {
-				http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid credentials", nil)
 				return
 			}
 
 			if user.Status != "active" {
-				http.Error(w, `{"error":"account is not active"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "account is not active", nil)
 				return
 			}
 
 			// Resolve group membership
 			groups, err := queries.ListGroupsByUserID(r.Context(), user.ID)
 			if err != nil || len(groups) == 0 {
-				http.Error(w, `{"error":"no group membership found"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "no group membership found", nil)
 				return
 			}
 
@@ -201,13 +250,20 @@ func UnifiedAuth(jwtService *JWTService, queries storage.Querier) func(http.Hand
 				GroupID: group.ID,
 			})
 			if err != nil {
-				http.Error(w, `{"error":"failed to resolve group role"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "failed to resolve group role", nil)
+				return
+			}
+
+			groupPath, err := GroupPath(r.Context(), queries, group.ID)
+			if err != nil {
+				apierror.Write(w, http.StatusUnauthorized, "", "failed to resolve group hierarchy", nil)
 				return
 			}
 
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, userIDKey, user.ID)
 			ctx = context.WithValue(ctx, groupIDKey, group.ID)
+			ctx = context.WithValue(ctx, groupPathKey, groupPath)
 			ctx = context.WithValue(ctx, groupTypeKey, group.GroupType)
 			ctx = context.WithValue(ctx, userEmailKey, user.Email)
 			ctx = context.WithValue(ctx, userRoleKey, member.Role)
@@ -224,46 +280,54 @@ func JWTAuth(jwtService *JWTService) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, `{"error":"authorization header required"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "authorization header required", nil)
 				return
 			}
 
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-				http.Error(w, `{"error":"invalid authorization format, expected Bearer <token>"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid authorization format, expected Bearer <token>", nil)
 				return
 			}
 
 			tokenStr := parts[1]
 			if tokenStr == "" {
-				http.Error(w, `{"error":"empty token"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "empty token", nil)
 				return
 			}
 
 			claims, err := jwtService.ValidateAccessToken(tokenStr)
 			if err != nil {
-				http.Error(w, `{"error":"invalid or expired token"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid or expired token", nil)
 				return
 			}
 
 			userID, err := uuid.Parse(claims.Subject)
 			if err != nil {
-				http.Error(w, `{"error":"invalid token claims"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid token claims", nil)
 				return
 			}
 
 			groupID, err := uuid.Parse(claims.GroupID)
 			if err != nil {
-				http.Error(w, `{"error":"invalid token claims"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid token claims", nil)
+				return
+			}
+
+			groupPath, err := groupPathFromClaims(claims, groupID)
+			if err != nil {
+				apierror.Write(w, http.StatusUnauthorized, "", "invalid token claims", nil)
 				return
 			}
 
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, userIDKey, userID)
 			ctx = context.WithValue(ctx, groupIDKey, groupID)
+			ctx = context.WithValue(ctx, groupPathKey, groupPath)
 			ctx = context.WithValue(ctx, groupTypeKey, claims.GroupType)
 			ctx = context.WithValue(ctx, userEmailKey, claims.Email)
 			ctx = context.WithValue(ctx, userRoleKey, claims.Role)
+			ctx = context.WithValue(ctx, tokenScopeKey, claims.Scope)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
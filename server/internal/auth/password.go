@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PasswordPolicy lists the rules new passwords must satisfy. A nil policy
+// accepts any password.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Breach, when set, rejects passwords found in known data breaches.
+	Breach BreachChecker
+}
+
+// BreachChecker reports whether a password appears in known data breaches.
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// PasswordPolicyError lists the rules a password failed.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password " + strings.Join(e.Violations, ", ")
+}
+
+// Check returns a *PasswordPolicyError when password breaks the policy.
+// A failed breach lookup does not reject the password, so an unreachable
+// breach service cannot block password changes.
+func (p *PasswordPolicy) Check(ctx context.Context, password string) error {
+	if p == nil {
+		return nil
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var violations []string
+	if n := len([]rune(password)); n < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+	if len(violations) == 0 && p.Breach != nil {
+		if breached, err := p.Breach.Breached(ctx, password); err == nil && breached {
+			violations = append(violations, "appears in a known data breach")
+		}
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// DefaultHIBPURL is the Have I Been Pwned Pwned Passwords API.
+const DefaultHIBPURL = "https://api.pwnedpasswords.com"
+
+// HIBPChecker checks passwords against the Have I Been Pwned Pwned
+// Passwords range API using k-anonymity: only the first five hex
+// characters of the password's SHA-1 hash leave the process.
+type HIBPChecker struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHIBPChecker creates a HIBPChecker for the API at baseURL (DefaultHIBPURL
+// when empty). timeout bounds each lookup.
+func NewHIBPChecker(baseURL string, timeout time.Duration) *HIBPChecker {
+	if baseURL == "" {
+		baseURL = DefaultHIBPURL
+	}
+	return &HIBPChecker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Breached implements BreachChecker.
+func (c *HIBPChecker) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("build breach lookup: %w", err)
+	}
+	// Padding hides the real number of matches from observers.
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "smtp-proxy")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("breach lookup: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach lookup: unexpected status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		// Padding entries have a count of zero.
+		return strings.TrimSpace(count) != "0", nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read breach lookup: %w", err)
+	}
+	return false, nil
+}
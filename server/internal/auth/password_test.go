@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeBreachChecker struct {
+	breached bool
+	err      error
+}
+
+func (f fakeBreachChecker) Breached(ctx context.Context, password string) (bool, error) {
+	return f.breached, f.err
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+	tests := []struct {
+		password   string
+		violations int
+	}{
+		{"Correct-horse-9", 0},
+		{"Short-9a", 1},
+		{"alllowercase", 3},
+		{"NO-LOWER-CASE-1", 1},
+	}
+	for _, tt := range tests {
+		err := policy.Check(context.Background(), tt.password)
+		var perr *PasswordPolicyError
+		if tt.violations == 0 {
+			if err != nil {
+				t.Errorf("Check(%q) = %v, want nil", tt.password, err)
+			}
+			continue
+		}
+		if !errors.As(err, &perr) || len(perr.Violations) != tt.violations {
+			t.Errorf("Check(%q) = %v, want %d violations", tt.password, err, tt.violations)
+		}
+	}
+}
+
+func TestPasswordPolicy_Breach(t *testing.T) {
+	ctx := context.Background()
+	policy := &PasswordPolicy{Breach: fakeBreachChecker{breached: true}}
+	if err := policy.Check(ctx, "password"); err == nil {
+		t.Error("breached password accepted")
+	}
+
+	policy.Breach = fakeBreachChecker{err: errors.New("unreachable")}
+	if err := policy.Check(ctx, "password"); err != nil {
+		t.Errorf("failed lookup rejected the password: %v", err)
+	}
+
+	var nilPolicy *PasswordPolicy
+	if err := nilPolicy.Check(ctx, ""); err != nil {
+		t.Errorf("nil policy: %v", err)
+	}
+}
+
+func TestHIBPChecker(t *testing.T) {
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	var gotPath, gotPadding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotPadding = r.URL.Path, r.Header.Get("Add-Padding")
+		fmt.Fprint(w, "003D68EB55068C33ACE09247EE4C639306B:3\r\n")
+		fmt.Fprint(w, "1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n")
+		fmt.Fprint(w, "FFFF0000000000000000000000000000000:0\r\n")
+	}))
+	defer srv.Close()
+
+	c := NewHIBPChecker(srv.URL+"/", 5*time.Second)
+	breached, err := c.Breached(context.Background(), "password")
+	if err != nil || !breached {
+		t.Fatalf("Breached(password) = %v, %v; want true", breached, err)
+	}
+	if gotPath != "/range/5BAA6" || gotPadding != "true" {
+		t.Errorf("request path %q, Add-Padding %q", gotPath, gotPadding)
+	}
+
+	breached, err = c.Breached(context.Background(), "a much less common passphrase")
+	if err != nil || breached {
+		t.Errorf("Breached(uncommon) = %v, %v; want false", breached, err)
+	}
+}
+
+func TestHIBPChecker_Unavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewHIBPChecker(srv.URL, time.Second).Breached(context.Background(), "password")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("err = %v, want unexpected status 503", err)
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PBKDF2 hashes are stored as $pbkdf2-sha256$i=<iterations>$<salt>$<key>,
+// with unpadded standard base64 salt and key.
+const (
+	pbkdf2Prefix     = "$pbkdf2-sha256$"
+	pbkdf2Iterations = 600000
+	pbkdf2SaltSize   = 16
+	pbkdf2KeySize    = 32
+)
+
+var errPasswordMismatch = errors.New("password does not match")
+
+var pbkdf2Encoding = base64.RawStdEncoding
+
+func hashPBKDF2(password string) (string, error) {
+	salt := make([]byte, pbkdf2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pbkdf2Iterations, pbkdf2KeySize)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return fmt.Sprintf("%si=%d$%s$%s", pbkdf2Prefix, pbkdf2Iterations,
+		pbkdf2Encoding.EncodeToString(salt), pbkdf2Encoding.EncodeToString(key)), nil
+}
+
+func verifyPBKDF2(hash, password string) error {
+	parts := strings.Split(strings.TrimPrefix(hash, pbkdf2Prefix), "$")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "i=") {
+		return errors.New("malformed PBKDF2 hash")
+	}
+	iterations, err := strconv.Atoi(strings.TrimPrefix(parts[0], "i="))
+	if err != nil || iterations <= 0 {
+		return errors.New("malformed PBKDF2 hash")
+	}
+	salt, err := pbkdf2Encoding.DecodeString(parts[1])
+	if err != nil {
+		return errors.New("malformed PBKDF2 hash")
+	}
+	want, err := pbkdf2Encoding.DecodeString(parts[2])
+	if err != nil || len(want) == 0 {
+		return errors.New("malformed PBKDF2 hash")
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
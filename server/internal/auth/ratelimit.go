@@ -17,9 +17,18 @@ type RateLimitConfig struct {
 	LoginAttemptsLimit int `mapstructure:"login_attempts_limit"`
 	// LoginLockoutDuration is how long a user is locked out after exceeding attempts.
 	LoginLockoutDuration time.Duration `mapstructure:"login_lockout_duration"`
+	// LoginDelayAfter is the number of failed login attempts after which
+	// each further attempt must wait. Zero disables the delays.
+	LoginDelayAfter int `mapstructure:"login_delay_after"`
+	// LoginDelayBase is the first wait; it doubles with each further failure.
+	LoginDelayBase time.Duration `mapstructure:"login_delay_base"`
+	// LoginDelayMax caps the wait between attempts.
+	LoginDelayMax time.Duration `mapstructure:"login_delay_max"`
 }
 
 // RateLimiter provides per-tenant rate limiting using Redis sliding window.
+// Login counters live in Redis too, so every API server replica sees the
+// same failed attempts, delays and lockouts.
 type RateLimiter struct {
 	client *redis.Client
 	config RateLimitConfig
@@ -33,6 +42,12 @@ func NewRateLimiter(client *redis.Client, config RateLimitConfig) *RateLimiter {
 	}
 }
 
+// LoginLockoutDuration returns how long an account stays locked after
+// exceeding the failed login attempts.
+func (rl *RateLimiter) LoginLockoutDuration() time.Duration {
+	return rl.config.LoginLockoutDuration
+}
+
 // CheckSMTPRateLimit checks whether the given tenant has exceeded their monthly send limit.
 // Returns nil if allowed, or an error if the rate limit is exceeded.
 func (rl *RateLimiter) CheckSMTPRateLimit(ctx context.Context, tenantID uuid.UUID, monthlyLimit int) error {
@@ -81,8 +96,7 @@ func (rl *RateLimiter) CheckLoginRateLimit(ctx context.Context, email string) er
 		return nil
 	}
 
-	key := fmt.Sprintf("ratelimit:login:%s", email)
-	count, err := rl.client.Get(ctx, key).Int64()
+	count, err := rl.client.Get(ctx, loginKey(email)).Int64()
 	if err != nil && err != redis.Nil {
 		return fmt.Errorf("check login rate limit: %w", err)
 	}
@@ -94,33 +108,147 @@ func (rl *RateLimiter) CheckLoginRateLimit(ctx context.Context, email string) er
 	return nil
 }
 
-// RecordFailedLogin increments the failed login counter for the given email.
+// RecordFailedLogin increments the failed login counter for the given email
+// and, past LoginDelayAfter failures, makes the next attempt wait.
 func (rl *RateLimiter) RecordFailedLogin(ctx context.Context, email string) error {
 	if rl.client == nil {
 		return nil
 	}
 
-	key := fmt.Sprintf("ratelimit:login:%s", email)
+	key := loginKey(email)
 
 	pipe := rl.client.Pipeline()
-	pipe.Incr(ctx, key)
+	count := pipe.Incr(ctx, key)
 	pipe.Expire(ctx, key, rl.config.LoginLockoutDuration)
 	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("record failed login: %w", err)
 	}
 
+	if wait := rl.loginDelay(int(count.Val())); wait > 0 {
+		if err := rl.client.Set(ctx, loginWaitKey(email), 1, wait).Err(); err != nil {
+			return fmt.Errorf("record failed login: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// ClearFailedLogins resets the failed login counter for the given email.
+// LoginDelay returns how long the client must wait before the next login
+// attempt for the given email, or zero.
+func (rl *RateLimiter) LoginDelay(ctx context.Context, email string) (time.Duration, error) {
+	if rl.client == nil {
+		return 0, nil
+	}
+
+	ttl, err := rl.client.PTTL(ctx, loginWaitKey(email)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("check login delay: %w", err)
+	}
+	if ttl < 0 {
+		// The key does not exist.
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// loginDelay returns the wait after count consecutive failed attempts:
+// LoginDelayBase after LoginDelayAfter failures, doubling with each
+// further failure up to LoginDelayMax.
+func (rl *RateLimiter) loginDelay(count int) time.Duration {
+	after, delay, limit := rl.config.LoginDelayAfter, rl.config.LoginDelayBase, rl.config.LoginDelayMax
+	if after <= 0 || delay <= 0 || count < after {
+		return 0
+	}
+	for i := after; i < count && (limit <= 0 || delay < limit); i++ {
+		delay *= 2
+	}
+	if limit > 0 && delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// ClearFailedLogins resets the failed login counter, delay and unlock link
+// for the given email.
 func (rl *RateLimiter) ClearFailedLogins(ctx context.Context, email string) error {
 	if rl.client == nil {
 		return nil
 	}
 
-	key := fmt.Sprintf("ratelimit:login:%s", email)
-	return rl.client.Del(ctx, key).Err()
+	return rl.client.Del(ctx, loginKey(email), loginWaitKey(email), loginUnlockKey(email)).Err()
+}
+
+// IssueUnlockNonce returns the nonce of a new self-service unlock link for
+// the locked account email. issued is false when a link was already issued
+// during the current lockout, so each lockout sends at most one email.
+func (rl *RateLimiter) IssueUnlockNonce(ctx context.Context, email string) (nonce string, issued bool, err error) {
+	if rl.client == nil {
+		return "", false, nil
+	}
+
+	nonce = uuid.NewString()
+	issued, err = rl.client.SetNX(ctx, loginUnlockKey(email), nonce, rl.config.LoginLockoutDuration).Result()
+	if err != nil {
+		return "", false, fmt.Errorf("issue unlock nonce: %w", err)
+	}
+	return nonce, issued, nil
+}
+
+// UnlockPending reports whether nonce is the unlock link of a lockout of
+// email that has not ended or been lifted yet. Unlike Unlock it changes
+// nothing, so the link can be shown before it is used.
+func (rl *RateLimiter) UnlockPending(ctx context.Context, email, nonce string) (bool, error) {
+	if rl.client == nil {
+		return false, nil
+	}
+
+	current, err := rl.client.Get(ctx, loginUnlockKey(email)).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get unlock nonce: %w", err)
+	}
+	return current == nonce, nil
+}
+
+// unlockScript clears the login counters of KEYS[2..] when KEYS[1] holds the
+// nonce ARGV[1], so a stale or guessed link cannot consume a valid one.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1], KEYS[2], KEYS[3])
+end
+return 0
+`)
+
+// Unlock lifts the lockout of email when nonce matches its unlock link.
+// It reports whether the account was unlocked; each link works once.
+func (rl *RateLimiter) Unlock(ctx context.Context, email, nonce string) (bool, error) {
+	if rl.client == nil {
+		return false, nil
+	}
+
+	n, err := unlockScript.Run(ctx, rl.client, []string{loginUnlockKey(email), loginKey(email), loginWaitKey(email)}, nonce).Int()
+	if err != nil {
+		return false, fmt.Errorf("unlock login: %w", err)
+	}
+	return n > 0, nil
+}
+
+// loginKey is the failed login counter of an email address.
+func loginKey(email string) string {
+	return "ratelimit:login:" + email
+}
+
+// loginWaitKey exists while the next login attempt must wait.
+func loginWaitKey(email string) string {
+	return "ratelimit:login-wait:" + email
+}
+
+// loginUnlockKey holds the nonce of the unlock link sent for a lockout.
+func loginUnlockKey(email string) string {
+	return "ratelimit:login-unlock:" + email
 }
 
 // currentMonth returns the current year-month string (e.g., "2026-02").
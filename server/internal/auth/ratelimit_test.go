@@ -51,4 +51,45 @@ func TestNewRateLimiter_NilClient(t *testing.T) {
 	if err := rl.ClearFailedLogins(ctx, "test@example.com"); err != nil {
 		t.Errorf("ClearFailedLogins() with nil client error = %v", err)
 	}
+	if d, err := rl.LoginDelay(ctx, "test@example.com"); err != nil || d != 0 {
+		t.Errorf("LoginDelay() with nil client = %v, %v", d, err)
+	}
+	if _, issued, err := rl.IssueUnlockNonce(ctx, "test@example.com"); err != nil || issued {
+		t.Errorf("IssueUnlockNonce() with nil client = %v, %v", issued, err)
+	}
+	if ok, err := rl.UnlockPending(ctx, "test@example.com", "nonce"); err != nil || ok {
+		t.Errorf("UnlockPending() with nil client = %v, %v", ok, err)
+	}
+	if ok, err := rl.Unlock(ctx, "test@example.com", "nonce"); err != nil || ok {
+		t.Errorf("Unlock() with nil client = %v, %v", ok, err)
+	}
+}
+
+func TestRateLimiter_LoginDelay(t *testing.T) {
+	rl := NewRateLimiter(nil, RateLimitConfig{
+		LoginDelayAfter: 3,
+		LoginDelayBase:  time.Second,
+		LoginDelayMax:   5 * time.Second,
+	})
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 5 * time.Second},
+		{40, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := rl.loginDelay(tt.failures); got != tt.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+
+	if got := NewRateLimiter(nil, RateLimitConfig{LoginDelayBase: time.Second}).loginDelay(10); got != 0 {
+		t.Errorf("loginDelay without LoginDelayAfter = %v, want 0", got)
+	}
 }
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"net/http"
+
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
 )
 
 // RequireRole returns an HTTP middleware that checks the user's role from context
@@ -17,12 +19,12 @@ func RequireRole(roles ...string) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			role := RoleFromContext(r.Context())
 			if role == "" {
-				http.Error(w, `{"error":"authentication required"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "authentication required", nil)
 				return
 			}
 
 			if _, ok := allowed[role]; !ok {
-				http.Error(w, `{"error":"insufficient permissions"}`, http.StatusForbidden)
+				apierror.Write(w, http.StatusForbidden, "", "insufficient permissions", nil)
 				return
 			}
 
@@ -40,12 +42,12 @@ func RequireSystemAdmin() func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			groupType := GroupTypeFromContext(r.Context())
 			if groupType == "" {
-				http.Error(w, `{"error":"authentication required"}`, http.StatusUnauthorized)
+				apierror.Write(w, http.StatusUnauthorized, "", "authentication required", nil)
 				return
 			}
 
 			if groupType != "system" {
-				http.Error(w, `{"error":"system admin access required"}`, http.StatusForbidden)
+				apierror.Write(w, http.StatusForbidden, "", "system admin access required", nil)
 				return
 			}
 
@@ -53,3 +55,27 @@ func RequireSystemAdmin() func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// RestrictScopedTokens returns an HTTP middleware that limits scoped tokens
+// to the requests their scopes allow: GET and HEAD with ScopeRead. Every
+// other request made with a scoped token, including minting further
+// tokens, gets 403 Forbidden. Unrestricted credentials pass through.
+// Must be used after JWTAuth or UnifiedAuth middleware.
+func RestrictScopedTokens() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := TokenScopeFromContext(r.Context())
+			if len(scope) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if (r.Method == http.MethodGet || r.Method == http.MethodHead) && hasScope(scope, ScopeRead) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			apierror.Write(w, http.StatusForbidden, "", "token scope does not permit this request", nil)
+		})
+	}
+}
@@ -163,3 +163,37 @@ func TestRequireSystemAdmin_NoGroupType(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
 	}
 }
+
+func TestRestrictScopedTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		scope      []string
+		wantStatus int
+	}{
+		{"unrestricted write", http.MethodPost, nil, http.StatusOK},
+		{"read scope GET", http.MethodGet, []string{ScopeRead}, http.StatusOK},
+		{"read scope POST", http.MethodPost, []string{ScopeRead}, http.StatusForbidden},
+		{"send scope GET", http.MethodGet, []string{ScopeSend}, http.StatusForbidden},
+		{"send scope POST", http.MethodPost, []string{ScopeSend}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RestrictScopedTokens()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			ctx := context.WithValue(req.Context(), tokenScopeKey, tt.scope)
+			req = req.WithContext(ctx)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
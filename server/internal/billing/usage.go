@@ -0,0 +1,156 @@
+// Package billing aggregates per-group monthly message usage (count and
+// bytes, with a per-provider breakdown) for export to billing systems.
+package billing
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// MonthLayout is the format of a billing month, e.g. "2026-09".
+const MonthLayout = "2006-01"
+
+// Querier is the subset of storage.Querier used to build a usage report.
+type Querier interface {
+	GetGroupByID(ctx context.Context, id uuid.UUID) (storage.Group, error)
+	MonthlyMessageUsage(ctx context.Context, arg storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error)
+	MonthlyProviderUsage(ctx context.Context, arg storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error)
+}
+
+// ProviderUsage is the messages a group delivered through one provider.
+type ProviderUsage struct {
+	Provider string `json:"provider"`
+	Messages int64  `json:"messages"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// GroupUsage is a group's usage for the month. Messages and Bytes count
+// messages accepted during the month; Providers counts messages delivered
+// during the month, so the two can differ around month boundaries and for
+// messages that failed or are still queued.
+type GroupUsage struct {
+	GroupID   uuid.UUID       `json:"group_id"`
+	GroupName string          `json:"group_name"`
+	Messages  int64           `json:"messages"`
+	Bytes     int64           `json:"bytes"`
+	Providers []ProviderUsage `json:"providers"`
+}
+
+// Report is the usage of every group for one calendar month (UTC).
+type Report struct {
+	Month  string       `json:"month"`
+	Groups []GroupUsage `json:"groups"`
+}
+
+// Load builds the usage report for the month containing month. If groupID
+// is not uuid.Nil only that group is included.
+func Load(ctx context.Context, q Querier, month time.Time, groupID uuid.UUID) (*Report, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	accepted, err := q.MonthlyMessageUsage(ctx, storage.MonthlyMessageUsageParams{
+		EnqueuedAt:   pgtype.Timestamptz{Time: start, Valid: true},
+		EnqueuedAt_2: pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("billing: load message usage: %w", err)
+	}
+	delivered, err := q.MonthlyProviderUsage(ctx, storage.MonthlyProviderUsageParams{
+		CreatedAt:   pgtype.Timestamptz{Time: start, Valid: true},
+		CreatedAt_2: pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("billing: load provider usage: %w", err)
+	}
+
+	groups := make(map[uuid.UUID]*GroupUsage)
+	for _, row := range accepted {
+		id := uuid.UUID(row.GroupID.Bytes)
+		if groupID != uuid.Nil && id != groupID {
+			continue
+		}
+		groups[id] = &GroupUsage{
+			GroupID:   id,
+			GroupName: row.GroupName,
+			Messages:  row.Messages,
+			Bytes:     row.TotalBytes,
+			Providers: []ProviderUsage{},
+		}
+	}
+	for _, row := range delivered {
+		id := uuid.UUID(row.GroupID.Bytes)
+		if groupID != uuid.Nil && id != groupID {
+			continue
+		}
+		g, ok := groups[id]
+		if !ok {
+			// Delivered this month but accepted in an earlier one.
+			g = &GroupUsage{GroupID: id, Providers: []ProviderUsage{}}
+			if grp, err := q.GetGroupByID(ctx, id); err == nil {
+				g.GroupName = grp.Name
+			}
+			groups[id] = g
+		}
+		g.Providers = append(g.Providers, ProviderUsage{
+			Provider: row.Provider.String,
+			Messages: row.Messages,
+			Bytes:    row.TotalBytes,
+		})
+	}
+
+	report := &Report{Month: start.Format(MonthLayout), Groups: make([]GroupUsage, 0, len(groups))}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, *g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.GroupName != b.GroupName {
+			return a.GroupName < b.GroupName
+		}
+		return a.GroupID.String() < b.GroupID.String()
+	})
+	return report, nil
+}
+
+// WriteCSV writes the report as CSV with the columns month, group_id,
+// group_name, provider, messages and bytes. Each group has a row with an
+// empty provider holding its accepted totals, followed by one row per
+// provider it delivered through.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"month", "group_id", "group_name", "provider", "messages", "bytes"}); err != nil {
+		return err
+	}
+	for _, g := range r.Groups {
+		rows := make([][]string, 0, len(g.Providers)+1)
+		rows = append(rows, r.csvRow(g, "", g.Messages, g.Bytes))
+		for _, p := range g.Providers {
+			rows = append(rows, r.csvRow(g, p.Provider, p.Messages, p.Bytes))
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Report) csvRow(g GroupUsage, provider string, messages, bytes int64) []string {
+	return []string{
+		r.Month,
+		g.GroupID.String(),
+		g.GroupName,
+		provider,
+		strconv.FormatInt(messages, 10),
+		strconv.FormatInt(bytes, 10),
+	}
+}
@@ -0,0 +1,109 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+type fakeQuerier struct {
+	accepted  []storage.MonthlyMessageUsageRow
+	delivered []storage.MonthlyProviderUsageRow
+	groups    map[uuid.UUID]storage.Group
+
+	gotFrom, gotTo time.Time
+}
+
+func (f *fakeQuerier) GetGroupByID(_ context.Context, id uuid.UUID) (storage.Group, error) {
+	if g, ok := f.groups[id]; ok {
+		return g, nil
+	}
+	return storage.Group{}, sql.ErrNoRows
+}
+
+func (f *fakeQuerier) MonthlyMessageUsage(_ context.Context, arg storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error) {
+	f.gotFrom, f.gotTo = arg.EnqueuedAt.Time, arg.EnqueuedAt_2.Time
+	return f.accepted, nil
+}
+
+func (f *fakeQuerier) MonthlyProviderUsage(_ context.Context, _ storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error) {
+	return f.delivered, nil
+}
+
+func pgUUID(id uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+func TestLoad(t *testing.T) {
+	acme := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	beta := uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	q := &fakeQuerier{
+		accepted: []storage.MonthlyMessageUsageRow{
+			{GroupID: pgUUID(acme), GroupName: "acme", Messages: 3, TotalBytes: 3000},
+		},
+		delivered: []storage.MonthlyProviderUsageRow{
+			{GroupID: pgUUID(acme), Provider: sql.NullString{String: "sendgrid", Valid: true}, Messages: 2, TotalBytes: 2000},
+			{GroupID: pgUUID(acme), Provider: sql.NullString{String: "ses", Valid: true}, Messages: 1, TotalBytes: 1000},
+			{GroupID: pgUUID(beta), Provider: sql.NullString{String: "ses", Valid: true}, Messages: 1, TotalBytes: 500},
+		},
+		groups: map[uuid.UUID]storage.Group{beta: {ID: beta, Name: "beta"}},
+	}
+
+	report, err := Load(context.Background(), q, time.Date(2026, 9, 17, 8, 0, 0, 0, time.UTC), uuid.Nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if !q.gotFrom.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) || !q.gotTo.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("queried [%v, %v), want September 2026", q.gotFrom, q.gotTo)
+	}
+	if report.Month != "2026-09" || len(report.Groups) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	a := report.Groups[0]
+	if a.GroupName != "acme" || a.Messages != 3 || a.Bytes != 3000 || len(a.Providers) != 2 {
+		t.Errorf("unexpected acme usage: %+v", a)
+	}
+	b := report.Groups[1]
+	if b.GroupName != "beta" || b.Messages != 0 || len(b.Providers) != 1 || b.Providers[0].Bytes != 500 {
+		t.Errorf("unexpected beta usage: %+v", b)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "month,group_id,group_name,provider,messages,bytes\n" +
+		"2026-09," + acme.String() + ",acme,,3,3000\n" +
+		"2026-09," + acme.String() + ",acme,sendgrid,2,2000\n" +
+		"2026-09," + acme.String() + ",acme,ses,1,1000\n" +
+		"2026-09," + beta.String() + ",beta,,0,0\n" +
+		"2026-09," + beta.String() + ",beta,ses,1,500\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestLoad_SingleGroup(t *testing.T) {
+	acme := uuid.New()
+	q := &fakeQuerier{
+		accepted: []storage.MonthlyMessageUsageRow{
+			{GroupID: pgUUID(acme), GroupName: "acme", Messages: 1, TotalBytes: 10},
+			{GroupID: pgUUID(uuid.New()), GroupName: "other", Messages: 5, TotalBytes: 50},
+		},
+	}
+
+	report, err := Load(context.Background(), q, time.Now(), acme)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(report.Groups) != 1 || report.Groups[0].GroupID != acme {
+		t.Errorf("expected only acme, got %+v", report.Groups)
+	}
+}
@@ -0,0 +1,299 @@
+// Package certmon tracks the expiry of the TLS certificates the proxy
+// serves and depends on: the SMTP and API server certificates, extra PEM
+// files and the certificates presented by SMTP relays. Days remaining are
+// exported as the cert_expiry_days gauge, listed by Monitor.Status and
+// alerted on through notify as expiry approaches.
+package certmon
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+)
+
+// Config controls the certificate monitor.
+type Config struct {
+	// Interval is the delay between checks.
+	Interval time.Duration
+	// WarnDays are the days-remaining thresholds at which an alert is
+	// sent, e.g. 30, 14, 7 and 1. Each is alerted at most once per
+	// certificate.
+	WarnDays []int
+	// CriticalDays marks alerts at or below this many days as critical.
+	CriticalDays int
+}
+
+// DefaultConfig returns sensible defaults for the monitor.
+func DefaultConfig() Config {
+	return Config{
+		Interval:     6 * time.Hour,
+		WarnDays:     []int{30, 14, 7, 1},
+		CriticalDays: 7,
+	}
+}
+
+// Target is a certificate to watch.
+type Target struct {
+	// Source groups targets by kind: smtp, api, file or relay.
+	Source string
+	// Name identifies the target within its source, e.g. a path or a
+	// relay address.
+	Name string
+	// Load returns the target's current leaf certificate.
+	Load func(ctx context.Context) (*x509.Certificate, error)
+}
+
+// Status is the last check result of one target.
+type Status struct {
+	Source        string    `json:"source"`
+	Name          string    `json:"name"`
+	Subject       string    `json:"subject,omitempty"`
+	Issuer        string    `json:"issuer,omitempty"`
+	NotAfter      time.Time `json:"not_after,omitzero"`
+	DaysRemaining int       `json:"days_remaining"`
+	Error         string    `json:"error,omitempty"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// Monitor periodically loads every target's certificate, records its days
+// remaining and sends a cert_expiring alert each time a certificate
+// crosses one of the WarnDays thresholds. A renewed certificate starts
+// over with a fresh set of thresholds.
+type Monitor struct {
+	targets  []Target
+	notifier notify.Notifier
+	config   Config
+	log      zerolog.Logger
+	now      func() time.Time
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	status  map[string]Status
+	alerted map[string]alertState // target key -> thresholds alerted for a certificate
+}
+
+type alertState struct {
+	notAfter time.Time
+	lowest   int // lowest threshold alerted, or 0
+}
+
+// NewMonitor creates a Monitor for targets. notifier may be nil to only
+// export metrics and status. Zero-valued config fields fall back to
+// DefaultConfig.
+func NewMonitor(targets []Target, notifier notify.Notifier, cfg Config, log zerolog.Logger) *Monitor {
+	defaults := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if len(cfg.WarnDays) == 0 {
+		cfg.WarnDays = defaults.WarnDays
+	}
+	if cfg.CriticalDays <= 0 {
+		cfg.CriticalDays = defaults.CriticalDays
+	}
+	warn := append([]int(nil), cfg.WarnDays...)
+	sort.Sort(sort.Reverse(sort.IntSlice(warn)))
+	cfg.WarnDays = warn
+
+	return &Monitor{
+		targets:  targets,
+		notifier: notifier,
+		config:   cfg,
+		log:      log,
+		now:      time.Now,
+		status:   make(map[string]Status),
+		alerted:  make(map[string]alertState),
+	}
+}
+
+// Start launches the check loop in a background goroutine. The first check
+// runs immediately.
+func (m *Monitor) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	m.wg.Add(1)
+	go m.run(ctx)
+
+	m.log.Info().
+		Int("targets", len(m.targets)).
+		Dur("interval", m.config.Interval).
+		Ints("warn_days", m.config.WarnDays).
+		Msg("certificate monitor started")
+}
+
+// Stop signals the check loop to exit and waits for the current check.
+func (m *Monitor) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce checks every target once.
+func (m *Monitor) CheckOnce(ctx context.Context) {
+	for _, t := range m.targets {
+		if ctx.Err() != nil {
+			return
+		}
+		m.check(ctx, t)
+	}
+}
+
+// Status returns the last result of every target, ordered by source and
+// name.
+func (m *Monitor) Status() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Status, 0, len(m.status))
+	for _, s := range m.status {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Source != out[j].Source {
+			return out[i].Source < out[j].Source
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func (m *Monitor) check(ctx context.Context, t Target) {
+	key := t.Source + "/" + t.Name
+	now := m.now()
+	st := Status{Source: t.Source, Name: t.Name, CheckedAt: now}
+
+	cert, err := t.Load(ctx)
+	if err != nil {
+		metrics.CertCheckFailuresTotal.WithLabelValues(t.Source).Inc()
+		m.log.Warn().Err(err).Str("source", t.Source).Str("name", t.Name).Msg("failed to check certificate expiry")
+		st.Error = err.Error()
+		m.mu.Lock()
+		// Keep the last known expiry so the status still shows it.
+		if prev, ok := m.status[key]; ok {
+			st.Subject, st.Issuer, st.NotAfter, st.DaysRemaining = prev.Subject, prev.Issuer, prev.NotAfter, prev.DaysRemaining
+		}
+		m.status[key] = st
+		m.mu.Unlock()
+		return
+	}
+
+	days := daysRemaining(cert.NotAfter, now)
+	st.Subject = cert.Subject.String()
+	st.Issuer = cert.Issuer.String()
+	st.NotAfter = cert.NotAfter
+	st.DaysRemaining = days
+	metrics.CertExpiryDays.WithLabelValues(t.Source, t.Name).Set(cert.NotAfter.Sub(now).Hours() / 24)
+
+	threshold, due := m.due(key, cert.NotAfter, days)
+	m.mu.Lock()
+	m.status[key] = st
+	m.mu.Unlock()
+
+	if due {
+		m.alert(ctx, key, st, threshold)
+	}
+}
+
+// due reports the lowest WarnDays threshold days has reached and whether it
+// has not been alerted for this certificate yet, recording it as alerted.
+func (m *Monitor) due(key string, notAfter time.Time, days int) (int, bool) {
+	threshold := 0
+	for _, w := range m.config.WarnDays {
+		if days <= w {
+			threshold = w
+		}
+	}
+	if threshold == 0 {
+		return 0, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prev, ok := m.alerted[key]
+	if ok && prev.notAfter.Equal(notAfter) && prev.lowest <= threshold {
+		return 0, false
+	}
+	m.alerted[key] = alertState{notAfter: notAfter, lowest: threshold}
+	return threshold, true
+}
+
+func (m *Monitor) alert(ctx context.Context, key string, st Status, threshold int) {
+	m.log.Warn().
+		Str("source", st.Source).
+		Str("name", st.Name).
+		Time("not_after", st.NotAfter).
+		Int("days_remaining", st.DaysRemaining).
+		Msg("TLS certificate expiring")
+
+	if m.notifier == nil {
+		return
+	}
+
+	severity := notify.SeverityWarning
+	if st.DaysRemaining <= m.config.CriticalDays {
+		severity = notify.SeverityCritical
+	}
+	summary := fmt.Sprintf("%s certificate %s (%s) expires in %d days on %s",
+		st.Source, st.Name, st.Subject, st.DaysRemaining, st.NotAfter.UTC().Format(time.DateOnly))
+	if st.DaysRemaining < 0 {
+		summary = fmt.Sprintf("%s certificate %s (%s) expired on %s",
+			st.Source, st.Name, st.Subject, st.NotAfter.UTC().Format(time.DateOnly))
+	}
+
+	err := m.notifier.Notify(ctx, notify.Alert{
+		Kind:     notify.KindCertExpiring,
+		Severity: severity,
+		Summary:  summary,
+		Labels: map[string]string{
+			"source":  st.Source,
+			"name":    st.Name,
+			"subject": st.Subject,
+		},
+		Values: map[string]float64{
+			"days_remaining": float64(st.DaysRemaining),
+			"threshold_days": float64(threshold),
+		},
+		FiredAt: st.CheckedAt,
+	})
+	if err != nil {
+		m.log.Error().Err(err).Str("target", key).Msg("failed to send certificate expiry alert")
+
+		// Allow the next check to retry the notification.
+		m.mu.Lock()
+		delete(m.alerted, key)
+		m.mu.Unlock()
+	}
+}
+
+// daysRemaining returns the whole days from now until notAfter, rounded
+// down, so a certificate expiring later today has 0 days left.
+func daysRemaining(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
@@ -0,0 +1,219 @@
+package certmon
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newCert creates a self-signed certificate expiring at notAfter.
+func newCert(t *testing.T, cn string, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     []string{cn},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+type mockNotifier struct {
+	err    error
+	alerts []notify.Alert
+}
+
+func (m *mockNotifier) Notify(_ context.Context, alert notify.Alert) error {
+	m.alerts = append(m.alerts, alert)
+	return m.err
+}
+
+func newTestMonitor(targets []Target, n notify.Notifier) *Monitor {
+	m := NewMonitor(targets, n, Config{WarnDays: []int{7, 30}, CriticalDays: 7}, zerolog.Nop())
+	m.now = func() time.Time { return testNow }
+	return m
+}
+
+func TestMonitor_AlertsOncePerThreshold(t *testing.T) {
+	cert := newCert(t, "mail.example.com", testNow.Add(20*24*time.Hour+time.Hour))
+	n := &mockNotifier{}
+	m := newTestMonitor([]Target{CertificateTarget("smtp", "server", cert.Leaf)}, n)
+
+	m.CheckOnce(context.Background())
+	m.CheckOnce(context.Background())
+	if len(n.alerts) != 1 {
+		t.Fatalf("alerts = %d, want 1", len(n.alerts))
+	}
+	a := n.alerts[0]
+	if a.Kind != notify.KindCertExpiring || a.Severity != notify.SeverityWarning || a.Values["days_remaining"] != 20 || a.Values["threshold_days"] != 30 {
+		t.Errorf("alert = %+v", a)
+	}
+
+	// Crossing the next threshold alerts again, as critical.
+	m.now = func() time.Time { return testNow.Add(14 * 24 * time.Hour) }
+	m.CheckOnce(context.Background())
+	if len(n.alerts) != 2 || n.alerts[1].Severity != notify.SeverityCritical {
+		t.Fatalf("alerts = %+v, want a critical second alert", n.alerts)
+	}
+
+	status := m.Status()
+	if len(status) != 1 || status[0].DaysRemaining != 6 || !strings.Contains(status[0].Subject, "mail.example.com") {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestMonitor_RenewedCertificateStartsOver(t *testing.T) {
+	cert := newCert(t, "a", testNow.Add(5*24*time.Hour))
+	n := &mockNotifier{}
+	target := Target{Source: "file", Name: "a.pem", Load: func(context.Context) (*x509.Certificate, error) { return cert.Leaf, nil }}
+	m := newTestMonitor([]Target{target}, n)
+
+	m.CheckOnce(context.Background())
+	cert = newCert(t, "a", testNow.Add(25*24*time.Hour))
+	m.CheckOnce(context.Background())
+	if len(n.alerts) != 2 {
+		t.Errorf("alerts = %d, want the renewed certificate alerted at its own threshold", len(n.alerts))
+	}
+
+	cert = newCert(t, "a", testNow.Add(90*24*time.Hour))
+	m.CheckOnce(context.Background())
+	if len(n.alerts) != 2 {
+		t.Errorf("alerts = %d, want no alert far from expiry", len(n.alerts))
+	}
+}
+
+func TestMonitor_RetriesFailedNotification(t *testing.T) {
+	cert := newCert(t, "a", testNow.Add(3*24*time.Hour))
+	n := &mockNotifier{err: errors.New("pagerduty down")}
+	m := newTestMonitor([]Target{CertificateTarget("api", "a", cert.Leaf)}, n)
+
+	m.CheckOnce(context.Background())
+	m.CheckOnce(context.Background())
+	if len(n.alerts) != 2 {
+		t.Errorf("alerts = %d, want a retry after the failed notification", len(n.alerts))
+	}
+}
+
+func TestMonitor_LoadErrorKeepsLastExpiry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cert.pem")
+	cert := newCert(t, "a", testNow.Add(100*24*time.Hour))
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestMonitor([]Target{FileTarget("file", path)}, nil)
+
+	m.CheckOnce(context.Background())
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	m.CheckOnce(context.Background())
+
+	status := m.Status()
+	if len(status) != 1 || status[0].Error == "" || status[0].DaysRemaining != 100 {
+		t.Errorf("status = %+v, want the error and the last known expiry", status)
+	}
+}
+
+// serveStartTLS runs a minimal SMTP server offering STARTTLS with cert.
+func serveStartTLS(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte("220 relay.test ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				_, _ = conn.Write([]byte("250-relay.test\r\n250 STARTTLS\r\n"))
+			case cmd == "STARTTLS":
+				_, _ = conn.Write([]byte("220 ready\r\n"))
+				tc := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+				if err := tc.Handshake(); err != nil {
+					return
+				}
+				tr := bufio.NewReader(tc)
+				if _, err := tr.ReadString('\n'); err != nil {
+					return
+				}
+				_, _ = tc.Write([]byte("250 relay.test\r\n"))
+				_, _ = tr.ReadString('\n')
+				_, _ = tc.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				_, _ = conn.Write([]byte("502 unsupported\r\n"))
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRelayTarget_STARTTLS(t *testing.T) {
+	cert := newCert(t, "relay.test", time.Now().Add(10*24*time.Hour))
+	addr := serveStartTLS(t, cert)
+
+	got, err := RelayTarget(addr, 5*time.Second).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.Subject.CommonName != "relay.test" || !got.NotAfter.Equal(cert.Leaf.NotAfter) {
+		t.Errorf("certificate = %s, expires %s", got.Subject, got.NotAfter)
+	}
+}
+
+func TestRelayTarget_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	if _, err := RelayTarget(addr, time.Second).Load(context.Background()); err == nil {
+		t.Fatal("expected an error for a closed port")
+	}
+}
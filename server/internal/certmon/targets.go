@@ -0,0 +1,114 @@
+package certmon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"time"
+
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+)
+
+// FileTarget watches the first certificate in a PEM file. The file is read
+// on every check, so renewals on disk are picked up.
+func FileTarget(source, path string) Target {
+	return Target{
+		Source: source,
+		Name:   path,
+		Load: func(context.Context) (*x509.Certificate, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return tlsutil.ParseCertificatePEM(data)
+		},
+	}
+}
+
+// CertificateTarget watches a certificate held in memory, such as the
+// SMTP server's auto-generated self-signed certificate.
+func CertificateTarget(source, name string, cert *x509.Certificate) Target {
+	return Target{
+		Source: source,
+		Name:   name,
+		Load: func(context.Context) (*x509.Certificate, error) {
+			return cert, nil
+		},
+	}
+}
+
+// RelayTarget watches the certificate an SMTP relay at addr (host:port)
+// presents. Port 465 is dialed with implicit TLS; any other port with
+// STARTTLS. The certificate is read whether or not it verifies, since an
+// expired or untrusted certificate is what the check is for.
+func RelayTarget(addr string, timeout time.Duration) Target {
+	return Target{
+		Source: "relay",
+		Name:   addr,
+		Load: func(ctx context.Context) (*x509.Certificate, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return relayCertificate(ctx, addr)
+		},
+	}
+}
+
+func relayCertificate(ctx context.Context, addr string) (*x509.Certificate, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay address %q: %w", addr, err)
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, //nolint:gosec // The certificate is inspected, not trusted.
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if port == "465" {
+		tc := tls.Client(conn, tlsConfig)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("tls handshake: %w", err)
+		}
+		return leaf(tc.ConnectionState())
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return nil, fmt.Errorf("smtp greeting: %w", err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return nil, errors.New("relay does not offer STARTTLS")
+	}
+	if err := c.StartTLS(tlsConfig); err != nil {
+		return nil, fmt.Errorf("starttls: %w", err)
+	}
+	state, _ := c.TLSConnectionState()
+	cert, err := leaf(state)
+	if err != nil {
+		return nil, err
+	}
+	_ = c.Quit()
+	return cert, nil
+}
+
+func leaf(state tls.ConnectionState) (*x509.Certificate, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no certificate presented")
+	}
+	return state.PeerCertificates[0], nil
+}
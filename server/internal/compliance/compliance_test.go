@@ -0,0 +1,269 @@
+package compliance
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+type fakeQuerier struct {
+	group        storage.Group
+	members      []storage.GroupMember
+	users        map[uuid.UUID]storage.User
+	messages     []storage.ExportGroupMessagesRow
+	deliveryLogs []storage.DeliveryLog
+	activityLogs []storage.ActivityLog
+	storageRefs  []pgtype.Text
+	archives     []storage.DeliveryLogArchive
+	captures     int64
+
+	erased, scrubbed bool
+}
+
+func (f *fakeQuerier) DeleteDeliveryLogArchive(_ context.Context, id uuid.UUID) error {
+	for i, a := range f.archives {
+		if a.ID == id {
+			f.archives = append(f.archives[:i], f.archives[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeQuerier) DeleteGroupProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	n := f.captures
+	f.captures = 0
+	return n, nil
+}
+
+func (f *fakeQuerier) EraseGroupMessages(_ context.Context, _ pgtype.UUID) (int64, error) {
+	f.erased = true
+	return int64(len(f.messages)), nil
+}
+
+func (f *fakeQuerier) ExportGroupActivityLogs(_ context.Context, _ uuid.UUID) ([]storage.ActivityLog, error) {
+	return f.activityLogs, nil
+}
+
+func (f *fakeQuerier) ExportGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) ([]storage.DeliveryLog, error) {
+	return f.deliveryLogs, nil
+}
+
+func (f *fakeQuerier) ExportGroupMessages(_ context.Context, _ pgtype.UUID) ([]storage.ExportGroupMessagesRow, error) {
+	return f.messages, nil
+}
+
+func (f *fakeQuerier) GetGroupByID(_ context.Context, id uuid.UUID) (storage.Group, error) {
+	if id != f.group.ID {
+		return storage.Group{}, sql.ErrNoRows
+	}
+	return f.group, nil
+}
+
+func (f *fakeQuerier) GetUserByID(_ context.Context, id uuid.UUID) (storage.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return storage.User{}, sql.ErrNoRows
+}
+
+func (f *fakeQuerier) ListGroupDeliveryLogArchives(_ context.Context, _ storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+	return f.archives, nil
+}
+
+func (f *fakeQuerier) ListGroupMembersByGroupID(_ context.Context, _ uuid.UUID) ([]storage.GroupMember, error) {
+	return f.members, nil
+}
+
+func (f *fakeQuerier) ListGroupMessageStorageRefs(_ context.Context, _ pgtype.UUID) ([]pgtype.Text, error) {
+	return f.storageRefs, nil
+}
+
+func (f *fakeQuerier) ScrubGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) (int64, error) {
+	f.scrubbed = true
+	return int64(len(f.deliveryLogs)), nil
+}
+
+type fakeStore struct {
+	deleted []string
+	failOn  string
+}
+
+func (s *fakeStore) Put(_ context.Context, _ string, _ []byte) error { return nil }
+
+func (s *fakeStore) Get(_ context.Context, _ string) ([]byte, error) { return nil, nil }
+
+func (s *fakeStore) Delete(_ context.Context, messageID string) error {
+	if messageID == s.failOn {
+		return errors.New("store unavailable")
+	}
+	s.deleted = append(s.deleted, messageID)
+	return nil
+}
+
+func testData() *fakeQuerier {
+	groupID := uuid.New()
+	userID := uuid.New()
+	messageID := uuid.New()
+	return &fakeQuerier{
+		group:   storage.Group{ID: groupID, Name: "acme", GroupType: "company", Status: "active", MonthlyLimit: 1000},
+		members: []storage.GroupMember{{GroupID: groupID, UserID: userID, Role: "owner"}},
+		users: map[uuid.UUID]storage.User{userID: {
+			ID:           userID,
+			Email:        "owner@acme.test",
+			PasswordHash: "$2a$10$secret",
+			ApiKey:       sql.NullString{String: "key-secret", Valid: true},
+			Status:       "active",
+			AccountType:  "human",
+		}},
+		messages: []storage.ExportGroupMessagesRow{{
+			ID:         messageID,
+			Sender:     "owner@acme.test",
+			Recipients: []byte(`["alice@example.com"]`),
+			Subject:    sql.NullString{String: "Hello", Valid: true},
+			Headers:    []byte(`{}`),
+			Status:     storage.MessageStatusDelivered,
+			SizeBytes:  42,
+		}},
+		deliveryLogs: []storage.DeliveryLog{{ID: uuid.New(), MessageID: messageID, Status: "delivered", AttemptNumber: 1}},
+		activityLogs: []storage.ActivityLog{{ID: uuid.New(), GroupID: groupID, Action: "create", ResourceType: "user"}},
+	}
+}
+
+func TestExport(t *testing.T) {
+	q := testData()
+	var buf bytes.Buffer
+
+	manifest, err := Export(context.Background(), q, q.group.ID, &buf)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if manifest.Users != 1 || manifest.Messages != 1 || manifest.DeliveryLogs != 1 || manifest.ActivityLogs != 1 {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"manifest.json", "group.json", "users.json", "messages.json", "delivery_logs.json", "activity_logs.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+
+	if strings.Contains(files["users.json"], "secret") {
+		t.Errorf("users.json leaks credentials: %s", files["users.json"])
+	}
+	var messages []MessageRecord
+	if err := json.Unmarshal([]byte(files["messages.json"]), &messages); err != nil {
+		t.Fatalf("decode messages.json: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Subject != "Hello" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	var recipients []string
+	if err := json.Unmarshal(messages[0].Recipients, &recipients); err != nil || len(recipients) != 1 || recipients[0] != "alice@example.com" {
+		t.Errorf("recipients = %s, want [alice@example.com]", messages[0].Recipients)
+	}
+}
+
+func TestExport_GroupNotFound(t *testing.T) {
+	q := testData()
+	if _, err := Export(context.Background(), q, uuid.New(), io.Discard); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Export() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestErase(t *testing.T) {
+	q := testData()
+	q.storageRefs = []pgtype.Text{{String: "a", Valid: true}, {String: "b", Valid: true}}
+	q.captures = 3
+	store := &fakeStore{}
+
+	erasure, err := Erase(context.Background(), q, store, q.group.ID)
+	if err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if erasure.BodiesDeleted != 2 || erasure.MessagesErased != 1 || erasure.DeliveryLogsScrubbed != 1 || erasure.CapturesDeleted != 3 {
+		t.Errorf("unexpected erasure: %+v", erasure)
+	}
+	if len(store.deleted) != 2 {
+		t.Errorf("deleted %v, want both bodies", store.deleted)
+	}
+	if !q.erased || !q.scrubbed {
+		t.Error("expected messages erased and delivery logs scrubbed")
+	}
+}
+
+func TestErase_StoreFailureLeavesDatabase(t *testing.T) {
+	q := testData()
+	q.storageRefs = []pgtype.Text{{String: "a", Valid: true}, {String: "b", Valid: true}}
+	store := &fakeStore{failOn: "b"}
+
+	erasure, err := Erase(context.Background(), q, store, q.group.ID)
+	if err == nil {
+		t.Fatal("expected error when a body cannot be deleted")
+	}
+	if erasure.BodiesDeleted != 1 {
+		t.Errorf("BodiesDeleted = %d, want 1", erasure.BodiesDeleted)
+	}
+	if q.erased || q.scrubbed || erasure.CapturesDeleted != 0 {
+		t.Error("database must not be changed when body deletion fails")
+	}
+}
+
+func TestErase_NoStore(t *testing.T) {
+	q := testData()
+	q.storageRefs = []pgtype.Text{{String: "a", Valid: true}}
+
+	if _, err := Erase(context.Background(), q, nil, q.group.ID); !errors.Is(err, ErrNoMessageStore) {
+		t.Errorf("Erase() error = %v, want ErrNoMessageStore", err)
+	}
+
+	q.storageRefs = nil
+	if _, err := Erase(context.Background(), q, nil, q.group.ID); err != nil {
+		t.Errorf("Erase() without stored bodies error = %v", err)
+	}
+}
+
+func TestErase_DeletesArchives(t *testing.T) {
+	q := testData()
+	q.archives = []storage.DeliveryLogArchive{{ID: uuid.New(), ObjectKey: "delivery-logs-20250101-a.ndjson.gz"}}
+	store := &fakeStore{}
+
+	if _, err := Erase(context.Background(), q, nil, q.group.ID); !errors.Is(err, ErrNoMessageStore) {
+		t.Fatalf("Erase() without store error = %v, want ErrNoMessageStore", err)
+	}
+
+	erasure, err := Erase(context.Background(), q, store, q.group.ID)
+	if err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if erasure.ArchivesDeleted != 1 || len(q.archives) != 0 {
+		t.Errorf("ArchivesDeleted = %d with %d index rows left, want 1 and 0", erasure.ArchivesDeleted, len(q.archives))
+	}
+	if len(store.deleted) != 1 || store.deleted[0] != "delivery-logs-20250101-a.ndjson.gz" {
+		t.Errorf("deleted %v, want the archive file", store.deleted)
+	}
+}
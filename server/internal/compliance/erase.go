@@ -0,0 +1,87 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// ErrNoMessageStore is returned by Erase when the group has message bodies
+// or delivery log archives in the message store but no store was given to
+// delete them from.
+var ErrNoMessageStore = errors.New("compliance: message store not configured")
+
+// Erasure summarises a compliance delete.
+type Erasure struct {
+	GroupID              uuid.UUID `json:"group_id"`
+	BodiesDeleted        int       `json:"bodies_deleted"`
+	ArchivesDeleted      int       `json:"archives_deleted"`
+	MessagesErased       int64     `json:"messages_erased"`
+	DeliveryLogsScrubbed int64     `json:"delivery_logs_scrubbed"`
+	CapturesDeleted      int64     `json:"captures_deleted"`
+}
+
+// Erase purges the personal data held for the group's messages:
+//
+//   - bodies kept in the message store are deleted;
+//   - archived delivery logs are deleted with their archive files;
+//   - sender, recipients, subject, headers and inline bodies are cleared,
+//     keeping status, timestamps and sizes for usage reporting;
+//   - email addresses in delivery log responses, errors and metadata are
+//     replaced with "[redacted]";
+//   - provider captures of the group's providers and messages, which hold
+//     the ESP requests and responses, are deleted.
+//
+// Message store deletes run first and stop at the first failure, so a
+// failed erasure leaves the database untouched and can be retried. Every
+// step is idempotent. Users, sub-groups and the activity log are kept.
+func Erase(ctx context.Context, q Querier, store msgstore.MessageStore, groupID uuid.UUID) (*Erasure, error) {
+	pgGroupID := pgtype.UUID{Bytes: groupID, Valid: true}
+	erasure := &Erasure{GroupID: groupID}
+
+	refs, err := q.ListGroupMessageStorageRefs(ctx, pgGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: list stored bodies: %w", err)
+	}
+	archives, err := q.ListGroupDeliveryLogArchives(ctx, storage.ListGroupDeliveryLogArchivesParams{GroupID: pgGroupID})
+	if err != nil {
+		return nil, fmt.Errorf("compliance: list delivery log archives: %w", err)
+	}
+	if (len(refs) > 0 || len(archives) > 0) && store == nil {
+		return nil, ErrNoMessageStore
+	}
+	for _, ref := range refs {
+		if err := store.Delete(ctx, ref.String); err != nil {
+			return erasure, fmt.Errorf("compliance: delete body %s: %w", ref.String, err)
+		}
+		erasure.BodiesDeleted++
+	}
+	for _, a := range archives {
+		if err := store.Delete(ctx, a.ObjectKey); err != nil {
+			return erasure, fmt.Errorf("compliance: delete archive %s: %w", a.ObjectKey, err)
+		}
+		if err := q.DeleteDeliveryLogArchive(ctx, a.ID); err != nil {
+			return erasure, fmt.Errorf("compliance: delete archive %s: %w", a.ObjectKey, err)
+		}
+		erasure.ArchivesDeleted++
+	}
+
+	erasure.MessagesErased, err = q.EraseGroupMessages(ctx, pgGroupID)
+	if err != nil {
+		return erasure, fmt.Errorf("compliance: erase messages: %w", err)
+	}
+	erasure.DeliveryLogsScrubbed, err = q.ScrubGroupDeliveryLogs(ctx, pgGroupID)
+	if err != nil {
+		return erasure, fmt.Errorf("compliance: scrub delivery logs: %w", err)
+	}
+	erasure.CapturesDeleted, err = q.DeleteGroupProviderCaptures(ctx, groupID)
+	if err != nil {
+		return erasure, fmt.Errorf("compliance: delete provider captures: %w", err)
+	}
+	return erasure, nil
+}
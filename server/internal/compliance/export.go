@@ -0,0 +1,300 @@
+// Package compliance exports all data held for a group and erases the
+// personal data in it on request (GDPR access and erasure requests).
+package compliance
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Querier is the subset of storage.Querier used to export and erase group
+// data.
+type Querier interface {
+	DeleteDeliveryLogArchive(ctx context.Context, id uuid.UUID) error
+	DeleteGroupProviderCaptures(ctx context.Context, groupID uuid.UUID) (int64, error)
+	EraseGroupMessages(ctx context.Context, groupID pgtype.UUID) (int64, error)
+	ExportGroupActivityLogs(ctx context.Context, groupID uuid.UUID) ([]storage.ActivityLog, error)
+	ExportGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) ([]storage.DeliveryLog, error)
+	ExportGroupMessages(ctx context.Context, groupID pgtype.UUID) ([]storage.ExportGroupMessagesRow, error)
+	GetGroupByID(ctx context.Context, id uuid.UUID) (storage.Group, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (storage.User, error)
+	ListGroupDeliveryLogArchives(ctx context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error)
+	ListGroupMembersByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.GroupMember, error)
+	ListGroupMessageStorageRefs(ctx context.Context, groupID pgtype.UUID) ([]pgtype.Text, error)
+	ScrubGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) (int64, error)
+}
+
+// Manifest describes an export archive. It is written as manifest.json.
+type Manifest struct {
+	GroupID      uuid.UUID `json:"group_id"`
+	GroupName    string    `json:"group_name"`
+	ExportedAt   time.Time `json:"exported_at"`
+	Users        int       `json:"users"`
+	Messages     int       `json:"messages"`
+	DeliveryLogs int       `json:"delivery_logs"`
+	ActivityLogs int       `json:"activity_logs"`
+}
+
+// GroupRecord is the group as written to group.json.
+type GroupRecord struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	GroupType    string     `json:"group_type"`
+	Status       string     `json:"status"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	MonthlyLimit int32      `json:"monthly_limit"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// UserRecord is a group member as written to users.json. Password hashes
+// and API keys are never exported.
+type UserRecord struct {
+	ID          uuid.UUID  `json:"id"`
+	Email       string     `json:"email"`
+	Username    string     `json:"username,omitempty"`
+	AccountType string     `json:"account_type"`
+	Status      string     `json:"status"`
+	Role        string     `json:"role"`
+	MemberSince time.Time  `json:"member_since"`
+	LastLogin   *time.Time `json:"last_login,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// MessageRecord is a message's metadata as written to messages.json.
+// Bodies are not exported.
+type MessageRecord struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       *uuid.UUID      `json:"user_id,omitempty"`
+	Sender       string          `json:"sender"`
+	Recipients   json.RawMessage `json:"recipients"`
+	Subject      string          `json:"subject,omitempty"`
+	Headers      json.RawMessage `json:"headers"`
+	Status       string          `json:"status"`
+	ProviderID   *uuid.UUID      `json:"provider_id,omitempty"`
+	SizeBytes    int64           `json:"size_bytes"`
+	RequeueCount int32           `json:"requeue_count"`
+	Tags         json.RawMessage `json:"tags,omitempty"`
+	Metadata     json.RawMessage `json:"metadata,omitempty"`
+	EnqueuedAt   time.Time       `json:"enqueued_at"`
+	ProcessedAt  *time.Time      `json:"processed_at,omitempty"`
+}
+
+// DeliveryLogRecord is a delivery attempt as written to delivery_logs.json.
+type DeliveryLogRecord struct {
+	ID                uuid.UUID       `json:"id"`
+	MessageID         uuid.UUID       `json:"message_id"`
+	Provider          string          `json:"provider,omitempty"`
+	ProviderMessageID string          `json:"provider_message_id,omitempty"`
+	Status            string          `json:"status"`
+	ResponseCode      *int32          `json:"response_code,omitempty"`
+	ResponseBody      string          `json:"response_body,omitempty"`
+	LastError         string          `json:"last_error,omitempty"`
+	AttemptNumber     int32           `json:"attempt_number"`
+	DurationMs        *int32          `json:"duration_ms,omitempty"`
+	Metadata          json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	DeliveredAt       *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// ActivityLogRecord is an audit log entry as written to activity_logs.json.
+type ActivityLogRecord struct {
+	ID           uuid.UUID       `json:"id"`
+	ActorID      *uuid.UUID      `json:"actor_id,omitempty"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   *uuid.UUID      `json:"resource_id,omitempty"`
+	Changes      json.RawMessage `json:"changes,omitempty"`
+	Comment      string          `json:"comment,omitempty"`
+	IPAddress    string          `json:"ip_address,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Export writes a zip archive with everything stored for the group:
+// manifest.json, group.json, users.json, messages.json (metadata only),
+// delivery_logs.json and activity_logs.json. Data of sub-groups is not
+// included; export them separately.
+func Export(ctx context.Context, q Querier, groupID uuid.UUID, w io.Writer) (*Manifest, error) {
+	group, err := q.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: load group: %w", err)
+	}
+	pgGroupID := pgtype.UUID{Bytes: groupID, Valid: true}
+
+	members, err := q.ListGroupMembersByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: list members: %w", err)
+	}
+	users := make([]UserRecord, 0, len(members))
+	for _, m := range members {
+		u, err := q.GetUserByID(ctx, m.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("compliance: load user %s: %w", m.UserID, err)
+		}
+		users = append(users, UserRecord{
+			ID:          u.ID,
+			Email:       u.Email,
+			Username:    u.Username.String,
+			AccountType: u.AccountType,
+			Status:      u.Status,
+			Role:        m.Role,
+			MemberSince: m.CreatedAt.Time,
+			LastLogin:   optionalTime(u.LastLogin),
+			CreatedAt:   u.CreatedAt.Time,
+		})
+	}
+
+	messageRows, err := q.ExportGroupMessages(ctx, pgGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: export messages: %w", err)
+	}
+	messages := make([]MessageRecord, 0, len(messageRows))
+	for _, m := range messageRows {
+		messages = append(messages, MessageRecord{
+			ID:           m.ID,
+			UserID:       optionalUUID(m.UserID),
+			Sender:       m.Sender,
+			Recipients:   rawJSON(m.Recipients),
+			Subject:      m.Subject.String,
+			Headers:      rawJSON(m.Headers),
+			Status:       string(m.Status),
+			ProviderID:   optionalUUID(m.ProviderID),
+			SizeBytes:    m.SizeBytes,
+			RequeueCount: m.RequeueCount,
+			Tags:         rawJSON(m.Tags),
+			Metadata:     rawJSON(m.Metadata),
+			EnqueuedAt:   m.EnqueuedAt.Time,
+			ProcessedAt:  optionalTime(m.ProcessedAt),
+		})
+	}
+
+	logRows, err := q.ExportGroupDeliveryLogs(ctx, pgGroupID)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: export delivery logs: %w", err)
+	}
+	deliveryLogs := make([]DeliveryLogRecord, 0, len(logRows))
+	for _, l := range logRows {
+		rec := DeliveryLogRecord{
+			ID:                l.ID,
+			MessageID:         l.MessageID,
+			Provider:          l.Provider.String,
+			ProviderMessageID: l.ProviderMessageID.String,
+			Status:            l.Status,
+			ResponseBody:      l.ResponseBody.String,
+			LastError:         l.LastError.String,
+			AttemptNumber:     l.AttemptNumber,
+			Metadata:          rawJSON(l.Metadata),
+			CreatedAt:         l.CreatedAt.Time,
+			DeliveredAt:       optionalTime(l.DeliveredAt),
+		}
+		if l.ResponseCode.Valid {
+			rec.ResponseCode = &l.ResponseCode.Int32
+		}
+		if l.DurationMs.Valid {
+			rec.DurationMs = &l.DurationMs.Int32
+		}
+		deliveryLogs = append(deliveryLogs, rec)
+	}
+
+	activityRows, err := q.ExportGroupActivityLogs(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("compliance: export activity logs: %w", err)
+	}
+	activityLogs := make([]ActivityLogRecord, 0, len(activityRows))
+	for _, a := range activityRows {
+		rec := ActivityLogRecord{
+			ID:           a.ID,
+			ActorID:      optionalUUID(a.ActorID),
+			Action:       a.Action,
+			ResourceType: a.ResourceType,
+			ResourceID:   optionalUUID(a.ResourceID),
+			Changes:      rawJSON(a.Changes),
+			Comment:      a.Comment.String,
+			CreatedAt:    a.CreatedAt.Time,
+		}
+		if a.IpAddress != nil {
+			rec.IPAddress = a.IpAddress.String()
+		}
+		activityLogs = append(activityLogs, rec)
+	}
+
+	manifest := &Manifest{
+		GroupID:      groupID,
+		GroupName:    group.Name,
+		ExportedAt:   time.Now().UTC(),
+		Users:        len(users),
+		Messages:     len(messages),
+		DeliveryLogs: len(deliveryLogs),
+		ActivityLogs: len(activityLogs),
+	}
+	groupRecord := GroupRecord{
+		ID:           group.ID,
+		Name:         group.Name,
+		GroupType:    group.GroupType,
+		Status:       group.Status,
+		ParentID:     optionalUUID(group.ParentID),
+		MonthlyLimit: group.MonthlyLimit,
+		CreatedAt:    group.CreatedAt.Time,
+		UpdatedAt:    group.UpdatedAt.Time,
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"manifest.json", manifest},
+		{"group.json", groupRecord},
+		{"users.json", users},
+		{"messages.json", messages},
+		{"delivery_logs.json", deliveryLogs},
+		{"activity_logs.json", activityLogs},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: manifest.ExportedAt})
+		if err != nil {
+			return nil, fmt.Errorf("compliance: create %s: %w", f.name, err)
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, fmt.Errorf("compliance: write %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compliance: close archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// rawJSON returns a JSONB column as raw JSON, or nil (null) when empty.
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.RawMessage(b)
+}
+
+func optionalUUID(id pgtype.UUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	u := uuid.UUID(id.Bytes)
+	return &u
+}
+
+func optionalTime(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
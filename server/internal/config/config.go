@@ -10,15 +10,39 @@ import (
 
 // Config holds all application configuration.
 type Config struct {
-	SMTP      SMTPConfig      `mapstructure:"smtp"`
-	API       APIConfig       `mapstructure:"api"`
-	Database  DatabaseConfig  `mapstructure:"database"`
-	Logging   LoggingConfig   `mapstructure:"logging"`
-	TLS       TLSConfig       `mapstructure:"tls"`
-	Queue     QueueConfig     `mapstructure:"queue"`
-	Auth      AuthConfig      `mapstructure:"auth"`
-	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
-	Storage   StorageConfig   `mapstructure:"storage"`
+	SMTP             SMTPConfig             `mapstructure:"smtp"`
+	API              APIConfig              `mapstructure:"api"`
+	Database         DatabaseConfig         `mapstructure:"database"`
+	Logging          LoggingConfig          `mapstructure:"logging"`
+	TLS              TLSConfig              `mapstructure:"tls"`
+	Delivery         DeliveryConfig         `mapstructure:"delivery"`
+	Queue            QueueConfig            `mapstructure:"queue"`
+	Auth             AuthConfig             `mapstructure:"auth"`
+	RateLimit        RateLimitConfig        `mapstructure:"rate_limit"`
+	Storage          StorageConfig          `mapstructure:"storage"`
+	Sweeper          SweeperConfig          `mapstructure:"sweeper"`
+	SLO              SLOConfig              `mapstructure:"slo"`
+	Prober           ProberConfig           `mapstructure:"prober"`
+	AccountPoller    AccountPollerConfig    `mapstructure:"account_poller"`
+	Preview          PreviewConfig          `mapstructure:"preview"`
+	QuotaWarnings    QuotaWarningsConfig    `mapstructure:"quota_warnings"`
+	Capture          CaptureConfig          `mapstructure:"capture"`
+	DNS              DNSConfig              `mapstructure:"dns"`
+	Egress           EgressConfig           `mapstructure:"egress"`
+	Plugins          PluginsConfig          `mapstructure:"plugins"`
+	Scripting        ScriptingConfig        `mapstructure:"scripting"`
+	Alerts           AlertsConfig           `mapstructure:"alerts"`
+	CertMonitor      CertMonitorConfig      `mapstructure:"cert_monitor"`
+	LogArchive       LogArchiveConfig       `mapstructure:"log_archive"`
+	Analytics        AnalyticsConfig        `mapstructure:"analytics_export"`
+	PasswordPolicy   PasswordPolicyConfig   `mapstructure:"password_policy"`
+	CredentialExpiry CredentialExpiryConfig `mapstructure:"credential_expiry"`
+	SystemMail       SystemMailConfig       `mapstructure:"system_mail"`
+	Signup           SignupConfig           `mapstructure:"signup"`
+	Resend           ResendConfig           `mapstructure:"resend"`
+	DeliveryReports  DeliveryReportsConfig  `mapstructure:"delivery_reports"`
+	Profiling        ProfilingConfig        `mapstructure:"profiling"`
+	CrashReporting   CrashReportingConfig   `mapstructure:"crash_reporting"`
 }
 
 // AuthConfig holds JWT authentication configuration.
@@ -34,6 +58,17 @@ type AuthConfig struct {
 	Issuer string `mapstructure:"issuer"`
 	// Audience is the JWT audience claim.
 	Audience string `mapstructure:"audience"`
+	// ScopedTokenMaxTTL is the longest lifetime of a scoped token minted
+	// with POST /api/v1/auth/tokens.
+	ScopedTokenMaxTTL time.Duration `mapstructure:"scoped_token_max_ttl"`
+	// LoginAnomalyDetection flags logins from networks or countries the
+	// user has not logged in from before.
+	LoginAnomalyDetection bool `mapstructure:"login_anomaly_detection"`
+	// CountryHeader names a request header with the client's ISO country
+	// code set by a trusted proxy or CDN, e.g. CF-IPCountry.
+	CountryHeader string `mapstructure:"country_header"`
+	// InvitationTTL is how long a group invitation link stays valid.
+	InvitationTTL time.Duration `mapstructure:"invitation_ttl"`
 }
 
 // RateLimitConfig holds rate limiting configuration.
@@ -44,6 +79,13 @@ type RateLimitConfig struct {
 	LoginAttemptsLimit int `mapstructure:"login_attempts_limit"`
 	// LoginLockoutDuration is how long a user is locked out after exceeding attempts.
 	LoginLockoutDuration time.Duration `mapstructure:"login_lockout_duration"`
+	// LoginDelayAfter is the number of failed login attempts after which
+	// each further attempt must wait; 0 disables the delays.
+	LoginDelayAfter int `mapstructure:"login_delay_after"`
+	// LoginDelayBase is the first wait; it doubles with each further failure.
+	LoginDelayBase time.Duration `mapstructure:"login_delay_base"`
+	// LoginDelayMax caps the wait between attempts.
+	LoginDelayMax time.Duration `mapstructure:"login_delay_max"`
 }
 
 // SMTPConfig holds SMTP server configuration.
@@ -54,6 +96,188 @@ type SMTPConfig struct {
 	ReadTimeout    time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout   time.Duration `mapstructure:"write_timeout"`
 	MaxMessageSize int64         `mapstructure:"max_message_size"`
+	// Greylist configures greylisting for listeners that accept
+	// unauthenticated mail. Authenticated submission is never greylisted.
+	Greylist GreylistConfig `mapstructure:"greylist"`
+	// DNSBL checks clients of listeners that accept unauthenticated mail
+	// against DNS blocklists.
+	DNSBL DNSBLConfig `mapstructure:"dnsbl"`
+	// Dedup drops or tags authenticated submissions identical to one
+	// accepted within a window.
+	Dedup DedupConfig `mapstructure:"dedup"`
+	// LoopDetection rejects messages that have passed through too many
+	// relays or through this server before.
+	LoopDetection LoopDetectionConfig `mapstructure:"loop_detection"`
+	// Inbound configures the inbound listener that receives mail for
+	// domains with an inbound route (MX records pointing at smtp-proxy).
+	Inbound InboundConfig `mapstructure:"inbound"`
+	// LoadShedding defers sessions with 421 while the database pool is
+	// saturated.
+	LoadShedding LoadSheddingConfig `mapstructure:"load_shedding"`
+	// Backpressure defers transactions with 451/452, and eventually
+	// rejects sessions with 421, while the queue backlog or database write
+	// latency is too high.
+	Backpressure BackpressureConfig `mapstructure:"backpressure"`
+	// MemoryBudget bounds the in-flight message bodies held in memory;
+	// bodies beyond it are spilled to temporary files.
+	MemoryBudget MemoryBudgetConfig `mapstructure:"memory_budget"`
+	// Admin configures the HTTP listener serving health checks and the
+	// drain endpoints.
+	Admin SMTPAdminConfig `mapstructure:"admin"`
+	// Drain configures maintenance drain mode, entered via the admin API,
+	// SIGUSR1 or on shutdown.
+	Drain DrainConfig `mapstructure:"drain"`
+	// PassThrough relays authenticated submissions to the provider before
+	// answering DATA instead of queueing them.
+	PassThrough PassThroughConfig `mapstructure:"pass_through"`
+	// SessionActivity records a summary of each authenticated session in
+	// the activity log.
+	SessionActivity SessionActivityConfig `mapstructure:"session_activity"`
+	// Listeners configures the SMTP listeners served by the process. When
+	// empty, a single submission listener on Host:Port using tls.mode (and
+	// the inbound listener, when enabled) is served.
+	Listeners []ListenerConfig `mapstructure:"listeners"`
+}
+
+// ListenerConfig holds the configuration of one SMTP listener. Listeners
+// inherited from systemd socket activation are matched by Name (the
+// socket's FileDescriptorName=) or by Address.
+type ListenerConfig struct {
+	Name    string `mapstructure:"name"`
+	Address string `mapstructure:"address"`
+	// TLS is the listener's TLS policy: "starttls" (offered, required
+	// for AUTH), "implicit" (TLS from connect, as on port 465) or "none".
+	TLS string `mapstructure:"tls"`
+	// RequireTLS rejects MAIL FROM until STARTTLS has completed.
+	RequireTLS bool `mapstructure:"require_tls"`
+	// Inbound serves the unauthenticated inbound listener (port 25)
+	// instead of authenticated submission.
+	Inbound bool `mapstructure:"inbound"`
+}
+
+// MemoryBudgetConfig holds the SMTP server's memory budget for in-flight
+// message bodies. A MaxBytes of zero disables the budget.
+type MemoryBudgetConfig struct {
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// SpillDir receives bodies read while the budget is exhausted. The
+	// system temporary directory is used when empty.
+	SpillDir string `mapstructure:"spill_dir"`
+}
+
+// SMTPAdminConfig holds the SMTP server's admin HTTP listener
+// configuration. The /admin endpoints require Token as a bearer token and
+// are disabled when it is empty.
+type SMTPAdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    int    `mapstructure:"port"`
+	Token   string `mapstructure:"token"`
+}
+
+// DrainConfig holds SMTP drain mode configuration.
+type DrainConfig struct {
+	// RetryAfter is the retry delay advertised in 421 replies.
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+	// Timeout bounds how long shutdown waits for sessions to finish.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// PassThroughConfig holds SMTP pass-through mode configuration. In
+// pass-through mode the DATA reply carries the provider's outcome: 250 once
+// it accepted the message, 5xx for permanent and 4xx for transient
+// failures, which the client retries. Messages are still recorded for the
+// messages API and delivery logs, but never queued or retried.
+type PassThroughConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Timeout bounds each delivery; on expiry the client gets 451.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// LoadSheddingConfig holds SMTP load shedding configuration.
+type LoadSheddingConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	MaxAcquireWait time.Duration `mapstructure:"max_acquire_wait"`
+}
+
+// BackpressureConfig holds SMTP backpressure configuration. The backlog is
+// the number of queue stream entries not yet acknowledged by the queue
+// workers; the write latency is the average duration of the transactions
+// persisting accepted messages. A zero threshold is disabled.
+type BackpressureConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Interval           time.Duration `mapstructure:"interval"`
+	DeferBacklog       int64         `mapstructure:"defer_backlog"`
+	RejectBacklog      int64         `mapstructure:"reject_backlog"`
+	DeferWriteLatency  time.Duration `mapstructure:"defer_write_latency"`
+	RejectWriteLatency time.Duration `mapstructure:"reject_write_latency"`
+	// RetryAfter is the retry delay suggested in 451, 452 and 421 replies.
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+}
+
+// InboundConfig holds inbound listener configuration.
+type InboundConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	Port    int    `mapstructure:"port"`
+}
+
+// GreylistConfig holds greylisting configuration. See package greylist.
+type GreylistConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Delay          time.Duration `mapstructure:"delay"`
+	RetryWindow    time.Duration `mapstructure:"retry_window"`
+	KnownSenderTTL time.Duration `mapstructure:"known_sender_ttl"`
+	Allowlist      []string      `mapstructure:"allowlist"`
+	// DNSBLOnly greylists only clients whose DNS blocklist score reaches
+	// smtp.dnsbl.greylist_score.
+	DNSBLOnly bool `mapstructure:"dnsbl_only"`
+}
+
+// DNSBLConfig holds DNS blocklist configuration. See package dnsbl.
+type DNSBLConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Lists are the blocklists checked and the score a listing adds.
+	Lists []DNSBLListConfig `mapstructure:"lists"`
+	// RejectScore and GreylistScore are the scores at which a client is
+	// rejected or greylisted; zero disables the action.
+	RejectScore   float64       `mapstructure:"reject_score"`
+	GreylistScore float64       `mapstructure:"greylist_score"`
+	CacheTTL      time.Duration `mapstructure:"cache_ttl"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// DNSBLListConfig is one DNS blocklist.
+type DNSBLListConfig struct {
+	Zone   string  `mapstructure:"zone"`
+	Weight float64 `mapstructure:"weight"`
+}
+
+// LoopDetectionConfig holds mail loop detection configuration.
+type LoopDetectionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxReceived is the most Received headers a message may carry; zero
+	// disables the count.
+	MaxReceived int `mapstructure:"max_received"`
+	// Hostnames are this server's names as they appear in Received
+	// headers added by downstream relays. The OS hostname is always
+	// included.
+	Hostnames []string `mapstructure:"hostnames"`
+}
+
+// DedupConfig holds duplicate submission detection configuration. See
+// package dedup.
+type DedupConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Window  time.Duration `mapstructure:"window"`
+	// Action is "drop" or "tag".
+	Action string `mapstructure:"action"`
+}
+
+// SessionActivityConfig holds the configuration of SMTP session activity
+// logging. When enabled, each authenticated session writes one activity
+// log entry with its message count, duration and command errors.
+type SessionActivityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
 }
 
 // APIConfig holds REST API server configuration.
@@ -62,27 +286,161 @@ type APIConfig struct {
 	Port         int           `mapstructure:"port"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// TLS terminates HTTPS (and optionally mTLS) in the API server itself,
+	// for deployments without a gateway in front of it.
+	TLS APITLSConfig `mapstructure:"tls"`
+	// Access restricts route groups by client address and certificate.
+	// A request is checked against the rule with the longest matching
+	// PathPrefix; paths matching no rule are unrestricted.
+	Access []APIAccessRule `mapstructure:"access"`
+	// RateLimit limits requests per client address and per API key or
+	// token, with separate limits per endpoint class.
+	RateLimit APIRateLimitConfig `mapstructure:"rate_limit"`
+	// CORS configures cross-origin access for browser clients.
+	CORS APICORSConfig `mapstructure:"cors"`
+	// CSRF protects cookie-authenticated browser sessions.
+	CSRF APICSRFConfig `mapstructure:"csrf"`
+	// Legacy serves the deprecated account and tenant endpoints.
+	Legacy APILegacyConfig `mapstructure:"legacy"`
+}
+
+// APILegacyConfig enables the account and tenant endpoints of the API
+// server from before the Group/User redesign, mapped onto SMTP users and
+// groups, for integrations that have not moved to the new endpoints.
+type APILegacyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// APIRateLimitConfig holds the API's request rate limits. Buckets are kept
+// in Redis (queue.redis_addr) so limits hold across API servers; without
+// Redis each server limits on its own.
+type APIRateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TrustedProxies lists proxy CIDRs whose X-Forwarded-For header
+	// identifies the client.
+	TrustedProxies []string          `mapstructure:"trusted_proxies"`
+	Auth           APIRateLimitClass `mapstructure:"auth"`
+	Webhooks       APIRateLimitClass `mapstructure:"webhooks"`
+	Default        APIRateLimitClass `mapstructure:"default"`
+}
+
+// APIRateLimitClass holds the limits of one endpoint class in requests per
+// minute. Zero disables a limit.
+type APIRateLimitClass struct {
+	PerIP  float64 `mapstructure:"per_ip"`
+	PerKey float64 `mapstructure:"per_key"`
+	// Burst is the number of requests allowed at once. Defaults to one
+	// minute's worth.
+	Burst int `mapstructure:"burst"`
+}
+
+// APICORSConfig holds the API's CORS configuration. With no allowed
+// origins, no CORS headers are sent.
+type APICORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age"`
+}
+
+// APICSRFConfig holds the API's CSRF protection configuration.
+type APICSRFConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	CookieName   string `mapstructure:"cookie_name"`
+	CookieSecure bool   `mapstructure:"cookie_secure"`
+}
+
+// APITLSConfig holds the API server's TLS configuration.
+type APITLSConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// ClientCAFile is a PEM bundle of CAs that client certificates are
+	// verified against. When set, clients may present a certificate;
+	// access rules with require_client_cert demand one.
+	ClientCAFile string `mapstructure:"client_ca_file"`
+}
+
+// APIAccessRule restricts the API routes under PathPrefix.
+type APIAccessRule struct {
+	PathPrefix string `mapstructure:"path_prefix"`
+	// AllowCIDRs lists the client networks (or single addresses) allowed
+	// to call the routes. Empty allows any address. The client address is
+	// the TCP peer; X-Forwarded-For is not trusted.
+	AllowCIDRs []string `mapstructure:"allow_cidrs"`
+	// RequireClientCert demands a client certificate verified against
+	// tls.client_ca_file.
+	RequireClientCert bool `mapstructure:"require_client_cert"`
 }
 
 // DatabaseConfig holds PostgreSQL connection configuration.
 type DatabaseConfig struct {
-	URL            string        `mapstructure:"url"`
-	PoolMin        int32         `mapstructure:"pool_min"`
-	PoolMax        int32         `mapstructure:"pool_max"`
-	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
+	URL               string        `mapstructure:"url"`
+	PoolMin           int32         `mapstructure:"pool_min"`
+	PoolMax           int32         `mapstructure:"pool_max"`
+	ConnectTimeout    time.Duration `mapstructure:"connect_timeout"`
+	MaxConnLifetime   time.Duration `mapstructure:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `mapstructure:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period"`
+	// QueryExecMode is one of cache_statement, cache_describe,
+	// describe_exec, exec or simple_protocol.
+	QueryExecMode          string `mapstructure:"query_exec_mode"`
+	StatementCacheCapacity int    `mapstructure:"statement_cache_capacity"`
+	// StatsInterval is how often pool statistics are sampled for metrics
+	// and SMTP load shedding.
+	StatsInterval time.Duration `mapstructure:"stats_interval"`
+	// MigrateOnStart applies the embedded migrations before the daemon
+	// starts serving.
+	MigrateOnStart bool `mapstructure:"migrate_on_start"`
+	// SchemaCheck is how the daemon reacts at startup to a database schema
+	// version other than the one it was built for: strict refuses to
+	// start, read_only runs read-only while the schema is newer, off skips
+	// the check.
+	SchemaCheck string `mapstructure:"schema_check"`
 }
 
 // LoggingConfig holds logging configuration.
 type LoggingConfig struct {
 	Level     string `mapstructure:"level"`
 	Format    string `mapstructure:"format"`
-	Output    string `mapstructure:"output"`       // stdout, file, cloudwatch
+	Output    string `mapstructure:"output"`       // stdout, file, syslog, cloudwatch
+	// Outputs lists several sinks at once, e.g. [stdout, file]. When set it
+	// takes precedence over Output.
+	Outputs []string `mapstructure:"outputs"`
 	FilePath  string `mapstructure:"file_path"`     // for file output
 	MaxSizeMB int    `mapstructure:"max_size_mb"`   // for file rotation (MB)
 	MaxFiles  int    `mapstructure:"max_files"`      // rotated files to retain
 	CWGroup   string `mapstructure:"cw_group"`       // CloudWatch log group
 	CWStream  string `mapstructure:"cw_stream"`      // CloudWatch log stream
 	CWRegion  string `mapstructure:"cw_region"`      // AWS region for CloudWatch
+	// RedactRecipients masks email addresses (j***@example.com) in structured
+	// logs, delivery logs and activity logs.
+	RedactRecipients bool `mapstructure:"redact_recipients"`
+	// RedactSubjects replaces message subjects in structured logs.
+	RedactSubjects bool `mapstructure:"redact_subjects"`
+	// Syslog configures the syslog output.
+	Syslog LogSyslogConfig `mapstructure:"syslog"`
+	// Modules overrides Level per module: smtp, queue, api.
+	Modules map[string]string `mapstructure:"modules"`
+	// Sampling limits the volume of debug events.
+	Sampling LogSamplingConfig `mapstructure:"sampling"`
+}
+
+// LogSyslogConfig holds syslog output settings. An empty Network logs to
+// the local syslog daemon.
+type LogSyslogConfig struct {
+	Network string `mapstructure:"network"` // udp, tcp, unix or empty
+	Address string `mapstructure:"address"` // e.g. localhost:514
+	Tag     string `mapstructure:"tag"`
+}
+
+// LogSamplingConfig controls sampling of debug events. The first Burst
+// events in each Period are logged, then one in every Every. Sampling is
+// disabled when Burst and Every are both zero.
+type LogSamplingConfig struct {
+	Burst  uint32        `mapstructure:"burst"`
+	Period time.Duration `mapstructure:"period"`
+	Every  uint32        `mapstructure:"every"`
 }
 
 // TLSConfig holds TLS certificate configuration.
@@ -91,6 +449,53 @@ type TLSConfig struct {
 	Mode     string `mapstructure:"mode"`
 	CertFile string `mapstructure:"cert_file"`
 	KeyFile  string `mapstructure:"key_file"`
+	// ClientAuth requests client certificates so SMTP users mapped to a
+	// certificate can authenticate without a password.
+	ClientAuth bool `mapstructure:"client_auth"`
+	// ClientCAFile is a PEM bundle of CAs that client certificates are
+	// verified against. Without it only certificates registered by
+	// fingerprint authenticate; with it, certificates can also be matched
+	// by subject alternative name.
+	ClientCAFile string `mapstructure:"client_ca_file"`
+}
+
+// DeliveryConfig selects how the SMTP server hands persisted messages to
+// the providers.
+type DeliveryConfig struct {
+	// Mode is "async" (default): the outbox relay enqueues messages to Redis
+	// Streams for the queue worker. In "sync" mode the SMTP server delivers
+	// them itself with the queue worker's handler and Redis is not needed.
+	Mode string `mapstructure:"mode"`
+	// Workers is the number of messages delivered at once in sync mode.
+	Workers int `mapstructure:"workers"`
+	// Timeout bounds one delivery in sync mode.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// PluginsConfig lists the Go plugins loaded by the daemons that deliver
+// mail (queue-worker, and smtp-server in sync or pass-through mode).
+type PluginsConfig struct {
+	// Paths are the plugin .so files, loaded in order.
+	Paths []string `mapstructure:"paths"`
+}
+
+// ScriptingConfig holds the sandbox limits for per-group Lua message
+// scripts. A script that exceeds a limit fails the delivery attempt.
+type ScriptingConfig struct {
+	// Enabled runs each group's enabled scripts before its messages are
+	// routed. Scripts can still be managed through the API when disabled.
+	Enabled bool `mapstructure:"enabled"`
+	// Timeout bounds the CPU time of one script run.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// CallStackSize is the maximum Lua call depth.
+	CallStackSize int `mapstructure:"call_stack_size"`
+	// RegistryMaxSize is the maximum number of Lua stack slots, which
+	// bounds the values a script can hold at once.
+	RegistryMaxSize int `mapstructure:"registry_max_size"`
+	// MaxStringSize caps the strings a script can build, in bytes.
+	MaxStringSize int `mapstructure:"max_string_size"`
+	// MaxMemory caps the estimated bytes a script can hold at once.
+	MaxMemory int `mapstructure:"max_memory"`
 }
 
 // QueueConfig holds Redis-based queue configuration for async delivery mode.
@@ -103,6 +508,11 @@ type QueueConfig struct {
 	ConsumerID    string        `mapstructure:"consumer_id"`
 	Workers       int           `mapstructure:"workers"`
 	BlockTimeout  time.Duration `mapstructure:"block_timeout"`
+	// OutboxPollInterval is how often the SMTP server's outbox relay polls
+	// for messages waiting to be published to the queue.
+	OutboxPollInterval time.Duration `mapstructure:"outbox_poll_interval"`
+	// OutboxBatchSize is the maximum number of outbox entries published per poll.
+	OutboxBatchSize int `mapstructure:"outbox_batch_size"`
 }
 
 // StorageConfig holds message body storage configuration.
@@ -121,6 +531,341 @@ type StorageConfig struct {
 	S3Region string `mapstructure:"s3_region"`
 }
 
+// SweeperConfig holds configuration for the queue worker's stuck-message sweeper.
+type SweeperConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Interval          time.Duration `mapstructure:"interval"`
+	QueuedTimeout     time.Duration `mapstructure:"queued_timeout"`
+	ProcessingTimeout time.Duration `mapstructure:"processing_timeout"`
+	MaxRequeues       int           `mapstructure:"max_requeues"`
+	BatchSize         int           `mapstructure:"batch_size"`
+}
+
+// LogArchiveConfig holds configuration for the queue worker's delivery log
+// archiver, which moves delivery logs older than After into gzipped NDJSON
+// files in the message store (storage.type).
+type LogArchiveConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	After    time.Duration `mapstructure:"after"`
+	Interval time.Duration `mapstructure:"interval"`
+	// BatchSize is the number of delivery logs archived per transaction.
+	BatchSize int `mapstructure:"batch_size"`
+	// MaxBatches bounds the batches handled per run.
+	MaxBatches int `mapstructure:"max_batches"`
+}
+
+// AnalyticsConfig holds configuration for the queue worker's analytics
+// exporter, which streams delivery events to ClickHouse or to a Kafka topic
+// through a REST proxy.
+type AnalyticsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Sink is "clickhouse" or "kafka".
+	Sink     string        `mapstructure:"sink"`
+	Interval time.Duration `mapstructure:"interval"`
+	// BatchSize is the number of events written per request.
+	BatchSize int `mapstructure:"batch_size"`
+	// MaxBatches bounds the batches handled per run.
+	MaxBatches int `mapstructure:"max_batches"`
+	// Lag holds back delivery logs updated more recently than this.
+	Lag time.Duration `mapstructure:"lag"`
+	// Backfill exports the existing delivery logs when a sink starts
+	// without a cursor.
+	Backfill   bool                      `mapstructure:"backfill"`
+	Timeout    time.Duration             `mapstructure:"timeout"`
+	ClickHouse AnalyticsClickHouseConfig `mapstructure:"clickhouse"`
+	Kafka      AnalyticsKafkaConfig      `mapstructure:"kafka"`
+}
+
+// AnalyticsClickHouseConfig locates the ClickHouse table events are
+// inserted into through the HTTP interface.
+type AnalyticsClickHouseConfig struct {
+	URL      string `mapstructure:"url"`
+	Database string `mapstructure:"database"`
+	Table    string `mapstructure:"table"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// AnalyticsKafkaConfig locates the Kafka REST proxy and topic events are
+// produced to.
+type AnalyticsKafkaConfig struct {
+	RestURL string `mapstructure:"rest_url"`
+	Topic   string `mapstructure:"topic"`
+}
+
+// SLOConfig holds delivery latency SLO thresholds and alert destinations.
+// A zero percentile threshold disables alerting on that percentile.
+type SLOConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Interval      time.Duration `mapstructure:"interval"`
+	Window        time.Duration `mapstructure:"window"`
+	P50Threshold  time.Duration `mapstructure:"p50_threshold"`
+	P95Threshold  time.Duration `mapstructure:"p95_threshold"`
+	P99Threshold  time.Duration `mapstructure:"p99_threshold"`
+	MinSamples    int           `mapstructure:"min_samples"`
+	AlertCooldown time.Duration `mapstructure:"alert_cooldown"`
+	// WebhookURL receives a JSON POST for each breach when set.
+	WebhookURL string `mapstructure:"webhook_url"`
+	// Email sends a plain-text alert through an SMTP relay when SMTPAddr
+	// and To are set.
+	Email SLOEmailConfig `mapstructure:"email"`
+}
+
+// SLOEmailConfig holds SMTP relay settings for SLO alert emails.
+type SLOEmailConfig struct {
+	SMTPAddr string   `mapstructure:"smtp_addr"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// AlertsConfig holds the deployment-wide destinations for operational
+// alerts (DLQ growth, provider auto-disable, quota warnings, SLO breaches).
+// Groups add their own destinations through /api/v1/alert-channels.
+type AlertsConfig struct {
+	// Timeout bounds each request to an alert channel.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Channels receive every alert matching their kinds.
+	Channels []AlertChannelConfig `mapstructure:"channels"`
+	// DLQ alerts when a group moves DLQ.Threshold messages to the dead
+	// letter queue within DLQ.Window.
+	DLQ AlertsDLQConfig `mapstructure:"dlq"`
+}
+
+// AlertChannelConfig is one deployment-wide alert destination.
+type AlertChannelConfig struct {
+	// Type is slack, pagerduty or webhook.
+	Type string `mapstructure:"type"`
+	// Target is the webhook URL for slack and webhook channels and the
+	// Events API routing key for pagerduty.
+	Target string `mapstructure:"target"`
+	// Kinds limits the channel to these alert kinds; empty means all.
+	Kinds []string `mapstructure:"kinds"`
+}
+
+// AlertsDLQConfig holds the DLQ growth alert threshold.
+type AlertsDLQConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Threshold int           `mapstructure:"threshold"`
+	Window    time.Duration `mapstructure:"window"`
+}
+
+// CertMonitorConfig holds the SMTP server's certificate expiry monitor
+// configuration. The SMTP server certificate (tls.cert_file or the
+// self-signed one) and api.tls.cert_file are always watched.
+type CertMonitorConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// WarnDays are the days-remaining thresholds that raise a
+	// cert_expiring alert, each once per certificate.
+	WarnDays []int `mapstructure:"warn_days"`
+	// CriticalDays marks alerts at or below this many days as critical.
+	CriticalDays int `mapstructure:"critical_days"`
+	// Files are additional PEM certificate files to watch.
+	Files []string `mapstructure:"files"`
+	// Relays are SMTP relays (host:port) whose certificates are checked
+	// over STARTTLS, or implicit TLS on port 465.
+	Relays []string `mapstructure:"relays"`
+	// Timeout bounds each relay check.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// ProberConfig holds configuration for the queue worker's provider health prober.
+type ProberConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	HistoryRetention time.Duration `mapstructure:"history_retention"`
+}
+
+// AccountPollerConfig holds configuration for the queue worker's ESP quota
+// and reputation poller.
+type AccountPollerConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// Reputation pauses or throttles providers whose sender reputation
+	// degrades.
+	Reputation ReputationConfig `mapstructure:"reputation"`
+}
+
+// ReputationConfig holds the thresholds at which the account poller pauses
+// a provider. A zero threshold disables its check.
+type ReputationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Action is "pause" (stop routing to the provider) or "throttle"
+	// (route to it only when no other provider is available).
+	Action           string  `mapstructure:"action"`
+	MaxBounceRate    float64 `mapstructure:"max_bounce_rate"`
+	MaxComplaintRate float64 `mapstructure:"max_complaint_rate"`
+	MinReputation    float64 `mapstructure:"min_reputation"`
+	// BlocklistZones are the DNSBLs providers' relay_ips are checked
+	// against.
+	BlocklistZones []string `mapstructure:"blocklist_zones"`
+}
+
+// QuotaWarningsConfig holds configuration for the queue worker's monthly
+// limit warning emails to group owners.
+type QuotaWarningsConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Interval   time.Duration `mapstructure:"interval"`
+	Thresholds []int         `mapstructure:"thresholds"`
+	From       string        `mapstructure:"from"`
+}
+
+// PasswordPolicyConfig holds the rules for passwords set through the API.
+type PasswordPolicyConfig struct {
+	MinLength     int  `mapstructure:"min_length"`
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+	// BreachCheck rejects passwords listed by the Have I Been Pwned
+	// Pwned Passwords API, queried by k-anonymity range at BreachAPIURL.
+	BreachCheck   bool          `mapstructure:"breach_check"`
+	BreachAPIURL  string        `mapstructure:"breach_api_url"`
+	BreachTimeout time.Duration `mapstructure:"breach_timeout"`
+}
+
+// CredentialExpiryConfig holds SMTP password expiry. The SMTP server
+// refuses passwords older than MaxAge and the queue worker warns the
+// account's group WarnBefore expiry.
+type CredentialExpiryConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	MaxAge     time.Duration `mapstructure:"max_age"`
+	WarnBefore time.Duration `mapstructure:"warn_before"`
+	Interval   time.Duration `mapstructure:"interval"`
+}
+
+// SystemMailConfig holds the system emails sent through the proxy's own
+// pipeline, such as account unlock links.
+type SystemMailConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	From    string `mapstructure:"from"`
+	// BaseURL is the externally reachable API URL emailed links point to.
+	BaseURL string `mapstructure:"base_url"`
+}
+
+// SignupConfig holds self-service signup. Verification links are sent as
+// system emails, so signup also requires system_mail.
+type SignupConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// VerificationTTL is how long an emailed verification link stays valid.
+	VerificationTTL time.Duration `mapstructure:"verification_ttl"`
+	// MonthlyLimit is the monthly message limit of groups created by signup.
+	MonthlyLimit int32 `mapstructure:"monthly_limit"`
+	// RecipientValidation is the recipient validation policy of groups
+	// created by signup: off, tag or reject.
+	RecipientValidation string `mapstructure:"recipient_validation"`
+}
+
+// ResendConfig holds bulk resends of failed messages.
+type ResendConfig struct {
+	// Rate is how many resent messages are released to the queue per second.
+	Rate int `mapstructure:"rate"`
+	// MaxMessages caps the messages resent by one request.
+	MaxMessages int `mapstructure:"max_messages"`
+}
+
+// DeliveryReportsConfig holds CSV delivery reports, generated by the queue
+// worker and downloaded from the API through signed URLs.
+type DeliveryReportsConfig struct {
+	// Enabled runs the report generator in the queue worker.
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the delay between checks for pending reports.
+	Interval time.Duration `mapstructure:"interval"`
+	// Retention is how long reports are kept after they complete.
+	Retention time.Duration `mapstructure:"retention"`
+	// MaxRows caps the recipient rows of one report.
+	MaxRows int `mapstructure:"max_rows"`
+	// URLTTL is how long a signed download URL stays valid.
+	URLTTL time.Duration `mapstructure:"url_ttl"`
+}
+
+// CaptureConfig holds configuration for viewing messages captured by the
+// file provider in development.
+type CaptureConfig struct {
+	POP3 CapturePOP3Config `mapstructure:"pop3"`
+}
+
+// CapturePOP3Config holds the queue worker's read-only POP3 server for
+// captured messages. Dir must match the file provider's output directory.
+// When Username is empty any credentials are accepted.
+type CapturePOP3Config struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Dir      string `mapstructure:"dir"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// DNSConfig holds the internal caching resolver used for provider API
+// hosts and recipient MX lookups. When Servers is empty the nameservers in
+// /etc/resolv.conf are queried directly. Disabling it falls back to the host
+// resolver.
+type DNSConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Servers     []string      `mapstructure:"servers"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MinTTL      time.Duration `mapstructure:"min_ttl"`
+	MaxTTL      time.Duration `mapstructure:"max_ttl"`
+	NegativeTTL time.Duration `mapstructure:"negative_ttl"`
+	MaxEntries  int           `mapstructure:"max_entries"`
+}
+
+// EgressConfig holds the queue worker's outbound proxy for provider API
+// traffic. ProxyURL accepts http, https and socks5 URLs; a provider's own
+// smtp_config.proxy_url takes precedence.
+type EgressConfig struct {
+	ProxyURL string `mapstructure:"proxy_url"`
+}
+
+// PreviewConfig holds the external rendering test service used by the
+// message preview endpoint. Rendering tests are disabled when
+// RenderTestURL is empty.
+type PreviewConfig struct {
+	RenderTestURL    string        `mapstructure:"render_test_url"`
+	RenderTestAPIKey string        `mapstructure:"render_test_api_key"`
+	Clients          []string      `mapstructure:"clients"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+}
+
+// ProfilingConfig holds runtime profiling configuration, shared by every
+// daemon. The pprof listener is disabled when PprofAddr is empty; each
+// daemon's --pprof-addr flag overrides it, so daemons sharing a host can
+// use different ports. Its endpoints require Token as a bearer token and
+// refuse every request when it is empty.
+type ProfilingConfig struct {
+	PprofAddr            string                    `mapstructure:"pprof_addr"`
+	Token                string                    `mapstructure:"token"`
+	BlockProfileRate     int                       `mapstructure:"block_profile_rate"`
+	MutexProfileFraction int                       `mapstructure:"mutex_profile_fraction"`
+	Continuous           ContinuousProfilingConfig `mapstructure:"continuous"`
+}
+
+// ContinuousProfilingConfig holds configuration for exporting CPU and heap
+// profiles to a Pyroscope-compatible server.
+type ContinuousProfilingConfig struct {
+	Enabled   bool              `mapstructure:"enabled"`
+	ServerURL string            `mapstructure:"server_url"`
+	AuthToken string            `mapstructure:"auth_token"`
+	AppName   string            `mapstructure:"app_name"`
+	Interval  time.Duration     `mapstructure:"interval"`
+	Tags      map[string]string `mapstructure:"tags"`
+}
+
+// CrashReportingConfig holds configuration for reporting recovered panics
+// to Sentry. Panics are only logged and counted when SentryDSN is empty.
+type CrashReportingConfig struct {
+	SentryDSN   string        `mapstructure:"sentry_dsn"`
+	Environment string        `mapstructure:"environment"`
+	Release     string        `mapstructure:"release"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
 // Load reads configuration from the given config directory path.
 // It looks for a file named "config.yaml" in that directory.
 // Environment variables with prefix SMTP_PROXY_ override file values.
@@ -132,6 +877,11 @@ func Load(configPath string) (*Config, error) {
 	v.SetConfigType("yaml")
 	v.AddConfigPath(configPath)
 
+	// Set defaults for delivery mode configuration.
+	v.SetDefault("delivery.mode", "async")
+	v.SetDefault("delivery.workers", 10)
+	v.SetDefault("delivery.timeout", "30s")
+
 	// Set defaults for queue configuration.
 	v.SetDefault("queue.redis_addr", "localhost:6379")
 	v.SetDefault("queue.redis_db", 0)
@@ -140,6 +890,8 @@ func Load(configPath string) (*Config, error) {
 	v.SetDefault("queue.consumer_id", "worker-1")
 	v.SetDefault("queue.workers", 10)
 	v.SetDefault("queue.block_timeout", "5s")
+	v.SetDefault("queue.outbox_poll_interval", "1s")
+	v.SetDefault("queue.outbox_batch_size", 100)
 
 	// Set defaults for auth configuration.
 	v.SetDefault("auth.signing_key", "")
@@ -147,26 +899,270 @@ func Load(configPath string) (*Config, error) {
 	v.SetDefault("auth.refresh_token_expiry", "168h") // 7 days
 	v.SetDefault("auth.issuer", "smtp-proxy")
 	v.SetDefault("auth.audience", "smtp-proxy-api")
+	v.SetDefault("auth.scoped_token_max_ttl", "24h")
+	v.SetDefault("auth.login_anomaly_detection", false)
+	v.SetDefault("auth.country_header", "")
+	v.SetDefault("auth.invitation_ttl", "168h") // 7 days
 
 	// Set defaults for rate limiting configuration.
 	v.SetDefault("rate_limit.default_monthly_limit", 10000)
 	v.SetDefault("rate_limit.login_attempts_limit", 5)
 	v.SetDefault("rate_limit.login_lockout_duration", "15m")
+	v.SetDefault("rate_limit.login_delay_after", 3)
+	v.SetDefault("rate_limit.login_delay_base", "1s")
+	v.SetDefault("rate_limit.login_delay_max", "30s")
 
 	// Set defaults for logging configuration.
 	v.SetDefault("logging.output", "stdout")
 	v.SetDefault("logging.file_path", "/var/log/smtp-proxy.log")
 	v.SetDefault("logging.max_size_mb", 100)
 	v.SetDefault("logging.max_files", 10)
+	v.SetDefault("logging.redact_recipients", true)
+	v.SetDefault("logging.redact_subjects", true)
+	v.SetDefault("logging.syslog.tag", "smtp-proxy")
+	v.SetDefault("logging.sampling.period", "1s")
+
+	// Set defaults for greylisting configuration.
+	v.SetDefault("smtp.greylist.enabled", false)
+	v.SetDefault("smtp.greylist.delay", "5m")
+	v.SetDefault("smtp.greylist.retry_window", "4h")
+	v.SetDefault("smtp.greylist.known_sender_ttl", "864h") // 36 days
+	v.SetDefault("smtp.greylist.dnsbl_only", false)
+
+	// Set defaults for DNS blocklist checks.
+	v.SetDefault("smtp.dnsbl.enabled", false)
+	v.SetDefault("smtp.dnsbl.lists", []map[string]any{{"zone": "zen.spamhaus.org", "weight": 1}})
+	v.SetDefault("smtp.dnsbl.reject_score", 1)
+	v.SetDefault("smtp.dnsbl.greylist_score", 0)
+	v.SetDefault("smtp.dnsbl.cache_ttl", "1h")
+	v.SetDefault("smtp.dnsbl.timeout", "2s")
+
+	// Set defaults for duplicate detection.
+	v.SetDefault("smtp.dedup.enabled", false)
+	v.SetDefault("smtp.dedup.window", "10m")
+	v.SetDefault("smtp.dedup.action", "drop")
+
+	// Set defaults for mail loop detection.
+	v.SetDefault("smtp.loop_detection.enabled", true)
+	v.SetDefault("smtp.loop_detection.max_received", 50)
+
+	// Set defaults for the inbound listener.
+	v.SetDefault("smtp.inbound.enabled", false)
+	v.SetDefault("smtp.inbound.host", "0.0.0.0")
+	v.SetDefault("smtp.inbound.port", 25)
+
+	// Set defaults for SMTP load shedding.
+	v.SetDefault("smtp.load_shedding.enabled", true)
+	v.SetDefault("smtp.load_shedding.max_acquire_wait", "100ms")
+	v.SetDefault("smtp.backpressure.enabled", true)
+	v.SetDefault("smtp.backpressure.interval", "5s")
+	v.SetDefault("smtp.backpressure.defer_backlog", 50000)
+	v.SetDefault("smtp.backpressure.reject_backlog", 200000)
+	v.SetDefault("smtp.backpressure.defer_write_latency", "500ms")
+	v.SetDefault("smtp.backpressure.reject_write_latency", "2s")
+	v.SetDefault("smtp.backpressure.retry_after", "30s")
+	v.SetDefault("smtp.memory_budget.max_bytes", 268435456) // 256MB
+	v.SetDefault("smtp.memory_budget.spill_dir", "")
+	v.SetDefault("smtp.admin.enabled", true)
+	v.SetDefault("smtp.admin.host", "0.0.0.0")
+	v.SetDefault("smtp.admin.port", 8081)
+	v.SetDefault("smtp.admin.token", "")
+	v.SetDefault("smtp.drain.retry_after", "60s")
+	v.SetDefault("smtp.drain.timeout", "30s")
+	v.SetDefault("smtp.pass_through.enabled", false)
+	v.SetDefault("smtp.pass_through.timeout", "30s")
+	v.SetDefault("smtp.session_activity.enabled", false)
+
+	// Set defaults for database pool tuning.
+	v.SetDefault("database.max_conn_lifetime", "1h")
+	v.SetDefault("database.max_conn_idle_time", "30m")
+	v.SetDefault("database.health_check_period", "1m")
+	v.SetDefault("database.query_exec_mode", "cache_statement")
+	v.SetDefault("database.statement_cache_capacity", 512)
+	v.SetDefault("database.stats_interval", "5s")
+	v.SetDefault("database.migrate_on_start", false)
+	v.SetDefault("database.schema_check", "strict")
 
 	// Set defaults for TLS configuration.
 	v.SetDefault("tls.mode", "starttls")
+	v.SetDefault("tls.client_auth", false)
+	v.SetDefault("tls.client_ca_file", "")
+
+	// Set defaults for API server TLS configuration.
+	v.SetDefault("api.tls.enabled", false)
+	v.SetDefault("api.tls.cert_file", "")
+	v.SetDefault("api.tls.key_file", "")
+	v.SetDefault("api.tls.client_ca_file", "")
+
+	// Set defaults for API request rate limits (requests per minute).
+	v.SetDefault("api.rate_limit.enabled", true)
+	v.SetDefault("api.rate_limit.auth.per_ip", 20)
+	v.SetDefault("api.rate_limit.auth.burst", 10)
+	v.SetDefault("api.rate_limit.webhooks.per_ip", 6000)
+	v.SetDefault("api.rate_limit.webhooks.burst", 1000)
+	v.SetDefault("api.rate_limit.default.per_ip", 600)
+	v.SetDefault("api.rate_limit.default.per_key", 1200)
+	v.SetDefault("api.rate_limit.default.burst", 100)
+
+	// Set defaults for API CORS and CSRF configuration.
+	v.SetDefault("api.cors.allow_credentials", false)
+	v.SetDefault("api.cors.max_age", "10m")
+	v.SetDefault("api.csrf.enabled", false)
+	v.SetDefault("api.csrf.cookie_name", "csrf_token")
+	v.SetDefault("api.csrf.cookie_secure", true)
+	v.SetDefault("api.legacy.enabled", false)
 
 	// Set defaults for storage configuration.
 	v.SetDefault("storage.type", "local")
 	v.SetDefault("storage.path", "/data/messages")
 	v.SetDefault("storage.s3_region", "us-east-1")
 
+	// Set defaults for stuck-message sweeper configuration.
+	v.SetDefault("sweeper.enabled", true)
+	v.SetDefault("sweeper.interval", "1m")
+	v.SetDefault("sweeper.queued_timeout", "30m")
+	v.SetDefault("sweeper.processing_timeout", "15m")
+	v.SetDefault("sweeper.max_requeues", 3)
+	v.SetDefault("sweeper.batch_size", 100)
+
+	// Set defaults for the delivery log archiver.
+	v.SetDefault("log_archive.enabled", false)
+	v.SetDefault("log_archive.after", "2160h") // 90 days
+	v.SetDefault("log_archive.interval", "1h")
+	v.SetDefault("log_archive.batch_size", 1000)
+	v.SetDefault("log_archive.max_batches", 10)
+
+	// Set defaults for the analytics exporter.
+	v.SetDefault("analytics_export.enabled", false)
+	v.SetDefault("analytics_export.sink", "clickhouse")
+	v.SetDefault("analytics_export.interval", "10s")
+	v.SetDefault("analytics_export.batch_size", 1000)
+	v.SetDefault("analytics_export.max_batches", 10)
+	v.SetDefault("analytics_export.lag", "30s")
+	v.SetDefault("analytics_export.backfill", false)
+	v.SetDefault("analytics_export.timeout", "10s")
+	v.SetDefault("analytics_export.clickhouse.database", "default")
+	v.SetDefault("analytics_export.clickhouse.table", "delivery_events")
+
+	// Set defaults for delivery latency SLO configuration.
+	v.SetDefault("slo.enabled", false)
+	v.SetDefault("slo.interval", "1m")
+	v.SetDefault("slo.window", "1h")
+	v.SetDefault("slo.p50_threshold", "0s")
+	v.SetDefault("slo.p95_threshold", "5m")
+	v.SetDefault("slo.p99_threshold", "15m")
+	v.SetDefault("slo.min_samples", 20)
+	v.SetDefault("slo.alert_cooldown", "30m")
+	v.SetDefault("slo.email.from", "smtp-proxy@localhost")
+
+	// Set defaults for provider health prober configuration.
+	v.SetDefault("prober.enabled", true)
+	v.SetDefault("prober.interval", "1m")
+	v.SetDefault("prober.timeout", "10s")
+	v.SetDefault("prober.failure_threshold", 3)
+	v.SetDefault("prober.history_retention", "168h") // 7 days
+
+	// Set defaults for ESP account poller configuration.
+	v.SetDefault("account_poller.enabled", true)
+	v.SetDefault("account_poller.interval", "15m")
+	v.SetDefault("account_poller.timeout", "30s")
+	v.SetDefault("account_poller.reputation.enabled", false)
+	v.SetDefault("account_poller.reputation.action", "pause")
+	v.SetDefault("account_poller.reputation.max_bounce_rate", 0.05)
+	v.SetDefault("account_poller.reputation.max_complaint_rate", 0.001)
+	v.SetDefault("account_poller.reputation.min_reputation", 80)
+	v.SetDefault("account_poller.reputation.blocklist_zones", []string{"zen.spamhaus.org"})
+
+	// Set defaults for message preview configuration.
+	v.SetDefault("preview.timeout", "30s")
+
+	// Set defaults for quota warning configuration.
+	v.SetDefault("quota_warnings.enabled", true)
+	v.SetDefault("quota_warnings.interval", "15m")
+	v.SetDefault("quota_warnings.thresholds", []int{80, 95, 100})
+	v.SetDefault("quota_warnings.from", "smtp-proxy@localhost")
+
+	// Set defaults for the password policy and SMTP credential expiry.
+	v.SetDefault("password_policy.min_length", 8)
+	v.SetDefault("password_policy.require_upper", false)
+	v.SetDefault("password_policy.require_lower", false)
+	v.SetDefault("password_policy.require_digit", false)
+	v.SetDefault("password_policy.require_symbol", false)
+	v.SetDefault("password_policy.breach_check", false)
+	v.SetDefault("password_policy.breach_api_url", "https://api.pwnedpasswords.com")
+	v.SetDefault("password_policy.breach_timeout", "5s")
+	v.SetDefault("credential_expiry.enabled", false)
+	v.SetDefault("credential_expiry.max_age", "2160h") // 90 days
+	v.SetDefault("credential_expiry.warn_before", "336h") // 14 days
+	v.SetDefault("credential_expiry.interval", "1h")
+
+	// Set defaults for system emails.
+	v.SetDefault("system_mail.enabled", false)
+	v.SetDefault("system_mail.from", "smtp-proxy@localhost")
+	v.SetDefault("system_mail.base_url", "http://localhost:8080")
+
+	// Set defaults for self-service signup.
+	v.SetDefault("signup.enabled", false)
+	v.SetDefault("signup.verification_ttl", "24h")
+	v.SetDefault("signup.monthly_limit", 200)
+	v.SetDefault("signup.recipient_validation", "reject")
+
+	// Set defaults for bulk resends of failed messages.
+	v.SetDefault("resend.rate", 10)
+	v.SetDefault("resend.max_messages", 1000)
+
+	// Set defaults for CSV delivery reports.
+	v.SetDefault("delivery_reports.enabled", true)
+	v.SetDefault("delivery_reports.interval", "30s")
+	v.SetDefault("delivery_reports.retention", "168h") // 7 days
+	v.SetDefault("delivery_reports.max_rows", 100000)
+	v.SetDefault("delivery_reports.url_ttl", "1h")
+
+	// Set defaults for runtime profiling.
+	v.SetDefault("profiling.pprof_addr", "")
+	v.SetDefault("profiling.continuous.enabled", false)
+	v.SetDefault("profiling.continuous.app_name", "smtp-proxy")
+	v.SetDefault("profiling.continuous.interval", "10s")
+
+	// Set defaults for crash reporting.
+	v.SetDefault("crash_reporting.sentry_dsn", "")
+	v.SetDefault("crash_reporting.timeout", "5s")
+
+	// Set defaults for the captured message POP3 server.
+	v.SetDefault("capture.pop3.enabled", false)
+	v.SetDefault("capture.pop3.host", "127.0.0.1")
+	v.SetDefault("capture.pop3.port", 1110)
+	v.SetDefault("capture.pop3.dir", "./mail_output")
+
+	// Set defaults for the caching DNS resolver.
+	v.SetDefault("dns.enabled", false)
+	v.SetDefault("dns.timeout", "2s")
+	v.SetDefault("dns.min_ttl", "5s")
+	v.SetDefault("dns.max_ttl", "1h")
+	v.SetDefault("dns.negative_ttl", "1m")
+	v.SetDefault("dns.max_entries", 10000)
+
+	// Set defaults for per-group message scripts.
+	v.SetDefault("scripting.enabled", true)
+	v.SetDefault("scripting.timeout", "100ms")
+	v.SetDefault("scripting.call_stack_size", 200)
+	v.SetDefault("scripting.registry_max_size", 65536)
+	v.SetDefault("scripting.max_string_size", 1<<20)
+	v.SetDefault("scripting.max_memory", 16<<20)
+
+	// Set defaults for operational alert channels.
+	v.SetDefault("alerts.timeout", "10s")
+	v.SetDefault("alerts.dlq.enabled", true)
+	v.SetDefault("alerts.dlq.threshold", 10)
+	v.SetDefault("alerts.dlq.window", "15m")
+
+	// Set defaults for certificate expiry monitoring.
+	v.SetDefault("cert_monitor.enabled", true)
+	v.SetDefault("cert_monitor.interval", "6h")
+	v.SetDefault("cert_monitor.warn_days", []int{30, 14, 7, 1})
+	v.SetDefault("cert_monitor.critical_days", 7)
+	v.SetDefault("cert_monitor.timeout", "10s")
+
 	v.SetEnvPrefix("SMTP_PROXY")
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
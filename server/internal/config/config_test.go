@@ -132,6 +132,20 @@ logging:
 	}
 }
 
+func TestLoad_DNSDefaults(t *testing.T) {
+	cfg, err := Load("../../config")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.DNS.Enabled {
+		t.Error("expected the caching resolver to be disabled by default")
+	}
+	if cfg.DNS.Timeout != 2*time.Second {
+		t.Errorf("expected dns timeout 2s, got %s", cfg.DNS.Timeout)
+	}
+}
+
 func TestLoad_MissingConfigFile(t *testing.T) {
 	_, err := Load("/nonexistent/path")
 	if err == nil {
@@ -194,3 +208,33 @@ func TestLoad_EnvironmentVariableOverrideSMTPPort(t *testing.T) {
 		t.Errorf("expected SMTP port 2525 from env override, got %d", cfg.SMTP.Port)
 	}
 }
+
+func TestLoad_DeliveryDefaults(t *testing.T) {
+	cfg, err := Load("../../config")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Delivery.Mode != "async" {
+		t.Errorf("expected delivery mode async, got %s", cfg.Delivery.Mode)
+	}
+	if cfg.Delivery.Workers != 10 {
+		t.Errorf("expected 10 delivery workers, got %d", cfg.Delivery.Workers)
+	}
+	if cfg.Delivery.Timeout != 30*time.Second {
+		t.Errorf("expected delivery timeout 30s, got %s", cfg.Delivery.Timeout)
+	}
+}
+
+func TestLoad_DeliveryModeEnvironmentVariableOverride(t *testing.T) {
+	t.Setenv("SMTP_PROXY_DELIVERY_MODE", "sync")
+
+	cfg, err := Load("../../config")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.Delivery.Mode != "sync" {
+		t.Errorf("expected delivery mode sync from env override, got %s", cfg.Delivery.Mode)
+	}
+}
@@ -0,0 +1,193 @@
+// Package cost models ESP pricing and estimates delivery spend from
+// delivered message volume.
+package cost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCurrency is used when a cost model does not specify one.
+const DefaultCurrency = "USD"
+
+// Tier is one pricing band of a Model. A tier applies to monthly volume up
+// to and including UpTo messages; an UpTo of zero marks the final,
+// unbounded tier.
+type Tier struct {
+	UpTo       int64   `json:"up_to,omitempty"`
+	PricePer1K float64 `json:"price_per_1k"`
+}
+
+// Model is a provider's pricing, stored as JSON in esp_providers.cost_model.
+// Tiers are applied to the provider's month-to-date delivered volume, so a
+// flat price is a single tier without an upper bound.
+type Model struct {
+	Currency string `json:"currency,omitempty"`
+	Tiers    []Tier `json:"tiers"`
+}
+
+// ParseModel decodes and validates a cost model. Empty or null input means
+// the provider has no cost model and returns a nil Model.
+func ParseModel(data []byte) (*Model, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decode cost model: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if m.Currency == "" {
+		m.Currency = DefaultCurrency
+	}
+	return &m, nil
+}
+
+// Validate checks that the tiers are non-empty, non-negative and ordered by
+// ascending upper bound with only the last tier left unbounded.
+func (m *Model) Validate() error {
+	if len(m.Tiers) == 0 {
+		return errors.New("cost model must have at least one tier")
+	}
+	var prev int64
+	for i, t := range m.Tiers {
+		if t.PricePer1K < 0 {
+			return fmt.Errorf("tier %d: price_per_1k must not be negative", i)
+		}
+		if t.UpTo == 0 {
+			if i != len(m.Tiers)-1 {
+				return fmt.Errorf("tier %d: only the last tier may omit up_to", i)
+			}
+			continue
+		}
+		if t.UpTo <= prev {
+			return fmt.Errorf("tier %d: up_to must be greater than the previous tier", i)
+		}
+		prev = t.UpTo
+	}
+	return nil
+}
+
+// BasePrice returns the price per 1,000 messages of the first tier.
+func (m *Model) BasePrice() float64 {
+	if m == nil || len(m.Tiers) == 0 {
+		return 0
+	}
+	return m.Tiers[0].PricePer1K
+}
+
+// Cost returns the price of sending count messages when alreadySent
+// messages have been delivered earlier in the same billing month. Volume
+// beyond the last bounded tier is charged at that tier's price.
+func (m *Model) Cost(alreadySent, count int64) float64 {
+	if m == nil || count <= 0 || len(m.Tiers) == 0 {
+		return 0
+	}
+
+	var total float64
+	pos := alreadySent
+	end := alreadySent + count
+	var lower int64
+	for i, t := range m.Tiers {
+		upper := t.UpTo
+		if upper == 0 || i == len(m.Tiers)-1 {
+			upper = end
+		}
+		if pos < upper && end > lower {
+			n := min(end, upper) - max(pos, lower)
+			total += float64(n) * t.PricePer1K / 1000
+			pos += n
+		}
+		if pos >= end {
+			break
+		}
+		lower = t.UpTo
+	}
+	return total
+}
+
+// Volume is the number of messages a provider delivered for a group on a
+// single day.
+type Volume struct {
+	Day        time.Time
+	GroupID    uuid.UUID
+	ProviderID uuid.UUID
+	Count      int64
+}
+
+// Spend is the estimated cost of a Volume.
+type Spend struct {
+	Day        time.Time
+	GroupID    uuid.UUID
+	ProviderID uuid.UUID
+	Messages   int64
+	Cost       float64
+	Currency   string
+}
+
+// Estimate prices daily volumes using each provider's cost model. Tiers are
+// positioned on the provider's month-to-date volume across all groups, so
+// volumes should start at the beginning of a month for tiered models to be
+// priced correctly. A day's cost for a provider is shared between groups in
+// proportion to their volume. Providers without a model are reported with
+// zero cost and no currency.
+func Estimate(volumes []Volume, models map[uuid.UUID]*Model) []Spend {
+	sorted := make([]Volume, len(volumes))
+	copy(sorted, volumes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Day.Before(sorted[j].Day)
+	})
+
+	type monthKey struct {
+		provider uuid.UUID
+		year     int
+		month    time.Month
+	}
+	monthToDate := make(map[monthKey]int64)
+
+	result := make([]Spend, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start
+		for end < len(sorted) && sorted[end].Day.Equal(sorted[start].Day) {
+			end++
+		}
+		day := sorted[start:end]
+
+		dayTotals := make(map[uuid.UUID]int64)
+		for _, v := range day {
+			dayTotals[v.ProviderID] += v.Count
+		}
+		dayCost := make(map[uuid.UUID]float64, len(dayTotals))
+		for providerID, total := range dayTotals {
+			key := monthKey{providerID, day[0].Day.Year(), day[0].Day.Month()}
+			dayCost[providerID] = models[providerID].Cost(monthToDate[key], total)
+			monthToDate[key] += total
+		}
+
+		for _, v := range day {
+			s := Spend{
+				Day:        v.Day,
+				GroupID:    v.GroupID,
+				ProviderID: v.ProviderID,
+				Messages:   v.Count,
+			}
+			if m := models[v.ProviderID]; m != nil {
+				s.Currency = m.Currency
+				if total := dayTotals[v.ProviderID]; total > 0 {
+					s.Cost = dayCost[v.ProviderID] * float64(v.Count) / float64(total)
+				}
+			}
+			result = append(result, s)
+		}
+		start = end
+	}
+	return result
+}
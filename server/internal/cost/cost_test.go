@@ -0,0 +1,159 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestParseModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty", input: "", wantNil: true},
+		{name: "null", input: "null", wantNil: true},
+		{name: "flat", input: `{"tiers":[{"price_per_1k":1.0}]}`},
+		{name: "tiered", input: `{"currency":"EUR","tiers":[{"up_to":1000,"price_per_1k":1.0},{"price_per_1k":0.5}]}`},
+		{name: "no tiers", input: `{"tiers":[]}`, wantErr: true},
+		{name: "negative price", input: `{"tiers":[{"price_per_1k":-1}]}`, wantErr: true},
+		{name: "unbounded not last", input: `{"tiers":[{"price_per_1k":1},{"up_to":10,"price_per_1k":1}]}`, wantErr: true},
+		{name: "descending", input: `{"tiers":[{"up_to":100,"price_per_1k":1},{"up_to":50,"price_per_1k":1}]}`, wantErr: true},
+		{name: "malformed", input: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseModel([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (m == nil) != tt.wantNil {
+				t.Fatalf("model = %v, wantNil %v", m, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestParseModel_DefaultsCurrency(t *testing.T) {
+	m, err := ParseModel([]byte(`{"tiers":[{"price_per_1k":1}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Currency != DefaultCurrency {
+		t.Errorf("Currency = %q, want %q", m.Currency, DefaultCurrency)
+	}
+}
+
+func TestModel_Cost(t *testing.T) {
+	tiered := &Model{Tiers: []Tier{
+		{UpTo: 1000, PricePer1K: 1.0},
+		{UpTo: 2000, PricePer1K: 0.5},
+		{PricePer1K: 0.1},
+	}}
+	bounded := &Model{Tiers: []Tier{
+		{UpTo: 1000, PricePer1K: 1.0},
+		{UpTo: 2000, PricePer1K: 0.5},
+	}}
+
+	tests := []struct {
+		name        string
+		model       *Model
+		alreadySent int64
+		count       int64
+		want        float64
+	}{
+		{name: "nil model", model: nil, count: 1000, want: 0},
+		{name: "zero count", model: tiered, count: 0, want: 0},
+		{name: "first tier", model: tiered, count: 500, want: 0.5},
+		{name: "spans tiers", model: tiered, count: 2500, want: 1.0 + 0.5 + 0.05},
+		{name: "starts mid tier", model: tiered, alreadySent: 1500, count: 1000, want: 0.25 + 0.05},
+		{name: "past all bounds", model: tiered, alreadySent: 5000, count: 1000, want: 0.1},
+		{name: "beyond last bounded tier", model: bounded, count: 3000, want: 1.0 + 1.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.Cost(tt.alreadySent, tt.count); !approx(got, tt.want) {
+				t.Errorf("Cost(%d, %d) = %v, want %v", tt.alreadySent, tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimate_TiersAcrossGroupsAndDays(t *testing.T) {
+	provider := uuid.New()
+	unpriced := uuid.New()
+	groupA := uuid.New()
+	groupB := uuid.New()
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	models := map[uuid.UUID]*Model{
+		provider: {Currency: "USD", Tiers: []Tier{{UpTo: 1000, PricePer1K: 1.0}, {PricePer1K: 0.5}}},
+	}
+	volumes := []Volume{
+		// Out of order on purpose; Estimate sorts by day.
+		{Day: day2, GroupID: groupA, ProviderID: provider, Count: 1000},
+		{Day: day1, GroupID: groupA, ProviderID: provider, Count: 600},
+		{Day: day1, GroupID: groupB, ProviderID: provider, Count: 200},
+		{Day: day1, GroupID: groupB, ProviderID: unpriced, Count: 50},
+	}
+
+	got := Estimate(volumes, models)
+	if len(got) != 4 {
+		t.Fatalf("len = %d, want 4", len(got))
+	}
+
+	byKey := make(map[[2]uuid.UUID]map[time.Time]Spend)
+	for _, s := range got {
+		k := [2]uuid.UUID{s.GroupID, s.ProviderID}
+		if byKey[k] == nil {
+			byKey[k] = make(map[time.Time]Spend)
+		}
+		byKey[k][s.Day] = s
+	}
+
+	// Day 1: 800 messages in the first tier, $0.80 split 600:200.
+	if s := byKey[[2]uuid.UUID{groupA, provider}][day1]; !approx(s.Cost, 0.6) {
+		t.Errorf("group A day 1 cost = %v, want 0.6", s.Cost)
+	}
+	if s := byKey[[2]uuid.UUID{groupB, provider}][day1]; !approx(s.Cost, 0.2) {
+		t.Errorf("group B day 1 cost = %v, want 0.2", s.Cost)
+	}
+	// Day 2: 200 left in the first tier, 800 in the second.
+	if s := byKey[[2]uuid.UUID{groupA, provider}][day2]; !approx(s.Cost, 0.2+0.4) {
+		t.Errorf("group A day 2 cost = %v, want 0.6", s.Cost)
+	}
+	if s := byKey[[2]uuid.UUID{groupB, unpriced}][day1]; s.Cost != 0 || s.Currency != "" {
+		t.Errorf("unpriced spend = %+v, want zero cost and no currency", s)
+	}
+}
+
+func TestEstimate_ResetsEachMonth(t *testing.T) {
+	provider := uuid.New()
+	group := uuid.New()
+	models := map[uuid.UUID]*Model{
+		provider: {Tiers: []Tier{{UpTo: 100, PricePer1K: 10}, {PricePer1K: 1}}},
+	}
+	volumes := []Volume{
+		{Day: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), GroupID: group, ProviderID: provider, Count: 100},
+		{Day: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), GroupID: group, ProviderID: provider, Count: 100},
+	}
+
+	got := Estimate(volumes, models)
+	for _, s := range got {
+		if !approx(s.Cost, 1.0) {
+			t.Errorf("%s cost = %v, want 1.0", s.Day.Format("2006-01-02"), s.Cost)
+		}
+	}
+}
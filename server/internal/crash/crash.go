@@ -0,0 +1,118 @@
+// Package crash reports panics that components recover from instead of
+// crashing the process: the SMTP session commands, the queue worker's
+// message handler and the API's HTTP handlers.
+//
+// Report logs a recovered panic with its stack trace, counts it in
+// panics_recovered_total and, when a Sentry DSN is configured, sends it to
+// Sentry. Like redact, the package is configured once at startup with
+// Configure; until then panics are only logged and counted.
+package crash
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+)
+
+// DefaultTimeout bounds each request to Sentry when Config.Timeout is zero.
+const DefaultTimeout = 5 * time.Second
+
+// Config controls crash reporting.
+type Config struct {
+	// SentryDSN is the DSN of the Sentry project receiving panics. Panics
+	// are not sent anywhere when it is empty.
+	SentryDSN string
+	// Environment and Release are attached to Sentry events.
+	Environment string
+	Release     string
+	// Service names the daemon; it is sent as the event's server name.
+	Service string
+	// Timeout bounds each request to Sentry.
+	Timeout time.Duration
+}
+
+var (
+	sentry  atomic.Pointer[sentryClient]
+	pending sync.WaitGroup
+)
+
+// Configure sets where recovered panics are sent. It fails when the
+// Sentry DSN is invalid.
+func Configure(cfg Config) error {
+	if cfg.SentryDSN == "" {
+		sentry.Store(nil)
+		return nil
+	}
+	c, err := newSentryClient(cfg)
+	if err != nil {
+		return err
+	}
+	sentry.Store(c)
+	return nil
+}
+
+// PanicError is the error a recovered panic is converted to.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Report handles value v recovered from a panic in component (api, smtp or
+// worker). It must be called from the deferred function that recovered
+// it, so the stack trace still shows where the panic happened. tags are
+// logged and sent to Sentry with the panic. The panic value is redacted
+// like other error text. Sentry events are sent in the background; Flush
+// waits for them.
+func Report(log zerolog.Logger, component string, v any, tags map[string]string) *PanicError {
+	perr := &PanicError{Value: v, Stack: debug.Stack()}
+	metrics.PanicsRecoveredTotal.WithLabelValues(component).Inc()
+
+	value := redact.Text(fmt.Sprint(v))
+	event := log.Error().
+		Str("component", component).
+		Str("panic", value).
+		Str("stack", string(perr.Stack))
+	for k, val := range tags {
+		event = event.Str(k, val)
+	}
+	event.Msg("panic recovered")
+
+	if c := sentry.Load(); c != nil {
+		ev := c.event(component, value, stackFrames(), tags)
+		pending.Add(1)
+		go func() {
+			defer pending.Done()
+			if err := c.send(ev); err != nil {
+				log.Warn().Err(err).Msg("failed to send panic to Sentry")
+			}
+		}()
+	}
+	return perr
+}
+
+// Flush waits up to timeout for panics still being sent to Sentry and
+// reports whether all of them were.
+func Flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
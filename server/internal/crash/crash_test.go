@@ -0,0 +1,103 @@
+package crash
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func recoverInto(log zerolog.Logger, out **PanicError, tags map[string]string) {
+	if v := recover(); v != nil {
+		*out = Report(log, "worker", v, tags)
+	}
+}
+
+func panicNilMap() {
+	var m map[string]int
+	m["boom"] = 1
+}
+
+func TestReport_LogsAndReturnsPanicError(t *testing.T) {
+	var buf bytes.Buffer
+	log := zerolog.New(&buf)
+
+	var perr *PanicError
+	func() {
+		defer recoverInto(log, &perr, map[string]string{"message_id": "m-1"})
+		panic("mime parser exploded")
+	}()
+
+	if perr == nil || perr.Error() != "panic: mime parser exploded" {
+		t.Fatalf("Report() = %v, want the panic as an error", perr)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log entry: %v", err)
+	}
+	if entry["panic"] != "mime parser exploded" || entry["component"] != "worker" || entry["message_id"] != "m-1" {
+		t.Errorf("unexpected log entry %v", entry)
+	}
+	if stack, _ := entry["stack"].(string); !strings.Contains(stack, "TestReport_LogsAndReturnsPanicError") {
+		t.Errorf("stack trace does not show the panicking function:\n%s", stack)
+	}
+}
+
+func TestConfigure_InvalidDSN(t *testing.T) {
+	for _, dsn := range []string{"not a url", "https://sentry.example.com/42", "https://key@sentry.example.com/", "ftp://key@host/1"} {
+		if err := Configure(Config{SentryDSN: dsn}); err == nil {
+			t.Errorf("Configure(%q) succeeded, want an error", dsn)
+		}
+	}
+}
+
+func TestReport_SendsToSentry(t *testing.T) {
+	var (
+		gotPath, gotAuth string
+		got              sentryEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "://", "://public@", 1) + "/sentry/42"
+	if err := Configure(Config{SentryDSN: dsn, Environment: "test", Service: "queue-worker"}); err != nil {
+		t.Fatalf("Configure() error: %v", err)
+	}
+	defer Configure(Config{})
+
+	var perr *PanicError
+	func() {
+		defer recoverInto(zerolog.Nop(), &perr, map[string]string{"message_id": "m-2"})
+		panicNilMap()
+	}()
+	if !Flush(5 * time.Second) {
+		t.Fatal("Flush() timed out")
+	}
+
+	if gotPath != "/sentry/api/42/store/" || !strings.Contains(gotAuth, "sentry_key=public") {
+		t.Errorf("request to %q with auth %q", gotPath, gotAuth)
+	}
+	if got.Environment != "test" || got.Tags["component"] != "worker" || got.Tags["message_id"] != "m-2" {
+		t.Errorf("unexpected event %+v", got)
+	}
+	exc := got.Exception.Values
+	if len(exc) != 1 || !strings.Contains(exc[0].Value, "nil map") {
+		t.Fatalf("unexpected exception %+v", exc)
+	}
+	frames := exc[0].Stacktrace.Frames
+	if len(frames) == 0 {
+		t.Fatal("no stack frames")
+	}
+	top := frames[len(frames)-1]
+	if top.Function != "panicNilMap" || !top.InApp || top.Filename != "crash_test.go" {
+		t.Errorf("innermost frame = %+v, want panicNilMap", top)
+	}
+}
@@ -0,0 +1,207 @@
+package crash
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// modulePrefix marks the frames of this repository as in-app in Sentry.
+const modulePrefix = "github.com/sungwon/smtp-proxy/"
+
+// sentryClient sends events to the store endpoint of a Sentry project.
+// Only what panic reports need is implemented, so the Sentry SDK is not
+// required.
+type sentryClient struct {
+	storeURL    string
+	auth        string
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+}
+
+// newSentryClient parses cfg.SentryDSN, which has the form
+// scheme://public_key@host[/path]/project_id.
+func newSentryClient(cfg Config) (*sentryClient, error) {
+	u, err := url.Parse(cfg.SentryDSN)
+	if err != nil {
+		return nil, fmt.Errorf("parse Sentry DSN: %w", err)
+	}
+	key := u.User.Username()
+	dir, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || key == "" || project == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: want scheme://public_key@host/project_id")
+	}
+
+	auth := "Sentry sentry_version=7, sentry_client=smtp-proxy/1.0, sentry_key=" + key
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	serverName := cfg.Service
+	if host, err := os.Hostname(); err == nil {
+		serverName = host
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &sentryClient{
+		storeURL:    fmt.Sprintf("%s://%s%sapi/%s/store/", u.Scheme, u.Host, dir, project),
+		auth:        auth,
+		environment: cfg.Environment,
+		release:     cfg.Release,
+		serverName:  serverName,
+		client:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Stacktrace sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+func (c *sentryClient) event(component, value string, frames []sentryFrame, tags map[string]string) sentryEvent {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	all := map[string]string{"component": component}
+	for k, v := range tags {
+		all[k] = v
+	}
+	return sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      component,
+		ServerName:  c.serverName,
+		Environment: c.environment,
+		Release:     c.release,
+		Tags:        all,
+		Exception: sentryExceptions{Values: []sentryException{{
+			Type:       "panic",
+			Value:      value,
+			Stacktrace: sentryStacktrace{Frames: frames},
+		}}},
+	}
+}
+
+func (c *sentryClient) send(ev sentryEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned %s", resp.Status)
+	}
+	return nil
+}
+
+// stackFrames returns the stack of the panicking goroutine, oldest call
+// first as Sentry expects, starting at the function that panicked. It
+// must be called by Report from the deferred function that recovered.
+func stackFrames() []sentryFrame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var out []sentryFrame
+	seenPanic, inPanic := false, false
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			// Everything so far was the recovery itself.
+			out, seenPanic, inPanic = nil, true, true
+		case inPanic && strings.HasPrefix(f.Function, "runtime."):
+			// runtime.panicmem, runtime.sigpanic and the like.
+		case f.Function == "runtime.goexit":
+		default:
+			inPanic = false
+			out = append(out, newSentryFrame(f))
+		}
+		if !more {
+			break
+		}
+	}
+	if !seenPanic && len(out) > 2 {
+		// Not called while panicking: drop stackFrames and Report.
+		out = out[2:]
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+func newSentryFrame(f runtime.Frame) sentryFrame {
+	module, function := f.Function, f.Function
+	if slash := strings.LastIndex(f.Function, "/"); slash >= 0 {
+		if dot := strings.Index(f.Function[slash:], "."); dot >= 0 {
+			module, function = f.Function[:slash+dot], f.Function[slash+dot+1:]
+		}
+	} else if dot := strings.Index(f.Function, "."); dot >= 0 {
+		module, function = f.Function[:dot], f.Function[dot+1:]
+	}
+	return sentryFrame{
+		Function: function,
+		Module:   module,
+		Filename: path.Base(f.File),
+		AbsPath:  f.File,
+		Lineno:   f.Line,
+		InApp:    strings.HasPrefix(f.Function, modulePrefix),
+	}
+}
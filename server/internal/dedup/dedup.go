@@ -0,0 +1,205 @@
+// Package dedup detects messages submitted twice within a window, such as
+// the copies an application sends when it retries a submission whose reply
+// it did not receive.
+//
+// A message is identified by a hash of its sender, recipients, headers and
+// body. Headers that differ between otherwise identical submissions, like
+// Date and Message-ID, are left out of the hash. The first message with a
+// hash is remembered in Redis for the window, so every SMTP server instance
+// shares it.
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Actions taken on a duplicate.
+const (
+	// ActionDrop accepts the duplicate without queueing it.
+	ActionDrop = "drop"
+	// ActionTag queues the duplicate with the DuplicateTag tag.
+	ActionTag = "tag"
+)
+
+// DuplicateTag is added to duplicates under ActionTag.
+const DuplicateTag = "duplicate"
+
+// Config holds deduplication configuration.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window is how long a message is remembered.
+	Window time.Duration `mapstructure:"window"`
+	// Action is ActionDrop or ActionTag.
+	Action string `mapstructure:"action"`
+}
+
+// DefaultConfig returns the default deduplication configuration.
+func DefaultConfig() Config {
+	return Config{Window: 10 * time.Minute, Action: ActionDrop}
+}
+
+// volatileHeaders are left out of the hash: they are set per submission or
+// per hop and differ between retries of the same message.
+var volatileHeaders = map[string]bool{
+	"Date":                 true,
+	"Message-Id":           true,
+	"Received":             true,
+	"Dkim-Signature":       true,
+	"X-Smtpproxy-Tag":      true,
+	"X-Smtpproxy-Metadata": true,
+}
+
+// store is the key-value storage used by the Deduper. It is satisfied by
+// redisStore in production and by an in-memory fake in tests.
+type store interface {
+	// setNX stores value under key with ttl unless the key exists. It
+	// returns the value stored under key after the call.
+	setNX(ctx context.Context, key, value string, ttl time.Duration) (string, error)
+	// replace stores value under key, if it exists, keeping its TTL.
+	replace(ctx context.Context, key, value string) error
+	del(ctx context.Context, key string) error
+}
+
+// Deduper remembers recently submitted messages.
+type Deduper struct {
+	store store
+	cfg   Config
+}
+
+// New creates a Deduper backed by the given Redis client. It returns an
+// error for an unknown action.
+func New(client *redis.Client, cfg Config) (*Deduper, error) {
+	return newDeduper(&redisStore{client: client}, cfg)
+}
+
+func newDeduper(s store, cfg Config) (*Deduper, error) {
+	def := DefaultConfig()
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.Action == "" {
+		cfg.Action = def.Action
+	}
+	if cfg.Action != ActionDrop && cfg.Action != ActionTag {
+		return nil, fmt.Errorf("dedup action %q: must be %s or %s", cfg.Action, ActionDrop, ActionTag)
+	}
+	return &Deduper{store: s, cfg: cfg}, nil
+}
+
+// Action returns the configured action for duplicates.
+func (d *Deduper) Action() string {
+	return d.cfg.Action
+}
+
+// Result is the outcome of a deduplication check.
+type Result struct {
+	// Duplicate reports whether an identical message was submitted within
+	// the window.
+	Duplicate bool
+	// OriginalID is the message ID of the first submission.
+	OriginalID string
+	// Key identifies the recorded message for Confirm and Forget.
+	Key string
+}
+
+// Check records the message of group under messageID, unless an identical
+// message was recorded within the window, in which case it is reported as a
+// duplicate of that one. Storage errors fail open: the message is not a
+// duplicate and the error is returned so the caller can log it.
+//
+// A recorded message must be confirmed once it is accepted, with the ID
+// it was stored under, or forgotten if it was not, so that the client's
+// retry is not taken for a duplicate.
+func (d *Deduper) Check(ctx context.Context, group, messageID, sender string, recipients []string, raw []byte) (Result, error) {
+	key := "dedup:" + group + ":" + Hash(sender, recipients, raw)
+	stored, err := d.store.setNX(ctx, key, messageID, d.cfg.Window)
+	if err != nil {
+		return Result{}, fmt.Errorf("record message hash: %w", err)
+	}
+	if stored == messageID {
+		return Result{Key: key}, nil
+	}
+	return Result{Duplicate: true, OriginalID: stored, Key: key}, nil
+}
+
+// Confirm replaces the ID recorded under key with the ID the message was
+// accepted as, which duplicates are then reported against.
+func (d *Deduper) Confirm(ctx context.Context, key, messageID string) error {
+	return d.store.replace(ctx, key, messageID)
+}
+
+// Forget removes the message recorded under key.
+func (d *Deduper) Forget(ctx context.Context, key string) error {
+	return d.store.del(ctx, key)
+}
+
+// Hash returns the content hash of a message. Sender and recipients are
+// compared case-insensitively and recipients in any order. When raw cannot
+// be parsed, the whole of it is hashed.
+func Hash(sender string, recipients []string, raw []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", strings.ToLower(sender))
+	rcpts := make([]string, len(recipients))
+	for i, r := range recipients {
+		rcpts[i] = strings.ToLower(r)
+	}
+	slices.Sort(rcpts)
+	fmt.Fprintf(h, "%s\n\n", strings.Join(rcpts, ","))
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		h.Write(raw)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	keys := make([]string, 0, len(msg.Header))
+	for k := range msg.Header {
+		if !volatileHeaders[k] {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		for _, v := range msg.Header[k] {
+			fmt.Fprintf(h, "%s: %s\n", k, v)
+		}
+	}
+	h.Write([]byte("\n"))
+	body := new(bytes.Buffer)
+	_, _ = body.ReadFrom(msg.Body)
+	h.Write(body.Bytes())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// redisStore implements store with Redis.
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) setNX(ctx context.Context, key, value string, ttl time.Duration) (string, error) {
+	ok, err := s.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return value, nil
+	}
+	return s.client.Get(ctx, key).Result()
+}
+
+func (s *redisStore) replace(ctx context.Context, key, value string) error {
+	return s.client.SetArgs(ctx, key, value, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+}
+
+func (s *redisStore) del(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
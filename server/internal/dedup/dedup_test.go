@@ -0,0 +1,133 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory store that ignores TTLs.
+type memStore struct {
+	data map[string]string
+	err  error
+}
+
+func (m *memStore) setNX(_ context.Context, key, value string, _ time.Duration) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if v, ok := m.data[key]; ok {
+		return v, nil
+	}
+	m.data[key] = value
+	return value, nil
+}
+
+func (m *memStore) replace(_ context.Context, key, value string) error {
+	if _, ok := m.data[key]; ok {
+		m.data[key] = value
+	}
+	return nil
+}
+
+func (m *memStore) del(_ context.Context, key string) error {
+	delete(m.data, key)
+	return nil
+}
+
+const testMessage = "From: app@example.com\r\n" +
+	"To: user@example.com\r\n" +
+	"Subject: Your receipt\r\n" +
+	"Date: Mon, 02 Mar 2026 10:00:00 +0000\r\n" +
+	"Message-ID: <1@example.com>\r\n" +
+	"\r\n" +
+	"Thanks for your order.\r\n"
+
+func TestHash(t *testing.T) {
+	base := Hash("app@example.com", []string{"a@example.com", "b@example.com"}, []byte(testMessage))
+
+	retry := "From: app@example.com\r\n" +
+		"To: user@example.com\r\n" +
+		"Subject: Your receipt\r\n" +
+		"Date: Mon, 02 Mar 2026 10:00:05 +0000\r\n" +
+		"Message-ID: <2@example.com>\r\n" +
+		"\r\n" +
+		"Thanks for your order.\r\n"
+	if got := Hash("App@Example.com", []string{"B@example.com", "a@example.com"}, []byte(retry)); got != base {
+		t.Error("expected a retry with new Date and Message-ID to hash the same")
+	}
+
+	tests := []struct {
+		name       string
+		sender     string
+		recipients []string
+		raw        string
+	}{
+		{"other sender", "other@example.com", []string{"a@example.com", "b@example.com"}, testMessage},
+		{"other recipients", "app@example.com", []string{"a@example.com"}, testMessage},
+		{"other subject", "app@example.com", []string{"a@example.com", "b@example.com"}, "Subject: Other\r\n\r\nThanks for your order.\r\n"},
+		{"other body", "app@example.com", []string{"a@example.com", "b@example.com"}, testMessage + "PS\r\n"},
+	}
+	for _, tt := range tests {
+		if Hash(tt.sender, tt.recipients, []byte(tt.raw)) == base {
+			t.Errorf("%s: expected a different hash", tt.name)
+		}
+	}
+}
+
+func TestDeduper_Check(t *testing.T) {
+	d, err := newDeduper(&memStore{data: map[string]string{}}, Config{})
+	if err != nil {
+		t.Fatalf("newDeduper: %v", err)
+	}
+	if d.Action() != ActionDrop {
+		t.Errorf("expected default action drop, got %q", d.Action())
+	}
+	ctx := context.Background()
+	rcpts := []string{"user@example.com"}
+
+	res, err := d.Check(ctx, "g1", "m1", "app@example.com", rcpts, []byte(testMessage))
+	if err != nil || res.Duplicate {
+		t.Fatalf("first submission: %+v, %v", res, err)
+	}
+	res, err = d.Check(ctx, "g1", "m2", "app@example.com", rcpts, []byte(testMessage))
+	if err != nil || !res.Duplicate || res.OriginalID != "m1" {
+		t.Fatalf("second submission: %+v, %v; want a duplicate of m1", res, err)
+	}
+	// Duplicates are reported against the confirmed ID.
+	if err := d.Confirm(ctx, res.Key, "stored-1"); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	res, _ = d.Check(ctx, "g1", "m4", "app@example.com", rcpts, []byte(testMessage))
+	if res.OriginalID != "stored-1" {
+		t.Errorf("expected a duplicate of the confirmed ID, got %+v", res)
+	}
+	// A forgotten message may be submitted again.
+	if err := d.Forget(ctx, res.Key); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	res, _ = d.Check(ctx, "g1", "m5", "app@example.com", rcpts, []byte(testMessage))
+	if res.Duplicate {
+		t.Error("expected no duplicate after Forget")
+	}
+	// Groups do not share hashes.
+	res, _ = d.Check(ctx, "g2", "m3", "app@example.com", rcpts, []byte(testMessage))
+	if res.Duplicate {
+		t.Error("expected no duplicate across groups")
+	}
+}
+
+func TestDeduper_FailOpen(t *testing.T) {
+	d, _ := newDeduper(&memStore{err: errors.New("connection refused")}, Config{Action: ActionTag})
+	res, err := d.Check(context.Background(), "g1", "m1", "app@example.com", nil, []byte(testMessage))
+	if err == nil || res.Duplicate {
+		t.Errorf("expected an error and no duplicate, got %+v, %v", res, err)
+	}
+}
+
+func TestNew_InvalidAction(t *testing.T) {
+	if _, err := newDeduper(&memStore{}, Config{Action: "bounce"}); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
@@ -29,17 +29,20 @@ func NewAsyncService(enqueuer queue.Enqueuer, log zerolog.Logger) *AsyncService
 // which fetches the full message body from the message store.
 func (a *AsyncService) DeliverMessage(ctx context.Context, req *Request) error {
 	msg := queue.NewIDOnlyMessage(req.MessageID.String(), req.GroupID.String(), req.GroupID.String())
+	msg.RequestID = req.RequestID
 
 	entryID, err := a.enqueuer.Enqueue(ctx, msg)
 	if err != nil {
 		a.log.Error().Err(err).
 			Stringer("message_id", req.MessageID).
+			Str("correlation_id", req.RequestID).
 			Msg("failed to enqueue message to Redis")
 		return fmt.Errorf("enqueue to redis: %w", err)
 	}
 
 	a.log.Info().
 		Stringer("message_id", req.MessageID).
+		Str("correlation_id", req.RequestID).
 		Str("entry_id", entryID).
 		Msg("message enqueued for async delivery")
 
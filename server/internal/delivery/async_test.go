@@ -80,6 +80,7 @@ func TestAsyncService_DeliverMessage(t *testing.T) {
 		MessageID: uuid.New(),
 		UserID:    uuid.New(),
 		GroupID:   uuid.New(),
+		RequestID: "req-789",
 	}
 
 	err := svc.DeliverMessage(context.Background(), req)
@@ -99,4 +100,7 @@ func TestAsyncService_DeliverMessage(t *testing.T) {
 	if capturedMsg.TenantID != req.GroupID.String() {
 		t.Errorf("tenant ID (group) = %q, want %q", capturedMsg.TenantID, req.GroupID.String())
 	}
+	if capturedMsg.RequestID != req.RequestID {
+		t.Errorf("request ID = %q, want %q", capturedMsg.RequestID, req.RequestID)
+	}
 }
@@ -3,8 +3,10 @@ package delivery
 import (
 	"context"
 	"database/sql"
+	"sync"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
@@ -16,6 +18,11 @@ type mockQuerier struct {
 	listProvidersFn   func(ctx context.Context, groupID uuid.UUID) ([]storage.EspProvider, error)
 	capturedStatus    storage.MessageStatus
 	capturedLogParams storage.CreateDeliveryLogParams
+
+	claimOutboxFn      func(ctx context.Context, arg storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error)
+	outboxMu           sync.Mutex // the relay publishes concurrently
+	deletedOutboxIDs   []uuid.UUID
+	failedOutboxParams []storage.RecordOutboxEntryFailureParams
 }
 
 // ActivityLog methods.
@@ -70,6 +77,12 @@ func (m *mockQuerier) CountDeliveryLogsByGroup(_ context.Context, _ storage.Coun
 func (m *mockQuerier) AverageDeliveryDuration(_ context.Context, _ storage.AverageDeliveryDurationParams) ([]storage.AverageDeliveryDurationRow, error) {
 	return nil, nil
 }
+func (m *mockQuerier) DeliveryLatencyPercentiles(_ context.Context, _ pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) DailyDeliveryVolume(_ context.Context, _ storage.DailyDeliveryVolumeParams) ([]storage.DailyDeliveryVolumeRow, error) {
+	return nil, nil
+}
 
 // Group methods.
 func (m *mockQuerier) CreateGroup(_ context.Context, _ storage.CreateGroupParams) (storage.Group, error) {
@@ -95,6 +108,15 @@ func (m *mockQuerier) UpdateGroupStatus(_ context.Context, _ storage.UpdateGroup
 func (m *mockQuerier) CountGroupOwners(_ context.Context, _ uuid.UUID) (int64, error) {
 	return 0, nil
 }
+func (m *mockQuerier) UpdateGroupHTMLProcessing(_ context.Context, _ storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+func (m *mockQuerier) ListGroupMonthlyUsage(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListGroupMonthlyUsageRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) UpdateGroupRecipientValidation(_ context.Context, _ storage.UpdateGroupRecipientValidationParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
 
 // GroupMember methods.
 func (m *mockQuerier) CreateGroupMember(_ context.Context, _ storage.CreateGroupMemberParams) (storage.GroupMember, error) {
@@ -104,6 +126,9 @@ func (m *mockQuerier) DeleteGroupMember(_ context.Context, _ uuid.UUID) error {
 func (m *mockQuerier) DeleteGroupMembersByUserID(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
+func (m *mockQuerier) DeleteGroupProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
 func (m *mockQuerier) GetGroupMemberByID(_ context.Context, _ uuid.UUID) (storage.GroupMember, error) {
 	return storage.GroupMember{}, nil
 }
@@ -116,6 +141,9 @@ func (m *mockQuerier) ListGroupMembersByGroupID(_ context.Context, _ uuid.UUID)
 func (m *mockQuerier) UpdateGroupMemberRole(_ context.Context, _ storage.UpdateGroupMemberRoleParams) (storage.GroupMember, error) {
 	return storage.GroupMember{}, nil
 }
+func (m *mockQuerier) ListGroupOwnerEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
 
 // Message methods.
 func (m *mockQuerier) EnqueueMessage(_ context.Context, _ storage.EnqueueMessageParams) (storage.Message, error) {
@@ -143,6 +171,34 @@ func (m *mockQuerier) UpdateMessageStatus(ctx context.Context, arg storage.Updat
 	}
 	return nil
 }
+func (m *mockQuerier) ListStuckMessages(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+	return nil, nil
+}
+func (m *mockQuerier) RequeueMessage(_ context.Context, _ storage.RequeueMessageParams) (int64, error) {
+	return 1, nil
+}
+func (m *mockQuerier) ExpireStuckMessage(_ context.Context, _ storage.ExpireStuckMessageParams) (int64, error) {
+	return 1, nil
+}
+func (m *mockQuerier) MonthlyMessageUsage(_ context.Context, _ storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) MonthlyProviderUsage(_ context.Context, _ storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) CountGroupMessagesSince(_ context.Context, _ storage.CountGroupMessagesSinceParams) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) CountGroupMessagesByTag(_ context.Context, _ storage.CountGroupMessagesByTagParams) ([]storage.CountGroupMessagesByTagRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListGroupDeliveryLogs(_ context.Context, _ storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMessages(_ context.Context, _ storage.ListGroupMessagesParams) ([]storage.Message, error) {
+	return nil, nil
+}
 
 // Provider methods.
 func (m *mockQuerier) CreateProvider(_ context.Context, _ storage.CreateProviderParams) (storage.EspProvider, error) {
@@ -161,6 +217,24 @@ func (m *mockQuerier) ListProvidersByGroupID(ctx context.Context, groupID uuid.U
 func (m *mockQuerier) UpdateProvider(_ context.Context, _ storage.UpdateProviderParams) (storage.EspProvider, error) {
 	return storage.EspProvider{}, nil
 }
+func (m *mockQuerier) AutoDisableProvider(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) AutoEnableProvider(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) ListProvidersForHealthCheck(_ context.Context) ([]storage.EspProvider, error) {
+	return nil, nil
+}
+func (m *mockQuerier) UpdateProviderHealth(_ context.Context, _ storage.UpdateProviderHealthParams) error {
+	return nil
+}
+func (m *mockQuerier) UpdateProviderReputationPause(_ context.Context, _ storage.UpdateProviderReputationPauseParams) error {
+	return nil
+}
+func (m *mockQuerier) ListEnabledProviders(_ context.Context) ([]storage.EspProvider, error) {
+	return nil, nil
+}
 
 // RoutingRule methods.
 func (m *mockQuerier) CreateRoutingRule(_ context.Context, _ storage.CreateRoutingRuleParams) (storage.RoutingRule, error) {
@@ -226,5 +300,567 @@ func (m *mockQuerier) UpdateUserStatus(_ context.Context, _ storage.UpdateUserSt
 	return storage.User{}, nil
 }
 
+func (m *mockQuerier) UpdateUserAutoBCC(_ context.Context, _ storage.UpdateUserAutoBCCParams) (storage.User, error) {
+	return storage.User{}, nil
+}
+
+func (m *mockQuerier) UpdateUserTLSPolicy(_ context.Context, _ storage.UpdateUserTLSPolicyParams) (storage.User, error) {
+	return storage.User{}, nil
+}
+
+func (m *mockQuerier) CreateMessageScript(_ context.Context, _ storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) GetMessageScript(_ context.Context, _ storage.GetMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) ListMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListEnabledMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateMessageScript(_ context.Context, _ storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) DeleteMessageScript(_ context.Context, _ storage.DeleteMessageScriptParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateAlertChannel(_ context.Context, _ storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+	return storage.AlertChannel{}, nil
+}
+
+func (m *mockQuerier) ListAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListEnabledAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) DeleteAlertChannel(_ context.Context, _ storage.DeleteAlertChannelParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateSenderIdentity(_ context.Context, _ storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) GetSenderIdentity(_ context.Context, _ storage.GetSenderIdentityParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) ListSenderIdentitiesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.SenderIdentity, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListVerifiedSenderIdentities(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkSenderIdentityVerified(_ context.Context, _ storage.MarkSenderIdentityVerifiedParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) DeleteSenderIdentity(_ context.Context, _ storage.DeleteSenderIdentityParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSenderPolicy(_ context.Context, _ uuid.UUID) (storage.SenderPolicy, error) {
+	return storage.SenderPolicy{}, nil
+}
+
+func (m *mockQuerier) UpsertSenderPolicy(_ context.Context, _ storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error) {
+	return storage.SenderPolicy{}, nil
+}
+
+func (m *mockQuerier) GetSendingDomain(_ context.Context, _ storage.GetSendingDomainParams) (storage.SendingDomain, error) {
+	return storage.SendingDomain{}, nil
+}
+
+func (m *mockQuerier) ListSendingDomainsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.SendingDomain, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertSendingDomain(_ context.Context, _ storage.UpsertSendingDomainParams) (storage.SendingDomain, error) {
+	return storage.SendingDomain{}, nil
+}
+
+func (m *mockQuerier) DeleteSendingDomain(_ context.Context, _ storage.DeleteSendingDomainParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSigningKey(_ context.Context, _ uuid.UUID) (storage.SigningKey, error) {
+	return storage.SigningKey{}, nil
+}
+
+func (m *mockQuerier) UpsertSigningKey(_ context.Context, _ storage.UpsertSigningKeyParams) (storage.SigningKey, error) {
+	return storage.SigningKey{}, nil
+}
+
+func (m *mockQuerier) DeleteSigningKey(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ListRecipientCertificatesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.RecipientCertificate, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListRecipientDeliveryLogs(_ context.Context, _ storage.ListRecipientDeliveryLogsParams) ([]storage.ListRecipientDeliveryLogsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) AddSuppression(_ context.Context, _ storage.AddSuppressionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSuppression(_ context.Context, _ storage.DeleteSuppressionParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) FindMessageByHeaderMessageID(_ context.Context, _ storage.FindMessageByHeaderMessageIDParams) (storage.Message, error) {
+	return storage.Message{}, nil
+}
+
+func (m *mockQuerier) IsRecipientSuppressed(_ context.Context, _ storage.IsRecipientSuppressedParams) (bool, error) {
+	return false, nil
+}
+
+func (m *mockQuerier) ListSuppressions(_ context.Context, _ storage.ListSuppressionsParams) ([]storage.Suppression, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertRecipientCertificate(_ context.Context, _ storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error) {
+	return storage.RecipientCertificate{}, nil
+}
+
+func (m *mockQuerier) DeleteRecipientCertificate(_ context.Context, _ storage.DeleteRecipientCertificateParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateSmtpClientCert(_ context.Context, _ storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) ListSmtpClientCertsByUserID(_ context.Context, _ uuid.UUID) ([]storage.SmtpClientCert, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertByFingerprint(_ context.Context, _ pgtype.Text) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertBySAN(_ context.Context, _ []string) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) TouchSmtpClientCert(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSmtpClientCert(_ context.Context, _ storage.DeleteSmtpClientCertParams) (int64, error) {
+	return 0, nil
+}
+
+// Outbox methods.
+func (m *mockQuerier) ClaimOutboxEntries(ctx context.Context, arg storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+	if m.claimOutboxFn != nil {
+		return m.claimOutboxFn(ctx, arg)
+	}
+	return nil, nil
+}
+func (m *mockQuerier) CountOutboxEntries(_ context.Context) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) CreateOutboxEntry(_ context.Context, _ storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+	return storage.OutboxEntry{}, nil
+}
+func (m *mockQuerier) DeleteOutboxEntry(_ context.Context, id uuid.UUID) error {
+	m.outboxMu.Lock()
+	defer m.outboxMu.Unlock()
+	m.deletedOutboxIDs = append(m.deletedOutboxIDs, id)
+	return nil
+}
+func (m *mockQuerier) RecordOutboxEntryFailure(_ context.Context, arg storage.RecordOutboxEntryFailureParams) error {
+	m.outboxMu.Lock()
+	defer m.outboxMu.Unlock()
+	m.failedOutboxParams = append(m.failedOutboxParams, arg)
+	return nil
+}
+
+// ProviderHealthCheck methods.
+func (m *mockQuerier) CreateProviderHealthCheck(_ context.Context, _ storage.CreateProviderHealthCheckParams) (storage.ProviderHealthCheck, error) {
+	return storage.ProviderHealthCheck{}, nil
+}
+func (m *mockQuerier) DeleteProviderHealthChecksBefore(_ context.Context, _ pgtype.Timestamptz) error {
+	return nil
+}
+func (m *mockQuerier) ListProviderHealthChecks(_ context.Context, _ storage.ListProviderHealthChecksParams) ([]storage.ProviderHealthCheck, error) {
+	return nil, nil
+}
+
+// ProviderAccountStats methods.
+func (m *mockQuerier) ListProviderAccountStatsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.ProviderAccountStat, error) {
+	return nil, nil
+}
+func (m *mockQuerier) RecordProviderAccountStatsError(_ context.Context, _ storage.RecordProviderAccountStatsErrorParams) error {
+	return nil
+}
+func (m *mockQuerier) UpsertProviderAccountStats(_ context.Context, _ storage.UpsertProviderAccountStatsParams) (storage.ProviderAccountStat, error) {
+	return storage.ProviderAccountStat{}, nil
+}
+
+// QuotaNotification methods.
+func (m *mockQuerier) RecordQuotaNotification(_ context.Context, _ storage.RecordQuotaNotificationParams) (int64, error) {
+	return 0, nil
+}
+
+// Send window methods.
+func (m *mockQuerier) GetSendWindow(_ context.Context, _ uuid.UUID) (storage.SendWindow, error) {
+	return storage.SendWindow{}, nil
+}
+
+func (m *mockQuerier) UpsertSendWindow(_ context.Context, _ storage.UpsertSendWindowParams) (storage.SendWindow, error) {
+	return storage.SendWindow{}, nil
+}
+
+func (m *mockQuerier) DeleteSendWindow(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+// Message defaults methods.
+func (m *mockQuerier) GetMessageDefaults(_ context.Context, _ uuid.UUID) (storage.MessageDefault, error) {
+	return storage.MessageDefault{}, nil
+}
+
+func (m *mockQuerier) UpsertMessageDefaults(_ context.Context, _ storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error) {
+	return storage.MessageDefault{}, nil
+}
+
+func (m *mockQuerier) DeleteMessageDefaults(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ListProviderDailySendsByGroupID(_ context.Context, _ storage.ListProviderDailySendsByGroupIDParams) ([]storage.ProviderDailySend, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ReserveProviderDailySend(_ context.Context, _ storage.ReserveProviderDailySendParams) (int32, error) {
+	return 1, nil
+}
+
+// Shadow policy methods.
+func (m *mockQuerier) ListShadowPolicies(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) DeleteShadowPolicies(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) AddShadowPolicy(_ context.Context, _ storage.AddShadowPolicyParams) error {
+	return nil
+}
+
+func (m *mockQuerier) RecordShadowRejection(_ context.Context, _ storage.RecordShadowRejectionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) SummarizeShadowRejections(_ context.Context, _ storage.SummarizeShadowRejectionsParams) ([]storage.SummarizeShadowRejectionsRow, error) {
+	return nil, nil
+}
+
+// Groups methods.
+func (m *mockQuerier) ListGroupAncestors(_ context.Context, _ uuid.UUID) ([]storage.Group, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSubGroups(_ context.Context, _ pgtype.UUID) ([]storage.Group, error) {
+	return nil, nil
+}
+
+// Compliance methods.
+func (m *mockQuerier) EraseGroupMessages(_ context.Context, _ pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) ExportGroupActivityLogs(_ context.Context, _ uuid.UUID) ([]storage.ActivityLog, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ExportGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ExportGroupMessages(_ context.Context, _ pgtype.UUID) ([]storage.ExportGroupMessagesRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListGroupMessageStorageRefs(_ context.Context, _ pgtype.UUID) ([]pgtype.Text, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ScrubGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+
+// SMTP debug methods.
+func (m *mockQuerier) CreateSMTPDebugTarget(_ context.Context, _ storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error) {
+	return storage.SmtpDebugTarget{}, nil
+}
+func (m *mockQuerier) CreateSMTPTranscript(_ context.Context, _ storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+	return storage.SmtpTranscript{}, nil
+}
+func (m *mockQuerier) DeleteSMTPDebugTarget(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) GetSMTPDebugTarget(_ context.Context, _ uuid.UUID) (storage.SmtpDebugTarget, error) {
+	return storage.SmtpDebugTarget{}, nil
+}
+func (m *mockQuerier) ListActiveSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSMTPDebugTargetsByGroupID(_ context.Context, _ pgtype.UUID) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSMTPTranscriptsByTarget(_ context.Context, _ uuid.UUID) ([]storage.SmtpTranscript, error) {
+	return nil, nil
+}
+
+// Inbound routes methods.
+func (m *mockQuerier) CreateInboundRoute(_ context.Context, _ storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+func (m *mockQuerier) DeleteInboundRoute(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) GetInboundRouteByDomain(_ context.Context, _ string) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+func (m *mockQuerier) GetInboundRouteByID(_ context.Context, _ uuid.UUID) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+func (m *mockQuerier) ListInboundRoutesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.InboundRoute, error) {
+	return nil, nil
+}
+func (m *mockQuerier) UpdateInboundRoute(_ context.Context, _ storage.UpdateInboundRouteParams) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+
 // Ensure mockQuerier satisfies the Querier interface at compile time.
 var _ storage.Querier = (*mockQuerier)(nil)
+
+func (m *mockQuerier) CreateProviderCapture(_ context.Context, _ storage.CreateProviderCaptureParams) (storage.ProviderCapture, error) {
+	return storage.ProviderCapture{}, nil
+}
+
+func (m *mockQuerier) ListProviderCaptures(_ context.Context, _ uuid.UUID) ([]storage.ProviderCapture, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) PruneProviderCaptures(_ context.Context, _ storage.PruneProviderCapturesParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDeliveryLogArchive(_ context.Context, _ storage.CreateDeliveryLogArchiveParams) (storage.DeliveryLogArchive, error) {
+	return storage.DeliveryLogArchive{}, nil
+}
+
+func (m *mockQuerier) DeleteArchivedDeliveryLogs(_ context.Context, _ []uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryLogArchive(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsToArchive(_ context.Context, _ storage.ListDeliveryLogsToArchiveParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryLogArchives(_ context.Context, _ storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetAnalyticsExportCursor(_ context.Context, _ string) (storage.AnalyticsExportCursor, error) {
+	return storage.AnalyticsExportCursor{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsForExport(_ context.Context, _ storage.ListDeliveryLogsForExportParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertAnalyticsExportCursor(_ context.Context, _ storage.UpsertAnalyticsExportCursorParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeliveryTimeSeries(_ context.Context, _ storage.DeliveryTimeSeriesParams) ([]storage.DeliveryTimeSeriesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListMessagesSentSince(_ context.Context, _ storage.ListMessagesSentSinceParams) ([]storage.ListMessagesSentSinceRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) PauseQueuedMessages(_ context.Context, _ storage.PauseQueuedMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ResumePausedMessages(_ context.Context, _ storage.ResumePausedMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) RevokeUserAPIKey(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteUserSession(_ context.Context, _ storage.DeleteUserSessionParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) TouchSession(_ context.Context, _ storage.TouchSessionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) ListExpiringSMTPPasswords(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListExpiringSMTPPasswordsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkPasswordExpiryWarned(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListLoginNetworks(_ context.Context, _ uuid.UUID) ([]storage.LoginNetwork, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RecordLoginNetwork(_ context.Context, _ storage.RecordLoginNetworkParams) error {
+	return nil
+}
+
+func (m *mockQuerier) AcceptInvitation(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateInvitation(_ context.Context, _ storage.CreateInvitationParams) (storage.Invitation, error) {
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) DeleteInvitation(_ context.Context, _ storage.DeleteInvitationParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetInvitationByID(_ context.Context, _ uuid.UUID) (storage.Invitation, error) {
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) ListPendingInvitationsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.Invitation, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) CreateSignup(_ context.Context, _ storage.CreateSignupParams) (storage.Signup, error) {
+	return storage.Signup{}, nil
+}
+
+func (m *mockQuerier) DeleteExpiredSignups(_ context.Context) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSignup(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSignupByID(_ context.Context, _ uuid.UUID) (storage.Signup, error) {
+	return storage.Signup{}, nil
+}
+
+func (m *mockQuerier) ListVerifiedGroupMemberEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateGroupSandbox(_ context.Context, _ storage.UpdateGroupSandboxParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) MarkEmailVerified(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteGroupBranding(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetGroupBranding(_ context.Context, _ uuid.UUID) (storage.GroupBranding, error) {
+	return storage.GroupBranding{}, nil
+}
+
+func (m *mockQuerier) UpsertGroupBranding(_ context.Context, _ storage.UpsertGroupBrandingParams) (storage.GroupBranding, error) {
+	return storage.GroupBranding{}, nil
+}
+
+func (m *mockQuerier) UpdateGroupLocale(_ context.Context, _ storage.UpdateGroupLocaleParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) CountResendableMessages(_ context.Context, _ storage.CountResendableMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDelayedOutboxEntry(_ context.Context, _ storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+	return storage.OutboxEntry{}, nil
+}
+
+func (m *mockQuerier) ListResendableMessages(_ context.Context, _ storage.ListResendableMessagesParams) ([]storage.ListResendableMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ResendMessage(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ClaimDeliveryReport(_ context.Context, _ int32) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) CompleteDeliveryReport(_ context.Context, _ storage.CompleteDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) CreateDeliveryReport(_ context.Context, _ storage.CreateDeliveryReportParams) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryReport(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) FailDeliveryReport(_ context.Context, _ storage.FailDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) GetDeliveryReport(_ context.Context, _ uuid.UUID) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsByMessageIDs(_ context.Context, _ []uuid.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListDeliveryReportMessages(_ context.Context, _ storage.ListDeliveryReportMessagesParams) ([]storage.ListDeliveryReportMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListExpiredDeliveryReports(_ context.Context, _ storage.ListExpiredDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryReports(_ context.Context, _ storage.ListGroupDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
+}
@@ -0,0 +1,202 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// OutboxRelayConfig controls how often the relay polls for pending outbox
+// entries and how many it claims per poll.
+type OutboxRelayConfig struct {
+	// PollInterval is the delay between polls when the outbox is drained.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of entries claimed per poll.
+	BatchSize int
+	// ClaimLease is how long a claimed entry is hidden from other relays
+	// before it becomes eligible for another publish attempt.
+	ClaimLease time.Duration
+	// Concurrency is the number of entries of a batch published at once.
+	// It matters when the Service delivers synchronously.
+	Concurrency int
+}
+
+// DefaultOutboxRelayConfig returns sensible defaults for the outbox relay.
+func DefaultOutboxRelayConfig() OutboxRelayConfig {
+	return OutboxRelayConfig{
+		PollInterval: 1 * time.Second,
+		BatchSize:    100,
+		ClaimLease:   30 * time.Second,
+		Concurrency:  1,
+	}
+}
+
+// OutboxRelay publishes outbox entries written by the SMTP server to the
+// delivery Service. Entries are deleted once published; failed publishes
+// are retried on a later poll, so delivery to the queue is at-least-once.
+type OutboxRelay struct {
+	queries storage.Querier
+	svc     Service
+	config  OutboxRelayConfig
+	log     zerolog.Logger
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+// NewOutboxRelay creates an OutboxRelay that claims entries through queries
+// and publishes them via svc.
+func NewOutboxRelay(queries storage.Querier, svc Service, cfg OutboxRelayConfig, log zerolog.Logger) *OutboxRelay {
+	defaults := DefaultOutboxRelayConfig()
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.ClaimLease <= 0 {
+		cfg.ClaimLease = defaults.ClaimLease
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaults.Concurrency
+	}
+	return &OutboxRelay{
+		queries: queries,
+		svc:     svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Start launches the relay loop in a background goroutine.
+func (r *OutboxRelay) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(1)
+	go r.run(ctx)
+
+	r.log.Info().
+		Dur("poll_interval", r.config.PollInterval).
+		Int("batch_size", r.config.BatchSize).
+		Int("concurrency", r.config.Concurrency).
+		Msg("outbox relay started")
+}
+
+// Stop signals the relay loop to exit and waits for the in-flight batch
+// to finish.
+func (r *OutboxRelay) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	r.log.Info().Msg("outbox relay stopped")
+}
+
+func (r *OutboxRelay) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Error().Err(err).Msg("outbox relay poll failed")
+		}
+
+		// Keep draining without delay while full batches are returned.
+		if err == nil && n >= r.config.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.config.PollInterval):
+		}
+	}
+}
+
+// RelayOnce claims one batch of pending entries and publishes them. It
+// returns the number of entries claimed.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	entries, err := r.queries.ClaimOutboxEntries(ctx, storage.ClaimOutboxEntriesParams{
+		LeaseSeconds: int32(r.config.ClaimLease / time.Second),
+		BatchSize:    int32(r.config.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sem := make(chan struct{}, r.config.Concurrency)
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.publish(ctx, entry)
+		}()
+	}
+	wg.Wait()
+
+	if count, err := r.queries.CountOutboxEntries(ctx); err == nil {
+		metrics.OutboxPending.Set(float64(count))
+	}
+
+	return len(entries), nil
+}
+
+// publish hands a single entry to the delivery service and removes it from
+// the outbox on success. Failures are recorded on the entry so the next
+// poll retries it, except final delivery failures (ErrDeliveryFailed),
+// whose entries are removed: the message is already marked failed.
+func (r *OutboxRelay) publish(ctx context.Context, entry storage.OutboxEntry) {
+	err := r.svc.DeliverMessage(ctx, &Request{
+		MessageID: entry.MessageID,
+		UserID:    entry.UserID,
+		GroupID:   entry.GroupID,
+		RequestID: entry.RequestID.String,
+	})
+	if err != nil && !errors.Is(err, ErrDeliveryFailed) {
+		metrics.OutboxPublishedTotal.WithLabelValues("failure").Inc()
+		r.log.Warn().Err(err).
+			Stringer("message_id", entry.MessageID).
+			Str("correlation_id", entry.RequestID.String).
+			Int32("attempts", entry.Attempts+1).
+			Msg("outbox publish failed, will retry")
+
+		if err := r.queries.RecordOutboxEntryFailure(ctx, storage.RecordOutboxEntryFailureParams{
+			ID:        entry.ID,
+			LastError: pgtype.Text{String: err.Error(), Valid: true},
+		}); err != nil {
+			r.log.Error().Err(err).
+				Stringer("outbox_id", entry.ID).
+				Msg("failed to record outbox publish failure")
+		}
+		return
+	}
+
+	if err != nil {
+		metrics.OutboxPublishedTotal.WithLabelValues("failure").Inc()
+		r.log.Warn().Err(err).
+			Stringer("message_id", entry.MessageID).
+			Str("correlation_id", entry.RequestID.String).
+			Msg("delivery failed, removing outbox entry")
+	} else {
+		metrics.OutboxPublishedTotal.WithLabelValues("success").Inc()
+	}
+
+	if err := r.queries.DeleteOutboxEntry(ctx, entry.ID); err != nil {
+		// The entry will be republished after its lease expires, which can
+		// enqueue the same message twice.
+		r.log.Error().Err(err).
+			Stringer("outbox_id", entry.ID).
+			Stringer("message_id", entry.MessageID).
+			Msg("failed to delete published outbox entry")
+	}
+}
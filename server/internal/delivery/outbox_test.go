@@ -0,0 +1,230 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockService implements Service for testing.
+type mockService struct {
+	deliverFn func(ctx context.Context, req *Request) error
+	requests  []*Request
+}
+
+func (m *mockService) DeliverMessage(ctx context.Context, req *Request) error {
+	m.requests = append(m.requests, req)
+	if m.deliverFn != nil {
+		return m.deliverFn(ctx, req)
+	}
+	return nil
+}
+
+func TestNewOutboxRelay_AppliesDefaults(t *testing.T) {
+	r := NewOutboxRelay(&mockQuerier{}, &mockService{}, OutboxRelayConfig{}, zerolog.Nop())
+
+	defaults := DefaultOutboxRelayConfig()
+	if r.config != defaults {
+		t.Errorf("expected defaults %+v, got %+v", defaults, r.config)
+	}
+}
+
+func TestOutboxRelay_RelayOnce_PublishesAndDeletes(t *testing.T) {
+	entry := storage.OutboxEntry{
+		ID:        uuid.New(),
+		MessageID: uuid.New(),
+		GroupID:   uuid.New(),
+		UserID:    uuid.New(),
+		RequestID: pgtype.Text{String: "req-123", Valid: true},
+	}
+
+	var capturedClaim storage.ClaimOutboxEntriesParams
+	q := &mockQuerier{
+		claimOutboxFn: func(_ context.Context, arg storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+			capturedClaim = arg
+			return []storage.OutboxEntry{entry}, nil
+		},
+	}
+	svc := &mockService{}
+
+	r := NewOutboxRelay(q, svc, OutboxRelayConfig{BatchSize: 50, ClaimLease: 10 * time.Second}, zerolog.Nop())
+
+	n, err := r.RelayOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 entry relayed, got %d", n)
+	}
+	if capturedClaim.BatchSize != 50 || capturedClaim.LeaseSeconds != 10 {
+		t.Errorf("unexpected claim params: %+v", capturedClaim)
+	}
+
+	if len(svc.requests) != 1 {
+		t.Fatalf("expected 1 delivery request, got %d", len(svc.requests))
+	}
+	req := svc.requests[0]
+	if req.MessageID != entry.MessageID || req.GroupID != entry.GroupID || req.UserID != entry.UserID || req.RequestID != "req-123" {
+		t.Errorf("request does not match outbox entry: %+v", req)
+	}
+
+	if len(q.deletedOutboxIDs) != 1 || q.deletedOutboxIDs[0] != entry.ID {
+		t.Errorf("expected outbox entry %s to be deleted, got %v", entry.ID, q.deletedOutboxIDs)
+	}
+	if len(q.failedOutboxParams) != 0 {
+		t.Errorf("expected no failures recorded, got %d", len(q.failedOutboxParams))
+	}
+}
+
+func TestOutboxRelay_RelayOnce_PublishFailureRecorded(t *testing.T) {
+	entry := storage.OutboxEntry{ID: uuid.New(), MessageID: uuid.New()}
+	q := &mockQuerier{
+		claimOutboxFn: func(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+			return []storage.OutboxEntry{entry}, nil
+		},
+	}
+	svc := &mockService{
+		deliverFn: func(_ context.Context, _ *Request) error {
+			return errors.New("redis connection refused")
+		},
+	}
+
+	r := NewOutboxRelay(q, svc, OutboxRelayConfig{}, zerolog.Nop())
+
+	if _, err := r.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(q.deletedOutboxIDs) != 0 {
+		t.Errorf("expected entry to remain in outbox, got deletes %v", q.deletedOutboxIDs)
+	}
+	if len(q.failedOutboxParams) != 1 {
+		t.Fatalf("expected 1 failure recorded, got %d", len(q.failedOutboxParams))
+	}
+	failure := q.failedOutboxParams[0]
+	if failure.ID != entry.ID {
+		t.Errorf("expected failure for %s, got %s", entry.ID, failure.ID)
+	}
+	if failure.LastError.String != "redis connection refused" {
+		t.Errorf("unexpected last_error: %q", failure.LastError.String)
+	}
+}
+
+func TestOutboxRelay_RelayOnce_FinalFailureRemoved(t *testing.T) {
+	entry := storage.OutboxEntry{ID: uuid.New(), MessageID: uuid.New()}
+	q := &mockQuerier{
+		claimOutboxFn: func(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+			return []storage.OutboxEntry{entry}, nil
+		},
+	}
+	svc := &mockService{
+		deliverFn: func(_ context.Context, _ *Request) error {
+			return fmt.Errorf("%w: provider send: bad request", ErrDeliveryFailed)
+		},
+	}
+
+	r := NewOutboxRelay(q, svc, OutboxRelayConfig{}, zerolog.Nop())
+
+	if _, err := r.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(q.failedOutboxParams) != 0 {
+		t.Errorf("expected no retry to be recorded, got %d", len(q.failedOutboxParams))
+	}
+	if len(q.deletedOutboxIDs) != 1 || q.deletedOutboxIDs[0] != entry.ID {
+		t.Errorf("expected outbox entry %s to be deleted, got %v", entry.ID, q.deletedOutboxIDs)
+	}
+}
+
+func TestOutboxRelay_RelayOnce_Concurrency(t *testing.T) {
+	entries := make([]storage.OutboxEntry, 6)
+	for i := range entries {
+		entries[i] = storage.OutboxEntry{ID: uuid.New(), MessageID: uuid.New()}
+	}
+	q := &mockQuerier{
+		claimOutboxFn: func(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+			return entries, nil
+		},
+	}
+
+	var inFlight, peak atomic.Int32
+	svc := serviceFunc(func(_ context.Context, _ *Request) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	r := NewOutboxRelay(q, svc, OutboxRelayConfig{Concurrency: 3}, zerolog.Nop())
+
+	if _, err := r.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := peak.Load(); got < 2 || got > 3 {
+		t.Errorf("expected 2-3 concurrent deliveries, got %d", got)
+	}
+	if len(q.deletedOutboxIDs) != len(entries) {
+		t.Errorf("expected %d entries deleted, got %d", len(entries), len(q.deletedOutboxIDs))
+	}
+}
+
+// serviceFunc adapts a function to Service; unlike mockService it is safe
+// for concurrent use.
+type serviceFunc func(ctx context.Context, req *Request) error
+
+func (f serviceFunc) DeliverMessage(ctx context.Context, req *Request) error {
+	return f(ctx, req)
+}
+
+func TestOutboxRelay_RelayOnce_ClaimError(t *testing.T) {
+	q := &mockQuerier{
+		claimOutboxFn: func(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+			return nil, errors.New("database unavailable")
+		},
+	}
+	svc := &mockService{}
+
+	r := NewOutboxRelay(q, svc, OutboxRelayConfig{}, zerolog.Nop())
+
+	if _, err := r.RelayOnce(context.Background()); err == nil {
+		t.Fatal("expected error when claim fails")
+	}
+	if len(svc.requests) != 0 {
+		t.Errorf("expected no delivery requests, got %d", len(svc.requests))
+	}
+}
+
+func TestOutboxRelay_StartStop(t *testing.T) {
+	r := NewOutboxRelay(&mockQuerier{}, &mockService{}, OutboxRelayConfig{PollInterval: 5 * time.Millisecond}, zerolog.Nop())
+
+	r.Start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		r.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("relay did not stop in time")
+	}
+}
@@ -2,17 +2,24 @@ package delivery
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 )
 
 // Service delivers email messages after they have been persisted to the database.
-// The sole implementation is AsyncService, which enqueues ID-only references to
-// Redis Streams for background worker delivery.
+// AsyncService enqueues ID-only references to Redis Streams for background
+// worker delivery; SyncService delivers immediately, for sync delivery mode
+// and SMTP pass-through mode.
 type Service interface {
 	DeliverMessage(ctx context.Context, req *Request) error
 }
 
+// ErrDeliveryFailed is wrapped by errors from a Service that attempted the
+// delivery and marked the message failed. The failure is final: the request
+// must not be retried.
+var ErrDeliveryFailed = errors.New("delivery failed")
+
 // Request contains the minimal data needed to enqueue a message for delivery.
 // The worker process fetches the full message body from the message store using
 // the MessageID.
@@ -20,4 +27,7 @@ type Request struct {
 	MessageID uuid.UUID
 	UserID    uuid.UUID
 	GroupID   uuid.UUID
+	// RequestID is the ID of the SMTP session or API request that
+	// submitted the message, carried through the queue to the worker.
+	RequestID string
 }
@@ -0,0 +1,75 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// SyncService delivers messages immediately, in the caller's goroutine,
+// with the same handler the queue worker uses. It backs SMTP pass-through
+// mode, where the DATA reply waits for the provider's answer.
+type SyncService struct {
+	handler queue.MessageHandler
+	queries storage.Querier
+	timeout time.Duration
+	log     zerolog.Logger
+}
+
+// NewSyncService creates a SyncService that delivers through handler,
+// giving each delivery at most timeout (no limit when zero).
+func NewSyncService(handler queue.MessageHandler, queries storage.Querier, timeout time.Duration, log zerolog.Logger) *SyncService {
+	return &SyncService{
+		handler: handler,
+		queries: queries,
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+// DeliverMessage delivers the message and returns the handler's error,
+// which wraps the provider's error, together with ErrDeliveryFailed.
+// Failures are final: there is no retry, and the message is marked failed
+// even when the delivery was cut short by the timeout, so the stuck-message
+// sweeper does not later deliver a message the client was told had failed.
+func (s *SyncService) DeliverMessage(ctx context.Context, req *Request) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	msg := queue.NewIDOnlyMessage(req.MessageID.String(), req.GroupID.String(), req.GroupID.String())
+	msg.RequestID = req.RequestID
+
+	start := time.Now()
+	if err := s.handler.HandleMessage(ctx, msg); err != nil {
+		if statusErr := s.queries.UpdateMessageStatus(context.WithoutCancel(ctx), storage.UpdateMessageStatusParams{
+			ID:     req.MessageID,
+			Status: storage.MessageStatusFailed,
+		}); statusErr != nil {
+			s.log.Error().Err(statusErr).
+				Stringer("message_id", req.MessageID).
+				Str("correlation_id", req.RequestID).
+				Msg("failed to set failed status")
+		}
+		s.log.Warn().Err(err).
+			Stringer("message_id", req.MessageID).
+			Str("correlation_id", req.RequestID).
+			Dur("duration", time.Since(start)).
+			Msg("synchronous delivery failed")
+		return fmt.Errorf("%w: %w", ErrDeliveryFailed, err)
+	}
+
+	s.log.Info().
+		Stringer("message_id", req.MessageID).
+		Str("correlation_id", req.RequestID).
+		Dur("duration", time.Since(start)).
+		Msg("message delivered synchronously")
+	return nil
+}
@@ -0,0 +1,85 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// handlerFunc adapts a function to queue.MessageHandler.
+type handlerFunc func(ctx context.Context, msg *queue.Message) error
+
+func (f handlerFunc) HandleMessage(ctx context.Context, msg *queue.Message) error {
+	return f(ctx, msg)
+}
+
+func TestSyncService_ImplementsInterface(t *testing.T) {
+	var _ Service = (*SyncService)(nil)
+}
+
+func TestSyncService_DeliverMessage(t *testing.T) {
+	var captured *queue.Message
+	var hasDeadline bool
+	handler := handlerFunc(func(ctx context.Context, msg *queue.Message) error {
+		captured = msg
+		_, hasDeadline = ctx.Deadline()
+		return nil
+	})
+	q := &mockQuerier{}
+	svc := NewSyncService(handler, q, time.Minute, zerolog.Nop())
+
+	req := &Request{MessageID: uuid.New(), GroupID: uuid.New(), RequestID: "req-1"}
+	if err := svc.DeliverMessage(context.Background(), req); err != nil {
+		t.Fatalf("DeliverMessage() error: %v", err)
+	}
+
+	if captured == nil || captured.ID != req.MessageID.String() || captured.TenantID != req.GroupID.String() {
+		t.Fatalf("handler got %+v, want an ID-only message for %s", captured, req.MessageID)
+	}
+	if captured.RequestID != "req-1" {
+		t.Errorf("request ID = %q, want req-1", captured.RequestID)
+	}
+	if !hasDeadline {
+		t.Error("expected the delivery context to carry the timeout")
+	}
+	if q.capturedStatus != "" {
+		t.Errorf("status set to %q on success, want the handler's status kept", q.capturedStatus)
+	}
+}
+
+func TestSyncService_DeliverMessage_FailureMarksFailed(t *testing.T) {
+	sendErr := errors.New("provider unavailable")
+	handler := handlerFunc(func(ctx context.Context, _ *queue.Message) error {
+		<-ctx.Done()
+		return sendErr
+	})
+	var statusCtxErr error
+	q := &mockQuerier{
+		updateStatusFn: func(ctx context.Context, _ storage.UpdateMessageStatusParams) error {
+			statusCtxErr = ctx.Err()
+			return nil
+		},
+	}
+	svc := NewSyncService(handler, q, 10*time.Millisecond, zerolog.Nop())
+
+	err := svc.DeliverMessage(context.Background(), &Request{MessageID: uuid.New(), GroupID: uuid.New()})
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("DeliverMessage() error = %v, want it to wrap %v", err, sendErr)
+	}
+	if !errors.Is(err, ErrDeliveryFailed) {
+		t.Errorf("DeliverMessage() error = %v, want it to wrap ErrDeliveryFailed", err)
+	}
+	if q.capturedStatus != storage.MessageStatusFailed {
+		t.Errorf("status = %q, want failed", q.capturedStatus)
+	}
+	if statusCtxErr != nil {
+		t.Errorf("status update ran with a done context: %v", statusCtxErr)
+	}
+}
@@ -0,0 +1,177 @@
+// Package deliveryreport builds CSV reports of per-recipient delivery
+// outcomes for the messages of one batch, identified by a shared tag
+// (X-SMTPProxy-Tag), within a time range.
+//
+// Reports are requested through the API and generated asynchronously by
+// the queue worker's Generator, which writes the CSV to the message store.
+// The API hands out signed download URLs for ready reports. Each recipient
+// gets one row: its outcome is the latest delivery log recorded for that
+// recipient by a provider event (bounce, complaint, delivery), falling back
+// to the message's latest delivery attempt and then to the message status.
+package deliveryreport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Report statuses. A report is pending until a generator claims it, then
+// running until it is ready or failed.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// Header is the first row of every report.
+var Header = []string{
+	"message_id",
+	"request_id",
+	"enqueued_at",
+	"recipient",
+	"status",
+	"provider",
+	"provider_message_id",
+	"response_code",
+	"error",
+	"updated_at",
+}
+
+// ObjectKey returns the message store key of a report's CSV file. Keys are
+// flat so the local store can hold them next to message bodies.
+func ObjectKey(id uuid.UUID) string {
+	return "delivery-report-" + id.String() + ".csv"
+}
+
+// Writer writes report rows as CSV.
+type Writer struct {
+	w    *csv.Writer
+	rows int
+}
+
+// NewWriter returns a Writer that has written the header row to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: cw}, nil
+}
+
+// Rows returns the number of recipient rows written.
+func (w *Writer) Rows() int {
+	return w.rows
+}
+
+// WriteMessage writes one row per recipient of m, given the message's
+// delivery logs oldest first. It stops after limit rows in total and
+// reports whether every recipient was written.
+func (w *Writer) WriteMessage(m storage.ListDeliveryReportMessagesRow, logs []storage.DeliveryLog, limit int) (bool, error) {
+	var recipients []string
+	if err := json.Unmarshal(m.Recipients, &recipients); err != nil {
+		return false, err
+	}
+
+	// The latest attempt is the newest log not tied to one recipient;
+	// provider events name the recipient they are about in metadata.
+	var attempt *storage.DeliveryLog
+	events := make(map[string]*storage.DeliveryLog)
+	for i := range logs {
+		if rcpt := eventRecipient(logs[i].Metadata); rcpt != "" {
+			events[strings.ToLower(rcpt)] = &logs[i]
+		} else {
+			attempt = &logs[i]
+		}
+	}
+
+	for _, rcpt := range recipients {
+		if w.rows >= limit {
+			return false, nil
+		}
+		outcome := attempt
+		if e, ok := events[strings.ToLower(rcpt)]; ok {
+			outcome = e
+		}
+		if err := w.w.Write(row(m, rcpt, outcome, attempt)); err != nil {
+			return false, err
+		}
+		w.rows++
+	}
+	return true, nil
+}
+
+// Flush writes buffered rows to the underlying writer.
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func row(m storage.ListDeliveryReportMessagesRow, rcpt string, outcome, attempt *storage.DeliveryLog) []string {
+	r := []string{
+		m.ID.String(),
+		m.RequestID.String,
+		formatTime(m.EnqueuedAt.Time),
+		cell(rcpt),
+		string(m.Status),
+		"", "", "", "",
+		"",
+	}
+	if m.ProcessedAt.Valid {
+		r[9] = formatTime(m.ProcessedAt.Time)
+	}
+	if outcome == nil {
+		return r
+	}
+	r[4] = outcome.Status
+	r[5] = outcome.Provider.String
+	r[6] = outcome.ProviderMessageID.String
+	if r[6] == "" && attempt != nil {
+		r[6] = attempt.ProviderMessageID.String
+	}
+	if outcome.ResponseCode.Valid {
+		r[7] = strconv.Itoa(int(outcome.ResponseCode.Int32))
+	}
+	r[8] = outcome.LastError.String
+	if r[8] == "" && outcome.Status != "delivered" && outcome.Status != "sent" {
+		r[8] = outcome.ResponseBody.String
+	}
+	r[8] = cell(r[8])
+	r[9] = formatTime(outcome.UpdatedAt.Time)
+	return r
+}
+
+// eventRecipient returns the recipient a provider event log is about.
+func eventRecipient(metadata []byte) string {
+	var m struct {
+		Recipient string `json:"recipient"`
+	}
+	if len(metadata) == 0 || json.Unmarshal(metadata, &m) != nil {
+		return ""
+	}
+	return m.Recipient
+}
+
+// cell neutralizes values a spreadsheet would run as a formula. Recipients
+// and error strings come from outside, so they are not trusted.
+func cell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,271 @@
+package deliveryreport
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakeQuerier keeps reports, messages and delivery logs in memory.
+// Methods the generator does not use panic through the nil embedded
+// interface.
+type fakeQuerier struct {
+	storage.Querier
+	reports  []*storage.DeliveryReport
+	messages []storage.ListDeliveryReportMessagesRow
+	logs     []storage.DeliveryLog
+}
+
+func (f *fakeQuerier) ClaimDeliveryReport(_ context.Context, _ int32) (storage.DeliveryReport, error) {
+	for _, r := range f.reports {
+		if r.Status == StatusPending {
+			r.Status = StatusRunning
+			return *r, nil
+		}
+	}
+	return storage.DeliveryReport{}, pgx.ErrNoRows
+}
+
+func (f *fakeQuerier) CompleteDeliveryReport(_ context.Context, arg storage.CompleteDeliveryReportParams) error {
+	r := f.report(arg.ID)
+	r.Status, r.ObjectKey, r.RowCount, r.Truncated = StatusReady, arg.ObjectKey, arg.RowCount, arg.Truncated
+	r.CompletedAt = pgtype.Timestamptz{Time: now, Valid: true}
+	return nil
+}
+
+func (f *fakeQuerier) FailDeliveryReport(_ context.Context, arg storage.FailDeliveryReportParams) error {
+	r := f.report(arg.ID)
+	r.Status, r.Error = StatusFailed, arg.Error
+	return nil
+}
+
+func (f *fakeQuerier) ListExpiredDeliveryReports(_ context.Context, arg storage.ListExpiredDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	var out []storage.DeliveryReport
+	for _, r := range f.reports {
+		if r.CompletedAt.Valid && r.CompletedAt.Time.Before(arg.CompletedAt.Time) {
+			out = append(out, *r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeQuerier) DeleteDeliveryReport(_ context.Context, id uuid.UUID) error {
+	kept := f.reports[:0]
+	for _, r := range f.reports {
+		if r.ID != id {
+			kept = append(kept, r)
+		}
+	}
+	f.reports = kept
+	return nil
+}
+
+func (f *fakeQuerier) ListDeliveryReportMessages(_ context.Context, arg storage.ListDeliveryReportMessagesParams) ([]storage.ListDeliveryReportMessagesRow, error) {
+	var out []storage.ListDeliveryReportMessagesRow
+	for _, m := range f.messages {
+		after := m.EnqueuedAt.Time.After(arg.AfterEnqueuedAt.Time) ||
+			(m.EnqueuedAt.Time.Equal(arg.AfterEnqueuedAt.Time) && m.ID.String() > arg.AfterID.String())
+		if after && len(out) < int(arg.MaxResults) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeQuerier) ListDeliveryLogsByMessageIDs(_ context.Context, ids []uuid.UUID) ([]storage.DeliveryLog, error) {
+	var out []storage.DeliveryLog
+	for _, l := range f.logs {
+		for _, id := range ids {
+			if l.MessageID == id {
+				out = append(out, l)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeQuerier) report(id uuid.UUID) *storage.DeliveryReport {
+	for _, r := range f.reports {
+		if r.ID == id {
+			return r
+		}
+	}
+	panic("unknown report " + id.String())
+}
+
+type memStore map[string][]byte
+
+func (s memStore) Put(_ context.Context, key string, data []byte) error {
+	s[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s memStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, msgstore.ErrNotFound
+	}
+	return data, nil
+}
+
+func (s memStore) Delete(_ context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func message(recipients string, status storage.MessageStatus, age time.Duration) storage.ListDeliveryReportMessagesRow {
+	return storage.ListDeliveryReportMessagesRow{
+		ID:         uuid.New(),
+		Recipients: []byte(recipients),
+		Status:     status,
+		EnqueuedAt: pgtype.Timestamptz{Time: now.Add(-age), Valid: true},
+		RequestID:  pgtype.Text{String: "req-1", Valid: true},
+	}
+}
+
+func deliveryLog(messageID uuid.UUID, status, metadata string) storage.DeliveryLog {
+	l := storage.DeliveryLog{
+		ID:        uuid.New(),
+		MessageID: messageID,
+		Status:    status,
+		Provider:  sql.NullString{String: "sendgrid", Valid: true},
+		Metadata:  []byte(metadata),
+		UpdatedAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	if status == "delivered" {
+		l.ProviderMessageID = sql.NullString{String: "sg-delivered", Valid: true}
+	}
+	return l
+}
+
+func readCSV(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	return records
+}
+
+func TestWriter_RecipientOutcomes(t *testing.T) {
+	m := message(`["a@example.com","B@example.com","=cmd@example.com"]`, storage.MessageStatusDelivered, time.Hour)
+	attempt := deliveryLog(m.ID, "delivered", `{}`)
+	bounce := deliveryLog(m.ID, "bounced", `{"event":"bounce","recipient":"b@example.com"}`)
+	bounce.LastError = pgtype.Text{String: "550 mailbox unavailable", Valid: true}
+
+	var buf strings.Builder
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	complete, err := w.WriteMessage(m, []storage.DeliveryLog{attempt, bounce}, 10)
+	if err != nil || !complete {
+		t.Fatalf("WriteMessage() = %v, %v", complete, err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readCSV(t, []byte(buf.String()))
+	if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(Header, ",") {
+		t.Fatalf("unexpected records: %v", records)
+	}
+	if r := records[1]; r[3] != "a@example.com" || r[4] != "delivered" || r[6] != "sg-delivered" || r[8] != "" {
+		t.Errorf("delivered row = %v", r)
+	}
+	if r := records[2]; r[4] != "bounced" || r[6] != "sg-delivered" || r[8] != "550 mailbox unavailable" {
+		t.Errorf("bounced row = %v", r)
+	}
+	if r := records[3]; r[3] != "'=cmd@example.com" {
+		t.Errorf("formula recipient not neutralized: %v", r)
+	}
+}
+
+func TestWriter_NoLogsUsesMessageStatus(t *testing.T) {
+	m := message(`["a@example.com","b@example.com"]`, storage.MessageStatusQueued, time.Hour)
+	var buf strings.Builder
+	w, _ := NewWriter(&buf)
+	complete, err := w.WriteMessage(m, nil, 1)
+	if err != nil || complete {
+		t.Fatalf("WriteMessage() = %v, %v; want truncated after 1 row", complete, err)
+	}
+	w.Flush()
+	records := readCSV(t, []byte(buf.String()))
+	if len(records) != 2 || records[1][4] != "queued" || records[1][0] != m.ID.String() {
+		t.Errorf("unexpected records: %v", records)
+	}
+}
+
+func TestRunOnce_GeneratesPendingReports(t *testing.T) {
+	first := message(`["a@example.com"]`, storage.MessageStatusDelivered, 2*time.Hour)
+	second := message(`["b@example.com","c@example.com"]`, storage.MessageStatusFailed, time.Hour)
+	report := &storage.DeliveryReport{
+		ID:      uuid.New(),
+		GroupID: uuid.New(),
+		Tag:     "spring-sale",
+		Since:   pgtype.Timestamptz{Time: now.Add(-24 * time.Hour), Valid: true},
+		Until:   pgtype.Timestamptz{Time: now, Valid: true},
+		Status:  StatusPending,
+	}
+	q := &fakeQuerier{
+		reports:  []*storage.DeliveryReport{report},
+		messages: []storage.ListDeliveryReportMessagesRow{first, second},
+		logs:     []storage.DeliveryLog{deliveryLog(first.ID, "delivered", `{}`)},
+	}
+	store := memStore{}
+
+	g := NewGenerator(q, store, Config{MaxRows: 2}, zerolog.Nop())
+	g.now = func() time.Time { return now }
+	n, err := g.RunOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("RunOnce() = %d, %v; want 1 report", n, err)
+	}
+	if report.Status != StatusReady || report.RowCount != 2 || !report.Truncated || report.ObjectKey.String != ObjectKey(report.ID) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	records := readCSV(t, store[ObjectKey(report.ID)])
+	if len(records) != 3 || records[1][3] != "a@example.com" || records[2][4] != "failed" {
+		t.Errorf("unexpected records: %v", records)
+	}
+}
+
+func TestRunOnce_PurgesExpiredReports(t *testing.T) {
+	expired := &storage.DeliveryReport{
+		ID:          uuid.New(),
+		Status:      StatusReady,
+		ObjectKey:   pgtype.Text{String: "delivery-report-old.csv", Valid: true},
+		CompletedAt: pgtype.Timestamptz{Time: now.Add(-8 * 24 * time.Hour), Valid: true},
+	}
+	recent := &storage.DeliveryReport{
+		ID:          uuid.New(),
+		Status:      StatusFailed,
+		CompletedAt: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+	}
+	q := &fakeQuerier{reports: []*storage.DeliveryReport{expired, recent}}
+	store := memStore{"delivery-report-old.csv": []byte("x")}
+
+	g := NewGenerator(q, store, Config{}, zerolog.Nop())
+	g.now = func() time.Time { return now }
+	if _, err := g.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(q.reports) != 1 || q.reports[0].ID != recent.ID {
+		t.Errorf("remaining reports = %d, want only the recent one", len(q.reports))
+	}
+	if len(store) != 0 {
+		t.Error("expired report file was not deleted")
+	}
+}
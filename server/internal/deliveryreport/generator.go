@@ -0,0 +1,255 @@
+package deliveryreport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// claimLease is how long a running report stays claimed. A report still
+// running after it, e.g. because its worker stopped, is generated again.
+const claimLease = 10 * time.Minute
+
+// pageSize is the number of messages read per query.
+const pageSize = 500
+
+// Config controls how reports are generated and kept.
+type Config struct {
+	// Interval is the delay between checks for pending reports.
+	Interval time.Duration
+	// Retention is how long reports are kept after they complete.
+	Retention time.Duration
+	// MaxRows caps the recipient rows of one report; larger reports are
+	// marked truncated.
+	MaxRows int
+}
+
+// DefaultConfig returns sensible defaults for the generator.
+func DefaultConfig() Config {
+	return Config{
+		Interval:  30 * time.Second,
+		Retention: 7 * 24 * time.Hour,
+		MaxRows:   100000,
+	}
+}
+
+// Generator periodically generates pending delivery reports and deletes
+// expired ones. Several generators can run at once; each report is
+// claimed by one of them.
+type Generator struct {
+	queries storage.Querier
+	store   msgstore.MessageStore
+	config  Config
+	log     zerolog.Logger
+	now     func() time.Time
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+// NewGenerator creates a Generator writing to store. Zero-valued config
+// fields fall back to DefaultConfig.
+func NewGenerator(queries storage.Querier, store msgstore.MessageStore, cfg Config, log zerolog.Logger) *Generator {
+	defaults := DefaultConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaults.Retention
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaults.MaxRows
+	}
+	return &Generator{
+		queries: queries,
+		store:   store,
+		config:  cfg,
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// Start launches the generator loop in a background goroutine.
+func (g *Generator) Start(ctx context.Context) {
+	ctx, g.cancel = context.WithCancel(ctx)
+
+	g.wg.Add(1)
+	go g.run(ctx)
+
+	g.log.Info().
+		Dur("interval", g.config.Interval).
+		Dur("retention", g.config.Retention).
+		Msg("delivery report generator started")
+}
+
+// Stop signals the generator loop to exit and waits for the current run.
+func (g *Generator) Stop() {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.wg.Wait()
+	g.log.Info().Msg("delivery report generator stopped")
+}
+
+func (g *Generator) run(ctx context.Context) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := g.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				g.log.Error().Err(err).Msg("delivery report generation failed")
+			}
+		}
+	}
+}
+
+// RunOnce deletes expired reports and generates every pending report. It
+// returns the number of reports generated.
+func (g *Generator) RunOnce(ctx context.Context) (int, error) {
+	if err := g.purge(ctx); err != nil {
+		return 0, err
+	}
+	generated := 0
+	for {
+		report, err := g.queries.ClaimDeliveryReport(ctx, int32(claimLease/time.Second))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return generated, nil
+		}
+		if err != nil {
+			return generated, fmt.Errorf("claim report: %w", err)
+		}
+		if err := g.generate(ctx, report); err != nil {
+			if ctx.Err() != nil {
+				return generated, ctx.Err()
+			}
+			g.log.Warn().Err(err).Str("report_id", report.ID.String()).Msg("delivery report failed")
+			if ferr := g.queries.FailDeliveryReport(ctx, storage.FailDeliveryReportParams{
+				ID:    report.ID,
+				Error: pgtype.Text{String: err.Error(), Valid: true},
+			}); ferr != nil {
+				return generated, fmt.Errorf("record report failure: %w", ferr)
+			}
+			continue
+		}
+		generated++
+	}
+}
+
+// generate writes the report's CSV to the store and marks it ready.
+func (g *Generator) generate(ctx context.Context, report storage.DeliveryReport) error {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		return err
+	}
+
+	params := storage.ListDeliveryReportMessagesParams{
+		GroupID:         pgtype.UUID{Bytes: report.GroupID, Valid: true},
+		Tag:             report.Tag,
+		Since:           report.Since,
+		Until:           report.Until,
+		AfterEnqueuedAt: report.Since,
+		AfterID:         uuid.Nil,
+		MaxResults:      pageSize,
+	}
+	truncated := false
+	for !truncated {
+		messages, err := g.queries.ListDeliveryReportMessages(ctx, params)
+		if err != nil {
+			return fmt.Errorf("list messages: %w", err)
+		}
+		if len(messages) == 0 {
+			break
+		}
+		ids := make([]uuid.UUID, len(messages))
+		for i, m := range messages {
+			ids[i] = m.ID
+		}
+		logs, err := g.queries.ListDeliveryLogsByMessageIDs(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("list delivery logs: %w", err)
+		}
+		byMessage := make(map[uuid.UUID][]storage.DeliveryLog, len(messages))
+		for _, l := range logs {
+			byMessage[l.MessageID] = append(byMessage[l.MessageID], l)
+		}
+		for _, m := range messages {
+			complete, err := w.WriteMessage(m, byMessage[m.ID], g.config.MaxRows)
+			if err != nil {
+				return fmt.Errorf("message %s: %w", m.ID, err)
+			}
+			if !complete {
+				truncated = true
+				break
+			}
+		}
+		if len(messages) < pageSize {
+			break
+		}
+		last := messages[len(messages)-1]
+		params.AfterEnqueuedAt, params.AfterID = last.EnqueuedAt, last.ID
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	key := ObjectKey(report.ID)
+	if err := g.store.Put(ctx, key, buf.Bytes()); err != nil {
+		return fmt.Errorf("write %s: %w", key, err)
+	}
+	if err := g.queries.CompleteDeliveryReport(ctx, storage.CompleteDeliveryReportParams{
+		ID:        report.ID,
+		ObjectKey: pgtype.Text{String: key, Valid: true},
+		RowCount:  int32(w.Rows()),
+		Truncated: truncated,
+	}); err != nil {
+		return fmt.Errorf("complete report: %w", err)
+	}
+
+	g.log.Info().
+		Str("report_id", report.ID.String()).
+		Str("group_id", report.GroupID.String()).
+		Int("rows", w.Rows()).
+		Bool("truncated", truncated).
+		Msg("delivery report generated")
+	return nil
+}
+
+// purge deletes reports completed more than Retention ago, with their
+// files.
+func (g *Generator) purge(ctx context.Context) error {
+	expired, err := g.queries.ListExpiredDeliveryReports(ctx, storage.ListExpiredDeliveryReportsParams{
+		CompletedAt: pgtype.Timestamptz{Time: g.now().Add(-g.config.Retention), Valid: true},
+		Limit:       100,
+	})
+	if err != nil {
+		return fmt.Errorf("list expired reports: %w", err)
+	}
+	for _, r := range expired {
+		if r.ObjectKey.Valid {
+			if err := g.store.Delete(ctx, r.ObjectKey.String); err != nil && !errors.Is(err, msgstore.ErrNotFound) {
+				return fmt.Errorf("delete %s: %w", r.ObjectKey.String, err)
+			}
+		}
+		if err := g.queries.DeleteDeliveryReport(ctx, r.ID); err != nil {
+			return fmt.Errorf("delete report: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// Actions a Checker takes on a client.
+const (
+	// ActionNone accepts the client.
+	ActionNone = ""
+	// ActionGreylist greylists the client.
+	ActionGreylist = "greylist"
+	// ActionReject rejects the client.
+	ActionReject = "reject"
+)
+
+// maxCacheEntries bounds the verdict cache. When it is full, expired
+// entries are dropped, and if none have expired the cache is cleared.
+const maxCacheEntries = 10000
+
+// List is a blocklist and the score a listing on it adds.
+type List struct {
+	Zone   string  `mapstructure:"zone"`
+	Weight float64 `mapstructure:"weight"`
+}
+
+// Config holds the blocklist policy of unauthenticated listeners.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Lists are the blocklists checked. A zero weight counts as 1.
+	Lists []List `mapstructure:"lists"`
+	// RejectScore and GreylistScore are the scores at which a client is
+	// rejected or greylisted. Zero disables the action.
+	RejectScore   float64 `mapstructure:"reject_score"`
+	GreylistScore float64 `mapstructure:"greylist_score"`
+	// CacheTTL is how long a client's verdict is remembered.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// Timeout bounds the lookups of one client.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultConfig returns the default blocklist configuration.
+func DefaultConfig() Config {
+	return Config{
+		Lists:       []List{{Zone: "zen.spamhaus.org", Weight: 1}},
+		RejectScore: 1,
+		CacheTTL:    time.Hour,
+		Timeout:     2 * time.Second,
+	}
+}
+
+// Verdict is the outcome of checking a client on every list.
+type Verdict struct {
+	// Score is the sum of the weights of the lists the client is on.
+	Score float64
+	// Results holds the result of each list that answered.
+	Results []Result
+	// Failed holds the zones of the lists that could not be queried.
+	Failed []string
+	// Action is ActionNone, ActionGreylist or ActionReject.
+	Action string
+}
+
+// Listed returns the zones the client is listed on.
+func (v Verdict) Listed() []string {
+	var zones []string
+	for _, r := range v.Results {
+		if r.Listed {
+			zones = append(zones, r.Zone)
+		}
+	}
+	return zones
+}
+
+type cacheEntry struct {
+	verdict Verdict
+	expires time.Time
+}
+
+// Checker scores clients against the configured lists and caches the
+// verdicts. It is safe for concurrent use.
+type Checker struct {
+	resolver Resolver
+	cfg      Config
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[netip.Addr]cacheEntry
+}
+
+// NewChecker creates a Checker that queries the lists through r.
+// Zero-valued config fields fall back to DefaultConfig.
+func NewChecker(r Resolver, cfg Config) *Checker {
+	def := DefaultConfig()
+	if len(cfg.Lists) == 0 {
+		cfg.Lists = def.Lists
+	}
+	for i := range cfg.Lists {
+		if cfg.Lists[i].Weight == 0 {
+			cfg.Lists[i].Weight = 1
+		}
+	}
+	if cfg.RejectScore == 0 && cfg.GreylistScore == 0 {
+		cfg.RejectScore = def.RejectScore
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = def.CacheTTL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	return &Checker{resolver: r, cfg: cfg, now: time.Now, cache: make(map[netip.Addr]cacheEntry)}
+}
+
+// Check returns the verdict for ip. Private and loopback addresses are
+// never listed. Lookups are made in parallel; a list that cannot be
+// queried adds nothing to the score, and its error is returned with the
+// verdict of the other lists, which is then not cached.
+func (c *Checker) Check(ctx context.Context, ip netip.Addr) (Verdict, error) {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return Verdict{}, nil
+	}
+	if v, ok := c.cached(ip); ok {
+		return v, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	results := make([]Result, len(c.cfg.Lists))
+	errs := make([]error, len(c.cfg.Lists))
+	var wg sync.WaitGroup
+	for i, l := range c.cfg.Lists {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = Lookup(ctx, c.resolver, net.IP(ip.AsSlice()), l.Zone)
+		}()
+	}
+	wg.Wait()
+
+	var v Verdict
+	for i, r := range results {
+		if errs[i] != nil {
+			v.Failed = append(v.Failed, c.cfg.Lists[i].Zone)
+			continue
+		}
+		v.Results = append(v.Results, r)
+		if r.Listed {
+			v.Score += c.cfg.Lists[i].Weight
+		}
+	}
+	v.Action = c.action(v.Score)
+
+	err := errors.Join(errs...)
+	if err == nil {
+		c.store(ip, v)
+	}
+	return v, err
+}
+
+// action returns the action for a score.
+func (c *Checker) action(score float64) string {
+	switch {
+	case score <= 0:
+		return ActionNone
+	case c.cfg.RejectScore > 0 && score >= c.cfg.RejectScore:
+		return ActionReject
+	case c.cfg.GreylistScore > 0 && score >= c.cfg.GreylistScore:
+		return ActionGreylist
+	}
+	return ActionNone
+}
+
+func (c *Checker) cached(ip netip.Addr) (Verdict, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.cache[ip]
+	if !ok || !c.now().Before(e.expires) {
+		return Verdict{}, false
+	}
+	return e.verdict, true
+}
+
+func (c *Checker) store(ip netip.Addr, v Verdict) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.cache) >= maxCacheEntries {
+		for k, e := range c.cache {
+			if !now.Before(e.expires) {
+				delete(c.cache, k)
+			}
+		}
+		if len(c.cache) >= maxCacheEntries {
+			clear(c.cache)
+		}
+	}
+	c.cache[ip] = cacheEntry{verdict: v, expires: now.Add(c.cfg.CacheTTL)}
+}
@@ -0,0 +1,78 @@
+// Package dnsbl queries DNS-based blocklists such as Spamhaus ZEN.
+//
+// An IP address is looked up by reversing its octets (or, for IPv6, its
+// nibbles) under the list's zone: 192.0.2.1 is listed on zen.spamhaus.org
+// when 1.2.0.192.zen.spamhaus.org resolves. The returned 127.0.0.x
+// addresses say why it is listed.
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DefaultZones are the lists checked when none are configured.
+var DefaultZones = []string{"zen.spamhaus.org"}
+
+// Resolver is the subset of net.Resolver used to query lists.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Result is the outcome of looking up an IP on one list.
+type Result struct {
+	Zone   string
+	Listed bool
+	// Codes are the 127.0.0.x return codes of a listed IP.
+	Codes []string
+}
+
+// QueryName returns the name looked up to check ip on zone.
+func QueryName(ip net.IP, zone string) (string, error) {
+	zone = strings.TrimSuffix(zone, ".")
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.%s", v4[3], v4[2], v4[1], v4[0], zone), nil
+	}
+	v6 := ip.To16()
+	if v6 == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+	var b strings.Builder
+	for i := len(v6) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", v6[i]&0x0f, v6[i]>>4)
+	}
+	return b.String() + zone, nil
+}
+
+// Lookup checks ip on zone. An IP the list does not know is not listed.
+// Spamhaus answers 127.255.255.x when it refuses a query, for example one
+// sent through a public resolver; that is returned as an error rather than
+// a listing.
+func Lookup(ctx context.Context, r Resolver, ip net.IP, zone string) (Result, error) {
+	res := Result{Zone: zone}
+	name, err := QueryName(ip, zone)
+	if err != nil {
+		return res, err
+	}
+	addrs, err := r.LookupHost(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return res, nil
+		}
+		return res, fmt.Errorf("dnsbl %s: %w", zone, err)
+	}
+	for _, a := range addrs {
+		if strings.HasPrefix(a, "127.255.255.") {
+			return res, fmt.Errorf("dnsbl %s: query refused (%s)", zone, a)
+		}
+		if strings.HasPrefix(a, "127.") {
+			res.Codes = append(res.Codes, a)
+		}
+	}
+	res.Listed = len(res.Codes) > 0
+	return res, nil
+}
@@ -0,0 +1,127 @@
+package dnsbl
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := f[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.0.2.1", "1.2.0.192.zen.spamhaus.org"},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.zen.spamhaus.org"},
+	}
+	for _, tt := range tests {
+		got, err := QueryName(net.ParseIP(tt.ip), "zen.spamhaus.org.")
+		if err != nil || got != tt.want {
+			t.Errorf("QueryName(%s) = %q, %v; want %q", tt.ip, got, err, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	r := fakeResolver{
+		"2.0.0.127.zen.spamhaus.org": {"127.0.0.2", "127.0.0.10"},
+		"1.0.0.127.zen.spamhaus.org": {"127.255.255.254"},
+	}
+	ctx := context.Background()
+
+	res, err := Lookup(ctx, r, net.ParseIP("127.0.0.2"), "zen.spamhaus.org")
+	if err != nil || !res.Listed || len(res.Codes) != 2 {
+		t.Errorf("listed IP: %+v, %v", res, err)
+	}
+	res, err = Lookup(ctx, r, net.ParseIP("192.0.2.1"), "zen.spamhaus.org")
+	if err != nil || res.Listed {
+		t.Errorf("unlisted IP: %+v, %v", res, err)
+	}
+	if _, err := Lookup(ctx, r, net.ParseIP("127.0.0.1"), "zen.spamhaus.org"); err == nil {
+		t.Error("expected an error for a refused query")
+	}
+}
+
+// countingResolver counts lookups and fails those of the zones in fail.
+type countingResolver struct {
+	fakeResolver
+	fail  map[string]bool
+	calls atomic.Int32
+}
+
+func (c *countingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.calls.Add(1)
+	for zone := range c.fail {
+		if strings.HasSuffix(host, "."+zone) {
+			return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+		}
+	}
+	return c.fakeResolver.LookupHost(ctx, host)
+}
+
+func TestChecker_Check(t *testing.T) {
+	r := &countingResolver{fakeResolver: fakeResolver{
+		"7.100.51.198.zen.spamhaus.org":       {"127.0.0.4"},
+		"7.100.51.198.bl.spamcop.net":         {"127.0.0.2"},
+		"8.100.51.198.bl.spamcop.net":         {"127.0.0.2"},
+		"9.100.51.198.b.barracudacentral.org": {"127.0.0.2"},
+	}}
+	c := NewChecker(r, Config{
+		Lists: []List{
+			{Zone: "zen.spamhaus.org", Weight: 2},
+			{Zone: "bl.spamcop.net"},
+		},
+		RejectScore:   3,
+		GreylistScore: 1,
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		ip         string
+		wantScore  float64
+		wantAction string
+	}{
+		{"198.51.100.7", 3, ActionReject},
+		{"198.51.100.8", 1, ActionGreylist},
+		{"198.51.100.9", 0, ActionNone},
+		{"10.0.0.1", 0, ActionNone},
+	}
+	for _, tt := range tests {
+		v, err := c.Check(ctx, netip.MustParseAddr(tt.ip))
+		if err != nil || v.Score != tt.wantScore || v.Action != tt.wantAction {
+			t.Errorf("Check(%s) = %+v, %v; want score %v, action %q", tt.ip, v, err, tt.wantScore, tt.wantAction)
+		}
+	}
+
+	// Verdicts are cached.
+	calls := r.calls.Load()
+	if v, _ := c.Check(ctx, netip.MustParseAddr("198.51.100.7")); v.Action != ActionReject || r.calls.Load() != calls {
+		t.Errorf("expected a cached reject verdict, got %+v after %d lookups", v, r.calls.Load()-calls)
+	}
+
+	// A failing list adds nothing and the verdict is not cached.
+	r.fail = map[string]bool{"zen.spamhaus.org": true}
+	if _, err := c.Check(ctx, netip.MustParseAddr("198.51.100.8")); err != nil {
+		t.Fatalf("cached verdict should not be looked up again: %v", err)
+	}
+	c.cache = map[netip.Addr]cacheEntry{}
+	v, err := c.Check(ctx, netip.MustParseAddr("198.51.100.7"))
+	if err == nil || v.Score != 1 || len(v.Listed()) != 1 || v.Listed()[0] != "bl.spamcop.net" || len(v.Failed) != 1 {
+		t.Errorf("partial verdict = %+v, %v", v, err)
+	}
+	if _, ok := c.cached(netip.MustParseAddr("198.51.100.7")); ok {
+		t.Error("a verdict with lookup errors should not be cached")
+	}
+}
@@ -0,0 +1,470 @@
+// Package dnscache provides a caching DNS resolver that queries configured
+// upstream servers directly instead of going through the host resolver.
+// Answers are cached for their record TTL (clamped to configured bounds) and
+// NXDOMAIN/NODATA answers are cached for a fixed negative TTL, so hot names
+// such as provider API hosts and recipient MX records resolve from memory.
+package dnscache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults applied by New for zero-valued Config fields.
+const (
+	DefaultTimeout     = 2 * time.Second
+	DefaultMaxTTL      = time.Hour
+	DefaultNegativeTTL = time.Minute
+	DefaultMaxEntries  = 10000
+
+	resolvConfPath = "/etc/resolv.conf"
+	maxUDPSize     = 4096
+)
+
+// Config controls upstream servers and cache lifetimes.
+type Config struct {
+	// Servers are upstream nameservers as "host" or "host:port". When empty
+	// the nameservers from /etc/resolv.conf are used.
+	Servers []string
+	// Timeout bounds a single query to one server.
+	Timeout time.Duration
+	// MinTTL and MaxTTL clamp the TTL of positive answers.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// NegativeTTL is how long NXDOMAIN and NODATA answers are cached.
+	NegativeTTL time.Duration
+	// MaxEntries caps the number of cached answers.
+	MaxEntries int
+}
+
+// Resolver is a caching DNS resolver safe for concurrent use. Its lookup
+// methods mirror net.Resolver and return *net.DNSError on failure.
+type Resolver struct {
+	cfg     Config
+	servers []string
+	dialer  net.Dialer
+	now     func() time.Time
+
+	mu       sync.Mutex
+	cache    map[cacheKey]cacheEntry
+	inflight map[cacheKey]*call
+}
+
+type cacheKey struct {
+	name  string
+	qtype uint16
+}
+
+type cacheEntry struct {
+	records []record
+	expires time.Time
+}
+
+type call struct {
+	done    chan struct{}
+	records []record
+	err     error
+}
+
+// New creates a Resolver from cfg.
+func New(cfg Config) *Resolver {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = DefaultMaxTTL
+	}
+	if cfg.MinTTL > cfg.MaxTTL {
+		cfg.MinTTL = cfg.MaxTTL
+	}
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = DefaultNegativeTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+
+	servers := cfg.Servers
+	if len(servers) == 0 {
+		servers = systemServers(resolvConfPath)
+	}
+	normalized := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			s = net.JoinHostPort(strings.Trim(s, "[]"), "53")
+		}
+		normalized = append(normalized, s)
+	}
+
+	return &Resolver{
+		cfg:      cfg,
+		servers:  normalized,
+		now:      time.Now,
+		cache:    make(map[cacheKey]cacheEntry),
+		inflight: make(map[cacheKey]*call),
+	}
+}
+
+// Servers returns the upstream nameservers in query order.
+func (r *Resolver) Servers() []string {
+	return slices.Clone(r.servers)
+}
+
+// LookupMX returns the MX records for name sorted by preference.
+func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	records, err := r.lookup(ctx, name, typeMX)
+	if err != nil {
+		return nil, err
+	}
+	mxs := make([]*net.MX, 0, len(records))
+	for _, rec := range records {
+		mx := *rec.mx
+		mxs = append(mxs, &mx)
+	}
+	slices.SortStableFunc(mxs, func(a, b *net.MX) int { return int(a.Pref) - int(b.Pref) })
+	return mxs, nil
+}
+
+// LookupTXT returns the TXT records for name, with the character strings of
+// each record concatenated.
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, err := r.lookup(ctx, name, typeTXT)
+	if err != nil {
+		return nil, err
+	}
+	txts := make([]string, 0, len(records))
+	for _, rec := range records {
+		txts = append(txts, rec.text)
+	}
+	return txts, nil
+}
+
+// LookupHost returns the IPv4 and IPv6 addresses of host, IPv4 first. IP
+// literals are returned unchanged.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+
+	var (
+		wg      sync.WaitGroup
+		results [2][]record
+		errs    [2]error
+	)
+	for i, qtype := range []uint16{typeA, typeAAAA} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = r.lookup(ctx, host, qtype)
+		}()
+	}
+	wg.Wait()
+
+	var addrs []string
+	for _, records := range results {
+		for _, rec := range records {
+			addrs = append(addrs, rec.ip.String())
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	// Report the more actionable error: a failure beats "not found".
+	if errs[0] != nil && !isNotFound(errs[0]) {
+		return nil, errs[0]
+	}
+	if errs[1] != nil && !isNotFound(errs[1]) {
+		return nil, errs[1]
+	}
+	return nil, notFoundError(host)
+}
+
+// DialContext resolves the host in address through the cache and dials the
+// resulting addresses in turn. It has the signature of
+// net.Dialer.DialContext so it can back an http.Transport. Single-label
+// names and names the upstreams do not know fall back to the system dialer
+// so /etc/hosts entries and search domains keep working.
+func (r *Resolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil || !strings.Contains(strings.TrimSuffix(host, "."), ".") {
+		return r.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := r.LookupHost(ctx, host)
+	if isNotFound(err) {
+		return r.dialer.DialContext(ctx, network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+			continue
+		}
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if lastErr == nil {
+		lastErr = &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+	return nil, lastErr
+}
+
+// lookup returns the records of qtype for name, from the cache when a live
+// entry exists. Concurrent lookups of the same key share one query.
+func (r *Resolver) lookup(ctx context.Context, name string, qtype uint16) ([]record, error) {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	key := cacheKey{name: name, qtype: qtype}
+
+	r.mu.Lock()
+	if entry, ok := r.cache[key]; ok {
+		if r.now().Before(entry.expires) {
+			r.mu.Unlock()
+			return cachedResult(name, entry.records)
+		}
+		delete(r.cache, key)
+	}
+	if c, ok := r.inflight[key]; ok {
+		r.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.records, c.err
+		case <-ctx.Done():
+			return nil, contextError(name, ctx.Err())
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	r.inflight[key] = c
+	r.mu.Unlock()
+
+	records, ttl, err := r.query(ctx, name, qtype)
+
+	r.mu.Lock()
+	delete(r.inflight, key)
+	if err == nil || isNotFound(err) {
+		r.store(key, records, ttl)
+	}
+	r.mu.Unlock()
+
+	c.records, c.err = records, err
+	close(c.done)
+	return records, err
+}
+
+// cachedResult converts a cache entry into a lookup result. Entries without
+// records are cached negative answers.
+func cachedResult(name string, records []record) ([]record, error) {
+	if len(records) == 0 {
+		return nil, notFoundError(name)
+	}
+	return records, nil
+}
+
+// store caches records for ttl. When the cache is full, expired entries are
+// evicted first, then arbitrary ones. Callers must hold r.mu.
+func (r *Resolver) store(key cacheKey, records []record, ttl time.Duration) {
+	if len(r.cache) >= r.cfg.MaxEntries {
+		now := r.now()
+		for k, e := range r.cache {
+			if !now.Before(e.expires) {
+				delete(r.cache, k)
+			}
+		}
+		for k := range r.cache {
+			if len(r.cache) < r.cfg.MaxEntries {
+				break
+			}
+			delete(r.cache, k)
+		}
+	}
+	r.cache[key] = cacheEntry{records: records, expires: r.now().Add(ttl)}
+}
+
+// query asks each upstream in order until one gives a definitive answer.
+// It returns the records and how long they may be cached.
+func (r *Resolver) query(ctx context.Context, name string, qtype uint16) ([]record, time.Duration, error) {
+	if len(r.servers) == 0 {
+		return nil, 0, &net.DNSError{Err: "no DNS servers configured", Name: name, IsTemporary: true}
+	}
+
+	var lastErr error
+	for _, server := range r.servers {
+		resp, err := r.exchange(ctx, server, name, qtype)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return nil, 0, contextError(name, ctx.Err())
+			}
+			continue
+		}
+		switch resp.rcode {
+		case rcodeSuccess:
+			if len(resp.records) == 0 {
+				return nil, r.cfg.NegativeTTL, notFoundError(name)
+			}
+			return resp.records, r.ttl(resp.records), nil
+		case rcodeNXDomain:
+			return nil, r.cfg.NegativeTTL, notFoundError(name)
+		default:
+			lastErr = errors.New("server misbehaving")
+		}
+	}
+
+	dnsErr := &net.DNSError{Err: lastErr.Error(), Name: name, Server: r.servers[len(r.servers)-1], IsTemporary: true}
+	var netErr net.Error
+	if errors.As(lastErr, &netErr) && netErr.Timeout() {
+		dnsErr.Err = "i/o timeout"
+		dnsErr.IsTimeout = true
+	}
+	return nil, 0, dnsErr
+}
+
+// ttl returns the smallest record TTL clamped to the configured bounds.
+func (r *Resolver) ttl(records []record) time.Duration {
+	minTTL := records[0].ttl
+	for _, rec := range records[1:] {
+		minTTL = min(minTTL, rec.ttl)
+	}
+	return min(max(time.Duration(minTTL)*time.Second, r.cfg.MinTTL), r.cfg.MaxTTL)
+}
+
+// exchange sends one query to server over UDP, retrying over TCP when the
+// answer is truncated.
+func (r *Resolver) exchange(ctx context.Context, server, name string, qtype uint16) (*response, error) {
+	id := uint16(rand.UintN(1 << 16))
+	query, err := buildQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+
+	resp, err := r.exchangeUDP(ctx, server, query, id, qtype)
+	if err != nil || !resp.truncated {
+		return resp, err
+	}
+	return r.exchangeTCP(ctx, server, query, id, qtype)
+}
+
+func (r *Resolver) exchangeUDP(ctx context.Context, server string, query []byte, id, qtype uint16) (*response, error) {
+	conn, err := r.dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxUDPSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := parseResponse(buf[:n], qtype)
+		// Ignore stray or spoofed datagrams and keep waiting for ours.
+		if err != nil || resp.id != id {
+			continue
+		}
+		return resp, nil
+	}
+}
+
+func (r *Resolver) exchangeTCP(ctx context.Context, server string, query []byte, id, qtype uint16) (*response, error) {
+	conn, err := r.dialer.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	resp, err := parseResponse(msg, qtype)
+	if err != nil {
+		return nil, err
+	}
+	if resp.id != id {
+		return nil, errMalformed
+	}
+	return resp, nil
+}
+
+// systemServers reads the nameserver lines of a resolv.conf file, falling
+// back to a local resolver when none are found.
+func systemServers(path string) []string {
+	var servers []string
+	if f, err := os.Open(path); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, fields[1])
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1"}
+	}
+	return servers
+}
+
+func notFoundError(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func contextError(name string, err error) error {
+	return &net.DNSError{
+		Err:       err.Error(),
+		Name:      name,
+		IsTimeout: errors.Is(err, context.DeadlineExceeded),
+	}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
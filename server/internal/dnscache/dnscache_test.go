@@ -0,0 +1,364 @@
+package dnscache
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAnswer is one answer record served by fakeServer. data is the raw
+// RDATA.
+type fakeAnswer struct {
+	qtype uint16
+	ttl   uint32
+	data  []byte
+}
+
+// fakeServer is a UDP nameserver answering from a fixed zone. It answers
+// NXDOMAIN for unknown names unless rcode overrides the response code.
+type fakeServer struct {
+	conn    net.PacketConn
+	queries atomic.Int32
+
+	mu    sync.Mutex
+	zone  map[cacheKey][]fakeAnswer
+	rcode int
+	delay time.Duration
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeServer{conn: conn, zone: make(map[cacheKey][]fakeAnswer)}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.conn.LocalAddr().String() }
+
+func (s *fakeServer) add(name string, qtype uint16, ttl uint32, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := cacheKey{name: name, qtype: qtype}
+	s.zone[key] = append(s.zone[key], fakeAnswer{qtype: qtype, ttl: ttl, data: data})
+}
+
+func (s *fakeServer) set(rcode int, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rcode, s.delay = rcode, delay
+}
+
+func (s *fakeServer) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, peer, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.queries.Add(1)
+		s.mu.Lock()
+		delay := s.delay
+		reply := s.reply(buf[:n])
+		s.mu.Unlock()
+		time.Sleep(delay)
+		s.conn.WriteTo(reply, peer)
+	}
+}
+
+func (s *fakeServer) reply(query []byte) []byte {
+	name, off, _ := readName(query, 12)
+	qtype := binary.BigEndian.Uint16(query[off:])
+	question := query[12 : off+4]
+
+	answers := s.zone[cacheKey{name: name, qtype: qtype}]
+	rcode := s.rcode
+	if rcode == 0 && answers == nil && !s.hasName(name) {
+		rcode = rcodeNXDomain
+	}
+
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[0:], binary.BigEndian.Uint16(query))
+	binary.BigEndian.PutUint16(msg[2:], flagQR|flagRD|uint16(rcode))
+	binary.BigEndian.PutUint16(msg[4:], 1)
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(answers)))
+	msg = append(msg, question...)
+	for _, a := range answers {
+		msg = append(msg, 0xc0, 12) // pointer to the question name
+		msg = binary.BigEndian.AppendUint16(msg, a.qtype)
+		msg = binary.BigEndian.AppendUint16(msg, classINET)
+		msg = binary.BigEndian.AppendUint32(msg, a.ttl)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(a.data)))
+		msg = append(msg, a.data...)
+	}
+	return msg
+}
+
+func (s *fakeServer) hasName(name string) bool {
+	for key := range s.zone {
+		if key.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeName(name string) []byte {
+	q, _ := buildQuery(0, name, 0)
+	return q[12 : len(q)-4]
+}
+
+func mxData(pref uint16, host string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, pref), encodeName(host)...)
+}
+
+func TestLookupMX_SortsByPreference(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.add("example.com.", typeMX, 300, mxData(20, "mx2.example.com"))
+	srv.add("example.com.", typeMX, 300, mxData(10, "mx1.example.com"))
+
+	r := New(Config{Servers: []string{srv.addr()}})
+	mxs, err := r.LookupMX(context.Background(), "Example.COM")
+	if err != nil {
+		t.Fatalf("LookupMX: %v", err)
+	}
+	if len(mxs) != 2 || mxs[0].Host != "mx1.example.com." || mxs[0].Pref != 10 || mxs[1].Host != "mx2.example.com." {
+		t.Errorf("unexpected MX records: %+v %+v", mxs[0], mxs[1])
+	}
+}
+
+func TestLookupHost_ReturnsIPv4AndIPv6(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.add("api.example.com.", typeA, 60, []byte{192, 0, 2, 1})
+	srv.add("api.example.com.", typeAAAA, 60, net.ParseIP("2001:db8::1"))
+
+	r := New(Config{Servers: []string{srv.addr()}})
+	addrs, err := r.LookupHost(context.Background(), "api.example.com")
+	if err != nil {
+		t.Fatalf("LookupHost: %v", err)
+	}
+	if len(addrs) != 2 || addrs[0] != "192.0.2.1" || addrs[1] != "2001:db8::1" {
+		t.Errorf("unexpected addresses: %v", addrs)
+	}
+
+	addrs, err = r.LookupHost(context.Background(), "198.51.100.7")
+	if err != nil || len(addrs) != 1 || addrs[0] != "198.51.100.7" {
+		t.Errorf("IP literal: got %v, %v", addrs, err)
+	}
+}
+
+func TestLookupTXT_JoinsStrings(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.add("example.com.", typeTXT, 60, append([]byte("\x06v=spf1"), "\x05 -all"...))
+
+	r := New(Config{Servers: []string{srv.addr()}})
+	txts, err := r.LookupTXT(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("LookupTXT: %v", err)
+	}
+	if len(txts) != 1 || txts[0] != "v=spf1 -all" {
+		t.Errorf("unexpected TXT records: %q", txts)
+	}
+}
+
+func TestLookup_CachesUntilTTLExpires(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.add("example.com.", typeMX, 30, mxData(10, "mx.example.com"))
+
+	now := time.Now()
+	r := New(Config{Servers: []string{srv.addr()}})
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.LookupMX(context.Background(), "example.com"); err != nil {
+			t.Fatalf("LookupMX: %v", err)
+		}
+	}
+	if got := srv.queries.Load(); got != 1 {
+		t.Errorf("expected 1 upstream query while cached, got %d", got)
+	}
+
+	now = now.Add(31 * time.Second)
+	if _, err := r.LookupMX(context.Background(), "example.com"); err != nil {
+		t.Fatalf("LookupMX: %v", err)
+	}
+	if got := srv.queries.Load(); got != 2 {
+		t.Errorf("expected re-query after TTL expiry, got %d queries", got)
+	}
+}
+
+func TestLookup_ClampsTTL(t *testing.T) {
+	r := New(Config{MinTTL: 10 * time.Second, MaxTTL: time.Minute})
+
+	if got := r.ttl([]record{{ttl: 1}}); got != 10*time.Second {
+		t.Errorf("expected TTL raised to MinTTL, got %v", got)
+	}
+	if got := r.ttl([]record{{ttl: 86400}, {ttl: 3600}}); got != time.Minute {
+		t.Errorf("expected TTL capped at MaxTTL, got %v", got)
+	}
+	if got := r.ttl([]record{{ttl: 45}, {ttl: 20}}); got != 20*time.Second {
+		t.Errorf("expected smallest record TTL, got %v", got)
+	}
+}
+
+func TestLookup_NegativeCaching(t *testing.T) {
+	srv := newFakeServer(t)
+
+	now := time.Now()
+	r := New(Config{Servers: []string{srv.addr()}, NegativeTTL: time.Minute})
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupMX(context.Background(), "missing.example")
+		if !isNotFound(err) {
+			t.Fatalf("expected not-found DNSError, got %v", err)
+		}
+	}
+	if got := srv.queries.Load(); got != 1 {
+		t.Errorf("expected NXDOMAIN to be cached, got %d queries", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	r.LookupMX(context.Background(), "missing.example")
+	if got := srv.queries.Load(); got != 2 {
+		t.Errorf("expected re-query after negative TTL, got %d queries", got)
+	}
+}
+
+func TestLookup_FallsBackToNextServer(t *testing.T) {
+	failing := newFakeServer(t)
+	failing.set(2, 0) // SERVFAIL
+	good := newFakeServer(t)
+	good.add("example.com.", typeA, 60, []byte{192, 0, 2, 10})
+
+	r := New(Config{Servers: []string{failing.addr(), good.addr()}})
+	addrs, err := r.LookupHost(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("LookupHost: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "192.0.2.10" {
+		t.Errorf("unexpected addresses: %v", addrs)
+	}
+}
+
+func TestLookup_ServerFailureIsTemporaryAndNotCached(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.set(2, 0)
+
+	r := New(Config{Servers: []string{srv.addr()}})
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupMX(context.Background(), "example.com")
+		dnsErr, ok := err.(*net.DNSError)
+		if !ok || !dnsErr.IsTemporary || dnsErr.IsNotFound {
+			t.Fatalf("expected temporary DNSError, got %#v", err)
+		}
+	}
+	if got := srv.queries.Load(); got != 2 {
+		t.Errorf("expected failures not to be cached, got %d queries", got)
+	}
+}
+
+func TestLookup_TimeoutReportsIsTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	r := New(Config{Servers: []string{conn.LocalAddr().String()}, Timeout: 50 * time.Millisecond})
+	_, err = r.LookupMX(context.Background(), "example.com")
+	dnsErr, ok := err.(*net.DNSError)
+	if !ok || !dnsErr.IsTimeout {
+		t.Fatalf("expected timeout DNSError, got %#v", err)
+	}
+}
+
+func TestLookup_CoalescesConcurrentQueries(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.set(0, 50*time.Millisecond)
+	srv.add("example.com.", typeMX, 60, mxData(10, "mx.example.com"))
+
+	r := New(Config{Servers: []string{srv.addr()}})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.LookupMX(context.Background(), "example.com"); err != nil {
+				t.Errorf("LookupMX: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := srv.queries.Load(); got != 1 {
+		t.Errorf("expected concurrent lookups to share one query, got %d", got)
+	}
+}
+
+func TestStore_EvictsWhenFull(t *testing.T) {
+	r := New(Config{MaxEntries: 2})
+	for _, name := range []string{"a.", "b.", "c."} {
+		r.store(cacheKey{name: name, qtype: typeA}, nil, time.Minute)
+	}
+	if len(r.cache) != 2 {
+		t.Errorf("expected cache capped at 2 entries, got %d", len(r.cache))
+	}
+	if _, ok := r.cache[cacheKey{name: "c.", qtype: typeA}]; !ok {
+		t.Error("expected newest entry to be cached")
+	}
+}
+
+func TestDialContext_UsesCachedAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	srv := newFakeServer(t)
+	srv.add("relay.example.com.", typeA, 60, []byte{127, 0, 0, 1})
+
+	r := New(Config{Servers: []string{srv.addr()}})
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	conn, err := r.DialContext(context.Background(), "tcp", net.JoinHostPort("relay.example.com", port))
+	if err != nil {
+		t.Fatalf("DialContext: %v", err)
+	}
+	conn.Close()
+}
+
+func TestNew_ServersDefaultPortAndResolvConf(t *testing.T) {
+	r := New(Config{Servers: []string{"192.0.2.53", "[2001:db8::53]", "192.0.2.54:5353"}})
+	want := []string{"192.0.2.53:53", "[2001:db8::53]:53", "192.0.2.54:5353"}
+	got := r.Servers()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("server %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	os.WriteFile(path, []byte("# comment\nsearch svc.local\nnameserver 10.0.0.2\nnameserver 10.0.0.3\n"), 0o644)
+	servers := systemServers(path)
+	if len(servers) != 2 || servers[0] != "10.0.0.2" || servers[1] != "10.0.0.3" {
+		t.Errorf("unexpected resolv.conf servers: %v", servers)
+	}
+	if servers := systemServers(filepath.Join(t.TempDir(), "missing")); len(servers) != 1 || servers[0] != "127.0.0.1" {
+		t.Errorf("expected local fallback, got %v", servers)
+	}
+}
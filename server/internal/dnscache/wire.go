@@ -0,0 +1,191 @@
+package dnscache
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// DNS record types and response codes used by the resolver (RFC 1035,
+// RFC 3596).
+const (
+	typeA     uint16 = 1
+	typeCNAME uint16 = 5
+	typeMX    uint16 = 15
+	typeTXT   uint16 = 16
+	typeAAAA  uint16 = 28
+
+	classINET uint16 = 1
+
+	rcodeSuccess  = 0
+	rcodeNXDomain = 3
+
+	flagRD = 0x0100 // recursion desired
+	flagTC = 0x0200 // truncated
+	flagQR = 0x8000 // response
+)
+
+var errMalformed = errors.New("dnscache: malformed DNS message")
+
+// record is one answer record of the queried type. Only the field matching
+// the type is set.
+type record struct {
+	ttl  uint32
+	ip   net.IP
+	mx   *net.MX
+	text string
+}
+
+// response is the relevant part of a parsed DNS response.
+type response struct {
+	id        uint16
+	rcode     int
+	truncated bool
+	records   []record
+}
+
+// buildQuery encodes a recursive query for name and qtype.
+func buildQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 12+len(name)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], flagRD)
+	binary.BigEndian.PutUint16(msg[4:], 1) // QDCOUNT
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, errors.New("dnscache: invalid name " + name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classINET)
+	return msg, nil
+}
+
+// parseResponse decodes the answer records of type qtype. Records of other
+// types, such as the CNAMEs leading to the answer, are skipped.
+func parseResponse(msg []byte, qtype uint16) (*response, error) {
+	if len(msg) < 12 {
+		return nil, errMalformed
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&flagQR == 0 {
+		return nil, errMalformed
+	}
+	resp := &response{
+		id:        binary.BigEndian.Uint16(msg[0:]),
+		rcode:     int(flags & 0x000f),
+		truncated: flags&flagTC != 0,
+	}
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+
+	for i := 0; i < ancount; i++ {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next
+		if off+10 > len(msg) {
+			return nil, errMalformed
+		}
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		ttl := binary.BigEndian.Uint32(msg[off+4:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return nil, errMalformed
+		}
+		rdata := msg[off : off+rdlen]
+		rdoff := off
+		off += rdlen
+
+		if rtype != qtype {
+			continue
+		}
+		rec := record{ttl: ttl}
+		switch rtype {
+		case typeA:
+			if rdlen != net.IPv4len {
+				return nil, errMalformed
+			}
+			rec.ip = net.IP(append([]byte(nil), rdata...))
+		case typeAAAA:
+			if rdlen != net.IPv6len {
+				return nil, errMalformed
+			}
+			rec.ip = net.IP(append([]byte(nil), rdata...))
+		case typeMX:
+			if rdlen < 3 {
+				return nil, errMalformed
+			}
+			host, _, err := readName(msg, rdoff+2)
+			if err != nil {
+				return nil, err
+			}
+			rec.mx = &net.MX{Host: host, Pref: binary.BigEndian.Uint16(rdata)}
+		case typeTXT:
+			var b strings.Builder
+			for i := 0; i < len(rdata); {
+				n := int(rdata[i])
+				if i+1+n > len(rdata) {
+					return nil, errMalformed
+				}
+				b.Write(rdata[i+1 : i+1+n])
+				i += 1 + n
+			}
+			rec.text = b.String()
+		}
+		resp.records = append(resp.records, rec)
+	}
+	return resp, nil
+}
+
+// readName decodes a possibly compressed domain name at off and returns it
+// in absolute form along with the offset following it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for hops := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errMalformed
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case n&0xc0 == 0xc0:
+			if off+1 >= len(msg) || hops > 16 {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+			hops++
+		case n&0xc0 != 0:
+			return "", 0, errMalformed
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
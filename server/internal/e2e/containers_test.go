@@ -0,0 +1,169 @@
+//go:build integration
+
+package e2e_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
+)
+
+const (
+	minioUser   = "minioadmin"
+	minioPass   = "minioadmin"
+	minioBucket = "smtp-proxy"
+	minioRegion = "us-east-1"
+)
+
+// env holds the addresses of the containers shared by all tests.
+var env struct {
+	DatabaseURL string
+	RedisAddr   string
+	S3Endpoint  string
+	// SinkURL is the MockServer base URL. It stands in for the SendGrid
+	// API and records every request it receives.
+	SinkURL string
+}
+
+// TestMain starts the containers once, applies the migrations and runs
+// the suite.
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	containers, err := startContainers(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start containers: %v\n", err)
+		terminate(containers)
+		os.Exit(1)
+	}
+
+	if err := migrate.Run(ctx, env.DatabaseURL, zerolog.Nop()); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to run migrations: %v\n", err)
+		terminate(containers)
+		os.Exit(1)
+	}
+	if err := createBucket(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create bucket: %v\n", err)
+		terminate(containers)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	terminate(containers)
+	os.Exit(code)
+}
+
+// startContainers starts every container and fills in env. The containers
+// started so far are returned even on error so they can be terminated.
+func startContainers(ctx context.Context) ([]testcontainers.Container, error) {
+	var started []testcontainers.Container
+	start := func(req testcontainers.ContainerRequest) (string, error) {
+		c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: req,
+			Started:          true,
+		})
+		if c != nil {
+			started = append(started, c)
+		}
+		if err != nil {
+			return "", fmt.Errorf("start %s: %w", req.Image, err)
+		}
+		endpoint, err := c.Endpoint(ctx, "")
+		if err != nil {
+			return "", fmt.Errorf("endpoint of %s: %w", req.Image, err)
+		}
+		return endpoint, nil
+	}
+
+	pg, err := start(testcontainers.ContainerRequest{
+		Image:        "postgres:18-alpine",
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_USER":     "test",
+			"POSTGRES_PASSWORD": "test",
+			"POSTGRES_DB":       "test",
+		},
+		WaitingFor: wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(60 * time.Second),
+	})
+	if err != nil {
+		return started, err
+	}
+	env.DatabaseURL = fmt.Sprintf("postgres://test:test@%s/test?sslmode=disable", pg)
+
+	env.RedisAddr, err = start(testcontainers.ContainerRequest{
+		Image:        "redis:7.4-alpine",
+		ExposedPorts: []string{"6379/tcp"},
+		WaitingFor:   wait.ForLog("Ready to accept connections"),
+	})
+	if err != nil {
+		return started, err
+	}
+
+	minio, err := start(testcontainers.ContainerRequest{
+		Image:        "minio/minio:RELEASE.2024-10-13T13-34-11Z",
+		ExposedPorts: []string{"9000/tcp"},
+		Env: map[string]string{
+			"MINIO_ROOT_USER":     minioUser,
+			"MINIO_ROOT_PASSWORD": minioPass,
+		},
+		Cmd:        []string{"server", "/data"},
+		WaitingFor: wait.ForHTTP("/minio/health/ready").WithPort("9000/tcp"),
+	})
+	if err != nil {
+		return started, err
+	}
+	env.S3Endpoint = "http://" + minio
+
+	sink, err := start(testcontainers.ContainerRequest{
+		Image:        "mockserver/mockserver:5.15.0",
+		ExposedPorts: []string{"1080/tcp"},
+		WaitingFor:   wait.ForListeningPort("1080/tcp"),
+	})
+	if err != nil {
+		return started, err
+	}
+	env.SinkURL = "http://" + sink
+
+	// The message store builds its S3 client from the default AWS
+	// credential chain.
+	os.Setenv("AWS_ACCESS_KEY_ID", minioUser)
+	os.Setenv("AWS_SECRET_ACCESS_KEY", minioPass)
+
+	return started, nil
+}
+
+// createBucket creates the message store bucket in MinIO, with the
+// credentials startContainers put in the environment.
+func createBucket(ctx context.Context) error {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(minioRegion))
+	if err != nil {
+		return err
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = &env.S3Endpoint
+		o.UsePathStyle = true
+	})
+	bucket := minioBucket
+	_, err = client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: &bucket})
+	return err
+}
+
+func terminate(containers []testcontainers.Container) {
+	for _, c := range containers {
+		if err := testcontainers.TerminateContainer(c); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to terminate container: %v\n", err)
+		}
+	}
+}
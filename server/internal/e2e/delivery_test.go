@@ -0,0 +1,111 @@
+//go:build integration
+
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestSubmitDeliverWebhook follows one message through the whole pipeline:
+// SMTP submission, body upload to MinIO, outbox relay to the Redis stream,
+// delivery by the queue worker to the ESP sink, and the ESP's event webhook
+// updating the delivery log.
+func TestSubmitDeliverWebhook(t *testing.T) {
+	ctx := context.Background()
+	tn := seedTenant(t, ctx)
+	s := startStack(t, tn.GroupID)
+
+	marker := "e2e-" + uuid.NewString()
+	providerMessageID := "sg-" + marker
+	expectSend(t, marker, providerMessageID)
+
+	// Submit.
+	msg := strings.Join([]string{
+		"From: sender@e2e.test",
+		"To: rcpt@example.com",
+		"Subject: " + marker,
+		"",
+		"Hello from the integration suite.",
+		"",
+	}, "\r\n")
+	smtpAuth := smtp.PlainAuth("", tn.Username, tn.Password, "127.0.0.1")
+	if err := smtp.SendMail(s.SMTPAddr, smtpAuth, "sender@e2e.test", []string{"rcpt@example.com"}, []byte(msg)); err != nil {
+		t.Fatalf("SendMail() error = %v", err)
+	}
+
+	var messageID uuid.UUID
+	if err := s.DB.Pool.QueryRow(ctx, `SELECT id FROM messages WHERE subject = $1`, marker).Scan(&messageID); err != nil {
+		t.Fatalf("find submitted message: %v", err)
+	}
+
+	// The body is stored in the object store.
+	body, err := s.Store.Get(ctx, messageID.String())
+	if err != nil {
+		t.Fatalf("message store Get() error = %v", err)
+	}
+	if !bytes.Contains(body, []byte("Hello from the integration suite.")) {
+		t.Errorf("stored body = %q, want the submitted body", body)
+	}
+
+	// Deliver.
+	eventually(t, 30*time.Second, func() error {
+		m, err := s.Queries.GetMessageByID(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if m.Status != "delivered" {
+			return fmt.Errorf("message status = %q, want delivered", m.Status)
+		}
+		return nil
+	})
+	sent := sentTo(t, marker)
+	if len(sent) != 1 {
+		t.Fatalf("sink received %d sends, want 1", len(sent))
+	}
+	if !bytes.Contains(sent[0], []byte("rcpt@example.com")) {
+		t.Errorf("send body = %s, want recipient rcpt@example.com", sent[0])
+	}
+
+	logs, err := s.Queries.ListDeliveryLogsByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListDeliveryLogsByMessageID() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0].ProviderMessageID.String != providerMessageID {
+		t.Fatalf("delivery logs = %+v, want one with provider message ID %q", logs, providerMessageID)
+	}
+
+	// Webhook.
+	events, _ := json.Marshal([]map[string]any{{
+		"event":         "bounce",
+		"email":         "rcpt@example.com",
+		"sg_message_id": providerMessageID,
+		"reason":        "550 mailbox unavailable",
+		"timestamp":     time.Now().Unix(),
+	}})
+	resp, err := http.Post(s.APIURL+"/api/v1/webhooks/sendgrid", "application/json", bytes.NewReader(events))
+	if err != nil {
+		t.Fatalf("post webhook: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("webhook status = %d, want 200", resp.StatusCode)
+	}
+
+	logs, err = s.Queries.ListDeliveryLogsByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListDeliveryLogsByMessageID() error = %v", err)
+	}
+	if len(logs) != 1 || logs[0].Status != "bounced" {
+		t.Errorf("delivery logs after webhook = %+v, want status bounced", logs)
+	}
+}
@@ -0,0 +1,6 @@
+// Package e2e holds the end-to-end integration suite. Its tests carry the
+// integration build tag and start PostgreSQL, Redis, MinIO and a MockServer
+// ESP sink with testcontainers, so they need a running Docker daemon:
+//
+//	make test-integration
+package e2e
@@ -0,0 +1,254 @@
+//go:build integration
+
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/api"
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	smtpserver "github.com/sungwon/smtp-proxy/server/internal/smtp"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/worker"
+)
+
+// stack is an in-process deployment wired as the smtp-server, queue-worker
+// and api-server binaries wire it, against the shared containers.
+type stack struct {
+	DB       *storage.DB
+	Queries  *storage.Queries
+	Store    msgstore.MessageStore
+	SMTPAddr string
+	APIURL   string
+}
+
+// startStack starts the SMTP listener with its outbox relay, a queue
+// worker consuming the stream of groupID, and the API router. Everything
+// is stopped when the test ends.
+func startStack(t *testing.T, groupID uuid.UUID) *stack {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	log := zerolog.New(zerolog.NewTestWriter(t)).Level(zerolog.InfoLevel)
+
+	db, err := storage.NewDB(ctx, env.DatabaseURL, storage.PoolConfig{MinConns: 1, MaxConns: 10, ConnectTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	t.Cleanup(db.Close)
+	queries := storage.New(db.Pool)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: env.RedisAddr})
+	t.Cleanup(func() { redisClient.Close() })
+
+	store, err := msgstore.New(msgstore.Config{
+		Type:       "s3",
+		S3Bucket:   minioBucket,
+		S3Prefix:   "messages/",
+		S3Endpoint: env.S3Endpoint,
+		S3Region:   minioRegion,
+	}, log)
+	if err != nil {
+		t.Fatalf("create message store: %v", err)
+	}
+
+	// smtp-server: persist messages with an outbox entry and relay them
+	// to the Redis stream.
+	enqueuer := queue.NewRedisEnqueuer(redisClient)
+	relay := delivery.NewOutboxRelay(queries, delivery.NewAsyncService(enqueuer, log), delivery.OutboxRelayConfig{
+		PollInterval: 100 * time.Millisecond,
+	}, log)
+	relay.Start(ctx)
+	t.Cleanup(relay.Stop)
+
+	backend := smtpserver.NewBackend(queries, db, store, log, 10)
+	srv := gosmtp.NewServer(backend.Listener("submission", false))
+	srv.Domain = "smtp-proxy"
+	srv.AllowInsecureAuth = true
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	// queue-worker: deliver through the group's providers.
+	resolver := provider.NewResolver(queries, provider.NewHTTPClient(10*time.Second), log)
+	handler := worker.NewHandler(resolver, queries, store, log)
+	queueCfg := queue.DefaultConfig()
+	queueCfg.WorkerCount = 2
+	queueCfg.BlockTimeout = 200 * time.Millisecond
+	queueCfg.ShutdownTimeout = 5 * time.Second
+	dlq := queue.NewRedisDLQ(redisClient, enqueuer)
+	dequeuer := queue.NewRedisDequeuer(redisClient, enqueuer, dlq, handler,
+		queue.NewRetryStrategy(queueCfg.MaxRetries), queueCfg, log, groupID.String(), "e2e")
+	if err := dequeuer.Start(ctx); err != nil {
+		t.Fatalf("start dequeuer: %v", err)
+	}
+	t.Cleanup(func() { dequeuer.Stop(context.Background()) })
+
+	// api-server: webhooks and management API.
+	apiServer := httptest.NewServer(api.NewRouterWithConfig(api.RouterConfig{
+		Queries: queries,
+		DB:      db,
+		Log:     log,
+		DLQ:     dlq,
+	}))
+	t.Cleanup(apiServer.Close)
+
+	return &stack{
+		DB:       db,
+		Queries:  queries,
+		Store:    store,
+		SMTPAddr: ln.Addr().String(),
+		APIURL:   apiServer.URL,
+	}
+}
+
+// tenant is a group with an SMTP account and a SendGrid provider pointing
+// at the sink.
+type tenant struct {
+	GroupID  uuid.UUID
+	Username string
+	Password string
+}
+
+// seedTenant creates a tenant whose mail is delivered to the sink.
+func seedTenant(t *testing.T, ctx context.Context) tenant {
+	t.Helper()
+
+	conn, err := pgx.Connect(ctx, env.DatabaseURL)
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	suffix := uuid.NewString()[:8]
+	tn := tenant{
+		GroupID:  uuid.New(),
+		Username: "e2e-" + suffix,
+		Password: "e2e-password",
+	}
+	hash, err := auth.HashPassword(tn.Password)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	userID := uuid.New()
+	smtpConfig, _ := json.Marshal(map[string]string{"endpoint": env.SinkURL})
+
+	stmts := []struct {
+		sql  string
+		args []any
+	}{
+		{`INSERT INTO groups (id, name) VALUES ($1, $2)`, []any{tn.GroupID, "e2e-" + suffix}},
+		{`INSERT INTO users (id, email, password_hash, username, account_type) VALUES ($1, $2, $3, $4, 'smtp')`,
+			[]any{userID, tn.Username + "@e2e.test", hash, tn.Username}},
+		{`INSERT INTO group_members (group_id, user_id, role) VALUES ($1, $2, 'member')`, []any{tn.GroupID, userID}},
+		{`INSERT INTO esp_providers (name, provider_type, api_key, smtp_config, group_id) VALUES ('sink', 'sendgrid', 'e2e-key', $1, $2)`,
+			[]any{smtpConfig, tn.GroupID}},
+	}
+	for _, s := range stmts {
+		if _, err := conn.Exec(ctx, s.sql, s.args...); err != nil {
+			t.Fatalf("seed tenant: %s: %v", s.sql, err)
+		}
+	}
+	return tn
+}
+
+// mockSink sends a request to the MockServer control API.
+func mockSink(t *testing.T, path string, body any) []byte {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("marshal sink request: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPut, env.SinkURL+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("build sink request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("sink %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		t.Fatalf("sink %s: status %d: %s", path, resp.StatusCode, out)
+	}
+	return out
+}
+
+// expectSend makes the sink accept SendGrid sends whose body contains
+// marker, answering with providerMessageID as SendGrid's X-Message-Id.
+func expectSend(t *testing.T, marker, providerMessageID string) {
+	t.Helper()
+	mockSink(t, "/mockserver/expectation", map[string]any{
+		"httpRequest": map[string]any{
+			"method": "POST",
+			"path":   "/v3/mail/send",
+			"body":   map[string]any{"type": "STRING", "string": marker, "subString": true},
+		},
+		"httpResponse": map[string]any{
+			"statusCode": 202,
+			"headers":    map[string][]string{"X-Message-Id": {providerMessageID}},
+		},
+	})
+}
+
+// sentTo returns the bodies of the SendGrid sends received by the sink
+// that contain marker.
+func sentTo(t *testing.T, marker string) []json.RawMessage {
+	t.Helper()
+	out := mockSink(t, "/mockserver/retrieve?type=REQUESTS&format=JSON", map[string]any{
+		"method": "POST",
+		"path":   "/v3/mail/send",
+		"body":   map[string]any{"type": "STRING", "string": marker, "subString": true},
+	})
+	var requests []struct {
+		Body json.RawMessage `json:"body"`
+	}
+	if err := json.Unmarshal(out, &requests); err != nil {
+		t.Fatalf("decode sink requests: %v: %s", err, out)
+	}
+	bodies := make([]json.RawMessage, len(requests))
+	for i, r := range requests {
+		bodies[i] = r.Body
+	}
+	return bodies
+}
+
+// eventually polls cond until it returns nil or the deadline passes, then
+// fails the test with the last error.
+func eventually(t *testing.T, timeout time.Duration, cond func() error) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := cond()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met after %s: %v", timeout, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,146 @@
+// Package fbl parses complaint feedback loop reports in the Abuse
+// Reporting Format (ARF, RFC 5965) that mailbox providers send when a
+// recipient marks a message as spam.
+package fbl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// ErrNotFeedbackReport is returned by Parse for messages that are not a
+// multipart/report with report-type=feedback-report.
+var ErrNotFeedbackReport = errors.New("fbl: not an ARF feedback report")
+
+// Report is a parsed feedback report.
+type Report struct {
+	// FeedbackType is the kind of report, usually "abuse".
+	FeedbackType string
+	UserAgent    string
+	SourceIP     string
+	// OriginalMailFrom and OriginalRcptTo are the envelope of the reported
+	// message. Many providers redact or omit the recipient.
+	OriginalMailFrom string
+	OriginalRcptTo   []string
+	// MessageID is the Message-ID header of the reported message, angle
+	// brackets included.
+	MessageID string
+	// OriginalTo holds the To addresses of the reported message.
+	OriginalTo []string
+}
+
+// Recipients returns the recipients the report is about: the
+// Original-Rcpt-To fields, or the To header of the reported message when
+// the provider left those out.
+func (r *Report) Recipients() []string {
+	if len(r.OriginalRcptTo) > 0 {
+		return r.OriginalRcptTo
+	}
+	return r.OriginalTo
+}
+
+// Parse parses a raw ARF message. The reported message may be attached as
+// message/rfc822 or, when the provider strips bodies, as
+// text/rfc822-headers.
+func Parse(raw []byte) (*Report, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("fbl: read message: %w", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "feedback-report") || params["boundary"] == "" {
+		return nil, ErrNotFeedbackReport
+	}
+
+	r := &Report{}
+	var sawReport bool
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fbl: read part: %w", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := readPart(part)
+		if err != nil {
+			return nil, fmt.Errorf("fbl: read %s part: %w", partType, err)
+		}
+
+		switch partType {
+		case "message/feedback-report":
+			if err := r.parseFields(body); err != nil {
+				return nil, err
+			}
+			sawReport = true
+		case "message/rfc822", "text/rfc822-headers":
+			r.parseOriginal(body)
+		}
+	}
+	if !sawReport {
+		return nil, ErrNotFeedbackReport
+	}
+	return r, nil
+}
+
+// readPart returns the decoded content of a part. Quoted-printable is
+// decoded by the multipart reader.
+func readPart(part *multipart.Part) ([]byte, error) {
+	var rd io.Reader = part
+	if strings.EqualFold(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding")), "base64") {
+		rd = base64.NewDecoder(base64.StdEncoding, part)
+	}
+	return io.ReadAll(rd)
+}
+
+// parseFields reads the machine-readable message/feedback-report part.
+func (r *Report) parseFields(body []byte) error {
+	// The part is a header block; make sure it is terminated.
+	tp := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(body), strings.NewReader("\r\n\r\n"))))
+	fields, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return fmt.Errorf("fbl: read feedback-report fields: %w", err)
+	}
+	r.FeedbackType = strings.ToLower(strings.TrimSpace(fields.Get("Feedback-Type")))
+	r.UserAgent = strings.TrimSpace(fields.Get("User-Agent"))
+	r.SourceIP = strings.TrimSpace(fields.Get("Source-Ip"))
+	r.OriginalMailFrom = trimAddress(fields.Get("Original-Mail-From"))
+	for _, rcpt := range fields.Values("Original-Rcpt-To") {
+		if addr := trimAddress(rcpt); addr != "" {
+			r.OriginalRcptTo = append(r.OriginalRcptTo, addr)
+		}
+	}
+	return nil
+}
+
+// parseOriginal reads the Message-ID and To header of the reported message.
+// Reports whose original cannot be parsed keep the fields empty.
+func (r *Report) parseOriginal(body []byte) {
+	// text/rfc822-headers parts may lack the blank line ending the header.
+	orig, err := mail.ReadMessage(io.MultiReader(bytes.NewReader(body), strings.NewReader("\r\n\r\n")))
+	if err != nil {
+		return
+	}
+	r.MessageID = strings.TrimSpace(orig.Header.Get("Message-Id"))
+	if to, err := orig.Header.AddressList("To"); err == nil {
+		for _, a := range to {
+			r.OriginalTo = append(r.OriginalTo, a.Address)
+		}
+	}
+}
+
+// trimAddress strips the angle brackets of an envelope address.
+func trimAddress(s string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "<"), ">")
+}
@@ -0,0 +1,85 @@
+package fbl
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const sampleReport = "From: feedbackloop@mail.example.net\r\n" +
+	"To: fbl@fbl.example.com\r\n" +
+	"Subject: FW: Spring sale\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=feedback-report; boundary=\"part\"\r\n" +
+	"\r\n" +
+	"--part\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"This is an email abuse report.\r\n" +
+	"--part\r\n" +
+	"Content-Type: message/feedback-report\r\n" +
+	"\r\n" +
+	"Feedback-Type: abuse\r\n" +
+	"User-Agent: ExampleFBL/1.0\r\n" +
+	"Version: 1\r\n" +
+	"Original-Mail-From: <bounces@example.com>\r\n" +
+	"Original-Rcpt-To: <alice@mail.example.net>\r\n" +
+	"Source-IP: 192.0.2.10\r\n" +
+	"--part\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"From: news@example.com\r\n" +
+	"To: Alice <alice@mail.example.net>\r\n" +
+	"Message-ID: <sale-1@example.com>\r\n" +
+	"Subject: Spring sale\r\n" +
+	"\r\n" +
+	"Everything must go.\r\n" +
+	"--part--\r\n"
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(sampleReport))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if r.FeedbackType != "abuse" || r.UserAgent != "ExampleFBL/1.0" || r.SourceIP != "192.0.2.10" {
+		t.Errorf("fields = %+v", r)
+	}
+	if r.OriginalMailFrom != "bounces@example.com" || r.MessageID != "<sale-1@example.com>" {
+		t.Errorf("original = %q, %q", r.OriginalMailFrom, r.MessageID)
+	}
+	if got := r.Recipients(); !slices.Equal(got, []string{"alice@mail.example.net"}) {
+		t.Errorf("Recipients() = %v", got)
+	}
+}
+
+func TestParse_RedactedRecipientHeadersOnly(t *testing.T) {
+	raw := strings.NewReplacer(
+		"Original-Rcpt-To: <alice@mail.example.net>\r\n", "",
+		"Content-Type: message/rfc822", "Content-Type: text/rfc822-headers",
+		"\r\nEverything must go.\r\n", "",
+	).Replace(sampleReport)
+
+	r, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if r.MessageID != "<sale-1@example.com>" {
+		t.Errorf("MessageID = %q", r.MessageID)
+	}
+	if got := r.Recipients(); !slices.Equal(got, []string{"alice@mail.example.net"}) {
+		t.Errorf("Recipients() = %v, want the To header of the original", got)
+	}
+}
+
+func TestParse_NotFeedbackReport(t *testing.T) {
+	for name, raw := range map[string]string{
+		"plain":     "From: a@example.com\r\nSubject: hi\r\n\r\nhello\r\n",
+		"dsn":       strings.Replace(sampleReport, "report-type=feedback-report", "report-type=delivery-status", 1),
+		"no fields": strings.Replace(sampleReport, "message/feedback-report", "text/plain", 1),
+	} {
+		if _, err := Parse([]byte(raw)); !errors.Is(err, ErrNotFeedbackReport) {
+			t.Errorf("%s: Parse() error = %v, want ErrNotFeedbackReport", name, err)
+		}
+	}
+}
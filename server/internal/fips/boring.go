@@ -0,0 +1,15 @@
+//go:build boringcrypto
+
+package fips
+
+import (
+	"crypto/boring"
+
+	// Restrict TLS to FIPS-approved settings, as the Go Cryptographic
+	// Module does in FIPS 140-3 mode.
+	_ "crypto/tls/fipsonly"
+)
+
+func boringEnabled() bool {
+	return boring.Enabled()
+}
@@ -0,0 +1,34 @@
+// Package fips reports whether the process runs in FIPS 140 mode and lets
+// components select FIPS-approved cryptography when it does.
+//
+// FIPS mode is active when the binary runs the Go Cryptographic Module in
+// FIPS 140-3 mode (built with GOFIPS140, or run with GODEBUG=fips140=on)
+// or was built with GOEXPERIMENT=boringcrypto. Either way crypto/tls only
+// negotiates approved versions, cipher suites and curves. Binaries built
+// with the fips tag require FIPS mode: Check fails without it.
+package fips
+
+import (
+	"crypto/fips140"
+	"errors"
+)
+
+// Enabled reports whether FIPS mode is active.
+func Enabled() bool {
+	return fips140.Enabled() || boringEnabled()
+}
+
+// Required reports whether the binary was built with the fips tag.
+func Required() bool {
+	return required
+}
+
+// Check fails when the binary was built with the fips tag but FIPS mode is
+// not active, so a regulated deployment never silently runs without it.
+// The daemons call it at startup.
+func Check() error {
+	if required && !Enabled() {
+		return errors.New("built with the fips tag but FIPS mode is not active: build with GOFIPS140=v1.0.0 or GOEXPERIMENT=boringcrypto, or run with GODEBUG=fips140=on")
+	}
+	return nil
+}
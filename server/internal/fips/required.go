@@ -0,0 +1,5 @@
+//go:build fips
+
+package fips
+
+const required = true
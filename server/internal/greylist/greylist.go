@@ -0,0 +1,233 @@
+// Package greylist implements greylisting for SMTP listeners that accept
+// unauthenticated mail. The first delivery attempt for an unknown
+// (client network, sender, recipient) triplet is temporarily rejected;
+// legitimate MTAs retry after the delay and are let through, while most
+// spam software never retries.
+//
+// State is kept in Redis so every SMTP server instance shares it.
+package greylist
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config holds greylisting configuration.
+type Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Delay is how long an unknown triplet is deferred.
+	Delay time.Duration `mapstructure:"delay"`
+	// RetryWindow is how long a deferred triplet is remembered. A retry
+	// after the window is treated as a new triplet.
+	RetryWindow time.Duration `mapstructure:"retry_window"`
+	// KnownSenderTTL is how long a client network and sender domain that
+	// passed greylisting bypass it for further messages.
+	KnownSenderTTL time.Duration `mapstructure:"known_sender_ttl"`
+	// Allowlist bypasses greylisting for client IPs ("192.0.2.10"),
+	// networks ("198.51.100.0/24"), sender domains ("example.com") and
+	// sender addresses ("alerts@example.com").
+	Allowlist []string `mapstructure:"allowlist"`
+}
+
+// DefaultConfig returns the default greylisting configuration.
+func DefaultConfig() Config {
+	return Config{
+		Delay:          5 * time.Minute,
+		RetryWindow:    4 * time.Hour,
+		KnownSenderTTL: 36 * 24 * time.Hour,
+	}
+}
+
+// store is the key-value storage used by the Greylister. It is satisfied
+// by redisStore in production and by an in-memory fake in tests.
+type store interface {
+	// setNX stores value under key with ttl unless the key exists. It
+	// returns the value stored under key after the call.
+	setNX(ctx context.Context, key, value string, ttl time.Duration) (string, error)
+	exists(ctx context.Context, key string) (bool, error)
+	set(ctx context.Context, key, value string, ttl time.Duration) error
+	del(ctx context.Context, key string) error
+}
+
+// Greylister decides whether a delivery attempt is accepted or deferred.
+type Greylister struct {
+	store   store
+	cfg     Config
+	nets    []netip.Prefix
+	senders map[string]bool
+	now     func() time.Time
+}
+
+// New creates a Greylister backed by the given Redis client. It returns an
+// error when an allowlist entry cannot be parsed.
+func New(client *redis.Client, cfg Config) (*Greylister, error) {
+	return newGreylister(&redisStore{client: client}, cfg)
+}
+
+func newGreylister(s store, cfg Config) (*Greylister, error) {
+	def := DefaultConfig()
+	if cfg.Delay <= 0 {
+		cfg.Delay = def.Delay
+	}
+	if cfg.RetryWindow <= cfg.Delay {
+		cfg.RetryWindow = max(def.RetryWindow, cfg.Delay*2)
+	}
+	if cfg.KnownSenderTTL <= 0 {
+		cfg.KnownSenderTTL = def.KnownSenderTTL
+	}
+
+	g := &Greylister{store: s, cfg: cfg, senders: make(map[string]bool), now: time.Now}
+	for _, entry := range cfg.Allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("greylist allowlist entry %q: %w", entry, err)
+			}
+			g.nets = append(g.nets, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				g.nets = append(g.nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+			g.senders[entry] = true
+		}
+	}
+	return g, nil
+}
+
+// Result is the outcome of a greylisting check.
+type Result struct {
+	// Allowed reports whether the attempt may proceed.
+	Allowed bool
+	// RetryAfter is how long a deferred client must wait before retrying.
+	RetryAfter time.Duration
+	// Reason explains the decision for logging: "allowlist",
+	// "known_sender", "passed", "new" or "too_early".
+	Reason string
+}
+
+// Check greylists a delivery attempt from ip for sender and recipient.
+// Storage errors fail open: the attempt is allowed and the error returned
+// so the caller can log it.
+func (g *Greylister) Check(ctx context.Context, ip netip.Addr, sender, recipient string) (Result, error) {
+	ip = ip.Unmap()
+	sender = strings.ToLower(sender)
+	recipient = strings.ToLower(recipient)
+
+	if g.allowlisted(ip, sender) {
+		return Result{Allowed: true, Reason: "allowlist"}, nil
+	}
+
+	network := clientNetwork(ip)
+	knownKey := "greylist:known:" + network + ":" + senderDomain(sender)
+	known, err := g.store.exists(ctx, knownKey)
+	if err != nil {
+		return Result{Allowed: true, Reason: "error"}, fmt.Errorf("check known sender: %w", err)
+	}
+	if known {
+		return Result{Allowed: true, Reason: "known_sender"}, nil
+	}
+
+	now := g.now()
+	tripletKey := "greylist:triplet:" + network + ":" + sender + ":" + recipient
+	stored, err := g.store.setNX(ctx, tripletKey, strconv.FormatInt(now.Unix(), 10), g.cfg.RetryWindow)
+	if err != nil {
+		return Result{Allowed: true, Reason: "error"}, fmt.Errorf("record triplet: %w", err)
+	}
+	firstUnix, err := strconv.ParseInt(stored, 10, 64)
+	if err != nil {
+		return Result{Allowed: true, Reason: "error"}, fmt.Errorf("parse triplet timestamp: %w", err)
+	}
+
+	first := time.Unix(firstUnix, 0)
+	if wait := first.Add(g.cfg.Delay).Sub(now); wait > 0 {
+		reason := "too_early"
+		if firstUnix == now.Unix() {
+			reason = "new"
+		}
+		return Result{RetryAfter: wait, Reason: reason}, nil
+	}
+
+	// The client retried after the delay: remember the network and sender
+	// domain so further messages are not delayed.
+	if err := g.store.set(ctx, knownKey, "1", g.cfg.KnownSenderTTL); err != nil {
+		return Result{Allowed: true, Reason: "passed"}, fmt.Errorf("record known sender: %w", err)
+	}
+	if err := g.store.del(ctx, tripletKey); err != nil {
+		return Result{Allowed: true, Reason: "passed"}, fmt.Errorf("delete triplet: %w", err)
+	}
+	return Result{Allowed: true, Reason: "passed"}, nil
+}
+
+func (g *Greylister) allowlisted(ip netip.Addr, sender string) bool {
+	for _, n := range g.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	if len(g.senders) == 0 || sender == "" {
+		return false
+	}
+	return g.senders[sender] || g.senders[senderDomain(sender)]
+}
+
+// clientNetwork groups client addresses the way large senders spread
+// retries across hosts: IPv4 by /24 and IPv6 by /64.
+func clientNetwork(ip netip.Addr) string {
+	bits := 64
+	if ip.Is4() {
+		bits = 24
+	}
+	if !ip.IsValid() {
+		return "unknown"
+	}
+	prefix, _ := ip.Prefix(bits)
+	return prefix.String()
+}
+
+// senderDomain returns the domain of an address, or the whole value when
+// it has none (including the null sender "").
+func senderDomain(sender string) string {
+	if at := strings.LastIndexByte(sender, '@'); at >= 0 {
+		return sender[at+1:]
+	}
+	return sender
+}
+
+// redisStore implements store with Redis.
+type redisStore struct {
+	client *redis.Client
+}
+
+func (s *redisStore) setNX(ctx context.Context, key, value string, ttl time.Duration) (string, error) {
+	ok, err := s.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return value, nil
+	}
+	return s.client.Get(ctx, key).Result()
+}
+
+func (s *redisStore) exists(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+func (s *redisStore) set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *redisStore) del(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
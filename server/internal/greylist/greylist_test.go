@@ -0,0 +1,150 @@
+package greylist
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory store that ignores TTLs.
+type memStore struct {
+	data map[string]string
+	err  error
+}
+
+func newMemStore() *memStore {
+	return &memStore{data: make(map[string]string)}
+}
+
+func (m *memStore) setNX(_ context.Context, key, value string, _ time.Duration) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if v, ok := m.data[key]; ok {
+		return v, nil
+	}
+	m.data[key] = value
+	return value, nil
+}
+
+func (m *memStore) exists(_ context.Context, key string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	_, ok := m.data[key]
+	return ok, nil
+}
+
+func (m *memStore) set(_ context.Context, key, value string, _ time.Duration) error {
+	m.data[key] = value
+	return nil
+}
+
+func (m *memStore) del(_ context.Context, key string) error {
+	delete(m.data, key)
+	return nil
+}
+
+func newTestGreylister(t *testing.T, s store, cfg Config) (*Greylister, *time.Time) {
+	t.Helper()
+	g, err := newGreylister(s, cfg)
+	if err != nil {
+		t.Fatalf("newGreylister: %v", err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestCheck_DefersThenPasses(t *testing.T) {
+	g, now := newTestGreylister(t, newMemStore(), Config{Delay: 5 * time.Minute})
+	ctx := context.Background()
+	ip := netip.MustParseAddr("192.0.2.10")
+
+	res, err := g.Check(ctx, ip, "sender@example.com", "rcpt@inbound.test")
+	if err != nil || res.Allowed || res.Reason != "new" || res.RetryAfter != 5*time.Minute {
+		t.Fatalf("first attempt = %+v, %v; want deferred as new", res, err)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	res, _ = g.Check(ctx, ip, "sender@example.com", "rcpt@inbound.test")
+	if res.Allowed || res.Reason != "too_early" || res.RetryAfter != 3*time.Minute {
+		t.Fatalf("early retry = %+v; want deferred for 3m", res)
+	}
+
+	// A retry from another host in the same /24 counts as the same client.
+	*now = now.Add(4 * time.Minute)
+	res, _ = g.Check(ctx, netip.MustParseAddr("192.0.2.77"), "sender@example.com", "rcpt@inbound.test")
+	if !res.Allowed || res.Reason != "passed" {
+		t.Fatalf("retry after delay = %+v; want passed", res)
+	}
+
+	// The sender domain is now known for this network.
+	res, _ = g.Check(ctx, ip, "other@example.com", "someone@inbound.test")
+	if !res.Allowed || res.Reason != "known_sender" {
+		t.Fatalf("known sender = %+v; want allowed", res)
+	}
+}
+
+func TestCheck_Allowlist(t *testing.T) {
+	g, _ := newTestGreylister(t, newMemStore(), Config{
+		Allowlist: []string{"198.51.100.0/24", "203.0.113.5", "partner.test", "alerts@example.com"},
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		ip     string
+		sender string
+		want   bool
+	}{
+		{"198.51.100.42", "x@unknown.test", true},
+		{"203.0.113.5", "x@unknown.test", true},
+		{"192.0.2.1", "billing@partner.test", true},
+		{"192.0.2.1", "alerts@example.com", true},
+		{"192.0.2.1", "news@example.com", false},
+	}
+	for _, tt := range tests {
+		res, err := g.Check(ctx, netip.MustParseAddr(tt.ip), tt.sender, "rcpt@inbound.test")
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		if res.Allowed != tt.want {
+			t.Errorf("Check(%s, %s) allowed = %v, want %v", tt.ip, tt.sender, res.Allowed, tt.want)
+		}
+	}
+}
+
+func TestCheck_StoreErrorFailsOpen(t *testing.T) {
+	s := newMemStore()
+	s.err = errors.New("redis down")
+	g, _ := newTestGreylister(t, s, Config{})
+
+	res, err := g.Check(context.Background(), netip.MustParseAddr("192.0.2.10"), "a@example.com", "b@inbound.test")
+	if err == nil {
+		t.Error("expected the store error to be returned")
+	}
+	if !res.Allowed {
+		t.Error("expected the attempt to be allowed when the store fails")
+	}
+}
+
+func TestNew_InvalidAllowlist(t *testing.T) {
+	if _, err := newGreylister(newMemStore(), Config{Allowlist: []string{"10.0.0.0/99"}}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
+
+func TestClientNetwork(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.10":            "192.0.2.0/24",
+		"2001:db8:1:2:3:4:5:6":  "2001:db8:1:2::/64",
+		"::ffff:198.51.100.200": "198.51.100.0/24",
+	}
+	for in, want := range tests {
+		if got := clientNetwork(netip.MustParseAddr(in).Unmap()); got != want {
+			t.Errorf("clientNetwork(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package htmlutil
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// simpleSelector matches selectors made of an optional element name followed
+// by any number of .class and #id parts, e.g. "td", ".btn", "a.btn#cta".
+var simpleSelector = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*|\*)?((?:[.#][a-zA-Z_][a-zA-Z0-9_-]*)*)$`)
+
+// cssComment matches /* ... */ comments.
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// cssRule is a stylesheet rule with a single simple selector.
+type cssRule struct {
+	element string
+	id      string
+	classes []string
+	decls   string
+	// specificity is ids, classes, elements; order is the rule's position
+	// in the document.
+	specificity [3]int
+	order       int
+}
+
+// InlineCSS copies rules from <style> blocks into the style attribute of
+// every element they match, as many mail clients ignore or strip <style>.
+// Only simple selectors (element, .class, #id and combinations of them)
+// are inlined; rules with other selectors, @media and other at-rules stay
+// in the <style> block, which is dropped once it is empty. Declarations
+// already in a style attribute take precedence over inlined ones.
+func InlineCSS(src string) string {
+	var rules []cssRule
+	var remaining []string
+	for rest := src; ; {
+		i := strings.IndexByte(rest, '<')
+		if i < 0 {
+			break
+		}
+		rest = rest[i:]
+		if strings.HasPrefix(rest, "<!--") {
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				break
+			}
+			rest = rest[4+end+3:]
+			continue
+		}
+		t, after, ok := parseTag(rest)
+		if !ok {
+			rest = rest[1:]
+			continue
+		}
+		rest = after
+		if t.name != "style" || t.closing {
+			continue
+		}
+		body, next := rawText(rest, "style")
+		kept, parsed := parseStylesheet(body, len(rules))
+		rules = append(rules, parsed...)
+		remaining = append(remaining, kept)
+		rest = next
+	}
+	if len(rules) == 0 {
+		return src
+	}
+
+	var b strings.Builder
+	b.Grow(len(src) + len(src)/4)
+	block := 0
+	for len(src) > 0 {
+		i := strings.IndexByte(src, '<')
+		if i < 0 {
+			b.WriteString(src)
+			break
+		}
+		b.WriteString(src[:i])
+		src = src[i:]
+
+		if strings.HasPrefix(src, "<!--") {
+			end := strings.Index(src[4:], "-->")
+			if end < 0 {
+				b.WriteString(src)
+				break
+			}
+			b.WriteString(src[:4+end+3])
+			src = src[4+end+3:]
+			continue
+		}
+
+		t, rest, ok := parseTag(src)
+		if !ok || t.name == "" || t.closing {
+			end := len(src) - len(rest)
+			if !ok {
+				end = 1
+			}
+			b.WriteString(src[:end])
+			src = src[end:]
+			continue
+		}
+		src = rest
+
+		if t.name == "style" {
+			_, after := rawText(src, "style")
+			if block < len(remaining) && strings.TrimSpace(remaining[block]) != "" {
+				b.WriteString("<style")
+				writeAttrs(&b, t.attrs, nil)
+				b.WriteString(">")
+				b.WriteString(remaining[block])
+				b.WriteString("</style>")
+			}
+			block++
+			src = after
+			continue
+		}
+
+		applyRules(&t, rules)
+		writeTag(&b, t, nil)
+	}
+	return b.String()
+}
+
+// parseStylesheet splits css into rules that can be inlined and the text
+// that must stay in the <style> block. order is the position of the first
+// returned rule.
+func parseStylesheet(css string, order int) (string, []cssRule) {
+	css = cssComment.ReplaceAllString(css, "")
+
+	var kept strings.Builder
+	var rules []cssRule
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			break
+		}
+
+		if css[0] == '@' {
+			end := atRuleEnd(css)
+			kept.WriteString(css[:end])
+			kept.WriteByte('\n')
+			css = css[end:]
+			continue
+		}
+
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		closeIdx := strings.IndexByte(css[open:], '}')
+		if closeIdx < 0 {
+			break
+		}
+		closeIdx += open
+		selectors := css[:open]
+		decls := strings.TrimSpace(strings.Trim(strings.TrimSpace(css[open+1:closeIdx]), ";"))
+		css = css[closeIdx+1:]
+
+		var complex []string
+		for _, sel := range strings.Split(selectors, ",") {
+			sel = strings.TrimSpace(sel)
+			rule, ok := parseSelector(sel)
+			if !ok {
+				complex = append(complex, sel)
+				continue
+			}
+			rule.decls = decls
+			rule.order = order
+			order++
+			rules = append(rules, rule)
+		}
+		if len(complex) > 0 {
+			kept.WriteString(strings.Join(complex, ", "))
+			kept.WriteString(" { ")
+			kept.WriteString(decls)
+			kept.WriteString(" }\n")
+		}
+	}
+	return kept.String(), rules
+}
+
+// atRuleEnd returns the length of the at-rule at the start of css, either up
+// to its terminating semicolon or its balanced closing brace.
+func atRuleEnd(css string) int {
+	depth := 0
+	for i := 0; i < len(css); i++ {
+		switch css[i] {
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(css)
+}
+
+// parseSelector parses a simple selector into a rule without declarations.
+func parseSelector(sel string) (cssRule, bool) {
+	m := simpleSelector.FindStringSubmatch(sel)
+	if m == nil || sel == "" {
+		return cssRule{}, false
+	}
+
+	var r cssRule
+	if m[1] != "" && m[1] != "*" {
+		r.element = strings.ToLower(m[1])
+		r.specificity[2] = 1
+	}
+	parts := m[2]
+	for parts != "" {
+		kind := parts[0]
+		end := strings.IndexAny(parts[1:], ".#")
+		var name string
+		if end < 0 {
+			name, parts = parts[1:], ""
+		} else {
+			name, parts = parts[1:end+1], parts[end+1:]
+		}
+		if kind == '#' {
+			if r.id != "" && r.id != name {
+				return cssRule{}, false
+			}
+			r.id = name
+			r.specificity[0]++
+		} else {
+			r.classes = append(r.classes, name)
+			r.specificity[1]++
+		}
+	}
+	return r, true
+}
+
+// applyRules prepends the declarations of every matching rule, in
+// specificity order, to the tag's style attribute.
+func applyRules(t *tag, rules []cssRule) {
+	var id, style string
+	var classes []string
+	styleIdx := -1
+	for i, a := range t.attrs {
+		switch a.name {
+		case "id":
+			id = a.value
+		case "class":
+			classes = strings.Fields(a.value)
+		case "style":
+			style = a.value
+			styleIdx = i
+		}
+	}
+
+	var matched []cssRule
+	for _, r := range rules {
+		if r.matches(t.name, id, classes) {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		for k := range a.specificity {
+			if a.specificity[k] != b.specificity[k] {
+				return a.specificity[k] < b.specificity[k]
+			}
+		}
+		return a.order < b.order
+	})
+
+	parts := make([]string, 0, len(matched)+1)
+	for _, r := range matched {
+		if r.decls != "" {
+			parts = append(parts, r.decls)
+		}
+	}
+	if s := strings.TrimSpace(strings.Trim(strings.TrimSpace(style), ";")); s != "" {
+		parts = append(parts, s)
+	}
+	merged := strings.Join(parts, "; ")
+
+	if styleIdx >= 0 {
+		t.attrs[styleIdx].value = merged
+		t.attrs[styleIdx].bare = false
+	} else {
+		t.attrs = append(t.attrs, attr{name: "style", value: merged})
+	}
+}
+
+func (r cssRule) matches(element, id string, classes []string) bool {
+	if r.element != "" && r.element != element {
+		return false
+	}
+	if r.id != "" && r.id != id {
+		return false
+	}
+	for _, want := range r.classes {
+		found := false
+		for _, c := range classes {
+			if c == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
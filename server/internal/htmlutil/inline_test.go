@@ -0,0 +1,55 @@
+package htmlutil
+
+import "testing"
+
+func TestInlineCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "no style block unchanged",
+			in:   `<p class="x">Hi</p>`,
+			want: `<p class="x">Hi</p>`,
+		},
+		{
+			name: "element and class rules inlined, block dropped",
+			in:   `<style>p { color: red; } .big { font-size: 20px }</style><p class="big">Hi</p><p>There</p>`,
+			want: `<p class="big" style="color: red; font-size: 20px">Hi</p><p style="color: red">There</p>`,
+		},
+		{
+			name: "specificity orders declarations",
+			in:   `<style>#cta { color: green } a.btn { color: blue } a { color: red }</style><a id="cta" class="btn">x</a>`,
+			want: `<a id="cta" class="btn" style="color: red; color: blue; color: green">x</a>`,
+		},
+		{
+			name: "existing style attribute wins",
+			in:   `<style>td { padding: 4px }</style><td style="padding: 0;">x</td>`,
+			want: `<td style="padding: 4px; padding: 0">x</td>`,
+		},
+		{
+			name: "selector lists split",
+			in:   `<style>h1, h2 { margin: 0 }</style><h1>a</h1><h2>b</h2>`,
+			want: `<h1 style="margin: 0">a</h1><h2 style="margin: 0">b</h2>`,
+		},
+		{
+			name: "media queries and complex selectors kept",
+			in:   `<style>@media (max-width: 600px) { p { width: 100% } } div p { color: red } p { margin: 0 }</style><p>x</p>`,
+			want: `<style>@media (max-width: 600px) { p { width: 100% } }` + "\n" + `div p { color: red }` + "\n" + `</style><p style="margin: 0">x</p>`,
+		},
+		{
+			name: "comments stripped from css and html comments kept",
+			in:   `<style>/* brand */ b { color: red }</style><!--[if mso]><b>x</b><![endif]--><b>y</b>`,
+			want: `<!--[if mso]><b>x</b><![endif]--><b style="color: red">y</b>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InlineCSS(tt.in); got != tt.want {
+				t.Errorf("InlineCSS()\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+// Package htmlutil provides lightweight HTML processing for email bodies.
+// It works on the token level rather than building a DOM, which is enough
+// for the well-formed markup produced by templates and mail clients.
+package htmlutil
+
+import (
+	"html"
+	"strings"
+)
+
+// droppedElements are removed together with their content.
+var droppedElements = map[string]bool{
+	"script":   true,
+	"iframe":   true,
+	"frame":    true,
+	"frameset": true,
+	"object":   true,
+	"embed":    true,
+	"applet":   true,
+	"base":     true,
+}
+
+// urlAttributes hold URLs and are checked for script schemes.
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"background": true,
+	"poster":     true,
+	"xlink:href": true,
+}
+
+// Sanitize removes scriptable content from an HTML document: script,
+// iframe, object and similar elements including their content, event
+// handler attributes, javascript: and vbscript: URLs, and CSS expressions.
+// Other markup, including <style> blocks, conditional comments and the
+// doctype, is preserved; other comments and declarations are dropped.
+func Sanitize(src string) string {
+	var b strings.Builder
+	b.Grow(len(src))
+
+	for len(src) > 0 {
+		i := strings.IndexByte(src, '<')
+		if i < 0 {
+			b.WriteString(src)
+			break
+		}
+		b.WriteString(src[:i])
+		src = src[i:]
+
+		// Conditional comments are copied verbatim and other comments are
+		// dropped; an unterminated comment swallows the rest of the
+		// document, as it would in a browser.
+		if strings.HasPrefix(src, "<!--") {
+			n := commentLen(src)
+			if n < 0 {
+				break
+			}
+			if conditionalComment(src[:n]) {
+				b.WriteString(src[:n])
+			}
+			src = src[n:]
+			continue
+		}
+
+		t, rest, ok := parseTag(src)
+		if !ok {
+			b.WriteString("&lt;")
+			src = src[1:]
+			continue
+		}
+		src = rest
+
+		if t.name == "" {
+			// Only a doctype is kept; other declarations and processing
+			// instructions are dropped.
+			b.WriteString(doctype(t.raw))
+			continue
+		}
+		if droppedElements[t.name] {
+			if !t.closing && !t.selfClosing {
+				src = skipElement(src, t.name)
+			}
+			continue
+		}
+		if t.name == "style" && !t.closing {
+			body, after := rawText(src, "style")
+			b.WriteString("<style")
+			writeAttrs(&b, t.attrs, safeAttr)
+			b.WriteString(">")
+			if !unsafeCSS(body) {
+				b.WriteString(body)
+			}
+			b.WriteString("</style>")
+			src = after
+			continue
+		}
+
+		writeTag(&b, t, safeAttr)
+	}
+	return b.String()
+}
+
+// commentLen returns the length of the comment at the start of s, which
+// starts with "<!--", or -1 if it is never closed. Like a browser, it ends
+// the comment at the first "-->" or "--!>", and at once for "<!-->" and
+// "<!--->".
+func commentLen(s string) int {
+	if strings.HasPrefix(s, "<!-->") {
+		return len("<!-->")
+	}
+	if strings.HasPrefix(s, "<!--->") {
+		return len("<!--->")
+	}
+	end := -1
+	for _, term := range []string{"-->", "--!>"} {
+		if i := strings.Index(s[4:], term); i >= 0 && (end < 0 || 4+i+len(term) < end) {
+			end = 4 + i + len(term)
+		}
+	}
+	return end
+}
+
+// conditionalComment reports whether the comment c is the start or end of
+// an Outlook conditional comment, such as "<!--[if mso]>...<![endif]-->"
+// or the "<!--<![endif]-->" that closes a downlevel-revealed one.
+func conditionalComment(c string) bool {
+	body := strings.ToLower(c[4:])
+	return strings.HasPrefix(body, "[if ") || strings.HasPrefix(body, "<![endif]")
+}
+
+// doctype returns the doctype declaration raw rebuilt from its words, or
+// an empty string if raw is not a doctype. A doctype with characters
+// beyond those of the public and system identifiers of common doctypes
+// is replaced with "<!DOCTYPE html>".
+func doctype(raw string) string {
+	if len(raw) < len("<!doctype>") || !strings.EqualFold(raw[:len("<!doctype")], "<!doctype") {
+		return ""
+	}
+	words := raw[len("<!doctype") : len(raw)-1]
+	for i := 0; i < len(words); i++ {
+		c := words[i]
+		if !isNameChar(c) && !isSpace(c) && !strings.ContainsRune(`"/.`, rune(c)) {
+			return "<!DOCTYPE html>"
+		}
+	}
+	return "<!DOCTYPE" + words + ">"
+}
+
+// tag is a parsed start or end tag.
+type tag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attrs       []attr
+	raw         string
+}
+
+type attr struct {
+	name  string
+	value string
+	bare  bool
+}
+
+// parseTag parses the tag at the start of s. It returns ok=false when s
+// does not start with a tag, in which case the '<' is literal text.
+// Declarations such as <!DOCTYPE> are returned with an empty name.
+func parseTag(s string) (tag, string, bool) {
+	if len(s) < 2 {
+		return tag{}, s, false
+	}
+	if s[1] == '!' || s[1] == '?' {
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return tag{}, s, false
+		}
+		return tag{raw: s[:end+1]}, s[end+1:], true
+	}
+
+	var t tag
+	i := 1
+	if s[i] == '/' {
+		t.closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && isNameChar(s[i]) {
+		i++
+	}
+	if i == start || !isLetter(s[start]) {
+		return tag{}, s, false
+	}
+	t.name = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case '>':
+			return t, s[i+1:], true
+		case '/':
+			t.selfClosing = true
+			i++
+			continue
+		}
+
+		nameStart := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		a := attr{name: strings.ToLower(s[nameStart:i]), bare: true}
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			a.bare = false
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return tag{}, s, false
+				}
+				a.value = html.UnescapeString(s[i+1 : i+1+end])
+				i += end + 2
+			} else {
+				valStart := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				a.value = html.UnescapeString(s[valStart:i])
+			}
+		}
+		if a.name != "" {
+			t.attrs = append(t.attrs, a)
+		}
+	}
+	// Unterminated tag.
+	return tag{}, s, false
+}
+
+// skipElement returns s after the end tag of the named element, or an
+// empty string if the element is never closed.
+func skipElement(s, name string) string {
+	_, after := rawText(s, name)
+	return after
+}
+
+// rawText splits s at the end tag of the named element, returning the
+// element's content and the remainder after the end tag.
+func rawText(s, name string) (string, string) {
+	lower := strings.ToLower(s)
+	closing := "</" + name
+	from := 0
+	for {
+		i := strings.Index(lower[from:], closing)
+		if i < 0 {
+			return s, ""
+		}
+		i += from
+		j := i + len(closing)
+		if j >= len(s) || s[j] == '>' || isSpace(s[j]) || s[j] == '/' {
+			end := strings.IndexByte(s[j:], '>')
+			if end < 0 {
+				return s[:i], ""
+			}
+			return s[:i], s[j+end+1:]
+		}
+		from = j
+	}
+}
+
+// writeTag writes t, keeping only the attributes for which keep returns
+// true. A nil keep writes every attribute.
+func writeTag(b *strings.Builder, t tag, keep func(attr) bool) {
+	b.WriteByte('<')
+	if t.closing {
+		b.WriteByte('/')
+	}
+	b.WriteString(t.name)
+	if !t.closing {
+		writeAttrs(b, t.attrs, keep)
+	}
+	if t.selfClosing && !t.closing {
+		b.WriteString(" /")
+	}
+	b.WriteByte('>')
+}
+
+func writeAttrs(b *strings.Builder, attrs []attr, keep func(attr) bool) {
+	for _, a := range attrs {
+		if keep != nil && !keep(a) {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(a.name)
+		if a.bare {
+			continue
+		}
+		b.WriteString(`="`)
+		b.WriteString(html.EscapeString(a.value))
+		b.WriteByte('"')
+	}
+}
+
+// safeAttr reports whether an attribute may be kept.
+func safeAttr(a attr) bool {
+	for i := 0; i < len(a.name); i++ {
+		if !isNameChar(a.name[i]) && a.name[i] != '_' && a.name[i] != '.' {
+			return false
+		}
+	}
+	if strings.HasPrefix(a.name, "on") || a.name == "srcdoc" {
+		return false
+	}
+	if urlAttributes[a.name] && unsafeURL(a.value) {
+		return false
+	}
+	if a.name == "style" && unsafeCSS(a.value) {
+		return false
+	}
+	return true
+}
+
+// unsafeURL reports whether a URL uses a scheme that executes script.
+// Browsers ignore whitespace and control characters inside the scheme, so
+// they are removed before comparing.
+func unsafeURL(v string) bool {
+	var b strings.Builder
+	for _, r := range v {
+		if r > ' ' {
+			b.WriteRune(r)
+		}
+		if b.Len() >= 16 {
+			break
+		}
+	}
+	s := strings.ToLower(b.String())
+	return strings.HasPrefix(s, "javascript:") || strings.HasPrefix(s, "vbscript:") ||
+		(strings.HasPrefix(s, "data:") && !strings.HasPrefix(s, "data:image/"))
+}
+
+// unsafeCSS reports whether CSS contains constructs that can run script
+// in some mail clients or browsers.
+func unsafeCSS(v string) bool {
+	s := strings.ToLower(v)
+	return strings.Contains(s, "expression(") ||
+		strings.Contains(s, "javascript:") ||
+		strings.Contains(s, "behavior:") ||
+		strings.Contains(s, "-moz-binding")
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '-' || c == ':'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
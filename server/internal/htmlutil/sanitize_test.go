@@ -0,0 +1,155 @@
+package htmlutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "plain markup kept",
+			in:   `<p class="x">Hello <b>world</b></p>`,
+			want: `<p class="x">Hello <b>world</b></p>`,
+		},
+		{
+			name: "script removed with content",
+			in:   `<p>a</p><script type="text/javascript">alert(1)</script><p>b</p>`,
+			want: `<p>a</p><p>b</p>`,
+		},
+		{
+			name: "uppercase script and end tag",
+			in:   `x<SCRIPT>alert(1)</Script >y`,
+			want: `xy`,
+		},
+		{
+			name: "iframe removed",
+			in:   `<div><iframe src="https://evil.example"></iframe></div>`,
+			want: `<div></div>`,
+		},
+		{
+			name: "unclosed script drops rest",
+			in:   `ok<script>alert(1)`,
+			want: `ok`,
+		},
+		{
+			name: "event handlers removed",
+			in:   `<img src="a.png" onerror="alert(1)" alt="A">`,
+			want: `<img src="a.png" alt="A">`,
+		},
+		{
+			name: "javascript url removed",
+			in:   `<a href=" java	script:alert(1)">x</a>`,
+			want: `<a>x</a>`,
+		},
+		{
+			name: "data image allowed",
+			in:   `<img src="data:image/png;base64,AAAA">`,
+			want: `<img src="data:image/png;base64,AAAA">`,
+		},
+		{
+			name: "data html blocked",
+			in:   `<a href="data:text/html,<script>">x</a>`,
+			want: `<a>x</a>`,
+		},
+		{
+			name: "css expression removed from style attribute",
+			in:   `<td style="width: expression(alert(1))">x</td>`,
+			want: `<td>x</td>`,
+		},
+		{
+			name: "style block kept",
+			in:   `<style type="text/css">p { color: red; }</style>`,
+			want: `<style type="text/css">p { color: red; }</style>`,
+		},
+		{
+			name: "unsafe style block emptied",
+			in:   `<style>p { behavior: url(x.htc); }</style><p>x</p>`,
+			want: `<style></style><p>x</p>`,
+		},
+		{
+			name: "comments and doctype kept",
+			in:   `<!DOCTYPE html><!--[if mso]><table><![endif]--><p>x</p>`,
+			want: `<!DOCTYPE html><!--[if mso]><table><![endif]--><p>x</p>`,
+		},
+		{
+			name: "downlevel-revealed conditional comment kept",
+			in:   `<!--[if !mso]><!--><p>x</p><!--<![endif]-->`,
+			want: `<!--[if !mso]><!--><p>x</p><!--<![endif]-->`,
+		},
+		{
+			name: "plain comment dropped",
+			in:   `a<!-- note -->b`,
+			want: `ab`,
+		},
+		{
+			name: "abruptly closed empty comment",
+			in:   `<!--><script>alert(1)</script>-->`,
+			want: `-->`,
+		},
+		{
+			name: "abruptly closed dash comment",
+			in:   `<!---><img src=x onerror=alert(1)>-->`,
+			want: `<img src="x">-->`,
+		},
+		{
+			name: "comment closed with bang",
+			in:   `<!--[if mso]>--!><script>alert(1)</script>-->`,
+			want: `<!--[if mso]>--!>-->`,
+		},
+		{
+			name: "declarations dropped",
+			in:   `<!ENTITY x "y"><?xml version="1.0"?><![CDATA[x]]><p>x</p>`,
+			want: `<p>x</p>`,
+		},
+		{
+			name: "xhtml doctype kept",
+			in:   `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
+			want: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
+		},
+		{
+			name: "stray less-than escaped",
+			in:   `1 < 2`,
+			want: `1 &lt; 2`,
+		},
+		{
+			name: "attribute values re-escaped",
+			in:   `<a title='say "hi"' href=/path>x</a>`,
+			want: `<a title="say &#34;hi&#34;" href="/path">x</a>`,
+		},
+		{
+			name: "self-closing and bare attributes",
+			in:   `<input type="checkbox" checked/><br>`,
+			want: `<input type="checkbox" checked /><br>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got  %q\n want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitize_NoScriptSurvives(t *testing.T) {
+	inputs := []string{
+		`<scr<script>ipt>alert(1)</script>`,
+		`<svg><script>alert(1)</script></svg>`,
+		`<object data="x.swf"><embed src="x.swf"></object>`,
+		`<a href="JaVaScRiPt:alert(1)" onclick='x()'>x</a>`,
+	}
+	for _, in := range inputs {
+		out := strings.ToLower(Sanitize(in))
+		for _, bad := range []string{"<script", "<object", "<embed", "javascript:", "onclick"} {
+			if strings.Contains(out, bad) {
+				t.Errorf("Sanitize(%q) = %q, contains %q", in, out, bad)
+			}
+		}
+	}
+}
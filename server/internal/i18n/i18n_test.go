@@ -0,0 +1,74 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"ko", "ko"},
+		{"ja-JP,ja;q=0.9,en;q=0.8", "ja"},
+		{"fr-CA, ko-KR;q=0.9, en;q=0.8", "ko"},
+		{"en;q=0.5, ja;q=0.7", "ja"},
+		{"ko;q=0, en", "en"},
+		{"ko;q=abc", ""},
+		{"fr, de", ""},
+		{"*", ""},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("ja", "access denied"); got != "アクセスが拒否されました" {
+		t.Errorf("Translate(ja) = %q", got)
+	}
+	if got := Translate("en", "access denied"); got != "access denied" {
+		t.Errorf("Translate(en) = %q", got)
+	}
+	if got := Translate("ko", "no such message"); got != "no such message" {
+		t.Errorf("Translate(untranslated) = %q", got)
+	}
+	if got := Translate("fr", "access denied"); got != "access denied" {
+		t.Errorf("Translate(unsupported) = %q", got)
+	}
+}
+
+// TestCatalogs checks that every catalog translates the same messages.
+func TestCatalogs(t *testing.T) {
+	if got := Locales(); len(got) != 3 || got[0] != "en" || got[1] != "ja" || got[2] != "ko" {
+		t.Fatalf("Locales() = %v", got)
+	}
+	for locale, messages := range catalogs {
+		if locale == Default {
+			continue
+		}
+		for other, otherMessages := range catalogs {
+			if other == Default {
+				continue
+			}
+			for message := range otherMessages {
+				if _, ok := messages[message]; !ok {
+					t.Errorf("%s catalog is missing %q from %s", locale, message, other)
+				}
+			}
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != "" {
+		t.Errorf("FromContext() = %q, want empty", got)
+	}
+	if got := FromContext(WithLocale(context.Background(), "ko")); got != "ko" {
+		t.Errorf("FromContext() = %q, want ko", got)
+	}
+}
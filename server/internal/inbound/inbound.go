@@ -0,0 +1,210 @@
+// Package inbound delivers mail received on the inbound SMTP listener to
+// HTTP endpoints ("inbound parse"). Each inbound route maps a recipient
+// domain to a URL that receives either the parsed message as JSON or the
+// raw message.
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sungwon/smtp-proxy/server/internal/mimeparse"
+)
+
+// Supported route formats.
+const (
+	// FormatJSON posts the parsed message as a Payload.
+	FormatJSON = "json"
+	// FormatRaw posts the message unchanged as message/rfc822.
+	FormatRaw = "raw"
+	// FormatARF does not post the message: it is processed as a complaint
+	// feedback report by the queue worker.
+	FormatARF = "arf"
+)
+
+// Request headers set on every post.
+const (
+	HeaderMessageID = "X-SMTPProxy-Message-ID"
+	HeaderFrom      = "X-SMTPProxy-From"
+	HeaderTo        = "X-SMTPProxy-To"
+	HeaderSignature = "X-SMTPProxy-Signature"
+)
+
+// DefaultTimeout bounds a single post to an inbound endpoint.
+const DefaultTimeout = 30 * time.Second
+
+// Envelope is the SMTP envelope of a received message.
+type Envelope struct {
+	MessageID  string
+	From       string
+	To         []string
+	ReceivedAt time.Time
+}
+
+// Payload is the JSON document posted for FormatJSON routes.
+type Payload struct {
+	ID          string              `json:"id"`
+	From        string              `json:"from"`
+	To          []string            `json:"to"`
+	Subject     string              `json:"subject"`
+	Headers     map[string][]string `json:"headers"`
+	Text        string              `json:"text,omitempty"`
+	HTML        string              `json:"html,omitempty"`
+	Attachments []Attachment        `json:"attachments,omitempty"`
+	ReceivedAt  time.Time           `json:"received_at"`
+}
+
+// Attachment is an attachment or inline part of a Payload. Content is
+// base64-encoded in JSON.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"`
+	Inline      bool   `json:"inline"`
+	Size        int    `json:"size"`
+	Content     []byte `json:"content"`
+}
+
+// Route is the destination of an inbound message.
+type Route struct {
+	URL    string
+	Format string
+	// Secret, when set, signs the request body with HMAC-SHA256. The hex
+	// digest is sent as "sha256=<digest>" in HeaderSignature.
+	Secret string
+}
+
+// PermanentError reports a response that will not succeed on retry, such
+// as 400 or 404. Other failures are temporary and should be retried.
+type PermanentError struct {
+	StatusCode int
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("inbound endpoint rejected message: HTTP %d", e.StatusCode)
+}
+
+// IsPermanent reports whether err is a PermanentError.
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// Poster posts inbound messages to route endpoints.
+type Poster struct {
+	client *http.Client
+}
+
+// NewPoster creates a Poster whose requests time out after timeout, or
+// DefaultTimeout when timeout is zero.
+func NewPoster(timeout time.Duration) *Poster {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Poster{client: &http.Client{Timeout: timeout}}
+}
+
+// NewPosterWithDialer creates a Poster like NewPoster whose connections are
+// made with dial, such as one that refuses non-public addresses.
+func NewPosterWithDialer(timeout time.Duration, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *Poster {
+	p := NewPoster(timeout)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dial
+	p.client.Transport = transport
+	return p
+}
+
+// BuildPayload parses raw into a Payload. Messages that cannot be parsed
+// as MIME are passed on with the raw content as the text body.
+func BuildPayload(env Envelope, raw []byte) Payload {
+	p := Payload{
+		ID:         env.MessageID,
+		From:       env.From,
+		To:         env.To,
+		ReceivedAt: env.ReceivedAt,
+	}
+	parsed, err := mimeparse.Parse(raw)
+	if err != nil {
+		p.Text = string(raw)
+		return p
+	}
+	p.Subject = parsed.Subject
+	p.Headers = map[string][]string(parsed.Headers)
+	p.Text = parsed.TextBody
+	p.HTML = parsed.HTMLBody
+	for _, att := range parsed.Attachments {
+		p.Attachments = append(p.Attachments, Attachment{
+			Filename:    att.Filename,
+			ContentType: att.ContentType,
+			ContentID:   att.ContentID,
+			Inline:      att.IsInline,
+			Size:        len(att.Content),
+			Content:     att.Content,
+		})
+	}
+	return p
+}
+
+// Post delivers the message to the route's endpoint and returns the HTTP
+// status code. Any 2xx response is a success.
+func (p *Poster) Post(ctx context.Context, route Route, env Envelope, raw []byte) (int, error) {
+	body := raw
+	contentType := "message/rfc822"
+	if route.Format != FormatRaw {
+		data, err := json.Marshal(BuildPayload(env, raw))
+		if err != nil {
+			return 0, fmt.Errorf("marshal payload: %w", err)
+		}
+		body = data
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, route.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, &PermanentError{}
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "smtp-proxy-inbound")
+	req.Header.Set(HeaderMessageID, env.MessageID)
+	req.Header.Set(HeaderFrom, env.From)
+	for _, to := range env.To {
+		req.Header.Add(HeaderTo, to)
+	}
+	if route.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(route.Secret, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("post to inbound endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.StatusCode, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return resp.StatusCode, &PermanentError{StatusCode: resp.StatusCode}
+	default:
+		return resp.StatusCode, fmt.Errorf("inbound endpoint returned HTTP %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,136 @@
+package inbound
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testEML = "From: Alice <alice@example.com>\r\n" +
+	"To: support@inbound.test\r\n" +
+	"Subject: Help\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"My order is missing.\r\n"
+
+func testEnvelope() Envelope {
+	return Envelope{
+		MessageID:  "6f1c2c1e-0000-4000-8000-000000000001",
+		From:       "alice@example.com",
+		To:         []string{"support@inbound.test"},
+		ReceivedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestPost_JSON(t *testing.T) {
+	var got Payload
+	var sig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		sig = r.Header.Get(HeaderSignature)
+		if want := "sha256=" + Sign("s3cret", body); sig != want {
+			t.Errorf("signature = %q, want %q", sig, want)
+		}
+		_ = json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	status, err := NewPoster(0).Post(context.Background(), Route{URL: srv.URL, Format: FormatJSON, Secret: "s3cret"}, testEnvelope(), []byte(testEML))
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("Post() = %d, %v", status, err)
+	}
+	if got.Subject != "Help" || got.From != "alice@example.com" || len(got.To) != 1 {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if got.Text != "My order is missing.\r\n" {
+		t.Errorf("text = %q", got.Text)
+	}
+}
+
+func TestPost_Raw(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != testEML {
+			t.Errorf("raw body changed: %q", body)
+		}
+		if r.Header.Get("Content-Type") != "message/rfc822" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get(HeaderTo) != "support@inbound.test" {
+			t.Errorf("%s = %q", HeaderTo, r.Header.Get(HeaderTo))
+		}
+		if r.Header.Get(HeaderSignature) != "" {
+			t.Error("unsigned route must not send a signature")
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := NewPoster(0).Post(context.Background(), Route{URL: srv.URL, Format: FormatRaw}, testEnvelope(), []byte(testEML)); err != nil {
+		t.Fatalf("Post() error: %v", err)
+	}
+}
+
+func TestPost_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		_, err := NewPoster(0).Post(context.Background(), Route{URL: srv.URL}, testEnvelope(), []byte(testEML))
+		srv.Close()
+		if err == nil {
+			t.Errorf("HTTP %d: expected error", tt.status)
+			continue
+		}
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("HTTP %d: permanent = %v, want %v", tt.status, IsPermanent(err), tt.permanent)
+		}
+	}
+}
+
+func TestNewPosterWithDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("refused dial must not reach the endpoint")
+	}))
+	defer srv.Close()
+
+	errRefused := errors.New("refused")
+	var dialed string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return nil, errRefused
+	}
+	_, err := NewPosterWithDialer(0, dial).Post(context.Background(), Route{URL: srv.URL}, testEnvelope(), []byte(testEML))
+	if !errors.Is(err, errRefused) {
+		t.Fatalf("Post() error = %v, want %v", err, errRefused)
+	}
+	if dialed != srv.Listener.Addr().String() {
+		t.Errorf("dialed %q, want %q", dialed, srv.Listener.Addr().String())
+	}
+}
+
+func TestBuildPayload_Unparseable(t *testing.T) {
+	p := BuildPayload(testEnvelope(), []byte("not a mime message"))
+	if p.Text != "not a mime message" {
+		t.Errorf("expected raw content as text, got %q", p.Text)
+	}
+}
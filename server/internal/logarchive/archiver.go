@@ -0,0 +1,239 @@
+package logarchive
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Config controls which delivery logs the archiver moves and how fast.
+type Config struct {
+	// After is the age at which delivery logs are archived.
+	After time.Duration
+	// Interval is the delay between archiver runs.
+	Interval time.Duration
+	// BatchSize is the number of delivery logs read per batch.
+	BatchSize int
+	// MaxBatches bounds the batches handled per run, so a large backlog
+	// is worked off over several runs.
+	MaxBatches int
+}
+
+// DefaultConfig returns sensible defaults for the archiver.
+func DefaultConfig() Config {
+	return Config{
+		After:      90 * 24 * time.Hour,
+		Interval:   1 * time.Hour,
+		BatchSize:  1000,
+		MaxBatches: 10,
+	}
+}
+
+// Result summarises one archiver run.
+type Result struct {
+	Archived int
+	Files    int
+}
+
+// Archiver periodically moves delivery logs older than Config.After into
+// archive files. A batch's files are written before its rows are indexed
+// and deleted in one transaction, so a failure leaves the rows in place to
+// be archived again by a later run.
+type Archiver struct {
+	queries storage.Querier
+	tx      storage.TxRunner
+	store   msgstore.MessageStore
+	config  Config
+	log     zerolog.Logger
+	now     func() time.Time
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+// NewArchiver creates an Archiver writing to store. Zero-valued config
+// fields fall back to DefaultConfig.
+func NewArchiver(queries storage.Querier, tx storage.TxRunner, store msgstore.MessageStore, cfg Config, log zerolog.Logger) *Archiver {
+	defaults := DefaultConfig()
+	if cfg.After <= 0 {
+		cfg.After = defaults.After
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.MaxBatches <= 0 {
+		cfg.MaxBatches = defaults.MaxBatches
+	}
+	return &Archiver{
+		queries: queries,
+		tx:      tx,
+		store:   store,
+		config:  cfg,
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// Start launches the archive loop in a background goroutine.
+func (a *Archiver) Start(ctx context.Context) {
+	ctx, a.cancel = context.WithCancel(ctx)
+
+	a.wg.Add(1)
+	go a.run(ctx)
+
+	a.log.Info().
+		Dur("after", a.config.After).
+		Dur("interval", a.config.Interval).
+		Msg("delivery log archiver started")
+}
+
+// Stop signals the archive loop to exit and waits for the current run.
+func (a *Archiver) Stop() {
+	if a.cancel != nil {
+		a.cancel()
+	}
+	a.wg.Wait()
+	a.log.Info().Msg("delivery log archiver stopped")
+}
+
+func (a *Archiver) run(ctx context.Context) {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.ArchiveOnce(ctx); err != nil && ctx.Err() == nil {
+				a.log.Error().Err(err).Msg("delivery log archiving failed")
+			}
+		}
+	}
+}
+
+// ArchiveOnce archives up to MaxBatches batches of delivery logs older
+// than Config.After.
+func (a *Archiver) ArchiveOnce(ctx context.Context) (Result, error) {
+	cutoff := a.now().Add(-a.config.After)
+	var result Result
+	for i := 0; i < a.config.MaxBatches; i++ {
+		n, files, err := a.archiveBatch(ctx, cutoff)
+		result.Archived += n
+		result.Files += files
+		if err != nil {
+			return result, err
+		}
+		if n < a.config.BatchSize {
+			break
+		}
+	}
+
+	if result.Archived > 0 {
+		a.log.Info().
+			Int("archived", result.Archived).
+			Int("files", result.Files).
+			Time("cutoff", cutoff).
+			Msg("delivery logs archived")
+	}
+	return result, nil
+}
+
+// archiveFile is one group's share of a batch.
+type archiveFile struct {
+	groupID pgtype.UUID
+	logs    []storage.DeliveryLog
+	key     string
+}
+
+func (a *Archiver) archiveBatch(ctx context.Context, cutoff time.Time) (int, int, error) {
+	logs, err := a.queries.ListDeliveryLogsToArchive(ctx, storage.ListDeliveryLogsToArchiveParams{
+		CreatedAt: pgtype.Timestamptz{Time: cutoff, Valid: true},
+		Limit:     int32(a.config.BatchSize),
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("list delivery logs: %w", err)
+	}
+	if len(logs) == 0 {
+		return 0, 0, nil
+	}
+
+	// Logs arrive oldest first, so each group's file covers a contiguous
+	// time range.
+	var files []*archiveFile
+	byGroup := make(map[pgtype.UUID]*archiveFile)
+	ids := make([]uuid.UUID, len(logs))
+	for i, l := range logs {
+		ids[i] = l.ID
+		f, ok := byGroup[l.GroupID]
+		if !ok {
+			f = &archiveFile{groupID: l.GroupID}
+			byGroup[l.GroupID] = f
+			files = append(files, f)
+		}
+		f.logs = append(f.logs, l)
+	}
+
+	for i, f := range files {
+		data, err := Encode(f.logs)
+		if err != nil {
+			a.discard(ctx, files[:i])
+			return 0, 0, err
+		}
+		f.key = ObjectKey(f.logs[0].CreatedAt.Time, uuid.New())
+		if err := a.store.Put(ctx, f.key, data); err != nil {
+			a.discard(ctx, files[:i])
+			return 0, 0, fmt.Errorf("write %s: %w", f.key, err)
+		}
+	}
+
+	err = a.tx.ExecTx(ctx, func(q storage.Querier) error {
+		for _, f := range files {
+			if _, err := q.CreateDeliveryLogArchive(ctx, storage.CreateDeliveryLogArchiveParams{
+				GroupID:        f.groupID,
+				ObjectKey:      f.key,
+				FirstCreatedAt: f.logs[0].CreatedAt,
+				LastCreatedAt:  f.logs[len(f.logs)-1].CreatedAt,
+				RowCount:       int32(len(f.logs)),
+			}); err != nil {
+				return fmt.Errorf("index %s: %w", f.key, err)
+			}
+		}
+		if _, err := q.DeleteArchivedDeliveryLogs(ctx, ids); err != nil {
+			return fmt.Errorf("delete archived delivery logs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		a.discard(ctx, files)
+		return 0, 0, err
+	}
+
+	metrics.DeliveryLogsArchivedTotal.Add(float64(len(logs)))
+	return len(logs), len(files), nil
+}
+
+// discard deletes files written for a batch that was not committed.
+func (a *Archiver) discard(ctx context.Context, files []*archiveFile) {
+	for _, f := range files {
+		if f.key == "" {
+			continue
+		}
+		if err := a.store.Delete(ctx, f.key); err != nil {
+			a.log.Warn().Err(err).Str("key", f.key).Msg("failed to delete uncommitted archive file")
+		}
+	}
+}
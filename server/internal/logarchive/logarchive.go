@@ -0,0 +1,159 @@
+// Package logarchive moves delivery logs older than a retention period out
+// of PostgreSQL into gzipped NDJSON files in the message store, and reads
+// them back for lookups that reach into archived time ranges.
+//
+// Each file holds one group's logs from one archiver batch, one JSON
+// encoded storage.DeliveryLog per line, so archived rows come back exactly
+// as they were stored. The delivery_log_archives table indexes the files
+// by group and time range.
+package logarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Querier is the subset of storage.Querier used to look up archived
+// delivery logs.
+type Querier interface {
+	ListGroupDeliveryLogArchives(ctx context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error)
+}
+
+// Encode returns logs as gzipped NDJSON.
+func Encode(logs []storage.DeliveryLog) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(zw)
+	for _, l := range logs {
+		if err := enc.Encode(l); err != nil {
+			return nil, fmt.Errorf("logarchive: encode delivery log %s: %w", l.ID, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("logarchive: compress: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode parses a file written by Encode.
+func Decode(data []byte) ([]storage.DeliveryLog, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("logarchive: decompress: %w", err)
+	}
+	defer zr.Close()
+
+	var logs []storage.DeliveryLog
+	r := bufio.NewReader(zr)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var l storage.DeliveryLog
+			if jerr := json.Unmarshal(line, &l); jerr != nil {
+				return nil, fmt.Errorf("logarchive: decode line %d: %w", len(logs)+1, jerr)
+			}
+			logs = append(logs, l)
+		}
+		if err == io.EOF {
+			return logs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("logarchive: read: %w", err)
+		}
+	}
+}
+
+// ObjectKey returns the message store key of an archive file. Keys are
+// flat so the local store can hold them next to message bodies.
+func ObjectKey(first time.Time, id uuid.UUID) string {
+	return "delivery-logs-" + first.UTC().Format("20060102") + "-" + id.String() + ".ndjson.gz"
+}
+
+// Filter selects archived delivery logs. Empty fields match everything;
+// Until is exclusive.
+type Filter struct {
+	GroupID  uuid.UUID
+	EgressIP string
+	Provider string
+	Since    time.Time
+	Until    time.Time
+}
+
+func (f Filter) match(l storage.DeliveryLog) bool {
+	if !l.GroupID.Valid || uuid.UUID(l.GroupID.Bytes) != f.GroupID {
+		return false
+	}
+	if f.EgressIP != "" && l.EgressIp.String != f.EgressIP {
+		return false
+	}
+	if f.Provider != "" && l.Provider.String != f.Provider {
+		return false
+	}
+	if !f.Since.IsZero() && l.CreatedAt.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !l.CreatedAt.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Search returns up to limit archived delivery logs matching f, newest
+// first. Files are read newest first and reading stops once limit logs
+// are found, so a lookup of recent history only touches recent files.
+func Search(ctx context.Context, q Querier, store msgstore.MessageStore, f Filter, limit int) ([]storage.DeliveryLog, error) {
+	params := storage.ListGroupDeliveryLogArchivesParams{
+		GroupID: pgtype.UUID{Bytes: f.GroupID, Valid: true},
+	}
+	if !f.Since.IsZero() {
+		params.Since = pgtype.Timestamptz{Time: f.Since, Valid: true}
+	}
+	if !f.Until.IsZero() {
+		params.Until = pgtype.Timestamptz{Time: f.Until, Valid: true}
+	}
+	archives, err := q.ListGroupDeliveryLogArchives(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("logarchive: list archives: %w", err)
+	}
+
+	var found []storage.DeliveryLog
+	for _, a := range archives {
+		if len(found) >= limit {
+			break
+		}
+		data, err := store.Get(ctx, a.ObjectKey)
+		if err != nil {
+			return nil, fmt.Errorf("logarchive: read %s: %w", a.ObjectKey, err)
+		}
+		logs, err := Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.ObjectKey, err)
+		}
+		for _, l := range logs {
+			if f.match(l) {
+				found = append(found, l)
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].CreatedAt.Time.After(found[j].CreatedAt.Time)
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
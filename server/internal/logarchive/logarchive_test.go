@@ -0,0 +1,267 @@
+package logarchive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakeQuerier keeps delivery logs and archive index rows in memory.
+// Methods the archiver does not use panic through the nil embedded
+// interface.
+type fakeQuerier struct {
+	storage.Querier
+	logs      []storage.DeliveryLog
+	archives  []storage.DeliveryLogArchive
+	failIndex bool
+}
+
+func (f *fakeQuerier) ListDeliveryLogsToArchive(_ context.Context, arg storage.ListDeliveryLogsToArchiveParams) ([]storage.DeliveryLog, error) {
+	var out []storage.DeliveryLog
+	for _, l := range f.logs {
+		if l.CreatedAt.Time.Before(arg.CreatedAt.Time) && len(out) < int(arg.Limit) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeQuerier) CreateDeliveryLogArchive(_ context.Context, arg storage.CreateDeliveryLogArchiveParams) (storage.DeliveryLogArchive, error) {
+	if f.failIndex {
+		return storage.DeliveryLogArchive{}, errors.New("index failed")
+	}
+	a := storage.DeliveryLogArchive{
+		ID:             uuid.New(),
+		GroupID:        arg.GroupID,
+		ObjectKey:      arg.ObjectKey,
+		FirstCreatedAt: arg.FirstCreatedAt,
+		LastCreatedAt:  arg.LastCreatedAt,
+		RowCount:       arg.RowCount,
+	}
+	f.archives = append(f.archives, a)
+	return a, nil
+}
+
+func (f *fakeQuerier) DeleteArchivedDeliveryLogs(_ context.Context, ids []uuid.UUID) (int64, error) {
+	drop := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := f.logs[:0]
+	for _, l := range f.logs {
+		if !drop[l.ID] {
+			kept = append(kept, l)
+		}
+	}
+	n := len(f.logs) - len(kept)
+	f.logs = kept
+	return int64(n), nil
+}
+
+func (f *fakeQuerier) ListGroupDeliveryLogArchives(_ context.Context, arg storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+	var out []storage.DeliveryLogArchive
+	for i := len(f.archives) - 1; i >= 0; i-- {
+		a := f.archives[i]
+		if a.GroupID != arg.GroupID {
+			continue
+		}
+		if arg.Since.Valid && a.LastCreatedAt.Time.Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !a.FirstCreatedAt.Time.Before(arg.Until.Time) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+type fakeTx struct{ q *fakeQuerier }
+
+func (t fakeTx) ExecTx(_ context.Context, fn func(storage.Querier) error) error {
+	return fn(t.q)
+}
+
+type memStore map[string][]byte
+
+func (s memStore) Put(_ context.Context, key string, data []byte) error {
+	s[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s memStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, msgstore.ErrNotFound
+	}
+	return data, nil
+}
+
+func (s memStore) Delete(_ context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func deliveryLog(group uuid.UUID, age time.Duration, provider string) storage.DeliveryLog {
+	return storage.DeliveryLog{
+		ID:        uuid.New(),
+		MessageID: uuid.New(),
+		GroupID:   pgtype.UUID{Bytes: group, Valid: true},
+		Status:    "delivered",
+		Provider:  sql.NullString{String: provider, Valid: true},
+		CreatedAt: pgtype.Timestamptz{Time: now.Add(-age), Valid: true},
+	}
+}
+
+func newTestArchiver(q *fakeQuerier, store memStore, cfg Config) *Archiver {
+	a := NewArchiver(q, fakeTx{q}, store, cfg, zerolog.Nop())
+	a.now = func() time.Time { return now }
+	return a
+}
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	l := deliveryLog(uuid.New(), time.Hour, "sendgrid")
+	l.Metadata = []byte(`{"tag":"welcome"}`)
+	l.ResponseCode = pgtype.Int4{Int32: 202, Valid: true}
+
+	data, err := Encode([]storage.DeliveryLog{l, l})
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("decoded %d logs, want 2", len(got))
+	}
+	if got[0].ID != l.ID || got[0].Provider != l.Provider || got[0].ResponseCode != l.ResponseCode ||
+		string(got[0].Metadata) != string(l.Metadata) || !got[0].CreatedAt.Time.Equal(l.CreatedAt.Time) {
+		t.Errorf("round trip mismatch: got %+v, want %+v", got[0], l)
+	}
+}
+
+func TestArchiveOnce_MovesOldLogsPerGroup(t *testing.T) {
+	groupA, groupB := uuid.New(), uuid.New()
+	recent := deliveryLog(groupA, time.Hour, "ses")
+	q := &fakeQuerier{logs: []storage.DeliveryLog{
+		deliveryLog(groupA, 100*24*time.Hour, "sendgrid"),
+		deliveryLog(groupB, 99*24*time.Hour, "sendgrid"),
+		deliveryLog(groupA, 98*24*time.Hour, "ses"),
+		recent,
+	}}
+	store := memStore{}
+
+	result, err := newTestArchiver(q, store, Config{After: 90 * 24 * time.Hour}).ArchiveOnce(context.Background())
+	if err != nil {
+		t.Fatalf("ArchiveOnce() error: %v", err)
+	}
+	if result.Archived != 3 || result.Files != 2 {
+		t.Errorf("result = %+v, want 3 archived in 2 files", result)
+	}
+	if len(q.logs) != 1 || q.logs[0].ID != recent.ID {
+		t.Errorf("remaining logs = %+v, want only the recent one", q.logs)
+	}
+	if len(store) != 2 || len(q.archives) != 2 {
+		t.Fatalf("store has %d files and index %d rows, want 2", len(store), len(q.archives))
+	}
+	a := q.archives[0]
+	if uuid.UUID(a.GroupID.Bytes) != groupA || a.RowCount != 2 || !a.FirstCreatedAt.Time.Before(a.LastCreatedAt.Time) {
+		t.Errorf("unexpected index row: %+v", a)
+	}
+}
+
+func TestArchiveOnce_IndexFailureKeepsRows(t *testing.T) {
+	q := &fakeQuerier{
+		logs:      []storage.DeliveryLog{deliveryLog(uuid.New(), 100*24*time.Hour, "ses")},
+		failIndex: true,
+	}
+	store := memStore{}
+
+	if _, err := newTestArchiver(q, store, Config{}).ArchiveOnce(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(q.logs) != 1 {
+		t.Error("delivery logs were deleted although the batch was not indexed")
+	}
+	if len(store) != 0 {
+		t.Errorf("uncommitted archive files left in the store: %d", len(store))
+	}
+}
+
+func TestArchiveOnce_Batches(t *testing.T) {
+	group := uuid.New()
+	q := &fakeQuerier{}
+	for i := 0; i < 5; i++ {
+		q.logs = append(q.logs, deliveryLog(group, time.Duration(200-i)*24*time.Hour, "ses"))
+	}
+
+	result, err := newTestArchiver(q, memStore{}, Config{BatchSize: 2, MaxBatches: 2}).ArchiveOnce(context.Background())
+	if err != nil {
+		t.Fatalf("ArchiveOnce() error: %v", err)
+	}
+	if result.Archived != 4 || len(q.logs) != 1 {
+		t.Errorf("archived %d with %d left, want 4 archived and 1 left for the next run", result.Archived, len(q.logs))
+	}
+}
+
+func TestSearch(t *testing.T) {
+	group := uuid.New()
+	q := &fakeQuerier{}
+	for i := 0; i < 6; i++ {
+		provider := "ses"
+		if i%2 == 0 {
+			provider = "sendgrid"
+		}
+		q.logs = append(q.logs, deliveryLog(group, time.Duration(200-i)*24*time.Hour, provider))
+	}
+	q.logs = append(q.logs, deliveryLog(uuid.New(), 150*24*time.Hour, "ses"))
+	store := memStore{}
+	if _, err := newTestArchiver(q, store, Config{BatchSize: 3}).ArchiveOnce(context.Background()); err != nil {
+		t.Fatalf("ArchiveOnce() error: %v", err)
+	}
+
+	got, err := Search(context.Background(), q, store, Filter{GroupID: group, Provider: "ses"}, 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("found %d logs, want 3", len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].CreatedAt.Time.After(got[i-1].CreatedAt.Time) {
+			t.Error("results are not newest first")
+		}
+	}
+
+	got, err = Search(context.Background(), q, store, Filter{
+		GroupID: group,
+		Since:   now.Add(-199 * 24 * time.Hour),
+		Until:   now.Add(-196 * 24 * time.Hour),
+	}, 10)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("found %d logs in range, want 3", len(got))
+	}
+
+	got, err = Search(context.Background(), q, store, Filter{GroupID: group}, 2)
+	if err != nil {
+		t.Fatalf("Search() error: %v", err)
+	}
+	if len(got) != 2 || !got[0].CreatedAt.Time.Equal(now.Add(-195*24*time.Hour)) {
+		t.Errorf("limited search = %+v, want the 2 newest logs", got)
+	}
+}
@@ -2,8 +2,11 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -13,13 +16,29 @@ import (
 // Callers should populate this from config.LoggingConfig fields.
 type LoggingConfig struct {
 	Level     string
-	Output    string // stdout (default), file, cloudwatch
+	Output    string   // stdout (default), file, syslog, cloudwatch
+	Outputs   []string // multiple sinks; takes precedence over Output
 	FilePath  string
 	MaxSizeMB int
 	MaxFiles  int
 	CWGroup   string
 	CWStream  string
 	CWRegion  string
+
+	// Syslog output. An empty SyslogNetwork logs to the local syslog daemon.
+	SyslogNetwork string
+	SyslogAddress string
+	SyslogTag     string
+
+	// Modules overrides Level per module, e.g. {"smtp": "debug"}. See Module.
+	Modules map[string]string
+
+	// Debug event sampling. The first SampleBurst debug events in each
+	// SamplePeriod are logged, then one in every SampleEvery. Sampling is
+	// disabled when both SampleBurst and SampleEvery are zero.
+	SampleBurst  uint32
+	SamplePeriod time.Duration
+	SampleEvery  uint32
 }
 
 type contextKey string
@@ -44,12 +63,17 @@ func New(level string) zerolog.Logger {
 		Logger()
 }
 
-// NewFromConfig creates a zerolog.Logger from a LoggingConfig, selecting the
-// appropriate output writer based on cfg.Output:
+// NewFromConfig creates a zerolog.Logger from a LoggingConfig, writing to
+// every sink in cfg.Outputs (or cfg.Output when Outputs is empty):
 //   - "file": rotating file via lumberjack
+//   - "syslog": local or remote syslog, with zerolog levels mapped to
+//     syslog severities
 //   - "cloudwatch": CloudWatch Logs (currently a stub writing to stdout)
 //   - "stdout" or any other value: os.Stdout (default)
 //
+// Debug events are sampled when SampleBurst or SampleEvery is set. A sink
+// that cannot be opened is reported on stderr and skipped.
+//
 // The existing New() function is preserved for backward compatibility.
 func NewFromConfig(cfg LoggingConfig) zerolog.Logger {
 	lvl, err := zerolog.ParseLevel(cfg.Level)
@@ -57,29 +81,105 @@ func NewFromConfig(cfg LoggingConfig) zerolog.Logger {
 		lvl = zerolog.InfoLevel
 	}
 
-	var writer io.Writer
-	switch cfg.Output {
-	case "file":
-		writer = NewFileWriter(FileConfig{
-			Path:      cfg.FilePath,
-			MaxSizeMB: cfg.MaxSizeMB,
-			MaxFiles:  cfg.MaxFiles,
-		})
-	case "cloudwatch":
-		writer = NewCloudWatchWriter(CloudWatchConfig{
-			Group:  cfg.CWGroup,
-			Stream: cfg.CWStream,
-			Region: cfg.CWRegion,
-		})
-	default:
-		writer = os.Stdout
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{cfg.Output}
 	}
 
-	return zerolog.New(writer).
+	var writers []io.Writer
+	seen := make(map[string]bool, len(outputs))
+	for _, output := range outputs {
+		output = strings.ToLower(strings.TrimSpace(output))
+		if output != "file" && output != "syslog" && output != "cloudwatch" {
+			output = "stdout"
+		}
+		if seen[output] {
+			continue
+		}
+		seen[output] = true
+
+		switch output {
+		case "file":
+			writers = append(writers, NewFileWriter(FileConfig{
+				Path:      cfg.FilePath,
+				MaxSizeMB: cfg.MaxSizeMB,
+				MaxFiles:  cfg.MaxFiles,
+			}))
+		case "syslog":
+			w, err := NewSyslogWriter(SyslogConfig{
+				Network: cfg.SyslogNetwork,
+				Address: cfg.SyslogAddress,
+				Tag:     cfg.SyslogTag,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "syslog output disabled: %v\n", err)
+				continue
+			}
+			writers = append(writers, w)
+		case "cloudwatch":
+			writers = append(writers, NewCloudWatchWriter(CloudWatchConfig{
+				Group:  cfg.CWGroup,
+				Stream: cfg.CWStream,
+				Region: cfg.CWRegion,
+			}))
+		default:
+			writers = append(writers, os.Stdout)
+		}
+	}
+	if len(writers) == 0 {
+		writers = append(writers, os.Stdout)
+	}
+
+	var writer io.Writer = writers[0]
+	if len(writers) > 1 {
+		writer = zerolog.MultiLevelWriter(writers...)
+	}
+
+	log := zerolog.New(writer).
 		Level(lvl).
 		With().
 		Timestamp().
 		Logger()
+
+	if sampler := debugSampler(cfg); sampler != nil {
+		log = log.Sample(zerolog.LevelSampler{DebugSampler: sampler})
+	}
+	return log
+}
+
+// debugSampler returns the sampler for debug events, or nil when sampling
+// is disabled.
+func debugSampler(cfg LoggingConfig) zerolog.Sampler {
+	var every zerolog.Sampler
+	if cfg.SampleEvery > 1 {
+		every = &zerolog.BasicSampler{N: cfg.SampleEvery}
+	}
+	if cfg.SampleBurst == 0 {
+		return every
+	}
+	period := cfg.SamplePeriod
+	if period <= 0 {
+		period = time.Second
+	}
+	return &zerolog.BurstSampler{
+		Burst:       cfg.SampleBurst,
+		Period:      period,
+		NextSampler: every,
+	}
+}
+
+// Module returns a child of log for a named module ("smtp", "queue", "api",
+// ...) that tags every event with the module name and applies the module's
+// level from cfg.Modules, if set. Module levels may be more or less verbose
+// than the global level.
+func Module(log zerolog.Logger, cfg LoggingConfig, module string) zerolog.Logger {
+	l := log.With().Str("module", module).Logger()
+	if level, ok := cfg.Modules[module]; ok {
+		if lvl, err := zerolog.ParseLevel(level); err == nil {
+			l = l.Level(lvl)
+		}
+	}
+	return l
 }
 
 // WithLogger stores a logger in the context.
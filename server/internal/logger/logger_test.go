@@ -242,3 +242,69 @@ func TestNewCorrelationID(t *testing.T) {
 		t.Errorf("expected UUID format (5 groups), got %s", id1)
 	}
 }
+
+func TestNewFromConfig_MultipleOutputs(t *testing.T) {
+	logPath := t.TempDir() + "/multi.log"
+
+	log := NewFromConfig(LoggingConfig{
+		Level:     "info",
+		Outputs:   []string{"stdout", "file", "file"},
+		FilePath:  logPath,
+		MaxSizeMB: 10,
+		MaxFiles:  3,
+	})
+	log.Info().Msg("multi message")
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if n := strings.Count(string(data), "multi message"); n != 1 {
+		t.Errorf("expected message written to file once, got %d times", n)
+	}
+}
+
+func TestNewFromConfig_DebugSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewFromConfig(LoggingConfig{
+		Level:       "debug",
+		SampleBurst: 2,
+		SampleEvery: 0,
+	}).Output(&buf)
+
+	for i := 0; i < 10; i++ {
+		log.Debug().Msg("noisy")
+	}
+	log.Info().Msg("important")
+
+	if n := strings.Count(buf.String(), "noisy"); n != 2 {
+		t.Errorf("expected 2 sampled debug events, got %d", n)
+	}
+	if !strings.Contains(buf.String(), "important") {
+		t.Error("info events must not be sampled")
+	}
+}
+
+func TestModule(t *testing.T) {
+	var buf bytes.Buffer
+	base := New("info").Output(&buf)
+	cfg := LoggingConfig{Modules: map[string]string{"smtp": "debug", "api": "warn"}}
+
+	smtpLog := Module(base, cfg, "smtp")
+	apiLog := Module(base, cfg, "api")
+	queueLog := Module(base, cfg, "queue")
+	smtpLog.Debug().Msg("smtp debug")
+	apiLog.Info().Msg("api info")
+	queueLog.Info().Msg("queue info")
+
+	out := buf.String()
+	if !strings.Contains(out, "smtp debug") || !strings.Contains(out, `"module":"smtp"`) {
+		t.Errorf("expected smtp debug event with module field, got: %s", out)
+	}
+	if strings.Contains(out, "api info") {
+		t.Error("api module at warn level must drop info events")
+	}
+	if !strings.Contains(out, "queue info") {
+		t.Error("module without override must use the base level")
+	}
+}
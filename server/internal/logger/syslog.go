@@ -0,0 +1,36 @@
+//go:build !windows
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+
+	"github.com/rs/zerolog"
+)
+
+// SyslogConfig holds configuration for syslog output.
+type SyslogConfig struct {
+	// Network is "udp", "tcp" or "unix". Empty connects to the local
+	// syslog daemon.
+	Network string
+	// Address is the syslog server address, e.g. "localhost:514".
+	Address string
+	// Tag is the syslog tag (program name). Defaults to "smtp-proxy".
+	Tag string
+}
+
+// NewSyslogWriter returns an io.Writer that sends each log entry to syslog
+// with the severity matching its zerolog level.
+func NewSyslogWriter(cfg SyslogConfig) (io.Writer, error) {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "smtp-proxy"
+	}
+	w, err := syslog.Dial(cfg.Network, cfg.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("connect to syslog: %w", err)
+	}
+	return zerolog.SyslogLevelWriter(w), nil
+}
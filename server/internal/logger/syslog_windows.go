@@ -0,0 +1,18 @@
+package logger
+
+import (
+	"errors"
+	"io"
+)
+
+// SyslogConfig holds configuration for syslog output.
+type SyslogConfig struct {
+	Network string
+	Address string
+	Tag     string
+}
+
+// NewSyslogWriter always fails: syslog is not available on Windows.
+func NewSyslogWriter(cfg SyslogConfig) (io.Writer, error) {
+	return nil, errors.New("syslog output is not supported on windows")
+}
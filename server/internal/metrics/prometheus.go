@@ -44,6 +44,371 @@ var (
 			Buckets: prometheus.DefBuckets,
 		},
 	)
+
+	SMTPLoadShedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_load_shed_total",
+			Help: "Total number of SMTP commands rejected with 421 because the database pool was saturated",
+		},
+		[]string{"stage"}, // connect, mail
+	)
+
+	SMTPDuplicateMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_duplicate_messages_total",
+			Help: "Total number of submitted messages detected as duplicates",
+		},
+		[]string{"action"}, // drop, tag
+	)
+
+	SMTPLoopsDetectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_loops_detected_total",
+			Help: "Total number of messages rejected as mail loops",
+		},
+		[]string{"reason"}, // hops, hostname
+	)
+
+	SMTPDNSBLChecksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_dnsbl_checks_total",
+			Help: "Total number of inbound clients checked against each DNS blocklist",
+		},
+		[]string{"list", "result"}, // result: listed, clean, error
+	)
+
+	SMTPDNSBLActionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_dnsbl_actions_total",
+			Help: "Total number of inbound clients rejected or greylisted for their DNS blocklist score",
+		},
+		[]string{"action"}, // reject, greylist
+	)
+
+	SMTPDraining = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smtp_draining",
+			Help: "Whether the SMTP server is draining and rejecting new connections (1) or not (0)",
+		},
+	)
+
+	SMTPBackpressureLevel = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smtp_backpressure_level",
+			Help: "SMTP backpressure level: 0 accepting, 1 deferring transactions with 451/452, 2 rejecting with 421",
+		},
+	)
+
+	SMTPBackpressureRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_backpressure_rejections_total",
+			Help: "Total number of SMTP commands deferred or rejected because of queue backlog or database write latency",
+		},
+		[]string{"stage", "code"}, // connect, mail, rcpt; 421, 451, 452
+	)
+
+	SMTPQueueBacklog = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smtp_queue_backlog",
+			Help: "Queue stream entries not yet acknowledged by the queue workers, as last sampled for backpressure",
+		},
+	)
+
+	SMTPBodyMemoryBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smtp_body_memory_bytes",
+			Help: "Bytes of in-flight message bodies held in memory under the SMTP memory budget",
+		},
+	)
+
+	SMTPBodySpillsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "smtp_body_spills_total",
+			Help: "Total number of message bodies spilled to temporary files because the SMTP memory budget was exhausted",
+		},
+	)
+
+	SMTPSessionDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "smtp_session_duration_seconds",
+			Help:    "Duration of SMTP sessions, from connect to disconnect",
+			Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900, 3600},
+		},
+	)
+
+	SMTPMessagesPerSession = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "smtp_messages_per_session",
+			Help:    "Number of messages accepted per SMTP session",
+			Buckets: []float64{0, 1, 2, 5, 10, 25, 100, 500},
+		},
+	)
+
+	SMTPCommandErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_command_errors_total",
+			Help: "Total number of SMTP commands answered with an error reply",
+		},
+		[]string{"command", "class"}, // command: auth, mail, rcpt, data; class: temporary (4xx), permanent (5xx)
+	)
+
+	SMTPSendWindowMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smtp_send_window_messages_total",
+			Help: "Total number of messages submitted outside their group's sending windows",
+		},
+		[]string{"action"}, // held, urgent
+	)
+
+	SMTPDBWriteLatency = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "smtp_db_write_latency_seconds",
+			Help: "Average duration of message persistence transactions during the last backpressure interval",
+		},
+	)
+)
+
+// Outbox metrics
+var (
+	OutboxPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_entries_pending",
+			Help: "Number of outbox entries waiting to be published to the queue",
+		},
+	)
+
+	OutboxPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "Total number of outbox publish attempts",
+		},
+		[]string{"result"}, // success, failure
+	)
+)
+
+// Sweeper metrics
+var (
+	SweeperStuckMessages = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "sweeper_stuck_messages",
+			Help: "Number of stuck messages found by the last sweep",
+		},
+	)
+
+	SweeperActionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sweeper_actions_total",
+			Help: "Total number of stuck messages handled by the sweeper",
+		},
+		[]string{"action"}, // requeued, failed
+	)
+)
+
+// Delivery log archive metrics
+var (
+	DeliveryLogsArchivedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "delivery_logs_archived_total",
+			Help: "Total number of delivery logs moved to archive files in the message store",
+		},
+	)
+)
+
+// Analytics export metrics
+var (
+	AnalyticsEventsExportedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analytics_events_exported_total",
+			Help: "Total number of delivery events written to an analytics sink",
+		},
+		[]string{"sink"},
+	)
+
+	AnalyticsExportFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analytics_export_failures_total",
+			Help: "Total number of analytics export runs that failed",
+		},
+		[]string{"sink"},
+	)
+)
+
+// Delivery SLO metrics
+var (
+	DeliveryLatencySeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "delivery_latency_seconds",
+			Help:    "Time from SMTP accept to successful provider delivery",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{"provider"},
+	)
+
+	DeliveryDomainThrottledTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "delivery_domain_throttled_total",
+			Help: "Total number of deliveries deferred by a provider's recipient domain limits",
+		},
+		[]string{"provider", "domain", "limit"}, // limit: rate, concurrency
+	)
+
+	SLODeliveryLatency = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "slo_delivery_latency_seconds",
+			Help: "Rolling accept-to-delivered latency percentile per group and provider",
+		},
+		[]string{"group_id", "provider", "quantile"}, // quantile: p50, p95, p99
+	)
+
+	SLOBreachesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "slo_breaches_total",
+			Help: "Total number of delivery latency SLO breaches that started, counted once per breach",
+		},
+		[]string{"quantile"},
+	)
+
+	SLOAlertsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "slo_alerts_total",
+			Help: "Total number of SLO breach notifications sent",
+		},
+		[]string{"result"}, // success, failure
+	)
+)
+
+// Message signing metrics
+var (
+	MessageSigningTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_signing_total",
+			Help: "Total number of outbound messages through the signing stage",
+		},
+		[]string{"kind", "result"}, // result: signed, encrypted, unencrypted, unsigned, failed
+	)
+)
+
+// Feedback loop metrics
+var (
+	FBLReportsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fbl_reports_total",
+			Help: "Total number of complaint feedback reports received on arf inbound routes",
+		},
+		[]string{"result"}, // complained, unmatched, invalid
+	)
+
+	SMTPSuppressedRecipientsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "smtp_suppressed_recipients_total",
+			Help: "Total number of recipients rejected because their group suppressed them",
+		},
+	)
+)
+
+// Quota warning metrics
+var (
+	QuotaWarningsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quota_warnings_total",
+			Help: "Total number of monthly limit warning emails enqueued",
+		},
+		[]string{"threshold"},
+	)
+)
+
+// Certificate expiry metrics
+var (
+	CertExpiryDays = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cert_expiry_days",
+			Help: "Days until a monitored TLS certificate expires; negative once expired",
+		},
+		[]string{"source", "name"}, // source: smtp, api, file, relay
+	)
+
+	CertCheckFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cert_check_failures_total",
+			Help: "Total number of certificate expiry checks that could not read the certificate",
+		},
+		[]string{"source"},
+	)
+)
+
+// Provider health metrics
+var (
+	ProviderHealthChecksTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "provider_health_checks_total",
+			Help: "Total number of provider health checks",
+		},
+		[]string{"provider_type", "result"}, // result: success, failure
+	)
+
+	ProviderAutoToggleTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "provider_auto_toggle_total",
+			Help: "Total number of providers automatically disabled or re-enabled by the health prober",
+		},
+		[]string{"action"}, // disabled, enabled
+	)
+
+	ProviderQuotaRemaining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_quota_remaining",
+			Help: "Sending quota left as last reported by the ESP",
+		},
+		[]string{"provider_id", "provider"},
+	)
+
+	ProviderBounceRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_bounce_rate",
+			Help: "Bounce rate as last reported by the ESP (0-1)",
+		},
+		[]string{"provider_id", "provider"},
+	)
+
+	ProviderComplaintRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_complaint_rate",
+			Help: "Complaint rate as last reported by the ESP (0-1)",
+		},
+		[]string{"provider_id", "provider"},
+	)
+
+	ProviderReputationScore = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_reputation_score",
+			Help: "Sender reputation score as last reported by the ESP (0-100)",
+		},
+		[]string{"provider_id", "provider"},
+	)
+
+	ProviderReputationPaused = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_reputation_paused",
+			Help: "1 while the provider is paused or throttled for degraded sender reputation",
+		},
+		[]string{"provider_id", "provider"},
+	)
+
+	ProviderDailyCapRemaining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_daily_cap_remaining",
+			Help: "Deliveries left today under the provider's daily cap",
+		},
+		[]string{"provider_id", "provider"},
+	)
+
+	ProviderDailyCapReachedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "provider_daily_cap_reached_total",
+			Help: "Total number of deliveries routed away from a provider that reached its daily cap",
+		},
+		[]string{"provider_id", "provider"},
+	)
 )
 
 // API metrics
@@ -71,6 +436,14 @@ var (
 			Help: "Total number of API authentication failures",
 		},
 	)
+
+	APIRateLimitedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_rate_limited_total",
+			Help: "Total number of API requests rejected with 429 by endpoint class and limit scope",
+		},
+		[]string{"class", "scope"}, // scope: ip, key
+	)
 )
 
 // Database metrics
@@ -89,6 +462,55 @@ var (
 		},
 	)
 
+	DBConnectionsTotal = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_connections_total",
+			Help: "Number of open database connections, including those being established",
+		},
+	)
+
+	DBConnectionsMax = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_connections_max",
+			Help: "Maximum size of the database connection pool",
+		},
+	)
+
+	DBPoolAcquiresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "db_pool_acquires_total",
+			Help: "Total number of successful connection acquires from the pool",
+		},
+	)
+
+	DBPoolEmptyAcquiresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "db_pool_empty_acquires_total",
+			Help: "Total number of acquires that had to wait for a connection",
+		},
+	)
+
+	DBPoolCanceledAcquiresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "db_pool_canceled_acquires_total",
+			Help: "Total number of acquires canceled by their context while waiting",
+		},
+	)
+
+	DBPoolAcquireWaitSecondsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "db_pool_acquire_wait_seconds_total",
+			Help: "Total time acquires spent waiting for a connection",
+		},
+	)
+
+	DBPoolSaturated = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "db_pool_saturated",
+			Help: "Whether the database pool is saturated (1) or not (0)",
+		},
+	)
+
 	DBQueryDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "db_query_duration_seconds",
@@ -117,3 +539,37 @@ var (
 		[]string{"status"}, // queued, processing, failed
 	)
 )
+
+// Crash metrics
+var (
+	PanicsRecoveredTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "panics_recovered_total",
+			Help: "Total number of panics recovered instead of crashing the process",
+		},
+		[]string{"component"}, // api, smtp, worker
+	)
+
+	PolicyShadowRejectionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_shadow_rejections_total",
+			Help: "Total number of messages a policy in shadow mode would have rejected",
+		},
+		[]string{"policy"}, // allowed_domains, recipient_validation, sender_policy, scripts
+	)
+
+	APILegacyRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_legacy_requests_total",
+			Help: "Total number of requests to the deprecated legacy account and tenant endpoints",
+		},
+		[]string{"resource"}, // accounts, tenants
+	)
+
+	SchemaReadOnly = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "schema_read_only",
+			Help: "Whether the daemon runs read-only because the database schema is newer than the binary (1) or not (0)",
+		},
+	)
+)
@@ -20,14 +20,43 @@ func TestMetricsRegistered(t *testing.T) {
 		{"SMTPAuthAttemptsTotal", SMTPAuthAttemptsTotal},
 		{"SMTPMessageEnqueuedTotal", SMTPMessageEnqueuedTotal},
 		{"SMTPMessageEnqueueDuration", SMTPMessageEnqueueDuration},
+		{"SMTPLoadShedTotal", SMTPLoadShedTotal},
+		{"SMTPDraining", SMTPDraining},
+		{"SMTPBackpressureLevel", SMTPBackpressureLevel},
+		{"SMTPBackpressureRejectionsTotal", SMTPBackpressureRejectionsTotal},
+		{"SMTPQueueBacklog", SMTPQueueBacklog},
+		{"SMTPSessionDuration", SMTPSessionDuration},
+		{"SMTPMessagesPerSession", SMTPMessagesPerSession},
+		{"SMTPCommandErrorsTotal", SMTPCommandErrorsTotal},
+		{"SMTPSendWindowMessagesTotal", SMTPSendWindowMessagesTotal},
+		{"SMTPDBWriteLatency", SMTPDBWriteLatency},
+		{"SMTPBodyMemoryBytes", SMTPBodyMemoryBytes},
+		{"SMTPBodySpillsTotal", SMTPBodySpillsTotal},
 		{"APIRequestsTotal", APIRequestsTotal},
 		{"APIRequestDuration", APIRequestDuration},
 		{"APIAuthFailuresTotal", APIAuthFailuresTotal},
+		{"APIRateLimitedTotal", APIRateLimitedTotal},
 		{"DBConnectionsActive", DBConnectionsActive},
 		{"DBConnectionsIdle", DBConnectionsIdle},
+		{"DBConnectionsTotal", DBConnectionsTotal},
+		{"DBConnectionsMax", DBConnectionsMax},
+		{"DBPoolAcquiresTotal", DBPoolAcquiresTotal},
+		{"DBPoolEmptyAcquiresTotal", DBPoolEmptyAcquiresTotal},
+		{"DBPoolCanceledAcquiresTotal", DBPoolCanceledAcquiresTotal},
+		{"DBPoolAcquireWaitSecondsTotal", DBPoolAcquireWaitSecondsTotal},
+		{"DBPoolSaturated", DBPoolSaturated},
 		{"DBQueryDuration", DBQueryDuration},
 		{"DBErrorsTotal", DBErrorsTotal},
 		{"QueueDepth", QueueDepth},
+		{"PanicsRecoveredTotal", PanicsRecoveredTotal},
+		{"SchemaReadOnly", SchemaReadOnly},
+		{"APILegacyRequestsTotal", APILegacyRequestsTotal},
+		{"PolicyShadowRejectionsTotal", PolicyShadowRejectionsTotal},
+		{"DeliveryDomainThrottledTotal", DeliveryDomainThrottledTotal},
+		{"ProviderDailyCapRemaining", ProviderDailyCapRemaining},
+		{"ProviderDailyCapReachedTotal", ProviderDailyCapReachedTotal},
+		{"CertExpiryDays", CertExpiryDays},
+		{"CertCheckFailuresTotal", CertCheckFailuresTotal},
 	}
 
 	for _, tt := range tests {
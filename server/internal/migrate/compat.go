@@ -0,0 +1,122 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+)
+
+// Schema check modes, set by database.schema_check.
+const (
+	// CheckStrict refuses to start on any schema version mismatch.
+	CheckStrict = "strict"
+	// CheckReadOnly starts read-only when the schema is newer than the
+	// binary, as during a rollout once a new release has migrated. An
+	// older or dirty schema is still refused.
+	CheckReadOnly = "read_only"
+	// CheckOff skips the check.
+	CheckOff = "off"
+)
+
+// ErrSchemaBehind is returned when the database has not been migrated to
+// the version the binary was built for.
+var ErrSchemaBehind = errors.New("database schema is older than this binary")
+
+// ErrSchemaAhead is returned when the database was migrated by a newer
+// release than the binary.
+var ErrSchemaAhead = errors.New("database schema is newer than this binary")
+
+// Compatibility compares the schema version of a database with the one a
+// binary was built for.
+type Compatibility struct {
+	// Database is the version recorded in schema_migrations.
+	Database uint64
+	// Dirty is set when the last migration failed part-way.
+	Dirty bool
+	// Binary is the latest migration embedded in the binary.
+	Binary uint64
+}
+
+// Err returns nil when the binary can run against the database, or an
+// error explaining how to fix the mismatch.
+func (c Compatibility) Err() error {
+	switch {
+	case c.Dirty:
+		return fmt.Errorf("%w at version %d: a migration failed part-way; repair the schema, then run `migrate force %d` before starting", ErrDirty, c.Database, c.Database)
+	case c.Database < c.Binary:
+		return fmt.Errorf("%w (version %d, want %d): apply the migrations with --migrate or database.migrate_on_start before starting this release", ErrSchemaBehind, c.Database, c.Binary)
+	case c.Database > c.Binary:
+		return fmt.Errorf("%w (version %d, want %d): upgrade this daemon to the release that migrated the database, or set database.schema_check to read_only to keep it running read-only until then", ErrSchemaAhead, c.Database, c.Binary)
+	}
+	return nil
+}
+
+// Decide applies mode (see CheckStrict) to c. It returns whether the
+// daemon must run read-only, or an error when it must not start.
+func (c Compatibility) Decide(mode string) (readOnly bool, err error) {
+	switch mode {
+	case CheckOff:
+		return false, nil
+	case CheckStrict, "":
+		return false, c.Err()
+	case CheckReadOnly:
+		err := c.Err()
+		if errors.Is(err, ErrSchemaAhead) {
+			return true, nil
+		}
+		return false, err
+	default:
+		return false, fmt.Errorf("unsupported database.schema_check %q (want strict, read_only or off)", mode)
+	}
+}
+
+// Check reads the schema version of the database on conn and compares it
+// with the embedded migrations.
+func Check(ctx context.Context, conn *pgx.Conn) (Compatibility, error) {
+	list, err := Embedded()
+	if err != nil {
+		return Compatibility{}, err
+	}
+	current, dirty, err := Version(ctx, conn)
+	if err != nil {
+		return Compatibility{}, err
+	}
+	return Compatibility{Database: current, Dirty: dirty, Binary: Latest(list)}, nil
+}
+
+// Verify checks the schema on a connection from pool at startup, applying
+// mode. It returns whether the daemon must run read-only, or an error
+// when it must not start.
+func Verify(ctx context.Context, pool *pgxpool.Pool, mode string, log zerolog.Logger) (bool, error) {
+	if mode == CheckOff {
+		return false, nil
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+	compat, err := Check(ctx, conn.Conn())
+	if err != nil {
+		return false, err
+	}
+	readOnly, err := compat.Decide(mode)
+	if err != nil {
+		return false, err
+	}
+	if readOnly {
+		metrics.SchemaReadOnly.Set(1)
+		log.Warn().
+			Err(compat.Err()).
+			Uint64("database_version", compat.Database).
+			Uint64("binary_version", compat.Binary).
+			Msg("running read-only until this daemon is upgraded")
+	}
+	return readOnly, nil
+}
@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompatibility_Decide(t *testing.T) {
+	current := Compatibility{Database: 47, Binary: 47}
+	behind := Compatibility{Database: 45, Binary: 47}
+	ahead := Compatibility{Database: 48, Binary: 47}
+	dirty := Compatibility{Database: 47, Dirty: true, Binary: 47}
+
+	tests := []struct {
+		name         string
+		compat       Compatibility
+		mode         string
+		wantReadOnly bool
+		wantErr      error
+	}{
+		{"current strict", current, CheckStrict, false, nil},
+		{"current read_only", current, CheckReadOnly, false, nil},
+		{"behind strict", behind, CheckStrict, false, ErrSchemaBehind},
+		{"behind read_only", behind, CheckReadOnly, false, ErrSchemaBehind},
+		{"ahead strict", ahead, CheckStrict, false, ErrSchemaAhead},
+		{"ahead default", ahead, "", false, ErrSchemaAhead},
+		{"ahead read_only", ahead, CheckReadOnly, true, nil},
+		{"dirty read_only", dirty, CheckReadOnly, false, ErrDirty},
+		{"ahead off", ahead, CheckOff, false, nil},
+		{"dirty off", dirty, CheckOff, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readOnly, err := tt.compat.Decide(tt.mode)
+			if readOnly != tt.wantReadOnly || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decide(%q) = %v, %v; want %v, %v", tt.mode, readOnly, err, tt.wantReadOnly, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompatibility_ErrRemediation(t *testing.T) {
+	tests := []struct {
+		compat Compatibility
+		want   string
+	}{
+		{Compatibility{Database: 45, Binary: 47}, "--migrate"},
+		{Compatibility{Database: 48, Binary: 47}, "read_only"},
+		{Compatibility{Database: 46, Dirty: true, Binary: 47}, "migrate force 46"},
+	}
+	for _, tt := range tests {
+		if err := tt.compat.Err(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: Err() = %v, want it to mention %q", tt.compat, err, tt.want)
+		}
+	}
+}
+
+func TestCompatibility_Decide_UnknownMode(t *testing.T) {
+	if _, err := (Compatibility{}).Decide("lenient"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,258 @@
+// Package migrate applies the embedded database migrations.
+//
+// Progress is recorded in the schema_migrations table used by the
+// golang-migrate CLI, so databases migrated by either tool can be managed
+// by the other. Runs are serialized with a PostgreSQL advisory lock, which
+// makes it safe for every replica of every daemon to migrate on start. The
+// lock is not shared with the CLI, so do not run both at once.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/migrations"
+)
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads NNN_name.up.sql / NNN_name.down.sql pairs from fsys and
+// returns them in version order. Every version needs an up migration.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Embedded returns the migrations built into the binary.
+func Embedded() ([]Migration, error) {
+	return Load(migrations.FS)
+}
+
+// Latest returns the highest version in list, or 0 when it is empty.
+func Latest(list []Migration) uint64 {
+	if len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1].Version
+}
+
+// ErrDirty is returned when a previous migration failed part-way. The
+// schema must be repaired by hand and the version forced before migrating
+// again.
+var ErrDirty = errors.New("database schema is dirty")
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`
+
+// Version returns the schema version recorded in the database and whether
+// the last migration failed part-way. A database never migrated is at
+// version 0.
+func Version(ctx context.Context, conn *pgx.Conn) (uint64, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		if isUndefinedTable(err) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("read schema version: %w", err)
+	}
+	return uint64(version), dirty, nil
+}
+
+// Up applies the migrations in list newer than the database's version, in
+// order, holding the advisory lock for the whole run. It returns the
+// number of migrations applied.
+//
+// Migrations are not wrapped in a transaction, as some manage their own:
+// the version is marked dirty before each one runs and clean after, so a
+// failure leaves the database dirty, as with golang-migrate.
+func Up(ctx context.Context, conn *pgx.Conn, list []Migration, log zerolog.Logger) (int, error) {
+	lockID, err := advisoryLockID(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return 0, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	}()
+
+	if _, err := conn.Exec(ctx, createTable); err != nil {
+		return 0, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	current, dirty, err := Version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+	if latest := Latest(list); current > latest {
+		return 0, fmt.Errorf("database schema version %d is newer than this binary's %d", current, latest)
+	}
+
+	applied := 0
+	for _, m := range list {
+		if m.Version <= current {
+			continue
+		}
+		if err := setVersion(ctx, conn, m.Version, true); err != nil {
+			return applied, err
+		}
+		if _, err := conn.Exec(ctx, m.Up); err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		if err := setVersion(ctx, conn, m.Version, false); err != nil {
+			return applied, err
+		}
+		applied++
+		log.Info().Uint64("version", m.Version).Str("name", m.Name).Msg("migration applied")
+	}
+	return applied, nil
+}
+
+// UpPool applies the embedded migrations on a connection from pool.
+func UpPool(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger) error {
+	list, err := Embedded()
+	if err != nil {
+		return err
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+	applied, err := Up(ctx, conn.Conn(), list, log)
+	if err != nil {
+		return err
+	}
+	logDone(log, list, applied)
+	return nil
+}
+
+// Run connects to url and applies the embedded migrations.
+func Run(ctx context.Context, url string, log zerolog.Logger) error {
+	list, err := Embedded()
+	if err != nil {
+		return err
+	}
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+	applied, err := Up(ctx, conn, list, log)
+	if err != nil {
+		return err
+	}
+	logDone(log, list, applied)
+	return nil
+}
+
+func logDone(log zerolog.Logger, list []Migration, applied int) {
+	log.Info().
+		Int("applied", applied).
+		Uint64("version", Latest(list)).
+		Msg("database schema up to date")
+}
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
+
+func setVersion(ctx context.Context, conn *pgx.Conn, version uint64, dirty bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+	if _, err := tx.Exec(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, int64(version), dirty); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+	return nil
+}
+
+// advisoryLockID returns the key of the advisory lock serializing
+// migration runs against the connection's database.
+func advisoryLockID(ctx context.Context, conn *pgx.Conn) (int64, error) {
+	var database string
+	if err := conn.QueryRow(ctx, `SELECT current_database()`).Scan(&database); err != nil {
+		return 0, fmt.Errorf("read database name: %w", err)
+	}
+	return lockKey(database), nil
+}
+
+func lockKey(database string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte("smtp-proxy:schema_migrations:" + database)))
+}
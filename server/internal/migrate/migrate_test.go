@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad_OrdersPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (c);")},
+		"010_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+		"002_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"002_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                 {Data: []byte("not a migration")},
+	}
+
+	list, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(list))
+	}
+	if list[0].Version != 2 || list[0].Name != "create_table" || list[1].Version != 10 {
+		t.Errorf("unexpected order: %+v", list)
+	}
+	if list[1].Up != "CREATE INDEX i ON t (c);" || list[1].Down != "DROP INDEX i;" {
+		t.Errorf("unexpected bodies: %+v", list[1])
+	}
+	if Latest(list) != 10 {
+		t.Errorf("Latest() = %d, want 10", Latest(list))
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{"001_a.down.sql": {Data: []byte("x")}},
+			want: "no up file",
+		},
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"001_a.up.sql": {Data: []byte("x")},
+				"001_b.up.sql": {Data: []byte("y")},
+			},
+			want: "used by both",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestEmbedded_Contiguous(t *testing.T) {
+	list, err := Embedded()
+	if err != nil {
+		t.Fatalf("Embedded() error: %v", err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range list {
+		if m.Version != uint64(i+1) {
+			t.Fatalf("migration %d_%s: expected version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLockKey(t *testing.T) {
+	if lockKey("smtp_proxy") != lockKey("smtp_proxy") {
+		t.Error("lock key is not deterministic")
+	}
+	if lockKey("smtp_proxy") == lockKey("other") {
+		t.Error("databases share a lock key")
+	}
+}
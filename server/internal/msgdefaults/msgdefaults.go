@@ -0,0 +1,167 @@
+// Package msgdefaults applies a group's message defaults: headers added to
+// every message that does not set them, and a text and HTML footer, such
+// as a legal disclaimer or the physical address CAN-SPAM requires,
+// appended to message bodies that do not already end with it.
+//
+// Messages with one of the excluded tags, or whose template metadata (the
+// X-SMTPProxy-Metadata key "template") is one of the excluded templates,
+// are sent without the defaults.
+package msgdefaults
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"slices"
+	"strings"
+)
+
+// TemplateKey is the metadata key naming a message's template.
+const TemplateKey = "template"
+
+// Limits of one group's defaults.
+const (
+	MaxHeaders      = 20
+	MaxHeaderLength = 998
+	MaxFooterLength = 8192
+	MaxExclusions   = 50
+)
+
+// reservedHeaders are set from the message itself and cannot have
+// defaults.
+var reservedHeaders = []string{
+	"From", "To", "Cc", "Bcc", "Subject", "Date", "Message-Id", "Return-Path",
+	"Mime-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// Defaults are a group's message defaults. Headers and the exclusion lists
+// are stored as JSON in message_defaults.
+type Defaults struct {
+	Headers          map[string]string `json:"headers"`
+	FooterText       string            `json:"footer_text"`
+	FooterHTML       string            `json:"footer_html"`
+	ExcludeTags      []string          `json:"exclude_tags"`
+	ExcludeTemplates []string          `json:"exclude_templates"`
+}
+
+// Normalize canonicalizes header names and trims surrounding whitespace,
+// then validates d.
+func (d *Defaults) Normalize() error {
+	headers := make(map[string]string, len(d.Headers))
+	for name, value := range d.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	d.Headers = headers
+	d.FooterText = strings.TrimSpace(d.FooterText)
+	d.FooterHTML = strings.TrimSpace(d.FooterHTML)
+	d.ExcludeTags = trimAll(d.ExcludeTags)
+	d.ExcludeTemplates = trimAll(d.ExcludeTemplates)
+	return d.validate()
+}
+
+func (d *Defaults) validate() error {
+	if len(d.Headers) > MaxHeaders {
+		return fmt.Errorf("at most %d headers are allowed", MaxHeaders)
+	}
+	for name, value := range d.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+		if slices.Contains(reservedHeaders, name) || strings.HasPrefix(name, "X-Smtpproxy-") {
+			return fmt.Errorf("header %s cannot have a default", name)
+		}
+		if value == "" || len(name)+2+len(value) > MaxHeaderLength || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s: value must be a single line of at most %d characters", name, MaxHeaderLength)
+		}
+	}
+	if len(d.FooterText) > MaxFooterLength || len(d.FooterHTML) > MaxFooterLength {
+		return fmt.Errorf("footers must be at most %d bytes", MaxFooterLength)
+	}
+	if len(d.ExcludeTags) > MaxExclusions || len(d.ExcludeTemplates) > MaxExclusions {
+		return fmt.Errorf("at most %d excluded tags and templates each are allowed", MaxExclusions)
+	}
+	if slices.Contains(d.ExcludeTags, "") || slices.Contains(d.ExcludeTemplates, "") {
+		return errors.New("excluded tags and templates must not be empty")
+	}
+	return nil
+}
+
+// Excludes reports whether a message with tags and metadata is sent
+// without the defaults.
+func (d *Defaults) Excludes(tags []string, metadata map[string]string) bool {
+	for _, tag := range tags {
+		if slices.Contains(d.ExcludeTags, tag) {
+			return true
+		}
+	}
+	template, ok := metadata[TemplateKey]
+	return ok && slices.Contains(d.ExcludeTemplates, template)
+}
+
+// ApplyHeaders sets each default header that headers does not have,
+// ignoring case, and returns the headers.
+func (d *Defaults) ApplyHeaders(headers map[string]string) map[string]string {
+	for name, value := range d.Headers {
+		if hasHeader(headers, name) {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// AppendText returns a text body with the text footer appended, after a
+// blank line, unless the body is empty or already ends with it.
+func (d *Defaults) AppendText(body string) string {
+	trimmed := strings.TrimRight(body, " \t\r\n")
+	if d.FooterText == "" || trimmed == "" || strings.HasSuffix(trimmed, d.FooterText) {
+		return body
+	}
+	return trimmed + "\n\n" + d.FooterText + "\n"
+}
+
+// AppendHTML returns an HTML body with the HTML footer inserted before
+// its closing body tag, or appended when it has none, unless the body is
+// empty or already contains the footer.
+func (d *Defaults) AppendHTML(body string) string {
+	if d.FooterHTML == "" || strings.TrimSpace(body) == "" || strings.Contains(body, d.FooterHTML) {
+		return body
+	}
+	if i := strings.LastIndex(strings.ToLower(body), "</body>"); i >= 0 {
+		return body[:i] + d.FooterHTML + body[i:]
+	}
+	return body + d.FooterHTML
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validHeaderName reports whether name is an RFC 5322 field name.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 33 || c > 126 || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+func trimAll(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		out = append(out, strings.TrimSpace(s))
+	}
+	return out
+}
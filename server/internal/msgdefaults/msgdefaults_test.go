@@ -0,0 +1,103 @@
+package msgdefaults
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaults_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		d       Defaults
+		wantErr string
+	}{
+		{"valid", Defaults{Headers: map[string]string{"x-department": " sales ", "Organization": "Example"}, FooterText: "Example Inc."}, ""},
+		{"empty", Defaults{}, ""},
+		{"reserved header", Defaults{Headers: map[string]string{"subject": "Hi"}}, "cannot have a default"},
+		{"control header", Defaults{Headers: map[string]string{"X-SMTPProxy-Tag": "x"}}, "cannot have a default"},
+		{"bad name", Defaults{Headers: map[string]string{"X Bad": "x"}}, "invalid header name"},
+		{"multi-line value", Defaults{Headers: map[string]string{"X-A": "a\r\nBcc: x@example.com"}}, "single line"},
+		{"empty value", Defaults{Headers: map[string]string{"X-A": " "}}, "single line"},
+		{"long footer", Defaults{FooterHTML: strings.Repeat("x", MaxFooterLength+1)}, "footers"},
+		{"empty exclusion", Defaults{ExcludeTags: []string{" "}}, "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.d.Normalize()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Normalize() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Normalize() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	d := Defaults{Headers: map[string]string{"x-department": " sales "}}
+	if err := d.Normalize(); err != nil || d.Headers["X-Department"] != "sales" {
+		t.Errorf("Normalize() headers = %v, %v", d.Headers, err)
+	}
+}
+
+func TestDefaults_Excludes(t *testing.T) {
+	d := Defaults{ExcludeTags: []string{"transactional"}, ExcludeTemplates: []string{"password-reset"}}
+	tests := []struct {
+		name     string
+		tags     []string
+		metadata map[string]string
+		want     bool
+	}{
+		{"no tags", nil, nil, false},
+		{"other tag", []string{"newsletter"}, nil, false},
+		{"excluded tag", []string{"newsletter", "transactional"}, nil, true},
+		{"excluded template", nil, map[string]string{"template": "password-reset"}, true},
+		{"other template", nil, map[string]string{"template": "welcome"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Excludes(tt.tags, tt.metadata); got != tt.want {
+				t.Errorf("Excludes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaults_ApplyHeaders(t *testing.T) {
+	d := Defaults{Headers: map[string]string{"Organization": "Example", "X-Department": "sales"}}
+
+	got := d.ApplyHeaders(map[string]string{"x-department": "support"})
+	if len(got) != 2 || got["Organization"] != "Example" || got["x-department"] != "support" {
+		t.Errorf("ApplyHeaders() = %v", got)
+	}
+	if got := d.ApplyHeaders(nil); len(got) != 2 {
+		t.Errorf("ApplyHeaders(nil) = %v", got)
+	}
+}
+
+func TestDefaults_AppendFooter(t *testing.T) {
+	d := Defaults{FooterText: "Example Inc., 1 Main St", FooterHTML: "<p>Example Inc., 1 Main St</p>"}
+
+	tests := []struct {
+		name string
+		fn   func(string) string
+		body string
+		want string
+	}{
+		{"text", d.AppendText, "Hello\n", "Hello\n\nExample Inc., 1 Main St\n"},
+		{"text already present", d.AppendText, "Hello\n\nExample Inc., 1 Main St\n", "Hello\n\nExample Inc., 1 Main St\n"},
+		{"empty text", d.AppendText, "", ""},
+		{"html with body", d.AppendHTML, "<html><BODY><p>Hi</p></BODY></html>", "<html><BODY><p>Hi</p><p>Example Inc., 1 Main St</p></BODY></html>"},
+		{"html fragment", d.AppendHTML, "<p>Hi</p>", "<p>Hi</p><p>Example Inc., 1 Main St</p>"},
+		{"html already present", d.AppendHTML, "<p>Hi</p><p>Example Inc., 1 Main St</p>", "<p>Hi</p><p>Example Inc., 1 Main St</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.body); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package msgsign
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CMS (RFC 5652) structures for detached SignedData and EnvelopedData,
+// the two content types S/MIME needs.
+
+var (
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSAESOAEP       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidMGF1            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidAES256CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	// Content is the EXPLICIT [0] wrapped content.
+	Content asn1.RawValue
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type encapContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    issuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+// rsaesOAEPParams (RFC 4055) selects SHA-256 for OAEP key transport. The
+// label source is left at its default, an empty label.
+type rsaesOAEPParams struct {
+	HashFunc    pkix.AlgorithmIdentifier `asn1:"explicit,tag:0"`
+	MaskGenFunc pkix.AlgorithmIdentifier `asn1:"explicit,tag:1"`
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue
+}
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []keyTransRecipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+// contextTag returns contents under the context-specific, constructed tag
+// n, as used for EXPLICIT fields and IMPLICIT SET OF fields.
+func contextTag(n int, contents []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: n, IsCompound: true, Bytes: contents}
+}
+
+func sid(cert *x509.Certificate) issuerAndSerial {
+	return issuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber}
+}
+
+// signDetached returns a DER ContentInfo holding SignedData with a
+// detached signature of content by key, whose certificate is chain[0].
+func signDetached(content []byte, key crypto.Signer, chain []*x509.Certificate) ([]byte, error) {
+	cert := chain[0]
+	digest := sha256.Sum256(content)
+
+	contentTypeValue, err := asn1.Marshal(oidData)
+	if err != nil {
+		return nil, err
+	}
+	digestValue, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	timeValue, err := asn1.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute{
+		{Type: oidContentType, Values: []asn1.RawValue{{FullBytes: contentTypeValue}}},
+		{Type: oidMessageDigest, Values: []asn1.RawValue{{FullBytes: digestValue}}},
+		{Type: oidSigningTime, Values: []asn1.RawValue{{FullBytes: timeValue}}},
+	}
+	// The signature covers the attributes DER encoded as a SET OF, while
+	// SignerInfo carries them under an IMPLICIT [0] tag.
+	attrSet, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		return nil, err
+	}
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(attrSet, &raw); err != nil {
+		return nil, err
+	}
+	attrDigest := sha256.Sum256(attrSet)
+
+	var sigAlg pkix.AlgorithmIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key.Public())
+	}
+	signature, err := key.Sign(rand.Reader, attrDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	var certs []byte
+	for _, c := range chain {
+		certs = append(certs, c.Raw...)
+	}
+	sha256Alg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Alg},
+		EncapContentInfo: encapContentInfo{ContentType: oidData},
+		Certificates:     contextTag(0, certs),
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                sid(cert),
+			DigestAlgorithm:    sha256Alg,
+			SignedAttrs:        contextTag(0, raw.Bytes),
+			SignatureAlgorithm: sigAlg,
+			Signature:          signature,
+		}},
+	}
+	return wrapContentInfo(oidSignedData, sd)
+}
+
+// encrypt returns a DER ContentInfo holding EnvelopedData: content
+// encrypted with a random AES-256-CBC key, itself encrypted to the RSA
+// public key of each recipient.
+func encrypt(content []byte, recipients []*x509.Certificate) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipient certificates")
+	}
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	pad := aes.BlockSize - len(content)%aes.BlockSize
+	padded := make([]byte, len(content)+pad)
+	copy(padded, content)
+	for i := len(content); i < len(padded); i++ {
+		padded[i] = byte(pad)
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	infos := make([]keyTransRecipientInfo, 0, len(recipients))
+	for _, cert := range recipients {
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("certificate %q does not have an RSA key", cert.Subject.CommonName)
+		}
+		encKey, keyAlg, err := keyTransport(pub, key)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt content key: %w", err)
+		}
+		infos = append(infos, keyTransRecipientInfo{
+			RID:                    sid(cert),
+			KeyEncryptionAlgorithm: keyAlg,
+			EncryptedKey:           encKey,
+		})
+	}
+
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	ed := envelopedData{
+		RecipientInfos: infos,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: ciphertext},
+		},
+	}
+	return wrapContentInfo(oidEnvelopedData, ed)
+}
+
+// keyTransport encrypts the content key for one recipient: with RSAES-OAEP
+// and SHA-256 in FIPS mode, where PKCS #1 v1.5 encryption is not approved,
+// and otherwise with PKCS #1 v1.5, which every S/MIME client supports.
+func keyTransport(pub *rsa.PublicKey, key []byte) ([]byte, pkix.AlgorithmIdentifier, error) {
+	if !fipsEnabled() {
+		encKey, err := rsa.EncryptPKCS1v15(rand.Reader, pub, key)
+		return encKey, pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}, err
+	}
+	sha256ID, err := asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: oidSHA256})
+	if err != nil {
+		return nil, pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(rsaesOAEPParams{
+		HashFunc:    pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+		MaskGenFunc: pkix.AlgorithmIdentifier{Algorithm: oidMGF1, Parameters: asn1.RawValue{FullBytes: sha256ID}},
+	})
+	if err != nil {
+		return nil, pkix.AlgorithmIdentifier{}, err
+	}
+	encKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	return encKey, pkix.AlgorithmIdentifier{Algorithm: oidRSAESOAEP, Parameters: asn1.RawValue{FullBytes: params}}, err
+}
+
+func wrapContentInfo(contentType asn1.ObjectIdentifier, content any) ([]byte, error) {
+	inner, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: contentType, Content: contextTag(0, inner)})
+}
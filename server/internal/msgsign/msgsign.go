@@ -0,0 +1,265 @@
+// Package msgsign signs and encrypts outbound messages with S/MIME
+// (RFC 8551) or PGP/MIME (RFC 3156).
+//
+// A message is split into its body entity, which is signed or encrypted,
+// and its outer headers (From, To, Subject and the like), which are left
+// readable for transport. Build assembles the two back into a complete
+// message that providers send as is.
+package msgsign
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sungwon/smtp-proxy/server/internal/fips"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// fipsEnabled selects FIPS-approved algorithms; tests replace it.
+var fipsEnabled = fips.Enabled
+
+// Kinds of signing keys.
+const (
+	KindSMIME = "smime"
+	KindPGP   = "pgp"
+)
+
+// Failure policies applied when a message cannot be signed or encrypted.
+const (
+	// OnFailureSendUnsigned sends the message as it is.
+	OnFailureSendUnsigned = "send_unsigned"
+	// OnFailureFail fails the message.
+	OnFailureFail = "fail"
+)
+
+// Signer wraps a MIME entity in a multipart/signed entity.
+type Signer interface {
+	Sign(entity []byte) ([]byte, error)
+}
+
+// entityHeaders are the headers that describe the body entity rather than
+// the message, and so are not copied from Message.Headers.
+var entityHeaders = map[string]bool{
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+	"Mime-Version":              true,
+	// Set from Message fields.
+	"From":    true,
+	"To":      true,
+	"Bcc":     true,
+	"Subject": true,
+}
+
+// Entity returns the body of msg, its text and HTML bodies and
+// attachments, as a MIME entity with CRLF line endings and 7-bit transfer
+// encodings, ready to be signed.
+func Entity(msg *provider.Message) ([]byte, error) {
+	text, html := msg.TextBody, msg.HTMLBody
+	if text == "" && html == "" {
+		text = string(msg.Body)
+	}
+
+	var body []byte
+	switch {
+	case text != "" && html != "":
+		alt, err := multipartEntity("alternative", func(w *multipart.Writer) error {
+			if err := writeTextPart(w, "text/plain", text); err != nil {
+				return err
+			}
+			return writeTextPart(w, "text/html", html)
+		})
+		if err != nil {
+			return nil, err
+		}
+		body = alt
+	case html != "":
+		body = textEntity("text/html", html)
+	default:
+		body = textEntity("text/plain", text)
+	}
+	if len(msg.Attachments) == 0 {
+		return body, nil
+	}
+
+	return multipartEntity("mixed", func(w *multipart.Writer) error {
+		if err := writeEntity(w, body); err != nil {
+			return err
+		}
+		for _, att := range msg.Attachments {
+			if err := writeAttachment(w, att); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Build returns a complete message: the outer headers of msg followed by
+// entity, which carries its own Content-Type.
+func Build(msg *provider.Message, entity []byte) []byte {
+	var b bytes.Buffer
+	writeHeader(&b, "From", msg.From)
+	writeHeader(&b, "To", strings.Join(msg.To, ", "))
+	if msg.Subject != "" {
+		writeHeader(&b, "Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	}
+
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		if !entityHeaders[textproto.CanonicalMIMEHeaderKey(k)] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	hasDate := false
+	for _, k := range keys {
+		if textproto.CanonicalMIMEHeaderKey(k) == "Date" {
+			hasDate = true
+		}
+		writeHeader(&b, k, msg.Headers[k])
+	}
+	if !hasDate {
+		writeHeader(&b, "Date", time.Now().Format(time.RFC1123Z))
+	}
+	writeHeader(&b, "MIME-Version", "1.0")
+	b.Write(entity)
+	return b.Bytes()
+}
+
+func writeHeader(b *bytes.Buffer, key, value string) {
+	fmt.Fprintf(b, "%s: %s\r\n", key, value)
+}
+
+// textEntity returns a quoted-printable text entity.
+func textEntity(contentType, content string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(content))
+	qp.Close()
+	return b.Bytes()
+}
+
+// multipartEntity returns a multipart/<subtype> entity whose parts are
+// written by fn.
+func multipartEntity(subtype string, fn func(w *multipart.Writer) error) ([]byte, error) {
+	var parts bytes.Buffer
+	w := multipart.NewWriter(&parts)
+	if err := fn(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Content-Type: multipart/%s; boundary=%q\r\n\r\n", subtype, w.Boundary())
+	b.Write(parts.Bytes())
+	return b.Bytes(), nil
+}
+
+func writeTextPart(w *multipart.Writer, contentType, content string) error {
+	return writeEntity(w, textEntity(contentType, content))
+}
+
+// writeEntity writes an entity, its headers included, as a part of w.
+func writeEntity(w *multipart.Writer, entity []byte) error {
+	header, body, err := splitEntity(entity)
+	if err != nil {
+		return err
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(body)
+	return err
+}
+
+func writeAttachment(w *multipart.Writer, att provider.Attachment) error {
+	header := make(textproto.MIMEHeader)
+	contentType := att.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	disposition := "attachment"
+	if att.IsInline {
+		disposition = "inline"
+		if att.ContentID != "" {
+			header.Set("Content-Id", "<"+att.ContentID+">")
+		}
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": att.Filename}))
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	return writeBase64(part, att.Content)
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) error {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		if _, err := io.WriteString(w, enc[:76]+"\r\n"); err != nil {
+			return err
+		}
+		enc = enc[76:]
+	}
+	_, err := io.WriteString(w, enc+"\r\n")
+	return err
+}
+
+// splitEntity splits an entity into its headers and body.
+func splitEntity(entity []byte) (textproto.MIMEHeader, []byte, error) {
+	i := bytes.Index(entity, []byte("\r\n\r\n"))
+	if i < 0 {
+		return nil, nil, fmt.Errorf("msgsign: entity has no header separator")
+	}
+	header := make(textproto.MIMEHeader)
+	for _, line := range strings.Split(string(entity[:i]), "\r\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("msgsign: malformed entity header %q", line)
+		}
+		header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	return header, entity[i+4:], nil
+}
+
+// signedEntity returns a multipart/signed entity carrying entity and its
+// detached signature as a part with the given headers.
+func signedEntity(protocol, micalg string, entity []byte, sigHeader textproto.MIMEHeader, sig []byte) []byte {
+	w := multipart.NewWriter(io.Discard)
+	boundary := w.Boundary()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Content-Type: multipart/signed; protocol=%q; micalg=%s; boundary=%q\r\n\r\n", protocol, micalg, boundary)
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	b.Write(entity)
+	fmt.Fprintf(&b, "\r\n--%s\r\n", boundary)
+	keys := make([]string, 0, len(sigHeader))
+	for k := range sigHeader {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, sigHeader.Get(k))
+	}
+	b.WriteString("\r\n")
+	b.Write(sig)
+	fmt.Fprintf(&b, "\r\n--%s--\r\n", boundary)
+	return b.Bytes()
+}
@@ -0,0 +1,299 @@
+package msgsign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	"github.com/sungwon/smtp-proxy/server/internal/mimeparse"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+func testMessage() *provider.Message {
+	return &provider.Message{
+		From:     "app@example.com",
+		To:       []string{"user@example.com"},
+		Bcc:      []string{"archive@example.com"},
+		Subject:  "Your invoice",
+		Headers:  map[string]string{"Reply-To": "billing@example.com", "Content-Type": "text/plain"},
+		TextBody: "Hello,\nyour invoice is attached.",
+		HTMLBody: "<p>Hello,</p><p>your invoice is attached.</p>",
+		Attachments: []provider.Attachment{
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4 test")},
+		},
+	}
+}
+
+// testCertificate returns a self-signed certificate and its key in PEM.
+func testCertificate(t *testing.T, cn string) (*x509.Certificate, *rsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return cert, key, string(certPEM), string(keyPEM)
+}
+
+// signedParts splits a multipart/signed entity into the signed entity and
+// the body of the signature part.
+func signedParts(t *testing.T, signed []byte) ([]byte, []byte) {
+	t.Helper()
+	header, body, err := splitEntity(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := header.Get("Content-Type")
+	_, boundary, ok := strings.Cut(ct, `boundary="`)
+	if !ok {
+		t.Fatalf("no boundary in %q", ct)
+	}
+	boundary = strings.TrimSuffix(boundary, `"`)
+	parts := bytes.Split(body, []byte("--"+boundary))
+	if len(parts) != 4 {
+		t.Fatalf("expected two parts, got %d pieces", len(parts))
+	}
+	entity := bytes.TrimPrefix(parts[1], []byte("\r\n"))
+	entity = bytes.TrimSuffix(entity, []byte("\r\n"))
+	_, sig, err := splitEntity(bytes.TrimPrefix(parts[2], []byte("\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity, bytes.TrimSuffix(sig, []byte("\r\n"))
+}
+
+func TestEntityAndBuild(t *testing.T) {
+	msg := testMessage()
+	entity, err := Entity(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := Build(msg, entity)
+
+	parsed, err := mimeparse.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if parsed.Subject != msg.Subject || parsed.Headers.Get("Reply-To") != "billing@example.com" {
+		t.Errorf("headers = %v", parsed.Headers)
+	}
+	if parsed.Headers.Get("Bcc") != "" || strings.Count(string(raw), "Content-Type: text/plain") != 1 {
+		t.Error("expected no Bcc header and no copied Content-Type header")
+	}
+	if strings.ReplaceAll(parsed.TextBody, "\r\n", "\n") != msg.TextBody || !strings.Contains(parsed.HTMLBody, "<p>Hello,</p>") {
+		t.Errorf("bodies = %q, %q", parsed.TextBody, parsed.HTMLBody)
+	}
+	if len(parsed.Attachments) != 1 || string(parsed.Attachments[0].Content) != "%PDF-1.4 test" {
+		t.Errorf("attachments = %+v", parsed.Attachments)
+	}
+}
+
+func TestSMIMESigner_Sign(t *testing.T) {
+	cert, _, certPEM, keyPEM := testCertificate(t, "app@example.com")
+	s, err := NewSMIMESigner(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, _ := Entity(testMessage())
+	signed, err := s.Sign(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, sigB64 := signedParts(t, signed)
+	if !bytes.Equal(got, entity) {
+		t.Fatal("signed part differs from the entity")
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(sigB64), "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil || !ci.ContentType.Equal(oidSignedData) {
+		t.Fatalf("content info = %v, %v", ci.ContentType, err)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		t.Fatal(err)
+	}
+	si := sd.SignerInfos[0]
+	if si.SID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Error("signer identifier does not match the certificate")
+	}
+
+	// The signature covers the signed attributes re-tagged as a SET.
+	attrSet, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: si.SignedAttrs.Bytes})
+	digest := sha256.Sum256(attrSet)
+	if err := rsa.VerifyPKCS1v15(cert.PublicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], si.Signature); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(attrSet, &attrs, "set"); err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256(entity)
+	for _, a := range attrs {
+		if a.Type.Equal(oidMessageDigest) {
+			var md []byte
+			asn1.Unmarshal(a.Values[0].FullBytes, &md)
+			if !bytes.Equal(md, want[:]) {
+				t.Error("message digest attribute does not match the entity")
+			}
+		}
+	}
+}
+
+func TestNewSMIMESigner_KeyMismatch(t *testing.T) {
+	_, _, certPEM, _ := testCertificate(t, "a@example.com")
+	_, _, _, otherKey := testCertificate(t, "b@example.com")
+	if _, err := NewSMIMESigner(certPEM, otherKey); err == nil {
+		t.Error("expected an error for a key that does not match the certificate")
+	}
+}
+
+func TestEncryptSMIME(t *testing.T) {
+	cert, key, _, _ := testCertificate(t, "user@example.com")
+	entity, _ := Entity(testMessage())
+	enc, err := EncryptSMIME(entity, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, err := splitEntity(enc)
+	if err != nil || !strings.Contains(header.Get("Content-Type"), "enveloped-data") {
+		t.Fatalf("header = %v, %v", header, err)
+	}
+	der, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(body), "\r\n", ""))
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil || !ci.ContentType.Equal(oidEnvelopedData) {
+		t.Fatalf("content info = %v, %v", ci.ContentType, err)
+	}
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		t.Fatal(err)
+	}
+	cek, err := rsa.DecryptPKCS1v15(rand.Reader, key, ed.RecipientInfos[0].EncryptedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var iv []byte
+	asn1.Unmarshal(ed.EncryptedContentInfo.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv)
+	block, _ := aes.NewCipher(cek)
+	plain := make([]byte, len(ed.EncryptedContentInfo.EncryptedContent.Bytes))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ed.EncryptedContentInfo.EncryptedContent.Bytes)
+	plain = plain[:len(plain)-int(plain[len(plain)-1])]
+	if !bytes.Equal(plain, entity) {
+		t.Error("decrypted content differs from the entity")
+	}
+
+	if _, err := EncryptSMIME(entity, nil); err == nil {
+		t.Error("expected an error without recipients")
+	}
+}
+
+func TestEncryptSMIME_FIPSUsesOAEP(t *testing.T) {
+	defer func(f func() bool) { fipsEnabled = f }(fipsEnabled)
+	fipsEnabled = func() bool { return true }
+
+	cert, key, _, _ := testCertificate(t, "user@example.com")
+	entity, _ := Entity(testMessage())
+	enc, err := EncryptSMIME(entity, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, body, _ := splitEntity(enc)
+	der, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(body), "\r\n", ""))
+	var ci contentInfo
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		t.Fatal(err)
+	}
+
+	alg := ed.RecipientInfos[0].KeyEncryptionAlgorithm
+	if !alg.Algorithm.Equal(oidRSAESOAEP) {
+		t.Fatalf("key encryption algorithm = %v, want RSAES-OAEP", alg.Algorithm)
+	}
+	var params rsaesOAEPParams
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil || !params.HashFunc.Algorithm.Equal(oidSHA256) || !params.MaskGenFunc.Algorithm.Equal(oidMGF1) {
+		t.Errorf("OAEP parameters = %+v, %v; want SHA-256 with MGF1", params, err)
+	}
+	if _, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, ed.RecipientInfos[0].EncryptedKey, nil); err != nil {
+		t.Errorf("content key does not decrypt with OAEP: %v", err)
+	}
+}
+
+func TestPGPSigner_Sign(t *testing.T) {
+	e, err := openpgp.NewEntity("App", "", "app@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var armored bytes.Buffer
+	w, _ := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err := e.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	s, err := NewPGPSigner(armored.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, _ := Entity(testMessage())
+	signed, err := s.Sign(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(signed, []byte(`protocol="application/pgp-signature"; micalg=pgp-sha256`)) {
+		t.Errorf("unexpected multipart/signed header: %.120s", signed)
+	}
+
+	got, sig := signedParts(t, signed)
+	if _, err := openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{e}, bytes.NewReader(got), bytes.NewReader(sig)); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	if _, err := NewPGPSigner("not a key"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestNewPGPSigner_FIPS(t *testing.T) {
+	defer func(f func() bool) { fipsEnabled = f }(fipsEnabled)
+	fipsEnabled = func() bool { return true }
+
+	if _, err := NewPGPSigner("any key"); err == nil || !strings.Contains(err.Error(), "FIPS") {
+		t.Errorf("NewPGPSigner() in FIPS mode = %v, want an error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package msgsign
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// PGPSigner signs entities with an OpenPGP key.
+type PGPSigner struct {
+	entity *openpgp.Entity
+}
+
+// NewPGPSigner parses an ASCII-armored OpenPGP private key. Keys protected
+// by a passphrase are not supported. OpenPGP is not available in FIPS mode:
+// its implementation is not part of a validated module.
+func NewPGPSigner(armoredKey string) (*PGPSigner, error) {
+	if fipsEnabled() {
+		return nil, errors.New("pgp signing is not available in FIPS mode")
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse pgp key: %w", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("expected one pgp key, got %d", len(entities))
+	}
+	e := entities[0]
+	if e.PrivateKey == nil {
+		return nil, errors.New("pgp key has no private key")
+	}
+	if e.PrivateKey.Encrypted {
+		return nil, errors.New("pgp private key is protected by a passphrase")
+	}
+	return &PGPSigner{entity: e}, nil
+}
+
+// Fingerprint returns the hex fingerprint of the primary key.
+func (s *PGPSigner) Fingerprint() string {
+	return fmt.Sprintf("%X", s.entity.PrimaryKey.Fingerprint)
+}
+
+// Sign implements Signer with a multipart/signed entity carrying a
+// detached application/pgp-signature.
+func (s *PGPSigner) Sign(entity []byte) ([]byte, error) {
+	var sig bytes.Buffer
+	cfg := &packet.Config{DefaultHash: crypto.SHA256}
+	if err := openpgp.ArmoredDetachSign(&sig, s.entity, bytes.NewReader(entity), cfg); err != nil {
+		return nil, fmt.Errorf("pgp: sign: %w", err)
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", `application/pgp-signature; name="signature.asc"`)
+	header.Set("Content-Description", "OpenPGP digital signature")
+	armored := strings.ReplaceAll(strings.TrimSpace(sig.String()), "\n", "\r\n")
+	return signedEntity("application/pgp-signature", "pgp-sha256", entity, header, []byte(armored)), nil
+}
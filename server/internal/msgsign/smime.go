@@ -0,0 +1,122 @@
+package msgsign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/textproto"
+)
+
+// SMIMESigner signs entities with an X.509 certificate and its key.
+type SMIMESigner struct {
+	chain []*x509.Certificate
+	key   crypto.Signer
+}
+
+// NewSMIMESigner parses a PEM certificate chain, signing certificate
+// first, and the PEM private key of the signing certificate.
+func NewSMIMESigner(certPEM, keyPEM string) (*SMIMESigner, error) {
+	chain, err := ParseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKeysEqual(chain[0].PublicKey, key.Public()) {
+		return nil, errors.New("private key does not match the certificate")
+	}
+	return &SMIMESigner{chain: chain, key: key}, nil
+}
+
+// Certificate returns the signing certificate.
+func (s *SMIMESigner) Certificate() *x509.Certificate {
+	return s.chain[0]
+}
+
+// Sign implements Signer with a multipart/signed entity carrying a
+// detached application/pkcs7-signature.
+func (s *SMIMESigner) Sign(entity []byte) ([]byte, error) {
+	sig, err := signDetached(entity, s.key, s.chain)
+	if err != nil {
+		return nil, fmt.Errorf("smime: %w", err)
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", `application/pkcs7-signature; name="smime.p7s"`)
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", `attachment; filename="smime.p7s"`)
+	var b bytes.Buffer
+	writeBase64(&b, sig)
+	return signedEntity("application/pkcs7-signature", "sha-256", entity, header, bytes.TrimSuffix(b.Bytes(), []byte("\r\n"))), nil
+}
+
+// EncryptSMIME returns entity encrypted to recipients as an
+// application/pkcs7-mime entity. Recipient certificates must have RSA
+// keys.
+func EncryptSMIME(entity []byte, recipients []*x509.Certificate) ([]byte, error) {
+	env, err := encrypt(entity, recipients)
+	if err != nil {
+		return nil, fmt.Errorf("smime: %w", err)
+	}
+	var b bytes.Buffer
+	b.WriteString("Content-Type: application/pkcs7-mime; smime-type=enveloped-data; name=\"smime.p7m\"\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n")
+	b.WriteString("Content-Disposition: attachment; filename=\"smime.p7m\"\r\n\r\n")
+	writeBase64(&b, env)
+	return b.Bytes(), nil
+}
+
+// ParseCertificates parses one or more PEM certificates.
+func ParseCertificates(certPEM string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(certPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return certs, nil
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key format")
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	eq, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && eq.Equal(b)
+}
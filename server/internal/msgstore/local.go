@@ -1,9 +1,11 @@
 package msgstore
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -24,6 +26,16 @@ func NewLocalFileStore(basePath string) (*LocalFileStore, error) {
 
 // Put writes message data to a file using an atomic write pattern.
 func (s *LocalFileStore) Put(_ context.Context, messageID string, data []byte) error {
+	return s.write(messageID, bytes.NewReader(data))
+}
+
+// PutStream writes message data read from r to a file using the same
+// atomic write pattern as Put.
+func (s *LocalFileStore) PutStream(_ context.Context, messageID string, r io.Reader, _ int64) error {
+	return s.write(messageID, r)
+}
+
+func (s *LocalFileStore) write(messageID string, r io.Reader) error {
 	finalPath := filepath.Join(s.basePath, messageID)
 
 	// Write to a temp file in the same directory, then rename for atomicity.
@@ -33,7 +45,7 @@ func (s *LocalFileStore) Put(_ context.Context, messageID string, data []byte) e
 	}
 	tmpName := tmp.Name()
 
-	if _, err := tmp.Write(data); err != nil {
+	if _, err := io.Copy(tmp, r); err != nil {
 		tmp.Close()
 		os.Remove(tmpName)
 		return fmt.Errorf("msgstore: write temp file: %w", err)
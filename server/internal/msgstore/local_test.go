@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -32,6 +33,27 @@ func TestLocalFileStore_PutAndGet(t *testing.T) {
 	}
 }
 
+func TestLocalFileStore_PutStream(t *testing.T) {
+	store, err := NewLocalFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalFileStore: %v", err)
+	}
+	ctx := context.Background()
+	data := strings.Repeat("large attachment ", 10000)
+
+	var _ StreamPutter = store
+	if err := store.PutStream(ctx, "msg-001", strings.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("PutStream: %v", err)
+	}
+	got, err := store.Get(ctx, "msg-001")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(got) != data {
+		t.Errorf("Get returned %d bytes, want %d", len(got), len(data))
+	}
+}
+
 func TestLocalFileStore_GetNotFound(t *testing.T) {
 	dir := t.TempDir()
 	store, err := NewLocalFileStore(dir)
@@ -83,6 +83,21 @@ func (s *S3Store) Put(ctx context.Context, messageID string, data []byte) error
 	return nil
 }
 
+// PutStream uploads size bytes read from r to S3.
+func (s *S3Store) PutStream(ctx context.Context, messageID string, r io.Reader, size int64) error {
+	k := s.key(messageID)
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           &k,
+		Body:          r,
+		ContentLength: &size,
+	})
+	if err != nil {
+		return fmt.Errorf("msgstore: s3 put: %w", err)
+	}
+	return nil
+}
+
 // Get downloads message data from S3.
 // Returns ErrNotFound if the object does not exist.
 func (s *S3Store) Get(ctx context.Context, messageID string) ([]byte, error) {
@@ -100,11 +115,16 @@ func (s *S3Store) Get(ctx context.Context, messageID string) ([]byte, error) {
 	}
 	defer out.Body.Close()
 
-	data, err := io.ReadAll(out.Body)
-	if err != nil {
+	// Size the buffer from Content-Length so large bodies are read in one
+	// allocation instead of repeatedly regrown.
+	var buf bytes.Buffer
+	if out.ContentLength != nil && *out.ContentLength > 0 {
+		buf.Grow(int(*out.ContentLength) + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(out.Body); err != nil {
 		return nil, fmt.Errorf("msgstore: s3 read body: %w", err)
 	}
-	return data, nil
+	return buf.Bytes(), nil
 }
 
 // Delete removes a message from S3.
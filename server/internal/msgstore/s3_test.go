@@ -80,6 +80,19 @@ func TestS3Store_PutAndGet(t *testing.T) {
 	}
 }
 
+func TestS3Store_PutStream(t *testing.T) {
+	mock := newMockS3Client()
+	store := NewS3Store(mock, "test-bucket", "prefix/")
+	data := []byte("streamed s3 data")
+
+	if err := store.PutStream(context.Background(), "msg-001", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("PutStream: %v", err)
+	}
+	if got := mock.objects["prefix/msg-001"]; !bytes.Equal(got, data) {
+		t.Errorf("stored %q, want %q", got, data)
+	}
+}
+
 func TestS3Store_GetNotFound(t *testing.T) {
 	mock := newMockS3Client()
 	store := NewS3Store(mock, "test-bucket", "prefix/")
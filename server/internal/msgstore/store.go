@@ -4,6 +4,7 @@ package msgstore
 import (
 	"context"
 	"errors"
+	"io"
 
 	"github.com/rs/zerolog"
 )
@@ -12,12 +13,25 @@ import (
 var ErrNotFound = errors.New("msgstore: message not found")
 
 // MessageStore defines the interface for message storage backends.
+//
+// Put writes data directly and must not retain it after returning: the
+// SMTP server reads bodies into pooled buffers and reuses them for the next
+// message.
 type MessageStore interface {
 	Put(ctx context.Context, messageID string, data []byte) error
 	Get(ctx context.Context, messageID string) ([]byte, error)
 	Delete(ctx context.Context, messageID string) error
 }
 
+// StreamPutter is implemented by stores that can write a message from a
+// reader without holding it in memory. The SMTP server streams bodies it
+// has spilled to disk through it.
+type StreamPutter interface {
+	// PutStream writes size bytes read from r. r is a file in practice;
+	// the S3 store needs it to be seekable to sign the upload.
+	PutStream(ctx context.Context, messageID string, r io.Reader, size int64) error
+}
+
 // Config holds configuration for creating a MessageStore.
 type Config struct {
 	Type       string // "local" or "s3"
@@ -0,0 +1,113 @@
+// Package msgtag extracts per-message tags and key-value metadata from the
+// X-SMTPProxy-Tag and X-SMTPProxy-Metadata submission headers.
+//
+// Tags and metadata are stored with the message, forwarded to providers that
+// support them (SendGrid categories and custom args, Mailgun tags and
+// variables) and can be used to filter the message list and stats APIs. The
+// control headers themselves are never forwarded to providers.
+package msgtag
+
+import (
+	"encoding/json"
+	"net/textproto"
+	"strings"
+	"unicode"
+)
+
+// Submission headers. X-SMTPProxy-Tag holds one or more comma-separated
+// tags; X-SMTPProxy-Metadata holds one key=value pair. Both may repeat.
+const (
+	HeaderTag      = "X-SMTPProxy-Tag"
+	HeaderMetadata = "X-SMTPProxy-Metadata"
+)
+
+// Limits applied at submission. Values beyond them are dropped rather than
+// rejecting the message.
+const (
+	MaxTags         = 10
+	MaxTagLength    = 64
+	MaxMetadataKeys = 20
+	MaxKeyLength    = 64
+	MaxValueLength  = 256
+)
+
+// Parse returns the tags and metadata carried by a message's headers, as
+// produced by net/mail. Duplicate tags are dropped; a repeated metadata key
+// keeps its first value. Tags or keys with whitespace or control characters
+// are ignored.
+func Parse(header map[string][]string) ([]string, map[string]string) {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, v := range header[textproto.CanonicalMIMEHeaderKey(HeaderTag)] {
+		for _, tag := range strings.Split(v, ",") {
+			tag = strings.TrimSpace(tag)
+			if !valid(tag, MaxTagLength) || seen[tag] || len(tags) == MaxTags {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	var metadata map[string]string
+	for _, v := range header[textproto.CanonicalMIMEHeaderKey(HeaderMetadata)] {
+		key, value, ok := strings.Cut(v, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || !valid(key, MaxKeyLength) || len(value) > MaxValueLength {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		if _, dup := metadata[key]; dup || len(metadata) == MaxMetadataKeys {
+			continue
+		}
+		metadata[key] = value
+	}
+	return tags, metadata
+}
+
+// Encode marshals tags and metadata for the messages.tags and
+// messages.metadata columns. Empty values encode as [] and {}.
+func Encode(tags []string, metadata map[string]string) (tagsJSON, metadataJSON []byte) {
+	if tags == nil {
+		tags = []string{}
+	}
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	tagsJSON, _ = json.Marshal(tags)
+	metadataJSON, _ = json.Marshal(metadata)
+	return tagsJSON, metadataJSON
+}
+
+// Decode is the inverse of Encode. Malformed column values decode as empty.
+func Decode(tagsJSON, metadataJSON []byte) ([]string, map[string]string) {
+	var tags []string
+	var metadata map[string]string
+	if len(tagsJSON) > 0 {
+		_ = json.Unmarshal(tagsJSON, &tags)
+	}
+	if len(metadataJSON) > 0 {
+		_ = json.Unmarshal(metadataJSON, &metadata)
+	}
+	return tags, metadata
+}
+
+// IsControlHeader reports whether name is one of the submission headers
+// consumed by this package, which must not be forwarded to providers.
+func IsControlHeader(name string) bool {
+	return strings.EqualFold(name, HeaderTag) || strings.EqualFold(name, HeaderMetadata)
+}
+
+func valid(s string, maxLen int) bool {
+	if s == "" || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package msgtag
+
+import (
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	raw := "From: a@example.com\r\n" +
+		"X-SMTPProxy-Tag: billing, invoice\r\n" +
+		"X-Smtpproxy-Tag: billing\r\n" +
+		"X-SMTPProxy-Tag: has space, ,\r\n" +
+		"X-SMTPProxy-Metadata: customer_id=42\r\n" +
+		"X-SMTPProxy-Metadata: plan = pro=annual\r\n" +
+		"X-SMTPProxy-Metadata: customer_id=43\r\n" +
+		"X-SMTPProxy-Metadata: novalue\r\n" +
+		"\r\nbody\r\n"
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, metadata := Parse(msg.Header)
+	if want := []string{"billing", "invoice"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+	if want := map[string]string{"customer_id": "42", "plan": "pro=annual"}; !reflect.DeepEqual(metadata, want) {
+		t.Errorf("metadata = %v, want %v", metadata, want)
+	}
+}
+
+func TestParse_Limits(t *testing.T) {
+	header := map[string][]string{
+		"X-Smtpproxy-Tag": {"a,b,c,d,e,f,g,h,i,j,k,l", strings.Repeat("x", MaxTagLength+1)},
+	}
+	tags, metadata := Parse(header)
+	if len(tags) != MaxTags {
+		t.Errorf("expected %d tags, got %d", MaxTags, len(tags))
+	}
+	if metadata != nil {
+		t.Errorf("expected no metadata, got %v", metadata)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tagsJSON, metadataJSON := Encode(nil, nil)
+	if string(tagsJSON) != "[]" || string(metadataJSON) != "{}" {
+		t.Errorf("Encode(nil, nil) = %s, %s", tagsJSON, metadataJSON)
+	}
+
+	tagsJSON, metadataJSON = Encode([]string{"billing"}, map[string]string{"k": "v"})
+	tags, metadata := Decode(tagsJSON, metadataJSON)
+	if !reflect.DeepEqual(tags, []string{"billing"}) || metadata["k"] != "v" {
+		t.Errorf("round trip = %v, %v", tags, metadata)
+	}
+}
+
+func TestIsControlHeader(t *testing.T) {
+	for _, h := range []string{"X-SMTPProxy-Tag", "X-Smtpproxy-Metadata"} {
+		if !IsControlHeader(h) {
+			t.Errorf("IsControlHeader(%q) = false", h)
+		}
+	}
+	if IsControlHeader("X-Mailer") {
+		t.Error("IsControlHeader(X-Mailer) = true")
+	}
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Channel types accepted by NewChannel.
+const (
+	ChannelSlack     = "slack"
+	ChannelPagerDuty = "pagerduty"
+	ChannelWebhook   = "webhook"
+)
+
+// NewChannel creates the notifier for a configured alert channel. target is
+// the webhook URL for slack and webhook channels and the integration
+// routing key for pagerduty.
+func NewChannel(channelType, target string, timeout time.Duration) (Notifier, error) {
+	switch channelType {
+	case ChannelSlack, ChannelWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("%s channel: target must be an http(s) URL", channelType)
+		}
+		if channelType == ChannelSlack {
+			return NewSlackNotifier(target, timeout), nil
+		}
+		return NewWebhookNotifier(target, timeout), nil
+	case ChannelPagerDuty:
+		if target == "" {
+			return nil, fmt.Errorf("pagerduty channel: routing key is required")
+		}
+		return NewPagerDutyNotifier(target, timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", channelType)
+	}
+}
+
+// ValidateKinds reports the first entry of kinds that is not a known alert
+// kind.
+func ValidateKinds(kinds []string) error {
+	for _, k := range kinds {
+		if !slices.Contains(Kinds, k) {
+			return fmt.Errorf("unknown alert kind %q", k)
+		}
+	}
+	return nil
+}
+
+// KindFilter passes only alerts of the listed kinds to Notifier. An empty
+// Kinds passes every alert.
+type KindFilter struct {
+	Kinds    []string
+	Notifier Notifier
+}
+
+// Notify implements Notifier.
+func (f KindFilter) Notify(ctx context.Context, alert Alert) error {
+	if len(f.Kinds) > 0 && !slices.Contains(f.Kinds, alert.Kind) {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, alert)
+}
+
+// ChannelConfig describes a deployment-wide alert channel.
+type ChannelConfig struct {
+	Type   string
+	Target string
+	Kinds  []string
+}
+
+// NewChannels builds the kind-filtered notifiers for configs.
+func NewChannels(configs []ChannelConfig, timeout time.Duration) (Multi, error) {
+	var channels Multi
+	for i, c := range configs {
+		n, err := NewChannel(c.Type, c.Target, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", i, err)
+		}
+		if err := ValidateKinds(c.Kinds); err != nil {
+			return nil, fmt.Errorf("channel %d: %w", i, err)
+		}
+		channels = append(channels, KindFilter{Kinds: c.Kinds, Notifier: n})
+	}
+	return channels, nil
+}
@@ -0,0 +1,195 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// recordServer captures the JSON bodies posted to it.
+func recordServer(t *testing.T) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestSlackNotifier_PostsText(t *testing.T) {
+	srv, bodies := recordServer(t)
+	alert := testAlert()
+	alert.Severity = SeverityCritical
+	alert.Summary = "a <b> & c"
+
+	if err := NewSlackNotifier(srv.URL, time.Second).Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(*bodies) != 1 {
+		t.Fatalf("requests = %d, want 1", len(*bodies))
+	}
+	text, _ := (*bodies)[0]["text"].(string)
+	for _, want := range []string{":red_circle:", "a &lt;b&gt; &amp; c", "kind: `slo_breach`", "provider: `sendgrid`", "threshold_seconds: 300"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestPagerDutyNotifier_TriggersEvent(t *testing.T) {
+	srv, bodies := recordServer(t)
+	n := NewPagerDutyNotifier("routing-key", time.Second)
+	n.url = srv.URL
+
+	if err := n.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if len(*bodies) != 1 {
+		t.Fatalf("requests = %d, want 1", len(*bodies))
+	}
+	ev := (*bodies)[0]
+	if ev["routing_key"] != "routing-key" || ev["event_action"] != "trigger" {
+		t.Errorf("event = %v", ev)
+	}
+	if ev["dedup_key"] != "slo_breach/group_id=g1/provider=sendgrid" {
+		t.Errorf("dedup_key = %v", ev["dedup_key"])
+	}
+	payload, _ := ev["payload"].(map[string]any)
+	if payload["severity"] != SeverityWarning || payload["timestamp"] != "2026-01-02T03:04:05Z" {
+		t.Errorf("payload = %v", payload)
+	}
+	details, _ := payload["custom_details"].(map[string]any)
+	if details["provider"] != "sendgrid" || details["observed_seconds"] != float64(420) {
+		t.Errorf("custom_details = %v", details)
+	}
+}
+
+func TestNewChannel(t *testing.T) {
+	tests := []struct {
+		channelType, target string
+		wantErr             bool
+	}{
+		{ChannelSlack, "https://hooks.slack.com/services/x", false},
+		{ChannelWebhook, "http://alerts.internal/hook", false},
+		{ChannelPagerDuty, "abc123", false},
+		{ChannelSlack, "not a url", true},
+		{ChannelWebhook, "ftp://example.com", true},
+		{ChannelPagerDuty, "", true},
+		{"sms", "+15550100", true},
+	}
+	for _, tt := range tests {
+		_, err := NewChannel(tt.channelType, tt.target, time.Second)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewChannel(%q, %q) error = %v, wantErr %v", tt.channelType, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKindFilter(t *testing.T) {
+	stub := &stubNotifier{}
+	f := KindFilter{Kinds: []string{KindDLQGrowth}, Notifier: stub}
+
+	_ = f.Notify(context.Background(), testAlert())
+	_ = f.Notify(context.Background(), Alert{Kind: KindDLQGrowth})
+	if stub.calls != 1 {
+		t.Errorf("calls = %d, want only the dlq_growth alert", stub.calls)
+	}
+
+	if err := ValidateKinds([]string{KindCertExpiring, "disk_full"}); err == nil {
+		t.Error("ValidateKinds accepted an unknown kind")
+	}
+}
+
+type groupStoreFunc func(ctx context.Context, groupID uuid.UUID) ([]storage.AlertChannel, error)
+
+func (f groupStoreFunc) ListEnabledAlertChannelsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.AlertChannel, error) {
+	return f(ctx, groupID)
+}
+
+func TestRouter_DeploymentAndGroupChannels(t *testing.T) {
+	srv, bodies := recordServer(t)
+	groupID := uuid.New()
+	var looked uuid.UUID
+	store := groupStoreFunc(func(_ context.Context, id uuid.UUID) ([]storage.AlertChannel, error) {
+		looked = id
+		return []storage.AlertChannel{
+			{ID: uuid.New(), ChannelType: ChannelWebhook, Target: srv.URL, Kinds: []byte(`[]`)},
+			{ID: uuid.New(), ChannelType: ChannelSlack, Target: srv.URL, Kinds: []byte(`["dlq_growth"]`)},
+		}, nil
+	})
+	deployment := &stubNotifier{}
+	r := NewRouter(deployment, store, time.Second)
+
+	alert := testAlert()
+	alert.Labels["group_id"] = groupID.String()
+	if err := r.Notify(context.Background(), alert); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if deployment.calls != 1 {
+		t.Errorf("deployment calls = %d, want 1", deployment.calls)
+	}
+	if looked != groupID {
+		t.Errorf("looked up group %s, want %s", looked, groupID)
+	}
+	if len(*bodies) != 1 || (*bodies)[0]["kind"] != "slo_breach" {
+		t.Errorf("group requests = %v, want only the unfiltered webhook", *bodies)
+	}
+}
+
+func TestRouter_NoGroupLabel(t *testing.T) {
+	store := groupStoreFunc(func(context.Context, uuid.UUID) ([]storage.AlertChannel, error) {
+		t.Error("group channels loaded for an alert without a group")
+		return nil, nil
+	})
+	deployment := &stubNotifier{}
+	if err := NewRouter(deployment, store, time.Second).Notify(context.Background(), Alert{Kind: KindDLQGrowth}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if deployment.calls != 1 {
+		t.Errorf("deployment calls = %d, want 1", deployment.calls)
+	}
+}
+
+func TestRouter_JoinsErrors(t *testing.T) {
+	errDB := errors.New("db down")
+	store := groupStoreFunc(func(context.Context, uuid.UUID) ([]storage.AlertChannel, error) {
+		return nil, errDB
+	})
+	errBoom := errors.New("boom")
+	r := NewRouter(&stubNotifier{err: errBoom}, store, time.Second)
+
+	err := r.Notify(context.Background(), Alert{Labels: map[string]string{"group_id": uuid.NewString()}})
+	if !errors.Is(err, errBoom) || !errors.Is(err, errDB) {
+		t.Errorf("Notify() error = %v, want both errors", err)
+	}
+}
+
+func TestNewChannels(t *testing.T) {
+	channels, err := NewChannels([]ChannelConfig{
+		{Type: ChannelSlack, Target: "https://hooks.slack.com/services/x"},
+		{Type: ChannelPagerDuty, Target: "key", Kinds: []string{KindCertExpiring}},
+	}, time.Second)
+	if err != nil || len(channels) != 2 {
+		t.Fatalf("NewChannels() = %d channels, %v", len(channels), err)
+	}
+
+	_, err = NewChannels([]ChannelConfig{{Type: ChannelPagerDuty, Target: "key", Kinds: []string{"disk_full"}}}, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "channel 0") {
+		t.Errorf("NewChannels() error = %v, want the invalid channel's index", err)
+	}
+}
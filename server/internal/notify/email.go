@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+)
+
+// EmailConfig configures the SMTP relay used for alert emails.
+type EmailConfig struct {
+	// Addr is the relay address in host:port form.
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// sendMailFunc matches net/smtp.SendMail so tests can capture messages.
+type sendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailNotifier sends alerts as plain-text email through an SMTP relay.
+type EmailNotifier struct {
+	cfg      EmailConfig
+	sendMail sendMailFunc
+}
+
+// NewEmailNotifier creates an EmailNotifier for the given relay settings.
+func NewEmailNotifier(cfg EmailConfig) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg, sendMail: smtp.SendMail}
+}
+
+// Notify implements Notifier. PLAIN auth is used when a username is set.
+func (e *EmailNotifier) Notify(_ context.Context, alert Alert) error {
+	var a smtp.Auth
+	if e.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(e.cfg.Addr)
+		if err != nil {
+			return fmt.Errorf("email: invalid relay address %q: %w", e.cfg.Addr, err)
+		}
+		a = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)
+	}
+
+	if err := e.sendMail(e.cfg.Addr, a, e.cfg.From, e.cfg.To, e.buildMessage(alert)); err != nil {
+		return fmt.Errorf("email: send: %w", err)
+	}
+	return nil
+}
+
+func (e *EmailNotifier) buildMessage(alert Alert) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: [smtp-proxy] %s\r\n", alert.Summary)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&b, "%s\r\n\r\n", alert.Summary)
+	fmt.Fprintf(&b, "kind: %s\r\n", alert.Kind)
+	fmt.Fprintf(&b, "severity: %s\r\n", alert.severity())
+	fmt.Fprintf(&b, "fired_at: %s\r\n", alert.FiredAt.UTC().Format("2006-01-02T15:04:05Z"))
+	for _, k := range sortedKeys(alert.Labels) {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, alert.Labels[k])
+	}
+	for _, k := range sortedKeys(alert.Values) {
+		fmt.Fprintf(&b, "%s: %g\r\n", k, alert.Values[k])
+	}
+	return []byte(b.String())
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
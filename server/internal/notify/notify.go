@@ -0,0 +1,75 @@
+// Package notify delivers operational alerts (SLO breaches, DLQ growth,
+// provider auto-disable and similar) to operators over outbound channels
+// such as Slack, PagerDuty, webhooks and email.
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Alert kinds raised by the worker.
+const (
+	KindSLOBreach          = "slo_breach"
+	KindDLQGrowth          = "dlq_growth"
+	KindProviderDisabled   = "provider_disabled"
+	KindQuotaNearLimit     = "quota_near_limit"
+	KindCertExpiring       = "cert_expiring"
+	KindReputationDegraded = "reputation_degraded"
+	KindCredentialExpiring = "credential_expiring"
+)
+
+// Kinds lists every alert kind, for validating channel filters.
+var Kinds = []string{KindSLOBreach, KindDLQGrowth, KindProviderDisabled, KindQuotaNearLimit, KindCertExpiring, KindReputationDegraded, KindCredentialExpiring}
+
+// Alert severities. An empty Severity is treated as SeverityWarning.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert is a single operator-facing notification.
+type Alert struct {
+	// Kind identifies the alert type, e.g. "slo_breach".
+	Kind string `json:"kind"`
+	// Severity is one of the Severity constants.
+	Severity string `json:"severity,omitempty"`
+	// Summary is a one-line human readable description.
+	Summary string `json:"summary"`
+	// Labels carry the dimensions the alert applies to (group, provider, ...).
+	Labels map[string]string `json:"labels,omitempty"`
+	// Values carry the measured and threshold values that triggered the alert.
+	Values map[string]float64 `json:"values,omitempty"`
+	// FiredAt is when the alert condition was detected.
+	FiredAt time.Time `json:"fired_at"`
+}
+
+// Notifier sends an Alert to a single destination.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Multi fans an alert out to every notifier and joins their errors. A
+// failing notifier does not prevent the remaining ones from being called.
+type Multi []Notifier
+
+// Notify implements Notifier.
+func (m Multi) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// severity returns the alert's severity, defaulting to SeverityWarning.
+func (a Alert) severity() string {
+	if a.Severity == "" {
+		return SeverityWarning
+	}
+	return a.Severity
+}
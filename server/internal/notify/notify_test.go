@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAlert() Alert {
+	return Alert{
+		Kind:    "slo_breach",
+		Summary: "p95 delivery latency 420s exceeds 300s",
+		Labels:  map[string]string{"group_id": "g1", "provider": "sendgrid"},
+		Values:  map[string]float64{"observed_seconds": 420, "threshold_seconds": 300},
+		FiredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestWebhookNotifier_PostsJSON(t *testing.T) {
+	var got Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.URL, time.Second)
+	if err := n.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got.Kind != "slo_breach" || got.Labels["provider"] != "sendgrid" {
+		t.Errorf("received alert = %+v", got)
+	}
+}
+
+func TestWebhookNotifier_Non2xxIsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.URL, time.Second)
+	err := n.Notify(context.Background(), testAlert())
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("Notify() error = %v, want status 502 error", err)
+	}
+}
+
+func TestEmailNotifier_BuildsMessage(t *testing.T) {
+	n := NewEmailNotifier(EmailConfig{
+		Addr:     "mail.example.com:587",
+		Username: "alerts",
+		Password: "secret",
+		From:     "alerts@example.com",
+		To:       []string{"ops@example.com", "oncall@example.com"},
+	})
+
+	var (
+		gotAddr string
+		gotAuth smtp.Auth
+		gotTo   []string
+		gotMsg  string
+	)
+	n.sendMail = func(addr string, a smtp.Auth, _ string, to []string, msg []byte) error {
+		gotAddr, gotAuth, gotTo, gotMsg = addr, a, to, string(msg)
+		return nil
+	}
+
+	if err := n.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if gotAddr != "mail.example.com:587" {
+		t.Errorf("addr = %q", gotAddr)
+	}
+	if gotAuth == nil {
+		t.Error("expected PLAIN auth when username is set")
+	}
+	if len(gotTo) != 2 {
+		t.Errorf("to = %v, want 2 recipients", gotTo)
+	}
+	for _, want := range []string{
+		"Subject: [smtp-proxy] p95 delivery latency 420s exceeds 300s",
+		"provider: sendgrid",
+		"threshold_seconds: 300",
+	} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("message missing %q:\n%s", want, gotMsg)
+		}
+	}
+}
+
+type stubNotifier struct {
+	err   error
+	calls int
+}
+
+func (s *stubNotifier) Notify(context.Context, Alert) error {
+	s.calls++
+	return s.err
+}
+
+func TestMulti_CallsAllAndJoinsErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	a := &stubNotifier{err: errBoom}
+	b := &stubNotifier{}
+
+	err := Multi{a, b}.Notify(context.Background(), testAlert())
+	if !errors.Is(err, errBoom) {
+		t.Errorf("Notify() error = %v, want %v", err, errBoom)
+	}
+	if a.calls != 1 || b.calls != 1 {
+		t.Errorf("calls = %d/%d, want 1/1", a.calls, b.calls)
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// GroupStore loads the alert channels a group has configured.
+type GroupStore interface {
+	ListEnabledAlertChannelsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.AlertChannel, error)
+}
+
+// Router sends every alert to the deployment's channels and, when the
+// alert carries a group_id label, to that group's own channels.
+type Router struct {
+	deployment Notifier
+	store      GroupStore
+	timeout    time.Duration
+}
+
+// NewRouter creates a Router. deployment may be nil when no
+// deployment-wide channels are configured; timeout bounds each request to
+// a group channel.
+func NewRouter(deployment Notifier, store GroupStore, timeout time.Duration) *Router {
+	return &Router{deployment: deployment, store: store, timeout: timeout}
+}
+
+// Notify implements Notifier. A failing channel does not prevent the
+// remaining ones from being called.
+func (r *Router) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	if r.deployment != nil {
+		if err := r.deployment.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	groupID, err := uuid.Parse(alert.Labels["group_id"])
+	if err != nil {
+		return errors.Join(errs...)
+	}
+	channels, err := r.store.ListEnabledAlertChannelsByGroupID(ctx, groupID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("list alert channels: %w", err))
+		return errors.Join(errs...)
+	}
+	for _, ch := range channels {
+		n, err := r.groupChannel(ch)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert channel %s: %w", ch.ID, err))
+			continue
+		}
+		if err := n.Notify(ctx, alert); err != nil {
+			errs = append(errs, fmt.Errorf("alert channel %s: %w", ch.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Router) groupChannel(ch storage.AlertChannel) (Notifier, error) {
+	n, err := NewChannel(ch.ChannelType, ch.Target, r.timeout)
+	if err != nil {
+		return nil, err
+	}
+	var kinds []string
+	if len(ch.Kinds) > 0 {
+		if err := json.Unmarshal(ch.Kinds, &kinds); err != nil {
+			return nil, fmt.Errorf("decode kinds: %w", err)
+		}
+	}
+	return KindFilter{Kinds: kinds, Notifier: n}, nil
+}
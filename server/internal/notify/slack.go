@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackNotifier posts alerts to a Slack incoming webhook.
+type SlackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlackNotifier creates a SlackNotifier for the incoming webhook url.
+func NewSlackNotifier(url string, timeout time.Duration) *SlackNotifier {
+	return &SlackNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify implements Notifier.
+func (s *SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, s.client, s.url, "slack", map[string]string{"text": slackText(alert)})
+}
+
+// slackText renders the alert as mrkdwn: the summary in bold followed by
+// its kind, labels and values.
+func slackText(alert Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s *%s*\n", slackIcon(alert.severity()), slackEscape(alert.Summary))
+	fmt.Fprintf(&b, "kind: `%s`", alert.Kind)
+	for _, k := range sortedKeys(alert.Labels) {
+		fmt.Fprintf(&b, " | %s: `%s`", k, slackEscape(alert.Labels[k]))
+	}
+	for _, k := range sortedKeys(alert.Values) {
+		fmt.Fprintf(&b, " | %s: %g", k, alert.Values[k])
+	}
+	return b.String()
+}
+
+func slackIcon(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return ":red_circle:"
+	case SeverityInfo:
+		return ":information_source:"
+	default:
+		return ":warning:"
+	}
+}
+
+// slackEscape escapes the characters Slack treats as control sequences.
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
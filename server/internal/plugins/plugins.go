@@ -0,0 +1,68 @@
+// Package plugins loads Go plugins that extend delivery with custom
+// provider types and message-processing hooks.
+//
+// A plugin is a package main built with -buildmode=plugin from inside this
+// module, with the same Go version and dependency versions as the daemon,
+// which itself must be built with cgo. It exports
+//
+//	func Register() error
+//
+// which calls provider.RegisterType and provider.RegisterHook. See
+// plugins/webhook for an example.
+package plugins
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// RegisterSymbol is the name of the function every plugin exports.
+const RegisterSymbol = "Register"
+
+// Load opens the Go plugins at paths, in order, and calls their Register
+// functions. It stops at the first plugin that fails to load.
+func Load(paths []string, log zerolog.Logger) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return fmt.Errorf("open plugin %s: %w", path, err)
+		}
+		sym, err := p.Lookup(RegisterSymbol)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", path, err)
+		}
+		if err := register(sym); err != nil {
+			return fmt.Errorf("plugin %s: %w", path, err)
+		}
+		log.Info().Str("path", path).Msg("plugin loaded")
+	}
+
+	var hookNames []string
+	for _, h := range provider.Hooks() {
+		hookNames = append(hookNames, h.Name)
+	}
+	log.Info().
+		Strs("provider_types", provider.RegisteredTypes()).
+		Strs("hooks", hookNames).
+		Msg("plugins registered")
+	return nil
+}
+
+// register calls a plugin's Register symbol.
+func register(sym plugin.Symbol) error {
+	fn, ok := sym.(func() error)
+	if !ok {
+		return fmt.Errorf("%s is %T, want func() error", RegisterSymbol, sym)
+	}
+	if err := fn(); err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	return nil
+}
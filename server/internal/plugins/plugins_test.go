@@ -0,0 +1,41 @@
+package plugins
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLoad_NoPaths(t *testing.T) {
+	if err := Load(nil, zerolog.Nop()); err != nil {
+		t.Fatalf("Load(nil) error = %v", err)
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	err := Load([]string{"/nonexistent/plugin.so"}, zerolog.Nop())
+	if err == nil || !strings.Contains(err.Error(), "/nonexistent/plugin.so") {
+		t.Fatalf("Load() error = %v, want an error naming the plugin", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	called := false
+	if err := register(func() error { called = true; return nil }); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if !called {
+		t.Error("Register was not called")
+	}
+
+	failing := errors.New("missing option")
+	if err := register(func() error { return failing }); !errors.Is(err, failing) {
+		t.Errorf("register() error = %v, want it to wrap %v", err, failing)
+	}
+
+	if err := register(func() {}); err == nil || !strings.Contains(err.Error(), "want func() error") {
+		t.Errorf("register() error = %v, want a signature error", err)
+	}
+}
@@ -0,0 +1,368 @@
+// Package pop3 serves messages captured by the file provider over a
+// read-only POP3 interface (RFC 1939), so developers can point a regular
+// mail client at the sandbox to view test emails.
+//
+// Every captured .eml file in the directory is one message. The mailbox is
+// snapshotted when the client logs in; messages are never deleted.
+package pop3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// idleTimeout is the RFC 1939 minimum autologout timer.
+const idleTimeout = 10 * time.Minute
+
+// Config holds POP3 server configuration.
+type Config struct {
+	// Dir is the output directory of the file provider.
+	Dir string
+	// Username and Password are the mailbox credentials. When Username is
+	// empty any credentials are accepted.
+	Username string
+	Password string
+}
+
+// Server is a read-only POP3 server for captured messages.
+type Server struct {
+	cfg Config
+	log zerolog.Logger
+
+	mu     sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer creates a POP3 server for the given configuration.
+func NewServer(cfg Config, log zerolog.Logger) *Server {
+	return &Server{
+		cfg:   cfg,
+		log:   log.With().Str("component", "pop3").Logger(),
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve accepts connections on ln until Close is called.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops the listener, closes open connections and waits for their
+// handlers to return.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// message is a captured message in a session's mailbox snapshot.
+type message struct {
+	uid  string
+	path string
+	size int
+}
+
+// session is the state of one POP3 connection.
+type session struct {
+	srv      *Server
+	conn     net.Conn
+	r        *bufio.Reader
+	w        *bufio.Writer
+	user     string
+	authed   bool
+	messages []message
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	sess := &session{
+		srv:  s,
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+	sess.ok("smtp-proxy capture POP3 server ready")
+	if err := sess.w.Flush(); err != nil {
+		return
+	}
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		line, err := sess.r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		if !sess.exec(strings.ToUpper(cmd), arg) {
+			return
+		}
+		if err := sess.w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs one command and reports whether the session continues.
+func (sess *session) exec(cmd, arg string) bool {
+	switch cmd {
+	case "QUIT":
+		sess.ok("bye")
+		_ = sess.w.Flush()
+		return false
+	case "CAPA":
+		sess.ok("capability list follows")
+		sess.multiline([]string{"USER", "TOP", "UIDL", "RESP-CODES"})
+		return true
+	case "NOOP":
+		sess.ok("")
+		return true
+	}
+
+	if !sess.authed {
+		switch cmd {
+		case "USER":
+			sess.user = arg
+			sess.ok("send PASS")
+		case "PASS":
+			sess.pass(arg)
+		default:
+			sess.err("authenticate with USER and PASS first")
+		}
+		return true
+	}
+
+	switch cmd {
+	case "STAT":
+		total := 0
+		for _, m := range sess.messages {
+			total += m.size
+		}
+		sess.ok(fmt.Sprintf("%d %d", len(sess.messages), total))
+	case "LIST":
+		sess.listing(arg, func(n int, m message) string { return fmt.Sprintf("%d %d", n, m.size) })
+	case "UIDL":
+		sess.listing(arg, func(n int, m message) string { return fmt.Sprintf("%d %s", n, m.uid) })
+	case "RETR":
+		sess.retrieve(arg, -1)
+	case "TOP":
+		n, lines, _ := strings.Cut(arg, " ")
+		count, err := strconv.Atoi(lines)
+		if err != nil || count < 0 {
+			sess.err("usage: TOP msg n")
+			return true
+		}
+		sess.retrieve(n, count)
+	case "DELE":
+		sess.err("[SYS/PERM] mailbox is read-only")
+	case "RSET":
+		sess.ok("")
+	default:
+		sess.err("unknown command")
+	}
+	return true
+}
+
+func (sess *session) pass(password string) {
+	cfg := sess.srv.cfg
+	if cfg.Username != "" {
+		userOK := subtle.ConstantTimeCompare([]byte(sess.user), []byte(cfg.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Password)) == 1
+		if !userOK || !passOK {
+			sess.srv.log.Warn().Str("user", sess.user).Msg("POP3 login failed")
+			sess.err("[AUTH] invalid credentials")
+			return
+		}
+	}
+
+	messages, err := scan(cfg.Dir)
+	if err != nil {
+		sess.srv.log.Error().Err(err).Str("dir", cfg.Dir).Msg("failed to read capture directory")
+		sess.err("[SYS/TEMP] mailbox unavailable")
+		return
+	}
+	sess.messages = messages
+	sess.authed = true
+	sess.ok(fmt.Sprintf("%d messages", len(messages)))
+}
+
+// listing answers LIST and UIDL for one message or the whole mailbox.
+func (sess *session) listing(arg string, format func(n int, m message) string) {
+	if arg != "" {
+		n, m, ok := sess.lookup(arg)
+		if !ok {
+			return
+		}
+		sess.ok(format(n, m))
+		return
+	}
+	lines := make([]string, len(sess.messages))
+	for i, m := range sess.messages {
+		lines[i] = format(i+1, m)
+	}
+	sess.ok(fmt.Sprintf("%d messages", len(lines)))
+	sess.multiline(lines)
+}
+
+// retrieve answers RETR (bodyLines < 0) and TOP.
+func (sess *session) retrieve(arg string, bodyLines int) {
+	_, m, ok := sess.lookup(arg)
+	if !ok {
+		return
+	}
+	data, err := load(m.path)
+	if err != nil {
+		sess.err("[SYS/TEMP] message unavailable")
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	if bodyLines >= 0 {
+		end := len(lines)
+		for i, l := range lines {
+			if l == "" {
+				end = min(len(lines), i+1+bodyLines)
+				break
+			}
+		}
+		lines = lines[:end]
+	}
+	sess.ok(fmt.Sprintf("%d octets", m.size))
+	sess.multiline(lines)
+}
+
+func (sess *session) lookup(arg string) (int, message, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 1 || n > len(sess.messages) {
+		sess.err("no such message")
+		return 0, message{}, false
+	}
+	return n, sess.messages[n-1], true
+}
+
+func (sess *session) ok(text string) {
+	if text == "" {
+		sess.w.WriteString("+OK\r\n")
+		return
+	}
+	sess.w.WriteString("+OK " + text + "\r\n")
+}
+
+func (sess *session) err(text string) {
+	sess.w.WriteString("-ERR " + text + "\r\n")
+}
+
+// multiline writes a dot-stuffed multi-line response body.
+func (sess *session) multiline(lines []string) {
+	for _, l := range lines {
+		if strings.HasPrefix(l, ".") {
+			sess.w.WriteString(".")
+		}
+		sess.w.WriteString(l + "\r\n")
+	}
+	sess.w.WriteString(".\r\n")
+}
+
+// scan lists the captured messages in dir, oldest first. File names start
+// with the capture timestamp, so name order is chronological.
+func scan(dir string) ([]message, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	messages := make([]message, 0, len(paths))
+	for _, p := range paths {
+		data, err := load(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		messages = append(messages, message{
+			uid:  strings.TrimSuffix(filepath.Base(p), ".eml"),
+			path: p,
+			size: len(data),
+		})
+	}
+	return messages, nil
+}
+
+// load reads a captured message with CRLF line endings. The file provider
+// writes a summary header block followed by the original message; when the
+// original is present it is returned instead, so clients render its MIME
+// structure.
+func load(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if _, rest, ok := bytes.Cut(data, []byte("\n\n")); ok {
+		if msg, err := mail.ReadMessage(bytes.NewReader(rest)); err == nil && (msg.Header.Get("From") != "" || msg.Header.Get("Subject") != "") {
+			data = rest
+		}
+	}
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n")), nil
+}
@@ -0,0 +1,160 @@
+package pop3
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// captured mimics the file provider's output: a summary header block
+// followed by the original message.
+const captured = "From: alice@example.com\n" +
+	"To: bob@example.com\n" +
+	"Subject: Hello\n" +
+	"X-Provider-Message-ID: file-1\n" +
+	"\n" +
+	"From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Hello\r\n" +
+	"\r\n" +
+	"line one\r\n" +
+	".starts with a dot\r\n" +
+	"line three\r\n"
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startServer(t *testing.T, cfg Config) *client {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := NewServer(cfg, zerolog.Nop())
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+	if line := c.line(); !strings.HasPrefix(line, "+OK") {
+		t.Fatalf("greeting = %q", line)
+	}
+	return c
+}
+
+func (c *client) line() string {
+	c.t.Helper()
+	l, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return strings.TrimRight(l, "\r\n")
+}
+
+func (c *client) cmd(command string) string {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(command + "\r\n")); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+	return c.line()
+}
+
+func (c *client) multiline() []string {
+	c.t.Helper()
+	var lines []string
+	for {
+		l := c.line()
+		if l == "." {
+			return lines
+		}
+		lines = append(lines, strings.TrimPrefix(l, "."))
+	}
+}
+
+func writeCapture(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSession_RetrieveCapturedMessages(t *testing.T) {
+	dir := t.TempDir()
+	writeCapture(t, dir, "20260301_120000_a.eml", captured)
+	writeCapture(t, dir, "20260301_120500_b.eml", "From: x@example.com\nSubject: Plain\n\nbody\n")
+	writeCapture(t, dir, "notes.txt", "ignored")
+
+	c := startServer(t, Config{Dir: dir})
+	c.cmd("USER dev")
+	if got := c.cmd("PASS anything"); got != "+OK 2 messages" {
+		t.Fatalf("PASS = %q", got)
+	}
+
+	if got := c.cmd("UIDL"); !strings.HasPrefix(got, "+OK") {
+		t.Fatalf("UIDL = %q", got)
+	}
+	if uids := c.multiline(); len(uids) != 2 || uids[0] != "1 20260301_120000_a" {
+		t.Errorf("UIDL listing = %v", uids)
+	}
+
+	if got := c.cmd("RETR 1"); !strings.HasPrefix(got, "+OK") {
+		t.Fatalf("RETR = %q", got)
+	}
+	msg := c.multiline()
+	if msg[0] != "From: Alice <alice@example.com>" {
+		t.Errorf("expected the original message, got first line %q", msg[0])
+	}
+	if msg[5] != ".starts with a dot" {
+		t.Errorf("dot-stuffed line = %q", msg[5])
+	}
+
+	if got := c.cmd("TOP 1 1"); !strings.HasPrefix(got, "+OK") {
+		t.Fatalf("TOP = %q", got)
+	}
+	if top := c.multiline(); len(top) != 5 || top[4] != "line one" {
+		t.Errorf("TOP 1 1 = %v", top)
+	}
+
+	if got := c.cmd("RETR 3"); !strings.HasPrefix(got, "-ERR") {
+		t.Errorf("RETR 3 = %q", got)
+	}
+	if got := c.cmd("DELE 1"); !strings.HasPrefix(got, "-ERR") {
+		t.Errorf("DELE must be refused, got %q", got)
+	}
+	if got := c.cmd("QUIT"); !strings.HasPrefix(got, "+OK") {
+		t.Errorf("QUIT = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20260301_120000_a.eml")); err != nil {
+		t.Errorf("captured message must not be removed: %v", err)
+	}
+}
+
+func TestSession_Credentials(t *testing.T) {
+	c := startServer(t, Config{Dir: t.TempDir(), Username: "dev", Password: "secret"})
+
+	if got := c.cmd("STAT"); !strings.HasPrefix(got, "-ERR") {
+		t.Errorf("STAT before login = %q", got)
+	}
+	c.cmd("USER dev")
+	if got := c.cmd("PASS wrong"); !strings.HasPrefix(got, "-ERR") {
+		t.Errorf("PASS wrong = %q", got)
+	}
+	c.cmd("USER dev")
+	if got := c.cmd("PASS secret"); got != "+OK 0 messages" {
+		t.Errorf("PASS secret = %q", got)
+	}
+	if got := c.cmd("STAT"); got != "+OK 0 0" {
+		t.Errorf("STAT = %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Postgres checks that the database at url accepts connections.
+func Postgres(url string) Check {
+	return Check{Name: "postgres", Run: func(ctx context.Context) error {
+		conn, err := pgx.Connect(ctx, url)
+		if err != nil {
+			return fmt.Errorf("connect: %w", err)
+		}
+		defer conn.Close(context.Background())
+		if err := conn.Ping(ctx); err != nil {
+			return fmt.Errorf("ping: %w", err)
+		}
+		return nil
+	}}
+}
+
+// Schema checks that the database at url is at a schema version the
+// daemon can start with under mode (database.schema_check). A database
+// behind the binary passes when migrateOnStart will migrate it.
+func Schema(url, mode string, migrateOnStart bool) Check {
+	return Check{Name: "schema", Run: func(ctx context.Context) error {
+		if mode == migrate.CheckOff {
+			return nil
+		}
+		conn, err := pgx.Connect(ctx, url)
+		if err != nil {
+			return fmt.Errorf("connect: %w", err)
+		}
+		defer conn.Close(context.Background())
+		compat, err := migrate.Check(ctx, conn)
+		if err != nil {
+			return err
+		}
+		_, err = compat.Decide(mode)
+		if errors.Is(err, migrate.ErrSchemaBehind) && migrateOnStart {
+			return nil
+		}
+		return err
+	}}
+}
+
+// Redis checks that the Redis server accepts commands.
+func Redis(opts *redis.Options) Check {
+	return Check{Name: "redis", Run: func(ctx context.Context) error {
+		// Fail on the first refused dial instead of retrying.
+		o := *opts
+		o.MaxRetries = -1
+		o.DialerRetries = 1
+		client := redis.NewClient(&o)
+		defer client.Close()
+		if err := client.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("ping %s: %w", opts.Addr, err)
+		}
+		return nil
+	}}
+}
+
+// MessageStore checks that the message store is writable by storing,
+// reading back and deleting a probe object.
+func MessageStore(cfg msgstore.Config) Check {
+	return Check{Name: "msgstore", Run: func(ctx context.Context) error {
+		if cfg.Type != "local" && cfg.Type != "s3" {
+			return fmt.Errorf("unsupported storage.type %q (want local or s3)", cfg.Type)
+		}
+		store, err := msgstore.New(cfg, zerolog.Nop())
+		if err != nil {
+			return err
+		}
+		id := "preflight-" + uuid.NewString()
+		probe := []byte("smtp-proxy preflight probe")
+		if err := store.Put(ctx, id, probe); err != nil {
+			return fmt.Errorf("write probe: %w", err)
+		}
+		got, err := store.Get(ctx, id)
+		if delErr := store.Delete(ctx, id); delErr != nil && err == nil {
+			err = fmt.Errorf("delete probe: %w", delErr)
+		}
+		if err != nil {
+			return fmt.Errorf("read probe: %w", err)
+		}
+		if !bytes.Equal(got, probe) {
+			return errors.New("probe read back does not match what was written")
+		}
+		return nil
+	}}
+}
+
+// KeyPair checks that certFile and keyFile hold a matching certificate
+// and key, and that the certificate is currently valid.
+func KeyPair(name, certFile, keyFile string) Check {
+	return Check{Name: name, Run: func(context.Context) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", certFile, err)
+		}
+		now := time.Now()
+		if now.After(leaf.NotAfter) {
+			return fmt.Errorf("%s expired on %s", certFile, leaf.NotAfter.Format(time.RFC3339))
+		}
+		if now.Before(leaf.NotBefore) {
+			return fmt.Errorf("%s is not valid until %s", certFile, leaf.NotBefore.Format(time.RFC3339))
+		}
+		return nil
+	}}
+}
+
+// CAFile checks that path holds at least one PEM certificate.
+func CAFile(name, path string) Check {
+	return Check{Name: name, Run: func(context.Context) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", path)
+		}
+		return nil
+	}}
+}
+
+// Providers runs the health check of every enabled ESP provider in the
+// database at dbURL through client. No mail is sent.
+func Providers(dbURL string, client provider.HTTPClient) Check {
+	return Check{Name: "providers", Run: func(ctx context.Context) error {
+		conn, err := pgx.Connect(ctx, dbURL)
+		if err != nil {
+			return fmt.Errorf("connect: %w", err)
+		}
+		defer conn.Close(context.Background())
+
+		esps, err := storage.New(conn).ListEnabledProviders(ctx)
+		if err != nil {
+			return fmt.Errorf("list providers: %w", err)
+		}
+		var errs []error
+		for i := range esps {
+			p, err := provider.NewProviderFromStorage(&esps[i], client)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := p.HealthCheck(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("provider %q: %w", esps[i].Name, err))
+			}
+		}
+		return errors.Join(errs...)
+	}}
+}
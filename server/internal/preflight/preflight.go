@@ -0,0 +1,88 @@
+// Package preflight checks a daemon's configuration and dependencies
+// without starting it. The daemons run the checks for --validate-config so
+// CI/CD pipelines can reject a bad configuration before rollout.
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultTimeout bounds each check.
+const DefaultTimeout = 10 * time.Second
+
+// Check is a single named validation.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Report holds the results of a preflight run, in check order.
+type Report struct {
+	Results []Result
+}
+
+// OK reports whether every check passed.
+func (r Report) OK() bool {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints one line per check followed by a summary line.
+func (r Report) Write(w io.Writer) {
+	failed := 0
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL  %-20s %v\n", res.Name, res.Err)
+			continue
+		}
+		fmt.Fprintf(w, "ok    %-20s %s\n", res.Name, res.Duration.Round(time.Millisecond))
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(r.Results))
+		return
+	}
+	fmt.Fprintf(w, "all %d checks passed\n", len(r.Results))
+}
+
+// Run runs checks in order, each bounded by timeout, and reports every
+// result: a failing check does not stop the others, so one run lists all
+// problems.
+func Run(ctx context.Context, timeout time.Duration, checks []Check) Report {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	report := Report{Results: make([]Result, 0, len(checks))}
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := c.Run(checkCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		report.Results = append(report.Results, Result{Name: c.Name, Err: err, Duration: time.Since(start)})
+	}
+	return report
+}
+
+// Static returns a check reporting an error found while building the
+// configuration, such as a parse error.
+func Static(name string, err error) Check {
+	return Check{Name: name, Run: func(context.Context) error { return err }}
+}
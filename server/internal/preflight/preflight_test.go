@@ -0,0 +1,181 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+)
+
+func TestRun_ReportsEveryCheck(t *testing.T) {
+	var ran []string
+	check := func(name string, err error) Check {
+		return Check{Name: name, Run: func(context.Context) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	report := Run(context.Background(), time.Second, []Check{
+		check("a", nil),
+		check("b", errors.New("broken")),
+		check("c", nil),
+	})
+
+	if strings.Join(ran, ",") != "a,b,c" {
+		t.Errorf("ran %v, want every check in order", ran)
+	}
+	if report.OK() {
+		t.Error("expected report to fail")
+	}
+
+	var buf bytes.Buffer
+	report.Write(&buf)
+	out := buf.String()
+	for _, want := range []string{"ok    a", "FAIL  b", "broken", "ok    c", "1 of 3 checks failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRun_AllPass(t *testing.T) {
+	report := Run(context.Background(), time.Second, []Check{Static("config", nil)})
+	if !report.OK() {
+		t.Fatal("expected report to pass")
+	}
+	var buf bytes.Buffer
+	report.Write(&buf)
+	if !strings.Contains(buf.String(), "all 1 checks passed") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	report := Run(context.Background(), 10*time.Millisecond, []Check{{
+		Name: "slow",
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}})
+	if report.OK() {
+		t.Fatal("expected timeout to fail the check")
+	}
+	if err := report.Results[0].Err; !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRedis_Unreachable(t *testing.T) {
+	err := Redis(&redis.Options{Addr: "127.0.0.1:1"}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.1:1") {
+		t.Errorf("expected ping error naming the address, got %v", err)
+	}
+}
+
+func TestMessageStore_Local(t *testing.T) {
+	dir := t.TempDir()
+	if err := MessageStore(msgstore.Config{Type: "local", Path: dir}).Run(context.Background()); err != nil {
+		t.Fatalf("expected local store to pass, got %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if !e.IsDir() {
+			t.Errorf("probe %s was not deleted", e.Name())
+		}
+	}
+}
+
+func TestMessageStore_UnsupportedType(t *testing.T) {
+	err := MessageStore(msgstore.Config{Type: "ftp"}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `"ftp"`) {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
+
+// writeCert writes a self-signed certificate valid from notBefore to
+// notAfter and its key to dir, returning the file paths.
+func writeCert(t *testing.T, dir string, notBefore, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "preflight.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestKeyPair(t *testing.T) {
+	now := time.Now()
+
+	t.Run("valid", func(t *testing.T) {
+		certFile, keyFile := writeCert(t, t.TempDir(), now.Add(-time.Hour), now.Add(time.Hour))
+		if err := KeyPair("tls", certFile, keyFile).Run(context.Background()); err != nil {
+			t.Errorf("expected valid key pair, got %v", err)
+		}
+		if err := CAFile("ca", certFile).Run(context.Background()); err != nil {
+			t.Errorf("expected certificate to load as CA file, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		certFile, keyFile := writeCert(t, t.TempDir(), now.Add(-2*time.Hour), now.Add(-time.Hour))
+		err := KeyPair("tls", certFile, keyFile).Run(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "expired") {
+			t.Errorf("expected expiry error, got %v", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if err := KeyPair("tls", "/nonexistent/cert.pem", "/nonexistent/key.pem").Run(context.Background()); err == nil {
+			t.Error("expected error for missing files")
+		}
+	})
+}
+
+func TestCAFile_NoCertificates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CAFile("ca", path).Run(context.Background()); err == nil {
+		t.Error("expected error for file without certificates")
+	}
+}
@@ -0,0 +1,108 @@
+// Package preview submits rendered email HTML to an external rendering-test
+// service (Litmus, Email on Acid or a compatible in-house tool) and returns
+// the per-client preview URLs it produces.
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrNotConfigured is returned by a nil or unconfigured RenderTester.
+var ErrNotConfigured = errors.New("preview: rendering test service not configured")
+
+// RenderRequest is the message submitted for a rendering test.
+type RenderRequest struct {
+	Subject string   `json:"subject"`
+	HTML    string   `json:"html"`
+	Clients []string `json:"clients,omitempty"`
+}
+
+// RenderPreview is a single client's rendering.
+type RenderPreview struct {
+	Client string `json:"client"`
+	URL    string `json:"url"`
+}
+
+// RenderResult is the rendering service's response. Previews may be empty
+// when the service renders asynchronously; StatusURL then points at the
+// test's results page.
+type RenderResult struct {
+	ID        string          `json:"id"`
+	StatusURL string          `json:"status_url,omitempty"`
+	Previews  []RenderPreview `json:"previews"`
+}
+
+// RenderTester submits a message for a rendering test.
+type RenderTester interface {
+	Submit(ctx context.Context, req RenderRequest) (*RenderResult, error)
+}
+
+// HTTPRenderTester POSTs a RenderRequest as JSON to a rendering-test API and
+// decodes a RenderResult from the response.
+type HTTPRenderTester struct {
+	url     string
+	apiKey  string
+	clients []string
+	client  *http.Client
+}
+
+// NewHTTPRenderTester creates an HTTPRenderTester for the API at url. The
+// apiKey, if set, is sent as a bearer token. clients is the default list of
+// mail clients to render when a request does not name any.
+func NewHTTPRenderTester(url, apiKey string, clients []string, timeout time.Duration) *HTTPRenderTester {
+	return &HTTPRenderTester{
+		url:     url,
+		apiKey:  apiKey,
+		clients: clients,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Submit implements RenderTester. Any non-2xx response is treated as a
+// failure.
+func (t *HTTPRenderTester) Submit(ctx context.Context, r RenderRequest) (*RenderResult, error) {
+	if t == nil || t.url == "" {
+		return nil, ErrNotConfigured
+	}
+	if len(r.Clients) == 0 {
+		r.Clients = t.clients
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("preview: marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("preview: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("preview: send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("preview: unexpected status %d", resp.StatusCode)
+	}
+
+	var result RenderResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("preview: decode response: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,60 @@
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPRenderTester_Submit(t *testing.T) {
+	var got RenderRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer key-1" {
+			t.Errorf("Authorization = %q, want Bearer key-1", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(RenderResult{
+			ID:       "test-1",
+			Previews: []RenderPreview{{Client: "outlook2019", URL: "https://render.example/test-1/outlook2019.png"}},
+		})
+	}))
+	defer srv.Close()
+
+	tester := NewHTTPRenderTester(srv.URL, "key-1", []string{"outlook2019", "gmail"}, time.Second)
+	result, err := tester.Submit(context.Background(), RenderRequest{Subject: "Hi", HTML: "<p>Hi</p>"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if got.HTML != "<p>Hi</p>" || len(got.Clients) != 2 {
+		t.Errorf("unexpected request: %+v", got)
+	}
+	if result.ID != "test-1" || len(result.Previews) != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestHTTPRenderTester_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+	}))
+	defer srv.Close()
+
+	tester := NewHTTPRenderTester(srv.URL, "", nil, time.Second)
+	if _, err := tester.Submit(context.Background(), RenderRequest{HTML: "<p>x</p>"}); err == nil {
+		t.Fatal("expected error for non-2xx status")
+	}
+}
+
+func TestHTTPRenderTester_NotConfigured(t *testing.T) {
+	var tester *HTTPRenderTester
+	if _, err := tester.Submit(context.Background(), RenderRequest{}); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("err = %v, want ErrNotConfigured", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package profiling exposes Go runtime profiles of the daemons: pprof
+// endpoints on an admin-only listener, and continuous export of CPU and
+// heap profiles to a Pyroscope-compatible server, for finding production
+// hotspots such as MIME parsing and provider calls.
+package profiling
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+)
+
+// Config controls profiling of one daemon.
+type Config struct {
+	// PprofAddr is the address of the admin listener serving
+	// /debug/pprof/. The listener is disabled when it is empty.
+	PprofAddr string
+	// Token is the bearer token required by the pprof endpoints. Without
+	// one every request is refused.
+	Token string
+	// BlockProfileRate and MutexProfileFraction enable the block and
+	// mutex profiles; see runtime.SetBlockProfileRate and
+	// runtime.SetMutexProfileFraction. Zero leaves them off.
+	BlockProfileRate     int
+	MutexProfileFraction int
+	// Continuous configures the profile exporter.
+	Continuous ContinuousConfig
+}
+
+// ConfigFrom returns the profiling configuration of a daemon from the
+// shared profiling section. A non-empty pprofAddr, from the daemon's
+// --pprof-addr flag, overrides cfg.PprofAddr.
+func ConfigFrom(cfg config.ProfilingConfig, pprofAddr string) Config {
+	c := Config{
+		PprofAddr:            cfg.PprofAddr,
+		Token:                cfg.Token,
+		BlockProfileRate:     cfg.BlockProfileRate,
+		MutexProfileFraction: cfg.MutexProfileFraction,
+		Continuous: ContinuousConfig{
+			Enabled:   cfg.Continuous.Enabled,
+			ServerURL: cfg.Continuous.ServerURL,
+			AuthToken: cfg.Continuous.AuthToken,
+			AppName:   cfg.Continuous.AppName,
+			Interval:  cfg.Continuous.Interval,
+			Tags:      cfg.Continuous.Tags,
+		},
+	}
+	if pprofAddr != "" {
+		c.PprofAddr = pprofAddr
+	}
+	return c
+}
+
+// Handler returns the pprof endpoints under /debug/pprof/, behind the
+// bearer token. Like the SMTP admin API, it refuses every request when no
+// token is configured.
+func Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			apierror.Write(w, http.StatusForbidden, "", "pprof token not configured", nil)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			apierror.Write(w, http.StatusUnauthorized, "", "unauthorized", nil)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Profiler runs a daemon's pprof listener and profile exporter.
+type Profiler struct {
+	server   *http.Server
+	exporter *Exporter
+	log      zerolog.Logger
+}
+
+// Start applies cfg's runtime profile rates and starts the pprof listener
+// and the exporter that cfg enables. service names the daemon in exported
+// profiles. The returned Profiler must be stopped with Shutdown.
+func Start(ctx context.Context, cfg Config, service string, log zerolog.Logger) (*Profiler, error) {
+	if cfg.BlockProfileRate > 0 {
+		runtime.SetBlockProfileRate(cfg.BlockProfileRate)
+	}
+	if cfg.MutexProfileFraction > 0 {
+		runtime.SetMutexProfileFraction(cfg.MutexProfileFraction)
+	}
+
+	p := &Profiler{log: log}
+	if cfg.PprofAddr != "" {
+		ln, err := net.Listen("tcp", cfg.PprofAddr)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Token == "" {
+			log.Warn().Msg("pprof listener has no token configured; all requests will be refused")
+		}
+		p.server = &http.Server{
+			Handler:           Handler(cfg.Token),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Info().Str("addr", ln.Addr().String()).Msg("pprof listener started")
+			if err := p.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("pprof listener error")
+			}
+		}()
+	}
+	if cfg.Continuous.Enabled {
+		p.exporter = NewExporter(cfg.Continuous, service, log)
+		p.exporter.Start(ctx)
+	}
+	return p, nil
+}
+
+// Shutdown stops the exporter and the pprof listener.
+func (p *Profiler) Shutdown(ctx context.Context) {
+	if p.exporter != nil {
+		p.exporter.Stop()
+	}
+	if p.server != nil {
+		if err := p.server.Shutdown(ctx); err != nil {
+			p.log.Error().Err(err).Msg("pprof listener shutdown error")
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package profiling
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/config"
+)
+
+func TestHandler_RequiresToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		authHeader string
+		wantStatus int
+	}{
+		{"no token configured", "", "Bearer anything", http.StatusForbidden},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			Handler(tt.token).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandler_ServesProfiles(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/heap?debug=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	Handler("secret").ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "heap profile") {
+		t.Errorf("heap profile: status %d, body %.80q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStart_PprofListener(t *testing.T) {
+	p, err := Start(context.Background(), Config{PprofAddr: "127.0.0.1:0", Token: "secret"}, "test", zerolog.Nop())
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer p.Shutdown(context.Background())
+	if p.server == nil || p.exporter != nil {
+		t.Fatalf("Start() = server %v, exporter %v; want only the listener", p.server, p.exporter)
+	}
+}
+
+func TestSeriesName(t *testing.T) {
+	got := seriesName("smtp-proxy", "queue-worker", map[string]string{"region": "eu", "env": "prod"})
+	want := "smtp-proxy{env=prod,region=eu,service=queue-worker}"
+	if got != want {
+		t.Errorf("seriesName() = %q, want %q", got, want)
+	}
+}
+
+type ingestRequest struct {
+	query   map[string]string
+	auth    string
+	profile int
+}
+
+func TestExporter_RunOnce(t *testing.T) {
+	var mu sync.Mutex
+	var got []ingestRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ingest" {
+			http.NotFound(w, r)
+			return
+		}
+		f, _, err := r.FormFile("profile")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		q := map[string]string{}
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		mu.Lock()
+		got = append(got, ingestRequest{query: q, auth: r.Header.Get("Authorization"), profile: len(data)})
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e := NewExporter(ContinuousConfig{
+		ServerURL: srv.URL + "/",
+		AuthToken: "key",
+		Interval:  50 * time.Millisecond,
+	}, "smtp-server", zerolog.Nop())
+	if err := e.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d uploads, want CPU and heap", len(got))
+	}
+	for _, req := range got {
+		if req.query["name"] != "smtp-proxy{service=smtp-server}" || req.query["format"] != "pprof" {
+			t.Errorf("unexpected query %v", req.query)
+		}
+		if req.query["from"] == "" || req.query["until"] == "" {
+			t.Errorf("missing time range in %v", req.query)
+		}
+		if req.auth != "Bearer key" {
+			t.Errorf("Authorization = %q, want bearer token", req.auth)
+		}
+		if req.profile == 0 {
+			t.Errorf("empty profile uploaded")
+		}
+	}
+}
+
+func TestExporter_RunOnce_ServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	e := NewExporter(ContinuousConfig{ServerURL: srv.URL, Interval: 10 * time.Millisecond}, "api-server", zerolog.Nop())
+	if err := e.RunOnce(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("RunOnce() = %v, want the server's 503", err)
+	}
+}
+
+func TestExporter_StopDuringInterval(t *testing.T) {
+	uploads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+	}))
+	defer srv.Close()
+
+	e := NewExporter(ContinuousConfig{ServerURL: srv.URL, Interval: time.Hour}, "queue-worker", zerolog.Nop())
+	e.Start(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not interrupt the CPU profile")
+	}
+	if uploads != 0 {
+		t.Errorf("%d uploads after Stop, want none", uploads)
+	}
+}
+
+func TestConfigFrom(t *testing.T) {
+	cfg := config.ProfilingConfig{
+		PprofAddr: "127.0.0.1:6060",
+		Token:     "secret",
+		Continuous: config.ContinuousProfilingConfig{
+			Enabled:  true,
+			AppName:  "smtp-proxy",
+			Interval: time.Minute,
+		},
+	}
+
+	got := ConfigFrom(cfg, "")
+	if got.PprofAddr != "127.0.0.1:6060" || got.Token != "secret" {
+		t.Errorf("ConfigFrom() = %+v", got)
+	}
+	if !got.Continuous.Enabled || got.Continuous.AppName != "smtp-proxy" || got.Continuous.Interval != time.Minute {
+		t.Errorf("ConfigFrom().Continuous = %+v", got.Continuous)
+	}
+	if got := ConfigFrom(cfg, "127.0.0.1:6061"); got.PprofAddr != "127.0.0.1:6061" {
+		t.Errorf("ConfigFrom() with flag PprofAddr = %q, want the flag's address", got.PprofAddr)
+	}
+}
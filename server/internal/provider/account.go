@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// maxSuppressionPages bounds how many pages of a suppression list are
+// fetched when counting entries, so a very large list cannot stall a poll.
+const maxSuppressionPages = 10
+
+// AccountStats is an account-level snapshot of sending quota and sender
+// reputation reported by an ESP. Nil fields were not reported.
+type AccountStats struct {
+	// Quota is the number of messages allowed in the ESP's quota period
+	// (rolling 24 hours for SES, the plan period for SendGrid).
+	Quota *int64
+	// QuotaUsed is the number of messages already sent in that period.
+	QuotaUsed *int64
+	// BounceRate and ComplaintRate are fractions (0.02 == 2%) over the
+	// most recent day.
+	BounceRate    *float64
+	ComplaintRate *float64
+	// SuppressionCount is the number of addresses on the suppression list.
+	SuppressionCount *int64
+	// Reputation is the ESP's sender reputation score from 0 to 100.
+	Reputation *float64
+	// EnforcementStatus is the ESP's standing of the account, such as the
+	// SES statuses HEALTHY, PROBATION and SHUTDOWN. Empty when not
+	// reported.
+	EnforcementStatus string
+}
+
+// QuotaExhausted reports whether the account has no sending quota left.
+// It is false when the ESP does not report a quota.
+func (s *AccountStats) QuotaExhausted() bool {
+	return s.Quota != nil && s.QuotaUsed != nil && *s.QuotaUsed >= *s.Quota
+}
+
+// AccountStatsReporter is implemented by providers that can report
+// account-level quota and reputation.
+type AccountStatsReporter interface {
+	AccountStats(ctx context.Context) (*AccountStats, error)
+}
+
+// getJSON performs a GET request and decodes a 200 response into out.
+func getJSON(client HTTPClient, name, url string, headers map[string]string, out interface{}) error {
+	resp, err := client.Do(&HTTPRequest{Method: "GET", URL: url, Headers: headers})
+	if err != nil {
+		return fmt.Errorf("%s: request %s: %w", name, url, err)
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("%s: %s returned status %d", name, url, resp.StatusCode)
+	}
+	if err := json.Unmarshal(resp.Body, out); err != nil {
+		return fmt.Errorf("%s: decode %s: %w", name, url, err)
+	}
+	return nil
+}
+
+func ratio(num, den int64) *float64 {
+	if den <= 0 {
+		return nil
+	}
+	r := float64(num) / float64(den)
+	return &r
+}
+
+// AccountStats reports the SES 24-hour send quota, the account's
+// enforcement status and the size of the account-level suppression list.
+// SES does not expose bounce and complaint rates through the v2 API, so
+// those fields are left nil.
+func (s *SES) AccountStats(ctx context.Context) (*AccountStats, error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	var account struct {
+		SendQuota struct {
+			Max24HourSend   float64 `json:"Max24HourSend"`
+			SentLast24Hours float64 `json:"SentLast24Hours"`
+		} `json:"SendQuota"`
+		EnforcementStatus string `json:"EnforcementStatus"`
+	}
+	if err := getJSON(s.client, "ses", s.endpoint+"/v2/email/account", headers, &account); err != nil {
+		return nil, err
+	}
+	quota := int64(account.SendQuota.Max24HourSend)
+	used := int64(account.SendQuota.SentLast24Hours)
+	stats := &AccountStats{Quota: &quota, QuotaUsed: &used, EnforcementStatus: account.EnforcementStatus}
+
+	var count int64
+	next := ""
+	for page := 0; page < maxSuppressionPages; page++ {
+		reqURL := s.endpoint + "/v2/email/suppression/addresses?PageSize=1000"
+		if next != "" {
+			reqURL += "&NextToken=" + url.QueryEscape(next)
+		}
+		var list struct {
+			SuppressedDestinationSummaries []json.RawMessage `json:"SuppressedDestinationSummaries"`
+			NextToken                      string            `json:"NextToken"`
+		}
+		if err := getJSON(s.client, "ses", reqURL, headers, &list); err != nil {
+			return nil, err
+		}
+		count += int64(len(list.SuppressedDestinationSummaries))
+		if list.NextToken == "" {
+			break
+		}
+		next = list.NextToken
+	}
+	stats.SuppressionCount = &count
+
+	return stats, nil
+}
+
+// AccountStats reports SendGrid plan credits, the account's reputation,
+// yesterday's bounce and spam report rates, and the size of the bounce
+// suppression list.
+func (s *SendGrid) AccountStats(ctx context.Context) (*AccountStats, error) {
+	headers := map[string]string{"Authorization": "Bearer " + s.apiKey}
+
+	var credits struct {
+		Total int64 `json:"total"`
+		Used  int64 `json:"used"`
+	}
+	if err := getJSON(s.client, "sendgrid", s.endpoint+"/v3/user/credits", headers, &credits); err != nil {
+		return nil, err
+	}
+	stats := &AccountStats{Quota: &credits.Total, QuotaUsed: &credits.Used}
+
+	var account struct {
+		Reputation float64 `json:"reputation"`
+	}
+	if err := getJSON(s.client, "sendgrid", s.endpoint+"/v3/user/account", headers, &account); err != nil {
+		return nil, err
+	}
+	stats.Reputation = &account.Reputation
+
+	since := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	var days []struct {
+		Stats []struct {
+			Metrics struct {
+				Requests    int64 `json:"requests"`
+				Delivered   int64 `json:"delivered"`
+				Bounces     int64 `json:"bounces"`
+				SpamReports int64 `json:"spam_reports"`
+			} `json:"metrics"`
+		} `json:"stats"`
+	}
+	if err := getJSON(s.client, "sendgrid", s.endpoint+"/v3/stats?start_date="+since, headers, &days); err != nil {
+		return nil, err
+	}
+	var requests, delivered, bounces, spam int64
+	for _, d := range days {
+		for _, st := range d.Stats {
+			requests += st.Metrics.Requests
+			delivered += st.Metrics.Delivered
+			bounces += st.Metrics.Bounces
+			spam += st.Metrics.SpamReports
+		}
+	}
+	stats.BounceRate = ratio(bounces, requests)
+	stats.ComplaintRate = ratio(spam, delivered)
+
+	var suppressed []json.RawMessage
+	if err := getJSON(s.client, "sendgrid", s.endpoint+"/v3/suppression/bounces", headers, &suppressed); err != nil {
+		return nil, err
+	}
+	count := int64(len(suppressed))
+	stats.SuppressionCount = &count
+
+	return stats, nil
+}
+
+// AccountStats reports the domain's bounce and complaint rates over the
+// last day and the size of its bounce list. Mailgun has no daily send
+// quota, so Quota and QuotaUsed are left nil.
+func (m *Mailgun) AccountStats(ctx context.Context) (*AccountStats, error) {
+	headers := map[string]string{"Authorization": "Basic " + basicAuth("api", m.apiKey)}
+
+	var totals struct {
+		Stats []struct {
+			Accepted   struct{ Total int64 } `json:"accepted"`
+			Delivered  struct{ Total int64 } `json:"delivered"`
+			Complained struct{ Total int64 } `json:"complained"`
+			Failed     struct {
+				Permanent struct{ Total int64 } `json:"permanent"`
+			} `json:"failed"`
+		} `json:"stats"`
+	}
+	url := fmt.Sprintf("%s/v3/%s/stats/total?event=accepted&event=delivered&event=failed&event=complained&duration=1d", m.endpoint, m.domain)
+	if err := getJSON(m.client, "mailgun", url, headers, &totals); err != nil {
+		return nil, err
+	}
+	var accepted, delivered, bounced, complained int64
+	for _, st := range totals.Stats {
+		accepted += st.Accepted.Total
+		delivered += st.Delivered.Total
+		bounced += st.Failed.Permanent.Total
+		complained += st.Complained.Total
+	}
+	stats := &AccountStats{
+		BounceRate:    ratio(bounced, accepted),
+		ComplaintRate: ratio(complained, delivered),
+	}
+
+	var count int64
+	next := fmt.Sprintf("%s/v3/%s/bounces?limit=1000", m.endpoint, m.domain)
+	for page := 0; page < maxSuppressionPages && next != ""; page++ {
+		var list struct {
+			Items  []json.RawMessage `json:"items"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := getJSON(m.client, "mailgun", next, headers, &list); err != nil {
+			return nil, err
+		}
+		count += int64(len(list.Items))
+		if len(list.Items) == 0 {
+			break
+		}
+		next = list.Paging.Next
+	}
+	stats.SuppressionCount = &count
+
+	return stats, nil
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// routeClient returns a canned response for the first matching URL fragment.
+func routeClient(t *testing.T, routes map[string]string) *mockHTTPClient2 {
+	t.Helper()
+	return &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		for frag, body := range routes {
+			if strings.Contains(req.URL, frag) {
+				return &HTTPResponse{StatusCode: 200, Body: []byte(body)}, nil
+			}
+		}
+		t.Errorf("unexpected request to %s", req.URL)
+		return &HTTPResponse{StatusCode: 404}, nil
+	}}
+}
+
+func TestAccountStats_QuotaExhausted(t *testing.T) {
+	q, used, over := int64(100), int64(40), int64(100)
+	if (&AccountStats{Quota: &q, QuotaUsed: &used}).QuotaExhausted() {
+		t.Error("40/100 should not be exhausted")
+	}
+	if !(&AccountStats{Quota: &q, QuotaUsed: &over}).QuotaExhausted() {
+		t.Error("100/100 should be exhausted")
+	}
+	if (&AccountStats{}).QuotaExhausted() {
+		t.Error("unknown quota should not be exhausted")
+	}
+}
+
+func TestSES_AccountStats(t *testing.T) {
+	client := routeClient(t, map[string]string{
+		"/v2/email/account":               `{"SendQuota":{"Max24HourSend":50000,"SentLast24Hours":1200,"MaxSendRate":14},"EnforcementStatus":"PROBATION"}`,
+		"/v2/email/suppression/addresses": `{"SuppressedDestinationSummaries":[{"EmailAddress":"a@x.com"},{"EmailAddress":"b@x.com"}]}`,
+	})
+	s := NewSES(ProviderConfig{Type: "ses", Region: "us-east-1"}, client)
+
+	stats, err := s.AccountStats(context.Background())
+	if err != nil {
+		t.Fatalf("AccountStats() error = %v", err)
+	}
+	if *stats.Quota != 50000 || *stats.QuotaUsed != 1200 {
+		t.Errorf("quota = %d/%d, want 1200/50000", *stats.QuotaUsed, *stats.Quota)
+	}
+	if *stats.SuppressionCount != 2 {
+		t.Errorf("SuppressionCount = %d, want 2", *stats.SuppressionCount)
+	}
+	if stats.BounceRate != nil {
+		t.Error("SES bounce rate should not be reported")
+	}
+	if stats.EnforcementStatus != "PROBATION" {
+		t.Errorf("EnforcementStatus = %q, want PROBATION", stats.EnforcementStatus)
+	}
+}
+
+func TestSES_AccountStats_EscapesNextToken(t *testing.T) {
+	var urls []string
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		urls = append(urls, req.URL)
+		switch {
+		case strings.Contains(req.URL, "/v2/email/account"):
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`{"SendQuota":{"Max24HourSend":100}}`)}, nil
+		case strings.Contains(req.URL, "NextToken="):
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`{"SuppressedDestinationSummaries":[{}]}`)}, nil
+		default:
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`{"SuppressedDestinationSummaries":[{}],"NextToken":"a+b/c=&d"}`)}, nil
+		}
+	}}
+	s := NewSES(ProviderConfig{Type: "ses", Region: "us-east-1"}, client)
+
+	stats, err := s.AccountStats(context.Background())
+	if err != nil {
+		t.Fatalf("AccountStats() error = %v", err)
+	}
+	if *stats.SuppressionCount != 2 {
+		t.Errorf("SuppressionCount = %d, want 2", *stats.SuppressionCount)
+	}
+	if last := urls[len(urls)-1]; !strings.HasSuffix(last, "&NextToken=a%2Bb%2Fc%3D%26d") {
+		t.Errorf("second page URL = %s, want an escaped NextToken", last)
+	}
+}
+
+func TestSendGrid_AccountStats(t *testing.T) {
+	client := routeClient(t, map[string]string{
+		"/v3/user/credits":        `{"remain":0,"total":40000,"used":40000}`,
+		"/v3/user/account":        `{"type":"paid","reputation":97.5}`,
+		"/v3/stats":               `[{"date":"2026-01-01","stats":[{"metrics":{"requests":1000,"delivered":900,"bounces":50,"spam_reports":9}}]}]`,
+		"/v3/suppression/bounces": `[{"email":"a@x.com"},{"email":"b@x.com"},{"email":"c@x.com"}]`,
+	})
+	sg := NewSendGrid(ProviderConfig{Type: "sendgrid", APIKey: "k"}, client)
+
+	stats, err := sg.AccountStats(context.Background())
+	if err != nil {
+		t.Fatalf("AccountStats() error = %v", err)
+	}
+	if !stats.QuotaExhausted() {
+		t.Error("expected quota to be exhausted")
+	}
+	if *stats.BounceRate != 0.05 {
+		t.Errorf("BounceRate = %v, want 0.05", *stats.BounceRate)
+	}
+	if *stats.ComplaintRate != 0.01 {
+		t.Errorf("ComplaintRate = %v, want 0.01", *stats.ComplaintRate)
+	}
+	if stats.Reputation == nil || *stats.Reputation != 97.5 {
+		t.Errorf("Reputation = %v, want 97.5", stats.Reputation)
+	}
+	if *stats.SuppressionCount != 3 {
+		t.Errorf("SuppressionCount = %d, want 3", *stats.SuppressionCount)
+	}
+}
+
+func TestMailgun_AccountStats(t *testing.T) {
+	client := routeClient(t, map[string]string{
+		"/stats/total": `{"stats":[{"accepted":{"total":200},"delivered":{"total":180},"failed":{"permanent":{"total":10}},"complained":{"total":2}}]}`,
+		"/bounces":     `{"items":[{"address":"a@x.com"}],"paging":{"next":""}}`,
+	})
+	mg := NewMailgun(ProviderConfig{Type: "mailgun", APIKey: "k", Domain: "mg.example.com"}, client)
+
+	stats, err := mg.AccountStats(context.Background())
+	if err != nil {
+		t.Fatalf("AccountStats() error = %v", err)
+	}
+	if stats.Quota != nil {
+		t.Error("Mailgun quota should not be reported")
+	}
+	if *stats.BounceRate != 0.05 {
+		t.Errorf("BounceRate = %v, want 0.05", *stats.BounceRate)
+	}
+	if *stats.SuppressionCount != 1 {
+		t.Errorf("SuppressionCount = %d, want 1", *stats.SuppressionCount)
+	}
+}
+
+func TestAccountStats_ErrorStatus(t *testing.T) {
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{StatusCode: 403}, nil
+	}}
+	sg := NewSendGrid(ProviderConfig{Type: "sendgrid", APIKey: "k"}, client)
+
+	if _, err := sg.AccountStats(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("AccountStats() error = %v, want status 403 error", err)
+	}
+}
@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+)
+
+const (
+	brevoDefaultEndpoint = "https://api.brevo.com"
+	brevoSendPath        = "/v3/smtp/email"
+)
+
+// Brevo implements the Provider interface for the Brevo (formerly
+// Sendinblue) transactional email API.
+type Brevo struct {
+	apiKey   string
+	endpoint string
+	client   HTTPClient
+}
+
+// NewBrevo creates a Brevo provider from the given configuration.
+func NewBrevo(cfg ProviderConfig, client HTTPClient) *Brevo {
+	return &Brevo{
+		apiKey:   cfg.APIKey,
+		endpoint: apiEndpoint(cfg, nil, brevoDefaultEndpoint),
+		client:   client,
+	}
+}
+
+func (b *Brevo) GetName() string { return "brevo" }
+
+// Send delivers a message via the Brevo smtp/email API.
+func (b *Brevo) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
+	body, err := json.Marshal(b.buildPayload(msg))
+	if err != nil {
+		return nil, fmt.Errorf("brevo: marshal request: %w", err)
+	}
+
+	resp, err := b.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    b.endpoint + brevoSendPath,
+		Headers: map[string]string{
+			"api-key":      b.apiKey,
+			"Content-Type": "application/json",
+			"Accept":       "application/json",
+		},
+		Body:    body,
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("brevo: send request: %w", err)
+	}
+
+	// Brevo returns 201 Created with the message ID.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var bvResp brevoResponse
+		_ = json.Unmarshal(resp.Body, &bvResp)
+		return &DeliveryResult{
+			ProviderMessageID: bvResp.MessageID,
+			Status:            StatusSent,
+			Timestamp:         time.Now(),
+			Metadata: map[string]string{
+				"status_code": fmt.Sprintf("%d", resp.StatusCode),
+			},
+		}, nil
+	}
+
+	return nil, ClassifyHTTPError("brevo", resp.StatusCode, string(resp.Body))
+}
+
+// HealthCheck verifies Brevo API connectivity by requesting account info.
+func (b *Brevo) HealthCheck(ctx context.Context) error {
+	resp, err := b.client.Do(&HTTPRequest{
+		Method: "GET",
+		URL:    b.endpoint + "/v3/account",
+		Headers: map[string]string{
+			"api-key": b.apiKey,
+			"Accept":  "application/json",
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("brevo: health check request: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return healthCheckError("brevo", resp.StatusCode, resp.Body)
+	}
+	return nil
+}
+
+// brevoPayload matches the Brevo sendTransacEmail JSON schema.
+type brevoPayload struct {
+	Sender      brevoContact      `json:"sender"`
+	To          []brevoContact    `json:"to"`
+	Bcc         []brevoContact    `json:"bcc,omitempty"`
+	ReplyTo     *brevoContact     `json:"replyTo,omitempty"`
+	Subject     string            `json:"subject"`
+	HTMLContent string            `json:"htmlContent,omitempty"`
+	TextContent string            `json:"textContent,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachment  []brevoAttachment `json:"attachment,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+}
+
+type brevoContact struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type brevoAttachment struct {
+	Content string `json:"content"` // base64 encoded
+	Name    string `json:"name"`
+}
+
+type brevoResponse struct {
+	MessageID string `json:"messageId"`
+}
+
+// buildPayload builds the Brevo request for msg. Brevo has no inline
+// attachments, so inline parts are sent as regular attachments.
+func (b *Brevo) buildPayload(msg *Message) brevoPayload {
+	payload := brevoPayload{
+		Sender:      brevoAddress(msg.From),
+		Subject:     msg.Subject,
+		HTMLContent: msg.HTMLBody,
+		TextContent: msg.TextBody,
+		Headers:     msg.headersExcept(apiReservedHeaders...),
+		Tags:        msg.Tags,
+	}
+	if payload.HTMLContent == "" && payload.TextContent == "" {
+		payload.TextContent = string(msg.Body)
+	}
+	for _, addr := range msg.To {
+		payload.To = append(payload.To, brevoContact{Email: addr})
+	}
+	for _, addr := range msg.Bcc {
+		payload.Bcc = append(payload.Bcc, brevoContact{Email: addr})
+	}
+	// Brevo takes a single reply-to address.
+	if replyTo := msg.ReplyTo(); len(replyTo) > 0 {
+		payload.ReplyTo = &brevoContact{Email: replyTo[0]}
+	}
+	for _, att := range msg.Attachments {
+		payload.Attachment = append(payload.Attachment, brevoAttachment{
+			Content: base64.StdEncoding.EncodeToString(att.Content),
+			Name:    att.Filename,
+		})
+	}
+	return payload
+}
+
+// brevoAddress splits a From address into Brevo's email and name.
+func brevoAddress(from string) brevoContact {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return brevoContact{Email: addr.Address, Name: addr.Name}
+	}
+	return brevoContact{Email: from}
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBrevo_buildPayload(t *testing.T) {
+	b := &Brevo{}
+	msg := &Message{
+		From:    "Shop <shop@example.com>",
+		To:      []string{"a@example.com"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Test",
+		Body:    []byte("plain"),
+		Headers: map[string]string{"Reply-To": "Support <support@example.com>, other@example.com", "X-Campaign": "spring"},
+		Tags:    []string{"welcome"},
+		Attachments: []Attachment{
+			{Filename: "logo.png", ContentType: "image/png", Content: []byte("png"), ContentID: "logo", IsInline: true},
+		},
+	}
+
+	payload := b.buildPayload(msg)
+
+	if payload.Sender != (brevoContact{Email: "shop@example.com", Name: "Shop"}) {
+		t.Errorf("sender = %+v", payload.Sender)
+	}
+	if payload.TextContent != "plain" || payload.HTMLContent != "" {
+		t.Errorf("content = %q, %q", payload.TextContent, payload.HTMLContent)
+	}
+	if len(payload.To) != 1 || len(payload.Bcc) != 1 || payload.Bcc[0].Email != "audit@example.com" {
+		t.Errorf("recipients = %+v, %+v", payload.To, payload.Bcc)
+	}
+	if payload.ReplyTo == nil || payload.ReplyTo.Email != "support@example.com" {
+		t.Errorf("replyTo = %+v, want the first Reply-To address", payload.ReplyTo)
+	}
+	if len(payload.Headers) != 1 || payload.Headers["X-Campaign"] != "spring" {
+		t.Errorf("headers = %v", payload.Headers)
+	}
+	if len(payload.Tags) != 1 || len(payload.Attachment) != 1 || payload.Attachment[0].Name != "logo.png" {
+		t.Errorf("tags = %v, attachments = %+v", payload.Tags, payload.Attachment)
+	}
+}
+
+func TestBrevo_Send(t *testing.T) {
+	var got *HTTPRequest
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		got = req
+		return &HTTPResponse{StatusCode: 201, Body: []byte(`{"messageId":"<202410171200.123@smtp-relay.mailin.fr>"}`)}, nil
+	}}
+	b := NewBrevo(ProviderConfig{APIKey: "xkeysib-1"}, client)
+
+	result, err := b.Send(context.Background(), &Message{From: "a@example.com", To: []string{"b@example.com"}, TextBody: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProviderMessageID != "<202410171200.123@smtp-relay.mailin.fr>" {
+		t.Errorf("ProviderMessageID = %q", result.ProviderMessageID)
+	}
+	if got.URL != brevoDefaultEndpoint+brevoSendPath || got.Headers["api-key"] != "xkeysib-1" {
+		t.Errorf("request = %s %v", got.URL, got.Headers)
+	}
+
+	client.doFn = func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{StatusCode: 401, Body: []byte(`{"code":"unauthorized","message":"Key not found"}`)}, nil
+	}
+	if _, err := b.Send(context.Background(), &Message{From: "a@example.com", To: []string{"b@example.com"}, TextBody: "hi"}); !IsPermanent(err) {
+		t.Errorf("expected a permanent error for an invalid key, got %v", err)
+	}
+}
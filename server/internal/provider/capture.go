@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxCaptureLimit is the largest number of deliveries a provider keeps
+	// captures of.
+	MaxCaptureLimit = 100
+
+	// maxCaptureBody is the number of bytes of each request and response
+	// body kept in a capture.
+	maxCaptureBody = 4096
+
+	redactedValue = "[redacted]"
+)
+
+// Exchange is a sanitized provider API request and its response, kept to
+// debug rejections by the ESP. Credentials in headers, query parameters
+// and bodies are redacted, and bodies are truncated.
+type Exchange struct {
+	At              time.Time         `json:"at"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	StatusCode      int               `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	// Error is set when no response was received.
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Captured is implemented by providers built by the resolver, which know
+// how many deliveries their esp_providers row asks to capture.
+type Captured interface {
+	CaptureLimit() int
+}
+
+// CaptureLimit returns the number of recent deliveries of p to keep
+// captures of, and 0 when capture is off.
+func CaptureLimit(p Provider) int {
+	if c, ok := p.(Captured); ok {
+		return c.CaptureLimit()
+	}
+	return 0
+}
+
+type captureKey struct{}
+
+type captureLog struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// WithCapture returns a context that records the provider requests made
+// with it, such as a token fetch followed by the send itself. Only clients
+// that honour HTTPRequest.Context, like DefaultHTTPClient, record them.
+func WithCapture(ctx context.Context) context.Context {
+	return context.WithValue(ctx, captureKey{}, &captureLog{})
+}
+
+// CapturedExchanges returns the exchanges recorded in a context returned
+// by WithCapture, in the order they were made.
+func CapturedExchanges(ctx context.Context) []Exchange {
+	l, ok := ctx.Value(captureKey{}).(*captureLog)
+	if !ok {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Exchange(nil), l.exchanges...)
+}
+
+// recordExchange adds a request and its response or error to the capture
+// of ctx, if any.
+func recordExchange(ctx context.Context, req *HTTPRequest, resp *HTTPResponse, err error, start time.Time) {
+	l, ok := ctx.Value(captureKey{}).(*captureLog)
+	if !ok {
+		return
+	}
+	ex := Exchange{
+		At:             start.UTC(),
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeaders(req.Headers),
+		RequestBody:    sanitizeBody(req.Body),
+		DurationMs:     time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		ex.Error = redactSecrets(err.Error())
+	}
+	if resp != nil {
+		ex.StatusCode = resp.StatusCode
+		ex.ResponseHeaders = redactHeaders(resp.Headers)
+		ex.ResponseBody = sanitizeBody(resp.Body)
+	}
+	l.mu.Lock()
+	l.exchanges = append(l.exchanges, ex)
+	l.mu.Unlock()
+}
+
+// sensitiveName reports whether a header, parameter or field name is
+// likely to hold a credential.
+func sensitiveName(name string) bool {
+	n := strings.ToLower(name)
+	for _, s := range []string{"auth", "token", "secret", "password", "key", "signature", "cookie", "credential"} {
+		if strings.Contains(n, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if sensitiveName(k) {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactURL removes userinfo and redacts sensitive query parameters.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if sensitiveName(k) {
+				q.Set(k, redactedValue)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+var (
+	// secretJSONField matches string fields of JSON objects with a
+	// sensitive name, e.g. "access_token": "...".
+	secretJSONField = regexp.MustCompile(`(?i)("[a-z0-9_-]*(?:auth|token|secret|password|key|signature|credential)[a-z0-9_-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// secretFormField matches sensitive fields of url-encoded forms, e.g.
+	// client_secret=...
+	secretFormField = regexp.MustCompile(`(?i)((?:^|&)[a-z0-9_.-]*(?:auth|token|secret|password|key|signature|credential)[a-z0-9_.-]*=)[^&]*`)
+)
+
+// redactSecrets redacts credentials in a JSON or url-encoded body.
+func redactSecrets(s string) string {
+	s = secretJSONField.ReplaceAllString(s, `${1}"`+redactedValue+`"`)
+	return secretFormField.ReplaceAllString(s, `${1}`+redactedValue)
+}
+
+// sanitizeBody redacts credentials in body and truncates it to
+// maxCaptureBody bytes. Binary bodies are summarized by their size.
+func sanitizeBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !utf8.Valid(body) {
+		return fmt.Sprintf("[binary body, %d bytes]", len(body))
+	}
+	s := redactSecrets(string(body))
+	if len(s) <= maxCaptureBody {
+		return s
+	}
+	cut := maxCaptureBody
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...[truncated]"
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestDefaultHTTPClient_Capture(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"invalid from address"}],"access_token":"tok"}`))
+	}))
+	defer srv.Close()
+
+	ctx := WithCapture(context.Background())
+	_, err := NewHTTPClient(0).Do(&HTTPRequest{
+		Method:  http.MethodPost,
+		URL:     srv.URL + "/v3/mail/send?api_key=secret&mode=test",
+		Headers: map[string]string{"Authorization": "Bearer SG.secret", "Content-Type": "application/json"},
+		Body:    []byte(`{"from":{"email":"a@example.com"},"password":"hunter2"}`),
+		Context: ctx,
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	exchanges := CapturedExchanges(ctx)
+	if len(exchanges) != 1 {
+		t.Fatalf("expected 1 exchange, got %d", len(exchanges))
+	}
+	ex := exchanges[0]
+	if ex.StatusCode != http.StatusBadRequest || ex.ResponseHeaders["X-Request-Id"] != "req-1" {
+		t.Errorf("unexpected response capture: %+v", ex)
+	}
+	if ex.RequestHeaders["Authorization"] != redactedValue || ex.RequestHeaders["Content-Type"] != "application/json" {
+		t.Errorf("unexpected request headers: %v", ex.RequestHeaders)
+	}
+	for _, s := range []string{ex.URL, ex.RequestBody, ex.ResponseBody} {
+		if strings.Contains(s, "secret") || strings.Contains(s, "hunter2") || strings.Contains(s, `"tok"`) {
+			t.Errorf("capture leaks a credential: %s", s)
+		}
+	}
+	if !strings.Contains(ex.URL, "mode=test") || !strings.Contains(ex.RequestBody, "a@example.com") || !strings.Contains(ex.ResponseBody, "invalid from address") {
+		t.Errorf("capture dropped too much: %+v", ex)
+	}
+}
+
+func TestDefaultHTTPClient_CaptureError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	ctx := WithCapture(context.Background())
+	if _, err := NewHTTPClient(0).Do(&HTTPRequest{Method: http.MethodGet, URL: srv.URL, Context: ctx}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if ex := CapturedExchanges(ctx); len(ex) != 1 || ex[0].Error == "" || ex[0].StatusCode != 0 {
+		t.Errorf("unexpected capture: %+v", ex)
+	}
+}
+
+func TestSanitizeBody(t *testing.T) {
+	long := strings.Repeat("é", maxCaptureBody)
+	got := sanitizeBody([]byte(long))
+	if !strings.HasSuffix(got, "...[truncated]") || len(got) > maxCaptureBody+len("...[truncated]") {
+		t.Errorf("unexpected truncation: %d bytes", len(got))
+	}
+	if !strings.HasPrefix(sanitizeBody([]byte{0xff, 0xfe, 0x00}), "[binary body") {
+		t.Error("expected a binary body summary")
+	}
+	if got := sanitizeBody([]byte("client_id=app&client_secret=s3cr3t&grant_type=client_credentials")); got != "client_id=app&client_secret=[redacted]&grant_type=client_credentials" {
+		t.Errorf("form body = %q", got)
+	}
+}
+
+func TestCaptureLimit(t *testing.T) {
+	tests := []struct {
+		config string
+		want   int
+	}{
+		{`{}`, 0},
+		{`{"debug_capture": 20}`, 20},
+		{`{"debug_capture": 5000}`, MaxCaptureLimit},
+	}
+	for _, tt := range tests {
+		esp := &storage.EspProvider{ID: uuid.New(), SmtpConfig: []byte(tt.config)}
+		if got := CaptureLimit(newIdentifiedProvider(NewStdout(ProviderConfig{}), esp)); got != tt.want {
+			t.Errorf("%s: CaptureLimit = %d, want %d", tt.config, got, tt.want)
+		}
+	}
+	if CaptureLimit(NewStdout(ProviderConfig{})) != 0 {
+		t.Error("expected no capture for a provider not built by the resolver")
+	}
+}
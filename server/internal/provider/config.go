@@ -2,34 +2,64 @@ package provider
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
 // ProviderConfig holds configuration for an ESP provider.
 type ProviderConfig struct {
-	// Type identifies the provider: "sendgrid", "ses", "mailgun", "msgraph", "stdout", "file".
+	// Type identifies the provider: "sendgrid", "ses", "mailgun", "msgraph", "gmail",
+	// "resend", "brevo", "zeptomail", "ews", "stdout", "file", or a custom
+	// type registered with RegisterType.
 	Type string
 
-	// APIKey is the authentication credential for the provider.
+	// APIKey is the authentication credential for the provider. For Gmail
+	// it is a service account JSON key or a user's OAuth2 refresh token.
 	APIKey string
 
-	// Endpoint overrides the default API URL (useful for testing).
+	// Endpoint overrides the API base URL of the provider type and region,
+	// e.g. for a self-hosted compatible API or in tests. For EWS it is the
+	// Exchange server's EWS URL.
 	Endpoint string
 
 	// Timeout is the maximum duration for API calls.
 	Timeout time.Duration
 
-	// Region is used for AWS SES to determine the API endpoint.
+	// Region selects the API endpoint: the AWS region of SES, "us" or "eu"
+	// for Mailgun, "global" or "eu" for SendGrid, and the data center
+	// ("us", "eu", "in" or "au") of ZeptoMail.
 	Region string
 
 	// Domain is the Mailgun sending domain.
 	Domain string
 
+	// IPPool and Subuser select the SendGrid IP pool and subuser messages
+	// are sent from.
+	IPPool  string
+	Subuser string
+
+	// ConfigurationSet is the SES configuration set messages are sent with.
+	ConfigurationSet string
+
 	// MSGraph-specific fields.
 	TenantID     string // Azure AD tenant ID
 	ClientID     string // Azure AD application client ID
 	ClientSecret string // Azure AD application client secret
 	UserID       string // Microsoft 365 user ID or UPN for sendMail
+	// Gmail uses ClientID and ClientSecret, the OAuth2 client of a refresh
+	// token, and UserID, the Workspace user a service account sends as.
+
+	// EWS-specific fields. The password is APIKey.
+	Username string // DOMAIN\user or user principal name
+	Auth     string // "ntlm" (default) or "basic"
+
+	// ProxyURL routes this provider's API calls through an egress proxy
+	// (http, https or socks5), overriding the worker-wide proxy.
+	ProxyURL string
+
+	// Options holds the settings of a custom provider type registered by a
+	// plugin.
+	Options map[string]string
 }
 
 const defaultTimeout = 30 * time.Second
@@ -44,6 +74,19 @@ func (c *ProviderConfig) Validate() error {
 		c.Timeout = defaultTimeout
 	}
 
+	if c.ProxyURL != "" {
+		if _, err := ParseProxyURL(c.ProxyURL); err != nil {
+			return fmt.Errorf("proxy_url: %w", err)
+		}
+	}
+
+	if err := ValidateRegion(c.Type, c.Region); err != nil {
+		return err
+	}
+	if err := ValidateEndpoint(c.Type, c.Endpoint); err != nil {
+		return fmt.Errorf("%s: %w", c.Type, err)
+	}
+
 	switch c.Type {
 	case "sendgrid":
 		if c.APIKey == "" {
@@ -76,12 +119,43 @@ func (c *ProviderConfig) Validate() error {
 		if c.UserID == "" {
 			return errors.New("msgraph: user_id is required")
 		}
+	case "gmail":
+		if c.APIKey == "" {
+			return errors.New("gmail: api_key (service account key or refresh token) is required")
+		}
+		if isServiceAccountKey(c.APIKey) {
+			if _, err := parseServiceAccountKey(c.APIKey); err != nil {
+				return fmt.Errorf("gmail: %w", err)
+			}
+		} else if c.ClientID == "" || c.ClientSecret == "" {
+			return errors.New("gmail: client_id and client_secret are required with a refresh token")
+		}
+	case "resend", "brevo", "zeptomail":
+		if c.APIKey == "" {
+			return errors.New(c.Type + ": api_key is required")
+		}
+	case "ews":
+		if c.Endpoint == "" {
+			return errors.New("ews: endpoint (EWS URL) is required")
+		}
+		if c.Username == "" {
+			return errors.New("ews: username is required")
+		}
+		if c.APIKey == "" {
+			return errors.New("ews: api_key (password) is required")
+		}
+		if err := ValidateEWSAuth(c.Auth); err != nil {
+			return err
+		}
 	case "stdout":
 		// No configuration required.
 	case "file":
 		// Endpoint is used as output directory; optional (defaults to ./mail_output).
 	default:
-		return errors.New("unknown provider type: " + c.Type)
+		// Custom types validate their own settings in their factory.
+		if _, ok := lookupFactory(c.Type); !ok {
+			return errors.New("unknown provider type: " + c.Type)
+		}
 	}
 
 	return nil
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Connection describes the network connection that carried a provider
+// request. It is recorded per delivery for outbound audit trails.
+type Connection struct {
+	// EgressIP is the local address the request left from.
+	EgressIP string
+	// RemoteAddr is the address the connection was made to: the provider,
+	// or the egress proxy when one is configured.
+	RemoteAddr string
+	// Endpoint is the scheme, host and path of the request URL.
+	Endpoint string
+	// TLSVersion and TLSCipher are empty for plaintext connections.
+	TLSVersion string
+	TLSCipher  string
+	// Reused reports whether the connection came from the idle pool, in
+	// which case Connect and TLSHandshake are zero.
+	Reused       bool
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// FirstByte is the time from writing the request to the first byte of
+	// the response.
+	FirstByte time.Duration
+}
+
+type connectionAuditKey struct{}
+
+// connectionAudit holds the connection of the last request made with a
+// context returned by WithConnectionAudit.
+type connectionAudit struct {
+	mu       sync.Mutex
+	conn     Connection
+	recorded bool
+}
+
+// WithConnectionAudit returns a context that records the connection of the
+// provider requests made with it. When a send makes several requests, such
+// as a token fetch followed by the send itself, the last one is kept.
+// Only clients that honour HTTPRequest.Context, like DefaultHTTPClient,
+// record connections.
+func WithConnectionAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connectionAuditKey{}, &connectionAudit{})
+}
+
+// ConnectionFromContext returns the connection recorded in a context
+// returned by WithConnectionAudit, and false if no request was recorded.
+func ConnectionFromContext(ctx context.Context) (Connection, bool) {
+	a, ok := ctx.Value(connectionAuditKey{}).(*connectionAudit)
+	if !ok {
+		return Connection{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.conn, a.recorded
+}
+
+// connTrace collects the httptrace events of a single request.
+type connTrace struct {
+	audit *connectionAudit
+
+	mu           sync.Mutex
+	conn         Connection
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	gotConn      bool
+}
+
+// newConnTrace returns a trace for a request to rawURL if ctx carries a
+// connection audit, and nil otherwise.
+func newConnTrace(ctx context.Context, rawURL string) *connTrace {
+	a, ok := ctx.Value(connectionAuditKey{}).(*connectionAudit)
+	if !ok {
+		return nil
+	}
+	t := &connTrace{audit: a}
+	if u, err := url.Parse(rawURL); err == nil {
+		t.conn.Endpoint = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	}
+	return t
+}
+
+func (t *connTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil && !t.connectStart.IsZero() {
+				t.conn.Connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil && !t.tlsStart.IsZero() {
+				t.conn.TLSHandshake = time.Since(t.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = true
+			t.conn.Reused = info.Reused
+			if host, _, err := net.SplitHostPort(info.Conn.LocalAddr().String()); err == nil {
+				t.conn.EgressIP = host
+			}
+			t.conn.RemoteAddr = info.Conn.RemoteAddr().String()
+			if tc, ok := info.Conn.(*tls.Conn); ok {
+				state := tc.ConnectionState()
+				t.conn.TLSVersion = tls.VersionName(state.Version)
+				t.conn.TLSCipher = tls.CipherSuiteName(state.CipherSuite)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.wroteRequest.IsZero() {
+				t.conn.FirstByte = time.Since(t.wroteRequest)
+			}
+		},
+	}
+}
+
+// finish stores the traced connection in the audit. Requests that never
+// got a connection, such as those failing DNS resolution, are recorded
+// with their endpoint only.
+func (t *connTrace) finish() {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+
+	t.audit.mu.Lock()
+	defer t.audit.mu.Unlock()
+	t.audit.conn = conn
+	t.audit.recorded = true
+}
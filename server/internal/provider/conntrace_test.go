@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDefaultHTTPClient_RecordsConnection(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	client := &DefaultHTTPClient{client: srv.Client()}
+
+	ctx := WithConnectionAudit(context.Background())
+	if _, err := client.Do(&HTTPRequest{Method: http.MethodPost, URL: srv.URL + "/v3/mail/send?key=secret", Context: ctx}); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	conn, ok := ConnectionFromContext(ctx)
+	if !ok {
+		t.Fatal("expected a recorded connection")
+	}
+	if conn.EgressIP != "127.0.0.1" {
+		t.Errorf("expected egress IP 127.0.0.1, got %q", conn.EgressIP)
+	}
+	if conn.RemoteAddr != strings.TrimPrefix(srv.URL, "https://") {
+		t.Errorf("expected remote addr %s, got %q", srv.URL, conn.RemoteAddr)
+	}
+	if conn.Endpoint != srv.URL+"/v3/mail/send" {
+		t.Errorf("expected endpoint without query, got %q", conn.Endpoint)
+	}
+	if !strings.HasPrefix(conn.TLSVersion, "TLS 1.") || conn.TLSCipher == "" {
+		t.Errorf("expected TLS details, got %q %q", conn.TLSVersion, conn.TLSCipher)
+	}
+	if conn.Reused || conn.TLSHandshake <= 0 {
+		t.Errorf("expected a new connection with a handshake time, got %+v", conn)
+	}
+
+	// A second request reuses the pooled connection and keeps its TLS
+	// details.
+	ctx = WithConnectionAudit(context.Background())
+	if _, err := client.Do(&HTTPRequest{Method: http.MethodPost, URL: srv.URL + "/v3/mail/send", Context: ctx}); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	conn, _ = ConnectionFromContext(ctx)
+	if !conn.Reused || conn.Connect != 0 || conn.TLSVersion == "" {
+		t.Errorf("expected a reused TLS connection, got %+v", conn)
+	}
+}
+
+func TestDefaultHTTPClient_RecordsConnection_NoAudit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	if _, err := NewHTTPClient(0).Do(&HTTPRequest{Method: http.MethodGet, URL: srv.URL, Context: ctx}); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if _, ok := ConnectionFromContext(ctx); ok {
+		t.Error("expected no connection without an audit context")
+	}
+}
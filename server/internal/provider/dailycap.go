@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// ErrProvidersCapped is matched by the error Resolve returns when every
+// enabled provider of a group has reached its daily cap.
+var ErrProvidersCapped = errors.New("all enabled providers have reached their daily cap")
+
+// CappedError is returned by Resolve when every enabled provider of a
+// group has reached its daily cap. Until is when the first cap resets.
+type CappedError struct {
+	Until time.Time
+}
+
+func (e *CappedError) Error() string {
+	return fmt.Sprintf("%v until %s", ErrProvidersCapped, e.Until.UTC().Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrProvidersCapped.
+func (e *CappedError) Is(target error) bool {
+	return target == ErrProvidersCapped
+}
+
+// DailyCap is a hard limit on the deliveries a provider makes per day,
+// from smtp_config.daily_cap. Days start at midnight in Location, which is
+// UTC unless smtp_config.daily_cap_timezone names the group's timezone.
+type DailyCap struct {
+	Limit    int
+	Location *time.Location
+}
+
+// Enabled reports whether the cap limits anything.
+func (c DailyCap) Enabled() bool {
+	return c.Limit > 0
+}
+
+// Day returns the date of t in the cap's timezone, as stored in
+// provider_daily_sends.day.
+func (c DailyCap) Day(t time.Time) pgtype.Date {
+	y, m, d := t.In(c.location()).Date()
+	return pgtype.Date{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+// Reset returns when the day containing t ends and the cap resets.
+func (c DailyCap) Reset(t time.Time) time.Time {
+	y, m, d := t.In(c.location()).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, c.location())
+}
+
+func (c DailyCap) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// ValidateDailyCap checks the daily_cap and daily_cap_timezone of a
+// provider's smtp_config.
+func ValidateDailyCap(limit int, timezone string) error {
+	if limit < 0 {
+		return errors.New("daily_cap must not be negative")
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("daily_cap_timezone: unknown timezone %q", timezone)
+		}
+	}
+	return nil
+}
+
+// parseDailyCap returns the daily cap of extra. An unknown timezone falls
+// back to UTC.
+func parseDailyCap(extra smtpConfigExtra) DailyCap {
+	c := DailyCap{Limit: max(extra.DailyCap, 0), Location: time.UTC}
+	if extra.DailyCapTimezone != "" {
+		if loc, err := time.LoadLocation(extra.DailyCapTimezone); err == nil {
+			c.Location = loc
+		}
+	}
+	return c
+}
+
+// StorageDailyCap returns the daily cap of an esp_providers row.
+func StorageDailyCap(esp *storage.EspProvider) DailyCap {
+	return parseDailyCap(parseSMTPConfigExtra(esp))
+}
+
+// DailyCapped is implemented by providers built by the resolver, which
+// know the daily cap of their esp_providers row.
+type DailyCapped interface {
+	DailyCap() DailyCap
+}
+
+// ProviderDailyCap returns the daily cap of p; it is disabled for
+// providers not built by the resolver.
+func ProviderDailyCap(p Provider) DailyCap {
+	if c, ok := p.(DailyCapped); ok {
+		return c.DailyCap()
+	}
+	return DailyCap{}
+}
+
+// DailySent returns the deliveries counted today against the cap of the
+// provider with the given ID.
+func DailySent(providerID uuid.UUID, c DailyCap, sends []storage.ProviderDailySend, now time.Time) int {
+	day := c.Day(now)
+	for _, s := range sends {
+		if s.ProviderID == providerID && s.Day.Time.Equal(day.Time) {
+			return int(s.Sent)
+		}
+	}
+	return 0
+}
+
+// DailySendsSince returns the earliest day whose provider_daily_sends rows
+// can be today's in some timezone, for ListProviderDailySendsByGroupID.
+func DailySendsSince(now time.Time) pgtype.Date {
+	y, m, d := now.UTC().AddDate(0, 0, -1).Date()
+	return pgtype.Date{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+// withoutCapped returns providers minus those that have reached their
+// daily cap, and when the first of those caps resets.
+func withoutCapped(providers []storage.EspProvider, sends []storage.ProviderDailySend, now time.Time) ([]storage.EspProvider, time.Time) {
+	var available []storage.EspProvider
+	var reset time.Time
+	for _, p := range providers {
+		c := StorageDailyCap(&p)
+		if p.Enabled && c.Enabled() && DailySent(p.ID, c, sends, now) >= c.Limit {
+			if r := c.Reset(now); reset.IsZero() || r.Before(reset) {
+				reset = r
+			}
+			continue
+		}
+		available = append(available, p)
+	}
+	return available, reset
+}
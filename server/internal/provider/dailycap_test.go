@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestDailyCap_DayAndReset(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := StorageDailyCap(&storage.EspProvider{SmtpConfig: []byte(`{"daily_cap":100,"daily_cap_timezone":"Asia/Seoul"}`)})
+	if c.Limit != 100 || c.Location.String() != "Asia/Seoul" {
+		t.Fatalf("StorageDailyCap() = %+v", c)
+	}
+
+	// 16:30 UTC on the 16th is already the 17th in Seoul.
+	now := time.Date(2026, 10, 16, 16, 30, 0, 0, time.UTC)
+	if got := c.Day(now).Time; !got.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Day() = %v, want 2026-10-17", got)
+	}
+	if got, want := c.Reset(now), time.Date(2026, 10, 18, 0, 0, 0, 0, seoul); !got.Equal(want) {
+		t.Errorf("Reset() = %v, want %v", got, want)
+	}
+	if got := (DailyCap{Limit: 1}).Reset(now); !got.Equal(time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("UTC Reset() = %v, want midnight UTC", got)
+	}
+}
+
+func TestWithoutCapped(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	capped := storage.EspProvider{ID: uuid.New(), Enabled: true, SmtpConfig: []byte(`{"daily_cap":10}`)}
+	under := storage.EspProvider{ID: uuid.New(), Enabled: true, SmtpConfig: []byte(`{"daily_cap":10}`)}
+	uncapped := storage.EspProvider{ID: uuid.New(), Enabled: true}
+	today := DailyCap{Limit: 10}.Day(now)
+	yesterday := DailyCap{Limit: 10}.Day(now.AddDate(0, 0, -1))
+	sends := []storage.ProviderDailySend{
+		{ProviderID: capped.ID, Day: today, Sent: 10},
+		{ProviderID: under.ID, Day: yesterday, Sent: 10},
+		{ProviderID: under.ID, Day: today, Sent: 9},
+	}
+
+	available, reset := withoutCapped([]storage.EspProvider{capped, under, uncapped}, sends, now)
+	if len(available) != 2 || available[0].ID != under.ID || available[1].ID != uncapped.ID {
+		t.Errorf("withoutCapped() kept %v, want the providers under their cap", available)
+	}
+	if want := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC); !reset.Equal(want) {
+		t.Errorf("reset = %v, want %v", reset, want)
+	}
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxDomainLimits limits the domain_limits entries of one provider.
+const MaxDomainLimits = 50
+
+// DomainLimit caps the deliveries a provider makes to a set of recipient
+// domains, such as every domain served by the same MX. PerMinute and
+// Concurrency are per relay IP, so they are multiplied by the number of the
+// provider's smtp_config.relay_ips; 0 leaves either unlimited.
+type DomainLimit struct {
+	Domains     []string `json:"domains"`
+	PerMinute   int      `json:"per_minute,omitempty"`
+	Concurrency int      `json:"concurrency,omitempty"`
+}
+
+// Key names the limit's buckets: its first domain.
+func (l DomainLimit) Key() string {
+	if len(l.Domains) == 0 {
+		return ""
+	}
+	return strings.ToLower(l.Domains[0])
+}
+
+// matches reports whether domain is one of the limit's domains or a
+// subdomain of one.
+func (l DomainLimit) matches(domain string) bool {
+	for _, d := range l.Domains {
+		d = strings.ToLower(d)
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDomainLimits checks the domain_limits of a provider's
+// smtp_config.
+func ValidateDomainLimits(limits []DomainLimit) error {
+	if len(limits) > MaxDomainLimits {
+		return fmt.Errorf("domain_limits: at most %d entries are allowed", MaxDomainLimits)
+	}
+	seen := make(map[string]bool)
+	for i, l := range limits {
+		if len(l.Domains) == 0 {
+			return fmt.Errorf("domain_limits %d: at least one domain is required", i+1)
+		}
+		for _, d := range l.Domains {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "" || strings.ContainsAny(d, "@ /") || !strings.Contains(d, ".") {
+				return fmt.Errorf("domain_limits %d: %q is not a domain", i+1, d)
+			}
+			if seen[d] {
+				return fmt.Errorf("domain_limits %d: %s is listed twice", i+1, d)
+			}
+			seen[d] = true
+		}
+		if l.PerMinute < 0 || l.Concurrency < 0 {
+			return fmt.Errorf("domain_limits %d: per_minute and concurrency must not be negative", i+1)
+		}
+		if l.PerMinute == 0 && l.Concurrency == 0 {
+			return fmt.Errorf("domain_limits %d: per_minute or concurrency is required", i+1)
+		}
+	}
+	return nil
+}
+
+// DomainLimited is implemented by providers built by the resolver, which
+// know the recipient domain limits of their esp_providers row.
+type DomainLimited interface {
+	DomainLimits() []DomainLimit
+}
+
+// RecipientDomainLimits returns the limits of p that apply to recipients,
+// at most once each, scaled to the provider's relay IPs.
+func RecipientDomainLimits(p Provider, recipients []string) []DomainLimit {
+	dl, ok := p.(DomainLimited)
+	if !ok {
+		return nil
+	}
+	limits := dl.DomainLimits()
+	if len(limits) == 0 {
+		return nil
+	}
+	var matched []DomainLimit
+	used := make([]bool, len(limits))
+	for _, rcpt := range recipients {
+		at := strings.LastIndexByte(rcpt, '@')
+		if at < 0 {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(rcpt[at+1:]), ">"))
+		for i, l := range limits {
+			if !used[i] && l.matches(domain) {
+				used[i] = true
+				matched = append(matched, l)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// scaleDomainLimits multiplies limits by the number of relay IPs the
+// provider sends from.
+func scaleDomainLimits(limits []DomainLimit, relayIPs int) []DomainLimit {
+	n := max(relayIPs, 1)
+	scaled := make([]DomainLimit, 0, len(limits))
+	for _, l := range limits {
+		if len(l.Domains) == 0 {
+			continue
+		}
+		l.PerMinute *= n
+		l.Concurrency *= n
+		scaled = append(scaled, l)
+	}
+	return scaled
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestRecipientDomainLimits(t *testing.T) {
+	esp := &storage.EspProvider{ID: uuid.New(), SmtpConfig: []byte(`{
+		"relay_ips": ["192.0.2.1", "192.0.2.2"],
+		"domain_limits": [
+			{"domains": ["gmail.com", "googlemail.com"], "per_minute": 10, "concurrency": 2},
+			{"domains": ["outlook.com"], "per_minute": 30}
+		]
+	}`)}
+	p := newIdentifiedProvider(NewStdout(ProviderConfig{}), esp)
+
+	got := RecipientDomainLimits(p, []string{"a@Gmail.com", "b@googlemail.com", "c@eu.outlook.com", "d@example.com"})
+	if len(got) != 2 {
+		t.Fatalf("RecipientDomainLimits = %+v, want gmail.com and outlook.com", got)
+	}
+	if got[0].Key() != "gmail.com" || got[0].PerMinute != 20 || got[0].Concurrency != 4 {
+		t.Errorf("gmail.com limit = %+v, want 20/min and 4 concurrent over two relay IPs", got[0])
+	}
+	if got[1].Key() != "outlook.com" || got[1].PerMinute != 60 || got[1].Concurrency != 0 {
+		t.Errorf("outlook.com limit = %+v, want 60/min", got[1])
+	}
+
+	if got := RecipientDomainLimits(p, []string{"someone@notgmail.com"}); len(got) != 0 {
+		t.Errorf("RecipientDomainLimits matched %+v for an unlisted domain", got)
+	}
+	if got := RecipientDomainLimits(NewStdout(ProviderConfig{}), []string{"a@gmail.com"}); got != nil {
+		t.Errorf("expected no limits for a provider not built by the resolver, got %+v", got)
+	}
+}
+
+func TestValidateDomainLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  []DomainLimit
+		wantErr bool
+	}{
+		{"valid", []DomainLimit{{Domains: []string{"gmail.com"}, PerMinute: 10}}, false},
+		{"no domains", []DomainLimit{{PerMinute: 10}}, true},
+		{"not a domain", []DomainLimit{{Domains: []string{"user@gmail.com"}, PerMinute: 10}}, true},
+		{"duplicate", []DomainLimit{{Domains: []string{"gmail.com"}, PerMinute: 10}, {Domains: []string{"GMAIL.com"}, Concurrency: 1}}, true},
+		{"negative", []DomainLimit{{Domains: []string{"gmail.com"}, PerMinute: -1, Concurrency: 2}}, true},
+		{"no limit", []DomainLimit{{Domains: []string{"gmail.com"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDomainLimits(tt.limits); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDomainLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
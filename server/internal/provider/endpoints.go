@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mailgunRegionEndpoints are the API base URLs of the Mailgun regions.
+// Accounts and domains live in one region and cannot be reached through
+// the other.
+var mailgunRegionEndpoints = map[string]string{
+	"us": mailgunDefaultEndpoint,
+	"eu": "https://api.eu.mailgun.net",
+}
+
+// sendgridRegionEndpoints are the API base URLs of the SendGrid regions.
+// EU subusers must send through the EU endpoint for data residency.
+var sendgridRegionEndpoints = map[string]string{
+	"global": sendgridDefaultEndpoint,
+	"eu":     "https://api.eu.sendgrid.com",
+}
+
+// zeptomailRegionEndpoints are the API base URLs of the ZeptoMail data
+// centers. A mail agent's token only works in the data center of its
+// account.
+var zeptomailRegionEndpoints = map[string]string{
+	"us": zeptomailDefaultEndpoint,
+	"eu": "https://api.zeptomail.eu",
+	"in": "https://api.zeptomail.in",
+	"au": "https://api.zeptomail.com.au",
+}
+
+// awsRegionPattern matches AWS region names such as us-east-1,
+// us-gov-west-1 and cn-north-1.
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// ValidateEndpoint checks the API base URL override of the built-in API
+// providers: an absolute http or https URL without query or fragment. A
+// self-hosted API compatible with the provider's can be reached this way.
+// Other provider types give endpoint their own meaning and are not checked.
+func ValidateEndpoint(providerType, endpoint string) error {
+	switch providerType {
+	case "sendgrid", "ses", "mailgun", "msgraph", "gmail", "resend", "brevo", "zeptomail", "ews":
+	default:
+		return nil
+	}
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("endpoint: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("endpoint: %q is not an absolute http(s) URL", endpoint)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("endpoint: %q must not have a query or fragment", endpoint)
+	}
+	return nil
+}
+
+// ValidateRegion checks the region of a provider type. Region is required
+// for SES, optional for Mailgun, SendGrid and ZeptoMail, and not used by
+// other types.
+func ValidateRegion(providerType, region string) error {
+	switch providerType {
+	case "ses":
+		if region != "" && !awsRegionPattern.MatchString(region) {
+			return fmt.Errorf("ses: %q is not an AWS region", region)
+		}
+	case "mailgun":
+		return checkRegion(providerType, region, mailgunRegionEndpoints)
+	case "sendgrid":
+		return checkRegion(providerType, region, sendgridRegionEndpoints)
+	case "zeptomail":
+		return checkRegion(providerType, region, zeptomailRegionEndpoints)
+	}
+	return nil
+}
+
+func checkRegion(providerType, region string, endpoints map[string]string) error {
+	if region == "" {
+		return nil
+	}
+	if _, ok := endpoints[region]; ok {
+		return nil
+	}
+	regions := make([]string, 0, len(endpoints))
+	for r := range endpoints {
+		regions = append(regions, r)
+	}
+	sort.Strings(regions)
+	return fmt.Errorf("%s: unknown region %q (want one of %s)", providerType, region, strings.Join(regions, ", "))
+}
+
+// apiEndpoint returns the API base URL of a provider: the endpoint
+// override if set, else the URL of the region, else the default. The
+// result has no trailing slash, so API paths can be appended to it.
+func apiEndpoint(cfg ProviderConfig, regions map[string]string, def string) string {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = regions[cfg.Region]
+	}
+	if endpoint == "" {
+		endpoint = def
+	}
+	return strings.TrimRight(endpoint, "/")
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -125,3 +126,25 @@ func containsPermanentServerIndicator(body string) bool {
 	}
 	return false
 }
+
+// maxHealthCheckDetail bounds the ESP response text in health check errors.
+const maxHealthCheckDetail = 200
+
+// healthCheckError returns the error of a failed health check response,
+// classified like a send error so that callers can tell rejected
+// credentials (permanent) from an unavailable ESP.
+func healthCheckError(providerName string, statusCode int, body []byte) *ProviderError {
+	pe := ClassifyHTTPError(providerName, statusCode, string(body))
+	if pe == nil {
+		// A success status the health check did not expect.
+		pe = &ProviderError{Provider: providerName, StatusCode: statusCode}
+	}
+	pe.Message = fmt.Sprintf("health check returned status %d", statusCode)
+	if detail := strings.TrimSpace(string(body)); detail != "" {
+		if len(detail) > maxHealthCheckDetail {
+			detail = detail[:maxHealthCheckDetail] + "..."
+		}
+		pe.Message += ": " + detail
+	}
+	return pe
+}
@@ -3,6 +3,7 @@ package provider
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -262,3 +263,26 @@ func TestProviderError_Error(t *testing.T) {
 		t.Errorf("Error() = %q, want %q", got, want)
 	}
 }
+
+func TestHealthCheckError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantMessage   string
+		wantPermanent bool
+	}{
+		{"rejected key", 401, `{"message":"Invalid private key"}`, `health check returned status 401: {"message":"Invalid private key"}`, true},
+		{"unavailable", 503, "", "health check returned status 503", false},
+		{"unexpected success", 200, "", "health check returned status 200", false},
+		{"long body", 403, strings.Repeat("x", 300), "health check returned status 403: " + strings.Repeat("x", maxHealthCheckDetail) + "...", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe := healthCheckError("mailgun", tt.status, []byte(tt.body))
+			if pe.Message != tt.wantMessage || pe.Permanent != tt.wantPermanent || pe.StatusCode != tt.status {
+				t.Errorf("healthCheckError() = %+v", pe)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EWS authentication methods.
+const (
+	EWSAuthNTLM  = "ntlm"
+	EWSAuthBasic = "basic"
+)
+
+// ewsTransientCodes are the EWS response codes of throttling and of
+// temporary server conditions. Messages failing with them are retried.
+var ewsTransientCodes = map[string]bool{
+	"ErrorServerBusy":                   true,
+	"ErrorTooManyObjectsOpened":         true,
+	"ErrorExceededConnectionCount":      true,
+	"ErrorTimeoutExpired":               true,
+	"ErrorInternalServerTransientError": true,
+	"ErrorMailboxStoreUnavailable":      true,
+	"ErrorMailboxMoveInProgress":        true,
+	"ErrorConnectionFailed":             true,
+}
+
+// EWS implements the Provider interface for Exchange Web Services, for
+// organizations relaying through on-premises Exchange. Messages are
+// created from their MIME content and sent with a copy saved to the
+// mailbox's Sent Items. The endpoint is the EWS URL of the server, such as
+// https://mail.example.com/EWS/Exchange.asmx.
+type EWS struct {
+	endpoint string
+	auth     string
+	username string
+	password string
+	client   HTTPClient
+}
+
+// ValidateEWSAuth checks the auth method of an EWS provider; empty means
+// NTLM.
+func ValidateEWSAuth(auth string) error {
+	switch auth {
+	case "", EWSAuthNTLM, EWSAuthBasic:
+		return nil
+	}
+	return fmt.Errorf("ews: unknown auth %q (want ntlm or basic)", auth)
+}
+
+// NewEWS creates an EWS provider from the given configuration. The
+// password is the APIKey.
+func NewEWS(cfg ProviderConfig, client HTTPClient) *EWS {
+	auth := cfg.Auth
+	if auth == "" {
+		auth = EWSAuthNTLM
+	}
+	return &EWS{
+		endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		auth:     auth,
+		username: cfg.Username,
+		password: cfg.APIKey,
+		client:   client,
+	}
+}
+
+func (e *EWS) GetName() string { return "ews" }
+
+// SendsRaw implements RawSender.
+func (e *EWS) SendsRaw() bool { return true }
+
+// Send delivers a message with an EWS CreateItem request.
+func (e *EWS) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
+	raw := msg.Raw
+	if raw == nil {
+		var err error
+		raw, err = buildRawMIMEWithHeaders(msg)
+		if err != nil {
+			return nil, fmt.Errorf("ews: build message: %w", err)
+		}
+	}
+	// Exchange reads the recipients from the MIME headers and removes the
+	// Bcc header before delivery.
+	if len(msg.Bcc) > 0 {
+		raw = append([]byte("Bcc: "+strings.Join(msg.Bcc, ", ")+"\r\n"), raw...)
+	}
+
+	body := fmt.Sprintf(ewsCreateItemFmt, base64.StdEncoding.EncodeToString(raw))
+	resp, err := e.post(ctx, []byte(body))
+	if err != nil {
+		return nil, fmt.Errorf("ews: send request: %w", err)
+	}
+	if err := classifyEWSResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Sent items have no ID in the CreateItem response.
+	return &DeliveryResult{
+		ProviderMessageID: msg.ID,
+		Status:            StatusSent,
+		Timestamp:         time.Now(),
+		Metadata: map[string]string{
+			"status_code": fmt.Sprintf("%d", resp.StatusCode),
+			"provider":    "ews",
+		},
+	}, nil
+}
+
+// HealthCheck verifies EWS connectivity and credentials by getting the
+// mailbox's Sent Items folder.
+func (e *EWS) HealthCheck(ctx context.Context) error {
+	resp, err := e.post(ctx, []byte(ewsGetFolderRequest))
+	if err != nil {
+		return fmt.Errorf("ews: health check request: %w", err)
+	}
+	if err := classifyEWSResponse(resp); err != nil {
+		return fmt.Errorf("ews: health check: %w", err)
+	}
+	return nil
+}
+
+// post sends a SOAP request, authenticating with Basic or with an NTLM
+// handshake. NTLM authenticates the connection, so the handshake needs
+// the server, and any proxy in between, to keep it alive across the
+// negotiate and authenticate requests.
+func (e *EWS) post(ctx context.Context, body []byte) (*HTTPResponse, error) {
+	headers := map[string]string{
+		"Content-Type": "text/xml; charset=utf-8",
+	}
+	if e.auth == EWSAuthBasic {
+		headers["Authorization"] = "Basic " + basicAuth(e.username, e.password)
+		return e.client.Do(&HTTPRequest{Method: "POST", URL: e.endpoint, Headers: headers, Body: body, Context: ctx})
+	}
+
+	// The negotiate request has no body: Exchange answers it with the
+	// challenge before reading one.
+	resp, err := e.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    e.endpoint,
+		Headers: map[string]string{
+			"Authorization": "NTLM " + base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()),
+			"Content-Type":  headers["Content-Type"],
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 401 {
+		// Throttling and server errors come before authentication.
+		return resp, nil
+	}
+	challengeMsg, ok := ntlmChallengeHeader(resp.Headers)
+	if !ok {
+		// The server does not offer NTLM.
+		return resp, nil
+	}
+	challenge, err := parseNTLMChallenge(challengeMsg)
+	if err != nil {
+		return nil, err
+	}
+	authenticate, err := ntlmAuthenticateMessage(newNTLMCredentials(e.username, e.password), challenge)
+	if err != nil {
+		return nil, err
+	}
+	headers["Authorization"] = "NTLM " + base64.StdEncoding.EncodeToString(authenticate)
+	return e.client.Do(&HTTPRequest{Method: "POST", URL: e.endpoint, Headers: headers, Body: body, Context: ctx})
+}
+
+// ntlmChallengeHeader returns the decoded NTLM challenge of a 401
+// response's WWW-Authenticate header.
+func ntlmChallengeHeader(headers map[string]string) ([]byte, bool) {
+	for k, v := range headers {
+		if !strings.EqualFold(k, "WWW-Authenticate") {
+			continue
+		}
+		for _, challenge := range strings.Split(v, ",") {
+			scheme, token, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+			if !strings.EqualFold(scheme, "NTLM") || token == "" {
+				continue
+			}
+			if msg, err := base64.StdEncoding.DecodeString(token); err == nil {
+				return msg, true
+			}
+		}
+	}
+	return nil, false
+}
+
+const ewsCreateItemFmt = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+  <soap:Header><t:RequestServerVersion Version="Exchange2013_SP1"/></soap:Header>
+  <soap:Body>
+    <m:CreateItem MessageDisposition="SendAndSaveCopy">
+      <m:SavedItemFolderId><t:DistinguishedFolderId Id="sentitems"/></m:SavedItemFolderId>
+      <m:Items><t:Message><t:MimeContent CharacterSet="UTF-8">%s</t:MimeContent></t:Message></m:Items>
+    </m:CreateItem>
+  </soap:Body>
+</soap:Envelope>`
+
+const ewsGetFolderRequest = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+  <soap:Header><t:RequestServerVersion Version="Exchange2013_SP1"/></soap:Header>
+  <soap:Body>
+    <m:GetFolder>
+      <m:FolderShape><t:BaseShape>IdOnly</t:BaseShape></m:FolderShape>
+      <m:FolderIds><t:DistinguishedFolderId Id="sentitems"/></m:FolderIds>
+    </m:GetFolder>
+  </soap:Body>
+</soap:Envelope>`
+
+// ewsEnvelope is the part of an EWS SOAP response used to classify it:
+// the response message of a single-item request, or a SOAP fault.
+type ewsEnvelope struct {
+	Body struct {
+		Response struct {
+			Messages struct {
+				Message []ewsResponseMessage `xml:",any"`
+			} `xml:"ResponseMessages"`
+		} `xml:",any"`
+		Fault *ewsFault `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+type ewsResponseMessage struct {
+	ResponseClass string        `xml:"ResponseClass,attr"`
+	MessageText   string        `xml:"MessageText"`
+	ResponseCode  string        `xml:"ResponseCode"`
+	MessageXML    ewsMessageXML `xml:"MessageXml"`
+}
+
+type ewsFault struct {
+	FaultString string `xml:"faultstring"`
+	Detail      struct {
+		ResponseCode string        `xml:"ResponseCode"`
+		Message      string        `xml:"Message"`
+		MessageXML   ewsMessageXML `xml:"MessageXml"`
+	} `xml:"detail"`
+}
+
+// ewsMessageXML carries details of an error, such as how long a throttled
+// client should back off.
+type ewsMessageXML struct {
+	Values []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"Value"`
+}
+
+func (m ewsMessageXML) backOff() string {
+	for _, v := range m.Values {
+		if v.Name == "BackOffMilliseconds" {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// classifyEWSResponse returns the error of an EWS response, or nil when
+// its request succeeded. Throttling (ErrorServerBusy, usually as a SOAP
+// fault with status 500, or status 503) and temporary server conditions
+// are transient; other errors, such as invalid recipients or missing send
+// as permission, are permanent.
+func classifyEWSResponse(resp *HTTPResponse) error {
+	var env ewsEnvelope
+	parseErr := xml.Unmarshal(resp.Body, &env)
+
+	if resp.StatusCode == 200 && parseErr == nil && env.Body.Fault == nil {
+		for _, m := range env.Body.Response.Messages.Message {
+			if m.ResponseClass == "Error" {
+				return ewsError(resp.StatusCode, m.ResponseCode, m.MessageText, m.MessageXML.backOff())
+			}
+		}
+		return nil
+	}
+	if parseErr == nil && env.Body.Fault != nil && env.Body.Fault.Detail.ResponseCode != "" {
+		f := env.Body.Fault
+		text := f.Detail.Message
+		if text == "" {
+			text = f.FaultString
+		}
+		return ewsError(resp.StatusCode, f.Detail.ResponseCode, text, f.Detail.MessageXML.backOff())
+	}
+	if resp.StatusCode == 200 {
+		return errors.New("ews: unreadable response")
+	}
+	text := string(bytes.TrimSpace(resp.Body))
+	if text == "" {
+		// Authentication failures have no body.
+		text = fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return ClassifyHTTPError("ews", resp.StatusCode, text)
+}
+
+func ewsError(statusCode int, code, text, backOff string) *ProviderError {
+	msg := code + ": " + text
+	if backOff != "" {
+		msg += " (back off " + backOff + "ms)"
+	}
+	return &ProviderError{
+		Provider:   "ews",
+		StatusCode: statusCode,
+		Message:    msg,
+		Permanent:  !ewsTransientCodes[code],
+	}
+}
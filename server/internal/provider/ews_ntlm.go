@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4" //nolint:staticcheck // NTLM is defined on MD4
+)
+
+// NTLM message flags (MS-NLMP 2.2.2.5) used by the client.
+const (
+	ntlmNegotiateUnicode                 = 0x00000001
+	ntlmNegotiateOEM                     = 0x00000002
+	ntlmRequestTarget                    = 0x00000004
+	ntlmNegotiateNTLM                    = 0x00000200
+	ntlmNegotiateAlwaysSign              = 0x00008000
+	ntlmNegotiateExtendedSessionSecurity = 0x00080000
+	ntlmNegotiate128                     = 0x20000000
+	ntlmNegotiate56                      = 0x80000000
+
+	ntlmNegotiateFlags = ntlmNegotiateUnicode | ntlmNegotiateOEM | ntlmRequestTarget | ntlmNegotiateNTLM |
+		ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSessionSecurity | ntlmNegotiate128 | ntlmNegotiate56
+)
+
+// ntlmSignature starts every NTLM message.
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmAvTimestamp is the AV_PAIR ID of the server's FILETIME in the
+// challenge's target info.
+const ntlmAvTimestamp = 7
+
+// ntlmCredentials are the user, domain and password NTLM authenticates
+// with. The user is given as DOMAIN\user or as a user principal name,
+// which NTLM accepts with an empty domain.
+type ntlmCredentials struct {
+	user     string
+	domain   string
+	password string
+}
+
+func newNTLMCredentials(username, password string) ntlmCredentials {
+	if domain, user, ok := strings.Cut(username, `\`); ok {
+		return ntlmCredentials{user: user, domain: domain, password: password}
+	}
+	return ntlmCredentials{user: username, password: password}
+}
+
+// ntlmNegotiateMessage returns the NEGOTIATE_MESSAGE that starts the
+// handshake, without domain or workstation.
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	return msg
+}
+
+// ntlmChallenge is the part of a CHALLENGE_MESSAGE the response is
+// computed from.
+type ntlmChallenge struct {
+	flags           uint32
+	serverChallenge []byte
+	targetInfo      []byte
+}
+
+// parseNTLMChallenge parses the server's CHALLENGE_MESSAGE.
+func parseNTLMChallenge(msg []byte) (*ntlmChallenge, error) {
+	if len(msg) < 48 || !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 2 {
+		return nil, errors.New("ntlm: not a challenge message")
+	}
+	c := &ntlmChallenge{
+		flags:           binary.LittleEndian.Uint32(msg[20:]),
+		serverChallenge: msg[24:32],
+	}
+	infoLen := int(binary.LittleEndian.Uint16(msg[40:]))
+	infoOff := int(binary.LittleEndian.Uint32(msg[44:]))
+	if infoLen > 0 {
+		if infoOff+infoLen > len(msg) {
+			return nil, errors.New("ntlm: challenge target info out of range")
+		}
+		c.targetInfo = msg[infoOff : infoOff+infoLen]
+	}
+	return c, nil
+}
+
+// timestamp returns the server time of the challenge's target info as a
+// FILETIME, if present.
+func (c *ntlmChallenge) timestamp() ([]byte, bool) {
+	info := c.targetInfo
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		n := int(binary.LittleEndian.Uint16(info[2:]))
+		if len(info) < 4+n {
+			break
+		}
+		if id == ntlmAvTimestamp && n == 8 {
+			return info[4:12], true
+		}
+		if id == 0 {
+			break
+		}
+		info = info[4+n:]
+	}
+	return nil, false
+}
+
+// ntlmAuthenticateMessage returns the NTLMv2 AUTHENTICATE_MESSAGE answering
+// challenge.
+func ntlmAuthenticateMessage(creds ntlmCredentials, challenge *ntlmChallenge) ([]byte, error) {
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+	timestamp, serverTime := challenge.timestamp()
+	if !serverTime {
+		timestamp = ntlmFiletime(time.Now())
+	}
+
+	key := ntowfv2(creds.user, creds.domain, creds.password)
+	ntResponse := ntlmv2Response(key, challenge.serverChallenge, clientChallenge, timestamp, challenge.targetInfo)
+	// With a server timestamp the LMv2 response must be zeros
+	// (MS-NLMP 3.1.5.1.2).
+	lmResponse := make([]byte, 24)
+	if !serverTime {
+		lmResponse = append(ntlmHMAC(key, challenge.serverChallenge, clientChallenge), clientChallenge...)
+	}
+
+	flags := challenge.flags & ntlmNegotiateFlags
+	encode := ntlmOEM
+	if flags&ntlmNegotiateUnicode != 0 {
+		encode = ntlmUnicode
+	}
+	fields := [][]byte{lmResponse, ntResponse, encode(creds.domain), encode(creds.user), nil, nil}
+
+	const headerLen = 64
+	msg := make([]byte, headerLen)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	offset := headerLen
+	for i, f := range fields {
+		pos := 12 + 8*i
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(f)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(f)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		offset += len(f)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], flags)
+	for _, f := range fields {
+		msg = append(msg, f...)
+	}
+	return msg, nil
+}
+
+// ntowfv2 is the NTLMv2 response key of a user (MS-NLMP 3.3.2).
+func ntowfv2(user, domain, password string) []byte {
+	h := md4.New()
+	h.Write(ntlmUnicode(password))
+	return ntlmHMAC(h.Sum(nil), ntlmUnicode(strings.ToUpper(user)+domain))
+}
+
+// ntlmv2Response is the NTLMv2 NtChallengeResponse: NTProofStr followed
+// by the client blob it was computed over.
+func ntlmv2Response(key, serverChallenge, clientChallenge, timestamp, targetInfo []byte) []byte {
+	var blob bytes.Buffer
+	blob.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	blob.Write(timestamp)
+	blob.Write(clientChallenge)
+	blob.Write([]byte{0, 0, 0, 0})
+	blob.Write(targetInfo)
+	blob.Write([]byte{0, 0, 0, 0})
+	proof := ntlmHMAC(key, serverChallenge, blob.Bytes())
+	return append(proof, blob.Bytes()...)
+}
+
+func ntlmHMAC(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// ntlmFiletime encodes t as a Windows FILETIME: 100ns intervals since 1601.
+func ntlmFiletime(t time.Time) []byte {
+	const epochDiff = 116444736000000000
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(t.UnixNano()/100+epochDiff))
+	return b
+}
+
+func ntlmUnicode(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, r := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], r)
+	}
+	return b
+}
+
+func ntlmOEM(s string) []byte {
+	return []byte(strings.ToUpper(s))
+}
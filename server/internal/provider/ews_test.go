@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const ewsSuccessResponse = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/">
+  <s:Body>
+    <m:CreateItemResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+      <m:ResponseMessages>
+        <m:CreateItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items/></m:CreateItemResponseMessage>
+      </m:ResponseMessages>
+    </m:CreateItemResponse>
+  </s:Body>
+</s:Envelope>`
+
+func TestNTOWFv2(t *testing.T) {
+	// MS-NLMP 4.2.4.1.1
+	got := hex.EncodeToString(ntowfv2("User", "Domain", "Password"))
+	if got != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Errorf("ntowfv2 = %s", got)
+	}
+}
+
+// testNTLMChallenge returns a CHALLENGE_MESSAGE with a timestamp in its
+// target info.
+func testNTLMChallenge(serverChallenge []byte) []byte {
+	info := []byte{ntlmAvTimestamp, 0, 8, 0, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0}
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmNegotiateFlags)
+	copy(msg[24:], serverChallenge)
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(info)))
+	binary.LittleEndian.PutUint16(msg[42:], uint16(len(info)))
+	binary.LittleEndian.PutUint32(msg[44:], 48)
+	return append(msg, info...)
+}
+
+// ntlmField returns the payload of the security buffer at pos of an NTLM
+// message.
+func ntlmField(msg []byte, pos int) []byte {
+	n := int(binary.LittleEndian.Uint16(msg[pos:]))
+	off := int(binary.LittleEndian.Uint32(msg[pos+4:]))
+	return msg[off : off+n]
+}
+
+func TestEWS_SendNTLM(t *testing.T) {
+	serverChallenge := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	var requests []*HTTPRequest
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		requests = append(requests, req)
+		if len(requests) == 1 {
+			return &HTTPResponse{
+				StatusCode: 401,
+				Headers:    map[string]string{"Www-Authenticate": "Negotiate, NTLM " + base64.StdEncoding.EncodeToString(testNTLMChallenge(serverChallenge))},
+			}, nil
+		}
+		return &HTTPResponse{StatusCode: 200, Body: []byte(ewsSuccessResponse)}, nil
+	}}
+	e := NewEWS(ProviderConfig{Endpoint: "https://mail.example.com/EWS/Exchange.asmx", Username: `CORP\alice`, APIKey: "secret"}, client)
+
+	result, err := e.Send(context.Background(), &Message{
+		ID: "msg-1", From: "alice@example.com", To: []string{"b@example.com"}, Bcc: []string{"audit@example.com"},
+		Subject: "Hi", TextBody: "hello",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProviderMessageID != "msg-1" {
+		t.Errorf("ProviderMessageID = %q", result.ProviderMessageID)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+
+	negotiate, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(requests[0].Headers["Authorization"], "NTLM "))
+	if !bytes.Equal(negotiate, ntlmNegotiateMessage()) || len(requests[0].Body) != 0 {
+		t.Errorf("negotiate request = %v, body %q", requests[0].Headers, requests[0].Body)
+	}
+
+	authenticate, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(requests[1].Headers["Authorization"], "NTLM "))
+	if err != nil || !bytes.HasPrefix(authenticate, ntlmSignature) || binary.LittleEndian.Uint32(authenticate[8:]) != 3 {
+		t.Fatalf("authenticate message = %x", authenticate)
+	}
+	if user := ntlmField(authenticate, 36); !bytes.Equal(user, ntlmUnicode("alice")) {
+		t.Errorf("user = %q", user)
+	}
+	if domain := ntlmField(authenticate, 28); !bytes.Equal(domain, ntlmUnicode("CORP")) {
+		t.Errorf("domain = %q", domain)
+	}
+	ntResponse := ntlmField(authenticate, 20)
+	proof, blob := ntResponse[:16], ntResponse[16:]
+	if want := ntlmHMAC(ntowfv2("alice", "CORP", "secret"), serverChallenge, blob); !bytes.Equal(proof, want) {
+		t.Errorf("NTProofStr = %x, want %x", proof, want)
+	}
+	if !bytes.Equal(blob[8:16], []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("blob timestamp = %x, want the server's", blob[8:16])
+	}
+
+	body := string(requests[1].Body)
+	if !strings.Contains(body, `MessageDisposition="SendAndSaveCopy"`) {
+		t.Errorf("body = %s", body)
+	}
+	start := strings.Index(body, `CharacterSet="UTF-8">`) + len(`CharacterSet="UTF-8">`)
+	mime, err := base64.StdEncoding.DecodeString(body[start : start+strings.Index(body[start:], "<")])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(mime), "Bcc: audit@example.com\r\n") || !strings.Contains(string(mime), "Subject: Hi") {
+		t.Errorf("mime = %q", mime)
+	}
+}
+
+func TestEWS_SendBasic(t *testing.T) {
+	var got *HTTPRequest
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		got = req
+		return &HTTPResponse{StatusCode: 200, Body: []byte(ewsSuccessResponse)}, nil
+	}}
+	e := NewEWS(ProviderConfig{Endpoint: "https://mail.example.com/EWS/Exchange.asmx", Username: "alice@example.com", APIKey: "secret", Auth: EWSAuthBasic}, client)
+
+	if _, err := e.Send(context.Background(), &Message{From: "alice@example.com", To: []string{"b@example.com"}, Raw: []byte("Subject: x\r\n\r\nbody")}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers["Authorization"] != "Basic "+basicAuth("alice@example.com", "secret") {
+		t.Errorf("Authorization = %q", got.Headers["Authorization"])
+	}
+}
+
+func TestClassifyEWSResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantErr       string
+		wantPermanent bool
+	}{
+		{"success", 200, ewsSuccessResponse, "", false},
+		{"invalid recipients", 200, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<m:CreateItemResponse xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages"><m:ResponseMessages>
+<m:CreateItemResponseMessage ResponseClass="Error"><m:MessageText>At least one recipient isn't valid.</m:MessageText><m:ResponseCode>ErrorInvalidRecipients</m:ResponseCode></m:CreateItemResponseMessage>
+</m:ResponseMessages></m:CreateItemResponse></s:Body></s:Envelope>`, "ErrorInvalidRecipients", true},
+		{"server busy", 500, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>
+<faultcode>a:ErrorServerBusy</faultcode><faultstring>The server cannot service this request right now.</faultstring>
+<detail><e:ResponseCode xmlns:e="http://schemas.microsoft.com/exchange/services/2006/errors">ErrorServerBusy</e:ResponseCode>
+<e:Message xmlns:e="http://schemas.microsoft.com/exchange/services/2006/errors">The server cannot service this request right now. Try again later.</e:Message>
+<t:MessageXml xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types"><t:Value Name="BackOffMilliseconds">297749</t:Value></t:MessageXml>
+</detail></s:Fault></s:Body></s:Envelope>`, "back off 297749ms", false},
+		{"unavailable", 503, "Service Unavailable", "Service Unavailable", false},
+		{"unauthorized", 401, "", "status 401", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyEWSResponse(&HTTPResponse{StatusCode: tt.status, Body: []byte(tt.body)})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("classifyEWSResponse() = %v", err)
+				}
+				return
+			}
+			var pe *ProviderError
+			if !isProviderError(err, &pe) {
+				t.Fatalf("classifyEWSResponse() = %v, want a ProviderError", err)
+			}
+			if !strings.Contains(pe.Error(), tt.wantErr) || pe.Permanent != tt.wantPermanent {
+				t.Errorf("error = %v (permanent %v), want %q (permanent %v)", pe, pe.Permanent, tt.wantErr, tt.wantPermanent)
+			}
+		})
+	}
+}
+
+func TestEWS_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ProviderConfig
+		wantErr string
+	}{
+		{"ntlm", ProviderConfig{Type: "ews", Endpoint: "https://mail.example.com/EWS/Exchange.asmx", Username: `CORP\alice`, APIKey: "secret"}, ""},
+		{"basic", ProviderConfig{Type: "ews", Endpoint: "https://mail.example.com/EWS/Exchange.asmx", Username: "alice@example.com", APIKey: "secret", Auth: "basic"}, ""},
+		{"no endpoint", ProviderConfig{Type: "ews", Username: "alice", APIKey: "secret"}, "endpoint"},
+		{"no username", ProviderConfig{Type: "ews", Endpoint: "https://mail.example.com/EWS/Exchange.asmx", APIKey: "secret"}, "username"},
+		{"no password", ProviderConfig{Type: "ews", Endpoint: "https://mail.example.com/EWS/Exchange.asmx", Username: "alice"}, "api_key"},
+		{"unknown auth", ProviderConfig{Type: "ews", Endpoint: "https://mail.example.com/EWS/Exchange.asmx", Username: "alice", APIKey: "secret", Auth: "kerberos"}, "auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -59,11 +59,21 @@ func (r *Registry) All() []Provider {
 }
 
 // NewProvider creates a provider instance from the given config and HTTP client.
+// When cfg.ProxyURL is set and client is a DefaultHTTPClient, the provider
+// uses a copy of client that goes through that proxy.
 func NewProvider(cfg ProviderConfig, client HTTPClient) (Provider, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid provider config: %w", err)
 	}
 
+	if dc, ok := client.(*DefaultHTTPClient); ok && cfg.ProxyURL != "" {
+		proxied, err := dc.WithProxy(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid provider config: %w", err)
+		}
+		client = proxied
+	}
+
 	switch cfg.Type {
 	case "sendgrid":
 		return NewSendGrid(cfg, client), nil
@@ -73,11 +83,29 @@ func NewProvider(cfg ProviderConfig, client HTTPClient) (Provider, error) {
 		return NewMailgun(cfg, client), nil
 	case "msgraph":
 		return NewMSGraph(cfg, client), nil
+	case "gmail":
+		g, err := NewGmail(cfg, client)
+		if err != nil {
+			return nil, fmt.Errorf("invalid provider config: %w", err)
+		}
+		return g, nil
+	case "resend":
+		return NewResend(cfg, client), nil
+	case "brevo":
+		return NewBrevo(cfg, client), nil
+	case "zeptomail":
+		return NewZeptoMail(cfg, client), nil
+	case "ews":
+		return NewEWS(cfg, client), nil
 	case "stdout":
 		return NewStdout(cfg), nil
 	case "file":
 		return NewFile(cfg), nil
-	default:
-		return nil, fmt.Errorf("unsupported provider type: %s", cfg.Type)
 	}
+
+	// Custom types registered by plugins.
+	if factory, ok := lookupFactory(cfg.Type); ok {
+		return factory(cfg, client)
+	}
+	return nil, fmt.Errorf("unsupported provider type: %s", cfg.Type)
 }
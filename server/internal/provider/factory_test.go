@@ -4,6 +4,7 @@ import (
 	"context"
 	"sort"
 	"testing"
+	"time"
 )
 
 // mockProvider implements Provider for registry tests.
@@ -223,3 +224,21 @@ func TestRegistry_RegisterOverwrite(t *testing.T) {
 		t.Errorf("expected 1 provider after overwrite, got %d", len(names))
 	}
 }
+
+func TestNewProvider_ProxyURLWrapsDefaultClient(t *testing.T) {
+	client := NewHTTPClient(5 * time.Second)
+	cfg := ProviderConfig{Type: "sendgrid", APIKey: "key", ProxyURL: "http://proxy.internal:3128"}
+
+	p, err := NewProvider(cfg, client)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	sg, ok := p.(*SendGrid)
+	if !ok {
+		t.Fatalf("expected *SendGrid, got %T", p)
+	}
+	proxied, _ := client.WithProxy(cfg.ProxyURL)
+	if sg.client != HTTPClient(proxied) {
+		t.Error("expected provider to use the proxied client")
+	}
+}
@@ -31,6 +31,9 @@ func NewFile(cfg ProviderConfig) *File {
 
 func (f *File) GetName() string { return "file" }
 
+// SendsRaw implements RawSender.
+func (f *File) SendsRaw() bool { return true }
+
 // Send writes the message to a file named <timestamp>_<message-id>.eml
 // in the output directory and returns a successful result.
 func (f *File) Send(_ context.Context, msg *Message) (*DeliveryResult, error) {
@@ -44,8 +47,23 @@ func (f *File) Send(_ context.Context, msg *Message) (*DeliveryResult, error) {
 	path := filepath.Join(f.outputDir, filename)
 
 	var b strings.Builder
+	if msg.ReturnPath != "" {
+		fmt.Fprintf(&b, "Return-Path: <%s>\n", msg.ReturnPath)
+	}
+	if msg.Raw != nil {
+		// A signed message is written as sent; its headers are part of it.
+		if len(msg.Bcc) > 0 {
+			fmt.Fprintf(&b, "Bcc: %s\n", strings.Join(msg.Bcc, ", "))
+		}
+		fmt.Fprintf(&b, "X-Provider-Message-ID: file-%s\n", msg.ID)
+		b.Write(msg.Raw)
+		return f.write(path, msg.ID, b.String())
+	}
 	fmt.Fprintf(&b, "From: %s\n", msg.From)
 	fmt.Fprintf(&b, "To: %s\n", strings.Join(msg.To, ", "))
+	if len(msg.Bcc) > 0 {
+		fmt.Fprintf(&b, "Bcc: %s\n", strings.Join(msg.Bcc, ", "))
+	}
 	fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
 	for k, v := range msg.Headers {
 		fmt.Fprintf(&b, "%s: %s\n", k, v)
@@ -59,13 +77,16 @@ func (f *File) Send(_ context.Context, msg *Message) (*DeliveryResult, error) {
 	}
 	b.WriteString("\n")
 	b.Write(msg.Body)
+	return f.write(path, msg.ID, b.String())
+}
 
-	if err := os.WriteFile(path, []byte(b.String()), 0o640); err != nil {
+func (f *File) write(path, id, content string) (*DeliveryResult, error) {
+	if err := os.WriteFile(path, []byte(content), 0o640); err != nil {
 		return nil, fmt.Errorf("file: write %s: %w", path, err)
 	}
 
 	return &DeliveryResult{
-		ProviderMessageID: "file-" + msg.ID,
+		ProviderMessageID: "file-" + id,
 		Status:            StatusSent,
 		Timestamp:         time.Now(),
 		Metadata:          map[string]string{"path": path},
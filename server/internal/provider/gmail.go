@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	gmailDefaultEndpoint = "https://gmail.googleapis.com"
+	gmailSendPath        = "/upload/gmail/v1/users/me/messages/send?uploadType=media"
+)
+
+// gmailQuotaReasons are the error reasons of the Gmail API's per-user and
+// per-project rate limits and of the daily sending limit of a Workspace
+// user. They come with status 403 or 429 and clear with time, so they are
+// retried instead of failing the message.
+var gmailQuotaReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"dailyLimitExceeded":    true,
+	"quotaExceeded":         true,
+}
+
+// Gmail implements the Provider interface for the Gmail API, for Google
+// Workspace customers that must send through their users' mailboxes. The
+// api_key is either a service account JSON key with domain-wide delegation
+// of the gmail.send scope, or a user's OAuth2 refresh token issued to
+// client_id. Messages are uploaded as raw MIME, so Gmail keeps them in the
+// sender's Sent folder as sent.
+type Gmail struct {
+	userID       string
+	endpoint     string
+	client       HTTPClient
+	tokenManager *GoogleTokenManager
+}
+
+// NewGmail creates a Gmail API provider from the given configuration. It
+// fails when the api_key is a service account key that cannot be parsed.
+func NewGmail(cfg ProviderConfig, client HTTPClient) (*Gmail, error) {
+	tm, err := NewGoogleTokenManager(cfg.APIKey, cfg.ClientID, cfg.ClientSecret, client)
+	if err != nil {
+		return nil, fmt.Errorf("gmail: %w", err)
+	}
+	return &Gmail{
+		userID:       cfg.UserID,
+		endpoint:     apiEndpoint(cfg, nil, gmailDefaultEndpoint),
+		client:       client,
+		tokenManager: tm,
+	}, nil
+}
+
+func (g *Gmail) GetName() string { return "gmail" }
+
+// SendsRaw implements RawSender.
+func (g *Gmail) SendsRaw() bool { return true }
+
+// Send delivers a message via the Gmail API messages.send media upload.
+// A service account sends as user_id, or as the message's sender when
+// user_id is not set. On 401 responses, it refreshes the token and retries
+// once.
+func (g *Gmail) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
+	subject := g.sender(msg)
+	result, err := g.sendWithToken(ctx, msg, subject)
+	if err == nil {
+		return result, nil
+	}
+
+	var pe *ProviderError
+	if isProviderError(err, &pe) && pe.StatusCode == 401 {
+		g.tokenManager.InvalidateToken(subject)
+		return g.sendWithToken(ctx, msg, subject)
+	}
+	return nil, err
+}
+
+// sender returns the user a service account impersonates for msg.
+func (g *Gmail) sender(msg *Message) string {
+	if g.userID != "" || !g.tokenManager.ServiceAccount() {
+		return g.userID
+	}
+	if addr, err := mail.ParseAddress(msg.From); err == nil {
+		return addr.Address
+	}
+	return msg.From
+}
+
+func (g *Gmail) sendWithToken(ctx context.Context, msg *Message, subject string) (*DeliveryResult, error) {
+	token, err := g.tokenManager.GetToken(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := msg.Raw
+	if raw == nil {
+		raw, err = buildRawMIMEWithHeaders(msg)
+		if err != nil {
+			return nil, fmt.Errorf("gmail: build message: %w", err)
+		}
+	}
+	// Gmail reads the recipients from the headers and removes the Bcc
+	// header before delivery.
+	if len(msg.Bcc) > 0 {
+		raw = append([]byte("Bcc: "+strings.Join(msg.Bcc, ", ")+"\r\n"), raw...)
+	}
+
+	resp, err := g.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    g.endpoint + gmailSendPath,
+		Headers: map[string]string{
+			"Authorization": "Bearer " + token,
+			"Content-Type":  "message/rfc822",
+		},
+		Body:    raw,
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gmail: send request: %w", err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var sent gmailMessage
+		_ = json.Unmarshal(resp.Body, &sent)
+		metadata := map[string]string{
+			"status_code": fmt.Sprintf("%d", resp.StatusCode),
+			"provider":    "gmail",
+		}
+		if sent.ThreadID != "" {
+			metadata["thread_id"] = sent.ThreadID
+		}
+		return &DeliveryResult{
+			ProviderMessageID: sent.ID,
+			Status:            StatusSent,
+			Timestamp:         time.Now(),
+			Metadata:          metadata,
+		}, nil
+	}
+
+	return nil, classifyGmailError(resp.StatusCode, resp.Body)
+}
+
+// HealthCheck verifies the Gmail credentials by acquiring an access token.
+// The gmail.send scope grants no read access to probe the mailbox with.
+func (g *Gmail) HealthCheck(ctx context.Context) error {
+	if _, err := g.tokenManager.GetToken(ctx, g.userID); err != nil {
+		return fmt.Errorf("gmail: health check token: %w", err)
+	}
+	return nil
+}
+
+// gmailMessage is the messages.send response.
+type gmailMessage struct {
+	ID       string `json:"id"`
+	ThreadID string `json:"threadId"`
+}
+
+// gmailErrorResponse is the error body of Google APIs.
+type gmailErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// classifyGmailError classifies a Gmail API error response. Rate limit and
+// sending quota errors are transient even when returned with 403, which
+// otherwise means the user or project lacks permission.
+func classifyGmailError(statusCode int, body []byte) *ProviderError {
+	pe := ClassifyHTTPError("gmail", statusCode, string(body))
+
+	var errResp gmailErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		return pe
+	}
+	pe.Message = errResp.Error.Message
+	for _, e := range errResp.Error.Errors {
+		if gmailQuotaReasons[e.Reason] {
+			pe.Message = e.Reason + ": " + errResp.Error.Message
+			pe.Permanent = false
+			break
+		}
+	}
+	return pe
+}
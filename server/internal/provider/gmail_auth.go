@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	gmailSendScope = "https://www.googleapis.com/auth/gmail.send"
+	jwtBearerGrant = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// googleAssertionLifetime is the longest lifetime Google accepts for
+	// a service account assertion.
+	googleAssertionLifetime = time.Hour
+)
+
+// googleServiceAccount is the part of a service account JSON key file used
+// to sign token requests.
+type googleServiceAccount struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	key *rsa.PrivateKey
+}
+
+// isServiceAccountKey reports whether a Gmail api_key holds a service
+// account JSON key rather than a user's refresh token.
+func isServiceAccountKey(apiKey string) bool {
+	return strings.HasPrefix(strings.TrimSpace(apiKey), "{")
+}
+
+// parseServiceAccountKey parses a service account JSON key file.
+func parseServiceAccountKey(apiKey string) (*googleServiceAccount, error) {
+	var sa googleServiceAccount
+	if err := json.Unmarshal([]byte(apiKey), &sa); err != nil {
+		return nil, fmt.Errorf("parse service account key: %w", err)
+	}
+	if sa.Type != "service_account" {
+		return nil, fmt.Errorf("service account key has type %q, want service_account", sa.Type)
+	}
+	if sa.ClientEmail == "" {
+		return nil, errors.New("service account key has no client_email")
+	}
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account key has no PEM private_key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		// Older keys are PKCS #1.
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("parse service account private_key: %w", err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private_key is not an RSA key")
+	}
+	sa.key = rsaKey
+	if sa.TokenURI == "" {
+		sa.TokenURI = googleTokenURL
+	}
+	return &sa, nil
+}
+
+// assertion returns a signed JWT requesting the gmail.send scope, on
+// behalf of subject when it is set (domain-wide delegation).
+func (sa *googleServiceAccount) assertion(subject string, now time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":   sa.ClientEmail,
+		"scope": gmailSendScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(googleAssertionLifetime).Unix(),
+	}
+	if subject != "" {
+		claims["sub"] = subject
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if sa.PrivateKeyID != "" {
+		token.Header["kid"] = sa.PrivateKeyID
+	}
+	signed, err := token.SignedString(sa.key)
+	if err != nil {
+		return "", fmt.Errorf("sign assertion: %w", err)
+	}
+	return signed, nil
+}
+
+// googleToken is a cached access token.
+type googleToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// GoogleTokenManager acquires and caches Google OAuth2 access tokens for
+// the Gmail API, either for a service account impersonating users of its
+// Workspace domain or from a user's refresh token. Service account tokens
+// are cached per impersonated user.
+type GoogleTokenManager struct {
+	mu      sync.Mutex
+	account *googleServiceAccount // nil for refresh tokens
+
+	clientID     string
+	clientSecret string
+	refreshToken string
+	tokenURL     string
+	client       HTTPClient
+
+	tokens map[string]googleToken
+}
+
+// NewGoogleTokenManager creates a token manager for a Gmail api_key: a
+// service account JSON key, or a refresh token issued to clientID.
+func NewGoogleTokenManager(apiKey, clientID, clientSecret string, client HTTPClient) (*GoogleTokenManager, error) {
+	tm := &GoogleTokenManager{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenURL:     googleTokenURL,
+		client:       client,
+		tokens:       make(map[string]googleToken),
+	}
+	if isServiceAccountKey(apiKey) {
+		sa, err := parseServiceAccountKey(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		tm.account = sa
+		tm.tokenURL = sa.TokenURI
+	} else {
+		tm.refreshToken = apiKey
+	}
+	return tm, nil
+}
+
+// ServiceAccount reports whether tokens are issued to a service account,
+// which can impersonate any user of its domain.
+func (tm *GoogleTokenManager) ServiceAccount() bool {
+	return tm.account != nil
+}
+
+// GetToken returns a valid access token for subject, the user a service
+// account impersonates. Subject is ignored for refresh tokens.
+func (tm *GoogleTokenManager) GetToken(ctx context.Context, subject string) (string, error) {
+	if tm.account == nil {
+		subject = ""
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tok, ok := tm.tokens[subject]; ok && time.Now().Before(tok.expiresAt.Add(-tokenExpiryBuffer)) {
+		return tok.accessToken, nil
+	}
+
+	form := url.Values{}
+	if tm.account != nil {
+		assertion, err := tm.account.assertion(subject, time.Now())
+		if err != nil {
+			return "", fmt.Errorf("gmail auth: %w", err)
+		}
+		form.Set("grant_type", jwtBearerGrant)
+		form.Set("assertion", assertion)
+	} else {
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", tm.clientID)
+		form.Set("client_secret", tm.clientSecret)
+		form.Set("refresh_token", tm.refreshToken)
+	}
+
+	resp, err := tm.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    tm.tokenURL,
+		Headers: map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+		},
+		Body:    []byte(form.Encode()),
+		Context: ctx,
+	})
+	if err != nil {
+		return "", fmt.Errorf("gmail auth: token request: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		// An unauthorized_client error here usually means domain-wide
+		// delegation of the gmail.send scope is missing; invalid_grant
+		// means the user does not exist or the refresh token was revoked.
+		return "", &ProviderError{
+			Provider:   "gmail",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("token request returned status %d: %s", resp.StatusCode, string(resp.Body)),
+			Permanent:  resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 429,
+		}
+	}
+
+	var tokenResp tokenResponse
+	if err := json.Unmarshal(resp.Body, &tokenResp); err != nil {
+		return "", fmt.Errorf("gmail auth: parse token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", errors.New("gmail auth: empty access token in response")
+	}
+
+	tm.tokens[subject] = googleToken{
+		accessToken: tokenResp.AccessToken,
+		expiresAt:   time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}
+	return tokenResp.AccessToken, nil
+}
+
+// InvalidateToken clears the cached token of subject, forcing a refresh on
+// the next call.
+func (tm *GoogleTokenManager) InvalidateToken(subject string) {
+	if tm.account == nil {
+		subject = ""
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	delete(tm.tokens, subject)
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func testServiceAccountKey(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sa, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "sender@project.iam.gserviceaccount.com",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"private_key_id": "key-1",
+		"token_uri":      "https://oauth2.example.com/token",
+	})
+	return string(sa)
+}
+
+// gmailTestClient serves token requests and records the token forms and
+// send requests it receives.
+type gmailTestClient struct {
+	tokenForms []url.Values
+	sends      []*HTTPRequest
+	sendStatus []int
+	sendBody   string
+}
+
+func (c *gmailTestClient) Do(req *HTTPRequest) (*HTTPResponse, error) {
+	if strings.HasSuffix(req.URL, "/token") {
+		form, _ := url.ParseQuery(string(req.Body))
+		c.tokenForms = append(c.tokenForms, form)
+		return &HTTPResponse{StatusCode: 200, Body: []byte(`{"access_token":"tok","expires_in":3600}`)}, nil
+	}
+	c.sends = append(c.sends, req)
+	status := 200
+	if len(c.sendStatus) > 0 {
+		status, c.sendStatus = c.sendStatus[0], c.sendStatus[1:]
+	}
+	body := `{"id":"18c0a","threadId":"18c0b"}`
+	if status != 200 {
+		body = c.sendBody
+	}
+	return &HTTPResponse{StatusCode: status, Body: []byte(body)}, nil
+}
+
+func TestGmail_Send_ServiceAccount(t *testing.T) {
+	client := &gmailTestClient{}
+	g, err := NewGmail(ProviderConfig{Type: "gmail", APIKey: testServiceAccountKey(t)}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := g.Send(context.Background(), &Message{
+		From:     "Alice <alice@example.com>",
+		To:       []string{"bob@example.net"},
+		Bcc:      []string{"audit@example.com"},
+		Subject:  "Hello",
+		TextBody: "hi",
+		Headers:  map[string]string{"Reply-To": "support@example.com", "Message-Id": "<m1@example.com>", "Subject": "Hello"},
+	})
+	if err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if result.ProviderMessageID != "18c0a" || result.Metadata["thread_id"] != "18c0b" {
+		t.Errorf("result = %+v", result)
+	}
+
+	if len(client.tokenForms) != 1 || client.tokenForms[0].Get("grant_type") != jwtBearerGrant {
+		t.Fatalf("token requests = %v", client.tokenForms)
+	}
+	parts := strings.Split(client.tokenForms[0].Get("assertion"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("assertion is not a JWT: %q", client.tokenForms[0].Get("assertion"))
+	}
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "alice@example.com" || claims["scope"] != gmailSendScope || claims["aud"] != "https://oauth2.example.com/token" {
+		t.Errorf("claims = %v, want the sender impersonated", claims)
+	}
+
+	req := client.sends[0]
+	if req.URL != gmailDefaultEndpoint+gmailSendPath || req.Headers["Authorization"] != "Bearer tok" || req.Headers["Content-Type"] != "message/rfc822" {
+		t.Errorf("request = %s %v", req.URL, req.Headers)
+	}
+	raw := string(req.Body)
+	for _, want := range []string{"Bcc: audit@example.com\r\n", "Message-Id: <m1@example.com>\r\n", "Reply-To: support@example.com\r\n", "Subject: Hello\r\n"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("message lacks %q:\n%s", want, raw)
+		}
+	}
+	if strings.Count(raw, "Subject:") != 1 {
+		t.Errorf("expected one Subject header:\n%s", raw)
+	}
+}
+
+func TestGmail_Send_RefreshToken(t *testing.T) {
+	client := &gmailTestClient{}
+	g, err := NewGmail(ProviderConfig{Type: "gmail", APIKey: "1//refresh", ClientID: "cid", ClientSecret: "secret"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := g.Send(context.Background(), &Message{From: "alice@example.com", To: []string{"bob@example.net"}, Raw: []byte("Subject: x\r\n\r\nhi")}); err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+	}
+	if len(client.tokenForms) != 1 {
+		t.Fatalf("expected the token to be cached, got %d token requests", len(client.tokenForms))
+	}
+	form := client.tokenForms[0]
+	if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "1//refresh" || form.Get("client_id") != "cid" {
+		t.Errorf("token form = %v", form)
+	}
+	if string(client.sends[0].Body) != "Subject: x\r\n\r\nhi" {
+		t.Errorf("raw message changed: %q", client.sends[0].Body)
+	}
+}
+
+func TestGmail_Send_RetriesUnauthorized(t *testing.T) {
+	client := &gmailTestClient{sendStatus: []int{401}}
+	g, _ := NewGmail(ProviderConfig{Type: "gmail", APIKey: "1//refresh", ClientID: "cid", ClientSecret: "secret"}, client)
+
+	if _, err := g.Send(context.Background(), &Message{From: "alice@example.com", To: []string{"bob@example.net"}, TextBody: "hi"}); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if len(client.tokenForms) != 2 || len(client.sends) != 2 {
+		t.Errorf("token requests = %d, sends = %d, want 2 each", len(client.tokenForms), len(client.sends))
+	}
+}
+
+func TestGmail_Send_QuotaErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		permanent bool
+	}{
+		{"user rate limit", 403, `{"error":{"code":403,"message":"User-rate limit exceeded","errors":[{"reason":"userRateLimitExceeded"}]}}`, false},
+		{"daily sending limit", 403, `{"error":{"code":403,"message":"Daily user sending limit exceeded","errors":[{"reason":"dailyLimitExceeded"}]}}`, false},
+		{"too many requests", 429, `{"error":{"code":429,"message":"Too many concurrent requests for user","errors":[{"reason":"rateLimitExceeded"}]}}`, false},
+		{"permission denied", 403, `{"error":{"code":403,"message":"Delegation denied for alice@example.com","errors":[{"reason":"forbidden"}]}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &gmailTestClient{sendStatus: []int{tt.status}, sendBody: tt.body}
+			g, _ := NewGmail(ProviderConfig{Type: "gmail", APIKey: "1//refresh", ClientID: "cid", ClientSecret: "secret"}, client)
+
+			_, err := g.Send(context.Background(), &Message{From: "alice@example.com", To: []string{"bob@example.net"}, TextBody: "hi"})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.permanent)
+			}
+		})
+	}
+}
+
+func TestGmail_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ProviderConfig
+		wantErr string
+	}{
+		{"service account", ProviderConfig{Type: "gmail", APIKey: testServiceAccountKey(t)}, ""},
+		{"refresh token", ProviderConfig{Type: "gmail", APIKey: "1//refresh", ClientID: "cid", ClientSecret: "secret"}, ""},
+		{"no key", ProviderConfig{Type: "gmail"}, "api_key"},
+		{"refresh token without client", ProviderConfig{Type: "gmail", APIKey: "1//refresh"}, "client_id"},
+		{"bad service account", ProviderConfig{Type: "gmail", APIKey: `{"type":"service_account","client_email":"x"}`}, "private_key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,14 +2,25 @@ package provider
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
 	"time"
 )
 
 // DefaultHTTPClient wraps net/http.Client to implement the provider.HTTPClient interface.
 type DefaultHTTPClient struct {
 	client *http.Client
+
+	// proxied caches the clients returned by WithProxy so providers sharing
+	// a proxy also share its connection pool.
+	mu      sync.Mutex
+	proxied map[string]*DefaultHTTPClient
 }
 
 // NewHTTPClient creates a DefaultHTTPClient with the given timeout.
@@ -19,10 +30,89 @@ func NewHTTPClient(timeout time.Duration) *DefaultHTTPClient {
 	}
 }
 
+// NewHTTPClientWithDialer creates a DefaultHTTPClient whose connections are
+// opened with dial, such as a caching resolver's DialContext.
+func NewHTTPClientWithDialer(timeout time.Duration, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *DefaultHTTPClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dial
+	return &DefaultHTTPClient{
+		client: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// ParseProxyURL validates an egress proxy URL. The http, https, socks5 and
+// socks5h schemes are supported; credentials may be given in the userinfo.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q has no host", raw)
+	}
+	return u, nil
+}
+
+// WithProxy returns a client that sends requests through the egress proxy
+// at proxyURL, keeping c's timeout and dialer. The dialer is used to reach
+// the proxy itself.
+func (c *DefaultHTTPClient) WithProxy(proxyURL string) (*DefaultHTTPClient, error) {
+	u, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if proxied, ok := c.proxied[proxyURL]; ok {
+		return proxied, nil
+	}
+
+	base, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.Proxy = http.ProxyURL(u)
+
+	proxied := &DefaultHTTPClient{
+		client: &http.Client{Timeout: c.client.Timeout, Transport: transport},
+	}
+	if c.proxied == nil {
+		c.proxied = make(map[string]*DefaultHTTPClient)
+	}
+	c.proxied[proxyURL] = proxied
+	return proxied, nil
+}
+
 // Do converts a provider.HTTPRequest to a net/http request, executes it,
 // and returns the result as a provider.HTTPResponse.
+// Requests made with a context from WithConnectionAudit record their
+// connection, and those made with a context from WithCapture record the
+// exchange.
 func (c *DefaultHTTPClient) Do(req *HTTPRequest) (*HTTPResponse, error) {
-	httpReq, err := http.NewRequest(req.Method, req.URL, bytes.NewReader(req.Body))
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	start := time.Now()
+	resp, err := c.do(ctx, req)
+	recordExchange(ctx, req, resp, err, start)
+	return resp, err
+}
+
+func (c *DefaultHTTPClient) do(ctx context.Context, req *HTTPRequest) (*HTTPResponse, error) {
+	trace := newConnTrace(ctx, req.URL)
+	if trace != nil {
+		ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
 	if err != nil {
 		return nil, err
 	}
@@ -31,6 +121,9 @@ func (c *DefaultHTTPClient) Do(req *HTTPRequest) (*HTTPResponse, error) {
 	}
 
 	resp, err := c.client.Do(httpReq)
+	if trace != nil {
+		trace.finish()
+	}
 	if err != nil {
 		return nil, err
 	}
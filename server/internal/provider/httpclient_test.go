@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDefaultHTTPClient_WithProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute target URL.
+		if r.URL.Host != "api.provider.example" {
+			t.Errorf("expected request for api.provider.example, got %q", r.URL.Host)
+		}
+		proxied.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "ok")
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(5 * time.Second).WithProxy(proxy.URL)
+	if err != nil {
+		t.Fatalf("WithProxy: %v", err)
+	}
+	resp, err := client.Do(&HTTPRequest{Method: http.MethodPost, URL: "http://api.provider.example/v3/mail/send"})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted || string(resp.Body) != "ok" {
+		t.Errorf("unexpected response: %d %q", resp.StatusCode, resp.Body)
+	}
+	if proxied.Load() != 1 {
+		t.Errorf("expected request to go through the proxy, got %d", proxied.Load())
+	}
+}
+
+func TestDefaultHTTPClient_WithProxy_ReusesClient(t *testing.T) {
+	base := NewHTTPClient(5 * time.Second)
+	a, err := base.WithProxy("http://proxy.internal:3128")
+	if err != nil {
+		t.Fatalf("WithProxy: %v", err)
+	}
+	b, _ := base.WithProxy("http://proxy.internal:3128")
+	if a != b {
+		t.Error("expected the same client for the same proxy")
+	}
+	if a.client.Timeout != 5*time.Second {
+		t.Errorf("expected timeout to carry over, got %v", a.client.Timeout)
+	}
+}
+
+func TestParseProxyURL(t *testing.T) {
+	for _, raw := range []string{"http://proxy:3128", "https://proxy:443", "socks5://u:p@proxy:1080", "socks5h://proxy:1080"} {
+		if _, err := ParseProxyURL(raw); err != nil {
+			t.Errorf("ParseProxyURL(%q): unexpected error %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"proxy:3128", "ftp://proxy", "socks5://", "://bad"} {
+		if _, err := ParseProxyURL(raw); err == nil {
+			t.Errorf("ParseProxyURL(%q): expected error", raw)
+		}
+	}
+}
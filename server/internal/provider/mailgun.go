@@ -27,28 +27,37 @@ type Mailgun struct {
 
 // NewMailgun creates a Mailgun provider from the given configuration.
 func NewMailgun(cfg ProviderConfig, client HTTPClient) *Mailgun {
-	endpoint := cfg.Endpoint
-	if endpoint == "" {
-		endpoint = mailgunDefaultEndpoint
-	}
 	return &Mailgun{
 		apiKey:   cfg.APIKey,
 		domain:   cfg.Domain,
-		endpoint: endpoint,
+		endpoint: apiEndpoint(cfg, mailgunRegionEndpoints, mailgunDefaultEndpoint),
 		client:   client,
 	}
 }
 
 func (m *Mailgun) GetName() string { return "mailgun" }
 
+// SendsRaw implements RawSender.
+func (m *Mailgun) SendsRaw() bool { return true }
+
 // Send delivers a message via the Mailgun messages API.
 // When attachments are present, it uses multipart/form-data encoding;
 // otherwise it uses the simpler application/x-www-form-urlencoded format.
+// A signed message (Message.Raw) is posted to the messages.mime API.
 func (m *Mailgun) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
 	var reqBody []byte
 	var contentType string
+	path := "messages"
 
-	if len(msg.Attachments) > 0 {
+	if msg.Raw != nil {
+		body, ct, err := m.buildMIMEForm(msg)
+		if err != nil {
+			return nil, fmt.Errorf("mailgun: build mime form: %w", err)
+		}
+		reqBody = body
+		contentType = ct
+		path = "messages.mime"
+	} else if len(msg.Attachments) > 0 {
 		body, ct, err := m.buildMultipartForm(msg)
 		if err != nil {
 			return nil, fmt.Errorf("mailgun: build multipart form: %w", err)
@@ -63,12 +72,13 @@ func (m *Mailgun) Send(ctx context.Context, msg *Message) (*DeliveryResult, erro
 
 	resp, err := m.client.Do(&HTTPRequest{
 		Method: "POST",
-		URL:    fmt.Sprintf("%s/v3/%s/messages", m.endpoint, m.domain),
+		URL:    fmt.Sprintf("%s/v3/%s/%s", m.endpoint, m.domain, path),
 		Headers: map[string]string{
 			"Authorization": "Basic " + basicAuth("api", m.apiKey),
 			"Content-Type":  contentType,
 		},
-		Body: reqBody,
+		Body:    reqBody,
+		Context: ctx,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("mailgun: send request: %w", err)
@@ -102,12 +112,13 @@ func (m *Mailgun) HealthCheck(ctx context.Context) error {
 		Headers: map[string]string{
 			"Authorization": "Basic " + basicAuth("api", m.apiKey),
 		},
+		Context: ctx,
 	})
 	if err != nil {
 		return fmt.Errorf("mailgun: health check request: %w", err)
 	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("mailgun: health check returned status %d", resp.StatusCode)
+		return healthCheckError("mailgun", resp.StatusCode, resp.Body)
 	}
 	return nil
 }
@@ -121,6 +132,9 @@ func (m *Mailgun) buildForm(msg *Message) url.Values {
 	form := url.Values{}
 	form.Set("from", msg.From)
 	form.Set("to", strings.Join(msg.To, ","))
+	if len(msg.Bcc) > 0 {
+		form.Set("bcc", strings.Join(msg.Bcc, ","))
+	}
 	form.Set("subject", msg.Subject)
 
 	// Prefer parsed text body; fall back to raw Body.
@@ -137,9 +151,42 @@ func (m *Mailgun) buildForm(msg *Message) url.Values {
 	for key, value := range msg.Headers {
 		form.Set("h:"+key, value)
 	}
+	for _, tag := range mailgunTags(msg.Tags) {
+		form.Add("o:tag", tag)
+	}
+	for key, value := range msg.Metadata {
+		form.Set("v:"+key, value)
+	}
 	return form
 }
 
+// buildMIMEForm creates the multipart/form-data body of a messages.mime
+// request: the recipients, including blind copies, and the message as is.
+func (m *Mailgun) buildMIMEForm(msg *Message) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	writer.WriteField("to", strings.Join(append(append([]string(nil), msg.To...), msg.Bcc...), ","))
+	for _, tag := range mailgunTags(msg.Tags) {
+		writer.WriteField("o:tag", tag)
+	}
+	for key, value := range msg.Metadata {
+		writer.WriteField("v:"+key, value)
+	}
+
+	part, err := writer.CreateFormFile("message", "message.mime")
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(msg.Raw); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
 // buildMultipartForm creates a multipart/form-data request body that includes
 // form fields and file attachments.
 func (m *Mailgun) buildMultipartForm(msg *Message) ([]byte, string, error) {
@@ -149,6 +196,9 @@ func (m *Mailgun) buildMultipartForm(msg *Message) ([]byte, string, error) {
 	// Add form fields.
 	writer.WriteField("from", msg.From)
 	writer.WriteField("to", strings.Join(msg.To, ","))
+	if len(msg.Bcc) > 0 {
+		writer.WriteField("bcc", strings.Join(msg.Bcc, ","))
+	}
 	writer.WriteField("subject", msg.Subject)
 
 	text := msg.TextBody
@@ -164,6 +214,12 @@ func (m *Mailgun) buildMultipartForm(msg *Message) ([]byte, string, error) {
 	for key, value := range msg.Headers {
 		writer.WriteField("h:"+key, value)
 	}
+	for _, tag := range mailgunTags(msg.Tags) {
+		writer.WriteField("o:tag", tag)
+	}
+	for key, value := range msg.Metadata {
+		writer.WriteField("v:"+key, value)
+	}
 
 	// Add attachments.
 	for _, att := range msg.Attachments {
@@ -193,6 +249,17 @@ func (m *Mailgun) buildMultipartForm(msg *Message) ([]byte, string, error) {
 	return buf.Bytes(), writer.FormDataContentType(), nil
 }
 
+// mailgunMaxTags is the number of tags Mailgun accepts per message.
+const mailgunMaxTags = 3
+
+// mailgunTags returns the tags Mailgun accepts; extra tags are dropped.
+func mailgunTags(tags []string) []string {
+	if len(tags) > mailgunMaxTags {
+		return tags[:mailgunMaxTags]
+	}
+	return tags
+}
+
 // basicAuth encodes credentials as base64 for HTTP Basic Authentication.
 func basicAuth(username, password string) string {
 	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
@@ -217,6 +217,39 @@ func TestMailgun_Send_UsesURLEncodedWithoutAttachments(t *testing.T) {
 	}
 }
 
+func TestMailgun_Send_SignedMessageUsesMIMEAPI(t *testing.T) {
+	var captured *HTTPRequest
+	client := &mockHTTPClient2{
+		doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+			captured = req
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`{"id":"<msg@mg>","message":"Queued"}`)}, nil
+		},
+	}
+	mg := NewMailgun(ProviderConfig{Type: "mailgun", APIKey: "key-test", Domain: "mg.example.com"}, client)
+
+	msg := &Message{
+		From:     "sender@example.com",
+		To:       []string{"a@example.com"},
+		Bcc:      []string{"archive@example.com"},
+		TextBody: "hello",
+		Raw:      []byte("Content-Type: multipart/signed; boundary=b\r\n\r\n--b--\r\n"),
+	}
+	if _, err := mg.Send(nil, msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if !strings.HasSuffix(captured.URL, "/v3/mg.example.com/messages.mime") {
+		t.Errorf("expected the messages.mime API, got %s", captured.URL)
+	}
+	body := string(captured.Body)
+	if !strings.Contains(body, "a@example.com,archive@example.com") || !strings.Contains(body, "multipart/signed") {
+		t.Errorf("expected recipients and the raw message in the form, got %s", body)
+	}
+	if strings.Contains(body, "hello") {
+		t.Error("expected the parsed body not to be sent")
+	}
+}
+
 // mockHTTPClient2 is a flexible mock for HTTP tests.
 type mockHTTPClient2 struct {
 	doFn func(req *HTTPRequest) (*HTTPResponse, error)
@@ -225,3 +258,24 @@ type mockHTTPClient2 struct {
 func (m *mockHTTPClient2) Do(req *HTTPRequest) (*HTTPResponse, error) {
 	return m.doFn(req)
 }
+
+func TestMailgun_buildForm_TagsAndMetadata(t *testing.T) {
+	mg := &Mailgun{}
+	msg := &Message{
+		From:     "sender@example.com",
+		To:       []string{"a@example.com"},
+		Subject:  "Test",
+		TextBody: "body",
+		Tags:     []string{"one", "two", "three", "four"},
+		Metadata: map[string]string{"customer_id": "42"},
+	}
+
+	form := mg.buildForm(msg)
+
+	if tags := form["o:tag"]; len(tags) != 3 || tags[0] != "one" {
+		t.Errorf("expected the first 3 tags, got %v", tags)
+	}
+	if form.Get("v:customer_id") != "42" {
+		t.Errorf("expected v:customer_id=42, got %q", form.Get("v:customer_id"))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -26,10 +27,7 @@ type MSGraph struct {
 
 // NewMSGraph creates a Microsoft Graph provider from the given configuration.
 func NewMSGraph(cfg ProviderConfig, client HTTPClient) *MSGraph {
-	endpoint := cfg.Endpoint
-	if endpoint == "" {
-		endpoint = graphDefaultEndpoint
-	}
+	endpoint := apiEndpoint(cfg, nil, graphDefaultEndpoint)
 	tm := NewTokenManager(cfg.TenantID, cfg.ClientID, cfg.ClientSecret, client)
 	return &MSGraph{
 		userID:       cfg.UserID,
@@ -41,10 +39,13 @@ func NewMSGraph(cfg ProviderConfig, client HTTPClient) *MSGraph {
 
 func (m *MSGraph) GetName() string { return "msgraph" }
 
+// SendsRaw implements RawSender.
+func (m *MSGraph) SendsRaw() bool { return true }
+
 // Send delivers a message via the Microsoft Graph sendMail API.
 // On 401 responses, it invalidates the token and retries once.
 func (m *MSGraph) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
-	result, err := m.sendWithToken(msg)
+	result, err := m.sendWithToken(ctx, msg)
 	if err == nil {
 		return result, nil
 	}
@@ -53,22 +54,35 @@ func (m *MSGraph) Send(ctx context.Context, msg *Message) (*DeliveryResult, erro
 	var pe *ProviderError
 	if isProviderError(err, &pe) && pe.StatusCode == 401 {
 		m.tokenManager.InvalidateToken()
-		return m.sendWithToken(msg)
+		return m.sendWithToken(ctx, msg)
 	}
 
 	return nil, err
 }
 
-func (m *MSGraph) sendWithToken(msg *Message) (*DeliveryResult, error) {
+func (m *MSGraph) sendWithToken(ctx context.Context, msg *Message) (*DeliveryResult, error) {
 	token, err := m.tokenManager.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("msgraph: acquire token: %w", err)
 	}
 
-	payload := m.buildPayload(msg)
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("msgraph: marshal request: %w", err)
+	var body []byte
+	contentType := "application/json"
+	if msg.Raw != nil {
+		// sendMail takes a MIME message base64 encoded as text/plain. Graph
+		// reads the recipients from its headers and removes the Bcc header.
+		raw := msg.Raw
+		if len(msg.Bcc) > 0 {
+			raw = append([]byte("Bcc: "+strings.Join(msg.Bcc, ", ")+"\r\n"), raw...)
+		}
+		body = []byte(base64.StdEncoding.EncodeToString(raw))
+		contentType = "text/plain"
+	} else {
+		payload := m.buildPayload(msg)
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("msgraph: marshal request: %w", err)
+		}
 	}
 
 	sendURL := m.endpoint + fmt.Sprintf(graphSendMailPathFmt, m.userID)
@@ -77,9 +91,10 @@ func (m *MSGraph) sendWithToken(msg *Message) (*DeliveryResult, error) {
 		URL:    sendURL,
 		Headers: map[string]string{
 			"Authorization": "Bearer " + token,
-			"Content-Type":  "application/json",
+			"Content-Type":  contentType,
 		},
-		Body: body,
+		Body:    body,
+		Context: ctx,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("msgraph: send request: %w", err)
@@ -114,12 +129,13 @@ func (m *MSGraph) HealthCheck(ctx context.Context) error {
 		Headers: map[string]string{
 			"Authorization": "Bearer " + token,
 		},
+		Context: ctx,
 	})
 	if err != nil {
 		return fmt.Errorf("msgraph: health check request: %w", err)
 	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("msgraph: health check returned status %d", resp.StatusCode)
+		return healthCheckError("msgraph", resp.StatusCode, resp.Body)
 	}
 	return nil
 }
@@ -138,7 +154,9 @@ type graphMessage struct {
 	Subject      string             `json:"subject"`
 	Body         graphBody          `json:"body"`
 	ToRecipients []graphRecipient   `json:"toRecipients"`
+	BccRecipients []graphRecipient  `json:"bccRecipients,omitempty"`
 	From         *graphRecipient    `json:"from,omitempty"`
+	ReplyTo      []graphRecipient   `json:"replyTo,omitempty"`
 	Attachments  []graphAttachment  `json:"attachments,omitempty"`
 }
 
@@ -195,6 +213,17 @@ func (m *MSGraph) buildPayload(msg *Message) graphSendMailPayload {
 		},
 	}
 
+	for _, addr := range msg.Bcc {
+		gMsg.BccRecipients = append(gMsg.BccRecipients, graphRecipient{
+			EmailAddress: graphEmailAddress{Address: addr},
+		})
+	}
+	for _, addr := range msg.ReplyTo() {
+		gMsg.ReplyTo = append(gMsg.ReplyTo, graphRecipient{
+			EmailAddress: graphEmailAddress{Address: addr},
+		})
+	}
+
 	// Attach files if present.
 	for _, att := range msg.Attachments {
 		gMsg.Attachments = append(gMsg.Attachments, graphAttachment{
@@ -81,7 +81,14 @@ func (tm *TokenManager) refreshToken() (string, error) {
 	}
 
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("msgraph auth: token request returned status %d: %s", resp.StatusCode, string(resp.Body))
+		// invalid_client means a wrong client secret; it is permanent
+		// like other 4xx responses except 429.
+		return "", &ProviderError{
+			Provider:   "msgraph auth",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("token request returned status %d: %s", resp.StatusCode, string(resp.Body)),
+			Permanent:  resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 429,
+		}
 	}
 
 	var tokenResp tokenResponse
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Plugins extend delivery without forking: they register custom provider
+// types and message-processing hooks, usually from the Register function of
+// a Go plugin loaded at startup (see internal/plugin).
+
+// Factory creates a provider of a custom type from its configuration.
+type Factory func(cfg ProviderConfig, client HTTPClient) (Provider, error)
+
+// Hook is a message-processing stage run around every ESP delivery. Every
+// function is optional.
+type Hook struct {
+	// Name identifies the hook in logs and errors.
+	Name string
+	// PreRoute runs before the provider is resolved and may modify msg.
+	// An error fails the delivery attempt, which is retried unless the
+	// error is a permanent *ProviderError.
+	PreRoute func(ctx context.Context, msg *Message) error
+	// PreSend runs after the provider is resolved, just before Send. Its
+	// errors are handled like PreRoute's.
+	PreSend func(ctx context.Context, p Provider, msg *Message) error
+	// PostSend observes the outcome of Send: result on success, sendErr
+	// otherwise.
+	PostSend func(ctx context.Context, p Provider, msg *Message, result *DeliveryResult, sendErr error)
+}
+
+// builtinTypes are the provider types NewProvider implements itself.
+var builtinTypes = []string{"sendgrid", "ses", "mailgun", "msgraph", "gmail", "resend", "brevo", "zeptomail", "ews", "stdout", "file"}
+
+var (
+	pluginMu  sync.RWMutex
+	factories = make(map[string]Factory)
+	hooks     []Hook
+)
+
+// RegisterType makes a custom provider type available to NewProvider. Like
+// database/sql.Register, it panics when name is empty, a built-in type or
+// already registered.
+func RegisterType(name string, factory Factory) {
+	pluginMu.Lock()
+	defer pluginMu.Unlock()
+	if name == "" || factory == nil {
+		panic("provider: RegisterType needs a name and a factory")
+	}
+	if slices.Contains(builtinTypes, name) {
+		panic("provider: RegisterType of built-in type " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("provider: RegisterType called twice for type " + name)
+	}
+	factories[name] = factory
+}
+
+// RegisteredTypes returns the custom provider types, sorted.
+func RegisteredTypes() []string {
+	pluginMu.RLock()
+	defer pluginMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func lookupFactory(name string) (Factory, bool) {
+	pluginMu.RLock()
+	defer pluginMu.RUnlock()
+	f, ok := factories[name]
+	return f, ok
+}
+
+// RegisterHook adds h to the hooks run around every delivery, after the
+// hooks registered before it. It panics when h has no name.
+func RegisterHook(h Hook) {
+	if h.Name == "" {
+		panic("provider: RegisterHook needs a name")
+	}
+	pluginMu.Lock()
+	defer pluginMu.Unlock()
+	hooks = append(hooks, h)
+}
+
+// Hooks returns the registered hooks in registration order.
+func Hooks() []Hook {
+	pluginMu.RLock()
+	defer pluginMu.RUnlock()
+	return slices.Clone(hooks)
+}
+
+// RunPreRoute runs the PreRoute hooks in order and stops at the first
+// error.
+func RunPreRoute(ctx context.Context, msg *Message) error {
+	for _, h := range Hooks() {
+		if h.PreRoute == nil {
+			continue
+		}
+		if err := h.PreRoute(ctx, msg); err != nil {
+			return fmt.Errorf("hook %s: pre-route: %w", h.Name, err)
+		}
+	}
+	return nil
+}
+
+// RunPreSend runs the PreSend hooks in order and stops at the first error.
+func RunPreSend(ctx context.Context, p Provider, msg *Message) error {
+	for _, h := range Hooks() {
+		if h.PreSend == nil {
+			continue
+		}
+		if err := h.PreSend(ctx, p, msg); err != nil {
+			return fmt.Errorf("hook %s: pre-send: %w", h.Name, err)
+		}
+	}
+	return nil
+}
+
+// RunPostSend runs every PostSend hook.
+func RunPostSend(ctx context.Context, p Provider, msg *Message, result *DeliveryResult, sendErr error) {
+	for _, h := range Hooks() {
+		if h.PostSend != nil {
+			h.PostSend(ctx, p, msg, result, sendErr)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// resetPlugins clears the registered types and hooks when the test ends.
+func resetPlugins(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		pluginMu.Lock()
+		defer pluginMu.Unlock()
+		factories = make(map[string]Factory)
+		hooks = nil
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	resetPlugins(t)
+
+	var gotCfg ProviderConfig
+	RegisterType("custom", func(cfg ProviderConfig, _ HTTPClient) (Provider, error) {
+		gotCfg = cfg
+		return NewStdout(cfg), nil
+	})
+
+	p, err := NewProvider(ProviderConfig{Type: "custom", Options: map[string]string{"url": "https://example.com"}}, nil)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if p.GetName() != "stdout" {
+		t.Errorf("provider = %q, want the factory's", p.GetName())
+	}
+	if gotCfg.Options["url"] != "https://example.com" {
+		t.Errorf("factory options = %v, want the config's", gotCfg.Options)
+	}
+	if got := RegisteredTypes(); !slices.Equal(got, []string{"custom"}) {
+		t.Errorf("RegisteredTypes() = %v, want [custom]", got)
+	}
+
+	if _, err := NewProvider(ProviderConfig{Type: "unregistered"}, nil); err == nil {
+		t.Error("NewProvider() of an unregistered type succeeded")
+	}
+}
+
+func TestRegisterType_Panics(t *testing.T) {
+	resetPlugins(t)
+	factory := func(cfg ProviderConfig, _ HTTPClient) (Provider, error) { return NewStdout(cfg), nil }
+	RegisterType("custom", factory)
+
+	for _, name := range []string{"", "sendgrid", "custom"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterType(%q) did not panic", name)
+				}
+			}()
+			RegisterType(name, factory)
+		}()
+	}
+}
+
+func TestEspToConfig_Plugin(t *testing.T) {
+	cfg, err := espToConfig(&storage.EspProvider{
+		ProviderType: storage.ProviderTypePlugin,
+		SmtpConfig:   []byte(`{"plugin": "webhook", "options": {"url": "https://hooks.example.com"}}`),
+	})
+	if err != nil {
+		t.Fatalf("espToConfig() error = %v", err)
+	}
+	if cfg.Type != "webhook" || cfg.Options["url"] != "https://hooks.example.com" {
+		t.Errorf("config = %+v, want type webhook with its options", cfg)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	resetPlugins(t)
+
+	var calls []string
+	RegisterHook(Hook{
+		Name: "first",
+		PreRoute: func(_ context.Context, msg *Message) error {
+			calls = append(calls, "first pre-route")
+			msg.To = append(msg.To, "bcc@example.com")
+			return nil
+		},
+		PostSend: func(_ context.Context, _ Provider, _ *Message, result *DeliveryResult, sendErr error) {
+			calls = append(calls, "first post-send "+result.ProviderMessageID)
+		},
+	})
+	RegisterHook(Hook{
+		Name: "second",
+		PreRoute: func(_ context.Context, _ *Message) error {
+			calls = append(calls, "second pre-route")
+			return nil
+		},
+		PreSend: func(_ context.Context, _ Provider, _ *Message) error {
+			return errors.New("blocked")
+		},
+	})
+
+	msg := &Message{To: []string{"rcpt@example.com"}}
+	if err := RunPreRoute(context.Background(), msg); err != nil {
+		t.Fatalf("RunPreRoute() error = %v", err)
+	}
+	if len(msg.To) != 2 {
+		t.Errorf("recipients = %v, want the hook's addition", msg.To)
+	}
+
+	err := RunPreSend(context.Background(), NewStdout(ProviderConfig{}), msg)
+	if err == nil || !strings.Contains(err.Error(), "hook second: pre-send: blocked") {
+		t.Errorf("RunPreSend() error = %v, want the second hook's error", err)
+	}
+
+	RunPostSend(context.Background(), nil, msg, &DeliveryResult{ProviderMessageID: "pm-1"}, nil)
+	want := []string{"first pre-route", "second pre-route", "first post-send pm-1"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
@@ -2,7 +2,11 @@ package provider
 
 import (
 	"context"
+	"net/mail"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Provider defines the interface for sending email through an ESP.
@@ -15,6 +19,26 @@ type Provider interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// Identified is implemented by providers resolved from an esp_providers row,
+// so deliveries can be attributed to the configured provider.
+type Identified interface {
+	ProviderID() uuid.UUID
+}
+
+// RawSender is implemented by providers that can send Message.Raw.
+type RawSender interface {
+	SendsRaw() bool
+}
+
+// SendsRaw reports whether p sends Message.Raw as is.
+func SendsRaw(p Provider) bool {
+	if ip, ok := p.(*identifiedProvider); ok {
+		p = ip.Provider
+	}
+	rs, ok := p.(RawSender)
+	return ok && rs.SendsRaw()
+}
+
 // HTTPClient abstracts HTTP operations for testability.
 type HTTPClient interface {
 	Do(req *HTTPRequest) (*HTTPResponse, error)
@@ -26,6 +50,10 @@ type HTTPRequest struct {
 	URL     string
 	Headers map[string]string
 	Body    []byte
+	// Context, when set, cancels the request and carries the connection
+	// audit of WithConnectionAudit. A nil Context means
+	// context.Background().
+	Context context.Context
 }
 
 // HTTPResponse represents an HTTP response from a provider API.
@@ -41,12 +69,34 @@ type Message struct {
 	TenantID    string
 	From        string
 	To          []string
+	Bcc         []string // blind copies, delivered to but not shown in the message
 	Subject     string
 	Headers     map[string]string
-	Body        []byte       // raw body (kept for backward compat, used by stdout/file)
-	TextBody    string       // parsed plain text body
-	HTMLBody    string       // parsed HTML body
-	Attachments []Attachment // parsed attachments
+	Body        []byte            // raw body (kept for backward compat, used by stdout/file)
+	TextBody    string            // parsed plain text body
+	HTMLBody    string            // parsed HTML body
+	Attachments []Attachment      // parsed attachments
+	Tags        []string          // X-SMTPProxy-Tag values, forwarded where supported
+	Metadata    map[string]string // X-SMTPProxy-Metadata pairs, forwarded where supported
+	ReturnPath  string            // bounce address, used by providers that accept one per message
+	// Raw, when set, is the complete signed or encrypted message. Providers
+	// that implement RawSender send it as is instead of building one from
+	// the fields above; the envelope still comes from From, To and Bcc.
+	Raw []byte
+}
+
+// ReplyTo returns the addresses of the Reply-To header, or nil when the
+// header is missing or cannot be parsed.
+func (m *Message) ReplyTo() []string {
+	list, err := mail.ParseAddressList(m.Headers["Reply-To"])
+	if err != nil {
+		return nil
+	}
+	addrs := make([]string, len(list))
+	for i, a := range list {
+		addrs[i] = a.Address
+	}
+	return addrs
 }
 
 // Attachment represents a single MIME attachment or inline part.
@@ -74,3 +124,30 @@ const (
 	StatusFailed  DeliveryStatus = "failed"
 	StatusBounced DeliveryStatus = "bounced"
 )
+
+// apiReservedHeaders are the headers the JSON send APIs take as fields of
+// their own and reject as custom headers.
+var apiReservedHeaders = []string{
+	"From", "To", "Cc", "Bcc", "Subject", "Reply-To",
+	"Mime-Version", "Content-Type", "Content-Transfer-Encoding",
+}
+
+// headersExcept returns the headers of m without the named ones, for APIs
+// that take those as fields of their own. m.Headers is left unchanged.
+func (m *Message) headersExcept(names ...string) map[string]string {
+	if len(m.Headers) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(m.Headers))
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	for _, name := range names {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				delete(headers, k)
+			}
+		}
+	}
+	return headers
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned when a request would reach a loopback,
+// private, link-local or unspecified address, such as the API server's own
+// admin listeners or a cloud metadata endpoint.
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// publicAddr reports whether ip may be reached on behalf of a user.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// PublicDialer returns a dial function that refuses connections to
+// non-public addresses. The address is checked after name resolution, so a
+// hostname that resolves to a private address is refused too.
+func PublicDialer(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrNonPublicAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+	return dialer.DialContext
+}
+
+// ValidatePublicURL checks that the host of raw is not localhost or a
+// non-public IP address. Hostnames are not resolved; PublicDialer refuses
+// those that resolve to a non-public address when they are dialed.
+func ValidatePublicURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !publicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, ip)
+	}
+	return nil
+}
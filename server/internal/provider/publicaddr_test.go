@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestValidatePublicURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		public bool
+	}{
+		{"https://api.sendgrid.com", true},
+		{"https://203.0.113.10/v3", true},
+		{"http://localhost:8080", false},
+		{"http://api.localhost.", false},
+		{"http://127.0.0.1:8080", false},
+		{"http://[::1]:8080", false},
+		{"http://10.1.2.3", false},
+		{"http://192.168.0.1", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]", false},
+		{"http://[::ffff:127.0.0.1]", false},
+		{"http://0.0.0.0", false},
+	}
+	for _, tt := range tests {
+		err := ValidatePublicURL(tt.url)
+		if (err == nil) != tt.public {
+			t.Errorf("ValidatePublicURL(%q) = %v, want public %v", tt.url, err, tt.public)
+		}
+		if err != nil && !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("ValidatePublicURL(%q) = %v, want ErrNonPublicAddress", tt.url, err)
+		}
+	}
+}
+
+func TestPublicDialer_RefusesLoopback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	dial := PublicDialer(time.Second)
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	if err == nil {
+		conn.Close()
+		t.Fatal("dial to loopback succeeded")
+	}
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("dial error = %v, want ErrNonPublicAddress", err)
+	}
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	resendDefaultEndpoint = "https://api.resend.com"
+	resendSendPath        = "/emails"
+)
+
+// Resend implements the Provider interface for the Resend API.
+type Resend struct {
+	apiKey   string
+	endpoint string
+	client   HTTPClient
+}
+
+// NewResend creates a Resend provider from the given configuration.
+func NewResend(cfg ProviderConfig, client HTTPClient) *Resend {
+	return &Resend{
+		apiKey:   cfg.APIKey,
+		endpoint: apiEndpoint(cfg, nil, resendDefaultEndpoint),
+		client:   client,
+	}
+}
+
+func (r *Resend) GetName() string { return "resend" }
+
+// Send delivers a message via the Resend emails API.
+func (r *Resend) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
+	body, err := json.Marshal(r.buildPayload(msg))
+	if err != nil {
+		return nil, fmt.Errorf("resend: marshal request: %w", err)
+	}
+
+	resp, err := r.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    r.endpoint + resendSendPath,
+		Headers: map[string]string{
+			"Authorization": "Bearer " + r.apiKey,
+			"Content-Type":  "application/json",
+		},
+		Body:    body,
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("resend: send request: %w", err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var rsResp resendResponse
+		_ = json.Unmarshal(resp.Body, &rsResp)
+		return &DeliveryResult{
+			ProviderMessageID: rsResp.ID,
+			Status:            StatusSent,
+			Timestamp:         time.Now(),
+			Metadata: map[string]string{
+				"status_code": fmt.Sprintf("%d", resp.StatusCode),
+			},
+		}, nil
+	}
+
+	return nil, ClassifyHTTPError("resend", resp.StatusCode, string(resp.Body))
+}
+
+// HealthCheck verifies Resend API connectivity by listing the domains. A
+// key restricted to sending cannot list them, but is reported valid.
+func (r *Resend) HealthCheck(ctx context.Context) error {
+	resp, err := r.client.Do(&HTTPRequest{
+		Method: "GET",
+		URL:    r.endpoint + "/domains",
+		Headers: map[string]string{
+			"Authorization": "Bearer " + r.apiKey,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("resend: health check request: %w", err)
+	}
+	if resp.StatusCode == 401 && strings.Contains(string(resp.Body), "restricted_api_key") {
+		return nil
+	}
+	if resp.StatusCode != 200 {
+		return healthCheckError("resend", resp.StatusCode, resp.Body)
+	}
+	return nil
+}
+
+// resendPayload matches the Resend send email JSON schema.
+type resendPayload struct {
+	From        string             `json:"from"`
+	To          []string           `json:"to"`
+	Bcc         []string           `json:"bcc,omitempty"`
+	ReplyTo     []string           `json:"reply_to,omitempty"`
+	Subject     string             `json:"subject"`
+	HTML        string             `json:"html,omitempty"`
+	Text        string             `json:"text,omitempty"`
+	Headers     map[string]string  `json:"headers,omitempty"`
+	Attachments []resendAttachment `json:"attachments,omitempty"`
+	Tags        []resendTag        `json:"tags,omitempty"`
+}
+
+type resendAttachment struct {
+	Filename    string `json:"filename"`
+	Content     string `json:"content"` // base64 encoded
+	ContentType string `json:"content_type,omitempty"`
+	ContentID   string `json:"content_id,omitempty"` // inline images
+}
+
+type resendTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type resendResponse struct {
+	ID string `json:"id"`
+}
+
+func (r *Resend) buildPayload(msg *Message) resendPayload {
+	payload := resendPayload{
+		From:    msg.From,
+		To:      msg.To,
+		Bcc:     msg.Bcc,
+		ReplyTo: msg.ReplyTo(),
+		Subject: msg.Subject,
+		HTML:    msg.HTMLBody,
+		Text:    msg.TextBody,
+		Headers: msg.headersExcept(apiReservedHeaders...),
+	}
+	if payload.HTML == "" && payload.Text == "" {
+		payload.Text = string(msg.Body)
+	}
+
+	for _, att := range msg.Attachments {
+		a := resendAttachment{
+			Filename:    att.Filename,
+			Content:     base64.StdEncoding.EncodeToString(att.Content),
+			ContentType: att.ContentType,
+		}
+		if att.IsInline {
+			a.ContentID = att.ContentID
+		}
+		payload.Attachments = append(payload.Attachments, a)
+	}
+
+	// Resend tags are name/value pairs: metadata keeps its pairs and each
+	// tag becomes a name with the value "true".
+	keys := make([]string, 0, len(msg.Metadata))
+	for k := range msg.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		payload.Tags = append(payload.Tags, resendTag{Name: resendTagText(k), Value: resendTagText(msg.Metadata[k])})
+	}
+	for _, tag := range msg.Tags {
+		payload.Tags = append(payload.Tags, resendTag{Name: resendTagText(tag), Value: "true"})
+	}
+	return payload
+}
+
+// resendTagText replaces the characters Resend does not allow in tag names
+// and values (anything but ASCII letters, digits, _ and -) with _.
+func resendTagText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestResend_buildPayload(t *testing.T) {
+	r := &Resend{}
+	msg := &Message{
+		From:     "sender@example.com",
+		To:       []string{"a@example.com"},
+		Bcc:      []string{"audit@example.com"},
+		Subject:  "Test",
+		HTMLBody: "<p>hi</p>",
+		TextBody: "hi",
+		Headers:  map[string]string{"Reply-To": "support@example.com", "Subject": "Test", "X-Campaign": "spring"},
+		Tags:     []string{"welcome mail"},
+		Metadata: map[string]string{"user_id": "42"},
+		Attachments: []Attachment{
+			{Filename: "a.pdf", ContentType: "application/pdf", Content: []byte("pdf")},
+			{Filename: "logo.png", ContentType: "image/png", Content: []byte("png"), ContentID: "logo", IsInline: true},
+		},
+	}
+
+	payload := r.buildPayload(msg)
+
+	if payload.HTML != "<p>hi</p>" || payload.Text != "hi" || payload.Bcc[0] != "audit@example.com" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if len(payload.ReplyTo) != 1 || payload.ReplyTo[0] != "support@example.com" {
+		t.Errorf("reply_to = %v", payload.ReplyTo)
+	}
+	if len(payload.Headers) != 1 || payload.Headers["X-Campaign"] != "spring" {
+		t.Errorf("headers = %v, want only the custom header", payload.Headers)
+	}
+	if msg.Headers["Reply-To"] == "" {
+		t.Error("expected the message headers to be left unchanged")
+	}
+	if len(payload.Tags) != 2 || payload.Tags[0] != (resendTag{Name: "user_id", Value: "42"}) || payload.Tags[1] != (resendTag{Name: "welcome_mail", Value: "true"}) {
+		t.Errorf("tags = %+v", payload.Tags)
+	}
+	if len(payload.Attachments) != 2 || payload.Attachments[0].ContentID != "" || payload.Attachments[1].ContentID != "logo" {
+		t.Fatalf("attachments = %+v", payload.Attachments)
+	}
+	if payload.Attachments[0].Content != base64.StdEncoding.EncodeToString([]byte("pdf")) {
+		t.Errorf("attachment content = %q", payload.Attachments[0].Content)
+	}
+}
+
+func TestResend_Send(t *testing.T) {
+	var got *HTTPRequest
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		got = req
+		return &HTTPResponse{StatusCode: 200, Body: []byte(`{"id":"49a3999c-0ce1-4ea6-ab68-afcd6dc2e794"}`)}, nil
+	}}
+	r := NewResend(ProviderConfig{APIKey: "re_123"}, client)
+
+	result, err := r.Send(context.Background(), &Message{From: "a@example.com", To: []string{"b@example.com"}, Body: []byte("plain")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProviderMessageID != "49a3999c-0ce1-4ea6-ab68-afcd6dc2e794" {
+		t.Errorf("ProviderMessageID = %q", result.ProviderMessageID)
+	}
+	if got.URL != resendDefaultEndpoint+resendSendPath || got.Headers["Authorization"] != "Bearer re_123" {
+		t.Errorf("request = %s %v", got.URL, got.Headers)
+	}
+	var payload resendPayload
+	if err := json.Unmarshal(got.Body, &payload); err != nil || payload.Text != "plain" {
+		t.Errorf("text = %q, %v", payload.Text, err)
+	}
+}
+
+func TestResend_HealthCheck_RestrictedKey(t *testing.T) {
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{StatusCode: 401, Body: []byte(`{"name":"restricted_api_key","message":"This API key is restricted to only send emails"}`)}, nil
+	}}
+	if err := NewResend(ProviderConfig{APIKey: "re_123"}, client).HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck() error: %v, want a sending-only key reported valid", err)
+	}
+
+	client.doFn = func(req *HTTPRequest) (*HTTPResponse, error) {
+		return &HTTPResponse{StatusCode: 401, Body: []byte(`{"name":"validation_error","message":"API key is invalid"}`)}, nil
+	}
+	if err := NewResend(ProviderConfig{APIKey: "bad"}, client).HealthCheck(context.Background()); err == nil {
+		t.Error("expected an invalid key to fail the health check")
+	}
+}
@@ -3,18 +3,36 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 
+	"github.com/sungwon/smtp-proxy/server/internal/cost"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
 const defaultCacheTTL = 5 * time.Minute
 
+// Actions the account poller takes on a provider whose reputation
+// degraded, stored in provider_account_stats.pause_action.
+const (
+	// PauseActionPause stops routing to the provider. Messages of a group
+	// whose providers are all paused wait in the queue.
+	PauseActionPause = "pause"
+	// PauseActionThrottle routes to the provider only when no other
+	// provider is available, as with an exhausted quota.
+	PauseActionThrottle = "throttle"
+)
+
+// ErrProvidersPaused is returned by Resolve when every enabled provider of
+// a group is paused for its reputation.
+var ErrProvidersPaused = errors.New("all enabled providers are paused for sender reputation")
+
 // cachedProvider holds a provider instance and its expiration time.
 type cachedProvider struct {
 	provider  Provider
@@ -22,9 +40,11 @@ type cachedProvider struct {
 }
 
 // ProviderResolver resolves the ESP provider for a given group by looking up
-// the group's provider configuration in the database. Results are cached with
-// a configurable TTL. When no provider is configured for a group, a shared
-// stdout provider is returned as the default.
+// the group's provider configuration in the database. A sub-group without
+// enabled providers of its own uses those of its nearest ancestor. Results
+// are cached with a configurable TTL. When no provider is configured for a
+// group or its ancestors, a shared stdout provider is returned as the
+// default.
 type ProviderResolver struct {
 	queries storage.Querier
 	log     zerolog.Logger
@@ -64,18 +84,62 @@ func (r *ProviderResolver) Resolve(ctx context.Context, groupID uuid.UUID) (Prov
 	r.mu.RUnlock()
 
 	// Cache miss or expired: query the database.
-	providers, err := r.queries.ListProvidersByGroupID(ctx, groupID)
+	owner, providers, err := r.providerGroup(ctx, groupID)
 	if err != nil {
-		return nil, fmt.Errorf("list providers for group %s: %w", groupID, err)
+		return nil, err
 	}
 
-	// Find the first enabled provider (ordered by created_at DESC from query).
+	// Quota snapshots are advisory: if they cannot be loaded, resolve as if
+	// every provider had quota left.
+	stats, err := r.queries.ListProviderAccountStatsByGroupID(ctx, owner)
+	if err != nil {
+		r.log.Warn().Err(err).
+			Stringer("group_id", owner).
+			Msg("failed to load provider quota, ignoring quota in resolution")
+	}
+
+	// Routing rules are likewise advisory: without them the default
+	// selection applies. They belong to the group that owns the providers.
+	rules, err := r.queries.ListRoutingRulesByGroupID(ctx, owner)
+	if err != nil {
+		r.log.Warn().Err(err).
+			Stringer("group_id", owner).
+			Msg("failed to load routing rules, using default provider selection")
+	}
+
+	// Providers that reached their daily cap are skipped until it
+	// resets. The counts are advisory too: without them no cap applies.
+	now := time.Now()
+	sends, err := r.queries.ListProviderDailySendsByGroupID(ctx, storage.ListProviderDailySendsByGroupIDParams{
+		GroupID: owner,
+		Day:     DailySendsSince(now),
+	})
+	if err != nil {
+		r.log.Warn().Err(err).
+			Stringer("group_id", owner).
+			Msg("failed to load provider daily sends, ignoring daily caps")
+	}
+	available, reset := withoutCapped(providers, sends, now)
+	if !hasEnabledProvider(available) && hasEnabledProvider(providers) {
+		return nil, &CappedError{Until: reset}
+	}
+	providers = available
+
 	var espProvider *storage.EspProvider
-	for i := range providers {
-		if providers[i].Enabled {
-			espProvider = &providers[i]
-			break
-		}
+	if routingStrategy(rules) == StrategyCheapest {
+		espProvider = selectCheapestProvider(providers, stats)
+	}
+	if espProvider == nil {
+		espProvider = selectProvider(providers, stats)
+	}
+	if espProvider == nil && hasEnabledProvider(providers) {
+		return nil, fmt.Errorf("group %s: %w", owner, ErrProvidersPaused)
+	}
+	if espProvider != nil && quotaExhausted(espProvider.ID, stats) {
+		r.log.Warn().
+			Stringer("group_id", groupID).
+			Str("provider", espProvider.Name).
+			Msg("all enabled providers have exhausted their quota")
 	}
 
 	// No enabled provider found: return stdout default.
@@ -92,6 +156,7 @@ func (r *ProviderResolver) Resolve(ctx context.Context, groupID uuid.UUID) (Prov
 	if err != nil {
 		return nil, fmt.Errorf("convert provider config for %q: %w", espProvider.Name, err)
 	}
+	ruleOptions(rules, espProvider.ID).apply(&cfg)
 
 	p, err := NewProvider(cfg, r.client)
 	if err != nil {
@@ -100,11 +165,259 @@ func (r *ProviderResolver) Resolve(ctx context.Context, groupID uuid.UUID) (Prov
 
 	r.log.Debug().
 		Stringer("group_id", groupID).
+		Stringer("provider_group_id", owner).
 		Str("provider", p.GetName()).
 		Msg("resolved provider from database")
 
-	r.cacheProvider(groupID, p)
-	return p, nil
+	resolved := newIdentifiedProvider(p, espProvider)
+	r.cacheProvider(groupID, resolved)
+	return resolved, nil
+}
+
+// ResolveByID returns the provider with the given ID for a message of
+// groupID, bypassing routing and quota selection. The provider must be
+// enabled and belong to groupID or one of its ancestors. Results are not
+// cached; pinned deliveries are rare.
+func (r *ProviderResolver) ResolveByID(ctx context.Context, groupID, providerID uuid.UUID) (Provider, error) {
+	esp, err := r.queries.GetProviderByID(ctx, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("get provider %s: %w", providerID, err)
+	}
+	if !esp.Enabled {
+		return nil, fmt.Errorf("provider %q is disabled", esp.Name)
+	}
+
+	if esp.GroupID != groupID {
+		ancestors, err := r.queries.ListGroupAncestors(ctx, groupID)
+		if err != nil {
+			return nil, fmt.Errorf("list ancestors of group %s: %w", groupID, err)
+		}
+		owned := false
+		for _, a := range ancestors {
+			if a.ID == esp.GroupID {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			return nil, fmt.Errorf("provider %q does not serve group %s", esp.Name, groupID)
+		}
+	}
+
+	cfg, err := espToConfig(&esp)
+	if err != nil {
+		return nil, fmt.Errorf("convert provider config for %q: %w", esp.Name, err)
+	}
+	// A pinned provider keeps the routing options its rules give it.
+	rules, err := r.queries.ListRoutingRulesByGroupID(ctx, esp.GroupID)
+	if err != nil {
+		r.log.Warn().Err(err).
+			Stringer("group_id", esp.GroupID).
+			Msg("failed to load routing rules, using provider routing options")
+	}
+	ruleOptions(rules, esp.ID).apply(&cfg)
+
+	p, err := NewProvider(cfg, r.client)
+	if err != nil {
+		return nil, fmt.Errorf("create provider %q: %w", esp.Name, err)
+	}
+	return newIdentifiedProvider(p, &esp), nil
+}
+
+// providerGroup returns the group whose providers serve groupID, along with
+// those providers: groupID itself when it has an enabled provider, otherwise
+// its nearest ancestor that has one. When no group in the hierarchy has an
+// enabled provider, groupID and its own providers are returned.
+func (r *ProviderResolver) providerGroup(ctx context.Context, groupID uuid.UUID) (uuid.UUID, []storage.EspProvider, error) {
+	providers, err := r.queries.ListProvidersByGroupID(ctx, groupID)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("list providers for group %s: %w", groupID, err)
+	}
+	if hasEnabledProvider(providers) {
+		return groupID, providers, nil
+	}
+
+	ancestors, err := r.queries.ListGroupAncestors(ctx, groupID)
+	if err != nil {
+		r.log.Warn().Err(err).
+			Stringer("group_id", groupID).
+			Msg("failed to load parent groups, not inheriting providers")
+		return groupID, providers, nil
+	}
+	for _, a := range ancestors {
+		if a.ID == groupID {
+			continue
+		}
+		inherited, err := r.queries.ListProvidersByGroupID(ctx, a.ID)
+		if err != nil {
+			return uuid.Nil, nil, fmt.Errorf("list providers for group %s: %w", a.ID, err)
+		}
+		if hasEnabledProvider(inherited) {
+			return a.ID, inherited, nil
+		}
+	}
+	return groupID, providers, nil
+}
+
+// hasEnabledProvider reports whether any of the providers is enabled.
+func hasEnabledProvider(providers []storage.EspProvider) bool {
+	for _, p := range providers {
+		if p.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// identifiedProvider attaches the esp_providers row ID, capture setting
+// and recipient domain limits to a provider built by the resolver.
+type identifiedProvider struct {
+	Provider
+	id       uuid.UUID
+	capture  int
+	limits   []DomainLimit
+	dailyCap DailyCap
+}
+
+// ProviderID implements Identified.
+func (p *identifiedProvider) ProviderID() uuid.UUID {
+	return p.id
+}
+
+// CaptureLimit implements Captured.
+func (p *identifiedProvider) CaptureLimit() int {
+	return p.capture
+}
+
+// DomainLimits implements DomainLimited.
+func (p *identifiedProvider) DomainLimits() []DomainLimit {
+	return p.limits
+}
+
+// DailyCap implements DailyCapped.
+func (p *identifiedProvider) DailyCap() DailyCap {
+	return p.dailyCap
+}
+
+// newIdentifiedProvider wraps a provider built from esp.
+func newIdentifiedProvider(p Provider, esp *storage.EspProvider) *identifiedProvider {
+	extra := parseSMTPConfigExtra(esp)
+	return &identifiedProvider{
+		Provider: p,
+		id:       esp.ID,
+		capture:  min(max(extra.DebugCapture, 0), MaxCaptureLimit),
+		limits:   scaleDomainLimits(extra.DomainLimits, len(extra.RelayIPs)),
+		dailyCap: parseDailyCap(extra),
+	}
+}
+
+// selectProvider returns the first enabled provider (ordered by created_at
+// DESC from query) that has not exhausted its ESP quota and is not paused
+// for its reputation. When every enabled provider is exhausted or
+// throttled the first of them is returned anyway, so the message is
+// retried against the ESP rather than silently dropped. Providers paused
+// with PauseActionPause are never returned; nil means none is left.
+func selectProvider(providers []storage.EspProvider, stats []storage.ProviderAccountStat) *storage.EspProvider {
+	var first *storage.EspProvider
+	for i := range providers {
+		if !providers[i].Enabled {
+			continue
+		}
+		action := pauseAction(providers[i].ID, stats)
+		if action == PauseActionPause {
+			continue
+		}
+		if first == nil {
+			first = &providers[i]
+		}
+		if action == "" && !quotaExhausted(providers[i].ID, stats) {
+			return &providers[i]
+		}
+	}
+	return first
+}
+
+// StrategyCheapest is the routing rule strategy that prefers the healthy
+// provider with the lowest cost model price.
+const StrategyCheapest = "cheapest"
+
+// routingStrategy returns the strategy of the highest-priority enabled
+// routing rule that sets one in its conditions, e.g. {"strategy":
+// "cheapest"}. A strategy rule's provider_id is not used.
+func routingStrategy(rules []storage.RoutingRule) string {
+	for _, rule := range rules {
+		if !rule.Enabled || len(rule.Conditions) == 0 {
+			continue
+		}
+		var cond struct {
+			Strategy string `json:"strategy"`
+		}
+		if err := json.Unmarshal(rule.Conditions, &cond); err != nil {
+			continue
+		}
+		if cond.Strategy != "" {
+			return cond.Strategy
+		}
+	}
+	return ""
+}
+
+// selectCheapestProvider returns the enabled provider with the lowest
+// first-tier price among those with a cost model, quota left, no
+// reputation pause and a health status other than unhealthy. Ties keep
+// query order. It returns nil when
+// no provider qualifies so the caller can fall back to selectProvider.
+func selectCheapestProvider(providers []storage.EspProvider, stats []storage.ProviderAccountStat) *storage.EspProvider {
+	var best *storage.EspProvider
+	var bestPrice float64
+	for i := range providers {
+		p := &providers[i]
+		if !p.Enabled || p.HealthStatus == "unhealthy" || quotaExhausted(p.ID, stats) || pauseAction(p.ID, stats) != "" {
+			continue
+		}
+		model, err := cost.ParseModel(p.CostModel)
+		if err != nil || model == nil {
+			continue
+		}
+		if best == nil || model.BasePrice() < bestPrice {
+			best, bestPrice = p, model.BasePrice()
+		}
+	}
+	return best
+}
+
+// quotaExhausted reports whether the latest quota snapshot for the provider
+// shows no quota left.
+func quotaExhausted(providerID uuid.UUID, stats []storage.ProviderAccountStat) bool {
+	for _, s := range stats {
+		if s.ProviderID == providerID {
+			return s.Quota.Valid && s.QuotaUsed.Valid && s.QuotaUsed.Int64 >= s.Quota.Int64
+		}
+	}
+	return false
+}
+
+// pauseAction returns the action of the provider's reputation pause, or ""
+// when it is not paused.
+func pauseAction(providerID uuid.UUID, stats []storage.ProviderAccountStat) string {
+	for _, s := range stats {
+		if s.ProviderID == providerID && s.PauseReason.Valid {
+			if s.PauseAction.String == PauseActionThrottle {
+				return PauseActionThrottle
+			}
+			return PauseActionPause
+		}
+	}
+	return ""
+}
+
+// Invalidate drops the cached provider of groupID, so the next Resolve
+// routes the group again, for instance once its provider reached its daily
+// cap.
+func (r *ProviderResolver) Invalidate(groupID uuid.UUID) {
+	r.mu.Lock()
+	delete(r.cache, groupID)
+	r.mu.Unlock()
 }
 
 // cacheProvider stores a provider in the cache with the configured TTL.
@@ -117,6 +430,17 @@ func (r *ProviderResolver) cacheProvider(groupID uuid.UUID, p Provider) {
 	r.mu.Unlock()
 }
 
+// NewProviderFromStorage creates a provider instance from an esp_providers
+// row, for callers that work with specific providers rather than resolving
+// one per group.
+func NewProviderFromStorage(esp *storage.EspProvider, client HTTPClient) (Provider, error) {
+	cfg, err := espToConfig(esp)
+	if err != nil {
+		return nil, fmt.Errorf("convert provider config for %q: %w", esp.Name, err)
+	}
+	return NewProvider(cfg, client)
+}
+
 // smtpConfigExtra holds optional fields parsed from the esp_providers.smtp_config JSONB column.
 type smtpConfigExtra struct {
 	Region       string `json:"region,omitempty"`
@@ -126,6 +450,31 @@ type smtpConfigExtra struct {
 	ClientSecret string `json:"client_secret,omitempty"`
 	UserID       string `json:"user_id,omitempty"`
 	Endpoint     string `json:"endpoint,omitempty"`
+	ProxyURL     string `json:"proxy_url,omitempty"`
+	// Username and Auth are the EWS account and authentication method.
+	Username string `json:"username,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+	// IPPool, Subuser and ConfigurationSet are the provider's default
+	// routing options; see RoutingOptions.
+	IPPool           string `json:"ip_pool,omitempty"`
+	Subuser          string `json:"subuser,omitempty"`
+	ConfigurationSet string `json:"configuration_set,omitempty"`
+	// RelayIPs are the IPs the ESP sends the provider's mail from, such
+	// as dedicated IPs, checked against blocklists by the account poller.
+	RelayIPs []string `json:"relay_ips,omitempty"`
+	// DebugCapture is the number of recent deliveries whose ESP API
+	// requests and responses are kept for debugging; 0 turns capture off.
+	DebugCapture int `json:"debug_capture,omitempty"`
+	// DomainLimits pace deliveries to recipient domains per relay IP.
+	DomainLimits []DomainLimit `json:"domain_limits,omitempty"`
+	// DailyCap is the most deliveries per day in DailyCapTimezone (UTC
+	// by default); 0 is no cap.
+	DailyCap         int    `json:"daily_cap,omitempty"`
+	DailyCapTimezone string `json:"daily_cap_timezone,omitempty"`
+	// Plugin and Options configure providers of type "plugin": the custom
+	// provider type and its settings.
+	Plugin  string            `json:"plugin,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
 }
 
 // espToConfig converts a storage.EspProvider to a provider.ProviderConfig.
@@ -150,10 +499,43 @@ func espToConfig(esp *storage.EspProvider) (ProviderConfig, error) {
 		cfg.ClientID = extra.ClientID
 		cfg.ClientSecret = extra.ClientSecret
 		cfg.UserID = extra.UserID
+		cfg.ProxyURL = extra.ProxyURL
+		cfg.Username = extra.Username
+		cfg.Auth = extra.Auth
+		cfg.IPPool = extra.IPPool
+		cfg.Subuser = extra.Subuser
+		cfg.ConfigurationSet = extra.ConfigurationSet
 		if extra.Endpoint != "" {
 			cfg.Endpoint = extra.Endpoint
 		}
+		if esp.ProviderType == storage.ProviderTypePlugin {
+			cfg.Type = extra.Plugin
+			cfg.Options = extra.Options
+		}
 	}
 
 	return cfg, nil
 }
+
+// parseSMTPConfigExtra returns the optional smtp_config fields of esp,
+// or none when the column does not decode.
+func parseSMTPConfigExtra(esp *storage.EspProvider) smtpConfigExtra {
+	var extra smtpConfigExtra
+	if len(esp.SmtpConfig) > 0 && json.Unmarshal(esp.SmtpConfig, &extra) != nil {
+		return smtpConfigExtra{}
+	}
+	return extra
+}
+
+// RelayIPs returns the relay IPs listed in the provider's
+// smtp_config.relay_ips. Entries that are not IP addresses are skipped.
+func RelayIPs(esp *storage.EspProvider) []net.IP {
+	extra := parseSMTPConfigExtra(esp)
+	var ips []net.IP
+	for _, s := range extra.RelayIPs {
+		if ip := net.ParseIP(s); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
@@ -0,0 +1,317 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func quotaStat(id uuid.UUID, quota, used int64) storage.ProviderAccountStat {
+	return storage.ProviderAccountStat{
+		ProviderID: id,
+		Quota:      pgtype.Int8{Int64: quota, Valid: true},
+		QuotaUsed:  pgtype.Int8{Int64: used, Valid: true},
+	}
+}
+
+func TestSelectProvider_FirstEnabled(t *testing.T) {
+	providers := []storage.EspProvider{
+		{ID: uuid.New(), Name: "disabled", Enabled: false},
+		{ID: uuid.New(), Name: "primary", Enabled: true},
+		{ID: uuid.New(), Name: "secondary", Enabled: true},
+	}
+
+	got := selectProvider(providers, nil)
+	if got == nil || got.Name != "primary" {
+		t.Fatalf("selectProvider() = %v, want primary", got)
+	}
+}
+
+func TestSelectProvider_SkipsExhaustedQuota(t *testing.T) {
+	providers := []storage.EspProvider{
+		{ID: uuid.New(), Name: "primary", Enabled: true},
+		{ID: uuid.New(), Name: "secondary", Enabled: true},
+	}
+	stats := []storage.ProviderAccountStat{
+		quotaStat(providers[0].ID, 50000, 50000),
+		quotaStat(providers[1].ID, 50000, 10),
+	}
+
+	got := selectProvider(providers, stats)
+	if got == nil || got.Name != "secondary" {
+		t.Fatalf("selectProvider() = %v, want secondary", got)
+	}
+}
+
+func TestSelectProvider_AllExhaustedFallsBackToFirst(t *testing.T) {
+	providers := []storage.EspProvider{
+		{ID: uuid.New(), Name: "primary", Enabled: true},
+		{ID: uuid.New(), Name: "secondary", Enabled: true},
+	}
+	stats := []storage.ProviderAccountStat{
+		quotaStat(providers[0].ID, 100, 100),
+		quotaStat(providers[1].ID, 100, 150),
+	}
+
+	got := selectProvider(providers, stats)
+	if got == nil || got.Name != "primary" {
+		t.Fatalf("selectProvider() = %v, want primary", got)
+	}
+}
+
+func TestSelectProvider_NoneEnabled(t *testing.T) {
+	providers := []storage.EspProvider{{ID: uuid.New(), Enabled: false}}
+	if got := selectProvider(providers, nil); got != nil {
+		t.Fatalf("selectProvider() = %v, want nil", got)
+	}
+}
+
+func pausedStat(id uuid.UUID, action string) storage.ProviderAccountStat {
+	return storage.ProviderAccountStat{
+		ProviderID:  id,
+		PauseReason: pgtype.Text{String: "bounce rate 9.00% above 5.00%", Valid: true},
+		PauseAction: pgtype.Text{String: action, Valid: true},
+	}
+}
+
+func TestSelectProvider_ReputationPause(t *testing.T) {
+	providers := []storage.EspProvider{
+		{ID: uuid.New(), Name: "primary", Enabled: true},
+		{ID: uuid.New(), Name: "secondary", Enabled: true},
+	}
+
+	tests := []struct {
+		name  string
+		stats []storage.ProviderAccountStat
+		want  string
+	}{
+		{
+			name:  "paused provider is skipped",
+			stats: []storage.ProviderAccountStat{pausedStat(providers[0].ID, PauseActionPause)},
+			want:  "secondary",
+		},
+		{
+			name:  "throttled provider is skipped",
+			stats: []storage.ProviderAccountStat{pausedStat(providers[0].ID, PauseActionThrottle)},
+			want:  "secondary",
+		},
+		{
+			name: "all throttled falls back to first",
+			stats: []storage.ProviderAccountStat{
+				pausedStat(providers[0].ID, PauseActionThrottle),
+				pausedStat(providers[1].ID, PauseActionThrottle),
+			},
+			want: "primary",
+		},
+		{
+			name: "all paused selects none",
+			stats: []storage.ProviderAccountStat{
+				pausedStat(providers[0].ID, PauseActionPause),
+				pausedStat(providers[1].ID, PauseActionPause),
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectProvider(providers, tt.stats)
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("selectProvider() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelayIPs(t *testing.T) {
+	esp := &storage.EspProvider{SmtpConfig: []byte(`{"relay_ips":["192.0.2.10","not-an-ip","2001:db8::1"]}`)}
+	ips := RelayIPs(esp)
+	if len(ips) != 2 || ips[0].String() != "192.0.2.10" || ips[1].String() != "2001:db8::1" {
+		t.Errorf("RelayIPs() = %v", ips)
+	}
+}
+
+func TestParseRuleOptions(t *testing.T) {
+	opts, err := ParseRuleOptions([]byte(`{"strategy":"cheapest","provider_options":{"domain":"mg.example.com","configuration_set":"tx"}}`))
+	if err != nil || opts != (RoutingOptions{Domain: "mg.example.com", ConfigurationSet: "tx"}) {
+		t.Errorf("ParseRuleOptions() = %+v, %v", opts, err)
+	}
+	if _, err := ParseRuleOptions([]byte(`{"provider_options":{"ip_poool":"x"}}`)); err == nil {
+		t.Error("expected an error for an unknown option")
+	}
+	if opts, err := ParseRuleOptions(nil); err != nil || opts != (RoutingOptions{}) {
+		t.Errorf("ParseRuleOptions(nil) = %+v, %v", opts, err)
+	}
+}
+
+func flatCost(price string) []byte {
+	return []byte(`{"tiers":[{"price_per_1k":` + price + `}]}`)
+}
+
+func TestRoutingStrategy(t *testing.T) {
+	rules := []storage.RoutingRule{
+		{Enabled: false, Conditions: []byte(`{"strategy":"disabled"}`)},
+		{Enabled: true, Conditions: []byte(`{"recipient_domain":"example.com"}`)},
+		{Enabled: true, Conditions: []byte(`{"strategy":"cheapest"}`)},
+	}
+	if got := routingStrategy(rules); got != StrategyCheapest {
+		t.Errorf("routingStrategy() = %q, want %q", got, StrategyCheapest)
+	}
+	if got := routingStrategy(nil); got != "" {
+		t.Errorf("routingStrategy(nil) = %q, want empty", got)
+	}
+}
+
+func TestSelectCheapestProvider(t *testing.T) {
+	providers := []storage.EspProvider{
+		{ID: uuid.New(), Name: "unpriced", Enabled: true},
+		{ID: uuid.New(), Name: "expensive", Enabled: true, CostModel: flatCost("1.00")},
+		{ID: uuid.New(), Name: "cheap-unhealthy", Enabled: true, HealthStatus: "unhealthy", CostModel: flatCost("0.10")},
+		{ID: uuid.New(), Name: "cheap-exhausted", Enabled: true, CostModel: flatCost("0.20")},
+		{ID: uuid.New(), Name: "cheap-disabled", Enabled: false, CostModel: flatCost("0.30")},
+		{ID: uuid.New(), Name: "cheapest", Enabled: true, HealthStatus: "healthy", CostModel: flatCost("0.50")},
+	}
+	stats := []storage.ProviderAccountStat{quotaStat(providers[3].ID, 100, 100)}
+
+	got := selectCheapestProvider(providers, stats)
+	if got == nil || got.Name != "cheapest" {
+		t.Fatalf("selectCheapestProvider() = %v, want cheapest", got)
+	}
+}
+
+func TestSelectCheapestProvider_NoPricedProviders(t *testing.T) {
+	providers := []storage.EspProvider{{ID: uuid.New(), Name: "unpriced", Enabled: true}}
+	if got := selectCheapestProvider(providers, nil); got != nil {
+		t.Fatalf("selectCheapestProvider() = %v, want nil", got)
+	}
+}
+
+// hierarchyQuerier serves providers and ancestors for resolver tests. Other
+// Querier methods are not used by providerGroup.
+type hierarchyQuerier struct {
+	storage.Querier
+	providers map[uuid.UUID][]storage.EspProvider
+	ancestors map[uuid.UUID][]storage.Group
+	rules     map[uuid.UUID][]storage.RoutingRule
+}
+
+func (q *hierarchyQuerier) ListProvidersByGroupID(_ context.Context, groupID uuid.UUID) ([]storage.EspProvider, error) {
+	return q.providers[groupID], nil
+}
+
+func (q *hierarchyQuerier) ListGroupAncestors(_ context.Context, id uuid.UUID) ([]storage.Group, error) {
+	return q.ancestors[id], nil
+}
+
+func (q *hierarchyQuerier) ListRoutingRulesByGroupID(_ context.Context, groupID uuid.UUID) ([]storage.RoutingRule, error) {
+	return q.rules[groupID], nil
+}
+
+func TestProviderGroup_InheritsFromNearestAncestor(t *testing.T) {
+	company, team, squad := uuid.New(), uuid.New(), uuid.New()
+	q := &hierarchyQuerier{
+		providers: map[uuid.UUID][]storage.EspProvider{
+			company: {{ID: uuid.New(), Name: "company-ses", Enabled: true}},
+			team:    {{ID: uuid.New(), Name: "team-disabled", Enabled: false}},
+		},
+		ancestors: map[uuid.UUID][]storage.Group{
+			squad: {{ID: squad}, {ID: team}, {ID: company}},
+		},
+	}
+	r := NewResolver(q, nil, zerolog.Nop())
+
+	owner, providers, err := r.providerGroup(context.Background(), squad)
+	if err != nil {
+		t.Fatalf("providerGroup() error = %v", err)
+	}
+	if owner != company || len(providers) != 1 || providers[0].Name != "company-ses" {
+		t.Errorf("providerGroup() = %v, %v, want company providers", owner, providers)
+	}
+}
+
+func TestProviderGroup_OwnProvidersOverride(t *testing.T) {
+	company, team := uuid.New(), uuid.New()
+	q := &hierarchyQuerier{
+		providers: map[uuid.UUID][]storage.EspProvider{
+			company: {{ID: uuid.New(), Name: "company-ses", Enabled: true}},
+			team:    {{ID: uuid.New(), Name: "team-sendgrid", Enabled: true}},
+		},
+		ancestors: map[uuid.UUID][]storage.Group{
+			team: {{ID: team}, {ID: company}},
+		},
+	}
+	r := NewResolver(q, nil, zerolog.Nop())
+
+	owner, providers, err := r.providerGroup(context.Background(), team)
+	if err != nil {
+		t.Fatalf("providerGroup() error = %v", err)
+	}
+	if owner != team || providers[0].Name != "team-sendgrid" {
+		t.Errorf("providerGroup() = %v, %v, want team providers", owner, providers)
+	}
+}
+
+// pinnedQuerier extends hierarchyQuerier with provider lookup by ID.
+type pinnedQuerier struct {
+	hierarchyQuerier
+	byID map[uuid.UUID]storage.EspProvider
+}
+
+func (q *pinnedQuerier) GetProviderByID(_ context.Context, id uuid.UUID) (storage.EspProvider, error) {
+	p, ok := q.byID[id]
+	if !ok {
+		return storage.EspProvider{}, errors.New("no rows")
+	}
+	return p, nil
+}
+
+func TestResolveByID(t *testing.T) {
+	company, team, other := uuid.New(), uuid.New(), uuid.New()
+	inherited := storage.EspProvider{ID: uuid.New(), Name: "company-sg", ProviderType: storage.ProviderTypeSendgrid, ApiKey: sql.NullString{String: "key", Valid: true}, Enabled: true, GroupID: company,
+		SmtpConfig: []byte(`{"ip_pool":"shared","subuser":"company"}`)}
+	foreign := storage.EspProvider{ID: uuid.New(), Name: "other-sg", ProviderType: storage.ProviderTypeSendgrid, ApiKey: sql.NullString{String: "key", Valid: true}, Enabled: true, GroupID: other}
+	disabled := storage.EspProvider{ID: uuid.New(), Name: "team-off", ProviderType: storage.ProviderTypeSendgrid, ApiKey: sql.NullString{String: "key", Valid: true}, GroupID: team}
+	q := &pinnedQuerier{
+		hierarchyQuerier: hierarchyQuerier{
+			ancestors: map[uuid.UUID][]storage.Group{team: {{ID: team}, {ID: company}}},
+			rules: map[uuid.UUID][]storage.RoutingRule{company: {
+				{Enabled: true, ProviderID: uuid.New(), Conditions: []byte(`{"provider_options":{"ip_pool":"other"}}`)},
+				{Enabled: true, ProviderID: inherited.ID, Conditions: []byte(`{"provider_options":{"ip_pool":"transactional"}}`)},
+			}},
+		},
+		byID: map[uuid.UUID]storage.EspProvider{inherited.ID: inherited, foreign.ID: foreign, disabled.ID: disabled},
+	}
+	r := NewResolver(q, nil, zerolog.Nop())
+
+	p, err := r.ResolveByID(context.Background(), team, inherited.ID)
+	if err != nil {
+		t.Fatalf("ResolveByID() error = %v", err)
+	}
+	if ident, ok := p.(Identified); !ok || ident.ProviderID() != inherited.ID {
+		t.Errorf("expected provider identified as %s, got %v", inherited.ID, p)
+	}
+
+	if sg := p.(*identifiedProvider).Provider.(*SendGrid); sg.ipPool != "transactional" || sg.subuser != "company" {
+		t.Errorf("routing options = %q, %q, want the rule's over the provider's", sg.ipPool, sg.subuser)
+	}
+
+	if _, err := r.ResolveByID(context.Background(), team, foreign.ID); err == nil {
+		t.Error("expected error for a provider outside the group tree")
+	}
+	if _, err := r.ResolveByID(context.Background(), team, disabled.ID); err == nil {
+		t.Error("expected error for a disabled provider")
+	}
+	if _, err := r.ResolveByID(context.Background(), team, uuid.New()); err == nil {
+		t.Error("expected error for an unknown provider")
+	}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// RoutingOptions are the ESP-specific routing settings of a provider, set
+// in its smtp_config and overridden per routing rule. Each provider type
+// uses the fields it understands and ignores the rest.
+type RoutingOptions struct {
+	// IPPool is the SendGrid IP pool the message is sent from.
+	IPPool string `json:"ip_pool,omitempty"`
+	// Subuser is the SendGrid subuser the message is sent as.
+	Subuser string `json:"subuser,omitempty"`
+	// Domain is the Mailgun sending domain.
+	Domain string `json:"domain,omitempty"`
+	// ConfigurationSet is the SES configuration set of the message.
+	ConfigurationSet string `json:"configuration_set,omitempty"`
+}
+
+// apply sets the fields of o that are not empty on cfg.
+func (o RoutingOptions) apply(cfg *ProviderConfig) {
+	if o.IPPool != "" {
+		cfg.IPPool = o.IPPool
+	}
+	if o.Subuser != "" {
+		cfg.Subuser = o.Subuser
+	}
+	if o.Domain != "" {
+		cfg.Domain = o.Domain
+	}
+	if o.ConfigurationSet != "" {
+		cfg.ConfigurationSet = o.ConfigurationSet
+	}
+}
+
+// ParseRuleOptions returns the provider_options of routing rule conditions,
+// e.g. {"provider_options": {"ip_pool": "transactional"}}. Unknown option
+// names are an error so that typos do not go unnoticed.
+func ParseRuleOptions(conditions []byte) (RoutingOptions, error) {
+	var opts RoutingOptions
+	if len(conditions) == 0 {
+		return opts, nil
+	}
+	var cond struct {
+		ProviderOptions json.RawMessage `json:"provider_options"`
+	}
+	if err := json.Unmarshal(conditions, &cond); err != nil {
+		return opts, err
+	}
+	if len(cond.ProviderOptions) == 0 || string(cond.ProviderOptions) == "null" {
+		return opts, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(cond.ProviderOptions))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		return opts, fmt.Errorf("provider_options: %w", err)
+	}
+	return opts, nil
+}
+
+// ruleOptions returns the provider options of the highest-priority enabled
+// routing rule for providerID that sets any. Rules are ordered by priority.
+func ruleOptions(rules []storage.RoutingRule, providerID uuid.UUID) RoutingOptions {
+	for _, rule := range rules {
+		if !rule.Enabled || rule.ProviderID != providerID {
+			continue
+		}
+		opts, err := ParseRuleOptions(rule.Conditions)
+		if err != nil || opts == (RoutingOptions{}) {
+			continue
+		}
+		return opts
+	}
+	return RoutingOptions{}
+}
@@ -18,18 +18,18 @@ const (
 type SendGrid struct {
 	apiKey   string
 	endpoint string
+	ipPool   string
+	subuser  string
 	client   HTTPClient
 }
 
 // NewSendGrid creates a SendGrid provider from the given configuration.
 func NewSendGrid(cfg ProviderConfig, client HTTPClient) *SendGrid {
-	endpoint := cfg.Endpoint
-	if endpoint == "" {
-		endpoint = sendgridDefaultEndpoint
-	}
 	return &SendGrid{
 		apiKey:   cfg.APIKey,
-		endpoint: endpoint,
+		endpoint: apiEndpoint(cfg, sendgridRegionEndpoints, sendgridDefaultEndpoint),
+		ipPool:   cfg.IPPool,
+		subuser:  cfg.Subuser,
 		client:   client,
 	}
 }
@@ -44,14 +44,20 @@ func (s *SendGrid) Send(ctx context.Context, msg *Message) (*DeliveryResult, err
 		return nil, fmt.Errorf("sendgrid: marshal request: %w", err)
 	}
 
+	headers := map[string]string{
+		"Authorization": "Bearer " + s.apiKey,
+		"Content-Type":  "application/json",
+	}
+	// A parent account key sends as one of its subusers.
+	if s.subuser != "" {
+		headers["on-behalf-of"] = s.subuser
+	}
 	resp, err := s.client.Do(&HTTPRequest{
-		Method: "POST",
-		URL:    s.endpoint + sendgridSendPath,
-		Headers: map[string]string{
-			"Authorization": "Bearer " + s.apiKey,
-			"Content-Type":  "application/json",
-		},
-		Body: body,
+		Method:  "POST",
+		URL:     s.endpoint + sendgridSendPath,
+		Headers: headers,
+		Body:    body,
+		Context: ctx,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("sendgrid: send request: %w", err)
@@ -83,13 +89,14 @@ func (s *SendGrid) HealthCheck(ctx context.Context) error {
 		Headers: map[string]string{
 			"Authorization": "Bearer " + s.apiKey,
 		},
+		Context: ctx,
 	})
 	if err != nil {
 		return fmt.Errorf("sendgrid: health check request: %w", err)
 	}
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("sendgrid: health check returned status %d", resp.StatusCode)
+		return healthCheckError("sendgrid", resp.StatusCode, resp.Body)
 	}
 	return nil
 }
@@ -98,14 +105,19 @@ func (s *SendGrid) HealthCheck(ctx context.Context) error {
 type sendgridPayload struct {
 	Personalizations []sendgridPersonalization `json:"personalizations"`
 	From             sendgridEmail             `json:"from"`
+	ReplyTo          *sendgridEmail            `json:"reply_to,omitempty"`
 	Subject          string                    `json:"subject"`
 	Content          []sendgridContent         `json:"content"`
 	Headers          map[string]string         `json:"headers,omitempty"`
 	Attachments      []sendgridAttachment      `json:"attachments,omitempty"`
+	Categories       []string                  `json:"categories,omitempty"`
+	CustomArgs       map[string]string         `json:"custom_args,omitempty"`
+	IPPoolName       string                    `json:"ip_pool_name,omitempty"`
 }
 
 type sendgridPersonalization struct {
-	To []sendgridEmail `json:"to"`
+	To  []sendgridEmail `json:"to"`
+	Bcc []sendgridEmail `json:"bcc,omitempty"`
 }
 
 type sendgridEmail struct {
@@ -130,6 +142,10 @@ func (s *SendGrid) buildPayload(msg *Message) sendgridPayload {
 	for i, addr := range msg.To {
 		tos[i] = sendgridEmail{Email: addr}
 	}
+	var bccs []sendgridEmail
+	for _, addr := range msg.Bcc {
+		bccs = append(bccs, sendgridEmail{Email: addr})
+	}
 
 	// Build content parts: prefer parsed bodies, fall back to raw Body.
 	var content []sendgridContent
@@ -147,12 +163,29 @@ func (s *SendGrid) buildPayload(msg *Message) sendgridPayload {
 
 	payload := sendgridPayload{
 		Personalizations: []sendgridPersonalization{
-			{To: tos},
+			{To: tos, Bcc: bccs},
 		},
-		From:    sendgridEmail{Email: msg.From},
-		Subject: msg.Subject,
-		Content: content,
-		Headers: msg.Headers,
+		From:       sendgridEmail{Email: msg.From},
+		Subject:    msg.Subject,
+		Content:    content,
+		Headers:    msg.Headers,
+		Categories: msg.Tags,
+		CustomArgs: msg.Metadata,
+		IPPoolName: s.ipPool,
+	}
+
+	// Reply-To is a reserved header in SendGrid and must be set through
+	// reply_to instead.
+	if _, ok := msg.Headers["Reply-To"]; ok {
+		payload.Headers = make(map[string]string, len(msg.Headers))
+		for k, v := range msg.Headers {
+			if k != "Reply-To" {
+				payload.Headers[k] = v
+			}
+		}
+		if replyTo := msg.ReplyTo(); len(replyTo) > 0 {
+			payload.ReplyTo = &sendgridEmail{Email: replyTo[0]}
+		}
 	}
 
 	// Attach files if present.
@@ -1,8 +1,10 @@
 package provider
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -167,3 +169,68 @@ func TestSendGrid_buildPayload_JSONMarshal(t *testing.T) {
 		t.Errorf("expected 1 attachment after round-trip, got %d", len(decoded.Attachments))
 	}
 }
+
+func TestSendGrid_buildPayload_TagsAndMetadata(t *testing.T) {
+	sg := &SendGrid{}
+	msg := &Message{
+		From:     "sender@example.com",
+		To:       []string{"a@example.com"},
+		Subject:  "Test",
+		TextBody: "body",
+		Tags:     []string{"billing", "invoice"},
+		Metadata: map[string]string{"customer_id": "42"},
+	}
+
+	data, err := json.Marshal(sg.buildPayload(msg))
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+	if !strings.Contains(string(data), `"categories":["billing","invoice"]`) {
+		t.Errorf("expected categories in payload, got %s", data)
+	}
+	if !strings.Contains(string(data), `"custom_args":{"customer_id":"42"}`) {
+		t.Errorf("expected custom_args in payload, got %s", data)
+	}
+}
+
+func TestSendGrid_buildPayload_ReplyTo(t *testing.T) {
+	s := &SendGrid{}
+	msg := &Message{
+		From:     "sender@example.com",
+		To:       []string{"a@example.com"},
+		Headers:  map[string]string{"Reply-To": "Support <support@example.com>", "X-Test": "1"},
+		TextBody: "body",
+	}
+
+	payload := s.buildPayload(msg)
+
+	if payload.ReplyTo == nil || payload.ReplyTo.Email != "support@example.com" {
+		t.Errorf("expected reply_to support@example.com, got %+v", payload.ReplyTo)
+	}
+	if _, ok := payload.Headers["Reply-To"]; ok || payload.Headers["X-Test"] != "1" {
+		t.Errorf("expected Reply-To removed from headers, got %v", payload.Headers)
+	}
+	if msg.Headers["Reply-To"] == "" {
+		t.Error("expected the message headers to be left unchanged")
+	}
+}
+
+func TestSendGrid_Send_RoutingOptions(t *testing.T) {
+	var got *HTTPRequest
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		got = req
+		return &HTTPResponse{StatusCode: 202}, nil
+	}}
+	s := NewSendGrid(ProviderConfig{APIKey: "key", IPPool: "transactional", Subuser: "billing"}, client)
+
+	if _, err := s.Send(context.Background(), &Message{From: "a@example.com", To: []string{"b@example.com"}, TextBody: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Headers["on-behalf-of"] != "billing" {
+		t.Errorf("on-behalf-of = %q, want billing", got.Headers["on-behalf-of"])
+	}
+	var payload sendgridPayload
+	if err := json.Unmarshal(got.Body, &payload); err != nil || payload.IPPoolName != "transactional" {
+		t.Errorf("ip_pool_name = %q, %v", payload.IPPoolName, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"mime/multipart"
 	"net/textproto"
+	"sort"
 	"strings"
 	"time"
 )
@@ -20,28 +21,34 @@ const (
 // SES implements the Provider interface for AWS SES v2 API.
 // It uses a configurable HTTP client for testability rather than the AWS SDK.
 type SES struct {
-	region   string
-	endpoint string
-	client   HTTPClient
+	region           string
+	endpoint         string
+	snsEndpoint      string
+	configurationSet string
+	client           HTTPClient
 }
 
 // NewSES creates an AWS SES provider from the given configuration.
 // The APIKey field in config is used as a placeholder; real AWS auth
 // (Signature V4) should be handled by the HTTPClient wrapper in production.
 func NewSES(cfg ProviderConfig, client HTTPClient) *SES {
-	endpoint := cfg.Endpoint
-	if endpoint == "" {
-		endpoint = fmt.Sprintf(sesDefaultEndpointFmt, cfg.Region)
-	}
+	endpoint := apiEndpoint(cfg, nil, fmt.Sprintf(sesDefaultEndpointFmt, cfg.Region))
+	// Local AWS emulators serve every API on one endpoint.
+	snsEndpoint := apiEndpoint(cfg, nil, fmt.Sprintf(snsDefaultEndpointFmt, cfg.Region))
 	return &SES{
-		region:   cfg.Region,
-		endpoint: endpoint,
-		client:   client,
+		region:           cfg.Region,
+		endpoint:         endpoint,
+		snsEndpoint:      snsEndpoint,
+		configurationSet: cfg.ConfigurationSet,
+		client:           client,
 	}
 }
 
 func (s *SES) GetName() string { return "ses" }
 
+// SendsRaw implements RawSender.
+func (s *SES) SendsRaw() bool { return true }
+
 // Send delivers a message via the AWS SES v2 SendEmail API.
 func (s *SES) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
 	payload := s.buildPayload(msg)
@@ -56,7 +63,8 @@ func (s *SES) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
 		Headers: map[string]string{
 			"Content-Type": "application/json",
 		},
-		Body: body,
+		Body:    body,
+		Context: ctx,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ses: send request: %w", err)
@@ -90,24 +98,29 @@ func (s *SES) HealthCheck(ctx context.Context) error {
 		Headers: map[string]string{
 			"Content-Type": "application/json",
 		},
+		Context: ctx,
 	})
 	if err != nil {
 		return fmt.Errorf("ses: health check request: %w", err)
 	}
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("ses: health check returned status %d", resp.StatusCode)
+		return healthCheckError("ses", resp.StatusCode, resp.Body)
 	}
 	return nil
 }
 
 type sesPayload struct {
-	FromEmailAddress string         `json:"FromEmailAddress"`
-	Destination      sesDestination `json:"Destination"`
-	Content          sesContent     `json:"Content"`
+	FromEmailAddress               string         `json:"FromEmailAddress"`
+	Destination                    sesDestination `json:"Destination"`
+	ReplyToAddresses               []string       `json:"ReplyToAddresses,omitempty"`
+	FeedbackForwardingEmailAddress string         `json:"FeedbackForwardingEmailAddress,omitempty"`
+	ConfigurationSetName           string         `json:"ConfigurationSetName,omitempty"`
+	Content                        sesContent     `json:"Content"`
 }
 
 type sesDestination struct {
-	ToAddresses []string `json:"ToAddresses"`
+	ToAddresses  []string `json:"ToAddresses"`
+	BccAddresses []string `json:"BccAddresses,omitempty"`
 }
 
 type sesContent struct {
@@ -142,8 +155,22 @@ func (s *SES) buildPayload(msg *Message) sesPayload {
 	payload := sesPayload{
 		FromEmailAddress: msg.From,
 		Destination: sesDestination{
-			ToAddresses: msg.To,
+			ToAddresses:  msg.To,
+			BccAddresses: msg.Bcc,
 		},
+		// SES takes the Reply-To and bounce address as parameters: custom
+		// headers are not sent in Simple mode.
+		ReplyToAddresses:               msg.ReplyTo(),
+		FeedbackForwardingEmailAddress: msg.ReturnPath,
+		ConfigurationSetName:           s.configurationSet,
+	}
+
+	// A signed message is sent in Raw mode as is.
+	if msg.Raw != nil {
+		payload.Content = sesContent{
+			Raw: &sesRawContent{Data: base64.StdEncoding.EncodeToString(msg.Raw)},
+		}
+		return payload
 	}
 
 	// Use Raw mode when attachments are present.
@@ -252,3 +279,41 @@ func buildRawMIME(msg *Message) ([]byte, error) {
 	writer.Close()
 	return buf.Bytes(), nil
 }
+
+// rawMIMEGeneratedHeaders are the headers of Message.Headers that
+// buildRawMIME writes itself, or that the ESP sets.
+var rawMIMEGeneratedHeaders = map[string]bool{
+	"From":                      true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Subject":                   true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Return-Path":               true,
+}
+
+// buildRawMIMEWithHeaders builds the message buildRawMIME builds, preceded
+// by the headers of msg it does not write, such as Reply-To and Message-ID.
+// It is used by the APIs that take nothing but a MIME message.
+func buildRawMIMEWithHeaders(msg *Message) ([]byte, error) {
+	body, err := buildRawMIME(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		if !rawMIMEGeneratedHeaders[textproto.CanonicalMIMEHeaderKey(k)] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, msg.Headers[k])
+	}
+	return append([]byte(b.String()), body...), nil
+}
@@ -0,0 +1,310 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	snsDefaultEndpointFmt = "https://sns.%s.amazonaws.com"
+	snsAPIVersion         = "2010-03-31"
+
+	// sesEventDestination is the name of the event destination SetupEvents
+	// manages in the configuration set.
+	sesEventDestination = "smtp-proxy-webhook"
+
+	// snsPendingConfirmation is the subscription ARN SNS reports until the
+	// endpoint confirms the subscription.
+	snsPendingConfirmation = "PendingConfirmation"
+)
+
+// sesEventTypes are the SES events the webhook handles.
+var sesEventTypes = []string{"DELIVERY", "BOUNCE", "COMPLAINT"}
+
+// SESSetupOptions name the AWS resources SetupEvents creates or checks.
+type SESSetupOptions struct {
+	// ConfigurationSet is the SES configuration set messages are sent
+	// with; the provider's own configuration set when empty.
+	ConfigurationSet string
+	// TopicName is the SNS topic SES publishes events to.
+	TopicName string
+	// WebhookURL is the SES webhook endpoint subscribed to the topic.
+	WebhookURL string
+}
+
+// SESSetupResult reports the state of each resource after SetupEvents.
+// The Created fields tell which resources did not exist before.
+type SESSetupResult struct {
+	ConfigurationSet        string `json:"configuration_set"`
+	ConfigurationSetCreated bool   `json:"configuration_set_created"`
+	TopicARN                string `json:"topic_arn"`
+	EventDestination        string `json:"event_destination"`
+	EventDestinationCreated bool   `json:"event_destination_created"`
+	// EventDestinationUpdated is set when an existing destination pointed
+	// elsewhere or was disabled.
+	EventDestinationUpdated bool   `json:"event_destination_updated"`
+	SubscriptionARN         string `json:"subscription_arn,omitempty"`
+	SubscriptionCreated     bool   `json:"subscription_created"`
+	// SubscriptionConfirmed is false until the webhook has confirmed the
+	// subscription, which it does when SNS first posts to it.
+	SubscriptionConfirmed bool `json:"subscription_confirmed"`
+}
+
+// SetupEvents makes SES publish delivery, bounce and complaint events of
+// a configuration set to an SNS topic subscribed by the SES webhook. Each
+// step checks for the resource first, so SetupEvents can be run again to
+// validate or repair the setup. Subscriptions use raw message delivery.
+func (s *SES) SetupEvents(ctx context.Context, opts SESSetupOptions) (*SESSetupResult, error) {
+	if opts.ConfigurationSet == "" {
+		opts.ConfigurationSet = s.configurationSet
+	}
+	if opts.ConfigurationSet == "" || opts.TopicName == "" || opts.WebhookURL == "" {
+		return nil, errors.New("ses: configuration set, topic name and webhook URL are required")
+	}
+	res := &SESSetupResult{ConfigurationSet: opts.ConfigurationSet, EventDestination: sesEventDestination}
+
+	created, err := s.ensureConfigurationSet(ctx, opts.ConfigurationSet)
+	if err != nil {
+		return nil, err
+	}
+	res.ConfigurationSetCreated = created
+
+	// CreateTopic returns the existing topic when there is one.
+	var topic struct {
+		TopicArn string `xml:"CreateTopicResult>TopicArn"`
+	}
+	if err := s.snsCall(ctx, url.Values{"Action": {"CreateTopic"}, "Name": {opts.TopicName}}, &topic); err != nil {
+		return nil, err
+	}
+	res.TopicARN = topic.TopicArn
+
+	res.EventDestinationCreated, res.EventDestinationUpdated, err = s.ensureEventDestination(ctx, opts.ConfigurationSet, topic.TopicArn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureSubscription(ctx, topic.TopicArn, opts.WebhookURL, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ensureConfigurationSet creates the configuration set unless it exists.
+func (s *SES) ensureConfigurationSet(ctx context.Context, name string) (bool, error) {
+	resp, err := s.sesCall(ctx, "GET", "/v2/email/configuration-sets/"+url.PathEscape(name), nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == 200 {
+		return false, nil
+	}
+	if resp.StatusCode != 404 {
+		return false, ClassifyHTTPError("ses", resp.StatusCode, string(resp.Body))
+	}
+	resp, err = s.sesCall(ctx, "POST", "/v2/email/configuration-sets", map[string]string{"ConfigurationSetName": name})
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, ClassifyHTTPError("ses", resp.StatusCode, string(resp.Body))
+	}
+	return true, nil
+}
+
+type sesEventDestinationDef struct {
+	Enabled            bool               `json:"Enabled"`
+	MatchingEventTypes []string           `json:"MatchingEventTypes"`
+	SnsDestination     *sesSNSDestination `json:"SnsDestination,omitempty"`
+}
+
+type sesSNSDestination struct {
+	TopicArn string `json:"TopicArn"`
+}
+
+// ensureEventDestination creates the webhook event destination of the
+// configuration set, or updates it when it is disabled or publishes
+// elsewhere.
+func (s *SES) ensureEventDestination(ctx context.Context, configSet, topicARN string) (created, updated bool, err error) {
+	base := "/v2/email/configuration-sets/" + url.PathEscape(configSet) + "/event-destinations"
+	resp, err := s.sesCall(ctx, "GET", base, nil)
+	if err != nil {
+		return false, false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, false, ClassifyHTTPError("ses", resp.StatusCode, string(resp.Body))
+	}
+	var list struct {
+		EventDestinations []struct {
+			Name string `json:"Name"`
+			sesEventDestinationDef
+		} `json:"EventDestinations"`
+	}
+	if err := json.Unmarshal(resp.Body, &list); err != nil {
+		return false, false, fmt.Errorf("ses: decode event destinations: %w", err)
+	}
+
+	want := sesEventDestinationDef{
+		Enabled:            true,
+		MatchingEventTypes: sesEventTypes,
+		SnsDestination:     &sesSNSDestination{TopicArn: topicARN},
+	}
+
+	for _, d := range list.EventDestinations {
+		if d.Name != sesEventDestination {
+			continue
+		}
+		if d.Enabled && d.SnsDestination != nil && d.SnsDestination.TopicArn == topicARN && coversEventTypes(d.MatchingEventTypes) {
+			return false, false, nil
+		}
+		resp, err := s.sesCall(ctx, "PUT", base+"/"+sesEventDestination, map[string]any{"EventDestination": want})
+		if err != nil {
+			return false, false, err
+		}
+		if resp.StatusCode != 200 {
+			return false, false, ClassifyHTTPError("ses", resp.StatusCode, string(resp.Body))
+		}
+		return false, true, nil
+	}
+
+	resp, err = s.sesCall(ctx, "POST", base, map[string]any{
+		"EventDestinationName": sesEventDestination,
+		"EventDestination":     want,
+	})
+	if err != nil {
+		return false, false, err
+	}
+	if resp.StatusCode != 200 {
+		return false, false, ClassifyHTTPError("ses", resp.StatusCode, string(resp.Body))
+	}
+	return true, false, nil
+}
+
+// coversEventTypes reports whether types include every event the webhook
+// handles.
+func coversEventTypes(types []string) bool {
+	for _, want := range sesEventTypes {
+		found := false
+		for _, t := range types {
+			if strings.EqualFold(t, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureSubscription subscribes the webhook to the topic unless it is
+// subscribed already, and records whether the subscription is confirmed.
+func (s *SES) ensureSubscription(ctx context.Context, topicARN, webhookURL string, res *SESSetupResult) error {
+	protocol := "https"
+	if strings.HasPrefix(webhookURL, "http://") {
+		protocol = "http"
+	}
+
+	next := ""
+	for {
+		params := url.Values{"Action": {"ListSubscriptionsByTopic"}, "TopicArn": {topicARN}}
+		if next != "" {
+			params.Set("NextToken", next)
+		}
+		var list struct {
+			Subscriptions []struct {
+				SubscriptionArn string `xml:"SubscriptionArn"`
+				Endpoint        string `xml:"Endpoint"`
+			} `xml:"ListSubscriptionsByTopicResult>Subscriptions>member"`
+			NextToken string `xml:"ListSubscriptionsByTopicResult>NextToken"`
+		}
+		if err := s.snsCall(ctx, params, &list); err != nil {
+			return err
+		}
+		for _, sub := range list.Subscriptions {
+			if sub.Endpoint == webhookURL {
+				res.SubscriptionConfirmed = sub.SubscriptionArn != snsPendingConfirmation
+				if res.SubscriptionConfirmed {
+					res.SubscriptionARN = sub.SubscriptionArn
+				}
+				return nil
+			}
+		}
+		if list.NextToken == "" {
+			break
+		}
+		next = list.NextToken
+	}
+
+	var sub struct {
+		SubscriptionArn string `xml:"SubscribeResult>SubscriptionArn"`
+	}
+	if err := s.snsCall(ctx, url.Values{
+		"Action":                   {"Subscribe"},
+		"TopicArn":                 {topicARN},
+		"Protocol":                 {protocol},
+		"Endpoint":                 {webhookURL},
+		"Attributes.entry.1.key":   {"RawMessageDelivery"},
+		"Attributes.entry.1.value": {"true"},
+	}, &sub); err != nil {
+		return err
+	}
+	res.SubscriptionCreated = true
+	// HTTP(S) subscriptions are answered with "pending confirmation".
+	res.SubscriptionConfirmed = strings.HasPrefix(sub.SubscriptionArn, "arn:")
+	if res.SubscriptionConfirmed {
+		res.SubscriptionARN = sub.SubscriptionArn
+	}
+	return nil
+}
+
+// sesCall makes a JSON request to the SES v2 API. The response is
+// returned for any status.
+func (s *SES) sesCall(ctx context.Context, method, path string, body any) (*HTTPResponse, error) {
+	req := &HTTPRequest{
+		Method:  method,
+		URL:     s.endpoint + path,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Context: ctx,
+	}
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("ses: marshal request: %w", err)
+		}
+		req.Body = b
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ses: %s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// snsCall makes a request to the SNS query API and decodes its XML
+// response into out.
+func (s *SES) snsCall(ctx context.Context, params url.Values, out any) error {
+	params.Set("Version", snsAPIVersion)
+	resp, err := s.client.Do(&HTTPRequest{
+		Method:  "POST",
+		URL:     s.snsEndpoint + "/",
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:    []byte(params.Encode()),
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("sns: %s: %w", params.Get("Action"), err)
+	}
+	if resp.StatusCode != 200 {
+		return ClassifyHTTPError("sns", resp.StatusCode, string(resp.Body))
+	}
+	if err := xml.Unmarshal(resp.Body, out); err != nil {
+		return fmt.Errorf("sns: decode %s response: %w", params.Get("Action"), err)
+	}
+	return nil
+}
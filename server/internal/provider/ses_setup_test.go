@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeAWS simulates the SES v2 and SNS APIs SetupEvents calls.
+type fakeAWS struct {
+	configSetExists bool
+	destinations    string
+	subscriptions   string
+	calls           []string
+	subscribeForm   url.Values
+}
+
+func (f *fakeAWS) Do(req *HTTPRequest) (*HTTPResponse, error) {
+	path := strings.TrimPrefix(req.URL, "https://aws.test")
+	if path == "/" {
+		form, _ := url.ParseQuery(string(req.Body))
+		f.calls = append(f.calls, "SNS "+form.Get("Action"))
+		switch form.Get("Action") {
+		case "CreateTopic":
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`<CreateTopicResponse><CreateTopicResult><TopicArn>arn:aws:sns:us-east-1:1:events</TopicArn></CreateTopicResult></CreateTopicResponse>`)}, nil
+		case "ListSubscriptionsByTopic":
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`<ListSubscriptionsByTopicResponse><ListSubscriptionsByTopicResult><Subscriptions>` + f.subscriptions + `</Subscriptions></ListSubscriptionsByTopicResult></ListSubscriptionsByTopicResponse>`)}, nil
+		case "Subscribe":
+			f.subscribeForm = form
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`<SubscribeResponse><SubscribeResult><SubscriptionArn>pending confirmation</SubscriptionArn></SubscribeResult></SubscribeResponse>`)}, nil
+		}
+		return &HTTPResponse{StatusCode: 400}, nil
+	}
+
+	f.calls = append(f.calls, req.Method+" "+path)
+	switch {
+	case req.Method == "GET" && path == "/v2/email/configuration-sets/app":
+		if f.configSetExists {
+			return &HTTPResponse{StatusCode: 200, Body: []byte(`{}`)}, nil
+		}
+		return &HTTPResponse{StatusCode: 404, Body: []byte(`{"message":"not found"}`)}, nil
+	case req.Method == "GET" && strings.HasSuffix(path, "/event-destinations"):
+		return &HTTPResponse{StatusCode: 200, Body: []byte(`{"EventDestinations":[` + f.destinations + `]}`)}, nil
+	}
+	return &HTTPResponse{StatusCode: 200, Body: []byte(`{}`)}, nil
+}
+
+func TestSES_SetupEvents_Fresh(t *testing.T) {
+	aws := &fakeAWS{}
+	s := NewSES(ProviderConfig{Region: "us-east-1", Endpoint: "https://aws.test"}, aws)
+
+	res, err := s.SetupEvents(context.Background(), SESSetupOptions{
+		ConfigurationSet: "app",
+		TopicName:        "events",
+		WebhookURL:       "https://relay.example.com/api/v1/webhooks/ses",
+	})
+	if err != nil {
+		t.Fatalf("SetupEvents: %v", err)
+	}
+	if !res.ConfigurationSetCreated || !res.EventDestinationCreated || !res.SubscriptionCreated || res.SubscriptionConfirmed {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	if res.TopicARN != "arn:aws:sns:us-east-1:1:events" {
+		t.Errorf("TopicARN = %q", res.TopicARN)
+	}
+	want := []string{
+		"GET /v2/email/configuration-sets/app",
+		"POST /v2/email/configuration-sets",
+		"SNS CreateTopic",
+		"GET /v2/email/configuration-sets/app/event-destinations",
+		"POST /v2/email/configuration-sets/app/event-destinations",
+		"SNS ListSubscriptionsByTopic",
+		"SNS Subscribe",
+	}
+	if strings.Join(aws.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(aws.calls, "\n"), strings.Join(want, "\n"))
+	}
+	if aws.subscribeForm.Get("Protocol") != "https" || aws.subscribeForm.Get("Attributes.entry.1.value") != "true" {
+		t.Errorf("unexpected Subscribe params: %v", aws.subscribeForm)
+	}
+}
+
+func TestSES_SetupEvents_AlreadyConfigured(t *testing.T) {
+	aws := &fakeAWS{
+		configSetExists: true,
+		destinations:    `{"Name":"smtp-proxy-webhook","Enabled":true,"MatchingEventTypes":["DELIVERY","BOUNCE","COMPLAINT","SEND"],"SnsDestination":{"TopicArn":"arn:aws:sns:us-east-1:1:events"}}`,
+		subscriptions:   `<member><SubscriptionArn>arn:aws:sns:us-east-1:1:events:abc</SubscriptionArn><Endpoint>https://relay.example.com/api/v1/webhooks/ses</Endpoint></member>`,
+	}
+	s := NewSES(ProviderConfig{Region: "us-east-1", Endpoint: "https://aws.test", ConfigurationSet: "app"}, aws)
+
+	res, err := s.SetupEvents(context.Background(), SESSetupOptions{
+		TopicName:  "events",
+		WebhookURL: "https://relay.example.com/api/v1/webhooks/ses",
+	})
+	if err != nil {
+		t.Fatalf("SetupEvents: %v", err)
+	}
+	if res.ConfigurationSetCreated || res.EventDestinationCreated || res.EventDestinationUpdated || res.SubscriptionCreated {
+		t.Errorf("expected nothing to change: %+v", res)
+	}
+	if !res.SubscriptionConfirmed || res.SubscriptionARN != "arn:aws:sns:us-east-1:1:events:abc" {
+		t.Errorf("unexpected subscription: %+v", res)
+	}
+}
+
+func TestSES_SetupEvents_UpdatesStaleDestination(t *testing.T) {
+	aws := &fakeAWS{
+		configSetExists: true,
+		destinations:    `{"Name":"smtp-proxy-webhook","Enabled":false,"MatchingEventTypes":["BOUNCE"],"SnsDestination":{"TopicArn":"arn:aws:sns:us-east-1:1:old"}}`,
+	}
+	s := NewSES(ProviderConfig{Region: "us-east-1", Endpoint: "https://aws.test"}, aws)
+
+	res, err := s.SetupEvents(context.Background(), SESSetupOptions{
+		ConfigurationSet: "app",
+		TopicName:        "events",
+		WebhookURL:       "https://relay.example.com/api/v1/webhooks/ses",
+	})
+	if err != nil {
+		t.Fatalf("SetupEvents: %v", err)
+	}
+	if !res.EventDestinationUpdated || res.EventDestinationCreated {
+		t.Errorf("expected the destination to be updated: %+v", res)
+	}
+}
+
+func TestSES_SetupEvents_RequiresOptions(t *testing.T) {
+	s := NewSES(ProviderConfig{Region: "us-east-1"}, &fakeAWS{})
+	if _, err := s.SetupEvents(context.Background(), SESSetupOptions{TopicName: "events", WebhookURL: "https://x"}); err == nil {
+		t.Error("expected an error without a configuration set")
+	}
+}
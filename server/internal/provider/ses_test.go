@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"testing"
 )
@@ -33,6 +34,34 @@ func TestSES_buildPayload_PlainTextOnly(t *testing.T) {
 	}
 }
 
+func TestSES_buildPayload_ReplyToAndBounce(t *testing.T) {
+	s := &SES{}
+	msg := &Message{
+		From:       "sender@example.com",
+		To:         []string{"a@example.com"},
+		Headers:    map[string]string{"Reply-To": "Support <support@example.com>, help@example.com"},
+		ReturnPath: "bounces@example.com",
+		Body:       []byte("body"),
+	}
+
+	payload := s.buildPayload(msg)
+
+	if len(payload.ReplyToAddresses) != 2 || payload.ReplyToAddresses[0] != "support@example.com" || payload.ReplyToAddresses[1] != "help@example.com" {
+		t.Errorf("unexpected ReplyToAddresses: %v", payload.ReplyToAddresses)
+	}
+	if payload.FeedbackForwardingEmailAddress != "bounces@example.com" {
+		t.Errorf("expected bounce address, got %q", payload.FeedbackForwardingEmailAddress)
+	}
+}
+
+func TestSES_buildPayload_ConfigurationSet(t *testing.T) {
+	s := NewSES(ProviderConfig{Region: "us-east-1", ConfigurationSet: "transactional"}, nil)
+	payload := s.buildPayload(&Message{From: "a@example.com", To: []string{"b@example.com"}, Body: []byte("body")})
+	if payload.ConfigurationSetName != "transactional" {
+		t.Errorf("ConfigurationSetName = %q, want transactional", payload.ConfigurationSetName)
+	}
+}
+
 func TestSES_buildPayload_HTMLAndText(t *testing.T) {
 	s := &SES{}
 	msg := &Message{
@@ -92,6 +121,33 @@ func TestSES_buildPayload_WithAttachments_UsesRaw(t *testing.T) {
 	}
 }
 
+func TestSES_buildPayload_SignedMessageSentRaw(t *testing.T) {
+	s := &SES{}
+	raw := []byte("From: sender@example.com\r\nContent-Type: multipart/signed; boundary=b\r\n\r\n--b--\r\n")
+	msg := &Message{
+		From:     "sender@example.com",
+		To:       []string{"a@example.com"},
+		Bcc:      []string{"archive@example.com"},
+		TextBody: "hello",
+		Raw:      raw,
+	}
+
+	payload := s.buildPayload(msg)
+
+	if payload.Content.Raw == nil || payload.Content.Simple != nil {
+		t.Fatal("expected only Raw content for a signed message")
+	}
+	if payload.Content.Raw.Data != base64.StdEncoding.EncodeToString(raw) {
+		t.Error("expected the signed message to be sent as is")
+	}
+	if len(payload.Destination.BccAddresses) != 1 {
+		t.Errorf("expected the Bcc envelope to be kept, got %v", payload.Destination.BccAddresses)
+	}
+	if !SendsRaw(s) {
+		t.Error("expected SES to send raw messages")
+	}
+}
+
 func TestSES_buildPayload_NoAttachments_NoRaw(t *testing.T) {
 	s := &SES{}
 	msg := &Message{
@@ -22,6 +22,9 @@ func NewStdout(_ ProviderConfig) *Stdout {
 
 func (s *Stdout) GetName() string { return "stdout" }
 
+// SendsRaw implements RawSender.
+func (s *Stdout) SendsRaw() bool { return true }
+
 // Send prints the message details to stdout and returns a successful result.
 func (s *Stdout) Send(_ context.Context, msg *Message) (*DeliveryResult, error) {
 	var b strings.Builder
@@ -29,11 +32,20 @@ func (s *Stdout) Send(_ context.Context, msg *Message) (*DeliveryResult, error)
 	fmt.Fprintf(&b, "ID:      %s\n", msg.ID)
 	fmt.Fprintf(&b, "From:    %s\n", msg.From)
 	fmt.Fprintf(&b, "To:      %s\n", strings.Join(msg.To, ", "))
+	if len(msg.Bcc) > 0 {
+		fmt.Fprintf(&b, "Bcc:     %s\n", strings.Join(msg.Bcc, ", "))
+	}
 	fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
+	if msg.ReturnPath != "" {
+		fmt.Fprintf(&b, "Bounce:  %s\n", msg.ReturnPath)
+	}
 	for k, v := range msg.Headers {
 		fmt.Fprintf(&b, "Header:  %s: %s\n", k, v)
 	}
 	fmt.Fprintf(&b, "Body:    (%d bytes)\n", len(msg.Body))
+	if msg.Raw != nil {
+		fmt.Fprintf(&b, "Raw:     (%d bytes, signed)\n", len(msg.Raw))
+	}
 	if msg.TextBody != "" {
 		fmt.Fprintf(&b, "Text:    (%d chars)\n", len(msg.TextBody))
 	}
@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	zeptomailDefaultEndpoint = "https://api.zeptomail.com"
+	zeptomailSendPath        = "/v1.1/email"
+	// zeptomailAuthScheme prefixes send mail tokens in the Authorization
+	// header. The ZeptoMail console shows tokens with it.
+	zeptomailAuthScheme = "Zoho-enczapikey "
+)
+
+// ZeptoMail implements the Provider interface for the Zoho ZeptoMail API.
+// The api_key is a send mail token of a mail agent.
+type ZeptoMail struct {
+	token    string
+	endpoint string
+	client   HTTPClient
+}
+
+// NewZeptoMail creates a ZeptoMail provider from the given configuration.
+func NewZeptoMail(cfg ProviderConfig, client HTTPClient) *ZeptoMail {
+	return &ZeptoMail{
+		token:    strings.TrimPrefix(strings.TrimSpace(cfg.APIKey), zeptomailAuthScheme),
+		endpoint: apiEndpoint(cfg, zeptomailRegionEndpoints, zeptomailDefaultEndpoint),
+		client:   client,
+	}
+}
+
+func (z *ZeptoMail) GetName() string { return "zeptomail" }
+
+// Send delivers a message via the ZeptoMail email API.
+func (z *ZeptoMail) Send(ctx context.Context, msg *Message) (*DeliveryResult, error) {
+	body, err := json.Marshal(z.buildPayload(msg))
+	if err != nil {
+		return nil, fmt.Errorf("zeptomail: marshal request: %w", err)
+	}
+
+	resp, err := z.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    z.endpoint + zeptomailSendPath,
+		Headers: map[string]string{
+			"Authorization": zeptomailAuthScheme + z.token,
+			"Content-Type":  "application/json",
+			"Accept":        "application/json",
+		},
+		Body:    body,
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("zeptomail: send request: %w", err)
+	}
+
+	// ZeptoMail returns 201 with a request ID, which its webhooks carry.
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var zmResp zeptomailResponse
+		_ = json.Unmarshal(resp.Body, &zmResp)
+		return &DeliveryResult{
+			ProviderMessageID: zmResp.RequestID,
+			Status:            StatusSent,
+			Timestamp:         time.Now(),
+			Metadata: map[string]string{
+				"status_code": fmt.Sprintf("%d", resp.StatusCode),
+			},
+		}, nil
+	}
+
+	return nil, ClassifyHTTPError("zeptomail", resp.StatusCode, string(resp.Body))
+}
+
+// HealthCheck verifies the ZeptoMail token. Send mail tokens can call no
+// read-only API, so an empty message is posted: ZeptoMail rejects it with
+// 400 when the token is valid and with 401 when it is not.
+func (z *ZeptoMail) HealthCheck(ctx context.Context) error {
+	resp, err := z.client.Do(&HTTPRequest{
+		Method: "POST",
+		URL:    z.endpoint + zeptomailSendPath,
+		Headers: map[string]string{
+			"Authorization": zeptomailAuthScheme + z.token,
+			"Content-Type":  "application/json",
+			"Accept":        "application/json",
+		},
+		Body:    []byte("{}"),
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("zeptomail: health check request: %w", err)
+	}
+	if resp.StatusCode != 400 {
+		return healthCheckError("zeptomail", resp.StatusCode, resp.Body)
+	}
+	return nil
+}
+
+// zeptomailPayload matches the ZeptoMail send email JSON schema.
+type zeptomailPayload struct {
+	From            zeptomailAddress       `json:"from"`
+	To              []zeptomailRecipient   `json:"to"`
+	Bcc             []zeptomailRecipient   `json:"bcc,omitempty"`
+	ReplyTo         []zeptomailAddress     `json:"reply_to,omitempty"`
+	Subject         string                 `json:"subject"`
+	HTMLBody        string                 `json:"htmlbody,omitempty"`
+	TextBody        string                 `json:"textbody,omitempty"`
+	MIMEHeaders     map[string]string      `json:"mime_headers,omitempty"`
+	ClientReference string                 `json:"client_reference,omitempty"`
+	Attachments     []zeptomailAttachment  `json:"attachments,omitempty"`
+	InlineImages    []zeptomailInlineImage `json:"inline_images,omitempty"`
+}
+
+type zeptomailAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+}
+
+type zeptomailRecipient struct {
+	EmailAddress zeptomailAddress `json:"email_address"`
+}
+
+type zeptomailAttachment struct {
+	Content  string `json:"content"` // base64 encoded
+	MimeType string `json:"mime_type"`
+	Name     string `json:"name"`
+}
+
+type zeptomailInlineImage struct {
+	Content  string `json:"content"` // base64 encoded
+	MimeType string `json:"mime_type"`
+	CID      string `json:"cid"`
+}
+
+type zeptomailResponse struct {
+	RequestID string `json:"request_id"`
+	Message   string `json:"message"`
+}
+
+// buildPayload builds the ZeptoMail request for msg. The message ID is
+// sent as the client reference, which ZeptoMail returns in its webhooks.
+func (z *ZeptoMail) buildPayload(msg *Message) zeptomailPayload {
+	payload := zeptomailPayload{
+		From:            zeptomailFrom(msg.From),
+		Subject:         msg.Subject,
+		HTMLBody:        msg.HTMLBody,
+		TextBody:        msg.TextBody,
+		MIMEHeaders:     msg.headersExcept(apiReservedHeaders...),
+		ClientReference: msg.ID,
+	}
+	if payload.HTMLBody == "" && payload.TextBody == "" {
+		payload.TextBody = string(msg.Body)
+	}
+	for _, addr := range msg.To {
+		payload.To = append(payload.To, zeptomailRecipient{EmailAddress: zeptomailAddress{Address: addr}})
+	}
+	for _, addr := range msg.Bcc {
+		payload.Bcc = append(payload.Bcc, zeptomailRecipient{EmailAddress: zeptomailAddress{Address: addr}})
+	}
+	for _, addr := range msg.ReplyTo() {
+		payload.ReplyTo = append(payload.ReplyTo, zeptomailAddress{Address: addr})
+	}
+	for _, att := range msg.Attachments {
+		content := base64.StdEncoding.EncodeToString(att.Content)
+		if att.IsInline && att.ContentID != "" {
+			payload.InlineImages = append(payload.InlineImages, zeptomailInlineImage{
+				Content:  content,
+				MimeType: att.ContentType,
+				CID:      att.ContentID,
+			})
+			continue
+		}
+		payload.Attachments = append(payload.Attachments, zeptomailAttachment{
+			Content:  content,
+			MimeType: att.ContentType,
+			Name:     att.Filename,
+		})
+	}
+	return payload
+}
+
+// zeptomailFrom splits a From address into ZeptoMail's address and name.
+func zeptomailFrom(from string) zeptomailAddress {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return zeptomailAddress{Address: addr.Address, Name: addr.Name}
+	}
+	return zeptomailAddress{Address: from}
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestZeptoMail_buildPayload(t *testing.T) {
+	z := &ZeptoMail{}
+	msg := &Message{
+		ID:       "msg-1",
+		From:     "Shop <shop@example.com>",
+		To:       []string{"a@example.com"},
+		Bcc:      []string{"audit@example.com"},
+		Subject:  "Test",
+		HTMLBody: `<img src="cid:logo">`,
+		Headers:  map[string]string{"Reply-To": "support@example.com", "X-Campaign": "spring"},
+		Attachments: []Attachment{
+			{Filename: "a.pdf", ContentType: "application/pdf", Content: []byte("pdf")},
+			{Filename: "logo.png", ContentType: "image/png", Content: []byte("png"), ContentID: "logo", IsInline: true},
+		},
+	}
+
+	payload := z.buildPayload(msg)
+
+	if payload.From != (zeptomailAddress{Address: "shop@example.com", Name: "Shop"}) || payload.ClientReference != "msg-1" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if len(payload.To) != 1 || payload.To[0].EmailAddress.Address != "a@example.com" || len(payload.Bcc) != 1 {
+		t.Errorf("recipients = %+v, %+v", payload.To, payload.Bcc)
+	}
+	if len(payload.ReplyTo) != 1 || payload.ReplyTo[0].Address != "support@example.com" {
+		t.Errorf("reply_to = %+v", payload.ReplyTo)
+	}
+	if len(payload.MIMEHeaders) != 1 || payload.MIMEHeaders["X-Campaign"] != "spring" {
+		t.Errorf("mime_headers = %v", payload.MIMEHeaders)
+	}
+	if len(payload.Attachments) != 1 || payload.Attachments[0].Name != "a.pdf" {
+		t.Errorf("attachments = %+v", payload.Attachments)
+	}
+	if len(payload.InlineImages) != 1 || payload.InlineImages[0].CID != "logo" {
+		t.Errorf("inline_images = %+v", payload.InlineImages)
+	}
+}
+
+func TestZeptoMail_Send(t *testing.T) {
+	var got *HTTPRequest
+	client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+		got = req
+		return &HTTPResponse{StatusCode: 201, Body: []byte(`{"data":[{"code":"EM_104","message":"Email request received"}],"message":"OK","request_id":"2d6f.4c1d","object":"email"}`)}, nil
+	}}
+	// Tokens copied from the console keep their scheme.
+	z := NewZeptoMail(ProviderConfig{APIKey: "Zoho-enczapikey wSsVR61", Region: "eu"}, client)
+
+	result, err := z.Send(context.Background(), &Message{From: "a@example.com", To: []string{"b@example.com"}, Body: []byte("plain")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProviderMessageID != "2d6f.4c1d" {
+		t.Errorf("ProviderMessageID = %q", result.ProviderMessageID)
+	}
+	if got.URL != "https://api.zeptomail.eu"+zeptomailSendPath || got.Headers["Authorization"] != "Zoho-enczapikey wSsVR61" {
+		t.Errorf("request = %s %v", got.URL, got.Headers)
+	}
+	var payload zeptomailPayload
+	if err := json.Unmarshal(got.Body, &payload); err != nil || payload.TextBody != "plain" {
+		t.Errorf("textbody = %q, %v", payload.TextBody, err)
+	}
+}
+
+func TestZeptoMail_HealthCheck(t *testing.T) {
+	for status, healthy := range map[int]bool{400: true, 401: false, 500: false} {
+		client := &mockHTTPClient2{doFn: func(req *HTTPRequest) (*HTTPResponse, error) {
+			return &HTTPResponse{StatusCode: status}, nil
+		}}
+		err := NewZeptoMail(ProviderConfig{APIKey: "tok"}, client).HealthCheck(context.Background())
+		if (err == nil) != healthy {
+			t.Errorf("status %d: HealthCheck() error = %v, healthy = %v", status, err, healthy)
+		}
+	}
+}
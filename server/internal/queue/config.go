@@ -4,7 +4,9 @@ import "time"
 
 // Config holds configuration for the queue system.
 type Config struct {
-	// Type selects the queue backend: "redis" (default) or "sqs".
+	// Type selects the queue backend: "redis" (default), "sqs" or "memory".
+	// The memory backend keeps messages in-process and needs no external
+	// service; queued messages are lost on restart.
 	Type            string        `mapstructure:"type"`
 	RedisAddr       string        `mapstructure:"redis_addr"`
 	RedisPassword   string        `mapstructure:"redis_password"`
@@ -21,6 +23,10 @@ type Config struct {
 	SQSRegion     string `mapstructure:"sqs_region"`
 	SQSWaitTime   int32  `mapstructure:"sqs_wait_time"`          // long poll seconds, default 20
 	SQSVisTimeout int32  `mapstructure:"sqs_visibility_timeout"` // seconds, default 30
+
+	// MemoryBufferSize is the number of messages the memory backend holds
+	// before Enqueue blocks. Zero selects DefaultMemoryBufferSize.
+	MemoryBufferSize int `mapstructure:"memory_buffer_size"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -33,5 +39,7 @@ func DefaultConfig() Config {
 		ProcessTimeout:  30 * time.Second,
 		ShutdownTimeout: 30 * time.Second,
 		MaxRetries:      5,
+
+		MemoryBufferSize: DefaultMemoryBufferSize,
 	}
 }
@@ -45,6 +45,14 @@ func NewQueue(
 
 		return enqueuer, dequeuer, dlq, nil
 
+	case "memory":
+		retry := NewRetryStrategy(cfg.MaxRetries)
+		enqueuer := NewMemoryEnqueuer(cfg.MemoryBufferSize)
+		dlq := NewMemoryDLQ(enqueuer)
+		dequeuer := NewMemoryDequeuer(enqueuer, dlq, handler, retry, cfg, log)
+
+		return enqueuer, dequeuer, dlq, nil
+
 	default:
 		return nil, nil, nil, fmt.Errorf("unknown queue type: %s", cfg.Type)
 	}
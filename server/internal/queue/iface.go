@@ -18,7 +18,15 @@ type Dequeuer interface {
 // DeadLetterQueue manages failed messages.
 type DeadLetterQueue interface {
 	MoveToDLQ(ctx context.Context, msg *Message, reason string) error
-	Reprocess(ctx context.Context, tenantID string, messageIDs []string) (int, error)
+	// List returns up to limit entries of the tenant's DLQ, oldest first,
+	// without removing them.
+	List(ctx context.Context, tenantID string, limit int) ([]DLQEntry, error)
+	// Get returns a single entry, or ErrDLQEntryNotFound.
+	Get(ctx context.Context, tenantID, entryID string) (*DLQEntry, error)
+	// Reprocess re-enqueues the given entries of the tenant to the primary
+	// queue and removes them from the DLQ. Entries of other tenants are
+	// skipped. It returns the number of entries reprocessed.
+	Reprocess(ctx context.Context, tenantID string, entryIDs []string, opts ReprocessOptions) (int, error)
 }
 
 // MessageHandler processes a single queue message. Implementations define
@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// MemoryDequeuer manages a pool of worker goroutines that consume and
+// process messages from a MemoryEnqueuer. Failed messages are retried and
+// dead-lettered exactly as by the Redis and SQS dequeuers.
+type MemoryDequeuer struct {
+	enqueuer *MemoryEnqueuer
+	dlq      DeadLetterQueue
+	handler  MessageHandler
+	retry    *RetryStrategy
+	config   Config
+	log      zerolog.Logger
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+// NewMemoryDequeuer creates a MemoryDequeuer consuming the messages of
+// enqueuer. The handler defines message processing logic.
+func NewMemoryDequeuer(
+	enqueuer *MemoryEnqueuer,
+	dlq DeadLetterQueue,
+	handler MessageHandler,
+	retry *RetryStrategy,
+	cfg Config,
+	log zerolog.Logger,
+) *MemoryDequeuer {
+	if cfg.WorkerCount <= 0 {
+		cfg.WorkerCount = 10
+	}
+	if cfg.ProcessTimeout <= 0 {
+		cfg.ProcessTimeout = 30 * time.Second
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	return &MemoryDequeuer{
+		enqueuer: enqueuer,
+		dlq:      dlq,
+		handler:  handler,
+		retry:    retry,
+		config:   cfg,
+		log:      log,
+	}
+}
+
+// Start launches the configured number of worker goroutines.
+func (d *MemoryDequeuer) Start(ctx context.Context) error {
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	for i := range d.config.WorkerCount {
+		d.wg.Add(1)
+		go d.runWorker(ctx, fmt.Sprintf("worker-%d", i))
+	}
+
+	d.log.Info().
+		Int("worker_count", d.config.WorkerCount).
+		Msg("memory dequeuer started")
+
+	return nil
+}
+
+// Stop signals all workers to stop and waits up to the configured shutdown
+// timeout for them to finish processing. Messages still buffered stay in
+// the enqueuer.
+func (d *MemoryDequeuer) Stop(ctx context.Context) error {
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.log.Info().Msg("memory dequeuer stopped gracefully")
+		return nil
+	case <-time.After(d.config.ShutdownTimeout):
+		d.log.Warn().Msg("memory dequeuer shutdown timed out")
+		return fmt.Errorf("shutdown timed out after %s", d.config.ShutdownTimeout)
+	}
+}
+
+// runWorker is the main loop for a single worker goroutine.
+func (d *MemoryDequeuer) runWorker(ctx context.Context, workerName string) {
+	defer d.wg.Done()
+
+	d.log.Info().Str("consumer", workerName).Msg("worker started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.log.Info().Str("consumer", workerName).Msg("worker stopping")
+			return
+		case entry := <-d.enqueuer.ch:
+			d.processMessage(ctx, entry)
+		}
+	}
+}
+
+// processMessage decodes an entry, invokes the handler, and retries or
+// dead-letters the message on failure.
+func (d *MemoryDequeuer) processMessage(ctx context.Context, entry memoryEntry) {
+	start := time.Now()
+
+	var msg Message
+	if err := json.Unmarshal(entry.data, &msg); err != nil {
+		d.log.Error().Err(err).Str("entry_id", entry.id).Msg("failed to unmarshal message")
+		return
+	}
+
+	processCtx, cancel := context.WithTimeout(ctx, d.config.ProcessTimeout)
+	defer cancel()
+
+	err := d.handler.HandleMessage(processCtx, &msg)
+
+	duration := time.Since(start).Seconds()
+	MessageProcessingDuration.Observe(duration)
+
+	if err == nil {
+		MessagesProcessedTotal.WithLabelValues("sent").Inc()
+		return
+	}
+
+	d.log.Error().
+		Err(err).
+		Str("message_id", msg.ID).
+		Int("retry_count", msg.RetryCount).
+		Msg("message processing failed")
+
+	msg.RetryCount++
+
+	if d.retry.ShouldRetry(msg.RetryCount) {
+		backoff := d.retry.NextBackoff(msg.RetryCount - 1)
+		d.log.Info().
+			Str("message_id", msg.ID).
+			Int("retry_count", msg.RetryCount).
+			Dur("backoff", backoff).
+			Msg("scheduling retry")
+
+		if enqErr := d.enqueuer.EnqueueWithDelay(ctx, &msg, backoff); enqErr != nil {
+			d.log.Error().Err(enqErr).Str("message_id", msg.ID).Msg("failed to re-enqueue message for retry")
+		}
+
+		MessagesProcessedTotal.WithLabelValues("failed").Inc()
+		return
+	}
+
+	d.log.Warn().
+		Str("message_id", msg.ID).
+		Int("retry_count", msg.RetryCount).
+		Msg("max retries exhausted, moving to DLQ")
+
+	if dlqErr := d.dlq.MoveToDLQ(ctx, &msg, err.Error()); dlqErr != nil {
+		d.log.Error().Err(dlqErr).Str("message_id", msg.ID).Msg("failed to move to DLQ")
+	}
+}
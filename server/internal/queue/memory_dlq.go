@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryDLQ is an in-process dead letter queue, kept per tenant in the
+// order messages were moved to it. Entries are lost when the process exits.
+type MemoryDLQ struct {
+	enqueuer Enqueuer
+
+	mu      sync.Mutex
+	seq     uint64
+	entries map[string][]memoryEntry // tenant ID -> encoded DLQMessages
+}
+
+// NewMemoryDLQ creates a MemoryDLQ that reprocesses entries through
+// enqueuer.
+func NewMemoryDLQ(enqueuer Enqueuer) *MemoryDLQ {
+	return &MemoryDLQ{
+		enqueuer: enqueuer,
+		entries:  make(map[string][]memoryEntry),
+	}
+}
+
+// MoveToDLQ appends a failed message to the tenant's dead letter queue.
+func (d *MemoryDLQ) MoveToDLQ(_ context.Context, msg *Message, reason string) error {
+	dlqMsg := DLQMessage{
+		OriginalMessage: msg,
+		FailureReason:   reason,
+		FinalError:      reason,
+		MovedAt:         time.Now(),
+	}
+
+	data, err := json.Marshal(dlqMsg)
+	if err != nil {
+		return fmt.Errorf("marshal dlq message: %w", err)
+	}
+
+	d.mu.Lock()
+	d.seq++
+	d.entries[msg.TenantID] = append(d.entries[msg.TenantID], memoryEntry{
+		id:   strconv.FormatUint(d.seq, 10),
+		data: data,
+	})
+	d.mu.Unlock()
+
+	DLQMessagesTotal.WithLabelValues(reason).Inc()
+	MessagesProcessedTotal.WithLabelValues("dlq").Inc()
+
+	return nil
+}
+
+// List returns up to limit entries of the tenant's DLQ, oldest first.
+func (d *MemoryDLQ) List(_ context.Context, tenantID string, limit int) ([]DLQEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stored := d.entries[tenantID]
+	if limit > 0 && len(stored) > limit {
+		stored = stored[:limit]
+	}
+	entries := make([]DLQEntry, 0, len(stored))
+	for _, e := range stored {
+		entry, err := decodeDLQEntry(e.id, string(e.data))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// Get returns the DLQ entry with the given ID.
+func (d *MemoryDLQ) Get(_ context.Context, tenantID, entryID string) (*DLQEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.indexLocked(tenantID, entryID)
+	if i < 0 {
+		return nil, ErrDLQEntryNotFound
+	}
+	e := d.entries[tenantID][i]
+	return decodeDLQEntry(e.id, string(e.data))
+}
+
+// Reprocess removes entries from the DLQ, resets their retry count, applies
+// opts and re-enqueues them to the primary queue. It returns the number of
+// entries successfully reprocessed.
+func (d *MemoryDLQ) Reprocess(ctx context.Context, tenantID string, entryIDs []string, opts ReprocessOptions) (int, error) {
+	reprocessed := 0
+
+	for _, entryID := range entryIDs {
+		entry, err := d.Get(ctx, tenantID, entryID)
+		if err != nil {
+			continue
+		}
+
+		msg := entry.Message.OriginalMessage
+		opts.apply(msg)
+		if _, err := d.enqueuer.Enqueue(ctx, msg); err != nil {
+			return reprocessed, fmt.Errorf("re-enqueue message %s: %w", msg.ID, err)
+		}
+
+		d.mu.Lock()
+		if i := d.indexLocked(tenantID, entryID); i >= 0 {
+			stored := d.entries[tenantID]
+			d.entries[tenantID] = append(stored[:i:i], stored[i+1:]...)
+		}
+		d.mu.Unlock()
+
+		reprocessed++
+	}
+
+	return reprocessed, nil
+}
+
+// indexLocked returns the position of entryID in the tenant's DLQ, or -1.
+// d.mu must be held.
+func (d *MemoryDLQ) indexLocked(tenantID, entryID string) int {
+	for i, e := range d.entries[tenantID] {
+		if e.id == entryID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMemoryBufferSize is the number of messages a MemoryEnqueuer holds
+// when Config.MemoryBufferSize is not set.
+const DefaultMemoryBufferSize = 10000
+
+// memoryEntry is a message waiting in a MemoryEnqueuer. Messages are kept
+// JSON-encoded, as in Redis and SQS, so a handler modifying the message it
+// was given cannot change what is queued.
+type memoryEntry struct {
+	id   string
+	data []byte
+}
+
+// MemoryEnqueuer publishes messages to an in-process buffered channel
+// consumed by a MemoryDequeuer. It needs no external service, for tests and
+// single-process deployments; queued messages are lost when the process
+// exits.
+type MemoryEnqueuer struct {
+	ch  chan memoryEntry
+	seq atomic.Uint64
+}
+
+// NewMemoryEnqueuer creates a MemoryEnqueuer holding up to size messages.
+// A size of zero or less selects DefaultMemoryBufferSize.
+func NewMemoryEnqueuer(size int) *MemoryEnqueuer {
+	if size <= 0 {
+		size = DefaultMemoryBufferSize
+	}
+	return &MemoryEnqueuer{ch: make(chan memoryEntry, size)}
+}
+
+// Enqueue adds a message to the buffer and returns its entry ID. When the
+// buffer is full it blocks until a worker takes a message or ctx is done.
+func (e *MemoryEnqueuer) Enqueue(ctx context.Context, msg *Message) (string, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("marshal message: %w", err)
+	}
+
+	entry := memoryEntry{id: strconv.FormatUint(e.seq.Add(1), 10), data: data}
+	select {
+	case e.ch <- entry:
+	case <-ctx.Done():
+		return "", fmt.Errorf("enqueue message %s: %w", msg.ID, ctx.Err())
+	}
+
+	MessagesEnqueuedTotal.Inc()
+
+	return entry.id, nil
+}
+
+// EnqueueWithDelay enqueues a copy of msg once delay has passed. The
+// message is held in memory until then and dropped if the process exits.
+func (e *MemoryEnqueuer) EnqueueWithDelay(ctx context.Context, msg *Message, delay time.Duration) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+	var delayed Message
+	if err := json.Unmarshal(data, &delayed); err != nil {
+		return fmt.Errorf("copy message: %w", err)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	time.AfterFunc(delay, func() {
+		_, _ = e.Enqueue(ctx, &delayed)
+	})
+	return nil
+}
+
+// Len returns the number of messages waiting in the buffer.
+func (e *MemoryEnqueuer) Len() int {
+	return len(e.ch)
+}
@@ -0,0 +1,254 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// funcHandler adapts a function to MessageHandler.
+type funcHandler func(ctx context.Context, msg *Message) error
+
+func (f funcHandler) HandleMessage(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// fastRetry returns a retry strategy with millisecond backoffs.
+func fastRetry(maxRetries int) *RetryStrategy {
+	return &RetryStrategy{
+		MaxRetries: maxRetries,
+		Schedule:   []time.Duration{time.Millisecond, 2 * time.Millisecond},
+	}
+}
+
+func startMemoryQueue(t *testing.T, handler MessageHandler, retry *RetryStrategy) (*MemoryEnqueuer, *MemoryDLQ) {
+	t.Helper()
+
+	enqueuer := NewMemoryEnqueuer(16)
+	dlq := NewMemoryDLQ(enqueuer)
+	cfg := DefaultConfig()
+	cfg.WorkerCount = 2
+	cfg.ShutdownTimeout = time.Second
+	dequeuer := NewMemoryDequeuer(enqueuer, dlq, handler, retry, cfg, testLogger())
+
+	if err := dequeuer.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := dequeuer.Stop(context.Background()); err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+	})
+	return enqueuer, dlq
+}
+
+// waitFor polls cond until it holds or a deadline passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryQueue_ProcessesMessage(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var got []*Message
+	enqueuer, _ := startMemoryQueue(t, funcHandler(func(_ context.Context, msg *Message) error {
+		mu.Lock()
+		got = append(got, msg)
+		mu.Unlock()
+		return nil
+	}), fastRetry(3))
+
+	id, err := enqueuer.Enqueue(context.Background(), &Message{ID: "msg-1", TenantID: "t1", Subject: "hi"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if id == "" {
+		t.Error("Enqueue() returned empty entry ID")
+	}
+
+	waitFor(t, "message to be handled", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 1
+	})
+	if got[0].ID != "msg-1" || got[0].Subject != "hi" {
+		t.Errorf("handled message = %+v", got[0])
+	}
+}
+
+func TestMemoryQueue_RetriesUntilSuccess(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	var lastRetryCount atomic.Int32
+	enqueuer, dlq := startMemoryQueue(t, funcHandler(func(_ context.Context, msg *Message) error {
+		lastRetryCount.Store(int32(msg.RetryCount))
+		if attempts.Add(1) < 3 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	}), fastRetry(5))
+
+	if _, err := enqueuer.Enqueue(context.Background(), &Message{ID: "msg-1", TenantID: "t1"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	waitFor(t, "third attempt", func() bool { return attempts.Load() == 3 })
+	if got := lastRetryCount.Load(); got != 2 {
+		t.Errorf("RetryCount on final attempt = %d, want 2", got)
+	}
+	entries, _ := dlq.List(context.Background(), "t1", 10)
+	if len(entries) != 0 {
+		t.Errorf("DLQ has %d entries, want 0", len(entries))
+	}
+}
+
+func TestMemoryQueue_ExhaustedRetriesMoveToDLQ(t *testing.T) {
+	t.Parallel()
+
+	var attempts atomic.Int32
+	enqueuer, dlq := startMemoryQueue(t, funcHandler(func(context.Context, *Message) error {
+		attempts.Add(1)
+		return errors.New("permanent failure")
+	}), fastRetry(2))
+	if _, err := enqueuer.Enqueue(context.Background(), &Message{ID: "msg-1", TenantID: "t1"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	var entries []DLQEntry
+	waitFor(t, "DLQ entry", func() bool {
+		entries, _ = dlq.List(context.Background(), "t1", 10)
+		return len(entries) == 1
+	})
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("handler attempts = %d, want 2", got)
+	}
+	e := entries[0]
+	if e.Message.OriginalMessage.ID != "msg-1" {
+		t.Errorf("DLQ message ID = %q, want msg-1", e.Message.OriginalMessage.ID)
+	}
+	if e.Message.FailureReason != "permanent failure" {
+		t.Errorf("FailureReason = %q, want %q", e.Message.FailureReason, "permanent failure")
+	}
+	if e.Message.OriginalMessage.RetryCount != 2 {
+		t.Errorf("RetryCount = %d, want 2", e.Message.OriginalMessage.RetryCount)
+	}
+}
+
+func TestMemoryDLQ_ListGetReprocess(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	enqueuer := NewMemoryEnqueuer(4)
+	dlq := NewMemoryDLQ(enqueuer)
+
+	for _, msg := range []*Message{
+		{ID: "a", TenantID: "t1", RetryCount: 5},
+		{ID: "b", TenantID: "t1", RetryCount: 5},
+		{ID: "c", TenantID: "t2", RetryCount: 5},
+	} {
+		if err := dlq.MoveToDLQ(ctx, msg, "failed"); err != nil {
+			t.Fatalf("MoveToDLQ(%s) error = %v", msg.ID, err)
+		}
+	}
+
+	entries, err := dlq.List(ctx, "t1", 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message.OriginalMessage.ID != "a" || entries[1].Message.OriginalMessage.ID != "b" {
+		t.Fatalf("List(t1) = %+v, want entries a, b", entries)
+	}
+	if limited, _ := dlq.List(ctx, "t1", 1); len(limited) != 1 {
+		t.Errorf("List(limit 1) returned %d entries", len(limited))
+	}
+
+	if _, err := dlq.Get(ctx, "t2", entries[0].ID); !errors.Is(err, ErrDLQEntryNotFound) {
+		t.Errorf("Get() from another tenant error = %v, want ErrDLQEntryNotFound", err)
+	}
+	got, err := dlq.Get(ctx, "t1", entries[1].ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Message.OriginalMessage.ID != "b" {
+		t.Errorf("Get() message ID = %q, want b", got.Message.OriginalMessage.ID)
+	}
+
+	n, err := dlq.Reprocess(ctx, "t1", []string{entries[0].ID, "missing"}, ReprocessOptions{ProviderID: "p-1"})
+	if err != nil {
+		t.Fatalf("Reprocess() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Reprocess() = %d, want 1", n)
+	}
+	if enqueuer.Len() != 1 {
+		t.Fatalf("enqueuer holds %d messages, want 1", enqueuer.Len())
+	}
+	requeued := <-enqueuer.ch
+	dequeuer := NewMemoryDequeuer(enqueuer, dlq, funcHandler(func(_ context.Context, msg *Message) error {
+		if msg.ID != "a" || msg.RetryCount != 0 || msg.ProviderID != "p-1" {
+			t.Errorf("reprocessed message = %+v, want ID a, RetryCount 0, ProviderID p-1", msg)
+		}
+		return nil
+	}), fastRetry(1), DefaultConfig(), testLogger())
+	dequeuer.processMessage(ctx, requeued)
+
+	if remaining, _ := dlq.List(ctx, "t1", 10); len(remaining) != 1 || remaining[0].Message.OriginalMessage.ID != "b" {
+		t.Errorf("List(t1) after reprocess = %+v, want only b", remaining)
+	}
+}
+
+func TestMemoryEnqueuer_FullBufferRespectsContext(t *testing.T) {
+	t.Parallel()
+
+	enqueuer := NewMemoryEnqueuer(1)
+	if _, err := enqueuer.Enqueue(context.Background(), &Message{ID: "1"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := enqueuer.Enqueue(ctx, &Message{ID: "2"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Enqueue() on full buffer error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestNewMemoryEnqueuer_DefaultSize(t *testing.T) {
+	t.Parallel()
+
+	if got := cap(NewMemoryEnqueuer(0).ch); got != DefaultMemoryBufferSize {
+		t.Errorf("buffer size = %d, want %d", got, DefaultMemoryBufferSize)
+	}
+}
+
+func TestNewQueue_Memory(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultConfig()
+	cfg.Type = "memory"
+	enqueuer, dequeuer, dlq, err := NewQueue(cfg, funcHandler(func(context.Context, *Message) error { return nil }), testLogger(), "", "")
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	if _, ok := enqueuer.(*MemoryEnqueuer); !ok {
+		t.Errorf("enqueuer type = %T, want *MemoryEnqueuer", enqueuer)
+	}
+	if _, ok := dequeuer.(*MemoryDequeuer); !ok {
+		t.Errorf("dequeuer type = %T, want *MemoryDequeuer", dequeuer)
+	}
+	if _, ok := dlq.(*MemoryDLQ); !ok {
+		t.Errorf("dlq type = %T, want *MemoryDLQ", dlq)
+	}
+}
@@ -23,9 +23,21 @@ type Message struct {
 	Body       []byte            `json:"body,omitempty"`
 	RetryCount int               `json:"retry_count"`
 	CreatedAt  time.Time         `json:"created_at"`
+	// ProviderID, when set, pins delivery to that esp_providers row instead
+	// of the group's routing. It is set when a DLQ entry is reprocessed
+	// with a different provider.
+	ProviderID string `json:"provider_id,omitempty"`
+	// RequestID is the ID of the SMTP session or API request that
+	// submitted the message. The worker logs it and records it on
+	// delivery logs so one message can be traced end to end.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // NewMessage creates a new Message with a generated UUID and current timestamp.
+//
+// Deprecated: producers enqueue ID-only messages (NewIDOnlyMessage) so the
+// body is never copied into, or base64-encoded in, the queue payload.
+// Full payloads remain readable for messages queued by older versions.
 func NewMessage(tenantID, from string, to []string, subject string, body []byte) *Message {
 	return &Message{
 		ID:        uuid.New().String(),
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBacklog measures how far the queue workers are behind on a tenant's
+// stream. Acknowledged entries stay in the stream, so its length alone
+// does not tell how much work is outstanding.
+type RedisBacklog struct {
+	client    *redis.Client
+	tenantID  string
+	groupName string
+}
+
+// NewRedisBacklog creates a RedisBacklog for the stream and consumer group
+// the queue workers read, as passed to NewRedisDequeuer.
+func NewRedisBacklog(client *redis.Client, tenantID, groupName string) *RedisBacklog {
+	return &RedisBacklog{client: client, tenantID: tenantID, groupName: groupName}
+}
+
+// Backlog returns the number of stream entries not yet acknowledged by the
+// consumer group: entries not yet delivered plus entries delivered but
+// pending. Before the group exists, or when Redis cannot tell the group's
+// lag, every entry in the stream is counted.
+func (b *RedisBacklog) Backlog(ctx context.Context) (int64, error) {
+	key := streamKey(b.tenantID)
+	groups, err := b.client.XInfoGroups(ctx, key).Result()
+	if err != nil && err.Error() != "ERR no such key" {
+		return 0, fmt.Errorf("xinfo groups %s: %w", key, err)
+	}
+	for _, g := range groups {
+		if g.Name == b.groupName && g.Lag >= 0 {
+			return g.Lag + g.Pending, nil
+		}
+	}
+	n, err := b.client.XLen(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("xlen %s: %w", key, err)
+	}
+	return n, nil
+}
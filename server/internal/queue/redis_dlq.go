@@ -3,6 +3,7 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,6 +19,44 @@ type DLQMessage struct {
 	MovedAt         time.Time `json:"moved_at"`
 }
 
+// DLQEntry is a DLQMessage as stored in a dead letter queue. ID addresses
+// the entry for Get and Reprocess: the stream entry ID for Redis and the
+// SQS message ID for SQS.
+type DLQEntry struct {
+	ID      string     `json:"id"`
+	Message DLQMessage `json:"message"`
+}
+
+// ReprocessOptions changes how DLQ entries are re-enqueued.
+type ReprocessOptions struct {
+	// ProviderID, when set, overrides the routing of every reprocessed
+	// message; see Message.ProviderID.
+	ProviderID string
+}
+
+// apply resets the retry count of msg and applies the routing override.
+func (o ReprocessOptions) apply(msg *Message) {
+	msg.RetryCount = 0
+	if o.ProviderID != "" {
+		msg.ProviderID = o.ProviderID
+	}
+}
+
+// ErrDLQEntryNotFound is returned by Get when no entry has the given ID.
+var ErrDLQEntryNotFound = errors.New("dlq entry not found")
+
+// decodeDLQEntry decodes a DLQMessage envelope.
+func decodeDLQEntry(id, data string) (*DLQEntry, error) {
+	entry := &DLQEntry{ID: id}
+	if err := json.Unmarshal([]byte(data), &entry.Message); err != nil {
+		return nil, fmt.Errorf("decode dlq entry %s: %w", id, err)
+	}
+	if entry.Message.OriginalMessage == nil {
+		return nil, fmt.Errorf("decode dlq entry %s: missing original message", id)
+	}
+	return entry, nil
+}
+
 // RedisDLQ manages dead letter queue operations backed by Redis Streams.
 type RedisDLQ struct {
 	client   *redis.Client
@@ -59,41 +98,75 @@ func (d *RedisDLQ) MoveToDLQ(ctx context.Context, msg *Message, reason string) e
 	return nil
 }
 
-// Reprocess removes messages from the DLQ, resets their retry count,
-// and re-enqueues them to the primary queue. It returns the number of
-// messages successfully reprocessed.
-func (d *RedisDLQ) Reprocess(ctx context.Context, tenantID string, messageIDs []string) (int, error) {
-	reprocessed := 0
+// List returns up to limit entries of the tenant's DLQ stream, oldest
+// first. Entries that cannot be decoded are skipped.
+func (d *RedisDLQ) List(ctx context.Context, tenantID string, limit int) ([]DLQEntry, error) {
+	msgs, err := d.client.XRangeN(ctx, dlqStreamKey(tenantID), "-", "+", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("xrange dlq stream %s: %w", dlqStreamKey(tenantID), err)
+	}
 
-	for _, msgID := range messageIDs {
-		// Read the message from DLQ.
-		msgs, err := d.client.XRange(ctx, dlqStreamKey(tenantID), msgID, msgID).Result()
+	entries := make([]DLQEntry, 0, len(msgs))
+	for _, m := range msgs {
+		entry, err := redisDLQEntry(m)
 		if err != nil {
-			return reprocessed, fmt.Errorf("xrange dlq message %s: %w", msgID, err)
-		}
-		if len(msgs) == 0 {
 			continue
 		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
 
-		data, ok := msgs[0].Values["data"].(string)
-		if !ok {
+// Get returns the DLQ entry with the given stream entry ID.
+func (d *RedisDLQ) Get(ctx context.Context, tenantID, entryID string) (*DLQEntry, error) {
+	msgs, err := d.client.XRange(ctx, dlqStreamKey(tenantID), entryID, entryID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("xrange dlq message %s: %w", entryID, err)
+	}
+	if len(msgs) == 0 {
+		return nil, ErrDLQEntryNotFound
+	}
+	return redisDLQEntry(msgs[0])
+}
+
+// redisDLQEntry decodes the envelope stored in a DLQ stream entry.
+func redisDLQEntry(m redis.XMessage) (*DLQEntry, error) {
+	data, ok := m.Values["data"].(string)
+	if !ok {
+		return nil, fmt.Errorf("dlq entry %s has no data field", m.ID)
+	}
+	return decodeDLQEntry(m.ID, data)
+}
+
+// Reprocess removes entries from the DLQ, resets their retry count, applies
+// opts and re-enqueues them to the primary queue. It returns the number of
+// entries successfully reprocessed.
+func (d *RedisDLQ) Reprocess(ctx context.Context, tenantID string, entryIDs []string, opts ReprocessOptions) (int, error) {
+	reprocessed := 0
+
+	for _, entryID := range entryIDs {
+		// Read the entry from the DLQ.
+		msgs, err := d.client.XRange(ctx, dlqStreamKey(tenantID), entryID, entryID).Result()
+		if err != nil {
+			return reprocessed, fmt.Errorf("xrange dlq message %s: %w", entryID, err)
+		}
+		if len(msgs) == 0 {
 			continue
 		}
-
-		var dlqMsg DLQMessage
-		if err := json.Unmarshal([]byte(data), &dlqMsg); err != nil {
+		entry, err := redisDLQEntry(msgs[0])
+		if err != nil {
 			continue
 		}
 
-		// Reset retry count and re-enqueue.
-		dlqMsg.OriginalMessage.RetryCount = 0
-		if _, err := d.enqueuer.Enqueue(ctx, dlqMsg.OriginalMessage); err != nil {
-			return reprocessed, fmt.Errorf("re-enqueue message %s: %w", dlqMsg.OriginalMessage.ID, err)
+		msg := entry.Message.OriginalMessage
+		opts.apply(msg)
+		if _, err := d.enqueuer.Enqueue(ctx, msg); err != nil {
+			return reprocessed, fmt.Errorf("re-enqueue message %s: %w", msg.ID, err)
 		}
 
 		// Remove from DLQ.
-		if err := d.client.XDel(ctx, dlqStreamKey(tenantID), msgID).Err(); err != nil {
-			return reprocessed, fmt.Errorf("xdel dlq message %s: %w", msgID, err)
+		if err := d.client.XDel(ctx, dlqStreamKey(tenantID), entryID).Err(); err != nil {
+			return reprocessed, fmt.Errorf("xdel dlq message %s: %w", entryID, err)
 		}
 
 		reprocessed++
@@ -59,26 +59,87 @@ func (d *SQSDLQ) MoveToDLQ(ctx context.Context, msg *Message, reason string) err
 	return nil
 }
 
-// Reprocess reads messages from the DLQ, resets their retry count, and
-// re-enqueues them to the primary queue via the Enqueuer. It returns
-// the number of messages successfully reprocessed.
-func (d *SQSDLQ) Reprocess(ctx context.Context, _ string, messageIDs []string) (int, error) {
-	// SQS does not support reading by message ID. We poll the DLQ and
-	// re-enqueue any messages we receive, up to len(messageIDs) count.
-	// This is a best-effort approach; in production the SQS redrive
-	// policy is the primary mechanism.
-
-	batchSize := len(messageIDs)
-	if batchSize == 0 {
+// sqsMaxReceive is the most messages a single SQS ReceiveMessage returns.
+const sqsMaxReceive = 10
+
+// List peeks at up to limit DLQ messages without consuming them: messages
+// are received with a zero visibility timeout so they stay available to
+// other readers. SQS does not guarantee ordering or that every message is
+// returned, so this is a best-effort sample of the queue. The tenant is
+// ignored; the DLQ is shared.
+func (d *SQSDLQ) List(ctx context.Context, _ string, limit int) ([]DLQEntry, error) {
+	var entries []DLQEntry
+	seen := make(map[string]bool)
+
+	for len(entries) < limit {
+		out, err := d.client.ReceiveMessage(ctx, &sqsReceiveInput{
+			QueueURL:            d.dlqURL,
+			MaxNumberOfMessages: int32(min(limit-len(entries), sqsMaxReceive)),
+			VisibilityTimeout:   0,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sqs peek dlq: %w", err)
+		}
+
+		added := 0
+		for _, sqsMsg := range out.Messages {
+			if seen[sqsMsg.MessageID] || len(entries) == limit {
+				continue
+			}
+			seen[sqsMsg.MessageID] = true
+			entry, err := decodeDLQEntry(sqsMsg.MessageID, sqsMsg.Body)
+			if err != nil {
+				d.log.Warn().Err(err).Msg("skipping malformed dlq message")
+				continue
+			}
+			entries = append(entries, *entry)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// Get returns the DLQ message with the given SQS message ID if a peek of
+// the queue finds it.
+func (d *SQSDLQ) Get(ctx context.Context, tenantID, entryID string) (*DLQEntry, error) {
+	entries, err := d.List(ctx, tenantID, sqsMaxReceive)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == entryID {
+			return &entries[i], nil
+		}
+	}
+	return nil, ErrDLQEntryNotFound
+}
+
+// Reprocess receives messages from the DLQ and re-enqueues those whose SQS
+// message ID is in entryIDs and whose message belongs to the tenant to the
+// primary queue with their retry count reset and opts applied. The DLQ is
+// shared, so the tenant is checked on the received messages themselves.
+// Other received messages are released back to the DLQ. It returns the
+// number of messages successfully reprocessed.
+func (d *SQSDLQ) Reprocess(ctx context.Context, tenantID string, entryIDs []string, opts ReprocessOptions) (int, error) {
+	// SQS does not support reading by message ID. We receive a batch from
+	// the DLQ and pick out the requested messages. This is a best-effort
+	// approach; in production the SQS redrive policy is the primary
+	// mechanism.
+	if len(entryIDs) == 0 {
 		return 0, nil
 	}
-	if batchSize > 10 {
-		batchSize = 10
+	wanted := make(map[string]bool, len(entryIDs))
+	for _, id := range entryIDs {
+		wanted[id] = true
 	}
 
 	out, err := d.client.ReceiveMessage(ctx, &sqsReceiveInput{
 		QueueURL:            d.dlqURL,
-		MaxNumberOfMessages: int32(batchSize),
+		MaxNumberOfMessages: sqsMaxReceive,
 		WaitTimeSeconds:     0, // no long-poll for reprocessing
 		VisibilityTimeout:   30,
 	})
@@ -88,16 +149,26 @@ func (d *SQSDLQ) Reprocess(ctx context.Context, _ string, messageIDs []string) (
 
 	reprocessed := 0
 	for _, sqsMsg := range out.Messages {
-		var dlqMsg DLQMessage
-		if err := json.Unmarshal([]byte(sqsMsg.Body), &dlqMsg); err != nil {
+		if !wanted[sqsMsg.MessageID] {
+			d.release(ctx, sqsMsg.ReceiptHandle)
+			continue
+		}
+
+		entry, err := decodeDLQEntry(sqsMsg.MessageID, sqsMsg.Body)
+		if err != nil {
 			d.log.Warn().Err(err).Msg("skipping malformed dlq message")
 			continue
 		}
+		if entry.Message.OriginalMessage == nil || entry.Message.OriginalMessage.TenantID != tenantID {
+			d.release(ctx, sqsMsg.ReceiptHandle)
+			continue
+		}
 
 		// Reset retry count and re-enqueue to primary queue.
-		dlqMsg.OriginalMessage.RetryCount = 0
-		if _, err := d.enqueuer.Enqueue(ctx, dlqMsg.OriginalMessage); err != nil {
-			return reprocessed, fmt.Errorf("re-enqueue message %s: %w", dlqMsg.OriginalMessage.ID, err)
+		msg := entry.Message.OriginalMessage
+		opts.apply(msg)
+		if _, err := d.enqueuer.Enqueue(ctx, msg); err != nil {
+			return reprocessed, fmt.Errorf("re-enqueue message %s: %w", msg.ID, err)
 		}
 
 		// Delete from DLQ after successful re-enqueue.
@@ -113,3 +184,14 @@ func (d *SQSDLQ) Reprocess(ctx context.Context, _ string, messageIDs []string) (
 
 	return reprocessed, nil
 }
+
+// release makes a received DLQ message visible again immediately.
+func (d *SQSDLQ) release(ctx context.Context, receiptHandle string) {
+	if err := d.client.ChangeMessageVisibility(ctx, &sqsChangeVisibilityInput{
+		QueueURL:          d.dlqURL,
+		ReceiptHandle:     receiptHandle,
+		VisibilityTimeout: 0,
+	}); err != nil {
+		d.log.Warn().Err(err).Msg("failed to release dlq message")
+	}
+}
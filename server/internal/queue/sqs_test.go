@@ -526,7 +526,7 @@ func TestSQSDLQ_Reprocess(t *testing.T) {
 	enqueuer := NewSQSEnqueuer(mock, "https://sqs.example.com/queue", testLogger())
 	dlq := NewSQSDLQ(mock, "https://sqs.example.com/dlq", "https://sqs.example.com/queue", enqueuer, testLogger())
 
-	count, err := dlq.Reprocess(context.Background(), "tenant-1", []string{"dlq-sqs-1"})
+	count, err := dlq.Reprocess(context.Background(), "tenant-1", []string{"dlq-sqs-1"}, ReprocessOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -566,3 +566,121 @@ func TestSQSDLQ_Reprocess(t *testing.T) {
 		t.Errorf("expected receipt handle %q, got %q", "dlq-receipt-1", deleted[0].ReceiptHandle)
 	}
 }
+
+func dlqTestBody(t *testing.T, id string) string {
+	t.Helper()
+	body, err := json.Marshal(DLQMessage{
+		OriginalMessage: &Message{ID: id, TenantID: "tenant-1", RetryCount: 5},
+		FailureReason:   "provider send: 503",
+		FinalError:      "provider send: 503",
+		MovedAt:         time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("marshal dlq message: %v", err)
+	}
+	return string(body)
+}
+
+func TestSQSDLQ_List(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSQSClient()
+	mock.messages = []sqsReceivedMessage{
+		{MessageID: "dlq-sqs-1", ReceiptHandle: "r1", Body: dlqTestBody(t, "msg-1")},
+		{MessageID: "dlq-sqs-2", ReceiptHandle: "r2", Body: "not json"},
+		{MessageID: "dlq-sqs-3", ReceiptHandle: "r3", Body: dlqTestBody(t, "msg-3")},
+	}
+	dlq := NewSQSDLQ(mock, "https://sqs.example.com/dlq", "https://sqs.example.com/queue", nil, testLogger())
+
+	entries, err := dlq.List(context.Background(), "tenant-1", 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The mock returns the same batch on every receive; duplicates and the
+	// malformed message are dropped.
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].ID != "dlq-sqs-1" || entries[0].Message.OriginalMessage.ID != "msg-1" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Message.FailureReason != "provider send: 503" {
+		t.Errorf("expected failure reason to be decoded, got %q", entries[1].Message.FailureReason)
+	}
+	if len(mock.getDeleted()) != 0 {
+		t.Error("expected List not to delete messages")
+	}
+
+	if _, err := dlq.Get(context.Background(), "tenant-1", "dlq-sqs-9"); !errors.Is(err, ErrDLQEntryNotFound) {
+		t.Errorf("expected ErrDLQEntryNotFound, got %v", err)
+	}
+}
+
+func TestSQSDLQ_Reprocess_ProviderOverride(t *testing.T) {
+	t.Parallel()
+
+	mock := newMockSQSClient()
+	mock.messages = []sqsReceivedMessage{
+		{MessageID: "dlq-sqs-1", ReceiptHandle: "r1", Body: dlqTestBody(t, "msg-1")},
+		{MessageID: "dlq-sqs-2", ReceiptHandle: "r2", Body: dlqTestBody(t, "msg-2")},
+	}
+	enqueuer := NewSQSEnqueuer(mock, "https://sqs.example.com/queue", testLogger())
+	dlq := NewSQSDLQ(mock, "https://sqs.example.com/dlq", "https://sqs.example.com/queue", enqueuer, testLogger())
+
+	count, err := dlq.Reprocess(context.Background(), "tenant-1", []string{"dlq-sqs-2"}, ReprocessOptions{ProviderID: "provider-7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 reprocessed, got %d", count)
+	}
+
+	sent := mock.getSent()
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 sent message, got %d", len(sent))
+	}
+	var requeued Message
+	if err := json.Unmarshal([]byte(sent[0].MessageBody), &requeued); err != nil {
+		t.Fatalf("failed to unmarshal requeued message: %v", err)
+	}
+	if requeued.ID != "msg-2" || requeued.ProviderID != "provider-7" || requeued.RetryCount != 0 {
+		t.Errorf("unexpected requeued message: %+v", requeued)
+	}
+
+	deleted := mock.getDeleted()
+	if len(deleted) != 1 || deleted[0].ReceiptHandle != "r2" {
+		t.Errorf("expected only the requested message to be deleted, got %+v", deleted)
+	}
+}
+
+func TestSQSDLQ_Reprocess_OtherTenant(t *testing.T) {
+	t.Parallel()
+
+	other, err := json.Marshal(DLQMessage{
+		OriginalMessage: &Message{ID: "msg-9", TenantID: "tenant-2", RetryCount: 5},
+		FailureReason:   "provider send: 503",
+		MovedAt:         time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("marshal dlq message: %v", err)
+	}
+	mock := newMockSQSClient()
+	mock.messages = []sqsReceivedMessage{
+		{MessageID: "dlq-sqs-1", ReceiptHandle: "r1", Body: dlqTestBody(t, "msg-1")},
+		{MessageID: "dlq-sqs-9", ReceiptHandle: "r9", Body: string(other)},
+	}
+	enqueuer := NewSQSEnqueuer(mock, "https://sqs.example.com/queue", testLogger())
+	dlq := NewSQSDLQ(mock, "https://sqs.example.com/dlq", "https://sqs.example.com/queue", enqueuer, testLogger())
+
+	count, err := dlq.Reprocess(context.Background(), "tenant-1", []string{"dlq-sqs-1", "dlq-sqs-9"}, ReprocessOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 reprocessed, got %d", count)
+	}
+	deleted := mock.getDeleted()
+	if len(deleted) != 1 || deleted[0].ReceiptHandle != "r1" {
+		t.Errorf("expected another tenant's message to stay in the DLQ, got %+v", deleted)
+	}
+}
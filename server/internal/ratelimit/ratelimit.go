@@ -0,0 +1,96 @@
+// Package ratelimit implements token buckets for request rate limiting,
+// shared across processes through Redis or kept in memory.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst
+// tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute returns a Limit of n requests per minute with the given burst.
+// A burst below 1 defaults to one minute's worth of requests.
+func PerMinute(n float64, burst int) Limit {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(n)))
+	}
+	return Limit{Rate: n / 60, Burst: burst}
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// Bucket takes tokens from named token buckets.
+type Bucket interface {
+	// Take takes one token from the bucket key. When the bucket is empty
+	// it returns false and how long until a token is available.
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// memoryPruneSize is the number of buckets above which the memory bucket
+// drops buckets that have refilled completely.
+const memoryPruneSize = 10000
+
+// MemoryBucket is an in-process Bucket, used when Redis is unavailable.
+// Limits are then enforced per process.
+type MemoryBucket struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryState
+	now     func() time.Time
+}
+
+type memoryState struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// NewMemoryBucket creates an empty MemoryBucket.
+func NewMemoryBucket() *MemoryBucket {
+	return &MemoryBucket{buckets: make(map[string]*memoryState), now: time.Now}
+}
+
+// Take implements Bucket.
+func (m *MemoryBucket) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	st, ok := m.buckets[key]
+	if !ok {
+		if len(m.buckets) >= memoryPruneSize {
+			m.prune(now)
+		}
+		st = &memoryState{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = st
+	}
+
+	st.tokens = math.Min(float64(limit.Burst), st.tokens+now.Sub(st.last).Seconds()*limit.Rate)
+	st.last = now
+	if st.tokens < 1 {
+		wait := time.Duration((1 - st.tokens) / limit.Rate * float64(time.Second))
+		return false, wait, nil
+	}
+	st.tokens--
+	st.full = now.Add(time.Duration((float64(limit.Burst) - st.tokens) / limit.Rate * float64(time.Second)))
+	return true, 0, nil
+}
+
+// prune drops buckets that are full again, which behave like new ones.
+func (m *MemoryBucket) prune(now time.Time) {
+	for k, st := range m.buckets {
+		if !now.Before(st.full) {
+			delete(m.buckets, k)
+		}
+	}
+}
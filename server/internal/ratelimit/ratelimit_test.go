@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPerMinute(t *testing.T) {
+	l := PerMinute(120, 0)
+	if l.Rate != 2 || l.Burst != 120 {
+		t.Errorf("expected rate 2/s burst 120, got %+v", l)
+	}
+	if PerMinute(0, 10).Enabled() {
+		t.Error("expected zero rate to be disabled")
+	}
+}
+
+func TestMemoryBucket_Take(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewMemoryBucket()
+	b.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := b.Take(ctx, "k", limit); !ok {
+			t.Fatalf("take %d: expected burst to allow", i)
+		}
+	}
+	ok, wait, _ := b.Take(ctx, "k", limit)
+	if ok || wait != time.Second {
+		t.Fatalf("expected empty bucket with 1s wait, got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _, _ := b.Take(ctx, "other", limit); !ok {
+		t.Error("expected separate key to have its own bucket")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if ok, _, _ := b.Take(ctx, "k", limit); !ok {
+		t.Error("expected refilled token after 1.5s")
+	}
+	ok, wait, _ = b.Take(ctx, "k", limit)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("expected 500ms wait, got ok=%v wait=%v", ok, wait)
+	}
+}
+
+func TestMemoryBucket_Prune(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewMemoryBucket()
+	b.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, _, _ = b.Take(context.Background(), "old", limit)
+	now = now.Add(time.Minute)
+	b.prune(now)
+	if _, ok := b.buckets["old"]; ok {
+		t.Error("expected refilled bucket to be pruned")
+	}
+}
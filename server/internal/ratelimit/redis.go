@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a token bucket stored as a hash of
+// tokens and last refill time (ms), using the Redis clock so API servers
+// with skewed clocks share buckets correctly. It returns {allowed, wait_ms}.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`)
+
+// RedisBucket is a Bucket stored in Redis, shared by every API server.
+type RedisBucket struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisBucket creates a RedisBucket whose keys start with prefix.
+func NewRedisBucket(client *redis.Client, prefix string) *RedisBucket {
+	return &RedisBucket{client: client, prefix: prefix}
+}
+
+// Take implements Bucket.
+func (b *RedisBucket) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	res, err := takeScript.Run(ctx, b.client, []string{b.prefix + key}, limit.Rate, limit.Burst).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("take token: %w", err)
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("take token: unexpected reply %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,146 @@
+// Package redact masks personal data (recipient addresses and subjects)
+// before it is written to structured logs, delivery logs and activity logs.
+//
+// Masking is configured once at startup with Configure and applied through
+// the package-level helpers, so every component masks data the same way.
+// Until Configure is called nothing is masked.
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Config controls which personal data is masked.
+type Config struct {
+	// Recipients masks email addresses, e.g. john@example.com becomes
+	// j***@example.com. The domain is kept for troubleshooting.
+	Recipients bool
+	// Subjects replaces message subjects with Placeholder.
+	Subjects bool
+}
+
+// Placeholder replaces values that are masked entirely.
+const Placeholder = "[redacted]"
+
+var current atomic.Pointer[Config]
+
+// emailPattern matches email addresses embedded in free text such as ESP
+// responses and error messages.
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Configure sets the masking applied by the package helpers. It is safe to
+// call concurrently with the helpers.
+func Configure(cfg Config) {
+	current.Store(&cfg)
+}
+
+// Current returns the active configuration.
+func Current() Config {
+	if cfg := current.Load(); cfg != nil {
+		return *cfg
+	}
+	return Config{}
+}
+
+// Email masks a single email address when recipient masking is enabled.
+func Email(addr string) string {
+	if !Current().Recipients {
+		return addr
+	}
+	return maskEmail(addr)
+}
+
+// Emails masks each address in addrs. The input slice is not modified.
+func Emails(addrs []string) []string {
+	if !Current().Recipients {
+		return addrs
+	}
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = maskEmail(a)
+	}
+	return out
+}
+
+// Subject masks a message subject when subject masking is enabled. Empty
+// subjects are returned unchanged.
+func Subject(subject string) string {
+	if subject == "" || !Current().Subjects {
+		return subject
+	}
+	return Placeholder
+}
+
+// Text masks every email address found in free text, such as provider
+// responses, error messages or JSON metadata. JSON stays valid because
+// only address characters are replaced.
+func Text(s string) string {
+	if s == "" || !Current().Recipients {
+		return s
+	}
+	return emailPattern.ReplaceAllStringFunc(s, maskEmail)
+}
+
+// JSON masks every email address in a JSON document. See Text.
+func JSON(b []byte) []byte {
+	if len(b) == 0 || !Current().Recipients {
+		return b
+	}
+	return emailPattern.ReplaceAllFunc(b, func(m []byte) []byte {
+		return []byte(maskEmail(string(m)))
+	})
+}
+
+// Fields returns a copy of fields with email addresses masked in every
+// string value, including strings nested in maps and slices.
+func Fields(fields map[string]interface{}) map[string]interface{} {
+	if fields == nil || !Current().Recipients {
+		return fields
+	}
+	out := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		out[k] = value(v)
+	}
+	return out
+}
+
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return emailPattern.ReplaceAllStringFunc(v, maskEmail)
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = emailPattern.ReplaceAllStringFunc(s, maskEmail)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = value(e)
+		}
+		return out
+	case map[string]interface{}:
+		return Fields(v)
+	default:
+		return v
+	}
+}
+
+// maskEmail keeps the first character of the local part and the domain.
+func maskEmail(addr string) string {
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		if addr == "" {
+			return addr
+		}
+		return addr[:1] + "***"
+	}
+	local, domain := addr[:at], addr[at:]
+	if local == "" {
+		return "***" + domain
+	}
+	return local[:1] + "***" + domain
+}
@@ -0,0 +1,92 @@
+package redact
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEmail(t *testing.T) {
+	t.Cleanup(func() { Configure(Config{}) })
+
+	Configure(Config{})
+	if got := Email("john@example.com"); got != "john@example.com" {
+		t.Errorf("Email() with masking off = %q", got)
+	}
+
+	Configure(Config{Recipients: true})
+	tests := map[string]string{
+		"john@example.com": "j***@example.com",
+		"@example.com":     "***@example.com",
+		"postmaster":       "p***",
+		"":                 "",
+	}
+	for in, want := range tests {
+		if got := Email(in); got != want {
+			t.Errorf("Email(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSubject(t *testing.T) {
+	t.Cleanup(func() { Configure(Config{}) })
+
+	Configure(Config{Recipients: true})
+	if got := Subject("Invoice"); got != "Invoice" {
+		t.Errorf("Subject() with subject masking off = %q", got)
+	}
+
+	Configure(Config{Subjects: true})
+	if got := Subject("Invoice"); got != Placeholder {
+		t.Errorf("Subject() = %q, want %q", got, Placeholder)
+	}
+	if got := Subject(""); got != "" {
+		t.Errorf("Subject(\"\") = %q, want empty", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	t.Cleanup(func() { Configure(Config{}) })
+	Configure(Config{Recipients: true})
+
+	got := Text("550 5.1.1 <jane.doe@example.org>: mailbox unavailable, cc bob@test.io")
+	want := "550 5.1.1 <j***@example.org>: mailbox unavailable, cc b***@test.io"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	t.Cleanup(func() { Configure(Config{}) })
+	Configure(Config{Recipients: true})
+
+	out := JSON([]byte(`{"event":"bounce","email":"alice@example.com"}`))
+	var m map[string]string
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatalf("masked JSON is invalid: %v", err)
+	}
+	if m["email"] != "a***@example.com" || m["event"] != "bounce" {
+		t.Errorf("unexpected masked JSON: %s", out)
+	}
+}
+
+func TestFields(t *testing.T) {
+	t.Cleanup(func() { Configure(Config{}) })
+	Configure(Config{Recipients: true})
+
+	in := map[string]interface{}{
+		"email":  "owner@acme.test",
+		"role":   "admin",
+		"count":  3,
+		"nested": map[string]interface{}{"to": []string{"x@y.io"}},
+	}
+	out := Fields(in)
+	if out["email"] != "o***@acme.test" || out["role"] != "admin" || out["count"] != 3 {
+		t.Errorf("unexpected fields: %v", out)
+	}
+	if to := out["nested"].(map[string]interface{})["to"].([]string); to[0] != "x***@y.io" {
+		t.Errorf("nested value not masked: %v", to)
+	}
+	if in["email"] != "owner@acme.test" {
+		t.Error("Fields() modified its input")
+	}
+}
@@ -0,0 +1,280 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/pm"
+)
+
+// ErrMemoryLimit is returned when a script builds a string longer than
+// Config.MaxStringSize or holds more than Config.MaxMemory.
+var ErrMemoryLimit = errors.New("script exceeded its memory limit")
+
+// memoryCheckInterval is the number of instructions between two walks of
+// everything a script can reach.
+const memoryCheckInterval = 1024
+
+// Estimated sizes of Lua values, in bytes, used to weigh what a script
+// holds against Config.MaxMemory.
+const (
+	valueSize    = 16
+	tableSize    = 64
+	functionSize = 64
+)
+
+// memoryGuard enforces the memory limits of one script run. gopher-lua has
+// no allocation hook, but its VM polls the state's context before every
+// instruction; the guard uses that poll to check the strings in the
+// running function's registers, which stops a string that outgrew
+// MaxStringSize one instruction after it was built, and every
+// memoryCheckInterval instructions weighs everything reachable from the
+// globals and the call stack against MaxMemory. Once a limit is exceeded
+// the context stays done, so a pcall cannot swallow the error.
+type memoryGuard struct {
+	context.Context
+	L         *lua.LState
+	maxString int
+	maxMemory int
+	steps     int
+	err       error
+	done      chan struct{}
+}
+
+// withMemoryLimit returns a context for L that is done when ctx is or
+// when the script running in L exceeds the limits in cfg.
+func withMemoryLimit(ctx context.Context, L *lua.LState, cfg Config) *memoryGuard {
+	return &memoryGuard{
+		Context:   ctx,
+		L:         L,
+		maxString: cfg.MaxStringSize,
+		maxMemory: cfg.MaxMemory,
+		done:      make(chan struct{}),
+	}
+}
+
+// Done is called by the VM before every instruction.
+func (g *memoryGuard) Done() <-chan struct{} {
+	if g.err == nil {
+		if g.err = g.check(); g.err != nil {
+			close(g.done)
+		}
+	}
+	if g.err != nil {
+		return g.done
+	}
+	return g.Context.Done()
+}
+
+func (g *memoryGuard) Err() error {
+	if g.err != nil {
+		return g.err
+	}
+	return g.Context.Err()
+}
+
+func (g *memoryGuard) check() error {
+	for i := 1; i <= g.L.GetTop(); i++ {
+		if s, ok := g.L.Get(i).(lua.LString); ok && len(s) > g.maxString {
+			return fmt.Errorf("%w: string of %d bytes exceeds %d", ErrMemoryLimit, len(s), g.maxString)
+		}
+	}
+	g.steps++
+	if g.steps%memoryCheckInterval != 0 {
+		return nil
+	}
+	if n := g.reachable(); n > g.maxMemory {
+		return fmt.Errorf("%w: holds more than %d bytes", ErrMemoryLimit, g.maxMemory)
+	}
+	return nil
+}
+
+// reachable estimates the bytes held by the globals and the registers of
+// every active call. It stops counting once the total passes maxMemory.
+func (g *memoryGuard) reachable() int {
+	m := meter{seen: make(map[lua.LValue]bool), limit: g.maxMemory}
+	m.add(g.L.G.Global)
+	for level := 0; ; level++ {
+		dbg, ok := g.L.GetStack(level)
+		if !ok {
+			break
+		}
+		for n := 1; ; n++ {
+			name, v := g.L.GetLocal(dbg, n)
+			if name == "" {
+				break
+			}
+			m.add(v)
+		}
+	}
+	return m.total
+}
+
+// meter sums the estimated size of a graph of Lua values. A string stored
+// in several places is counted each time.
+type meter struct {
+	seen  map[lua.LValue]bool
+	total int
+	limit int
+}
+
+func (m *meter) add(v lua.LValue) {
+	if m.total > m.limit {
+		return
+	}
+	switch v := v.(type) {
+	case lua.LString:
+		m.total += valueSize + len(v)
+	case *lua.LTable:
+		if m.seen[v] {
+			return
+		}
+		m.seen[v] = true
+		m.total += tableSize
+		v.ForEach(func(key, value lua.LValue) {
+			m.add(key)
+			m.add(value)
+		})
+		if v.Metatable != lua.LNil {
+			m.add(v.Metatable)
+		}
+	case *lua.LFunction:
+		if m.seen[v] {
+			return
+		}
+		m.seen[v] = true
+		m.total += functionSize
+		for _, uv := range v.Upvalues {
+			m.add(uv.Value())
+		}
+	default:
+		m.total += valueSize
+	}
+}
+
+// limitLibraries replaces the library functions that can build a string
+// far larger than their arguments in a single call, before memoryGuard
+// gets to see it, with versions that refuse results over maxString.
+func limitLibraries(L *lua.LState, maxString int) {
+	tooLong := func(L *lua.LState, fn string, n int) {
+		if n > maxString {
+			L.RaiseError("%s result exceeds %d bytes", fn, maxString)
+		}
+	}
+
+	if str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", L.NewFunction(func(L *lua.LState) int {
+			s := L.CheckString(1)
+			n := L.CheckInt(2)
+			if n > 0 {
+				tooLong(L, "string.rep", len(s)*min(n, maxString+1))
+			}
+			L.Push(lua.LString(strings.Repeat(s, max(n, 0))))
+			return 1
+		}))
+
+		format := str.RawGetString("format").(*lua.LFunction)
+		str.RawSetString("format", L.NewFunction(func(L *lua.LState) int {
+			f := L.CheckString(1)
+			n := len(f) + formatWidths(f)
+			// %q and %x can more than double a string argument.
+			factor := 1
+			if strings.ContainsAny(f, "qxX") {
+				factor = 4
+			}
+			for i := 2; i <= L.GetTop(); i++ {
+				if s, ok := L.Get(i).(lua.LString); ok {
+					n += factor * len(s)
+				} else {
+					n += 32
+				}
+			}
+			tooLong(L, "string.format", n)
+			return format.GFunction(L)
+		}))
+
+		gsub := str.RawGetString("gsub").(*lua.LFunction)
+		str.RawSetString("gsub", L.NewFunction(func(L *lua.LState) int {
+			s := L.CheckString(1)
+			switch repl := L.Get(3).(type) {
+			case lua.LString:
+				// Each match adds the replacement's text, and each %n in
+				// it a capture; the captures of different matches do not
+				// overlap, so all the copies of one %n add up to at most
+				// len(s). A position capture adds a number.
+				matches, err := pm.Find(L.CheckString(2), []byte(s), 0, L.OptInt(4, -1))
+				if err == nil {
+					refs := strings.Count(string(repl), "%")
+					tooLong(L, "string.gsub", len(s)+len(matches)*(len(repl)+20*refs)+refs*len(s))
+				}
+			case *lua.LTable, *lua.LFunction:
+				n := len(s)
+				L.Replace(3, L.NewFunction(func(L *lua.LState) int {
+					var v lua.LValue
+					if fn, ok := repl.(*lua.LFunction); ok {
+						args := make([]lua.LValue, L.GetTop())
+						for i := range args {
+							args[i] = L.Get(i + 1)
+						}
+						L.CallByParam(lua.P{Fn: fn, NRet: 1}, args...)
+						v = L.Get(-1)
+						L.Pop(1)
+					} else {
+						v = L.GetTable(repl, L.Get(1))
+					}
+					if lua.LVCanConvToString(v) {
+						n += len(lua.LVAsString(v))
+						tooLong(L, "string.gsub", n)
+					}
+					L.Push(v)
+					return 1
+				}))
+			}
+			return gsub.GFunction(L)
+		}))
+	}
+
+	if tbl, ok := L.GetGlobal(lua.TabLibName).(*lua.LTable); ok {
+		concat := tbl.RawGetString("concat").(*lua.LFunction)
+		tbl.RawSetString("concat", L.NewFunction(func(L *lua.LState) int {
+			t := L.CheckTable(1)
+			sep := len(L.OptString(2, ""))
+			n := 0
+			for i := max(L.OptInt(3, 1), 1); i <= min(L.OptInt(4, t.Len()), t.Len()); i++ {
+				if v := t.RawGetInt(i); lua.LVCanConvToString(v) {
+					n += len(lua.LVAsString(v)) + sep
+				}
+			}
+			tooLong(L, "table.concat", n)
+			return concat.GFunction(L)
+		}))
+	}
+}
+
+// formatWidths sums the numbers in a format string, which bounds the
+// padding its widths and precisions can add.
+func formatWidths(f string) int {
+	total := 0
+	for f != "" {
+		i := strings.IndexAny(f, "0123456789")
+		if i < 0 {
+			break
+		}
+		f = f[i:]
+		j := 0
+		for j < len(f) && f[j] >= '0' && f[j] <= '9' {
+			j++
+		}
+		n, err := strconv.Atoi(f[:j])
+		if err != nil || n > 1<<30 {
+			n = 1 << 30
+		}
+		total += n
+		f = f[j:]
+	}
+	return total
+}
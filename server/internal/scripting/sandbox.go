@@ -0,0 +1,196 @@
+package scripting
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// removedGlobals are base library functions that reach the file system,
+// load code at run time or escape the sandbox's environment.
+var removedGlobals = []string{
+	"dofile", "loadfile", "load", "loadstring", "require", "module",
+	"getfenv", "setfenv", "collectgarbage", "newproxy", "_printregs",
+}
+
+// newSandbox returns a Lua state with only the base, table, string and
+// math libraries, minus removedGlobals. print writes to log. The caller
+// sets a context from withMemoryLimit to enforce cfg's memory limits.
+func newSandbox(cfg Config, log zerolog.Logger) *lua.LState {
+	registrySize := min(lua.RegistrySize, cfg.RegistryMaxSize)
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       cfg.CallStackSize,
+		RegistrySize:        registrySize,
+		RegistryMaxSize:     cfg.RegistryMaxSize,
+		IncludeGoStackTrace: false,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range removedGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		parts := make([]string, L.GetTop())
+		for i := range parts {
+			parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+		}
+		log.Info().Msg(strings.Join(parts, " "))
+		return 0
+	}))
+
+	limitLibraries(L, cfg.MaxStringSize)
+	return L
+}
+
+// load runs a compiled script's top-level chunk, which defines its
+// functions.
+func load(L *lua.LState, proto *lua.FunctionProto) error {
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// callProcess loads proto, calls its process function with msg and applies
+// the changes it made to msg.
+func callProcess(L *lua.LState, proto *lua.FunctionProto, msg *provider.Message, maxString int) (Result, error) {
+	if err := load(L, proto); err != nil {
+		return Result{}, err
+	}
+	fn, ok := L.GetGlobal(EntryPoint).(*lua.LFunction)
+	if !ok {
+		return Result{}, fmt.Errorf("script does not define function %s(msg)", EntryPoint)
+	}
+
+	tbl := messageTable(L, msg)
+	if err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, tbl); err != nil {
+		return Result{}, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	if err := applyMessageTable(tbl, msg, maxString); err != nil {
+		return Result{}, err
+	}
+	return parseResult(ret)
+}
+
+// messageTable converts msg to the table passed to process.
+func messageTable(L *lua.LState, msg *provider.Message) *lua.LTable {
+	tbl := L.NewTable()
+	tbl.RawSetString("id", lua.LString(msg.ID))
+	tbl.RawSetString("from", lua.LString(msg.From))
+	tbl.RawSetString("to", stringList(L, msg.To))
+	tbl.RawSetString("subject", lua.LString(msg.Subject))
+	tbl.RawSetString("headers", stringMap(L, msg.Headers))
+	tbl.RawSetString("tags", stringList(L, msg.Tags))
+	tbl.RawSetString("metadata", stringMap(L, msg.Metadata))
+	return tbl
+}
+
+// applyMessageTable copies the subject and headers of tbl back to msg.
+func applyMessageTable(tbl *lua.LTable, msg *provider.Message, maxString int) error {
+	subject, ok := tbl.RawGetString("subject").(lua.LString)
+	if !ok {
+		return fmt.Errorf("msg.subject must be a string")
+	}
+	if len(subject) > maxString {
+		return fmt.Errorf("msg.subject exceeds %d bytes", maxString)
+	}
+
+	headersTbl, ok := tbl.RawGetString("headers").(*lua.LTable)
+	if !ok {
+		return fmt.Errorf("msg.headers must be a table")
+	}
+	headers := make(map[string]string)
+	var err error
+	headersTbl.ForEach(func(k, v lua.LValue) {
+		if err != nil {
+			return
+		}
+		name, ok := k.(lua.LString)
+		if !ok || name == "" {
+			err = fmt.Errorf("msg.headers keys must be non-empty strings")
+			return
+		}
+		switch v.Type() {
+		case lua.LTString, lua.LTNumber:
+		default:
+			err = fmt.Errorf("header %s: value must be a string", name)
+			return
+		}
+		value := v.String()
+		if len(value) > maxString {
+			err = fmt.Errorf("header %s: value exceeds %d bytes", name, maxString)
+			return
+		}
+		if strings.ContainsAny(string(name)+value, "\r\n") {
+			err = fmt.Errorf("header %s: line breaks are not allowed", name)
+			return
+		}
+		headers[string(name)] = value
+	})
+	if err != nil {
+		return err
+	}
+
+	msg.Subject = string(subject)
+	msg.Headers = headers
+	return nil
+}
+
+// parseResult reads the value process returned.
+func parseResult(v lua.LValue) (Result, error) {
+	if v == lua.LNil {
+		return Result{}, nil
+	}
+	tbl, ok := v.(*lua.LTable)
+	if !ok {
+		return Result{}, fmt.Errorf("%s must return nil or a table, not a %s", EntryPoint, v.Type())
+	}
+	var res Result
+	for field, dst := range map[string]*string{"provider": &res.Provider, "reject": &res.Reject} {
+		switch val := tbl.RawGetString(field).(type) {
+		case *lua.LNilType:
+		case lua.LString:
+			*dst = string(val)
+		default:
+			return Result{}, fmt.Errorf("result field %s must be a string", field)
+		}
+	}
+	return res, nil
+}
+
+func stringList(L *lua.LState, values []string) *lua.LTable {
+	tbl := L.CreateTable(len(values), 0)
+	for _, v := range values {
+		tbl.Append(lua.LString(v))
+	}
+	return tbl
+}
+
+func stringMap(L *lua.LState, values map[string]string) *lua.LTable {
+	tbl := L.CreateTable(0, len(values))
+	for k, v := range values {
+		tbl.RawSetString(k, lua.LString(v))
+	}
+	return tbl
+}
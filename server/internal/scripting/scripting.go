@@ -0,0 +1,232 @@
+// Package scripting runs per-group Lua scripts on outgoing messages before
+// they are routed. A script defines
+//
+//	function process(msg)
+//
+// where msg has the fields id, from, to, subject, headers, tags and
+// metadata. The script may change msg.subject and msg.headers (setting a
+// header to nil removes it) and may return a table to steer delivery:
+//
+//	return {provider = "sendgrid-eu"}  -- deliver through the named provider
+//	return {reject = "blocked domain"} -- fail the message without retrying
+//
+// Returning nothing delivers the message normally. Scripts run in a
+// sandbox with only the base, string, table and math libraries, no file,
+// OS or module access, and CPU time, memory, call depth and stack limits.
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// EntryPoint is the global function every script must define.
+const EntryPoint = "process"
+
+// validateTimeout bounds the top-level chunk run by Validate.
+const validateTimeout = time.Second
+
+// ErrTimeout is returned when a script runs past Config.Timeout.
+var ErrTimeout = errors.New("script timed out")
+
+// Config holds the sandbox limits applied to every script run.
+type Config struct {
+	Timeout         time.Duration
+	CallStackSize   int
+	RegistryMaxSize int
+	MaxStringSize   int
+	MaxMemory       int
+}
+
+// DefaultConfig returns the limits used when config.yaml sets none.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:         100 * time.Millisecond,
+		CallStackSize:   200,
+		RegistryMaxSize: 64 * 1024,
+		MaxStringSize:   1 << 20,
+		MaxMemory:       16 << 20,
+	}
+}
+
+// Store loads the scripts a group runs.
+type Store interface {
+	ListEnabledMessageScriptsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.MessageScript, error)
+}
+
+// Result is the combined outcome of a group's scripts.
+type Result struct {
+	// Provider is the name of the provider a script chose, or empty.
+	Provider string
+	// Reject is the reason a script rejected the message, or empty.
+	Reject string
+	// Script names the script that set Provider or Reject.
+	Script string
+}
+
+// Engine runs the enabled scripts of a message's group in name order,
+// caching compiled scripts until they are updated.
+type Engine struct {
+	store Store
+	cfg   Config
+	log   zerolog.Logger
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]compiled
+}
+
+type compiled struct {
+	updatedAt time.Time
+	proto     *lua.FunctionProto
+}
+
+// NewEngine creates an Engine that loads scripts from store. Zero limits
+// in cfg take their DefaultConfig values.
+func NewEngine(store Store, cfg Config, log zerolog.Logger) *Engine {
+	def := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.CallStackSize <= 0 {
+		cfg.CallStackSize = def.CallStackSize
+	}
+	if cfg.RegistryMaxSize <= 0 {
+		cfg.RegistryMaxSize = def.RegistryMaxSize
+	}
+	if cfg.MaxStringSize <= 0 {
+		cfg.MaxStringSize = def.MaxStringSize
+	}
+	if cfg.MaxMemory <= 0 {
+		cfg.MaxMemory = def.MaxMemory
+	}
+	return &Engine{
+		store: store,
+		cfg:   cfg,
+		log:   log,
+		cache: make(map[uuid.UUID]compiled),
+	}
+}
+
+// Run runs the group's enabled scripts on msg, which they may modify. It
+// stops at the first script that rejects the message or fails; when
+// several scripts choose a provider the last one wins.
+func (e *Engine) Run(ctx context.Context, groupID uuid.UUID, msg *provider.Message) (Result, error) {
+	scripts, err := e.store.ListEnabledMessageScriptsByGroupID(ctx, groupID)
+	if err != nil {
+		return Result{}, fmt.Errorf("list scripts: %w", err)
+	}
+
+	var res Result
+	for _, s := range scripts {
+		proto, err := e.compile(s)
+		if err != nil {
+			return Result{}, fmt.Errorf("script %s: %w", s.Name, err)
+		}
+		out, err := e.run(ctx, s.Name, proto, msg)
+		if err != nil {
+			return Result{}, fmt.Errorf("script %s: %w", s.Name, err)
+		}
+		if out.Reject != "" {
+			return Result{Reject: out.Reject, Script: s.Name}, nil
+		}
+		if out.Provider != "" {
+			res = Result{Provider: out.Provider, Script: s.Name}
+		}
+	}
+	return res, nil
+}
+
+// compile returns the compiled form of s, reusing the cached one while s
+// is unchanged.
+func (e *Engine) compile(s storage.MessageScript) (*lua.FunctionProto, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, ok := e.cache[s.ID]; ok && c.updatedAt.Equal(s.UpdatedAt.Time) {
+		return c.proto, nil
+	}
+	proto, err := compile(s.Name, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	e.cache[s.ID] = compiled{updatedAt: s.UpdatedAt.Time, proto: proto}
+	return proto, nil
+}
+
+// run executes one script on msg in a fresh sandbox.
+func (e *Engine) run(ctx context.Context, name string, proto *lua.FunctionProto, msg *provider.Message) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	L := newSandbox(e.cfg, e.log.With().Str("script", name).Logger())
+	defer L.Close()
+	guard := withMemoryLimit(ctx, L, e.cfg)
+	L.SetContext(guard)
+
+	out, err := callProcess(L, proto, msg, e.cfg.MaxStringSize)
+	if err != nil {
+		if errors.Is(guard.Err(), ErrMemoryLimit) {
+			return Result{}, guard.Err()
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Result{}, fmt.Errorf("%w after %s", ErrTimeout, e.cfg.Timeout)
+		}
+		return Result{}, err
+	}
+	return out, nil
+}
+
+// Validate checks that source compiles and defines the process function,
+// for the API to reject broken scripts before they are stored.
+func Validate(source string) error {
+	proto, err := compile("script", source)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+	cfg := DefaultConfig()
+	L := newSandbox(cfg, zerolog.Nop())
+	defer L.Close()
+	guard := withMemoryLimit(ctx, L, cfg)
+	L.SetContext(guard)
+
+	if err := load(L, proto); err != nil {
+		if errors.Is(guard.Err(), ErrMemoryLimit) {
+			return guard.Err()
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s", ErrTimeout, validateTimeout)
+		}
+		return err
+	}
+	if L.GetGlobal(EntryPoint).Type() != lua.LTFunction {
+		return fmt.Errorf("script does not define function %s(msg)", EntryPoint)
+	}
+	return nil
+}
+
+// compile parses and compiles a script's source.
+func compile(name, source string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(source), name)
+	if err != nil {
+		return nil, fmt.Errorf("syntax error: %w", err)
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	return proto, nil
+}
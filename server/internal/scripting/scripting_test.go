@@ -0,0 +1,299 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+type storeFunc func(ctx context.Context, groupID uuid.UUID) ([]storage.MessageScript, error)
+
+func (f storeFunc) ListEnabledMessageScriptsByGroupID(ctx context.Context, groupID uuid.UUID) ([]storage.MessageScript, error) {
+	return f(ctx, groupID)
+}
+
+func scripts(sources ...string) storeFunc {
+	list := make([]storage.MessageScript, len(sources))
+	for i, src := range sources {
+		list[i] = storage.MessageScript{
+			ID:        uuid.New(),
+			Name:      "script" + string(rune('a'+i)),
+			Source:    src,
+			Enabled:   true,
+			UpdatedAt: pgtype.Timestamptz{Time: time.Unix(1700000000, 0), Valid: true},
+		}
+	}
+	return func(context.Context, uuid.UUID) ([]storage.MessageScript, error) { return list, nil }
+}
+
+func testMessage() *provider.Message {
+	return &provider.Message{
+		ID:       "msg-1",
+		From:     "sender@example.com",
+		To:       []string{"a@example.com", "b@example.org"},
+		Subject:  "Hello",
+		Headers:  map[string]string{"X-Keep": "1", "X-Drop": "2"},
+		Tags:     []string{"welcome"},
+		Metadata: map[string]string{"plan": "pro"},
+	}
+}
+
+func run(t *testing.T, store Store, cfg Config) (Result, *provider.Message, error) {
+	t.Helper()
+	msg := testMessage()
+	res, err := NewEngine(store, cfg, zerolog.Nop()).Run(context.Background(), uuid.New(), msg)
+	return res, msg, err
+}
+
+func TestEngine_Run_ModifiesHeaders(t *testing.T) {
+	res, msg, err := run(t, scripts(`
+function process(msg)
+  msg.headers["X-Drop"] = nil
+  msg.headers["X-Plan"] = msg.metadata.plan
+  msg.headers["X-Rcpts"] = #msg.to
+  msg.subject = "[" .. msg.tags[1] .. "] " .. msg.subject
+end`), Config{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res != (Result{}) {
+		t.Errorf("result = %+v, want zero", res)
+	}
+	want := map[string]string{"X-Keep": "1", "X-Plan": "pro", "X-Rcpts": "2"}
+	if len(msg.Headers) != len(want) {
+		t.Errorf("headers = %v, want %v", msg.Headers, want)
+	}
+	for k, v := range want {
+		if msg.Headers[k] != v {
+			t.Errorf("header %s = %q, want %q", k, msg.Headers[k], v)
+		}
+	}
+	if msg.Subject != "[welcome] Hello" {
+		t.Errorf("subject = %q", msg.Subject)
+	}
+}
+
+func TestEngine_Run_Reject(t *testing.T) {
+	res, _, err := run(t, scripts(
+		`function process(msg) if msg.to[2]:find("example.org$") then return {reject = "no .org"} end end`,
+		`function process(msg) error("must not run") end`,
+	), Config{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Reject != "no .org" || res.Script != "scripta" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestEngine_Run_LastProviderWins(t *testing.T) {
+	res, _, err := run(t, scripts(
+		`function process(msg) return {provider = "first"} end`,
+		`function process(msg) return {provider = "second"} end`,
+		`function process(msg) end`,
+	), Config{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Provider != "second" || res.Script != "scriptb" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestEngine_Run_Timeout(t *testing.T) {
+	start := time.Now()
+	_, _, err := run(t, scripts(`function process(msg) while true do end end`), Config{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("script ran for %s", time.Since(start))
+	}
+}
+
+func TestEngine_Run_Limits(t *testing.T) {
+	tests := map[string]string{
+		"call depth": `local function f(n) return f(n + 1) + 1 end
+function process(msg) f(1) end`,
+		"string.rep":  `function process(msg) local s = string.rep("x", 1e9) end`,
+		"header size": `function process(msg) msg.headers["X-Big"] = string.rep("x", 2000) end`,
+	}
+	cfg := Config{MaxStringSize: 1000}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := run(t, scripts(src), cfg); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestEngine_Run_MemoryLimit(t *testing.T) {
+	tests := map[string]string{
+		"concat doubling": `function process(msg) local s = "x" while true do s = s .. s end end`,
+		"global doubling": `function process(msg) s = "x" while true do s = s .. s end end`,
+		"pcall doubling": `function process(msg)
+  local s = "x"
+  while true do pcall(function() s = s .. s end) end
+end`,
+		"table growth":  `function process(msg) local t = {} for i = 1, 1e9 do t[i] = "entry " .. i end end`,
+		"nested tables": `function process(msg) local t = {} while true do t = {t, t} end end`,
+	}
+	cfg := Config{Timeout: 10 * time.Second, MaxStringSize: 64 << 10, MaxMemory: 1 << 20}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			_, _, err := run(t, scripts(src), cfg)
+			if !errors.Is(err, ErrMemoryLimit) {
+				t.Fatalf("err = %v, want ErrMemoryLimit", err)
+			}
+			if time.Since(start) > 2*time.Second {
+				t.Errorf("script ran for %s", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestEngine_Run_LibraryLimits(t *testing.T) {
+	tests := map[string]string{
+		"string.format width": `function process(msg) local s = string.format("%999999999s", "x") end`,
+		"string.gsub string":  `function process(msg) local s = string.gsub(string.rep("x", 1000), "x", string.rep("y", 1000)) end`,
+		"string.gsub function": `function process(msg)
+  local big = string.rep("y", 1000)
+  local s = string.gsub(string.rep("x", 1000), "x", function() return big end)
+end`,
+		"table.concat separator": `function process(msg)
+  local t = {}
+  for i = 1, 1000 do t[i] = "" end
+  local s = table.concat(t, string.rep("y", 1000))
+end`,
+	}
+	cfg := Config{MaxStringSize: 64 << 10}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := run(t, scripts(src), cfg); err == nil || !strings.Contains(err.Error(), "result exceeds") {
+				t.Fatalf("err = %v, want a result size error", err)
+			}
+		})
+	}
+
+	// Within the limit the wrapped functions behave as before.
+	res, _, err := run(t, scripts(`function process(msg)
+  local s = string.format("%s-%03d", "a", 7) .. string.gsub("a.b", "%.", {["."] = "-"}) .. table.concat({"x", 1}, ",")
+  return {provider = s .. string.gsub("ab", "(a)", "%1%1")}
+end`), cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Provider != "a-007a-bx,1aab" {
+		t.Errorf("provider = %q", res.Provider)
+	}
+}
+
+func TestEngine_Run_Sandbox(t *testing.T) {
+	for _, global := range []string{"os", "io", "require", "dofile", "loadfile", "load", "loadstring", "debug", "package"} {
+		t.Run(global, func(t *testing.T) {
+			res, _, err := run(t, scripts(`function process(msg) if `+global+` ~= nil then return {reject = "reachable"} end end`), Config{})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if res.Reject != "" {
+				t.Errorf("%s is reachable from scripts", global)
+			}
+		})
+	}
+}
+
+func TestEngine_Run_InvalidResults(t *testing.T) {
+	tests := map[string]string{
+		"non-table return": `function process(msg) return "sendgrid" end`,
+		"non-string field": `function process(msg) return {provider = 1} end`,
+		"header newline":   `function process(msg) msg.headers["X-A"] = "a\r\nBcc: x@example.com" end`,
+		"header table":     `function process(msg) msg.headers["X-A"] = {} end`,
+		"no process":       `x = 1`,
+		"runtime error":    `function process(msg) error("boom") end`,
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := run(t, scripts(src), Config{}); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestEngine_Run_StoreError(t *testing.T) {
+	store := storeFunc(func(context.Context, uuid.UUID) ([]storage.MessageScript, error) {
+		return nil, errors.New("db down")
+	})
+	if _, _, err := run(t, store, Config{}); err == nil || !strings.Contains(err.Error(), "db down") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestEngine_CompileCache(t *testing.T) {
+	s := storage.MessageScript{
+		ID:        uuid.New(),
+		Name:      "cached",
+		Source:    `function process(msg) return {provider = "v1"} end`,
+		UpdatedAt: pgtype.Timestamptz{Time: time.Unix(1, 0), Valid: true},
+	}
+	e := NewEngine(storeFunc(func(context.Context, uuid.UUID) ([]storage.MessageScript, error) {
+		return []storage.MessageScript{s}, nil
+	}), Config{}, zerolog.Nop())
+
+	res, err := e.Run(context.Background(), uuid.New(), testMessage())
+	if err != nil || res.Provider != "v1" {
+		t.Fatalf("first run = %+v, %v", res, err)
+	}
+
+	s.Source = `function process(msg) return {provider = "v2"} end`
+	res, _ = e.Run(context.Background(), uuid.New(), testMessage())
+	if res.Provider != "v1" {
+		t.Errorf("unchanged UpdatedAt recompiled the script: %+v", res)
+	}
+
+	s.UpdatedAt.Time = time.Unix(2, 0)
+	res, _ = e.Run(context.Background(), uuid.New(), testMessage())
+	if res.Provider != "v2" {
+		t.Errorf("updated script not recompiled: %+v", res)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{"valid", `function process(msg) return nil end`, ""},
+		{"syntax error", `function process(msg`, "syntax error"},
+		{"missing process", `function other() end`, "does not define"},
+		{"top-level error", `error("nope")`, "nope"},
+		{"top-level loop", `while true do end`, "timed out"},
+		{"top-level doubling", `local s = "x" while true do s = s .. s end`, "memory limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.source)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
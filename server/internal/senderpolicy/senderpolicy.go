@@ -0,0 +1,217 @@
+// Package senderpolicy restricts the From of outgoing messages to a group's
+// verified sender identities.
+//
+// An identity is an address (alice@example.com) or a domain (example.com).
+// The From header, the sender the providers send as, and any address
+// hidden in the From display name ("ceo@bank.example" <x@evil.example>)
+// must all match one. What happens otherwise depends on the group's mode:
+// ModeReject fails the message, ModeRewrite sends it from the policy's
+// rewrite address and keeps the original From in Reply-To.
+package senderpolicy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// Modes of a group's sender policy, stored in sender_policies.mode.
+const (
+	ModeOff     = "off"
+	ModeReject  = "reject"
+	ModeRewrite = "rewrite"
+)
+
+// Actions of a Decision.
+const (
+	ActionAllow   = "allow"
+	ActionReject  = "reject"
+	ActionRewrite = "rewrite"
+)
+
+// Policy is a group's sender policy with its verified identities.
+type Policy struct {
+	Mode string
+	// RewriteAddress replaces a disallowed From in ModeRewrite.
+	RewriteAddress string
+	Identities     []string
+}
+
+// Decision is the outcome of Enforce.
+type Decision struct {
+	Action string
+	// Reason names the violation; empty when the From is allowed.
+	Reason string
+	// From is the From header before enforcement, and RewrittenFrom the
+	// header after a rewrite.
+	From          string
+	RewrittenFrom string
+}
+
+// displayNameAddress matches address-like words in a display name.
+var displayNameAddress = regexp.MustCompile(`[^\s<>"'(),;:]+@[^\s<>"'(),;:]+`)
+
+// Verification records. An identity is verified by publishing
+// VerificationPrefix followed by its token in a TXT record at
+// VerificationLabel under the identity's domain.
+const (
+	VerificationLabel  = "_smtp-proxy"
+	VerificationPrefix = "smtp-proxy-verification="
+)
+
+// ErrNotVerified is returned by Verify when no TXT record carries the
+// token.
+var ErrNotVerified = errors.New("verification record not found")
+
+// Resolver is the subset of net.Resolver used to verify identities.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// ValidMode reports whether mode is a known policy mode.
+func ValidMode(mode string) bool {
+	switch mode {
+	case ModeOff, ModeReject, ModeRewrite:
+		return true
+	}
+	return false
+}
+
+// Allowed reports whether addr matches one of the identities: an identity
+// equal to the address, or to its domain. Comparison is case-insensitive.
+func Allowed(identities []string, addr string) bool {
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 {
+		return false
+	}
+	domain := addr[at+1:]
+	for _, id := range identities {
+		id = strings.ToLower(id)
+		if id == addr || id == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// Enforce checks msg against p. In ModeRewrite a disallowed From is
+// replaced in msg; in ModeReject msg is left unchanged and the caller fails
+// it. With ModeOff, or an empty mode, every message is allowed.
+func Enforce(p Policy, msg *provider.Message) Decision {
+	from := msg.Headers["From"]
+	if from == "" {
+		from = msg.From
+	}
+	d := Decision{Action: ActionAllow, From: from}
+	if p.Mode == "" || p.Mode == ModeOff {
+		return d
+	}
+
+	name, reason := check(p.Identities, from, msg.From)
+	if reason == "" {
+		return d
+	}
+	d.Reason = reason
+	if p.Mode != ModeRewrite {
+		d.Action = ActionReject
+		return d
+	}
+
+	d.Action = ActionRewrite
+	d.RewrittenFrom = (&mail.Address{Name: name, Address: p.RewriteAddress}).String()
+	msg.From = p.RewriteAddress
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	msg.Headers["From"] = d.RewrittenFrom
+	if msg.Headers["Reply-To"] == "" {
+		msg.Headers["Reply-To"] = from
+	}
+	return d
+}
+
+// check returns the reason from, or the sender the providers send as, is
+// not allowed, and the display name to keep on a rewrite, stripped of any
+// addresses.
+func check(identities []string, from, sender string) (name, reason string) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Sprintf("From %q cannot be parsed", from)
+	}
+	name = strings.TrimSpace(displayNameAddress.ReplaceAllString(addr.Name, ""))
+
+	if !Allowed(identities, addr.Address) {
+		return name, fmt.Sprintf("From address %s is not a verified sender identity", addr.Address)
+	}
+	if sender != "" && !Allowed(identities, sender) {
+		return name, fmt.Sprintf("sender %s is not a verified sender identity", sender)
+	}
+	for _, a := range displayNameAddress.FindAllString(addr.Name, -1) {
+		if !Allowed(identities, a) {
+			return name, fmt.Sprintf("From display name contains unverified address %s", a)
+		}
+	}
+	return name, ""
+}
+
+// NormalizeIdentity validates an address or domain identity and returns it
+// lower-cased. Display names are not accepted.
+func NormalizeIdentity(identity string) (string, error) {
+	identity = strings.ToLower(strings.TrimSpace(identity))
+	domain := identity
+	if strings.Contains(identity, "@") {
+		addr, err := mail.ParseAddress(identity)
+		if err != nil || addr.Name != "" || addr.Address != identity {
+			return "", fmt.Errorf("identity %q is not a valid address", identity)
+		}
+		domain = Domain(identity)
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 || strings.ContainsAny(domain, " <>[]\"") || slices.Contains(labels, "") {
+		return "", fmt.Errorf("identity %q is not a valid address or domain", identity)
+	}
+	return identity, nil
+}
+
+// Domain returns the domain of an identity.
+func Domain(identity string) string {
+	return identity[strings.LastIndex(identity, "@")+1:]
+}
+
+// NewToken returns a random verification token.
+func NewToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// VerificationRecord returns the name and value of the TXT record that
+// verifies an identity with token.
+func VerificationRecord(identity, token string) (name, value string) {
+	return VerificationLabel + "." + Domain(identity), VerificationPrefix + token
+}
+
+// Verify looks up the verification record of identity and returns
+// ErrNotVerified if none of its TXT records carries token.
+func Verify(ctx context.Context, resolver Resolver, identity, token string) error {
+	name, value := VerificationRecord(identity, token)
+	records, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("%w: lookup %s: %v", ErrNotVerified, name, err)
+	}
+	for _, r := range records {
+		if strings.TrimSpace(r) == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no TXT record at %s matches", ErrNotVerified, name)
+}
@@ -0,0 +1,156 @@
+package senderpolicy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+func TestAllowed(t *testing.T) {
+	identities := []string{"example.com", "alice@other.example"}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"bob@example.com", true},
+		{"Bob@Example.COM", true},
+		{"alice@other.example", true},
+		{"bob@other.example", false},
+		{"bob@sub.example.com", false},
+		{"example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Allowed(identities, tt.addr); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	identities := []string{"example.com"}
+	tests := []struct {
+		name       string
+		mode       string
+		from       string
+		sender     string
+		wantAction string
+		wantReason string
+	}{
+		{name: "off", mode: ModeOff, from: "x@evil.example", sender: "x@evil.example", wantAction: ActionAllow},
+		{name: "no policy", mode: "", from: "x@evil.example", sender: "x@evil.example", wantAction: ActionAllow},
+		{name: "verified", mode: ModeReject, from: `"Billing" <billing@example.com>`, sender: "billing@example.com", wantAction: ActionAllow},
+		{name: "envelope only", mode: ModeReject, sender: "billing@example.com", wantAction: ActionAllow},
+		{name: "unverified header", mode: ModeReject, from: "x@evil.example", sender: "billing@example.com", wantAction: ActionReject, wantReason: "From address x@evil.example"},
+		{name: "unverified sender", mode: ModeReject, from: "billing@example.com", sender: "x@evil.example", wantAction: ActionReject, wantReason: "sender x@evil.example"},
+		{name: "display name spoof", mode: ModeReject, from: `"ceo@bank.example" <billing@example.com>`, sender: "billing@example.com", wantAction: ActionReject, wantReason: "display name contains unverified address ceo@bank.example"},
+		{name: "display name own address", mode: ModeReject, from: `"billing@example.com" <billing@example.com>`, sender: "billing@example.com", wantAction: ActionAllow},
+		{name: "unparseable", mode: ModeReject, from: "not an address", sender: "billing@example.com", wantAction: ActionReject, wantReason: "cannot be parsed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &provider.Message{From: tt.sender, Headers: map[string]string{}}
+			if tt.from != "" {
+				msg.Headers["From"] = tt.from
+			}
+			d := Enforce(Policy{Mode: tt.mode, Identities: identities}, msg)
+			if d.Action != tt.wantAction {
+				t.Errorf("action = %q, want %q (reason %q)", d.Action, tt.wantAction, d.Reason)
+			}
+			if !strings.Contains(d.Reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to contain %q", d.Reason, tt.wantReason)
+			}
+			if d.Action == ActionReject && (msg.From != tt.sender || msg.Headers["From"] != tt.from) {
+				t.Errorf("rejected message was modified: %+v", msg)
+			}
+		})
+	}
+}
+
+func TestEnforce_Rewrite(t *testing.T) {
+	msg := &provider.Message{
+		From:    "x@evil.example",
+		Headers: map[string]string{"From": `"Support ceo@bank.example" <x@evil.example>`},
+	}
+	d := Enforce(Policy{Mode: ModeRewrite, RewriteAddress: "noreply@example.com", Identities: []string{"example.com"}}, msg)
+
+	if d.Action != ActionRewrite {
+		t.Fatalf("action = %q, want rewrite", d.Action)
+	}
+	if msg.From != "noreply@example.com" {
+		t.Errorf("sender = %q, want the rewrite address", msg.From)
+	}
+	if msg.Headers["From"] != `"Support" <noreply@example.com>` || d.RewrittenFrom != msg.Headers["From"] {
+		t.Errorf("From header = %q, want the display name without the address", msg.Headers["From"])
+	}
+	if msg.Headers["Reply-To"] != `"Support ceo@bank.example" <x@evil.example>` {
+		t.Errorf("Reply-To = %q, want the original From", msg.Headers["Reply-To"])
+	}
+}
+
+func TestEnforce_RewriteKeepsReplyTo(t *testing.T) {
+	msg := &provider.Message{
+		From:    "x@evil.example",
+		Headers: map[string]string{"Reply-To": "help@example.com"},
+	}
+	Enforce(Policy{Mode: ModeRewrite, RewriteAddress: "noreply@example.com", Identities: []string{"example.com"}}, msg)
+
+	if msg.Headers["Reply-To"] != "help@example.com" {
+		t.Errorf("Reply-To = %q, want the existing one kept", msg.Headers["Reply-To"])
+	}
+	if msg.Headers["From"] != "<noreply@example.com>" {
+		t.Errorf("From header = %q", msg.Headers["From"])
+	}
+}
+
+func TestNormalizeIdentity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: " Example.COM ", want: "example.com"},
+		{in: "Alice@Example.com", want: "alice@example.com"},
+		{in: "localhost", wantErr: true},
+		{in: "Alice <alice@example.com>", wantErr: true},
+		{in: "alice@", wantErr: true},
+		{in: "example..com", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeIdentity(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeIdentity(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if txt, ok := r[name]; ok {
+		return txt, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestVerify(t *testing.T) {
+	name, value := VerificationRecord("alice@example.com", "tok")
+	if name != "_smtp-proxy.example.com" || value != "smtp-proxy-verification=tok" {
+		t.Fatalf("VerificationRecord = %q %q", name, value)
+	}
+	resolver := fakeResolver{name: {"v=spf1 -all", value}}
+
+	if err := Verify(context.Background(), resolver, "example.com", "tok"); err != nil {
+		t.Errorf("expected verified, got %v", err)
+	}
+	if err := Verify(context.Background(), resolver, "example.com", "other"); !errors.Is(err, ErrNotVerified) {
+		t.Errorf("expected ErrNotVerified for a wrong token, got %v", err)
+	}
+	if err := Verify(context.Background(), resolver, "missing.example", "tok"); !errors.Is(err, ErrNotVerified) {
+		t.Errorf("expected ErrNotVerified for a failed lookup, got %v", err)
+	}
+}
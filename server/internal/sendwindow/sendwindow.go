@@ -0,0 +1,179 @@
+// Package sendwindow enforces quiet hours: a group can restrict sending to
+// windows of the week in its timezone, and messages submitted outside every
+// window are held until the next one opens.
+//
+// A message with the X-SMTPProxy-Urgent header set to yes or true is sent
+// at once, for transactional mail such as password resets that cannot wait.
+package sendwindow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"time"
+	// The runtime images have no zoneinfo; embed it so group timezones
+	// resolve everywhere.
+	_ "time/tzdata"
+)
+
+// HeaderUrgent marks a message to be sent outside the sending windows. It
+// is a submission control header and is not forwarded to providers.
+const HeaderUrgent = "X-SMTPProxy-Urgent"
+
+// HeldTag is added to messages held until a window opens.
+const HeldTag = "quiet-hours"
+
+// MaxWindows limits the windows of one group.
+const MaxWindows = 28
+
+// days are the accepted day names, indexed by time.Weekday.
+var days = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Window is a daily time range in which messages may be sent, on Days
+// (every day when empty). Start and End are HH:MM in the schedule's
+// timezone; End is after Start, and may be 24:00 for the end of the day.
+type Window struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// window is a parsed Window: a weekday mask and minutes since midnight.
+type window struct {
+	days       [7]bool
+	start, end int
+}
+
+// Schedule is a group's parsed sending windows.
+type Schedule struct {
+	loc     *time.Location
+	windows []window
+}
+
+// Parse validates a timezone and windows, and returns their schedule.
+func Parse(timezone string, windows []Window) (*Schedule, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	if len(windows) == 0 {
+		return nil, errors.New("at least one window is required")
+	}
+	if len(windows) > MaxWindows {
+		return nil, fmt.Errorf("at most %d windows are allowed", MaxWindows)
+	}
+	s := &Schedule{loc: loc}
+	for i, w := range windows {
+		pw, err := parseWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i+1, err)
+		}
+		s.windows = append(s.windows, pw)
+	}
+	return s, nil
+}
+
+// Decode parses windows stored as JSON, as in send_windows.windows.
+func Decode(timezone string, data []byte) (*Schedule, error) {
+	var windows []Window
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return nil, fmt.Errorf("decode windows: %w", err)
+	}
+	return Parse(timezone, windows)
+}
+
+func parseWindow(w Window) (window, error) {
+	var pw window
+	if len(w.Days) == 0 {
+		pw.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, d := range w.Days {
+		i := dayIndex(strings.ToLower(strings.TrimSpace(d)))
+		if i < 0 {
+			return pw, fmt.Errorf("unknown day %q (want mon, tue, wed, thu, fri, sat or sun)", d)
+		}
+		pw.days[i] = true
+	}
+	var err error
+	if pw.start, err = parseClock(w.Start); err != nil || pw.start == 24*60 {
+		return pw, fmt.Errorf("start must be HH:MM, got %q", w.Start)
+	}
+	if pw.end, err = parseClock(w.End); err != nil {
+		return pw, fmt.Errorf("end must be HH:MM, got %q", w.End)
+	}
+	if pw.end <= pw.start {
+		return pw, errors.New("end must be after start")
+	}
+	return pw, nil
+}
+
+func dayIndex(day string) int {
+	for i, d := range days {
+		if d == day {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseClock returns the minutes since midnight of HH:MM, up to 24:00.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, err
+}
+
+// Next returns t when it falls in a window, or else when the next window
+// opens. Windows are interpreted on the wall clock, so across a DST change
+// they keep their local times.
+func (s *Schedule) Next(t time.Time) time.Time {
+	local := t.In(s.loc)
+	var next time.Time
+	for i := 0; i <= 7; i++ {
+		day := local.AddDate(0, 0, i)
+		for _, w := range s.windows {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			open := clock(day, w.start, s.loc)
+			if !t.Before(open) && t.Before(clock(day, w.end, s.loc)) {
+				return t
+			}
+			if open.After(t) && (next.IsZero() || open.Before(next)) {
+				next = open
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+	return t
+}
+
+// clock returns the time minutes after midnight of day's date in loc.
+func clock(day time.Time, minutes int, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, minutes/60, minutes%60, 0, 0, loc)
+}
+
+// Urgent reports whether header, as produced by net/mail, marks the
+// message as urgent.
+func Urgent(header map[string][]string) bool {
+	for _, v := range header[textproto.CanonicalMIMEHeaderKey(HeaderUrgent)] {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "yes", "true", "1":
+			return true
+		}
+	}
+	return false
+}
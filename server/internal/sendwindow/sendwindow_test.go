@@ -0,0 +1,100 @@
+package sendwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched, err := Parse("Asia/Seoul", []Window{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00"},
+		{Days: []string{"sat"}, Start: "10:00", End: "12:00"},
+	})
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	// 2026-10-16 is a Friday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, seoul)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"inside window", at(16, 10, 30), at(16, 10, 30)},
+		{"at opening", at(16, 9, 0), at(16, 9, 0)},
+		{"before opening", at(16, 7, 15), at(16, 9, 0)},
+		{"at closing", at(16, 18, 0), at(17, 10, 0)},
+		{"saturday afternoon", at(17, 13, 0), at(19, 9, 0)},
+		{"sunday", at(18, 23, 59), at(19, 9, 0)},
+		{"other timezone", time.Date(2026, 10, 16, 0, 30, 0, 0, time.UTC), at(16, 9, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sched.Next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.now, got.In(seoul), tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next_EndOfDay(t *testing.T) {
+	sched, err := Parse("", []Window{{Start: "20:00", End: "24:00"}})
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	now := time.Date(2026, 10, 16, 23, 59, 0, 0, time.UTC)
+	if got := sched.Next(now); !got.Equal(now) {
+		t.Errorf("Next(%v) = %v, want now", now, got)
+	}
+	now = time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	if got, want := sched.Next(now), time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next(%v) = %v, want %v", now, got, want)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		windows  []Window
+	}{
+		{"no windows", "UTC", nil},
+		{"unknown timezone", "Mars/Olympus", []Window{{Start: "09:00", End: "17:00"}}},
+		{"unknown day", "UTC", []Window{{Days: []string{"monday"}, Start: "09:00", End: "17:00"}}},
+		{"bad start", "UTC", []Window{{Start: "9am", End: "17:00"}}},
+		{"start at 24:00", "UTC", []Window{{Start: "24:00", End: "24:00"}}},
+		{"end before start", "UTC", []Window{{Start: "22:00", End: "07:00"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.timezone, tt.windows); err == nil {
+				t.Error("Parse() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestUrgent(t *testing.T) {
+	tests := []struct {
+		header map[string][]string
+		want   bool
+	}{
+		{map[string][]string{"X-Smtpproxy-Urgent": {"yes"}}, true},
+		{map[string][]string{"X-Smtpproxy-Urgent": {" TRUE "}}, true},
+		{map[string][]string{"X-Smtpproxy-Urgent": {"no"}}, false},
+		{map[string][]string{"Subject": {"urgent"}}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := Urgent(tt.header); got != tt.want {
+			t.Errorf("Urgent(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
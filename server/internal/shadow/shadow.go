@@ -0,0 +1,96 @@
+// Package shadow runs reject policies in shadow mode, so a new policy can
+// be evaluated against real traffic before it is enforced.
+//
+// A group lists the policies it runs in shadow mode in shadow_policies.
+// When one of them would reject a message, the rejection is counted in
+// shadow_rejections per policy, reason and day, and the message is let
+// through as if the policy were off. GET /api/v1/policy-shadow/report
+// summarizes the counts.
+package shadow
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Policies that can run in shadow mode.
+const (
+	// PolicyAllowedDomains rejects MAIL FROM domains outside the SMTP
+	// user's allowed_domains.
+	PolicyAllowedDomains = "allowed_domains"
+	// PolicyRecipientValidation rejects risky recipients at RCPT TO when
+	// the group's recipient_validation is reject.
+	PolicyRecipientValidation = "recipient_validation"
+	// PolicySenderPolicy rejects messages whose From is not a verified
+	// sender identity when the group's sender policy mode is reject.
+	PolicySenderPolicy = "sender_policy"
+	// PolicyScripts rejects messages a message script returns reject for.
+	PolicyScripts = "scripts"
+)
+
+// Policies lists the policies that can run in shadow mode.
+var Policies = []string{PolicyAllowedDomains, PolicyRecipientValidation, PolicySenderPolicy, PolicyScripts}
+
+// Valid reports whether policy can run in shadow mode.
+func Valid(policy string) bool {
+	return slices.Contains(Policies, policy)
+}
+
+// Store is the subset of storage.Querier used by Group.
+type Store interface {
+	ListShadowPolicies(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	RecordShadowRejection(ctx context.Context, arg storage.RecordShadowRejectionParams) error
+}
+
+// Group holds the shadow policies of one group, loaded on first use so
+// that messages no policy rejects cost no query. It is not safe for
+// concurrent use.
+type Group struct {
+	store    Store
+	groupID  uuid.UUID
+	loaded   bool
+	policies []string
+}
+
+// NewGroup returns the shadow policies of groupID, read from store.
+func NewGroup(store Store, groupID uuid.UUID) *Group {
+	return &Group{store: store, groupID: groupID}
+}
+
+// Shadowed reports whether the group runs policy in shadow mode. If so,
+// it records reason as a would-be rejection, and the caller must let the
+// message through. When the shadow policies cannot be loaded the policy
+// is enforced; the error is returned for logging either way.
+func (g *Group) Shadowed(ctx context.Context, policy, reason string) (bool, error) {
+	if !g.loaded {
+		policies, err := g.store.ListShadowPolicies(ctx, g.groupID)
+		if err != nil {
+			return false, fmt.Errorf("list shadow policies: %w", err)
+		}
+		g.policies, g.loaded = policies, true
+	}
+	if !slices.Contains(g.policies, policy) {
+		return false, nil
+	}
+
+	metrics.PolicyShadowRejectionsTotal.WithLabelValues(policy).Inc()
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	if err := g.store.RecordShadowRejection(ctx, storage.RecordShadowRejectionParams{
+		GroupID: g.groupID,
+		Policy:  policy,
+		Reason:  redact.Text(reason),
+		Day:     pgtype.Date{Time: day, Valid: true},
+	}); err != nil {
+		return true, fmt.Errorf("record shadow rejection: %w", err)
+	}
+	return true, nil
+}
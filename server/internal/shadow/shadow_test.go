@@ -0,0 +1,85 @@
+package shadow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+type fakeStore struct {
+	policies []string
+	listErr  error
+	lists    int
+	recorded []storage.RecordShadowRejectionParams
+}
+
+func (f *fakeStore) ListShadowPolicies(_ context.Context, _ uuid.UUID) ([]string, error) {
+	f.lists++
+	return f.policies, f.listErr
+}
+
+func (f *fakeStore) RecordShadowRejection(_ context.Context, arg storage.RecordShadowRejectionParams) error {
+	f.recorded = append(f.recorded, arg)
+	return nil
+}
+
+func TestGroup_Shadowed(t *testing.T) {
+	redact.Configure(redact.Config{Recipients: true})
+	defer redact.Configure(redact.Config{})
+
+	store := &fakeStore{policies: []string{PolicySenderPolicy}}
+	g := NewGroup(store, uuid.New())
+
+	ok, err := g.Shadowed(context.Background(), PolicySenderPolicy, "From bob@evil.example not verified")
+	if err != nil || !ok {
+		t.Fatalf("Shadowed(sender_policy) = %v, %v, want true", ok, err)
+	}
+	ok, err = g.Shadowed(context.Background(), PolicyScripts, "script footer: spam")
+	if err != nil || ok {
+		t.Fatalf("Shadowed(scripts) = %v, %v, want false", ok, err)
+	}
+
+	if store.lists != 1 {
+		t.Errorf("shadow policies listed %d times, want once", store.lists)
+	}
+	if len(store.recorded) != 1 {
+		t.Fatalf("recorded %+v, want one rejection", store.recorded)
+	}
+	rec := store.recorded[0]
+	if rec.Policy != PolicySenderPolicy || strings.Contains(rec.Reason, "bob@evil.example") || !rec.Day.Valid {
+		t.Errorf("unexpected recorded rejection: %+v", rec)
+	}
+	if h, m, s := rec.Day.Time.Clock(); h != 0 || m != 0 || s != 0 {
+		t.Errorf("day %v is not truncated to midnight", rec.Day.Time)
+	}
+}
+
+func TestGroup_Shadowed_LoadErrorEnforces(t *testing.T) {
+	store := &fakeStore{policies: []string{PolicyScripts}, listErr: errors.New("connection refused")}
+	g := NewGroup(store, uuid.New())
+
+	ok, err := g.Shadowed(context.Background(), PolicyScripts, "script footer: spam")
+	if err == nil || ok {
+		t.Errorf("Shadowed() = %v, %v, want the policy enforced with an error", ok, err)
+	}
+	if len(store.recorded) != 0 {
+		t.Errorf("recorded %+v, want nothing", store.recorded)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, p := range Policies {
+		if !Valid(p) {
+			t.Errorf("Valid(%q) = false", p)
+		}
+	}
+	if Valid("sandbox") {
+		t.Error("Valid(sandbox) = true")
+	}
+}
@@ -0,0 +1,114 @@
+package smtp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+	"github.com/sungwon/smtp-proxy/server/internal/certmon"
+)
+
+// AdminConfig configures the SMTP server's admin HTTP handler.
+type AdminConfig struct {
+	// Token is the bearer token required by the /admin endpoints. When it
+	// is empty the /admin endpoints are disabled.
+	Token string
+	// Sessions returns the number of active SMTP sessions.
+	Sessions func() int64
+	// Ping checks a dependency required to accept mail (the database).
+	// It may be nil.
+	Ping func(ctx context.Context) error
+	// Certificates returns the certificate expiry monitor's status. It
+	// may be nil when the monitor is disabled.
+	Certificates func() []certmon.Status
+}
+
+// NewAdminHandler returns the HTTP handler of the SMTP server's admin
+// listener:
+//
+//	GET    /healthz             liveness, always 200
+//	GET    /readyz              503 while draining or when Ping fails
+//	GET    /admin/drain         drain status and active session count
+//	POST   /admin/drain         enter drain mode
+//	DELETE /admin/drain         leave drain mode
+//	GET    /admin/certificates  TLS certificate expiry status
+func NewAdminHandler(d *Drainer, cfg AdminConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if d.Draining() {
+			w.Header().Set("Retry-After", "30")
+			apierror.Write(w, http.StatusServiceUnavailable, "draining", "draining", nil)
+			return
+		}
+		if cfg.Ping != nil {
+			if err := cfg.Ping(r.Context()); err != nil {
+				w.Header().Set("Retry-After", "30")
+				apierror.Write(w, http.StatusServiceUnavailable, apierror.CodeDatabaseUnavailable, "database unavailable", nil)
+				return
+			}
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	status := func(w http.ResponseWriter, code int) {
+		var sessions int64
+		if cfg.Sessions != nil {
+			sessions = cfg.Sessions()
+		}
+		writeAdminJSON(w, code, map[string]any{
+			"draining":        d.Draining(),
+			"active_sessions": sessions,
+		})
+	}
+	mux.Handle("GET /admin/drain", requireAdminToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		status(w, http.StatusOK)
+	}))
+	mux.Handle("POST /admin/drain", requireAdminToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		d.Drain()
+		status(w, http.StatusOK)
+	}))
+	mux.Handle("DELETE /admin/drain", requireAdminToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		d.Resume()
+		status(w, http.StatusOK)
+	}))
+
+	mux.Handle("GET /admin/certificates", requireAdminToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		certs := []certmon.Status{}
+		if cfg.Certificates != nil {
+			certs = cfg.Certificates()
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]any{"certificates": certs})
+	}))
+
+	return mux
+}
+
+// requireAdminToken rejects requests without the configured bearer token.
+func requireAdminToken(token string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			apierror.Write(w, http.StatusForbidden, "", "admin token not configured", nil)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			apierror.Write(w, http.StatusUnauthorized, "", "unauthorized", nil)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,131 @@
+package smtp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/certmon"
+)
+
+func TestAdminHandler_Readyz(t *testing.T) {
+	d := NewDrainer(0, zerolog.Nop())
+	var pingErr error
+	h := NewAdminHandler(d, AdminConfig{
+		Ping: func(context.Context) error { return pingErr },
+	})
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected 200, got %d", code)
+	}
+	d.Drain()
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while draining, got %d", code)
+	}
+	d.Resume()
+	pingErr = errors.New("connection refused")
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when the database is down, got %d", code)
+	}
+}
+
+func TestAdminHandler_Drain(t *testing.T) {
+	d := NewDrainer(0, zerolog.Nop())
+	h := NewAdminHandler(d, AdminConfig{
+		Token:    "secret",
+		Sessions: func() int64 { return 2 },
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		wantCode int
+		wantMode bool
+	}{
+		{"no token", http.MethodPost, "", http.StatusUnauthorized, false},
+		{"wrong token", http.MethodPost, "nope", http.StatusUnauthorized, false},
+		{"drain", http.MethodPost, "secret", http.StatusOK, true},
+		{"status", http.MethodGet, "secret", http.StatusOK, true},
+		{"resume", http.MethodDelete, "secret", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/drain", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, rec.Code)
+			}
+			if d.Draining() != tt.wantMode {
+				t.Errorf("expected draining=%v", tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_DrainDisabledWithoutToken(t *testing.T) {
+	d := NewDrainer(0, zerolog.Nop())
+	h := NewAdminHandler(d, AdminConfig{})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/drain", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+	if d.Draining() {
+		t.Error("expected drain mode to be unchanged")
+	}
+}
+
+func TestAdminHandler_Certificates(t *testing.T) {
+	d := NewDrainer(0, zerolog.Nop())
+	h := NewAdminHandler(d, AdminConfig{
+		Token: "secret",
+		Certificates: func() []certmon.Status {
+			return []certmon.Status{{Source: "smtp", Name: "/etc/tls/cert.pem", DaysRemaining: 12}}
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/certificates", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Certificates []certmon.Status `json:"certificates"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Certificates) != 1 || body.Certificates[0].DaysRemaining != 12 {
+		t.Errorf("certificates = %+v", body.Certificates)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/certificates", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
@@ -2,43 +2,142 @@ package smtp
 
 import (
 	"context"
+	"crypto/x509"
+	"io"
 	"sync/atomic"
+	"time"
 
 	gosmtp "github.com/emersion/go-smtp"
 	"github.com/rs/zerolog"
 
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/dedup"
 	"github.com/sungwon/smtp-proxy/server/internal/delivery"
 	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
 	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
 )
 
 // Backend implements the go-smtp Backend interface.
 // It manages session creation and enforces connection limits.
 type Backend struct {
 	queries  storage.Querier
-	delivery delivery.Service
+	tx       storage.TxRunner
 	store    msgstore.MessageStore
 	log      zerolog.Logger
 	maxConns int
 	active   atomic.Int64
+	debug    *debugTargets
+	inbound  bool
+	greylist greylister
+	// validator checks recipients of groups with a recipient validation
+	// policy.
+	validator recipientValidator
+	// shedder reports database pool saturation; while saturated, new
+	// sessions and transactions are deferred with 421.
+	shedder loadShedder
+	// backpressure, when set, defers transactions with 451/452 and
+	// rejects sessions with 421 while the queue backlog or database write
+	// latency is too high.
+	backpressure *Backpressure
+	// memory, when set, bounds the message bodies held in memory; bodies
+	// beyond it are spilled to temporary files.
+	memory *MemoryBudget
+	// drainer, when draining, rejects new sessions with 421 so a restart
+	// does not bounce mail.
+	drainer *Drainer
+	// clientCAs verifies client certificates before they are matched to
+	// users by subject alternative name.
+	clientCAs *x509.CertPool
+	// passThrough, when set, delivers authenticated submissions before
+	// DATA is answered instead of queueing them.
+	passThrough delivery.Service
+	// dedup, when set, drops or tags authenticated submissions identical
+	// to one accepted within its window.
+	dedup deduplicator
+	// loop, when set, rejects messages that have looped back through a
+	// downstream relay.
+	loop *loopDetector
+	// dnsbl, when set, scores inbound clients against DNS blocklists;
+	// greylistListedOnly restricts greylisting to the clients it flags.
+	dnsbl              blocklistChecker
+	greylistListedOnly bool
+	// tokens, when set, validates scoped tokens presented as AUTH
+	// passwords.
+	tokens tokenValidator
+	// passwordMaxAge, when positive, refuses passwords set longer ago.
+	passwordMaxAge time.Duration
+	// sessionActivity records a summary of each authenticated session in
+	// the activity log.
+	sessionActivity bool
 }
 
-// NewBackend creates a new SMTP backend with the given Querier, delivery service,
+// tokenValidator is the subset of *auth.JWTService used by Backend.
+type tokenValidator interface {
+	ValidateAccessToken(token string) (*auth.AccessTokenClaims, error)
+	WeakSigningKey() bool
+}
+
+// deduplicator is the subset of *dedup.Deduper used by Backend.
+type deduplicator interface {
+	Check(ctx context.Context, group, messageID, sender string, recipients []string, raw []byte) (dedup.Result, error)
+	Confirm(ctx context.Context, key, messageID string) error
+	Forget(ctx context.Context, key string) error
+	Action() string
+}
+
+// loadShedder is the subset of *storage.PoolMonitor used by Backend.
+type loadShedder interface {
+	Saturated() bool
+}
+
+// NewBackend creates a new SMTP backend with the given Querier, transaction
+// runner used to persist messages together with their outbox entries,
 // logger, and maximum concurrent connection limit.
-func NewBackend(queries storage.Querier, delivery delivery.Service, store msgstore.MessageStore, log zerolog.Logger, maxConns int) *Backend {
+func NewBackend(queries storage.Querier, tx storage.TxRunner, store msgstore.MessageStore, log zerolog.Logger, maxConns int) *Backend {
 	return &Backend{
-		queries:  queries,
-		delivery: delivery,
-		store:    store,
-		log:      log,
-		maxConns: maxConns,
+		queries:   queries,
+		tx:        tx,
+		store:     store,
+		log:       log,
+		maxConns:  maxConns,
+		debug:     newDebugTargets(queries, log),
+		validator: validation.New(nil),
 	}
 }
 
 // NewSession is called after a client sends EHLO/HELO. It enforces connection
 // limits and creates a new Session for the connection.
 func (b *Backend) NewSession(conn *gosmtp.Conn) (gosmtp.Session, error) {
+	return b.newSession(conn, "", false)
+}
+
+// Listener returns a go-smtp backend for the named listener. Its sessions
+// share b's connection limit and counters; with requireTLS, MAIL FROM is
+// rejected until the client has completed STARTTLS.
+func (b *Backend) Listener(name string, requireTLS bool) gosmtp.Backend {
+	return listenerBackend{backend: b, name: name, requireTLS: requireTLS}
+}
+
+// listenerBackend applies a listener's policy to the sessions of a shared
+// Backend.
+type listenerBackend struct {
+	backend    *Backend
+	name       string
+	requireTLS bool
+}
+
+func (l listenerBackend) NewSession(conn *gosmtp.Conn) (gosmtp.Session, error) {
+	return l.backend.newSession(conn, l.name, l.requireTLS)
+}
+
+func (b *Backend) newSession(conn *gosmtp.Conn, listener string, requireTLS bool) (gosmtp.Session, error) {
+	if b.drainer != nil && b.drainer.Draining() {
+		return nil, b.drainer.reply()
+	}
+
 	current := b.active.Add(1)
 	if int(current) > b.maxConns {
 		b.active.Add(-1)
@@ -53,26 +152,185 @@ func (b *Backend) NewSession(conn *gosmtp.Conn) (gosmtp.Session, error) {
 		}
 	}
 
+	if err := b.shed("connect"); err != nil {
+		b.active.Add(-1)
+		return nil, err
+	}
+	if err := b.pushBack("connect"); err != nil {
+		b.active.Add(-1)
+		return nil, err
+	}
+
 	correlationID := logger.NewCorrelationID()
 	ctx := context.Background()
 	ctx = logger.WithCorrelationID(ctx, correlationID)
 
-	sessionLog := b.log.With().
+	logCtx := b.log.With().
 		Str("correlation_id", correlationID).
-		Str("remote_addr", conn.Hostname()).
-		Logger()
+		Str("remote_addr", conn.Hostname())
+	if listener != "" {
+		logCtx = logCtx.Str("listener", listener)
+	}
+	sessionLog := logCtx.Logger()
 
 	sessionLog.Info().Msg("new SMTP session")
 
-	return &Session{
-		ctx:     ctx,
-		queries: b.queries,
-		log:     sessionLog,
-		backend: b,
-	}, nil
+	session := &Session{
+		ctx:        ctx,
+		queries:    b.queries,
+		log:        sessionLog,
+		backend:    b,
+		remoteIP:   remoteIP(conn.Conn().RemoteAddr()),
+		connState:  conn.TLSConnectionState,
+		requireTLS: requireTLS,
+		stats:      sessionStats{started: time.Now()},
+	}
+
+	// Record a transcript while any debug target is active; whether it is
+	// kept is decided on logout, once the user is known.
+	if len(b.debug.active(ctx)) > 0 {
+		session.transcript = newTranscript(conn.Conn().RemoteAddr(), b.debug.now())
+		session.trace("EHLO "+conn.Hostname(), nil, "250 Hello "+conn.Hostname())
+	}
+
+	return connSession{session}, nil
+}
+
+// connSession is the Session handed to go-smtp. go-smtp answers a nil Data
+// error with a fixed "250 2.0.0 OK: queued"; to include the message ID,
+// a successful Data is reported as a 250 SMTPError, which go-smtp writes
+// verbatim.
+type connSession struct {
+	*Session
+}
+
+// Data delegates to Session.Data and replies with the queued message ID.
+func (c connSession) Data(r io.Reader) error {
+	if err := c.Session.Data(r); err != nil {
+		return err
+	}
+	return &gosmtp.SMTPError{
+		Code:         250,
+		EnhancedCode: gosmtp.EnhancedCode{2, 0, 0},
+		Message:      queuedMessage(c.queuedID),
+	}
 }
 
 // ActiveSessions returns the current number of active SMTP sessions.
 func (b *Backend) ActiveSessions() int64 {
 	return b.active.Load()
 }
+
+// SetLoadShedder makes the backend reject new sessions and MAIL FROM with
+// 421 while s reports the database pool saturated, so clients retry later
+// instead of holding a session until the pool times out.
+func (b *Backend) SetLoadShedder(s loadShedder) {
+	b.shedder = s
+}
+
+// SetBackpressure makes the backend push back on clients at the level p
+// reports, and records message persistence latency in p.
+func (b *Backend) SetBackpressure(p *Backpressure) {
+	b.backpressure = p
+}
+
+// SetMemoryBudget makes the backend's sessions hold message bodies in
+// memory only while m has room. Bodies read while it is exhausted are
+// spilled to temporary files and streamed into the message store; without
+// a store that can stream, they are refused with 452 4.3.1.
+func (b *Backend) SetMemoryBudget(m *MemoryBudget) {
+	b.memory = m
+}
+
+// SetDrainer makes the backend reject new sessions with 421 while d is
+// draining. Sessions already in progress are unaffected.
+func (b *Backend) SetDrainer(d *Drainer) {
+	b.drainer = d
+}
+
+// SetPassThrough switches authenticated submission to pass-through mode:
+// messages are persisted without an outbox entry and delivered by svc
+// before DATA is answered, and the reply reflects the provider's outcome.
+// Inbound mail is always queued.
+func (b *Backend) SetPassThrough(svc delivery.Service) {
+	b.passThrough = svc
+}
+
+// SetDeduper enables duplicate detection for authenticated submissions.
+// Inbound mail is never deduplicated.
+func (b *Backend) SetDeduper(d deduplicator) {
+	b.dedup = d
+}
+
+// SetLoopDetection makes the backend reject messages carrying more than
+// maxReceived Received headers, or naming one of hostnames in one, with
+// 554 5.4.6. A maxReceived of zero disables the count.
+func (b *Backend) SetLoopDetection(maxReceived int, hostnames []string) {
+	b.loop = newLoopDetector(maxReceived, hostnames)
+}
+
+// SetDNSBL makes inbound sessions check the client IP against DNS
+// blocklists before accepting MAIL FROM. Clients scoring the reject score
+// are refused with 554 5.7.1; clients scoring the greylist score are
+// greylisted, and when greylistListedOnly is set they are the only ones.
+// Authenticated submission is never checked.
+func (b *Backend) SetDNSBL(c blocklistChecker, greylistListedOnly bool) {
+	b.dnsbl = c
+	b.greylistListedOnly = greylistListedOnly
+}
+
+// SetClientCAs sets the CAs client certificates must chain to before
+// they can authenticate by subject alternative name. Certificates
+// registered by fingerprint authenticate regardless.
+func (b *Backend) SetClientCAs(pool *x509.CertPool) {
+	b.clientCAs = pool
+}
+
+// SetTokenValidator lets SMTP users authenticate with a scoped token
+// carrying the send scope in place of their password. Tokens are refused
+// while v signs with an empty or placeholder key.
+func (b *Backend) SetTokenValidator(v tokenValidator) {
+	b.tokens = v
+}
+
+// SetPasswordMaxAge makes AUTH PLAIN refuse passwords set more than maxAge
+// ago. Client certificates and scoped tokens are not affected.
+func (b *Backend) SetPasswordMaxAge(maxAge time.Duration) {
+	b.passwordMaxAge = maxAge
+}
+
+// SetSessionActivity records the messages, commands, command errors and
+// duration of each authenticated session in the activity log of the
+// user's group when it ends.
+func (b *Backend) SetSessionActivity(enabled bool) {
+	b.sessionActivity = enabled
+}
+
+// shed returns a 421 error when the database pool is saturated.
+func (b *Backend) shed(stage string) error {
+	if b.shedder == nil || !b.shedder.Saturated() {
+		return nil
+	}
+	metrics.SMTPLoadShedTotal.WithLabelValues(stage).Inc()
+	b.log.Warn().Str("stage", stage).Msg("database pool saturated, shedding load")
+	return &gosmtp.SMTPError{
+		Code:         421,
+		EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+		Message:      "Service temporarily unavailable, try again later",
+	}
+}
+
+// pushBack returns the backpressure reply for a command at stage, or nil
+// when no backpressure is configured or the command may proceed.
+func (b *Backend) pushBack(stage string) error {
+	if b.backpressure == nil {
+		return nil
+	}
+	return b.backpressure.check(stage)
+}
+
+// SetResolver routes recipient validation lookups through r instead of the
+// host resolver.
+func (b *Backend) SetResolver(r validation.Resolver) {
+	b.validator = validation.New(r)
+}
@@ -1,10 +1,16 @@
 package smtp
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
 // stubConn creates a minimal *gosmtp.Conn for testing purposes.
@@ -16,7 +22,7 @@ import (
 func TestNewBackend_NewSession(t *testing.T) {
 	mock := &mockQuerier{}
 	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, nil, log, 10)
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, log, 10)
 
 	// NewSession requires a *gosmtp.Conn. Since we cannot construct one
 	// directly (it is created by the go-smtp server), we test the backend
@@ -35,7 +41,7 @@ func TestNewBackend_NewSession(t *testing.T) {
 func TestNewBackend_ActiveSessionCounter(t *testing.T) {
 	mock := &mockQuerier{}
 	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, nil, log, 100)
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, log, 100)
 
 	// Simulate session creation by incrementing the counter directly.
 	b.active.Add(1)
@@ -58,7 +64,7 @@ func TestNewBackend_ActiveSessionCounter(t *testing.T) {
 func TestNewBackend_ConnectionLimitCheck(t *testing.T) {
 	mock := &mockQuerier{}
 	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, nil, log, 2)
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, log, 2)
 
 	// Simulate filling up to the limit.
 	b.active.Add(2)
@@ -76,3 +82,88 @@ func TestNewBackend_ConnectionLimitCheck(t *testing.T) {
 	// Clean up: revert the test increment.
 	b.active.Add(-1)
 }
+
+func TestConnSession_DataRepliesWithMessageID(t *testing.T) {
+	messageID := uuid.New()
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			return storage.Message{ID: messageID, Status: storage.MessageStatusQueued}, nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	err := connSession{s}.Data(strings.NewReader("Subject: Test\r\n\r\nHello"))
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) {
+		t.Fatalf("expected an SMTP reply, got %v", err)
+	}
+	if smtpErr.Code != 250 || smtpErr.Message != "OK: queued as "+messageID.String() {
+		t.Errorf("unexpected reply: %d %s", smtpErr.Code, smtpErr.Message)
+	}
+}
+
+func TestConnSession_DataPassesErrorsThrough(t *testing.T) {
+	s := newTestSession(&mockQuerier{})
+
+	err := connSession{s}.Data(strings.NewReader("Subject: Test\r\n\r\nHello"))
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 530 {
+		t.Errorf("expected 530 for an unauthenticated session, got %v", err)
+	}
+}
+
+type stubShedder struct{ saturated bool }
+
+func (s *stubShedder) Saturated() bool { return s.saturated }
+
+func TestBackend_NewSession_ShedsWhenPoolSaturated(t *testing.T) {
+	mock := &mockQuerier{}
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, zerolog.Nop(), 10)
+	b.SetLoadShedder(&stubShedder{saturated: true})
+
+	// The saturation check runs before the connection is used.
+	_, err := b.NewSession(nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 421 {
+		t.Fatalf("expected 421, got %v", err)
+	}
+	if b.ActiveSessions() != 0 {
+		t.Errorf("expected shed session to be uncounted, got %d active", b.ActiveSessions())
+	}
+}
+
+func TestSession_Mail_ShedsWhenPoolSaturated(t *testing.T) {
+	s := newAuthenticatedSession(&mockQuerier{}, uuid.New(), uuid.New(), nil)
+	shedder := &stubShedder{saturated: true}
+	s.backend.SetLoadShedder(shedder)
+
+	err := s.Mail("sender@example.com", nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 421 {
+		t.Fatalf("expected 421 while saturated, got %v", err)
+	}
+
+	shedder.saturated = false
+	if err := s.Mail("sender@example.com", nil); err != nil {
+		t.Errorf("expected MAIL FROM to succeed once the pool recovers, got %v", err)
+	}
+}
+
+func TestSession_Mail_RequiresTLS(t *testing.T) {
+	s := newAuthenticatedSession(&mockQuerier{}, uuid.New(), uuid.New(), nil)
+	s.requireTLS = true
+
+	// Without a TLS connection MAIL FROM is rejected before any other check.
+	err := s.Mail("sender@example.com", nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 530 {
+		t.Fatalf("expected 530 before STARTTLS, got %v", err)
+	}
+
+	s.requireTLS = false
+	if err := s.Mail("sender@example.com", nil); err != nil {
+		t.Errorf("expected MAIL FROM to succeed without require_tls, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+)
+
+// BackpressureLevel is how strongly the SMTP server pushes back on clients.
+type BackpressureLevel int32
+
+const (
+	// BackpressureNone accepts mail normally.
+	BackpressureNone BackpressureLevel = iota
+	// BackpressureDefer answers new transactions with 451 and recipients
+	// of transactions already under way with 452, so clients keep the
+	// mail and retry later.
+	BackpressureDefer
+	// BackpressureReject also refuses new sessions, and answers MAIL FROM,
+	// with 421.
+	BackpressureReject
+)
+
+// String returns the level's name as used in logs.
+func (l BackpressureLevel) String() string {
+	switch l {
+	case BackpressureDefer:
+		return "defer"
+	case BackpressureReject:
+		return "reject"
+	default:
+		return "none"
+	}
+}
+
+// DefaultBackpressureInterval and DefaultBackpressureRetryAfter are used
+// when the corresponding BackpressureConfig fields are zero.
+const (
+	DefaultBackpressureInterval   = 5 * time.Second
+	DefaultBackpressureRetryAfter = 30 * time.Second
+)
+
+// BackpressureConfig holds the thresholds at which the SMTP server defers
+// and rejects mail. A zero threshold is disabled.
+type BackpressureConfig struct {
+	// Interval is the delay between samples.
+	Interval time.Duration
+	// DeferBacklog and RejectBacklog are queue backlogs, in messages not
+	// yet acknowledged by the queue workers.
+	DeferBacklog  int64
+	RejectBacklog int64
+	// DeferWriteLatency and RejectWriteLatency are average durations of
+	// the transactions persisting accepted messages.
+	DeferWriteLatency  time.Duration
+	RejectWriteLatency time.Duration
+	// RetryAfter is the retry delay suggested to deferred and rejected
+	// clients.
+	RetryAfter time.Duration
+}
+
+// backlogSource is the subset of *queue.RedisBacklog used by Backpressure.
+type backlogSource interface {
+	Backlog(ctx context.Context) (int64, error)
+}
+
+// Backpressure tracks the queue backlog and database write latency and
+// derives the level at which sessions push back, so the server does not
+// keep accepting mail it cannot hand off. One Backpressure is shared by
+// every listener of the process.
+type Backpressure struct {
+	backlog backlogSource
+	config  BackpressureConfig
+	log     zerolog.Logger
+	level   atomic.Int32
+
+	mu         sync.Mutex
+	writes     int64
+	writeTotal time.Duration
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewBackpressure creates a Backpressure reading the queue backlog from
+// backlog. A nil backlog, e.g. in sync delivery mode, leaves only the
+// write latency thresholds.
+func NewBackpressure(backlog backlogSource, cfg BackpressureConfig, log zerolog.Logger) *Backpressure {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultBackpressureInterval
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = DefaultBackpressureRetryAfter
+	}
+	return &Backpressure{backlog: backlog, config: cfg, log: log}
+}
+
+// Start launches the sampling loop in a background goroutine.
+func (p *Backpressure) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	p.wg.Add(1)
+	go p.run(ctx)
+
+	p.log.Info().
+		Dur("interval", p.config.Interval).
+		Int64("defer_backlog", p.config.DeferBacklog).
+		Int64("reject_backlog", p.config.RejectBacklog).
+		Dur("defer_write_latency", p.config.DeferWriteLatency).
+		Dur("reject_write_latency", p.config.RejectWriteLatency).
+		Msg("SMTP backpressure monitor started")
+}
+
+// Stop signals the sampling loop to exit and waits for it to finish.
+func (p *Backpressure) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+}
+
+func (p *Backpressure) run(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.SampleOnce(ctx)
+		}
+	}
+}
+
+// Level returns the level set by the most recent sample.
+func (p *Backpressure) Level() BackpressureLevel {
+	return BackpressureLevel(p.level.Load())
+}
+
+// ObserveWrite records the duration of one message persistence
+// transaction.
+func (p *Backpressure) ObserveWrite(d time.Duration) {
+	p.mu.Lock()
+	p.writes++
+	p.writeTotal += d
+	p.mu.Unlock()
+}
+
+// SampleOnce reads the queue backlog and the average write latency since
+// the previous sample, updates the metrics and sets the level to the
+// highest one either reaches. With no writes in the interval the latency
+// counts as zero, so a server deferring because of slow writes accepts
+// mail again once per interval to measure them. A backlog that cannot be
+// read is ignored: the outbox keeps accepted mail while Redis is down.
+func (p *Backpressure) SampleOnce(ctx context.Context) BackpressureLevel {
+	p.mu.Lock()
+	var latency time.Duration
+	if p.writes > 0 {
+		latency = p.writeTotal / time.Duration(p.writes)
+	}
+	p.writes, p.writeTotal = 0, 0
+	p.mu.Unlock()
+	metrics.SMTPDBWriteLatency.Set(latency.Seconds())
+
+	level := thresholdLevel(int64(latency), int64(p.config.DeferWriteLatency), int64(p.config.RejectWriteLatency))
+	var backlog int64
+	if p.backlog != nil {
+		n, err := p.backlog.Backlog(ctx)
+		if err != nil {
+			p.log.Warn().Err(err).Msg("failed to read queue backlog")
+		} else {
+			backlog = n
+			metrics.SMTPQueueBacklog.Set(float64(n))
+			level = max(level, thresholdLevel(n, p.config.DeferBacklog, p.config.RejectBacklog))
+		}
+	}
+
+	if prev := BackpressureLevel(p.level.Swap(int32(level))); prev != level {
+		event := p.log.Warn()
+		if level < prev {
+			event = p.log.Info()
+		}
+		event.
+			Stringer("level", level).
+			Stringer("previous", prev).
+			Int64("backlog", backlog).
+			Dur("write_latency", latency).
+			Msg("SMTP backpressure level changed")
+	}
+	metrics.SMTPBackpressureLevel.Set(float64(level))
+	return level
+}
+
+// thresholdLevel returns the level value v reaches. Zero thresholds are
+// disabled.
+func thresholdLevel(v, deferAt, rejectAt int64) BackpressureLevel {
+	switch {
+	case rejectAt > 0 && v >= rejectAt:
+		return BackpressureReject
+	case deferAt > 0 && v >= deferAt:
+		return BackpressureDefer
+	default:
+		return BackpressureNone
+	}
+}
+
+// check returns the reply pushing back on a command at stage (connect,
+// mail or rcpt), or nil when it may proceed. New sessions are only refused
+// at BackpressureReject; recipients are answered 452 at either level so
+// the client retries them with the next transaction.
+func (p *Backpressure) check(stage string) error {
+	level := p.Level()
+	if level == BackpressureNone || (stage == "connect" && level != BackpressureReject) {
+		return nil
+	}
+	retry := int(p.config.RetryAfter.Seconds())
+	var reply *gosmtp.SMTPError
+	switch {
+	case stage == "rcpt":
+		reply = &gosmtp.SMTPError{
+			Code:         452,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 1},
+			Message:      fmt.Sprintf("Mail system busy, try this recipient again in %d seconds", retry),
+		}
+	case level == BackpressureReject:
+		reply = &gosmtp.SMTPError{
+			Code:         421,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+			Message:      fmt.Sprintf("Service overloaded, try again in %d seconds", retry),
+		}
+	default:
+		reply = &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+			Message:      fmt.Sprintf("Mail system busy, try again in %d seconds", retry),
+		}
+	}
+	metrics.SMTPBackpressureRejectionsTotal.WithLabelValues(stage, strconv.Itoa(reply.Code)).Inc()
+	return reply
+}
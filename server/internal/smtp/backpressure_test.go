@@ -0,0 +1,142 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+type stubBacklog struct {
+	n   int64
+	err error
+}
+
+func (s *stubBacklog) Backlog(context.Context) (int64, error) { return s.n, s.err }
+
+func replyCode(err error) int {
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) {
+		return 0
+	}
+	return smtpErr.Code
+}
+
+func TestBackpressure_SampleOnce(t *testing.T) {
+	backlog := &stubBacklog{}
+	p := NewBackpressure(backlog, BackpressureConfig{
+		DeferBacklog:       100,
+		RejectBacklog:      1000,
+		DeferWriteLatency:  100 * time.Millisecond,
+		RejectWriteLatency: time.Second,
+	}, zerolog.Nop())
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		backlog int64
+		writes  []time.Duration
+		want    BackpressureLevel
+	}{
+		{"idle", 0, nil, BackpressureNone},
+		{"backlog defers", 100, nil, BackpressureDefer},
+		{"backlog rejects", 5000, []time.Duration{time.Millisecond}, BackpressureReject},
+		{"slow writes defer", 10, []time.Duration{50 * time.Millisecond, 250 * time.Millisecond}, BackpressureDefer},
+		{"very slow writes reject", 10, []time.Duration{3 * time.Second}, BackpressureReject},
+		{"recovered", 10, []time.Duration{time.Millisecond}, BackpressureNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlog.n = tt.backlog
+			for _, d := range tt.writes {
+				p.ObserveWrite(d)
+			}
+			if got := p.SampleOnce(ctx); got != tt.want || p.Level() != tt.want {
+				t.Errorf("SampleOnce() = %v, Level() = %v; want %v", got, p.Level(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBackpressure_IgnoresUnreadableBacklog(t *testing.T) {
+	p := NewBackpressure(&stubBacklog{n: 5000, err: errors.New("redis down")}, BackpressureConfig{
+		DeferBacklog: 100,
+	}, zerolog.Nop())
+	if got := p.SampleOnce(context.Background()); got != BackpressureNone {
+		t.Errorf("SampleOnce() = %v, want none when the backlog cannot be read", got)
+	}
+}
+
+func TestBackend_Backpressure(t *testing.T) {
+	backlog := &stubBacklog{}
+	p := NewBackpressure(backlog, BackpressureConfig{
+		DeferBacklog:  100,
+		RejectBacklog: 1000,
+		RetryAfter:    45 * time.Second,
+	}, zerolog.Nop())
+	s := newAuthenticatedSession(&mockQuerier{}, uuid.New(), uuid.New(), nil)
+	s.backend.SetBackpressure(p)
+	ctx := context.Background()
+
+	// Deferring: transactions are refused, sessions are not.
+	backlog.n = 500
+	p.SampleOnce(ctx)
+	err := s.Mail("sender@example.com", nil)
+	if replyCode(err) != 451 || !strings.Contains(err.Error(), "45 seconds") {
+		t.Errorf("MAIL FROM while deferring = %v, want 451 with a retry hint", err)
+	}
+	if err := s.Rcpt("rcpt@example.com", nil); replyCode(err) != 452 {
+		t.Errorf("RCPT TO while deferring = %v, want 452", err)
+	}
+	if err := s.backend.pushBack("connect"); err != nil {
+		t.Errorf("new session while deferring = %v, want accepted", err)
+	}
+
+	// Rejecting: new sessions get 421 and are not counted.
+	backlog.n = 5000
+	p.SampleOnce(ctx)
+	active := s.backend.ActiveSessions()
+	if _, err := s.backend.NewSession(nil); replyCode(err) != 421 {
+		t.Errorf("NewSession while rejecting = %v, want 421", err)
+	}
+	if s.backend.ActiveSessions() != active {
+		t.Errorf("rejected session was counted")
+	}
+	if err := s.Mail("sender@example.com", nil); replyCode(err) != 421 {
+		t.Errorf("MAIL FROM while rejecting = %v, want 421", err)
+	}
+
+	backlog.n = 0
+	p.SampleOnce(ctx)
+	if err := s.Mail("sender@example.com", nil); err != nil {
+		t.Errorf("MAIL FROM after recovery = %v, want success", err)
+	}
+}
+
+func TestSession_Data_ObservesWriteLatency(t *testing.T) {
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			time.Sleep(5 * time.Millisecond)
+			return storage.Message{ID: uuid.New(), UserID: arg.UserID}, nil
+		},
+	}
+	p := NewBackpressure(nil, BackpressureConfig{DeferWriteLatency: time.Millisecond}, zerolog.Nop())
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.backend.SetBackpressure(p)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	if err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello")); err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if got := p.SampleOnce(context.Background()); got != BackpressureDefer {
+		t.Errorf("SampleOnce() after a slow write = %v, want defer", got)
+	}
+}
@@ -0,0 +1,101 @@
+package smtp
+
+import (
+	"crypto/x509"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+)
+
+// clientCert returns the certificate the client presented during the TLS
+// handshake, or nil.
+func (s *Session) clientCert() *x509.Certificate {
+	state := s.tlsState()
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	return state.PeerCertificates[0]
+}
+
+// clientCertVerified reports whether the client certificate chains to the
+// configured client CAs.
+func (s *Session) clientCertVerified() bool {
+	state := s.tlsState()
+	if len(state.VerifiedChains) > 0 {
+		return true
+	}
+	if s.backend.clientCAs == nil {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         s.backend.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// authenticateCert authenticates the session as the SMTP user mapped to the
+// client certificate. The certificate matches by fingerprint or, when it
+// was verified against the configured client CAs, by a subject alternative
+// name. A non-empty identity (the AUTH EXTERNAL authorization identity)
+// must be the user's username.
+func (s *Session) authenticateCert(identity string) error {
+	cert := s.clientCert()
+	if cert == nil {
+		return certAuthFailed()
+	}
+
+	mapping, err := s.queries.GetSmtpClientCertByFingerprint(s.ctx, pgtype.Text{String: tlsutil.Fingerprint(cert), Valid: true})
+	if err != nil && s.clientCertVerified() {
+		mapping, err = s.queries.GetSmtpClientCertBySAN(s.ctx, tlsutil.SANs(cert))
+	}
+	if err != nil {
+		s.log.Warn().
+			Str("fingerprint", tlsutil.Fingerprint(cert)).
+			Str("subject", cert.Subject.String()).
+			Msg("cert auth failed: certificate not mapped to a user")
+		return certAuthFailed()
+	}
+
+	user, err := s.queries.GetUserByID(s.ctx, mapping.UserID)
+	if err != nil || !smtpUserEligible(user) {
+		s.log.Warn().Str("user_id", mapping.UserID.String()).Msg("cert auth failed: user not eligible for SMTP")
+		return certAuthFailed()
+	}
+	if identity != "" && identity != user.Username.String {
+		s.log.Warn().
+			Str("identity", identity).
+			Str("username", user.Username.String).
+			Msg("cert auth failed: identity does not match certificate")
+		return certAuthFailed()
+	}
+
+	if err := s.login(user.Username.String, user, "certificate"); err != nil {
+		return err
+	}
+	if err := s.queries.TouchSmtpClientCert(s.ctx, mapping.ID); err != nil {
+		s.log.Warn().Err(err).Msg("failed to record client certificate use")
+	}
+	return nil
+}
+
+// smtpUserEligible reports whether a user may send over SMTP.
+func smtpUserEligible(u storage.User) bool {
+	return u.AccountType == "smtp" && u.Status == "active"
+}
+
+func certAuthFailed() error {
+	return &gosmtp.SMTPError{
+		Code:         535,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 8},
+		Message:      "Authentication failed",
+	}
+}
@@ -0,0 +1,152 @@
+package smtp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
+)
+
+// newClientCert returns a self-signed client certificate for
+// mailer.example.com.
+func newClientCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mailer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"mailer.example.com"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create cert: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse cert: %v", err)
+	}
+	return cert
+}
+
+// newCertSession returns a session whose connection presented cert.
+func newCertSession(mock *mockQuerier, cert *x509.Certificate) *Session {
+	state := tls.ConnectionState{
+		Version:          tls.VersionTLS13,
+		CipherSuite:      tls.TLS_AES_128_GCM_SHA256,
+		PeerCertificates: []*x509.Certificate{cert},
+	}
+	s := newTestSession(mock)
+	s.connState = func() (tls.ConnectionState, bool) { return state, true }
+	return s
+}
+
+// newMockWithCert returns a mock where the SMTP user is mapped to a client
+// certificate by fingerprint or SAN.
+func newMockWithCert(userID, groupID uuid.UUID) *mockQuerier {
+	mock := newMockWithAuth(userID, groupID, "", nil)
+	mock.getUserByIDFn = func(ctx context.Context, id uuid.UUID) (storage.User, error) {
+		if id != userID {
+			return storage.User{}, errNotFound
+		}
+		return mock.getUserByUsernameFn(ctx, sql.NullString{String: "testuser", Valid: true})
+	}
+	return mock
+}
+
+func TestSession_CertAuth_Fingerprint(t *testing.T) {
+	userID, groupID := uuid.New(), uuid.New()
+	mock := newMockWithCert(userID, groupID)
+	cert := newClientCert(t)
+	s := newCertSession(mock, cert)
+	mock.getSmtpClientCertByFingerprintFn = func(_ context.Context, fp pgtype.Text) (storage.SmtpClientCert, error) {
+		if fp.String != tlsutil.Fingerprint(cert) {
+			return storage.SmtpClientCert{}, errNotFound
+		}
+		return storage.SmtpClientCert{ID: uuid.New(), UserID: userID}, nil
+	}
+
+	if !slices.Contains(s.AuthMechanisms(), sasl.External) {
+		t.Errorf("expected EXTERNAL to be offered, got %v", s.AuthMechanisms())
+	}
+
+	// MAIL FROM authenticates the session from the certificate.
+	if err := s.Mail("sender@example.com", nil); err != nil {
+		t.Fatalf("expected MAIL FROM to succeed, got %v", err)
+	}
+	if !s.authenticated || s.userID != userID || s.groupID != groupID {
+		t.Errorf("expected session authenticated as %s, got %v %s", userID, s.authenticated, s.userID)
+	}
+}
+
+func TestSession_CertAuth_SANRequiresVerifiedChain(t *testing.T) {
+	userID, groupID := uuid.New(), uuid.New()
+	mock := newMockWithCert(userID, groupID)
+	mock.getSmtpClientCertBySANFn = func(_ context.Context, sans []string) (storage.SmtpClientCert, error) {
+		if !slices.Contains(sans, "mailer.example.com") {
+			return storage.SmtpClientCert{}, errNotFound
+		}
+		return storage.SmtpClientCert{ID: uuid.New(), UserID: userID}, nil
+	}
+
+	// A certificate that does not chain to a client CA only matches by
+	// fingerprint.
+	cert := newClientCert(t)
+	s := newCertSession(mock, cert)
+	err := s.authenticateCert("")
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 535 {
+		t.Fatalf("expected 535 for unverified SAN match, got %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	s.backend.SetClientCAs(pool)
+	if err := s.authenticateCert(""); err != nil {
+		t.Fatalf("expected verified SAN match to authenticate, got %v", err)
+	}
+	if s.userID != userID {
+		t.Errorf("expected userID=%s, got %s", userID, s.userID)
+	}
+}
+
+func TestSession_CertAuth_IdentityMismatch(t *testing.T) {
+	userID, groupID := uuid.New(), uuid.New()
+	mock := newMockWithCert(userID, groupID)
+	mock.getSmtpClientCertByFingerprintFn = func(_ context.Context, _ pgtype.Text) (storage.SmtpClientCert, error) {
+		return storage.SmtpClientCert{ID: uuid.New(), UserID: userID}, nil
+	}
+	s := newCertSession(mock, newClientCert(t))
+
+	if err := s.authenticateCert("someone-else"); err == nil {
+		t.Fatal("expected AUTH EXTERNAL with another identity to fail")
+	}
+	if err := s.authenticateCert("testuser"); err != nil {
+		t.Fatalf("expected AUTH EXTERNAL as testuser to succeed, got %v", err)
+	}
+}
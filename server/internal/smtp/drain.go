@@ -0,0 +1,101 @@
+package smtp
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+)
+
+// DefaultDrainRetryAfter is the retry delay advertised to clients rejected
+// while draining when NewDrainer is given zero.
+const DefaultDrainRetryAfter = 60 * time.Second
+
+// drainPollInterval is how often WaitIdle checks the session count.
+const drainPollInterval = 100 * time.Millisecond
+
+// Drainer puts SMTP backends in drain mode for maintenance: new sessions
+// are rejected with 421 while existing sessions run to completion. One
+// Drainer is shared by every listener of the process.
+type Drainer struct {
+	draining   atomic.Bool
+	held       atomic.Bool
+	retryAfter time.Duration
+	log        zerolog.Logger
+}
+
+// NewDrainer creates a Drainer whose 421 replies ask clients to retry after
+// retryAfter.
+func NewDrainer(retryAfter time.Duration, log zerolog.Logger) *Drainer {
+	if retryAfter <= 0 {
+		retryAfter = DefaultDrainRetryAfter
+	}
+	return &Drainer{retryAfter: retryAfter, log: log}
+}
+
+// Drain enters drain mode. It reports whether the mode changed.
+func (d *Drainer) Drain() bool {
+	if d.draining.Swap(true) {
+		return false
+	}
+	metrics.SMTPDraining.Set(1)
+	d.log.Warn().Msg("SMTP server draining: rejecting new connections")
+	return true
+}
+
+// Hold enters drain mode for the life of the process: Resume no longer
+// leaves it. The SMTP server is held drained while it runs read-only
+// because the database schema is newer than the binary.
+func (d *Drainer) Hold() {
+	d.held.Store(true)
+	d.Drain()
+}
+
+// Resume leaves drain mode. It reports whether the mode changed; it does
+// not while drain mode is held.
+func (d *Drainer) Resume() bool {
+	if d.held.Load() || !d.draining.Swap(false) {
+		return false
+	}
+	metrics.SMTPDraining.Set(0)
+	d.log.Info().Msg("SMTP server resumed: accepting new connections")
+	return true
+}
+
+// Draining reports whether drain mode is active.
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// reply returns the 421 sent to connections rejected while draining.
+func (d *Drainer) reply() *gosmtp.SMTPError {
+	return &gosmtp.SMTPError{
+		Code:         421,
+		EnhancedCode: gosmtp.EnhancedCode{4, 3, 2},
+		Message:      fmt.Sprintf("Service restarting for maintenance, try again in %d seconds", int(d.retryAfter.Seconds())),
+	}
+}
+
+// WaitIdle blocks until sessions reports no active sessions or ctx is
+// done, and returns the number of sessions still active.
+func WaitIdle(ctx context.Context, sessions func() int64) int64 {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		n := sessions()
+		if n <= 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return n
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/rs/zerolog"
+)
+
+func TestBackend_NewSession_RejectsWhileDraining(t *testing.T) {
+	mock := &mockQuerier{}
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, zerolog.Nop(), 10)
+	d := NewDrainer(45*time.Second, zerolog.Nop())
+	b.SetDrainer(d)
+
+	if !d.Drain() {
+		t.Fatal("expected Drain to enter drain mode")
+	}
+	if d.Drain() {
+		t.Error("expected a second Drain to be a no-op")
+	}
+
+	// The drain check runs before the connection is used.
+	_, err := b.NewSession(nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 421 {
+		t.Fatalf("expected 421 while draining, got %v", err)
+	}
+	if !strings.Contains(smtpErr.Message, "45 seconds") {
+		t.Errorf("expected retry delay in reply, got %q", smtpErr.Message)
+	}
+	if b.ActiveSessions() != 0 {
+		t.Errorf("expected rejected session to be uncounted, got %d active", b.ActiveSessions())
+	}
+
+	if !d.Resume() || d.Draining() {
+		t.Error("expected Resume to leave drain mode")
+	}
+}
+
+func TestDrainer_Hold(t *testing.T) {
+	d := NewDrainer(0, zerolog.Nop())
+	d.Hold()
+	if !d.Draining() {
+		t.Fatal("expected Hold to enter drain mode")
+	}
+	if d.Resume() || !d.Draining() {
+		t.Error("expected Resume to keep a held drain")
+	}
+}
+
+func TestWaitIdle(t *testing.T) {
+	var active atomic.Int64
+	active.Store(2)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		active.Store(0)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if n := WaitIdle(ctx, active.Load); n != 0 {
+		t.Errorf("expected all sessions to finish, %d still active", n)
+	}
+
+	active.Store(3)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if n := WaitIdle(ctx, active.Load); n != 3 {
+		t.Errorf("expected 3 sessions at timeout, got %d", n)
+	}
+}
@@ -0,0 +1,205 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/netip"
+	"strings"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/dnsbl"
+	"github.com/sungwon/smtp-proxy/server/internal/greylist"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// greylister is the subset of *greylist.Greylister used by inbound
+// sessions.
+type greylister interface {
+	Check(ctx context.Context, ip netip.Addr, sender, recipient string) (greylist.Result, error)
+}
+
+// blocklistChecker is the subset of *dnsbl.Checker used by inbound
+// sessions.
+type blocklistChecker interface {
+	Check(ctx context.Context, ip netip.Addr) (dnsbl.Verdict, error)
+}
+
+// NewInboundBackend creates a backend for the inbound listener. Inbound
+// sessions accept mail without authentication for recipient domains that
+// have an enabled inbound route, and queue it for delivery to the route's
+// HTTP endpoint. gl may be nil to disable greylisting.
+func NewInboundBackend(queries storage.Querier, tx storage.TxRunner, store msgstore.MessageStore, log zerolog.Logger, maxConns int, gl *greylist.Greylister) *Backend {
+	b := NewBackend(queries, tx, store, log.With().Str("listener", "inbound").Logger(), maxConns)
+	b.inbound = true
+	if gl != nil {
+		b.greylist = gl
+	}
+	return b
+}
+
+// remoteIP returns the client IP of a connection, or the zero Addr when it
+// is not a TCP connection.
+func remoteIP(addr net.Addr) netip.Addr {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.AddrPort().Addr().Unmap()
+	}
+	return netip.Addr{}
+}
+
+// inboundMail handles MAIL FROM on the inbound listener. Any syntactically
+// valid sender is accepted, including the null reverse-path used by
+// bounces.
+func (s *Session) inboundMail(from string) error {
+	if err := s.checkDNSBL(); err != nil {
+		return err
+	}
+	if from == "" {
+		s.sender = ""
+		return nil
+	}
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		s.log.Warn().Str("from", redact.Text(from)).Msg("invalid sender address format")
+		return &gosmtp.SMTPError{
+			Code:         550,
+			EnhancedCode: gosmtp.EnhancedCode{5, 1, 7},
+			Message:      "Invalid sender address",
+		}
+	}
+	s.sender = addr.Address
+	s.log.Info().Str("from", redact.Email(s.sender)).Msg("inbound MAIL FROM accepted")
+	return nil
+}
+
+// inboundRcpt handles RCPT TO on the inbound listener. The recipient domain
+// must have an enabled inbound route, and every recipient of a message must
+// resolve to the same route because the message is posted once.
+func (s *Session) inboundRcpt(to string) error {
+	addr, err := mail.ParseAddress("<" + to + ">")
+	if err != nil {
+		s.log.Warn().Str("to", redact.Text(to)).Msg("invalid recipient address format")
+		return &gosmtp.SMTPError{
+			Code:         550,
+			EnhancedCode: gosmtp.EnhancedCode{5, 1, 1},
+			Message:      "Invalid recipient address",
+		}
+	}
+
+	domain := strings.ToLower(domainFromEmail(addr.Address))
+	route, err := s.queries.GetInboundRouteByDomain(s.ctx, domain)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.log.Error().Err(err).Str("domain", domain).Msg("failed to look up inbound route")
+			return &gosmtp.SMTPError{
+				Code:         451,
+				EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+				Message:      "Temporary lookup failure",
+			}
+		}
+		s.log.Warn().Str("domain", domain).Msg("no inbound route for recipient domain")
+		return &gosmtp.SMTPError{
+			Code:         550,
+			EnhancedCode: gosmtp.EnhancedCode{5, 1, 2},
+			Message:      "Relay not permitted",
+		}
+	}
+
+	if s.route != nil && s.route.ID != route.ID {
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 5, 3},
+			Message:      "Recipients for other domains must be sent separately",
+		}
+	}
+
+	if s.backend.greylist != nil && (!s.backend.greylistListedOnly || s.dnsblAction == dnsbl.ActionGreylist) {
+		res, err := s.backend.greylist.Check(s.ctx, s.remoteIP, s.sender, addr.Address)
+		if err != nil {
+			s.log.Error().Err(err).Msg("greylist check failed, accepting recipient")
+		}
+		if !res.Allowed {
+			s.log.Info().
+				Str("to", redact.Email(addr.Address)).
+				Str("reason", res.Reason).
+				Dur("retry_after", res.RetryAfter).
+				Msg("recipient greylisted")
+			return &gosmtp.SMTPError{
+				Code:         451,
+				EnhancedCode: gosmtp.EnhancedCode{4, 7, 1},
+				Message:      "Greylisted, please try again later",
+			}
+		}
+	}
+
+	if s.route == nil {
+		s.route = &route
+		s.groupID = route.GroupID
+	}
+	s.recipients = append(s.recipients, addr.Address)
+	s.log.Info().
+		Str("to", redact.Email(addr.Address)).
+		Stringer("route_id", route.ID).
+		Msg("inbound RCPT TO accepted")
+	return nil
+}
+
+// checkDNSBL checks the client IP against the DNS blocklists once per
+// session and rejects it when its score reaches the reject score. A
+// greylist verdict is kept for inboundRcpt. Lookup failures accept the
+// client on the lists that answered.
+func (s *Session) checkDNSBL() error {
+	if s.backend.dnsbl == nil || s.dnsblChecked {
+		if s.dnsblAction == dnsbl.ActionReject {
+			return dnsblRejection(s.remoteIP, s.dnsblListed)
+		}
+		return nil
+	}
+	s.dnsblChecked = true
+
+	v, err := s.backend.dnsbl.Check(s.ctx, s.remoteIP)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("dnsbl lookup failed")
+	}
+	for _, r := range v.Results {
+		result := "clean"
+		if r.Listed {
+			result = "listed"
+		}
+		metrics.SMTPDNSBLChecksTotal.WithLabelValues(r.Zone, result).Inc()
+	}
+	for _, zone := range v.Failed {
+		metrics.SMTPDNSBLChecksTotal.WithLabelValues(zone, "error").Inc()
+	}
+	if v.Action == dnsbl.ActionNone {
+		return nil
+	}
+
+	s.dnsblAction = v.Action
+	s.dnsblListed = v.Listed()
+	metrics.SMTPDNSBLActionsTotal.WithLabelValues(v.Action).Inc()
+	s.log.Info().
+		Strs("lists", s.dnsblListed).
+		Float64("score", v.Score).
+		Str("action", v.Action).
+		Msg("client listed on dns blocklists")
+	if v.Action == dnsbl.ActionReject {
+		return dnsblRejection(s.remoteIP, s.dnsblListed)
+	}
+	return nil
+}
+
+func dnsblRejection(ip netip.Addr, lists []string) error {
+	return &gosmtp.SMTPError{
+		Code:         554,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+		Message:      "Client host [" + ip.String() + "] blocked using " + strings.Join(lists, ", "),
+	}
+}
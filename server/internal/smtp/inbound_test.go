@@ -0,0 +1,242 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/dnsbl"
+	"github.com/sungwon/smtp-proxy/server/internal/greylist"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakeGreylister returns a fixed greylisting result.
+type fakeGreylister struct {
+	result greylist.Result
+	err    error
+	calls  int
+}
+
+func (f *fakeGreylister) Check(_ context.Context, _ netip.Addr, _, _ string) (greylist.Result, error) {
+	f.calls++
+	return f.result, f.err
+}
+
+// fakeBlocklist returns a fixed blocklist verdict.
+type fakeBlocklist struct {
+	verdict dnsbl.Verdict
+	calls   int
+}
+
+func (f *fakeBlocklist) Check(_ context.Context, _ netip.Addr) (dnsbl.Verdict, error) {
+	f.calls++
+	return f.verdict, nil
+}
+
+// newInboundMock returns a mock with one inbound route per domain.
+func newInboundMock(routes ...storage.InboundRoute) *mockQuerier {
+	return &mockQuerier{
+		getInboundRouteByDomainFn: func(_ context.Context, domain string) (storage.InboundRoute, error) {
+			for _, r := range routes {
+				if r.Domain == domain {
+					return r, nil
+				}
+			}
+			return storage.InboundRoute{}, pgx.ErrNoRows
+		},
+	}
+}
+
+func newInboundSession(mock *mockQuerier) *Session {
+	b := NewInboundBackend(mock, &mockTxRunner{queries: mock}, nil, zerolog.Nop(), 100, nil)
+	b.active.Add(1)
+	return &Session{
+		ctx:      context.Background(),
+		queries:  mock,
+		log:      zerolog.Nop(),
+		backend:  b,
+		remoteIP: netip.MustParseAddr("192.0.2.10"),
+	}
+}
+
+func smtpCode(err error) int {
+	var smtpErr *gosmtp.SMTPError
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code
+	}
+	return 0
+}
+
+func TestInbound_NoAuthOffered(t *testing.T) {
+	s := newInboundSession(newInboundMock())
+	if mechs := s.AuthMechanisms(); len(mechs) != 0 {
+		t.Errorf("expected no auth mechanisms, got %v", mechs)
+	}
+	if _, err := s.Auth("PLAIN"); err == nil {
+		t.Error("expected AUTH to be rejected on the inbound listener")
+	}
+}
+
+func TestInbound_AcceptsRoutedDomain(t *testing.T) {
+	groupID := uuid.New()
+	route := storage.InboundRoute{ID: uuid.New(), GroupID: groupID, Domain: "inbound.test", Enabled: true}
+	mock := newInboundMock(route)
+
+	var enqueued storage.EnqueueMessageParams
+	mock.enqueueMessageFn = func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+		enqueued = arg
+		return storage.Message{ID: uuid.New()}, nil
+	}
+	var outbox storage.CreateOutboxEntryParams
+	mock.createOutboxEntryFn = func(_ context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+		outbox = arg
+		return storage.OutboxEntry{}, nil
+	}
+
+	s := newInboundSession(mock)
+	if err := s.Mail("alice@example.com", nil); err != nil {
+		t.Fatalf("Mail() error: %v", err)
+	}
+	if err := s.Rcpt("Support@Inbound.TEST", nil); err != nil {
+		t.Fatalf("Rcpt() error: %v", err)
+	}
+	if err := s.Data(strings.NewReader("Subject: Help\r\n\r\nhello")); err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+
+	if enqueued.UserID.Valid {
+		t.Error("inbound message must not have a user")
+	}
+	if enqueued.GroupID.Bytes != groupID || !enqueued.InboundRouteID.Valid || enqueued.InboundRouteID.Bytes != route.ID {
+		t.Errorf("unexpected enqueue params: %+v", enqueued)
+	}
+	if outbox.GroupID != groupID || outbox.UserID != uuid.Nil {
+		t.Errorf("unexpected outbox params: %+v", outbox)
+	}
+}
+
+func TestInbound_AcceptsNullSender(t *testing.T) {
+	s := newInboundSession(newInboundMock())
+	if err := s.Mail("", nil); err != nil {
+		t.Fatalf("expected null reverse-path to be accepted, got %v", err)
+	}
+}
+
+func TestInbound_RejectsUnknownDomain(t *testing.T) {
+	s := newInboundSession(newInboundMock())
+	_ = s.Mail("alice@example.com", nil)
+	if code := smtpCode(s.Rcpt("someone@elsewhere.test", nil)); code != 550 {
+		t.Errorf("expected 550, got %d", code)
+	}
+}
+
+func TestInbound_LookupErrorIsTemporary(t *testing.T) {
+	mock := &mockQuerier{
+		getInboundRouteByDomainFn: func(_ context.Context, _ string) (storage.InboundRoute, error) {
+			return storage.InboundRoute{}, errors.New("connection refused")
+		},
+	}
+	s := newInboundSession(mock)
+	_ = s.Mail("alice@example.com", nil)
+	if code := smtpCode(s.Rcpt("support@inbound.test", nil)); code != 451 {
+		t.Errorf("expected 451, got %d", code)
+	}
+}
+
+func TestInbound_RecipientsMustShareRoute(t *testing.T) {
+	mock := newInboundMock(
+		storage.InboundRoute{ID: uuid.New(), Domain: "a.test", Enabled: true},
+		storage.InboundRoute{ID: uuid.New(), Domain: "b.test", Enabled: true},
+	)
+	s := newInboundSession(mock)
+	_ = s.Mail("alice@example.com", nil)
+	if err := s.Rcpt("one@a.test", nil); err != nil {
+		t.Fatalf("Rcpt() error: %v", err)
+	}
+	if code := smtpCode(s.Rcpt("two@b.test", nil)); code != 451 {
+		t.Errorf("expected 451 for a second route, got %d", code)
+	}
+
+	s.Reset()
+	if err := s.Rcpt("two@b.test", nil); err != nil {
+		t.Errorf("expected next transaction to accept another route, got %v", err)
+	}
+}
+
+func TestInbound_Greylisting(t *testing.T) {
+	mock := newInboundMock(storage.InboundRoute{ID: uuid.New(), Domain: "inbound.test", Enabled: true})
+	gl := &fakeGreylister{result: greylist.Result{RetryAfter: 5 * time.Minute, Reason: "new"}}
+	s := newInboundSession(mock)
+	s.backend.greylist = gl
+	_ = s.Mail("alice@example.com", nil)
+
+	if code := smtpCode(s.Rcpt("support@inbound.test", nil)); code != 451 {
+		t.Errorf("expected 451 while greylisted, got %d", code)
+	}
+
+	gl.result = greylist.Result{Allowed: true, Reason: "passed"}
+	if err := s.Rcpt("support@inbound.test", nil); err != nil {
+		t.Errorf("expected recipient to pass greylisting, got %v", err)
+	}
+
+	gl.result = greylist.Result{Allowed: true, Reason: "error"}
+	gl.err = errors.New("redis down")
+	if err := s.Rcpt("sales@inbound.test", nil); err != nil {
+		t.Errorf("expected greylist errors to fail open, got %v", err)
+	}
+}
+
+func TestInbound_DNSBL(t *testing.T) {
+	mock := newInboundMock(storage.InboundRoute{ID: uuid.New(), Domain: "inbound.test", Enabled: true})
+	listed := []dnsbl.Result{{Zone: "zen.spamhaus.org", Listed: true, Codes: []string{"127.0.0.4"}}}
+
+	t.Run("reject", func(t *testing.T) {
+		bl := &fakeBlocklist{verdict: dnsbl.Verdict{Score: 1, Results: listed, Action: dnsbl.ActionReject}}
+		s := newInboundSession(mock)
+		s.backend.SetDNSBL(bl, false)
+
+		err := s.Mail("alice@example.com", nil)
+		var smtpErr *gosmtp.SMTPError
+		if !errors.As(err, &smtpErr) || smtpErr.Code != 554 || !strings.Contains(smtpErr.Message, "zen.spamhaus.org") {
+			t.Fatalf("expected 554 naming the list, got %v", err)
+		}
+		s.Reset()
+		if code := smtpCode(s.Mail("bob@example.com", nil)); code != 554 || bl.calls != 1 {
+			t.Errorf("expected a repeated 554 from one lookup, got %d after %d lookups", code, bl.calls)
+		}
+	})
+
+	t.Run("greylist listed only", func(t *testing.T) {
+		gl := &fakeGreylister{result: greylist.Result{RetryAfter: 5 * time.Minute, Reason: "new"}}
+		bl := &fakeBlocklist{}
+		s := newInboundSession(mock)
+		s.backend.greylist = gl
+		s.backend.SetDNSBL(bl, true)
+
+		if err := s.Mail("alice@example.com", nil); err != nil {
+			t.Fatalf("Mail() error = %v", err)
+		}
+		if err := s.Rcpt("support@inbound.test", nil); err != nil || gl.calls != 0 {
+			t.Errorf("expected an unlisted client not to be greylisted, got %v", err)
+		}
+
+		bl.verdict = dnsbl.Verdict{Score: 1, Results: listed, Action: dnsbl.ActionGreylist}
+		s = newInboundSession(mock)
+		s.backend.greylist = gl
+		s.backend.SetDNSBL(bl, true)
+		if err := s.Mail("alice@example.com", nil); err != nil {
+			t.Fatalf("Mail() error = %v", err)
+		}
+		if code := smtpCode(s.Rcpt("support@inbound.test", nil)); code != 451 {
+			t.Errorf("expected a listed client to be greylisted, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package smtp
+
+import (
+	"strings"
+
+	gosmtp "github.com/emersion/go-smtp"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+)
+
+// Loop detection reasons, used as the smtp_loops_detected_total label.
+const (
+	loopReasonHops     = "hops"
+	loopReasonHostname = "hostname"
+)
+
+// loopDetector rejects messages that have passed through too many relays or
+// through this server before, as happens when a downstream relay is
+// misconfigured to send mail back to us.
+type loopDetector struct {
+	// maxReceived is the most Received headers a message may carry; zero
+	// disables the count.
+	maxReceived int
+	// hostnames are this server's names, matched against the from and by
+	// clauses of Received headers.
+	hostnames map[string]bool
+}
+
+// newLoopDetector returns a loopDetector for the given limit and hostnames.
+func newLoopDetector(maxReceived int, hostnames []string) *loopDetector {
+	d := &loopDetector{maxReceived: maxReceived, hostnames: make(map[string]bool, len(hostnames))}
+	for _, h := range hostnames {
+		if h = normalizeHostname(h); h != "" {
+			d.hostnames[h] = true
+		}
+	}
+	return d
+}
+
+// detect returns the reason the message with the given Received headers is
+// a loop, or "" if it is not.
+func (d *loopDetector) detect(received []string) string {
+	if d.maxReceived > 0 && len(received) > d.maxReceived {
+		return loopReasonHops
+	}
+	if len(d.hostnames) == 0 {
+		return ""
+	}
+	for _, r := range received {
+		for _, h := range receivedHosts(r) {
+			if d.hostnames[h] {
+				return loopReasonHostname
+			}
+		}
+	}
+	return ""
+}
+
+// receivedHosts returns the hostnames of the from and by clauses of a
+// Received header, e.g. "mx.example.com" for "from mx.example.com (...)".
+func receivedHosts(received string) []string {
+	fields := strings.Fields(received)
+	var hosts []string
+	for i := 0; i+1 < len(fields); i++ {
+		switch strings.ToLower(fields[i]) {
+		case "from", "by":
+			if h := normalizeHostname(fields[i+1]); h != "" {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	return hosts
+}
+
+func normalizeHostname(h string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.Trim(h, "[]();")), ".")
+}
+
+// checkLoop returns a 554 error when the message is a mail loop.
+func (s *Session) checkLoop(headers map[string][]string) error {
+	if s.backend.loop == nil {
+		return nil
+	}
+	received := headers["Received"]
+	reason := s.backend.loop.detect(received)
+	if reason == "" {
+		return nil
+	}
+	metrics.SMTPLoopsDetectedTotal.WithLabelValues(reason).Inc()
+	s.log.Warn().
+		Str("reason", reason).
+		Int("received_count", len(received)).
+		Msg("mail loop detected")
+	return &gosmtp.SMTPError{
+		Code:         554,
+		EnhancedCode: gosmtp.EnhancedCode{5, 4, 6},
+		Message:      "Mail loop detected",
+	}
+}
@@ -0,0 +1,82 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestLoopDetector_Detect(t *testing.T) {
+	d := newLoopDetector(3, []string{"Relay.Example.com.", ""})
+
+	tests := []struct {
+		name     string
+		received []string
+		want     string
+	}{
+		{name: "no headers", want: ""},
+		{
+			name:     "other hosts",
+			received: []string{"from app.example.net (app.example.net [192.0.2.1]) by mx.example.org with ESMTPS id abc; Mon, 2 Mar 2026 10:00:00 +0000"},
+			want:     "",
+		},
+		{
+			name:     "own hostname in from clause",
+			received: []string{"from relay.example.com ([198.51.100.7]) by mx.example.org with ESMTP; Mon, 2 Mar 2026 10:00:00 +0000"},
+			want:     loopReasonHostname,
+		},
+		{
+			name:     "own hostname in by clause",
+			received: []string{"from app.example.net by RELAY.example.com. with ESMTP; Mon, 2 Mar 2026 10:00:00 +0000"},
+			want:     loopReasonHostname,
+		},
+		{
+			name:     "too many hops",
+			received: []string{"from a by b", "from b by c", "from c by d", "from d by e"},
+			want:     loopReasonHops,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.detect(tt.received); got != tt.want {
+				t.Errorf("detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := newLoopDetector(0, nil).detect(make([]string, 1000)); got != "" {
+		t.Errorf("expected no limit with max_received 0, got %q", got)
+	}
+}
+
+func TestSession_Data_Loop(t *testing.T) {
+	var queued bool
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, _ storage.EnqueueMessageParams) (storage.Message, error) {
+			queued = true
+			return storage.Message{ID: uuid.New(), Status: storage.MessageStatusQueued}, nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.backend.SetLoopDetection(50, []string{"relay.example.com"})
+	s.sender = "app@example.com"
+	s.recipients = []string{"user@example.com"}
+
+	raw := "Received: from relay.example.com by mx.example.org; Mon, 2 Mar 2026 10:00:00 +0000\r\n" +
+		"Subject: Looped\r\n\r\nbody"
+	err := s.Data(strings.NewReader(raw))
+
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 554 || smtpErr.EnhancedCode != (gosmtp.EnhancedCode{5, 4, 6}) {
+		t.Fatalf("expected 554 5.4.6, got %v", err)
+	}
+	if queued {
+		t.Error("expected the looped message not to be queued")
+	}
+}
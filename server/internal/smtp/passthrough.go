@@ -0,0 +1,71 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// deliverPassThrough delivers a persisted message in pass-through mode and
+// translates the outcome into the DATA reply.
+func (s *Session) deliverPassThrough(messageID uuid.UUID, requestID string) error {
+	err := s.backend.passThrough.DeliverMessage(s.ctx, &delivery.Request{
+		MessageID: messageID,
+		UserID:    s.userID,
+		GroupID:   s.groupID,
+		RequestID: requestID,
+	})
+	if err != nil {
+		s.log.Warn().Err(err).Stringer("message_id", messageID).Msg("pass-through delivery failed")
+		return passThroughError(err)
+	}
+	return nil
+}
+
+// passThroughError maps a delivery error to the DATA reply. Provider
+// rejections keep their permanence and name the provider's HTTP status;
+// everything else, including a timeout, is transient so the client retries.
+func passThroughError(err error) *gosmtp.SMTPError {
+	var pe *provider.ProviderError
+	switch {
+	case errors.As(err, &pe) && pe.Permanent:
+		return &gosmtp.SMTPError{
+			Code:         554,
+			EnhancedCode: gosmtp.EnhancedCode{5, 0, 0},
+			Message:      "Rejected by upstream " + upstream(pe),
+		}
+	case errors.As(err, &pe):
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 4, 0},
+			Message:      "Upstream " + upstream(pe) + " temporarily failed, try again later",
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 4, 7},
+			Message:      "Upstream delivery timed out, try again later",
+		}
+	default:
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 4, 0},
+			Message:      "Upstream delivery failed, try again later",
+		}
+	}
+}
+
+// upstream names the provider of a ProviderError and, for HTTP APIs, its
+// response status.
+func upstream(pe *provider.ProviderError) string {
+	if pe.StatusCode == 0 {
+		return pe.Provider
+	}
+	return fmt.Sprintf("%s (HTTP %d)", pe.Provider, pe.StatusCode)
+}
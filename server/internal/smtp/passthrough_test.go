@@ -0,0 +1,128 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// deliveryFunc adapts a function to delivery.Service.
+type deliveryFunc func(ctx context.Context, req *delivery.Request) error
+
+func (f deliveryFunc) DeliverMessage(ctx context.Context, req *delivery.Request) error {
+	return f(ctx, req)
+}
+
+func TestSession_Data_PassThrough(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	messageID := uuid.New()
+	outboxCreated := false
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			return storage.Message{ID: messageID, UserID: arg.UserID, Status: storage.MessageStatusQueued}, nil
+		},
+		createOutboxEntryFn: func(_ context.Context, _ storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+			outboxCreated = true
+			return storage.OutboxEntry{}, nil
+		},
+	}
+
+	var got *delivery.Request
+	s := newAuthenticatedSession(mock, userID, groupID, nil)
+	s.backend.SetPassThrough(deliveryFunc(func(_ context.Context, req *delivery.Request) error {
+		got = req
+		return nil
+	}))
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	if err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello")); err != nil {
+		t.Fatalf("Data() error = %v", err)
+	}
+	if outboxCreated {
+		t.Error("pass-through message was also written to the outbox")
+	}
+	if got == nil || got.MessageID != messageID || got.GroupID != groupID || got.UserID != userID {
+		t.Errorf("delivery request = %+v, want message %s of group %s", got, messageID, groupID)
+	}
+	if s.queuedID != messageID {
+		t.Errorf("queuedID = %s, want %s", s.queuedID, messageID)
+	}
+}
+
+func TestSession_Data_PassThroughFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantCode     int
+		wantEnhanced gosmtp.EnhancedCode
+		wantMessage  string
+	}{
+		{
+			name:         "permanent provider error",
+			err:          fmt.Errorf("provider send: %w", &provider.ProviderError{Provider: "sendgrid", StatusCode: 400, Permanent: true}),
+			wantCode:     554,
+			wantEnhanced: gosmtp.EnhancedCode{5, 0, 0},
+			wantMessage:  "sendgrid (HTTP 400)",
+		},
+		{
+			name:         "transient provider error",
+			err:          &provider.ProviderError{Provider: "mailgun", StatusCode: 503},
+			wantCode:     451,
+			wantEnhanced: gosmtp.EnhancedCode{4, 4, 0},
+			wantMessage:  "mailgun (HTTP 503)",
+		},
+		{
+			name:         "timeout",
+			err:          fmt.Errorf("provider send: %w", context.DeadlineExceeded),
+			wantCode:     451,
+			wantEnhanced: gosmtp.EnhancedCode{4, 4, 7},
+			wantMessage:  "timed out",
+		},
+		{
+			name:         "other error",
+			err:          errors.New("resolve provider: no provider configured"),
+			wantCode:     451,
+			wantEnhanced: gosmtp.EnhancedCode{4, 4, 0},
+			wantMessage:  "Upstream delivery failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockQuerier{
+				enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+					return storage.Message{ID: uuid.New(), UserID: arg.UserID, Status: storage.MessageStatusQueued}, nil
+				},
+			}
+			s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+			s.backend.SetPassThrough(deliveryFunc(func(context.Context, *delivery.Request) error {
+				return tt.err
+			}))
+			s.sender = "sender@example.com"
+			s.recipients = []string{"recipient@example.com"}
+
+			err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello"))
+			var smtpErr *gosmtp.SMTPError
+			if !errors.As(err, &smtpErr) {
+				t.Fatalf("Data() error = %v, want an SMTPError", err)
+			}
+			if smtpErr.Code != tt.wantCode || smtpErr.EnhancedCode != tt.wantEnhanced {
+				t.Errorf("reply = %d %v, want %d %v", smtpErr.Code, smtpErr.EnhancedCode, tt.wantCode, tt.wantEnhanced)
+			}
+			if !strings.Contains(smtpErr.Message, tt.wantMessage) {
+				t.Errorf("reply message = %q, want it to contain %q", smtpErr.Message, tt.wantMessage)
+			}
+		})
+	}
+}
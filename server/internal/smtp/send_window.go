@@ -0,0 +1,52 @@
+package smtp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/sendwindow"
+)
+
+// holdUntil returns when the current message may be sent under the
+// group's sending windows, or the zero time when it may be sent at now.
+// The windows are loaded on the first message of the session. Inbound and
+// pass-through messages are never held, and neither are urgent ones.
+func (s *Session) holdUntil(headers map[string][]string, now time.Time) time.Time {
+	if s.route != nil || s.backend.passThrough != nil {
+		return time.Time{}
+	}
+	if !s.sendWindowLoaded {
+		sw, err := s.queries.GetSendWindow(s.ctx, s.groupID)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			s.sendWindowLoaded = true
+		case err != nil:
+			// Retried on the next message of the session.
+			s.log.Warn().Err(err).Msg("failed to load send window, sending at once")
+			return time.Time{}
+		default:
+			s.sendWindowLoaded = true
+			s.sendWindow, err = sendwindow.Decode(sw.Timezone, sw.Windows)
+			if err != nil {
+				s.log.Error().Err(err).Msg("invalid send window, sending at once")
+			}
+		}
+	}
+	if s.sendWindow == nil {
+		return time.Time{}
+	}
+
+	next := s.sendWindow.Next(now)
+	if !next.After(now) {
+		return time.Time{}
+	}
+	if sendwindow.Urgent(headers) {
+		metrics.SMTPSendWindowMessagesTotal.WithLabelValues("urgent").Inc()
+		s.log.Info().Time("window_opens", next).Msg("urgent message sent outside send window")
+		return time.Time{}
+	}
+	return next
+}
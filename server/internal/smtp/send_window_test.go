@@ -0,0 +1,100 @@
+package smtp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/sendwindow"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// closedToday returns sending windows that are closed for the rest of
+// today and tomorrow in UTC.
+func closedToday() []byte {
+	var days []string
+	today := time.Now().UTC().Weekday()
+	for _, d := range []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"} {
+		if d != strings.ToLower(today.String()[:3]) && d != strings.ToLower(((today + 1) % 7).String()[:3]) {
+			days = append(days, d)
+		}
+	}
+	windows, _ := json.Marshal([]sendwindow.Window{{Days: days, Start: "00:00", End: "24:00"}})
+	return windows
+}
+
+func TestSession_Data_HeldOutsideSendWindow(t *testing.T) {
+	groupID := uuid.New()
+	var tags []byte
+	var held storage.CreateDelayedOutboxEntryParams
+	loads := 0
+	mock := &mockQuerier{
+		getSendWindowFn: func(_ context.Context, id uuid.UUID) (storage.SendWindow, error) {
+			loads++
+			return storage.SendWindow{GroupID: id, Timezone: "UTC", Windows: closedToday()}, nil
+		},
+		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			tags = arg.Tags
+			return storage.Message{ID: uuid.New()}, nil
+		},
+		createOutboxEntryFn: func(_ context.Context, _ storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+			t.Error("outbox entry created without delay")
+			return storage.OutboxEntry{}, nil
+		},
+		createDelayedOutboxEntryFn: func(_ context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+			held = arg
+			return storage.OutboxEntry{ID: uuid.New(), MessageID: arg.MessageID}, nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), groupID, nil)
+
+	for i := 0; i < 2; i++ {
+		s.sender = "sender@example.com"
+		s.recipients = []string{"recipient@example.com"}
+		if err := s.Data(strings.NewReader("Subject: Newsletter\r\n\r\nHello")); err != nil {
+			t.Fatalf("Data() error: %v", err)
+		}
+	}
+
+	tomorrow := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if !held.ClaimedUntil.Valid || !held.ClaimedUntil.Time.After(tomorrow) || held.GroupID != groupID {
+		t.Errorf("held outbox entry = %+v, want one claimed after %v", held, tomorrow)
+	}
+	if !strings.Contains(string(tags), sendwindow.HeldTag) {
+		t.Errorf("tags = %s, want %s", tags, sendwindow.HeldTag)
+	}
+	if loads != 1 {
+		t.Errorf("send window loaded %d times, want once per session", loads)
+	}
+}
+
+func TestSession_Data_UrgentSentOutsideSendWindow(t *testing.T) {
+	var queued bool
+	mock := &mockQuerier{
+		getSendWindowFn: func(_ context.Context, id uuid.UUID) (storage.SendWindow, error) {
+			return storage.SendWindow{GroupID: id, Timezone: "UTC", Windows: closedToday()}, nil
+		},
+		createOutboxEntryFn: func(_ context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+			queued = true
+			return storage.OutboxEntry{ID: uuid.New(), MessageID: arg.MessageID}, nil
+		},
+		createDelayedOutboxEntryFn: func(_ context.Context, _ storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+			t.Error("urgent message held")
+			return storage.OutboxEntry{}, nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	if err := s.Data(strings.NewReader("Subject: Reset your password\r\nX-SMTPProxy-Urgent: yes\r\n\r\nHello")); err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if !queued {
+		t.Error("expected the urgent message to be queued at once")
+	}
+}
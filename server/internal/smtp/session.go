@@ -1,14 +1,19 @@
 package smtp
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/mail"
+	"net/netip"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/emersion/go-sasl"
@@ -18,15 +23,39 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
-	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
+	"github.com/sungwon/smtp-proxy/server/internal/dedup"
+	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/msgtag"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/sendwindow"
+	"github.com/sungwon/smtp-proxy/server/internal/shadow"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
 )
 
-// enqueueRetryBackoff defines the backoff durations for Redis enqueue retries (REQ-SMTP-005).
-var enqueueRetryBackoff = []time.Duration{
-	500 * time.Millisecond,
-	1 * time.Second,
-	2 * time.Second,
+// maxPooledBodySize is the largest message buffer returned to bodyPool, so
+// an occasional huge message does not pin its memory.
+const maxPooledBodySize = 4 << 20
+
+// bodyPool holds buffers for reading DATA, so steady-state traffic reads
+// each message into reused memory instead of a freshly grown buffer.
+var bodyPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func getBodyBuffer() *bytes.Buffer {
+	return bodyPool.Get().(*bytes.Buffer)
+}
+
+func putBodyBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBodySize {
+		return
+	}
+	buf.Reset()
+	bodyPool.Put(buf)
 }
 
 // Session handles a single SMTP connection and implements the go-smtp Session
@@ -43,20 +72,82 @@ type Session struct {
 	allowedDomains []string
 	sender         string
 	recipients     []string
+	transcript     *transcript
+	remoteIP       netip.Addr
+	// queuedID is the ID of the message most recently accepted by Data.
+	queuedID uuid.UUID
+	// route is the inbound route of the current message; it is only set on
+	// the inbound listener.
+	route *storage.InboundRoute
+	// recipientPolicy is the group's recipient validation policy and
+	// riskyRecipient records that the current message has a recipient it
+	// flagged.
+	recipientPolicy string
+	riskyRecipient  bool
+	// sandbox restricts recipients to the verified addresses of the group's
+	// members, loaded into sandboxRecipients on the first RCPT TO.
+	sandbox           bool
+	sandboxRecipients map[string]bool
+	// connState returns the TLS state of the client connection, and
+	// requireTLS rejects MAIL FROM on it until STARTTLS has completed.
+	connState  func() (tls.ConnectionState, bool)
+	requireTLS bool
+	// tlsPolicy is the authenticated user's TLS policy.
+	tlsPolicy tlsutil.Policy
+	// dnsblChecked records that the inbound client was checked against
+	// the DNS blocklists; dnsblAction and dnsblListed hold the verdict.
+	dnsblChecked bool
+	dnsblAction  string
+	dnsblListed  []string
+	// username is the SMTP username the session authenticated as, and
+	// stats count its commands and messages for reportStats.
+	username string
+	stats    sessionStats
+	// shadow holds the authenticated group's policies in shadow mode.
+	shadow *shadow.Group
+	// sendWindow holds the group's sending windows, nil when it may send
+	// at any time, once sendWindowLoaded is set.
+	sendWindow       *sendwindow.Schedule
+	sendWindowLoaded bool
 }
 
 // AuthMechanisms returns the list of supported SASL authentication mechanisms.
+// The inbound listener does not offer authentication.
+// EXTERNAL is offered once the client has presented a certificate.
 func (s *Session) AuthMechanisms() []string {
+	if s.backend.inbound {
+		return nil
+	}
+	if s.clientCert() != nil {
+		return []string{sasl.Plain, sasl.External}
+	}
 	return []string{sasl.Plain}
 }
 
 // Auth handles SASL authentication for the given mechanism.
 func (s *Session) Auth(mech string) (sasl.Server, error) {
+	if s.backend.inbound {
+		return nil, fmt.Errorf("unsupported mechanism: %s", mech)
+	}
+	if mech == sasl.External && s.clientCert() != nil {
+		return sasl.NewExternalServer(func(identity string) (err error) {
+			defer func() {
+				s.countCommand("auth", err)
+				s.trace("AUTH EXTERNAL "+identity, err, "235 2.0.0 Authentication succeeded")
+			}()
+			return s.authenticateCert(identity)
+		}), nil
+	}
 	if mech != sasl.Plain {
 		return nil, fmt.Errorf("unsupported mechanism: %s", mech)
 	}
 
-	return sasl.NewPlainServer(func(identity, username, password string) error {
+	return sasl.NewPlainServer(func(identity, username, password string) (err error) {
+		defer func() {
+			s.countCommand("auth", err)
+			s.trace("AUTH PLAIN [credentials redacted, username "+username+"]", err, "235 2.0.0 Authentication succeeded")
+		}()
+
 		s.log.Info().Str("username", username).Msg("auth attempt")
 
 		// Step 1: Look up user by SMTP username.
@@ -71,7 +162,7 @@ func (s *Session) Auth(mech string) (sasl.Server, error) {
 		}
 
 		// Verify user is an active SMTP account.
-		if user.AccountType != "smtp" || user.Status != "active" {
+		if !smtpUserEligible(user) {
 			s.log.Warn().Str("username", username).
 				Str("account_type", user.AccountType).
 				Str("status", user.Status).
@@ -83,7 +174,10 @@ func (s *Session) Auth(mech string) (sasl.Server, error) {
 			}
 		}
 
-		// Step 2: Verify password.
+		// Step 2: Verify password, or a scoped token with the send scope.
+		if s.verifySendToken(user, password) {
+			return s.login(username, user, "token")
+		}
 		if err := auth.VerifyPassword(user.PasswordHash, password); err != nil {
 			s.log.Warn().Str("username", username).Msg("auth failed: invalid password")
 			return &gosmtp.SMTPError{
@@ -93,58 +187,154 @@ func (s *Session) Auth(mech string) (sasl.Server, error) {
 			}
 		}
 
-		// Step 3: Resolve group membership (SMTP accounts belong to exactly one group).
-		groups, err := s.queries.ListGroupsByUserID(s.ctx, user.ID)
-		if err != nil || len(groups) == 0 {
-			s.log.Warn().Str("username", username).Msg("auth failed: no group membership")
+		// Step 3: Refuse expired passwords.
+		if s.passwordExpired(user) {
+			s.log.Warn().Str("username", username).Msg("auth failed: password expired")
 			return &gosmtp.SMTPError{
 				Code:         535,
 				EnhancedCode: gosmtp.EnhancedCode{5, 7, 8},
-				Message:      "Authentication failed",
+				Message:      "Authentication failed: password expired",
 			}
 		}
 
-		// Step 4: Check group status.
-		group, err := s.queries.GetGroupByID(s.ctx, groups[0].ID)
-		if err != nil || group.Status != "active" {
-			s.log.Warn().Str("username", username).
-				Str("group_id", groups[0].ID.String()).
-				Msg("auth failed: group not active")
-			return &gosmtp.SMTPError{
-				Code:         535,
-				EnhancedCode: gosmtp.EnhancedCode{5, 7, 8},
-				Message:      "Authentication failed",
-			}
+		return s.login(username, user, "password")
+	}), nil
+}
+
+// passwordExpired reports whether the user's password is older than the
+// backend's password max age.
+func (s *Session) passwordExpired(user storage.User) bool {
+	maxAge := s.backend.passwordMaxAge
+	return maxAge > 0 && user.PasswordChangedAt.Valid && time.Since(user.PasswordChangedAt.Time) > maxAge
+}
+
+// verifySendToken reports whether password is a valid scoped token for
+// user that grants the send scope. Unscoped access tokens are not accepted,
+// nor is any token while the signing key is empty or the placeholder.
+func (s *Session) verifySendToken(user storage.User, password string) bool {
+	if s.backend.tokens == nil || s.backend.tokens.WeakSigningKey() || strings.Count(password, ".") != 2 {
+		return false
+	}
+	claims, err := s.backend.tokens.ValidateAccessToken(password)
+	if err != nil {
+		return false
+	}
+	return len(claims.Scope) > 0 && claims.HasScope(auth.ScopeSend) && claims.Subject == user.ID.String()
+}
+
+// login completes authentication of an eligible SMTP user whose
+// credentials (password or client certificate) were verified: it enforces
+// the user's TLS policy and group status and loads the session's sending
+// permissions.
+func (s *Session) login(username string, user storage.User, method string) error {
+	// Enforce the user's TLS policy, even where the listener allows
+	// plaintext AUTH.
+	policy, err := tlsutil.ParsePolicy(user.TlsPolicy)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("auth failed: invalid tls policy")
+		return &gosmtp.SMTPError{
+			Code:         454,
+			EnhancedCode: gosmtp.EnhancedCode{4, 7, 0},
+			Message:      "Temporary authentication failure",
 		}
+	}
+	if err := policy.Check(s.tlsState()); err != nil {
+		s.log.Warn().Err(err).Str("username", username).Msg("auth failed: tls policy not met")
+		return &gosmtp.SMTPError{
+			Code:         538,
+			EnhancedCode: gosmtp.EnhancedCode{5, 7, 11},
+			Message:      "Encryption required for requested authentication mechanism",
+		}
+	}
+	s.tlsPolicy = policy
 
-		s.userID = user.ID
-		s.groupID = group.ID
-		s.authenticated = true
+	// Resolve group membership (SMTP accounts belong to exactly one group).
+	groups, err := s.queries.ListGroupsByUserID(s.ctx, user.ID)
+	if err != nil || len(groups) == 0 {
+		s.log.Warn().Str("username", username).Msg("auth failed: no group membership")
+		return &gosmtp.SMTPError{
+			Code:         535,
+			EnhancedCode: gosmtp.EnhancedCode{5, 7, 8},
+			Message:      "Authentication failed",
+		}
+	}
 
-		// Parse allowed domains from JSONB column.
-		var domains []string
-		if len(user.AllowedDomains) > 0 {
-			if err := json.Unmarshal(user.AllowedDomains, &domains); err != nil {
-				s.log.Error().Err(err).Msg("failed to parse allowed domains")
-				domains = nil
-			}
+	// Check group status.
+	group, err := s.queries.GetGroupByID(s.ctx, groups[0].ID)
+	if err != nil || group.Status != "active" {
+		s.log.Warn().Str("username", username).
+			Str("group_id", groups[0].ID.String()).
+			Msg("auth failed: group not active")
+		return &gosmtp.SMTPError{
+			Code:         535,
+			EnhancedCode: gosmtp.EnhancedCode{5, 7, 8},
+			Message:      "Authentication failed",
 		}
-		s.allowedDomains = domains
+	}
 
-		s.log.Info().
-			Str("username", username).
-			Str("user_id", user.ID.String()).
-			Str("group_id", group.ID.String()).
-			Msg("auth successful")
+	s.userID = user.ID
+	s.groupID = group.ID
+	s.username = username
+	s.shadow = shadow.NewGroup(s.queries, group.ID)
+	s.recipientPolicy = group.RecipientValidation
+	s.sandbox = group.Sandbox
+	s.authenticated = true
+
+	// Parse allowed domains from JSONB column.
+	var domains []string
+	if len(user.AllowedDomains) > 0 {
+		if err := json.Unmarshal(user.AllowedDomains, &domains); err != nil {
+			s.log.Error().Err(err).Msg("failed to parse allowed domains")
+			domains = nil
+		}
+	}
+	s.allowedDomains = domains
 
-		return nil
-	}), nil
+	s.log.Info().
+		Str("username", username).
+		Str("method", method).
+		Str("user_id", user.ID.String()).
+		Str("group_id", group.ID.String()).
+		Msg("auth successful")
+
+	return nil
 }
 
 // Mail handles the MAIL FROM command. It validates that the session is
 // authenticated and that the sender domain is in the user's allowed
 // domains list.
-func (s *Session) Mail(from string, opts *gosmtp.MailOptions) error {
+func (s *Session) Mail(from string, opts *gosmtp.MailOptions) (err error) {
+	defer func() {
+		s.countCommand("mail", err)
+		s.trace("MAIL FROM:<"+from+">", err, "250 2.0.0 Roger, accepting mail from <"+from+">")
+	}()
+	defer s.recoverPanic("mail", &err)
+
+	if err := s.backend.shed("mail"); err != nil {
+		return err
+	}
+	if err := s.backend.pushBack("mail"); err != nil {
+		return err
+	}
+
+	if s.requireTLS && s.tlsState() == nil {
+		return &gosmtp.SMTPError{
+			Code:         530,
+			EnhancedCode: gosmtp.EnhancedCode{5, 7, 0},
+			Message:      "Must issue a STARTTLS command first",
+		}
+	}
+
+	if s.backend.inbound {
+		return s.inboundMail(from)
+	}
+
+	// A client certificate mapped to an SMTP user authenticates the session
+	// without AUTH.
+	if !s.authenticated && s.clientCert() != nil {
+		_ = s.authenticateCert("")
+	}
+
 	if !s.authenticated {
 		return &gosmtp.SMTPError{
 			Code:         530,
@@ -153,10 +343,19 @@ func (s *Session) Mail(from string, opts *gosmtp.MailOptions) error {
 		}
 	}
 
+	if err := s.tlsPolicy.Check(s.tlsState()); err != nil {
+		s.log.Warn().Err(err).Msg("MAIL FROM rejected: tls policy not met")
+		return &gosmtp.SMTPError{
+			Code:         530,
+			EnhancedCode: gosmtp.EnhancedCode{5, 7, 0},
+			Message:      "Must issue a STARTTLS command first",
+		}
+	}
+
 	// Validate sender address format.
 	addr, err := mail.ParseAddress(from)
 	if err != nil {
-		s.log.Warn().Str("from", from).Msg("invalid sender address format")
+		s.log.Warn().Str("from", redact.Text(from)).Msg("invalid sender address format")
 		return &gosmtp.SMTPError{
 			Code:         550,
 			EnhancedCode: gosmtp.EnhancedCode{5, 1, 7},
@@ -165,9 +364,9 @@ func (s *Session) Mail(from string, opts *gosmtp.MailOptions) error {
 	}
 
 	senderDomain := domainFromEmail(addr.Address)
-	if !s.isDomainAllowed(senderDomain) {
+	if !s.isDomainAllowed(senderDomain) && !s.shadowed(shadow.PolicyAllowedDomains, "sender domain "+senderDomain+" not allowed") {
 		s.log.Warn().
-			Str("from", from).
+			Str("from", redact.Email(addr.Address)).
 			Str("domain", senderDomain).
 			Strs("allowed", s.allowedDomains).
 			Msg("sender domain not allowed")
@@ -179,13 +378,27 @@ func (s *Session) Mail(from string, opts *gosmtp.MailOptions) error {
 	}
 
 	s.sender = addr.Address
-	s.log.Info().Str("from", s.sender).Msg("MAIL FROM accepted")
+	s.log.Info().Str("from", redact.Email(s.sender)).Msg("MAIL FROM accepted")
 	return nil
 }
 
 // Rcpt handles the RCPT TO command. It validates the recipient address format
 // and appends it to the session's recipient list.
-func (s *Session) Rcpt(to string, opts *gosmtp.RcptOptions) error {
+func (s *Session) Rcpt(to string, opts *gosmtp.RcptOptions) (err error) {
+	defer func() {
+		s.countCommand("rcpt", err)
+		s.trace("RCPT TO:<"+to+">", err, "250 2.0.0 I'll make sure <"+to+"> gets this")
+	}()
+	defer s.recoverPanic("rcpt", &err)
+
+	if err := s.backend.pushBack("rcpt"); err != nil {
+		return err
+	}
+
+	if s.backend.inbound {
+		return s.inboundRcpt(to)
+	}
+
 	if !s.authenticated {
 		return &gosmtp.SMTPError{
 			Code:         530,
@@ -198,7 +411,7 @@ func (s *Session) Rcpt(to string, opts *gosmtp.RcptOptions) error {
 	if err != nil {
 		// Try parsing as a bare address without angle brackets.
 		if _, err2 := mail.ParseAddress("<" + to + ">"); err2 != nil {
-			s.log.Warn().Str("to", to).Msg("invalid recipient address format")
+			s.log.Warn().Str("to", redact.Text(to)).Msg("invalid recipient address format")
 			return &gosmtp.SMTPError{
 				Code:         550,
 				EnhancedCode: gosmtp.EnhancedCode{5, 1, 1},
@@ -208,16 +421,33 @@ func (s *Session) Rcpt(to string, opts *gosmtp.RcptOptions) error {
 		addr = &mail.Address{Address: to}
 	}
 
+	if err := s.checkSandbox(addr.Address); err != nil {
+		return err
+	}
+	if err := s.checkRecipient(addr.Address); err != nil {
+		return err
+	}
+	if err := s.checkSuppressed(addr.Address); err != nil {
+		return err
+	}
+
 	s.recipients = append(s.recipients, addr.Address)
-	s.log.Info().Str("to", addr.Address).Msg("RCPT TO accepted")
+	s.log.Info().Str("to", redact.Email(addr.Address)).Msg("RCPT TO accepted")
 	return nil
 }
 
 // Data handles the DATA command. It reads the message content, extracts
 // headers and subject, and enqueues the message for processing.
 // Per R-CORE-018, message body content is not logged.
-func (s *Session) Data(r io.Reader) error {
-	if !s.authenticated {
+func (s *Session) Data(r io.Reader) (err error) {
+	var size int
+	defer func() {
+		s.countCommand("data", err)
+		s.trace(fmt.Sprintf("DATA [%d bytes]", size), err, "250 2.0.0 "+queuedMessage(s.queuedID))
+	}()
+	defer s.recoverPanic("data", &err)
+
+	if !s.authenticated && !s.backend.inbound {
 		return &gosmtp.SMTPError{
 			Code:         530,
 			EnhancedCode: gosmtp.EnhancedCode{5, 7, 0},
@@ -233,9 +463,19 @@ func (s *Session) Data(r io.Reader) error {
 		}
 	}
 
-	// Read the full message (headers + body).
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, r); err != nil {
+	// Read the full message (headers + body) into a pooled buffer, or a
+	// temporary file once the memory budget is exhausted. Nothing below may
+	// keep a reference to its bytes once Data returns.
+	body, err := s.backend.readBody(r)
+	if errors.Is(err, errMemoryBudget) {
+		s.log.Warn().Msg("message body memory budget exhausted")
+		return &gosmtp.SMTPError{
+			Code:         452,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 1},
+			Message:      "Insufficient system storage, try again later",
+		}
+	}
+	if err != nil {
 		s.log.Error().Err(err).Msg("failed to read message data")
 		return &gosmtp.SMTPError{
 			Code:         451,
@@ -243,163 +483,262 @@ func (s *Session) Data(r io.Reader) error {
 			Message:      "Error reading message",
 		}
 	}
+	defer body.close()
 
-	body := buf.String()
+	// bodyBytes is nil for a spilled body, which is only ever streamed.
+	bodyBytes := body.bytes()
+	size = int(body.size)
 
 	// Extract subject and headers from the message.
 	subject := ""
 	var headers map[string][]string
-	msg, err := mail.ReadMessage(strings.NewReader(body))
+	content, err := body.reader()
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to read spilled message")
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+			Message:      "Error reading message",
+		}
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(content))
 	if err == nil {
 		subject = msg.Header.Get("Subject")
 		headers = map[string][]string(msg.Header)
 	}
+	if err := s.checkLoop(headers); err != nil {
+		return err
+	}
 
 	// Generate message ID for storage reference.
 	messageID := uuid.New()
 
+	// A submission identical to one accepted within the dedup window is
+	// answered as if queued, with the first message's ID, or tagged.
+	// dedupKey is set when this message was recorded as the first of its
+	// kind, and is confirmed or forgotten once the message is persisted.
+	// Spilled bodies are too large to hash in memory and are not checked.
+	var duplicate bool
+	var dedupKey string
+	if s.backend.dedup != nil && s.route == nil && !body.spilled() {
+		res, err := s.backend.dedup.Check(s.ctx, s.groupID.String(), messageID.String(), s.sender, s.recipients, bodyBytes)
+		if err != nil {
+			s.log.Warn().Err(err).Msg("duplicate check failed, accepting message")
+		} else if !res.Duplicate {
+			dedupKey = res.Key
+		}
+		if res.Duplicate {
+			action := s.backend.dedup.Action()
+			metrics.SMTPDuplicateMessagesTotal.WithLabelValues(action).Inc()
+			s.log.Info().
+				Str("from", redact.Email(s.sender)).
+				Str("original_id", res.OriginalID).
+				Str("action", action).
+				Msg("duplicate message")
+			if action == dedup.ActionDrop {
+				if id, err := uuid.Parse(res.OriginalID); err == nil {
+					s.queuedID = id
+				}
+				return nil
+			}
+			duplicate = true
+		}
+	}
+
 	// Marshal recipients and headers to JSON for storage.
 	recipientsJSON, _ := json.Marshal(s.recipients)
 	headersJSON, _ := json.Marshal(headers)
 
-	bodyBytes := buf.Bytes()
-
 	// Build pgtype.UUID values for user and group identifiers.
 	userPgID := pgtype.UUID{Bytes: s.userID, Valid: true}
 	groupPgID := pgtype.UUID{Bytes: s.groupID, Valid: true}
 
-	// Try to store body in MessageStore and persist metadata.
-	var dbMsg storage.Message
-	if s.backend.store != nil {
+	// Inbound messages belong to the route's group and have no sending user.
+	// Tags and metadata are only taken from authenticated submissions.
+	// Submissions outside the group's sending windows are held until the
+	// next window opens.
+	var routePgID pgtype.UUID
+	var tagsJSON, metadataJSON []byte
+	var holdUntil time.Time
+	if s.route != nil {
+		userPgID = pgtype.UUID{}
+		routePgID = pgtype.UUID{Bytes: s.route.ID, Valid: true}
+	} else {
+		tags, metadata := msgtag.Parse(headers)
+		holdUntil = s.holdUntil(headers, time.Now())
+		if !holdUntil.IsZero() {
+			tags = append(tags, sendwindow.HeldTag)
+		}
+		if s.riskyRecipient {
+			tags = append(tags, riskyRecipientTag)
+		}
+		if duplicate {
+			tags = append(tags, dedup.DuplicateTag)
+		}
+		tagsJSON, metadataJSON = msgtag.Encode(tags, metadata)
+	}
+
+	// Try to store body in MessageStore; fall back to an inline body when
+	// no store is configured or the write fails. A spilled body is streamed
+	// from its file and cannot fall back.
+	storedExternally := false
+	if body.spilled() {
+		if err := s.putSpilled(messageID, body); err != nil {
+			s.log.Error().Err(err).Str("message_id", messageID.String()).
+				Msg("MessageStore write of spilled body failed")
+			return &gosmtp.SMTPError{
+				Code:         451,
+				EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+				Message:      "Error queuing message",
+			}
+		}
+		storedExternally = true
+	} else if s.backend.store != nil {
 		if err := s.backend.store.Put(s.ctx, messageID.String(), bodyBytes); err != nil {
 			s.log.Warn().Err(err).Str("message_id", messageID.String()).
 				Msg("MessageStore write failed, falling back to inline body")
-			// Fall back to inline body storage.
-			dbMsg, err = s.queries.EnqueueMessage(s.ctx, storage.EnqueueMessageParams{
-				UserID:     userPgID,
-				GroupID:    groupPgID,
-				Sender:     s.sender,
-				Recipients: recipientsJSON,
-				Subject:    sql.NullString{String: subject, Valid: subject != ""},
-				Headers:    headersJSON,
-				Body:       pgtype.Text{String: body, Valid: true},
+		} else {
+			storedExternally = true
+		}
+	}
+
+	// Persist the message and its outbox entry in one transaction. The outbox
+	// relay publishes the entry to the queue, so a queue outage no longer
+	// affects the SMTP response once the transaction commits.
+	passThrough := s.backend.passThrough != nil && s.route == nil
+	tlsVersion, tlsCipher := s.tlsParams()
+	requestID := pgtype.Text{String: logger.CorrelationIDFromContext(s.ctx)}
+	requestID.Valid = requestID.String != ""
+	var dbMsg storage.Message
+	writeStart := time.Now()
+	err = s.backend.tx.ExecTx(s.ctx, func(q storage.Querier) error {
+		var err error
+		if storedExternally {
+			dbMsg, err = q.EnqueueMessageMetadata(s.ctx, storage.EnqueueMessageMetadataParams{
+				UserID:         userPgID,
+				GroupID:        groupPgID,
+				Sender:         s.sender,
+				Recipients:     recipientsJSON,
+				Subject:        sql.NullString{String: subject, Valid: subject != ""},
+				Headers:        headersJSON,
+				StorageRef:     pgtype.Text{String: messageID.String(), Valid: true},
+				SizeBytes:      body.size,
+				InboundRouteID: routePgID,
+				Tags:           tagsJSON,
+				Metadata:       metadataJSON,
+				TlsVersion:     tlsVersion,
+				TlsCipher:      tlsCipher,
+				RequestID:      requestID,
 			})
-			if err != nil {
-				s.log.Error().Err(err).Msg("failed to enqueue message (fallback)")
-				return &gosmtp.SMTPError{
-					Code:         451,
-					EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
-					Message:      "Error queuing message",
-				}
-			}
 		} else {
-			// Body stored successfully -- persist metadata only.
-			dbMsg, err = s.queries.EnqueueMessageMetadata(s.ctx, storage.EnqueueMessageMetadataParams{
-				UserID:     userPgID,
-				GroupID:    groupPgID,
-				Sender:     s.sender,
-				Recipients: recipientsJSON,
-				Subject:    sql.NullString{String: subject, Valid: subject != ""},
-				Headers:    headersJSON,
-				StorageRef: pgtype.Text{String: messageID.String(), Valid: true},
+			dbMsg, err = q.EnqueueMessage(s.ctx, storage.EnqueueMessageParams{
+				UserID:         userPgID,
+				GroupID:        groupPgID,
+				Sender:         s.sender,
+				Recipients:     recipientsJSON,
+				Subject:        sql.NullString{String: subject, Valid: subject != ""},
+				Headers:        headersJSON,
+				Body:           pgtype.Text{String: string(bodyBytes), Valid: true},
+				SizeBytes:      int64(len(bodyBytes)),
+				InboundRouteID: routePgID,
+				Tags:           tagsJSON,
+				Metadata:       metadataJSON,
+				TlsVersion:     tlsVersion,
+				TlsCipher:      tlsCipher,
+				RequestID:      requestID,
 			})
-			if err != nil {
-				s.log.Error().Err(err).Msg("failed to enqueue message metadata")
-				return &gosmtp.SMTPError{
-					Code:         451,
-					EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
-					Message:      "Error queuing message",
-				}
-			}
 		}
-	} else {
-		// No MessageStore configured -- use inline body (backward compat).
-		dbMsg, err = s.queries.EnqueueMessage(s.ctx, storage.EnqueueMessageParams{
-			UserID:     userPgID,
-			GroupID:    groupPgID,
-			Sender:     s.sender,
-			Recipients: recipientsJSON,
-			Subject:    sql.NullString{String: subject, Valid: subject != ""},
-			Headers:    headersJSON,
-			Body:       pgtype.Text{String: body, Valid: true},
-		})
 		if err != nil {
-			s.log.Error().Err(err).Msg("failed to enqueue message")
-			return &gosmtp.SMTPError{
-				Code:         451,
-				EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
-				Message:      "Error queuing message",
+			return fmt.Errorf("insert message: %w", err)
+		}
+
+		// Pass-through messages are delivered below, not by the relay.
+		if passThrough {
+			return nil
+		}
+		// A held entry is hidden from the outbox relay until the window
+		// opens.
+		if !holdUntil.IsZero() {
+			_, err = q.CreateDelayedOutboxEntry(s.ctx, storage.CreateDelayedOutboxEntryParams{
+				MessageID:    dbMsg.ID,
+				GroupID:      s.groupID,
+				UserID:       s.userID,
+				RequestID:    requestID,
+				ClaimedUntil: pgtype.Timestamptz{Time: holdUntil, Valid: true},
+			})
+		} else {
+			_, err = q.CreateOutboxEntry(s.ctx, storage.CreateOutboxEntryParams{
+				MessageID: dbMsg.ID,
+				GroupID:   s.groupID,
+				UserID:    s.userID,
+				RequestID: requestID,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("insert outbox entry: %w", err)
+		}
+		return nil
+	})
+	writeDuration := time.Since(writeStart)
+	metrics.SMTPMessageEnqueueDuration.Observe(writeDuration.Seconds())
+	if s.backend.backpressure != nil {
+		s.backend.backpressure.ObserveWrite(writeDuration)
+	}
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to enqueue message")
+		// The client retries, which must not be taken for a duplicate.
+		if dedupKey != "" {
+			if err := s.backend.dedup.Forget(s.ctx, dedupKey); err != nil {
+				s.log.Warn().Err(err).Msg("failed to forget message hash")
 			}
 		}
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+			Message:      "Error queuing message",
+		}
 	}
 
+	s.queuedID = dbMsg.ID
+	if dedupKey != "" {
+		if err := s.backend.dedup.Confirm(s.ctx, dedupKey, dbMsg.ID.String()); err != nil {
+			s.log.Warn().Err(err).Msg("failed to record message hash")
+		}
+	}
 	s.log.Info().
-		Str("from", s.sender).
+		Str("from", redact.Email(s.sender)).
+		Str("subject", redact.Subject(subject)).
 		Int("recipient_count", len(s.recipients)).
 		Stringer("message_id", dbMsg.ID).
+		Bool("external_body", storedExternally).
 		Msg("message persisted")
-
-	// Enqueue ID-only reference for async delivery by the worker process.
-	// Retry with exponential backoff per REQ-SMTP-005.
-	req := &delivery.Request{
-		MessageID: dbMsg.ID,
-		UserID:    s.userID,
-		GroupID:   s.groupID,
-	}
-
-	enqueueStart := time.Now()
-	var lastErr error
-	for attempt, delay := range enqueueRetryBackoff {
-		if err := s.backend.delivery.DeliverMessage(s.ctx, req); err != nil {
-			lastErr = err
-			s.log.Warn().Err(err).
-				Stringer("message_id", dbMsg.ID).
-				Int("attempt", attempt+1).
-				Int("max_attempts", len(enqueueRetryBackoff)).
-				Dur("elapsed", time.Since(enqueueStart)).
-				Msg("enqueue failed, retrying")
-
-			select {
-			case <-s.ctx.Done():
-				lastErr = s.ctx.Err()
-				goto exhausted
-			case <-time.After(delay):
-			}
-			continue
-		}
-		// Enqueue succeeded.
-		if attempt > 0 {
-			s.log.Info().
-				Stringer("message_id", dbMsg.ID).
-				Int("attempt", attempt+1).
-				Dur("elapsed", time.Since(enqueueStart)).
-				Msg("enqueue succeeded on retry")
-		}
-		return nil
+	if !holdUntil.IsZero() {
+		metrics.SMTPSendWindowMessagesTotal.WithLabelValues("held").Inc()
+		s.log.Info().
+			Stringer("message_id", dbMsg.ID).
+			Time("hold_until", holdUntil).
+			Msg("message held until send window opens")
 	}
 
-exhausted:
-	// All retries exhausted -- mark as enqueue_failed (REQ-SMTP-005).
-	s.log.Error().Err(lastErr).
-		Stringer("message_id", dbMsg.ID).
-		Int("attempts", len(enqueueRetryBackoff)).
-		Dur("elapsed", time.Since(enqueueStart)).
-		Msg("enqueue failed after all retries")
-
-	if err := s.queries.UpdateMessageStatus(s.ctx, storage.UpdateMessageStatusParams{
-		ID:     dbMsg.ID,
-		Status: storage.MessageStatusEnqueueFailed,
-	}); err != nil {
-		s.log.Error().Err(err).
-			Stringer("message_id", dbMsg.ID).
-			Msg("failed to update enqueue_failed status")
+	if passThrough {
+		return s.deliverPassThrough(dbMsg.ID, requestID.String)
 	}
+	return nil
+}
 
-	return &gosmtp.SMTPError{
-		Code:         451,
-		EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
-		Message:      "Error queuing message for delivery",
+// putSpilled streams a spilled body into the message store.
+func (s *Session) putSpilled(messageID uuid.UUID, body *spooledBody) error {
+	content, err := body.reader()
+	if err != nil {
+		return err
 	}
+	return s.backend.store.(msgstore.StreamPutter).PutStream(s.ctx, messageID.String(), content, body.size)
+}
+
+// queuedMessage is the text of the final DATA reply. It carries the
+// message ID so clients can correlate submissions with the status API.
+func queuedMessage(id uuid.UUID) string {
+	return "OK: queued as " + id.String()
 }
 
 // Reset is called between messages in the same session. It clears the sender
@@ -407,16 +746,81 @@ exhausted:
 func (s *Session) Reset() {
 	s.sender = ""
 	s.recipients = nil
+	s.route = nil
+	s.riskyRecipient = false
+}
+
+// recoverPanic converts a panic in the command at stage (mail, rcpt or
+// data) into a 451 reply, so the client keeps the message and retries it
+// instead of losing the connection. It must be deferred after trace, so
+// the transcript records the reply sent.
+func (s *Session) recoverPanic(stage string, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	crash.Report(s.log, "smtp", v, map[string]string{"stage": stage})
+	*err = &gosmtp.SMTPError{
+		Code:         451,
+		EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+		Message:      "Internal server error, try again later",
+	}
 }
 
 // Logout is called when the client disconnects. It decrements the backend's
-// active session counter and logs the session closure.
+// active session counter, saves the debug transcript if one was recorded,
+// and reports the session's statistics.
 func (s *Session) Logout() error {
 	s.backend.active.Add(-1)
-	s.log.Info().Msg("session closed")
+	s.saveTranscript()
+	s.reportStats()
 	return nil
 }
 
+// shadowed reports whether the group runs policy in shadow mode, in which
+// case the rejection it would have made for reason is recorded and the
+// command must be accepted.
+func (s *Session) shadowed(policy, reason string) bool {
+	if s.shadow == nil {
+		return false
+	}
+	ok, err := s.shadow.Shadowed(s.ctx, policy, reason)
+	if err != nil {
+		s.log.Warn().Err(err).Str("policy", policy).Msg("shadow mode check failed")
+	}
+	if ok {
+		s.log.Info().
+			Str("policy", policy).
+			Str("reason", redact.Text(reason)).
+			Msg("would reject, policy in shadow mode")
+	}
+	return ok
+}
+
+// tlsState returns the TLS state of the client connection, established by
+// implicit TLS or STARTTLS, or nil when it is unencrypted.
+func (s *Session) tlsState() *tls.ConnectionState {
+	if s.connState == nil {
+		return nil
+	}
+	state, ok := s.connState()
+	if !ok {
+		return nil
+	}
+	return &state
+}
+
+// tlsParams returns the negotiated TLS version and cipher suite recorded on
+// accepted messages, or NULLs for an unencrypted connection.
+func (s *Session) tlsParams() (version, cipher pgtype.Text) {
+	state := s.tlsState()
+	if state == nil {
+		return version, cipher
+	}
+	return pgtype.Text{String: tls.VersionName(state.Version), Valid: true},
+		pgtype.Text{String: tls.CipherSuiteName(state.CipherSuite), Valid: true}
+}
+
 // isDomainAllowed checks whether the given domain is in the user's allowed
 // domains list. If no domains are configured, all domains are allowed.
 func (s *Session) isDomainAllowed(domain string) bool {
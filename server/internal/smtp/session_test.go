@@ -12,24 +12,30 @@ import (
 	"github.com/emersion/go-sasl"
 	gosmtp "github.com/emersion/go-smtp"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog"
 
 	"github.com/sungwon/smtp-proxy/server/internal/auth"
-	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/dedup"
+	"github.com/sungwon/smtp-proxy/server/internal/shadow"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/tlsutil"
 )
 
-// mockDeliveryService implements delivery.Service for testing.
-type mockDeliveryService struct {
-	deliverFn func(ctx context.Context, req *delivery.Request) error
+// mockTxRunner implements storage.TxRunner by running fn directly against
+// the mock querier. A non-nil beginErr simulates a failure to start the
+// transaction.
+type mockTxRunner struct {
+	queries  storage.Querier
+	beginErr error
 }
 
-func (m *mockDeliveryService) DeliverMessage(ctx context.Context, req *delivery.Request) error {
-	if m.deliverFn != nil {
-		return m.deliverFn(ctx, req)
+func (m *mockTxRunner) ExecTx(_ context.Context, fn func(storage.Querier) error) error {
+	if m.beginErr != nil {
+		return m.beginErr
 	}
-	return nil
+	return fn(m.queries)
 }
 
 // errNotFound is a sentinel error for simulating "not found" database results.
@@ -42,6 +48,9 @@ type mockQuerier struct {
 	listGroupsByUserIDFn func(ctx context.Context, userID uuid.UUID) ([]storage.Group, error)
 	getGroupByIDFn       func(ctx context.Context, id uuid.UUID) (storage.Group, error)
 
+	// Sandbox recipients behavior
+	listVerifiedGroupMemberEmailsFn func(ctx context.Context, groupID uuid.UUID) ([]string, error)
+
 	// EnqueueMessage behavior
 	enqueueMessageFn func(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error)
 
@@ -50,14 +59,55 @@ type mockQuerier struct {
 
 	// UpdateMessageStatus behavior
 	updateMessageStatusFn func(ctx context.Context, arg storage.UpdateMessageStatusParams) error
+
+	// CreateOutboxEntry behavior
+	createOutboxEntryFn func(ctx context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error)
+
+	// SMTP debug behavior
+	listActiveSMTPDebugTargetsFn func(ctx context.Context) ([]storage.SmtpDebugTarget, error)
+	createSMTPTranscriptFn       func(ctx context.Context, arg storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error)
+
+	// Inbound route behavior
+	getInboundRouteByDomainFn func(ctx context.Context, domain string) (storage.InboundRoute, error)
+
+	// Client certificate behavior
+	getUserByIDFn                    func(ctx context.Context, id uuid.UUID) (storage.User, error)
+	getSmtpClientCertByFingerprintFn func(ctx context.Context, fingerprint pgtype.Text) (storage.SmtpClientCert, error)
+	getSmtpClientCertBySANFn         func(ctx context.Context, sans []string) (storage.SmtpClientCert, error)
+
+	// Activity log behavior
+	createActivityLogFn func(ctx context.Context, arg storage.CreateActivityLogParams) (storage.ActivityLog, error)
+
+	// Shadow policy behavior
+	listShadowPoliciesFn    func(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	recordShadowRejectionFn func(ctx context.Context, arg storage.RecordShadowRejectionParams) error
+
+	// Send window behavior
+	getSendWindowFn            func(ctx context.Context, groupID uuid.UUID) (storage.SendWindow, error)
+	createDelayedOutboxEntryFn func(ctx context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error)
+
+	// Suppression list behavior
+	isRecipientSuppressedFn func(ctx context.Context, arg storage.IsRecipientSuppressedParams) (bool, error)
 }
 
 // --- Stub implementations for the full Querier interface ---
 
+func (m *mockQuerier) AutoDisableProvider(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) AutoEnableProvider(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
 func (m *mockQuerier) AverageDeliveryDuration(_ context.Context, _ storage.AverageDeliveryDurationParams) ([]storage.AverageDeliveryDurationRow, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) ClaimOutboxEntries(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) CountDeliveryLogsByGroup(_ context.Context, _ storage.CountDeliveryLogsByGroupParams) ([]storage.CountDeliveryLogsByGroupRow, error) {
 	return nil, nil
 }
@@ -70,11 +120,26 @@ func (m *mockQuerier) CountDeliveryLogsByStatus(_ context.Context, _ storage.Cou
 	return nil, nil
 }
 
+func (m *mockQuerier) CountGroupMessagesByTag(_ context.Context, _ storage.CountGroupMessagesByTagParams) ([]storage.CountGroupMessagesByTagRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) CountGroupMessagesSince(_ context.Context, _ storage.CountGroupMessagesSinceParams) (int64, error) {
+	return 0, nil
+}
+
 func (m *mockQuerier) CountGroupOwners(_ context.Context, _ uuid.UUID) (int64, error) {
 	return 0, nil
 }
 
-func (m *mockQuerier) CreateActivityLog(_ context.Context, _ storage.CreateActivityLogParams) (storage.ActivityLog, error) {
+func (m *mockQuerier) CountOutboxEntries(_ context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateActivityLog(ctx context.Context, arg storage.CreateActivityLogParams) (storage.ActivityLog, error) {
+	if m.createActivityLogFn != nil {
+		return m.createActivityLogFn(ctx, arg)
+	}
 	return storage.ActivityLog{}, nil
 }
 
@@ -90,14 +155,40 @@ func (m *mockQuerier) CreateGroupMember(_ context.Context, _ storage.CreateGroup
 	return storage.GroupMember{}, nil
 }
 
+func (m *mockQuerier) CreateInboundRoute(_ context.Context, _ storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+
+func (m *mockQuerier) CreateOutboxEntry(ctx context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+	if m.createOutboxEntryFn != nil {
+		return m.createOutboxEntryFn(ctx, arg)
+	}
+	return storage.OutboxEntry{ID: uuid.New(), MessageID: arg.MessageID}, nil
+}
+
 func (m *mockQuerier) CreateProvider(_ context.Context, _ storage.CreateProviderParams) (storage.EspProvider, error) {
 	return storage.EspProvider{}, nil
 }
 
+func (m *mockQuerier) CreateProviderHealthCheck(_ context.Context, _ storage.CreateProviderHealthCheckParams) (storage.ProviderHealthCheck, error) {
+	return storage.ProviderHealthCheck{}, nil
+}
+
 func (m *mockQuerier) CreateRoutingRule(_ context.Context, _ storage.CreateRoutingRuleParams) (storage.RoutingRule, error) {
 	return storage.RoutingRule{}, nil
 }
 
+func (m *mockQuerier) CreateSMTPDebugTarget(_ context.Context, _ storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error) {
+	return storage.SmtpDebugTarget{}, nil
+}
+
+func (m *mockQuerier) CreateSMTPTranscript(ctx context.Context, arg storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+	if m.createSMTPTranscriptFn != nil {
+		return m.createSMTPTranscriptFn(ctx, arg)
+	}
+	return storage.SmtpTranscript{}, nil
+}
+
 func (m *mockQuerier) CreateSession(_ context.Context, _ storage.CreateSessionParams) (storage.Session, error) {
 	return storage.Session{}, nil
 }
@@ -106,6 +197,10 @@ func (m *mockQuerier) CreateUser(_ context.Context, _ storage.CreateUserParams)
 	return storage.User{}, nil
 }
 
+func (m *mockQuerier) DailyDeliveryVolume(_ context.Context, _ storage.DailyDeliveryVolumeParams) ([]storage.DailyDeliveryVolumeRow, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) DeleteExpiredSessions(_ context.Context) error {
 	return nil
 }
@@ -122,14 +217,34 @@ func (m *mockQuerier) DeleteGroupMembersByUserID(_ context.Context, _ uuid.UUID)
 	return nil
 }
 
+func (m *mockQuerier) DeleteGroupProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteInboundRoute(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteOutboxEntry(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
 func (m *mockQuerier) DeleteProvider(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
 
+func (m *mockQuerier) DeleteProviderHealthChecksBefore(_ context.Context, _ pgtype.Timestamptz) error {
+	return nil
+}
+
 func (m *mockQuerier) DeleteRoutingRule(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
 
+func (m *mockQuerier) DeleteSMTPDebugTarget(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
 func (m *mockQuerier) DeleteSession(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
@@ -142,6 +257,10 @@ func (m *mockQuerier) DeleteUser(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
 
+func (m *mockQuerier) DeliveryLatencyPercentiles(_ context.Context, _ pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) EnqueueMessage(ctx context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
 	if m.enqueueMessageFn != nil {
 		return m.enqueueMessageFn(ctx, arg)
@@ -164,6 +283,22 @@ func (m *mockQuerier) EnqueueMessageMetadata(ctx context.Context, arg storage.En
 	}, nil
 }
 
+func (m *mockQuerier) EraseGroupMessages(_ context.Context, _ pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ExportGroupActivityLogs(_ context.Context, _ uuid.UUID) ([]storage.ActivityLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ExportGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ExportGroupMessages(_ context.Context, _ pgtype.UUID) ([]storage.ExportGroupMessagesRow, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) GetActivityLogByID(_ context.Context, _ uuid.UUID) (storage.ActivityLog, error) {
 	return storage.ActivityLog{}, nil
 }
@@ -195,6 +330,17 @@ func (m *mockQuerier) GetGroupMemberByUserAndGroup(_ context.Context, _ storage.
 	return storage.GroupMember{}, nil
 }
 
+func (m *mockQuerier) GetInboundRouteByDomain(ctx context.Context, domain string) (storage.InboundRoute, error) {
+	if m.getInboundRouteByDomainFn != nil {
+		return m.getInboundRouteByDomainFn(ctx, domain)
+	}
+	return storage.InboundRoute{}, nil
+}
+
+func (m *mockQuerier) GetInboundRouteByID(_ context.Context, _ uuid.UUID) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+
 func (m *mockQuerier) GetMessageByID(_ context.Context, _ uuid.UUID) (storage.Message, error) {
 	return storage.Message{}, nil
 }
@@ -211,6 +357,10 @@ func (m *mockQuerier) GetRoutingRuleByID(_ context.Context, _ uuid.UUID) (storag
 	return storage.RoutingRule{}, nil
 }
 
+func (m *mockQuerier) GetSMTPDebugTarget(_ context.Context, _ uuid.UUID) (storage.SmtpDebugTarget, error) {
+	return storage.SmtpDebugTarget{}, nil
+}
+
 func (m *mockQuerier) GetSessionByID(_ context.Context, _ uuid.UUID) (storage.Session, error) {
 	return storage.Session{}, nil
 }
@@ -223,7 +373,10 @@ func (m *mockQuerier) GetUserByEmail(_ context.Context, _ string) (storage.User,
 	return storage.User{}, nil
 }
 
-func (m *mockQuerier) GetUserByID(_ context.Context, _ uuid.UUID) (storage.User, error) {
+func (m *mockQuerier) GetUserByID(ctx context.Context, id uuid.UUID) (storage.User, error) {
+	if m.getUserByIDFn != nil {
+		return m.getUserByIDFn(ctx, id)
+	}
 	return storage.User{}, nil
 }
 
@@ -246,6 +399,13 @@ func (m *mockQuerier) IncrementRetryCount(_ context.Context, _ storage.Increment
 	return nil
 }
 
+func (m *mockQuerier) ListActiveSMTPDebugTargets(ctx context.Context) ([]storage.SmtpDebugTarget, error) {
+	if m.listActiveSMTPDebugTargetsFn != nil {
+		return m.listActiveSMTPDebugTargetsFn(ctx)
+	}
+	return nil, nil
+}
+
 func (m *mockQuerier) ListActivityLogsByActorID(_ context.Context, _ storage.ListActivityLogsByActorIDParams) ([]storage.ActivityLog, error) {
 	return nil, nil
 }
@@ -266,10 +426,38 @@ func (m *mockQuerier) ListDeliveryLogsByMessageID(_ context.Context, _ uuid.UUID
 	return nil, nil
 }
 
+func (m *mockQuerier) ListEnabledProviders(_ context.Context) ([]storage.EspProvider, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupAncestors(_ context.Context, _ uuid.UUID) ([]storage.Group, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListGroupMembersByGroupID(_ context.Context, _ uuid.UUID) ([]storage.GroupMember, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) ListGroupMessageStorageRefs(_ context.Context, _ pgtype.UUID) ([]pgtype.Text, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryLogs(_ context.Context, _ storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMessages(_ context.Context, _ storage.ListGroupMessagesParams) ([]storage.Message, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMonthlyUsage(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListGroupMonthlyUsageRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupOwnerEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListGroups(_ context.Context) ([]storage.Group, error) {
 	return nil, nil
 }
@@ -281,26 +469,151 @@ func (m *mockQuerier) ListGroupsByUserID(ctx context.Context, userID uuid.UUID)
 	return nil, nil
 }
 
+func (m *mockQuerier) ListInboundRoutesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.InboundRoute, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListMessagesByGroupID(_ context.Context, _ storage.ListMessagesByGroupIDParams) ([]storage.Message, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) ListProviderAccountStatsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.ProviderAccountStat, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListProviderHealthChecks(_ context.Context, _ storage.ListProviderHealthChecksParams) ([]storage.ProviderHealthCheck, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListProvidersByGroupID(_ context.Context, _ uuid.UUID) ([]storage.EspProvider, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) ListProvidersForHealthCheck(_ context.Context) ([]storage.EspProvider, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListRoutingRulesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.RoutingRule, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) ListSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSMTPDebugTargetsByGroupID(_ context.Context, _ pgtype.UUID) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSMTPTranscriptsByTarget(_ context.Context, _ uuid.UUID) ([]storage.SmtpTranscript, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListSessionsByUserID(_ context.Context, _ uuid.UUID) ([]storage.Session, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) ListStuckMessages(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListSubGroups(_ context.Context, _ pgtype.UUID) ([]storage.Group, error) {
+	return nil, nil
+}
+
 func (m *mockQuerier) ListUsers(_ context.Context) ([]storage.User, error) {
 	return nil, nil
 }
 
+func (m *mockQuerier) MonthlyMessageUsage(_ context.Context, _ storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MonthlyProviderUsage(_ context.Context, _ storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RecordOutboxEntryFailure(_ context.Context, _ storage.RecordOutboxEntryFailureParams) error {
+	return nil
+}
+
+func (m *mockQuerier) RecordProviderAccountStatsError(_ context.Context, _ storage.RecordProviderAccountStatsErrorParams) error {
+	return nil
+}
+
+func (m *mockQuerier) RecordQuotaNotification(_ context.Context, _ storage.RecordQuotaNotificationParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSendWindow(ctx context.Context, groupID uuid.UUID) (storage.SendWindow, error) {
+	if m.getSendWindowFn != nil {
+		return m.getSendWindowFn(ctx, groupID)
+	}
+	return storage.SendWindow{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertSendWindow(_ context.Context, _ storage.UpsertSendWindowParams) (storage.SendWindow, error) {
+	return storage.SendWindow{}, nil
+}
+
+func (m *mockQuerier) DeleteSendWindow(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetMessageDefaults(_ context.Context, _ uuid.UUID) (storage.MessageDefault, error) {
+	return storage.MessageDefault{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) UpsertMessageDefaults(_ context.Context, _ storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error) {
+	return storage.MessageDefault{}, nil
+}
+
+func (m *mockQuerier) DeleteMessageDefaults(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ListProviderDailySendsByGroupID(_ context.Context, _ storage.ListProviderDailySendsByGroupIDParams) ([]storage.ProviderDailySend, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ReserveProviderDailySend(_ context.Context, _ storage.ReserveProviderDailySendParams) (int32, error) {
+	return 1, nil
+}
+
+func (m *mockQuerier) ListShadowPolicies(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	if m.listShadowPoliciesFn != nil {
+		return m.listShadowPoliciesFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) DeleteShadowPolicies(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) AddShadowPolicy(_ context.Context, _ storage.AddShadowPolicyParams) error {
+	return nil
+}
+
+func (m *mockQuerier) RecordShadowRejection(ctx context.Context, arg storage.RecordShadowRejectionParams) error {
+	if m.recordShadowRejectionFn != nil {
+		return m.recordShadowRejectionFn(ctx, arg)
+	}
+	return nil
+}
+
+func (m *mockQuerier) SummarizeShadowRejections(_ context.Context, _ storage.SummarizeShadowRejectionsParams) ([]storage.SummarizeShadowRejectionsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RequeueMessage(_ context.Context, _ storage.RequeueMessageParams) (int64, error) {
+	return 1, nil
+}
+
+func (m *mockQuerier) ExpireStuckMessage(_ context.Context, _ storage.ExpireStuckMessageParams) (int64, error) {
+	return 1, nil
+}
+
 func (m *mockQuerier) ResetFailedAttempts(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
@@ -309,6 +622,10 @@ func (m *mockQuerier) ResetMonthlySent(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
 
+func (m *mockQuerier) ScrubGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+
 func (m *mockQuerier) UpdateDeliveryLogStatus(_ context.Context, _ storage.UpdateDeliveryLogStatusParams) error {
 	return nil
 }
@@ -317,14 +634,26 @@ func (m *mockQuerier) UpdateGroup(_ context.Context, _ storage.UpdateGroupParams
 	return storage.Group{}, nil
 }
 
+func (m *mockQuerier) UpdateGroupHTMLProcessing(_ context.Context, _ storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
 func (m *mockQuerier) UpdateGroupMemberRole(_ context.Context, _ storage.UpdateGroupMemberRoleParams) (storage.GroupMember, error) {
 	return storage.GroupMember{}, nil
 }
 
+func (m *mockQuerier) UpdateGroupRecipientValidation(_ context.Context, _ storage.UpdateGroupRecipientValidationParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
 func (m *mockQuerier) UpdateGroupStatus(_ context.Context, _ storage.UpdateGroupStatusParams) (storage.Group, error) {
 	return storage.Group{}, nil
 }
 
+func (m *mockQuerier) UpdateInboundRoute(_ context.Context, _ storage.UpdateInboundRouteParams) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+
 func (m *mockQuerier) UpdateMessageStatus(ctx context.Context, arg storage.UpdateMessageStatusParams) error {
 	if m.updateMessageStatusFn != nil {
 		return m.updateMessageStatusFn(ctx, arg)
@@ -336,6 +665,14 @@ func (m *mockQuerier) UpdateProvider(_ context.Context, _ storage.UpdateProvider
 	return storage.EspProvider{}, nil
 }
 
+func (m *mockQuerier) UpdateProviderHealth(_ context.Context, _ storage.UpdateProviderHealthParams) error {
+	return nil
+}
+
+func (m *mockQuerier) UpdateProviderReputationPause(_ context.Context, _ storage.UpdateProviderReputationPauseParams) error {
+	return nil
+}
+
 func (m *mockQuerier) UpdateRoutingRule(_ context.Context, _ storage.UpdateRoutingRuleParams) (storage.RoutingRule, error) {
 	return storage.RoutingRule{}, nil
 }
@@ -356,60 +693,241 @@ func (m *mockQuerier) UpdateUserStatus(_ context.Context, _ storage.UpdateUserSt
 	return storage.User{}, nil
 }
 
-// newTestSession creates a Session with a mock backend for testing.
-func newTestSession(mock *mockQuerier) *Session {
-	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, nil, log, 100)
-	b.active.Add(1) // Simulate that the session was counted on creation.
-	return &Session{
-		ctx:     context.Background(),
-		queries: mock,
-		log:     log,
-		backend: b,
-	}
+func (m *mockQuerier) UpdateUserAutoBCC(_ context.Context, _ storage.UpdateUserAutoBCCParams) (storage.User, error) {
+	return storage.User{}, nil
 }
 
-// newAuthenticatedSession creates a session that has already been authenticated.
-func newAuthenticatedSession(mock *mockQuerier, userID, groupID uuid.UUID, allowedDomains []string) *Session {
-	s := newTestSession(mock)
-	s.userID = userID
-	s.groupID = groupID
-	s.authenticated = true
-	s.allowedDomains = allowedDomains
-	return s
+func (m *mockQuerier) UpdateUserTLSPolicy(_ context.Context, _ storage.UpdateUserTLSPolicyParams) (storage.User, error) {
+	return storage.User{}, nil
 }
 
-// hashTestPassword creates a bcrypt hash for testing.
-func hashTestPassword(t *testing.T, password string) string {
-	t.Helper()
-	hash, err := auth.HashPassword(password)
-	if err != nil {
-		t.Fatalf("failed to hash password: %v", err)
-	}
-	return hash
+func (m *mockQuerier) CreateMessageScript(_ context.Context, _ storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
 }
 
-// newMockWithAuth creates a mockQuerier pre-configured for a successful auth flow.
-func newMockWithAuth(userID, groupID uuid.UUID, passwordHash string, domainsJSON []byte) *mockQuerier {
-	return &mockQuerier{
-		getUserByUsernameFn: func(_ context.Context, username sql.NullString) (storage.User, error) {
-			if username.String == "testuser" {
-				return storage.User{
-					ID:             userID,
-					Username:       sql.NullString{String: "testuser", Valid: true},
-					PasswordHash:   passwordHash,
-					AccountType:    "smtp",
-					Status:         "active",
-					AllowedDomains: domainsJSON,
-				}, nil
-			}
-			return storage.User{}, errNotFound
-		},
-		listGroupsByUserIDFn: func(_ context.Context, _ uuid.UUID) ([]storage.Group, error) {
-			return []storage.Group{{ID: groupID, Name: "test-group", Status: "active"}}, nil
-		},
-		getGroupByIDFn: func(_ context.Context, id uuid.UUID) (storage.Group, error) {
-			if id == groupID {
+func (m *mockQuerier) GetMessageScript(_ context.Context, _ storage.GetMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) ListMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListEnabledMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateMessageScript(_ context.Context, _ storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
+}
+
+func (m *mockQuerier) DeleteMessageScript(_ context.Context, _ storage.DeleteMessageScriptParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateAlertChannel(_ context.Context, _ storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+	return storage.AlertChannel{}, nil
+}
+
+func (m *mockQuerier) ListAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListEnabledAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) DeleteAlertChannel(_ context.Context, _ storage.DeleteAlertChannelParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateSenderIdentity(_ context.Context, _ storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) GetSenderIdentity(_ context.Context, _ storage.GetSenderIdentityParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) ListSenderIdentitiesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.SenderIdentity, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListVerifiedSenderIdentities(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkSenderIdentityVerified(_ context.Context, _ storage.MarkSenderIdentityVerifiedParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) DeleteSenderIdentity(_ context.Context, _ storage.DeleteSenderIdentityParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSenderPolicy(_ context.Context, _ uuid.UUID) (storage.SenderPolicy, error) {
+	return storage.SenderPolicy{}, nil
+}
+
+func (m *mockQuerier) UpsertSenderPolicy(_ context.Context, _ storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error) {
+	return storage.SenderPolicy{}, nil
+}
+
+func (m *mockQuerier) GetSendingDomain(_ context.Context, _ storage.GetSendingDomainParams) (storage.SendingDomain, error) {
+	return storage.SendingDomain{}, nil
+}
+
+func (m *mockQuerier) ListSendingDomainsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.SendingDomain, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertSendingDomain(_ context.Context, _ storage.UpsertSendingDomainParams) (storage.SendingDomain, error) {
+	return storage.SendingDomain{}, nil
+}
+
+func (m *mockQuerier) DeleteSendingDomain(_ context.Context, _ storage.DeleteSendingDomainParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSigningKey(_ context.Context, _ uuid.UUID) (storage.SigningKey, error) {
+	return storage.SigningKey{}, nil
+}
+
+func (m *mockQuerier) UpsertSigningKey(_ context.Context, _ storage.UpsertSigningKeyParams) (storage.SigningKey, error) {
+	return storage.SigningKey{}, nil
+}
+
+func (m *mockQuerier) DeleteSigningKey(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ListRecipientCertificatesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.RecipientCertificate, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListRecipientDeliveryLogs(_ context.Context, _ storage.ListRecipientDeliveryLogsParams) ([]storage.ListRecipientDeliveryLogsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) AddSuppression(_ context.Context, _ storage.AddSuppressionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSuppression(_ context.Context, _ storage.DeleteSuppressionParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) FindMessageByHeaderMessageID(_ context.Context, _ storage.FindMessageByHeaderMessageIDParams) (storage.Message, error) {
+	return storage.Message{}, pgx.ErrNoRows
+}
+
+func (m *mockQuerier) IsRecipientSuppressed(ctx context.Context, arg storage.IsRecipientSuppressedParams) (bool, error) {
+	if m.isRecipientSuppressedFn != nil {
+		return m.isRecipientSuppressedFn(ctx, arg)
+	}
+	return false, nil
+}
+
+func (m *mockQuerier) ListSuppressions(_ context.Context, _ storage.ListSuppressionsParams) ([]storage.Suppression, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertRecipientCertificate(_ context.Context, _ storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error) {
+	return storage.RecipientCertificate{}, nil
+}
+
+func (m *mockQuerier) DeleteRecipientCertificate(_ context.Context, _ storage.DeleteRecipientCertificateParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateSmtpClientCert(_ context.Context, _ storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) ListSmtpClientCertsByUserID(_ context.Context, _ uuid.UUID) ([]storage.SmtpClientCert, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertByFingerprint(ctx context.Context, fingerprint pgtype.Text) (storage.SmtpClientCert, error) {
+	if m.getSmtpClientCertByFingerprintFn != nil {
+		return m.getSmtpClientCertByFingerprintFn(ctx, fingerprint)
+	}
+	return storage.SmtpClientCert{}, errNotFound
+}
+
+func (m *mockQuerier) GetSmtpClientCertBySAN(ctx context.Context, sans []string) (storage.SmtpClientCert, error) {
+	if m.getSmtpClientCertBySANFn != nil {
+		return m.getSmtpClientCertBySANFn(ctx, sans)
+	}
+	return storage.SmtpClientCert{}, errNotFound
+}
+
+func (m *mockQuerier) TouchSmtpClientCert(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSmtpClientCert(_ context.Context, _ storage.DeleteSmtpClientCertParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) UpsertProviderAccountStats(_ context.Context, _ storage.UpsertProviderAccountStatsParams) (storage.ProviderAccountStat, error) {
+	return storage.ProviderAccountStat{}, nil
+}
+
+// newTestSession creates a Session with a mock backend for testing.
+func newTestSession(mock *mockQuerier) *Session {
+	log := zerolog.Nop()
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, log, 100)
+	b.active.Add(1) // Simulate that the session was counted on creation.
+	return &Session{
+		ctx:     context.Background(),
+		queries: mock,
+		log:     log,
+		backend: b,
+	}
+}
+
+// newAuthenticatedSession creates a session that has already been authenticated.
+func newAuthenticatedSession(mock *mockQuerier, userID, groupID uuid.UUID, allowedDomains []string) *Session {
+	s := newTestSession(mock)
+	s.userID = userID
+	s.groupID = groupID
+	s.authenticated = true
+	s.allowedDomains = allowedDomains
+	return s
+}
+
+// hashTestPassword creates a bcrypt hash for testing.
+func hashTestPassword(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return hash
+}
+
+// newMockWithAuth creates a mockQuerier pre-configured for a successful auth flow.
+func newMockWithAuth(userID, groupID uuid.UUID, passwordHash string, domainsJSON []byte) *mockQuerier {
+	return &mockQuerier{
+		getUserByUsernameFn: func(_ context.Context, username sql.NullString) (storage.User, error) {
+			if username.String == "testuser" {
+				return storage.User{
+					ID:             userID,
+					Username:       sql.NullString{String: "testuser", Valid: true},
+					PasswordHash:   passwordHash,
+					AccountType:    "smtp",
+					Status:         "active",
+					AllowedDomains: domainsJSON,
+				}, nil
+			}
+			return storage.User{}, errNotFound
+		},
+		listGroupsByUserIDFn: func(_ context.Context, _ uuid.UUID) ([]storage.Group, error) {
+			return []storage.Group{{ID: groupID, Name: "test-group", Status: "active"}}, nil
+		},
+		getGroupByIDFn: func(_ context.Context, id uuid.UUID) (storage.Group, error) {
+			if id == groupID {
 				return storage.Group{ID: groupID, Name: "test-group", Status: "active"}, nil
 			}
 			return storage.Group{}, errNotFound
@@ -497,6 +1015,100 @@ func TestSession_Auth_InvalidPassword(t *testing.T) {
 	}
 }
 
+func TestSession_Auth_ExpiredPassword(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	passwordHash := hashTestPassword(t, "correct-password")
+
+	mock := newMockWithAuth(userID, groupID, passwordHash, nil)
+	lookup := mock.getUserByUsernameFn
+	mock.getUserByUsernameFn = func(ctx context.Context, username sql.NullString) (storage.User, error) {
+		user, err := lookup(ctx, username)
+		user.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now().Add(-100 * 24 * time.Hour), Valid: true}
+		return user, err
+	}
+	s := newTestSession(mock)
+	s.backend.SetPasswordMaxAge(90 * 24 * time.Hour)
+
+	err := authenticateSession(t, s, "testuser", "correct-password")
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 535 {
+		t.Fatalf("expected 535 for an expired password, got %v", err)
+	}
+	if s.authenticated {
+		t.Error("session should not be authenticated")
+	}
+
+	s = newTestSession(mock)
+	if err := authenticateSession(t, s, "testuser", "correct-password"); err != nil {
+		t.Errorf("expected no error without a max age, got %v", err)
+	}
+}
+
+func TestSession_Auth_ScopedToken(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	passwordHash := hashTestPassword(t, "correct-password")
+	jwtSvc := auth.NewJWTService(auth.JWTConfig{
+		SigningKey:        "test-secret-key-at-least-32-chars!",
+		AccessTokenExpiry: 15 * time.Minute,
+		ScopedTokenMaxTTL: time.Hour,
+	})
+	sendToken, _, err := jwtSvc.GenerateScopedToken(userID, groupID, "", "member", "company", nil, []string{auth.ScopeSend}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readToken, _, _ := jwtSvc.GenerateScopedToken(userID, groupID, "", "member", "company", nil, []string{auth.ScopeRead}, time.Hour)
+	otherToken, _, _ := jwtSvc.GenerateScopedToken(uuid.New(), groupID, "", "member", "company", nil, []string{auth.ScopeSend}, time.Hour)
+	accessToken, _ := jwtSvc.GenerateAccessToken(userID, groupID, "", "member", "company", nil)
+
+	tests := []struct {
+		name     string
+		password string
+		wantOK   bool
+	}{
+		{"send scope", sendToken, true},
+		{"read scope", readToken, false},
+		{"another user's token", otherToken, false},
+		{"unscoped access token", accessToken, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockWithAuth(userID, groupID, passwordHash, nil)
+			s := newTestSession(mock)
+			s.backend.SetTokenValidator(jwtSvc)
+
+			err := authenticateSession(t, s, "testuser", tt.password)
+			if (err == nil) != tt.wantOK || s.authenticated != tt.wantOK {
+				t.Errorf("err = %v, authenticated = %v, want ok %v", err, s.authenticated, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSession_Auth_ScopedToken_WeakSigningKey(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	passwordHash := hashTestPassword(t, "correct-password")
+
+	for _, key := range []string{"", auth.PlaceholderSigningKey} {
+		// Anyone who knows the user's ID can sign this token.
+		jwtSvc := auth.NewJWTService(auth.JWTConfig{SigningKey: key, ScopedTokenMaxTTL: time.Hour})
+		forged, _, err := jwtSvc.GenerateScopedToken(userID, groupID, "", "member", "company", nil, []string{auth.ScopeSend}, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mock := newMockWithAuth(userID, groupID, passwordHash, nil)
+		s := newTestSession(mock)
+		s.backend.SetTokenValidator(jwtSvc)
+
+		if err := authenticateSession(t, s, "testuser", forged); err == nil || s.authenticated {
+			t.Errorf("signing key %q: token accepted", key)
+		}
+	}
+}
+
 func TestSession_Auth_UnknownUser(t *testing.T) {
 	mock := &mockQuerier{
 		getUserByUsernameFn: func(_ context.Context, _ sql.NullString) (storage.User, error) {
@@ -662,6 +1274,46 @@ func TestSession_Auth_SuspendedGroup(t *testing.T) {
 	}
 }
 
+func TestSession_Auth_TLSPolicyRejectsPlaintext(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	passwordHash := hashTestPassword(t, "correct-password")
+
+	mock := newMockWithAuth(userID, groupID, passwordHash, nil)
+	getUser := mock.getUserByUsernameFn
+	mock.getUserByUsernameFn = func(ctx context.Context, username sql.NullString) (storage.User, error) {
+		u, err := getUser(ctx, username)
+		u.TlsPolicy = []byte(`{"require_tls":true,"min_version":"1.2"}`)
+		return u, err
+	}
+
+	// The test session has no connection, i.e. it is unencrypted.
+	s := newTestSession(mock)
+	err := authenticateSession(t, s, "testuser", "correct-password")
+
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) {
+		t.Fatalf("expected SMTPError, got %v", err)
+	}
+	if smtpErr.Code != 538 {
+		t.Errorf("expected code 538, got %d", smtpErr.Code)
+	}
+	if s.authenticated {
+		t.Error("session should not be authenticated")
+	}
+}
+
+func TestSession_Mail_TLSPolicyRejectsPlaintext(t *testing.T) {
+	s := newAuthenticatedSession(&mockQuerier{}, uuid.New(), uuid.New(), nil)
+	s.tlsPolicy = tlsutil.Policy{RequireTLS: true}
+
+	err := s.Mail("sender@example.com", nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 530 {
+		t.Fatalf("expected 530, got %v", err)
+	}
+}
+
 // --- Mail Tests ---
 
 func TestSession_Mail_ValidSender(t *testing.T) {
@@ -708,6 +1360,35 @@ func TestSession_Mail_UnauthorizedDomain(t *testing.T) {
 	}
 }
 
+func TestSession_Mail_UnauthorizedDomain_Shadowed(t *testing.T) {
+	groupID := uuid.New()
+	var recorded []storage.RecordShadowRejectionParams
+	mock := &mockQuerier{
+		listShadowPoliciesFn: func(_ context.Context, id uuid.UUID) ([]string, error) {
+			if id != groupID {
+				t.Errorf("listed shadow policies of group %s, want %s", id, groupID)
+			}
+			return []string{shadow.PolicyAllowedDomains}, nil
+		},
+		recordShadowRejectionFn: func(_ context.Context, arg storage.RecordShadowRejectionParams) error {
+			recorded = append(recorded, arg)
+			return nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), groupID, []string{"allowed.com"})
+	s.shadow = shadow.NewGroup(mock, groupID)
+
+	if err := s.Mail("sender@forbidden.com", nil); err != nil {
+		t.Fatalf("expected the sender to be accepted in shadow mode, got %v", err)
+	}
+	if s.sender != "sender@forbidden.com" {
+		t.Errorf("expected sender=sender@forbidden.com, got %s", s.sender)
+	}
+	if len(recorded) != 1 || recorded[0].Policy != shadow.PolicyAllowedDomains || !strings.Contains(recorded[0].Reason, "forbidden.com") {
+		t.Errorf("recorded shadow rejections = %+v", recorded)
+	}
+}
+
 func TestSession_Mail_Unauthenticated(t *testing.T) {
 	s := newTestSession(&mockQuerier{})
 
@@ -871,6 +1552,9 @@ func TestSession_Data_EnqueuesMessage(t *testing.T) {
 	if capturedParams.Body.String != messageContent || !capturedParams.Body.Valid {
 		t.Errorf("expected body to match message content")
 	}
+	if capturedParams.SizeBytes != int64(len(messageContent)) {
+		t.Errorf("expected SizeBytes=%d, got %d", len(messageContent), capturedParams.SizeBytes)
+	}
 }
 
 func TestSession_Data_NoRecipients(t *testing.T) {
@@ -938,6 +1622,27 @@ func TestSession_Data_EnqueueError(t *testing.T) {
 	}
 }
 
+func TestSession_Data_RecoversPanic(t *testing.T) {
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, _ storage.EnqueueMessageParams) (storage.Message, error) {
+			panic("unexpected nil pointer in enqueue")
+		},
+	}
+	budget := NewMemoryBudget(1<<20, t.TempDir())
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.backend.SetMemoryBudget(budget)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	err := s.Data(strings.NewReader("Subject: Test\r\n\r\nBody"))
+	if replyCode(err) != 451 {
+		t.Fatalf("Data() = %v, want 451 after a panic", err)
+	}
+	if used := budget.used.Load(); used != 0 {
+		t.Errorf("budget still holds %d bytes after the panic", used)
+	}
+}
+
 func TestSession_Data_NoSubjectHeader(t *testing.T) {
 	userID := uuid.New()
 	groupID := uuid.New()
@@ -965,6 +1670,36 @@ func TestSession_Data_NoSubjectHeader(t *testing.T) {
 	}
 }
 
+func TestSession_Data_CapturesTagsAndMetadata(t *testing.T) {
+	var capturedParams storage.EnqueueMessageParams
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			capturedParams = arg
+			return storage.Message{ID: uuid.New(), Status: storage.MessageStatusQueued}, nil
+		},
+	}
+
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	data := "From: sender@example.com\r\n" +
+		"Subject: Invoice\r\n" +
+		"X-SMTPProxy-Tag: billing, invoice\r\n" +
+		"X-SMTPProxy-Metadata: customer_id=42\r\n" +
+		"\r\nbody"
+	if err := s.Data(strings.NewReader(data)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := string(capturedParams.Tags); got != `["billing","invoice"]` {
+		t.Errorf("tags = %s", got)
+	}
+	if got := string(capturedParams.Metadata); got != `{"customer_id":"42"}` {
+		t.Errorf("metadata = %s", got)
+	}
+}
+
 // --- Reset Test ---
 
 func TestSession_Reset(t *testing.T) {
@@ -999,7 +1734,7 @@ func TestSession_Reset(t *testing.T) {
 func TestSession_Logout_DecrementsCounter(t *testing.T) {
 	mock := &mockQuerier{}
 	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, nil, log, 100)
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, nil, log, 100)
 	b.active.Add(3) // Simulate 3 active sessions.
 
 	s := &Session{
@@ -1065,7 +1800,7 @@ func TestSession_Data_WithMessageStore(t *testing.T) {
 	mockStore := &mockMessageStore{
 		putFn: func(_ context.Context, _ string, data []byte) error {
 			putCalled = true
-			capturedPutData = data
+			capturedPutData = append([]byte(nil), data...)
 			return nil
 		},
 	}
@@ -1082,7 +1817,7 @@ func TestSession_Data_WithMessageStore(t *testing.T) {
 	}
 
 	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, mockStore, log, 100)
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, mockStore, log, 100)
 	b.active.Add(1)
 	s := &Session{
 		ctx:           context.Background(),
@@ -1127,6 +1862,9 @@ func TestSession_Data_WithMessageStore(t *testing.T) {
 	if capturedMetadataParams.StorageRef.String == "" {
 		t.Error("expected StorageRef to be non-empty")
 	}
+	if capturedMetadataParams.SizeBytes != int64(len(messageContent)) {
+		t.Errorf("expected SizeBytes=%d, got %d", len(messageContent), capturedMetadataParams.SizeBytes)
+	}
 }
 
 func TestSession_Data_MessageStoreWriteFails_FallsBack(t *testing.T) {
@@ -1154,7 +1892,7 @@ func TestSession_Data_MessageStoreWriteFails_FallsBack(t *testing.T) {
 	}
 
 	log := zerolog.Nop()
-	b := NewBackend(mock, &mockDeliveryService{}, mockStore, log, 100)
+	b := NewBackend(mock, &mockTxRunner{queries: mock}, mockStore, log, 100)
 	b.active.Add(1)
 	s := &Session{
 		ctx:           context.Background(),
@@ -1182,98 +1920,62 @@ func TestSession_Data_MessageStoreWriteFails_FallsBack(t *testing.T) {
 	}
 }
 
-// --- Enqueue Retry Tests ---
-
-func TestSession_Data_EnqueueRetrySucceeds(t *testing.T) {
-	origBackoff := enqueueRetryBackoff
-	enqueueRetryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
-	defer func() { enqueueRetryBackoff = origBackoff }()
+// --- Outbox Tests ---
 
+func TestSession_Data_WritesOutboxEntry(t *testing.T) {
 	userID := uuid.New()
 	groupID := uuid.New()
+	messageID := uuid.New()
+	var captured storage.CreateOutboxEntryParams
+
 	mock := &mockQuerier{
 		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
-			return storage.Message{ID: uuid.New(), UserID: arg.UserID, Status: storage.MessageStatusQueued}, nil
+			return storage.Message{ID: messageID, UserID: arg.UserID, Status: storage.MessageStatusQueued}, nil
 		},
-	}
-
-	callCount := 0
-	deliverySvc := &mockDeliveryService{
-		deliverFn: func(_ context.Context, _ *delivery.Request) error {
-			callCount++
-			if callCount == 1 {
-				return errors.New("redis connection refused")
-			}
-			return nil
+		createOutboxEntryFn: func(_ context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+			captured = arg
+			return storage.OutboxEntry{ID: uuid.New(), MessageID: arg.MessageID}, nil
 		},
 	}
 
-	log := zerolog.Nop()
-	b := NewBackend(mock, deliverySvc, nil, log, 100)
-	b.active.Add(1)
-	s := &Session{
-		ctx:           context.Background(),
-		queries:       mock,
-		log:           log,
-		backend:       b,
-		userID:        userID,
-		groupID:       groupID,
-		authenticated: true,
-		sender:        "sender@example.com",
-		recipients:    []string{"recipient@example.com"},
+	s := newAuthenticatedSession(mock, userID, groupID, nil)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	if err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello"))
-	if err != nil {
-		t.Fatalf("expected no error after retry success, got %v", err)
+	if captured.MessageID != messageID {
+		t.Errorf("expected outbox MessageID=%s, got %s", messageID, captured.MessageID)
+	}
+	if captured.GroupID != groupID {
+		t.Errorf("expected outbox GroupID=%s, got %s", groupID, captured.GroupID)
 	}
-	if callCount != 2 {
-		t.Errorf("expected 2 delivery attempts, got %d", callCount)
+	if captured.UserID != userID {
+		t.Errorf("expected outbox UserID=%s, got %s", userID, captured.UserID)
 	}
 }
 
-func TestSession_Data_EnqueueRetryExhausted(t *testing.T) {
-	origBackoff := enqueueRetryBackoff
-	enqueueRetryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
-	defer func() { enqueueRetryBackoff = origBackoff }()
-
+func TestSession_Data_OutboxInsertFails(t *testing.T) {
 	userID := uuid.New()
 	groupID := uuid.New()
-	var capturedStatus storage.MessageStatus
 	mock := &mockQuerier{
 		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
 			return storage.Message{ID: uuid.New(), UserID: arg.UserID, Status: storage.MessageStatusQueued}, nil
 		},
-		updateMessageStatusFn: func(_ context.Context, arg storage.UpdateMessageStatusParams) error {
-			capturedStatus = arg.Status
-			return nil
-		},
-	}
-
-	deliverySvc := &mockDeliveryService{
-		deliverFn: func(_ context.Context, _ *delivery.Request) error {
-			return errors.New("redis connection refused")
+		createOutboxEntryFn: func(_ context.Context, _ storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+			return storage.OutboxEntry{}, errors.New("database error")
 		},
 	}
 
-	log := zerolog.Nop()
-	b := NewBackend(mock, deliverySvc, nil, log, 100)
-	b.active.Add(1)
-	s := &Session{
-		ctx:           context.Background(),
-		queries:       mock,
-		log:           log,
-		backend:       b,
-		userID:        userID,
-		groupID:       groupID,
-		authenticated: true,
-		sender:        "sender@example.com",
-		recipients:    []string{"recipient@example.com"},
-	}
+	s := newAuthenticatedSession(mock, userID, groupID, nil)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
 
 	err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello"))
 	if err == nil {
-		t.Fatal("expected SMTP error after retry exhaustion")
+		t.Fatal("expected error when outbox insert fails")
 	}
 
 	var smtpErr *gosmtp.SMTPError
@@ -1283,8 +1985,404 @@ func TestSession_Data_EnqueueRetryExhausted(t *testing.T) {
 	if smtpErr.Code != 451 {
 		t.Errorf("expected code 451, got %d", smtpErr.Code)
 	}
+}
+
+func TestSession_Data_TransactionBeginFails(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	enqueueCalled := false
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, _ storage.EnqueueMessageParams) (storage.Message, error) {
+			enqueueCalled = true
+			return storage.Message{ID: uuid.New()}, nil
+		},
+	}
+
+	s := newAuthenticatedSession(mock, userID, groupID, nil)
+	s.backend.tx = &mockTxRunner{queries: mock, beginErr: errors.New("connection refused")}
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	err := s.Data(strings.NewReader("Subject: Test\r\n\r\nHello"))
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 451 {
+		t.Fatalf("expected 451 SMTPError, got %v", err)
+	}
+	if enqueueCalled {
+		t.Error("expected no message insert when the transaction cannot start")
+	}
+}
+
+// --- Benchmarks ---
+
+// benchMessageSizes are the body sizes exercised by the DATA benchmarks.
+var benchMessageSizes = []struct {
+	name string
+	size int
+}{
+	{"2KB", 2 << 10},
+	{"64KB", 64 << 10},
+	{"1MB", 1 << 20},
+}
+
+// benchMessage returns a message with a text body of size bytes.
+func benchMessage(size int) string {
+	const line = "The quick brown fox jumps over the lazy dog. Pack my box with five dozen.\r\n"
+	var b strings.Builder
+	b.Grow(size + 256)
+	b.WriteString("From: sender@example.com\r\nTo: recipient@example.com\r\nSubject: Benchmark\r\n")
+	b.WriteString("X-SMTPProxy-Tag: bench\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	for b.Len() < size {
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// BenchmarkSession_Data measures accepting a message through DATA: reading
+// the body, parsing headers, storing the body and persisting the message
+// and outbox entry. Run with -benchmem to track body copies.
+func BenchmarkSession_Data(b *testing.B) {
+	for _, tc := range benchMessageSizes {
+		b.Run(tc.name, func(b *testing.B) {
+			mock := &mockQuerier{
+				enqueueMessageMetadataFn: func(_ context.Context, _ storage.EnqueueMessageMetadataParams) (storage.Message, error) {
+					return storage.Message{ID: uuid.New(), Status: storage.MessageStatusQueued}, nil
+				},
+			}
+			s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+			s.backend.store = &mockMessageStore{}
+			data := benchMessage(tc.size)
+
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.sender = "sender@example.com"
+				s.recipients = []string{"recipient@example.com"}
+				if err := s.Data(strings.NewReader(data)); err != nil {
+					b.Fatalf("Data: %v", err)
+				}
+				s.Reset()
+			}
+		})
+	}
+}
+
+// dataBytesBudget caps the bytes allocated per accepted message. Bodies are
+// read into pooled buffers, so the cost is per-message bookkeeping and does
+// not grow with the body; any copy of a 1 MB body fails the budget.
+const dataBytesBudget = 64 << 10
+
+func TestSession_Data_AllocationBudget(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("skipping allocation budget in short mode or under the race detector")
+	}
+	const size = 1 << 20
+	res := testing.Benchmark(func(b *testing.B) {
+		mock := &mockQuerier{}
+		s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+		s.backend.store = &mockMessageStore{}
+		data := benchMessage(size)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.sender = "sender@example.com"
+			s.recipients = []string{"recipient@example.com"}
+			if err := s.Data(strings.NewReader(data)); err != nil {
+				b.Fatalf("Data: %v", err)
+			}
+			s.Reset()
+		}
+	})
+	if got := res.AllocedBytesPerOp(); got > dataBytesBudget {
+		t.Errorf("DATA allocated %d bytes for a %d byte message, budget is %d", got, size, dataBytesBudget)
+	}
+}
+
+// fakeDeduper reports every message after the first as a duplicate of it.
+type fakeDeduper struct {
+	action string
+	first  string
+}
+
+func (f *fakeDeduper) Check(_ context.Context, _, messageID, _ string, _ []string, _ []byte) (dedup.Result, error) {
+	if f.first == "" {
+		f.first = messageID
+		return dedup.Result{Key: "k"}, nil
+	}
+	return dedup.Result{Duplicate: true, OriginalID: f.first, Key: "k"}, nil
+}
+
+func (f *fakeDeduper) Confirm(_ context.Context, _, messageID string) error {
+	f.first = messageID
+	return nil
+}
+
+func (f *fakeDeduper) Forget(_ context.Context, _ string) error {
+	f.first = ""
+	return nil
+}
+
+func (f *fakeDeduper) Action() string { return f.action }
+
+func TestSession_Data_Duplicate(t *testing.T) {
+	tests := []struct {
+		action      string
+		wantQueued  int
+		wantLastTag string
+	}{
+		{action: dedup.ActionDrop, wantQueued: 1},
+		{action: dedup.ActionTag, wantQueued: 2, wantLastTag: `["duplicate"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			var queued []storage.EnqueueMessageParams
+			mock := &mockQuerier{
+				enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+					queued = append(queued, arg)
+					return storage.Message{ID: uuid.New(), Status: storage.MessageStatusQueued}, nil
+				},
+			}
+			s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+			s.backend.dedup = &fakeDeduper{action: tt.action}
+
+			var ids []uuid.UUID
+			for i := 0; i < 2; i++ {
+				s.sender = "app@example.com"
+				s.recipients = []string{"user@example.com"}
+				if err := s.Data(strings.NewReader("Subject: Receipt\r\n\r\nbody")); err != nil {
+					t.Fatalf("Data: %v", err)
+				}
+				ids = append(ids, s.queuedID)
+				s.Reset()
+			}
+
+			if len(queued) != tt.wantQueued {
+				t.Fatalf("queued %d messages, want %d", len(queued), tt.wantQueued)
+			}
+			if tt.action == dedup.ActionDrop && ids[1] != ids[0] {
+				t.Errorf("expected the dropped duplicate to report the first message ID %s, got %s", ids[0], ids[1])
+			}
+			if tt.wantLastTag != "" && string(queued[1].Tags) != tt.wantLastTag {
+				t.Errorf("duplicate tags = %s, want %s", queued[1].Tags, tt.wantLastTag)
+			}
+		})
+	}
+}
+
+func (m *mockQuerier) CreateProviderCapture(_ context.Context, _ storage.CreateProviderCaptureParams) (storage.ProviderCapture, error) {
+	return storage.ProviderCapture{}, nil
+}
+
+func (m *mockQuerier) ListProviderCaptures(_ context.Context, _ uuid.UUID) ([]storage.ProviderCapture, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) PruneProviderCaptures(_ context.Context, _ storage.PruneProviderCapturesParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDeliveryLogArchive(_ context.Context, _ storage.CreateDeliveryLogArchiveParams) (storage.DeliveryLogArchive, error) {
+	return storage.DeliveryLogArchive{}, nil
+}
+
+func (m *mockQuerier) DeleteArchivedDeliveryLogs(_ context.Context, _ []uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryLogArchive(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsToArchive(_ context.Context, _ storage.ListDeliveryLogsToArchiveParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryLogArchives(_ context.Context, _ storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetAnalyticsExportCursor(_ context.Context, _ string) (storage.AnalyticsExportCursor, error) {
+	return storage.AnalyticsExportCursor{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsForExport(_ context.Context, _ storage.ListDeliveryLogsForExportParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertAnalyticsExportCursor(_ context.Context, _ storage.UpsertAnalyticsExportCursorParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeliveryTimeSeries(_ context.Context, _ storage.DeliveryTimeSeriesParams) ([]storage.DeliveryTimeSeriesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListMessagesSentSince(_ context.Context, _ storage.ListMessagesSentSinceParams) ([]storage.ListMessagesSentSinceRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) PauseQueuedMessages(_ context.Context, _ storage.PauseQueuedMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ResumePausedMessages(_ context.Context, _ storage.ResumePausedMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) RevokeUserAPIKey(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteUserSession(_ context.Context, _ storage.DeleteUserSessionParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) TouchSession(_ context.Context, _ storage.TouchSessionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) ListExpiringSMTPPasswords(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListExpiringSMTPPasswordsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkPasswordExpiryWarned(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListLoginNetworks(_ context.Context, _ uuid.UUID) ([]storage.LoginNetwork, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RecordLoginNetwork(_ context.Context, _ storage.RecordLoginNetworkParams) error {
+	return nil
+}
+
+func (m *mockQuerier) AcceptInvitation(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateInvitation(_ context.Context, _ storage.CreateInvitationParams) (storage.Invitation, error) {
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) DeleteInvitation(_ context.Context, _ storage.DeleteInvitationParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetInvitationByID(_ context.Context, _ uuid.UUID) (storage.Invitation, error) {
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) ListPendingInvitationsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.Invitation, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) CreateSignup(_ context.Context, _ storage.CreateSignupParams) (storage.Signup, error) {
+	return storage.Signup{}, nil
+}
+
+func (m *mockQuerier) DeleteExpiredSignups(_ context.Context) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSignup(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSignupByID(_ context.Context, _ uuid.UUID) (storage.Signup, error) {
+	return storage.Signup{}, nil
+}
+
+func (m *mockQuerier) ListVerifiedGroupMemberEmails(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	if m.listVerifiedGroupMemberEmailsFn != nil {
+		return m.listVerifiedGroupMemberEmailsFn(ctx, groupID)
+	}
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateGroupSandbox(_ context.Context, _ storage.UpdateGroupSandboxParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) MarkEmailVerified(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteGroupBranding(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetGroupBranding(_ context.Context, _ uuid.UUID) (storage.GroupBranding, error) {
+	return storage.GroupBranding{}, nil
+}
+
+func (m *mockQuerier) UpsertGroupBranding(_ context.Context, _ storage.UpsertGroupBrandingParams) (storage.GroupBranding, error) {
+	return storage.GroupBranding{}, nil
+}
 
-	if capturedStatus != storage.MessageStatusEnqueueFailed {
-		t.Errorf("expected status enqueue_failed, got %s", capturedStatus)
+func (m *mockQuerier) UpdateGroupLocale(_ context.Context, _ storage.UpdateGroupLocaleParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) CountResendableMessages(_ context.Context, _ storage.CountResendableMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDelayedOutboxEntry(ctx context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+	if m.createDelayedOutboxEntryFn != nil {
+		return m.createDelayedOutboxEntryFn(ctx, arg)
 	}
+	return storage.OutboxEntry{}, nil
+}
+
+func (m *mockQuerier) ListResendableMessages(_ context.Context, _ storage.ListResendableMessagesParams) ([]storage.ListResendableMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ResendMessage(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ClaimDeliveryReport(_ context.Context, _ int32) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) CompleteDeliveryReport(_ context.Context, _ storage.CompleteDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) CreateDeliveryReport(_ context.Context, _ storage.CreateDeliveryReportParams) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryReport(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) FailDeliveryReport(_ context.Context, _ storage.FailDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) GetDeliveryReport(_ context.Context, _ uuid.UUID) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsByMessageIDs(_ context.Context, _ []uuid.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListDeliveryReportMessages(_ context.Context, _ storage.ListDeliveryReportMessagesParams) ([]storage.ListDeliveryReportMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListExpiredDeliveryReports(_ context.Context, _ storage.ListExpiredDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryReports(_ context.Context, _ storage.ListGroupDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
 }
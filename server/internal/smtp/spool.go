@@ -0,0 +1,185 @@
+package smtp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+)
+
+// errMemoryBudget is returned by readBody when the memory budget is
+// exhausted and the body cannot be spilled to disk.
+var errMemoryBudget = errors.New("message body memory budget exhausted")
+
+// MemoryBudget bounds the bytes of message bodies held in memory by the
+// sessions of every listener of a process. Bodies that do not fit are
+// spilled to temporary files.
+type MemoryBudget struct {
+	limit int64
+	dir   string
+	used  atomic.Int64
+}
+
+// NewMemoryBudget creates a MemoryBudget of maxBytes whose spilled bodies
+// are written to dir, or to the system temporary directory when dir is
+// empty.
+func NewMemoryBudget(maxBytes int64, dir string) *MemoryBudget {
+	return &MemoryBudget{limit: maxBytes, dir: dir}
+}
+
+// reserve claims n bytes of the budget and reports whether they fit.
+func (m *MemoryBudget) reserve(n int64) bool {
+	if m.used.Add(n) > m.limit {
+		m.used.Add(-n)
+		return false
+	}
+	metrics.SMTPBodyMemoryBytes.Add(float64(n))
+	return true
+}
+
+// release returns n reserved bytes to the budget.
+func (m *MemoryBudget) release(n int64) {
+	m.used.Add(-n)
+	metrics.SMTPBodyMemoryBytes.Sub(float64(n))
+}
+
+// spooledBody is a message body read from DATA, held in a pooled buffer or,
+// once the memory budget is exhausted, in a temporary file. It must be
+// closed to release its memory or remove its file.
+type spooledBody struct {
+	buf      *bytes.Buffer
+	file     *os.File
+	size     int64
+	budget   *MemoryBudget
+	reserved int64
+	canSpill bool
+}
+
+// spoolChunkSize is how much of DATA is read at a time.
+const spoolChunkSize = 32 << 10
+
+// readBody reads r into a spooledBody. Without a memory budget the body is
+// always kept in memory. With one, it is spilled to disk when the budget
+// is exhausted and the message store can stream it; otherwise readBody
+// fails with errMemoryBudget.
+func (b *Backend) readBody(r io.Reader) (*spooledBody, error) {
+	_, canSpill := b.store.(msgstore.StreamPutter)
+	body := &spooledBody{
+		buf:      getBodyBuffer(),
+		budget:   b.memory,
+		canSpill: canSpill,
+	}
+	if err := body.readFrom(r); err != nil {
+		body.close()
+		return nil, err
+	}
+	return body, nil
+}
+
+// readFrom reads r into the pooled buffer's spare capacity, reserving the
+// budget for each chunk read, and into a temporary file once the budget is
+// exhausted.
+func (s *spooledBody) readFrom(r io.Reader) error {
+	for {
+		if s.file != nil {
+			n, err := io.Copy(s.file, r)
+			s.size += n
+			return err
+		}
+		s.buf.Grow(spoolChunkSize)
+		chunk := s.buf.AvailableBuffer()[:spoolChunkSize]
+		n, err := r.Read(chunk)
+		if n > 0 {
+			s.size += int64(n)
+			if s.budget == nil || s.budget.reserve(int64(n)) {
+				if s.budget != nil {
+					s.reserved += int64(n)
+				}
+				s.buf.Write(chunk[:n])
+			} else if serr := s.spill(chunk[:n]); serr != nil {
+				return serr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// spill moves the body read so far, followed by pending, to a temporary
+// file, which receives the rest, and frees its memory.
+func (s *spooledBody) spill(pending []byte) error {
+	if !s.canSpill {
+		return errMemoryBudget
+	}
+	f, err := os.CreateTemp(s.budget.dir, "smtp-body-*")
+	if err != nil {
+		return fmt.Errorf("create spill file: %w", err)
+	}
+	s.file = f
+	if _, err := f.Write(s.buf.Bytes()); err != nil {
+		return fmt.Errorf("write spill file: %w", err)
+	}
+	if _, err := f.Write(pending); err != nil {
+		return fmt.Errorf("write spill file: %w", err)
+	}
+	s.freeBuffer()
+	metrics.SMTPBodySpillsTotal.Inc()
+	return nil
+}
+
+// spilled reports whether the body is held in a file.
+func (s *spooledBody) spilled() bool {
+	return s.file != nil
+}
+
+// bytes returns the body held in memory, or nil when it was spilled. The
+// bytes must not be used after close.
+func (s *spooledBody) bytes() []byte {
+	if s.file != nil {
+		return nil
+	}
+	return s.buf.Bytes()
+}
+
+// reader returns a reader over the whole body. Each call starts from the
+// beginning; readers must not be used concurrently.
+func (s *spooledBody) reader() (io.Reader, error) {
+	if s.file == nil {
+		return bytes.NewReader(s.buf.Bytes()), nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.file, nil
+}
+
+// close releases the body's memory and removes its file.
+func (s *spooledBody) close() {
+	s.freeBuffer()
+	if s.file != nil {
+		name := s.file.Name()
+		s.file.Close()
+		os.Remove(name)
+	}
+}
+
+func (s *spooledBody) freeBuffer() {
+	if s.buf == nil {
+		return
+	}
+	putBodyBuffer(s.buf)
+	s.buf = nil
+	if s.reserved > 0 {
+		s.budget.release(s.reserved)
+		s.reserved = 0
+	}
+}
@@ -0,0 +1,97 @@
+package smtp
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestSession_Data_SpillsOverMemoryBudget(t *testing.T) {
+	store, err := msgstore.NewLocalFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spillDir := t.TempDir()
+	budget := NewMemoryBudget(64<<10, spillDir)
+
+	var stored storage.EnqueueMessageMetadataParams
+	mock := &mockQuerier{
+		enqueueMessageMetadataFn: func(_ context.Context, arg storage.EnqueueMessageMetadataParams) (storage.Message, error) {
+			stored = arg
+			return storage.Message{ID: uuid.New()}, nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.backend.store = store
+	s.backend.SetMemoryBudget(budget)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	data := "Subject: Large attachment\r\n\r\n" + strings.Repeat("A", 1<<20)
+	if err := s.Data(strings.NewReader(data)); err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if stored.Subject.String != "Large attachment" || stored.SizeBytes != int64(len(data)) {
+		t.Errorf("unexpected metadata: subject %q, size %d", stored.Subject.String, stored.SizeBytes)
+	}
+	got, err := store.Get(context.Background(), stored.StorageRef.String)
+	if err != nil || string(got) != data {
+		t.Fatalf("stored body: %d bytes, %v; want %d bytes", len(got), err, len(data))
+	}
+	if used := budget.used.Load(); used != 0 {
+		t.Errorf("budget still holds %d bytes after DATA", used)
+	}
+	if entries, _ := os.ReadDir(spillDir); len(entries) != 0 {
+		t.Errorf("spill file was not removed: %v", entries)
+	}
+}
+
+func TestSession_Data_WithinMemoryBudget(t *testing.T) {
+	var put []byte
+	store := &mockMessageStore{
+		putFn: func(_ context.Context, _ string, data []byte) error {
+			put = append([]byte(nil), data...)
+			return nil
+		},
+	}
+	budget := NewMemoryBudget(1<<20, t.TempDir())
+	s := newAuthenticatedSession(&mockQuerier{}, uuid.New(), uuid.New(), nil)
+	s.backend.store = store
+	s.backend.SetMemoryBudget(budget)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	data := "Subject: Small\r\n\r\nHello"
+	if err := s.Data(strings.NewReader(data)); err != nil {
+		t.Fatalf("Data() error: %v", err)
+	}
+	if string(put) != data {
+		t.Errorf("Put() got %q, want %q", put, data)
+	}
+	if used := budget.used.Load(); used != 0 {
+		t.Errorf("budget still holds %d bytes after DATA", used)
+	}
+}
+
+func TestSession_Data_MemoryBudgetWithoutStreamingStore(t *testing.T) {
+	budget := NewMemoryBudget(1<<10, t.TempDir())
+	s := newAuthenticatedSession(&mockQuerier{}, uuid.New(), uuid.New(), nil)
+	s.backend.store = &mockMessageStore{}
+	s.backend.SetMemoryBudget(budget)
+	s.sender = "sender@example.com"
+	s.recipients = []string{"recipient@example.com"}
+
+	err := s.Data(strings.NewReader("Subject: Large\r\n\r\n" + strings.Repeat("A", 64<<10)))
+	if replyCode(err) != 452 {
+		t.Fatalf("Data() = %v, want 452 when the body can neither fit nor spill", err)
+	}
+	if used := budget.used.Load(); used != 0 {
+		t.Errorf("budget still holds %d bytes after the refused DATA", used)
+	}
+}
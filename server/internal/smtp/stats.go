@@ -0,0 +1,97 @@
+package smtp
+
+import (
+	"errors"
+	"net/netip"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// sessionStats counts what a client did over one connection. They are
+// reported on logout, to find clients that reconnect for every message
+// instead of sending several per session, or that keep sending commands
+// the server rejects.
+type sessionStats struct {
+	started time.Time
+	// messages counts accepted DATA commands.
+	messages int
+	// commands counts AUTH, MAIL, RCPT and DATA commands, and errors
+	// those answered with a 4xx or 5xx reply.
+	commands int
+	errors   int
+}
+
+// countCommand records the reply to command (auth, mail, rcpt or data).
+// It must be deferred after recoverPanic, so a recovered panic counts as
+// the error reply it was turned into.
+func (s *Session) countCommand(command string, err error) {
+	s.stats.commands++
+	if err == nil {
+		if command == "data" {
+			s.stats.messages++
+		}
+		return
+	}
+	s.stats.errors++
+	metrics.SMTPCommandErrorsTotal.WithLabelValues(command, replyClass(err)).Inc()
+}
+
+// replyClass returns "temporary" for 4xx replies and "permanent" for 5xx
+// replies. Errors other than SMTPError are written as 451.
+func replyClass(err error) string {
+	var smtpErr *gosmtp.SMTPError
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+		return "permanent"
+	}
+	return "temporary"
+}
+
+// reportStats observes the session in the session metrics, logs its
+// summary and, for authenticated sessions when enabled, records it in the
+// activity log of the user's group.
+func (s *Session) reportStats() {
+	var duration time.Duration
+	if !s.stats.started.IsZero() {
+		duration = time.Since(s.stats.started)
+		metrics.SMTPSessionDuration.Observe(duration.Seconds())
+	}
+	metrics.SMTPMessagesPerSession.Observe(float64(s.stats.messages))
+
+	s.log.Info().
+		Int("messages", s.stats.messages).
+		Int("commands", s.stats.commands).
+		Int("command_errors", s.stats.errors).
+		Dur("duration", duration).
+		Msg("session closed")
+
+	if !s.authenticated || !s.backend.sessionActivity {
+		return
+	}
+	var ip *netip.Addr
+	if s.remoteIP.IsValid() {
+		ip = &s.remoteIP
+	}
+	if _, err := s.queries.CreateActivityLog(s.ctx, storage.CreateActivityLogParams{
+		GroupID:      s.groupID,
+		ActorID:      pgtype.UUID{Bytes: s.userID, Valid: true},
+		Action:       auth.AuditActionSMTPSession,
+		ResourceType: "user",
+		ResourceID:   pgtype.UUID{Bytes: s.userID, Valid: true},
+		Changes: auth.ChangesToJSON(map[string]interface{}{
+			"username":       s.username,
+			"messages":       s.stats.messages,
+			"commands":       s.stats.commands,
+			"command_errors": s.stats.errors,
+			"duration_ms":    duration.Milliseconds(),
+		}),
+		IpAddress: ip,
+	}); err != nil {
+		s.log.Error().Err(err).Msg("failed to write session activity log")
+	}
+}
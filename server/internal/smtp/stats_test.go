@@ -0,0 +1,102 @@
+package smtp
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestSessionStats_ActivityLog(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	passwordHash := hashTestPassword(t, "secret-password")
+
+	mock := newMockWithAuth(userID, groupID, passwordHash, nil)
+	var logged []storage.CreateActivityLogParams
+	mock.createActivityLogFn = func(_ context.Context, arg storage.CreateActivityLogParams) (storage.ActivityLog, error) {
+		logged = append(logged, arg)
+		return storage.ActivityLog{}, nil
+	}
+
+	s := newTestSession(mock)
+	s.backend.SetSessionActivity(true)
+	s.remoteIP = netip.MustParseAddr("198.51.100.4")
+	if err := authenticateSession(t, s, "testuser", "secret-password"); err != nil {
+		t.Fatalf("auth failed: %v", err)
+	}
+	if err := s.Mail("not an address", nil); err == nil {
+		t.Fatal("expected MAIL FROM to fail")
+	}
+	// Two messages accepted over the same connection.
+	s.countCommand("data", nil)
+	s.countCommand("data", nil)
+	_ = s.Logout()
+
+	if len(logged) != 1 {
+		t.Fatalf("expected 1 activity log entry, got %d", len(logged))
+	}
+	entry := logged[0]
+	if entry.Action != auth.AuditActionSMTPSession || entry.GroupID != groupID || uuid.UUID(entry.ActorID.Bytes) != userID {
+		t.Errorf("unexpected activity log entry %+v", entry)
+	}
+	if entry.IpAddress == nil || entry.IpAddress.String() != "198.51.100.4" {
+		t.Errorf("ip_address = %v, want 198.51.100.4", entry.IpAddress)
+	}
+	var changes map[string]interface{}
+	if err := json.Unmarshal(entry.Changes, &changes); err != nil {
+		t.Fatalf("changes: %v", err)
+	}
+	if changes["username"] != "testuser" || changes["messages"] != 2.0 || changes["commands"] != 4.0 || changes["command_errors"] != 1.0 {
+		t.Errorf("changes = %v, want 2 messages and 1 error in 4 commands", changes)
+	}
+}
+
+func TestSessionStats_NoActivityLog(t *testing.T) {
+	called := false
+	mock := &mockQuerier{
+		createActivityLogFn: func(_ context.Context, _ storage.CreateActivityLogParams) (storage.ActivityLog, error) {
+			called = true
+			return storage.ActivityLog{}, nil
+		},
+	}
+
+	// Disabled for an authenticated session.
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	_ = s.Logout()
+
+	// Enabled, but the client never authenticated.
+	s = newTestSession(mock)
+	s.backend.SetSessionActivity(true)
+	_ = s.Mail("sender@example.com", nil)
+	_ = s.Logout()
+
+	if called {
+		t.Error("activity log should not be written")
+	}
+	if s.stats.commands != 1 || s.stats.errors != 1 {
+		t.Errorf("stats = %+v, want 1 failed command", s.stats)
+	}
+}
+
+func TestReplyClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&gosmtp.SMTPError{Code: 530}, "permanent"},
+		{&gosmtp.SMTPError{Code: 451}, "temporary"},
+		{context.DeadlineExceeded, "temporary"},
+	}
+	for _, tt := range tests {
+		if got := replyClass(tt.err); got != tt.want {
+			t.Errorf("replyClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package smtp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+const (
+	// maxTranscriptLines caps the lines recorded per session so a looping
+	// client cannot grow a transcript without bound.
+	maxTranscriptLines = 500
+
+	// debugTargetsTTL is how long the list of active debug targets is
+	// cached before it is reloaded from the database.
+	debugTargetsTTL = 30 * time.Second
+)
+
+// Transcript line directions.
+const (
+	TranscriptClient = "C"
+	TranscriptServer = "S"
+)
+
+// TranscriptLine is one command or reply in an SMTP debug transcript.
+type TranscriptLine struct {
+	At   time.Time `json:"at"`
+	Dir  string    `json:"dir"`
+	Text string    `json:"text"`
+}
+
+// transcript records the commands a session handles and the replies sent
+// for them. Lines are recorded at the session level, so commands that
+// go-smtp rejects itself (syntax errors, unknown commands) and the
+// STARTTLS handshake are not part of the transcript. Message content is
+// never recorded, only its size.
+type transcript struct {
+	remoteAddr string
+	ip         netip.Addr
+	startedAt  time.Time
+	lines      []TranscriptLine
+	truncated  bool
+}
+
+func newTranscript(remote net.Addr, now time.Time) *transcript {
+	t := &transcript{startedAt: now}
+	if remote != nil {
+		t.remoteAddr = remote.String()
+		if ap, err := netip.ParseAddrPort(t.remoteAddr); err == nil {
+			t.ip = ap.Addr().Unmap()
+		}
+	}
+	return t
+}
+
+func (t *transcript) add(at time.Time, dir, text string) {
+	if len(t.lines) >= maxTranscriptLines {
+		t.truncated = true
+		return
+	}
+	t.lines = append(t.lines, TranscriptLine{At: at, Dir: dir, Text: text})
+}
+
+// trace records a client command and the reply go-smtp sends for err.
+// okReply is the reply used when err is nil.
+func (s *Session) trace(command string, err error, okReply string) {
+	if s.transcript == nil {
+		return
+	}
+	now := s.backend.debug.now()
+	s.transcript.add(now, TranscriptClient, redact.Text(command))
+	s.transcript.add(now, TranscriptServer, redact.Text(replyText(err, okReply)))
+}
+
+// replyText formats err the way go-smtp writes it to the client.
+func replyText(err error, okReply string) string {
+	if err == nil {
+		return okReply
+	}
+	if smtpErr, ok := err.(*gosmtp.SMTPError); ok {
+		if smtpErr.EnhancedCode == gosmtp.NoEnhancedCode {
+			return fmt.Sprintf("%d %s", smtpErr.Code, smtpErr.Message)
+		}
+		c := smtpErr.EnhancedCode
+		return fmt.Sprintf("%d %d.%d.%d %s", smtpErr.Code, c[0], c[1], c[2], smtpErr.Message)
+	}
+	return "451 4.0.0 " + err.Error()
+}
+
+// saveTranscript stores the session's transcript once for every active
+// debug target matching the client IP or the authenticated user.
+func (s *Session) saveTranscript() {
+	if s.transcript == nil || len(s.transcript.lines) == 0 {
+		return
+	}
+	lines := s.transcript.lines
+	if s.transcript.truncated {
+		lines = append(lines, TranscriptLine{
+			At:   s.backend.debug.now(),
+			Dir:  TranscriptServer,
+			Text: fmt.Sprintf("[transcript truncated after %d lines]", maxTranscriptLines),
+		})
+	}
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return
+	}
+
+	var userID pgtype.UUID
+	if s.authenticated {
+		userID = pgtype.UUID{Bytes: s.userID, Valid: true}
+	}
+	for _, targetID := range s.backend.debug.match(s.ctx, s.transcript.ip, userID) {
+		if _, err := s.queries.CreateSMTPTranscript(s.ctx, storage.CreateSMTPTranscriptParams{
+			TargetID:   targetID,
+			UserID:     userID,
+			RemoteAddr: s.transcript.remoteAddr,
+			Lines:      data,
+			StartedAt:  pgtype.Timestamptz{Time: s.transcript.startedAt, Valid: true},
+		}); err != nil {
+			s.log.Warn().Err(err).Stringer("target_id", targetID).Msg("failed to save SMTP transcript")
+		}
+	}
+}
+
+// debugTargets caches the active SMTP debug targets so that sessions only
+// pay for transcript recording while debugging is switched on somewhere.
+type debugTargets struct {
+	queries storage.Querier
+	log     zerolog.Logger
+	now     func() time.Time
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	targets  []storage.SmtpDebugTarget
+}
+
+func newDebugTargets(queries storage.Querier, log zerolog.Logger) *debugTargets {
+	return &debugTargets{queries: queries, log: log, now: time.Now}
+}
+
+// active returns the unexpired targets, reloading them when the cache is
+// older than debugTargetsTTL. A failed reload keeps the previous list.
+func (d *debugTargets) active(ctx context.Context) []storage.SmtpDebugTarget {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if d.loadedAt.IsZero() || now.Sub(d.loadedAt) >= debugTargetsTTL {
+		d.loadedAt = now
+		targets, err := d.queries.ListActiveSMTPDebugTargets(ctx)
+		if err != nil {
+			d.log.Warn().Err(err).Msg("failed to load SMTP debug targets")
+		} else {
+			d.targets = targets
+		}
+	}
+
+	var active []storage.SmtpDebugTarget
+	for _, t := range d.targets {
+		if t.ExpiresAt.Time.After(now) {
+			active = append(active, t)
+		}
+	}
+	return active
+}
+
+// match returns the IDs of the active targets for ip or userID.
+func (d *debugTargets) match(ctx context.Context, ip netip.Addr, userID pgtype.UUID) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, t := range d.active(ctx) {
+		switch {
+		case t.UserID.Valid && userID.Valid && t.UserID.Bytes == userID.Bytes:
+			ids = append(ids, t.ID)
+		case t.IpAddress != nil && ip.IsValid() && t.IpAddress.Unmap() == ip:
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,173 @@
+package smtp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func debugTarget(userID uuid.UUID, ip string, expiresAt time.Time) storage.SmtpDebugTarget {
+	t := storage.SmtpDebugTarget{
+		ID:        uuid.New(),
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}
+	if userID != uuid.Nil {
+		t.UserID = pgtype.UUID{Bytes: userID, Valid: true}
+	}
+	if ip != "" {
+		addr := netip.MustParseAddr(ip)
+		t.IpAddress = &addr
+	}
+	return t
+}
+
+func newTracedSession(mock *mockQuerier, remote string) *Session {
+	s := newTestSession(mock)
+	addr, _ := net.ResolveTCPAddr("tcp", remote)
+	s.transcript = newTranscript(addr, time.Now())
+	return s
+}
+
+func TestTranscript_SavedForUserTarget(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+	passwordHash := hashTestPassword(t, "secret-password")
+	target := debugTarget(userID, "", time.Now().Add(time.Hour))
+
+	mock := newMockWithAuth(userID, groupID, passwordHash, nil)
+	mock.listActiveSMTPDebugTargetsFn = func(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+		return []storage.SmtpDebugTarget{target}, nil
+	}
+	var saved []storage.CreateSMTPTranscriptParams
+	mock.createSMTPTranscriptFn = func(_ context.Context, arg storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+		saved = append(saved, arg)
+		return storage.SmtpTranscript{}, nil
+	}
+
+	s := newTracedSession(mock, "198.51.100.4:40000")
+	if err := authenticateSession(t, s, "testuser", "secret-password"); err != nil {
+		t.Fatalf("auth failed: %v", err)
+	}
+	_ = s.Mail("not an address", nil)
+	_ = s.Logout()
+
+	if len(saved) != 1 {
+		t.Fatalf("expected 1 saved transcript, got %d", len(saved))
+	}
+	if saved[0].TargetID != target.ID || saved[0].RemoteAddr != "198.51.100.4:40000" {
+		t.Errorf("unexpected transcript params: %+v", saved[0])
+	}
+	if strings.Contains(string(saved[0].Lines), "secret-password") {
+		t.Error("transcript must not contain the password")
+	}
+
+	var lines []TranscriptLine
+	if err := json.Unmarshal(saved[0].Lines, &lines); err != nil {
+		t.Fatalf("decode lines: %v", err)
+	}
+	want := []string{
+		"AUTH PLAIN [credentials redacted, username testuser]",
+		"235 2.0.0 Authentication succeeded",
+		"MAIL FROM:<not an address>",
+		"550 5.1.7 Invalid sender address",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d: %+v", len(want), len(lines), lines)
+	}
+	for i, w := range want {
+		if lines[i].Text != w {
+			t.Errorf("line %d = %q, want %q", i, lines[i].Text, w)
+		}
+	}
+	if lines[0].Dir != TranscriptClient || lines[1].Dir != TranscriptServer {
+		t.Errorf("unexpected directions: %q %q", lines[0].Dir, lines[1].Dir)
+	}
+}
+
+func TestTranscript_SavedForIPTarget(t *testing.T) {
+	target := debugTarget(uuid.Nil, "203.0.113.7", time.Now().Add(time.Hour))
+	mock := &mockQuerier{
+		listActiveSMTPDebugTargetsFn: func(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+			return []storage.SmtpDebugTarget{target}, nil
+		},
+	}
+	saved := 0
+	mock.createSMTPTranscriptFn = func(_ context.Context, arg storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+		saved++
+		if arg.UserID.Valid {
+			t.Error("unauthenticated transcript must not have a user")
+		}
+		return storage.SmtpTranscript{}, nil
+	}
+
+	s := newTracedSession(mock, "203.0.113.7:25000")
+	_ = s.Rcpt("user@example.com", nil)
+	_ = s.Logout()
+
+	if saved != 1 {
+		t.Fatalf("expected 1 saved transcript, got %d", saved)
+	}
+}
+
+func TestTranscript_NotSavedWithoutMatch(t *testing.T) {
+	mock := &mockQuerier{
+		listActiveSMTPDebugTargetsFn: func(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+			return []storage.SmtpDebugTarget{
+				debugTarget(uuid.Nil, "203.0.113.7", time.Now().Add(time.Hour)),
+				debugTarget(uuid.Nil, "198.51.100.4", time.Now().Add(-time.Minute)),
+			}, nil
+		},
+		createSMTPTranscriptFn: func(_ context.Context, _ storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+			t.Error("transcript must not be saved")
+			return storage.SmtpTranscript{}, nil
+		},
+	}
+
+	s := newTracedSession(mock, "198.51.100.4:40000")
+	_ = s.Rcpt("user@example.com", nil)
+	_ = s.Logout()
+}
+
+func TestTranscript_LineCap(t *testing.T) {
+	tr := newTranscript(nil, time.Now())
+	for i := 0; i < maxTranscriptLines+10; i++ {
+		tr.add(time.Now(), TranscriptClient, "NOOP")
+	}
+	if len(tr.lines) != maxTranscriptLines || !tr.truncated {
+		t.Errorf("expected %d lines and truncation, got %d (truncated=%v)", maxTranscriptLines, len(tr.lines), tr.truncated)
+	}
+}
+
+func TestDebugTargets_CachesList(t *testing.T) {
+	calls := 0
+	mock := &mockQuerier{
+		listActiveSMTPDebugTargetsFn: func(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+			calls++
+			return nil, nil
+		},
+	}
+	now := time.Now()
+	d := newDebugTargets(mock, newTestSession(mock).log)
+	d.now = func() time.Time { return now }
+
+	d.active(context.Background())
+	d.active(context.Background())
+	if calls != 1 {
+		t.Errorf("expected 1 load within TTL, got %d", calls)
+	}
+
+	now = now.Add(debugTargetsTTL)
+	d.active(context.Background())
+	if calls != 2 {
+		t.Errorf("expected reload after TTL, got %d loads", calls)
+	}
+}
@@ -0,0 +1,130 @@
+package smtp
+
+import (
+	"context"
+	"strings"
+
+	gosmtp "github.com/emersion/go-smtp"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/shadow"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
+)
+
+// Recipient validation policies, set per group in groups.recipient_validation.
+// Any other value ("off") disables validation.
+const (
+	recipientValidationTag    = "tag"
+	recipientValidationReject = "reject"
+)
+
+// riskyRecipientTag is added to messages with a risky recipient under the
+// "tag" policy.
+const riskyRecipientTag = "risky-recipient"
+
+// recipientValidator checks recipient addresses. It is satisfied by
+// *validation.Validator.
+type recipientValidator interface {
+	Validate(ctx context.Context, address string) validation.Result
+}
+
+// checkRecipient applies the group's recipient validation policy. Under
+// "reject" a risky recipient is refused; under "tag" it is accepted and the
+// message is tagged. Failed DNS lookups never make a recipient risky.
+func (s *Session) checkRecipient(address string) error {
+	if s.recipientPolicy != recipientValidationTag && s.recipientPolicy != recipientValidationReject {
+		return nil
+	}
+
+	res := s.backend.validator.Validate(s.ctx, address)
+	if !res.Risky {
+		return nil
+	}
+
+	s.log.Info().
+		Str("to", redact.Email(address)).
+		Str("policy", s.recipientPolicy).
+		Str("reason", res.Reason).
+		Msg("risky recipient")
+
+	if s.recipientPolicy == recipientValidationTag {
+		s.riskyRecipient = true
+		return nil
+	}
+	if s.shadowed(shadow.PolicyRecipientValidation, res.Reason) {
+		return nil
+	}
+	code := gosmtp.EnhancedCode{5, 7, 1}
+	if !res.Valid {
+		code = gosmtp.EnhancedCode{5, 1, 1}
+	}
+	return &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: code,
+		Message:      "Recipient rejected: " + res.Reason,
+	}
+}
+
+// checkSandbox refuses recipients other than the verified addresses of the
+// group's members while the group is sandboxed, as groups created by
+// self-service signup are until a system admin lifts the sandbox. The
+// addresses are loaded once per session.
+func (s *Session) checkSandbox(address string) error {
+	if !s.sandbox {
+		return nil
+	}
+	if s.sandboxRecipients == nil {
+		emails, err := s.queries.ListVerifiedGroupMemberEmails(s.ctx, s.groupID)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to load sandbox recipients")
+			return &gosmtp.SMTPError{
+				Code:         451,
+				EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+				Message:      "Temporary failure, try again later",
+			}
+		}
+		s.sandboxRecipients = make(map[string]bool, len(emails))
+		for _, email := range emails {
+			s.sandboxRecipients[strings.ToLower(email)] = true
+		}
+	}
+
+	if s.sandboxRecipients[strings.ToLower(address)] {
+		return nil
+	}
+	s.log.Info().Str("to", redact.Email(address)).Msg("recipient outside sandbox")
+	return &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+		Message:      "Recipient rejected: sandboxed accounts can only send to their group's verified members",
+	}
+}
+
+// checkSuppressed refuses recipients on the group's suppression list, such
+// as those who complained about earlier mail through a feedback loop.
+func (s *Session) checkSuppressed(address string) error {
+	suppressed, err := s.queries.IsRecipientSuppressed(s.ctx, storage.IsRecipientSuppressedParams{
+		GroupID: s.groupID,
+		Address: address,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to check suppression list")
+		return &gosmtp.SMTPError{
+			Code:         451,
+			EnhancedCode: gosmtp.EnhancedCode{4, 3, 0},
+			Message:      "Temporary failure, try again later",
+		}
+	}
+	if !suppressed {
+		return nil
+	}
+	metrics.SMTPSuppressedRecipientsTotal.Inc()
+	s.log.Info().Str("to", redact.Email(address)).Msg("suppressed recipient")
+	return &gosmtp.SMTPError{
+		Code:         550,
+		EnhancedCode: gosmtp.EnhancedCode{5, 7, 1},
+		Message:      "Recipient rejected: address is on the group's suppression list",
+	}
+}
@@ -0,0 +1,168 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gosmtp "github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/validation"
+)
+
+// fakeValidator flags every address in risky.
+type fakeValidator struct {
+	risky map[string]validation.Result
+	calls int
+}
+
+func (f *fakeValidator) Validate(_ context.Context, address string) validation.Result {
+	f.calls++
+	if r, ok := f.risky[address]; ok {
+		return r
+	}
+	return validation.Result{Address: address, Syntax: true, MX: validation.MXFound, Valid: true}
+}
+
+func newValidatingSession(mock *mockQuerier, policy string) (*Session, *fakeValidator) {
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.recipientPolicy = policy
+	fv := &fakeValidator{risky: map[string]validation.Result{
+		"x@mailinator.com":     {Syntax: true, MX: validation.MXFound, Valid: true, Disposable: true, Risky: true, Reason: "disposable domain"},
+		"jane@nowhere.example": {Syntax: true, MX: validation.MXNone, Risky: true, Reason: "domain does not accept mail"},
+	}}
+	s.backend.validator = fv
+	return s, fv
+}
+
+func TestSession_Rcpt_ValidationOff(t *testing.T) {
+	s, fv := newValidatingSession(&mockQuerier{}, "off")
+	if err := s.Rcpt("x@mailinator.com", nil); err != nil {
+		t.Fatalf("expected recipient to be accepted, got %v", err)
+	}
+	if fv.calls != 0 {
+		t.Errorf("expected no validation with policy off, got %d calls", fv.calls)
+	}
+}
+
+func TestSession_Rcpt_ValidationReject(t *testing.T) {
+	s, _ := newValidatingSession(&mockQuerier{}, "reject")
+
+	tests := []struct {
+		to       string
+		enhanced gosmtp.EnhancedCode
+	}{
+		{"x@mailinator.com", gosmtp.EnhancedCode{5, 7, 1}},
+		{"jane@nowhere.example", gosmtp.EnhancedCode{5, 1, 1}},
+	}
+	for _, tt := range tests {
+		err := s.Rcpt(tt.to, nil)
+		var smtpErr *gosmtp.SMTPError
+		if !errors.As(err, &smtpErr) || smtpErr.Code != 550 || smtpErr.EnhancedCode != tt.enhanced {
+			t.Errorf("Rcpt(%q) = %v", tt.to, err)
+		}
+	}
+	if err := s.Rcpt("jane@example.com", nil); err != nil {
+		t.Errorf("expected valid recipient to be accepted, got %v", err)
+	}
+	if len(s.recipients) != 1 {
+		t.Errorf("expected 1 accepted recipient, got %v", s.recipients)
+	}
+}
+
+func TestSession_Rcpt_ValidationTag(t *testing.T) {
+	var captured storage.EnqueueMessageParams
+	mock := &mockQuerier{
+		enqueueMessageFn: func(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+			captured = arg
+			return storage.Message{ID: uuid.New(), Status: storage.MessageStatusQueued}, nil
+		},
+	}
+	s, _ := newValidatingSession(mock, "tag")
+	s.sender = "sender@example.com"
+
+	if err := s.Rcpt("x@mailinator.com", nil); err != nil {
+		t.Fatalf("expected risky recipient to be accepted, got %v", err)
+	}
+	data := "From: sender@example.com\r\nX-SMTPProxy-Tag: billing\r\n\r\nbody"
+	if err := s.Data(strings.NewReader(data)); err != nil {
+		t.Fatalf("Data: %v", err)
+	}
+	if got := string(captured.Tags); got != `["billing","risky-recipient"]` {
+		t.Errorf("tags = %s", got)
+	}
+
+	s.Reset()
+	if s.riskyRecipient {
+		t.Error("expected Reset to clear the risky recipient flag")
+	}
+}
+
+func TestSession_Rcpt_Sandbox(t *testing.T) {
+	loads := 0
+	mock := &mockQuerier{
+		listVerifiedGroupMemberEmailsFn: func(_ context.Context, _ uuid.UUID) ([]string, error) {
+			loads++
+			return []string{"Owner@Example.com", "dev@example.com"}, nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.sandbox = true
+
+	for _, to := range []string{"owner@example.com", "dev@example.com"} {
+		if err := s.Rcpt(to, nil); err != nil {
+			t.Errorf("Rcpt(%q) = %v, want member address accepted", to, err)
+		}
+	}
+	err := s.Rcpt("customer@example.org", nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 550 || smtpErr.EnhancedCode != (gosmtp.EnhancedCode{5, 7, 1}) {
+		t.Errorf("Rcpt(outside) = %v, want 550 5.7.1", err)
+	}
+	if loads != 1 {
+		t.Errorf("member addresses loaded %d times, want once per session", loads)
+	}
+}
+
+func TestSession_Rcpt_SandboxLookupFails(t *testing.T) {
+	mock := &mockQuerier{
+		listVerifiedGroupMemberEmailsFn: func(_ context.Context, _ uuid.UUID) ([]string, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), uuid.New(), nil)
+	s.sandbox = true
+
+	err := s.Rcpt("owner@example.com", nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 451 {
+		t.Errorf("Rcpt() = %v, want 451", err)
+	}
+}
+
+func TestSession_Rcpt_Suppressed(t *testing.T) {
+	groupID := uuid.New()
+	var got storage.IsRecipientSuppressedParams
+	mock := &mockQuerier{
+		isRecipientSuppressedFn: func(_ context.Context, arg storage.IsRecipientSuppressedParams) (bool, error) {
+			got = arg
+			return arg.Address == "complainer@example.com", nil
+		},
+	}
+	s := newAuthenticatedSession(mock, uuid.New(), groupID, nil)
+
+	if err := s.Rcpt("customer@example.com", nil); err != nil {
+		t.Errorf("Rcpt(customer) = %v, want accepted", err)
+	}
+	err := s.Rcpt("complainer@example.com", nil)
+	var smtpErr *gosmtp.SMTPError
+	if !errors.As(err, &smtpErr) || smtpErr.Code != 550 || smtpErr.EnhancedCode != (gosmtp.EnhancedCode{5, 7, 1}) {
+		t.Errorf("Rcpt(suppressed) = %v, want 550 5.7.1", err)
+	}
+	if got.GroupID != groupID {
+		t.Errorf("suppression checked for group %v, want %v", got.GroupID, groupID)
+	}
+}
@@ -0,0 +1,111 @@
+// Package socketactivation inherits listening sockets passed by systemd
+// socket activation (sd_listen_fds(3)), so a service can bind privileged
+// ports without running as root and keep accepting connections across
+// restarts.
+package socketactivation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// Listener is an inherited listening socket. Name is the socket's
+// FileDescriptorName= from the unit, or "unknown" when none is set.
+type Listener struct {
+	Name string
+	net.Listener
+}
+
+// Listeners returns the listeners passed to this process by systemd and
+// unsets the LISTEN_* environment variables so they are not inherited by
+// child processes. It returns nil when the process was not socket
+// activated.
+func Listeners() ([]Listener, error) {
+	ls, err := listeners(os.Getenv, os.Getpid(), listenFDsStart)
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	return ls, err
+}
+
+func listeners(getenv func(string) string, pid, start int) ([]Listener, error) {
+	if getenv("LISTEN_PID") != strconv.Itoa(pid) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if v := getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	ls := make([]Listener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := start + i
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		syscall.CloseOnExec(fd)
+
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range ls {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket activation: fd %d (%s): %w", fd, name, err)
+		}
+		ls = append(ls, Listener{Name: name, Listener: ln})
+	}
+	return ls, nil
+}
+
+// Take removes and returns the listener named name or, failing that, the
+// one bound to addr. It reports false when neither is among ls.
+func Take(ls *[]Listener, name, addr string) (net.Listener, bool) {
+	for _, match := range []func(Listener) bool{
+		func(l Listener) bool { return name != "" && l.Name == name },
+		func(l Listener) bool { return sameAddr(l.Addr(), addr) },
+	} {
+		for i, l := range *ls {
+			if match(l) {
+				*ls = append((*ls)[:i], (*ls)[i+1:]...)
+				return l.Listener, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// sameAddr reports whether a bound address matches a configured host:port,
+// treating an empty or unspecified configured host as any address.
+func sameAddr(bound net.Addr, addr string) bool {
+	tcp, ok := bound.(*net.TCPAddr)
+	if !ok || addr == "" {
+		return false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || port != strconv.Itoa(tcp.Port) {
+		return false
+	}
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return ip.Equal(tcp.IP) || (ip.IsUnspecified() && tcp.IP.IsUnspecified())
+}
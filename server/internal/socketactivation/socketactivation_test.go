@@ -0,0 +1,86 @@
+package socketactivation
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// dupListener listens on a loopback port and returns a duplicate of its
+// file descriptor, standing in for a socket passed by systemd.
+func dupListener(t *testing.T) (int, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("file: %v", err)
+	}
+	defer f.Close()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatalf("dup: %v", err)
+	}
+	return fd, ln.Addr().String()
+}
+
+func TestListeners(t *testing.T) {
+	fd, addr := dupListener(t)
+	env := map[string]string{
+		"LISTEN_PID":     strconv.Itoa(os.Getpid()),
+		"LISTEN_FDS":     "1",
+		"LISTEN_FDNAMES": "submission",
+	}
+
+	ls, err := listeners(func(k string) string { return env[k] }, os.Getpid(), fd)
+	if err != nil {
+		t.Fatalf("listeners: %v", err)
+	}
+	if len(ls) != 1 || ls[0].Name != "submission" || ls[0].Addr().String() != addr {
+		t.Fatalf("unexpected listeners: %+v", ls)
+	}
+	defer ls[0].Close()
+
+	if _, ok := Take(&ls, "smtps", "0.0.0.0:1"); ok {
+		t.Error("expected no match for another name and port")
+	}
+	if ln, ok := Take(&ls, "submission", ""); !ok || ln.Addr().String() != addr {
+		t.Errorf("expected match by name, got %v", ln)
+	}
+	if len(ls) != 0 {
+		t.Errorf("expected taken listener to be removed, %d left", len(ls))
+	}
+}
+
+func TestListeners_NotActivated(t *testing.T) {
+	env := map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "2"}
+	ls, err := listeners(func(k string) string { return env[k] }, os.Getpid(), listenFDsStart)
+	if err != nil || ls != nil {
+		t.Errorf("expected no listeners for another PID, got %v, %v", ls, err)
+	}
+}
+
+func TestSameAddr(t *testing.T) {
+	bound := &net.TCPAddr{IP: net.IPv4zero, Port: 587}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"0.0.0.0:587", true},
+		{":587", true},
+		{"[::]:587", true},
+		{"0.0.0.0:465", false},
+		{"127.0.0.1:587", false},
+		{"mail.example.com:587", false},
+	}
+	for _, tt := range tests {
+		if got := sameAddr(bound, tt.addr); got != tt.want {
+			t.Errorf("sameAddr(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -57,6 +57,41 @@ func (q *Queries) CreateActivityLog(ctx context.Context, arg CreateActivityLogPa
 	return i, err
 }
 
+const exportGroupActivityLogs = `-- name: ExportGroupActivityLogs :many
+SELECT id, group_id, actor_id, action, resource_type, resource_id, changes, comment, ip_address, created_at FROM activity_logs WHERE group_id = $1 ORDER BY created_at ASC
+`
+
+func (q *Queries) ExportGroupActivityLogs(ctx context.Context, groupID uuid.UUID) ([]ActivityLog, error) {
+	rows, err := q.db.Query(ctx, exportGroupActivityLogs, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ActivityLog
+	for rows.Next() {
+		var i ActivityLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.ActorID,
+			&i.Action,
+			&i.ResourceType,
+			&i.ResourceID,
+			&i.Changes,
+			&i.Comment,
+			&i.IpAddress,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getActivityLogByID = `-- name: GetActivityLogByID :one
 SELECT id, group_id, actor_id, action, resource_type, resource_id, changes, comment, ip_address, created_at FROM activity_logs WHERE id = $1
 `
@@ -166,10 +166,12 @@ INSERT INTO delivery_logs (
     message_id, provider_id, group_id, user_id, status, provider,
     provider_message_id, response_code, response_body,
     retry_count, last_error, metadata,
-    duration_ms, attempt_number
+    duration_ms, attempt_number, request_id,
+    egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher,
+    connect_ms, tls_handshake_ms, first_byte_ms
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-RETURNING id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+RETURNING id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms
 `
 
 type CreateDeliveryLogParams struct {
@@ -187,6 +189,15 @@ type CreateDeliveryLogParams struct {
 	Metadata          []byte         `json:"metadata"`
 	DurationMs        pgtype.Int4    `json:"duration_ms"`
 	AttemptNumber     int32          `json:"attempt_number"`
+	RequestID         pgtype.Text    `json:"request_id"`
+	EgressIp          pgtype.Text    `json:"egress_ip"`
+	RemoteAddr        pgtype.Text    `json:"remote_addr"`
+	ProviderEndpoint  pgtype.Text    `json:"provider_endpoint"`
+	TlsVersion        pgtype.Text    `json:"tls_version"`
+	TlsCipher         pgtype.Text    `json:"tls_cipher"`
+	ConnectMs         pgtype.Int4    `json:"connect_ms"`
+	TlsHandshakeMs    pgtype.Int4    `json:"tls_handshake_ms"`
+	FirstByteMs       pgtype.Int4    `json:"first_byte_ms"`
 }
 
 func (q *Queries) CreateDeliveryLog(ctx context.Context, arg CreateDeliveryLogParams) (DeliveryLog, error) {
@@ -205,6 +216,15 @@ func (q *Queries) CreateDeliveryLog(ctx context.Context, arg CreateDeliveryLogPa
 		arg.Metadata,
 		arg.DurationMs,
 		arg.AttemptNumber,
+		arg.RequestID,
+		arg.EgressIp,
+		arg.RemoteAddr,
+		arg.ProviderEndpoint,
+		arg.TlsVersion,
+		arg.TlsCipher,
+		arg.ConnectMs,
+		arg.TlsHandshakeMs,
+		arg.FirstByteMs,
 	)
 	var i DeliveryLog
 	err := row.Scan(
@@ -226,12 +246,245 @@ func (q *Queries) CreateDeliveryLog(ctx context.Context, arg CreateDeliveryLogPa
 		&i.AttemptNumber,
 		&i.UserID,
 		&i.GroupID,
+		&i.RequestID,
+		&i.EgressIp,
+		&i.RemoteAddr,
+		&i.ProviderEndpoint,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.ConnectMs,
+		&i.TlsHandshakeMs,
+		&i.FirstByteMs,
 	)
 	return i, err
 }
 
+const dailyDeliveryVolume = `-- name: DailyDeliveryVolume :many
+SELECT provider_id, group_id, date_trunc('day', created_at)::date as day, COUNT(*) as count
+FROM delivery_logs
+WHERE status = 'delivered' AND provider_id IS NOT NULL AND created_at >= $1 AND created_at < $2
+GROUP BY provider_id, group_id, day
+ORDER BY day
+`
+
+type DailyDeliveryVolumeParams struct {
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	CreatedAt_2 pgtype.Timestamptz `json:"created_at_2"`
+}
+
+type DailyDeliveryVolumeRow struct {
+	ProviderID pgtype.UUID `json:"provider_id"`
+	GroupID    pgtype.UUID `json:"group_id"`
+	Day        pgtype.Date `json:"day"`
+	Count      int64       `json:"count"`
+}
+
+func (q *Queries) DailyDeliveryVolume(ctx context.Context, arg DailyDeliveryVolumeParams) ([]DailyDeliveryVolumeRow, error) {
+	rows, err := q.db.Query(ctx, dailyDeliveryVolume, arg.CreatedAt, arg.CreatedAt_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DailyDeliveryVolumeRow
+	for rows.Next() {
+		var i DailyDeliveryVolumeRow
+		if err := rows.Scan(
+			&i.ProviderID,
+			&i.GroupID,
+			&i.Day,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deliveryLatencyPercentiles = `-- name: DeliveryLatencyPercentiles :many
+SELECT dl.group_id, dl.provider, COUNT(*) as deliveries,
+    percentile_cont(0.50) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at))::float8 as p50_seconds,
+    percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at))::float8 as p95_seconds,
+    percentile_cont(0.99) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at))::float8 as p99_seconds
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.status = 'delivered' AND dl.group_id IS NOT NULL AND dl.delivered_at >= $1
+GROUP BY dl.group_id, dl.provider
+`
+
+type DeliveryLatencyPercentilesRow struct {
+	GroupID    pgtype.UUID    `json:"group_id"`
+	Provider   sql.NullString `json:"provider"`
+	Deliveries int64          `json:"deliveries"`
+	P50Seconds float64        `json:"p50_seconds"`
+	P95Seconds float64        `json:"p95_seconds"`
+	P99Seconds float64        `json:"p99_seconds"`
+}
+
+func (q *Queries) DeliveryLatencyPercentiles(ctx context.Context, deliveredAt pgtype.Timestamptz) ([]DeliveryLatencyPercentilesRow, error) {
+	rows, err := q.db.Query(ctx, deliveryLatencyPercentiles, deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryLatencyPercentilesRow
+	for rows.Next() {
+		var i DeliveryLatencyPercentilesRow
+		if err := rows.Scan(
+			&i.GroupID,
+			&i.Provider,
+			&i.Deliveries,
+			&i.P50Seconds,
+			&i.P95Seconds,
+			&i.P99Seconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deliveryTimeSeries = `-- name: DeliveryTimeSeries :many
+SELECT to_timestamp(floor(EXTRACT(EPOCH FROM dl.created_at) / $1::float8) * $1::float8)::timestamptz AS bucket,
+    COUNT(*) AS attempts,
+    COUNT(*) FILTER (WHERE dl.status IN ('delivered', 'sent')) AS delivered,
+    COUNT(*) FILTER (WHERE dl.status = 'bounced') AS bounced,
+    COUNT(*) FILTER (WHERE dl.status = 'complained') AS complained,
+    COUNT(*) FILTER (WHERE dl.status = 'failed') AS failed,
+    COALESCE(AVG(dl.duration_ms), 0)::float8 AS avg_duration_ms,
+    COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at)) FILTER (WHERE dl.status IN ('delivered', 'sent')), 0)::float8 AS p50_seconds,
+    COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at)) FILTER (WHERE dl.status IN ('delivered', 'sent')), 0)::float8 AS p95_seconds
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.group_id = $2
+  AND dl.created_at >= $3 AND dl.created_at < $4
+  AND ($5::text IS NULL OR dl.provider = $5::text)
+GROUP BY bucket
+ORDER BY bucket
+`
+
+type DeliveryTimeSeriesParams struct {
+	BucketSeconds float64            `json:"bucket_seconds"`
+	GroupID       pgtype.UUID        `json:"group_id"`
+	Since         pgtype.Timestamptz `json:"since"`
+	Until         pgtype.Timestamptz `json:"until"`
+	Provider      pgtype.Text        `json:"provider"`
+}
+
+type DeliveryTimeSeriesRow struct {
+	Bucket        pgtype.Timestamptz `json:"bucket"`
+	Attempts      int64              `json:"attempts"`
+	Delivered     int64              `json:"delivered"`
+	Bounced       int64              `json:"bounced"`
+	Complained    int64              `json:"complained"`
+	Failed        int64              `json:"failed"`
+	AvgDurationMs float64            `json:"avg_duration_ms"`
+	P50Seconds    float64            `json:"p50_seconds"`
+	P95Seconds    float64            `json:"p95_seconds"`
+}
+
+// Buckets a group's delivery attempts by created_at. Webhooks record
+// deliveries confirmed by the ESP as 'sent'.
+func (q *Queries) DeliveryTimeSeries(ctx context.Context, arg DeliveryTimeSeriesParams) ([]DeliveryTimeSeriesRow, error) {
+	rows, err := q.db.Query(ctx, deliveryTimeSeries,
+		arg.BucketSeconds,
+		arg.GroupID,
+		arg.Since,
+		arg.Until,
+		arg.Provider,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryTimeSeriesRow
+	for rows.Next() {
+		var i DeliveryTimeSeriesRow
+		if err := rows.Scan(
+			&i.Bucket,
+			&i.Attempts,
+			&i.Delivered,
+			&i.Bounced,
+			&i.Complained,
+			&i.Failed,
+			&i.AvgDurationMs,
+			&i.P50Seconds,
+			&i.P95Seconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const exportGroupDeliveryLogs = `-- name: ExportGroupDeliveryLogs :many
+SELECT dl.id, dl.message_id, dl.provider_id, dl.status, dl.response_code, dl.response_body, dl.delivered_at, dl.provider, dl.provider_message_id, dl.retry_count, dl.last_error, dl.metadata, dl.created_at, dl.updated_at, dl.duration_ms, dl.attempt_number, dl.user_id, dl.group_id, dl.request_id, dl.egress_ip, dl.remote_addr, dl.provider_endpoint, dl.tls_version, dl.tls_cipher, dl.connect_ms, dl.tls_handshake_ms, dl.first_byte_ms FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE m.group_id = $1
+ORDER BY dl.created_at ASC
+`
+
+func (q *Queries) ExportGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) ([]DeliveryLog, error) {
+	rows, err := q.db.Query(ctx, exportGroupDeliveryLogs, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryLog
+	for rows.Next() {
+		var i DeliveryLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.ProviderID,
+			&i.Status,
+			&i.ResponseCode,
+			&i.ResponseBody,
+			&i.DeliveredAt,
+			&i.Provider,
+			&i.ProviderMessageID,
+			&i.RetryCount,
+			&i.LastError,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationMs,
+			&i.AttemptNumber,
+			&i.UserID,
+			&i.GroupID,
+			&i.RequestID,
+			&i.EgressIp,
+			&i.RemoteAddr,
+			&i.ProviderEndpoint,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.ConnectMs,
+			&i.TlsHandshakeMs,
+			&i.FirstByteMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getDeliveryLogByMessageID = `-- name: GetDeliveryLogByMessageID :one
-SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id FROM delivery_logs WHERE message_id = $1
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs WHERE message_id = $1
 `
 
 func (q *Queries) GetDeliveryLogByMessageID(ctx context.Context, messageID uuid.UUID) (DeliveryLog, error) {
@@ -256,12 +509,21 @@ func (q *Queries) GetDeliveryLogByMessageID(ctx context.Context, messageID uuid.
 		&i.AttemptNumber,
 		&i.UserID,
 		&i.GroupID,
+		&i.RequestID,
+		&i.EgressIp,
+		&i.RemoteAddr,
+		&i.ProviderEndpoint,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.ConnectMs,
+		&i.TlsHandshakeMs,
+		&i.FirstByteMs,
 	)
 	return i, err
 }
 
 const getDeliveryLogByProviderMessageID = `-- name: GetDeliveryLogByProviderMessageID :one
-SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id FROM delivery_logs WHERE provider_message_id = $1
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs WHERE provider_message_id = $1
 `
 
 func (q *Queries) GetDeliveryLogByProviderMessageID(ctx context.Context, providerMessageID sql.NullString) (DeliveryLog, error) {
@@ -286,6 +548,15 @@ func (q *Queries) GetDeliveryLogByProviderMessageID(ctx context.Context, provide
 		&i.AttemptNumber,
 		&i.UserID,
 		&i.GroupID,
+		&i.RequestID,
+		&i.EgressIp,
+		&i.RemoteAddr,
+		&i.ProviderEndpoint,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.ConnectMs,
+		&i.TlsHandshakeMs,
+		&i.FirstByteMs,
 	)
 	return i, err
 }
@@ -309,7 +580,7 @@ func (q *Queries) IncrementRetryCount(ctx context.Context, arg IncrementRetryCou
 }
 
 const listDeliveryLogsByGroupAndStatus = `-- name: ListDeliveryLogsByGroupAndStatus :many
-SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id FROM delivery_logs
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs
 WHERE group_id = $1 AND status = $2
 ORDER BY created_at DESC
 LIMIT $3 OFFSET $4
@@ -355,6 +626,15 @@ func (q *Queries) ListDeliveryLogsByGroupAndStatus(ctx context.Context, arg List
 			&i.AttemptNumber,
 			&i.UserID,
 			&i.GroupID,
+			&i.RequestID,
+			&i.EgressIp,
+			&i.RemoteAddr,
+			&i.ProviderEndpoint,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.ConnectMs,
+			&i.TlsHandshakeMs,
+			&i.FirstByteMs,
 		); err != nil {
 			return nil, err
 		}
@@ -367,7 +647,7 @@ func (q *Queries) ListDeliveryLogsByGroupAndStatus(ctx context.Context, arg List
 }
 
 const listDeliveryLogsByMessageID = `-- name: ListDeliveryLogsByMessageID :many
-SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id FROM delivery_logs WHERE message_id = $1 ORDER BY delivered_at DESC
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs WHERE message_id = $1 ORDER BY delivered_at DESC
 `
 
 func (q *Queries) ListDeliveryLogsByMessageID(ctx context.Context, messageID uuid.UUID) ([]DeliveryLog, error) {
@@ -398,6 +678,15 @@ func (q *Queries) ListDeliveryLogsByMessageID(ctx context.Context, messageID uui
 			&i.AttemptNumber,
 			&i.UserID,
 			&i.GroupID,
+			&i.RequestID,
+			&i.EgressIp,
+			&i.RemoteAddr,
+			&i.ProviderEndpoint,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.ConnectMs,
+			&i.TlsHandshakeMs,
+			&i.FirstByteMs,
 		); err != nil {
 			return nil, err
 		}
@@ -409,6 +698,236 @@ func (q *Queries) ListDeliveryLogsByMessageID(ctx context.Context, messageID uui
 	return items, nil
 }
 
+const listDeliveryLogsByMessageIDs = `-- name: ListDeliveryLogsByMessageIDs :many
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs
+WHERE message_id = ANY($1::uuid[])
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListDeliveryLogsByMessageIDs(ctx context.Context, messageIds []uuid.UUID) ([]DeliveryLog, error) {
+	rows, err := q.db.Query(ctx, listDeliveryLogsByMessageIDs, messageIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryLog
+	for rows.Next() {
+		var i DeliveryLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.ProviderID,
+			&i.Status,
+			&i.ResponseCode,
+			&i.ResponseBody,
+			&i.DeliveredAt,
+			&i.Provider,
+			&i.ProviderMessageID,
+			&i.RetryCount,
+			&i.LastError,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationMs,
+			&i.AttemptNumber,
+			&i.UserID,
+			&i.GroupID,
+			&i.RequestID,
+			&i.EgressIp,
+			&i.RemoteAddr,
+			&i.ProviderEndpoint,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.ConnectMs,
+			&i.TlsHandshakeMs,
+			&i.FirstByteMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupDeliveryLogs = `-- name: ListGroupDeliveryLogs :many
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs
+WHERE group_id = $1
+  AND ($2::text IS NULL OR egress_ip = $2::text)
+  AND ($3::text IS NULL OR provider = $3::text)
+  AND ($4::timestamptz IS NULL OR created_at >= $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+ORDER BY created_at DESC
+LIMIT $6
+`
+
+type ListGroupDeliveryLogsParams struct {
+	GroupID    pgtype.UUID        `json:"group_id"`
+	EgressIp   pgtype.Text        `json:"egress_ip"`
+	Provider   pgtype.Text        `json:"provider"`
+	Since      pgtype.Timestamptz `json:"since"`
+	Until      pgtype.Timestamptz `json:"until"`
+	MaxResults int32              `json:"max_results"`
+}
+
+func (q *Queries) ListGroupDeliveryLogs(ctx context.Context, arg ListGroupDeliveryLogsParams) ([]DeliveryLog, error) {
+	rows, err := q.db.Query(ctx, listGroupDeliveryLogs,
+		arg.GroupID,
+		arg.EgressIp,
+		arg.Provider,
+		arg.Since,
+		arg.Until,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryLog
+	for rows.Next() {
+		var i DeliveryLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.ProviderID,
+			&i.Status,
+			&i.ResponseCode,
+			&i.ResponseBody,
+			&i.DeliveredAt,
+			&i.Provider,
+			&i.ProviderMessageID,
+			&i.RetryCount,
+			&i.LastError,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DurationMs,
+			&i.AttemptNumber,
+			&i.UserID,
+			&i.GroupID,
+			&i.RequestID,
+			&i.EgressIp,
+			&i.RemoteAddr,
+			&i.ProviderEndpoint,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.ConnectMs,
+			&i.TlsHandshakeMs,
+			&i.FirstByteMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecipientDeliveryLogs = `-- name: ListRecipientDeliveryLogs :many
+SELECT dl.id, dl.message_id, dl.provider_id, dl.status, dl.response_code, dl.response_body, dl.delivered_at, dl.provider, dl.provider_message_id, dl.retry_count, dl.last_error, dl.metadata, dl.created_at, dl.updated_at, dl.duration_ms, dl.attempt_number, dl.user_id, dl.group_id, dl.request_id, dl.egress_ip, dl.remote_addr, dl.provider_endpoint, dl.tls_version, dl.tls_cipher, dl.connect_ms, dl.tls_handshake_ms, dl.first_byte_ms, m.sender, m.subject
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE m.group_id = $1
+  AND lower(m.recipients::text)::jsonb @> jsonb_build_array(lower($2::text))
+  AND ($3::timestamptz IS NULL OR dl.created_at >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR dl.created_at < $4::timestamptz)
+ORDER BY dl.created_at DESC
+LIMIT $5
+`
+
+type ListRecipientDeliveryLogsParams struct {
+	GroupID    pgtype.UUID        `json:"group_id"`
+	Recipient  string             `json:"recipient"`
+	Since      pgtype.Timestamptz `json:"since"`
+	Until      pgtype.Timestamptz `json:"until"`
+	MaxResults int32              `json:"max_results"`
+}
+
+type ListRecipientDeliveryLogsRow struct {
+	DeliveryLog DeliveryLog    `json:"delivery_log"`
+	Sender      string         `json:"sender"`
+	Subject     sql.NullString `json:"subject"`
+}
+
+func (q *Queries) ListRecipientDeliveryLogs(ctx context.Context, arg ListRecipientDeliveryLogsParams) ([]ListRecipientDeliveryLogsRow, error) {
+	rows, err := q.db.Query(ctx, listRecipientDeliveryLogs,
+		arg.GroupID,
+		arg.Recipient,
+		arg.Since,
+		arg.Until,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecipientDeliveryLogsRow
+	for rows.Next() {
+		var i ListRecipientDeliveryLogsRow
+		if err := rows.Scan(
+			&i.DeliveryLog.ID,
+			&i.DeliveryLog.MessageID,
+			&i.DeliveryLog.ProviderID,
+			&i.DeliveryLog.Status,
+			&i.DeliveryLog.ResponseCode,
+			&i.DeliveryLog.ResponseBody,
+			&i.DeliveryLog.DeliveredAt,
+			&i.DeliveryLog.Provider,
+			&i.DeliveryLog.ProviderMessageID,
+			&i.DeliveryLog.RetryCount,
+			&i.DeliveryLog.LastError,
+			&i.DeliveryLog.Metadata,
+			&i.DeliveryLog.CreatedAt,
+			&i.DeliveryLog.UpdatedAt,
+			&i.DeliveryLog.DurationMs,
+			&i.DeliveryLog.AttemptNumber,
+			&i.DeliveryLog.UserID,
+			&i.DeliveryLog.GroupID,
+			&i.DeliveryLog.RequestID,
+			&i.DeliveryLog.EgressIp,
+			&i.DeliveryLog.RemoteAddr,
+			&i.DeliveryLog.ProviderEndpoint,
+			&i.DeliveryLog.TlsVersion,
+			&i.DeliveryLog.TlsCipher,
+			&i.DeliveryLog.ConnectMs,
+			&i.DeliveryLog.TlsHandshakeMs,
+			&i.DeliveryLog.FirstByteMs,
+			&i.Sender,
+			&i.Subject,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scrubGroupDeliveryLogs = `-- name: ScrubGroupDeliveryLogs :execrows
+UPDATE delivery_logs dl
+SET response_body = regexp_replace(dl.response_body, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    last_error = regexp_replace(dl.last_error, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    metadata = regexp_replace(dl.metadata::text, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g')::jsonb,
+    updated_at = NOW()
+FROM messages m
+WHERE m.id = dl.message_id AND m.group_id = $1
+`
+
+// Replaces email addresses in ESP responses, errors and metadata.
+func (q *Queries) ScrubGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, scrubGroupDeliveryLogs, groupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateDeliveryLogStatus = `-- name: UpdateDeliveryLogStatus :exec
 UPDATE delivery_logs
 SET status = $2,
@@ -134,8 +134,35 @@ func (q *Queries) ListGroupMembersByGroupID(ctx context.Context, groupID uuid.UU
 	return items, nil
 }
 
+const listGroupOwnerEmails = `-- name: ListGroupOwnerEmails :many
+SELECT u.email FROM group_members gm
+JOIN users u ON u.id = gm.user_id
+WHERE gm.group_id = $1 AND gm.role = 'owner' AND u.status = 'active'
+ORDER BY u.email
+`
+
+func (q *Queries) ListGroupOwnerEmails(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, listGroupOwnerEmails, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		items = append(items, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listGroupsByUserID = `-- name: ListGroupsByUserID :many
-SELECT g.id, g.name, g.status, g.monthly_limit, g.monthly_sent, g.allowed_ips, g.created_at, g.updated_at, g.group_type FROM groups g
+SELECT g.id, g.name, g.status, g.monthly_limit, g.monthly_sent, g.allowed_ips, g.created_at, g.updated_at, g.group_type, g.sanitize_html, g.inline_css, g.parent_id, g.recipient_validation, g.sandbox, g.locale FROM groups g
 JOIN group_members gm ON g.id = gm.group_id
 WHERE gm.user_id = $1
 ORDER BY gm.created_at ASC
@@ -160,6 +187,12 @@ func (q *Queries) ListGroupsByUserID(ctx context.Context, userID uuid.UUID) ([]G
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.GroupType,
+			&i.SanitizeHtml,
+			&i.InlineCss,
+			&i.ParentID,
+			&i.RecipientValidation,
+			&i.Sandbox,
+			&i.Locale,
 		); err != nil {
 			return nil, err
 		}
@@ -171,6 +204,33 @@ func (q *Queries) ListGroupsByUserID(ctx context.Context, userID uuid.UUID) ([]G
 	return items, nil
 }
 
+const listVerifiedGroupMemberEmails = `-- name: ListVerifiedGroupMemberEmails :many
+SELECT u.email FROM group_members gm
+JOIN users u ON u.id = gm.user_id
+WHERE gm.group_id = $1 AND u.status = 'active' AND u.email_verified_at IS NOT NULL
+ORDER BY u.email
+`
+
+func (q *Queries) ListVerifiedGroupMemberEmails(ctx context.Context, groupID uuid.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, listVerifiedGroupMemberEmails, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		items = append(items, email)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateGroupMemberRole = `-- name: UpdateGroupMemberRole :one
 UPDATE group_members
 SET role = $2
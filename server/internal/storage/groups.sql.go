@@ -9,21 +9,23 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createGroup = `-- name: CreateGroup :one
-INSERT INTO groups (name, group_type)
-VALUES ($1, $2)
-RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type
+INSERT INTO groups (name, group_type, parent_id)
+VALUES ($1, $2, $3)
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
 `
 
 type CreateGroupParams struct {
-	Name      string `json:"name"`
-	GroupType string `json:"group_type"`
+	Name      string      `json:"name"`
+	GroupType string      `json:"group_type"`
+	ParentID  pgtype.UUID `json:"parent_id"`
 }
 
 func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
-	row := q.db.QueryRow(ctx, createGroup, arg.Name, arg.GroupType)
+	row := q.db.QueryRow(ctx, createGroup, arg.Name, arg.GroupType, arg.ParentID)
 	var i Group
 	err := row.Scan(
 		&i.ID,
@@ -35,6 +37,12 @@ func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -49,7 +57,7 @@ func (q *Queries) DeleteGroup(ctx context.Context, id uuid.UUID) error {
 }
 
 const getGroupByID = `-- name: GetGroupByID :one
-SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type FROM groups WHERE id = $1
+SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale FROM groups WHERE id = $1
 `
 
 func (q *Queries) GetGroupByID(ctx context.Context, id uuid.UUID) (Group, error) {
@@ -65,12 +73,18 @@ func (q *Queries) GetGroupByID(ctx context.Context, id uuid.UUID) (Group, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
 	)
 	return i, err
 }
 
 const getGroupByName = `-- name: GetGroupByName :one
-SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type FROM groups WHERE name = $1
+SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale FROM groups WHERE name = $1
 `
 
 func (q *Queries) GetGroupByName(ctx context.Context, name string) (Group, error) {
@@ -86,6 +100,12 @@ func (q *Queries) GetGroupByName(ctx context.Context, name string) (Group, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -101,8 +121,112 @@ func (q *Queries) IncrementMonthlySent(ctx context.Context, id uuid.UUID) error
 	return err
 }
 
+const listGroupAncestors = `-- name: ListGroupAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT g.id, g.name, g.status, g.monthly_limit, g.monthly_sent, g.allowed_ips, g.created_at, g.updated_at, g.group_type, g.sanitize_html, g.inline_css, g.parent_id, g.recipient_validation, g.sandbox, g.locale, 0 AS depth FROM groups g WHERE g.id = $1
+    UNION ALL
+    SELECT p.id, p.name, p.status, p.monthly_limit, p.monthly_sent, p.allowed_ips, p.created_at, p.updated_at, p.group_type, p.sanitize_html, p.inline_css, p.parent_id, p.recipient_validation, p.sandbox, p.locale, a.depth + 1 FROM groups p
+    JOIN ancestors a ON p.id = a.parent_id
+    WHERE a.depth < 16
+)
+SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
+FROM ancestors
+ORDER BY depth ASC
+`
+
+// Returns the group followed by its parent, grandparent and so on up to the
+// root. Depth is capped to guard against corrupted parent links.
+func (q *Queries) ListGroupAncestors(ctx context.Context, id uuid.UUID) ([]Group, error) {
+	rows, err := q.db.Query(ctx, listGroupAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Group
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Status,
+			&i.MonthlyLimit,
+			&i.MonthlySent,
+			&i.AllowedIps,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.GroupType,
+			&i.SanitizeHtml,
+			&i.InlineCss,
+			&i.ParentID,
+			&i.RecipientValidation,
+			&i.Sandbox,
+			&i.Locale,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupMonthlyUsage = `-- name: ListGroupMonthlyUsage :many
+WITH RECURSIVE effective AS (
+    SELECT id, monthly_limit FROM groups WHERE parent_id IS NULL
+    UNION ALL
+    SELECT c.id, CASE WHEN c.monthly_limit > 0 THEN c.monthly_limit ELSE e.monthly_limit END
+    FROM groups c
+    JOIN effective e ON c.parent_id = e.id
+)
+SELECT g.id, g.name, e.monthly_limit, COUNT(m.id) as sent
+FROM groups g
+JOIN effective e ON e.id = g.id
+LEFT JOIN messages m ON m.group_id = g.id AND m.enqueued_at >= $1
+WHERE g.status = 'active' AND g.group_type <> 'system' AND e.monthly_limit > 0
+GROUP BY g.id, g.name, e.monthly_limit
+`
+
+// monthly_limit is the effective limit: a sub-group without its own limit
+// inherits the limit of its nearest ancestor that has one.
+
+type ListGroupMonthlyUsageRow struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	MonthlyLimit int32     `json:"monthly_limit"`
+	Sent         int64     `json:"sent"`
+}
+
+// monthly_limit is the effective limit: a sub-group without its own limit
+// inherits the limit of its nearest ancestor that has one.
+func (q *Queries) ListGroupMonthlyUsage(ctx context.Context, enqueuedAt pgtype.Timestamptz) ([]ListGroupMonthlyUsageRow, error) {
+	rows, err := q.db.Query(ctx, listGroupMonthlyUsage, enqueuedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListGroupMonthlyUsageRow
+	for rows.Next() {
+		var i ListGroupMonthlyUsageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.MonthlyLimit,
+			&i.Sent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listGroups = `-- name: ListGroups :many
-SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type FROM groups ORDER BY created_at DESC
+SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale FROM groups ORDER BY created_at DESC
 `
 
 func (q *Queries) ListGroups(ctx context.Context) ([]Group, error) {
@@ -124,6 +248,52 @@ func (q *Queries) ListGroups(ctx context.Context) ([]Group, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.GroupType,
+			&i.SanitizeHtml,
+			&i.InlineCss,
+			&i.ParentID,
+			&i.RecipientValidation,
+			&i.Sandbox,
+			&i.Locale,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubGroups = `-- name: ListSubGroups :many
+SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale FROM groups WHERE parent_id = $1 ORDER BY name ASC
+`
+
+func (q *Queries) ListSubGroups(ctx context.Context, parentID pgtype.UUID) ([]Group, error) {
+	rows, err := q.db.Query(ctx, listSubGroups, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Group
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Status,
+			&i.MonthlyLimit,
+			&i.MonthlySent,
+			&i.AllowedIps,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.GroupType,
+			&i.SanitizeHtml,
+			&i.InlineCss,
+			&i.ParentID,
+			&i.RecipientValidation,
+			&i.Sandbox,
+			&i.Locale,
 		); err != nil {
 			return nil, err
 		}
@@ -150,7 +320,7 @@ const updateGroup = `-- name: UpdateGroup :one
 UPDATE groups
 SET name = $2, status = $3, monthly_limit = $4, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
 `
 
 type UpdateGroupParams struct {
@@ -178,6 +348,153 @@ func (q *Queries) UpdateGroup(ctx context.Context, arg UpdateGroupParams) (Group
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
+	)
+	return i, err
+}
+
+const updateGroupHTMLProcessing = `-- name: UpdateGroupHTMLProcessing :one
+UPDATE groups
+SET sanitize_html = $2, inline_css = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
+`
+
+type UpdateGroupHTMLProcessingParams struct {
+	ID           uuid.UUID `json:"id"`
+	SanitizeHtml bool      `json:"sanitize_html"`
+	InlineCss    bool      `json:"inline_css"`
+}
+
+func (q *Queries) UpdateGroupHTMLProcessing(ctx context.Context, arg UpdateGroupHTMLProcessingParams) (Group, error) {
+	row := q.db.QueryRow(ctx, updateGroupHTMLProcessing, arg.ID, arg.SanitizeHtml, arg.InlineCss)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Status,
+		&i.MonthlyLimit,
+		&i.MonthlySent,
+		&i.AllowedIps,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
+	)
+	return i, err
+}
+
+const updateGroupLocale = `-- name: UpdateGroupLocale :one
+UPDATE groups
+SET locale = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
+`
+
+type UpdateGroupLocaleParams struct {
+	ID     uuid.UUID `json:"id"`
+	Locale string    `json:"locale"`
+}
+
+func (q *Queries) UpdateGroupLocale(ctx context.Context, arg UpdateGroupLocaleParams) (Group, error) {
+	row := q.db.QueryRow(ctx, updateGroupLocale, arg.ID, arg.Locale)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Status,
+		&i.MonthlyLimit,
+		&i.MonthlySent,
+		&i.AllowedIps,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
+	)
+	return i, err
+}
+
+const updateGroupRecipientValidation = `-- name: UpdateGroupRecipientValidation :one
+UPDATE groups
+SET recipient_validation = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
+`
+
+type UpdateGroupRecipientValidationParams struct {
+	ID                  uuid.UUID `json:"id"`
+	RecipientValidation string    `json:"recipient_validation"`
+}
+
+func (q *Queries) UpdateGroupRecipientValidation(ctx context.Context, arg UpdateGroupRecipientValidationParams) (Group, error) {
+	row := q.db.QueryRow(ctx, updateGroupRecipientValidation, arg.ID, arg.RecipientValidation)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Status,
+		&i.MonthlyLimit,
+		&i.MonthlySent,
+		&i.AllowedIps,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
+	)
+	return i, err
+}
+
+const updateGroupSandbox = `-- name: UpdateGroupSandbox :one
+UPDATE groups
+SET sandbox = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
+`
+
+type UpdateGroupSandboxParams struct {
+	ID      uuid.UUID `json:"id"`
+	Sandbox bool      `json:"sandbox"`
+}
+
+func (q *Queries) UpdateGroupSandbox(ctx context.Context, arg UpdateGroupSandboxParams) (Group, error) {
+	row := q.db.QueryRow(ctx, updateGroupSandbox, arg.ID, arg.Sandbox)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Status,
+		&i.MonthlyLimit,
+		&i.MonthlySent,
+		&i.AllowedIps,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -186,7 +503,7 @@ const updateGroupStatus = `-- name: UpdateGroupStatus :one
 UPDATE groups
 SET status = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type
+RETURNING id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
 `
 
 type UpdateGroupStatusParams struct {
@@ -207,6 +524,12 @@ func (q *Queries) UpdateGroupStatus(ctx context.Context, arg UpdateGroupStatusPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupType,
+		&i.SanitizeHtml,
+		&i.InlineCss,
+		&i.ParentID,
+		&i.RecipientValidation,
+		&i.Sandbox,
+		&i.Locale,
 	)
 	return i, err
 }
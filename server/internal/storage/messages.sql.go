@@ -13,20 +13,110 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countGroupMessagesByTag = `-- name: CountGroupMessagesByTag :many
+SELECT t.tag::text AS tag, m.status, COUNT(*) AS messages
+FROM messages m, jsonb_array_elements_text(m.tags) AS t(tag)
+WHERE m.group_id = $1 AND m.enqueued_at >= $2 AND m.enqueued_at < $3
+GROUP BY t.tag, m.status
+ORDER BY t.tag, m.status
+`
+
+type CountGroupMessagesByTagParams struct {
+	GroupID      pgtype.UUID        `json:"group_id"`
+	EnqueuedAt   pgtype.Timestamptz `json:"enqueued_at"`
+	EnqueuedAt_2 pgtype.Timestamptz `json:"enqueued_at_2"`
+}
+
+type CountGroupMessagesByTagRow struct {
+	Tag      string        `json:"tag"`
+	Status   MessageStatus `json:"status"`
+	Messages int64         `json:"messages"`
+}
+
+func (q *Queries) CountGroupMessagesByTag(ctx context.Context, arg CountGroupMessagesByTagParams) ([]CountGroupMessagesByTagRow, error) {
+	rows, err := q.db.Query(ctx, countGroupMessagesByTag, arg.GroupID, arg.EnqueuedAt, arg.EnqueuedAt_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountGroupMessagesByTagRow
+	for rows.Next() {
+		var i CountGroupMessagesByTagRow
+		if err := rows.Scan(&i.Tag, &i.Status, &i.Messages); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countGroupMessagesSince = `-- name: CountGroupMessagesSince :one
+SELECT COUNT(*) FROM messages WHERE group_id = $1 AND enqueued_at >= $2
+`
+
+type CountGroupMessagesSinceParams struct {
+	GroupID    pgtype.UUID        `json:"group_id"`
+	EnqueuedAt pgtype.Timestamptz `json:"enqueued_at"`
+}
+
+func (q *Queries) CountGroupMessagesSince(ctx context.Context, arg CountGroupMessagesSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countGroupMessagesSince, arg.GroupID, arg.EnqueuedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countResendableMessages = `-- name: CountResendableMessages :one
+SELECT COUNT(*) FROM messages m
+WHERE m.group_id = $1
+  AND m.status = 'failed'
+  AND m.enqueued_at >= $2 AND m.enqueued_at < $3
+  AND (m.body IS NOT NULL OR m.storage_ref IS NOT NULL)
+  AND NOT EXISTS (
+    SELECT 1 FROM delivery_logs dl
+    WHERE dl.message_id = m.id AND dl.status IN ('bounced', 'complained')
+  )
+`
+
+type CountResendableMessagesParams struct {
+	GroupID pgtype.UUID        `json:"group_id"`
+	Since   pgtype.Timestamptz `json:"since"`
+	Until   pgtype.Timestamptz `json:"until"`
+}
+
+// Counts the failed messages of a group enqueued in [since, until) that
+// never bounced or drew a complaint and still have a body.
+func (q *Queries) CountResendableMessages(ctx context.Context, arg CountResendableMessagesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countResendableMessages, arg.GroupID, arg.Since, arg.Until)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const enqueueMessage = `-- name: EnqueueMessage :one
-INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, body, status)
-VALUES ($1, $2, $3, $4, $5, $6, $7, 'queued')
-RETURNING id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id
+INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, body, size_bytes, status, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'queued', $9, COALESCE($10::jsonb, '[]'), COALESCE($11::jsonb, '{}'), $12, $13, $14)
+RETURNING id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id
 `
 
 type EnqueueMessageParams struct {
-	UserID     pgtype.UUID    `json:"user_id"`
-	GroupID    pgtype.UUID    `json:"group_id"`
-	Sender     string         `json:"sender"`
-	Recipients []byte         `json:"recipients"`
-	Subject    sql.NullString `json:"subject"`
-	Headers    []byte         `json:"headers"`
-	Body       pgtype.Text    `json:"body"`
+	UserID         pgtype.UUID    `json:"user_id"`
+	GroupID        pgtype.UUID    `json:"group_id"`
+	Sender         string         `json:"sender"`
+	Recipients     []byte         `json:"recipients"`
+	Subject        sql.NullString `json:"subject"`
+	Headers        []byte         `json:"headers"`
+	Body           pgtype.Text    `json:"body"`
+	SizeBytes      int64          `json:"size_bytes"`
+	InboundRouteID pgtype.UUID    `json:"inbound_route_id"`
+	Tags           []byte         `json:"tags"`
+	Metadata       []byte         `json:"metadata"`
+	TlsVersion     pgtype.Text    `json:"tls_version"`
+	TlsCipher      pgtype.Text    `json:"tls_cipher"`
+	RequestID      pgtype.Text    `json:"request_id"`
 }
 
 func (q *Queries) EnqueueMessage(ctx context.Context, arg EnqueueMessageParams) (Message, error) {
@@ -38,6 +128,13 @@ func (q *Queries) EnqueueMessage(ctx context.Context, arg EnqueueMessageParams)
 		arg.Subject,
 		arg.Headers,
 		arg.Body,
+		arg.SizeBytes,
+		arg.InboundRouteID,
+		arg.Tags,
+		arg.Metadata,
+		arg.TlsVersion,
+		arg.TlsCipher,
+		arg.RequestID,
 	)
 	var i Message
 	err := row.Scan(
@@ -54,24 +151,39 @@ func (q *Queries) EnqueueMessage(ctx context.Context, arg EnqueueMessageParams)
 		&i.StorageRef,
 		&i.GroupID,
 		&i.UserID,
+		&i.RequeueCount,
+		&i.SizeBytes,
+		&i.InboundRouteID,
+		&i.Tags,
+		&i.Metadata,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.RequestID,
 	)
 	return i, err
 }
 
 const enqueueMessageMetadata = `-- name: EnqueueMessageMetadata :one
-INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, storage_ref, status)
-VALUES ($1, $2, $3, $4, $5, $6, $7, 'queued')
-RETURNING id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id
+INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, storage_ref, size_bytes, status, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'queued', $9, COALESCE($10::jsonb, '[]'), COALESCE($11::jsonb, '{}'), $12, $13, $14)
+RETURNING id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id
 `
 
 type EnqueueMessageMetadataParams struct {
-	UserID     pgtype.UUID    `json:"user_id"`
-	GroupID    pgtype.UUID    `json:"group_id"`
-	Sender     string         `json:"sender"`
-	Recipients []byte         `json:"recipients"`
-	Subject    sql.NullString `json:"subject"`
-	Headers    []byte         `json:"headers"`
-	StorageRef pgtype.Text    `json:"storage_ref"`
+	UserID         pgtype.UUID    `json:"user_id"`
+	GroupID        pgtype.UUID    `json:"group_id"`
+	Sender         string         `json:"sender"`
+	Recipients     []byte         `json:"recipients"`
+	Subject        sql.NullString `json:"subject"`
+	Headers        []byte         `json:"headers"`
+	StorageRef     pgtype.Text    `json:"storage_ref"`
+	SizeBytes      int64          `json:"size_bytes"`
+	InboundRouteID pgtype.UUID    `json:"inbound_route_id"`
+	Tags           []byte         `json:"tags"`
+	Metadata       []byte         `json:"metadata"`
+	TlsVersion     pgtype.Text    `json:"tls_version"`
+	TlsCipher      pgtype.Text    `json:"tls_cipher"`
+	RequestID      pgtype.Text    `json:"request_id"`
 }
 
 func (q *Queries) EnqueueMessageMetadata(ctx context.Context, arg EnqueueMessageMetadataParams) (Message, error) {
@@ -83,7 +195,153 @@ func (q *Queries) EnqueueMessageMetadata(ctx context.Context, arg EnqueueMessage
 		arg.Subject,
 		arg.Headers,
 		arg.StorageRef,
+		arg.SizeBytes,
+		arg.InboundRouteID,
+		arg.Tags,
+		arg.Metadata,
+		arg.TlsVersion,
+		arg.TlsCipher,
+		arg.RequestID,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.Sender,
+		&i.Recipients,
+		&i.Subject,
+		&i.Headers,
+		&i.Body,
+		&i.Status,
+		&i.ProviderID,
+		&i.EnqueuedAt,
+		&i.ProcessedAt,
+		&i.StorageRef,
+		&i.GroupID,
+		&i.UserID,
+		&i.RequeueCount,
+		&i.SizeBytes,
+		&i.InboundRouteID,
+		&i.Tags,
+		&i.Metadata,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.RequestID,
 	)
+	return i, err
+}
+
+const eraseGroupMessages = `-- name: EraseGroupMessages :execrows
+UPDATE messages
+SET sender = '', recipients = '[]', subject = NULL, headers = '{}', body = NULL, storage_ref = NULL, tags = '[]', metadata = '{}'
+WHERE group_id = $1
+`
+
+func (q *Queries) EraseGroupMessages(ctx context.Context, groupID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, eraseGroupMessages, groupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const expireStuckMessage = `-- name: ExpireStuckMessage :execrows
+UPDATE messages
+SET status = 'failed', processed_at = NOW()
+WHERE id = $1 AND status = $2 AND processed_at IS NOT DISTINCT FROM $3
+`
+
+type ExpireStuckMessageParams struct {
+	ID          uuid.UUID          `json:"id"`
+	Status      MessageStatus      `json:"status"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+}
+
+// Fails a stuck message under the same condition as RequeueMessage.
+func (q *Queries) ExpireStuckMessage(ctx context.Context, arg ExpireStuckMessageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, expireStuckMessage, arg.ID, arg.Status, arg.ProcessedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const exportGroupMessages = `-- name: ExportGroupMessages :many
+SELECT id, sender, recipients, subject, headers, status, provider_id, enqueued_at, processed_at, user_id, requeue_count, size_bytes, tags, metadata
+FROM messages
+WHERE group_id = $1
+ORDER BY enqueued_at ASC
+`
+
+type ExportGroupMessagesRow struct {
+	ID           uuid.UUID          `json:"id"`
+	Sender       string             `json:"sender"`
+	Recipients   []byte             `json:"recipients"`
+	Subject      sql.NullString     `json:"subject"`
+	Headers      []byte             `json:"headers"`
+	Status       MessageStatus      `json:"status"`
+	ProviderID   pgtype.UUID        `json:"provider_id"`
+	EnqueuedAt   pgtype.Timestamptz `json:"enqueued_at"`
+	ProcessedAt  pgtype.Timestamptz `json:"processed_at"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	RequeueCount int32              `json:"requeue_count"`
+	SizeBytes    int64              `json:"size_bytes"`
+	Tags         []byte             `json:"tags"`
+	Metadata     []byte             `json:"metadata"`
+}
+
+func (q *Queries) ExportGroupMessages(ctx context.Context, groupID pgtype.UUID) ([]ExportGroupMessagesRow, error) {
+	rows, err := q.db.Query(ctx, exportGroupMessages, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportGroupMessagesRow
+	for rows.Next() {
+		var i ExportGroupMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Sender,
+			&i.Recipients,
+			&i.Subject,
+			&i.Headers,
+			&i.Status,
+			&i.ProviderID,
+			&i.EnqueuedAt,
+			&i.ProcessedAt,
+			&i.UserID,
+			&i.RequeueCount,
+			&i.SizeBytes,
+			&i.Tags,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findMessageByHeaderMessageID = `-- name: FindMessageByHeaderMessageID :one
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages
+WHERE group_id = $1
+  AND (headers -> 'Message-Id') ? $2::text
+  AND ($3::text IS NULL
+       OR lower(recipients::text)::jsonb @> jsonb_build_array(lower($3::text)))
+ORDER BY enqueued_at DESC
+LIMIT 1
+`
+
+type FindMessageByHeaderMessageIDParams struct {
+	GroupID         pgtype.UUID `json:"group_id"`
+	HeaderMessageID string      `json:"header_message_id"`
+	Recipient       pgtype.Text `json:"recipient"`
+}
+
+func (q *Queries) FindMessageByHeaderMessageID(ctx context.Context, arg FindMessageByHeaderMessageIDParams) (Message, error) {
+	row := q.db.QueryRow(ctx, findMessageByHeaderMessageID, arg.GroupID, arg.HeaderMessageID, arg.Recipient)
 	var i Message
 	err := row.Scan(
 		&i.ID,
@@ -99,12 +357,20 @@ func (q *Queries) EnqueueMessageMetadata(ctx context.Context, arg EnqueueMessage
 		&i.StorageRef,
 		&i.GroupID,
 		&i.UserID,
+		&i.RequeueCount,
+		&i.SizeBytes,
+		&i.InboundRouteID,
+		&i.Tags,
+		&i.Metadata,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.RequestID,
 	)
 	return i, err
 }
 
 const getMessageByID = `-- name: GetMessageByID :one
-SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id FROM messages WHERE id = $1
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages WHERE id = $1
 `
 
 func (q *Queries) GetMessageByID(ctx context.Context, id uuid.UUID) (Message, error) {
@@ -124,12 +390,20 @@ func (q *Queries) GetMessageByID(ctx context.Context, id uuid.UUID) (Message, er
 		&i.StorageRef,
 		&i.GroupID,
 		&i.UserID,
+		&i.RequeueCount,
+		&i.SizeBytes,
+		&i.InboundRouteID,
+		&i.Tags,
+		&i.Metadata,
+		&i.TlsVersion,
+		&i.TlsCipher,
+		&i.RequestID,
 	)
 	return i, err
 }
 
 const getQueuedMessages = `-- name: GetQueuedMessages :many
-SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id FROM messages WHERE status = 'queued' ORDER BY enqueued_at ASC LIMIT $1
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages WHERE status = 'queued' ORDER BY enqueued_at ASC LIMIT $1
 `
 
 func (q *Queries) GetQueuedMessages(ctx context.Context, limit int32) ([]Message, error) {
@@ -155,6 +429,145 @@ func (q *Queries) GetQueuedMessages(ctx context.Context, limit int32) ([]Message
 			&i.StorageRef,
 			&i.GroupID,
 			&i.UserID,
+			&i.RequeueCount,
+			&i.SizeBytes,
+			&i.InboundRouteID,
+			&i.Tags,
+			&i.Metadata,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeliveryReportMessages = `-- name: ListDeliveryReportMessages :many
+SELECT id, recipients, status, enqueued_at, processed_at, request_id
+FROM messages
+WHERE group_id = $1
+  AND tags ? $2::text
+  AND enqueued_at >= $3 AND enqueued_at < $4
+  AND (enqueued_at > $5 OR (enqueued_at = $5 AND id > $6))
+ORDER BY enqueued_at ASC, id ASC
+LIMIT $7
+`
+
+type ListDeliveryReportMessagesParams struct {
+	GroupID         pgtype.UUID        `json:"group_id"`
+	Tag             string             `json:"tag"`
+	Since           pgtype.Timestamptz `json:"since"`
+	Until           pgtype.Timestamptz `json:"until"`
+	AfterEnqueuedAt pgtype.Timestamptz `json:"after_enqueued_at"`
+	AfterID         uuid.UUID          `json:"after_id"`
+	MaxResults      int32              `json:"max_results"`
+}
+
+type ListDeliveryReportMessagesRow struct {
+	ID          uuid.UUID          `json:"id"`
+	Recipients  []byte             `json:"recipients"`
+	Status      MessageStatus      `json:"status"`
+	EnqueuedAt  pgtype.Timestamptz `json:"enqueued_at"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+	RequestID   pgtype.Text        `json:"request_id"`
+}
+
+// Pages through a group's messages carrying tag that were enqueued within
+// [since, until), oldest first, after the (after_enqueued_at, after_id)
+// keyset position.
+func (q *Queries) ListDeliveryReportMessages(ctx context.Context, arg ListDeliveryReportMessagesParams) ([]ListDeliveryReportMessagesRow, error) {
+	rows, err := q.db.Query(ctx, listDeliveryReportMessages,
+		arg.GroupID,
+		arg.Tag,
+		arg.Since,
+		arg.Until,
+		arg.AfterEnqueuedAt,
+		arg.AfterID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDeliveryReportMessagesRow
+	for rows.Next() {
+		var i ListDeliveryReportMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Recipients,
+			&i.Status,
+			&i.EnqueuedAt,
+			&i.ProcessedAt,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGroupMessages = `-- name: ListGroupMessages :many
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages
+WHERE group_id = $1
+  AND ($2::text IS NULL OR tags ? $2::text)
+  AND ($3::message_status IS NULL OR status = $3::message_status)
+ORDER BY enqueued_at DESC
+LIMIT $4
+`
+
+type ListGroupMessagesParams struct {
+	GroupID    pgtype.UUID       `json:"group_id"`
+	Tag        pgtype.Text       `json:"tag"`
+	Status     NullMessageStatus `json:"status"`
+	MaxResults int32             `json:"max_results"`
+}
+
+func (q *Queries) ListGroupMessages(ctx context.Context, arg ListGroupMessagesParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, listGroupMessages,
+		arg.GroupID,
+		arg.Tag,
+		arg.Status,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.Sender,
+			&i.Recipients,
+			&i.Subject,
+			&i.Headers,
+			&i.Body,
+			&i.Status,
+			&i.ProviderID,
+			&i.EnqueuedAt,
+			&i.ProcessedAt,
+			&i.StorageRef,
+			&i.GroupID,
+			&i.UserID,
+			&i.RequeueCount,
+			&i.SizeBytes,
+			&i.InboundRouteID,
+			&i.Tags,
+			&i.Metadata,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.RequestID,
 		); err != nil {
 			return nil, err
 		}
@@ -166,8 +579,32 @@ func (q *Queries) GetQueuedMessages(ctx context.Context, limit int32) ([]Message
 	return items, nil
 }
 
+const listGroupMessageStorageRefs = `-- name: ListGroupMessageStorageRefs :many
+SELECT storage_ref FROM messages WHERE group_id = $1 AND storage_ref IS NOT NULL
+`
+
+func (q *Queries) ListGroupMessageStorageRefs(ctx context.Context, groupID pgtype.UUID) ([]pgtype.Text, error) {
+	rows, err := q.db.Query(ctx, listGroupMessageStorageRefs, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Text
+	for rows.Next() {
+		var storage_ref pgtype.Text
+		if err := rows.Scan(&storage_ref); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMessagesByGroupID = `-- name: ListMessagesByGroupID :many
-SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id FROM messages WHERE group_id = $1 ORDER BY enqueued_at DESC LIMIT $2
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages WHERE group_id = $1 ORDER BY enqueued_at DESC LIMIT $2
 `
 
 type ListMessagesByGroupIDParams struct {
@@ -198,6 +635,291 @@ func (q *Queries) ListMessagesByGroupID(ctx context.Context, arg ListMessagesByG
 			&i.StorageRef,
 			&i.GroupID,
 			&i.UserID,
+			&i.RequeueCount,
+			&i.SizeBytes,
+			&i.InboundRouteID,
+			&i.Tags,
+			&i.Metadata,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessagesSentSince = `-- name: ListMessagesSentSince :many
+SELECT id, user_id, group_id, sender, recipients, subject, status, enqueued_at, processed_at, size_bytes, request_id
+FROM messages
+WHERE (user_id = $1 OR group_id = $2)
+  AND enqueued_at >= $3
+ORDER BY enqueued_at DESC
+LIMIT $4
+`
+
+type ListMessagesSentSinceParams struct {
+	UserID     pgtype.UUID        `json:"user_id"`
+	GroupID    pgtype.UUID        `json:"group_id"`
+	Since      pgtype.Timestamptz `json:"since"`
+	MaxResults int32              `json:"max_results"`
+}
+
+type ListMessagesSentSinceRow struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	GroupID     pgtype.UUID        `json:"group_id"`
+	Sender      string             `json:"sender"`
+	Recipients  []byte             `json:"recipients"`
+	Subject     sql.NullString     `json:"subject"`
+	Status      MessageStatus      `json:"status"`
+	EnqueuedAt  pgtype.Timestamptz `json:"enqueued_at"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+	SizeBytes   int64              `json:"size_bytes"`
+	RequestID   pgtype.Text        `json:"request_id"`
+}
+
+func (q *Queries) ListMessagesSentSince(ctx context.Context, arg ListMessagesSentSinceParams) ([]ListMessagesSentSinceRow, error) {
+	rows, err := q.db.Query(ctx, listMessagesSentSince,
+		arg.UserID,
+		arg.GroupID,
+		arg.Since,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessagesSentSinceRow
+	for rows.Next() {
+		var i ListMessagesSentSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GroupID,
+			&i.Sender,
+			&i.Recipients,
+			&i.Subject,
+			&i.Status,
+			&i.EnqueuedAt,
+			&i.ProcessedAt,
+			&i.SizeBytes,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listResendableMessages = `-- name: ListResendableMessages :many
+SELECT m.id, m.group_id, m.user_id, m.request_id FROM messages m
+WHERE m.group_id = $1
+  AND m.status = 'failed'
+  AND m.enqueued_at >= $2 AND m.enqueued_at < $3
+  AND (m.body IS NOT NULL OR m.storage_ref IS NOT NULL)
+  AND NOT EXISTS (
+    SELECT 1 FROM delivery_logs dl
+    WHERE dl.message_id = m.id AND dl.status IN ('bounced', 'complained')
+  )
+ORDER BY m.enqueued_at ASC
+LIMIT $4
+`
+
+type ListResendableMessagesParams struct {
+	GroupID    pgtype.UUID        `json:"group_id"`
+	Since      pgtype.Timestamptz `json:"since"`
+	Until      pgtype.Timestamptz `json:"until"`
+	MaxResults int32              `json:"max_results"`
+}
+
+type ListResendableMessagesRow struct {
+	ID        uuid.UUID   `json:"id"`
+	GroupID   pgtype.UUID `json:"group_id"`
+	UserID    pgtype.UUID `json:"user_id"`
+	RequestID pgtype.Text `json:"request_id"`
+}
+
+// Lists the messages counted by CountResendableMessages, oldest first.
+func (q *Queries) ListResendableMessages(ctx context.Context, arg ListResendableMessagesParams) ([]ListResendableMessagesRow, error) {
+	rows, err := q.db.Query(ctx, listResendableMessages,
+		arg.GroupID,
+		arg.Since,
+		arg.Until,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListResendableMessagesRow
+	for rows.Next() {
+		var i ListResendableMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.UserID,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStuckMessages = `-- name: ListStuckMessages :many
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages
+WHERE (
+    (status = 'queued' AND COALESCE(processed_at, enqueued_at) < $1)
+    OR (status = 'processing' AND processed_at < $2)
+)
+AND NOT EXISTS (SELECT 1 FROM outbox_entries WHERE outbox_entries.message_id = messages.id)
+ORDER BY enqueued_at ASC
+LIMIT $3
+`
+
+type ListStuckMessagesParams struct {
+	QueuedBefore     pgtype.Timestamptz `json:"queued_before"`
+	ProcessingBefore pgtype.Timestamptz `json:"processing_before"`
+	BatchSize        int32              `json:"batch_size"`
+}
+
+func (q *Queries) ListStuckMessages(ctx context.Context, arg ListStuckMessagesParams) ([]Message, error) {
+	rows, err := q.db.Query(ctx, listStuckMessages, arg.QueuedBefore, arg.ProcessingBefore, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.Sender,
+			&i.Recipients,
+			&i.Subject,
+			&i.Headers,
+			&i.Body,
+			&i.Status,
+			&i.ProviderID,
+			&i.EnqueuedAt,
+			&i.ProcessedAt,
+			&i.StorageRef,
+			&i.GroupID,
+			&i.UserID,
+			&i.RequeueCount,
+			&i.SizeBytes,
+			&i.InboundRouteID,
+			&i.Tags,
+			&i.Metadata,
+			&i.TlsVersion,
+			&i.TlsCipher,
+			&i.RequestID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const monthlyMessageUsage = `-- name: MonthlyMessageUsage :many
+SELECT m.group_id, g.name as group_name, COUNT(*) as messages, COALESCE(SUM(m.size_bytes), 0)::bigint as total_bytes
+FROM messages m
+JOIN groups g ON g.id = m.group_id
+WHERE m.enqueued_at >= $1 AND m.enqueued_at < $2
+GROUP BY m.group_id, g.name
+ORDER BY g.name
+`
+
+type MonthlyMessageUsageParams struct {
+	EnqueuedAt   pgtype.Timestamptz `json:"enqueued_at"`
+	EnqueuedAt_2 pgtype.Timestamptz `json:"enqueued_at_2"`
+}
+
+type MonthlyMessageUsageRow struct {
+	GroupID    pgtype.UUID `json:"group_id"`
+	GroupName  string      `json:"group_name"`
+	Messages   int64       `json:"messages"`
+	TotalBytes int64       `json:"total_bytes"`
+}
+
+func (q *Queries) MonthlyMessageUsage(ctx context.Context, arg MonthlyMessageUsageParams) ([]MonthlyMessageUsageRow, error) {
+	rows, err := q.db.Query(ctx, monthlyMessageUsage, arg.EnqueuedAt, arg.EnqueuedAt_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MonthlyMessageUsageRow
+	for rows.Next() {
+		var i MonthlyMessageUsageRow
+		if err := rows.Scan(
+			&i.GroupID,
+			&i.GroupName,
+			&i.Messages,
+			&i.TotalBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const monthlyProviderUsage = `-- name: MonthlyProviderUsage :many
+SELECT m.group_id, dl.provider, COUNT(*) as messages, COALESCE(SUM(m.size_bytes), 0)::bigint as total_bytes
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.status = 'delivered' AND m.group_id IS NOT NULL AND dl.created_at >= $1 AND dl.created_at < $2
+GROUP BY m.group_id, dl.provider
+ORDER BY dl.provider
+`
+
+type MonthlyProviderUsageParams struct {
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	CreatedAt_2 pgtype.Timestamptz `json:"created_at_2"`
+}
+
+type MonthlyProviderUsageRow struct {
+	GroupID    pgtype.UUID    `json:"group_id"`
+	Provider   sql.NullString `json:"provider"`
+	Messages   int64          `json:"messages"`
+	TotalBytes int64          `json:"total_bytes"`
+}
+
+func (q *Queries) MonthlyProviderUsage(ctx context.Context, arg MonthlyProviderUsageParams) ([]MonthlyProviderUsageRow, error) {
+	rows, err := q.db.Query(ctx, monthlyProviderUsage, arg.CreatedAt, arg.CreatedAt_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MonthlyProviderUsageRow
+	for rows.Next() {
+		var i MonthlyProviderUsageRow
+		if err := rows.Scan(
+			&i.GroupID,
+			&i.Provider,
+			&i.Messages,
+			&i.TotalBytes,
 		); err != nil {
 			return nil, err
 		}
@@ -209,6 +931,83 @@ func (q *Queries) ListMessagesByGroupID(ctx context.Context, arg ListMessagesByG
 	return items, nil
 }
 
+const pauseQueuedMessages = `-- name: PauseQueuedMessages :execrows
+UPDATE messages
+SET status = 'paused', processed_at = NOW()
+WHERE status = 'queued'
+  AND (user_id = $1 OR group_id = $2)
+`
+
+type PauseQueuedMessagesParams struct {
+	UserID  pgtype.UUID `json:"user_id"`
+	GroupID pgtype.UUID `json:"group_id"`
+}
+
+func (q *Queries) PauseQueuedMessages(ctx context.Context, arg PauseQueuedMessagesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, pauseQueuedMessages, arg.UserID, arg.GroupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const requeueMessage = `-- name: RequeueMessage :execrows
+UPDATE messages
+SET status = 'queued', processed_at = NOW(), requeue_count = requeue_count + 1
+WHERE id = $1 AND status = $2 AND processed_at IS NOT DISTINCT FROM $3
+`
+
+type RequeueMessageParams struct {
+	ID          uuid.UUID          `json:"id"`
+	Status      MessageStatus      `json:"status"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+}
+
+// Only matches while the message is still in the state the sweeper saw, so
+// a worker that finished it in the meantime is not undone.
+func (q *Queries) RequeueMessage(ctx context.Context, arg RequeueMessageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueMessage, arg.ID, arg.Status, arg.ProcessedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const resendMessage = `-- name: ResendMessage :execrows
+UPDATE messages
+SET status = 'queued', processed_at = NULL, requeue_count = 0
+WHERE id = $1 AND status = 'failed'
+`
+
+// Returns a failed message to the queue for a fresh round of attempts.
+func (q *Queries) ResendMessage(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, resendMessage, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const resumePausedMessages = `-- name: ResumePausedMessages :execrows
+UPDATE messages
+SET status = 'queued', processed_at = NULL
+WHERE status = 'paused'
+  AND (user_id = $1 OR group_id = $2)
+`
+
+type ResumePausedMessagesParams struct {
+	UserID  pgtype.UUID `json:"user_id"`
+	GroupID pgtype.UUID `json:"group_id"`
+}
+
+func (q *Queries) ResumePausedMessages(ctx context.Context, arg ResumePausedMessagesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, resumePausedMessages, arg.UserID, arg.GroupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateMessageStatus = `-- name: UpdateMessageStatus :exec
 UPDATE messages SET status = $2, processed_at = NOW() WHERE id = $1
 `
@@ -70,6 +70,7 @@ const (
 	MessageStatusFailed        MessageStatus = "failed"
 	MessageStatusEnqueueFailed MessageStatus = "enqueue_failed"
 	MessageStatusStorageError  MessageStatus = "storage_error"
+	MessageStatusPaused        MessageStatus = "paused"
 )
 
 func (e *MessageStatus) Scan(src interface{}) error {
@@ -110,11 +111,17 @@ func (ns NullMessageStatus) Value() (driver.Value, error) {
 type ProviderType string
 
 const (
-	ProviderTypeSendgrid ProviderType = "sendgrid"
-	ProviderTypeMailgun  ProviderType = "mailgun"
-	ProviderTypeSes      ProviderType = "ses"
-	ProviderTypeSmtp     ProviderType = "smtp"
-	ProviderTypeMsgraph  ProviderType = "msgraph"
+	ProviderTypeSendgrid  ProviderType = "sendgrid"
+	ProviderTypeMailgun   ProviderType = "mailgun"
+	ProviderTypeSes       ProviderType = "ses"
+	ProviderTypeSmtp      ProviderType = "smtp"
+	ProviderTypeMsgraph   ProviderType = "msgraph"
+	ProviderTypePlugin    ProviderType = "plugin"
+	ProviderTypeGmail     ProviderType = "gmail"
+	ProviderTypeResend    ProviderType = "resend"
+	ProviderTypeBrevo     ProviderType = "brevo"
+	ProviderTypeZeptomail ProviderType = "zeptomail"
+	ProviderTypeEws       ProviderType = "ews"
 )
 
 func (e *ProviderType) Scan(src interface{}) error {
@@ -165,6 +172,23 @@ type ActivityLog struct {
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
 
+type AlertChannel struct {
+	ID          uuid.UUID          `json:"id"`
+	GroupID     uuid.UUID          `json:"group_id"`
+	ChannelType string             `json:"channel_type"`
+	Target      string             `json:"target"`
+	Kinds       []byte             `json:"kinds"`
+	Enabled     bool               `json:"enabled"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type AnalyticsExportCursor struct {
+	Sink          string             `json:"sink"`
+	LastUpdatedAt pgtype.Timestamptz `json:"last_updated_at"`
+	LastID        uuid.UUID          `json:"last_id"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
 type DeliveryLog struct {
 	ID                uuid.UUID          `json:"id"`
 	MessageID         uuid.UUID          `json:"message_id"`
@@ -184,30 +208,88 @@ type DeliveryLog struct {
 	AttemptNumber     int32              `json:"attempt_number"`
 	UserID            pgtype.UUID        `json:"user_id"`
 	GroupID           pgtype.UUID        `json:"group_id"`
+	RequestID         pgtype.Text        `json:"request_id"`
+	EgressIp          pgtype.Text        `json:"egress_ip"`
+	RemoteAddr        pgtype.Text        `json:"remote_addr"`
+	ProviderEndpoint  pgtype.Text        `json:"provider_endpoint"`
+	TlsVersion        pgtype.Text        `json:"tls_version"`
+	TlsCipher         pgtype.Text        `json:"tls_cipher"`
+	ConnectMs         pgtype.Int4        `json:"connect_ms"`
+	TlsHandshakeMs    pgtype.Int4        `json:"tls_handshake_ms"`
+	FirstByteMs       pgtype.Int4        `json:"first_byte_ms"`
+}
+
+type DeliveryLogArchive struct {
+	ID             uuid.UUID          `json:"id"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	ObjectKey      string             `json:"object_key"`
+	FirstCreatedAt pgtype.Timestamptz `json:"first_created_at"`
+	LastCreatedAt  pgtype.Timestamptz `json:"last_created_at"`
+	RowCount       int32              `json:"row_count"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type DeliveryReport struct {
+	ID          uuid.UUID          `json:"id"`
+	GroupID     uuid.UUID          `json:"group_id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	Tag         string             `json:"tag"`
+	Since       pgtype.Timestamptz `json:"since"`
+	Until       pgtype.Timestamptz `json:"until"`
+	Status      string             `json:"status"`
+	ObjectKey   pgtype.Text        `json:"object_key"`
+	RowCount    int32              `json:"row_count"`
+	Truncated   bool               `json:"truncated"`
+	Error       pgtype.Text        `json:"error"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
 type EspProvider struct {
-	ID           uuid.UUID          `json:"id"`
-	Name         string             `json:"name"`
-	ProviderType ProviderType       `json:"provider_type"`
-	ApiKey       sql.NullString     `json:"api_key"`
-	SmtpConfig   []byte             `json:"smtp_config"`
-	Enabled      bool               `json:"enabled"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	GroupID      uuid.UUID          `json:"group_id"`
+	ID                  uuid.UUID          `json:"id"`
+	Name                string             `json:"name"`
+	ProviderType        ProviderType       `json:"provider_type"`
+	ApiKey              sql.NullString     `json:"api_key"`
+	SmtpConfig          []byte             `json:"smtp_config"`
+	Enabled             bool               `json:"enabled"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	GroupID             uuid.UUID          `json:"group_id"`
+	HealthStatus        string             `json:"health_status"`
+	ConsecutiveFailures int32              `json:"consecutive_failures"`
+	LastHealthCheckAt   pgtype.Timestamptz `json:"last_health_check_at"`
+	LastHealthError     pgtype.Text        `json:"last_health_error"`
+	AutoDisabledAt      pgtype.Timestamptz `json:"auto_disabled_at"`
+	CostModel           []byte             `json:"cost_model"`
 }
 
 type Group struct {
-	ID           uuid.UUID          `json:"id"`
-	Name         string             `json:"name"`
-	Status       string             `json:"status"`
-	MonthlyLimit int32              `json:"monthly_limit"`
-	MonthlySent  int32              `json:"monthly_sent"`
-	AllowedIps   []netip.Prefix     `json:"allowed_ips"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	GroupType    string             `json:"group_type"`
+	ID                  uuid.UUID          `json:"id"`
+	Name                string             `json:"name"`
+	Status              string             `json:"status"`
+	MonthlyLimit        int32              `json:"monthly_limit"`
+	MonthlySent         int32              `json:"monthly_sent"`
+	AllowedIps          []netip.Prefix     `json:"allowed_ips"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	GroupType           string             `json:"group_type"`
+	SanitizeHtml        bool               `json:"sanitize_html"`
+	InlineCss           bool               `json:"inline_css"`
+	ParentID            pgtype.UUID        `json:"parent_id"`
+	RecipientValidation string             `json:"recipient_validation"`
+	Sandbox             bool               `json:"sandbox"`
+	Locale              string             `json:"locale"`
+}
+
+type GroupBranding struct {
+	GroupID     uuid.UUID          `json:"group_id"`
+	FromAddress string             `json:"from_address"`
+	LogoUrl     string             `json:"logo_url"`
+	Footer      string             `json:"footer"`
+	Templates   []byte             `json:"templates"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 }
 
 type GroupMember struct {
@@ -218,20 +300,147 @@ type GroupMember struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
+type InboundRoute struct {
+	ID        uuid.UUID          `json:"id"`
+	GroupID   uuid.UUID          `json:"group_id"`
+	Domain    string             `json:"domain"`
+	Url       string             `json:"url"`
+	Format    string             `json:"format"`
+	Secret    pgtype.Text        `json:"secret"`
+	Enabled   bool               `json:"enabled"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Invitation struct {
+	ID         uuid.UUID          `json:"id"`
+	GroupID    uuid.UUID          `json:"group_id"`
+	Email      string             `json:"email"`
+	Role       string             `json:"role"`
+	InvitedBy  pgtype.UUID        `json:"invited_by"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	AcceptedAt pgtype.Timestamptz `json:"accepted_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type LoginNetwork struct {
+	UserID      uuid.UUID          `json:"user_id"`
+	Network     string             `json:"network"`
+	Country     pgtype.Text        `json:"country"`
+	FirstSeenAt pgtype.Timestamptz `json:"first_seen_at"`
+	LastSeenAt  pgtype.Timestamptz `json:"last_seen_at"`
+}
+
 type Message struct {
+	ID             uuid.UUID          `json:"id"`
+	Sender         string             `json:"sender"`
+	Recipients     []byte             `json:"recipients"`
+	Subject        sql.NullString     `json:"subject"`
+	Headers        []byte             `json:"headers"`
+	Body           pgtype.Text        `json:"body"`
+	Status         MessageStatus      `json:"status"`
+	ProviderID     pgtype.UUID        `json:"provider_id"`
+	EnqueuedAt     pgtype.Timestamptz `json:"enqueued_at"`
+	ProcessedAt    pgtype.Timestamptz `json:"processed_at"`
+	StorageRef     pgtype.Text        `json:"storage_ref"`
+	GroupID        pgtype.UUID        `json:"group_id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	RequeueCount   int32              `json:"requeue_count"`
+	SizeBytes      int64              `json:"size_bytes"`
+	InboundRouteID pgtype.UUID        `json:"inbound_route_id"`
+	Tags           []byte             `json:"tags"`
+	Metadata       []byte             `json:"metadata"`
+	TlsVersion     pgtype.Text        `json:"tls_version"`
+	TlsCipher      pgtype.Text        `json:"tls_cipher"`
+	RequestID      pgtype.Text        `json:"request_id"`
+}
+
+type MessageDefault struct {
+	GroupID          uuid.UUID          `json:"group_id"`
+	Headers          []byte             `json:"headers"`
+	FooterText       string             `json:"footer_text"`
+	FooterHtml       string             `json:"footer_html"`
+	ExcludeTags      []byte             `json:"exclude_tags"`
+	ExcludeTemplates []byte             `json:"exclude_templates"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+type MessageScript struct {
+	ID        uuid.UUID          `json:"id"`
+	GroupID   uuid.UUID          `json:"group_id"`
+	Name      string             `json:"name"`
+	Source    string             `json:"source"`
+	Enabled   bool               `json:"enabled"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type OutboxEntry struct {
+	ID           uuid.UUID          `json:"id"`
+	MessageID    uuid.UUID          `json:"message_id"`
+	GroupID      uuid.UUID          `json:"group_id"`
+	UserID       uuid.UUID          `json:"user_id"`
+	Attempts     int32              `json:"attempts"`
+	LastError    pgtype.Text        `json:"last_error"`
+	ClaimedUntil pgtype.Timestamptz `json:"claimed_until"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	RequestID    pgtype.Text        `json:"request_id"`
+}
+
+type ProviderAccountStat struct {
+	ProviderID       uuid.UUID          `json:"provider_id"`
+	Quota            pgtype.Int8        `json:"quota"`
+	QuotaUsed        pgtype.Int8        `json:"quota_used"`
+	BounceRate       pgtype.Float8      `json:"bounce_rate"`
+	ComplaintRate    pgtype.Float8      `json:"complaint_rate"`
+	SuppressionCount pgtype.Int8        `json:"suppression_count"`
+	SuppressionDelta pgtype.Int8        `json:"suppression_delta"`
+	LastError        pgtype.Text        `json:"last_error"`
+	PolledAt         pgtype.Timestamptz `json:"polled_at"`
+	PausedAt         pgtype.Timestamptz `json:"paused_at"`
+	PauseReason      pgtype.Text        `json:"pause_reason"`
+	PauseAction      pgtype.Text        `json:"pause_action"`
+}
+
+type ProviderCapture struct {
+	ID         uuid.UUID          `json:"id"`
+	ProviderID uuid.UUID          `json:"provider_id"`
+	MessageID  pgtype.UUID        `json:"message_id"`
+	Exchanges  []byte             `json:"exchanges"`
+	Error      pgtype.Text        `json:"error"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type ProviderDailySend struct {
+	ProviderID uuid.UUID   `json:"provider_id"`
+	Day        pgtype.Date `json:"day"`
+	Sent       int32       `json:"sent"`
+}
+
+type ProviderHealthCheck struct {
+	ID         uuid.UUID          `json:"id"`
+	ProviderID uuid.UUID          `json:"provider_id"`
+	Healthy    bool               `json:"healthy"`
+	LatencyMs  int32              `json:"latency_ms"`
+	Error      pgtype.Text        `json:"error"`
+	CheckedAt  pgtype.Timestamptz `json:"checked_at"`
+}
+
+type QuotaNotification struct {
+	GroupID   uuid.UUID          `json:"group_id"`
+	Period    pgtype.Date        `json:"period"`
+	Threshold int32              `json:"threshold"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RecipientCertificate struct {
 	ID          uuid.UUID          `json:"id"`
-	Sender      string             `json:"sender"`
-	Recipients  []byte             `json:"recipients"`
-	Subject     sql.NullString     `json:"subject"`
-	Headers     []byte             `json:"headers"`
-	Body        pgtype.Text        `json:"body"`
-	Status      MessageStatus      `json:"status"`
-	ProviderID  pgtype.UUID        `json:"provider_id"`
-	EnqueuedAt  pgtype.Timestamptz `json:"enqueued_at"`
-	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
-	StorageRef  pgtype.Text        `json:"storage_ref"`
-	GroupID     pgtype.UUID        `json:"group_id"`
-	UserID      pgtype.UUID        `json:"user_id"`
+	GroupID     uuid.UUID          `json:"group_id"`
+	Email       string             `json:"email"`
+	Certificate string             `json:"certificate"`
+	Fingerprint string             `json:"fingerprint"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
 type RoutingRule struct {
@@ -245,6 +454,39 @@ type RoutingRule struct {
 	GroupID    uuid.UUID          `json:"group_id"`
 }
 
+type SendWindow struct {
+	GroupID   uuid.UUID          `json:"group_id"`
+	Timezone  string             `json:"timezone"`
+	Windows   []byte             `json:"windows"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type SenderIdentity struct {
+	ID                uuid.UUID          `json:"id"`
+	GroupID           uuid.UUID          `json:"group_id"`
+	Identity          string             `json:"identity"`
+	VerificationToken string             `json:"verification_token"`
+	VerifiedAt        pgtype.Timestamptz `json:"verified_at"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+}
+
+type SenderPolicy struct {
+	GroupID        uuid.UUID          `json:"group_id"`
+	Mode           string             `json:"mode"`
+	RewriteAddress pgtype.Text        `json:"rewrite_address"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+type SendingDomain struct {
+	ID         uuid.UUID          `json:"id"`
+	GroupID    uuid.UUID          `json:"group_id"`
+	Domain     string             `json:"domain"`
+	ReplyTo    pgtype.Text        `json:"reply_to"`
+	ReturnPath pgtype.Text        `json:"return_path"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Session struct {
 	ID               uuid.UUID          `json:"id"`
 	UserID           uuid.UUID          `json:"user_id"`
@@ -252,19 +494,102 @@ type Session struct {
 	RefreshTokenHash string             `json:"refresh_token_hash"`
 	ExpiresAt        pgtype.Timestamptz `json:"expires_at"`
 	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UserAgent        pgtype.Text        `json:"user_agent"`
+	IpAddress        *netip.Addr        `json:"ip_address"`
+	LastUsedAt       pgtype.Timestamptz `json:"last_used_at"`
+}
+
+type ShadowPolicy struct {
+	GroupID   uuid.UUID          `json:"group_id"`
+	Policy    string             `json:"policy"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type ShadowRejection struct {
+	GroupID    uuid.UUID          `json:"group_id"`
+	Policy     string             `json:"policy"`
+	Reason     string             `json:"reason"`
+	Day        pgtype.Date        `json:"day"`
+	Count      int64              `json:"count"`
+	LastSeenAt pgtype.Timestamptz `json:"last_seen_at"`
+}
+
+type SigningKey struct {
+	GroupID     uuid.UUID          `json:"group_id"`
+	Kind        string             `json:"kind"`
+	Certificate pgtype.Text        `json:"certificate"`
+	PrivateKey  string             `json:"private_key"`
+	Fingerprint string             `json:"fingerprint"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	Encrypt     bool               `json:"encrypt"`
+	OnFailure   string             `json:"on_failure"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Signup struct {
+	ID           uuid.UUID          `json:"id"`
+	Email        string             `json:"email"`
+	PasswordHash string             `json:"password_hash"`
+	GroupName    string             `json:"group_name"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type SmtpClientCert struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Fingerprint pgtype.Text        `json:"fingerprint"`
+	San         pgtype.Text        `json:"san"`
+	Description string             `json:"description"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type SmtpDebugTarget struct {
+	ID        uuid.UUID          `json:"id"`
+	GroupID   pgtype.UUID        `json:"group_id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	IpAddress *netip.Addr        `json:"ip_address"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedBy pgtype.UUID        `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type SmtpTranscript struct {
+	ID         uuid.UUID          `json:"id"`
+	TargetID   uuid.UUID          `json:"target_id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	RemoteAddr string             `json:"remote_addr"`
+	Lines      []byte             `json:"lines"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	EndedAt    pgtype.Timestamptz `json:"ended_at"`
+}
+
+type Suppression struct {
+	GroupID   uuid.UUID          `json:"group_id"`
+	Address   string             `json:"address"`
+	Reason    string             `json:"reason"`
+	MessageID pgtype.UUID        `json:"message_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type User struct {
-	ID             uuid.UUID          `json:"id"`
-	Email          string             `json:"email"`
-	PasswordHash   string             `json:"password_hash"`
-	Status         string             `json:"status"`
-	FailedAttempts int32              `json:"failed_attempts"`
-	LastLogin      pgtype.Timestamptz `json:"last_login"`
-	CreatedAt      pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
-	Username       sql.NullString     `json:"username"`
-	AccountType    string             `json:"account_type"`
-	ApiKey         sql.NullString     `json:"api_key"`
-	AllowedDomains []byte             `json:"allowed_domains"`
+	ID                     uuid.UUID          `json:"id"`
+	Email                  string             `json:"email"`
+	PasswordHash           string             `json:"password_hash"`
+	Status                 string             `json:"status"`
+	FailedAttempts         int32              `json:"failed_attempts"`
+	LastLogin              pgtype.Timestamptz `json:"last_login"`
+	CreatedAt              pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt              pgtype.Timestamptz `json:"updated_at"`
+	Username               sql.NullString     `json:"username"`
+	AccountType            string             `json:"account_type"`
+	ApiKey                 sql.NullString     `json:"api_key"`
+	AllowedDomains         []byte             `json:"allowed_domains"`
+	TlsPolicy              []byte             `json:"tls_policy"`
+	AutoBcc                []byte             `json:"auto_bcc"`
+	PasswordChangedAt      pgtype.Timestamptz `json:"password_changed_at"`
+	PasswordExpiryWarnedAt pgtype.Timestamptz `json:"password_expiry_warned_at"`
+	EmailVerifiedAt        pgtype.Timestamptz `json:"email_verified_at"`
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,20 +14,79 @@ type DB struct {
 	Pool *pgxpool.Pool
 }
 
+// TxRunner executes a function against a Querier bound to a single
+// database transaction.
+type TxRunner interface {
+	ExecTx(ctx context.Context, fn func(Querier) error) error
+}
+
+// PoolConfig tunes the connection pool created by NewDB. Zero durations
+// and capacities keep the defaults below.
+type PoolConfig struct {
+	MinConns          int32
+	MaxConns          int32
+	ConnectTimeout    time.Duration
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// QueryExecMode selects how pgx executes queries; see ParseQueryExecMode.
+	// Use "exec" or "simple_protocol" behind a transaction-pooling
+	// PgBouncer, which cannot keep prepared statements.
+	QueryExecMode string
+	// StatementCacheCapacity is the per-connection prepared statement cache
+	// size used by the "cache_statement" mode.
+	StatementCacheCapacity int
+}
+
+// Pool defaults applied by NewDB.
+const (
+	DefaultMaxConnLifetime   = 1 * time.Hour
+	DefaultMaxConnIdleTime   = 30 * time.Minute
+	DefaultHealthCheckPeriod = 1 * time.Minute
+)
+
+// ParseQueryExecMode maps a configuration value to a pgx query execution
+// mode. The empty string selects pgx's default, "cache_statement".
+func ParseQueryExecMode(mode string) (pgx.QueryExecMode, error) {
+	switch mode {
+	case "", "cache_statement":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "cache_describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "describe_exec":
+		return pgx.QueryExecModeDescribeExec, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	default:
+		return 0, fmt.Errorf("unknown query exec mode %q", mode)
+	}
+}
+
 // NewDB creates a new database connection pool and verifies connectivity.
-func NewDB(ctx context.Context, databaseURL string, minConns, maxConns int32, connectTimeout time.Duration) (*DB, error) {
+func NewDB(ctx context.Context, databaseURL string, cfg PoolConfig) (*DB, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse database URL: %w", err)
 	}
 
-	config.MinConns = minConns
-	config.MaxConns = maxConns
-	config.MaxConnLifetime = 1 * time.Hour
-	config.MaxConnIdleTime = 30 * time.Minute
-	config.HealthCheckPeriod = 1 * time.Minute
+	execMode, err := ParseQueryExecMode(cfg.QueryExecMode)
+	if err != nil {
+		return nil, err
+	}
+
+	config.MinConns = cfg.MinConns
+	config.MaxConns = cfg.MaxConns
+	config.MaxConnLifetime = orDefault(cfg.MaxConnLifetime, DefaultMaxConnLifetime)
+	config.MaxConnIdleTime = orDefault(cfg.MaxConnIdleTime, DefaultMaxConnIdleTime)
+	config.HealthCheckPeriod = orDefault(cfg.HealthCheckPeriod, DefaultHealthCheckPeriod)
+	config.ConnConfig.DefaultQueryExecMode = execMode
+	if cfg.StatementCacheCapacity > 0 {
+		config.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
 	defer cancel()
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
@@ -42,6 +102,13 @@ func NewDB(ctx context.Context, databaseURL string, minConns, maxConns int32, co
 	return &DB{Pool: pool}, nil
 }
 
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
 // Close closes all connections in the pool.
 func (db *DB) Close() {
 	db.Pool.Close()
@@ -51,3 +118,22 @@ func (db *DB) Close() {
 func (db *DB) Ping(ctx context.Context) error {
 	return db.Pool.Ping(ctx)
 }
+
+// ExecTx runs fn inside a database transaction. The transaction is committed
+// when fn returns nil and rolled back otherwise.
+func (db *DB) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	tx, err := db.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op once committed
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
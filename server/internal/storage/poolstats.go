@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+)
+
+// PoolStats is a point-in-time snapshot of connection pool statistics.
+// Counters and durations are cumulative since the pool was created.
+type PoolStats struct {
+	AcquiredConns        int32
+	IdleConns            int32
+	TotalConns           int32
+	MaxConns             int32
+	AcquireCount         int64
+	EmptyAcquireCount    int64
+	CanceledAcquireCount int64
+	EmptyAcquireWaitTime time.Duration
+}
+
+// Stats returns a snapshot of the pool statistics.
+func (db *DB) Stats() PoolStats {
+	s := db.Pool.Stat()
+	return PoolStats{
+		AcquiredConns:        s.AcquiredConns(),
+		IdleConns:            s.IdleConns(),
+		TotalConns:           s.TotalConns(),
+		MaxConns:             s.MaxConns(),
+		AcquireCount:         s.AcquireCount(),
+		EmptyAcquireCount:    s.EmptyAcquireCount(),
+		CanceledAcquireCount: s.CanceledAcquireCount(),
+		EmptyAcquireWaitTime: s.EmptyAcquireWaitTime(),
+	}
+}
+
+// DefaultPoolMonitorInterval is the sampling interval used when
+// PoolMonitorConfig.Interval is zero.
+const DefaultPoolMonitorInterval = 5 * time.Second
+
+// PoolMonitorConfig controls how often the pool is sampled and when it is
+// considered saturated.
+type PoolMonitorConfig struct {
+	// Interval is the delay between samples.
+	Interval time.Duration
+	// MaxAcquireWait is the average time acquires may wait for a free
+	// connection during an interval, while every connection is in use,
+	// before the pool is reported saturated. Zero treats any wait as
+	// saturation.
+	MaxAcquireWait time.Duration
+}
+
+// PoolMonitor periodically samples connection pool statistics, exports them
+// as metrics and tracks whether the pool is saturated, so callers can shed
+// load instead of queueing behind the pool.
+type PoolMonitor struct {
+	stats  func() PoolStats
+	config PoolMonitorConfig
+	log    zerolog.Logger
+
+	last      PoolStats
+	saturated atomic.Bool
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewPoolMonitor creates a PoolMonitor that samples stats, typically
+// DB.Stats.
+func NewPoolMonitor(stats func() PoolStats, cfg PoolMonitorConfig, log zerolog.Logger) *PoolMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultPoolMonitorInterval
+	}
+	return &PoolMonitor{
+		stats:  stats,
+		config: cfg,
+		log:    log,
+		last:   stats(),
+	}
+}
+
+// Start launches the sampling loop in a background goroutine.
+func (m *PoolMonitor) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	m.wg.Add(1)
+	go m.run(ctx)
+
+	m.log.Info().
+		Dur("interval", m.config.Interval).
+		Dur("max_acquire_wait", m.config.MaxAcquireWait).
+		Msg("database pool monitor started")
+}
+
+// Stop signals the sampling loop to exit and waits for it to finish.
+func (m *PoolMonitor) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+}
+
+// Saturated reports whether the most recent sample found the pool
+// saturated.
+func (m *PoolMonitor) Saturated() bool {
+	return m.saturated.Load()
+}
+
+func (m *PoolMonitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.SampleOnce()
+		}
+	}
+}
+
+// SampleOnce takes one sample, updates the pool metrics and re-evaluates
+// saturation. The pool is saturated when every connection is acquired and,
+// since the previous sample, an acquire was canceled while waiting or
+// acquires that had to wait did so for MaxAcquireWait on average.
+func (m *PoolMonitor) SampleOnce() bool {
+	cur := m.stats()
+	prev := m.last
+	m.last = cur
+
+	metrics.DBConnectionsActive.Set(float64(cur.AcquiredConns))
+	metrics.DBConnectionsIdle.Set(float64(cur.IdleConns))
+	metrics.DBConnectionsTotal.Set(float64(cur.TotalConns))
+	metrics.DBConnectionsMax.Set(float64(cur.MaxConns))
+	metrics.DBPoolAcquiresTotal.Add(float64(max(cur.AcquireCount-prev.AcquireCount, 0)))
+	metrics.DBPoolEmptyAcquiresTotal.Add(float64(max(cur.EmptyAcquireCount-prev.EmptyAcquireCount, 0)))
+	metrics.DBPoolCanceledAcquiresTotal.Add(float64(max(cur.CanceledAcquireCount-prev.CanceledAcquireCount, 0)))
+	metrics.DBPoolAcquireWaitSecondsTotal.Add(max(cur.EmptyAcquireWaitTime-prev.EmptyAcquireWaitTime, 0).Seconds())
+
+	saturated := false
+	if cur.MaxConns > 0 && cur.AcquiredConns >= cur.MaxConns {
+		waits := cur.EmptyAcquireCount - prev.EmptyAcquireCount
+		waited := cur.EmptyAcquireWaitTime - prev.EmptyAcquireWaitTime
+		switch {
+		case cur.CanceledAcquireCount > prev.CanceledAcquireCount:
+			saturated = true
+		case waits > 0:
+			saturated = waited/time.Duration(waits) >= m.config.MaxAcquireWait
+		}
+	}
+
+	if saturated != m.saturated.Swap(saturated) {
+		if saturated {
+			m.log.Warn().
+				Int32("acquired", cur.AcquiredConns).
+				Int32("max", cur.MaxConns).
+				Msg("database pool saturated")
+		} else {
+			m.log.Info().Msg("database pool no longer saturated")
+		}
+	}
+	if saturated {
+		metrics.DBPoolSaturated.Set(1)
+	} else {
+		metrics.DBPoolSaturated.Set(0)
+	}
+	return saturated
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+func TestParseQueryExecMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want pgx.QueryExecMode
+	}{
+		{"", pgx.QueryExecModeCacheStatement},
+		{"cache_statement", pgx.QueryExecModeCacheStatement},
+		{"cache_describe", pgx.QueryExecModeCacheDescribe},
+		{"describe_exec", pgx.QueryExecModeDescribeExec},
+		{"exec", pgx.QueryExecModeExec},
+		{"simple_protocol", pgx.QueryExecModeSimpleProtocol},
+	}
+	for _, tt := range tests {
+		got, err := ParseQueryExecMode(tt.in)
+		if err != nil {
+			t.Errorf("ParseQueryExecMode(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseQueryExecMode(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ParseQueryExecMode("prepared"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestPoolMonitor_Saturation(t *testing.T) {
+	stats := PoolStats{MaxConns: 4}
+	m := NewPoolMonitor(func() PoolStats { return stats }, PoolMonitorConfig{MaxAcquireWait: 100 * time.Millisecond}, zerolog.Nop())
+
+	// Spare capacity: never saturated, even with waits.
+	stats.AcquiredConns = 2
+	stats.EmptyAcquireCount = 5
+	stats.EmptyAcquireWaitTime = 5 * time.Second
+	if m.SampleOnce() {
+		t.Error("expected pool with idle capacity not to be saturated")
+	}
+
+	// Fully acquired but waits below the threshold.
+	stats.AcquiredConns = 4
+	stats.EmptyAcquireCount += 10
+	stats.EmptyAcquireWaitTime += 10 * 10 * time.Millisecond
+	if m.SampleOnce() {
+		t.Error("expected short waits not to saturate the pool")
+	}
+
+	// Fully acquired and waits averaging above the threshold.
+	stats.EmptyAcquireCount += 2
+	stats.EmptyAcquireWaitTime += 2 * 300 * time.Millisecond
+	if !m.SampleOnce() || !m.Saturated() {
+		t.Error("expected long waits to saturate the pool")
+	}
+
+	// No new waits since the last sample: recovered.
+	if m.SampleOnce() || m.Saturated() {
+		t.Error("expected pool to recover once acquires stop waiting")
+	}
+
+	// A canceled acquire while fully acquired saturates regardless of wait.
+	stats.CanceledAcquireCount++
+	if !m.SampleOnce() {
+		t.Error("expected canceled acquire to saturate the pool")
+	}
+}
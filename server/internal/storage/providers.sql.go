@@ -10,12 +10,35 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const autoDisableProvider = `-- name: AutoDisableProvider :exec
+UPDATE esp_providers
+SET enabled = false, auto_disabled_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND enabled = true
+`
+
+func (q *Queries) AutoDisableProvider(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, autoDisableProvider, id)
+	return err
+}
+
+const autoEnableProvider = `-- name: AutoEnableProvider :exec
+UPDATE esp_providers
+SET enabled = true, auto_disabled_at = NULL, updated_at = NOW()
+WHERE id = $1 AND auto_disabled_at IS NOT NULL
+`
+
+func (q *Queries) AutoEnableProvider(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, autoEnableProvider, id)
+	return err
+}
+
 const createProvider = `-- name: CreateProvider :one
-INSERT INTO esp_providers (group_id, name, provider_type, api_key, smtp_config, enabled)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id
+INSERT INTO esp_providers (group_id, name, provider_type, api_key, smtp_config, enabled, cost_model)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id, health_status, consecutive_failures, last_health_check_at, last_health_error, auto_disabled_at, cost_model
 `
 
 type CreateProviderParams struct {
@@ -25,6 +48,7 @@ type CreateProviderParams struct {
 	ApiKey       sql.NullString `json:"api_key"`
 	SmtpConfig   []byte         `json:"smtp_config"`
 	Enabled      bool           `json:"enabled"`
+	CostModel    []byte         `json:"cost_model"`
 }
 
 func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams) (EspProvider, error) {
@@ -35,6 +59,7 @@ func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams)
 		arg.ApiKey,
 		arg.SmtpConfig,
 		arg.Enabled,
+		arg.CostModel,
 	)
 	var i EspProvider
 	err := row.Scan(
@@ -47,6 +72,12 @@ func (q *Queries) CreateProvider(ctx context.Context, arg CreateProviderParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupID,
+		&i.HealthStatus,
+		&i.ConsecutiveFailures,
+		&i.LastHealthCheckAt,
+		&i.LastHealthError,
+		&i.AutoDisabledAt,
+		&i.CostModel,
 	)
 	return i, err
 }
@@ -61,7 +92,7 @@ func (q *Queries) DeleteProvider(ctx context.Context, id uuid.UUID) error {
 }
 
 const getProviderByID = `-- name: GetProviderByID :one
-SELECT id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id FROM esp_providers WHERE id = $1
+SELECT id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id, health_status, consecutive_failures, last_health_check_at, last_health_error, auto_disabled_at, cost_model FROM esp_providers WHERE id = $1
 `
 
 func (q *Queries) GetProviderByID(ctx context.Context, id uuid.UUID) (EspProvider, error) {
@@ -77,12 +108,58 @@ func (q *Queries) GetProviderByID(ctx context.Context, id uuid.UUID) (EspProvide
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupID,
+		&i.HealthStatus,
+		&i.ConsecutiveFailures,
+		&i.LastHealthCheckAt,
+		&i.LastHealthError,
+		&i.AutoDisabledAt,
+		&i.CostModel,
 	)
 	return i, err
 }
 
+const listEnabledProviders = `-- name: ListEnabledProviders :many
+SELECT id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id, health_status, consecutive_failures, last_health_check_at, last_health_error, auto_disabled_at, cost_model FROM esp_providers WHERE enabled = true ORDER BY created_at
+`
+
+func (q *Queries) ListEnabledProviders(ctx context.Context) ([]EspProvider, error) {
+	rows, err := q.db.Query(ctx, listEnabledProviders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EspProvider
+	for rows.Next() {
+		var i EspProvider
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.ProviderType,
+			&i.ApiKey,
+			&i.SmtpConfig,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.GroupID,
+			&i.HealthStatus,
+			&i.ConsecutiveFailures,
+			&i.LastHealthCheckAt,
+			&i.LastHealthError,
+			&i.AutoDisabledAt,
+			&i.CostModel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProvidersByGroupID = `-- name: ListProvidersByGroupID :many
-SELECT id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id FROM esp_providers WHERE group_id = $1 ORDER BY created_at DESC
+SELECT id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id, health_status, consecutive_failures, last_health_check_at, last_health_error, auto_disabled_at, cost_model FROM esp_providers WHERE group_id = $1 ORDER BY created_at DESC
 `
 
 func (q *Queries) ListProvidersByGroupID(ctx context.Context, groupID uuid.UUID) ([]EspProvider, error) {
@@ -104,6 +181,54 @@ func (q *Queries) ListProvidersByGroupID(ctx context.Context, groupID uuid.UUID)
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.GroupID,
+			&i.HealthStatus,
+			&i.ConsecutiveFailures,
+			&i.LastHealthCheckAt,
+			&i.LastHealthError,
+			&i.AutoDisabledAt,
+			&i.CostModel,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProvidersForHealthCheck = `-- name: ListProvidersForHealthCheck :many
+SELECT id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id, health_status, consecutive_failures, last_health_check_at, last_health_error, auto_disabled_at, cost_model FROM esp_providers
+WHERE enabled = true OR auto_disabled_at IS NOT NULL
+ORDER BY created_at
+`
+
+func (q *Queries) ListProvidersForHealthCheck(ctx context.Context) ([]EspProvider, error) {
+	rows, err := q.db.Query(ctx, listProvidersForHealthCheck)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EspProvider
+	for rows.Next() {
+		var i EspProvider
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.ProviderType,
+			&i.ApiKey,
+			&i.SmtpConfig,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.GroupID,
+			&i.HealthStatus,
+			&i.ConsecutiveFailures,
+			&i.LastHealthCheckAt,
+			&i.LastHealthError,
+			&i.AutoDisabledAt,
+			&i.CostModel,
 		); err != nil {
 			return nil, err
 		}
@@ -117,9 +242,10 @@ func (q *Queries) ListProvidersByGroupID(ctx context.Context, groupID uuid.UUID)
 
 const updateProvider = `-- name: UpdateProvider :one
 UPDATE esp_providers
-SET name = $2, provider_type = $3, api_key = $4, smtp_config = $5, enabled = $6, updated_at = NOW()
+SET name = $2, provider_type = $3, api_key = $4, smtp_config = $5, enabled = $6,
+    cost_model = $7, auto_disabled_at = NULL, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id
+RETURNING id, name, provider_type, api_key, smtp_config, enabled, created_at, updated_at, group_id, health_status, consecutive_failures, last_health_check_at, last_health_error, auto_disabled_at, cost_model
 `
 
 type UpdateProviderParams struct {
@@ -129,6 +255,7 @@ type UpdateProviderParams struct {
 	ApiKey       sql.NullString `json:"api_key"`
 	SmtpConfig   []byte         `json:"smtp_config"`
 	Enabled      bool           `json:"enabled"`
+	CostModel    []byte         `json:"cost_model"`
 }
 
 func (q *Queries) UpdateProvider(ctx context.Context, arg UpdateProviderParams) (EspProvider, error) {
@@ -139,6 +266,7 @@ func (q *Queries) UpdateProvider(ctx context.Context, arg UpdateProviderParams)
 		arg.ApiKey,
 		arg.SmtpConfig,
 		arg.Enabled,
+		arg.CostModel,
 	)
 	var i EspProvider
 	err := row.Scan(
@@ -151,6 +279,38 @@ func (q *Queries) UpdateProvider(ctx context.Context, arg UpdateProviderParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.GroupID,
+		&i.HealthStatus,
+		&i.ConsecutiveFailures,
+		&i.LastHealthCheckAt,
+		&i.LastHealthError,
+		&i.AutoDisabledAt,
+		&i.CostModel,
 	)
 	return i, err
 }
+
+const updateProviderHealth = `-- name: UpdateProviderHealth :exec
+UPDATE esp_providers
+SET health_status = $2,
+    consecutive_failures = $3,
+    last_health_error = $4,
+    last_health_check_at = NOW()
+WHERE id = $1
+`
+
+type UpdateProviderHealthParams struct {
+	ID                  uuid.UUID   `json:"id"`
+	HealthStatus        string      `json:"health_status"`
+	ConsecutiveFailures int32       `json:"consecutive_failures"`
+	LastHealthError     pgtype.Text `json:"last_health_error"`
+}
+
+func (q *Queries) UpdateProviderHealth(ctx context.Context, arg UpdateProviderHealthParams) error {
+	_, err := q.db.Exec(ctx, updateProviderHealth,
+		arg.ID,
+		arg.HealthStatus,
+		arg.ConsecutiveFailures,
+		arg.LastHealthError,
+	)
+	return err
+}
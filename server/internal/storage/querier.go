@@ -9,45 +9,125 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	AcceptInvitation(ctx context.Context, id uuid.UUID) (int64, error)
+	AddShadowPolicy(ctx context.Context, arg AddShadowPolicyParams) error
+	AddSuppression(ctx context.Context, arg AddSuppressionParams) error
+	AutoDisableProvider(ctx context.Context, id uuid.UUID) error
+	AutoEnableProvider(ctx context.Context, id uuid.UUID) error
 	AverageDeliveryDuration(ctx context.Context, arg AverageDeliveryDurationParams) ([]AverageDeliveryDurationRow, error)
+	ClaimDeliveryReport(ctx context.Context, leaseSeconds int32) (DeliveryReport, error)
+	ClaimOutboxEntries(ctx context.Context, arg ClaimOutboxEntriesParams) ([]OutboxEntry, error)
+	CompleteDeliveryReport(ctx context.Context, arg CompleteDeliveryReportParams) error
 	CountDeliveryLogsByGroup(ctx context.Context, arg CountDeliveryLogsByGroupParams) ([]CountDeliveryLogsByGroupRow, error)
 	CountDeliveryLogsByProvider(ctx context.Context, arg CountDeliveryLogsByProviderParams) ([]CountDeliveryLogsByProviderRow, error)
 	CountDeliveryLogsByStatus(ctx context.Context, arg CountDeliveryLogsByStatusParams) ([]CountDeliveryLogsByStatusRow, error)
+	CountGroupMessagesByTag(ctx context.Context, arg CountGroupMessagesByTagParams) ([]CountGroupMessagesByTagRow, error)
+	CountGroupMessagesSince(ctx context.Context, arg CountGroupMessagesSinceParams) (int64, error)
 	CountGroupOwners(ctx context.Context, groupID uuid.UUID) (int64, error)
+	CountOutboxEntries(ctx context.Context) (int64, error)
+	CountResendableMessages(ctx context.Context, arg CountResendableMessagesParams) (int64, error)
 	CreateActivityLog(ctx context.Context, arg CreateActivityLogParams) (ActivityLog, error)
+	CreateAlertChannel(ctx context.Context, arg CreateAlertChannelParams) (AlertChannel, error)
+	CreateDelayedOutboxEntry(ctx context.Context, arg CreateDelayedOutboxEntryParams) (OutboxEntry, error)
 	CreateDeliveryLog(ctx context.Context, arg CreateDeliveryLogParams) (DeliveryLog, error)
+	CreateDeliveryLogArchive(ctx context.Context, arg CreateDeliveryLogArchiveParams) (DeliveryLogArchive, error)
+	CreateDeliveryReport(ctx context.Context, arg CreateDeliveryReportParams) (DeliveryReport, error)
 	CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error)
 	CreateGroupMember(ctx context.Context, arg CreateGroupMemberParams) (GroupMember, error)
+	CreateInboundRoute(ctx context.Context, arg CreateInboundRouteParams) (InboundRoute, error)
+	CreateInvitation(ctx context.Context, arg CreateInvitationParams) (Invitation, error)
+	CreateMessageScript(ctx context.Context, arg CreateMessageScriptParams) (MessageScript, error)
+	CreateOutboxEntry(ctx context.Context, arg CreateOutboxEntryParams) (OutboxEntry, error)
 	CreateProvider(ctx context.Context, arg CreateProviderParams) (EspProvider, error)
+	CreateProviderCapture(ctx context.Context, arg CreateProviderCaptureParams) (ProviderCapture, error)
+	CreateProviderHealthCheck(ctx context.Context, arg CreateProviderHealthCheckParams) (ProviderHealthCheck, error)
 	CreateRoutingRule(ctx context.Context, arg CreateRoutingRuleParams) (RoutingRule, error)
+	CreateSMTPDebugTarget(ctx context.Context, arg CreateSMTPDebugTargetParams) (SmtpDebugTarget, error)
+	CreateSMTPTranscript(ctx context.Context, arg CreateSMTPTranscriptParams) (SmtpTranscript, error)
+	CreateSenderIdentity(ctx context.Context, arg CreateSenderIdentityParams) (SenderIdentity, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateSignup(ctx context.Context, arg CreateSignupParams) (Signup, error)
+	CreateSmtpClientCert(ctx context.Context, arg CreateSmtpClientCertParams) (SmtpClientCert, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DailyDeliveryVolume(ctx context.Context, arg DailyDeliveryVolumeParams) ([]DailyDeliveryVolumeRow, error)
+	DeleteAlertChannel(ctx context.Context, arg DeleteAlertChannelParams) (int64, error)
+	DeleteArchivedDeliveryLogs(ctx context.Context, ids []uuid.UUID) (int64, error)
+	DeleteDeliveryLogArchive(ctx context.Context, id uuid.UUID) error
+	DeleteDeliveryReport(ctx context.Context, id uuid.UUID) error
 	DeleteExpiredSessions(ctx context.Context) error
+	DeleteExpiredSignups(ctx context.Context) error
 	DeleteGroup(ctx context.Context, id uuid.UUID) error
+	DeleteGroupBranding(ctx context.Context, groupID uuid.UUID) (int64, error)
 	DeleteGroupMember(ctx context.Context, id uuid.UUID) error
 	DeleteGroupMembersByUserID(ctx context.Context, userID uuid.UUID) error
+	DeleteGroupProviderCaptures(ctx context.Context, groupID uuid.UUID) (int64, error)
+	DeleteInboundRoute(ctx context.Context, id uuid.UUID) error
+	DeleteInvitation(ctx context.Context, arg DeleteInvitationParams) (int64, error)
+	DeleteMessageDefaults(ctx context.Context, groupID uuid.UUID) (int64, error)
+	DeleteMessageScript(ctx context.Context, arg DeleteMessageScriptParams) (int64, error)
+	DeleteOutboxEntry(ctx context.Context, id uuid.UUID) error
 	DeleteProvider(ctx context.Context, id uuid.UUID) error
+	DeleteProviderCaptures(ctx context.Context, providerID uuid.UUID) (int64, error)
+	DeleteProviderHealthChecksBefore(ctx context.Context, checkedAt pgtype.Timestamptz) error
+	DeleteRecipientCertificate(ctx context.Context, arg DeleteRecipientCertificateParams) (int64, error)
 	DeleteRoutingRule(ctx context.Context, id uuid.UUID) error
+	DeleteSMTPDebugTarget(ctx context.Context, id uuid.UUID) error
+	DeleteSendWindow(ctx context.Context, groupID uuid.UUID) (int64, error)
+	DeleteSenderIdentity(ctx context.Context, arg DeleteSenderIdentityParams) (int64, error)
+	DeleteSendingDomain(ctx context.Context, arg DeleteSendingDomainParams) (int64, error)
 	DeleteSession(ctx context.Context, id uuid.UUID) error
 	DeleteSessionsByUserID(ctx context.Context, userID uuid.UUID) error
+	DeleteShadowPolicies(ctx context.Context, groupID uuid.UUID) error
+	DeleteSigningKey(ctx context.Context, groupID uuid.UUID) (int64, error)
+	DeleteSignup(ctx context.Context, id uuid.UUID) (int64, error)
+	DeleteSmtpClientCert(ctx context.Context, arg DeleteSmtpClientCertParams) (int64, error)
+	DeleteSuppression(ctx context.Context, arg DeleteSuppressionParams) (int64, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserSession(ctx context.Context, arg DeleteUserSessionParams) (int64, error)
+	DeliveryLatencyPercentiles(ctx context.Context, deliveredAt pgtype.Timestamptz) ([]DeliveryLatencyPercentilesRow, error)
+	DeliveryTimeSeries(ctx context.Context, arg DeliveryTimeSeriesParams) ([]DeliveryTimeSeriesRow, error)
 	EnqueueMessage(ctx context.Context, arg EnqueueMessageParams) (Message, error)
 	EnqueueMessageMetadata(ctx context.Context, arg EnqueueMessageMetadataParams) (Message, error)
+	EraseGroupMessages(ctx context.Context, groupID pgtype.UUID) (int64, error)
+	ExpireStuckMessage(ctx context.Context, arg ExpireStuckMessageParams) (int64, error)
+	ExportGroupActivityLogs(ctx context.Context, groupID uuid.UUID) ([]ActivityLog, error)
+	ExportGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) ([]DeliveryLog, error)
+	ExportGroupMessages(ctx context.Context, groupID pgtype.UUID) ([]ExportGroupMessagesRow, error)
+	FailDeliveryReport(ctx context.Context, arg FailDeliveryReportParams) error
+	FindMessageByHeaderMessageID(ctx context.Context, arg FindMessageByHeaderMessageIDParams) (Message, error)
 	GetActivityLogByID(ctx context.Context, id uuid.UUID) (ActivityLog, error)
+	GetAnalyticsExportCursor(ctx context.Context, sink string) (AnalyticsExportCursor, error)
 	GetDeliveryLogByMessageID(ctx context.Context, messageID uuid.UUID) (DeliveryLog, error)
 	GetDeliveryLogByProviderMessageID(ctx context.Context, providerMessageID sql.NullString) (DeliveryLog, error)
+	GetDeliveryReport(ctx context.Context, id uuid.UUID) (DeliveryReport, error)
+	GetGroupBranding(ctx context.Context, groupID uuid.UUID) (GroupBranding, error)
 	GetGroupByID(ctx context.Context, id uuid.UUID) (Group, error)
 	GetGroupByName(ctx context.Context, name string) (Group, error)
 	GetGroupMemberByID(ctx context.Context, id uuid.UUID) (GroupMember, error)
 	GetGroupMemberByUserAndGroup(ctx context.Context, arg GetGroupMemberByUserAndGroupParams) (GroupMember, error)
+	GetInboundRouteByDomain(ctx context.Context, domain string) (InboundRoute, error)
+	GetInboundRouteByID(ctx context.Context, id uuid.UUID) (InboundRoute, error)
+	GetInvitationByID(ctx context.Context, id uuid.UUID) (Invitation, error)
 	GetMessageByID(ctx context.Context, id uuid.UUID) (Message, error)
+	GetMessageDefaults(ctx context.Context, groupID uuid.UUID) (MessageDefault, error)
+	GetMessageScript(ctx context.Context, arg GetMessageScriptParams) (MessageScript, error)
 	GetProviderByID(ctx context.Context, id uuid.UUID) (EspProvider, error)
 	GetQueuedMessages(ctx context.Context, limit int32) ([]Message, error)
 	GetRoutingRuleByID(ctx context.Context, id uuid.UUID) (RoutingRule, error)
+	GetSMTPDebugTarget(ctx context.Context, id uuid.UUID) (SmtpDebugTarget, error)
+	GetSenderIdentity(ctx context.Context, arg GetSenderIdentityParams) (SenderIdentity, error)
+	GetSendWindow(ctx context.Context, groupID uuid.UUID) (SendWindow, error)
+	GetSenderPolicy(ctx context.Context, groupID uuid.UUID) (SenderPolicy, error)
+	GetSendingDomain(ctx context.Context, arg GetSendingDomainParams) (SendingDomain, error)
 	GetSessionByID(ctx context.Context, id uuid.UUID) (Session, error)
+	GetSigningKey(ctx context.Context, groupID uuid.UUID) (SigningKey, error)
+	GetSignupByID(ctx context.Context, id uuid.UUID) (Signup, error)
+	GetSmtpClientCertByFingerprint(ctx context.Context, fingerprint pgtype.Text) (SmtpClientCert, error)
+	GetSmtpClientCertBySAN(ctx context.Context, sans []string) (SmtpClientCert, error)
 	GetUserByAPIKey(ctx context.Context, apiKey sql.NullString) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
@@ -55,32 +135,117 @@ type Querier interface {
 	IncrementFailedAttempts(ctx context.Context, id uuid.UUID) error
 	IncrementMonthlySent(ctx context.Context, id uuid.UUID) error
 	IncrementRetryCount(ctx context.Context, arg IncrementRetryCountParams) error
+	IsRecipientSuppressed(ctx context.Context, arg IsRecipientSuppressedParams) (bool, error)
+	ListActiveSMTPDebugTargets(ctx context.Context) ([]SmtpDebugTarget, error)
 	ListActivityLogsByActorID(ctx context.Context, arg ListActivityLogsByActorIDParams) ([]ActivityLog, error)
 	ListActivityLogsByGroupID(ctx context.Context, arg ListActivityLogsByGroupIDParams) ([]ActivityLog, error)
 	ListActivityLogsByResource(ctx context.Context, arg ListActivityLogsByResourceParams) ([]ActivityLog, error)
+	ListAlertChannelsByGroupID(ctx context.Context, groupID uuid.UUID) ([]AlertChannel, error)
 	ListDeliveryLogsByGroupAndStatus(ctx context.Context, arg ListDeliveryLogsByGroupAndStatusParams) ([]DeliveryLog, error)
 	ListDeliveryLogsByMessageID(ctx context.Context, messageID uuid.UUID) ([]DeliveryLog, error)
+	ListDeliveryLogsByMessageIDs(ctx context.Context, messageIds []uuid.UUID) ([]DeliveryLog, error)
+	ListDeliveryLogsForExport(ctx context.Context, arg ListDeliveryLogsForExportParams) ([]DeliveryLog, error)
+	ListDeliveryLogsToArchive(ctx context.Context, arg ListDeliveryLogsToArchiveParams) ([]DeliveryLog, error)
+	ListDeliveryReportMessages(ctx context.Context, arg ListDeliveryReportMessagesParams) ([]ListDeliveryReportMessagesRow, error)
+	ListEnabledAlertChannelsByGroupID(ctx context.Context, groupID uuid.UUID) ([]AlertChannel, error)
+	ListEnabledMessageScriptsByGroupID(ctx context.Context, groupID uuid.UUID) ([]MessageScript, error)
+	ListEnabledProviders(ctx context.Context) ([]EspProvider, error)
+	ListExpiredDeliveryReports(ctx context.Context, arg ListExpiredDeliveryReportsParams) ([]DeliveryReport, error)
+	ListExpiringSMTPPasswords(ctx context.Context, passwordChangedAt pgtype.Timestamptz) ([]ListExpiringSMTPPasswordsRow, error)
+	ListGroupAncestors(ctx context.Context, id uuid.UUID) ([]Group, error)
+	ListGroupDeliveryLogArchives(ctx context.Context, arg ListGroupDeliveryLogArchivesParams) ([]DeliveryLogArchive, error)
+	ListGroupDeliveryLogs(ctx context.Context, arg ListGroupDeliveryLogsParams) ([]DeliveryLog, error)
+	ListGroupDeliveryReports(ctx context.Context, arg ListGroupDeliveryReportsParams) ([]DeliveryReport, error)
 	ListGroupMembersByGroupID(ctx context.Context, groupID uuid.UUID) ([]GroupMember, error)
+	ListGroupMessageStorageRefs(ctx context.Context, groupID pgtype.UUID) ([]pgtype.Text, error)
+	ListGroupMessages(ctx context.Context, arg ListGroupMessagesParams) ([]Message, error)
+	ListGroupMonthlyUsage(ctx context.Context, enqueuedAt pgtype.Timestamptz) ([]ListGroupMonthlyUsageRow, error)
+	ListGroupOwnerEmails(ctx context.Context, groupID uuid.UUID) ([]string, error)
 	ListGroups(ctx context.Context) ([]Group, error)
 	ListGroupsByUserID(ctx context.Context, userID uuid.UUID) ([]Group, error)
+	ListInboundRoutesByGroupID(ctx context.Context, groupID uuid.UUID) ([]InboundRoute, error)
+	ListLoginNetworks(ctx context.Context, userID uuid.UUID) ([]LoginNetwork, error)
+	ListMessageScriptsByGroupID(ctx context.Context, groupID uuid.UUID) ([]MessageScript, error)
 	ListMessagesByGroupID(ctx context.Context, arg ListMessagesByGroupIDParams) ([]Message, error)
+	ListMessagesSentSince(ctx context.Context, arg ListMessagesSentSinceParams) ([]ListMessagesSentSinceRow, error)
+	ListPendingInvitationsByGroupID(ctx context.Context, groupID uuid.UUID) ([]Invitation, error)
+	ListProviderAccountStatsByGroupID(ctx context.Context, groupID uuid.UUID) ([]ProviderAccountStat, error)
+	ListProviderCaptures(ctx context.Context, providerID uuid.UUID) ([]ProviderCapture, error)
+	ListProviderDailySendsByGroupID(ctx context.Context, arg ListProviderDailySendsByGroupIDParams) ([]ProviderDailySend, error)
+	ListProviderHealthChecks(ctx context.Context, arg ListProviderHealthChecksParams) ([]ProviderHealthCheck, error)
 	ListProvidersByGroupID(ctx context.Context, groupID uuid.UUID) ([]EspProvider, error)
+	ListProvidersForHealthCheck(ctx context.Context) ([]EspProvider, error)
+	ListRecipientCertificatesByGroupID(ctx context.Context, groupID uuid.UUID) ([]RecipientCertificate, error)
+	ListRecipientDeliveryLogs(ctx context.Context, arg ListRecipientDeliveryLogsParams) ([]ListRecipientDeliveryLogsRow, error)
+	ListResendableMessages(ctx context.Context, arg ListResendableMessagesParams) ([]ListResendableMessagesRow, error)
 	ListRoutingRulesByGroupID(ctx context.Context, groupID uuid.UUID) ([]RoutingRule, error)
+	ListSMTPDebugTargets(ctx context.Context) ([]SmtpDebugTarget, error)
+	ListSMTPDebugTargetsByGroupID(ctx context.Context, groupID pgtype.UUID) ([]SmtpDebugTarget, error)
+	ListSMTPTranscriptsByTarget(ctx context.Context, targetID uuid.UUID) ([]SmtpTranscript, error)
+	ListSenderIdentitiesByGroupID(ctx context.Context, groupID uuid.UUID) ([]SenderIdentity, error)
+	ListSendingDomainsByGroupID(ctx context.Context, groupID uuid.UUID) ([]SendingDomain, error)
 	ListSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	ListShadowPolicies(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	ListSmtpClientCertsByUserID(ctx context.Context, userID uuid.UUID) ([]SmtpClientCert, error)
+	ListStuckMessages(ctx context.Context, arg ListStuckMessagesParams) ([]Message, error)
+	ListSubGroups(ctx context.Context, parentID pgtype.UUID) ([]Group, error)
+	ListSuppressions(ctx context.Context, arg ListSuppressionsParams) ([]Suppression, error)
 	ListUsers(ctx context.Context) ([]User, error)
+	ListVerifiedGroupMemberEmails(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	ListVerifiedSenderIdentities(ctx context.Context, groupID uuid.UUID) ([]string, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	MarkPasswordExpiryWarned(ctx context.Context, id uuid.UUID) error
+	MarkSenderIdentityVerified(ctx context.Context, arg MarkSenderIdentityVerifiedParams) (SenderIdentity, error)
+	MonthlyMessageUsage(ctx context.Context, arg MonthlyMessageUsageParams) ([]MonthlyMessageUsageRow, error)
+	MonthlyProviderUsage(ctx context.Context, arg MonthlyProviderUsageParams) ([]MonthlyProviderUsageRow, error)
+	PauseQueuedMessages(ctx context.Context, arg PauseQueuedMessagesParams) (int64, error)
+	PruneProviderCaptures(ctx context.Context, arg PruneProviderCapturesParams) error
+	RecordLoginNetwork(ctx context.Context, arg RecordLoginNetworkParams) error
+	RecordOutboxEntryFailure(ctx context.Context, arg RecordOutboxEntryFailureParams) error
+	RecordProviderAccountStatsError(ctx context.Context, arg RecordProviderAccountStatsErrorParams) error
+	RecordQuotaNotification(ctx context.Context, arg RecordQuotaNotificationParams) (int64, error)
+	RecordShadowRejection(ctx context.Context, arg RecordShadowRejectionParams) error
+	RequeueMessage(ctx context.Context, arg RequeueMessageParams) (int64, error)
+	ReserveProviderDailySend(ctx context.Context, arg ReserveProviderDailySendParams) (int32, error)
+	ResendMessage(ctx context.Context, id uuid.UUID) (int64, error)
 	ResetFailedAttempts(ctx context.Context, id uuid.UUID) error
 	ResetMonthlySent(ctx context.Context, id uuid.UUID) error
+	ResumePausedMessages(ctx context.Context, arg ResumePausedMessagesParams) (int64, error)
+	RevokeUserAPIKey(ctx context.Context, id uuid.UUID) (int64, error)
+	ScrubGroupDeliveryLogs(ctx context.Context, groupID pgtype.UUID) (int64, error)
+	SummarizeShadowRejections(ctx context.Context, arg SummarizeShadowRejectionsParams) ([]SummarizeShadowRejectionsRow, error)
+	TouchSession(ctx context.Context, arg TouchSessionParams) error
+	TouchSmtpClientCert(ctx context.Context, id uuid.UUID) error
 	UpdateDeliveryLogStatus(ctx context.Context, arg UpdateDeliveryLogStatusParams) error
 	UpdateGroup(ctx context.Context, arg UpdateGroupParams) (Group, error)
+	UpdateGroupHTMLProcessing(ctx context.Context, arg UpdateGroupHTMLProcessingParams) (Group, error)
+	UpdateGroupLocale(ctx context.Context, arg UpdateGroupLocaleParams) (Group, error)
 	UpdateGroupMemberRole(ctx context.Context, arg UpdateGroupMemberRoleParams) (GroupMember, error)
+	UpdateGroupRecipientValidation(ctx context.Context, arg UpdateGroupRecipientValidationParams) (Group, error)
+	UpdateGroupSandbox(ctx context.Context, arg UpdateGroupSandboxParams) (Group, error)
 	UpdateGroupStatus(ctx context.Context, arg UpdateGroupStatusParams) (Group, error)
+	UpdateInboundRoute(ctx context.Context, arg UpdateInboundRouteParams) (InboundRoute, error)
+	UpdateMessageScript(ctx context.Context, arg UpdateMessageScriptParams) (MessageScript, error)
 	UpdateMessageStatus(ctx context.Context, arg UpdateMessageStatusParams) error
 	UpdateProvider(ctx context.Context, arg UpdateProviderParams) (EspProvider, error)
+	UpdateProviderHealth(ctx context.Context, arg UpdateProviderHealthParams) error
+	UpdateProviderReputationPause(ctx context.Context, arg UpdateProviderReputationPauseParams) error
 	UpdateRoutingRule(ctx context.Context, arg UpdateRoutingRuleParams) (RoutingRule, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserAutoBCC(ctx context.Context, arg UpdateUserAutoBCCParams) (User, error)
 	UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	UpdateUserStatus(ctx context.Context, arg UpdateUserStatusParams) (User, error)
+	UpdateUserTLSPolicy(ctx context.Context, arg UpdateUserTLSPolicyParams) (User, error)
+	UpsertAnalyticsExportCursor(ctx context.Context, arg UpsertAnalyticsExportCursorParams) error
+	UpsertGroupBranding(ctx context.Context, arg UpsertGroupBrandingParams) (GroupBranding, error)
+	UpsertMessageDefaults(ctx context.Context, arg UpsertMessageDefaultsParams) (MessageDefault, error)
+	UpsertProviderAccountStats(ctx context.Context, arg UpsertProviderAccountStatsParams) (ProviderAccountStat, error)
+	UpsertRecipientCertificate(ctx context.Context, arg UpsertRecipientCertificateParams) (RecipientCertificate, error)
+	UpsertSendWindow(ctx context.Context, arg UpsertSendWindowParams) (SendWindow, error)
+	UpsertSenderPolicy(ctx context.Context, arg UpsertSenderPolicyParams) (SenderPolicy, error)
+	UpsertSendingDomain(ctx context.Context, arg UpsertSendingDomainParams) (SendingDomain, error)
+	UpsertSigningKey(ctx context.Context, arg UpsertSigningKeyParams) (SigningKey, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -23,3 +23,6 @@ SELECT * FROM activity_logs
 WHERE resource_type = $1 AND resource_id = $2
 ORDER BY created_at DESC
 LIMIT $3 OFFSET $4;
+
+-- name: ExportGroupActivityLogs :many
+SELECT * FROM activity_logs WHERE group_id = $1 ORDER BY created_at ASC;
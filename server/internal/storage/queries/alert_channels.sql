@@ -0,0 +1,13 @@
+-- name: CreateAlertChannel :one
+INSERT INTO alert_channels (group_id, channel_type, target, kinds, enabled)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListAlertChannelsByGroupID :many
+SELECT * FROM alert_channels WHERE group_id = $1 ORDER BY created_at;
+
+-- name: ListEnabledAlertChannelsByGroupID :many
+SELECT * FROM alert_channels WHERE group_id = $1 AND enabled = true ORDER BY created_at;
+
+-- name: DeleteAlertChannel :execrows
+DELETE FROM alert_channels WHERE id = $1 AND group_id = $2;
@@ -0,0 +1,17 @@
+-- name: GetAnalyticsExportCursor :one
+SELECT * FROM analytics_export_cursors WHERE sink = $1;
+
+-- name: UpsertAnalyticsExportCursor :exec
+INSERT INTO analytics_export_cursors (sink, last_updated_at, last_id, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (sink) DO UPDATE
+SET last_updated_at = EXCLUDED.last_updated_at,
+    last_id = EXCLUDED.last_id,
+    updated_at = NOW();
+
+-- name: ListDeliveryLogsForExport :many
+SELECT * FROM delivery_logs
+WHERE (updated_at, id) > (sqlc.arg(after_updated_at)::timestamptz, sqlc.arg(after_id)::uuid)
+  AND updated_at < sqlc.arg(before)::timestamptz
+ORDER BY updated_at, id
+LIMIT sqlc.arg(max_results);
@@ -0,0 +1,23 @@
+-- name: ListDeliveryLogsToArchive :many
+SELECT * FROM delivery_logs
+WHERE created_at < $1
+ORDER BY created_at ASC
+LIMIT $2;
+
+-- name: DeleteArchivedDeliveryLogs :execrows
+DELETE FROM delivery_logs WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: CreateDeliveryLogArchive :one
+INSERT INTO delivery_log_archives (group_id, object_key, first_created_at, last_created_at, row_count)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListGroupDeliveryLogArchives :many
+SELECT * FROM delivery_log_archives
+WHERE group_id = sqlc.arg(group_id)
+  AND (sqlc.narg(since)::timestamptz IS NULL OR last_created_at >= sqlc.narg(since)::timestamptz)
+  AND (sqlc.narg(until)::timestamptz IS NULL OR first_created_at < sqlc.narg(until)::timestamptz)
+ORDER BY last_created_at DESC;
+
+-- name: DeleteDeliveryLogArchive :exec
+DELETE FROM delivery_log_archives WHERE id = $1;
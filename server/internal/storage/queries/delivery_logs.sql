@@ -3,9 +3,11 @@ INSERT INTO delivery_logs (
     message_id, provider_id, group_id, user_id, status, provider,
     provider_message_id, response_code, response_body,
     retry_count, last_error, metadata,
-    duration_ms, attempt_number
+    duration_ms, attempt_number, request_id,
+    egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher,
+    connect_ms, tls_handshake_ms, first_byte_ms
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
 RETURNING *;
 
 -- name: GetDeliveryLogByMessageID :one
@@ -17,12 +19,38 @@ SELECT * FROM delivery_logs WHERE provider_message_id = $1;
 -- name: ListDeliveryLogsByMessageID :many
 SELECT * FROM delivery_logs WHERE message_id = $1 ORDER BY delivered_at DESC;
 
+-- name: ListDeliveryLogsByMessageIDs :many
+SELECT * FROM delivery_logs
+WHERE message_id = ANY(sqlc.arg(message_ids)::uuid[])
+ORDER BY created_at ASC;
+
 -- name: ListDeliveryLogsByGroupAndStatus :many
 SELECT * FROM delivery_logs
 WHERE group_id = $1 AND status = $2
 ORDER BY created_at DESC
 LIMIT $3 OFFSET $4;
 
+-- name: ListGroupDeliveryLogs :many
+SELECT * FROM delivery_logs
+WHERE group_id = sqlc.arg(group_id)
+  AND (sqlc.narg(egress_ip)::text IS NULL OR egress_ip = sqlc.narg(egress_ip)::text)
+  AND (sqlc.narg(provider)::text IS NULL OR provider = sqlc.narg(provider)::text)
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since)::timestamptz)
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until)::timestamptz)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: ListRecipientDeliveryLogs :many
+SELECT sqlc.embed(dl), m.sender, m.subject
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE m.group_id = sqlc.arg(group_id)
+  AND lower(m.recipients::text)::jsonb @> jsonb_build_array(lower(sqlc.arg(recipient)::text))
+  AND (sqlc.narg(since)::timestamptz IS NULL OR dl.created_at >= sqlc.narg(since)::timestamptz)
+  AND (sqlc.narg(until)::timestamptz IS NULL OR dl.created_at < sqlc.narg(until)::timestamptz)
+ORDER BY dl.created_at DESC
+LIMIT sqlc.arg(max_results);
+
 -- name: UpdateDeliveryLogStatus :exec
 UPDATE delivery_logs
 SET status = $2,
@@ -61,3 +89,56 @@ SELECT provider, AVG(duration_ms)::integer as avg_duration_ms, COUNT(*) as count
 FROM delivery_logs
 WHERE duration_ms IS NOT NULL AND created_at >= $1 AND created_at <= $2
 GROUP BY provider;
+
+-- name: DeliveryLatencyPercentiles :many
+SELECT dl.group_id, dl.provider, COUNT(*) as deliveries,
+    percentile_cont(0.50) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at))::float8 as p50_seconds,
+    percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at))::float8 as p95_seconds,
+    percentile_cont(0.99) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at))::float8 as p99_seconds
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.status = 'delivered' AND dl.group_id IS NOT NULL AND dl.delivered_at >= $1
+GROUP BY dl.group_id, dl.provider;
+
+-- name: DailyDeliveryVolume :many
+SELECT provider_id, group_id, date_trunc('day', created_at)::date as day, COUNT(*) as count
+FROM delivery_logs
+WHERE status = 'delivered' AND provider_id IS NOT NULL AND created_at >= $1 AND created_at < $2
+GROUP BY provider_id, group_id, day
+ORDER BY day;
+
+-- name: DeliveryTimeSeries :many
+-- Buckets a group's delivery attempts by created_at. Webhooks record
+-- deliveries confirmed by the ESP as 'sent'.
+SELECT to_timestamp(floor(EXTRACT(EPOCH FROM dl.created_at) / sqlc.arg(bucket_seconds)::float8) * sqlc.arg(bucket_seconds)::float8)::timestamptz AS bucket,
+    COUNT(*) AS attempts,
+    COUNT(*) FILTER (WHERE dl.status IN ('delivered', 'sent')) AS delivered,
+    COUNT(*) FILTER (WHERE dl.status = 'bounced') AS bounced,
+    COUNT(*) FILTER (WHERE dl.status = 'complained') AS complained,
+    COUNT(*) FILTER (WHERE dl.status = 'failed') AS failed,
+    COALESCE(AVG(dl.duration_ms), 0)::float8 AS avg_duration_ms,
+    COALESCE(percentile_cont(0.50) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at)) FILTER (WHERE dl.status IN ('delivered', 'sent')), 0)::float8 AS p50_seconds,
+    COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM dl.delivered_at - m.enqueued_at)) FILTER (WHERE dl.status IN ('delivered', 'sent')), 0)::float8 AS p95_seconds
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.group_id = sqlc.arg(group_id)
+  AND dl.created_at >= sqlc.arg(since) AND dl.created_at < sqlc.arg(until)
+  AND (sqlc.narg(provider)::text IS NULL OR dl.provider = sqlc.narg(provider)::text)
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: ExportGroupDeliveryLogs :many
+SELECT dl.* FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE m.group_id = $1
+ORDER BY dl.created_at ASC;
+
+-- name: ScrubGroupDeliveryLogs :execrows
+-- Replaces email addresses in ESP responses, errors and metadata.
+UPDATE delivery_logs dl
+SET response_body = regexp_replace(dl.response_body, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    last_error = regexp_replace(dl.last_error, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    metadata = regexp_replace(dl.metadata::text, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g')::jsonb,
+    updated_at = NOW()
+FROM messages m
+WHERE m.id = dl.message_id AND m.group_id = $1;
@@ -0,0 +1,47 @@
+-- name: CreateDeliveryReport :one
+INSERT INTO delivery_reports (group_id, user_id, tag, since, until)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetDeliveryReport :one
+SELECT * FROM delivery_reports WHERE id = $1;
+
+-- name: ListGroupDeliveryReports :many
+SELECT * FROM delivery_reports
+WHERE group_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimDeliveryReport :one
+-- Claims the oldest pending report, or a running report whose generator
+-- has not finished within lease_seconds.
+UPDATE delivery_reports
+SET status = 'running', started_at = NOW()
+WHERE id = (
+    SELECT id FROM delivery_reports
+    WHERE status = 'pending'
+       OR (status = 'running' AND started_at < NOW() - make_interval(secs => sqlc.arg(lease_seconds)::int))
+    ORDER BY created_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDeliveryReport :exec
+UPDATE delivery_reports
+SET status = 'ready', object_key = $2, row_count = $3, truncated = $4, completed_at = NOW()
+WHERE id = $1;
+
+-- name: FailDeliveryReport :exec
+UPDATE delivery_reports
+SET status = 'failed', error = $2, completed_at = NOW()
+WHERE id = $1;
+
+-- name: ListExpiredDeliveryReports :many
+SELECT * FROM delivery_reports
+WHERE completed_at < $1
+ORDER BY completed_at ASC
+LIMIT $2;
+
+-- name: DeleteDeliveryReport :exec
+DELETE FROM delivery_reports WHERE id = $1;
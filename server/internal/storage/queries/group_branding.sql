@@ -0,0 +1,16 @@
+-- name: GetGroupBranding :one
+SELECT * FROM group_branding WHERE group_id = $1;
+
+-- name: UpsertGroupBranding :one
+INSERT INTO group_branding (group_id, from_address, logo_url, footer, templates)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (group_id) DO UPDATE
+SET from_address = EXCLUDED.from_address,
+    logo_url = EXCLUDED.logo_url,
+    footer = EXCLUDED.footer,
+    templates = EXCLUDED.templates,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteGroupBranding :execrows
+DELETE FROM group_branding WHERE group_id = $1;
@@ -32,3 +32,15 @@ DELETE FROM group_members WHERE user_id = $1;
 
 -- name: CountGroupOwners :one
 SELECT count(*) FROM group_members WHERE group_id = $1 AND role = 'owner';
+
+-- name: ListGroupOwnerEmails :many
+SELECT u.email FROM group_members gm
+JOIN users u ON u.id = gm.user_id
+WHERE gm.group_id = $1 AND gm.role = 'owner' AND u.status = 'active'
+ORDER BY u.email;
+
+-- name: ListVerifiedGroupMemberEmails :many
+SELECT u.email FROM group_members gm
+JOIN users u ON u.id = gm.user_id
+WHERE gm.group_id = $1 AND u.status = 'active' AND u.email_verified_at IS NOT NULL
+ORDER BY u.email;
@@ -1,6 +1,6 @@
 -- name: CreateGroup :one
-INSERT INTO groups (name, group_type)
-VALUES ($1, $2)
+INSERT INTO groups (name, group_type, parent_id)
+VALUES ($1, $2, $3)
 RETURNING *;
 
 -- name: GetGroupByID :one
@@ -12,12 +12,53 @@ SELECT * FROM groups WHERE name = $1;
 -- name: ListGroups :many
 SELECT * FROM groups ORDER BY created_at DESC;
 
+-- name: ListSubGroups :many
+SELECT * FROM groups WHERE parent_id = $1 ORDER BY name ASC;
+
+-- name: ListGroupAncestors :many
+-- Returns the group followed by its parent, grandparent and so on up to the
+-- root. Depth is capped to guard against corrupted parent links.
+WITH RECURSIVE ancestors AS (
+    SELECT g.*, 0 AS depth FROM groups g WHERE g.id = $1
+    UNION ALL
+    SELECT p.*, a.depth + 1 FROM groups p
+    JOIN ancestors a ON p.id = a.parent_id
+    WHERE a.depth < 16
+)
+SELECT id, name, status, monthly_limit, monthly_sent, allowed_ips, created_at, updated_at, group_type, sanitize_html, inline_css, parent_id, recipient_validation, sandbox, locale
+FROM ancestors
+ORDER BY depth ASC;
+
 -- name: UpdateGroup :one
 UPDATE groups
 SET name = $2, status = $3, monthly_limit = $4, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateGroupHTMLProcessing :one
+UPDATE groups
+SET sanitize_html = $2, inline_css = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateGroupLocale :one
+UPDATE groups
+SET locale = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateGroupRecipientValidation :one
+UPDATE groups
+SET recipient_validation = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateGroupSandbox :one
+UPDATE groups
+SET sandbox = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: UpdateGroupStatus :one
 UPDATE groups
 SET status = $2, updated_at = NOW()
@@ -36,3 +77,20 @@ WHERE id = $1;
 UPDATE groups
 SET monthly_sent = 0, updated_at = NOW()
 WHERE id = $1;
+
+-- name: ListGroupMonthlyUsage :many
+-- monthly_limit is the effective limit: a sub-group without its own limit
+-- inherits the limit of its nearest ancestor that has one.
+WITH RECURSIVE effective AS (
+    SELECT id, monthly_limit FROM groups WHERE parent_id IS NULL
+    UNION ALL
+    SELECT c.id, CASE WHEN c.monthly_limit > 0 THEN c.monthly_limit ELSE e.monthly_limit END
+    FROM groups c
+    JOIN effective e ON c.parent_id = e.id
+)
+SELECT g.id, g.name, e.monthly_limit, COUNT(m.id) as sent
+FROM groups g
+JOIN effective e ON e.id = g.id
+LEFT JOIN messages m ON m.group_id = g.id AND m.enqueued_at >= $1
+WHERE g.status = 'active' AND g.group_type <> 'system' AND e.monthly_limit > 0
+GROUP BY g.id, g.name, e.monthly_limit;
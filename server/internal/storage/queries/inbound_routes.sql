@@ -0,0 +1,22 @@
+-- name: CreateInboundRoute :one
+INSERT INTO inbound_routes (group_id, domain, url, format, secret, enabled)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetInboundRouteByID :one
+SELECT * FROM inbound_routes WHERE id = $1;
+
+-- name: GetInboundRouteByDomain :one
+SELECT * FROM inbound_routes WHERE domain = $1 AND enabled = true;
+
+-- name: ListInboundRoutesByGroupID :many
+SELECT * FROM inbound_routes WHERE group_id = $1 ORDER BY domain;
+
+-- name: UpdateInboundRoute :one
+UPDATE inbound_routes
+SET url = $2, format = $3, secret = $4, enabled = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteInboundRoute :exec
+DELETE FROM inbound_routes WHERE id = $1;
@@ -0,0 +1,23 @@
+-- name: CreateInvitation :one
+INSERT INTO invitations (group_id, email, role, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (group_id, email) WHERE accepted_at IS NULL DO UPDATE
+SET role = EXCLUDED.role, invited_by = EXCLUDED.invited_by,
+    expires_at = EXCLUDED.expires_at, created_at = NOW()
+RETURNING *;
+
+-- name: GetInvitationByID :one
+SELECT * FROM invitations WHERE id = $1;
+
+-- name: ListPendingInvitationsByGroupID :many
+SELECT * FROM invitations
+WHERE group_id = $1 AND accepted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: AcceptInvitation :execrows
+UPDATE invitations
+SET accepted_at = NOW()
+WHERE id = $1 AND accepted_at IS NULL AND expires_at > NOW();
+
+-- name: DeleteInvitation :execrows
+DELETE FROM invitations WHERE id = $1 AND group_id = $2 AND accepted_at IS NULL;
@@ -0,0 +1,10 @@
+-- name: ListLoginNetworks :many
+SELECT * FROM login_networks
+WHERE user_id = $1
+ORDER BY last_seen_at DESC;
+
+-- name: RecordLoginNetwork :exec
+INSERT INTO login_networks (user_id, network, country)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, network) DO UPDATE
+SET country = EXCLUDED.country, last_seen_at = NOW();
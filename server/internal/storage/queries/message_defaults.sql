@@ -0,0 +1,17 @@
+-- name: GetMessageDefaults :one
+SELECT * FROM message_defaults WHERE group_id = $1;
+
+-- name: UpsertMessageDefaults :one
+INSERT INTO message_defaults (group_id, headers, footer_text, footer_html, exclude_tags, exclude_templates)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (group_id) DO UPDATE
+SET headers = EXCLUDED.headers,
+    footer_text = EXCLUDED.footer_text,
+    footer_html = EXCLUDED.footer_html,
+    exclude_tags = EXCLUDED.exclude_tags,
+    exclude_templates = EXCLUDED.exclude_templates,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteMessageDefaults :execrows
+DELETE FROM message_defaults WHERE group_id = $1;
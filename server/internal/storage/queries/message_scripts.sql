@@ -0,0 +1,22 @@
+-- name: CreateMessageScript :one
+INSERT INTO message_scripts (group_id, name, source, enabled)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetMessageScript :one
+SELECT * FROM message_scripts WHERE id = $1 AND group_id = $2;
+
+-- name: ListMessageScriptsByGroupID :many
+SELECT * FROM message_scripts WHERE group_id = $1 ORDER BY name;
+
+-- name: ListEnabledMessageScriptsByGroupID :many
+SELECT * FROM message_scripts WHERE group_id = $1 AND enabled = true ORDER BY name;
+
+-- name: UpdateMessageScript :one
+UPDATE message_scripts
+SET name = $3, source = $4, enabled = $5, updated_at = NOW()
+WHERE id = $1 AND group_id = $2
+RETURNING *;
+
+-- name: DeleteMessageScript :execrows
+DELETE FROM message_scripts WHERE id = $1 AND group_id = $2;
@@ -1,11 +1,11 @@
 -- name: EnqueueMessage :one
-INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, body, status)
-VALUES ($1, $2, $3, $4, $5, $6, $7, 'queued')
+INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, body, size_bytes, status, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'queued', $9, COALESCE(sqlc.narg(tags)::jsonb, '[]'), COALESCE(sqlc.narg(metadata)::jsonb, '{}'), sqlc.narg(tls_version), sqlc.narg(tls_cipher), sqlc.narg(request_id))
 RETURNING *;
 
 -- name: EnqueueMessageMetadata :one
-INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, storage_ref, status)
-VALUES ($1, $2, $3, $4, $5, $6, $7, 'queued')
+INSERT INTO messages (user_id, group_id, sender, recipients, subject, headers, storage_ref, size_bytes, status, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'queued', $9, COALESCE(sqlc.narg(tags)::jsonb, '[]'), COALESCE(sqlc.narg(metadata)::jsonb, '{}'), sqlc.narg(tls_version), sqlc.narg(tls_cipher), sqlc.narg(request_id))
 RETURNING *;
 
 -- name: GetMessageByID :one
@@ -19,3 +19,149 @@ UPDATE messages SET status = $2, processed_at = NOW() WHERE id = $1;
 
 -- name: GetQueuedMessages :many
 SELECT * FROM messages WHERE status = 'queued' ORDER BY enqueued_at ASC LIMIT $1;
+
+-- name: ListStuckMessages :many
+SELECT * FROM messages
+WHERE (
+    (status = 'queued' AND COALESCE(processed_at, enqueued_at) < sqlc.arg(queued_before))
+    OR (status = 'processing' AND processed_at < sqlc.arg(processing_before))
+)
+AND NOT EXISTS (SELECT 1 FROM outbox_entries WHERE outbox_entries.message_id = messages.id)
+ORDER BY enqueued_at ASC
+LIMIT sqlc.arg(batch_size);
+
+-- name: RequeueMessage :execrows
+-- Only matches while the message is still in the state the sweeper saw, so
+-- a worker that finished it in the meantime is not undone.
+UPDATE messages
+SET status = 'queued', processed_at = NOW(), requeue_count = requeue_count + 1
+WHERE id = $1 AND status = $2 AND processed_at IS NOT DISTINCT FROM $3;
+
+-- name: ExpireStuckMessage :execrows
+-- Fails a stuck message under the same condition as RequeueMessage.
+UPDATE messages
+SET status = 'failed', processed_at = NOW()
+WHERE id = $1 AND status = $2 AND processed_at IS NOT DISTINCT FROM $3;
+
+-- name: MonthlyMessageUsage :many
+SELECT m.group_id, g.name as group_name, COUNT(*) as messages, COALESCE(SUM(m.size_bytes), 0)::bigint as total_bytes
+FROM messages m
+JOIN groups g ON g.id = m.group_id
+WHERE m.enqueued_at >= $1 AND m.enqueued_at < $2
+GROUP BY m.group_id, g.name
+ORDER BY g.name;
+
+-- name: MonthlyProviderUsage :many
+SELECT m.group_id, dl.provider, COUNT(*) as messages, COALESCE(SUM(m.size_bytes), 0)::bigint as total_bytes
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.status = 'delivered' AND m.group_id IS NOT NULL AND dl.created_at >= $1 AND dl.created_at < $2
+GROUP BY m.group_id, dl.provider
+ORDER BY dl.provider;
+
+-- name: CountGroupMessagesSince :one
+SELECT COUNT(*) FROM messages WHERE group_id = $1 AND enqueued_at >= $2;
+
+-- name: ExportGroupMessages :many
+SELECT id, sender, recipients, subject, headers, status, provider_id, enqueued_at, processed_at, user_id, requeue_count, size_bytes, tags, metadata
+FROM messages
+WHERE group_id = $1
+ORDER BY enqueued_at ASC;
+
+-- name: ListGroupMessageStorageRefs :many
+SELECT storage_ref FROM messages WHERE group_id = $1 AND storage_ref IS NOT NULL;
+
+-- name: EraseGroupMessages :execrows
+UPDATE messages
+SET sender = '', recipients = '[]', subject = NULL, headers = '{}', body = NULL, storage_ref = NULL, tags = '[]', metadata = '{}'
+WHERE group_id = $1;
+
+-- name: ListGroupMessages :many
+SELECT * FROM messages
+WHERE group_id = sqlc.arg(group_id)
+  AND (sqlc.narg(tag)::text IS NULL OR tags ? sqlc.narg(tag)::text)
+  AND (sqlc.narg(status)::message_status IS NULL OR status = sqlc.narg(status)::message_status)
+ORDER BY enqueued_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: CountGroupMessagesByTag :many
+SELECT t.tag::text AS tag, m.status, COUNT(*) AS messages
+FROM messages m, jsonb_array_elements_text(m.tags) AS t(tag)
+WHERE m.group_id = $1 AND m.enqueued_at >= $2 AND m.enqueued_at < $3
+GROUP BY t.tag, m.status
+ORDER BY t.tag, m.status;
+
+-- name: PauseQueuedMessages :execrows
+UPDATE messages
+SET status = 'paused', processed_at = NOW()
+WHERE status = 'queued'
+  AND (user_id = sqlc.narg(user_id) OR group_id = sqlc.narg(group_id));
+
+-- name: ResumePausedMessages :execrows
+UPDATE messages
+SET status = 'queued', processed_at = NULL
+WHERE status = 'paused'
+  AND (user_id = sqlc.narg(user_id) OR group_id = sqlc.narg(group_id));
+
+-- name: CountResendableMessages :one
+-- Counts the failed messages of a group enqueued in [since, until) that
+-- never bounced or drew a complaint and still have a body.
+SELECT COUNT(*) FROM messages m
+WHERE m.group_id = sqlc.arg(group_id)
+  AND m.status = 'failed'
+  AND m.enqueued_at >= sqlc.arg(since) AND m.enqueued_at < sqlc.arg(until)
+  AND (m.body IS NOT NULL OR m.storage_ref IS NOT NULL)
+  AND NOT EXISTS (
+    SELECT 1 FROM delivery_logs dl
+    WHERE dl.message_id = m.id AND dl.status IN ('bounced', 'complained')
+  );
+
+-- name: ListResendableMessages :many
+-- Lists the messages counted by CountResendableMessages, oldest first.
+SELECT m.id, m.group_id, m.user_id, m.request_id FROM messages m
+WHERE m.group_id = sqlc.arg(group_id)
+  AND m.status = 'failed'
+  AND m.enqueued_at >= sqlc.arg(since) AND m.enqueued_at < sqlc.arg(until)
+  AND (m.body IS NOT NULL OR m.storage_ref IS NOT NULL)
+  AND NOT EXISTS (
+    SELECT 1 FROM delivery_logs dl
+    WHERE dl.message_id = m.id AND dl.status IN ('bounced', 'complained')
+  )
+ORDER BY m.enqueued_at ASC
+LIMIT sqlc.arg(max_results);
+
+-- name: ResendMessage :execrows
+-- Returns a failed message to the queue for a fresh round of attempts.
+UPDATE messages
+SET status = 'queued', processed_at = NULL, requeue_count = 0
+WHERE id = $1 AND status = 'failed';
+
+-- name: ListDeliveryReportMessages :many
+-- Pages through a group's messages carrying tag that were enqueued within
+-- [since, until), oldest first, after the (after_enqueued_at, after_id)
+-- keyset position.
+SELECT id, recipients, status, enqueued_at, processed_at, request_id
+FROM messages
+WHERE group_id = sqlc.arg(group_id)
+  AND tags ? sqlc.arg(tag)::text
+  AND enqueued_at >= sqlc.arg(since) AND enqueued_at < sqlc.arg(until)
+  AND (enqueued_at > sqlc.arg(after_enqueued_at) OR (enqueued_at = sqlc.arg(after_enqueued_at) AND id > sqlc.arg(after_id)))
+ORDER BY enqueued_at ASC, id ASC
+LIMIT sqlc.arg(max_results);
+
+-- name: ListMessagesSentSince :many
+SELECT id, user_id, group_id, sender, recipients, subject, status, enqueued_at, processed_at, size_bytes, request_id
+FROM messages
+WHERE (user_id = sqlc.narg(user_id) OR group_id = sqlc.narg(group_id))
+  AND enqueued_at >= sqlc.arg(since)
+ORDER BY enqueued_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: FindMessageByHeaderMessageID :one
+SELECT * FROM messages
+WHERE group_id = sqlc.arg(group_id)
+  AND (headers -> 'Message-Id') ? sqlc.arg(header_message_id)::text
+  AND (sqlc.narg(recipient)::text IS NULL
+       OR lower(recipients::text)::jsonb @> jsonb_build_array(lower(sqlc.narg(recipient)::text)))
+ORDER BY enqueued_at DESC
+LIMIT 1;
@@ -0,0 +1,34 @@
+-- name: CreateOutboxEntry :one
+INSERT INTO outbox_entries (message_id, group_id, user_id, request_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: CreateDelayedOutboxEntry :one
+-- The entry is hidden from relays until claimed_until, which paces bulk
+-- re-enqueues.
+INSERT INTO outbox_entries (message_id, group_id, user_id, request_id, claimed_until)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ClaimOutboxEntries :many
+UPDATE outbox_entries
+SET claimed_until = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id IN (
+    SELECT id FROM outbox_entries
+    WHERE claimed_until IS NULL OR claimed_until < NOW()
+    ORDER BY created_at ASC
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteOutboxEntry :exec
+DELETE FROM outbox_entries WHERE id = $1;
+
+-- name: RecordOutboxEntryFailure :exec
+UPDATE outbox_entries
+SET attempts = attempts + 1, last_error = $2, claimed_until = NULL
+WHERE id = $1;
+
+-- name: CountOutboxEntries :one
+SELECT COUNT(*) FROM outbox_entries;
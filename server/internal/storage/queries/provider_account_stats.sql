@@ -0,0 +1,38 @@
+-- name: UpsertProviderAccountStats :one
+INSERT INTO provider_account_stats (
+    provider_id, quota, quota_used, bounce_rate, complaint_rate, suppression_count, polled_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (provider_id) DO UPDATE
+SET quota = EXCLUDED.quota,
+    quota_used = EXCLUDED.quota_used,
+    bounce_rate = EXCLUDED.bounce_rate,
+    complaint_rate = EXCLUDED.complaint_rate,
+    suppression_delta = EXCLUDED.suppression_count - provider_account_stats.suppression_count,
+    suppression_count = EXCLUDED.suppression_count,
+    last_error = NULL,
+    polled_at = NOW()
+RETURNING *;
+
+-- name: RecordProviderAccountStatsError :exec
+INSERT INTO provider_account_stats (provider_id, last_error, polled_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (provider_id) DO UPDATE
+SET last_error = EXCLUDED.last_error,
+    polled_at = NOW();
+
+-- name: ListProviderAccountStatsByGroupID :many
+SELECT s.* FROM provider_account_stats s
+JOIN esp_providers p ON p.id = s.provider_id
+WHERE p.group_id = $1;
+
+-- name: UpdateProviderReputationPause :exec
+INSERT INTO provider_account_stats (provider_id, paused_at, pause_reason, pause_action, polled_at)
+VALUES ($1, CASE WHEN $2::text IS NULL THEN NULL ELSE NOW() END, $2, $3, NOW())
+ON CONFLICT (provider_id) DO UPDATE
+SET paused_at = CASE
+        WHEN EXCLUDED.pause_reason IS NULL THEN NULL
+        ELSE COALESCE(provider_account_stats.paused_at, NOW())
+    END,
+    pause_reason = EXCLUDED.pause_reason,
+    pause_action = EXCLUDED.pause_action;
@@ -0,0 +1,23 @@
+-- name: CreateProviderCapture :one
+INSERT INTO provider_captures (provider_id, message_id, exchanges, error)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListProviderCaptures :many
+SELECT * FROM provider_captures WHERE provider_id = $1 ORDER BY created_at DESC;
+
+-- name: PruneProviderCaptures :exec
+DELETE FROM provider_captures
+WHERE provider_id = $1 AND id NOT IN (
+    SELECT id FROM provider_captures WHERE provider_id = $1 ORDER BY created_at DESC LIMIT $2
+);
+
+-- name: DeleteGroupProviderCaptures :execrows
+-- Deletes the captures of a group's providers and of its messages, which
+-- may have gone out through an ancestor group's provider.
+DELETE FROM provider_captures
+WHERE provider_id IN (SELECT id FROM esp_providers WHERE group_id = $1)
+   OR message_id IN (SELECT id FROM messages WHERE group_id = $1);
+
+-- name: DeleteProviderCaptures :execrows
+DELETE FROM provider_captures WHERE provider_id = $1;
@@ -0,0 +1,16 @@
+-- name: ReserveProviderDailySend :one
+-- Counts one delivery against the provider's daily cap. No row is
+-- returned when the cap is already reached.
+INSERT INTO provider_daily_sends (provider_id, day, sent)
+VALUES ($1, $2, 1)
+ON CONFLICT (provider_id, day) DO UPDATE
+SET sent = provider_daily_sends.sent + 1
+WHERE provider_daily_sends.sent < sqlc.arg(daily_cap)::int
+RETURNING sent;
+
+-- name: ListProviderDailySendsByGroupID :many
+SELECT d.provider_id, d.day, d.sent
+FROM provider_daily_sends d
+JOIN esp_providers p ON p.id = d.provider_id
+WHERE p.group_id = $1 AND d.day >= $2
+ORDER BY d.provider_id, d.day;
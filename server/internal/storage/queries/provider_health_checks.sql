@@ -0,0 +1,13 @@
+-- name: CreateProviderHealthCheck :one
+INSERT INTO provider_health_checks (provider_id, healthy, latency_ms, error)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListProviderHealthChecks :many
+SELECT * FROM provider_health_checks
+WHERE provider_id = $1
+ORDER BY checked_at DESC
+LIMIT $2;
+
+-- name: DeleteProviderHealthChecksBefore :exec
+DELETE FROM provider_health_checks WHERE checked_at < $1;
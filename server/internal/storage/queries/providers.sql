@@ -1,6 +1,6 @@
 -- name: CreateProvider :one
-INSERT INTO esp_providers (group_id, name, provider_type, api_key, smtp_config, enabled)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO esp_providers (group_id, name, provider_type, api_key, smtp_config, enabled, cost_model)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetProviderByID :one
@@ -11,9 +11,36 @@ SELECT * FROM esp_providers WHERE group_id = $1 ORDER BY created_at DESC;
 
 -- name: UpdateProvider :one
 UPDATE esp_providers
-SET name = $2, provider_type = $3, api_key = $4, smtp_config = $5, enabled = $6, updated_at = NOW()
+SET name = $2, provider_type = $3, api_key = $4, smtp_config = $5, enabled = $6,
+    cost_model = $7, auto_disabled_at = NULL, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
 -- name: DeleteProvider :exec
 DELETE FROM esp_providers WHERE id = $1;
+
+-- name: ListProvidersForHealthCheck :many
+SELECT * FROM esp_providers
+WHERE enabled = true OR auto_disabled_at IS NOT NULL
+ORDER BY created_at;
+
+-- name: UpdateProviderHealth :exec
+UPDATE esp_providers
+SET health_status = $2,
+    consecutive_failures = $3,
+    last_health_error = $4,
+    last_health_check_at = NOW()
+WHERE id = $1;
+
+-- name: AutoDisableProvider :exec
+UPDATE esp_providers
+SET enabled = false, auto_disabled_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND enabled = true;
+
+-- name: AutoEnableProvider :exec
+UPDATE esp_providers
+SET enabled = true, auto_disabled_at = NULL, updated_at = NOW()
+WHERE id = $1 AND auto_disabled_at IS NOT NULL;
+
+-- name: ListEnabledProviders :many
+SELECT * FROM esp_providers WHERE enabled = true ORDER BY created_at;
@@ -0,0 +1,4 @@
+-- name: RecordQuotaNotification :execrows
+INSERT INTO quota_notifications (group_id, period, threshold)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
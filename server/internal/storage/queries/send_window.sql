@@ -0,0 +1,14 @@
+-- name: GetSendWindow :one
+SELECT * FROM send_windows WHERE group_id = $1;
+
+-- name: UpsertSendWindow :one
+INSERT INTO send_windows (group_id, timezone, windows)
+VALUES ($1, $2, $3)
+ON CONFLICT (group_id) DO UPDATE
+SET timezone = EXCLUDED.timezone,
+    windows = EXCLUDED.windows,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteSendWindow :execrows
+DELETE FROM send_windows WHERE group_id = $1;
@@ -0,0 +1,35 @@
+-- name: CreateSenderIdentity :one
+INSERT INTO sender_identities (group_id, identity, verification_token)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetSenderIdentity :one
+SELECT * FROM sender_identities WHERE id = $1 AND group_id = $2;
+
+-- name: ListSenderIdentitiesByGroupID :many
+SELECT * FROM sender_identities WHERE group_id = $1 ORDER BY identity;
+
+-- name: ListVerifiedSenderIdentities :many
+SELECT identity FROM sender_identities
+WHERE group_id = $1 AND verified_at IS NOT NULL
+ORDER BY identity;
+
+-- name: MarkSenderIdentityVerified :one
+UPDATE sender_identities SET verified_at = NOW()
+WHERE id = $1 AND group_id = $2
+RETURNING *;
+
+-- name: DeleteSenderIdentity :execrows
+DELETE FROM sender_identities WHERE id = $1 AND group_id = $2;
+
+-- name: GetSenderPolicy :one
+SELECT * FROM sender_policies WHERE group_id = $1;
+
+-- name: UpsertSenderPolicy :one
+INSERT INTO sender_policies (group_id, mode, rewrite_address)
+VALUES ($1, $2, $3)
+ON CONFLICT (group_id) DO UPDATE
+SET mode = EXCLUDED.mode,
+    rewrite_address = EXCLUDED.rewrite_address,
+    updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,17 @@
+-- name: GetSendingDomain :one
+SELECT * FROM sending_domains WHERE group_id = $1 AND domain = $2;
+
+-- name: ListSendingDomainsByGroupID :many
+SELECT * FROM sending_domains WHERE group_id = $1 ORDER BY domain;
+
+-- name: UpsertSendingDomain :one
+INSERT INTO sending_domains (group_id, domain, reply_to, return_path)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (group_id, domain) DO UPDATE
+SET reply_to = EXCLUDED.reply_to,
+    return_path = EXCLUDED.return_path,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteSendingDomain :execrows
+DELETE FROM sending_domains WHERE group_id = $1 AND domain = $2;
@@ -1,6 +1,6 @@
 -- name: CreateSession :one
-INSERT INTO sessions (user_id, group_id, refresh_token_hash, expires_at)
-VALUES ($1, $2, $3, $4)
+INSERT INTO sessions (id, user_id, group_id, refresh_token_hash, expires_at, user_agent, ip_address, last_used_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
 RETURNING *;
 
 -- name: GetSessionByID :one
@@ -17,3 +17,11 @@ DELETE FROM sessions WHERE expires_at < NOW();
 
 -- name: ListSessionsByUserID :many
 SELECT * FROM sessions WHERE user_id = $1 ORDER BY created_at DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW(), user_agent = $2, ip_address = $3
+WHERE id = $1;
+
+-- name: DeleteUserSession :execrows
+DELETE FROM sessions WHERE id = $1 AND user_id = $2;
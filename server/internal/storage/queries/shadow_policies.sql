@@ -0,0 +1,24 @@
+-- name: ListShadowPolicies :many
+SELECT policy FROM shadow_policies WHERE group_id = $1 ORDER BY policy;
+
+-- name: DeleteShadowPolicies :exec
+DELETE FROM shadow_policies WHERE group_id = $1;
+
+-- name: AddShadowPolicy :exec
+INSERT INTO shadow_policies (group_id, policy)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RecordShadowRejection :exec
+INSERT INTO shadow_rejections (group_id, policy, reason, day, count, last_seen_at)
+VALUES ($1, $2, $3, $4, 1, NOW())
+ON CONFLICT (group_id, policy, reason, day) DO UPDATE
+SET count = shadow_rejections.count + 1,
+    last_seen_at = NOW();
+
+-- name: SummarizeShadowRejections :many
+SELECT policy, reason, SUM(count)::bigint AS count, MAX(last_seen_at)::timestamptz AS last_seen_at
+FROM shadow_rejections
+WHERE group_id = $1 AND day >= $2
+GROUP BY policy, reason
+ORDER BY policy, count DESC, reason;
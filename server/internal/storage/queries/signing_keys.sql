@@ -0,0 +1,35 @@
+-- name: GetSigningKey :one
+SELECT * FROM signing_keys WHERE group_id = $1;
+
+-- name: UpsertSigningKey :one
+INSERT INTO signing_keys (group_id, kind, certificate, private_key, fingerprint, expires_at, encrypt, on_failure)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (group_id) DO UPDATE
+SET kind = EXCLUDED.kind,
+    certificate = EXCLUDED.certificate,
+    private_key = EXCLUDED.private_key,
+    fingerprint = EXCLUDED.fingerprint,
+    expires_at = EXCLUDED.expires_at,
+    encrypt = EXCLUDED.encrypt,
+    on_failure = EXCLUDED.on_failure,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteSigningKey :execrows
+DELETE FROM signing_keys WHERE group_id = $1;
+
+-- name: ListRecipientCertificatesByGroupID :many
+SELECT * FROM recipient_certificates WHERE group_id = $1 ORDER BY email;
+
+-- name: UpsertRecipientCertificate :one
+INSERT INTO recipient_certificates (group_id, email, certificate, fingerprint, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (group_id, email) DO UPDATE
+SET certificate = EXCLUDED.certificate,
+    fingerprint = EXCLUDED.fingerprint,
+    expires_at = EXCLUDED.expires_at,
+    created_at = NOW()
+RETURNING *;
+
+-- name: DeleteRecipientCertificate :execrows
+DELETE FROM recipient_certificates WHERE group_id = $1 AND email = $2;
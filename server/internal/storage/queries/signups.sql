@@ -0,0 +1,19 @@
+-- name: CreateSignup :one
+-- Signing up again with the same email replaces the pending registration
+-- and gives it a new ID, so links sent for the old one stop working.
+INSERT INTO signups (email, password_hash, group_name, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (email) DO UPDATE
+SET id = EXCLUDED.id, password_hash = EXCLUDED.password_hash,
+    group_name = EXCLUDED.group_name, expires_at = EXCLUDED.expires_at,
+    created_at = NOW()
+RETURNING *;
+
+-- name: GetSignupByID :one
+SELECT * FROM signups WHERE id = $1;
+
+-- name: DeleteSignup :execrows
+DELETE FROM signups WHERE id = $1;
+
+-- name: DeleteExpiredSignups :exec
+DELETE FROM signups WHERE expires_at < NOW();
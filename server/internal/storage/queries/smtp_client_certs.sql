@@ -0,0 +1,22 @@
+-- name: CreateSmtpClientCert :one
+INSERT INTO smtp_client_certs (user_id, fingerprint, san, description)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListSmtpClientCertsByUserID :many
+SELECT * FROM smtp_client_certs WHERE user_id = $1 ORDER BY created_at;
+
+-- name: GetSmtpClientCertByFingerprint :one
+SELECT * FROM smtp_client_certs WHERE fingerprint = $1;
+
+-- name: GetSmtpClientCertBySAN :one
+SELECT * FROM smtp_client_certs
+WHERE san = ANY(sqlc.arg(sans)::text[])
+ORDER BY created_at
+LIMIT 1;
+
+-- name: TouchSmtpClientCert :exec
+UPDATE smtp_client_certs SET last_used_at = NOW() WHERE id = $1;
+
+-- name: DeleteSmtpClientCert :execrows
+DELETE FROM smtp_client_certs WHERE id = $1 AND user_id = $2;
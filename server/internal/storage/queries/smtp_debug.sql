@@ -0,0 +1,27 @@
+-- name: CreateSMTPDebugTarget :one
+INSERT INTO smtp_debug_targets (group_id, user_id, ip_address, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetSMTPDebugTarget :one
+SELECT * FROM smtp_debug_targets WHERE id = $1;
+
+-- name: ListActiveSMTPDebugTargets :many
+SELECT * FROM smtp_debug_targets WHERE expires_at > NOW();
+
+-- name: ListSMTPDebugTargets :many
+SELECT * FROM smtp_debug_targets ORDER BY created_at DESC;
+
+-- name: ListSMTPDebugTargetsByGroupID :many
+SELECT * FROM smtp_debug_targets WHERE group_id = $1 ORDER BY created_at DESC;
+
+-- name: DeleteSMTPDebugTarget :exec
+DELETE FROM smtp_debug_targets WHERE id = $1;
+
+-- name: CreateSMTPTranscript :one
+INSERT INTO smtp_transcripts (target_id, user_id, remote_addr, lines, started_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListSMTPTranscriptsByTarget :many
+SELECT * FROM smtp_transcripts WHERE target_id = $1 ORDER BY started_at DESC;
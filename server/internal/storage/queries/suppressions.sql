@@ -0,0 +1,18 @@
+-- name: AddSuppression :exec
+INSERT INTO suppressions (group_id, address, reason, message_id)
+VALUES (sqlc.arg(group_id), lower(sqlc.arg(address)), sqlc.arg(reason), sqlc.narg(message_id))
+ON CONFLICT DO NOTHING;
+
+-- name: IsRecipientSuppressed :one
+SELECT EXISTS (
+    SELECT 1 FROM suppressions WHERE group_id = sqlc.arg(group_id) AND address = lower(sqlc.arg(address))
+);
+
+-- name: ListSuppressions :many
+SELECT * FROM suppressions
+WHERE group_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: DeleteSuppression :execrows
+DELETE FROM suppressions WHERE group_id = sqlc.arg(group_id) AND address = lower(sqlc.arg(address));
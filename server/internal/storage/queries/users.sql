@@ -24,6 +24,12 @@ SET email = $2, status = $3, allowed_domains = $4, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateUserAutoBCC :one
+UPDATE users
+SET auto_bcc = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: UpdateUserStatus :one
 UPDATE users
 SET status = $2, updated_at = NOW()
@@ -50,5 +56,36 @@ DELETE FROM users WHERE id = $1;
 
 -- name: UpdateUserPassword :exec
 UPDATE users
-SET password_hash = $2, updated_at = NOW()
+SET password_hash = $2, password_changed_at = NOW(), password_expiry_warned_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListExpiringSMTPPasswords :many
+SELECT u.id, u.username, u.email, u.password_changed_at, gm.group_id
+FROM users u
+JOIN group_members gm ON gm.user_id = u.id
+WHERE u.account_type = 'smtp'
+  AND u.status = 'active'
+  AND u.password_changed_at < $1
+  AND u.password_expiry_warned_at IS NULL
+ORDER BY u.password_changed_at;
+
+-- name: MarkPasswordExpiryWarned :exec
+UPDATE users
+SET password_expiry_warned_at = NOW()
 WHERE id = $1;
+
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: UpdateUserTLSPolicy :one
+UPDATE users
+SET tls_policy = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RevokeUserAPIKey :execrows
+UPDATE users
+SET api_key = NULL, updated_at = NOW()
+WHERE id = $1 AND api_key IS NOT NULL;
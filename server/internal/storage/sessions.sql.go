@@ -7,30 +7,37 @@ package storage
 
 import (
 	"context"
+	"net/netip"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createSession = `-- name: CreateSession :one
-INSERT INTO sessions (user_id, group_id, refresh_token_hash, expires_at)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, group_id, refresh_token_hash, expires_at, created_at
+INSERT INTO sessions (id, user_id, group_id, refresh_token_hash, expires_at, user_agent, ip_address, last_used_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+RETURNING id, user_id, group_id, refresh_token_hash, expires_at, created_at, user_agent, ip_address, last_used_at
 `
 
 type CreateSessionParams struct {
+	ID               uuid.UUID          `json:"id"`
 	UserID           uuid.UUID          `json:"user_id"`
 	GroupID          uuid.UUID          `json:"group_id"`
 	RefreshTokenHash string             `json:"refresh_token_hash"`
 	ExpiresAt        pgtype.Timestamptz `json:"expires_at"`
+	UserAgent        pgtype.Text        `json:"user_agent"`
+	IpAddress        *netip.Addr        `json:"ip_address"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
 	row := q.db.QueryRow(ctx, createSession,
+		arg.ID,
 		arg.UserID,
 		arg.GroupID,
 		arg.RefreshTokenHash,
 		arg.ExpiresAt,
+		arg.UserAgent,
+		arg.IpAddress,
 	)
 	var i Session
 	err := row.Scan(
@@ -40,6 +47,9 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.RefreshTokenHash,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -71,8 +81,25 @@ func (q *Queries) DeleteSessionsByUserID(ctx context.Context, userID uuid.UUID)
 	return err
 }
 
+const deleteUserSession = `-- name: DeleteUserSession :execrows
+DELETE FROM sessions WHERE id = $1 AND user_id = $2
+`
+
+type DeleteUserSessionParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteUserSession(ctx context.Context, arg DeleteUserSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUserSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, user_id, group_id, refresh_token_hash, expires_at, created_at FROM sessions WHERE id = $1
+SELECT id, user_id, group_id, refresh_token_hash, expires_at, created_at, user_agent, ip_address, last_used_at FROM sessions WHERE id = $1
 `
 
 func (q *Queries) GetSessionByID(ctx context.Context, id uuid.UUID) (Session, error) {
@@ -85,12 +112,15 @@ func (q *Queries) GetSessionByID(ctx context.Context, id uuid.UUID) (Session, er
 		&i.RefreshTokenHash,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
 const listSessionsByUserID = `-- name: ListSessionsByUserID :many
-SELECT id, user_id, group_id, refresh_token_hash, expires_at, created_at FROM sessions WHERE user_id = $1 ORDER BY created_at DESC
+SELECT id, user_id, group_id, refresh_token_hash, expires_at, created_at, user_agent, ip_address, last_used_at FROM sessions WHERE user_id = $1 ORDER BY created_at DESC
 `
 
 func (q *Queries) ListSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]Session, error) {
@@ -109,6 +139,9 @@ func (q *Queries) ListSessionsByUserID(ctx context.Context, userID uuid.UUID) ([
 			&i.RefreshTokenHash,
 			&i.ExpiresAt,
 			&i.CreatedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastUsedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -119,3 +152,20 @@ func (q *Queries) ListSessionsByUserID(ctx context.Context, userID uuid.UUID) ([
 	}
 	return items, nil
 }
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW(), user_agent = $2, ip_address = $3
+WHERE id = $1
+`
+
+type TouchSessionParams struct {
+	ID        uuid.UUID   `json:"id"`
+	UserAgent pgtype.Text `json:"user_agent"`
+	IpAddress *netip.Addr `json:"ip_address"`
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.Exec(ctx, touchSession, arg.ID, arg.UserAgent, arg.IpAddress)
+	return err
+}
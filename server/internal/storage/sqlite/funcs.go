@@ -0,0 +1,134 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+// registerFuncs adds the PostgreSQL functions the queries and schema use,
+// plus helpers for the rewritten queries, to every new connection.
+func registerFuncs(conn *sqlite3.SQLiteConn) error {
+	funcs := []struct {
+		name string
+		impl any
+		pure bool
+	}{
+		{"now", func() string { return formatTime(time.Now()) }, false},
+		{"gen_random_uuid", uuid.NewString, false},
+		{"add_seconds", addSeconds, true},
+		{"epoch", epoch, true},
+		{"to_timestamp", toTimestamp, true},
+		{"regexp_replace", regexpReplace, true},
+	}
+	for _, f := range funcs {
+		if err := conn.RegisterFunc(f.name, f.impl, f.pure); err != nil {
+			return fmt.Errorf("register %s: %w", f.name, err)
+		}
+	}
+	if err := conn.RegisterAggregator("percentile_cont", newPercentile, true); err != nil {
+		return fmt.Errorf("register percentile_cont: %w", err)
+	}
+	return nil
+}
+
+// addSeconds returns the timestamp ts moved by secs seconds.
+func addSeconds(ts string, secs any) (string, error) {
+	f, ok := asFloat(secs)
+	if !ok {
+		return "", fmt.Errorf("add_seconds: %T is not a number", secs)
+	}
+	t, err := parseTime(ts)
+	if err != nil {
+		return "", err
+	}
+	return formatTime(t.Add(time.Duration(f * float64(time.Second)))), nil
+}
+
+// epoch returns ts as seconds since the Unix epoch, like
+// EXTRACT(EPOCH FROM ts). NULL stays NULL.
+func epoch(ts any) (any, error) {
+	if ts == nil {
+		return nil, nil
+	}
+	t, err := parseTime(asString(ts))
+	if err != nil {
+		return nil, err
+	}
+	return float64(t.UnixNano()) / float64(time.Second), nil
+}
+
+// toTimestamp returns the timestamp secs seconds after the Unix epoch,
+// like to_timestamp(secs).
+func toTimestamp(secs any) (string, error) {
+	f, ok := asFloat(secs)
+	if !ok {
+		return "", fmt.Errorf("to_timestamp: %T is not a number", secs)
+	}
+	return formatTime(time.Unix(0, int64(f*float64(time.Second)))), nil
+}
+
+var patterns sync.Map // string -> *regexp.Regexp
+
+// regexpReplace implements PostgreSQL's regexp_replace for the queries in
+// this repository: the replacement is literal and the only flag honoured
+// is "g", which replaces every match instead of the first.
+func regexpReplace(src any, pattern, repl, flags string) (any, error) {
+	if src == nil {
+		return nil, nil
+	}
+	re, ok := patterns.Load(pattern)
+	if !ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		re, _ = patterns.LoadOrStore(pattern, compiled)
+	}
+	s := asString(src)
+	if strings.Contains(flags, "g") {
+		return re.(*regexp.Regexp).ReplaceAllLiteralString(s, repl), nil
+	}
+	loc := re.(*regexp.Regexp).FindStringIndex(s)
+	if loc == nil {
+		return s, nil
+	}
+	return s[:loc[0]] + repl + s[loc[1]:], nil
+}
+
+// percentile implements percentile_cont(value, fraction) as an ordinary
+// aggregate, since SQLite has no WITHIN GROUP syntax.
+type percentile struct {
+	values   []float64
+	fraction float64
+}
+
+func newPercentile() *percentile { return &percentile{} }
+
+func (p *percentile) Step(value, fraction any) {
+	p.fraction, _ = asFloat(fraction)
+	if f, ok := asFloat(value); ok {
+		p.values = append(p.values, f)
+	}
+}
+
+// Done interpolates linearly between the closest ranks, as PostgreSQL does.
+func (p *percentile) Done() any {
+	if len(p.values) == 0 {
+		return nil
+	}
+	sort.Float64s(p.values)
+	pos := p.fraction * float64(len(p.values)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return p.values[lo] + (p.values[hi]-p.values[lo])*(pos-float64(lo))
+}
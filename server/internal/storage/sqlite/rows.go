@@ -0,0 +1,387 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// timeFormat is how timestamps are stored. It is fixed-width so that
+// timestamps sort and compare correctly as strings.
+const timeFormat = "2006-01-02 15:04:05.000000"
+
+const dateFormat = "2006-01-02"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+func parseTime(s string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05.999999", s, time.UTC)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	return t, nil
+}
+
+// rows implements pgx.Rows over database/sql rows.
+type rows struct {
+	rows   *sql.Rows
+	err    error
+	closed bool
+}
+
+func (r *rows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if err := r.rows.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return mapError(r.rows.Err())
+}
+
+func (r *rows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription {
+	cols, err := r.rows.Columns()
+	if err != nil {
+		return nil
+	}
+	fields := make([]pgconn.FieldDescription, len(cols))
+	for i, c := range cols {
+		fields[i] = pgconn.FieldDescription{Name: c}
+	}
+	return fields
+}
+
+func (r *rows) Next() bool {
+	if r.closed || r.err != nil {
+		return false
+	}
+	if !r.rows.Next() {
+		r.Close()
+		return false
+	}
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	values, err := r.Values()
+	if err != nil {
+		return err
+	}
+	if len(values) != len(dest) {
+		return fmt.Errorf("sqlite: %d columns scanned into %d destinations", len(values), len(dest))
+	}
+	for i, d := range dest {
+		if err := assign(d, values[i]); err != nil {
+			return fmt.Errorf("sqlite: scan column %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *rows) Values() ([]any, error) {
+	cols, err := r.rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := r.rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (r *rows) RawValues() [][]byte { return nil }
+
+func (r *rows) Conn() *pgx.Conn { return nil }
+
+// row implements pgx.Row.
+type row struct {
+	rows *rows
+	err  error
+}
+
+func (r *row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+func convertArgs(args []any) ([]any, error) {
+	out := make([]any, len(args))
+	for i, a := range args {
+		v, err := convertArg(a)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: argument $%d: %w", i+1, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// convertArg converts a query argument to a value SQLite stores in the
+// format the schema expects.
+func convertArg(a any) (any, error) {
+	switch v := a.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return formatTime(v), nil
+	case pgtype.Timestamptz:
+		if !v.Valid {
+			return nil, nil
+		}
+		return formatTime(v.Time), nil
+	case pgtype.Date:
+		if !v.Valid {
+			return nil, nil
+		}
+		return v.Time.Format(dateFormat), nil
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		// JSON columns are TEXT: SQLite treats BLOBs as binary JSONB.
+		return string(v), nil
+	case []string:
+		return marshalJSON(v)
+	case []uuid.UUID:
+		return marshalJSON(v)
+	case []netip.Prefix:
+		if v == nil {
+			return nil, nil
+		}
+		return marshalJSON(v)
+	case netip.Addr:
+		return v.String(), nil
+	case *netip.Addr:
+		if v == nil {
+			return nil, nil
+		}
+		return v.String(), nil
+	case driver.Valuer:
+		dv, err := v.Value()
+		if err != nil {
+			return nil, err
+		}
+		return convertArg(dv)
+	}
+
+	rv := reflect.ValueOf(a)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", a)
+}
+
+func marshalJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// assign stores the SQLite value src in dest, one of the destination types
+// used by the generated storage code.
+func assign(dest, src any) error {
+	switch d := dest.(type) {
+	case *pgtype.Timestamptz:
+		if src == nil {
+			*d = pgtype.Timestamptz{}
+			return nil
+		}
+		t, err := parseTime(asString(src))
+		if err != nil {
+			return err
+		}
+		*d = pgtype.Timestamptz{Time: t, Valid: true}
+		return nil
+	case *pgtype.Date:
+		if src == nil {
+			*d = pgtype.Date{}
+			return nil
+		}
+		s := asString(src)
+		if len(s) > len(dateFormat) {
+			s = s[:len(dateFormat)]
+		}
+		t, err := time.ParseInLocation(dateFormat, s, time.UTC)
+		if err != nil {
+			return err
+		}
+		*d = pgtype.Date{Time: t, Valid: true}
+		return nil
+	case **netip.Addr:
+		if src == nil {
+			*d = nil
+			return nil
+		}
+		addr, err := netip.ParseAddr(asString(src))
+		if err != nil {
+			return err
+		}
+		*d = &addr
+		return nil
+	case *[]netip.Prefix:
+		if src == nil {
+			*d = nil
+			return nil
+		}
+		return json.Unmarshal([]byte(asString(src)), d)
+	case *[]byte:
+		switch s := src.(type) {
+		case nil:
+			*d = nil
+		case []byte:
+			*d = append([]byte(nil), s...)
+		default:
+			*d = []byte(asString(s))
+		}
+		return nil
+	case *string:
+		if src == nil {
+			return errors.New("cannot scan NULL into *string")
+		}
+		*d = asString(src)
+		return nil
+	case *bool:
+		b, ok := asInt(src)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into *bool", src)
+		}
+		*d = b != 0
+		return nil
+	case *int32:
+		n, ok := asInt(src)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into *int32", src)
+		}
+		*d = int32(n)
+		return nil
+	case *int64:
+		n, ok := asInt(src)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into *int64", src)
+		}
+		*d = n
+		return nil
+	case *float64:
+		f, ok := asFloat(src)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into *float64", src)
+		}
+		*d = f
+		return nil
+	case *pgtype.Int4:
+		n, ok := asInt(src)
+		*d = pgtype.Int4{Int32: int32(n), Valid: ok}
+		return nil
+	case *pgtype.Int8:
+		n, ok := asInt(src)
+		*d = pgtype.Int8{Int64: n, Valid: ok}
+		return nil
+	case *pgtype.Float8:
+		f, ok := asFloat(src)
+		*d = pgtype.Float8{Float64: f, Valid: ok}
+		return nil
+	case sql.Scanner:
+		// pgtype.UUID, pgtype.Text, uuid.UUID, sql.NullString and the
+		// sqlc enum types all accept strings.
+		if b, ok := src.([]byte); ok {
+			src = string(b)
+		}
+		return d.Scan(src)
+	}
+	return fmt.Errorf("unsupported destination %T", dest)
+}
+
+func asString(src any) string {
+	switch s := src.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case time.Time:
+		return formatTime(s)
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+// asInt converts an INTEGER, REAL or BOOLEAN value, rounding REALs as
+// PostgreSQL does when casting to an integer type.
+func asInt(src any) (int64, bool) {
+	switch n := src.(type) {
+	case int64:
+		return n, true
+	case float64:
+		if n < 0 {
+			return int64(n - 0.5), true
+		}
+		return int64(n + 0.5), true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func asFloat(src any) (float64, bool) {
+	switch n := src.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
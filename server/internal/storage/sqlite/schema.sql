@@ -0,0 +1,509 @@
+-- SQLite schema equivalent to the PostgreSQL migrations up to the version
+-- in SchemaVersion. Keep it in step with ../../../migrations.
+--
+-- Timestamps are TEXT in UTC, formatted "YYYY-MM-DD HH:MM:SS.ffffff" so
+-- they compare correctly as strings. UUIDs are TEXT, JSONB columns are
+-- TEXT holding JSON, and CIDR[] columns are JSON arrays of strings.
+
+CREATE TABLE groups (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    name TEXT NOT NULL UNIQUE,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'deleted')),
+    monthly_limit INTEGER NOT NULL DEFAULT 0,
+    monthly_sent INTEGER NOT NULL DEFAULT 0,
+    allowed_ips TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    group_type TEXT NOT NULL DEFAULT 'company',
+    sanitize_html BOOLEAN NOT NULL DEFAULT false,
+    inline_css BOOLEAN NOT NULL DEFAULT false,
+    parent_id TEXT REFERENCES groups(id) ON DELETE RESTRICT CHECK (parent_id <> id),
+    recipient_validation TEXT NOT NULL DEFAULT 'off' CHECK (recipient_validation IN ('off', 'tag', 'reject')),
+    sandbox BOOLEAN NOT NULL DEFAULT false,
+    locale TEXT NOT NULL DEFAULT 'en'
+);
+
+CREATE INDEX idx_groups_parent_id ON groups(parent_id) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'deleted')),
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_login TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    username TEXT UNIQUE,
+    account_type TEXT NOT NULL DEFAULT 'human',
+    api_key TEXT UNIQUE,
+    allowed_domains TEXT,
+    tls_policy TEXT NOT NULL DEFAULT '{}',
+    auto_bcc TEXT NOT NULL DEFAULT '[]',
+    password_changed_at TEXT NOT NULL DEFAULT (now()),
+    password_expiry_warned_at TEXT,
+    email_verified_at TEXT
+);
+
+CREATE TABLE group_members (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'member',
+    created_at TEXT NOT NULL DEFAULT (now()),
+    UNIQUE (group_id, user_id)
+);
+
+CREATE INDEX idx_group_members_user_id ON group_members(user_id);
+
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    refresh_token_hash TEXT NOT NULL,
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    user_agent TEXT,
+    ip_address TEXT,
+    last_used_at TEXT
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+
+CREATE TABLE activity_logs (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    actor_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    resource_type TEXT NOT NULL,
+    resource_id TEXT,
+    changes TEXT,
+    comment TEXT,
+    ip_address TEXT,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_activity_logs_group_created ON activity_logs(group_id, created_at DESC);
+CREATE INDEX idx_activity_logs_resource ON activity_logs(resource_type, resource_id);
+CREATE INDEX idx_activity_logs_actor ON activity_logs(actor_id, created_at DESC);
+
+CREATE TABLE esp_providers (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    name TEXT NOT NULL,
+    provider_type TEXT NOT NULL CHECK (provider_type IN ('sendgrid', 'mailgun', 'ses', 'smtp', 'msgraph', 'plugin', 'gmail', 'resend', 'brevo', 'zeptomail', 'ews')),
+    api_key TEXT,
+    smtp_config TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    health_status TEXT NOT NULL DEFAULT 'unknown',
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_health_check_at TEXT,
+    last_health_error TEXT,
+    auto_disabled_at TEXT,
+    cost_model TEXT
+);
+
+CREATE INDEX idx_esp_providers_group_id ON esp_providers(group_id);
+
+CREATE TABLE provider_health_checks (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    provider_id TEXT NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    healthy BOOLEAN NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    error TEXT,
+    checked_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_provider_health_checks_provider_checked ON provider_health_checks(provider_id, checked_at DESC);
+CREATE INDEX idx_provider_health_checks_checked_at ON provider_health_checks(checked_at);
+
+CREATE TABLE provider_account_stats (
+    provider_id TEXT PRIMARY KEY REFERENCES esp_providers(id) ON DELETE CASCADE,
+    quota INTEGER,
+    quota_used INTEGER,
+    bounce_rate REAL,
+    complaint_rate REAL,
+    suppression_count INTEGER,
+    suppression_delta INTEGER,
+    last_error TEXT,
+    polled_at TEXT NOT NULL DEFAULT (now()),
+    paused_at TEXT,
+    pause_reason TEXT,
+    pause_action TEXT
+);
+
+CREATE TABLE routing_rules (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    priority INTEGER NOT NULL DEFAULT 0,
+    conditions TEXT NOT NULL DEFAULT '{}',
+    provider_id TEXT NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_routing_rules_group_priority ON routing_rules(group_id, priority);
+
+CREATE TABLE inbound_routes (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    format TEXT NOT NULL DEFAULT 'json' CHECK (format IN ('json', 'raw', 'arf')),
+    secret TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_inbound_routes_group_id ON inbound_routes(group_id);
+
+CREATE TABLE messages (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    sender TEXT NOT NULL,
+    recipients TEXT NOT NULL DEFAULT '[]',
+    subject TEXT,
+    headers TEXT,
+    body TEXT,
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'processing', 'delivered', 'failed', 'enqueue_failed', 'storage_error', 'paused')),
+    provider_id TEXT REFERENCES esp_providers(id),
+    enqueued_at TEXT NOT NULL DEFAULT (now()),
+    processed_at TEXT,
+    storage_ref TEXT,
+    group_id TEXT REFERENCES groups(id) ON DELETE CASCADE,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    requeue_count INTEGER NOT NULL DEFAULT 0,
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    inbound_route_id TEXT,
+    tags TEXT NOT NULL DEFAULT '[]',
+    metadata TEXT NOT NULL DEFAULT '{}',
+    tls_version TEXT,
+    tls_cipher TEXT,
+    request_id TEXT
+);
+
+CREATE INDEX idx_messages_group_enqueued ON messages(group_id, enqueued_at);
+CREATE INDEX idx_messages_user_status ON messages(user_id, status);
+CREATE INDEX idx_messages_status_processed ON messages(status, processed_at)
+    WHERE status IN ('queued', 'processing');
+CREATE INDEX idx_messages_request_id ON messages(request_id) WHERE request_id IS NOT NULL;
+
+CREATE TABLE delivery_logs (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    message_id TEXT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    provider_id TEXT REFERENCES esp_providers(id),
+    status TEXT NOT NULL,
+    response_code INTEGER,
+    response_body TEXT,
+    delivered_at TEXT NOT NULL DEFAULT (now()),
+    provider TEXT,
+    provider_message_id TEXT,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    metadata TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    duration_ms INTEGER,
+    attempt_number INTEGER NOT NULL DEFAULT 1,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    group_id TEXT REFERENCES groups(id) ON DELETE SET NULL,
+    request_id TEXT,
+    egress_ip TEXT,
+    remote_addr TEXT,
+    provider_endpoint TEXT,
+    tls_version TEXT,
+    tls_cipher TEXT,
+    connect_ms INTEGER,
+    tls_handshake_ms INTEGER,
+    first_byte_ms INTEGER
+);
+
+CREATE INDEX idx_delivery_logs_message ON delivery_logs(message_id);
+CREATE INDEX idx_delivery_logs_provider_message_id ON delivery_logs(provider_message_id)
+    WHERE provider_message_id IS NOT NULL;
+CREATE INDEX idx_delivery_logs_group_status ON delivery_logs(group_id, status);
+CREATE INDEX idx_delivery_logs_status_created ON delivery_logs(status, created_at);
+CREATE INDEX idx_delivery_logs_egress_ip ON delivery_logs(egress_ip, created_at)
+    WHERE egress_ip IS NOT NULL;
+
+CREATE TABLE outbox_entries (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    message_id TEXT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    group_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    claimed_until TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    request_id TEXT
+);
+
+CREATE INDEX idx_outbox_entries_created_at ON outbox_entries(created_at);
+
+CREATE TABLE invitations (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member',
+    invited_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TEXT NOT NULL,
+    accepted_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX idx_invitations_pending ON invitations(group_id, email) WHERE accepted_at IS NULL;
+
+CREATE TABLE group_branding (
+    group_id TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    from_address TEXT NOT NULL DEFAULT '',
+    logo_url TEXT NOT NULL DEFAULT '',
+    footer TEXT NOT NULL DEFAULT '',
+    templates TEXT NOT NULL DEFAULT '{}',
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE signups (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    group_name TEXT NOT NULL,
+    expires_at TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE login_networks (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    network TEXT NOT NULL,
+    country TEXT,
+    first_seen_at TEXT NOT NULL DEFAULT (now()),
+    last_seen_at TEXT NOT NULL DEFAULT (now()),
+    PRIMARY KEY (user_id, network)
+);
+
+CREATE TABLE quota_notifications (
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    period TEXT NOT NULL,
+    threshold INTEGER NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    PRIMARY KEY (group_id, period, threshold)
+);
+
+CREATE TABLE smtp_debug_targets (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT REFERENCES groups(id) ON DELETE CASCADE,
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    ip_address TEXT,
+    expires_at TEXT NOT NULL,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    CHECK (user_id IS NOT NULL OR ip_address IS NOT NULL)
+);
+
+CREATE INDEX idx_smtp_debug_targets_expires_at ON smtp_debug_targets(expires_at);
+
+CREATE TABLE smtp_transcripts (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    target_id TEXT NOT NULL REFERENCES smtp_debug_targets(id) ON DELETE CASCADE,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    remote_addr TEXT NOT NULL,
+    lines TEXT NOT NULL DEFAULT '[]',
+    started_at TEXT NOT NULL,
+    ended_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_smtp_transcripts_target_id ON smtp_transcripts(target_id, started_at DESC);
+
+CREATE TABLE smtp_client_certs (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint TEXT UNIQUE,
+    san TEXT UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    last_used_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    CHECK (fingerprint IS NOT NULL OR san IS NOT NULL)
+);
+
+CREATE INDEX idx_smtp_client_certs_user_id ON smtp_client_certs(user_id);
+
+CREATE TABLE message_scripts (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    source TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    UNIQUE (group_id, name)
+);
+
+CREATE TABLE alert_channels (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    channel_type TEXT NOT NULL CHECK (channel_type IN ('slack', 'pagerduty', 'webhook')),
+    target TEXT NOT NULL,
+    kinds TEXT NOT NULL DEFAULT '[]',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_alert_channels_group_id ON alert_channels(group_id);
+
+CREATE TABLE sender_identities (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    identity TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    UNIQUE (group_id, identity)
+);
+
+CREATE TABLE sender_policies (
+    group_id TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    mode TEXT NOT NULL DEFAULT 'off' CHECK (mode IN ('off', 'reject', 'rewrite')),
+    rewrite_address TEXT,
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE sending_domains (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    reply_to TEXT,
+    return_path TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now()),
+    UNIQUE (group_id, domain)
+);
+
+CREATE TABLE signing_keys (
+    group_id TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('smime', 'pgp')),
+    certificate TEXT,
+    private_key TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    expires_at TEXT,
+    encrypt BOOLEAN NOT NULL DEFAULT false,
+    on_failure TEXT NOT NULL DEFAULT 'send_unsigned' CHECK (on_failure IN ('send_unsigned', 'fail')),
+    created_at TEXT NOT NULL DEFAULT (now()),
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE recipient_certificates (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    certificate TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    expires_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    UNIQUE (group_id, email)
+);
+
+CREATE TABLE provider_captures (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    provider_id TEXT NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    message_id TEXT,
+    exchanges TEXT NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_provider_captures_provider_id ON provider_captures(provider_id, created_at DESC);
+
+CREATE TABLE delivery_log_archives (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT REFERENCES groups(id) ON DELETE SET NULL,
+    object_key TEXT NOT NULL UNIQUE,
+    first_created_at TEXT NOT NULL,
+    last_created_at TEXT NOT NULL,
+    row_count INTEGER NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_delivery_log_archives_group ON delivery_log_archives(group_id, last_created_at DESC);
+
+CREATE TABLE analytics_export_cursors (
+    sink TEXT PRIMARY KEY,
+    last_updated_at TEXT NOT NULL,
+    last_id TEXT NOT NULL,
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_delivery_logs_updated ON delivery_logs(updated_at, id);
+
+CREATE TABLE delivery_reports (
+    id TEXT PRIMARY KEY DEFAULT (gen_random_uuid()),
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    tag TEXT NOT NULL,
+    since TEXT NOT NULL,
+    until TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    object_key TEXT,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    error TEXT,
+    started_at TEXT,
+    completed_at TEXT,
+    created_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX idx_delivery_reports_group ON delivery_reports(group_id, created_at DESC);
+CREATE INDEX idx_delivery_reports_status ON delivery_reports(status, created_at);
+
+CREATE TABLE shadow_policies (
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    policy TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    PRIMARY KEY (group_id, policy)
+);
+
+CREATE TABLE shadow_rejections (
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    policy TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    day TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    last_seen_at TEXT NOT NULL DEFAULT (now()),
+    PRIMARY KEY (group_id, policy, reason, day)
+);
+
+CREATE TABLE send_windows (
+    group_id TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    windows TEXT NOT NULL DEFAULT '[]',
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE message_defaults (
+    group_id TEXT PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    headers TEXT NOT NULL DEFAULT '{}',
+    footer_text TEXT NOT NULL DEFAULT '',
+    footer_html TEXT NOT NULL DEFAULT '',
+    exclude_tags TEXT NOT NULL DEFAULT '[]',
+    exclude_templates TEXT NOT NULL DEFAULT '[]',
+    updated_at TEXT NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE provider_daily_sends (
+    provider_id TEXT NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    sent INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (provider_id, day)
+);
+
+CREATE TABLE suppressions (
+    group_id TEXT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    address TEXT NOT NULL,
+    reason TEXT NOT NULL CHECK (reason IN ('complaint')),
+    message_id TEXT REFERENCES messages(id) ON DELETE SET NULL,
+    created_at TEXT NOT NULL DEFAULT (now()),
+    PRIMARY KEY (group_id, address)
+);
@@ -0,0 +1,254 @@
+//go:build sqlite
+
+// Package sqlite runs the storage.Queries generated by sqlc against an
+// embedded SQLite database, for development, single-node edge deployments
+// and tests that should not need a PostgreSQL server.
+//
+// DB implements storage.DBTX by translating each PostgreSQL query to
+// SQLite: placeholders are renumbered, type casts dropped, and the few
+// queries using PostgreSQL-only features are replaced by hand-written
+// equivalents. Arguments and results are converted between the pgtype
+// values sqlc uses and SQLite's storage classes.
+//
+// The package needs cgo and is only built with the sqlite build tag.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// SchemaVersion is the PostgreSQL migration version schema.sql matches.
+const SchemaVersion = 56
+
+//go:embed schema.sql
+var schema string
+
+const driverName = "sqlite3_smtp_proxy"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{ConnectHook: registerFuncs})
+}
+
+// DB is a SQLite database holding the smtp-proxy schema.
+type DB struct {
+	db *sql.DB
+}
+
+var (
+	_ storage.DBTX     = (*DB)(nil)
+	_ storage.TxRunner = (*DB)(nil)
+)
+
+// Open opens the SQLite database at path, creating it and its schema when
+// it does not exist. Use ":memory:" for a private in-memory database.
+//
+// The database is used through a single connection: SQLite serializes
+// writers anyway, and a single connection keeps an in-memory database
+// alive and avoids SQLITE_BUSY errors between the daemon's goroutines.
+func Open(ctx context.Context, path string) (*DB, error) {
+	dsn := path + "?_foreign_keys=on&_busy_timeout=5000"
+	if path != ":memory:" {
+		dsn += "&_journal_mode=WAL"
+	}
+	sqlDB, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+
+	db := &DB{db: sqlDB}
+	if err := db.initSchema(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) initSchema(ctx context.Context) error {
+	var version int
+	if err := db.db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("read sqlite schema version: %w", err)
+	}
+	switch version {
+	case SchemaVersion:
+		return nil
+	case 0:
+	default:
+		return fmt.Errorf("sqlite schema version %d does not match this binary's %d; recreate the database", version, SchemaVersion)
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create sqlite schema: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+	if _, err := tx.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("create sqlite schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, SchemaVersion)); err != nil {
+		return fmt.Errorf("create sqlite schema: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create sqlite schema: %w", err)
+	}
+	return nil
+}
+
+// Queries returns the sqlc queries bound to db.
+func (db *DB) Queries() *storage.Queries {
+	return storage.New(db)
+}
+
+// Close closes the database.
+func (db *DB) Close() error {
+	return db.db.Close()
+}
+
+// Ping verifies the database is usable.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// Exec implements storage.DBTX.
+func (db *DB) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return execContext(ctx, db.db, query, args)
+}
+
+// Query implements storage.DBTX.
+func (db *DB) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return queryContext(ctx, db.db, query, args)
+}
+
+// QueryRow implements storage.DBTX.
+func (db *DB) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	r, err := queryContext(ctx, db.db, query, args)
+	return &row{rows: r, err: err}
+}
+
+// ExecTx runs fn inside a database transaction. The transaction is committed
+// when fn returns nil and rolled back otherwise.
+func (db *DB) ExecTx(ctx context.Context, fn func(storage.Querier) error) error {
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	if err := fn(storage.New(&txConn{tx: tx})); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// txConn implements storage.DBTX on a transaction.
+type txConn struct {
+	tx *sql.Tx
+}
+
+func (c *txConn) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return execContext(ctx, c.tx, query, args)
+}
+
+func (c *txConn) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return queryContext(ctx, c.tx, query, args)
+}
+
+func (c *txConn) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	r, err := queryContext(ctx, c.tx, query, args)
+	return &row{rows: r, err: err}
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func execContext(ctx context.Context, e execer, query string, args []any) (pgconn.CommandTag, error) {
+	converted, err := convertArgs(args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	res, err := e.ExecContext(ctx, translate(query), converted...)
+	if err != nil {
+		return pgconn.CommandTag{}, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return commandTag(query, n), nil
+}
+
+func queryContext(ctx context.Context, e execer, query string, args []any) (*rows, error) {
+	converted, err := convertArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.QueryContext(ctx, translate(query), converted...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return &rows{rows: r}, nil
+}
+
+// commandTag builds the PostgreSQL command tag for a statement affecting
+// n rows, so that pgconn.CommandTag.RowsAffected works for :execrows
+// queries.
+func commandTag(query string, n int64) pgconn.CommandTag {
+	verb := "UPDATE"
+	for _, line := range strings.Split(query, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 {
+			verb = strings.ToUpper(fields[0])
+		}
+		break
+	}
+	if verb == "INSERT" {
+		return pgconn.NewCommandTag(fmt.Sprintf("INSERT 0 %d", n))
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("%s %d", verb, n))
+}
+
+// mapError reports SQLite constraint violations as the PostgreSQL errors
+// callers already recognize, such as apierror.Classify.
+func mapError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+	code := ""
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		code = "23505"
+	case sqlite3.ErrConstraintForeignKey:
+		code = "23503"
+	case sqlite3.ErrConstraintCheck:
+		code = "23514"
+	case sqlite3.ErrConstraintNotNull:
+		code = "23502"
+	default:
+		return err
+	}
+	return &pgconn.PgError{Severity: "ERROR", Code: code, Message: sqliteErr.Error()}
+}
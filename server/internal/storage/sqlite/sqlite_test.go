@@ -0,0 +1,1097 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/apierror"
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fixture creates a group, a user and a message in the group.
+type fixture struct {
+	group   storage.Group
+	user    storage.User
+	message storage.Message
+}
+
+func newFixture(t *testing.T, q *storage.Queries, tags string) fixture {
+	t.Helper()
+	ctx := context.Background()
+	group, err := q.CreateGroup(ctx, storage.CreateGroupParams{Name: "acme-" + uuid.NewString(), GroupType: "company"})
+	if err != nil {
+		t.Fatalf("CreateGroup() error: %v", err)
+	}
+	user, err := q.CreateUser(ctx, storage.CreateUserParams{
+		Email:        uuid.NewString() + "@example.com",
+		PasswordHash: "hash",
+		AccountType:  "smtp",
+		Username:     sql.NullString{String: uuid.NewString(), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	msg, err := q.EnqueueMessage(ctx, storage.EnqueueMessageParams{
+		UserID:     pgtype.UUID{Bytes: user.ID, Valid: true},
+		GroupID:    pgtype.UUID{Bytes: group.ID, Valid: true},
+		Sender:     "from@example.com",
+		Recipients: []byte(`["to@example.com"]`),
+		Subject:    sql.NullString{String: "hello", Valid: true},
+		Headers:    []byte(`{}`),
+		Body:       pgtype.Text{String: "body", Valid: true},
+		SizeBytes:  4,
+		Tags:       []byte(tags),
+		RequestID:  pgtype.Text{String: "req-1", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("EnqueueMessage() error: %v", err)
+	}
+	return fixture{group: group, user: user, message: msg}
+}
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	list, err := migrate.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest := migrate.Latest(list); latest != SchemaVersion {
+		t.Errorf("schema.sql is at version %d but the migrations are at %d; update schema.sql", SchemaVersion, latest)
+	}
+}
+
+func TestOpen_ReopensFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "smtp-proxy.db")
+	db, err := Open(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	newFixture(t, db.Queries(), `[]`)
+	db.Close()
+
+	db, err = Open(context.Background(), path)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	defer db.Close()
+	groups, err := db.Queries().ListGroups(context.Background())
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("ListGroups() = %d groups, %v; want 1", len(groups), err)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `["welcome","beta"]`)
+
+	if f.message.Status != storage.MessageStatusQueued {
+		t.Errorf("status = %q, want queued", f.message.Status)
+	}
+	if !f.message.EnqueuedAt.Valid || time.Since(f.message.EnqueuedAt.Time) > time.Minute {
+		t.Errorf("unexpected enqueued_at %v", f.message.EnqueuedAt)
+	}
+	if string(f.message.Metadata) != "{}" || f.message.RequestID.String != "req-1" {
+		t.Errorf("unexpected defaults: metadata=%s request_id=%v", f.message.Metadata, f.message.RequestID)
+	}
+
+	got, err := q.GetMessageByID(ctx, f.message.ID)
+	if err != nil || got.ID != f.message.ID || got.UserID.Bytes != f.user.ID {
+		t.Fatalf("GetMessageByID() = %+v, %v", got, err)
+	}
+	if _, err := q.GetMessageByID(ctx, uuid.New()); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows, got %v", err)
+	}
+
+	groupID := pgtype.UUID{Bytes: f.group.ID, Valid: true}
+	list, err := q.ListGroupMessages(ctx, storage.ListGroupMessagesParams{
+		GroupID:    groupID,
+		Tag:        pgtype.Text{String: "beta", Valid: true},
+		MaxResults: 10,
+	})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListGroupMessages(tag=beta) = %d, %v; want 1", len(list), err)
+	}
+	list, err = q.ListGroupMessages(ctx, storage.ListGroupMessagesParams{
+		GroupID:    groupID,
+		Status:     storage.NullMessageStatus{MessageStatus: storage.MessageStatusDelivered, Valid: true},
+		MaxResults: 10,
+	})
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListGroupMessages(status=delivered) = %d, %v; want 0", len(list), err)
+	}
+
+	now := time.Now()
+	counts, err := q.CountGroupMessagesByTag(ctx, storage.CountGroupMessagesByTagParams{
+		GroupID:      groupID,
+		EnqueuedAt:   pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+		EnqueuedAt_2: pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+	})
+	if err != nil || len(counts) != 2 || counts[0].Tag != "beta" || counts[1].Messages != 1 {
+		t.Errorf("CountGroupMessagesByTag() = %+v, %v", counts, err)
+	}
+
+	if err := q.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{ID: f.message.ID, Status: storage.MessageStatusProcessing}); err != nil {
+		t.Fatalf("UpdateMessageStatus() error: %v", err)
+	}
+	erased, err := q.EraseGroupMessages(ctx, groupID)
+	if err != nil || erased != 1 {
+		t.Errorf("EraseGroupMessages() = %d, %v; want 1", erased, err)
+	}
+}
+
+func TestPauseAndResumeMessages(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+	other := newFixture(t, q, `[]`)
+
+	userID := pgtype.UUID{Bytes: f.user.ID, Valid: true}
+	paused, err := q.PauseQueuedMessages(ctx, storage.PauseQueuedMessagesParams{UserID: userID})
+	if err != nil || paused != 1 {
+		t.Fatalf("PauseQueuedMessages() = %d, %v; want 1", paused, err)
+	}
+	if got, _ := q.GetMessageByID(ctx, other.message.ID); got.Status != storage.MessageStatusQueued {
+		t.Errorf("other user's message status = %q, want queued", got.Status)
+	}
+
+	report, err := q.ListMessagesSentSince(ctx, storage.ListMessagesSentSinceParams{
+		UserID:     userID,
+		Since:      pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		MaxResults: 10,
+	})
+	if err != nil || len(report) != 1 || report[0].Status != storage.MessageStatusPaused {
+		t.Fatalf("ListMessagesSentSince() = %+v, %v", report, err)
+	}
+
+	resumed, err := q.ResumePausedMessages(ctx, storage.ResumePausedMessagesParams{
+		GroupID: pgtype.UUID{Bytes: f.group.ID, Valid: true},
+	})
+	if err != nil || resumed != 1 {
+		t.Errorf("ResumePausedMessages() = %d, %v; want 1", resumed, err)
+	}
+}
+
+func TestRequeueStuckMessage(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	stuck, err := q.GetMessageByID(ctx, f.message.ID)
+	if err != nil {
+		t.Fatalf("GetMessageByID() error: %v", err)
+	}
+	seen := storage.RequeueMessageParams{ID: stuck.ID, Status: stuck.Status, ProcessedAt: stuck.ProcessedAt}
+	if n, err := q.RequeueMessage(ctx, seen); err != nil || n != 1 {
+		t.Fatalf("RequeueMessage() = %d, %v; want 1", n, err)
+	}
+
+	// The sweeper's view is now stale: a worker delivered the message.
+	if err := q.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{ID: stuck.ID, Status: storage.MessageStatusDelivered}); err != nil {
+		t.Fatalf("UpdateMessageStatus() error: %v", err)
+	}
+	if n, err := q.RequeueMessage(ctx, seen); err != nil || n != 0 {
+		t.Errorf("stale RequeueMessage() = %d, %v; want 0", n, err)
+	}
+	if n, err := q.ExpireStuckMessage(ctx, storage.ExpireStuckMessageParams(seen)); err != nil || n != 0 {
+		t.Errorf("stale ExpireStuckMessage() = %d, %v; want 0", n, err)
+	}
+	if got, _ := q.GetMessageByID(ctx, stuck.ID); got.Status != storage.MessageStatusDelivered || got.RequeueCount != 1 {
+		t.Errorf("message = %q with %d requeues, want delivered with 1", got.Status, got.RequeueCount)
+	}
+}
+
+func TestResendMessages(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	now := time.Now()
+	window := storage.CountResendableMessagesParams{
+		GroupID: pgtype.UUID{Bytes: f.group.ID, Valid: true},
+		Since:   pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+		Until:   pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+	}
+	if n, err := q.CountResendableMessages(ctx, window); err != nil || n != 0 {
+		t.Fatalf("CountResendableMessages() before failure = %d, %v; want 0", n, err)
+	}
+	if err := q.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{ID: f.message.ID, Status: storage.MessageStatusFailed}); err != nil {
+		t.Fatalf("UpdateMessageStatus() error: %v", err)
+	}
+	if n, err := q.CountResendableMessages(ctx, window); err != nil || n != 1 {
+		t.Fatalf("CountResendableMessages() = %d, %v; want 1", n, err)
+	}
+	rows, err := q.ListResendableMessages(ctx, storage.ListResendableMessagesParams{
+		GroupID:    window.GroupID,
+		Since:      window.Since,
+		Until:      window.Until,
+		MaxResults: 10,
+	})
+	if err != nil || len(rows) != 1 || rows[0].ID != f.message.ID || rows[0].UserID.Bytes != f.user.ID {
+		t.Fatalf("ListResendableMessages() = %+v, %v", rows, err)
+	}
+
+	if n, err := q.ResendMessage(ctx, f.message.ID); err != nil || n != 1 {
+		t.Fatalf("ResendMessage() = %d, %v; want 1", n, err)
+	}
+	if n, err := q.ResendMessage(ctx, f.message.ID); err != nil || n != 0 {
+		t.Errorf("second ResendMessage() = %d, %v; want 0", n, err)
+	}
+	if got, _ := q.GetMessageByID(ctx, f.message.ID); got.Status != storage.MessageStatusQueued {
+		t.Errorf("status after resend = %q, want queued", got.Status)
+	}
+
+	if _, err := q.CreateDelayedOutboxEntry(ctx, storage.CreateDelayedOutboxEntryParams{
+		MessageID:    f.message.ID,
+		GroupID:      f.group.ID,
+		UserID:       f.user.ID,
+		ClaimedUntil: pgtype.Timestamptz{Time: now.Add(time.Minute), Valid: true},
+	}); err != nil {
+		t.Fatalf("CreateDelayedOutboxEntry() error: %v", err)
+	}
+	claimed, err := q.ClaimOutboxEntries(ctx, storage.ClaimOutboxEntriesParams{LeaseSeconds: 30, BatchSize: 10})
+	if err != nil || len(claimed) != 0 {
+		t.Errorf("ClaimOutboxEntries() = %d entries, %v; want none before claimed_until", len(claimed), err)
+	}
+
+	// A bounced message is never resent.
+	if err := q.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{ID: f.message.ID, Status: storage.MessageStatusFailed}); err != nil {
+		t.Fatalf("UpdateMessageStatus() error: %v", err)
+	}
+	if _, err := q.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+		MessageID: f.message.ID,
+		GroupID:   window.GroupID,
+		Status:    "bounced",
+		Metadata:  []byte(`{}`),
+	}); err != nil {
+		t.Fatalf("CreateDeliveryLog() error: %v", err)
+	}
+	if n, err := q.CountResendableMessages(ctx, window); err != nil || n != 0 {
+		t.Errorf("CountResendableMessages() after bounce = %d, %v; want 0", n, err)
+	}
+}
+
+func TestDeliveryReports(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `["spring-sale"]`)
+
+	now := time.Now()
+	report, err := q.CreateDeliveryReport(ctx, storage.CreateDeliveryReportParams{
+		GroupID: f.group.ID,
+		UserID:  pgtype.UUID{Bytes: f.user.ID, Valid: true},
+		Tag:     "spring-sale",
+		Since:   pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+		Until:   pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+	})
+	if err != nil || report.Status != "pending" {
+		t.Fatalf("CreateDeliveryReport() = %+v, %v", report, err)
+	}
+	claimed, err := q.ClaimDeliveryReport(ctx, 600)
+	if err != nil || claimed.ID != report.ID || claimed.Status != "running" {
+		t.Fatalf("ClaimDeliveryReport() = %+v, %v", claimed, err)
+	}
+	if _, err := q.ClaimDeliveryReport(ctx, 600); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("second ClaimDeliveryReport() error = %v, want ErrNoRows", err)
+	}
+
+	params := storage.ListDeliveryReportMessagesParams{
+		GroupID:         pgtype.UUID{Bytes: f.group.ID, Valid: true},
+		Tag:             "spring-sale",
+		Since:           report.Since,
+		Until:           report.Until,
+		AfterEnqueuedAt: report.Since,
+		MaxResults:      10,
+	}
+	rows, err := q.ListDeliveryReportMessages(ctx, params)
+	if err != nil || len(rows) != 1 || rows[0].ID != f.message.ID || rows[0].RequestID.String != "req-1" {
+		t.Fatalf("ListDeliveryReportMessages() = %+v, %v", rows, err)
+	}
+	params.AfterEnqueuedAt, params.AfterID = rows[0].EnqueuedAt, rows[0].ID
+	if rows, err := q.ListDeliveryReportMessages(ctx, params); err != nil || len(rows) != 0 {
+		t.Errorf("ListDeliveryReportMessages() after last = %d rows, %v; want none", len(rows), err)
+	}
+	params.AfterEnqueuedAt, params.AfterID, params.Tag = report.Since, uuid.Nil, "other"
+	if rows, err := q.ListDeliveryReportMessages(ctx, params); err != nil || len(rows) != 0 {
+		t.Errorf("ListDeliveryReportMessages() other tag = %d rows, %v; want none", len(rows), err)
+	}
+
+	if _, err := q.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+		MessageID: f.message.ID,
+		GroupID:   pgtype.UUID{Bytes: f.group.ID, Valid: true},
+		Status:    "delivered",
+		Metadata:  []byte(`{}`),
+	}); err != nil {
+		t.Fatalf("CreateDeliveryLog() error: %v", err)
+	}
+	logs, err := q.ListDeliveryLogsByMessageIDs(ctx, []uuid.UUID{f.message.ID, uuid.New()})
+	if err != nil || len(logs) != 1 || logs[0].Status != "delivered" {
+		t.Fatalf("ListDeliveryLogsByMessageIDs() = %+v, %v", logs, err)
+	}
+
+	if err := q.CompleteDeliveryReport(ctx, storage.CompleteDeliveryReportParams{
+		ID:        report.ID,
+		ObjectKey: pgtype.Text{String: "delivery-report.csv", Valid: true},
+		RowCount:  1,
+	}); err != nil {
+		t.Fatalf("CompleteDeliveryReport() error: %v", err)
+	}
+	got, err := q.GetDeliveryReport(ctx, report.ID)
+	if err != nil || got.Status != "ready" || got.RowCount != 1 || !got.CompletedAt.Valid {
+		t.Fatalf("GetDeliveryReport() = %+v, %v", got, err)
+	}
+	list, err := q.ListGroupDeliveryReports(ctx, storage.ListGroupDeliveryReportsParams{GroupID: f.group.ID, Limit: 10})
+	if err != nil || len(list) != 1 {
+		t.Errorf("ListGroupDeliveryReports() = %d reports, %v; want 1", len(list), err)
+	}
+
+	expired, err := q.ListExpiredDeliveryReports(ctx, storage.ListExpiredDeliveryReportsParams{
+		CompletedAt: pgtype.Timestamptz{Time: now.Add(time.Minute), Valid: true},
+		Limit:       10,
+	})
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("ListExpiredDeliveryReports() = %d reports, %v; want 1", len(expired), err)
+	}
+	if err := q.DeleteDeliveryReport(ctx, report.ID); err != nil {
+		t.Fatalf("DeleteDeliveryReport() error: %v", err)
+	}
+	if _, err := q.GetDeliveryReport(ctx, report.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetDeliveryReport() after delete error = %v, want ErrNoRows", err)
+	}
+}
+
+func TestPasswordExpiryWarnings(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+	if _, err := q.CreateGroupMember(ctx, storage.CreateGroupMemberParams{GroupID: f.group.ID, UserID: f.user.ID, Role: "member"}); err != nil {
+		t.Fatalf("CreateGroupMember() error: %v", err)
+	}
+
+	future := pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}
+	rows, err := q.ListExpiringSMTPPasswords(ctx, future)
+	if err != nil || len(rows) != 1 || rows[0].ID != f.user.ID || rows[0].GroupID != f.group.ID {
+		t.Fatalf("ListExpiringSMTPPasswords() = %+v, %v", rows, err)
+	}
+	if !rows[0].PasswordChangedAt.Valid {
+		t.Error("password_changed_at not set on create")
+	}
+
+	if err := q.MarkPasswordExpiryWarned(ctx, f.user.ID); err != nil {
+		t.Fatalf("MarkPasswordExpiryWarned() error: %v", err)
+	}
+	if rows, _ := q.ListExpiringSMTPPasswords(ctx, future); len(rows) != 0 {
+		t.Errorf("warned user listed again: %+v", rows)
+	}
+
+	if err := q.UpdateUserPassword(ctx, storage.UpdateUserPasswordParams{ID: f.user.ID, PasswordHash: "new"}); err != nil {
+		t.Fatalf("UpdateUserPassword() error: %v", err)
+	}
+	if rows, _ := q.ListExpiringSMTPPasswords(ctx, future); len(rows) != 1 {
+		t.Errorf("new password not listed after change: %+v", rows)
+	}
+}
+
+func TestInvitations(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	params := storage.CreateInvitationParams{
+		GroupID:   f.group.ID,
+		Email:     "new@example.com",
+		Role:      "member",
+		InvitedBy: pgtype.UUID{Bytes: f.user.ID, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	inv, err := q.CreateInvitation(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateInvitation() error: %v", err)
+	}
+
+	// Inviting the same address again updates the pending invitation.
+	params.Role = "admin"
+	again, err := q.CreateInvitation(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateInvitation() again error: %v", err)
+	}
+	if again.ID != inv.ID || again.Role != "admin" {
+		t.Errorf("re-invite = %+v, want the updated invitation %s", again, inv.ID)
+	}
+
+	pending, err := q.ListPendingInvitationsByGroupID(ctx, f.group.ID)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("ListPendingInvitationsByGroupID() = %+v, %v", pending, err)
+	}
+
+	if n, err := q.AcceptInvitation(ctx, inv.ID); err != nil || n != 1 {
+		t.Fatalf("AcceptInvitation() = %d, %v", n, err)
+	}
+	if n, _ := q.AcceptInvitation(ctx, inv.ID); n != 0 {
+		t.Error("invitation accepted twice")
+	}
+	if n, _ := q.DeleteInvitation(ctx, storage.DeleteInvitationParams{ID: inv.ID, GroupID: f.group.ID}); n != 0 {
+		t.Error("accepted invitation deleted")
+	}
+	if pending, _ := q.ListPendingInvitationsByGroupID(ctx, f.group.ID); len(pending) != 0 {
+		t.Errorf("accepted invitation still pending: %+v", pending)
+	}
+
+	params.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	expired, err := q.CreateInvitation(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateInvitation() expired error: %v", err)
+	}
+	if n, _ := q.AcceptInvitation(ctx, expired.ID); n != 0 {
+		t.Error("expired invitation accepted")
+	}
+}
+
+func TestSignups(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+
+	params := storage.CreateSignupParams{
+		Email:        "new@example.com",
+		PasswordHash: "hash",
+		GroupName:    "new@example.com",
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	first, err := q.CreateSignup(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateSignup() error: %v", err)
+	}
+
+	// Signing up again replaces the registration under a new ID.
+	params.GroupName = "Acme"
+	second, err := q.CreateSignup(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateSignup() again error: %v", err)
+	}
+	if second.ID == first.ID || second.GroupName != "Acme" {
+		t.Errorf("re-signup = %+v, want a new ID and group name", second)
+	}
+	if _, err := q.GetSignupByID(ctx, first.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("replaced signup still found: %v", err)
+	}
+
+	params.Email = "stale@example.com"
+	params.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	stale, err := q.CreateSignup(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateSignup() stale error: %v", err)
+	}
+	if err := q.DeleteExpiredSignups(ctx); err != nil {
+		t.Fatalf("DeleteExpiredSignups() error: %v", err)
+	}
+	if _, err := q.GetSignupByID(ctx, stale.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expired signup not deleted: %v", err)
+	}
+	if n, err := q.DeleteSignup(ctx, second.ID); err != nil || n != 1 {
+		t.Errorf("DeleteSignup() = %d, %v", n, err)
+	}
+}
+
+func TestGroupSandbox(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	group, err := q.UpdateGroupSandbox(ctx, storage.UpdateGroupSandboxParams{ID: f.group.ID, Sandbox: true})
+	if err != nil || !group.Sandbox {
+		t.Fatalf("UpdateGroupSandbox() = %+v, %v", group, err)
+	}
+	if _, err := q.CreateGroupMember(ctx, storage.CreateGroupMemberParams{GroupID: f.group.ID, UserID: f.user.ID, Role: "owner"}); err != nil {
+		t.Fatalf("CreateGroupMember() error: %v", err)
+	}
+	if emails, err := q.ListVerifiedGroupMemberEmails(ctx, f.group.ID); err != nil || len(emails) != 0 {
+		t.Errorf("unverified member listed: %v, %v", emails, err)
+	}
+	if err := q.MarkEmailVerified(ctx, f.user.ID); err != nil {
+		t.Fatalf("MarkEmailVerified() error: %v", err)
+	}
+	emails, err := q.ListVerifiedGroupMemberEmails(ctx, f.group.ID)
+	if err != nil || len(emails) != 1 || emails[0] != f.user.Email {
+		t.Errorf("ListVerifiedGroupMemberEmails() = %v, %v", emails, err)
+	}
+}
+
+func TestGroupLocale(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	if f.group.Locale != "en" {
+		t.Errorf("default locale = %q, want en", f.group.Locale)
+	}
+	group, err := q.UpdateGroupLocale(ctx, storage.UpdateGroupLocaleParams{ID: f.group.ID, Locale: "ko"})
+	if err != nil || group.Locale != "ko" {
+		t.Fatalf("UpdateGroupLocale() = %+v, %v", group, err)
+	}
+	if got, err := q.GetGroupByID(ctx, f.group.ID); err != nil || got.Locale != "ko" {
+		t.Errorf("GetGroupByID() locale = %q, %v", got.Locale, err)
+	}
+}
+
+func TestGroupBranding(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	if _, err := q.GetGroupBranding(ctx, f.group.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetGroupBranding() before upsert error = %v, want pgx.ErrNoRows", err)
+	}
+	for _, footer := range []string{"Acme Inc.", "Acme Corp."} {
+		if _, err := q.UpsertGroupBranding(ctx, storage.UpsertGroupBrandingParams{
+			GroupID:     f.group.ID,
+			FromAddress: "noreply@acme.example",
+			Footer:      footer,
+			Templates:   []byte(`{"invitation":{"subject":"Join {{.Group}}","body":"{{.Link}}"}}`),
+		}); err != nil {
+			t.Fatalf("UpsertGroupBranding() error: %v", err)
+		}
+	}
+	b, err := q.GetGroupBranding(ctx, f.group.ID)
+	if err != nil || b.Footer != "Acme Corp." || b.FromAddress != "noreply@acme.example" || !strings.Contains(string(b.Templates), "invitation") {
+		t.Fatalf("GetGroupBranding() = %+v, %v", b, err)
+	}
+	if n, err := q.DeleteGroupBranding(ctx, f.group.ID); err != nil || n != 1 {
+		t.Errorf("DeleteGroupBranding() = %d, %v", n, err)
+	}
+}
+
+func TestLoginNetworks(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	for _, country := range []string{"", "KR"} {
+		if err := q.RecordLoginNetwork(ctx, storage.RecordLoginNetworkParams{
+			UserID:  f.user.ID,
+			Network: "192.0.2.0/24",
+			Country: pgtype.Text{String: country, Valid: country != ""},
+		}); err != nil {
+			t.Fatalf("RecordLoginNetwork() error: %v", err)
+		}
+	}
+
+	networks, err := q.ListLoginNetworks(ctx, f.user.ID)
+	if err != nil || len(networks) != 1 {
+		t.Fatalf("ListLoginNetworks() = %+v, %v", networks, err)
+	}
+	if networks[0].Network != "192.0.2.0/24" || networks[0].Country.String != "KR" || !networks[0].FirstSeenAt.Valid {
+		t.Errorf("unexpected login network: %+v", networks[0])
+	}
+}
+
+func TestOutboxClaim(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	entry, err := q.CreateOutboxEntry(ctx, storage.CreateOutboxEntryParams{
+		MessageID: f.message.ID,
+		GroupID:   f.group.ID,
+		UserID:    f.user.ID,
+		RequestID: pgtype.Text{String: "req-1", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateOutboxEntry() error: %v", err)
+	}
+
+	claimed, err := q.ClaimOutboxEntries(ctx, storage.ClaimOutboxEntriesParams{LeaseSeconds: 30, BatchSize: 10})
+	if err != nil || len(claimed) != 1 || claimed[0].ID != entry.ID {
+		t.Fatalf("ClaimOutboxEntries() = %+v, %v", claimed, err)
+	}
+	if until := time.Until(claimed[0].ClaimedUntil.Time); until < 25*time.Second || until > 35*time.Second {
+		t.Errorf("claimed_until is %v away, want about 30s", until)
+	}
+	claimed, err = q.ClaimOutboxEntries(ctx, storage.ClaimOutboxEntriesParams{LeaseSeconds: 30, BatchSize: 10})
+	if err != nil || len(claimed) != 0 {
+		t.Errorf("second claim = %d entries, %v; want none while leased", len(claimed), err)
+	}
+
+	if err := q.RecordOutboxEntryFailure(ctx, storage.RecordOutboxEntryFailureParams{
+		ID:        entry.ID,
+		LastError: pgtype.Text{String: "redis down", Valid: true},
+	}); err != nil {
+		t.Fatalf("RecordOutboxEntryFailure() error: %v", err)
+	}
+	claimed, err = q.ClaimOutboxEntries(ctx, storage.ClaimOutboxEntriesParams{LeaseSeconds: 30, BatchSize: 10})
+	if err != nil || len(claimed) != 1 || claimed[0].Attempts != 1 {
+		t.Errorf("claim after failure = %+v, %v", claimed, err)
+	}
+}
+
+func TestDeleteGroupProviderCaptures(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+	other := newFixture(t, q, `[]`)
+
+	capture := func(groupID uuid.UUID, messageID pgtype.UUID) uuid.UUID {
+		t.Helper()
+		p, err := q.CreateProvider(ctx, storage.CreateProviderParams{
+			GroupID:      groupID,
+			Name:         "captured-" + uuid.NewString(),
+			ProviderType: storage.ProviderTypeSmtp,
+			Enabled:      true,
+		})
+		if err != nil {
+			t.Fatalf("CreateProvider() error: %v", err)
+		}
+		if _, err := q.CreateProviderCapture(ctx, storage.CreateProviderCaptureParams{ProviderID: p.ID, MessageID: messageID, Exchanges: []byte(`[]`)}); err != nil {
+			t.Fatalf("CreateProviderCapture() error: %v", err)
+		}
+		return p.ID
+	}
+	capture(f.group.ID, pgtype.UUID{})
+	// The group's message delivered through another group's provider.
+	shared := capture(other.group.ID, pgtype.UUID{Bytes: f.message.ID, Valid: true})
+	kept := capture(other.group.ID, pgtype.UUID{Bytes: other.message.ID, Valid: true})
+
+	if n, err := q.DeleteGroupProviderCaptures(ctx, f.group.ID); err != nil || n != 2 {
+		t.Fatalf("DeleteGroupProviderCaptures() = %d, %v; want 2", n, err)
+	}
+	if got, _ := q.ListProviderCaptures(ctx, shared); len(got) != 0 {
+		t.Errorf("capture of the group's message kept: %+v", got)
+	}
+	if got, _ := q.ListProviderCaptures(ctx, kept); len(got) != 1 {
+		t.Errorf("other group's captures = %d, want 1", len(got))
+	}
+}
+
+func TestDeliveryLogs(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	p, err := q.CreateProvider(ctx, storage.CreateProviderParams{
+		GroupID:      f.group.ID,
+		Name:         "primary",
+		ProviderType: storage.ProviderTypeSmtp,
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+
+	groupID := pgtype.UUID{Bytes: f.group.ID, Valid: true}
+	for _, ms := range []int32{100, 200} {
+		_, err := q.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+			MessageID:    f.message.ID,
+			ProviderID:   pgtype.UUID{Bytes: p.ID, Valid: true},
+			GroupID:      groupID,
+			Status:       "delivered",
+			Provider:     sql.NullString{String: "smtp", Valid: true},
+			ResponseBody: pgtype.Text{String: "250 ok to@example.com", Valid: true},
+			Metadata:     []byte(`{"rcpt":"to@example.com"}`),
+			DurationMs:   pgtype.Int4{Int32: ms, Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("CreateDeliveryLog() error: %v", err)
+		}
+	}
+
+	now := time.Now()
+	from := pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true}
+	to := pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true}
+
+	avg, err := q.AverageDeliveryDuration(ctx, storage.AverageDeliveryDurationParams{CreatedAt: from, CreatedAt_2: to})
+	if err != nil || len(avg) != 1 || avg[0].AvgDurationMs != 150 || avg[0].Count != 2 {
+		t.Errorf("AverageDeliveryDuration() = %+v, %v", avg, err)
+	}
+
+	pct, err := q.DeliveryLatencyPercentiles(ctx, from)
+	if err != nil || len(pct) != 1 || pct[0].Deliveries != 2 || pct[0].P50Seconds < 0 {
+		t.Errorf("DeliveryLatencyPercentiles() = %+v, %v", pct, err)
+	}
+
+	daily, err := q.DailyDeliveryVolume(ctx, storage.DailyDeliveryVolumeParams{CreatedAt: from, CreatedAt_2: to})
+	if err != nil || len(daily) != 1 || daily[0].Count != 2 || !daily[0].Day.Valid {
+		t.Errorf("DailyDeliveryVolume() = %+v, %v", daily, err)
+	}
+
+	series, err := q.DeliveryTimeSeries(ctx, storage.DeliveryTimeSeriesParams{
+		BucketSeconds: 3600,
+		GroupID:       groupID,
+		Since:         from,
+		Until:         to,
+		Provider:      pgtype.Text{String: "smtp", Valid: true},
+	})
+	if err != nil || len(series) != 1 || series[0].Attempts != 2 || series[0].Delivered != 2 || series[0].AvgDurationMs != 150 ||
+		!series[0].Bucket.Valid || series[0].Bucket.Time.Unix()%3600 != 0 {
+		t.Errorf("DeliveryTimeSeries() = %+v, %v", series, err)
+	}
+
+	sent, err := q.ListRecipientDeliveryLogs(ctx, storage.ListRecipientDeliveryLogsParams{
+		GroupID:    groupID,
+		Recipient:  "To@Example.com",
+		Since:      from,
+		MaxResults: 10,
+	})
+	if err != nil || len(sent) != 2 || sent[0].DeliveryLog.MessageID != f.message.ID || sent[0].Subject.String != "hello" {
+		t.Errorf("ListRecipientDeliveryLogs() = %+v, %v", sent, err)
+	}
+	if other, err := q.ListRecipientDeliveryLogs(ctx, storage.ListRecipientDeliveryLogsParams{
+		GroupID:    groupID,
+		Recipient:  "someone@example.com",
+		MaxResults: 10,
+	}); err != nil || len(other) != 0 {
+		t.Errorf("ListRecipientDeliveryLogs(other recipient) = %+v, %v", other, err)
+	}
+
+	scrubbed, err := q.ScrubGroupDeliveryLogs(ctx, groupID)
+	if err != nil || scrubbed != 2 {
+		t.Fatalf("ScrubGroupDeliveryLogs() = %d, %v; want 2", scrubbed, err)
+	}
+	logs, err := q.ListDeliveryLogsByMessageID(ctx, f.message.ID)
+	if err != nil || len(logs) != 2 {
+		t.Fatalf("ListDeliveryLogsByMessageID() = %d, %v", len(logs), err)
+	}
+	if logs[0].ResponseBody.String != "250 ok [redacted]" || string(logs[0].Metadata) != `{"rcpt":"[redacted]"}` {
+		t.Errorf("not scrubbed: body=%q metadata=%s", logs[0].ResponseBody.String, logs[0].Metadata)
+	}
+}
+
+func TestGroupsAndCerts(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	child, err := q.CreateGroup(ctx, storage.CreateGroupParams{
+		Name:      "team",
+		GroupType: "team",
+		ParentID:  pgtype.UUID{Bytes: f.group.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup(child) error: %v", err)
+	}
+	ancestors, err := q.ListGroupAncestors(ctx, child.ID)
+	if err != nil || len(ancestors) != 2 || ancestors[1].ID != f.group.ID {
+		t.Errorf("ListGroupAncestors() = %+v, %v", ancestors, err)
+	}
+
+	_, err = q.CreateSmtpClientCert(ctx, storage.CreateSmtpClientCertParams{
+		UserID: f.user.ID,
+		San:    pgtype.Text{String: "mailer.example.com", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateSmtpClientCert() error: %v", err)
+	}
+	cert, err := q.GetSmtpClientCertBySAN(ctx, []string{"other.example.com", "mailer.example.com"})
+	if err != nil || cert.UserID != f.user.ID {
+		t.Errorf("GetSmtpClientCertBySAN() = %+v, %v", cert, err)
+	}
+
+	period := pgtype.Date{Time: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	for i, want := range []int64{1, 0} {
+		n, err := q.RecordQuotaNotification(ctx, storage.RecordQuotaNotificationParams{GroupID: f.group.ID, Period: period, Threshold: 80})
+		if err != nil || n != want {
+			t.Errorf("RecordQuotaNotification() call %d = %d, %v; want %d", i+1, n, err, want)
+		}
+	}
+}
+
+func TestConstraintErrors(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	_, err := q.CreateGroup(ctx, storage.CreateGroupParams{Name: f.group.Name, GroupType: "company"})
+	if _, code, ok := apierror.Classify(err); !ok || code != apierror.CodeAlreadyExists {
+		t.Errorf("duplicate group: Classify(%v) = %q, %v", err, code, ok)
+	}
+
+	_, err = q.CreateGroupMember(ctx, storage.CreateGroupMemberParams{GroupID: uuid.New(), UserID: f.user.ID, Role: "member"})
+	if _, code, ok := apierror.Classify(err); !ok || code != apierror.CodeReferenceConflict {
+		t.Errorf("missing group: Classify(%v) = %q, %v", err, code, ok)
+	}
+}
+
+func TestExecTx_RollsBack(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	err := db.ExecTx(ctx, func(q storage.Querier) error {
+		if _, err := q.CreateGroup(ctx, storage.CreateGroupParams{Name: "rolled-back", GroupType: "company"}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("ExecTx() error = %v, want boom", err)
+	}
+	if _, err := db.Queries().GetGroupByName(ctx, "rolled-back"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("group survived rollback: %v", err)
+	}
+}
+
+// TestTranslate_AllQueries prepares every query sqlc generated, so a new
+// query using PostgreSQL-only syntax fails here until it gets an override.
+func TestTranslate_AllQueries(t *testing.T) {
+	db := openTestDB(t)
+	files, err := filepath.Glob("../*.sql.go")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no generated query files found: %v", err)
+	}
+	constQuery := regexp.MustCompile("(?s)const \\w+ = `(-- name: .*?)`")
+	count := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range constQuery.FindAllStringSubmatch(string(src), -1) {
+			count++
+			stmt, err := db.db.PrepareContext(context.Background(), translate(m[1]))
+			if err != nil {
+				t.Errorf("%s: %v", queryName.FindStringSubmatch(m[1])[1], err)
+				continue
+			}
+			stmt.Close()
+		}
+	}
+	if count < 100 {
+		t.Errorf("found only %d queries", count)
+	}
+}
+
+func TestShadowPolicies(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	for _, policy := range []string{"scripts", "sender_policy", "scripts"} {
+		if err := q.AddShadowPolicy(ctx, storage.AddShadowPolicyParams{GroupID: f.group.ID, Policy: policy}); err != nil {
+			t.Fatalf("AddShadowPolicy(%s) error: %v", policy, err)
+		}
+	}
+	policies, err := q.ListShadowPolicies(ctx, f.group.ID)
+	if err != nil || strings.Join(policies, ",") != "scripts,sender_policy" {
+		t.Fatalf("ListShadowPolicies() = %v, %v", policies, err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, r := range []struct {
+		reason string
+		day    time.Time
+	}{
+		{"From not verified", today.AddDate(0, 0, -10)},
+		{"From not verified", today.AddDate(0, 0, -1)},
+		{"From not verified", today},
+		{"From not verified", today},
+		{"no From", today},
+	} {
+		if err := q.RecordShadowRejection(ctx, storage.RecordShadowRejectionParams{
+			GroupID: f.group.ID,
+			Policy:  "sender_policy",
+			Reason:  r.reason,
+			Day:     pgtype.Date{Time: r.day, Valid: true},
+		}); err != nil {
+			t.Fatalf("RecordShadowRejection() error: %v", err)
+		}
+	}
+	rows, err := q.SummarizeShadowRejections(ctx, storage.SummarizeShadowRejectionsParams{
+		GroupID: f.group.ID,
+		Day:     pgtype.Date{Time: today.AddDate(0, 0, -6), Valid: true},
+	})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("SummarizeShadowRejections() = %+v, %v", rows, err)
+	}
+	if rows[0].Reason != "From not verified" || rows[0].Count != 3 || !rows[0].LastSeenAt.Valid || rows[1].Count != 1 {
+		t.Errorf("unexpected summary: %+v", rows)
+	}
+
+	if err := q.DeleteShadowPolicies(ctx, f.group.ID); err != nil {
+		t.Fatalf("DeleteShadowPolicies() error: %v", err)
+	}
+	if policies, err := q.ListShadowPolicies(ctx, f.group.ID); err != nil || len(policies) != 0 {
+		t.Errorf("ListShadowPolicies() after delete = %v, %v", policies, err)
+	}
+}
+
+func TestSendWindow(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	if _, err := q.GetSendWindow(ctx, f.group.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetSendWindow() before upsert error = %v, want pgx.ErrNoRows", err)
+	}
+	for _, tz := range []string{"UTC", "Asia/Seoul"} {
+		if _, err := q.UpsertSendWindow(ctx, storage.UpsertSendWindowParams{
+			GroupID:  f.group.ID,
+			Timezone: tz,
+			Windows:  []byte(`[{"days":["mon"],"start":"09:00","end":"18:00"}]`),
+		}); err != nil {
+			t.Fatalf("UpsertSendWindow() error: %v", err)
+		}
+	}
+	sw, err := q.GetSendWindow(ctx, f.group.ID)
+	if err != nil || sw.Timezone != "Asia/Seoul" || !strings.Contains(string(sw.Windows), "09:00") || !sw.UpdatedAt.Valid {
+		t.Fatalf("GetSendWindow() = %+v, %v", sw, err)
+	}
+	if n, err := q.DeleteSendWindow(ctx, f.group.ID); err != nil || n != 1 {
+		t.Errorf("DeleteSendWindow() = %d, %v", n, err)
+	}
+}
+
+func TestMessageDefaults(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+
+	if _, err := q.GetMessageDefaults(ctx, f.group.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("GetMessageDefaults() before upsert error = %v, want pgx.ErrNoRows", err)
+	}
+	for _, footer := range []string{"Example", "Example Inc., 1 Main St"} {
+		if _, err := q.UpsertMessageDefaults(ctx, storage.UpsertMessageDefaultsParams{
+			GroupID:          f.group.ID,
+			Headers:          []byte(`{"Organization":"Example Inc."}`),
+			FooterText:       footer,
+			ExcludeTags:      []byte(`["transactional"]`),
+			ExcludeTemplates: []byte(`[]`),
+		}); err != nil {
+			t.Fatalf("UpsertMessageDefaults() error: %v", err)
+		}
+	}
+	md, err := q.GetMessageDefaults(ctx, f.group.ID)
+	if err != nil || md.FooterText != "Example Inc., 1 Main St" || !strings.Contains(string(md.Headers), "Organization") || !md.UpdatedAt.Valid {
+		t.Fatalf("GetMessageDefaults() = %+v, %v", md, err)
+	}
+	if n, err := q.DeleteMessageDefaults(ctx, f.group.ID); err != nil || n != 1 {
+		t.Errorf("DeleteMessageDefaults() = %d, %v", n, err)
+	}
+}
+
+func TestProviderDailySends(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+	p, err := q.CreateProvider(ctx, storage.CreateProviderParams{
+		GroupID:      f.group.ID,
+		Name:         "capped",
+		ProviderType: storage.ProviderTypeSmtp,
+		Enabled:      true,
+	})
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+
+	day := pgtype.Date{Time: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), Valid: true}
+	reserve := storage.ReserveProviderDailySendParams{ProviderID: p.ID, Day: day, DailyCap: 2}
+	for want := int32(1); want <= 2; want++ {
+		if sent, err := q.ReserveProviderDailySend(ctx, reserve); err != nil || sent != want {
+			t.Fatalf("ReserveProviderDailySend() = %d, %v, want %d", sent, err, want)
+		}
+	}
+	if _, err := q.ReserveProviderDailySend(ctx, reserve); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("ReserveProviderDailySend() over the cap error = %v, want pgx.ErrNoRows", err)
+	}
+
+	rows, err := q.ListProviderDailySendsByGroupID(ctx, storage.ListProviderDailySendsByGroupIDParams{GroupID: f.group.ID, Day: day})
+	if err != nil || len(rows) != 1 || rows[0].Sent != 2 || !rows[0].Day.Time.Equal(day.Time) {
+		t.Fatalf("ListProviderDailySendsByGroupID() = %+v, %v", rows, err)
+	}
+}
+
+func TestSuppressions(t *testing.T) {
+	db := openTestDB(t)
+	q := db.Queries()
+	ctx := context.Background()
+	f := newFixture(t, q, `[]`)
+	groupID := pgtype.UUID{Bytes: f.group.ID, Valid: true}
+
+	msg, err := q.EnqueueMessage(ctx, storage.EnqueueMessageParams{
+		GroupID:    groupID,
+		Sender:     "news@example.com",
+		Recipients: []byte(`["Alice@Example.com"]`),
+		Headers:    []byte(`{"Message-Id":["<n1@example.com>"]}`),
+		Tags:       []byte(`[]`),
+	})
+	if err != nil {
+		t.Fatalf("EnqueueMessage() error: %v", err)
+	}
+	found, err := q.FindMessageByHeaderMessageID(ctx, storage.FindMessageByHeaderMessageIDParams{
+		GroupID:         groupID,
+		HeaderMessageID: "<n1@example.com>",
+		Recipient:       pgtype.Text{String: "alice@example.com", Valid: true},
+	})
+	if err != nil || found.ID != msg.ID {
+		t.Fatalf("FindMessageByHeaderMessageID() = %v, %v; want %v", found.ID, err, msg.ID)
+	}
+	if _, err := q.FindMessageByHeaderMessageID(ctx, storage.FindMessageByHeaderMessageIDParams{
+		GroupID:         groupID,
+		HeaderMessageID: "<n1@example.com>",
+		Recipient:       pgtype.Text{String: "bob@example.com", Valid: true},
+	}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("FindMessageByHeaderMessageID(other recipient) error = %v, want pgx.ErrNoRows", err)
+	}
+
+	for range 2 {
+		if err := q.AddSuppression(ctx, storage.AddSuppressionParams{
+			GroupID:   f.group.ID,
+			Address:   "Alice@Example.com",
+			Reason:    "complaint",
+			MessageID: pgtype.UUID{Bytes: msg.ID, Valid: true},
+		}); err != nil {
+			t.Fatalf("AddSuppression() error: %v", err)
+		}
+	}
+	if ok, err := q.IsRecipientSuppressed(ctx, storage.IsRecipientSuppressedParams{GroupID: f.group.ID, Address: "ALICE@example.com"}); err != nil || !ok {
+		t.Errorf("IsRecipientSuppressed() = %v, %v; want true", ok, err)
+	}
+	list, err := q.ListSuppressions(ctx, storage.ListSuppressionsParams{GroupID: f.group.ID, Limit: 10})
+	if err != nil || len(list) != 1 || list[0].Address != "alice@example.com" || uuid.UUID(list[0].MessageID.Bytes) != msg.ID {
+		t.Fatalf("ListSuppressions() = %+v, %v", list, err)
+	}
+	if n, err := q.DeleteSuppression(ctx, storage.DeleteSuppressionParams{GroupID: f.group.ID, Address: "alice@example.com"}); err != nil || n != 1 {
+		t.Errorf("DeleteSuppression() = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,188 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"regexp"
+	"sync"
+)
+
+var (
+	queryName   = regexp.MustCompile(`^-- name: (\w+) :`)
+	placeholder = regexp.MustCompile(`\$(\d+)`)
+	typeCast    = regexp.MustCompile(`::[a-z_][a-z0-9_]*(\[\])?`)
+)
+
+var translated sync.Map // PostgreSQL query -> SQLite query
+
+// translate returns the SQLite form of a query generated by sqlc. Queries
+// listed in overrides are replaced wholesale; the rest only need $N
+// placeholders renumbered to ?N and ::type casts removed.
+func translate(query string) string {
+	if q, ok := translated.Load(query); ok {
+		return q.(string)
+	}
+	q := ""
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		q = overrides[m[1]]
+	}
+	if q == "" {
+		q = placeholder.ReplaceAllString(query, "?$1")
+		q = typeCast.ReplaceAllString(q, "")
+	}
+	translated.Store(query, q)
+	return q
+}
+
+// overrides holds SQLite versions of the queries that use PostgreSQL-only
+// features, keyed by sqlc query name. Parameters keep their sqlc order.
+var overrides = map[string]string{
+	// AVG()::integer rounds in PostgreSQL.
+	"AverageDeliveryDuration": `
+SELECT provider, CAST(ROUND(AVG(duration_ms)) AS INTEGER) AS avg_duration_ms, COUNT(*) AS count
+FROM delivery_logs
+WHERE duration_ms IS NOT NULL AND created_at >= ?1 AND created_at <= ?2
+GROUP BY provider`,
+
+	// percentile_cont ... WITHIN GROUP and EXTRACT(EPOCH).
+	"DeliveryLatencyPercentiles": `
+SELECT dl.group_id, dl.provider, COUNT(*) AS deliveries,
+    percentile_cont(epoch(dl.delivered_at) - epoch(m.enqueued_at), 0.50) AS p50_seconds,
+    percentile_cont(epoch(dl.delivered_at) - epoch(m.enqueued_at), 0.95) AS p95_seconds,
+    percentile_cont(epoch(dl.delivered_at) - epoch(m.enqueued_at), 0.99) AS p99_seconds
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.status = 'delivered' AND dl.group_id IS NOT NULL AND dl.delivered_at >= ?1
+GROUP BY dl.group_id, dl.provider`,
+
+	// date_trunc('day', ...)::date.
+	"DailyDeliveryVolume": `
+SELECT provider_id, group_id, substr(created_at, 1, 10) AS day, COUNT(*) AS count
+FROM delivery_logs
+WHERE status = 'delivered' AND provider_id IS NOT NULL AND created_at >= ?1 AND created_at < ?2
+GROUP BY provider_id, group_id, day
+ORDER BY day`,
+
+	// UPDATE ... FROM with an aliased target.
+	"ScrubGroupDeliveryLogs": `
+UPDATE delivery_logs
+SET response_body = regexp_replace(response_body, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    last_error = regexp_replace(last_error, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    metadata = regexp_replace(metadata, '[^[:space:]@<>",;:]+@[^[:space:]@<>",;]+', '[redacted]', 'g'),
+    updated_at = now()
+WHERE message_id IN (SELECT id FROM messages WHERE group_id = ?1)`,
+
+	// The jsonb ? operator.
+	"ListGroupMessages": `
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages
+WHERE group_id = ?1
+  AND (?2 IS NULL OR EXISTS (SELECT 1 FROM json_each(messages.tags) WHERE value = ?2))
+  AND (?3 IS NULL OR status = ?3)
+ORDER BY enqueued_at DESC
+LIMIT ?4`,
+
+	// jsonb_array_elements_text.
+	"CountGroupMessagesByTag": `
+SELECT t.value AS tag, m.status, COUNT(*) AS messages
+FROM messages m, json_each(m.tags) AS t
+WHERE m.group_id = ?1 AND m.enqueued_at >= ?2 AND m.enqueued_at < ?3
+GROUP BY t.value, m.status
+ORDER BY t.value, m.status`,
+
+	// make_interval and FOR UPDATE SKIP LOCKED; SQLite serializes writers,
+	// so a plain UPDATE claims the batch atomically.
+	"ClaimOutboxEntries": `
+UPDATE outbox_entries
+SET claimed_until = add_seconds(now(), ?1)
+WHERE id IN (
+    SELECT id FROM outbox_entries
+    WHERE claimed_until IS NULL OR claimed_until < now()
+    ORDER BY created_at ASC
+    LIMIT ?2
+)
+RETURNING id, message_id, group_id, user_id, attempts, last_error, claimed_until, created_at, request_id`,
+
+	// = ANY(text[]); the array argument is passed as a JSON array.
+	"GetSmtpClientCertBySAN": `
+SELECT id, user_id, fingerprint, san, description, last_used_at, created_at FROM smtp_client_certs
+WHERE san IN (SELECT value FROM json_each(?1))
+ORDER BY created_at
+LIMIT 1`,
+
+	// to_timestamp(floor(EXTRACT(EPOCH ...))), FILTER on percentile_cont
+	// and WITHIN GROUP.
+	"DeliveryTimeSeries": `
+SELECT to_timestamp(CAST(epoch(dl.created_at) / ?1 AS INTEGER) * ?1) AS bucket,
+    COUNT(*) AS attempts,
+    COUNT(*) FILTER (WHERE dl.status IN ('delivered', 'sent')) AS delivered,
+    COUNT(*) FILTER (WHERE dl.status = 'bounced') AS bounced,
+    COUNT(*) FILTER (WHERE dl.status = 'complained') AS complained,
+    COUNT(*) FILTER (WHERE dl.status = 'failed') AS failed,
+    COALESCE(AVG(dl.duration_ms), 0) AS avg_duration_ms,
+    COALESCE(percentile_cont(CASE WHEN dl.status IN ('delivered', 'sent') THEN epoch(dl.delivered_at) - epoch(m.enqueued_at) END, 0.50), 0) AS p50_seconds,
+    COALESCE(percentile_cont(CASE WHEN dl.status IN ('delivered', 'sent') THEN epoch(dl.delivered_at) - epoch(m.enqueued_at) END, 0.95), 0) AS p95_seconds
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE dl.group_id = ?2
+  AND dl.created_at >= ?3 AND dl.created_at < ?4
+  AND (?5 IS NULL OR dl.provider = ?5)
+GROUP BY bucket
+ORDER BY bucket`,
+
+	// = ANY(uuid[]); the array argument is passed as a JSON array.
+	"DeleteArchivedDeliveryLogs": `
+DELETE FROM delivery_logs WHERE id IN (SELECT value FROM json_each(?1))`,
+
+	// The jsonb ? operator.
+	"ListDeliveryReportMessages": `
+SELECT id, recipients, status, enqueued_at, processed_at, request_id
+FROM messages
+WHERE group_id = ?1
+  AND EXISTS (SELECT 1 FROM json_each(messages.tags) WHERE value = ?2)
+  AND enqueued_at >= ?3 AND enqueued_at < ?4
+  AND (enqueued_at > ?5 OR (enqueued_at = ?5 AND id > ?6))
+ORDER BY enqueued_at ASC, id ASC
+LIMIT ?7`,
+
+	// = ANY(uuid[]); the array argument is passed as a JSON array.
+	"ListDeliveryLogsByMessageIDs": `
+SELECT id, message_id, provider_id, status, response_code, response_body, delivered_at, provider, provider_message_id, retry_count, last_error, metadata, created_at, updated_at, duration_ms, attempt_number, user_id, group_id, request_id, egress_ip, remote_addr, provider_endpoint, tls_version, tls_cipher, connect_ms, tls_handshake_ms, first_byte_ms FROM delivery_logs
+WHERE message_id IN (SELECT value FROM json_each(?1))
+ORDER BY created_at ASC`,
+
+	// make_interval and FOR UPDATE SKIP LOCKED, as in ClaimOutboxEntries.
+	"ClaimDeliveryReport": `
+UPDATE delivery_reports
+SET status = 'running', started_at = now()
+WHERE id = (
+    SELECT id FROM delivery_reports
+    WHERE status = 'pending'
+       OR (status = 'running' AND started_at < add_seconds(now(), -?1))
+    ORDER BY created_at ASC
+    LIMIT 1
+)
+RETURNING id, group_id, user_id, tag, since, until, status, object_key, row_count, truncated, error, started_at, completed_at, created_at`,
+
+	// jsonb @> on the lowercased recipients.
+	"ListRecipientDeliveryLogs": `
+SELECT dl.id, dl.message_id, dl.provider_id, dl.status, dl.response_code, dl.response_body, dl.delivered_at, dl.provider, dl.provider_message_id, dl.retry_count, dl.last_error, dl.metadata, dl.created_at, dl.updated_at, dl.duration_ms, dl.attempt_number, dl.user_id, dl.group_id, dl.request_id, dl.egress_ip, dl.remote_addr, dl.provider_endpoint, dl.tls_version, dl.tls_cipher, dl.connect_ms, dl.tls_handshake_ms, dl.first_byte_ms, m.sender, m.subject
+FROM delivery_logs dl
+JOIN messages m ON m.id = dl.message_id
+WHERE m.group_id = ?1
+  AND EXISTS (SELECT 1 FROM json_each(m.recipients) WHERE lower(value) = lower(?2))
+  AND (?3 IS NULL OR dl.created_at >= ?3)
+  AND (?4 IS NULL OR dl.created_at < ?4)
+ORDER BY dl.created_at DESC
+LIMIT ?5`,
+
+	// The jsonb -> and ? operators and jsonb @> on the lowercased
+	// recipients.
+	"FindMessageByHeaderMessageID": `
+SELECT id, sender, recipients, subject, headers, body, status, provider_id, enqueued_at, processed_at, storage_ref, group_id, user_id, requeue_count, size_bytes, inbound_route_id, tags, metadata, tls_version, tls_cipher, request_id FROM messages
+WHERE group_id = ?1
+  AND EXISTS (SELECT 1 FROM json_each(messages.headers, '$."Message-Id"') WHERE value = ?2)
+  AND (?3 IS NULL
+       OR EXISTS (SELECT 1 FROM json_each(messages.recipients) WHERE lower(value) = lower(?3)))
+ORDER BY enqueued_at DESC
+LIMIT 1`,
+}
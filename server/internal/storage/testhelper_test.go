@@ -6,13 +6,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"runtime"
-	"sort"
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/sungwon/smtp-proxy/server/internal/migrate"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -72,7 +70,7 @@ func TestMain(m *testing.M) {
 	}
 
 	// Create shared DB pool
-	sharedDB, err = storage.NewDB(ctx, sharedDSN, 2, 10, 10*time.Second)
+	sharedDB, err = storage.NewDB(ctx, sharedDSN, storage.PoolConfig{MinConns: 2, MaxConns: 10, ConnectTimeout: 10 * time.Second})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create DB: %v\n", err)
 		os.Exit(1)
@@ -97,41 +95,7 @@ func setupTestDB(t *testing.T) (*storage.DB, *storage.Queries) {
 	return sharedDB, queries
 }
 
-// execMigrations runs all up migration files in order.
+// execMigrations applies the embedded migrations.
 func execMigrations(ctx context.Context, dsn string) error {
-	pool, err := pgxpool.New(ctx, dsn)
-	if err != nil {
-		return fmt.Errorf("connect for migrations: %w", err)
-	}
-	defer pool.Close()
-
-	_, filename, _, _ := runtime.Caller(0)
-	migrationsDir := filepath.Join(filepath.Dir(filename), "..", "..", "migrations")
-
-	entries, err := os.ReadDir(migrationsDir)
-	if err != nil {
-		return fmt.Errorf("read migrations directory: %w", err)
-	}
-
-	var upFiles []string
-	for _, e := range entries {
-		if !e.IsDir() && filepath.Ext(e.Name()) == ".sql" && len(e.Name()) > 7 && e.Name()[len(e.Name())-7:] == ".up.sql" {
-			upFiles = append(upFiles, e.Name())
-		}
-	}
-	sort.Strings(upFiles)
-
-	for _, f := range upFiles {
-		content, err := os.ReadFile(filepath.Join(migrationsDir, f))
-		if err != nil {
-			return fmt.Errorf("read migration %s: %w", f, err)
-		}
-
-		_, err = pool.Exec(ctx, string(content))
-		if err != nil {
-			return fmt.Errorf("execute migration %s: %w", f, err)
-		}
-	}
-
-	return nil
+	return migrate.Run(ctx, dsn, zerolog.Nop())
 }
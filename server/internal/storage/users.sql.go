@@ -10,12 +10,13 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, password_hash, account_type, username, api_key, allowed_domains)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains
+RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -50,6 +51,11 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -64,7 +70,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const getUserByAPIKey = `-- name: GetUserByAPIKey :one
-SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains FROM users WHERE api_key = $1
+SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at FROM users WHERE api_key = $1
 `
 
 func (q *Queries) GetUserByAPIKey(ctx context.Context, apiKey sql.NullString) (User, error) {
@@ -83,12 +89,17 @@ func (q *Queries) GetUserByAPIKey(ctx context.Context, apiKey sql.NullString) (U
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains FROM users WHERE email = $1
+SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -107,12 +118,17 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains FROM users WHERE id = $1
+SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -131,12 +147,17 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains FROM users WHERE username = $1
+SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at FROM users WHERE username = $1
 `
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username sql.NullString) (User, error) {
@@ -155,6 +176,11 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username sql.NullString
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -170,8 +196,53 @@ func (q *Queries) IncrementFailedAttempts(ctx context.Context, id uuid.UUID) err
 	return err
 }
 
+const listExpiringSMTPPasswords = `-- name: ListExpiringSMTPPasswords :many
+SELECT u.id, u.username, u.email, u.password_changed_at, gm.group_id
+FROM users u
+JOIN group_members gm ON gm.user_id = u.id
+WHERE u.account_type = 'smtp'
+  AND u.status = 'active'
+  AND u.password_changed_at < $1
+  AND u.password_expiry_warned_at IS NULL
+ORDER BY u.password_changed_at
+`
+
+type ListExpiringSMTPPasswordsRow struct {
+	ID                uuid.UUID          `json:"id"`
+	Username          sql.NullString     `json:"username"`
+	Email             string             `json:"email"`
+	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
+	GroupID           uuid.UUID          `json:"group_id"`
+}
+
+func (q *Queries) ListExpiringSMTPPasswords(ctx context.Context, passwordChangedAt pgtype.Timestamptz) ([]ListExpiringSMTPPasswordsRow, error) {
+	rows, err := q.db.Query(ctx, listExpiringSMTPPasswords, passwordChangedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListExpiringSMTPPasswordsRow
+	for rows.Next() {
+		var i ListExpiringSMTPPasswordsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.PasswordChangedAt,
+			&i.GroupID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains FROM users ORDER BY created_at DESC
+SELECT id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at FROM users ORDER BY created_at DESC
 `
 
 func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
@@ -196,6 +267,11 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 			&i.AccountType,
 			&i.ApiKey,
 			&i.AllowedDomains,
+			&i.TlsPolicy,
+			&i.AutoBcc,
+			&i.PasswordChangedAt,
+			&i.PasswordExpiryWarnedAt,
+			&i.EmailVerifiedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -207,6 +283,28 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email_verified_at IS NULL
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markEmailVerified, id)
+	return err
+}
+
+const markPasswordExpiryWarned = `-- name: MarkPasswordExpiryWarned :exec
+UPDATE users
+SET password_expiry_warned_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkPasswordExpiryWarned(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markPasswordExpiryWarned, id)
+	return err
+}
+
 const resetFailedAttempts = `-- name: ResetFailedAttempts :exec
 UPDATE users
 SET failed_attempts = 0, updated_at = NOW()
@@ -218,11 +316,25 @@ func (q *Queries) ResetFailedAttempts(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const revokeUserAPIKey = `-- name: RevokeUserAPIKey :execrows
+UPDATE users
+SET api_key = NULL, updated_at = NOW()
+WHERE id = $1 AND api_key IS NOT NULL
+`
+
+func (q *Queries) RevokeUserAPIKey(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeUserAPIKey, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET email = $2, status = $3, allowed_domains = $4, updated_at = NOW()
 WHERE id = $1
-RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains
+RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at
 `
 
 type UpdateUserParams struct {
@@ -253,6 +365,48 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserAutoBCC = `-- name: UpdateUserAutoBCC :one
+UPDATE users
+SET auto_bcc = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at
+`
+
+type UpdateUserAutoBCCParams struct {
+	ID      uuid.UUID `json:"id"`
+	AutoBcc []byte    `json:"auto_bcc"`
+}
+
+func (q *Queries) UpdateUserAutoBCC(ctx context.Context, arg UpdateUserAutoBCCParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserAutoBCC, arg.ID, arg.AutoBcc)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Status,
+		&i.FailedAttempts,
+		&i.LastLogin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.AccountType,
+		&i.ApiKey,
+		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -270,7 +424,7 @@ func (q *Queries) UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error {
 
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
-SET password_hash = $2, updated_at = NOW()
+SET password_hash = $2, password_changed_at = NOW(), password_expiry_warned_at = NULL, updated_at = NOW()
 WHERE id = $1
 `
 
@@ -288,7 +442,7 @@ const updateUserStatus = `-- name: UpdateUserStatus :one
 UPDATE users
 SET status = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains
+RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at
 `
 
 type UpdateUserStatusParams struct {
@@ -312,6 +466,48 @@ func (q *Queries) UpdateUserStatus(ctx context.Context, arg UpdateUserStatusPara
 		&i.AccountType,
 		&i.ApiKey,
 		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const updateUserTLSPolicy = `-- name: UpdateUserTLSPolicy :one
+UPDATE users
+SET tls_policy = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, password_hash, status, failed_attempts, last_login, created_at, updated_at, username, account_type, api_key, allowed_domains, tls_policy, auto_bcc, password_changed_at, password_expiry_warned_at, email_verified_at
+`
+
+type UpdateUserTLSPolicyParams struct {
+	ID        uuid.UUID `json:"id"`
+	TlsPolicy []byte    `json:"tls_policy"`
+}
+
+func (q *Queries) UpdateUserTLSPolicy(ctx context.Context, arg UpdateUserTLSPolicyParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserTLSPolicy, arg.ID, arg.TlsPolicy)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Status,
+		&i.FailedAttempts,
+		&i.LastLogin,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.AccountType,
+		&i.ApiKey,
+		&i.AllowedDomains,
+		&i.TlsPolicy,
+		&i.AutoBcc,
+		&i.PasswordChangedAt,
+		&i.PasswordExpiryWarnedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
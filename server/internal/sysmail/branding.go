@@ -0,0 +1,60 @@
+package sysmail
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/i18n"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Branding customizes the system emails sent on behalf of a group. The
+// zero value renders the built-in templates from Config.From.
+type Branding struct {
+	// From overrides Config.From as the sender address.
+	From string
+	// LogoURL, when set, adds an HTML alternative showing the logo above
+	// the text.
+	LogoURL string
+	// Footer is appended to every email.
+	Footer string
+	// Templates overrides built-in templates by name.
+	Templates map[string]Template
+	// Locale selects the language of the built-in templates. Unsupported
+	// locales get English.
+	Locale string
+}
+
+// BrandingFromStorage converts a group_branding row. Templates that do
+// not decode are ignored.
+func BrandingFromStorage(row storage.GroupBranding) Branding {
+	b := Branding{
+		From:    row.FromAddress,
+		LogoURL: row.LogoUrl,
+		Footer:  row.Footer,
+	}
+	if len(row.Templates) > 0 {
+		_ = json.Unmarshal(row.Templates, &b.Templates)
+	}
+	return b
+}
+
+// branding returns the branding of groupID in the group's locale. Emails
+// not sent on behalf of a group are written in the locale of the request
+// in ctx. Groups without branding, and lookups that fail, get the zero
+// Branding so the email is still sent.
+func (m *Mailer) branding(ctx context.Context, groupID uuid.UUID) Branding {
+	if groupID == uuid.Nil {
+		return Branding{Locale: i18n.FromContext(ctx)}
+	}
+	var b Branding
+	if row, err := m.queries.GetGroupBranding(ctx, groupID); err == nil {
+		b = BrandingFromStorage(row)
+	}
+	if group, err := m.queries.GetGroupByID(ctx, groupID); err == nil {
+		b.Locale = group.Locale
+	}
+	return b
+}
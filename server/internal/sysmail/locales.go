@@ -0,0 +1,90 @@
+package sysmail
+
+// localizedTemplates translate defaultTemplates, by locale. A template
+// missing from a locale falls back to English.
+var localizedTemplates = map[string]map[string]Template{
+	"ko": {
+		TemplateInvitation: {
+			Subject: "[smtp-proxy] {{.Group}} 그룹에 초대되었습니다",
+			Body: "{{.Inviter}} 님이 smtp-proxy의 {{.Group}} 그룹에 {{.Role}} 역할로 초대했습니다.\n\n" +
+				"아래 링크에서 초대를 수락하고 비밀번호를 설정하세요:\n\n{{.Link}}\n\n" +
+				"초대는 {{date .ExpiresAt}}에 만료됩니다. 예상하지 못한 초대라면 이 메일을 무시하셔도 됩니다.\n",
+		},
+		TemplateQuotaWarning: {
+			Subject: "[smtp-proxy] {{.Group}} {{if ge .Threshold 100}}그룹이 월간 발송 한도에 도달했습니다" +
+				"{{else}}그룹이 월간 발송 한도의 {{.Threshold}}%를 사용했습니다{{end}}",
+			Body: "{{printf \"%q\" .Group}} 그룹은 이번 달 허용된 {{.Limit}}건 중 {{.Sent}}건을 발송했습니다 ({{.Percent}}%).\n" +
+				"{{if ge .Threshold 100}}한도는 다음 달 초에 초기화됩니다." +
+				"{{else}}한도에 도달하기 전에 관리자에게 한도 상향을 요청하세요.{{end}}\n",
+		},
+		TemplateUnlock: {
+			Subject: "[smtp-proxy] 계정이 잠겼습니다",
+			Body: "로그인 실패가 여러 번 발생하여 {{.Email}} 계정이 잠겼습니다.\n\n" +
+				"본인의 시도였다면 지금 계정 잠금을 해제하세요:\n\n{{.Link}}\n\n" +
+				"그렇지 않으면 계정은 {{.Lockout}} 후 자동으로 잠금 해제됩니다. 로그인을 시도한 적이 없다면 " +
+				"누군가 비밀번호를 추측하고 있을 수 있으니 비밀번호 변경을 고려하세요.\n",
+		},
+		TemplateSignInNotice: {
+			Subject: "[smtp-proxy] 계정에 새로운 로그인이 있습니다",
+			Body: "{{date .Time}}에 이전에 사용된 적 없는 {{.Location}}에서 {{.Email}} 계정으로 로그인했습니다.\n\n" +
+				"본인이었다면 조치할 필요가 없습니다. 그렇지 않다면 비밀번호를 변경하고 " +
+				"알 수 없는 세션을 해지하세요:\n\n{{.Link}}\n",
+		},
+		TemplateVerifyEmail: {
+			Subject: "[smtp-proxy] 이메일 주소를 확인하세요",
+			Body: "이 주소로 smtp-proxy 가입이 요청되었습니다.\n\n" +
+				"주소를 확인하면 계정과 {{.Group}} 그룹이 생성됩니다:\n\n{{.Link}}\n\n" +
+				"링크는 {{date .ExpiresAt}}에 만료됩니다. 가입한 적이 없다면 이 메일을 무시하셔도 됩니다.\n",
+		},
+		TemplateSignupNotice: {
+			Subject: "[smtp-proxy] 이미 계정이 있습니다",
+			Body: "이미 계정이 있는 이 주소로 smtp-proxy 가입이 시도되었습니다.\n\n" +
+				"본인이었다면 기존 비밀번호로 로그인하세요. 그렇지 않다면 이 메일을 무시하셔도 됩니다.\n",
+		},
+	},
+	"ja": {
+		TemplateInvitation: {
+			Subject: "[smtp-proxy] {{.Group}} に招待されました",
+			Body: "{{.Inviter}} さんから smtp-proxy のグループ {{.Group}} に {{.Role}} として招待されました。\n\n" +
+				"次のリンクから招待を承認し、パスワードを設定してください:\n\n{{.Link}}\n\n" +
+				"招待の有効期限は {{date .ExpiresAt}} です。心当たりがない場合は、このメールを無視してください。\n",
+		},
+		TemplateQuotaWarning: {
+			Subject: "[smtp-proxy] {{.Group}} {{if ge .Threshold 100}}が月間送信上限に達しました" +
+				"{{else}}が月間送信上限の {{.Threshold}}% を使用しました{{end}}",
+			Body: "グループ {{printf \"%q\" .Group}} は今月の上限 {{.Limit}} 通のうち {{.Sent}} 通を送信しました ({{.Percent}}%)。\n" +
+				"{{if ge .Threshold 100}}上限は来月の初めにリセットされます。" +
+				"{{else}}上限に達する前に、管理者に上限の引き上げを依頼してください。{{end}}\n",
+		},
+		TemplateUnlock: {
+			Subject: "[smtp-proxy] アカウントがロックされました",
+			Body: "ログインの失敗が続いたため、アカウント {{.Email}} がロックされました。\n\n" +
+				"ご自身による操作であれば、今すぐロックを解除できます:\n\n{{.Link}}\n\n" +
+				"解除しない場合、アカウントは {{.Lockout}} 後に自動的にロック解除されます。ログインを試みていない場合は、" +
+				"第三者がパスワードを推測している可能性があります。パスワードの変更をご検討ください。\n",
+		},
+		TemplateSignInNotice: {
+			Subject: "[smtp-proxy] アカウントへの新しいサインイン",
+			Body: "{{date .Time}} に、これまで使用されたことのない {{.Location}} からアカウント {{.Email}} にログインがありました。\n\n" +
+				"ご自身であれば対応は不要です。心当たりがない場合は、パスワードを変更し、" +
+				"不明なセッションを無効にしてください:\n\n{{.Link}}\n",
+		},
+		TemplateVerifyEmail: {
+			Subject: "[smtp-proxy] メールアドレスを確認してください",
+			Body: "このアドレスで smtp-proxy への登録が申請されました。\n\n" +
+				"アドレスを確認すると、アカウントとグループ {{.Group}} が作成されます:\n\n{{.Link}}\n\n" +
+				"リンクの有効期限は {{date .ExpiresAt}} です。登録した覚えがない場合は、このメールを無視してください。\n",
+		},
+		TemplateSignupNotice: {
+			Subject: "[smtp-proxy] アカウントは既に存在します",
+			Body: "既にアカウントが存在するこのアドレスで、smtp-proxy への登録が試みられました。\n\n" +
+				"ご自身であれば、既存のパスワードでログインしてください。心当たりがない場合は、このメールを無視してください。\n",
+		},
+	},
+}
+
+// dateLayouts format the date template function, by locale.
+var dateLayouts = map[string]string{
+	"ko": "2006년 1월 2일 15:04 MST",
+	"ja": "2006年1月2日 15:04 MST",
+}
@@ -0,0 +1,224 @@
+// Package sysmail sends system-generated emails, such as account unlock
+// links and sign-in notices, through the proxy's own delivery pipeline.
+// Emails are rendered from named templates that groups can brand with
+// their own sender address, logo, footer and template overrides.
+package sysmail
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Config holds the sender and link settings of system emails.
+type Config struct {
+	// From is the sender address of system emails.
+	From string
+	// BaseURL is the externally reachable URL emailed links point to,
+	// e.g. https://mail.example.com.
+	BaseURL string
+}
+
+// DefaultFrom is the sender address used when Config.From is empty.
+const DefaultFrom = "smtp-proxy@localhost"
+
+// Mailer enqueues emails under the system group, like any
+// submitted message (messages row plus outbox entry), so they are
+// delivered by the system group's providers and do not count against the
+// recipient's group.
+type Mailer struct {
+	queries storage.Querier
+	tx      storage.TxRunner
+	config  Config
+	now     func() time.Time
+}
+
+// New creates a Mailer. Messages are enqueued in transactions run by tx.
+func New(queries storage.Querier, tx storage.TxRunner, cfg Config) *Mailer {
+	if cfg.From == "" {
+		cfg.From = DefaultFrom
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &Mailer{
+		queries: queries,
+		tx:      tx,
+		config:  cfg,
+		now:     time.Now,
+	}
+}
+
+// Link returns the absolute URL of path under the configured base URL.
+func (m *Mailer) Link(path string, query url.Values) string {
+	link := m.config.BaseURL + "/" + strings.TrimLeft(path, "/")
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// Message is a rendered system email. HTML, when set, is sent as an
+// alternative to Text.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Send enqueues a plain-text email with the given subject and body to the
+// recipients.
+func (m *Mailer) Send(ctx context.Context, to []string, subject, text string) error {
+	return m.send(ctx, Message{From: m.config.From, To: to, Subject: subject, Text: text})
+}
+
+// SendTemplate renders the named template with the branding of groupID and
+// enqueues it in the group's locale. Emails that are not about a group pass
+// uuid.Nil and get the default branding in the locale of the request in
+// ctx.
+func (m *Mailer) SendTemplate(ctx context.Context, groupID uuid.UUID, to []string, name string, data Data) error {
+	msg, err := m.Compose(ctx, groupID, to, name, data)
+	if err != nil {
+		return err
+	}
+	return m.send(ctx, msg)
+}
+
+// Compose renders the named template with the branding of groupID. When a
+// group's template override fails to render, the built-in template is
+// used instead.
+func (m *Mailer) Compose(ctx context.Context, groupID uuid.UUID, to []string, name string, data Data) (Message, error) {
+	branding := m.branding(ctx, groupID)
+	subject, text, html, err := branding.Render(name, data)
+	if err != nil && len(branding.Templates) > 0 {
+		branding.Templates = nil
+		subject, text, html, err = branding.Render(name, data)
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("render %s email: %w", name, err)
+	}
+	from := m.config.From
+	if branding.From != "" {
+		from = branding.From
+	}
+	return Message{From: from, To: to, Subject: subject, Text: text, HTML: html}, nil
+}
+
+func (m *Mailer) send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("send system email: no recipients")
+	}
+	return m.tx.ExecTx(ctx, func(q storage.Querier) error {
+		return m.Enqueue(ctx, q, msg)
+	})
+}
+
+// Enqueue inserts msg under the system group through q, so callers can
+// enqueue it in a transaction of their own.
+func (m *Mailer) Enqueue(ctx context.Context, q storage.Querier, msg Message) error {
+	body := m.build(msg)
+	recipientsJSON, _ := json.Marshal(msg.To)
+	headersJSON, _ := json.Marshal(map[string][]string{
+		"From":    {msg.From},
+		"To":      {strings.Join(msg.To, ", ")},
+		"Subject": {msg.Subject},
+	})
+
+	system, err := q.GetGroupByName(ctx, "system")
+	if err != nil {
+		return fmt.Errorf("load system group: %w", err)
+	}
+	inserted, err := q.EnqueueMessage(ctx, storage.EnqueueMessageParams{
+		GroupID:    pgtype.UUID{Bytes: system.ID, Valid: true},
+		Sender:     msg.From,
+		Recipients: recipientsJSON,
+		Subject:    sql.NullString{String: msg.Subject, Valid: true},
+		Headers:    headersJSON,
+		Body:       pgtype.Text{String: body, Valid: true},
+		SizeBytes:  int64(len(body)),
+	})
+	if err != nil {
+		return fmt.Errorf("insert message: %w", err)
+	}
+	if _, err := q.CreateOutboxEntry(ctx, storage.CreateOutboxEntryParams{
+		MessageID: inserted.ID,
+		GroupID:   system.ID,
+	}); err != nil {
+		return fmt.Errorf("insert outbox entry: %w", err)
+	}
+	return nil
+}
+
+// build renders the RFC 5322 message. Non-ASCII subjects are encoded as
+// RFC 2047 words and non-ASCII bodies as quoted-printable. Messages with an
+// HTML alternative are multipart/alternative with quoted-printable parts.
+func (m *Mailer) build(msg Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", m.now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		if isASCII(msg.Text) {
+			b.WriteString("\r\n")
+			b.WriteString(crlf(msg.Text))
+			return b.String()
+		}
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&b)
+		_, _ = qp.Write([]byte(crlf(msg.Text)))
+		_ = qp.Close()
+		return b.String()
+	}
+
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(w)
+		_, _ = qp.Write([]byte(crlf(part.content)))
+		_ = qp.Close()
+	}
+	_ = mw.Close()
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	b.Write(parts.Bytes())
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// crlf converts text to CRLF line endings with a single trailing CRLF.
+func crlf(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\r\n") + "\r\n"
+}
@@ -0,0 +1,217 @@
+package sysmail
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/i18n"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakeQuerier records enqueued messages. Methods the mailer does not use
+// panic through the nil embedded interface.
+type fakeQuerier struct {
+	storage.Querier
+	system   uuid.UUID
+	branding map[uuid.UUID]storage.GroupBranding
+	locales  map[uuid.UUID]string
+	messages []storage.EnqueueMessageParams
+	outbox   []storage.CreateOutboxEntryParams
+}
+
+func (f *fakeQuerier) GetGroupBranding(_ context.Context, groupID uuid.UUID) (storage.GroupBranding, error) {
+	b, ok := f.branding[groupID]
+	if !ok {
+		return storage.GroupBranding{}, errors.New("not found")
+	}
+	return b, nil
+}
+
+func (f *fakeQuerier) GetGroupByID(_ context.Context, id uuid.UUID) (storage.Group, error) {
+	locale, ok := f.locales[id]
+	if !ok {
+		return storage.Group{}, errors.New("not found")
+	}
+	return storage.Group{ID: id, Locale: locale}, nil
+}
+
+func (f *fakeQuerier) GetGroupByName(_ context.Context, name string) (storage.Group, error) {
+	if name != "system" || f.system == uuid.Nil {
+		return storage.Group{}, errors.New("not found")
+	}
+	return storage.Group{ID: f.system, Name: name}, nil
+}
+
+func (f *fakeQuerier) EnqueueMessage(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+	f.messages = append(f.messages, arg)
+	return storage.Message{ID: uuid.New()}, nil
+}
+
+func (f *fakeQuerier) CreateOutboxEntry(_ context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+	f.outbox = append(f.outbox, arg)
+	return storage.OutboxEntry{}, nil
+}
+
+type fakeTx struct{ q storage.Querier }
+
+func (t fakeTx) ExecTx(_ context.Context, fn func(storage.Querier) error) error {
+	return fn(t.q)
+}
+
+func TestMailer_Send(t *testing.T) {
+	q := &fakeQuerier{system: uuid.New()}
+	m := New(q, fakeTx{q}, Config{From: "noreply@example.com", BaseURL: "https://mail.example.com/"})
+	m.now = func() time.Time { return time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC) }
+
+	if err := m.Send(context.Background(), []string{"alice@example.com"}, "계정 잠금 해제", "line one\nline two\n"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(q.messages) != 1 || len(q.outbox) != 1 {
+		t.Fatalf("expected one message and outbox entry, got %d and %d", len(q.messages), len(q.outbox))
+	}
+	msg := q.messages[0]
+	if msg.GroupID.Bytes != q.system || msg.Sender != "noreply@example.com" || q.outbox[0].GroupID != q.system {
+		t.Errorf("message not enqueued under the system group: %+v", msg)
+	}
+	body := msg.Body.String
+	for _, want := range []string{"To: alice@example.com\r\n", "Subject: =?utf-8?q?", "\r\n\r\nline one\r\nline two\r\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+
+	if got := m.Link("/unlock", url.Values{"token": {"a b"}}); got != "https://mail.example.com/unlock?token=a+b" {
+		t.Errorf("Link() = %q", got)
+	}
+}
+
+func TestMailer_SendWithoutSystemGroup(t *testing.T) {
+	q := &fakeQuerier{}
+	m := New(q, fakeTx{q}, Config{})
+	if err := m.Send(context.Background(), []string{"alice@example.com"}, "s", "b"); err == nil {
+		t.Fatal("expected an error without a system group")
+	}
+	if len(q.messages) != 0 {
+		t.Error("message enqueued without a system group")
+	}
+}
+
+func TestMailer_SendTemplate(t *testing.T) {
+	groupID := uuid.New()
+	q := &fakeQuerier{system: uuid.New(), branding: map[uuid.UUID]storage.GroupBranding{groupID: {
+		FromAddress: "hello@acme.example",
+		LogoUrl:     "https://acme.example/logo.png",
+		Footer:      "Acme Inc., Seoul",
+		Templates:   []byte(`{"invitation":{"subject":"Join {{.Group}}\r\nBcc: x@example.com"}}`),
+	}}}
+	m := New(q, fakeTx{q}, Config{From: "noreply@example.com"})
+	data := Data{Group: "Acme", Inviter: "bob@acme.example", Role: "member", Link: "https://mail.example.com/accept"}
+
+	if err := m.SendTemplate(context.Background(), groupID, []string{"alice@example.com"}, TemplateInvitation, data); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	msg := q.messages[0]
+	if msg.Sender != "hello@acme.example" || msg.Subject.String != "Join Acme Bcc: x@example.com" {
+		t.Errorf("unexpected sender %q or subject %q", msg.Sender, msg.Subject.String)
+	}
+	body := msg.Body.String
+	for _, want := range []string{
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"bob@acme.example invited you to join the group Acme",
+		`<img src=3D"https://acme.example/logo.png"`,
+		"Acme Inc., Seoul",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+
+	// Groups without branding get the defaults.
+	if err := m.SendTemplate(context.Background(), uuid.New(), []string{"alice@example.com"}, TemplateInvitation, data); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	msg = q.messages[1]
+	if msg.Sender != "noreply@example.com" || msg.Subject.String != "[smtp-proxy] You are invited to join Acme" ||
+		strings.Contains(msg.Body.String, "multipart") {
+		t.Errorf("unexpected default email: %q, %q", msg.Sender, msg.Body.String)
+	}
+}
+
+func TestMailer_SendTemplate_Locale(t *testing.T) {
+	groupID := uuid.New()
+	q := &fakeQuerier{system: uuid.New(), locales: map[uuid.UUID]string{groupID: "ko"}}
+	m := New(q, fakeTx{q}, Config{From: "noreply@example.com"})
+	data := Data{Group: "Acme", Sent: 800, Limit: 1000, Percent: 80, Threshold: 80}
+
+	if err := m.SendTemplate(context.Background(), groupID, []string{"alice@example.com"}, TemplateQuotaWarning, data); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	if got := q.messages[0].Subject.String; got != "[smtp-proxy] Acme 그룹이 월간 발송 한도의 80%를 사용했습니다" {
+		t.Errorf("subject = %q", got)
+	}
+	if !strings.Contains(q.messages[0].Body.String, "Content-Transfer-Encoding: quoted-printable") {
+		t.Errorf("non-ASCII body not encoded:\n%s", q.messages[0].Body.String)
+	}
+
+	// Emails not about a group follow the request's locale; the group's
+	// locale wins over it.
+	ctx := i18n.WithLocale(context.Background(), "ja")
+	if err := m.SendTemplate(ctx, uuid.Nil, []string{"alice@example.com"}, TemplateSignupNotice, Data{}); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	if got := q.messages[1].Subject.String; got != "[smtp-proxy] アカウントは既に存在します" {
+		t.Errorf("subject = %q", got)
+	}
+	if err := m.SendTemplate(ctx, groupID, []string{"alice@example.com"}, TemplateSignupNotice, Data{}); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	if got := q.messages[2].Subject.String; got != "[smtp-proxy] 이미 계정이 있습니다" {
+		t.Errorf("subject = %q", got)
+	}
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+	for locale, templates := range localizedTemplates {
+		if !i18n.Supported(locale) {
+			t.Errorf("locale %q has no i18n catalog", locale)
+		}
+		for _, name := range TemplateNames() {
+			if _, ok := templates[name]; !ok {
+				t.Errorf("locale %q is missing template %q", locale, name)
+			}
+			b := Branding{Locale: locale}
+			if _, _, _, err := b.Render(name, sampleData); err != nil {
+				t.Errorf("%s/%s: %v", locale, name, err)
+			}
+		}
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]Template
+		wantErr   bool
+	}{
+		{"valid", map[string]Template{TemplateQuotaWarning: {Body: "{{.Group}} used {{.Percent}}%"}}, false},
+		{"unknown template", map[string]Template{"welcome": {Body: "hi"}}, true},
+		{"parse error", map[string]Template{TemplateUnlock: {Body: "{{.Link"}}, true},
+		{"unknown field", map[string]Template{TemplateUnlock: {Subject: "{{.Password}}"}}, true},
+		{"too large", map[string]Template{TemplateUnlock: {Body: "{{range 100000}}0123456789{{end}}"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTemplates(tt.overrides); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTemplates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
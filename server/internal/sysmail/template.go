@@ -0,0 +1,247 @@
+package sysmail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Names of the system email templates.
+const (
+	TemplateInvitation   = "invitation"
+	TemplateQuotaWarning = "quota_warning"
+	TemplateUnlock       = "unlock"
+	TemplateSignInNotice = "sign_in_notice"
+	TemplateVerifyEmail  = "verify_email"
+	TemplateSignupNotice = "signup_notice"
+)
+
+// maxRenderedSize caps the output of a template, so a group's template
+// cannot produce arbitrarily large emails.
+const maxRenderedSize = 256 << 10
+
+// Template is a system email template. Subject and Body are text/template
+// sources executed with Data. The rendered subject is folded onto one line.
+type Template struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+}
+
+// Data is what templates are executed with. Each template uses the fields
+// relevant to it; the others are zero.
+type Data struct {
+	// Email is the address the email is about, usually the recipient's.
+	Email string
+	// Group is the name of the group the email is about.
+	Group string
+	// Link is the email's action link and ExpiresAt when it stops working.
+	Link      string
+	ExpiresAt time.Time
+	// Inviter and Role describe an invitation.
+	Inviter string
+	Role    string
+	// Location and Time describe a sign-in from an unfamiliar network.
+	Location string
+	Time     time.Time
+	// Lockout is how long a locked account stays locked.
+	Lockout time.Duration
+	// Sent, Limit, Percent and Threshold describe the group's usage of its
+	// monthly limit.
+	Sent      int64
+	Limit     int32
+	Percent   int64
+	Threshold int
+}
+
+// defaultTemplates are used for emails whose group has no override.
+var defaultTemplates = map[string]Template{
+	TemplateInvitation: {
+		Subject: "[smtp-proxy] You are invited to join {{.Group}}",
+		Body: "{{.Inviter}} invited you to join the group {{.Group}} on smtp-proxy as {{.Role}}.\n\n" +
+			"Accept the invitation and set your password here:\n\n{{.Link}}\n\n" +
+			"The invitation expires on {{date .ExpiresAt}}. If you did not expect it, you can ignore this email.\n",
+	},
+	TemplateQuotaWarning: {
+		Subject: "[smtp-proxy] {{.Group}} {{if ge .Threshold 100}}has reached its monthly sending limit" +
+			"{{else}}has used {{.Threshold}}% of its monthly sending limit{{end}}",
+		Body: "Group {{printf \"%q\" .Group}} has sent {{.Sent}} of {{.Limit}} messages allowed this month ({{.Percent}}%).\n" +
+			"{{if ge .Threshold 100}}The limit resets at the start of next month." +
+			"{{else}}Contact your administrator to raise the limit before it is reached.{{end}}\n",
+	},
+	TemplateUnlock: {
+		Subject: "[smtp-proxy] Your account has been locked",
+		Body: "Your account {{.Email}} was locked after too many failed login attempts.\n\n" +
+			"If these attempts were yours, unlock the account now:\n\n{{.Link}}\n\n" +
+			"Otherwise the account unlocks by itself in {{.Lockout}}. If you did not try to log in, " +
+			"someone may be guessing your password; consider changing it.\n",
+	},
+	TemplateSignInNotice: {
+		Subject: "[smtp-proxy] New sign-in to your account",
+		Body: "Your account {{.Email}} was used to log in from {{.Location}} on {{date .Time}}, " +
+			"where it has not been used before.\n\n" +
+			"If this was you, no action is needed. Otherwise change your password and " +
+			"revoke unknown sessions:\n\n{{.Link}}\n",
+	},
+	TemplateVerifyEmail: {
+		Subject: "[smtp-proxy] Confirm your email address",
+		Body: "Someone, hopefully you, signed up for smtp-proxy with this address.\n\n" +
+			"Confirm the address to create your account and the group {{.Group}}:\n\n{{.Link}}\n\n" +
+			"The link expires on {{date .ExpiresAt}}. If you did not sign up, you can ignore this email.\n",
+	},
+	TemplateSignupNotice: {
+		Subject: "[smtp-proxy] You already have an account",
+		Body: "Someone tried to sign up for smtp-proxy with this address, which already has an account.\n\n" +
+			"If it was you, log in with your existing password instead. Otherwise you can ignore this email.\n",
+	},
+}
+
+// sampleData exercises every field when templates are validated.
+var sampleData = Data{
+	Email:     "user@example.com",
+	Group:     "Example",
+	Link:      "https://mail.example.com/link",
+	ExpiresAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Inviter:   "admin@example.com",
+	Role:      "member",
+	Location:  "192.0.2.0/24 (KR)",
+	Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Lockout:   15 * time.Minute,
+	Sent:      800,
+	Limit:     1000,
+	Percent:   80,
+	Threshold: 80,
+}
+
+// funcs returns the template functions for locale. date formats a time in
+// UTC.
+func funcs(locale string) template.FuncMap {
+	layout, ok := dateLayouts[locale]
+	if !ok {
+		layout = time.RFC1123
+	}
+	return template.FuncMap{
+		"date": func(t time.Time) string { return t.UTC().Format(layout) },
+	}
+}
+
+// htmlLayout wraps the text body when the branding has a logo.
+var htmlLayout = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html><body style="font-family:sans-serif">
+<p><img src="{{.LogoURL}}" alt="{{.Alt}}" style="max-height:60px"></p>
+<div style="white-space:pre-wrap">{{.Text}}</div>
+{{if .Footer}}<p style="color:#666;font-size:small;white-space:pre-wrap">{{.Footer}}</p>
+{{end}}</body></html>
+`))
+
+// TemplateNames returns the names of all system email templates, sorted.
+func TemplateNames() []string {
+	names := make([]string, 0, len(defaultTemplates))
+	for name := range defaultTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultTemplate returns the built-in template with the given name in
+// locale, falling back to English.
+func DefaultTemplate(locale, name string) (Template, bool) {
+	if t, ok := localizedTemplates[locale][name]; ok {
+		return t, true
+	}
+	t, ok := defaultTemplates[name]
+	return t, ok
+}
+
+// ValidateTemplates checks that overrides only name known templates and
+// that each parses and executes.
+func ValidateTemplates(overrides map[string]Template) error {
+	for name, t := range overrides {
+		if _, ok := defaultTemplates[name]; !ok {
+			return fmt.Errorf("unknown template %q", name)
+		}
+		b := Branding{Templates: map[string]Template{name: t}}
+		if _, _, _, err := b.Render(name, sampleData); err != nil {
+			return fmt.Errorf("template %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Render executes the named template in the branding's locale, using the
+// branding's override of Subject or Body where set. It returns the
+// subject, the plain-text body with the footer appended, and an HTML
+// alternative when the branding has a logo.
+func (b Branding) Render(name string, data Data) (subject, text, html string, err error) {
+	t, ok := DefaultTemplate(b.Locale, name)
+	if !ok {
+		return "", "", "", fmt.Errorf("unknown template %q", name)
+	}
+	o := b.Templates[name]
+	if o.Subject != "" {
+		t.Subject = o.Subject
+	}
+	if o.Body != "" {
+		t.Body = o.Body
+	}
+
+	subject, err = execute(name+".subject", t.Subject, b.Locale, data)
+	if err != nil {
+		return "", "", "", err
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+	body, err := execute(name+".body", t.Body, b.Locale, data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	text = body
+	if b.Footer != "" {
+		text = strings.TrimRight(text, "\n") + "\n\n-- \n" + b.Footer + "\n"
+	}
+	if b.LogoURL != "" {
+		var buf bytes.Buffer
+		if err := htmlLayout.Execute(&buf, map[string]string{
+			"LogoURL": b.LogoURL,
+			"Alt":     data.Group,
+			"Text":    strings.TrimRight(body, "\n"),
+			"Footer":  b.Footer,
+		}); err != nil {
+			return "", "", "", err
+		}
+		html = buf.String()
+	}
+	return subject, text, html, nil
+}
+
+func execute(name, src, locale string, data Data) (string, error) {
+	tpl, err := template.New(name).Funcs(funcs(locale)).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	w := &limitedBuffer{max: maxRenderedSize}
+	if err := tpl.Execute(w, data); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+var errTooLarge = errors.New("rendered template too large")
+
+// limitedBuffer fails writes beyond max bytes.
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, errTooLarge
+	}
+	return b.Buffer.Write(p)
+}
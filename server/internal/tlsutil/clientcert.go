@@ -0,0 +1,58 @@
+package tlsutil
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// Fingerprint returns the lowercase hex SHA-256 fingerprint of a
+// certificate's DER encoding, as printed by
+// `openssl x509 -noout -fingerprint -sha256` without colons.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeFingerprint lowercases a hex fingerprint and strips the colons
+// openssl prints. It returns "" when the result is not a SHA-256
+// fingerprint.
+func NormalizeFingerprint(fp string) string {
+	fp = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+	if len(fp) != sha256.Size*2 {
+		return ""
+	}
+	if _, err := hex.DecodeString(fp); err != nil {
+		return ""
+	}
+	return fp
+}
+
+// SANs returns a certificate's DNS, email and URI subject alternative
+// names.
+func SANs(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.URIs))
+	sans = append(sans, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+// ParseCertificatePEM parses the first certificate in PEM data.
+func ParseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
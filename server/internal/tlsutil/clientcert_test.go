@@ -0,0 +1,44 @@
+package tlsutil
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	pair, err := GenerateSelfSigned()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	fp := Fingerprint(cert)
+	if len(fp) != 64 {
+		t.Fatalf("expected 64 hex chars, got %q", fp)
+	}
+
+	// openssl prints uppercase, colon-separated fingerprints.
+	var colons []string
+	for i := 0; i < len(fp); i += 2 {
+		colons = append(colons, strings.ToUpper(fp[i:i+2]))
+	}
+	if got := NormalizeFingerprint(strings.Join(colons, ":")); got != fp {
+		t.Errorf("NormalizeFingerprint = %q, want %q", got, fp)
+	}
+	if NormalizeFingerprint("abc") != "" {
+		t.Error("expected short fingerprint to be rejected")
+	}
+
+	parsed, err := ParseCertificatePEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	if err != nil || Fingerprint(parsed) != fp {
+		t.Errorf("ParseCertificatePEM: %v", err)
+	}
+	if sans := SANs(cert); len(sans) != 3 || sans[0] != "localhost" {
+		t.Errorf("unexpected SANs %v", sans)
+	}
+}
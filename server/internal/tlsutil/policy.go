@@ -0,0 +1,98 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Policy is a per-user TLS requirement for SMTP submission. The zero Policy
+// imposes no requirement.
+type Policy struct {
+	// RequireTLS rejects AUTH and MAIL FROM on unencrypted connections.
+	RequireTLS bool `json:"require_tls"`
+	// MinVersion is the lowest accepted TLS version, "1.2" or "1.3".
+	// Empty accepts any version the server negotiates.
+	MinVersion string `json:"min_version,omitempty"`
+	// CipherSuites restricts the accepted cipher suites to these IANA
+	// names, e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". Empty accepts
+	// any. TLS 1.3 suites are named TLS_AES_128_GCM_SHA256 and so on.
+	CipherSuites []string `json:"cipher_suites,omitempty"`
+}
+
+// policyVersions maps accepted MinVersion values to TLS versions.
+var policyVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ErrTLSRequired is returned by Policy.Check for an unencrypted connection
+// when the policy requires TLS.
+var ErrTLSRequired = errors.New("TLS required")
+
+// ParsePolicy decodes a policy stored as JSON. Empty input is the zero
+// Policy.
+func ParsePolicy(data []byte) (Policy, error) {
+	var p Policy
+	if len(data) == 0 {
+		return p, nil
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Policy{}, fmt.Errorf("parse tls policy: %w", err)
+	}
+	return p, nil
+}
+
+// Validate checks that MinVersion and CipherSuites name known values.
+func (p Policy) Validate() error {
+	if p.MinVersion != "" {
+		if _, ok := policyVersions[p.MinVersion]; !ok {
+			return fmt.Errorf("min_version must be one of: 1.2, 1.3")
+		}
+	}
+	for _, name := range p.CipherSuites {
+		if cipherSuiteID(name) == 0 {
+			return fmt.Errorf("unknown cipher suite %q", name)
+		}
+	}
+	return nil
+}
+
+// Active reports whether the policy imposes any requirement. A minimum
+// version or cipher restriction implies that TLS is required.
+func (p Policy) Active() bool {
+	return p.RequireTLS || p.MinVersion != "" || len(p.CipherSuites) > 0
+}
+
+// Check reports whether a connection satisfies the policy. state is nil for
+// an unencrypted connection.
+func (p Policy) Check(state *tls.ConnectionState) error {
+	if !p.Active() {
+		return nil
+	}
+	if state == nil {
+		return ErrTLSRequired
+	}
+	if minVersion, ok := policyVersions[p.MinVersion]; ok && state.Version < minVersion {
+		return fmt.Errorf("TLS version %s below required %s", tls.VersionName(state.Version), p.MinVersion)
+	}
+	if len(p.CipherSuites) > 0 && !slices.Contains(p.CipherSuites, tls.CipherSuiteName(state.CipherSuite)) {
+		return fmt.Errorf("cipher suite %s not permitted", tls.CipherSuiteName(state.CipherSuite))
+	}
+	return nil
+}
+
+// cipherSuiteID returns the ID of a cipher suite name known to crypto/tls,
+// or 0.
+func cipherSuiteID(name string) uint16 {
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, cs := range suites {
+			if cs.Name == name {
+				return cs.ID
+			}
+		}
+	}
+	return 0
+}
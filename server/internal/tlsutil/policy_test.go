@@ -0,0 +1,62 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	tls12 := &tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	tls13 := &tls.ConnectionState{Version: tls.VersionTLS13, CipherSuite: tls.TLS_AES_128_GCM_SHA256}
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		state   *tls.ConnectionState
+		wantErr bool
+	}{
+		{"no policy plaintext", Policy{}, nil, false},
+		{"require tls plaintext", Policy{RequireTLS: true}, nil, true},
+		{"require tls encrypted", Policy{RequireTLS: true}, tls12, false},
+		{"min version implies tls", Policy{MinVersion: "1.2"}, nil, true},
+		{"min version too low", Policy{MinVersion: "1.3"}, tls12, true},
+		{"min version met", Policy{MinVersion: "1.3"}, tls13, false},
+		{"cipher allowed", Policy{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}, tls13, false},
+		{"cipher not allowed", Policy{CipherSuites: []string{"TLS_AES_256_GCM_SHA384"}}, tls13, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (Policy{RequireTLS: true}).Check(nil); !errors.Is(err, ErrTLSRequired) {
+		t.Errorf("expected ErrTLSRequired, got %v", err)
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	if err := (Policy{MinVersion: "1.3", CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}).Validate(); err != nil {
+		t.Errorf("expected valid policy, got %v", err)
+	}
+	if err := (Policy{MinVersion: "1.1"}).Validate(); err == nil {
+		t.Error("expected error for unsupported min_version")
+	}
+	if err := (Policy{CipherSuites: []string{"TLS_NOPE"}}).Validate(); err == nil {
+		t.Error("expected error for unknown cipher suite")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`{"require_tls":true,"min_version":"1.2"}`))
+	if err != nil || !p.RequireTLS || p.MinVersion != "1.2" {
+		t.Errorf("unexpected policy %+v, err %v", p, err)
+	}
+	if p, err := ParsePolicy(nil); err != nil || p.Active() {
+		t.Errorf("expected inactive policy for empty input, got %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,278 @@
+// Package validation checks email addresses before mail is sent to them:
+// syntax, whether the domain accepts mail (MX lookup), disposable-domain
+// providers and role accounts such as postmaster@ or sales@.
+//
+// It backs POST /api/v1/validate and the per-group recipient validation
+// policy applied at RCPT TO.
+package validation
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// DefaultTimeout bounds the DNS lookups of one domain.
+const DefaultTimeout = 3 * time.Second
+
+// MX lookup outcomes.
+const (
+	// MXFound means the domain accepts mail, through MX records or an
+	// implicit MX (an A/AAAA record, RFC 5321 section 5.1).
+	MXFound = "found"
+	// MXNone means the domain does not exist, has no address records or
+	// publishes a null MX (RFC 7505).
+	MXNone = "none"
+	// MXUnknown means the lookup failed temporarily or was not attempted.
+	MXUnknown = "unknown"
+)
+
+// Result is the outcome of validating one address.
+type Result struct {
+	Address    string `json:"address"`
+	Syntax     bool   `json:"syntax"`
+	MX         string `json:"mx"`
+	Disposable bool   `json:"disposable"`
+	Role       bool   `json:"role"`
+	// Valid reports that the address is well-formed and its domain is not
+	// known to reject mail. A failed lookup does not make it invalid.
+	Valid bool `json:"valid"`
+	// Risky reports that the address is invalid, disposable or a role
+	// account.
+	Risky bool `json:"risky"`
+	// Reason names the first problem found, if any.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Resolver is the subset of net.Resolver used for MX lookups.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Validator validates addresses. It is safe for concurrent use.
+type Validator struct {
+	resolver Resolver
+	timeout  time.Duration
+}
+
+// New creates a Validator. A nil resolver uses net.DefaultResolver.
+func New(resolver Resolver) *Validator {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &Validator{resolver: resolver, timeout: DefaultTimeout}
+}
+
+// Validate checks a single address.
+func (v *Validator) Validate(ctx context.Context, address string) Result {
+	return v.ValidateAll(ctx, []string{address})[0]
+}
+
+// lookupConcurrency caps parallel domain lookups in ValidateAll.
+const lookupConcurrency = 8
+
+// ValidateAll checks several addresses, looking up each distinct domain
+// once. Results are in input order.
+func (v *Validator) ValidateAll(ctx context.Context, addresses []string) []Result {
+	results := make([]Result, len(addresses))
+	domains := make(map[string]string)
+	for i, a := range addresses {
+		results[i] = check(a)
+		if results[i].Syntax {
+			domains[domainOf(results[i].Address)] = MXUnknown
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, lookupConcurrency)
+	for d := range domains {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			status := v.lookupMX(ctx, d)
+			mu.Lock()
+			domains[d] = status
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for i := range results {
+		r := &results[i]
+		if r.Syntax {
+			r.MX = domains[domainOf(r.Address)]
+		}
+		finish(r)
+	}
+	return results
+}
+
+// check runs the checks that need no network access.
+func check(address string) Result {
+	r := Result{Address: strings.TrimSpace(address), MX: MXUnknown}
+	local, domain, ok := splitAddress(r.Address)
+	if !ok {
+		return r
+	}
+	r.Address = local + "@" + domain
+	r.Syntax = true
+	r.Disposable = IsDisposable(domain)
+	r.Role = IsRole(local)
+	return r
+}
+
+func finish(r *Result) {
+	r.Valid = r.Syntax && r.MX != MXNone
+	r.Risky = !r.Valid || r.Disposable || r.Role
+	switch {
+	case !r.Syntax:
+		r.Reason = "invalid syntax"
+	case r.MX == MXNone:
+		r.Reason = "domain does not accept mail"
+	case r.Disposable:
+		r.Reason = "disposable domain"
+	case r.Role:
+		r.Reason = "role account"
+	}
+}
+
+// lookupMX reports whether domain accepts mail.
+func (v *Validator) lookupMX(ctx context.Context, domain string) string {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	mxs, err := v.resolver.LookupMX(ctx, domain)
+	if err == nil && len(mxs) > 0 {
+		if len(mxs) == 1 && (mxs[0].Host == "." || mxs[0].Host == "") {
+			return MXNone
+		}
+		return MXFound
+	}
+	if err != nil && !isNotFound(err) {
+		return MXUnknown
+	}
+
+	// No MX records: fall back to the implicit MX.
+	addrs, err := v.resolver.LookupHost(ctx, domain)
+	switch {
+	case err == nil && len(addrs) > 0:
+		return MXFound
+	case err == nil || isNotFound(err):
+		return MXNone
+	default:
+		return MXUnknown
+	}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// splitAddress parses a bare address (no display name) and returns its
+// local part and lower-cased domain.
+func splitAddress(address string) (local, domain string, ok bool) {
+	if address == "" || strings.ContainsAny(address, "<> ") {
+		return "", "", false
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Name != "" {
+		return "", "", false
+	}
+	at := strings.LastIndexByte(parsed.Address, '@')
+	if at < 1 {
+		return "", "", false
+	}
+	local, domain = parsed.Address[:at], strings.ToLower(parsed.Address[at+1:])
+	if len(local) > 64 || !validDomain(domain) {
+		return "", "", false
+	}
+	return local, domain, true
+}
+
+// validDomain accepts fully qualified host names: at least two labels of
+// letters, digits and hyphens, and a TLD that is not numeric. Address
+// literals such as [192.0.2.1] are rejected.
+func validDomain(domain string) bool {
+	if len(domain) > 253 {
+		return false
+	}
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, l := range labels {
+		if l == "" || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for _, r := range l {
+			if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return false
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	return strings.IndexFunc(tld, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0
+}
+
+func domainOf(address string) string {
+	return address[strings.LastIndexByte(address, '@')+1:]
+}
+
+//go:embed disposable_domains.txt
+var disposableList string
+
+var disposableDomains = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, line := range strings.Split(disposableList, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			m[line] = true
+		}
+	}
+	return m
+}()
+
+// IsDisposable reports whether domain, or a parent domain, belongs to a
+// known disposable mailbox provider.
+func IsDisposable(domain string) bool {
+	domain = strings.ToLower(domain)
+	for {
+		if disposableDomains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			return false
+		}
+		domain = parent
+	}
+}
+
+// roleAccounts are local parts that reach a team or a system rather than a
+// person.
+var roleAccounts = map[string]bool{
+	"abuse": true, "admin": true, "administrator": true, "billing": true,
+	"careers": true, "contact": true, "enquiries": true, "help": true,
+	"hostmaster": true, "hr": true, "info": true, "jobs": true,
+	"marketing": true, "news": true, "newsletter": true, "no-reply": true,
+	"noc": true, "noreply": true, "office": true, "postmaster": true,
+	"root": true, "sales": true, "security": true, "support": true,
+	"team": true, "webmaster": true,
+}
+
+// IsRole reports whether local is a role account. A +suffix is ignored.
+func IsRole(local string) bool {
+	local, _, _ = strings.Cut(strings.ToLower(local), "+")
+	return roleAccounts[local]
+}
@@ -0,0 +1,109 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeResolver answers from fixed tables; unknown names are NXDOMAIN.
+type fakeResolver struct {
+	mx    map[string][]*net.MX
+	hosts map[string][]string
+	fail  map[string]bool
+	calls int
+}
+
+func (f *fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	f.calls++
+	if f.fail[name] {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	if mx, ok := f.mx[name]; ok {
+		return mx, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if h, ok := f.hosts[host]; ok {
+		return h, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func newFakeResolver() *fakeResolver {
+	return &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+			"mailinator.com": {{Host: "mail.mailinator.com.", Pref: 10}},
+			"nomail.example": {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{"a-only.example": {"192.0.2.1"}},
+		fail:  map[string]bool{"slow.example": true},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	v := New(newFakeResolver())
+	tests := []struct {
+		address string
+		valid   bool
+		risky   bool
+		mx      string
+		reason  string
+	}{
+		{"jane@example.com", true, false, MXFound, ""},
+		{" Jane@Example.COM ", true, false, MXFound, ""},
+		{"jane@a-only.example", true, false, MXFound, ""},
+		{"jane@nomail.example", false, true, MXNone, "domain does not accept mail"},
+		{"jane@missing.example", false, true, MXNone, "domain does not accept mail"},
+		{"jane@slow.example", true, false, MXUnknown, ""},
+		{"x@mailinator.com", true, true, MXFound, "disposable domain"},
+		{"Sales+eu@example.com", true, true, MXFound, "role account"},
+		{"not-an-address", false, true, MXUnknown, "invalid syntax"},
+		{"Jane <jane@example.com>", false, true, MXUnknown, "invalid syntax"},
+		{"jane@localhost", false, true, MXUnknown, "invalid syntax"},
+		{"jane@[192.0.2.1]", false, true, MXUnknown, "invalid syntax"},
+		{"jane@-bad.example.com", false, true, MXUnknown, "invalid syntax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			r := v.Validate(context.Background(), tt.address)
+			if r.Valid != tt.valid || r.Risky != tt.risky || r.MX != tt.mx || r.Reason != tt.reason {
+				t.Errorf("Validate(%q) = %+v", tt.address, r)
+			}
+		})
+	}
+}
+
+func TestValidateAll_LooksUpDomainsOnce(t *testing.T) {
+	res := newFakeResolver()
+	v := New(res)
+	results := v.ValidateAll(context.Background(), []string{"a@example.com", "b@example.com", "c@example.com", "bad"})
+	if len(results) != 4 || results[3].Syntax {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if res.calls != 1 {
+		t.Errorf("expected 1 MX lookup, got %d", res.calls)
+	}
+	if results[1].Address != "b@example.com" {
+		t.Errorf("results out of order: %+v", results)
+	}
+}
+
+func TestIsDisposable(t *testing.T) {
+	if !IsDisposable("mx.Mailinator.com") {
+		t.Error("expected subdomain of a disposable domain to match")
+	}
+	if IsDisposable("example.com") || IsDisposable("com") {
+		t.Error("unexpected disposable match")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	if isNotFound(errors.New("boom")) {
+		t.Error("plain errors are not NXDOMAIN")
+	}
+}
@@ -0,0 +1,86 @@
+// Package version reports the build of the running daemon, for
+// GET /api/v1/version and each daemon's --version flag.
+//
+// Version, Commit and Date are set at build time, e.g. by the Makefile:
+//
+//	go build -ldflags "-X github.com/sungwon/smtp-proxy/server/internal/version.Version=v1.4.2 ..."
+//
+// When they are not set, the commit and date recorded by the Go toolchain
+// for builds from a git checkout are used.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/sungwon/smtp-proxy/server/internal/fips"
+)
+
+// Set with -ldflags -X at build time.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	FIPS      bool   `json:"fips"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		FIPS:      fips.Enabled(),
+	}
+	if info.Commit != "" && info.Date != "" {
+		return info
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Date == "":
+				info.Date = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// String formats the information for --version, e.g.
+// "v1.4.2 (commit 1a2b3c4, built 2026-05-01T10:00:00Z, go1.24.2 linux/arm64, FIPS)".
+func (i Info) String() string {
+	details := []string{}
+	if i.Commit != "" {
+		details = append(details, "commit "+shortCommit(i.Commit))
+	}
+	if i.Date != "" {
+		details = append(details, "built "+i.Date)
+	}
+	details = append(details, i.GoVersion+" "+i.Platform)
+	if i.FIPS {
+		details = append(details, "FIPS")
+	}
+	return fmt.Sprintf("%s (%s)", i.Version, strings.Join(details, ", "))
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,40 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet_LinkerValues(t *testing.T) {
+	defer func(v, c, d string) { Version, Commit, Date = v, c, d }(Version, Commit, Date)
+	Version, Commit, Date = "v1.4.2", "1a2b3c4d5e6f", "2026-05-01T10:00:00Z"
+
+	info := Get()
+	if info.Version != "v1.4.2" || info.Commit != "1a2b3c4d5e6f" || info.Date != "2026-05-01T10:00:00Z" {
+		t.Errorf("Get() = %+v, want the linker values", info)
+	}
+	if info.GoVersion != runtime.Version() || info.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("Get() = %+v, want the running toolchain and platform", info)
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	tests := []struct {
+		info Info
+		want string
+	}{
+		{
+			Info{Version: "v1.4.2", Commit: "1a2b3c4d5e6f", Date: "2026-05-01T10:00:00Z", GoVersion: "go1.24.2", Platform: "linux/arm64", FIPS: true},
+			"v1.4.2 (commit 1a2b3c4, built 2026-05-01T10:00:00Z, go1.24.2 linux/arm64, FIPS)",
+		},
+		{
+			Info{Version: "dev", GoVersion: "go1.24.2", Platform: "darwin/amd64"},
+			"dev (go1.24.2 darwin/amd64)",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// AccountPollerConfig controls the ESP quota and reputation poller.
+type AccountPollerConfig struct {
+	// Interval is the delay between poll rounds.
+	Interval time.Duration
+	// Timeout bounds the API calls made for a single provider.
+	Timeout time.Duration
+}
+
+// DefaultAccountPollerConfig returns sensible defaults for the poller.
+func DefaultAccountPollerConfig() AccountPollerConfig {
+	return AccountPollerConfig{
+		Interval: 15 * time.Minute,
+		Timeout:  30 * time.Second,
+	}
+}
+
+// AccountPoller periodically asks every enabled provider that implements
+// provider.AccountStatsReporter for its sending quota, bounce/complaint
+// rates and suppression list size, and stores the snapshot in
+// provider_account_stats. The provider resolver uses the stored quota to
+// route around exhausted providers. With a ReputationPolicy, providers
+// whose reputation degrades are paused the same way.
+type AccountPoller struct {
+	queries    storage.Querier
+	build      func(esp *storage.EspProvider) (provider.Provider, error)
+	config     AccountPollerConfig
+	reputation *ReputationPolicy
+	notifier   notify.Notifier
+	log        zerolog.Logger
+	wg         sync.WaitGroup
+	cancel     context.CancelFunc
+}
+
+// NewAccountPoller creates an AccountPoller that builds provider clients
+// with the given HTTP client. Zero-valued config fields fall back to
+// DefaultAccountPollerConfig.
+func NewAccountPoller(queries storage.Querier, client provider.HTTPClient, cfg AccountPollerConfig, log zerolog.Logger) *AccountPoller {
+	defaults := DefaultAccountPollerConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	return &AccountPoller{
+		queries: queries,
+		build: func(esp *storage.EspProvider) (provider.Provider, error) {
+			return provider.NewProviderFromStorage(esp, client)
+		},
+		config: cfg,
+		log:    log,
+	}
+}
+
+// Start launches the poll loop in a background goroutine. The first round
+// runs immediately.
+func (p *AccountPoller) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	p.wg.Add(1)
+	go p.run(ctx)
+
+	p.log.Info().
+		Dur("interval", p.config.Interval).
+		Msg("provider account poller started")
+}
+
+// Stop signals the poll loop to exit and waits for the current round.
+func (p *AccountPoller) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+	p.log.Info().Msg("provider account poller stopped")
+}
+
+func (p *AccountPoller) run(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.PollOnce(ctx); err != nil && ctx.Err() == nil {
+			p.log.Error().Err(err).Msg("provider account poll failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce refreshes the account snapshot of every enabled provider.
+// Providers that cannot report account stats are skipped.
+func (p *AccountPoller) PollOnce(ctx context.Context) error {
+	providers, err := p.queries.ListEnabledProviders(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range providers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		p.poll(ctx, &providers[i])
+	}
+	return nil
+}
+
+func (p *AccountPoller) poll(ctx context.Context, esp *storage.EspProvider) {
+	prov, err := p.build(esp)
+	if err != nil {
+		// Misconfigured providers are reported by the health prober.
+		return
+	}
+	reporter, ok := prov.(provider.AccountStatsReporter)
+	if !ok {
+		return
+	}
+
+	// Only the provider API calls are bounded by the timeout; the results,
+	// including a timeout error, are stored with the parent context.
+	apiCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	stats, err := reporter.AccountStats(apiCtx)
+	if err != nil {
+		p.log.Warn().Err(err).
+			Stringer("provider_id", esp.ID).
+			Str("provider", esp.Name).
+			Msg("failed to poll provider account stats")
+		if err := p.queries.RecordProviderAccountStatsError(ctx, storage.RecordProviderAccountStatsErrorParams{
+			ProviderID: esp.ID,
+			LastError:  pgtype.Text{String: err.Error(), Valid: true},
+		}); err != nil {
+			p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to record account poll error")
+		}
+		return
+	}
+
+	row, err := p.queries.UpsertProviderAccountStats(ctx, storage.UpsertProviderAccountStatsParams{
+		ProviderID:       esp.ID,
+		Quota:            int8Ptr(stats.Quota),
+		QuotaUsed:        int8Ptr(stats.QuotaUsed),
+		BounceRate:       float8Ptr(stats.BounceRate),
+		ComplaintRate:    float8Ptr(stats.ComplaintRate),
+		SuppressionCount: int8Ptr(stats.SuppressionCount),
+	})
+	if err != nil {
+		p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to store provider account stats")
+		return
+	}
+
+	id := esp.ID.String()
+	if stats.Quota != nil && stats.QuotaUsed != nil {
+		metrics.ProviderQuotaRemaining.WithLabelValues(id, esp.Name).Set(float64(*stats.Quota - *stats.QuotaUsed))
+	}
+	if stats.BounceRate != nil {
+		metrics.ProviderBounceRate.WithLabelValues(id, esp.Name).Set(*stats.BounceRate)
+	}
+	if stats.ComplaintRate != nil {
+		metrics.ProviderComplaintRate.WithLabelValues(id, esp.Name).Set(*stats.ComplaintRate)
+	}
+
+	if stats.QuotaExhausted() {
+		p.log.Warn().
+			Stringer("provider_id", esp.ID).
+			Str("provider", esp.Name).
+			Int64("quota", *stats.Quota).
+			Msg("provider quota exhausted, routing will prefer other providers")
+	}
+	p.applyReputation(ctx, esp, stats, row)
+	if row.SuppressionDelta.Valid && row.SuppressionDelta.Int64 != 0 {
+		p.log.Info().
+			Stringer("provider_id", esp.ID).
+			Str("provider", esp.Name).
+			Int64("suppression_count", row.SuppressionCount.Int64).
+			Int64("suppression_delta", row.SuppressionDelta.Int64).
+			Msg("provider suppression list changed")
+	}
+}
+
+func int8Ptr(v *int64) pgtype.Int8 {
+	if v == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: *v, Valid: true}
+}
+
+func float8Ptr(v *float64) pgtype.Float8 {
+	if v == nil {
+		return pgtype.Float8{}
+	}
+	return pgtype.Float8{Float64: *v, Valid: true}
+}
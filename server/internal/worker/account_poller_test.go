@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockStatsProvider is a provider that reports fixed account stats.
+type mockStatsProvider struct {
+	mockHealthProvider
+	stats *provider.AccountStats
+	err   error
+	// hang makes AccountStats wait for its context to end.
+	hang bool
+}
+
+func (m *mockStatsProvider) AccountStats(ctx context.Context) (*provider.AccountStats, error) {
+	if m.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return m.stats, m.err
+}
+
+func newTestAccountPoller(q *mockQuerier, prov provider.Provider) *AccountPoller {
+	p := NewAccountPoller(q, nil, AccountPollerConfig{}, zerolog.Nop())
+	p.build = func(_ *storage.EspProvider) (provider.Provider, error) {
+		return prov, nil
+	}
+	return p
+}
+
+func TestAccountPoller_StoresStats(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Name: "sg", Enabled: true}
+	q := &mockQuerier{enabledProviders: []storage.EspProvider{esp}}
+
+	quota, used, suppressed := int64(1000), int64(1000), int64(12)
+	bounce := 0.03
+	prov := &mockStatsProvider{stats: &provider.AccountStats{
+		Quota:            &quota,
+		QuotaUsed:        &used,
+		BounceRate:       &bounce,
+		SuppressionCount: &suppressed,
+	}}
+
+	if err := newTestAccountPoller(q, prov).PollOnce(context.Background()); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+
+	if len(q.accountStats) != 1 {
+		t.Fatalf("stored stats = %d, want 1", len(q.accountStats))
+	}
+	got := q.accountStats[0]
+	if got.ProviderID != esp.ID || got.Quota.Int64 != 1000 || got.QuotaUsed.Int64 != 1000 {
+		t.Errorf("stored stats = %+v", got)
+	}
+	if !got.BounceRate.Valid || got.BounceRate.Float64 != 0.03 {
+		t.Errorf("BounceRate = %+v, want 0.03", got.BounceRate)
+	}
+	if got.ComplaintRate.Valid {
+		t.Error("unreported complaint rate should be stored as NULL")
+	}
+}
+
+func TestAccountPoller_RecordsError(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Enabled: true}
+	q := &mockQuerier{enabledProviders: []storage.EspProvider{esp}}
+	prov := &mockStatsProvider{err: errors.New("403 forbidden")}
+
+	if err := newTestAccountPoller(q, prov).PollOnce(context.Background()); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+
+	if len(q.accountStats) != 0 {
+		t.Error("stats should not be stored when the poll fails")
+	}
+	if len(q.accountStatsErrors) != 1 || q.accountStatsErrors[0].LastError.String != "403 forbidden" {
+		t.Errorf("recorded errors = %+v", q.accountStatsErrors)
+	}
+}
+
+func TestAccountPoller_RecordsTimeout(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Enabled: true}
+	q := &mockQuerier{enabledProviders: []storage.EspProvider{esp}}
+	p := newTestAccountPoller(q, &mockStatsProvider{hang: true})
+	p.config.Timeout = 10 * time.Millisecond
+
+	if err := p.PollOnce(context.Background()); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+
+	if len(q.accountStatsErrors) != 1 || !strings.Contains(q.accountStatsErrors[0].LastError.String, "deadline exceeded") {
+		t.Errorf("recorded errors = %+v, want the timeout", q.accountStatsErrors)
+	}
+}
+
+func TestAccountPoller_SkipsProvidersWithoutStats(t *testing.T) {
+	q := &mockQuerier{enabledProviders: []storage.EspProvider{{ID: uuid.New(), Enabled: true}}}
+
+	if err := newTestAccountPoller(q, &mockHealthProvider{}).PollOnce(context.Background()); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+
+	if len(q.accountStats) != 0 || len(q.accountStatsErrors) != 0 {
+		t.Error("providers without account stats should be skipped")
+	}
+}
+
+type blocklistResolver map[string][]string
+
+func (r blocklistResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestAccountPoller_ReputationPause(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Name: "sg", Enabled: true, SmtpConfig: []byte(`{"relay_ips":["192.0.2.10"]}`)}
+	q := &mockQuerier{enabledProviders: []storage.EspProvider{esp}}
+
+	bounce, reputation := 0.09, 95.0
+	prov := &mockStatsProvider{stats: &provider.AccountStats{BounceRate: &bounce, Reputation: &reputation}}
+	n := &mockNotifier{}
+	p := newTestAccountPoller(q, prov)
+	p.SetReputationPolicy(ReputationPolicy{
+		MaxBounceRate: 0.05,
+		MinReputation: 80,
+		Resolver:      blocklistResolver{"10.2.0.192.zen.spamhaus.org": {"127.0.0.3"}},
+	})
+	p.SetNotifier(n)
+	ctx := context.Background()
+
+	if err := p.PollOnce(ctx); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+	if len(q.reputationPauses) != 1 {
+		t.Fatalf("pause updates = %d, want 1", len(q.reputationPauses))
+	}
+	got := q.reputationPauses[0]
+	if got.PauseAction.String != provider.PauseActionPause ||
+		!strings.Contains(got.PauseReason.String, "bounce rate 9.00%") ||
+		!strings.Contains(got.PauseReason.String, "192.0.2.10 listed on zen.spamhaus.org") {
+		t.Errorf("pause = %+v", got)
+	}
+	if len(n.alerts) != 1 || n.alerts[0].Kind != notify.KindReputationDegraded || n.alerts[0].Severity != notify.SeverityCritical {
+		t.Errorf("alerts = %+v", n.alerts)
+	}
+
+	// An unchanged reason neither updates nor alerts again.
+	if err := p.PollOnce(ctx); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+	if len(q.reputationPauses) != 1 || len(n.alerts) != 1 {
+		t.Fatalf("repeat poll: %d updates, %d alerts; want 1, 1", len(q.reputationPauses), len(n.alerts))
+	}
+
+	// Recovery resumes the provider.
+	bounce = 0.01
+	p.reputation.Resolver = blocklistResolver{}
+	if err := p.PollOnce(ctx); err != nil {
+		t.Fatalf("PollOnce() error = %v", err)
+	}
+	if len(q.reputationPauses) != 2 || q.reputationPauses[1].PauseReason.Valid {
+		t.Errorf("pause updates = %+v, want a resume", q.reputationPauses)
+	}
+	if len(n.alerts) != 2 || n.alerts[1].Severity != notify.SeverityInfo {
+		t.Errorf("alerts = %+v, want an info alert on resume", n.alerts)
+	}
+}
@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// CredentialExpiryConfig controls SMTP password expiry warnings.
+type CredentialExpiryConfig struct {
+	// Interval is the delay between checks.
+	Interval time.Duration
+	// MaxAge is how long an SMTP password stays valid after it is set.
+	MaxAge time.Duration
+	// WarnBefore is the grace period before expiry in which the account's
+	// group is warned.
+	WarnBefore time.Duration
+}
+
+// DefaultCredentialExpiryConfig returns sensible defaults for the notifier.
+func DefaultCredentialExpiryConfig() CredentialExpiryConfig {
+	return CredentialExpiryConfig{
+		Interval:   time.Hour,
+		MaxAge:     90 * 24 * time.Hour,
+		WarnBefore: 14 * 24 * time.Hour,
+	}
+}
+
+// CredentialExpiryNotifier periodically finds active SMTP accounts whose
+// password expires within WarnBefore and sends a credential_expiring alert
+// through the notification module, which delivers it to the deployment's
+// channels and the account group's own channels. Each password is
+// announced once; setting a new password re-arms the warning.
+type CredentialExpiryNotifier struct {
+	queries  storage.Querier
+	notifier notify.Notifier
+	config   CredentialExpiryConfig
+	log      zerolog.Logger
+	now      func() time.Time
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+// NewCredentialExpiryNotifier creates a CredentialExpiryNotifier.
+// Zero-valued config fields fall back to DefaultCredentialExpiryConfig.
+func NewCredentialExpiryNotifier(queries storage.Querier, notifier notify.Notifier, cfg CredentialExpiryConfig, log zerolog.Logger) *CredentialExpiryNotifier {
+	defaults := DefaultCredentialExpiryConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = defaults.MaxAge
+	}
+	if cfg.WarnBefore <= 0 {
+		cfg.WarnBefore = defaults.WarnBefore
+	}
+
+	return &CredentialExpiryNotifier{
+		queries:  queries,
+		notifier: notifier,
+		config:   cfg,
+		log:      log,
+		now:      time.Now,
+	}
+}
+
+// Start launches the check loop in a background goroutine. The first check
+// runs immediately.
+func (n *CredentialExpiryNotifier) Start(ctx context.Context) {
+	ctx, n.cancel = context.WithCancel(ctx)
+
+	n.wg.Add(1)
+	go n.run(ctx)
+
+	n.log.Info().
+		Dur("interval", n.config.Interval).
+		Dur("max_age", n.config.MaxAge).
+		Dur("warn_before", n.config.WarnBefore).
+		Msg("credential expiry notifier started")
+}
+
+// Stop signals the check loop to exit and waits for the current check.
+func (n *CredentialExpiryNotifier) Stop() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	n.wg.Wait()
+	n.log.Info().Msg("credential expiry notifier stopped")
+}
+
+func (n *CredentialExpiryNotifier) run(ctx context.Context) {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.CheckOnce(ctx); err != nil && ctx.Err() == nil {
+			n.log.Error().Err(err).Msg("credential expiry check failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce warns about every SMTP password that expires within
+// WarnBefore, or has expired, and has not been announced yet. A password
+// is marked as announced only once its alert was sent, so a failed alert
+// is retried on the next check.
+func (n *CredentialExpiryNotifier) CheckOnce(ctx context.Context) error {
+	now := n.now()
+	cutoff := now.Add(n.config.WarnBefore - n.config.MaxAge)
+
+	rows, err := n.queries.ListExpiringSMTPPasswords(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		return fmt.Errorf("list expiring passwords: %w", err)
+	}
+
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		expiresAt := row.PasswordChangedAt.Time.Add(n.config.MaxAge)
+		log := n.log.With().
+			Stringer("user_id", row.ID).
+			Str("username", row.Username.String).
+			Time("expires_at", expiresAt).
+			Logger()
+
+		if err := n.notifier.Notify(ctx, n.alert(row, expiresAt, now)); err != nil {
+			log.Error().Err(err).Msg("failed to send credential expiry alert")
+			continue
+		}
+		if err := n.queries.MarkPasswordExpiryWarned(ctx, row.ID); err != nil {
+			log.Error().Err(err).Msg("failed to record credential expiry warning")
+			continue
+		}
+		log.Info().Msg("credential expiry warning sent")
+	}
+	return nil
+}
+
+func (n *CredentialExpiryNotifier) alert(row storage.ListExpiringSMTPPasswordsRow, expiresAt, now time.Time) notify.Alert {
+	severity := notify.SeverityWarning
+	summary := fmt.Sprintf("SMTP password of %s expires on %s; set a new one to keep SMTP AUTH working",
+		row.Username.String, expiresAt.UTC().Format("2006-01-02"))
+	if !expiresAt.After(now) {
+		severity = notify.SeverityCritical
+		summary = fmt.Sprintf("SMTP password of %s expired on %s; SMTP AUTH with it is refused",
+			row.Username.String, expiresAt.UTC().Format("2006-01-02"))
+	}
+	return notify.Alert{
+		Kind:     notify.KindCredentialExpiring,
+		Severity: severity,
+		Summary:  summary,
+		Labels: map[string]string{
+			"group_id": row.GroupID.String(),
+			"user_id":  row.ID.String(),
+			"username": row.Username.String,
+		},
+		Values: map[string]float64{
+			"days_left": expiresAt.Sub(now).Hours() / 24,
+		},
+		FiredAt: now,
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func passwordRow(username string, changedAt time.Time) storage.ListExpiringSMTPPasswordsRow {
+	return storage.ListExpiringSMTPPasswordsRow{
+		ID:                uuid.New(),
+		Username:          sql.NullString{String: username, Valid: true},
+		PasswordChangedAt: pgtype.Timestamptz{Time: changedAt, Valid: true},
+		GroupID:           uuid.New(),
+	}
+}
+
+func TestCredentialExpiryNotifier_CheckOnce(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	expiring := passwordRow("billing", now.Add(-85*day))
+	expired := passwordRow("legacy", now.Add(-100*day))
+	fresh := passwordRow("reports", now.Add(-10*day))
+	q := &mockQuerier{expiringPasswords: []storage.ListExpiringSMTPPasswordsRow{expiring, expired, fresh}}
+	alerts := &mockNotifier{}
+
+	n := NewCredentialExpiryNotifier(q, alerts, CredentialExpiryConfig{
+		MaxAge:     90 * day,
+		WarnBefore: 14 * day,
+	}, zerolog.Nop())
+	n.now = func() time.Time { return now }
+
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(alerts.alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %+v", alerts.alerts)
+	}
+	first, second := alerts.alerts[0], alerts.alerts[1]
+	if first.Kind != notify.KindCredentialExpiring || first.Severity != notify.SeverityWarning || first.Labels["username"] != "billing" {
+		t.Errorf("unexpected alert for expiring password: %+v", first)
+	}
+	if first.Labels["group_id"] != expiring.GroupID.String() || first.Values["days_left"] != 5 {
+		t.Errorf("unexpected labels or values: %+v", first)
+	}
+	if second.Severity != notify.SeverityCritical || second.Labels["username"] != "legacy" {
+		t.Errorf("unexpected alert for expired password: %+v", second)
+	}
+
+	// Announced passwords are not warned about again.
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(alerts.alerts) != 2 {
+		t.Errorf("expected no repeat alerts, got %d", len(alerts.alerts))
+	}
+}
+
+func TestCredentialExpiryNotifier_RetriesFailedAlerts(t *testing.T) {
+	now := time.Now()
+	q := &mockQuerier{expiringPasswords: []storage.ListExpiringSMTPPasswordsRow{passwordRow("billing", now.Add(-89*24*time.Hour))}}
+	alerts := &mockNotifier{err: errors.New("slack down")}
+	n := NewCredentialExpiryNotifier(q, alerts, CredentialExpiryConfig{}, zerolog.Nop())
+
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(q.expiryWarned) != 0 {
+		t.Fatal("password marked as warned although the alert failed")
+	}
+
+	alerts.err = nil
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(alerts.alerts) != 2 || len(q.expiryWarned) != 1 {
+		t.Errorf("expected the alert to be retried once, got %d alerts and %d marks", len(alerts.alerts), len(q.expiryWarned))
+	}
+}
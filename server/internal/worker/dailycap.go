@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// maxCapFallbacks is the number of providers a message tries when the
+// ones it is routed to reach their daily cap while it is being handled.
+const maxCapFallbacks = 3
+
+// capRecheckDelay is how long a message waits when its group's providers
+// kept reaching their caps during routing.
+const capRecheckDelay = time.Minute
+
+// cacheInvalidator is implemented by resolvers that cache their routing,
+// such as provider.ProviderResolver.
+type cacheInvalidator interface {
+	Invalidate(groupID uuid.UUID)
+}
+
+// reserveDailySend counts the delivery against the daily cap of p. When p
+// has reached its cap, the group is routed again so that the message falls
+// back to another provider; a provider the message was pinned to or a
+// script chose is not replaced, and the message waits for its cap to reset
+// instead. A provider.CappedError is returned when no provider is left.
+func (h *Handler) reserveDailySend(ctx context.Context, groupID uuid.UUID, msg *queue.Message, scriptProvider string, p provider.Provider) (provider.Provider, error) {
+	for range maxCapFallbacks {
+		c := provider.ProviderDailyCap(p)
+		ident, ok := p.(provider.Identified)
+		if !c.Enabled() || !ok {
+			return p, nil
+		}
+		providerID := ident.ProviderID()
+		labels := []string{providerID.String(), p.GetName()}
+
+		now := time.Now()
+		sent, err := h.queries.ReserveProviderDailySend(ctx, storage.ReserveProviderDailySendParams{
+			ProviderID: providerID,
+			Day:        c.Day(now),
+			DailyCap:   int32(c.Limit),
+		})
+		if err == nil {
+			metrics.ProviderDailyCapRemaining.WithLabelValues(labels...).Set(float64(c.Limit - int(sent)))
+			return p, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("reserve daily send: %w", err)
+		}
+
+		metrics.ProviderDailyCapRemaining.WithLabelValues(labels...).Set(0)
+		metrics.ProviderDailyCapReachedTotal.WithLabelValues(labels...).Inc()
+		h.logger(ctx).Info().
+			Str("provider", p.GetName()).
+			Stringer("provider_id", providerID).
+			Int("daily_cap", c.Limit).
+			Str("message_id", msg.ID).
+			Msg("provider reached its daily cap")
+		if msg.ProviderID != "" || scriptProvider != "" {
+			return nil, &provider.CappedError{Until: c.Reset(now)}
+		}
+
+		if inv, ok := h.resolver.(cacheInvalidator); ok {
+			inv.Invalidate(groupID)
+		}
+		if p, err = h.resolver.Resolve(ctx, groupID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &provider.CappedError{Until: time.Now().Add(capRecheckDelay)}
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+func TestHandler_HandleMessage_DailyCapFallback(t *testing.T) {
+	groupID := uuid.New()
+	primary := storage.EspProvider{ID: uuid.New(), GroupID: groupID, Name: "primary", ProviderType: "stdout", Enabled: true, SmtpConfig: []byte(`{"daily_cap":1}`)}
+	backup := storage.EspProvider{ID: uuid.New(), GroupID: groupID, Name: "backup", ProviderType: "stdout", Enabled: true, SmtpConfig: []byte(`{"daily_cap":1,"daily_cap_timezone":"Asia/Seoul"}`)}
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, uuid.New()), nil
+		},
+		listProvidersFn: func(_ context.Context, _ uuid.UUID) ([]storage.EspProvider, error) {
+			return []storage.EspProvider{primary, backup}, nil
+		},
+	}
+	h := newHandler(t, mq, nil)
+
+	var deliveredBy []uuid.UUID
+	for i := 0; i < 3; i++ {
+		mq.createLogCalled = false
+		msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Subject: Hi\r\n\r\nHello")}
+		if err := h.HandleMessage(context.Background(), msg); err != nil {
+			t.Fatalf("HandleMessage() #%d error: %v", i+1, err)
+		}
+		if mq.createLogCalled {
+			deliveredBy = append(deliveredBy, uuid.UUID(mq.createLogParams.ProviderID.Bytes))
+		}
+	}
+
+	if len(deliveredBy) != 2 || deliveredBy[0] != primary.ID || deliveredBy[1] != backup.ID {
+		t.Fatalf("delivered by %v, want primary then backup", deliveredBy)
+	}
+	if len(mq.delayedEntries) != 1 {
+		t.Fatalf("expected the third message to be deferred, got %d delayed entries", len(mq.delayedEntries))
+	}
+	until := mq.delayedEntries[0].ClaimedUntil.Time
+	if !until.After(time.Now()) || until.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("deferred until %v, want the next cap reset", until)
+	}
+}
+
+// cappedCaptureProvider is a capture provider with a daily cap, as built
+// by the resolver from smtp_config.daily_cap.
+type cappedCaptureProvider struct {
+	mockCaptureProvider
+	id       uuid.UUID
+	dailyCap provider.DailyCap
+}
+
+func (p *cappedCaptureProvider) ProviderID() uuid.UUID       { return p.id }
+func (p *cappedCaptureProvider) DailyCap() provider.DailyCap { return p.dailyCap }
+
+func TestHandler_HandleMessage_DailyCapPinned(t *testing.T) {
+	groupID := uuid.New()
+	pinned := &cappedCaptureProvider{id: uuid.New(), dailyCap: provider.DailyCap{Limit: 1, Location: time.UTC}}
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, uuid.New()), nil
+		},
+		dailySends: map[storage.ReserveProviderDailySendParams]int32{
+			{ProviderID: pinned.id, Day: pinned.dailyCap.Day(time.Now())}: 1,
+		},
+	}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: pinned},
+		queries:  mq,
+		log:      zerolog.Nop(),
+	}
+
+	msg := &queue.Message{ID: uuid.New().String(), ProviderID: pinned.id.String(), Body: []byte("Subject: Hi\r\n\r\nHello")}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage() error: %v", err)
+	}
+	if pinned.captured != nil {
+		t.Error("expected no delivery through a capped pinned provider")
+	}
+	tomorrow := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if len(mq.delayedEntries) != 1 || mq.delayedEntries[0].ClaimedUntil.Time.Sub(tomorrow).Abs() > time.Second {
+		t.Errorf("delayed entries = %+v, want one until %v", mq.delayedEntries, tomorrow)
+	}
+}
@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+)
+
+// DLQAlertConfig controls when DLQ growth is alerted on.
+type DLQAlertConfig struct {
+	// Threshold is the number of messages a group may move to the DLQ
+	// within Window before an alert fires.
+	Threshold int
+	// Window is the sliding window messages are counted over. At most one
+	// alert per group is sent per window.
+	Window time.Duration
+}
+
+// DefaultDLQAlertConfig returns the defaults used when config.yaml sets
+// none.
+func DefaultDLQAlertConfig() DLQAlertConfig {
+	return DLQAlertConfig{
+		Threshold: 10,
+		Window:    15 * time.Minute,
+	}
+}
+
+// DLQAlerter wraps a dead letter queue and raises a dlq_growth alert when a
+// group moves Threshold messages to it within Window.
+type DLQAlerter struct {
+	queue.DeadLetterQueue
+	notifier notify.Notifier
+	config   DLQAlertConfig
+	log      zerolog.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	moves   map[string][]time.Time // tenant ID -> recent move times
+	alerted map[string]time.Time   // tenant ID -> last alert time
+}
+
+// NewDLQAlerter creates a DLQAlerter around dlq. Zero-valued config fields
+// fall back to DefaultDLQAlertConfig.
+func NewDLQAlerter(dlq queue.DeadLetterQueue, notifier notify.Notifier, cfg DLQAlertConfig, log zerolog.Logger) *DLQAlerter {
+	defaults := DefaultDLQAlertConfig()
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaults.Threshold
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaults.Window
+	}
+	return &DLQAlerter{
+		DeadLetterQueue: dlq,
+		notifier:        notifier,
+		config:          cfg,
+		log:             log,
+		now:             time.Now,
+		moves:           make(map[string][]time.Time),
+		alerted:         make(map[string]time.Time),
+	}
+}
+
+// MoveToDLQ moves msg to the wrapped DLQ and alerts if the group's recent
+// moves reach the threshold.
+func (a *DLQAlerter) MoveToDLQ(ctx context.Context, msg *queue.Message, reason string) error {
+	if err := a.DeadLetterQueue.MoveToDLQ(ctx, msg, reason); err != nil {
+		return err
+	}
+
+	now := a.now()
+	count, fire := a.record(msg.TenantID, now)
+	if !fire {
+		return nil
+	}
+
+	err := a.notifier.Notify(ctx, notify.Alert{
+		Kind:     notify.KindDLQGrowth,
+		Severity: notify.SeverityWarning,
+		Summary: fmt.Sprintf("%d messages moved to the dead letter queue in %s (group %s, last reason: %s)",
+			count, a.config.Window, msg.TenantID, reason),
+		Labels: map[string]string{"group_id": msg.TenantID},
+		Values: map[string]float64{
+			"messages":       float64(count),
+			"threshold":      float64(a.config.Threshold),
+			"window_seconds": a.config.Window.Seconds(),
+		},
+		FiredAt: now,
+	})
+	if err != nil {
+		a.log.Error().Err(err).Str("group_id", msg.TenantID).Msg("failed to send DLQ growth alert")
+
+		// Allow the next move to retry the notification.
+		a.mu.Lock()
+		delete(a.alerted, msg.TenantID)
+		a.mu.Unlock()
+	}
+	return nil
+}
+
+// record counts a move for tenantID at now and reports the number of moves
+// within the window and whether an alert is due.
+func (a *DLQAlerter) record(tenantID string, now time.Time) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cutoff := now.Add(-a.config.Window)
+	recent := a.moves[tenantID][:0]
+	for _, t := range a.moves[tenantID] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	a.moves[tenantID] = recent
+
+	if len(recent) < a.config.Threshold {
+		return len(recent), false
+	}
+	if last, ok := a.alerted[tenantID]; ok && last.After(cutoff) {
+		return len(recent), false
+	}
+	a.alerted[tenantID] = now
+	return len(recent), true
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+)
+
+func newTestDLQAlerter(notifier notify.Notifier) (*DLQAlerter, *time.Time) {
+	now := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	a := NewDLQAlerter(queue.NewMemoryDLQ(nil), notifier, DLQAlertConfig{Threshold: 3, Window: 10 * time.Minute}, zerolog.Nop())
+	a.now = func() time.Time { return now }
+	return a, &now
+}
+
+func TestDLQAlerter_AlertsAtThreshold(t *testing.T) {
+	notifier := &mockNotifier{}
+	a, now := newTestDLQAlerter(notifier)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if err := a.MoveToDLQ(ctx, &queue.Message{ID: "m", TenantID: "g1"}, "max retries"); err != nil {
+			t.Fatalf("MoveToDLQ: %v", err)
+		}
+		*now = now.Add(time.Minute)
+	}
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("alerts = %d, want 1 per window", len(notifier.alerts))
+	}
+	alert := notifier.alerts[0]
+	if alert.Kind != notify.KindDLQGrowth || alert.Labels["group_id"] != "g1" || alert.Values["messages"] != 3 {
+		t.Errorf("alert = %+v", alert)
+	}
+
+	// Messages are still stored in the wrapped DLQ.
+	entries, err := a.List(ctx, "g1", 10)
+	if err != nil || len(entries) != 5 {
+		t.Errorf("List = %d entries, %v; want 5", len(entries), err)
+	}
+
+	// Once the window has passed, growth alerts again.
+	*now = now.Add(10 * time.Minute)
+	for i := 0; i < 3; i++ {
+		_ = a.MoveToDLQ(ctx, &queue.Message{ID: "m", TenantID: "g1"}, "max retries")
+	}
+	if len(notifier.alerts) != 2 {
+		t.Errorf("alerts = %d, want a second alert in the next window", len(notifier.alerts))
+	}
+}
+
+func TestDLQAlerter_CountsPerGroupWithinWindow(t *testing.T) {
+	notifier := &mockNotifier{}
+	a, now := newTestDLQAlerter(notifier)
+	ctx := context.Background()
+
+	_ = a.MoveToDLQ(ctx, &queue.Message{TenantID: "g1"}, "x")
+	_ = a.MoveToDLQ(ctx, &queue.Message{TenantID: "g2"}, "x")
+	*now = now.Add(11 * time.Minute)
+	_ = a.MoveToDLQ(ctx, &queue.Message{TenantID: "g1"}, "x")
+	_ = a.MoveToDLQ(ctx, &queue.Message{TenantID: "g1"}, "x")
+
+	if len(notifier.alerts) != 0 {
+		t.Errorf("alerts = %+v, want none below the threshold", notifier.alerts)
+	}
+}
+
+func TestDLQAlerter_RetriesFailedNotification(t *testing.T) {
+	notifier := &mockNotifier{err: errors.New("slack down")}
+	a, _ := newTestDLQAlerter(notifier)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if err := a.MoveToDLQ(ctx, &queue.Message{TenantID: "g1"}, "x"); err != nil {
+			t.Fatalf("MoveToDLQ returned the notification error: %v", err)
+		}
+	}
+	if len(notifier.alerts) != 2 {
+		t.Errorf("alerts = %d, want a retry after the failed notification", len(notifier.alerts))
+	}
+}
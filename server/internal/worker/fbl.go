@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/fbl"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fblProviderName identifies processed feedback reports in delivery logs.
+const fblProviderName = "fbl"
+
+// processFeedbackReport handles a message received on an arf inbound
+// route. The report is matched to the message it is about by the
+// Message-ID header and recipient of the reported message; that message's
+// delivery is marked complained and the recipient is added to the group's
+// suppression list. Reports that cannot be parsed fail the report message;
+// reports that match no message are recorded and otherwise ignored.
+func (h *Handler) processFeedbackReport(ctx context.Context, messageID uuid.UUID, dbMsg storage.Message, route storage.InboundRoute, body []byte) error {
+	report, err := fbl.Parse(body)
+	if err != nil {
+		metrics.FBLReportsTotal.WithLabelValues("invalid").Inc()
+		h.logger(ctx).Warn().Err(err).
+			Str("domain", route.Domain).
+			Str("message_id", messageID.String()).
+			Msg("invalid feedback report")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, fblProviderName, pgtype.UUID{}, err)
+		return nil
+	}
+
+	orig, recipient, err := h.findReportedMessage(ctx, route.GroupID, report)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("find reported message: %w", err)
+	}
+
+	result := "unmatched"
+	if err == nil {
+		result = "complained"
+		if err := h.markComplained(ctx, orig, report, messageID); err != nil {
+			return err
+		}
+		if recipient != "" {
+			if err := h.queries.AddSuppression(ctx, storage.AddSuppressionParams{
+				GroupID:   route.GroupID,
+				Address:   recipient,
+				Reason:    "complaint",
+				MessageID: pgtype.UUID{Bytes: orig.ID, Valid: true},
+			}); err != nil {
+				return fmt.Errorf("add suppression: %w", err)
+			}
+		}
+	}
+	metrics.FBLReportsTotal.WithLabelValues(result).Inc()
+
+	logEvent := h.logger(ctx).Info().
+		Str("domain", route.Domain).
+		Str("message_id", messageID.String()).
+		Str("feedback_type", report.FeedbackType).
+		Str("user_agent", report.UserAgent).
+		Str("result", result)
+	if result == "complained" {
+		logEvent = logEvent.Str("reported_message_id", orig.ID.String()).Str("recipient", redact.Email(recipient))
+	}
+	logEvent.Msg("feedback report processed")
+
+	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
+		ID:     messageID,
+		Status: storage.MessageStatusDelivered,
+	}); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", messageID.String()).Msg("failed to update delivered status")
+	}
+	metadata := map[string]string{"result": result, "feedback_type": report.FeedbackType}
+	if result == "complained" {
+		metadata["reported_message_id"] = orig.ID.String()
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+	if _, err := h.queries.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+		MessageID:     messageID,
+		Status:        string(storage.MessageStatusDelivered),
+		Provider:      sql.NullString{String: fblProviderName, Valid: true},
+		GroupID:       dbMsg.GroupID,
+		UserID:        dbMsg.UserID,
+		Metadata:      metadataJSON,
+		AttemptNumber: 1,
+		RequestID:     requestID(ctx),
+	}); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", messageID.String()).Msg("failed to create delivery log")
+	}
+	return nil
+}
+
+// findReportedMessage returns the group's most recent message with the
+// report's Message-ID that was sent to one of the report's recipients, and
+// that recipient. When the report names no recipient, the message is
+// matched by Message-ID alone and the recipient is known only if the
+// message had a single one. pgx.ErrNoRows is returned when nothing matches.
+func (h *Handler) findReportedMessage(ctx context.Context, groupID uuid.UUID, report *fbl.Report) (storage.Message, string, error) {
+	if report.MessageID == "" {
+		return storage.Message{}, "", pgx.ErrNoRows
+	}
+	params := storage.FindMessageByHeaderMessageIDParams{
+		GroupID:         pgtype.UUID{Bytes: groupID, Valid: true},
+		HeaderMessageID: report.MessageID,
+	}
+
+	recipients := report.Recipients()
+	for _, rcpt := range recipients {
+		params.Recipient = pgtype.Text{String: rcpt, Valid: true}
+		msg, err := h.queries.FindMessageByHeaderMessageID(ctx, params)
+		if err == nil {
+			return msg, strings.ToLower(rcpt), nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return storage.Message{}, "", err
+		}
+	}
+	if len(recipients) > 0 {
+		return storage.Message{}, "", pgx.ErrNoRows
+	}
+
+	msg, err := h.queries.FindMessageByHeaderMessageID(ctx, params)
+	if err != nil {
+		return storage.Message{}, "", err
+	}
+	var recipient string
+	if sent := parseRecipients(msg.Recipients); len(sent) == 1 {
+		recipient = strings.ToLower(sent[0])
+	}
+	return msg, recipient, nil
+}
+
+// markComplained sets the delivery logs of a reported message to
+// complained, as the ESP complaint webhooks do, keeping the provider that
+// delivered it.
+func (h *Handler) markComplained(ctx context.Context, msg storage.Message, report *fbl.Report, reportID uuid.UUID) error {
+	logs, err := h.queries.ListDeliveryLogsByMessageID(ctx, msg.ID)
+	if err != nil {
+		return fmt.Errorf("list delivery logs: %w", err)
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	latest := logs[0]
+	for _, l := range logs[1:] {
+		if l.CreatedAt.Time.After(latest.CreatedAt.Time) {
+			latest = l
+		}
+	}
+
+	metadata, _ := json.Marshal(map[string]string{
+		"event":         "complaint",
+		"feedback_type": report.FeedbackType,
+		"user_agent":    report.UserAgent,
+		"report_id":     reportID.String(),
+	})
+	if err := h.queries.UpdateDeliveryLogStatus(ctx, storage.UpdateDeliveryLogStatusParams{
+		MessageID:         msg.ID,
+		Status:            string(storage.DeliveryStatusComplained),
+		Provider:          latest.Provider,
+		ProviderMessageID: latest.ProviderMessageID,
+		RetryCount:        latest.RetryCount,
+		LastError:         latest.LastError,
+		Metadata:          metadata,
+	}); err != nil {
+		return fmt.Errorf("update delivery log status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sungwon/smtp-proxy/server/internal/inbound"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+const testFeedbackReport = "From: fbl@mail.example.net\r\n" +
+	"To: fbl@fbl.example.com\r\n" +
+	"Content-Type: multipart/report; report-type=feedback-report; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/feedback-report\r\n" +
+	"\r\n" +
+	"Feedback-Type: abuse\r\n" +
+	"User-Agent: ExampleFBL/1.0\r\n" +
+	"Original-Rcpt-To: <Alice@Mail.Example.net>\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"Message-ID: <sale-1@example.com>\r\n" +
+	"--b--\r\n"
+
+func newFeedbackReportTest(t *testing.T) (*Handler, *mockQuerier, storage.Message) {
+	t.Helper()
+	poster := &fakePoster{}
+	h, mq, _ := newInboundTest(t, &storage.InboundRoute{Domain: "fbl.example.com", Format: inbound.FormatARF, Enabled: true}, poster)
+	t.Cleanup(func() {
+		if poster.calls != 0 {
+			t.Errorf("feedback report was posted %d times", poster.calls)
+		}
+	})
+
+	var route storage.InboundRoute
+	for id, r := range mq.inboundRoutes {
+		r.GroupID = uuid.New()
+		mq.inboundRoutes[id] = r
+		route = r
+	}
+	orig := newTestDBMessage(route.GroupID, uuid.New())
+	orig.ID = uuid.New()
+	orig.Recipients, _ = json.Marshal([]string{"alice@mail.example.net"})
+	orig.Headers, _ = json.Marshal(map[string][]string{"Message-Id": {"<sale-1@example.com>"}})
+	mq.reportedMessages = []storage.Message{orig}
+	mq.deliveryLogs = map[uuid.UUID][]storage.DeliveryLog{orig.ID: {{
+		MessageID:         orig.ID,
+		Status:            "delivered",
+		Provider:          sql.NullString{String: "sendgrid", Valid: true},
+		ProviderMessageID: sql.NullString{String: "sg-1", Valid: true},
+	}}}
+	return h, mq, orig
+}
+
+func TestHandler_FeedbackReport(t *testing.T) {
+	h, mq, orig := newFeedbackReportTest(t)
+
+	msg := &queue.Message{ID: uuid.New().String(), Body: []byte(testFeedbackReport)}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage() error: %v", err)
+	}
+
+	if len(mq.logStatusUpdates) != 1 {
+		t.Fatalf("expected the reported message to be marked complained, got %+v", mq.logStatusUpdates)
+	}
+	upd := mq.logStatusUpdates[0]
+	if upd.MessageID != orig.ID || upd.Status != "complained" || upd.Provider.String != "sendgrid" || upd.ProviderMessageID.String != "sg-1" {
+		t.Errorf("unexpected status update: %+v", upd)
+	}
+	if len(mq.suppressions) != 1 || mq.suppressions[0].Address != "alice@mail.example.net" ||
+		mq.suppressions[0].GroupID != uuid.UUID(orig.GroupID.Bytes) || uuid.UUID(mq.suppressions[0].MessageID.Bytes) != orig.ID {
+		t.Errorf("unexpected suppressions: %+v", mq.suppressions)
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusDelivered || mq.createLogProvider != fblProviderName ||
+		!strings.Contains(string(mq.createLogParams.Metadata), `"result":"complained"`) {
+		t.Errorf("report not recorded as processed: statuses=%v log=%+v", mq.statuses, mq.createLogParams)
+	}
+}
+
+func TestHandler_FeedbackReport_Unmatched(t *testing.T) {
+	h, mq, _ := newFeedbackReportTest(t)
+
+	msg := &queue.Message{ID: uuid.New().String(), Body: []byte(strings.Replace(testFeedbackReport, "sale-1", "other", 1))}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage() error: %v", err)
+	}
+	if len(mq.logStatusUpdates) != 0 || len(mq.suppressions) != 0 {
+		t.Errorf("unmatched report changed state: updates=%+v suppressions=%+v", mq.logStatusUpdates, mq.suppressions)
+	}
+	if !strings.Contains(string(mq.createLogParams.Metadata), `"result":"unmatched"`) {
+		t.Errorf("unexpected delivery log metadata: %s", mq.createLogParams.Metadata)
+	}
+}
+
+func TestHandler_FeedbackReport_Invalid(t *testing.T) {
+	h, mq, _ := newFeedbackReportTest(t)
+
+	msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Subject: not a report\r\n\r\nhello")}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no retry for an invalid report, got %v", err)
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed || mq.createLogProvider != fblProviderName {
+		t.Errorf("expected the report to fail: statuses=%v provider=%q", mq.statuses, mq.createLogProvider)
+	}
+}
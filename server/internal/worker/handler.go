@@ -2,10 +2,13 @@ package worker
 
 import (
 	"context"
+	"crypto/x509"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,10 +16,24 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog"
 
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
+	"github.com/sungwon/smtp-proxy/server/internal/htmlutil"
+	"github.com/sungwon/smtp-proxy/server/internal/inbound"
+	"github.com/sungwon/smtp-proxy/server/internal/logger"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
 	"github.com/sungwon/smtp-proxy/server/internal/mimeparse"
+	"github.com/sungwon/smtp-proxy/server/internal/msgdefaults"
+	"github.com/sungwon/smtp-proxy/server/internal/msgsign"
 	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
+	"github.com/sungwon/smtp-proxy/server/internal/msgtag"
 	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/redact"
+	"github.com/sungwon/smtp-proxy/server/internal/scripting"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
+	"github.com/sungwon/smtp-proxy/server/internal/sendwindow"
+	"github.com/sungwon/smtp-proxy/server/internal/shadow"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
 )
 
@@ -28,9 +45,24 @@ var storageRetryBackoff = []time.Duration{
 	4 * time.Second,
 }
 
-// providerResolver resolves the ESP provider for a given group ID.
+// inboundProviderName identifies inbound HTTP posts in delivery logs.
+const inboundProviderName = "inbound_http"
+
+// providerResolver resolves the ESP provider for a given group ID, or a
+// specific provider when a message is pinned to one.
 type providerResolver interface {
 	Resolve(ctx context.Context, groupID uuid.UUID) (provider.Provider, error)
+	ResolveByID(ctx context.Context, groupID, providerID uuid.UUID) (provider.Provider, error)
+}
+
+// inboundPoster posts inbound messages to their route's HTTP endpoint.
+type inboundPoster interface {
+	Post(ctx context.Context, route inbound.Route, env inbound.Envelope, raw []byte) (int, error)
+}
+
+// scriptRunner runs a group's message scripts.
+type scriptRunner interface {
+	Run(ctx context.Context, groupID uuid.UUID, msg *provider.Message) (scripting.Result, error)
 }
 
 // Handler implements queue.MessageHandler. It delivers messages via ESP
@@ -39,6 +71,9 @@ type Handler struct {
 	resolver providerResolver
 	queries  storage.Querier
 	store    msgstore.MessageStore
+	inbound  inboundPoster
+	scripts  scriptRunner
+	throttle *domainThrottle
 	log      zerolog.Logger
 }
 
@@ -55,24 +90,44 @@ func NewHandler(
 		resolver: resolver,
 		queries:  queries,
 		store:    store,
+		inbound:  inbound.NewPosterWithDialer(inbound.DefaultTimeout, provider.PublicDialer(10*time.Second)),
 		log:      log,
 	}
 }
 
+// SetScripts makes the handler run each group's message scripts before
+// routing. Scripts may rewrite the message, choose its provider or reject
+// it.
+func (h *Handler) SetScripts(s scriptRunner) {
+	h.scripts = s
+}
+
 // HandleMessage implements queue.MessageHandler. It resolves the provider,
-// sends the message, and updates the database.
-func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
+// sends the message, and updates the database. A panic while handling the
+// message is reported with crash.Report and recorded as a failed delivery,
+// and the message is retried like any other failure.
+func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		perr := crash.Report(*h.logger(ctx), "worker", v, map[string]string{"message_id": msg.ID})
+		if messageID, parseErr := uuid.Parse(msg.ID); parseErr == nil {
+			h.recordFailure(ctx, messageID, pgtype.UUID{}, pgtype.UUID{}, "", pgtype.UUID{}, perr)
+		}
+		err = fmt.Errorf("handle message %s: %w", msg.ID, perr)
+	}()
+	return h.handleMessage(ctx, msg)
+}
+
+func (h *Handler) handleMessage(ctx context.Context, msg *queue.Message) error {
 	messageID, err := uuid.Parse(msg.ID)
 	if err != nil {
 		return fmt.Errorf("parse message ID %q: %w", msg.ID, err)
 	}
-
-	// Update message status to processing.
-	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
-		ID:     messageID,
-		Status: storage.MessageStatusProcessing,
-	}); err != nil {
-		h.log.Error().Err(err).Str("message_id", msg.ID).Msg("failed to set processing status")
+	if msg.RequestID != "" {
+		ctx = logger.WithCorrelationID(ctx, msg.RequestID)
 	}
 
 	// Look up the message in DB to get the group/user IDs and metadata.
@@ -80,13 +135,33 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
 	if err != nil {
 		// REQ-QW-005: Orphaned message_id -- acknowledge without delivery.
 		if errors.Is(err, pgx.ErrNoRows) {
-			h.log.Warn().Str("message_id", msg.ID).Msg("orphaned message_id not found in database, acknowledging")
+			h.logger(ctx).Warn().Str("message_id", msg.ID).Msg("orphaned message_id not found in database, acknowledging")
 			return nil
 		}
-		h.log.Error().Err(err).Str("message_id", msg.ID).Msg("failed to get message from database")
-		h.recordFailure(ctx, messageID, pgtype.UUID{}, pgtype.UUID{}, "", fmt.Errorf("get message: %w", err))
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to get message from database")
+		h.recordFailure(ctx, messageID, pgtype.UUID{}, pgtype.UUID{}, "", pgtype.UUID{}, fmt.Errorf("get message: %w", err))
 		return fmt.Errorf("get message %s: %w", msg.ID, err)
 	}
+	// Messages queued before request IDs were carried in the queue payload
+	// still have theirs in the database.
+	if msg.RequestID == "" && dbMsg.RequestID.Valid {
+		ctx = logger.WithCorrelationID(ctx, dbMsg.RequestID.String)
+	}
+
+	// Messages paused by a credential lockdown are acknowledged without
+	// delivery; releasing them requeues them through the sweeper.
+	if dbMsg.Status == storage.MessageStatusPaused {
+		h.logger(ctx).Info().Str("message_id", msg.ID).Msg("message paused by lockdown, skipping delivery")
+		return nil
+	}
+
+	// Update message status to processing.
+	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
+		ID:     messageID,
+		Status: storage.MessageStatusProcessing,
+	}); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to set processing status")
+	}
 
 	// Extract group ID as uuid.UUID for provider resolution.
 	groupID := uuid.UUID(dbMsg.GroupID.Bytes)
@@ -96,7 +171,11 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
 	if msg.HasInlineBody() {
 		// Backward compatibility: old-format queue message with inline body.
 		body = msg.Body
-		h.log.Debug().Str("message_id", msg.ID).Msg("using inline body from queue (legacy format)")
+		h.logger(ctx).Debug().Str("message_id", msg.ID).Msg("using inline body from queue (legacy format)")
+	} else if dbMsg.Body.Valid {
+		// Messages enqueued with their body in the database, such as system
+		// emails and SMTP submissions the message store did not accept.
+		body = []byte(dbMsg.Body.String)
 	} else {
 		// New format: fetch from MessageStore with retry (REQ-QW-002).
 		body, err = h.fetchBodyWithRetry(ctx, msg.ID)
@@ -106,26 +185,18 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
 				ID:     messageID,
 				Status: storage.MessageStatusStorageError,
 			}); statusErr != nil {
-				h.log.Error().Err(statusErr).Str("message_id", msg.ID).Msg("failed to set storage_error status")
+				h.logger(ctx).Error().Err(statusErr).Str("message_id", msg.ID).Msg("failed to set storage_error status")
 			}
-			h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", fmt.Errorf("storage read: %w", err))
+			h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, fmt.Errorf("storage read: %w", err))
 			return fmt.Errorf("fetch body for %s: %w", msg.ID, err)
 		}
 	}
 
-	// Resolve provider for this group.
-	p, err := h.resolver.Resolve(ctx, groupID)
-	if err != nil {
-		h.log.Error().Err(err).
-			Stringer("group_id", groupID).
-			Str("message_id", msg.ID).
-			Msg("failed to resolve provider")
-		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", err)
-		return fmt.Errorf("resolve provider: %w", err)
+	// Inbound messages go to their route's HTTP endpoint, not to an ESP.
+	if dbMsg.InboundRouteID.Valid {
+		return h.deliverInbound(ctx, messageID, dbMsg, body)
 	}
 
-	providerName := p.GetName()
-
 	// Build provider message from DB metadata + body.
 	providerMsg := &provider.Message{
 		ID:       msg.ID,
@@ -136,6 +207,7 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
 		Headers:  parseHeaders(dbMsg.Headers),
 		Body:     body,
 	}
+	providerMsg.Tags, providerMsg.Metadata = msgtag.Decode(dbMsg.Tags, dbMsg.Metadata)
 
 	// Parse MIME structure to extract HTML body and attachments.
 	parsed, parseErr := mimeparse.Parse(body)
@@ -157,40 +229,200 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
 	} else {
 		// MIME parse failed -- fall back to raw body as text.
 		providerMsg.TextBody = string(body)
-		h.log.Debug().Err(parseErr).Str("message_id", msg.ID).Msg("MIME parse failed, using raw body as text")
+		h.logger(ctx).Debug().Err(parseErr).Str("message_id", msg.ID).Msg("MIME parse failed, using raw body as text")
+	}
+
+	if providerMsg.HTMLBody != "" {
+		providerMsg.HTMLBody = h.processHTML(ctx, groupID, msg.ID, providerMsg.HTMLBody)
+	}
+
+	// The group's scripts run before plugin hooks. A rejection fails the
+	// message for good, unless scripts run in shadow mode; a script error
+	// is retried like a send failure.
+	shadowGroup := shadow.NewGroup(h.queries, groupID)
+	var scriptProvider string
+	if h.scripts != nil {
+		res, err := h.scripts.Run(ctx, groupID, providerMsg)
+		if err != nil {
+			h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("message script failed")
+			h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+			return fmt.Errorf("run scripts: %w", err)
+		}
+		if res.Reject != "" && !h.shadowed(ctx, shadowGroup, shadow.PolicyScripts, "script "+res.Script+": "+res.Reject, messageID) {
+			h.logger(ctx).Info().
+				Str("script", res.Script).
+				Str("message_id", msg.ID).
+				Msg("message rejected by script")
+			h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, fmt.Errorf("rejected by script %s: %s", res.Script, res.Reject))
+			return nil
+		}
+		scriptProvider = res.Provider
+	}
+
+	// Plugin hooks may rewrite the message before it is routed.
+	if err := provider.RunPreRoute(ctx, providerMsg); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("pre-route hook failed")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+		return err
+	}
+
+	// The sender policy is enforced after scripts and plugin hooks so that
+	// neither can put an unverified address back into the From.
+	decision, err := h.enforceSenderPolicy(ctx, messageID, groupID, shadowGroup, providerMsg)
+	if err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to load sender policy")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+		return err
+	}
+	if decision.Action == senderpolicy.ActionReject {
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, fmt.Errorf("rejected by sender policy: %s", decision.Reason))
+		return nil
+	}
+
+	// Reply-To and bounce defaults come from the domain the message is
+	// sent from, after any sender policy rewrite.
+	if err := h.applySendingDefaults(ctx, groupID, providerMsg); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to load sending domain defaults")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+		return err
+	}
+
+	// The group's default headers and footer are added after scripts and
+	// hooks, so that neither can strip a required disclaimer.
+	if err := h.applyMessageDefaults(ctx, groupID, providerMsg); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to load message defaults")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+		return err
+	}
+
+	// Compliance copies are added last so that neither scripts nor hooks
+	// can drop them.
+	if err := h.applyAutoBCC(ctx, dbMsg.UserID, providerMsg); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to load auto-BCC addresses")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+		return err
+	}
+
+	// Resolve provider for this group, unless the message was pinned to a
+	// provider when it was reprocessed from the DLQ or a script chose one.
+	p, err := h.resolveProvider(ctx, groupID, msg, scriptProvider)
+	if err == nil {
+		p, err = h.reserveDailySend(ctx, groupID, msg, scriptProvider, p)
+	}
+	// Messages whose providers all reached their daily cap wait for the
+	// first cap to reset rather than using up their retries.
+	var capped *provider.CappedError
+	if errors.As(err, &capped) {
+		if err := h.deferDelivery(ctx, messageID, dbMsg, time.Until(capped.Until)); err != nil {
+			h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to defer capped delivery")
+			return fmt.Errorf("defer delivery: %w", err)
+		}
+		h.logger(ctx).Info().
+			Stringer("group_id", groupID).
+			Time("until", capped.Until).
+			Str("message_id", msg.ID).
+			Msg("delivery deferred until a provider's daily cap resets")
+		return nil
+	}
+	if err != nil {
+		h.logger(ctx).Error().Err(err).
+			Stringer("group_id", groupID).
+			Str("message_id", msg.ID).
+			Msg("failed to resolve provider")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, "", pgtype.UUID{}, err)
+		return fmt.Errorf("resolve provider: %w", err)
 	}
 
-	// Send via ESP provider.
+	providerName := p.GetName()
+	providerID := resolvedProviderID(p)
+
+	// Deliveries over one of the provider's recipient domain limits are
+	// deferred and come back once the domain has capacity again.
+	if h.throttle != nil {
+		hold, deferral := h.throttle.admit(ctx, p, slices.Concat(providerMsg.To, providerMsg.Bcc))
+		if deferral != nil {
+			metrics.DeliveryDomainThrottledTotal.WithLabelValues(providerName, deferral.domain, deferral.limit).Inc()
+			if err := h.deferDelivery(ctx, messageID, dbMsg, deferral.wait); err != nil {
+				h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to defer throttled delivery")
+				return fmt.Errorf("defer delivery: %w", err)
+			}
+			h.logger(ctx).Info().
+				Str("provider", providerName).
+				Str("domain", deferral.domain).
+				Str("limit", deferral.limit).
+				Dur("wait", deferral.wait).
+				Str("message_id", msg.ID).
+				Msg("delivery deferred by recipient domain limit")
+			return nil
+		}
+		defer hold.Release()
+	}
+
+	if err := provider.RunPreSend(ctx, p, providerMsg); err != nil {
+		h.logger(ctx).Error().Err(err).
+			Str("provider", providerName).
+			Str("message_id", msg.ID).
+			Msg("pre-send hook failed")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, providerName, providerID, err)
+		return err
+	}
+
+	// Signing comes after every hook and default so that nothing changes
+	// the message once it is signed.
+	if err := h.applySigning(ctx, messageID, groupID, p, providerMsg); err != nil {
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, providerName, providerID, err)
+		if errors.Is(err, errSigningFailed) {
+			return nil
+		}
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to load signing key")
+		return err
+	}
+
+	// Send via ESP provider, recording the connection for the delivery log
+	// and, in the provider's debug mode, the API requests it makes.
+	ctx = provider.WithConnectionAudit(ctx)
+	captureLimit := provider.CaptureLimit(p)
+	if captureLimit > 0 {
+		ctx = provider.WithCapture(ctx)
+	}
 	sendStart := time.Now()
 	result, sendErr := p.Send(ctx, providerMsg)
 	sendDuration := time.Since(sendStart)
+	provider.RunPostSend(ctx, p, providerMsg, result, sendErr)
+	if captureLimit > 0 {
+		h.storeCapture(ctx, messageID, providerID, captureLimit, sendErr)
+	}
 	if sendErr != nil {
-		h.log.Error().Err(sendErr).
+		h.logger(ctx).Error().Str("error", redact.Text(sendErr.Error())).
 			Str("provider", providerName).
 			Str("message_id", msg.ID).
 			Msg("provider send failed")
-		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, providerName, sendErr)
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, providerName, providerID, sendErr)
 		return fmt.Errorf("provider send: %w", sendErr)
 	}
 
 	// Record success.
-	h.log.Info().
+	h.logger(ctx).Info().
 		Str("provider", providerName).
 		Str("message_id", msg.ID).
 		Str("provider_message_id", result.ProviderMessageID).
 		Int64("duration_ms", sendDuration.Milliseconds()).
 		Msg("message delivered by worker")
 
+	if dbMsg.EnqueuedAt.Valid {
+		metrics.DeliveryLatencySeconds.WithLabelValues(providerName).Observe(time.Since(dbMsg.EnqueuedAt.Time).Seconds())
+	}
+
 	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
 		ID:     messageID,
 		Status: storage.MessageStatusDelivered,
 	}); err != nil {
-		h.log.Error().Err(err).Str("message_id", msg.ID).Msg("failed to update delivered status")
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to update delivered status")
 	}
 
-	if _, err := h.queries.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+	if _, err := h.queries.CreateDeliveryLog(ctx, withConnection(ctx, storage.CreateDeliveryLogParams{
 		MessageID:         messageID,
-		ProviderID:        pgtype.UUID{},
+		ProviderID:        providerID,
 		Status:            string(storage.MessageStatusDelivered),
 		Provider:          sql.NullString{String: providerName, Valid: true},
 		ProviderMessageID: sql.NullString{String: result.ProviderMessageID, Valid: result.ProviderMessageID != ""},
@@ -198,8 +430,90 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *queue.Message) error {
 		UserID:            dbMsg.UserID,
 		DurationMs:        pgtype.Int4{Int32: int32(sendDuration.Milliseconds()), Valid: true},
 		AttemptNumber:     1,
+		RequestID:         requestID(ctx),
+	})); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", msg.ID).Msg("failed to create delivery log")
+	}
+
+	return nil
+}
+
+// deliverInbound posts an inbound message to its route's endpoint, or
+// processes it as a feedback report for arf routes. Errors that a retry
+// cannot fix (route deleted or disabled, 4xx responses) fail the message
+// without a retry; other errors are returned so the queue retries the
+// message.
+func (h *Handler) deliverInbound(ctx context.Context, messageID uuid.UUID, dbMsg storage.Message, body []byte) error {
+	routeID := uuid.UUID(dbMsg.InboundRouteID.Bytes)
+	route, err := h.queries.GetInboundRouteByID(ctx, routeID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, inboundProviderName, pgtype.UUID{}, fmt.Errorf("inbound route %s no longer exists", routeID))
+			return nil
+		}
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, inboundProviderName, pgtype.UUID{}, fmt.Errorf("get inbound route: %w", err))
+		return fmt.Errorf("get inbound route %s: %w", routeID, err)
+	}
+	if !route.Enabled {
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, inboundProviderName, pgtype.UUID{}, fmt.Errorf("inbound route for %s is disabled", route.Domain))
+		return nil
+	}
+	if route.Format == inbound.FormatARF {
+		return h.processFeedbackReport(ctx, messageID, dbMsg, route, body)
+	}
+
+	env := inbound.Envelope{
+		MessageID:  messageID.String(),
+		From:       dbMsg.Sender,
+		To:         parseRecipients(dbMsg.Recipients),
+		ReceivedAt: dbMsg.EnqueuedAt.Time,
+	}
+	postStart := time.Now()
+	status, postErr := h.inbound.Post(ctx, inbound.Route{
+		URL:    route.Url,
+		Format: route.Format,
+		Secret: route.Secret.String,
+	}, env, body)
+	duration := time.Since(postStart)
+	if postErr != nil {
+		h.logger(ctx).Error().Err(postErr).
+			Str("domain", route.Domain).
+			Int("status_code", status).
+			Str("message_id", env.MessageID).
+			Msg("inbound post failed")
+		h.recordFailure(ctx, messageID, dbMsg.GroupID, dbMsg.UserID, inboundProviderName, pgtype.UUID{}, postErr)
+		if inbound.IsPermanent(postErr) {
+			return nil
+		}
+		return fmt.Errorf("inbound post: %w", postErr)
+	}
+
+	h.logger(ctx).Info().
+		Str("domain", route.Domain).
+		Int("status_code", status).
+		Str("message_id", env.MessageID).
+		Int64("duration_ms", duration.Milliseconds()).
+		Msg("inbound message posted")
+
+	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
+		ID:     messageID,
+		Status: storage.MessageStatusDelivered,
+	}); err != nil {
+		h.logger(ctx).Error().Err(err).Str("message_id", env.MessageID).Msg("failed to update delivered status")
+	}
+
+	if _, err := h.queries.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+		MessageID:     messageID,
+		Status:        string(storage.MessageStatusDelivered),
+		Provider:      sql.NullString{String: inboundProviderName, Valid: true},
+		ResponseCode:  pgtype.Int4{Int32: int32(status), Valid: true},
+		GroupID:       dbMsg.GroupID,
+		UserID:        dbMsg.UserID,
+		DurationMs:    pgtype.Int4{Int32: int32(duration.Milliseconds()), Valid: true},
+		AttemptNumber: 1,
+		RequestID:     requestID(ctx),
 	}); err != nil {
-		h.log.Error().Err(err).Str("message_id", msg.ID).Msg("failed to create delivery log")
+		h.logger(ctx).Error().Err(err).Str("message_id", env.MessageID).Msg("failed to create delivery log")
 	}
 
 	return nil
@@ -216,7 +530,7 @@ func (h *Handler) fetchBodyWithRetry(ctx context.Context, messageID string) ([]b
 			return data, nil
 		}
 		lastErr = err
-		h.log.Warn().Err(err).
+		h.logger(ctx).Warn().Err(err).
 			Str("message_id", messageID).
 			Int("attempt", attempt+1).
 			Int("max_attempts", len(storageRetryBackoff)).
@@ -229,32 +543,470 @@ func (h *Handler) fetchBodyWithRetry(ctx context.Context, messageID string) ([]b
 		}
 	}
 
-	h.log.Error().Err(lastErr).
+	h.logger(ctx).Error().Err(lastErr).
 		Str("message_id", messageID).
 		Msg("storage read failed after all retries")
 	return nil, fmt.Errorf("all %d retries exhausted: %w", len(storageRetryBackoff), lastErr)
 }
 
 // recordFailure updates the message status to failed and creates a delivery log.
-func (h *Handler) recordFailure(ctx context.Context, messageID uuid.UUID, groupID pgtype.UUID, userID pgtype.UUID, providerName string, deliveryErr error) {
+func (h *Handler) recordFailure(ctx context.Context, messageID uuid.UUID, groupID pgtype.UUID, userID pgtype.UUID, providerName string, providerID pgtype.UUID, deliveryErr error) {
 	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
 		ID:     messageID,
 		Status: storage.MessageStatusFailed,
 	}); err != nil {
-		h.log.Error().Err(err).Stringer("message_id", messageID).Msg("failed to update failed status")
+		h.logger(ctx).Error().Err(err).Stringer("message_id", messageID).Msg("failed to update failed status")
 	}
 
-	if _, err := h.queries.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+	if _, err := h.queries.CreateDeliveryLog(ctx, withConnection(ctx, storage.CreateDeliveryLogParams{
 		MessageID:  messageID,
-		ProviderID: pgtype.UUID{},
+		ProviderID: providerID,
 		Status:     string(storage.MessageStatusFailed),
 		Provider:   sql.NullString{String: providerName, Valid: providerName != ""},
-		LastError:  pgtype.Text{String: deliveryErr.Error(), Valid: true},
+		LastError:  pgtype.Text{String: redact.Text(deliveryErr.Error()), Valid: true},
 		GroupID:    groupID,
 		UserID:     userID,
+		RequestID:  requestID(ctx),
+	})); err != nil {
+		h.logger(ctx).Error().Err(err).Stringer("message_id", messageID).Msg("failed to create failure delivery log")
+	}
+}
+
+// applySendingDefaults sets the Reply-To and bounce address configured for
+// the sender's domain when msg does not carry its own.
+func (h *Handler) applySendingDefaults(ctx context.Context, groupID uuid.UUID, msg *provider.Message) error {
+	at := strings.LastIndex(msg.From, "@")
+	if at < 0 {
+		return nil
+	}
+	d, err := h.queries.GetSendingDomain(ctx, storage.GetSendingDomainParams{
+		GroupID: groupID,
+		Domain:  strings.ToLower(msg.From[at+1:]),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get sending domain: %w", err)
+	}
+	if d.ReplyTo.Valid && msg.Headers["Reply-To"] == "" {
+		if msg.Headers == nil {
+			msg.Headers = make(map[string]string)
+		}
+		msg.Headers["Reply-To"] = d.ReplyTo.String
+	}
+	if d.ReturnPath.Valid && msg.ReturnPath == "" && msg.Headers["Return-Path"] == "" {
+		msg.ReturnPath = d.ReturnPath.String
+	}
+	return nil
+}
+
+// applyMessageDefaults adds the group's default headers that msg does not
+// set and appends the group's footer to its text and HTML bodies, unless
+// one of msg's tags or its template is excluded. Groups without defaults
+// are not changed.
+func (h *Handler) applyMessageDefaults(ctx context.Context, groupID uuid.UUID, msg *provider.Message) error {
+	row, err := h.queries.GetMessageDefaults(ctx, groupID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get message defaults: %w", err)
+	}
+	d := msgdefaults.Defaults{FooterText: row.FooterText, FooterHTML: row.FooterHtml}
+	if err := json.Unmarshal(row.Headers, &d.Headers); err != nil {
+		return fmt.Errorf("decode default headers of group %s: %w", groupID, err)
+	}
+	if err := json.Unmarshal(row.ExcludeTags, &d.ExcludeTags); err != nil {
+		return fmt.Errorf("decode excluded tags of group %s: %w", groupID, err)
+	}
+	if err := json.Unmarshal(row.ExcludeTemplates, &d.ExcludeTemplates); err != nil {
+		return fmt.Errorf("decode excluded templates of group %s: %w", groupID, err)
+	}
+	if d.Excludes(msg.Tags, msg.Metadata) {
+		return nil
+	}
+
+	msg.Headers = d.ApplyHeaders(msg.Headers)
+	msg.TextBody = d.AppendText(msg.TextBody)
+	msg.HTMLBody = d.AppendHTML(msg.HTMLBody)
+	return nil
+}
+
+// applyAutoBCC adds the submitting user's auto-BCC addresses to msg.Bcc,
+// skipping addresses the message already goes to.
+func (h *Handler) applyAutoBCC(ctx context.Context, userID pgtype.UUID, msg *provider.Message) error {
+	if !userID.Valid {
+		return nil
+	}
+	user, err := h.queries.GetUserByID(ctx, uuid.UUID(userID.Bytes))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get user: %w", err)
+	}
+	if len(user.AutoBcc) == 0 {
+		return nil
+	}
+	var addrs []string
+	if err := json.Unmarshal(user.AutoBcc, &addrs); err != nil {
+		return fmt.Errorf("decode auto_bcc of user %s: %w", user.ID, err)
+	}
+	for _, addr := range addrs {
+		if !containsFold(msg.To, addr) && !containsFold(msg.Bcc, addr) {
+			msg.Bcc = append(msg.Bcc, addr)
+		}
+	}
+	return nil
+}
+
+// errSigningFailed marks messages failed by a signing key whose failure
+// policy is fail.
+var errSigningFailed = errors.New("message signing failed")
+
+// errNoRecipientCert marks a recipient without a usable encryption
+// certificate.
+var errNoRecipientCert = errors.New("no usable recipient certificate")
+
+// applySigning signs msg with the group's signing key, and encrypts it to
+// the recipients' certificates when the key has encrypt set, by setting
+// msg.Raw. Groups without a key are not signed. When msg cannot be signed,
+// or cannot be encrypted, the key's failure policy either sends it as it
+// is or fails it with an error wrapping errSigningFailed.
+func (h *Handler) applySigning(ctx context.Context, messageID, groupID uuid.UUID, p provider.Provider, msg *provider.Message) error {
+	key, err := h.queries.GetSigningKey(ctx, groupID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get signing key: %w", err)
+	}
+
+	fail := func(result string, cause error) error {
+		if key.OnFailure == msgsign.OnFailureFail {
+			metrics.MessageSigningTotal.WithLabelValues(key.Kind, "failed").Inc()
+			return fmt.Errorf("%w: %v", errSigningFailed, cause)
+		}
+		metrics.MessageSigningTotal.WithLabelValues(key.Kind, result).Inc()
+		h.logger(ctx).Warn().Err(cause).Stringer("message_id", messageID).Str("kind", key.Kind).
+			Msgf("sending message %s", result)
+		return nil
+	}
+
+	if !provider.SendsRaw(p) {
+		return fail("unsigned", fmt.Errorf("provider %s cannot send signed messages", p.GetName()))
+	}
+	entity, err := msgsign.Entity(msg)
+	if err != nil {
+		return fail("unsigned", err)
+	}
+	var signer msgsign.Signer
+	switch key.Kind {
+	case msgsign.KindSMIME:
+		signer, err = msgsign.NewSMIMESigner(key.Certificate.String, key.PrivateKey)
+	case msgsign.KindPGP:
+		signer, err = msgsign.NewPGPSigner(key.PrivateKey)
+	default:
+		err = fmt.Errorf("unknown signing key kind %q", key.Kind)
+	}
+	if err != nil {
+		return fail("unsigned", err)
+	}
+	signed, err := signer.Sign(entity)
+	if err != nil {
+		return fail("unsigned", err)
+	}
+	msg.Raw = msgsign.Build(msg, signed)
+	if !key.Encrypt {
+		metrics.MessageSigningTotal.WithLabelValues(key.Kind, "signed").Inc()
+		return nil
+	}
+
+	// Every recipient needs a certificate, or some could not read the
+	// message; the signed message is sent unencrypted instead.
+	certs, err := h.recipientCertificates(ctx, groupID, msg)
+	if err != nil && !errors.Is(err, errNoRecipientCert) {
+		msg.Raw = nil
+		return err
+	}
+	var encrypted []byte
+	if err == nil {
+		encrypted, err = msgsign.EncryptSMIME(signed, certs)
+	}
+	if err != nil {
+		return fail("unencrypted", err)
+	}
+	msg.Raw = msgsign.Build(msg, encrypted)
+	metrics.MessageSigningTotal.WithLabelValues(key.Kind, "encrypted").Inc()
+	return nil
+}
+
+// recipientCertificates returns the encryption certificates of every To
+// and Bcc recipient of msg, or an error naming a recipient without one.
+func (h *Handler) recipientCertificates(ctx context.Context, groupID uuid.UUID, msg *provider.Message) ([]*x509.Certificate, error) {
+	rows, err := h.queries.ListRecipientCertificatesByGroupID(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("list recipient certificates: %w", err)
+	}
+	byEmail := make(map[string]string, len(rows))
+	for _, c := range rows {
+		byEmail[strings.ToLower(c.Email)] = c.Certificate
+	}
+
+	var certs []*x509.Certificate
+	for _, rcpt := range append(append([]string{}, msg.To...), msg.Bcc...) {
+		pemData, ok := byEmail[strings.ToLower(rcpt)]
+		if !ok {
+			return nil, fmt.Errorf("%w for %s", errNoRecipientCert, rcpt)
+		}
+		parsed, err := msgsign.ParseCertificates(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", errNoRecipientCert, rcpt, err)
+		}
+		certs = append(certs, parsed[0])
+	}
+	return certs, nil
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowed reports whether the group runs policy in shadow mode, in which
+// case the rejection it would have made for reason is recorded and the
+// message must be delivered.
+func (h *Handler) shadowed(ctx context.Context, g *shadow.Group, policy, reason string, messageID uuid.UUID) bool {
+	ok, err := g.Shadowed(ctx, policy, reason)
+	if err != nil {
+		h.logger(ctx).Warn().Err(err).Str("policy", policy).Stringer("message_id", messageID).Msg("shadow mode check failed")
+	}
+	if ok {
+		h.logger(ctx).Info().
+			Str("policy", policy).
+			Str("reason", redact.Text(reason)).
+			Stringer("message_id", messageID).
+			Msg("would reject, policy in shadow mode")
+	}
+	return ok
+}
+
+// enforceSenderPolicy applies the group's sender policy to msg and records
+// rejections and rewrites in the activity log. Groups without a policy are
+// not checked, and rejections of a policy in shadow mode are only recorded
+// in shadowGroup.
+func (h *Handler) enforceSenderPolicy(ctx context.Context, messageID, groupID uuid.UUID, shadowGroup *shadow.Group, msg *provider.Message) (senderpolicy.Decision, error) {
+	allow := senderpolicy.Decision{Action: senderpolicy.ActionAllow}
+	policy, err := h.queries.GetSenderPolicy(ctx, groupID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return allow, nil
+	}
+	if err != nil {
+		return allow, fmt.Errorf("get sender policy: %w", err)
+	}
+	if policy.Mode == senderpolicy.ModeOff {
+		return allow, nil
+	}
+	identities, err := h.queries.ListVerifiedSenderIdentities(ctx, groupID)
+	if err != nil {
+		return allow, fmt.Errorf("list sender identities: %w", err)
+	}
+
+	d := senderpolicy.Enforce(senderpolicy.Policy{
+		Mode:           policy.Mode,
+		RewriteAddress: policy.RewriteAddress.String,
+		Identities:     identities,
+	}, msg)
+	if d.Action == senderpolicy.ActionAllow {
+		return d, nil
+	}
+	if d.Action == senderpolicy.ActionReject && h.shadowed(ctx, shadowGroup, shadow.PolicySenderPolicy, d.Reason, messageID) {
+		return allow, nil
+	}
+
+	action := auth.AuditActionSenderRejected
+	if d.Action == senderpolicy.ActionRewrite {
+		action = auth.AuditActionSenderRewritten
+	}
+	h.logger(ctx).Info().
+		Str("action", d.Action).
+		Str("reason", redact.Text(d.Reason)).
+		Stringer("message_id", messageID).
+		Msg("sender policy applied")
+	changes := map[string]interface{}{"from": d.From}
+	if d.RewrittenFrom != "" {
+		changes["rewritten_from"] = d.RewrittenFrom
+	}
+	if _, err := h.queries.CreateActivityLog(ctx, storage.CreateActivityLogParams{
+		GroupID:      groupID,
+		Action:       action,
+		ResourceType: "message",
+		ResourceID:   pgtype.UUID{Bytes: messageID, Valid: true},
+		Changes:      auth.ChangesToJSON(changes),
+		Comment:      pgtype.Text{String: d.Reason, Valid: true},
+	}); err != nil {
+		h.logger(ctx).Error().Err(err).Stringer("message_id", messageID).Msg("failed to write sender policy audit entry")
+	}
+	return d, nil
+}
+
+// withConnection fills the connection audit columns of a delivery log from
+// the provider request recorded in ctx, if any.
+func withConnection(ctx context.Context, params storage.CreateDeliveryLogParams) storage.CreateDeliveryLogParams {
+	conn, ok := provider.ConnectionFromContext(ctx)
+	if !ok {
+		return params
+	}
+	text := func(s string) pgtype.Text { return pgtype.Text{String: s, Valid: s != ""} }
+	ms := func(d time.Duration, valid bool) pgtype.Int4 {
+		return pgtype.Int4{Int32: int32(d.Milliseconds()), Valid: valid}
+	}
+	params.EgressIp = text(conn.EgressIP)
+	params.RemoteAddr = text(conn.RemoteAddr)
+	params.ProviderEndpoint = text(conn.Endpoint)
+	params.TlsVersion = text(conn.TLSVersion)
+	params.TlsCipher = text(conn.TLSCipher)
+	params.ConnectMs = ms(conn.Connect, conn.Connect > 0)
+	params.TlsHandshakeMs = ms(conn.TLSHandshake, conn.TLSHandshake > 0)
+	params.FirstByteMs = ms(conn.FirstByte, conn.FirstByte > 0)
+	return params
+}
+
+// storeCapture saves the provider requests captured while sending a
+// message and drops the provider's captures beyond the last limit.
+// Failures are logged; they never affect delivery.
+func (h *Handler) storeCapture(ctx context.Context, messageID uuid.UUID, providerID pgtype.UUID, limit int, sendErr error) {
+	if !providerID.Valid {
+		return
+	}
+	captured := provider.CapturedExchanges(ctx)
+	if captured == nil {
+		captured = []provider.Exchange{}
+	}
+	exchanges, err := json.Marshal(captured)
+	if err != nil {
+		h.logger(ctx).Error().Err(err).Stringer("message_id", messageID).Msg("failed to encode provider capture")
+		return
+	}
+	var errText pgtype.Text
+	if sendErr != nil {
+		errText = pgtype.Text{String: redact.Text(sendErr.Error()), Valid: true}
+	}
+	id := uuid.UUID(providerID.Bytes)
+	if _, err := h.queries.CreateProviderCapture(ctx, storage.CreateProviderCaptureParams{
+		ProviderID: id,
+		MessageID:  pgtype.UUID{Bytes: messageID, Valid: true},
+		Exchanges:  exchanges,
+		Error:      errText,
+	}); err != nil {
+		h.logger(ctx).Error().Err(err).Stringer("message_id", messageID).Msg("failed to store provider capture")
+		return
+	}
+	if err := h.queries.PruneProviderCaptures(ctx, storage.PruneProviderCapturesParams{
+		ProviderID: id,
+		Limit:      int32(limit),
 	}); err != nil {
-		h.log.Error().Err(err).Stringer("message_id", messageID).Msg("failed to create failure delivery log")
+		h.logger(ctx).Warn().Err(err).Stringer("provider_id", id).Msg("failed to prune provider captures")
+	}
+}
+
+// logger returns the handler's logger with the correlation ID of the
+// message being handled, so every log line can be traced back to the SMTP
+// session or API request that submitted it.
+func (h *Handler) logger(ctx context.Context) *zerolog.Logger {
+	log := h.log
+	if id := logger.CorrelationIDFromContext(ctx); id != "" {
+		log = log.With().Str("correlation_id", id).Logger()
 	}
+	return &log
+}
+
+// requestID returns the correlation ID in ctx for delivery log rows.
+func requestID(ctx context.Context) pgtype.Text {
+	id := logger.CorrelationIDFromContext(ctx)
+	return pgtype.Text{String: id, Valid: id != ""}
+}
+
+// processHTML applies the group's HTML processing settings to an HTML body:
+// CSS inlining first, so rules in <style> blocks are not lost, then
+// sanitization. If the group cannot be loaded the body is sent unchanged.
+func (h *Handler) processHTML(ctx context.Context, groupID uuid.UUID, messageID, body string) string {
+	group, err := h.queries.GetGroupByID(ctx, groupID)
+	if err != nil {
+		h.logger(ctx).Warn().Err(err).
+			Stringer("group_id", groupID).
+			Str("message_id", messageID).
+			Msg("failed to load group settings, skipping HTML processing")
+		return body
+	}
+
+	if group.InlineCss {
+		body = htmlutil.InlineCSS(body)
+	}
+	if group.SanitizeHtml {
+		body = htmlutil.Sanitize(body)
+	}
+	return body
+}
+
+// resolveProvider returns the provider msg is pinned to, else the provider
+// a script chose by name, else the group's provider.
+func (h *Handler) resolveProvider(ctx context.Context, groupID uuid.UUID, msg *queue.Message, scriptProvider string) (provider.Provider, error) {
+	if msg.ProviderID == "" {
+		if scriptProvider != "" {
+			return h.resolveNamedProvider(ctx, groupID, scriptProvider)
+		}
+		return h.resolver.Resolve(ctx, groupID)
+	}
+	providerID, err := uuid.Parse(msg.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("parse pinned provider ID %q: %w", msg.ProviderID, err)
+	}
+	return h.resolver.ResolveByID(ctx, groupID, providerID)
+}
+
+// resolveNamedProvider returns the provider called name from groupID or,
+// failing that, its nearest ancestor that has one.
+func (h *Handler) resolveNamedProvider(ctx context.Context, groupID uuid.UUID, name string) (provider.Provider, error) {
+	groupIDs := []uuid.UUID{groupID}
+	ancestors, err := h.queries.ListGroupAncestors(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("list ancestors of group %s: %w", groupID, err)
+	}
+	for _, a := range ancestors {
+		if a.ID != groupID {
+			groupIDs = append(groupIDs, a.ID)
+		}
+	}
+
+	for _, id := range groupIDs {
+		providers, err := h.queries.ListProvidersByGroupID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("list providers for group %s: %w", id, err)
+		}
+		for _, p := range providers {
+			if p.Name == name {
+				return h.resolver.ResolveByID(ctx, groupID, p.ID)
+			}
+		}
+	}
+	return nil, fmt.Errorf("script chose unknown provider %q", name)
+}
+
+// resolvedProviderID returns the esp_providers ID of a resolved provider, or
+// a null UUID for providers not backed by a database row (the stdout
+// default).
+func resolvedProviderID(p provider.Provider) pgtype.UUID {
+	if ident, ok := p.(provider.Identified); ok {
+		return pgtype.UUID{Bytes: ident.ProviderID(), Valid: true}
+	}
+	return pgtype.UUID{}
 }
 
 // parseRecipients decodes a JSON-encoded []string from the database recipients
@@ -267,7 +1019,9 @@ func parseRecipients(data []byte) []string {
 
 // parseHeaders decodes a JSON-encoded map[string][]string from the database
 // headers column and flattens it to map[string]string by taking the first
-// value of each key.
+// value of each key. Tag and metadata control headers are dropped; they are
+// passed to providers as Message.Tags and Message.Metadata instead. So is
+// the urgent header, which only applies to the sending windows.
 func parseHeaders(data []byte) map[string]string {
 	if len(data) == 0 {
 		return nil
@@ -278,6 +1032,9 @@ func parseHeaders(data []byte) map[string]string {
 	}
 	flat := make(map[string]string, len(multi))
 	for k, v := range multi {
+		if msgtag.IsControlHeader(k) || strings.EqualFold(k, sendwindow.HeaderUrgent) {
+			continue
+		}
 		if len(v) > 0 {
 			flat[k] = v[0]
 		}
@@ -1,10 +1,22 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -14,10 +26,15 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog"
 
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/crash"
 	"github.com/sungwon/smtp-proxy/server/internal/msgstore"
 	"github.com/sungwon/smtp-proxy/server/internal/provider"
 	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/senderpolicy"
+	"github.com/sungwon/smtp-proxy/server/internal/shadow"
 	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
 )
 
 // ---------------------------------------------------------------------------
@@ -32,10 +49,75 @@ type mockQuerier struct {
 	createLogParams   storage.CreateDeliveryLogParams
 	listProvidersFn   func(ctx context.Context, groupID uuid.UUID) ([]storage.EspProvider, error)
 	getMessageFn      func(ctx context.Context, id uuid.UUID) (storage.Message, error)
+	listStuckFn       func(ctx context.Context, arg storage.ListStuckMessagesParams) ([]storage.Message, error)
+	requeuedIDs       []uuid.UUID
+	progressed        bool // stuck messages changed state before the sweeper updated them
+	activityLogs      []storage.CreateActivityLogParams
+	deliveryLatencyFn func(ctx context.Context, deliveredAt pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error)
+
+	healthCheckProviders []storage.EspProvider
+	providerHealth       []storage.UpdateProviderHealthParams
+	healthChecks         []storage.CreateProviderHealthCheckParams
+	autoDisabledIDs      []uuid.UUID
+	autoEnabledIDs       []uuid.UUID
+
+	enabledProviders   []storage.EspProvider
+	accountStats       []storage.UpsertProviderAccountStatsParams
+	accountStatsErrors []storage.RecordProviderAccountStatsErrorParams
+	// pauseReasons is the stored pause reason per provider; every update
+	// is also recorded in reputationPauses.
+	pauseReasons     map[uuid.UUID]string
+	reputationPauses []storage.UpdateProviderReputationPauseParams
+
+	group storage.Group
+
+	monthlyUsage     []storage.ListGroupMonthlyUsageRow
+	ownerEmails      []string
+	quotaNotified    map[storage.RecordQuotaNotificationParams]bool
+	enqueuedMessages []storage.EnqueueMessageParams
+	outboxEntries    []storage.CreateOutboxEntryParams
+	delayedEntries   []storage.CreateDelayedOutboxEntryParams
+	groupBranding    map[uuid.UUID]storage.GroupBranding
+
+	// dailySends counts the deliveries reserved against daily caps.
+	dailySends map[storage.ReserveProviderDailySendParams]int32
+
+	expiringPasswords []storage.ListExpiringSMTPPasswordsRow
+	expiryWarned      []uuid.UUID
+
+	inboundRoutes map[uuid.UUID]storage.InboundRoute
+	// reportedMessages are the messages feedback reports can match;
+	// logStatusUpdates and suppressions record what the reports did.
+	reportedMessages []storage.Message
+	deliveryLogs     map[uuid.UUID][]storage.DeliveryLog
+	logStatusUpdates []storage.UpdateDeliveryLogStatusParams
+	suppressions     []storage.AddSuppressionParams
+
+	scripts []storage.MessageScript
+
+	captures      []storage.CreateProviderCaptureParams
+	capturePrunes []storage.PruneProviderCapturesParams
+
+	senderPolicy     storage.SenderPolicy
+	senderIdentities []string
+	// messageDefaults are the group's message defaults, if set.
+	messageDefaults *storage.MessageDefault
+	// shadowPolicies are the group's policies in shadow mode, and
+	// shadowRejections the would-be rejections recorded for them.
+	shadowPolicies   []string
+	shadowRejections []storage.RecordShadowRejectionParams
+
+	sendingDomains map[string]storage.SendingDomain
+
+	signingKey     *storage.SigningKey
+	recipientCerts []storage.RecipientCertificate
+
+	user storage.User
 }
 
 // ActivityLog methods.
-func (m *mockQuerier) CreateActivityLog(_ context.Context, _ storage.CreateActivityLogParams) (storage.ActivityLog, error) {
+func (m *mockQuerier) CreateActivityLog(_ context.Context, arg storage.CreateActivityLogParams) (storage.ActivityLog, error) {
+	m.activityLogs = append(m.activityLogs, arg)
 	return storage.ActivityLog{}, nil
 }
 func (m *mockQuerier) GetActivityLogByID(_ context.Context, _ uuid.UUID) (storage.ActivityLog, error) {
@@ -65,13 +147,14 @@ func (m *mockQuerier) GetDeliveryLogByMessageID(_ context.Context, _ uuid.UUID)
 func (m *mockQuerier) GetDeliveryLogByProviderMessageID(_ context.Context, _ sql.NullString) (storage.DeliveryLog, error) {
 	return storage.DeliveryLog{}, nil
 }
-func (m *mockQuerier) ListDeliveryLogsByMessageID(_ context.Context, _ uuid.UUID) ([]storage.DeliveryLog, error) {
-	return nil, nil
+func (m *mockQuerier) ListDeliveryLogsByMessageID(_ context.Context, messageID uuid.UUID) ([]storage.DeliveryLog, error) {
+	return m.deliveryLogs[messageID], nil
 }
 func (m *mockQuerier) ListDeliveryLogsByGroupAndStatus(_ context.Context, _ storage.ListDeliveryLogsByGroupAndStatusParams) ([]storage.DeliveryLog, error) {
 	return nil, nil
 }
-func (m *mockQuerier) UpdateDeliveryLogStatus(_ context.Context, _ storage.UpdateDeliveryLogStatusParams) error {
+func (m *mockQuerier) UpdateDeliveryLogStatus(_ context.Context, arg storage.UpdateDeliveryLogStatusParams) error {
+	m.logStatusUpdates = append(m.logStatusUpdates, arg)
 	return nil
 }
 
@@ -88,6 +171,15 @@ func (m *mockQuerier) CountDeliveryLogsByProvider(_ context.Context, _ storage.C
 func (m *mockQuerier) CountDeliveryLogsByStatus(_ context.Context, _ storage.CountDeliveryLogsByStatusParams) ([]storage.CountDeliveryLogsByStatusRow, error) {
 	return nil, nil
 }
+func (m *mockQuerier) DailyDeliveryVolume(_ context.Context, _ storage.DailyDeliveryVolumeParams) ([]storage.DailyDeliveryVolumeRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) DeliveryLatencyPercentiles(ctx context.Context, deliveredAt pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+	if m.deliveryLatencyFn != nil {
+		return m.deliveryLatencyFn(ctx, deliveredAt)
+	}
+	return nil, nil
+}
 
 // Group methods.
 func (m *mockQuerier) CreateGroup(_ context.Context, _ storage.CreateGroupParams) (storage.Group, error) {
@@ -95,7 +187,7 @@ func (m *mockQuerier) CreateGroup(_ context.Context, _ storage.CreateGroupParams
 }
 func (m *mockQuerier) DeleteGroup(_ context.Context, _ uuid.UUID) error { return nil }
 func (m *mockQuerier) GetGroupByID(_ context.Context, _ uuid.UUID) (storage.Group, error) {
-	return storage.Group{}, nil
+	return m.group, nil
 }
 func (m *mockQuerier) GetGroupByName(_ context.Context, _ string) (storage.Group, error) {
 	return storage.Group{}, nil
@@ -113,6 +205,15 @@ func (m *mockQuerier) UpdateGroupStatus(_ context.Context, _ storage.UpdateGroup
 func (m *mockQuerier) CountGroupOwners(_ context.Context, _ uuid.UUID) (int64, error) {
 	return 0, nil
 }
+func (m *mockQuerier) UpdateGroupHTMLProcessing(_ context.Context, _ storage.UpdateGroupHTMLProcessingParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+func (m *mockQuerier) ListGroupMonthlyUsage(_ context.Context, _ pgtype.Timestamptz) ([]storage.ListGroupMonthlyUsageRow, error) {
+	return m.monthlyUsage, nil
+}
+func (m *mockQuerier) UpdateGroupRecipientValidation(_ context.Context, _ storage.UpdateGroupRecipientValidationParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
 
 // GroupMember methods.
 func (m *mockQuerier) CreateGroupMember(_ context.Context, _ storage.CreateGroupMemberParams) (storage.GroupMember, error) {
@@ -122,6 +223,9 @@ func (m *mockQuerier) DeleteGroupMember(_ context.Context, _ uuid.UUID) error {
 func (m *mockQuerier) DeleteGroupMembersByUserID(_ context.Context, _ uuid.UUID) error {
 	return nil
 }
+func (m *mockQuerier) DeleteGroupProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
 func (m *mockQuerier) GetGroupMemberByID(_ context.Context, _ uuid.UUID) (storage.GroupMember, error) {
 	return storage.GroupMember{}, nil
 }
@@ -134,10 +238,14 @@ func (m *mockQuerier) ListGroupMembersByGroupID(_ context.Context, _ uuid.UUID)
 func (m *mockQuerier) UpdateGroupMemberRole(_ context.Context, _ storage.UpdateGroupMemberRoleParams) (storage.GroupMember, error) {
 	return storage.GroupMember{}, nil
 }
+func (m *mockQuerier) ListGroupOwnerEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return m.ownerEmails, nil
+}
 
 // Message methods.
-func (m *mockQuerier) EnqueueMessage(_ context.Context, _ storage.EnqueueMessageParams) (storage.Message, error) {
-	return storage.Message{}, nil
+func (m *mockQuerier) EnqueueMessage(_ context.Context, arg storage.EnqueueMessageParams) (storage.Message, error) {
+	m.enqueuedMessages = append(m.enqueuedMessages, arg)
+	return storage.Message{ID: uuid.New()}, nil
 }
 func (m *mockQuerier) EnqueueMessageMetadata(_ context.Context, _ storage.EnqueueMessageMetadataParams) (storage.Message, error) {
 	return storage.Message{}, nil
@@ -164,6 +272,45 @@ func (m *mockQuerier) UpdateMessageStatus(_ context.Context, arg storage.UpdateM
 	m.statuses = append(m.statuses, arg.Status)
 	return nil
 }
+func (m *mockQuerier) ListStuckMessages(ctx context.Context, arg storage.ListStuckMessagesParams) ([]storage.Message, error) {
+	if m.listStuckFn != nil {
+		return m.listStuckFn(ctx, arg)
+	}
+	return nil, nil
+}
+func (m *mockQuerier) RequeueMessage(_ context.Context, arg storage.RequeueMessageParams) (int64, error) {
+	if m.progressed {
+		return 0, nil
+	}
+	m.requeuedIDs = append(m.requeuedIDs, arg.ID)
+	return 1, nil
+}
+func (m *mockQuerier) ExpireStuckMessage(_ context.Context, arg storage.ExpireStuckMessageParams) (int64, error) {
+	if m.progressed {
+		return 0, nil
+	}
+	m.statuses = append(m.statuses, storage.MessageStatusFailed)
+	return 1, nil
+}
+func (m *mockQuerier) MonthlyMessageUsage(_ context.Context, _ storage.MonthlyMessageUsageParams) ([]storage.MonthlyMessageUsageRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) MonthlyProviderUsage(_ context.Context, _ storage.MonthlyProviderUsageParams) ([]storage.MonthlyProviderUsageRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) CountGroupMessagesSince(_ context.Context, _ storage.CountGroupMessagesSinceParams) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) CountGroupMessagesByTag(_ context.Context, _ storage.CountGroupMessagesByTagParams) ([]storage.CountGroupMessagesByTagRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListGroupDeliveryLogs(_ context.Context, _ storage.ListGroupDeliveryLogsParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupMessages(_ context.Context, _ storage.ListGroupMessagesParams) ([]storage.Message, error) {
+	return nil, nil
+}
 
 // Provider methods.
 func (m *mockQuerier) CreateProvider(_ context.Context, _ storage.CreateProviderParams) (storage.EspProvider, error) {
@@ -182,6 +329,24 @@ func (m *mockQuerier) ListProvidersByGroupID(ctx context.Context, groupID uuid.U
 func (m *mockQuerier) UpdateProvider(_ context.Context, _ storage.UpdateProviderParams) (storage.EspProvider, error) {
 	return storage.EspProvider{}, nil
 }
+func (m *mockQuerier) AutoDisableProvider(_ context.Context, id uuid.UUID) error {
+	m.autoDisabledIDs = append(m.autoDisabledIDs, id)
+	return nil
+}
+func (m *mockQuerier) AutoEnableProvider(_ context.Context, id uuid.UUID) error {
+	m.autoEnabledIDs = append(m.autoEnabledIDs, id)
+	return nil
+}
+func (m *mockQuerier) ListProvidersForHealthCheck(_ context.Context) ([]storage.EspProvider, error) {
+	return m.healthCheckProviders, nil
+}
+func (m *mockQuerier) UpdateProviderHealth(_ context.Context, arg storage.UpdateProviderHealthParams) error {
+	m.providerHealth = append(m.providerHealth, arg)
+	return nil
+}
+func (m *mockQuerier) ListEnabledProviders(_ context.Context) ([]storage.EspProvider, error) {
+	return m.enabledProviders, nil
+}
 
 // RoutingRule methods.
 func (m *mockQuerier) CreateRoutingRule(_ context.Context, _ storage.CreateRoutingRuleParams) (storage.RoutingRule, error) {
@@ -226,7 +391,7 @@ func (m *mockQuerier) GetUserByEmail(_ context.Context, _ string) (storage.User,
 	return storage.User{}, nil
 }
 func (m *mockQuerier) GetUserByID(_ context.Context, _ uuid.UUID) (storage.User, error) {
-	return storage.User{}, nil
+	return m.user, nil
 }
 func (m *mockQuerier) GetUserByUsername(_ context.Context, _ sql.NullString) (storage.User, error) {
 	return storage.User{}, nil
@@ -247,307 +412,500 @@ func (m *mockQuerier) UpdateUserStatus(_ context.Context, _ storage.UpdateUserSt
 	return storage.User{}, nil
 }
 
-// Ensure mockQuerier satisfies the Querier interface at compile time.
-var _ storage.Querier = (*mockQuerier)(nil)
+func (m *mockQuerier) UpdateUserAutoBCC(_ context.Context, _ storage.UpdateUserAutoBCCParams) (storage.User, error) {
+	return storage.User{}, nil
+}
 
-// ---------------------------------------------------------------------------
-// Mock: msgstore.MessageStore
-// ---------------------------------------------------------------------------
+func (m *mockQuerier) UpdateUserTLSPolicy(_ context.Context, _ storage.UpdateUserTLSPolicyParams) (storage.User, error) {
+	return storage.User{}, nil
+}
 
-type mockMessageStore struct {
-	data     map[string][]byte
-	getFn    func(ctx context.Context, messageID string) ([]byte, error)
-	getCalls int32 // atomic counter for Get calls
+func (m *mockQuerier) CreateMessageScript(_ context.Context, _ storage.CreateMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
 }
 
-func (m *mockMessageStore) Put(_ context.Context, messageID string, data []byte) error {
-	if m.data == nil {
-		m.data = make(map[string][]byte)
-	}
-	m.data[messageID] = data
-	return nil
+func (m *mockQuerier) GetMessageScript(_ context.Context, _ storage.GetMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
 }
 
-func (m *mockMessageStore) Get(ctx context.Context, messageID string) ([]byte, error) {
-	atomic.AddInt32(&m.getCalls, 1)
-	if m.getFn != nil {
-		return m.getFn(ctx, messageID)
-	}
-	if m.data != nil {
-		if d, ok := m.data[messageID]; ok {
-			return d, nil
-		}
-	}
-	return nil, msgstore.ErrNotFound
+func (m *mockQuerier) ListMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return m.scripts, nil
 }
 
-func (m *mockMessageStore) Delete(_ context.Context, _ string) error {
-	return nil
+func (m *mockQuerier) ListEnabledMessageScriptsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.MessageScript, error) {
+	return m.scripts, nil
 }
 
-// ---------------------------------------------------------------------------
-// Test helpers
-// ---------------------------------------------------------------------------
+func (m *mockQuerier) UpdateMessageScript(_ context.Context, _ storage.UpdateMessageScriptParams) (storage.MessageScript, error) {
+	return storage.MessageScript{}, nil
+}
 
-// newTestDBMessage creates a storage.Message with populated metadata fields
-// suitable for building a provider.Message.
-func newTestDBMessage(groupID, userID uuid.UUID) storage.Message {
-	recipients, _ := json.Marshal([]string{"recipient@example.com"})
-	headers, _ := json.Marshal(map[string][]string{"X-Test": {"value1"}})
-	return storage.Message{
-		GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
-		UserID:     pgtype.UUID{Bytes: userID, Valid: true},
-		Sender:     "sender@example.com",
-		Recipients: recipients,
-		Subject:    sql.NullString{String: "Test Subject", Valid: true},
-		Headers:    headers,
-	}
+func (m *mockQuerier) DeleteMessageScript(_ context.Context, _ storage.DeleteMessageScriptParams) (int64, error) {
+	return 0, nil
 }
 
-// newHandler creates a Handler for tests. If store is nil, storage fetch
-// is not available (inline-body only).
-func newHandler(t *testing.T, mq *mockQuerier, store msgstore.MessageStore) *Handler {
-	t.Helper()
-	log := zerolog.Nop()
-	httpClient := provider.NewHTTPClient(0)
-	resolver := provider.NewResolver(mq, httpClient, log)
-	return NewHandler(resolver, mq, store, log)
+func (m *mockQuerier) CreateAlertChannel(_ context.Context, _ storage.CreateAlertChannelParams) (storage.AlertChannel, error) {
+	return storage.AlertChannel{}, nil
 }
 
-// ---------------------------------------------------------------------------
-// Tests: Inline body (legacy format / backward compatibility)
-// ---------------------------------------------------------------------------
+func (m *mockQuerier) ListAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
 
-func TestHandler_HandleMessage_InlineBody_Success(t *testing.T) {
-	groupID := uuid.New()
-	userID := uuid.New()
-	msgID := uuid.New()
+func (m *mockQuerier) ListEnabledAlertChannelsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.AlertChannel, error) {
+	return nil, nil
+}
 
-	mq := &mockQuerier{
-		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
-			return newTestDBMessage(groupID, userID), nil
-		},
-	}
-	h := newHandler(t, mq, nil)
+func (m *mockQuerier) DeleteAlertChannel(_ context.Context, _ storage.DeleteAlertChannelParams) (int64, error) {
+	return 0, nil
+}
 
-	msg := &queue.Message{
-		ID:       msgID.String(),
-		TenantID: "tenant-1",
-		From:     "sender@example.com",
-		To:       []string{"recipient@example.com"},
-		Subject:  "Test",
-		Body:     []byte("Hello"),
-	}
+func (m *mockQuerier) CreateSenderIdentity(_ context.Context, _ storage.CreateSenderIdentityParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
 
-	err := h.HandleMessage(context.Background(), msg)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
+func (m *mockQuerier) GetSenderIdentity(_ context.Context, _ storage.GetSenderIdentityParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
 
-	// Should have set processing then delivered.
-	if len(mq.statuses) < 2 {
-		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
-	}
-	if mq.statuses[0] != storage.MessageStatusProcessing {
-		t.Errorf("expected first status to be processing, got %s", mq.statuses[0])
-	}
-	if mq.statuses[1] != storage.MessageStatusDelivered {
-		t.Errorf("expected second status to be delivered, got %s", mq.statuses[1])
-	}
+func (m *mockQuerier) ListSenderIdentitiesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.SenderIdentity, error) {
+	return nil, nil
+}
 
-	if !mq.createLogCalled {
-		t.Error("expected delivery log to be created")
-	}
-	if mq.createLogProvider != "stdout" {
-		t.Errorf("expected provider stdout, got %s", mq.createLogProvider)
-	}
-	// Verify new delivery log fields.
-	if !mq.createLogParams.GroupID.Valid {
-		t.Error("expected GroupID to be set in delivery log")
-	}
-	if !mq.createLogParams.DurationMs.Valid {
-		t.Error("expected DurationMs to be set in delivery log")
-	}
-	if mq.createLogParams.DurationMs.Int32 < 0 {
-		t.Errorf("expected DurationMs >= 0, got %d", mq.createLogParams.DurationMs.Int32)
-	}
-	if mq.createLogParams.AttemptNumber != 1 {
-		t.Errorf("expected AttemptNumber 1, got %d", mq.createLogParams.AttemptNumber)
-	}
+func (m *mockQuerier) ListVerifiedSenderIdentities(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return m.senderIdentities, nil
 }
 
-// ---------------------------------------------------------------------------
-// Tests: Storage fetch (new format)
-// ---------------------------------------------------------------------------
+func (m *mockQuerier) GetSendWindow(_ context.Context, _ uuid.UUID) (storage.SendWindow, error) {
+	return storage.SendWindow{}, pgx.ErrNoRows
+}
 
-func TestHandler_HandleMessage_StorageFetch_Success(t *testing.T) {
-	// Reduce backoff to make test faster.
-	origBackoff := storageRetryBackoff
-	storageRetryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
-	defer func() { storageRetryBackoff = origBackoff }()
+func (m *mockQuerier) UpsertSendWindow(_ context.Context, _ storage.UpsertSendWindowParams) (storage.SendWindow, error) {
+	return storage.SendWindow{}, nil
+}
 
-	groupID := uuid.New()
-	userID := uuid.New()
-	msgID := uuid.New()
-	bodyContent := []byte("Hello from storage")
+func (m *mockQuerier) DeleteSendWindow(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
 
-	mq := &mockQuerier{
-		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
-			return newTestDBMessage(groupID, userID), nil
-		},
-	}
-	store := &mockMessageStore{
-		data: map[string][]byte{msgID.String(): bodyContent},
+func (m *mockQuerier) GetMessageDefaults(_ context.Context, _ uuid.UUID) (storage.MessageDefault, error) {
+	if m.messageDefaults == nil {
+		return storage.MessageDefault{}, pgx.ErrNoRows
 	}
-	h := newHandler(t, mq, store)
+	return *m.messageDefaults, nil
+}
 
-	// ID-only message (no inline body).
-	msg := &queue.Message{
-		ID:        msgID.String(),
-		AccountID: groupID.String(),
-		TenantID:  "tenant-1",
-	}
+func (m *mockQuerier) UpsertMessageDefaults(_ context.Context, _ storage.UpsertMessageDefaultsParams) (storage.MessageDefault, error) {
+	return storage.MessageDefault{}, nil
+}
 
-	err := h.HandleMessage(context.Background(), msg)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
+func (m *mockQuerier) DeleteMessageDefaults(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
 
-	if len(mq.statuses) < 2 {
-		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
-	}
-	if mq.statuses[0] != storage.MessageStatusProcessing {
-		t.Errorf("expected first status processing, got %s", mq.statuses[0])
-	}
-	if mq.statuses[1] != storage.MessageStatusDelivered {
-		t.Errorf("expected second status delivered, got %s", mq.statuses[1])
+func (m *mockQuerier) ListProviderDailySendsByGroupID(_ context.Context, _ storage.ListProviderDailySendsByGroupIDParams) ([]storage.ProviderDailySend, error) {
+	var sends []storage.ProviderDailySend
+	for k, sent := range m.dailySends {
+		sends = append(sends, storage.ProviderDailySend{ProviderID: k.ProviderID, Day: k.Day, Sent: sent})
 	}
+	return sends, nil
+}
 
-	if !mq.createLogCalled {
-		t.Error("expected delivery log to be created")
-	}
-	// Verify new delivery log fields.
-	if !mq.createLogParams.GroupID.Valid {
-		t.Error("expected GroupID to be set in delivery log")
-	}
-	if mq.createLogParams.GroupID.Bytes != groupID {
-		t.Errorf("expected GroupID %v, got %v", groupID, mq.createLogParams.GroupID.Bytes)
-	}
-	if !mq.createLogParams.UserID.Valid {
-		t.Error("expected UserID to be set in delivery log")
-	}
-	if mq.createLogParams.UserID.Bytes != userID {
-		t.Errorf("expected UserID %v, got %v", userID, mq.createLogParams.UserID.Bytes)
-	}
-	if !mq.createLogParams.DurationMs.Valid {
-		t.Error("expected DurationMs to be set in delivery log")
+func (m *mockQuerier) ReserveProviderDailySend(_ context.Context, arg storage.ReserveProviderDailySendParams) (int32, error) {
+	key := storage.ReserveProviderDailySendParams{ProviderID: arg.ProviderID, Day: arg.Day}
+	if m.dailySends[key] >= arg.DailyCap {
+		return 0, pgx.ErrNoRows
 	}
-	if mq.createLogParams.AttemptNumber != 1 {
-		t.Errorf("expected AttemptNumber 1, got %d", mq.createLogParams.AttemptNumber)
+	if m.dailySends == nil {
+		m.dailySends = make(map[storage.ReserveProviderDailySendParams]int32)
 	}
+	m.dailySends[key]++
+	return m.dailySends[key], nil
 }
 
-// ---------------------------------------------------------------------------
-// Tests: Storage read failure with retry exhaustion -> storage_error
-// ---------------------------------------------------------------------------
+func (m *mockQuerier) ListShadowPolicies(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return m.shadowPolicies, nil
+}
 
-func TestHandler_HandleMessage_StorageRetryExhaustion(t *testing.T) {
-	// Reduce backoff to make test faster.
-	origBackoff := storageRetryBackoff
-	storageRetryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
-	defer func() { storageRetryBackoff = origBackoff }()
+func (m *mockQuerier) DeleteShadowPolicies(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
 
-	groupID := uuid.New()
-	userID := uuid.New()
-	msgID := uuid.New()
-	storageErr := errors.New("disk I/O error")
+func (m *mockQuerier) AddShadowPolicy(_ context.Context, _ storage.AddShadowPolicyParams) error {
+	return nil
+}
 
-	mq := &mockQuerier{
-		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
-			return newTestDBMessage(groupID, userID), nil
-		},
-	}
-	store := &mockMessageStore{
-		getFn: func(_ context.Context, _ string) ([]byte, error) {
-			return nil, storageErr
-		},
+func (m *mockQuerier) RecordShadowRejection(_ context.Context, arg storage.RecordShadowRejectionParams) error {
+	m.shadowRejections = append(m.shadowRejections, arg)
+	return nil
+}
+
+func (m *mockQuerier) SummarizeShadowRejections(_ context.Context, _ storage.SummarizeShadowRejectionsParams) ([]storage.SummarizeShadowRejectionsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) MarkSenderIdentityVerified(_ context.Context, _ storage.MarkSenderIdentityVerifiedParams) (storage.SenderIdentity, error) {
+	return storage.SenderIdentity{}, nil
+}
+
+func (m *mockQuerier) DeleteSenderIdentity(_ context.Context, _ storage.DeleteSenderIdentityParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSenderPolicy(_ context.Context, groupID uuid.UUID) (storage.SenderPolicy, error) {
+	if m.senderPolicy.Mode == "" {
+		return storage.SenderPolicy{}, pgx.ErrNoRows
 	}
-	h := newHandler(t, mq, store)
+	return m.senderPolicy, nil
+}
 
-	msg := &queue.Message{
-		ID:        msgID.String(),
-		AccountID: groupID.String(),
-		TenantID:  "tenant-1",
+func (m *mockQuerier) UpsertSenderPolicy(_ context.Context, _ storage.UpsertSenderPolicyParams) (storage.SenderPolicy, error) {
+	return storage.SenderPolicy{}, nil
+}
+
+func (m *mockQuerier) GetSendingDomain(_ context.Context, arg storage.GetSendingDomainParams) (storage.SendingDomain, error) {
+	if d, ok := m.sendingDomains[arg.Domain]; ok {
+		return d, nil
 	}
+	return storage.SendingDomain{}, pgx.ErrNoRows
+}
 
-	err := h.HandleMessage(context.Background(), msg)
-	if err == nil {
-		t.Fatal("expected error when storage retries are exhausted")
+func (m *mockQuerier) ListSendingDomainsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.SendingDomain, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertSendingDomain(_ context.Context, _ storage.UpsertSendingDomainParams) (storage.SendingDomain, error) {
+	return storage.SendingDomain{}, nil
+}
+
+func (m *mockQuerier) DeleteSendingDomain(_ context.Context, _ storage.DeleteSendingDomainParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSigningKey(_ context.Context, _ uuid.UUID) (storage.SigningKey, error) {
+	if m.signingKey == nil {
+		return storage.SigningKey{}, pgx.ErrNoRows
 	}
+	return *m.signingKey, nil
+}
 
-	// Verify Get was called exactly 3 times (the number of retry attempts).
-	calls := atomic.LoadInt32(&store.getCalls)
-	if calls != 3 {
-		t.Errorf("expected 3 storage Get calls, got %d", calls)
+func (m *mockQuerier) UpsertSigningKey(_ context.Context, _ storage.UpsertSigningKeyParams) (storage.SigningKey, error) {
+	return storage.SigningKey{}, nil
+}
+
+func (m *mockQuerier) DeleteSigningKey(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateProviderCapture(_ context.Context, arg storage.CreateProviderCaptureParams) (storage.ProviderCapture, error) {
+	m.captures = append(m.captures, arg)
+	return storage.ProviderCapture{ProviderID: arg.ProviderID, MessageID: arg.MessageID, Exchanges: arg.Exchanges, Error: arg.Error}, nil
+}
+
+func (m *mockQuerier) ListProviderCaptures(_ context.Context, _ uuid.UUID) ([]storage.ProviderCapture, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) PruneProviderCaptures(_ context.Context, arg storage.PruneProviderCapturesParams) error {
+	m.capturePrunes = append(m.capturePrunes, arg)
+	return nil
+}
+
+func (m *mockQuerier) DeleteProviderCaptures(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ListRecipientCertificatesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.RecipientCertificate, error) {
+	return m.recipientCerts, nil
+}
+
+func (m *mockQuerier) ListRecipientDeliveryLogs(_ context.Context, _ storage.ListRecipientDeliveryLogsParams) ([]storage.ListRecipientDeliveryLogsRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertRecipientCertificate(_ context.Context, _ storage.UpsertRecipientCertificateParams) (storage.RecipientCertificate, error) {
+	return storage.RecipientCertificate{}, nil
+}
+
+func (m *mockQuerier) DeleteRecipientCertificate(_ context.Context, _ storage.DeleteRecipientCertificateParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateSmtpClientCert(_ context.Context, _ storage.CreateSmtpClientCertParams) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) ListSmtpClientCertsByUserID(_ context.Context, _ uuid.UUID) ([]storage.SmtpClientCert, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertByFingerprint(_ context.Context, _ pgtype.Text) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) GetSmtpClientCertBySAN(_ context.Context, _ []string) (storage.SmtpClientCert, error) {
+	return storage.SmtpClientCert{}, nil
+}
+
+func (m *mockQuerier) TouchSmtpClientCert(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSmtpClientCert(_ context.Context, _ storage.DeleteSmtpClientCertParams) (int64, error) {
+	return 0, nil
+}
+
+// Outbox methods.
+func (m *mockQuerier) ClaimOutboxEntries(_ context.Context, _ storage.ClaimOutboxEntriesParams) ([]storage.OutboxEntry, error) {
+	return nil, nil
+}
+func (m *mockQuerier) CountOutboxEntries(_ context.Context) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) CreateOutboxEntry(_ context.Context, arg storage.CreateOutboxEntryParams) (storage.OutboxEntry, error) {
+	m.outboxEntries = append(m.outboxEntries, arg)
+	return storage.OutboxEntry{}, nil
+}
+func (m *mockQuerier) DeleteOutboxEntry(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) RecordOutboxEntryFailure(_ context.Context, _ storage.RecordOutboxEntryFailureParams) error {
+	return nil
+}
+
+// ProviderHealthCheck methods.
+func (m *mockQuerier) CreateProviderHealthCheck(_ context.Context, arg storage.CreateProviderHealthCheckParams) (storage.ProviderHealthCheck, error) {
+	m.healthChecks = append(m.healthChecks, arg)
+	return storage.ProviderHealthCheck{}, nil
+}
+func (m *mockQuerier) DeleteProviderHealthChecksBefore(_ context.Context, _ pgtype.Timestamptz) error {
+	return nil
+}
+func (m *mockQuerier) ListProviderHealthChecks(_ context.Context, _ storage.ListProviderHealthChecksParams) ([]storage.ProviderHealthCheck, error) {
+	return nil, nil
+}
+
+// ProviderAccountStats methods.
+func (m *mockQuerier) ListProviderAccountStatsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.ProviderAccountStat, error) {
+	return nil, nil
+}
+func (m *mockQuerier) RecordProviderAccountStatsError(ctx context.Context, arg storage.RecordProviderAccountStatsErrorParams) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.accountStatsErrors = append(m.accountStatsErrors, arg)
+	return nil
+}
+func (m *mockQuerier) UpsertProviderAccountStats(_ context.Context, arg storage.UpsertProviderAccountStatsParams) (storage.ProviderAccountStat, error) {
+	m.accountStats = append(m.accountStats, arg)
+	row := storage.ProviderAccountStat{ProviderID: arg.ProviderID, SuppressionCount: arg.SuppressionCount}
+	if reason, ok := m.pauseReasons[arg.ProviderID]; ok {
+		row.PauseReason = pgtype.Text{String: reason, Valid: true}
+	}
+	return row, nil
+}
+func (m *mockQuerier) UpdateProviderReputationPause(_ context.Context, arg storage.UpdateProviderReputationPauseParams) error {
+	m.reputationPauses = append(m.reputationPauses, arg)
+	if m.pauseReasons == nil {
+		m.pauseReasons = map[uuid.UUID]string{}
 	}
+	if arg.PauseReason.Valid {
+		m.pauseReasons[arg.ProviderID] = arg.PauseReason.String
+	} else {
+		delete(m.pauseReasons, arg.ProviderID)
+	}
+	return nil
+}
 
-	// Status should be: processing, storage_error, failed.
-	foundStorageError := false
-	for _, s := range mq.statuses {
-		if s == storage.MessageStatusStorageError {
-			foundStorageError = true
-			break
-		}
+// QuotaNotification methods.
+func (m *mockQuerier) RecordQuotaNotification(_ context.Context, arg storage.RecordQuotaNotificationParams) (int64, error) {
+	if m.quotaNotified[arg] {
+		return 0, nil
 	}
-	if !foundStorageError {
-		t.Errorf("expected storage_error status, got statuses: %v", mq.statuses)
+	if m.quotaNotified == nil {
+		m.quotaNotified = make(map[storage.RecordQuotaNotificationParams]bool)
 	}
+	m.quotaNotified[arg] = true
+	return 1, nil
+}
 
-	if mq.createLogStatus != string(storage.MessageStatusFailed) {
-		t.Errorf("expected delivery log status failed, got %s", mq.createLogStatus)
+// Groups methods.
+func (m *mockQuerier) ListGroupAncestors(_ context.Context, _ uuid.UUID) ([]storage.Group, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSubGroups(_ context.Context, _ pgtype.UUID) ([]storage.Group, error) {
+	return nil, nil
+}
+
+// Compliance methods.
+func (m *mockQuerier) EraseGroupMessages(_ context.Context, _ pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) ExportGroupActivityLogs(_ context.Context, _ uuid.UUID) ([]storage.ActivityLog, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ExportGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ExportGroupMessages(_ context.Context, _ pgtype.UUID) ([]storage.ExportGroupMessagesRow, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListGroupMessageStorageRefs(_ context.Context, _ pgtype.UUID) ([]pgtype.Text, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ScrubGroupDeliveryLogs(_ context.Context, _ pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+
+// SMTP debug methods.
+func (m *mockQuerier) CreateSMTPDebugTarget(_ context.Context, _ storage.CreateSMTPDebugTargetParams) (storage.SmtpDebugTarget, error) {
+	return storage.SmtpDebugTarget{}, nil
+}
+func (m *mockQuerier) CreateSMTPTranscript(_ context.Context, _ storage.CreateSMTPTranscriptParams) (storage.SmtpTranscript, error) {
+	return storage.SmtpTranscript{}, nil
+}
+func (m *mockQuerier) DeleteSMTPDebugTarget(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) GetSMTPDebugTarget(_ context.Context, _ uuid.UUID) (storage.SmtpDebugTarget, error) {
+	return storage.SmtpDebugTarget{}, nil
+}
+func (m *mockQuerier) ListActiveSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSMTPDebugTargets(_ context.Context) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSMTPDebugTargetsByGroupID(_ context.Context, _ pgtype.UUID) ([]storage.SmtpDebugTarget, error) {
+	return nil, nil
+}
+func (m *mockQuerier) ListSMTPTranscriptsByTarget(_ context.Context, _ uuid.UUID) ([]storage.SmtpTranscript, error) {
+	return nil, nil
+}
+
+// Inbound routes methods.
+func (m *mockQuerier) CreateInboundRoute(_ context.Context, _ storage.CreateInboundRouteParams) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+func (m *mockQuerier) DeleteInboundRoute(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockQuerier) GetInboundRouteByDomain(_ context.Context, _ string) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+func (m *mockQuerier) GetInboundRouteByID(_ context.Context, id uuid.UUID) (storage.InboundRoute, error) {
+	if route, ok := m.inboundRoutes[id]; ok {
+		return route, nil
+	}
+	return storage.InboundRoute{}, pgx.ErrNoRows
+}
+func (m *mockQuerier) FindMessageByHeaderMessageID(_ context.Context, arg storage.FindMessageByHeaderMessageIDParams) (storage.Message, error) {
+	for _, msg := range m.reportedMessages {
+		var headers map[string][]string
+		_ = json.Unmarshal(msg.Headers, &headers)
+		if msg.GroupID != arg.GroupID || !slices.Contains(headers["Message-Id"], arg.HeaderMessageID) {
+			continue
+		}
+		if !arg.Recipient.Valid || slices.ContainsFunc(parseRecipients(msg.Recipients), func(r string) bool {
+			return strings.EqualFold(r, arg.Recipient.String)
+		}) {
+			return msg, nil
+		}
 	}
+	return storage.Message{}, pgx.ErrNoRows
+}
+func (m *mockQuerier) AddSuppression(_ context.Context, arg storage.AddSuppressionParams) error {
+	m.suppressions = append(m.suppressions, arg)
+	return nil
+}
+func (m *mockQuerier) IsRecipientSuppressed(_ context.Context, _ storage.IsRecipientSuppressedParams) (bool, error) {
+	return false, nil
+}
+func (m *mockQuerier) ListSuppressions(_ context.Context, _ storage.ListSuppressionsParams) ([]storage.Suppression, error) {
+	return nil, nil
+}
+func (m *mockQuerier) DeleteSuppression(_ context.Context, _ storage.DeleteSuppressionParams) (int64, error) {
+	return 0, nil
+}
+func (m *mockQuerier) ListInboundRoutesByGroupID(_ context.Context, _ uuid.UUID) ([]storage.InboundRoute, error) {
+	return nil, nil
 }
+func (m *mockQuerier) UpdateInboundRoute(_ context.Context, _ storage.UpdateInboundRouteParams) (storage.InboundRoute, error) {
+	return storage.InboundRoute{}, nil
+}
+
+// Ensure mockQuerier satisfies the Querier interface at compile time.
+var _ storage.Querier = (*mockQuerier)(nil)
 
 // ---------------------------------------------------------------------------
-// Tests: Orphaned message_id -> return nil (ack), no delivery
+// Mock: msgstore.MessageStore
 // ---------------------------------------------------------------------------
 
-func TestHandler_HandleMessage_OrphanedMessageID(t *testing.T) {
-	msgID := uuid.New()
+type mockMessageStore struct {
+	data     map[string][]byte
+	getFn    func(ctx context.Context, messageID string) ([]byte, error)
+	getCalls int32 // atomic counter for Get calls
+}
 
-	mq := &mockQuerier{
-		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
-			return storage.Message{}, pgx.ErrNoRows
-		},
+func (m *mockMessageStore) Put(_ context.Context, messageID string, data []byte) error {
+	if m.data == nil {
+		m.data = make(map[string][]byte)
 	}
-	h := newHandler(t, mq, nil)
+	m.data[messageID] = data
+	return nil
+}
 
-	msg := &queue.Message{
-		ID:       msgID.String(),
-		TenantID: "tenant-1",
+func (m *mockMessageStore) Get(ctx context.Context, messageID string) ([]byte, error) {
+	atomic.AddInt32(&m.getCalls, 1)
+	if m.getFn != nil {
+		return m.getFn(ctx, messageID)
 	}
-
-	err := h.HandleMessage(context.Background(), msg)
-	if err != nil {
-		t.Fatalf("expected nil error for orphaned message, got %v", err)
+	if m.data != nil {
+		if d, ok := m.data[messageID]; ok {
+			return d, nil
+		}
 	}
+	return nil, msgstore.ErrNotFound
+}
 
-	// Should not have recorded a failure (no delivery log).
-	if mq.createLogCalled {
-		t.Error("expected no delivery log for orphaned message")
-	}
+func (m *mockMessageStore) Delete(_ context.Context, _ string) error {
+	return nil
+}
 
-	// Status updates: processing only (set before GetMessageByID).
-	if len(mq.statuses) != 1 {
-		t.Errorf("expected 1 status update (processing), got %d: %v", len(mq.statuses), mq.statuses)
+// ---------------------------------------------------------------------------
+// Test helpers
+// ---------------------------------------------------------------------------
+
+// newTestDBMessage creates a storage.Message with populated metadata fields
+// suitable for building a provider.Message.
+func newTestDBMessage(groupID, userID uuid.UUID) storage.Message {
+	recipients, _ := json.Marshal([]string{"recipient@example.com"})
+	headers, _ := json.Marshal(map[string][]string{"X-Test": {"value1"}})
+	return storage.Message{
+		GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
+		UserID:     pgtype.UUID{Bytes: userID, Valid: true},
+		Sender:     "sender@example.com",
+		Recipients: recipients,
+		Subject:    sql.NullString{String: "Test Subject", Valid: true},
+		Headers:    headers,
 	}
 }
 
+// newHandler creates a Handler for tests. If store is nil, storage fetch
+// is not available (inline-body only).
+func newHandler(t *testing.T, mq *mockQuerier, store msgstore.MessageStore) *Handler {
+	t.Helper()
+	log := zerolog.Nop()
+	httpClient := provider.NewHTTPClient(0)
+	resolver := provider.NewResolver(mq, httpClient, log)
+	return NewHandler(resolver, mq, store, log)
+}
+
 // ---------------------------------------------------------------------------
-// Tests: Provider resolution failure
+// Tests: Inline body (legacy format / backward compatibility)
 // ---------------------------------------------------------------------------
 
-func TestHandler_HandleMessage_SendFail(t *testing.T) {
+func TestHandler_HandleMessage_InlineBody_Success(t *testing.T) {
 	groupID := uuid.New()
 	userID := uuid.New()
 	msgID := uuid.New()
@@ -556,9 +914,6 @@ func TestHandler_HandleMessage_SendFail(t *testing.T) {
 		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
 			return newTestDBMessage(groupID, userID), nil
 		},
-		listProvidersFn: func(_ context.Context, _ uuid.UUID) ([]storage.EspProvider, error) {
-			return nil, errors.New("database error")
-		},
 	}
 	h := newHandler(t, mq, nil)
 
@@ -572,99 +927,183 @@ func TestHandler_HandleMessage_SendFail(t *testing.T) {
 	}
 
 	err := h.HandleMessage(context.Background(), msg)
-	if err == nil {
-		t.Fatal("expected error when resolver fails")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	// Should have set processing then failed.
+	// Should have set processing then delivered.
 	if len(mq.statuses) < 2 {
 		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
 	}
-	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
-		t.Errorf("expected final status to be failed, got %s", mq.statuses[len(mq.statuses)-1])
+	if mq.statuses[0] != storage.MessageStatusProcessing {
+		t.Errorf("expected first status to be processing, got %s", mq.statuses[0])
+	}
+	if mq.statuses[1] != storage.MessageStatusDelivered {
+		t.Errorf("expected second status to be delivered, got %s", mq.statuses[1])
 	}
 
-	if mq.createLogStatus != string(storage.MessageStatusFailed) {
-		t.Errorf("expected log status failed, got %s", mq.createLogStatus)
+	if !mq.createLogCalled {
+		t.Error("expected delivery log to be created")
 	}
-	// Verify GroupID is set in failure delivery log (we have dbMsg at this point).
+	if mq.createLogProvider != "stdout" {
+		t.Errorf("expected provider stdout, got %s", mq.createLogProvider)
+	}
+	// Verify new delivery log fields.
 	if !mq.createLogParams.GroupID.Valid {
-		t.Error("expected GroupID to be set in failure delivery log")
+		t.Error("expected GroupID to be set in delivery log")
 	}
-}
-
-// ---------------------------------------------------------------------------
-// Tests: Invalid message ID
-// ---------------------------------------------------------------------------
-
-func TestHandler_HandleMessage_InvalidMessageID(t *testing.T) {
-	mq := &mockQuerier{}
-	h := newHandler(t, mq, nil)
-
-	msg := &queue.Message{
-		ID:       "not-a-uuid",
-		TenantID: "tenant-1",
-		From:     "sender@example.com",
-		To:       []string{"recipient@example.com"},
+	if !mq.createLogParams.DurationMs.Valid {
+		t.Error("expected DurationMs to be set in delivery log")
 	}
-
-	err := h.HandleMessage(context.Background(), msg)
-	if err == nil {
-		t.Fatal("expected error for invalid message ID")
+	if mq.createLogParams.DurationMs.Int32 < 0 {
+		t.Errorf("expected DurationMs >= 0, got %d", mq.createLogParams.DurationMs.Int32)
+	}
+	if mq.createLogParams.AttemptNumber != 1 {
+		t.Errorf("expected AttemptNumber 1, got %d", mq.createLogParams.AttemptNumber)
+	}
+	if mq.createLogParams.ProviderID.Valid {
+		t.Error("expected no ProviderID for the stdout default provider")
 	}
 }
 
 // ---------------------------------------------------------------------------
-// Tests: GetMessageByID error (non-ErrNoRows)
+// Tests: Storage fetch (new format)
 // ---------------------------------------------------------------------------
 
-func TestHandler_HandleMessage_GetMessageError(t *testing.T) {
+func TestHandler_HandleMessage_StorageFetch_Success(t *testing.T) {
+	// Reduce backoff to make test faster.
+	origBackoff := storageRetryBackoff
+	storageRetryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+	defer func() { storageRetryBackoff = origBackoff }()
+
+	groupID := uuid.New()
+	userID := uuid.New()
 	msgID := uuid.New()
+	bodyContent := []byte("Hello from storage")
 
 	mq := &mockQuerier{
 		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
-			return storage.Message{}, errors.New("connection refused")
+			return newTestDBMessage(groupID, userID), nil
 		},
 	}
-	h := newHandler(t, mq, nil)
+	store := &mockMessageStore{
+		data: map[string][]byte{msgID.String(): bodyContent},
+	}
+	h := newHandler(t, mq, store)
 
+	// ID-only message (no inline body).
 	msg := &queue.Message{
-		ID:       msgID.String(),
-		TenantID: "tenant-1",
-		From:     "sender@example.com",
-		To:       []string{"recipient@example.com"},
+		ID:        msgID.String(),
+		AccountID: groupID.String(),
+		TenantID:  "tenant-1",
 	}
 
 	err := h.HandleMessage(context.Background(), msg)
-	if err == nil {
-		t.Fatal("expected error when message not found in database")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
 	}
 
-	// Final status should be failed.
-	if len(mq.statuses) == 0 {
-		t.Fatal("expected at least one status update")
+	if len(mq.statuses) < 2 {
+		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
 	}
-	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
-		t.Errorf("expected final status failed, got %s", mq.statuses[len(mq.statuses)-1])
+	if mq.statuses[0] != storage.MessageStatusProcessing {
+		t.Errorf("expected first status processing, got %s", mq.statuses[0])
 	}
-	// GroupID should NOT be set since we failed before getting dbMsg.
-	if mq.createLogParams.GroupID.Valid {
-		t.Error("expected GroupID to be unset when message lookup fails")
+	if mq.statuses[1] != storage.MessageStatusDelivered {
+		t.Errorf("expected second status delivered, got %s", mq.statuses[1])
+	}
+
+	if !mq.createLogCalled {
+		t.Error("expected delivery log to be created")
+	}
+	// Verify new delivery log fields.
+	if !mq.createLogParams.GroupID.Valid {
+		t.Error("expected GroupID to be set in delivery log")
+	}
+	if mq.createLogParams.GroupID.Bytes != groupID {
+		t.Errorf("expected GroupID %v, got %v", groupID, mq.createLogParams.GroupID.Bytes)
+	}
+	if !mq.createLogParams.UserID.Valid {
+		t.Error("expected UserID to be set in delivery log")
+	}
+	if mq.createLogParams.UserID.Bytes != userID {
+		t.Errorf("expected UserID %v, got %v", userID, mq.createLogParams.UserID.Bytes)
+	}
+	if !mq.createLogParams.DurationMs.Valid {
+		t.Error("expected DurationMs to be set in delivery log")
+	}
+	if mq.createLogParams.AttemptNumber != 1 {
+		t.Errorf("expected AttemptNumber 1, got %d", mq.createLogParams.AttemptNumber)
+	}
+}
+
+// TestHandler_HandleMessage_SystemEmail delivers a system email, whose body
+// is stored in the database rather than the message store.
+func TestHandler_HandleMessage_SystemEmail(t *testing.T) {
+	mq := &mockQuerier{}
+	mailer := sysmail.New(mq, &mockTxRunner{queries: mq}, sysmail.Config{From: "noreply@proxy.example"})
+	if err := mailer.Enqueue(context.Background(), mq, sysmail.Message{
+		From:    "noreply@proxy.example",
+		To:      []string{"owner@example.com"},
+		Subject: "Quota warning",
+		Text:    "You have used 80% of your monthly quota.",
+	}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	enqueued := mq.enqueuedMessages[0]
+	msgID := uuid.New()
+	mq.getMessageFn = func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+		return storage.Message{
+			ID:         msgID,
+			GroupID:    enqueued.GroupID,
+			Sender:     enqueued.Sender,
+			Recipients: enqueued.Recipients,
+			Subject:    enqueued.Subject,
+			Headers:    enqueued.Headers,
+			Body:       enqueued.Body,
+		}, nil
+	}
+	store := &mockMessageStore{
+		getFn: func(_ context.Context, _ string) ([]byte, error) {
+			return nil, errors.New("not found")
+		},
+	}
+	capture := &mockCaptureProvider{}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: capture},
+		queries:  mq,
+		store:    store,
+		log:      zerolog.Nop(),
+	}
+
+	if err := h.HandleMessage(context.Background(), &queue.Message{ID: msgID.String()}); err != nil {
+		t.Fatalf("HandleMessage() error = %v", err)
+	}
+	if calls := atomic.LoadInt32(&store.getCalls); calls != 0 {
+		t.Errorf("expected no message store reads, got %d", calls)
+	}
+	if capture.captured == nil || !strings.Contains(capture.captured.TextBody, "80% of your monthly quota") {
+		t.Fatalf("system email not delivered with its body: %+v", capture.captured)
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusDelivered {
+		t.Errorf("expected delivered status, got statuses %v", mq.statuses)
 	}
 }
 
 // ---------------------------------------------------------------------------
-// Tests: Context cancellation during storage retry
+// Tests: Storage read failure with retry exhaustion -> storage_error
 // ---------------------------------------------------------------------------
 
-func TestHandler_HandleMessage_StorageRetryContextCancelled(t *testing.T) {
+func TestHandler_HandleMessage_StorageRetryExhaustion(t *testing.T) {
+	// Reduce backoff to make test faster.
 	origBackoff := storageRetryBackoff
-	storageRetryBackoff = []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+	storageRetryBackoff = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
 	defer func() { storageRetryBackoff = origBackoff }()
 
 	groupID := uuid.New()
 	userID := uuid.New()
 	msgID := uuid.New()
+	storageErr := errors.New("disk I/O error")
 
 	mq := &mockQuerier{
 		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
@@ -673,235 +1112,264 @@ func TestHandler_HandleMessage_StorageRetryContextCancelled(t *testing.T) {
 	}
 	store := &mockMessageStore{
 		getFn: func(_ context.Context, _ string) ([]byte, error) {
-			return nil, errors.New("temporary error")
+			return nil, storageErr
 		},
 	}
 	h := newHandler(t, mq, store)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	// Cancel after a short delay to interrupt the retry loop.
-	go func() {
-		time.Sleep(25 * time.Millisecond)
-		cancel()
-	}()
-
 	msg := &queue.Message{
 		ID:        msgID.String(),
 		AccountID: groupID.String(),
 		TenantID:  "tenant-1",
 	}
 
-	err := h.HandleMessage(ctx, msg)
+	err := h.HandleMessage(context.Background(), msg)
 	if err == nil {
-		t.Fatal("expected error when context is cancelled during retry")
+		t.Fatal("expected error when storage retries are exhausted")
 	}
-}
 
-// ---------------------------------------------------------------------------
-// Tests: Helper functions
-// ---------------------------------------------------------------------------
+	// Verify Get was called exactly 3 times (the number of retry attempts).
+	calls := atomic.LoadInt32(&store.getCalls)
+	if calls != 3 {
+		t.Errorf("expected 3 storage Get calls, got %d", calls)
+	}
 
-func TestParseRecipients(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []byte
-		expected []string
-	}{
-		{"valid", mustJSON([]string{"a@b.com", "c@d.com"}), []string{"a@b.com", "c@d.com"}},
-		{"empty", nil, nil},
-		{"invalid json", []byte("not json"), nil},
+	// Status should be: processing, storage_error, failed.
+	foundStorageError := false
+	for _, s := range mq.statuses {
+		if s == storage.MessageStatusStorageError {
+			foundStorageError = true
+			break
+		}
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parseRecipients(tt.input)
-			if len(result) != len(tt.expected) {
-				t.Errorf("expected %d recipients, got %d", len(tt.expected), len(result))
-			}
-		})
+	if !foundStorageError {
+		t.Errorf("expected storage_error status, got statuses: %v", mq.statuses)
+	}
+
+	if mq.createLogStatus != string(storage.MessageStatusFailed) {
+		t.Errorf("expected delivery log status failed, got %s", mq.createLogStatus)
 	}
 }
 
-func TestParseHeaders(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []byte
-		expected map[string]string
-	}{
-		{
-			"valid",
-			mustJSON(map[string][]string{"X-Test": {"v1", "v2"}, "X-Other": {"v3"}}),
-			map[string]string{"X-Test": "v1", "X-Other": "v3"},
+// ---------------------------------------------------------------------------
+// Tests: Orphaned message_id -> return nil (ack), no delivery
+// ---------------------------------------------------------------------------
+
+func TestHandler_HandleMessage_OrphanedMessageID(t *testing.T) {
+	msgID := uuid.New()
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return storage.Message{}, pgx.ErrNoRows
 		},
-		{"empty", nil, nil},
-		{"invalid json", []byte("not json"), nil},
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parseHeaders(tt.input)
-			if tt.expected == nil {
-				if result != nil {
-					t.Errorf("expected nil, got %v", result)
-				}
-				return
-			}
-			for k, v := range tt.expected {
-				if result[k] != v {
-					t.Errorf("key %q: expected %q, got %q", k, v, result[k])
-				}
-			}
-		})
+	h := newHandler(t, mq, nil)
+
+	msg := &queue.Message{
+		ID:       msgID.String(),
+		TenantID: "tenant-1",
 	}
-}
 
-func TestNullStringValue(t *testing.T) {
-	if v := nullStringValue(sql.NullString{String: "hello", Valid: true}); v != "hello" {
-		t.Errorf("expected 'hello', got %q", v)
+	err := h.HandleMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("expected nil error for orphaned message, got %v", err)
 	}
-	if v := nullStringValue(sql.NullString{}); v != "" {
-		t.Errorf("expected empty string, got %q", v)
+
+	// Should not have recorded a failure (no delivery log).
+	if mq.createLogCalled {
+		t.Error("expected no delivery log for orphaned message")
 	}
-}
 
-func mustJSON(v interface{}) []byte {
-	data, _ := json.Marshal(v)
-	return data
+	// No status updates: the message is looked up before it is claimed.
+	if len(mq.statuses) != 0 {
+		t.Errorf("expected no status updates, got %d: %v", len(mq.statuses), mq.statuses)
+	}
 }
 
-// ---------------------------------------------------------------------------
-// Tests: MIME parsing integration (multipart message with HTML + attachments)
-// ---------------------------------------------------------------------------
+func TestHandler_HandleMessage_PausedMessage(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
 
-// mockCaptureProvider captures the provider.Message it receives via Send.
-type mockCaptureProvider struct {
-	captured *provider.Message
-}
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			m := newTestDBMessage(groupID, userID)
+			m.Status = storage.MessageStatusPaused
+			return m, nil
+		},
+	}
+	h := newHandler(t, mq, nil)
 
-func (m *mockCaptureProvider) GetName() string { return "capture" }
-func (m *mockCaptureProvider) Send(_ context.Context, msg *provider.Message) (*provider.DeliveryResult, error) {
-	m.captured = msg
-	return &provider.DeliveryResult{
-		ProviderMessageID: "capture-" + msg.ID,
-		Status:            provider.StatusSent,
-	}, nil
+	err := h.HandleMessage(context.Background(), &queue.Message{ID: uuid.NewString(), Body: []byte("Hello")})
+	if err != nil {
+		t.Fatalf("expected nil error for paused message, got %v", err)
+	}
+	if len(mq.statuses) != 0 || mq.createLogCalled {
+		t.Errorf("paused message was processed: statuses %v, delivery log %v", mq.statuses, mq.createLogCalled)
+	}
 }
-func (m *mockCaptureProvider) HealthCheck(_ context.Context) error { return nil }
 
-// mockCaptureResolver returns a fixed provider for any group.
-type mockCaptureResolver struct {
-	provider provider.Provider
-}
+// ---------------------------------------------------------------------------
+// Tests: Provider resolution failure
+// ---------------------------------------------------------------------------
 
-func (r *mockCaptureResolver) Resolve(_ context.Context, _ uuid.UUID) (provider.Provider, error) {
-	return r.provider, nil
+func TestHandler_HandleMessage_SendFail(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+		listProvidersFn: func(_ context.Context, _ uuid.UUID) ([]storage.EspProvider, error) {
+			return nil, errors.New("database error")
+		},
+	}
+	h := newHandler(t, mq, nil)
+
+	msg := &queue.Message{
+		ID:       msgID.String(),
+		TenantID: "tenant-1",
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Subject:  "Test",
+		Body:     []byte("Hello"),
+	}
+
+	err := h.HandleMessage(context.Background(), msg)
+	if err == nil {
+		t.Fatal("expected error when resolver fails")
+	}
+
+	// Should have set processing then failed.
+	if len(mq.statuses) < 2 {
+		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
+		t.Errorf("expected final status to be failed, got %s", mq.statuses[len(mq.statuses)-1])
+	}
+
+	if mq.createLogStatus != string(storage.MessageStatusFailed) {
+		t.Errorf("expected log status failed, got %s", mq.createLogStatus)
+	}
+	// Verify GroupID is set in failure delivery log (we have dbMsg at this point).
+	if !mq.createLogParams.GroupID.Valid {
+		t.Error("expected GroupID to be set in failure delivery log")
+	}
 }
 
-func TestHandler_HandleMessage_MIMEParsing(t *testing.T) {
-	// Reduce backoff for fast tests.
-	origBackoff := storageRetryBackoff
-	storageRetryBackoff = []time.Duration{time.Millisecond}
-	defer func() { storageRetryBackoff = origBackoff }()
+// ---------------------------------------------------------------------------
+// Tests: Panic recovery
+// ---------------------------------------------------------------------------
 
+func TestHandler_HandleMessage_RecoversPanic(t *testing.T) {
 	groupID := uuid.New()
 	userID := uuid.New()
 	msgID := uuid.New()
 
-	// Build a real multipart MIME message with HTML body and an attachment.
-	boundary := "----TestBoundary123"
-	mimeMsg := "MIME-Version: 1.0\r\n" +
-		"Subject: MIME Parsed Subject\r\n" +
-		"Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n" +
-		"\r\n" +
-		"--" + boundary + "\r\n" +
-		"Content-Type: text/plain; charset=utf-8\r\n" +
-		"\r\n" +
-		"Hello plain text\r\n" +
-		"--" + boundary + "\r\n" +
-		"Content-Type: text/html; charset=utf-8\r\n" +
-		"\r\n" +
-		"<h1>Hello HTML</h1>\r\n" +
-		"--" + boundary + "\r\n" +
-		"Content-Type: application/pdf; name=\"report.pdf\"\r\n" +
-		"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
-		"\r\n" +
-		"PDF-CONTENT-HERE\r\n" +
-		"--" + boundary + "--\r\n"
-
 	mq := &mockQuerier{
 		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
 			return newTestDBMessage(groupID, userID), nil
 		},
+		listProvidersFn: func(_ context.Context, _ uuid.UUID) ([]storage.EspProvider, error) {
+			panic("provider list corrupted")
+		},
 	}
-	store := &mockMessageStore{
-		data: map[string][]byte{msgID.String(): []byte(mimeMsg)},
+	h := newHandler(t, mq, nil)
+
+	msg := &queue.Message{
+		ID:       msgID.String(),
+		TenantID: "tenant-1",
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
+		Body:     []byte("Hello"),
 	}
 
-	capture := &mockCaptureProvider{}
-	log := zerolog.Nop()
-	resolver := &mockCaptureResolver{provider: capture}
-	h := &Handler{
-		resolver: resolver,
-		queries:  mq,
-		store:    store,
-		log:      log,
+	err := h.HandleMessage(context.Background(), msg)
+	var perr *crash.PanicError
+	if !errors.As(err, &perr) || perr.Value != "provider list corrupted" {
+		t.Fatalf("HandleMessage() = %v, want the recovered panic", err)
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
+		t.Errorf("expected final status failed, got %s", mq.statuses[len(mq.statuses)-1])
 	}
+	if mq.createLogStatus != string(storage.MessageStatusFailed) || !strings.Contains(mq.createLogParams.LastError.String, "provider list corrupted") {
+		t.Errorf("expected a failed delivery log with the panic, got %s %q", mq.createLogStatus, mq.createLogParams.LastError.String)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Tests: Invalid message ID
+// ---------------------------------------------------------------------------
+
+func TestHandler_HandleMessage_InvalidMessageID(t *testing.T) {
+	mq := &mockQuerier{}
+	h := newHandler(t, mq, nil)
 
 	msg := &queue.Message{
-		ID:        msgID.String(),
-		AccountID: groupID.String(),
-		TenantID:  "tenant-1",
+		ID:       "not-a-uuid",
+		TenantID: "tenant-1",
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
 	}
 
 	err := h.HandleMessage(context.Background(), msg)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
+	if err == nil {
+		t.Fatal("expected error for invalid message ID")
 	}
+}
 
-	// Verify delivery succeeded.
-	if len(mq.statuses) < 2 {
-		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
-	}
-	if mq.statuses[1] != storage.MessageStatusDelivered {
-		t.Errorf("expected delivered status, got %s", mq.statuses[1])
-	}
+// ---------------------------------------------------------------------------
+// Tests: GetMessageByID error (non-ErrNoRows)
+// ---------------------------------------------------------------------------
 
-	// Verify the provider received MIME-parsed fields.
-	if capture.captured == nil {
-		t.Fatal("expected provider to receive a message")
-	}
-	pm := capture.captured
+func TestHandler_HandleMessage_GetMessageError(t *testing.T) {
+	msgID := uuid.New()
 
-	// MIME-parsed subject should override the DB subject.
-	if pm.Subject != "MIME Parsed Subject" {
-		t.Errorf("expected subject 'MIME Parsed Subject', got %q", pm.Subject)
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return storage.Message{}, errors.New("connection refused")
+		},
 	}
-	if pm.TextBody != "Hello plain text" {
-		t.Errorf("expected TextBody 'Hello plain text', got %q", pm.TextBody)
+	h := newHandler(t, mq, nil)
+
+	msg := &queue.Message{
+		ID:       msgID.String(),
+		TenantID: "tenant-1",
+		From:     "sender@example.com",
+		To:       []string{"recipient@example.com"},
 	}
-	if pm.HTMLBody != "<h1>Hello HTML</h1>" {
-		t.Errorf("expected HTMLBody '<h1>Hello HTML</h1>', got %q", pm.HTMLBody)
+
+	err := h.HandleMessage(context.Background(), msg)
+	if err == nil {
+		t.Fatal("expected error when message not found in database")
 	}
-	if len(pm.Attachments) != 1 {
-		t.Fatalf("expected 1 attachment, got %d", len(pm.Attachments))
+
+	// Final status should be failed.
+	if len(mq.statuses) == 0 {
+		t.Fatal("expected at least one status update")
 	}
-	if pm.Attachments[0].Filename != "report.pdf" {
-		t.Errorf("expected attachment filename 'report.pdf', got %q", pm.Attachments[0].Filename)
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
+		t.Errorf("expected final status failed, got %s", mq.statuses[len(mq.statuses)-1])
 	}
-	if pm.Attachments[0].ContentType != "application/pdf" {
-		t.Errorf("expected attachment content type 'application/pdf', got %q", pm.Attachments[0].ContentType)
+	// GroupID should NOT be set since we failed before getting dbMsg.
+	if mq.createLogParams.GroupID.Valid {
+		t.Error("expected GroupID to be unset when message lookup fails")
 	}
 }
 
-func TestHandler_HandleMessage_MIMEParseFallback(t *testing.T) {
-	// When the body is not valid MIME, the handler should fall back
-	// to using the raw body as TextBody.
+// ---------------------------------------------------------------------------
+// Tests: Context cancellation during storage retry
+// ---------------------------------------------------------------------------
+
+func TestHandler_HandleMessage_StorageRetryContextCancelled(t *testing.T) {
 	origBackoff := storageRetryBackoff
-	storageRetryBackoff = []time.Duration{time.Millisecond}
+	storageRetryBackoff = []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
 	defer func() { storageRetryBackoff = origBackoff }()
 
 	groupID := uuid.New()
 	userID := uuid.New()
 	msgID := uuid.New()
-	plainBody := []byte("Just a plain string, not MIME at all")
 
 	mq := &mockQuerier{
 		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
@@ -909,18 +1377,18 @@ func TestHandler_HandleMessage_MIMEParseFallback(t *testing.T) {
 		},
 	}
 	store := &mockMessageStore{
-		data: map[string][]byte{msgID.String(): plainBody},
+		getFn: func(_ context.Context, _ string) ([]byte, error) {
+			return nil, errors.New("temporary error")
+		},
 	}
+	h := newHandler(t, mq, store)
 
-	capture := &mockCaptureProvider{}
-	log := zerolog.Nop()
-	resolver := &mockCaptureResolver{provider: capture}
-	h := &Handler{
-		resolver: resolver,
-		queries:  mq,
-		store:    store,
-		log:      log,
-	}
+	ctx, cancel := context.WithCancel(context.Background())
+	// Cancel after a short delay to interrupt the retry loop.
+	go func() {
+		time.Sleep(25 * time.Millisecond)
+		cancel()
+	}()
 
 	msg := &queue.Message{
 		ID:        msgID.String(),
@@ -928,24 +1396,1180 @@ func TestHandler_HandleMessage_MIMEParseFallback(t *testing.T) {
 		TenantID:  "tenant-1",
 	}
 
-	err := h.HandleMessage(context.Background(), msg)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
+	err := h.HandleMessage(ctx, msg)
+	if err == nil {
+		t.Fatal("expected error when context is cancelled during retry")
 	}
+}
 
-	if capture.captured == nil {
-		t.Fatal("expected provider to receive a message")
-	}
-	pm := capture.captured
+// ---------------------------------------------------------------------------
+// Tests: Helper functions
+// ---------------------------------------------------------------------------
 
-	// Fallback: raw body should be used as TextBody.
-	if pm.TextBody != string(plainBody) {
-		t.Errorf("expected TextBody to be raw body, got %q", pm.TextBody)
-	}
-	if pm.HTMLBody != "" {
-		t.Errorf("expected no HTMLBody, got %q", pm.HTMLBody)
-	}
-	if len(pm.Attachments) != 0 {
-		t.Errorf("expected no attachments, got %d", len(pm.Attachments))
-	}
+func TestParseRecipients(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected []string
+	}{
+		{"valid", mustJSON([]string{"a@b.com", "c@d.com"}), []string{"a@b.com", "c@d.com"}},
+		{"empty", nil, nil},
+		{"invalid json", []byte("not json"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseRecipients(tt.input)
+			if len(result) != len(tt.expected) {
+				t.Errorf("expected %d recipients, got %d", len(tt.expected), len(result))
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected map[string]string
+	}{
+		{
+			"valid",
+			mustJSON(map[string][]string{"X-Test": {"v1", "v2"}, "X-Other": {"v3"}}),
+			map[string]string{"X-Test": "v1", "X-Other": "v3"},
+		},
+		{"empty", nil, nil},
+		{"invalid json", []byte("not json"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseHeaders(tt.input)
+			if tt.expected == nil {
+				if result != nil {
+					t.Errorf("expected nil, got %v", result)
+				}
+				return
+			}
+			for k, v := range tt.expected {
+				if result[k] != v {
+					t.Errorf("key %q: expected %q, got %q", k, v, result[k])
+				}
+			}
+		})
+	}
+}
+
+func TestParseHeaders_DropsTagHeaders(t *testing.T) {
+	result := parseHeaders(mustJSON(map[string][]string{
+		"X-Test":               {"v1"},
+		"X-Smtpproxy-Tag":      {"billing"},
+		"X-Smtpproxy-Metadata": {"customer_id=42"},
+		"X-Smtpproxy-Urgent":   {"yes"},
+	}))
+	if len(result) != 1 || result["X-Test"] != "v1" {
+		t.Errorf("expected only X-Test, got %v", result)
+	}
+}
+
+func TestNullStringValue(t *testing.T) {
+	if v := nullStringValue(sql.NullString{String: "hello", Valid: true}); v != "hello" {
+		t.Errorf("expected 'hello', got %q", v)
+	}
+	if v := nullStringValue(sql.NullString{}); v != "" {
+		t.Errorf("expected empty string, got %q", v)
+	}
+}
+
+func mustJSON(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// ---------------------------------------------------------------------------
+// Tests: MIME parsing integration (multipart message with HTML + attachments)
+// ---------------------------------------------------------------------------
+
+// mockCaptureProvider captures the provider.Message it receives via Send.
+type mockCaptureProvider struct {
+	captured *provider.Message
+}
+
+func (m *mockCaptureProvider) GetName() string { return "capture" }
+func (m *mockCaptureProvider) Send(_ context.Context, msg *provider.Message) (*provider.DeliveryResult, error) {
+	m.captured = msg
+	return &provider.DeliveryResult{
+		ProviderMessageID: "capture-" + msg.ID,
+		Status:            provider.StatusSent,
+	}, nil
+}
+func (m *mockCaptureProvider) HealthCheck(_ context.Context) error { return nil }
+
+// mockCaptureResolver returns a fixed provider for any group.
+type mockCaptureResolver struct {
+	provider provider.Provider
+}
+
+func (r *mockCaptureResolver) Resolve(_ context.Context, _ uuid.UUID) (provider.Provider, error) {
+	return r.provider, nil
+}
+
+func (r *mockCaptureResolver) ResolveByID(_ context.Context, _, _ uuid.UUID) (provider.Provider, error) {
+	return r.provider, nil
+}
+
+func TestHandler_HandleMessage_MIMEParsing(t *testing.T) {
+	// Reduce backoff for fast tests.
+	origBackoff := storageRetryBackoff
+	storageRetryBackoff = []time.Duration{time.Millisecond}
+	defer func() { storageRetryBackoff = origBackoff }()
+
+	groupID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+
+	// Build a real multipart MIME message with HTML body and an attachment.
+	boundary := "----TestBoundary123"
+	mimeMsg := "MIME-Version: 1.0\r\n" +
+		"Subject: MIME Parsed Subject\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n" +
+		"\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Hello plain text\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<h1>Hello HTML</h1>\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Type: application/pdf; name=\"report.pdf\"\r\n" +
+		"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+		"\r\n" +
+		"PDF-CONTENT-HERE\r\n" +
+		"--" + boundary + "--\r\n"
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	store := &mockMessageStore{
+		data: map[string][]byte{msgID.String(): []byte(mimeMsg)},
+	}
+
+	capture := &mockCaptureProvider{}
+	log := zerolog.Nop()
+	resolver := &mockCaptureResolver{provider: capture}
+	h := &Handler{
+		resolver: resolver,
+		queries:  mq,
+		store:    store,
+		log:      log,
+	}
+
+	msg := &queue.Message{
+		ID:        msgID.String(),
+		AccountID: groupID.String(),
+		TenantID:  "tenant-1",
+	}
+
+	err := h.HandleMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Verify delivery succeeded.
+	if len(mq.statuses) < 2 {
+		t.Fatalf("expected at least 2 status updates, got %d", len(mq.statuses))
+	}
+	if mq.statuses[1] != storage.MessageStatusDelivered {
+		t.Errorf("expected delivered status, got %s", mq.statuses[1])
+	}
+
+	// Verify the provider received MIME-parsed fields.
+	if capture.captured == nil {
+		t.Fatal("expected provider to receive a message")
+	}
+	pm := capture.captured
+
+	// MIME-parsed subject should override the DB subject.
+	if pm.Subject != "MIME Parsed Subject" {
+		t.Errorf("expected subject 'MIME Parsed Subject', got %q", pm.Subject)
+	}
+	if pm.TextBody != "Hello plain text" {
+		t.Errorf("expected TextBody 'Hello plain text', got %q", pm.TextBody)
+	}
+	if pm.HTMLBody != "<h1>Hello HTML</h1>" {
+		t.Errorf("expected HTMLBody '<h1>Hello HTML</h1>', got %q", pm.HTMLBody)
+	}
+	if len(pm.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(pm.Attachments))
+	}
+	if pm.Attachments[0].Filename != "report.pdf" {
+		t.Errorf("expected attachment filename 'report.pdf', got %q", pm.Attachments[0].Filename)
+	}
+	if pm.Attachments[0].ContentType != "application/pdf" {
+		t.Errorf("expected attachment content type 'application/pdf', got %q", pm.Attachments[0].ContentType)
+	}
+}
+
+func TestHandler_HandleMessage_MIMEParseFallback(t *testing.T) {
+	// When the body is not valid MIME, the handler should fall back
+	// to using the raw body as TextBody.
+	origBackoff := storageRetryBackoff
+	storageRetryBackoff = []time.Duration{time.Millisecond}
+	defer func() { storageRetryBackoff = origBackoff }()
+
+	groupID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+	plainBody := []byte("Just a plain string, not MIME at all")
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	store := &mockMessageStore{
+		data: map[string][]byte{msgID.String(): plainBody},
+	}
+
+	capture := &mockCaptureProvider{}
+	log := zerolog.Nop()
+	resolver := &mockCaptureResolver{provider: capture}
+	h := &Handler{
+		resolver: resolver,
+		queries:  mq,
+		store:    store,
+		log:      log,
+	}
+
+	msg := &queue.Message{
+		ID:        msgID.String(),
+		AccountID: groupID.String(),
+		TenantID:  "tenant-1",
+	}
+
+	err := h.HandleMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if capture.captured == nil {
+		t.Fatal("expected provider to receive a message")
+	}
+	pm := capture.captured
+
+	// Fallback: raw body should be used as TextBody.
+	if pm.TextBody != string(plainBody) {
+		t.Errorf("expected TextBody to be raw body, got %q", pm.TextBody)
+	}
+	if pm.HTMLBody != "" {
+		t.Errorf("expected no HTMLBody, got %q", pm.HTMLBody)
+	}
+	if len(pm.Attachments) != 0 {
+		t.Errorf("expected no attachments, got %d", len(pm.Attachments))
+	}
+}
+
+// identifiedCaptureProvider is a mockCaptureProvider backed by an
+// esp_providers row.
+type identifiedCaptureProvider struct {
+	mockCaptureProvider
+	id uuid.UUID
+}
+
+func (p *identifiedCaptureProvider) ProviderID() uuid.UUID { return p.id }
+
+func TestHandler_HandleMessage_RecordsProviderID(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+	providerID := uuid.New()
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: &identifiedCaptureProvider{id: providerID}},
+		queries:  mq,
+		log:      zerolog.Nop(),
+	}
+
+	msg := &queue.Message{
+		ID:   msgID.String(),
+		From: "sender@example.com",
+		To:   []string{"recipient@example.com"},
+		Body: []byte("Hello"),
+	}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := mq.createLogParams.ProviderID
+	if !got.Valid || uuid.UUID(got.Bytes) != providerID {
+		t.Errorf("delivery log ProviderID = %v, want %s", got, providerID)
+	}
+}
+
+type capturingProvider struct {
+	identifiedCaptureProvider
+	limit int
+}
+
+func (p *capturingProvider) CaptureLimit() int { return p.limit }
+
+func TestHandler_HandleMessage_StoresProviderCapture(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+	providerID := uuid.New()
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: &capturingProvider{identifiedCaptureProvider: identifiedCaptureProvider{id: providerID}, limit: 5}},
+		queries:  mq,
+		log:      zerolog.Nop(),
+	}
+
+	msg := &queue.Message{
+		ID:   msgID.String(),
+		From: "sender@example.com",
+		To:   []string{"recipient@example.com"},
+		Body: []byte("Hello"),
+	}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(mq.captures) != 1 {
+		t.Fatalf("expected 1 capture, got %d", len(mq.captures))
+	}
+	c := mq.captures[0]
+	if c.ProviderID != providerID || uuid.UUID(c.MessageID.Bytes) != msgID || string(c.Exchanges) != "[]" || c.Error.Valid {
+		t.Errorf("unexpected capture: %+v", c)
+	}
+	if len(mq.capturePrunes) != 1 || mq.capturePrunes[0].Limit != 5 {
+		t.Errorf("unexpected prunes: %+v", mq.capturePrunes)
+	}
+
+	// Providers without debug mode are not captured.
+	mq.captures = nil
+	h.resolver = &mockCaptureResolver{provider: &identifiedCaptureProvider{id: providerID}}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mq.captures) != 0 {
+		t.Errorf("expected no capture, got %d", len(mq.captures))
+	}
+}
+
+func TestHandler_HandleMessage_RecordsRequestID(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		queued   string
+		stored   string
+		expected string
+	}{
+		{name: "from queue message", queued: "req-queue", stored: "req-db", expected: "req-queue"},
+		{name: "from database", stored: "req-db", expected: "req-db"},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					m := newTestDBMessage(groupID, userID)
+					m.RequestID = pgtype.Text{String: tt.stored, Valid: tt.stored != ""}
+					return m, nil
+				},
+			}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: &identifiedCaptureProvider{id: uuid.New()}},
+				queries:  mq,
+				log:      zerolog.Nop(),
+			}
+
+			msg := &queue.Message{
+				ID:        uuid.New().String(),
+				Body:      []byte("Hello"),
+				RequestID: tt.queued,
+			}
+			if err := h.HandleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			got := mq.createLogParams.RequestID
+			if got.String != tt.expected || got.Valid != (tt.expected != "") {
+				t.Errorf("delivery log RequestID = %+v, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// httpSendProvider posts every message to url with a DefaultHTTPClient, so
+// its connection is recorded like a real ESP's.
+type httpSendProvider struct {
+	url string
+}
+
+func (p *httpSendProvider) Send(ctx context.Context, _ *provider.Message) (*provider.DeliveryResult, error) {
+	if _, err := provider.NewHTTPClient(time.Second).Do(&provider.HTTPRequest{Method: "POST", URL: p.url, Context: ctx}); err != nil {
+		return nil, err
+	}
+	return &provider.DeliveryResult{Status: provider.StatusSent}, nil
+}
+func (p *httpSendProvider) GetName() string                     { return "http" }
+func (p *httpSendProvider) HealthCheck(_ context.Context) error { return nil }
+
+func TestHandler_HandleMessage_RecordsConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(uuid.New(), uuid.New()), nil
+		},
+	}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: &httpSendProvider{url: srv.URL + "/send?key=secret"}},
+		queries:  mq,
+		log:      zerolog.Nop(),
+	}
+
+	msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Hello")}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got := mq.createLogParams
+	if got.EgressIp.String != "127.0.0.1" {
+		t.Errorf("delivery log EgressIp = %+v, want 127.0.0.1", got.EgressIp)
+	}
+	if got.ProviderEndpoint.String != srv.URL+"/send" {
+		t.Errorf("delivery log ProviderEndpoint = %+v, want %s/send", got.ProviderEndpoint, srv.URL)
+	}
+	if got.RemoteAddr.String != strings.TrimPrefix(srv.URL, "http://") {
+		t.Errorf("delivery log RemoteAddr = %+v, want %s", got.RemoteAddr, srv.URL)
+	}
+	if got.TlsVersion.Valid || got.TlsCipher.Valid {
+		t.Errorf("expected no TLS details for a plaintext connection, got %+v %+v", got.TlsVersion, got.TlsCipher)
+	}
+}
+
+func TestHandler_HandleMessage_SenderPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		shadowed   bool
+		wantStatus string
+		wantFrom   string
+		wantAudit  string
+	}{
+		{name: "no policy", wantStatus: "delivered", wantFrom: "sender@example.com"},
+		{name: "off", mode: senderpolicy.ModeOff, wantStatus: "delivered", wantFrom: "sender@example.com"},
+		{name: "reject", mode: senderpolicy.ModeReject, wantStatus: "failed", wantAudit: auth.AuditActionSenderRejected},
+		{name: "reject shadowed", mode: senderpolicy.ModeReject, shadowed: true, wantStatus: "delivered", wantFrom: "sender@example.com"},
+		{name: "rewrite", mode: senderpolicy.ModeRewrite, wantStatus: "delivered", wantFrom: "noreply@verified.example", wantAudit: auth.AuditActionSenderRewritten},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					return newTestDBMessage(uuid.New(), uuid.New()), nil
+				},
+				senderPolicy: storage.SenderPolicy{
+					Mode:           tt.mode,
+					RewriteAddress: pgtype.Text{String: "noreply@verified.example", Valid: true},
+				},
+				senderIdentities: []string{"verified.example"},
+			}
+			if tt.shadowed {
+				mq.shadowPolicies = []string{shadow.PolicySenderPolicy}
+			}
+			p := &mockCaptureProvider{}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: p},
+				queries:  mq,
+				log:      zerolog.Nop(),
+			}
+
+			msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Hello")}
+			if err := h.HandleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if mq.createLogStatus != tt.wantStatus {
+				t.Errorf("delivery log status = %q, want %q", mq.createLogStatus, tt.wantStatus)
+			}
+			if tt.wantFrom == "" && p.captured != nil {
+				t.Error("expected the message not to be sent")
+			}
+			if tt.wantFrom != "" && (p.captured == nil || p.captured.From != tt.wantFrom) {
+				t.Errorf("sent message = %+v, want From %q", p.captured, tt.wantFrom)
+			}
+			if tt.shadowed != (len(mq.shadowRejections) == 1) {
+				t.Errorf("shadow rejections = %+v, want one when shadowed", mq.shadowRejections)
+			}
+			if tt.wantAudit == "" {
+				if len(mq.activityLogs) != 0 {
+					t.Errorf("expected no audit entries, got %+v", mq.activityLogs)
+				}
+				return
+			}
+			if len(mq.activityLogs) != 1 || mq.activityLogs[0].Action != tt.wantAudit {
+				t.Fatalf("audit entries = %+v, want one %s", mq.activityLogs, tt.wantAudit)
+			}
+			if !strings.Contains(mq.activityLogs[0].Comment.String, "sender@example.com") {
+				t.Errorf("audit comment = %q, want the violation", mq.activityLogs[0].Comment.String)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleMessage_SendingDefaults(t *testing.T) {
+	defaults := map[string]storage.SendingDomain{
+		"example.com": {
+			Domain:     "example.com",
+			ReplyTo:    pgtype.Text{String: "Support <support@example.com>", Valid: true},
+			ReturnPath: pgtype.Text{String: "bounces@example.com", Valid: true},
+		},
+	}
+	tests := []struct {
+		name           string
+		domains        map[string]storage.SendingDomain
+		headers        map[string][]string
+		wantReplyTo    string
+		wantReturnPath string
+	}{
+		{name: "no defaults"},
+		{name: "defaults applied", domains: defaults, wantReplyTo: "Support <support@example.com>", wantReturnPath: "bounces@example.com"},
+		{
+			name:           "message overrides",
+			domains:        defaults,
+			headers:        map[string][]string{"Reply-To": {"own@example.com"}, "Return-Path": {"<own-bounces@example.com>"}},
+			wantReplyTo:    "own@example.com",
+			wantReturnPath: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					m := newTestDBMessage(uuid.New(), uuid.New())
+					if tt.headers != nil {
+						m.Headers, _ = json.Marshal(tt.headers)
+					}
+					return m, nil
+				},
+				sendingDomains: tt.domains,
+			}
+			p := &mockCaptureProvider{}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: p},
+				queries:  mq,
+				log:      zerolog.Nop(),
+			}
+
+			msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Hello")}
+			if err := h.HandleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if p.captured == nil {
+				t.Fatal("expected the message to be sent")
+			}
+			if got := p.captured.Headers["Reply-To"]; got != tt.wantReplyTo {
+				t.Errorf("Reply-To = %q, want %q", got, tt.wantReplyTo)
+			}
+			if p.captured.ReturnPath != tt.wantReturnPath {
+				t.Errorf("ReturnPath = %q, want %q", p.captured.ReturnPath, tt.wantReturnPath)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleMessage_AutoBCC(t *testing.T) {
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(uuid.New(), uuid.New()), nil
+		},
+		user: storage.User{AutoBcc: []byte(`["archive@example.com", "Recipient@example.com"]`)},
+	}
+	p := &mockCaptureProvider{}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: p},
+		queries:  mq,
+		log:      zerolog.Nop(),
+	}
+
+	msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Hello")}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p.captured == nil {
+		t.Fatal("expected the message to be sent")
+	}
+	// The recipient is not copied a second time.
+	if len(p.captured.Bcc) != 1 || p.captured.Bcc[0] != "archive@example.com" {
+		t.Errorf("Bcc = %v, want [archive@example.com]", p.captured.Bcc)
+	}
+	if len(p.captured.To) != 1 || p.captured.To[0] != "recipient@example.com" {
+		t.Errorf("To = %v, want the original recipient only", p.captured.To)
+	}
+	if _, ok := p.captured.Headers["Bcc"]; ok {
+		t.Error("expected no Bcc header")
+	}
+}
+
+func TestHandler_HandleMessage_MessageDefaults(t *testing.T) {
+	defaults := &storage.MessageDefault{
+		Headers:          []byte(`{"Organization":"Example Inc.","X-Test":"default"}`),
+		FooterText:       "Example Inc., 1 Main St",
+		ExcludeTags:      []byte(`["transactional"]`),
+		ExcludeTemplates: []byte(`["password-reset"]`),
+	}
+	tests := []struct {
+		name        string
+		tags        string
+		metadata    string
+		wantApplied bool
+	}{
+		{"applied", "", "", true},
+		{"excluded tag", `["transactional"]`, "", false},
+		{"excluded template", "", `{"template":"password-reset"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbMsg := newTestDBMessage(uuid.New(), uuid.New())
+			dbMsg.Tags = []byte(tt.tags)
+			dbMsg.Metadata = []byte(tt.metadata)
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					return dbMsg, nil
+				},
+				messageDefaults: defaults,
+			}
+			p := &mockCaptureProvider{}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: p},
+				queries:  mq,
+				log:      zerolog.Nop(),
+			}
+
+			msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Hello")}
+			if err := h.HandleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if p.captured == nil {
+				t.Fatal("expected the message to be sent")
+			}
+			// The message's own header wins over the default.
+			if p.captured.Headers["X-Test"] != "value1" {
+				t.Errorf("X-Test = %q, want the message's value", p.captured.Headers["X-Test"])
+			}
+			applied := p.captured.Headers["Organization"] == "Example Inc."
+			footer := strings.HasSuffix(p.captured.TextBody, "\n\nExample Inc., 1 Main St\n")
+			if applied != tt.wantApplied || footer != tt.wantApplied {
+				t.Errorf("headers = %v, text = %q; want defaults applied %v", p.captured.Headers, p.captured.TextBody, tt.wantApplied)
+			}
+		})
+	}
+}
+
+// rawCaptureProvider is a mockCaptureProvider that sends signed messages.
+type rawCaptureProvider struct {
+	mockCaptureProvider
+}
+
+func (m *rawCaptureProvider) SendsRaw() bool { return true }
+
+// testSigningCert returns a self-signed certificate and key in PEM.
+func testSigningCert(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sender@example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestHandler_HandleMessage_Signing(t *testing.T) {
+	certPEM, keyPEM := testSigningCert(t)
+	smimeKey := func(encrypt bool, onFailure string) *storage.SigningKey {
+		return &storage.SigningKey{
+			Kind:        "smime",
+			Certificate: pgtype.Text{String: certPEM, Valid: true},
+			PrivateKey:  keyPEM,
+			Encrypt:     encrypt,
+			OnFailure:   onFailure,
+		}
+	}
+	recipientCert := []storage.RecipientCertificate{{Email: "Recipient@example.com", Certificate: certPEM}}
+
+	tests := []struct {
+		name       string
+		key        *storage.SigningKey
+		certs      []storage.RecipientCertificate
+		plain      bool // provider without raw MIME support
+		wantStatus string
+		wantRaw    string // substring of the sent raw message; "" for none
+	}{
+		{name: "no key", wantStatus: "delivered"},
+		{name: "signed", key: smimeKey(false, "fail"), wantStatus: "delivered", wantRaw: `protocol="application/pkcs7-signature"`},
+		{name: "encrypted", key: smimeKey(true, "fail"), certs: recipientCert, wantStatus: "delivered", wantRaw: "smime-type=enveloped-data"},
+		{name: "no recipient cert, send unsigned", key: smimeKey(true, "send_unsigned"), wantStatus: "delivered", wantRaw: `protocol="application/pkcs7-signature"`},
+		{name: "no recipient cert, fail", key: smimeKey(true, "fail"), wantStatus: "failed"},
+		{name: "provider cannot send raw, send unsigned", key: smimeKey(false, "send_unsigned"), plain: true, wantStatus: "delivered"},
+		{name: "provider cannot send raw, fail", key: smimeKey(false, "fail"), plain: true, wantStatus: "failed"},
+		{name: "bad key, send unsigned", key: &storage.SigningKey{Kind: "pgp", PrivateKey: "x", OnFailure: "send_unsigned"}, wantStatus: "delivered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					return newTestDBMessage(uuid.New(), uuid.New()), nil
+				},
+				signingKey:     tt.key,
+				recipientCerts: tt.certs,
+			}
+			raw := &rawCaptureProvider{}
+			var p provider.Provider = raw
+			captured := func() *provider.Message { return raw.captured }
+			if tt.plain {
+				plain := &mockCaptureProvider{}
+				p = plain
+				captured = func() *provider.Message { return plain.captured }
+			}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: p},
+				queries:  mq,
+				log:      zerolog.Nop(),
+			}
+
+			msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Hello")}
+			if err := h.HandleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if mq.createLogStatus != tt.wantStatus {
+				t.Errorf("delivery log status = %q, want %q", mq.createLogStatus, tt.wantStatus)
+			}
+			sent := captured()
+			if tt.wantStatus == "failed" {
+				if sent != nil {
+					t.Error("expected the message not to be sent")
+				}
+				return
+			}
+			if sent == nil {
+				t.Fatal("expected the message to be sent")
+			}
+			if tt.wantRaw == "" && sent.Raw != nil {
+				t.Errorf("expected no raw message, got %.200s", sent.Raw)
+			}
+			if tt.wantRaw != "" && !bytes.Contains(sent.Raw, []byte(tt.wantRaw)) {
+				t.Errorf("raw message does not contain %q: %.300s", tt.wantRaw, sent.Raw)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleMessage_HTMLProcessing(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+
+	body := "Subject: Styled\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<style>p { color: red }</style><p>Hi</p><script>alert(1)</script>"
+
+	tests := []struct {
+		name  string
+		group storage.Group
+		want  string
+	}{
+		{
+			name: "disabled",
+			want: "<style>p { color: red }</style><p>Hi</p><script>alert(1)</script>",
+		},
+		{
+			name:  "inline and sanitize",
+			group: storage.Group{ID: groupID, InlineCss: true, SanitizeHtml: true},
+			want:  `<p style="color: red">Hi</p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					return newTestDBMessage(groupID, userID), nil
+				},
+				group: tt.group,
+			}
+			capture := &mockCaptureProvider{}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: capture},
+				queries:  mq,
+				log:      zerolog.Nop(),
+			}
+
+			msg := &queue.Message{
+				ID:   msgID.String(),
+				From: "sender@example.com",
+				To:   []string{"recipient@example.com"},
+				Body: []byte(body),
+			}
+			if err := h.HandleMessage(context.Background(), msg); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if capture.captured == nil {
+				t.Fatal("expected provider to receive a message")
+			}
+			if capture.captured.HTMLBody != tt.want {
+				t.Errorf("HTMLBody = %q, want %q", capture.captured.HTMLBody, tt.want)
+			}
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Benchmarks
+// ---------------------------------------------------------------------------
+
+// benchMIMEMessage returns a multipart message with a text part, an HTML
+// part and a base64 attachment of about size bytes.
+func benchMIMEMessage(size int) []byte {
+	const boundary = "----BenchBoundary"
+	var b strings.Builder
+	b.WriteString("MIME-Version: 1.0\r\nSubject: Benchmark\r\n")
+	b.WriteString("Content-Type: multipart/mixed; boundary=\"" + boundary + "\"\r\n\r\n")
+	b.WriteString("--" + boundary + "\r\nContent-Type: text/plain; charset=utf-8\r\n\r\nHello plain text\r\n")
+	b.WriteString("--" + boundary + "\r\nContent-Type: text/html; charset=utf-8\r\n\r\n<p>Hello <b>HTML</b></p>\r\n")
+	b.WriteString("--" + boundary + "\r\nContent-Type: application/octet-stream\r\n")
+	b.WriteString("Content-Disposition: attachment; filename=\"blob.bin\"\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+	enc := base64.StdEncoding.EncodeToString(make([]byte, size*3/4))
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc + "\r\n--" + boundary + "--\r\n")
+	return []byte(b.String())
+}
+
+// BenchmarkHandler_HandleMessage measures delivering a stored message:
+// fetching the body, MIME parsing, HTML processing and recording the
+// result. Run with -benchmem to track body copies.
+func BenchmarkHandler_HandleMessage(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		size int
+	}{
+		{"2KB", 2 << 10},
+		{"64KB", 64 << 10},
+		{"1MB", 1 << 20},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			groupID := uuid.New()
+			msgID := uuid.New()
+			mq := &mockQuerier{
+				getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+					return newTestDBMessage(groupID, uuid.New()), nil
+				},
+			}
+			store := &mockMessageStore{
+				data: map[string][]byte{msgID.String(): benchMIMEMessage(tc.size)},
+			}
+			h := &Handler{
+				resolver: &mockCaptureResolver{provider: &mockCaptureProvider{}},
+				queries:  mq,
+				store:    store,
+				log:      zerolog.Nop(),
+			}
+			msg := &queue.Message{ID: msgID.String(), AccountID: groupID.String()}
+
+			b.SetBytes(int64(len(store.data[msgID.String()])))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mq.statuses = mq.statuses[:0]
+				if err := h.HandleMessage(context.Background(), msg); err != nil {
+					b.Fatalf("HandleMessage: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// pinnedResolver records which resolution path the handler used.
+type pinnedResolver struct {
+	routed, pinned provider.Provider
+	pinnedID       uuid.UUID
+}
+
+func (r *pinnedResolver) Resolve(_ context.Context, _ uuid.UUID) (provider.Provider, error) {
+	return r.routed, nil
+}
+
+func (r *pinnedResolver) ResolveByID(_ context.Context, _, providerID uuid.UUID) (provider.Provider, error) {
+	r.pinnedID = providerID
+	return r.pinned, nil
+}
+
+func TestHandler_HandleMessage_PinnedProvider(t *testing.T) {
+	groupID := uuid.New()
+	msgID := uuid.New()
+	providerID := uuid.New()
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, uuid.New()), nil
+		},
+	}
+	routed := &mockCaptureProvider{}
+	pinned := &mockCaptureProvider{}
+	resolver := &pinnedResolver{routed: routed, pinned: pinned}
+	h := NewHandler(resolver, mq, nil, zerolog.Nop())
+
+	msg := &queue.Message{ID: msgID.String(), Body: []byte("Hello"), ProviderID: providerID.String()}
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resolver.pinnedID != providerID {
+		t.Errorf("expected pinned provider %s to be resolved, got %s", providerID, resolver.pinnedID)
+	}
+	if pinned.captured == nil || routed.captured != nil {
+		t.Error("expected delivery through the pinned provider only")
+	}
+
+	msg.ProviderID = "not-a-uuid"
+	if err := h.HandleMessage(context.Background(), msg); err == nil {
+		t.Error("expected error for an invalid pinned provider ID")
+	}
+}
+
+func (m *mockQuerier) CreateDeliveryLogArchive(_ context.Context, _ storage.CreateDeliveryLogArchiveParams) (storage.DeliveryLogArchive, error) {
+	return storage.DeliveryLogArchive{}, nil
+}
+
+func (m *mockQuerier) DeleteArchivedDeliveryLogs(_ context.Context, _ []uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryLogArchive(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsToArchive(_ context.Context, _ storage.ListDeliveryLogsToArchiveParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryLogArchives(_ context.Context, _ storage.ListGroupDeliveryLogArchivesParams) ([]storage.DeliveryLogArchive, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) GetAnalyticsExportCursor(_ context.Context, _ string) (storage.AnalyticsExportCursor, error) {
+	return storage.AnalyticsExportCursor{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsForExport(_ context.Context, _ storage.ListDeliveryLogsForExportParams) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpsertAnalyticsExportCursor(_ context.Context, _ storage.UpsertAnalyticsExportCursorParams) error {
+	return nil
+}
+
+func (m *mockQuerier) DeliveryTimeSeries(_ context.Context, _ storage.DeliveryTimeSeriesParams) ([]storage.DeliveryTimeSeriesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListMessagesSentSince(_ context.Context, _ storage.ListMessagesSentSinceParams) ([]storage.ListMessagesSentSinceRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) PauseQueuedMessages(_ context.Context, _ storage.PauseQueuedMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ResumePausedMessages(_ context.Context, _ storage.ResumePausedMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) RevokeUserAPIKey(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) DeleteUserSession(_ context.Context, _ storage.DeleteUserSessionParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) TouchSession(_ context.Context, _ storage.TouchSessionParams) error {
+	return nil
+}
+
+func (m *mockQuerier) ListExpiringSMTPPasswords(_ context.Context, changedBefore pgtype.Timestamptz) ([]storage.ListExpiringSMTPPasswordsRow, error) {
+	var rows []storage.ListExpiringSMTPPasswordsRow
+	for _, r := range m.expiringPasswords {
+		if r.PasswordChangedAt.Time.Before(changedBefore.Time) && !slices.Contains(m.expiryWarned, r.ID) {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+func (m *mockQuerier) MarkPasswordExpiryWarned(_ context.Context, id uuid.UUID) error {
+	m.expiryWarned = append(m.expiryWarned, id)
+	return nil
+}
+
+func (m *mockQuerier) ListLoginNetworks(_ context.Context, _ uuid.UUID) ([]storage.LoginNetwork, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) RecordLoginNetwork(_ context.Context, _ storage.RecordLoginNetworkParams) error {
+	return nil
+}
+
+func (m *mockQuerier) AcceptInvitation(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateInvitation(_ context.Context, _ storage.CreateInvitationParams) (storage.Invitation, error) {
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) DeleteInvitation(_ context.Context, _ storage.DeleteInvitationParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetInvitationByID(_ context.Context, _ uuid.UUID) (storage.Invitation, error) {
+	return storage.Invitation{}, nil
+}
+
+func (m *mockQuerier) ListPendingInvitationsByGroupID(_ context.Context, _ uuid.UUID) ([]storage.Invitation, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) CreateSignup(_ context.Context, _ storage.CreateSignupParams) (storage.Signup, error) {
+	return storage.Signup{}, nil
+}
+
+func (m *mockQuerier) DeleteExpiredSignups(_ context.Context) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteSignup(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetSignupByID(_ context.Context, _ uuid.UUID) (storage.Signup, error) {
+	return storage.Signup{}, nil
+}
+
+func (m *mockQuerier) ListVerifiedGroupMemberEmails(_ context.Context, _ uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) UpdateGroupSandbox(_ context.Context, _ storage.UpdateGroupSandboxParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) MarkEmailVerified(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) DeleteGroupBranding(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) GetGroupBranding(_ context.Context, groupID uuid.UUID) (storage.GroupBranding, error) {
+	b, ok := m.groupBranding[groupID]
+	if !ok {
+		return storage.GroupBranding{}, pgx.ErrNoRows
+	}
+	return b, nil
+}
+
+func (m *mockQuerier) UpsertGroupBranding(_ context.Context, _ storage.UpsertGroupBrandingParams) (storage.GroupBranding, error) {
+	return storage.GroupBranding{}, nil
+}
+
+func (m *mockQuerier) UpdateGroupLocale(_ context.Context, _ storage.UpdateGroupLocaleParams) (storage.Group, error) {
+	return storage.Group{}, nil
+}
+
+func (m *mockQuerier) CountResendableMessages(_ context.Context, _ storage.CountResendableMessagesParams) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) CreateDelayedOutboxEntry(_ context.Context, arg storage.CreateDelayedOutboxEntryParams) (storage.OutboxEntry, error) {
+	m.delayedEntries = append(m.delayedEntries, arg)
+	return storage.OutboxEntry{}, nil
+}
+
+func (m *mockQuerier) ListResendableMessages(_ context.Context, _ storage.ListResendableMessagesParams) ([]storage.ListResendableMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ResendMessage(_ context.Context, _ uuid.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQuerier) ClaimDeliveryReport(_ context.Context, _ int32) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) CompleteDeliveryReport(_ context.Context, _ storage.CompleteDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) CreateDeliveryReport(_ context.Context, _ storage.CreateDeliveryReportParams) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) DeleteDeliveryReport(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockQuerier) FailDeliveryReport(_ context.Context, _ storage.FailDeliveryReportParams) error {
+	return nil
+}
+
+func (m *mockQuerier) GetDeliveryReport(_ context.Context, _ uuid.UUID) (storage.DeliveryReport, error) {
+	return storage.DeliveryReport{}, nil
+}
+
+func (m *mockQuerier) ListDeliveryLogsByMessageIDs(_ context.Context, _ []uuid.UUID) ([]storage.DeliveryLog, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListDeliveryReportMessages(_ context.Context, _ storage.ListDeliveryReportMessagesParams) ([]storage.ListDeliveryReportMessagesRow, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListExpiredDeliveryReports(_ context.Context, _ storage.ListExpiredDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
+}
+
+func (m *mockQuerier) ListGroupDeliveryReports(_ context.Context, _ storage.ListGroupDeliveryReportsParams) ([]storage.DeliveryReport, error) {
+	return nil, nil
 }
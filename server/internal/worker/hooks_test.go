@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+var (
+	registerTestHook sync.Once
+	// testHook holds the stages under test. Registered hooks cannot be
+	// removed, so one hook delegating to testHook is registered for the
+	// package.
+	testHook provider.Hook
+)
+
+// useHook runs h's stages around deliveries until the test ends.
+func useHook(t *testing.T, h provider.Hook) {
+	t.Helper()
+	registerTestHook.Do(func() {
+		provider.RegisterHook(provider.Hook{
+			Name: "test",
+			PreRoute: func(ctx context.Context, msg *provider.Message) error {
+				if testHook.PreRoute == nil {
+					return nil
+				}
+				return testHook.PreRoute(ctx, msg)
+			},
+			PreSend: func(ctx context.Context, p provider.Provider, msg *provider.Message) error {
+				if testHook.PreSend == nil {
+					return nil
+				}
+				return testHook.PreSend(ctx, p, msg)
+			},
+			PostSend: func(ctx context.Context, p provider.Provider, msg *provider.Message, result *provider.DeliveryResult, sendErr error) {
+				if testHook.PostSend != nil {
+					testHook.PostSend(ctx, p, msg, result, sendErr)
+				}
+			},
+		})
+	})
+	testHook = h
+	t.Cleanup(func() { testHook = provider.Hook{} })
+}
+
+func TestHandler_HandleMessage_Hooks(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+
+	var stages []string
+	var postResult *provider.DeliveryResult
+	useHook(t, provider.Hook{
+		PreRoute: func(_ context.Context, msg *provider.Message) error {
+			stages = append(stages, "pre-route")
+			msg.To = []string{"rewritten@example.com"}
+			return nil
+		},
+		PreSend: func(_ context.Context, p provider.Provider, _ *provider.Message) error {
+			stages = append(stages, "pre-send "+p.GetName())
+			return nil
+		},
+		PostSend: func(_ context.Context, _ provider.Provider, _ *provider.Message, result *provider.DeliveryResult, sendErr error) {
+			stages = append(stages, "post-send")
+			postResult = result
+		},
+	})
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	capture := &mockCaptureProvider{}
+	h := &Handler{resolver: &mockCaptureResolver{provider: capture}, queries: mq, log: zerolog.Nop()}
+
+	msgID := uuid.New()
+	if err := h.HandleMessage(context.Background(), &queue.Message{ID: msgID.String(), Body: []byte("Hello")}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{"pre-route", "pre-send capture", "post-send"}
+	if !slices.Equal(stages, want) {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+	if capture.captured == nil || len(capture.captured.To) != 1 || capture.captured.To[0] != "rewritten@example.com" {
+		t.Errorf("provider received %+v, want the pre-route hook's recipients", capture.captured)
+	}
+	if postResult == nil || postResult.ProviderMessageID != "capture-"+msgID.String() {
+		t.Errorf("post-send result = %+v, want the provider's", postResult)
+	}
+}
+
+func TestHandler_HandleMessage_PreSendHookFails(t *testing.T) {
+	groupID := uuid.New()
+	userID := uuid.New()
+	blocked := errors.New("blocked by policy")
+	useHook(t, provider.Hook{
+		PreSend: func(context.Context, provider.Provider, *provider.Message) error {
+			return blocked
+		},
+	})
+
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	capture := &mockCaptureProvider{}
+	h := &Handler{resolver: &mockCaptureResolver{provider: capture}, queries: mq, log: zerolog.Nop()}
+
+	err := h.HandleMessage(context.Background(), &queue.Message{ID: uuid.NewString(), Body: []byte("Hello")})
+	if !errors.Is(err, blocked) {
+		t.Fatalf("expected the hook's error, got %v", err)
+	}
+	if capture.captured != nil {
+		t.Error("message was sent despite the pre-send hook failing")
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
+		t.Errorf("expected final status failed, got %s", mq.statuses[len(mq.statuses)-1])
+	}
+	if mq.createLogParams.Provider.String != "capture" {
+		t.Errorf("failure log provider = %q, want capture", mq.createLogParams.Provider.String)
+	}
+}
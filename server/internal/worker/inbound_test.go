@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/inbound"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// fakePoster records inbound posts and returns a fixed result.
+type fakePoster struct {
+	calls  int
+	route  inbound.Route
+	env    inbound.Envelope
+	status int
+	err    error
+}
+
+func (f *fakePoster) Post(_ context.Context, route inbound.Route, env inbound.Envelope, _ []byte) (int, error) {
+	f.calls++
+	f.route = route
+	f.env = env
+	return f.status, f.err
+}
+
+func newInboundTest(t *testing.T, route *storage.InboundRoute, poster *fakePoster) (*Handler, *mockQuerier, *queue.Message) {
+	t.Helper()
+	groupID := uuid.New()
+	routeID := uuid.New()
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			m := newTestDBMessage(groupID, uuid.Nil)
+			m.UserID = pgtype.UUID{}
+			m.InboundRouteID = pgtype.UUID{Bytes: routeID, Valid: true}
+			return m, nil
+		},
+	}
+	if route != nil {
+		route.ID = routeID
+		mq.inboundRoutes = map[uuid.UUID]storage.InboundRoute{routeID: *route}
+	}
+	h := newHandler(t, mq, nil)
+	h.inbound = poster
+	msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Subject: hi\r\n\r\nhello")}
+	return h, mq, msg
+}
+
+func TestHandler_Inbound_Success(t *testing.T) {
+	poster := &fakePoster{status: 200}
+	h, mq, msg := newInboundTest(t, &storage.InboundRoute{
+		Domain:  "inbound.test",
+		Url:     "https://app.example.com/inbound",
+		Format:  inbound.FormatJSON,
+		Secret:  pgtype.Text{String: "s3cret", Valid: true},
+		Enabled: true,
+	}, poster)
+
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if poster.calls != 1 || poster.route.URL != "https://app.example.com/inbound" || poster.route.Secret != "s3cret" {
+		t.Errorf("unexpected post: %+v", poster)
+	}
+	if poster.env.From != "sender@example.com" || len(poster.env.To) != 1 {
+		t.Errorf("unexpected envelope: %+v", poster.env)
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusDelivered {
+		t.Errorf("expected delivered status, got %v", mq.statuses)
+	}
+	if mq.createLogProvider != inboundProviderName || mq.createLogParams.ResponseCode.Int32 != 200 {
+		t.Errorf("unexpected delivery log: %+v", mq.createLogParams)
+	}
+}
+
+func TestHandler_Inbound_TemporaryFailureRetries(t *testing.T) {
+	poster := &fakePoster{status: 503, err: errors.New("inbound endpoint returned HTTP 503")}
+	h, mq, msg := newInboundTest(t, &storage.InboundRoute{Url: "https://app.example.com/inbound", Enabled: true}, poster)
+
+	if err := h.HandleMessage(context.Background(), msg); err == nil {
+		t.Fatal("expected an error so the queue retries the message")
+	}
+	if mq.createLogStatus != string(storage.MessageStatusFailed) {
+		t.Errorf("expected failed delivery log, got %q", mq.createLogStatus)
+	}
+}
+
+func TestHandler_Inbound_PermanentFailureNotRetried(t *testing.T) {
+	poster := &fakePoster{status: 404, err: &inbound.PermanentError{StatusCode: 404}}
+	h, mq, msg := newInboundTest(t, &storage.InboundRoute{Url: "https://app.example.com/inbound", Enabled: true}, poster)
+
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no retry for a permanent failure, got %v", err)
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
+		t.Errorf("expected failed status, got %v", mq.statuses)
+	}
+}
+
+func TestHandler_Inbound_RouteDeleted(t *testing.T) {
+	poster := &fakePoster{status: 200}
+	h, mq, msg := newInboundTest(t, nil, poster)
+
+	if err := h.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("expected no retry for a deleted route, got %v", err)
+	}
+	if poster.calls != 0 {
+		t.Error("message must not be posted without a route")
+	}
+	if mq.createLogProvider == "stdout" {
+		t.Error("inbound message must never be sent through an ESP provider")
+	}
+}
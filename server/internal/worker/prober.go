@@ -0,0 +1,270 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// Provider health states stored in esp_providers.health_status.
+const (
+	ProviderHealthHealthy   = "healthy"
+	ProviderHealthUnhealthy = "unhealthy"
+)
+
+// ProberConfig controls the provider health prober.
+type ProberConfig struct {
+	// Interval is the delay between probe rounds.
+	Interval time.Duration
+	// Timeout bounds a single provider HealthCheck call.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed checks after
+	// which a provider is marked unhealthy and disabled automatically.
+	FailureThreshold int
+	// HistoryRetention is how long health check history is kept.
+	HistoryRetention time.Duration
+}
+
+// DefaultProberConfig returns sensible defaults for the health prober.
+func DefaultProberConfig() ProberConfig {
+	return ProberConfig{
+		Interval:         1 * time.Minute,
+		Timeout:          10 * time.Second,
+		FailureThreshold: 3,
+		HistoryRetention: 7 * 24 * time.Hour,
+	}
+}
+
+// ProviderProber periodically calls HealthCheck on every enabled provider
+// and on providers it previously auto-disabled. Each result is appended to
+// the provider's health history. A provider is disabled after
+// FailureThreshold consecutive failures and re-enabled by the first
+// successful check afterwards. Providers disabled by an administrator are
+// never probed or re-enabled.
+type ProviderProber struct {
+	queries  storage.Querier
+	build    func(esp *storage.EspProvider) (provider.Provider, error)
+	config   ProberConfig
+	notifier notify.Notifier
+	log      zerolog.Logger
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+// NewProviderProber creates a ProviderProber that builds provider clients
+// with the given HTTP client. Zero-valued config fields fall back to
+// DefaultProberConfig.
+func NewProviderProber(queries storage.Querier, client provider.HTTPClient, cfg ProberConfig, log zerolog.Logger) *ProviderProber {
+	defaults := DefaultProberConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.HistoryRetention <= 0 {
+		cfg.HistoryRetention = defaults.HistoryRetention
+	}
+	return &ProviderProber{
+		queries: queries,
+		build: func(esp *storage.EspProvider) (provider.Provider, error) {
+			return provider.NewProviderFromStorage(esp, client)
+		},
+		config: cfg,
+		log:    log,
+	}
+}
+
+// SetNotifier sends a provider_disabled alert whenever a provider is
+// auto-disabled.
+func (p *ProviderProber) SetNotifier(n notify.Notifier) {
+	p.notifier = n
+}
+
+// Start launches the probe loop in a background goroutine. The first round
+// runs immediately.
+func (p *ProviderProber) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	p.wg.Add(1)
+	go p.run(ctx)
+
+	p.log.Info().
+		Dur("interval", p.config.Interval).
+		Int("failure_threshold", p.config.FailureThreshold).
+		Msg("provider health prober started")
+}
+
+// Stop signals the probe loop to exit and waits for the current round.
+func (p *ProviderProber) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+	p.log.Info().Msg("provider health prober stopped")
+}
+
+func (p *ProviderProber) run(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.ProbeOnce(ctx); err != nil && ctx.Err() == nil {
+			p.log.Error().Err(err).Msg("provider health probe failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeOnce checks every eligible provider once and prunes expired history.
+func (p *ProviderProber) ProbeOnce(ctx context.Context) error {
+	providers, err := p.queries.ListProvidersForHealthCheck(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range providers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		p.probe(ctx, &providers[i])
+	}
+
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-p.config.HistoryRetention), Valid: true}
+	if err := p.queries.DeleteProviderHealthChecksBefore(ctx, cutoff); err != nil {
+		p.log.Warn().Err(err).Msg("failed to prune provider health history")
+	}
+	return nil
+}
+
+// probe runs a single health check and applies the resulting state change.
+func (p *ProviderProber) probe(ctx context.Context, esp *storage.EspProvider) {
+	start := time.Now()
+	checkErr := p.check(ctx, esp)
+	latency := time.Since(start)
+
+	healthy := checkErr == nil
+	var lastError pgtype.Text
+	if !healthy {
+		lastError = pgtype.Text{String: checkErr.Error(), Valid: true}
+	}
+
+	result := "success"
+	if !healthy {
+		result = "failure"
+	}
+	metrics.ProviderHealthChecksTotal.WithLabelValues(string(esp.ProviderType), result).Inc()
+
+	if _, err := p.queries.CreateProviderHealthCheck(ctx, storage.CreateProviderHealthCheckParams{
+		ProviderID: esp.ID,
+		Healthy:    healthy,
+		LatencyMs:  int32(latency.Milliseconds()),
+		Error:      lastError,
+	}); err != nil {
+		p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to record provider health check")
+	}
+
+	failures := int32(0)
+	status := ProviderHealthHealthy
+	if !healthy {
+		failures = esp.ConsecutiveFailures + 1
+		status = esp.HealthStatus
+		if int(failures) >= p.config.FailureThreshold {
+			status = ProviderHealthUnhealthy
+		}
+	}
+
+	if err := p.queries.UpdateProviderHealth(ctx, storage.UpdateProviderHealthParams{
+		ID:                  esp.ID,
+		HealthStatus:        status,
+		ConsecutiveFailures: failures,
+		LastHealthError:     lastError,
+	}); err != nil {
+		p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to update provider health")
+		return
+	}
+
+	switch {
+	case !healthy && esp.Enabled && status == ProviderHealthUnhealthy:
+		if err := p.queries.AutoDisableProvider(ctx, esp.ID); err != nil {
+			p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to auto-disable provider")
+			return
+		}
+		metrics.ProviderAutoToggleTotal.WithLabelValues("disabled").Inc()
+		p.log.Warn().
+			Stringer("provider_id", esp.ID).
+			Stringer("group_id", esp.GroupID).
+			Str("provider", esp.Name).
+			Int32("consecutive_failures", failures).
+			Str("error", lastError.String).
+			Msg("provider auto-disabled after consecutive health check failures")
+		p.alertDisabled(ctx, esp, failures, lastError.String)
+
+	case healthy && !esp.Enabled && esp.AutoDisabledAt.Valid:
+		if err := p.queries.AutoEnableProvider(ctx, esp.ID); err != nil {
+			p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to re-enable provider")
+			return
+		}
+		metrics.ProviderAutoToggleTotal.WithLabelValues("enabled").Inc()
+		p.log.Info().
+			Stringer("provider_id", esp.ID).
+			Stringer("group_id", esp.GroupID).
+			Str("provider", esp.Name).
+			Msg("provider re-enabled after successful health check")
+	}
+}
+
+// check builds the provider client and calls HealthCheck with a timeout.
+// A provider whose configuration cannot be turned into a client counts as
+// a failed check.
+func (p *ProviderProber) check(ctx context.Context, esp *storage.EspProvider) error {
+	prov, err := p.build(esp)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+	return prov.HealthCheck(ctx)
+}
+
+func (p *ProviderProber) alertDisabled(ctx context.Context, esp *storage.EspProvider, failures int32, lastError string) {
+	if p.notifier == nil {
+		return
+	}
+	err := p.notifier.Notify(ctx, notify.Alert{
+		Kind:     notify.KindProviderDisabled,
+		Severity: notify.SeverityCritical,
+		Summary: fmt.Sprintf("provider %s auto-disabled after %d consecutive health check failures: %s",
+			esp.Name, failures, lastError),
+		Labels: map[string]string{
+			"group_id":    esp.GroupID.String(),
+			"provider":    esp.Name,
+			"provider_id": esp.ID.String(),
+		},
+		Values:  map[string]float64{"consecutive_failures": float64(failures)},
+		FiredAt: time.Now(),
+	})
+	if err != nil {
+		p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to send provider disabled alert")
+	}
+}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockHealthProvider returns a fixed HealthCheck result.
+type mockHealthProvider struct {
+	err error
+}
+
+func (m *mockHealthProvider) GetName() string { return "health" }
+func (m *mockHealthProvider) Send(_ context.Context, _ *provider.Message) (*provider.DeliveryResult, error) {
+	return nil, nil
+}
+func (m *mockHealthProvider) HealthCheck(_ context.Context) error { return m.err }
+
+func newTestProber(q *mockQuerier, healthErr error) *ProviderProber {
+	p := NewProviderProber(q, nil, ProberConfig{FailureThreshold: 3}, zerolog.Nop())
+	p.build = func(_ *storage.EspProvider) (provider.Provider, error) {
+		return &mockHealthProvider{err: healthErr}, nil
+	}
+	return p
+}
+
+func TestProviderProber_RecordsHealthyCheck(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Enabled: true, HealthStatus: "unknown", ConsecutiveFailures: 2}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+
+	if err := newTestProber(q, nil).ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	if len(q.healthChecks) != 1 || !q.healthChecks[0].Healthy {
+		t.Fatalf("health checks = %+v, want one healthy entry", q.healthChecks)
+	}
+	if len(q.providerHealth) != 1 {
+		t.Fatalf("health updates = %d, want 1", len(q.providerHealth))
+	}
+	got := q.providerHealth[0]
+	if got.HealthStatus != ProviderHealthHealthy || got.ConsecutiveFailures != 0 || got.LastHealthError.Valid {
+		t.Errorf("health update = %+v, want healthy with failures reset", got)
+	}
+	if len(q.autoDisabledIDs) != 0 || len(q.autoEnabledIDs) != 0 {
+		t.Error("healthy enabled provider should not be toggled")
+	}
+}
+
+func TestProviderProber_FailureBelowThreshold(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Enabled: true, HealthStatus: ProviderHealthHealthy, ConsecutiveFailures: 1}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+
+	if err := newTestProber(q, errors.New("timeout")).ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	got := q.providerHealth[0]
+	if got.HealthStatus != ProviderHealthHealthy || got.ConsecutiveFailures != 2 {
+		t.Errorf("health update = %+v, want still healthy with 2 failures", got)
+	}
+	if got.LastHealthError.String != "timeout" {
+		t.Errorf("LastHealthError = %q, want timeout", got.LastHealthError.String)
+	}
+	if len(q.autoDisabledIDs) != 0 {
+		t.Error("provider should not be disabled below the failure threshold")
+	}
+}
+
+func TestProviderProber_AutoDisablesAtThreshold(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Enabled: true, HealthStatus: ProviderHealthHealthy, ConsecutiveFailures: 2}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+
+	if err := newTestProber(q, errors.New("401 unauthorized")).ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	if q.providerHealth[0].HealthStatus != ProviderHealthUnhealthy {
+		t.Errorf("HealthStatus = %q, want unhealthy", q.providerHealth[0].HealthStatus)
+	}
+	if len(q.autoDisabledIDs) != 1 || q.autoDisabledIDs[0] != esp.ID {
+		t.Errorf("auto-disabled = %v, want [%s]", q.autoDisabledIDs, esp.ID)
+	}
+	if q.healthChecks[0].Healthy || !q.healthChecks[0].Error.Valid {
+		t.Errorf("health check = %+v, want failed entry with error", q.healthChecks[0])
+	}
+}
+
+func TestProviderProber_AlertsOnAutoDisable(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), GroupID: uuid.New(), Name: "sendgrid", Enabled: true, HealthStatus: ProviderHealthHealthy, ConsecutiveFailures: 2}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+	notifier := &mockNotifier{}
+	p := newTestProber(q, errors.New("401 unauthorized"))
+	p.SetNotifier(notifier)
+
+	if err := p.ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("alerts = %d, want 1", len(notifier.alerts))
+	}
+	alert := notifier.alerts[0]
+	if alert.Kind != notify.KindProviderDisabled || alert.Severity != notify.SeverityCritical {
+		t.Errorf("alert = %+v", alert)
+	}
+	if alert.Labels["group_id"] != esp.GroupID.String() || alert.Labels["provider_id"] != esp.ID.String() {
+		t.Errorf("labels = %v", alert.Labels)
+	}
+}
+
+func TestProviderProber_ReenablesAutoDisabledProvider(t *testing.T) {
+	esp := storage.EspProvider{
+		ID:                  uuid.New(),
+		Enabled:             false,
+		HealthStatus:        ProviderHealthUnhealthy,
+		ConsecutiveFailures: 7,
+		AutoDisabledAt:      pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+	}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+
+	if err := newTestProber(q, nil).ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	if len(q.autoEnabledIDs) != 1 || q.autoEnabledIDs[0] != esp.ID {
+		t.Errorf("auto-enabled = %v, want [%s]", q.autoEnabledIDs, esp.ID)
+	}
+}
+
+func TestProviderProber_AutoDisabledProviderStillFailing(t *testing.T) {
+	esp := storage.EspProvider{
+		ID:                  uuid.New(),
+		Enabled:             false,
+		HealthStatus:        ProviderHealthUnhealthy,
+		ConsecutiveFailures: 3,
+		AutoDisabledAt:      pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+
+	if err := newTestProber(q, errors.New("down")).ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	if len(q.autoDisabledIDs) != 0 || len(q.autoEnabledIDs) != 0 {
+		t.Error("already auto-disabled provider should not be toggled while failing")
+	}
+	if q.providerHealth[0].ConsecutiveFailures != 4 {
+		t.Errorf("ConsecutiveFailures = %d, want 4", q.providerHealth[0].ConsecutiveFailures)
+	}
+}
+
+func TestProviderProber_BuildErrorCountsAsFailure(t *testing.T) {
+	esp := storage.EspProvider{ID: uuid.New(), Enabled: true, ProviderType: storage.ProviderTypeSmtp}
+	q := &mockQuerier{healthCheckProviders: []storage.EspProvider{esp}}
+
+	p := newTestProber(q, nil)
+	p.build = func(_ *storage.EspProvider) (provider.Provider, error) {
+		return nil, errors.New("unsupported provider type")
+	}
+	if err := p.ProbeOnce(context.Background()); err != nil {
+		t.Fatalf("ProbeOnce() error = %v", err)
+	}
+
+	if q.healthChecks[0].Healthy {
+		t.Error("expected failed health check when provider cannot be built")
+	}
+}
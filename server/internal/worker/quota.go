@@ -0,0 +1,266 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+	"github.com/sungwon/smtp-proxy/server/internal/sysmail"
+)
+
+// QuotaNotifierConfig controls the monthly limit warning emails.
+type QuotaNotifierConfig struct {
+	// Interval is the delay between usage checks.
+	Interval time.Duration
+	// Thresholds are the percentages of the monthly limit at which group
+	// owners are warned, e.g. 80, 95 and 100.
+	Thresholds []int
+	// From is the sender address of warning emails.
+	From string
+}
+
+// DefaultQuotaNotifierConfig returns sensible defaults for the notifier.
+func DefaultQuotaNotifierConfig() QuotaNotifierConfig {
+	return QuotaNotifierConfig{
+		Interval:   15 * time.Minute,
+		Thresholds: []int{80, 95, 100},
+		From:       "smtp-proxy@localhost",
+	}
+}
+
+// QuotaNotifier periodically compares each group's messages accepted this
+// month against its monthly limit and emails the group's owners when usage
+// crosses a configured threshold. Warnings are rendered from the
+// quota_warning system email template with the group's branding and
+// enqueued through the proxy's own delivery pipeline (messages plus outbox
+// entry) under the system group, so they are delivered by the system
+// group's providers and do not count against the warned group's limit.
+// Each threshold is announced at most once per group and month.
+type QuotaNotifier struct {
+	queries  storage.Querier
+	tx       storage.TxRunner
+	config   QuotaNotifierConfig
+	mailer   *sysmail.Mailer
+	notifier notify.Notifier
+	log      zerolog.Logger
+	now      func() time.Time
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+// NewQuotaNotifier creates a QuotaNotifier. Zero-valued config fields fall
+// back to DefaultQuotaNotifierConfig.
+func NewQuotaNotifier(queries storage.Querier, tx storage.TxRunner, cfg QuotaNotifierConfig, log zerolog.Logger) *QuotaNotifier {
+	defaults := DefaultQuotaNotifierConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if len(cfg.Thresholds) == 0 {
+		cfg.Thresholds = defaults.Thresholds
+	}
+	if cfg.From == "" {
+		cfg.From = defaults.From
+	}
+	thresholds := append([]int(nil), cfg.Thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+	cfg.Thresholds = thresholds
+
+	return &QuotaNotifier{
+		queries: queries,
+		tx:      tx,
+		config:  cfg,
+		mailer:  sysmail.New(queries, tx, sysmail.Config{From: cfg.From}),
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// SetNotifier also sends a quota_near_limit alert to operators whenever a
+// warning email is enqueued.
+func (n *QuotaNotifier) SetNotifier(notifier notify.Notifier) {
+	n.notifier = notifier
+}
+
+// Start launches the check loop in a background goroutine. The first check
+// runs immediately.
+func (n *QuotaNotifier) Start(ctx context.Context) {
+	ctx, n.cancel = context.WithCancel(ctx)
+
+	n.wg.Add(1)
+	go n.run(ctx)
+
+	n.log.Info().
+		Dur("interval", n.config.Interval).
+		Ints("thresholds", n.config.Thresholds).
+		Msg("quota notifier started")
+}
+
+// Stop signals the check loop to exit and waits for the current check.
+func (n *QuotaNotifier) Stop() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	n.wg.Wait()
+	n.log.Info().Msg("quota notifier stopped")
+}
+
+func (n *QuotaNotifier) run(ctx context.Context) {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.CheckOnce(ctx); err != nil && ctx.Err() == nil {
+			n.log.Error().Err(err).Msg("quota check failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce warns the owners of every group whose usage this month has
+// crossed a threshold that has not been announced yet. Only the highest
+// crossed threshold is sent, so a group that jumps from 70% to 100%
+// receives a single email.
+func (n *QuotaNotifier) CheckOnce(ctx context.Context) error {
+	now := n.now().UTC()
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	usage, err := n.queries.ListGroupMonthlyUsage(ctx, pgtype.Timestamptz{Time: period, Valid: true})
+	if err != nil {
+		return fmt.Errorf("list group usage: %w", err)
+	}
+
+	for _, u := range usage {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		threshold, ok := n.crossed(u.Sent, u.MonthlyLimit)
+		if !ok {
+			continue
+		}
+		n.warn(ctx, u, threshold, period)
+	}
+	return nil
+}
+
+// crossed returns the highest configured threshold reached by sent.
+func (n *QuotaNotifier) crossed(sent int64, limit int32) (int, bool) {
+	if limit <= 0 {
+		return 0, false
+	}
+	for _, t := range n.config.Thresholds {
+		if sent*100 >= int64(t)*int64(limit) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+func (n *QuotaNotifier) warn(ctx context.Context, u storage.ListGroupMonthlyUsageRow, threshold int, period time.Time) {
+	log := n.log.With().
+		Stringer("group_id", u.ID).
+		Str("group", u.Name).
+		Int("threshold", threshold).
+		Logger()
+
+	owners, err := n.queries.ListGroupOwnerEmails(ctx, u.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list group owners for quota warning")
+		return
+	}
+	if len(owners) == 0 {
+		log.Warn().Msg("group has no active owners, skipping quota warning")
+		return
+	}
+
+	msg, err := n.mailer.Compose(ctx, u.ID, owners, sysmail.TemplateQuotaWarning, sysmail.Data{
+		Group:     u.Name,
+		Sent:      u.Sent,
+		Limit:     u.MonthlyLimit,
+		Percent:   u.Sent * 100 / int64(u.MonthlyLimit),
+		Threshold: threshold,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to render quota warning")
+		return
+	}
+
+	sent := false
+	err = n.tx.ExecTx(ctx, func(q storage.Querier) error {
+		recorded, err := q.RecordQuotaNotification(ctx, storage.RecordQuotaNotificationParams{
+			GroupID:   u.ID,
+			Period:    pgtype.Date{Time: period, Valid: true},
+			Threshold: int32(threshold),
+		})
+		if err != nil {
+			return fmt.Errorf("record quota notification: %w", err)
+		}
+		if recorded == 0 {
+			// Already announced this period.
+			return nil
+		}
+
+		if err := n.mailer.Enqueue(ctx, q, msg); err != nil {
+			return err
+		}
+		sent = true
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to enqueue quota warning")
+		return
+	}
+	if sent {
+		metrics.QuotaWarningsTotal.WithLabelValues(strconv.Itoa(threshold)).Inc()
+		log.Info().
+			Int64("sent", u.Sent).
+			Int32("monthly_limit", u.MonthlyLimit).
+			Int("recipients", len(owners)).
+			Msg("quota warning enqueued")
+		n.alert(ctx, u, threshold)
+	}
+}
+
+func (n *QuotaNotifier) alert(ctx context.Context, u storage.ListGroupMonthlyUsageRow, threshold int) {
+	if n.notifier == nil {
+		return
+	}
+	severity := notify.SeverityWarning
+	if threshold >= 100 {
+		severity = notify.SeverityCritical
+	}
+	err := n.notifier.Notify(ctx, notify.Alert{
+		Kind:     notify.KindQuotaNearLimit,
+		Severity: severity,
+		Summary: fmt.Sprintf("group %s has sent %d of %d messages allowed this month (%d%% threshold)",
+			u.Name, u.Sent, u.MonthlyLimit, threshold),
+		Labels: map[string]string{
+			"group_id": u.ID.String(),
+			"group":    u.Name,
+		},
+		Values: map[string]float64{
+			"sent":              float64(u.Sent),
+			"monthly_limit":     float64(u.MonthlyLimit),
+			"threshold_percent": float64(threshold),
+		},
+		FiredAt: n.now(),
+	})
+	if err != nil {
+		n.log.Error().Err(err).Stringer("group_id", u.ID).Msg("failed to send quota alert")
+	}
+}
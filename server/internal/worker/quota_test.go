@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockTxRunner runs transactions directly against a Querier.
+type mockTxRunner struct {
+	queries storage.Querier
+}
+
+func (r *mockTxRunner) ExecTx(_ context.Context, fn func(storage.Querier) error) error {
+	return fn(r.queries)
+}
+
+func newTestQuotaNotifier(q *mockQuerier, now time.Time) *QuotaNotifier {
+	n := NewQuotaNotifier(q, &mockTxRunner{queries: q}, QuotaNotifierConfig{
+		Thresholds: []int{80, 95, 100},
+		From:       "noreply@proxy.example",
+	}, zerolog.Nop())
+	n.now = func() time.Time { return now }
+	return n
+}
+
+func TestQuotaNotifier_CheckOnce_WarnsOnce(t *testing.T) {
+	groupID := uuid.New()
+	q := &mockQuerier{
+		monthlyUsage: []storage.ListGroupMonthlyUsageRow{
+			{ID: groupID, Name: "acme", MonthlyLimit: 1000, Sent: 960},
+			{ID: uuid.New(), Name: "quiet", MonthlyLimit: 1000, Sent: 100},
+		},
+		ownerEmails: []string{"owner@acme.example"},
+	}
+	n := newTestQuotaNotifier(q, time.Date(2026, 9, 17, 8, 0, 0, 0, time.UTC))
+
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(q.enqueuedMessages) != 1 || len(q.outboxEntries) != 1 {
+		t.Fatalf("expected 1 warning enqueued, got %d messages and %d outbox entries",
+			len(q.enqueuedMessages), len(q.outboxEntries))
+	}
+
+	msg := q.enqueuedMessages[0]
+	if !strings.Contains(msg.Subject.String, "95%") {
+		t.Errorf("expected 95%% warning, got subject %q", msg.Subject.String)
+	}
+	var recipients []string
+	if err := json.Unmarshal(msg.Recipients, &recipients); err != nil || len(recipients) != 1 || recipients[0] != "owner@acme.example" {
+		t.Errorf("unexpected recipients %s", msg.Recipients)
+	}
+	if msg.Sender != "noreply@proxy.example" || !strings.Contains(msg.Body.String, "960 of 1000") {
+		t.Errorf("unexpected message: sender %q, body %q", msg.Sender, msg.Body.String)
+	}
+
+	// The same threshold is not announced again in the same month.
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(q.enqueuedMessages) != 1 {
+		t.Errorf("expected no repeat warning, got %d messages", len(q.enqueuedMessages))
+	}
+
+	// Crossing the next threshold sends another warning.
+	q.monthlyUsage[0].Sent = 1000
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(q.enqueuedMessages) != 2 || !strings.Contains(q.enqueuedMessages[1].Subject.String, "reached") {
+		t.Errorf("expected limit reached warning, got %+v", q.enqueuedMessages)
+	}
+}
+
+func TestQuotaNotifier_CheckOnce_Branding(t *testing.T) {
+	groupID := uuid.New()
+	q := &mockQuerier{
+		monthlyUsage: []storage.ListGroupMonthlyUsageRow{{ID: groupID, Name: "acme", MonthlyLimit: 1000, Sent: 850}},
+		ownerEmails:  []string{"owner@acme.example"},
+		groupBranding: map[uuid.UUID]storage.GroupBranding{groupID: {
+			GroupID:     groupID,
+			FromAddress: "noreply@acme.example",
+			Footer:      "Acme Inc.",
+			Templates:   []byte(`{"quota_warning":{"subject":"{{.Group}} at {{.Percent}}%"}}`),
+		}},
+	}
+	n := newTestQuotaNotifier(q, time.Date(2026, 9, 17, 8, 0, 0, 0, time.UTC))
+
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(q.enqueuedMessages) != 1 {
+		t.Fatalf("expected 1 warning enqueued, got %d", len(q.enqueuedMessages))
+	}
+	msg := q.enqueuedMessages[0]
+	if msg.Sender != "noreply@acme.example" || msg.Subject.String != "acme at 85%" {
+		t.Errorf("branding not applied: sender %q, subject %q", msg.Sender, msg.Subject.String)
+	}
+	if !strings.Contains(msg.Body.String, "850 of 1000") || !strings.Contains(msg.Body.String, "-- \r\nAcme Inc.") {
+		t.Errorf("unexpected body %q", msg.Body.String)
+	}
+}
+
+func TestQuotaNotifier_CheckOnce_Alerts(t *testing.T) {
+	groupID := uuid.New()
+	q := &mockQuerier{
+		monthlyUsage: []storage.ListGroupMonthlyUsageRow{{ID: groupID, Name: "acme", MonthlyLimit: 1000, Sent: 1000}},
+		ownerEmails:  []string{"owner@acme.example"},
+	}
+	notifier := &mockNotifier{}
+	n := newTestQuotaNotifier(q, time.Date(2026, 9, 17, 8, 0, 0, 0, time.UTC))
+	n.SetNotifier(notifier)
+
+	for i := 0; i < 2; i++ {
+		if err := n.CheckOnce(context.Background()); err != nil {
+			t.Fatalf("CheckOnce() error = %v", err)
+		}
+	}
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("alerts = %d, want one per announced threshold", len(notifier.alerts))
+	}
+	alert := notifier.alerts[0]
+	if alert.Kind != notify.KindQuotaNearLimit || alert.Severity != notify.SeverityCritical || alert.Labels["group_id"] != groupID.String() {
+		t.Errorf("alert = %+v", alert)
+	}
+}
+
+func TestQuotaNotifier_CheckOnce_NoOwners(t *testing.T) {
+	q := &mockQuerier{
+		monthlyUsage: []storage.ListGroupMonthlyUsageRow{
+			{ID: uuid.New(), Name: "acme", MonthlyLimit: 10, Sent: 10},
+		},
+	}
+	n := newTestQuotaNotifier(q, time.Now())
+
+	if err := n.CheckOnce(context.Background()); err != nil {
+		t.Fatalf("CheckOnce() error = %v", err)
+	}
+	if len(q.enqueuedMessages) != 0 || len(q.quotaNotified) != 0 {
+		t.Errorf("expected no warning without owners, got %d messages", len(q.enqueuedMessages))
+	}
+}
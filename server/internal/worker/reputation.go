@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/dnsbl"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// ReputationPolicy decides when the account poller pauses a provider.
+// A zero threshold disables its check.
+type ReputationPolicy struct {
+	// Action is provider.PauseActionPause or provider.PauseActionThrottle.
+	Action string
+	// MaxBounceRate and MaxComplaintRate are the highest rates (0.05 ==
+	// 5%) a provider may report.
+	MaxBounceRate    float64
+	MaxComplaintRate float64
+	// MinReputation is the lowest reputation score (0-100) a provider may
+	// report.
+	MinReputation float64
+	// BlocklistZones are the DNSBLs the provider's relay IPs are checked
+	// against. Nil uses dnsbl.DefaultZones.
+	BlocklistZones []string
+	// Resolver queries the blocklists.
+	Resolver dnsbl.Resolver
+}
+
+// degradedEnforcementStatuses are ESP account standings that pause a
+// provider.
+var degradedEnforcementStatuses = map[string]bool{
+	"PROBATION": true,
+	"SHUTDOWN":  true,
+}
+
+// SetReputationPolicy makes the poller pause providers whose reputation
+// degrades according to policy, and resume them once it recovers.
+func (p *AccountPoller) SetReputationPolicy(policy ReputationPolicy) {
+	if policy.Action != provider.PauseActionThrottle {
+		policy.Action = provider.PauseActionPause
+	}
+	if policy.BlocklistZones == nil {
+		policy.BlocklistZones = dnsbl.DefaultZones
+	}
+	p.reputation = &policy
+}
+
+// SetNotifier sends a reputation_degraded alert whenever a provider is
+// paused or resumed.
+func (p *AccountPoller) SetNotifier(n notify.Notifier) {
+	p.notifier = n
+}
+
+// reputationIssues returns why the provider's reputation is degraded, or
+// nil when it is not.
+func (p *AccountPoller) reputationIssues(ctx context.Context, esp *storage.EspProvider, stats *provider.AccountStats) []string {
+	policy := p.reputation
+	var issues []string
+	if degradedEnforcementStatuses[stats.EnforcementStatus] {
+		issues = append(issues, "account enforcement status "+stats.EnforcementStatus)
+	}
+	if policy.MaxBounceRate > 0 && stats.BounceRate != nil && *stats.BounceRate > policy.MaxBounceRate {
+		issues = append(issues, fmt.Sprintf("bounce rate %.2f%% above %.2f%%", *stats.BounceRate*100, policy.MaxBounceRate*100))
+	}
+	if policy.MaxComplaintRate > 0 && stats.ComplaintRate != nil && *stats.ComplaintRate > policy.MaxComplaintRate {
+		issues = append(issues, fmt.Sprintf("complaint rate %.3f%% above %.3f%%", *stats.ComplaintRate*100, policy.MaxComplaintRate*100))
+	}
+	if policy.MinReputation > 0 && stats.Reputation != nil && *stats.Reputation < policy.MinReputation {
+		issues = append(issues, fmt.Sprintf("reputation %.1f below %.1f", *stats.Reputation, policy.MinReputation))
+	}
+	if policy.Resolver == nil {
+		return issues
+	}
+	for _, ip := range provider.RelayIPs(esp) {
+		for _, zone := range policy.BlocklistZones {
+			res, err := dnsbl.Lookup(ctx, policy.Resolver, ip, zone)
+			if err != nil {
+				// An unanswered lookup neither pauses nor resumes.
+				p.log.Warn().Err(err).
+					Stringer("provider_id", esp.ID).
+					Stringer("ip", ip).
+					Msg("blocklist lookup failed")
+				continue
+			}
+			if res.Listed {
+				issues = append(issues, fmt.Sprintf("relay IP %s listed on %s (%s)", ip, zone, strings.Join(res.Codes, ", ")))
+			}
+		}
+	}
+	return issues
+}
+
+// applyReputation pauses, updates or resumes the provider according to
+// the stats just polled. row is the stored snapshot, carrying the current
+// pause.
+func (p *AccountPoller) applyReputation(ctx context.Context, esp *storage.EspProvider, stats *provider.AccountStats, row storage.ProviderAccountStat) {
+	id := esp.ID.String()
+	if stats.Reputation != nil {
+		metrics.ProviderReputationScore.WithLabelValues(id, esp.Name).Set(*stats.Reputation)
+	}
+	if p.reputation == nil {
+		return
+	}
+
+	reason := strings.Join(p.reputationIssues(ctx, esp, stats), "; ")
+	wasPaused := row.PauseReason.Valid
+	if reason == row.PauseReason.String && (reason != "") == wasPaused {
+		return
+	}
+
+	params := storage.UpdateProviderReputationPauseParams{ProviderID: esp.ID}
+	if reason != "" {
+		params.PauseReason = pgtype.Text{String: reason, Valid: true}
+		params.PauseAction = pgtype.Text{String: p.reputation.Action, Valid: true}
+	}
+	if err := p.queries.UpdateProviderReputationPause(ctx, params); err != nil {
+		p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to update provider reputation pause")
+		return
+	}
+
+	switch {
+	case reason != "" && !wasPaused:
+		metrics.ProviderReputationPaused.WithLabelValues(id, esp.Name).Set(1)
+		p.log.Warn().
+			Stringer("provider_id", esp.ID).
+			Stringer("group_id", esp.GroupID).
+			Str("provider", esp.Name).
+			Str("action", p.reputation.Action).
+			Str("reason", reason).
+			Msg("provider paused for degraded sender reputation")
+		p.alertReputation(ctx, esp, notify.SeverityCritical,
+			fmt.Sprintf("provider %s %sd for degraded sender reputation: %s", esp.Name, p.reputation.Action, reason))
+	case reason == "":
+		metrics.ProviderReputationPaused.WithLabelValues(id, esp.Name).Set(0)
+		p.log.Info().
+			Stringer("provider_id", esp.ID).
+			Stringer("group_id", esp.GroupID).
+			Str("provider", esp.Name).
+			Msg("provider resumed after sender reputation recovered")
+		p.alertReputation(ctx, esp, notify.SeverityInfo,
+			fmt.Sprintf("provider %s resumed: sender reputation recovered", esp.Name))
+	}
+}
+
+func (p *AccountPoller) alertReputation(ctx context.Context, esp *storage.EspProvider, severity, summary string) {
+	if p.notifier == nil {
+		return
+	}
+	err := p.notifier.Notify(ctx, notify.Alert{
+		Kind:     notify.KindReputationDegraded,
+		Severity: severity,
+		Summary:  summary,
+		Labels: map[string]string{
+			"group_id":    esp.GroupID.String(),
+			"provider":    esp.Name,
+			"provider_id": esp.ID.String(),
+		},
+		FiredAt: time.Now(),
+	})
+	if err != nil {
+		p.log.Error().Err(err).Stringer("provider_id", esp.ID).Msg("failed to send reputation alert")
+	}
+}
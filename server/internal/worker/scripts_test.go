@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/scripting"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// pinResolver records which resolution path the handler took.
+type pinResolver struct {
+	provider   provider.Provider
+	resolved   bool
+	resolvedID uuid.UUID
+}
+
+func (r *pinResolver) Resolve(context.Context, uuid.UUID) (provider.Provider, error) {
+	r.resolved = true
+	return r.provider, nil
+}
+
+func (r *pinResolver) ResolveByID(_ context.Context, _, providerID uuid.UUID) (provider.Provider, error) {
+	r.resolvedID = providerID
+	return r.provider, nil
+}
+
+func newScriptHandler(mq *mockQuerier, resolver providerResolver, sources ...string) *Handler {
+	for i, src := range sources {
+		mq.scripts = append(mq.scripts, storage.MessageScript{
+			ID:        uuid.New(),
+			Name:      "script" + string(rune('a'+i)),
+			Source:    src,
+			Enabled:   true,
+			UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		})
+	}
+	h := &Handler{resolver: resolver, queries: mq, log: zerolog.Nop()}
+	h.SetScripts(scripting.NewEngine(mq, scripting.Config{}, zerolog.Nop()))
+	return h
+}
+
+func TestHandler_HandleMessage_ScriptModifiesHeaders(t *testing.T) {
+	groupID, userID := uuid.New(), uuid.New()
+	mq := &mockQuerier{
+		getMessageFn: func(context.Context, uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	capture := &mockCaptureProvider{}
+	h := newScriptHandler(mq, &mockCaptureResolver{provider: capture},
+		`function process(msg) msg.headers["X-Script"] = "yes" end`)
+
+	if err := h.HandleMessage(context.Background(), &queue.Message{ID: uuid.NewString(), Body: []byte("Hello")}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if capture.captured == nil || capture.captured.Headers["X-Script"] != "yes" {
+		t.Errorf("provider received %+v, want the script's header", capture.captured)
+	}
+}
+
+func TestHandler_HandleMessage_ScriptRejects(t *testing.T) {
+	groupID, userID := uuid.New(), uuid.New()
+	mq := &mockQuerier{
+		getMessageFn: func(context.Context, uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+	}
+	capture := &mockCaptureProvider{}
+	h := newScriptHandler(mq, &mockCaptureResolver{provider: capture},
+		`function process(msg) return {reject = "blocked recipient"} end`)
+
+	if err := h.HandleMessage(context.Background(), &queue.Message{ID: uuid.NewString(), Body: []byte("Hello")}); err != nil {
+		t.Fatalf("expected a rejection not to be retried, got %v", err)
+	}
+	if capture.captured != nil {
+		t.Error("rejected message was sent")
+	}
+	if mq.statuses[len(mq.statuses)-1] != storage.MessageStatusFailed {
+		t.Errorf("expected final status failed, got %s", mq.statuses[len(mq.statuses)-1])
+	}
+	if !strings.Contains(mq.createLogParams.LastError.String, "blocked recipient") {
+		t.Errorf("failure log error = %q, want the script's reason", mq.createLogParams.LastError.String)
+	}
+}
+
+func TestHandler_HandleMessage_ScriptChoosesProvider(t *testing.T) {
+	groupID, userID := uuid.New(), uuid.New()
+	euID := uuid.New()
+	mq := &mockQuerier{
+		getMessageFn: func(context.Context, uuid.UUID) (storage.Message, error) {
+			return newTestDBMessage(groupID, userID), nil
+		},
+		listProvidersFn: func(context.Context, uuid.UUID) ([]storage.EspProvider, error) {
+			return []storage.EspProvider{{ID: uuid.New(), Name: "us"}, {ID: euID, Name: "eu"}}, nil
+		},
+	}
+	resolver := &pinResolver{provider: &mockCaptureProvider{}}
+	h := newScriptHandler(mq, resolver, `function process(msg) return {provider = "eu"} end`)
+
+	if err := h.HandleMessage(context.Background(), &queue.Message{ID: uuid.NewString(), Body: []byte("Hello")}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resolver.resolved || resolver.resolvedID != euID {
+		t.Errorf("resolved = %v, resolvedID = %s, want provider %s", resolver.resolved, resolver.resolvedID, euID)
+	}
+}
+
+func TestHandler_HandleMessage_ScriptErrors(t *testing.T) {
+	tests := map[string]string{
+		"runtime error":    `function process(msg) error("boom") end`,
+		"unknown provider": `function process(msg) return {provider = "missing"} end`,
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			groupID, userID := uuid.New(), uuid.New()
+			mq := &mockQuerier{
+				getMessageFn: func(context.Context, uuid.UUID) (storage.Message, error) {
+					return newTestDBMessage(groupID, userID), nil
+				},
+			}
+			capture := &mockCaptureProvider{}
+			h := newScriptHandler(mq, &mockCaptureResolver{provider: capture}, src)
+
+			err := h.HandleMessage(context.Background(), &queue.Message{ID: uuid.NewString(), Body: []byte("Hello")})
+			if err == nil {
+				t.Fatal("expected an error so the message is retried")
+			}
+			if capture.captured != nil {
+				t.Error("message was sent despite the script failing")
+			}
+		})
+	}
+}
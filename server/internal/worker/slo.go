@@ -0,0 +1,279 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// SLOConfig controls how delivery latency SLOs are evaluated. Latency is
+// measured from SMTP accept (messages.enqueued_at) to provider delivery.
+type SLOConfig struct {
+	// Interval is the delay between evaluations.
+	Interval time.Duration
+	// Window is the rolling window over which percentiles are computed.
+	Window time.Duration
+	// P50, P95 and P99 are the latency thresholds for each percentile.
+	// A zero threshold disables alerting on that percentile.
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	// MinSamples is the minimum number of deliveries in the window before
+	// a group/provider pair is evaluated, to avoid alerting on noise.
+	MinSamples int
+	// AlertCooldown suppresses repeat notifications for the same breach.
+	AlertCooldown time.Duration
+}
+
+// DefaultSLOConfig returns sensible defaults for the SLO monitor.
+func DefaultSLOConfig() SLOConfig {
+	return SLOConfig{
+		Interval:      1 * time.Minute,
+		Window:        1 * time.Hour,
+		P95:           5 * time.Minute,
+		P99:           15 * time.Minute,
+		MinSamples:    20,
+		AlertCooldown: 30 * time.Minute,
+	}
+}
+
+// SLOBreach describes a percentile that exceeded its threshold.
+type SLOBreach struct {
+	GroupID   uuid.UUID
+	Provider  string
+	Quantile  string
+	Observed  time.Duration
+	Threshold time.Duration
+	Samples   int64
+}
+
+// key identifies the group/provider/percentile a breach is about.
+func (b SLOBreach) key() string {
+	return b.GroupID.String() + "/" + b.Provider + "/" + b.Quantile
+}
+
+// SLOMonitor periodically computes rolling delivery latency percentiles per
+// group and provider, exports them as metrics, and notifies operators when
+// a configured threshold is breached.
+type SLOMonitor struct {
+	queries  storage.Querier
+	notifier notify.Notifier
+	config   SLOConfig
+	log      zerolog.Logger
+	now      func() time.Time
+
+	mu        sync.Mutex
+	lastAlert map[string]time.Time
+	// firing holds the keys breached at the last evaluation, so a breach
+	// is counted once when it starts rather than on every evaluation.
+	firing map[string]bool
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewSLOMonitor creates an SLOMonitor. notifier may be nil, in which case
+// breaches are only logged and counted. Zero-valued Interval, Window,
+// MinSamples and AlertCooldown fall back to DefaultSLOConfig.
+func NewSLOMonitor(queries storage.Querier, notifier notify.Notifier, cfg SLOConfig, log zerolog.Logger) *SLOMonitor {
+	defaults := DefaultSLOConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaults.Window
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = defaults.MinSamples
+	}
+	if cfg.AlertCooldown <= 0 {
+		cfg.AlertCooldown = defaults.AlertCooldown
+	}
+	return &SLOMonitor{
+		queries:   queries,
+		notifier:  notifier,
+		config:    cfg,
+		log:       log,
+		now:       time.Now,
+		lastAlert: make(map[string]time.Time),
+		firing:    make(map[string]bool),
+	}
+}
+
+// Start launches the evaluation loop in a background goroutine.
+func (m *SLOMonitor) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	m.wg.Add(1)
+	go m.run(ctx)
+
+	m.log.Info().
+		Dur("interval", m.config.Interval).
+		Dur("window", m.config.Window).
+		Dur("p50", m.config.P50).
+		Dur("p95", m.config.P95).
+		Dur("p99", m.config.P99).
+		Msg("delivery SLO monitor started")
+}
+
+// Stop signals the evaluation loop to exit and waits for it to finish.
+func (m *SLOMonitor) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+	m.log.Info().Msg("delivery SLO monitor stopped")
+}
+
+func (m *SLOMonitor) run(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.EvaluateOnce(ctx); err != nil && ctx.Err() == nil {
+				m.log.Error().Err(err).Msg("SLO evaluation failed")
+			}
+		}
+	}
+}
+
+// EvaluateOnce computes percentiles over the rolling window, updates the
+// latency gauges and notifies on every breach not within its cooldown.
+// slo_breaches_total counts breaches that were not already ongoing at the
+// previous evaluation. It returns all breaches found, including those whose
+// alert was suppressed.
+func (m *SLOMonitor) EvaluateOnce(ctx context.Context) ([]SLOBreach, error) {
+	now := m.now()
+	rows, err := m.queries.DeliveryLatencyPercentiles(ctx, pgtype.Timestamptz{Time: now.Add(-m.config.Window), Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("compute delivery latency percentiles: %w", err)
+	}
+
+	// Drop series for group/provider pairs that left the window.
+	metrics.SLODeliveryLatency.Reset()
+
+	var breaches []SLOBreach
+	firing := make(map[string]bool)
+	for _, row := range rows {
+		groupID := uuid.UUID(row.GroupID.Bytes)
+		providerName := row.Provider.String
+
+		observed := []struct {
+			quantile  string
+			seconds   float64
+			threshold time.Duration
+		}{
+			{"p50", row.P50Seconds, m.config.P50},
+			{"p95", row.P95Seconds, m.config.P95},
+			{"p99", row.P99Seconds, m.config.P99},
+		}
+
+		for _, o := range observed {
+			metrics.SLODeliveryLatency.WithLabelValues(groupID.String(), providerName, o.quantile).Set(o.seconds)
+		}
+
+		if row.Deliveries < int64(m.config.MinSamples) {
+			continue
+		}
+
+		for _, o := range observed {
+			if o.threshold <= 0 || o.seconds <= o.threshold.Seconds() {
+				continue
+			}
+			breach := SLOBreach{
+				GroupID:   groupID,
+				Provider:  providerName,
+				Quantile:  o.quantile,
+				Observed:  time.Duration(o.seconds * float64(time.Second)),
+				Threshold: o.threshold,
+				Samples:   row.Deliveries,
+			}
+			breaches = append(breaches, breach)
+			firing[breach.key()] = true
+			m.mu.Lock()
+			started := !m.firing[breach.key()]
+			m.mu.Unlock()
+			if started {
+				metrics.SLOBreachesTotal.WithLabelValues(o.quantile).Inc()
+			}
+			m.alert(ctx, breach, now)
+		}
+	}
+
+	m.mu.Lock()
+	m.firing = firing
+	m.mu.Unlock()
+
+	return breaches, nil
+}
+
+// alert logs the breach and notifies unless an alert for the same
+// group/provider/percentile was sent within the cooldown.
+func (m *SLOMonitor) alert(ctx context.Context, b SLOBreach, now time.Time) {
+	m.log.Warn().
+		Stringer("group_id", b.GroupID).
+		Str("provider", b.Provider).
+		Str("quantile", b.Quantile).
+		Dur("observed", b.Observed).
+		Dur("threshold", b.Threshold).
+		Int64("samples", b.Samples).
+		Msg("delivery latency SLO breached")
+
+	if m.notifier == nil {
+		return
+	}
+
+	key := b.key()
+	m.mu.Lock()
+	last, seen := m.lastAlert[key]
+	if seen && now.Sub(last) < m.config.AlertCooldown {
+		m.mu.Unlock()
+		return
+	}
+	m.lastAlert[key] = now
+	m.mu.Unlock()
+
+	err := m.notifier.Notify(ctx, notify.Alert{
+		Kind: "slo_breach",
+		Summary: fmt.Sprintf("%s delivery latency %s exceeds %s (group %s, provider %s)",
+			b.Quantile, b.Observed.Round(time.Second), b.Threshold, b.GroupID, b.Provider),
+		Labels: map[string]string{
+			"group_id": b.GroupID.String(),
+			"provider": b.Provider,
+			"quantile": b.Quantile,
+		},
+		Values: map[string]float64{
+			"observed_seconds":  b.Observed.Seconds(),
+			"threshold_seconds": b.Threshold.Seconds(),
+			"samples":           float64(b.Samples),
+			"window_seconds":    m.config.Window.Seconds(),
+		},
+		FiredAt: now,
+	})
+	if err != nil {
+		metrics.SLOAlertsTotal.WithLabelValues("failure").Inc()
+		m.log.Error().Err(err).Str("key", key).Msg("failed to send SLO breach notification")
+
+		// Allow the next evaluation to retry the notification.
+		m.mu.Lock()
+		delete(m.lastAlert, key)
+		m.mu.Unlock()
+		return
+	}
+	metrics.SLOAlertsTotal.WithLabelValues("success").Inc()
+}
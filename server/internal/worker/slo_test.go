@@ -0,0 +1,225 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/notify"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockNotifier records alerts passed to Notify.
+type mockNotifier struct {
+	err    error
+	alerts []notify.Alert
+}
+
+func (m *mockNotifier) Notify(_ context.Context, alert notify.Alert) error {
+	m.alerts = append(m.alerts, alert)
+	return m.err
+}
+
+func latencyRow(groupID uuid.UUID, providerName string, n int64, p50, p95, p99 float64) storage.DeliveryLatencyPercentilesRow {
+	return storage.DeliveryLatencyPercentilesRow{
+		GroupID:    pgtype.UUID{Bytes: groupID, Valid: true},
+		Provider:   sql.NullString{String: providerName, Valid: true},
+		Deliveries: n,
+		P50Seconds: p50,
+		P95Seconds: p95,
+		P99Seconds: p99,
+	}
+}
+
+func TestSLOMonitor_EvaluateOnce_DetectsBreach(t *testing.T) {
+	groupID := uuid.New()
+	var since pgtype.Timestamptz
+	q := &mockQuerier{
+		deliveryLatencyFn: func(_ context.Context, deliveredAt pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+			since = deliveredAt
+			return []storage.DeliveryLatencyPercentilesRow{
+				latencyRow(groupID, "sendgrid", 100, 10, 400, 600),
+			}, nil
+		},
+	}
+	n := &mockNotifier{}
+	m := NewSLOMonitor(q, n, SLOConfig{
+		Window:     time.Hour,
+		P95:        5 * time.Minute,
+		P99:        15 * time.Minute,
+		MinSamples: 10,
+	}, zerolog.Nop())
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	breaches, err := m.EvaluateOnce(context.Background())
+	if err != nil {
+		t.Fatalf("EvaluateOnce() error = %v", err)
+	}
+	if !since.Time.Equal(now.Add(-time.Hour)) {
+		t.Errorf("window start = %v, want %v", since.Time, now.Add(-time.Hour))
+	}
+	if len(breaches) != 1 {
+		t.Fatalf("breaches = %d, want 1", len(breaches))
+	}
+	b := breaches[0]
+	if b.Quantile != "p95" || b.GroupID != groupID || b.Provider != "sendgrid" {
+		t.Errorf("breach = %+v", b)
+	}
+	if b.Observed != 400*time.Second {
+		t.Errorf("Observed = %v, want 400s", b.Observed)
+	}
+	if len(n.alerts) != 1 {
+		t.Fatalf("alerts = %d, want 1", len(n.alerts))
+	}
+	if n.alerts[0].Labels["quantile"] != "p95" || n.alerts[0].Values["threshold_seconds"] != 300 {
+		t.Errorf("alert = %+v", n.alerts[0])
+	}
+}
+
+func TestSLOMonitor_EvaluateOnce_SkipsBelowMinSamples(t *testing.T) {
+	q := &mockQuerier{
+		deliveryLatencyFn: func(context.Context, pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+			return []storage.DeliveryLatencyPercentilesRow{
+				latencyRow(uuid.New(), "ses", 3, 900, 900, 900),
+			}, nil
+		},
+	}
+	n := &mockNotifier{}
+	m := NewSLOMonitor(q, n, SLOConfig{P50: time.Minute, MinSamples: 10}, zerolog.Nop())
+
+	breaches, err := m.EvaluateOnce(context.Background())
+	if err != nil {
+		t.Fatalf("EvaluateOnce() error = %v", err)
+	}
+	if len(breaches) != 0 || len(n.alerts) != 0 {
+		t.Errorf("breaches = %d, alerts = %d, want 0/0", len(breaches), len(n.alerts))
+	}
+}
+
+func TestSLOMonitor_EvaluateOnce_AlertCooldown(t *testing.T) {
+	groupID := uuid.New()
+	q := &mockQuerier{
+		deliveryLatencyFn: func(context.Context, pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+			return []storage.DeliveryLatencyPercentilesRow{
+				latencyRow(groupID, "mailgun", 50, 10, 20, 1200),
+			}, nil
+		},
+	}
+	n := &mockNotifier{}
+	m := NewSLOMonitor(q, n, SLOConfig{
+		P99:           10 * time.Minute,
+		MinSamples:    1,
+		AlertCooldown: 30 * time.Minute,
+	}, zerolog.Nop())
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.EvaluateOnce(context.Background()); err != nil {
+			t.Fatalf("EvaluateOnce() error = %v", err)
+		}
+	}
+	if len(n.alerts) != 1 {
+		t.Errorf("alerts within cooldown = %d, want 1", len(n.alerts))
+	}
+
+	now = now.Add(31 * time.Minute)
+	if _, err := m.EvaluateOnce(context.Background()); err != nil {
+		t.Fatalf("EvaluateOnce() error = %v", err)
+	}
+	if len(n.alerts) != 2 {
+		t.Errorf("alerts after cooldown = %d, want 2", len(n.alerts))
+	}
+}
+
+// counterValue returns the current value of c.
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestSLOMonitor_EvaluateOnce_CountsBreachOnce(t *testing.T) {
+	groupID := uuid.New()
+	p50 := 900.0
+	q := &mockQuerier{
+		deliveryLatencyFn: func(context.Context, pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+			return []storage.DeliveryLatencyPercentilesRow{
+				latencyRow(groupID, "ses", 50, p50, p50, p50),
+			}, nil
+		},
+	}
+	m := NewSLOMonitor(q, nil, SLOConfig{P50: time.Minute, MinSamples: 1}, zerolog.Nop())
+	counter := metrics.SLOBreachesTotal.WithLabelValues("p50")
+	start := counterValue(t, counter)
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.EvaluateOnce(context.Background()); err != nil {
+			t.Fatalf("EvaluateOnce() error = %v", err)
+		}
+	}
+	if got := counterValue(t, counter) - start; got != 1 {
+		t.Errorf("breaches counted during one ongoing breach = %v, want 1", got)
+	}
+
+	// Recovery and a new breach count again.
+	p50 = 10
+	if _, err := m.EvaluateOnce(context.Background()); err != nil {
+		t.Fatalf("EvaluateOnce() error = %v", err)
+	}
+	p50 = 900
+	if _, err := m.EvaluateOnce(context.Background()); err != nil {
+		t.Fatalf("EvaluateOnce() error = %v", err)
+	}
+	if got := counterValue(t, counter) - start; got != 2 {
+		t.Errorf("breaches counted after a new breach = %v, want 2", got)
+	}
+}
+
+func TestSLOMonitor_EvaluateOnce_RetriesFailedNotification(t *testing.T) {
+	groupID := uuid.New()
+	q := &mockQuerier{
+		deliveryLatencyFn: func(context.Context, pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+			return []storage.DeliveryLatencyPercentilesRow{
+				latencyRow(groupID, "sendgrid", 50, 600, 600, 600),
+			}, nil
+		},
+	}
+	n := &mockNotifier{err: errors.New("webhook down")}
+	m := NewSLOMonitor(q, n, SLOConfig{P50: time.Minute, MinSamples: 1}, zerolog.Nop())
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.EvaluateOnce(context.Background()); err != nil {
+			t.Fatalf("EvaluateOnce() error = %v", err)
+		}
+	}
+	if len(n.alerts) != 2 {
+		t.Errorf("notify attempts = %d, want 2 (failures are not subject to cooldown)", len(n.alerts))
+	}
+}
+
+func TestSLOMonitor_EvaluateOnce_QueryError(t *testing.T) {
+	q := &mockQuerier{
+		deliveryLatencyFn: func(context.Context, pgtype.Timestamptz) ([]storage.DeliveryLatencyPercentilesRow, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	m := NewSLOMonitor(q, nil, SLOConfig{}, zerolog.Nop())
+
+	if _, err := m.EvaluateOnce(context.Background()); err == nil {
+		t.Fatal("expected error when percentile query fails")
+	}
+}
@@ -0,0 +1,271 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/metrics"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// SweeperConfig controls how the stuck-message sweeper detects and handles
+// messages that stopped progressing through the pipeline.
+type SweeperConfig struct {
+	// Interval is the delay between sweeps.
+	Interval time.Duration
+	// QueuedTimeout is how long a message may stay queued without being
+	// picked up before it is considered lost (e.g. a dropped stream entry).
+	QueuedTimeout time.Duration
+	// ProcessingTimeout is how long a message may stay in processing before
+	// the worker handling it is assumed to have crashed.
+	ProcessingTimeout time.Duration
+	// MaxRequeues is the number of re-enqueue attempts before a stuck
+	// message is marked failed.
+	MaxRequeues int
+	// BatchSize is the maximum number of stuck messages handled per sweep.
+	BatchSize int
+}
+
+// DefaultSweeperConfig returns sensible defaults for the sweeper.
+func DefaultSweeperConfig() SweeperConfig {
+	return SweeperConfig{
+		Interval:          1 * time.Minute,
+		QueuedTimeout:     30 * time.Minute,
+		ProcessingTimeout: 15 * time.Minute,
+		MaxRequeues:       3,
+		BatchSize:         100,
+	}
+}
+
+// SweepResult summarises the outcome of a single sweep.
+type SweepResult struct {
+	Found    int
+	Requeued int
+	Failed   int
+}
+
+// Sweeper periodically finds messages stuck in queued or processing state,
+// re-enqueues them for delivery, and marks them failed once MaxRequeues is
+// exceeded. Every action is written to the activity log.
+type Sweeper struct {
+	queries storage.Querier
+	svc     delivery.Service
+	config  SweeperConfig
+	log     zerolog.Logger
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+// NewSweeper creates a Sweeper that re-enqueues stuck messages via svc.
+// Zero-valued config fields fall back to DefaultSweeperConfig.
+func NewSweeper(queries storage.Querier, svc delivery.Service, cfg SweeperConfig, log zerolog.Logger) *Sweeper {
+	defaults := DefaultSweeperConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.QueuedTimeout <= 0 {
+		cfg.QueuedTimeout = defaults.QueuedTimeout
+	}
+	if cfg.ProcessingTimeout <= 0 {
+		cfg.ProcessingTimeout = defaults.ProcessingTimeout
+	}
+	if cfg.MaxRequeues <= 0 {
+		cfg.MaxRequeues = defaults.MaxRequeues
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	return &Sweeper{
+		queries: queries,
+		svc:     svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Start launches the sweep loop in a background goroutine.
+func (s *Sweeper) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	s.log.Info().
+		Dur("interval", s.config.Interval).
+		Dur("queued_timeout", s.config.QueuedTimeout).
+		Dur("processing_timeout", s.config.ProcessingTimeout).
+		Int("max_requeues", s.config.MaxRequeues).
+		Msg("stuck-message sweeper started")
+}
+
+// Stop signals the sweep loop to exit and waits for the current sweep.
+func (s *Sweeper) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.log.Info().Msg("stuck-message sweeper stopped")
+}
+
+func (s *Sweeper) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SweepOnce(ctx); err != nil && ctx.Err() == nil {
+				s.log.Error().Err(err).Msg("sweep failed")
+			}
+		}
+	}
+}
+
+// SweepOnce scans for stuck messages and handles one batch of them.
+func (s *Sweeper) SweepOnce(ctx context.Context) (SweepResult, error) {
+	now := time.Now()
+	stuck, err := s.queries.ListStuckMessages(ctx, storage.ListStuckMessagesParams{
+		QueuedBefore:     pgtype.Timestamptz{Time: now.Add(-s.config.QueuedTimeout), Valid: true},
+		ProcessingBefore: pgtype.Timestamptz{Time: now.Add(-s.config.ProcessingTimeout), Valid: true},
+		BatchSize:        int32(s.config.BatchSize),
+	})
+	if err != nil {
+		return SweepResult{}, fmt.Errorf("list stuck messages: %w", err)
+	}
+
+	result := SweepResult{Found: len(stuck)}
+	metrics.SweeperStuckMessages.Set(float64(len(stuck)))
+
+	for _, msg := range stuck {
+		if int(msg.RequeueCount) >= s.config.MaxRequeues {
+			if s.expire(ctx, msg) {
+				result.Failed++
+			}
+			continue
+		}
+		if s.requeue(ctx, msg) {
+			result.Requeued++
+		}
+	}
+
+	if result.Found > 0 {
+		s.log.Info().
+			Int("found", result.Found).
+			Int("requeued", result.Requeued).
+			Int("failed", result.Failed).
+			Msg("sweep completed")
+	}
+
+	return result, nil
+}
+
+// requeue resets a stuck message to queued and publishes it again. If the
+// publish fails the message stays queued and is picked up by a later sweep.
+// A message that changed state since it was listed is left alone.
+func (s *Sweeper) requeue(ctx context.Context, msg storage.Message) bool {
+	n, err := s.queries.RequeueMessage(ctx, storage.RequeueMessageParams{
+		ID:          msg.ID,
+		Status:      msg.Status,
+		ProcessedAt: msg.ProcessedAt,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Stringer("message_id", msg.ID).Msg("failed to requeue stuck message")
+		return false
+	}
+	if n == 0 {
+		s.log.Debug().Stringer("message_id", msg.ID).Msg("stuck message progressed before requeue")
+		return false
+	}
+
+	groupID := uuid.UUID(msg.GroupID.Bytes)
+	if err := s.svc.DeliverMessage(ctx, &delivery.Request{
+		MessageID: msg.ID,
+		UserID:    uuid.UUID(msg.UserID.Bytes),
+		GroupID:   groupID,
+		RequestID: msg.RequestID.String,
+	}); err != nil {
+		s.log.Warn().Err(err).Stringer("message_id", msg.ID).Msg("failed to publish requeued message")
+	}
+
+	metrics.SweeperActionsTotal.WithLabelValues("requeued").Inc()
+	s.log.Warn().
+		Stringer("message_id", msg.ID).
+		Str("previous_status", string(msg.Status)).
+		Int32("requeue_count", msg.RequeueCount+1).
+		Msg("stuck message requeued")
+
+	s.audit(ctx, msg, auth.AuditActionMessageRequeued, "message stuck in "+string(msg.Status))
+	return true
+}
+
+// expire marks a stuck message as failed after it exhausted its requeues.
+func (s *Sweeper) expire(ctx context.Context, msg storage.Message) bool {
+	n, err := s.queries.ExpireStuckMessage(ctx, storage.ExpireStuckMessageParams{
+		ID:          msg.ID,
+		Status:      msg.Status,
+		ProcessedAt: msg.ProcessedAt,
+	})
+	if err != nil {
+		s.log.Error().Err(err).Stringer("message_id", msg.ID).Msg("failed to mark stuck message failed")
+		return false
+	}
+	if n == 0 {
+		s.log.Debug().Stringer("message_id", msg.ID).Msg("stuck message progressed before expiry")
+		return false
+	}
+
+	reason := fmt.Sprintf("stuck in %s after %d requeues", msg.Status, msg.RequeueCount)
+	if _, err := s.queries.CreateDeliveryLog(ctx, storage.CreateDeliveryLogParams{
+		MessageID: msg.ID,
+		Status:    string(storage.MessageStatusFailed),
+		LastError: pgtype.Text{String: reason, Valid: true},
+		GroupID:   msg.GroupID,
+		UserID:    msg.UserID,
+		RequestID: msg.RequestID,
+	}); err != nil {
+		s.log.Error().Err(err).Stringer("message_id", msg.ID).Msg("failed to create sweeper delivery log")
+	}
+
+	metrics.SweeperActionsTotal.WithLabelValues("failed").Inc()
+	s.log.Error().
+		Stringer("message_id", msg.ID).
+		Str("previous_status", string(msg.Status)).
+		Int32("requeue_count", msg.RequeueCount).
+		Msg("stuck message marked failed")
+
+	s.audit(ctx, msg, auth.AuditActionMessageExpired, reason)
+	return true
+}
+
+// audit records a sweeper action in the activity log. Messages without a
+// group cannot be attributed and are only logged.
+func (s *Sweeper) audit(ctx context.Context, msg storage.Message, action, comment string) {
+	if !msg.GroupID.Valid {
+		return
+	}
+	if _, err := s.queries.CreateActivityLog(ctx, storage.CreateActivityLogParams{
+		GroupID:      uuid.UUID(msg.GroupID.Bytes),
+		Action:       action,
+		ResourceType: "message",
+		ResourceID:   pgtype.UUID{Bytes: msg.ID, Valid: true},
+		Changes: auth.ChangesToJSON(map[string]interface{}{
+			"previous_status": string(msg.Status),
+			"requeue_count":   msg.RequeueCount,
+		}),
+		Comment: pgtype.Text{String: comment, Valid: true},
+	}); err != nil {
+		s.log.Error().Err(err).Stringer("message_id", msg.ID).Msg("failed to write sweeper audit entry")
+	}
+}
@@ -0,0 +1,207 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/auth"
+	"github.com/sungwon/smtp-proxy/server/internal/delivery"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// mockDeliveryService implements delivery.Service for testing.
+type mockDeliveryService struct {
+	deliverFn func(ctx context.Context, req *delivery.Request) error
+	requests  []*delivery.Request
+}
+
+func (m *mockDeliveryService) DeliverMessage(ctx context.Context, req *delivery.Request) error {
+	m.requests = append(m.requests, req)
+	if m.deliverFn != nil {
+		return m.deliverFn(ctx, req)
+	}
+	return nil
+}
+
+func newStuckMessage(status storage.MessageStatus, requeueCount int32) storage.Message {
+	return storage.Message{
+		ID:           uuid.New(),
+		Status:       status,
+		GroupID:      pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:       pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		RequeueCount: requeueCount,
+	}
+}
+
+func TestSweeper_SweepOnce_RequeuesStuckMessage(t *testing.T) {
+	msg := newStuckMessage(storage.MessageStatusProcessing, 0)
+
+	var captured storage.ListStuckMessagesParams
+	q := &mockQuerier{
+		listStuckFn: func(_ context.Context, arg storage.ListStuckMessagesParams) ([]storage.Message, error) {
+			captured = arg
+			return []storage.Message{msg}, nil
+		},
+	}
+	svc := &mockDeliveryService{}
+
+	s := NewSweeper(q, svc, SweeperConfig{
+		QueuedTimeout:     time.Hour,
+		ProcessingTimeout: 10 * time.Minute,
+		MaxRequeues:       3,
+	}, zerolog.Nop())
+
+	before := time.Now()
+	result, err := s.SweepOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Found != 1 || result.Requeued != 1 || result.Failed != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Cutoffs are derived from the configured timeouts.
+	if d := before.Sub(captured.QueuedBefore.Time); d < time.Hour-time.Second || d > time.Hour+time.Second {
+		t.Errorf("unexpected queued cutoff offset: %v", d)
+	}
+	if d := before.Sub(captured.ProcessingBefore.Time); d < 10*time.Minute-time.Second || d > 10*time.Minute+time.Second {
+		t.Errorf("unexpected processing cutoff offset: %v", d)
+	}
+
+	if len(q.requeuedIDs) != 1 || q.requeuedIDs[0] != msg.ID {
+		t.Errorf("expected message %s requeued, got %v", msg.ID, q.requeuedIDs)
+	}
+	if len(svc.requests) != 1 || svc.requests[0].MessageID != msg.ID {
+		t.Fatalf("expected one delivery request for %s", msg.ID)
+	}
+	if svc.requests[0].GroupID != uuid.UUID(msg.GroupID.Bytes) {
+		t.Errorf("expected group %s, got %s", uuid.UUID(msg.GroupID.Bytes), svc.requests[0].GroupID)
+	}
+
+	if len(q.activityLogs) != 1 || q.activityLogs[0].Action != auth.AuditActionMessageRequeued {
+		t.Fatalf("expected one %s audit entry, got %+v", auth.AuditActionMessageRequeued, q.activityLogs)
+	}
+	if q.activityLogs[0].ResourceID.Bytes != msg.ID {
+		t.Errorf("expected audit resource %s", msg.ID)
+	}
+}
+
+func TestSweeper_SweepOnce_FailsAfterMaxRequeues(t *testing.T) {
+	msg := newStuckMessage(storage.MessageStatusQueued, 3)
+	q := &mockQuerier{
+		listStuckFn: func(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+			return []storage.Message{msg}, nil
+		},
+	}
+	svc := &mockDeliveryService{}
+
+	s := NewSweeper(q, svc, SweeperConfig{MaxRequeues: 3}, zerolog.Nop())
+
+	result, err := s.SweepOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Failed != 1 || result.Requeued != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if len(q.statuses) != 1 || q.statuses[0] != storage.MessageStatusFailed {
+		t.Errorf("expected status failed, got %v", q.statuses)
+	}
+	if !q.createLogCalled || q.createLogStatus != string(storage.MessageStatusFailed) {
+		t.Error("expected a failed delivery log")
+	}
+	if len(svc.requests) != 0 {
+		t.Errorf("expected no re-enqueue, got %d", len(svc.requests))
+	}
+	if len(q.activityLogs) != 1 || q.activityLogs[0].Action != auth.AuditActionMessageExpired {
+		t.Errorf("expected one %s audit entry, got %+v", auth.AuditActionMessageExpired, q.activityLogs)
+	}
+}
+
+func TestSweeper_SweepOnce_PublishFailureStillRequeues(t *testing.T) {
+	msg := newStuckMessage(storage.MessageStatusQueued, 0)
+	q := &mockQuerier{
+		listStuckFn: func(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+			return []storage.Message{msg}, nil
+		},
+	}
+	svc := &mockDeliveryService{
+		deliverFn: func(_ context.Context, _ *delivery.Request) error {
+			return errors.New("redis unavailable")
+		},
+	}
+
+	s := NewSweeper(q, svc, SweeperConfig{}, zerolog.Nop())
+
+	result, err := s.SweepOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// The requeue count is still bumped so repeated publish failures
+	// eventually fail the message instead of looping forever.
+	if result.Requeued != 1 || len(q.requeuedIDs) != 1 {
+		t.Errorf("expected message to be requeued, got %+v", result)
+	}
+}
+
+func TestSweeper_SweepOnce_SkipsMessagesThatProgressed(t *testing.T) {
+	requeue := newStuckMessage(storage.MessageStatusProcessing, 0)
+	expire := newStuckMessage(storage.MessageStatusProcessing, 3)
+	q := &mockQuerier{
+		listStuckFn: func(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+			return []storage.Message{requeue, expire}, nil
+		},
+		progressed: true,
+	}
+	svc := &mockDeliveryService{}
+
+	s := NewSweeper(q, svc, SweeperConfig{MaxRequeues: 3}, zerolog.Nop())
+
+	result, err := s.SweepOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Found != 2 || result.Requeued != 0 || result.Failed != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(svc.requests) != 0 {
+		t.Errorf("expected no re-enqueue, got %d", len(svc.requests))
+	}
+	if q.createLogCalled || len(q.activityLogs) != 0 {
+		t.Error("expected no delivery log or audit entry")
+	}
+}
+
+func TestSweeper_SweepOnce_ListError(t *testing.T) {
+	q := &mockQuerier{
+		listStuckFn: func(_ context.Context, _ storage.ListStuckMessagesParams) ([]storage.Message, error) {
+			return nil, errors.New("database unavailable")
+		},
+	}
+
+	s := NewSweeper(q, &mockDeliveryService{}, SweeperConfig{}, zerolog.Nop())
+
+	if _, err := s.SweepOnce(context.Background()); err == nil {
+		t.Fatal("expected error when listing stuck messages fails")
+	}
+}
+
+func TestNewSweeper_AppliesDefaults(t *testing.T) {
+	s := NewSweeper(&mockQuerier{}, &mockDeliveryService{}, SweeperConfig{}, zerolog.Nop())
+
+	defaults := DefaultSweeperConfig()
+	if s.config.Interval != defaults.Interval ||
+		s.config.QueuedTimeout != defaults.QueuedTimeout ||
+		s.config.ProcessingTimeout != defaults.ProcessingTimeout ||
+		s.config.BatchSize != defaults.BatchSize {
+		t.Errorf("expected defaults %+v, got %+v", defaults, s.config)
+	}
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/ratelimit"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// concurrencyDeferral is how long a message waits when a recipient
+// domain already has as many deliveries in flight as its limit allows.
+const concurrencyDeferral = 5 * time.Second
+
+// domainThrottle enforces the recipient domain limits of providers: a
+// token bucket per provider and domain for the rate, shared by workers
+// through the bucket, and a count of in-flight deliveries per worker
+// process for the concurrency.
+type domainThrottle struct {
+	bucket ratelimit.Bucket
+
+	mu       sync.Mutex
+	inflight map[string]int
+}
+
+// throttleHold is a delivery admitted by the throttle. Release frees its
+// concurrency slots once the send is done.
+type throttleHold struct {
+	t    *domainThrottle
+	keys []string
+}
+
+// Release frees the hold's concurrency slots. It may be called on a nil
+// hold.
+func (h *throttleHold) Release() {
+	if h == nil || len(h.keys) == 0 {
+		return
+	}
+	h.t.mu.Lock()
+	defer h.t.mu.Unlock()
+	for _, k := range h.keys {
+		if h.t.inflight[k]--; h.t.inflight[k] <= 0 {
+			delete(h.t.inflight, k)
+		}
+	}
+}
+
+// throttleDeferral reports a delivery that exceeds a domain limit.
+type throttleDeferral struct {
+	domain string
+	limit  string // rate, concurrency
+	wait   time.Duration
+}
+
+// SetDomainThrottle makes the handler enforce the recipient domain limits
+// in providers' smtp_config.domain_limits, taking rate tokens from bucket.
+// Messages over a limit are deferred through the outbox rather than
+// retried, so bulk sends are paced without exhausting their retries.
+func (h *Handler) SetDomainThrottle(bucket ratelimit.Bucket) {
+	h.throttle = &domainThrottle{bucket: bucket, inflight: make(map[string]int)}
+}
+
+// admit takes a concurrency slot and a rate token for every domain limit
+// of p that applies to recipients. It returns the hold to release after
+// sending, or the deferral when a limit is reached, in which case nothing
+// is held.
+func (t *domainThrottle) admit(ctx context.Context, p provider.Provider, recipients []string) (*throttleHold, *throttleDeferral) {
+	limits := provider.RecipientDomainLimits(p, recipients)
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	prefix := p.GetName()
+	if ident, ok := p.(provider.Identified); ok {
+		prefix = ident.ProviderID().String()
+	}
+
+	hold := &throttleHold{t: t}
+	t.mu.Lock()
+	for _, l := range limits {
+		key := prefix + ":" + l.Key()
+		if l.Concurrency > 0 {
+			if t.inflight[key] >= l.Concurrency {
+				t.mu.Unlock()
+				hold.Release()
+				return nil, &throttleDeferral{domain: l.Key(), limit: "concurrency", wait: concurrencyDeferral}
+			}
+			t.inflight[key]++
+			hold.keys = append(hold.keys, key)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, l := range limits {
+		if l.PerMinute <= 0 {
+			continue
+		}
+		allowed, wait, err := t.bucket.Take(ctx, prefix+":"+l.Key(), ratelimit.PerMinute(float64(l.PerMinute), 0))
+		if err != nil {
+			// The limit is advisory; a bucket outage must not stop delivery.
+			continue
+		}
+		if !allowed {
+			hold.Release()
+			// Spread deferred messages over the next token intervals so
+			// they do not all come back at once.
+			interval := time.Minute / time.Duration(l.PerMinute)
+			wait += time.Duration(rand.Int64N(int64(interval) * 4))
+			return nil, &throttleDeferral{domain: l.Key(), limit: "rate", wait: wait}
+		}
+	}
+	return hold, nil
+}
+
+// deferDelivery puts a message back in the queue through a delayed outbox
+// entry, which the relay re-enqueues once wait has passed. The message
+// keeps its retries.
+func (h *Handler) deferDelivery(ctx context.Context, messageID uuid.UUID, dbMsg storage.Message, wait time.Duration) error {
+	if err := h.queries.UpdateMessageStatus(ctx, storage.UpdateMessageStatusParams{
+		ID:     messageID,
+		Status: storage.MessageStatusQueued,
+	}); err != nil {
+		return err
+	}
+	_, err := h.queries.CreateDelayedOutboxEntry(ctx, storage.CreateDelayedOutboxEntryParams{
+		MessageID:    messageID,
+		GroupID:      uuid.UUID(dbMsg.GroupID.Bytes),
+		UserID:       uuid.UUID(dbMsg.UserID.Bytes),
+		RequestID:    dbMsg.RequestID,
+		ClaimedUntil: pgtype.Timestamptz{Time: time.Now().Add(wait), Valid: true},
+	})
+	return err
+}
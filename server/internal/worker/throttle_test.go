@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+	"github.com/sungwon/smtp-proxy/server/internal/queue"
+	"github.com/sungwon/smtp-proxy/server/internal/ratelimit"
+	"github.com/sungwon/smtp-proxy/server/internal/storage"
+)
+
+// limitedCaptureProvider is a capture provider with recipient domain
+// limits, as built by the resolver from smtp_config.domain_limits.
+type limitedCaptureProvider struct {
+	mockCaptureProvider
+	limits []provider.DomainLimit
+}
+
+func (p *limitedCaptureProvider) DomainLimits() []provider.DomainLimit { return p.limits }
+
+func TestHandler_HandleMessage_DomainRateLimit(t *testing.T) {
+	groupID := uuid.New()
+	mq := &mockQuerier{
+		getMessageFn: func(_ context.Context, _ uuid.UUID) (storage.Message, error) {
+			dbMsg := newTestDBMessage(groupID, uuid.New())
+			dbMsg.Recipients, _ = json.Marshal([]string{"someone@gmail.com"})
+			return dbMsg, nil
+		},
+	}
+	p := &limitedCaptureProvider{limits: []provider.DomainLimit{{Domains: []string{"gmail.com"}, PerMinute: 1}}}
+	h := &Handler{
+		resolver: &mockCaptureResolver{provider: p},
+		queries:  mq,
+		log:      zerolog.Nop(),
+	}
+	h.SetDomainThrottle(ratelimit.NewMemoryBucket())
+
+	for i := 0; i < 2; i++ {
+		msg := &queue.Message{ID: uuid.New().String(), Body: []byte("Subject: Hi\r\n\r\nHello")}
+		if err := h.HandleMessage(context.Background(), msg); err != nil {
+			t.Fatalf("HandleMessage() error: %v", err)
+		}
+	}
+
+	if p.captured == nil {
+		t.Fatal("expected the first message to be sent")
+	}
+	if len(mq.delayedEntries) != 1 {
+		t.Fatalf("expected the second message to be deferred once, got %d delayed entries", len(mq.delayedEntries))
+	}
+	deferred := mq.delayedEntries[0]
+	if deferred.GroupID != groupID || !deferred.ClaimedUntil.Time.After(time.Now().Add(30*time.Second)) {
+		t.Errorf("delayed entry = %+v, want one for the group about a minute out", deferred)
+	}
+	if last := mq.statuses[len(mq.statuses)-1]; last != storage.MessageStatusQueued {
+		t.Errorf("deferred message status = %s, want queued", last)
+	}
+}
+
+func TestDomainThrottle_Concurrency(t *testing.T) {
+	throttle := &domainThrottle{bucket: ratelimit.NewMemoryBucket(), inflight: make(map[string]int)}
+	p := &limitedCaptureProvider{limits: []provider.DomainLimit{{Domains: []string{"gmail.com"}, Concurrency: 1}}}
+	ctx := context.Background()
+
+	hold, deferral := throttle.admit(ctx, p, []string{"a@gmail.com"})
+	if deferral != nil {
+		t.Fatalf("first delivery deferred: %+v", deferral)
+	}
+	if _, deferral := throttle.admit(ctx, p, []string{"b@mail.gmail.com"}); deferral == nil || deferral.limit != "concurrency" {
+		t.Fatalf("second concurrent delivery deferral = %+v, want a concurrency deferral", deferral)
+	}
+	if _, deferral := throttle.admit(ctx, p, []string{"c@example.com"}); deferral != nil {
+		t.Errorf("unlimited domain deferred: %+v", deferral)
+	}
+	hold.Release()
+	if _, deferral := throttle.admit(ctx, p, []string{"b@gmail.com"}); deferral != nil {
+		t.Errorf("delivery deferred after the slot was released: %+v", deferral)
+	}
+}
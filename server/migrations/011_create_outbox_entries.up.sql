@@ -0,0 +1,19 @@
+-- Transactional outbox for message enqueue.
+--
+-- The SMTP server writes an outbox row in the same transaction as the
+-- message row. A relay goroutine publishes pending rows to Redis Streams
+-- and deletes them once the publish succeeds, so a Redis outage can no
+-- longer leave a persisted message without a queue entry.
+
+CREATE TABLE outbox_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    group_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    claimed_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_outbox_entries_created_at ON outbox_entries(created_at);
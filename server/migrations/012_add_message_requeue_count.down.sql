@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_status_processed;
+ALTER TABLE messages DROP COLUMN IF EXISTS requeue_count;
@@ -0,0 +1,7 @@
+-- Track how often the stuck-message sweeper has re-enqueued a message so it
+-- can give up and mark the message failed after a configurable limit.
+ALTER TABLE messages ADD COLUMN requeue_count INTEGER NOT NULL DEFAULT 0;
+
+-- Supports the sweeper's scan for messages stuck in queued/processing.
+CREATE INDEX idx_messages_status_processed ON messages(status, processed_at)
+    WHERE status IN ('queued', 'processing');
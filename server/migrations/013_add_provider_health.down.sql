@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS provider_health_checks;
+ALTER TABLE esp_providers
+    DROP COLUMN IF EXISTS auto_disabled_at,
+    DROP COLUMN IF EXISTS last_health_error,
+    DROP COLUMN IF EXISTS last_health_check_at,
+    DROP COLUMN IF EXISTS consecutive_failures,
+    DROP COLUMN IF EXISTS health_status;
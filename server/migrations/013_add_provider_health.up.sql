@@ -0,0 +1,28 @@
+-- Provider health tracking.
+--
+-- The queue worker's health prober calls HealthCheck on every enabled
+-- provider, appends the result to provider_health_checks, and keeps the
+-- current state on esp_providers. After too many consecutive failures a
+-- provider is disabled automatically (auto_disabled_at is set) and is
+-- re-enabled by the prober once a check succeeds again. Providers disabled
+-- by an administrator have auto_disabled_at NULL and are never re-enabled.
+
+ALTER TABLE esp_providers
+    ADD COLUMN health_status VARCHAR(20) NOT NULL DEFAULT 'unknown',
+    ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_health_check_at TIMESTAMPTZ,
+    ADD COLUMN last_health_error TEXT,
+    ADD COLUMN auto_disabled_at TIMESTAMPTZ;
+
+CREATE TABLE provider_health_checks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider_id UUID NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    healthy BOOLEAN NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    error TEXT,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_provider_health_checks_provider_checked
+    ON provider_health_checks(provider_id, checked_at DESC);
+CREATE INDEX idx_provider_health_checks_checked_at ON provider_health_checks(checked_at);
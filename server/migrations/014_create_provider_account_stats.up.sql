@@ -0,0 +1,19 @@
+-- Account-level quota and reputation reported by each ESP.
+--
+-- The queue worker's account poller refreshes one row per provider. Columns
+-- are NULL when the ESP does not report that figure. quota/quota_used cover
+-- the ESP's own quota period (rolling 24h for SES, plan period for
+-- SendGrid). suppression_delta is the change in suppression_count since the
+-- previous poll.
+
+CREATE TABLE provider_account_stats (
+    provider_id UUID PRIMARY KEY REFERENCES esp_providers(id) ON DELETE CASCADE,
+    quota BIGINT,
+    quota_used BIGINT,
+    bounce_rate DOUBLE PRECISION,
+    complaint_rate DOUBLE PRECISION,
+    suppression_count BIGINT,
+    suppression_delta BIGINT,
+    last_error TEXT,
+    polled_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
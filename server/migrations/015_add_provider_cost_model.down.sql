@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_delivery_logs_status_created;
+ALTER TABLE esp_providers DROP COLUMN IF EXISTS cost_model;
@@ -0,0 +1,9 @@
+-- Per-provider pricing used to estimate spend from delivery logs and to
+-- support the "cheapest" routing strategy. Example:
+--   {"currency": "USD", "tiers": [{"up_to": 100000, "price_per_1k": 0.90},
+--                                 {"price_per_1k": 0.60}]}
+-- Tiers are applied to the provider's month-to-date delivered volume.
+ALTER TABLE esp_providers ADD COLUMN cost_model JSONB;
+
+-- Supports daily spend aggregation over delivered messages.
+CREATE INDEX idx_delivery_logs_status_created ON delivery_logs(status, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE groups
+    DROP COLUMN IF EXISTS inline_css,
+    DROP COLUMN IF EXISTS sanitize_html;
@@ -0,0 +1,5 @@
+-- Per-group HTML processing applied by the queue worker before handing a
+-- message to the ESP. CSS inlining runs before sanitization.
+ALTER TABLE groups
+    ADD COLUMN sanitize_html BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN inline_css BOOLEAN NOT NULL DEFAULT false;
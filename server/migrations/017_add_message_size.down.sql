@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_group_enqueued;
+ALTER TABLE messages DROP COLUMN IF EXISTS size_bytes;
@@ -0,0 +1,7 @@
+-- Size in bytes of the message as accepted (headers and body), recorded for
+-- usage accounting and billing exports. Messages accepted before this
+-- migration are counted with size 0.
+ALTER TABLE messages ADD COLUMN size_bytes BIGINT NOT NULL DEFAULT 0;
+
+-- Supports monthly per-group usage aggregation.
+CREATE INDEX idx_messages_group_enqueued ON messages(group_id, enqueued_at);
@@ -0,0 +1,10 @@
+-- One row per monthly-limit warning emailed to a group's owners, so each
+-- threshold is only announced once per billing period even with several
+-- queue workers running.
+CREATE TABLE quota_notifications (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    period DATE NOT NULL,
+    threshold INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, period, threshold)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_groups_parent_id;
+
+ALTER TABLE groups
+    DROP CONSTRAINT IF EXISTS groups_parent_not_self,
+    DROP COLUMN IF EXISTS parent_id;
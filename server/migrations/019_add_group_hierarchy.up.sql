@@ -0,0 +1,9 @@
+-- Sub-groups: a group may belong to a parent group (e.g. company -> team).
+-- Sub-groups inherit the monthly limit and providers of their nearest
+-- ancestor unless they define their own. A parent cannot be deleted while
+-- it still has sub-groups.
+ALTER TABLE groups
+    ADD COLUMN parent_id UUID REFERENCES groups(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT groups_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX idx_groups_parent_id ON groups(parent_id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS smtp_transcripts;
+DROP TABLE IF EXISTS smtp_debug_targets;
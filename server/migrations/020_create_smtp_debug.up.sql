@@ -0,0 +1,26 @@
+-- Opt-in SMTP debug mode. While a target is active, sessions from the
+-- matching user or client IP record their command/response transcript.
+CREATE TABLE smtp_debug_targets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ip_address INET,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (user_id IS NOT NULL OR ip_address IS NOT NULL)
+);
+
+CREATE INDEX idx_smtp_debug_targets_expires_at ON smtp_debug_targets(expires_at);
+
+CREATE TABLE smtp_transcripts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    target_id UUID NOT NULL REFERENCES smtp_debug_targets(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    remote_addr TEXT NOT NULL,
+    lines JSONB NOT NULL DEFAULT '[]',
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_smtp_transcripts_target_id ON smtp_transcripts(target_id, started_at DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS inbound_route_id;
+DROP TABLE IF EXISTS inbound_routes;
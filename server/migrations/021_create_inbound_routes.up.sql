@@ -0,0 +1,20 @@
+-- Inbound mail: messages received for a route's domain on the inbound
+-- SMTP listener are posted to the route's HTTP endpoint by the queue worker.
+CREATE TABLE inbound_routes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    domain VARCHAR(255) NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    format VARCHAR(10) NOT NULL DEFAULT 'json' CHECK (format IN ('json', 'raw')),
+    secret TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_inbound_routes_group_id ON inbound_routes(group_id);
+
+-- Set for inbound messages. There is deliberately no foreign key: a message
+-- queued for a route that is deleted afterwards must fail rather than be
+-- sent out through an ESP.
+ALTER TABLE messages ADD COLUMN inbound_route_id UUID;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_tags;
+ALTER TABLE messages DROP COLUMN IF EXISTS metadata;
+ALTER TABLE messages DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,7 @@
+-- Custom tags and key-value metadata captured at submission from the
+-- X-SMTPProxy-Tag and X-SMTPProxy-Metadata headers.
+ALTER TABLE messages
+    ADD COLUMN tags JSONB NOT NULL DEFAULT '[]',
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_messages_tags ON messages USING GIN (tags);
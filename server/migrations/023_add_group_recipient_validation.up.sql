@@ -0,0 +1,4 @@
+-- Recipient validation policy applied at RCPT TO: 'off', 'tag' (accept and
+-- tag the message as risky-recipient) or 'reject' (refuse risky recipients).
+ALTER TABLE groups ADD COLUMN recipient_validation VARCHAR(10) NOT NULL DEFAULT 'off'
+    CHECK (recipient_validation IN ('off', 'tag', 'reject'));
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS tls_cipher, DROP COLUMN IF EXISTS tls_version;
+ALTER TABLE users DROP COLUMN IF EXISTS tls_policy;
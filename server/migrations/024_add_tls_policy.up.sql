@@ -0,0 +1,7 @@
+-- Per-user TLS policy for SMTP submission, and the TLS parameters each
+-- message was submitted with, for auditing.
+ALTER TABLE users ADD COLUMN tls_policy JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE messages
+    ADD COLUMN tls_version TEXT,
+    ADD COLUMN tls_cipher TEXT;
@@ -0,0 +1,15 @@
+-- Client certificates that authenticate SMTP accounts over mutual TLS. A
+-- certificate is matched by its SHA-256 fingerprint or, when it chains to
+-- a configured client CA, by one of its subject alternative names.
+CREATE TABLE smtp_client_certs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) UNIQUE,
+    san TEXT UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (fingerprint IS NOT NULL OR san IS NOT NULL)
+);
+
+CREATE INDEX idx_smtp_client_certs_user_id ON smtp_client_certs(user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_request_id;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS request_id;
+ALTER TABLE outbox_entries DROP COLUMN IF EXISTS request_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS request_id;
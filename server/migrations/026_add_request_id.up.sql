@@ -0,0 +1,7 @@
+-- Request IDs correlate an SMTP session or API request with the message,
+-- its outbox entry and every delivery attempt.
+ALTER TABLE messages ADD COLUMN request_id TEXT;
+ALTER TABLE outbox_entries ADD COLUMN request_id TEXT;
+ALTER TABLE delivery_logs ADD COLUMN request_id TEXT;
+
+CREATE INDEX idx_messages_request_id ON messages (request_id) WHERE request_id IS NOT NULL;
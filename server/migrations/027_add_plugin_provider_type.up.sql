@@ -0,0 +1,3 @@
+-- Providers of type plugin are implemented by a plugin loaded by the
+-- worker; smtp_config.plugin names the registered provider type.
+ALTER TYPE provider_type ADD VALUE IF NOT EXISTS 'plugin';
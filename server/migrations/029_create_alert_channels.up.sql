@@ -0,0 +1,14 @@
+-- Per-group destinations for operational alerts (DLQ growth, provider
+-- auto-disable, quota warnings, ...). target is the webhook URL for slack
+-- and webhook channels and the Events API routing key for pagerduty.
+CREATE TABLE alert_channels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    channel_type VARCHAR(20) NOT NULL CHECK (channel_type IN ('slack', 'pagerduty', 'webhook')),
+    target TEXT NOT NULL,
+    kinds JSONB NOT NULL DEFAULT '[]'::jsonb,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_alert_channels_group_id ON alert_channels(group_id);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_delivery_logs_egress_ip;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS first_byte_ms;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS tls_handshake_ms;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS connect_ms;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS tls_cipher;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS tls_version;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS provider_endpoint;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS remote_addr;
+ALTER TABLE delivery_logs DROP COLUMN IF EXISTS egress_ip;
@@ -0,0 +1,13 @@
+-- Connection details of each provider request, kept for compliance regimes
+-- that require an outbound relay to record which egress IP, TLS session and
+-- endpoint carried a message.
+ALTER TABLE delivery_logs ADD COLUMN egress_ip TEXT;
+ALTER TABLE delivery_logs ADD COLUMN remote_addr TEXT;
+ALTER TABLE delivery_logs ADD COLUMN provider_endpoint TEXT;
+ALTER TABLE delivery_logs ADD COLUMN tls_version TEXT;
+ALTER TABLE delivery_logs ADD COLUMN tls_cipher TEXT;
+ALTER TABLE delivery_logs ADD COLUMN connect_ms INTEGER;
+ALTER TABLE delivery_logs ADD COLUMN tls_handshake_ms INTEGER;
+ALTER TABLE delivery_logs ADD COLUMN first_byte_ms INTEGER;
+
+CREATE INDEX idx_delivery_logs_egress_ip ON delivery_logs (egress_ip, created_at) WHERE egress_ip IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sender_policies;
+DROP TABLE IF EXISTS sender_identities;
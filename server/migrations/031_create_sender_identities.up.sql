@@ -0,0 +1,22 @@
+-- Sender identities are the addresses and domains a group may put in the
+-- From header. An identity is verified by publishing verification_token in
+-- a TXT record of its domain.
+CREATE TABLE sender_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    identity TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (group_id, identity)
+);
+
+-- The From policy of a group decides what the worker does with messages
+-- whose From is not a verified sender identity. Groups without a row are
+-- not checked.
+CREATE TABLE sender_policies (
+    group_id UUID PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    mode VARCHAR(20) NOT NULL DEFAULT 'off' CHECK (mode IN ('off', 'reject', 'rewrite')),
+    rewrite_address TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sending_domains;
@@ -0,0 +1,13 @@
+-- Sending domains hold a group's defaults for messages whose From is in the
+-- domain: the Reply-To and bounce (Return-Path) addresses applied when the
+-- submitted message does not set them.
+CREATE TABLE sending_domains (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    domain VARCHAR(255) NOT NULL,
+    reply_to TEXT,
+    return_path TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (group_id, domain)
+);
@@ -0,0 +1,3 @@
+-- Addresses the worker copies every message of an SMTP user to, such as a
+-- compliance archive. Stored as a JSON array of addresses.
+ALTER TABLE users ADD COLUMN auto_bcc JSONB NOT NULL DEFAULT '[]';
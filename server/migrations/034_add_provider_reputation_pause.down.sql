@@ -0,0 +1,3 @@
+ALTER TABLE provider_account_stats DROP COLUMN IF EXISTS pause_action;
+ALTER TABLE provider_account_stats DROP COLUMN IF EXISTS pause_reason;
+ALTER TABLE provider_account_stats DROP COLUMN IF EXISTS paused_at;
@@ -0,0 +1,8 @@
+-- Providers paused by the account poller because their sender reputation
+-- degraded or a relay IP is on a blocklist. pause_reason is NULL while the
+-- provider is not paused; paused_at is when the current pause began.
+-- pause_action is 'pause' (not routed to) or 'throttle' (routed to only
+-- when no other provider is available).
+ALTER TABLE provider_account_stats ADD COLUMN paused_at TIMESTAMPTZ;
+ALTER TABLE provider_account_stats ADD COLUMN pause_reason TEXT;
+ALTER TABLE provider_account_stats ADD COLUMN pause_action TEXT;
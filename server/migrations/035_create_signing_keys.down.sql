@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recipient_certificates;
+DROP TABLE IF EXISTS signing_keys;
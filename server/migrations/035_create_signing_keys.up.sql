@@ -0,0 +1,30 @@
+-- Signing keys hold a group's S/MIME certificate or PGP key. The worker
+-- signs the group's outbound messages with it and, for S/MIME with encrypt
+-- set, encrypts them to the recipients' uploaded certificates. on_failure
+-- decides whether a message that cannot be signed or encrypted is sent as
+-- is or failed.
+CREATE TABLE signing_keys (
+    group_id UUID PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('smime', 'pgp')),
+    certificate TEXT,
+    private_key TEXT NOT NULL,
+    fingerprint VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ,
+    encrypt BOOLEAN NOT NULL DEFAULT FALSE,
+    on_failure VARCHAR(20) NOT NULL DEFAULT 'send_unsigned' CHECK (on_failure IN ('send_unsigned', 'fail')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Recipient certificates are the S/MIME certificates messages to an
+-- address are encrypted to.
+CREATE TABLE recipient_certificates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    email VARCHAR(320) NOT NULL,
+    certificate TEXT NOT NULL,
+    fingerprint VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (group_id, email)
+);
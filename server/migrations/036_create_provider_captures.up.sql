@@ -0,0 +1,13 @@
+-- Provider captures hold the sanitized ESP API requests and responses of a
+-- provider's recent deliveries, recorded while smtp_config.debug_capture
+-- is set. The worker keeps the last debug_capture deliveries per provider.
+CREATE TABLE provider_captures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider_id UUID NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    message_id UUID,
+    exchanges JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_provider_captures_provider_id ON provider_captures(provider_id, created_at DESC);
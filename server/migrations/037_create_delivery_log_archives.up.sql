@@ -0,0 +1,16 @@
+-- Delivery log archives index the gzipped NDJSON files in the message store
+-- that hold delivery logs moved out of delivery_logs by the archiver. Each
+-- file holds one group's logs created between first_created_at and
+-- last_created_at; the delivery logs API reads the files covering a
+-- requested range.
+CREATE TABLE delivery_log_archives (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID REFERENCES groups(id) ON DELETE SET NULL,
+    object_key TEXT NOT NULL UNIQUE,
+    first_created_at TIMESTAMPTZ NOT NULL,
+    last_created_at TIMESTAMPTZ NOT NULL,
+    row_count INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_delivery_log_archives_group ON delivery_log_archives(group_id, last_created_at DESC);
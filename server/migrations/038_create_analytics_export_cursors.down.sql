@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_delivery_logs_updated;
+DROP TABLE IF EXISTS analytics_export_cursors;
@@ -0,0 +1,11 @@
+-- Analytics export cursors record how far each analytics sink has read
+-- delivery_logs. Rows are read in (updated_at, id) order, so webhook status
+-- updates are exported again as a new version of the attempt.
+CREATE TABLE analytics_export_cursors (
+    sink TEXT PRIMARY KEY,
+    last_updated_at TIMESTAMPTZ NOT NULL,
+    last_id UUID NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_delivery_logs_updated ON delivery_logs(updated_at, id);
@@ -0,0 +1,5 @@
+-- Release paused messages; the sweeper requeues them.
+UPDATE messages SET status = 'queued' WHERE status = 'paused';
+
+-- Note: PostgreSQL does not support removing individual enum values.
+-- The 'paused' value remains in the enum type.
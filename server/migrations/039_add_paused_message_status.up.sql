@@ -0,0 +1,3 @@
+-- Messages paused by a credential lockdown are held back from delivery
+-- until they are released.
+ALTER TYPE message_status ADD VALUE IF NOT EXISTS 'paused';
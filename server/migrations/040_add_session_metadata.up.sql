@@ -0,0 +1,6 @@
+-- Record the client of each refresh session so users and admins can tell
+-- sessions apart before revoking them.
+ALTER TABLE sessions
+    ADD COLUMN user_agent TEXT,
+    ADD COLUMN ip_address INET,
+    ADD COLUMN last_used_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Track password age for SMTP credential expiry. Existing passwords count
+-- from the migration, so no account expires the moment expiry is enabled.
+-- password_expiry_warned_at records the expiry warning of the current
+-- password; it is cleared when the password changes.
+ALTER TABLE users
+    ADD COLUMN password_changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN password_expiry_warned_at TIMESTAMPTZ;
@@ -0,0 +1,10 @@
+-- Networks each user has logged in from, so a login from an unfamiliar
+-- network or country can be flagged and the user notified.
+CREATE TABLE login_networks (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    network TEXT NOT NULL,
+    country TEXT,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, network)
+);
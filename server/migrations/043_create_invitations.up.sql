@@ -0,0 +1,16 @@
+-- Pending group invitations. An admin invites an email address with a
+-- role; the invitee accepts through an emailed link and joins the group.
+-- Emails are stored lowercased; an address has at most one pending
+-- invitation per group.
+CREATE TABLE invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'member',
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_invitations_pending ON invitations(group_id, email) WHERE accepted_at IS NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS signups;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS sandbox;
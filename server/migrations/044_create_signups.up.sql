@@ -0,0 +1,17 @@
+-- Self-service signup. A registration waits in signups until its email
+-- address is verified; the user and their group are created only then.
+-- Groups created by signup are sandboxed: they can only send to their own
+-- members' verified addresses until a system admin lifts the sandbox.
+-- Addresses are verified by signup and by accepting an invitation.
+ALTER TABLE groups ADD COLUMN sandbox BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+CREATE TABLE signups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    group_name VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
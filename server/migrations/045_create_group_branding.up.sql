@@ -0,0 +1,12 @@
+-- Per-group branding of system emails sent on the group's behalf, such as
+-- invitations and quota warnings. templates maps template names to
+-- {"subject", "body"} overrides of the built-in text/template sources.
+CREATE TABLE group_branding (
+    group_id UUID PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    from_address VARCHAR(255) NOT NULL DEFAULT '',
+    logo_url TEXT NOT NULL DEFAULT '',
+    footer TEXT NOT NULL DEFAULT '',
+    templates JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- Default locale of a group: the language of system emails sent on the
+-- group's behalf. One of the locales of the i18n catalog.
+ALTER TABLE groups ADD COLUMN locale VARCHAR(8) NOT NULL DEFAULT 'en';
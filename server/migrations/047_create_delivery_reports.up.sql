@@ -0,0 +1,24 @@
+-- Delivery reports are CSV exports of per-recipient outcomes for the
+-- messages of one tag (a batch or campaign) within a time range. They are
+-- requested through the API and generated asynchronously by the queue
+-- worker, which writes the CSV to the message store under object_key.
+-- status moves from pending to running to ready or failed.
+CREATE TABLE delivery_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    tag VARCHAR(64) NOT NULL,
+    since TIMESTAMPTZ NOT NULL,
+    until TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    object_key TEXT,
+    row_count INTEGER NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    error TEXT,
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_delivery_reports_group ON delivery_reports(group_id, created_at DESC);
+CREATE INDEX idx_delivery_reports_status ON delivery_reports(status, created_at);
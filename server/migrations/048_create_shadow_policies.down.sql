@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shadow_rejections;
+DROP TABLE IF EXISTS shadow_policies;
@@ -0,0 +1,21 @@
+-- Policies a group runs in shadow mode: what they would reject is counted
+-- in shadow_rejections and let through, so a new policy can be evaluated
+-- before it is enforced. policy is one of allowed_domains,
+-- recipient_validation, sender_policy or scripts.
+CREATE TABLE shadow_policies (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    policy VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, policy)
+);
+
+-- Daily counts of would-be rejections per policy and reason.
+CREATE TABLE shadow_rejections (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    policy VARCHAR(32) NOT NULL,
+    reason TEXT NOT NULL,
+    day DATE NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, policy, reason, day)
+);
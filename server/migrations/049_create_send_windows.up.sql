@@ -0,0 +1,10 @@
+-- The sending windows of a group. Messages submitted over SMTP outside
+-- every window are held until the next one opens, unless marked urgent.
+-- windows is a JSON array of {"days": ["mon", ...], "start": "HH:MM",
+-- "end": "HH:MM"} in timezone. Groups without a row send at any time.
+CREATE TABLE send_windows (
+    group_id UUID PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    windows JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,8 @@
+-- Deliveries per provider and day, counted against the provider's
+-- smtp_config.daily_cap. day is the date in the cap's timezone.
+CREATE TABLE provider_daily_sends (
+    provider_id UUID NOT NULL REFERENCES esp_providers(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    sent INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (provider_id, day)
+);
@@ -0,0 +1,3 @@
+-- Recipient lookups ("what did we send to alice@example.com?") match
+-- addresses case-insensitively, so the index covers the lowercased array.
+CREATE INDEX idx_messages_recipients ON messages USING GIN ((lower(recipients::text)::jsonb) jsonb_path_ops);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS suppressions;
+DELETE FROM inbound_routes WHERE format = 'arf';
+ALTER TABLE inbound_routes DROP CONSTRAINT IF EXISTS inbound_routes_format_check;
+ALTER TABLE inbound_routes ADD CONSTRAINT inbound_routes_format_check
+    CHECK (format IN ('json', 'raw'));
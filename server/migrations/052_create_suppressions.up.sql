@@ -0,0 +1,17 @@
+-- Inbound routes of format 'arf' receive complaint feedback loop reports
+-- instead of posting mail to a URL.
+ALTER TABLE inbound_routes DROP CONSTRAINT IF EXISTS inbound_routes_format_check;
+ALTER TABLE inbound_routes ADD CONSTRAINT inbound_routes_format_check
+    CHECK (format IN ('json', 'raw', 'arf'));
+
+-- Recipients a group no longer sends to. address is lowercased; message_id
+-- is the message that caused the suppression, such as the one a complaint
+-- was about.
+CREATE TABLE suppressions (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    address TEXT NOT NULL,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('complaint')),
+    message_id UUID REFERENCES messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, address)
+);
@@ -0,0 +1,3 @@
+-- Providers of type gmail send through the Gmail API of a Google
+-- Workspace user.
+ALTER TYPE provider_type ADD VALUE IF NOT EXISTS 'gmail';
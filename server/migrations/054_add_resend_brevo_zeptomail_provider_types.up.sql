@@ -0,0 +1,4 @@
+-- Resend, Brevo and ZeptoMail transactional email API providers.
+ALTER TYPE provider_type ADD VALUE IF NOT EXISTS 'resend';
+ALTER TYPE provider_type ADD VALUE IF NOT EXISTS 'brevo';
+ALTER TYPE provider_type ADD VALUE IF NOT EXISTS 'zeptomail';
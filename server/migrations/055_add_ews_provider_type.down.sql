@@ -0,0 +1,8 @@
+-- Enum values cannot be dropped, so the type is recreated without
+-- 'ews'. EWS providers are deleted.
+DELETE FROM esp_providers WHERE provider_type = 'ews';
+ALTER TYPE provider_type RENAME TO provider_type_old;
+CREATE TYPE provider_type AS ENUM ('sendgrid', 'mailgun', 'ses', 'smtp', 'msgraph', 'plugin', 'gmail', 'resend', 'brevo', 'zeptomail');
+ALTER TABLE esp_providers
+    ALTER COLUMN provider_type TYPE provider_type USING provider_type::text::provider_type;
+DROP TYPE provider_type_old;
@@ -0,0 +1,3 @@
+-- Providers of type ews send through Exchange Web Services of an
+-- on-premises Exchange server.
+ALTER TYPE provider_type ADD VALUE IF NOT EXISTS 'ews';
@@ -0,0 +1,14 @@
+-- The message defaults of a group: headers added to messages that do not
+-- set them, and a text and HTML footer appended to their bodies. Messages
+-- with one of exclude_tags, or whose "template" metadata is one of
+-- exclude_templates, are sent without them. headers is a JSON object of
+-- header names to values; the exclusion lists are JSON arrays of strings.
+CREATE TABLE message_defaults (
+    group_id UUID PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    headers JSONB NOT NULL DEFAULT '{}',
+    footer_text TEXT NOT NULL DEFAULT '',
+    footer_html TEXT NOT NULL DEFAULT '',
+    exclude_tags JSONB NOT NULL DEFAULT '[]',
+    exclude_templates JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations so the daemons can apply
+// them without a separate migration tool.
+package migrations
+
+import "embed"
+
+// FS holds the NNN_name.up.sql and NNN_name.down.sql migration files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,97 @@
+// Command webhook is an example delivery plugin. It adds a "webhook"
+// provider type that posts each message as JSON to an HTTP endpoint, and a
+// hook that stamps outgoing messages with the provider that sent them.
+//
+// Build it from the server directory with the daemon's toolchain:
+//
+//	go build -buildmode=plugin -o webhook.so ./plugins/webhook
+//
+// then list webhook.so in plugins.paths and create a provider with
+// provider_type "plugin" and smtp_config
+// {"plugin": "webhook", "options": {"url": "https://hooks.example.com/mail"}}.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sungwon/smtp-proxy/server/internal/provider"
+)
+
+// Register is called by the daemon when the plugin is loaded.
+func Register() error {
+	provider.RegisterType("webhook", newWebhook)
+	provider.RegisterHook(provider.Hook{
+		Name: "provider-header",
+		PreSend: func(_ context.Context, p provider.Provider, msg *provider.Message) error {
+			if msg.Headers == nil {
+				msg.Headers = make(map[string]string)
+			}
+			msg.Headers["X-SMTPProxy-Provider"] = p.GetName()
+			return nil
+		},
+	})
+	return nil
+}
+
+// webhook delivers messages by posting them to options["url"].
+type webhook struct {
+	url    string
+	client provider.HTTPClient
+}
+
+func newWebhook(cfg provider.ProviderConfig, client provider.HTTPClient) (provider.Provider, error) {
+	url := cfg.Options["url"]
+	if url == "" {
+		return nil, errors.New("webhook: options.url is required")
+	}
+	return &webhook{url: url, client: client}, nil
+}
+
+func (w *webhook) GetName() string { return "webhook" }
+
+// Send posts the message. 4xx responses are permanent failures.
+func (w *webhook) Send(_ context.Context, msg *provider.Message) (*provider.DeliveryResult, error) {
+	body, err := json.Marshal(map[string]any{
+		"id":      msg.ID,
+		"from":    msg.From,
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"headers": msg.Headers,
+		"text":    msg.TextBody,
+		"html":    msg.HTMLBody,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webhook: marshal: %w", err)
+	}
+	resp, err := w.client.Do(&provider.HTTPRequest{
+		Method:  "POST",
+		URL:     w.url,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webhook: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &provider.ProviderError{
+			Provider:   "webhook",
+			StatusCode: resp.StatusCode,
+			Message:    string(resp.Body),
+			Permanent:  resp.StatusCode >= 400 && resp.StatusCode < 500,
+		}
+	}
+	return &provider.DeliveryResult{
+		ProviderMessageID: msg.ID,
+		Status:            provider.StatusSent,
+		Timestamp:         time.Now(),
+	}, nil
+}
+
+// HealthCheck reports the webhook as healthy; there is no endpoint to probe.
+func (w *webhook) HealthCheck(context.Context) error { return nil }
+
+func main() {}